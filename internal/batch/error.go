@@ -5,6 +5,7 @@ package batch
 import (
 	"errors"
 
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -56,6 +57,31 @@ type WalkableError interface {
 	error
 }
 
+// FailedSubset returns a batch containing only the messages of msg that failed
+// according to a batch error, along with their indexes within msg. If the
+// error does not identify individual failed messages then false is returned
+// and the entire batch should be considered failed.
+func FailedSubset(msg types.Message, err error) (types.Message, []int, bool) {
+	walkable, ok := err.(WalkableError)
+	if !ok || walkable.IndexedErrors() == 0 || walkable.IndexedErrors() >= msg.Len() {
+		return nil, nil, false
+	}
+
+	failed := message.New(nil)
+	var indexes []int
+	walkable.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil && i < msg.Len() {
+			failed.Append(msg.Get(i))
+			indexes = append(indexes, i)
+		}
+		return true
+	})
+	if len(indexes) == 0 {
+		return nil, nil, false
+	}
+	return failed, indexes, true
+}
+
 // WalkParts applies a closure to each message that was part of the request that
 // caused this error. The closure is provided the message part index, a pointer
 // to the part, and its individual error, which may be nil if the message itself
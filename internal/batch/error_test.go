@@ -0,0 +1,33 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedSubset(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	})
+	errTest := errors.New("test err")
+
+	_, _, ok := FailedSubset(msg, errTest)
+	assert.False(t, ok)
+
+	_, _, ok = FailedSubset(msg, NewError(msg, errTest))
+	assert.False(t, ok)
+
+	_, _, ok = FailedSubset(msg, NewError(msg, errTest).Failed(0, errTest).Failed(1, errTest).Failed(2, errTest))
+	assert.False(t, ok)
+
+	failed, indexes, ok := FailedSubset(msg, NewError(msg, errTest).Failed(0, errTest).Failed(2, errTest))
+	require.True(t, ok)
+	assert.Equal(t, []int{0, 2}, indexes)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(failed))
+}
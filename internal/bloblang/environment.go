@@ -150,6 +150,36 @@ func (e *Environment) WithCustomImporter(fn func(name string) ([]byte, error)) *
 	return &env
 }
 
+// WithImports returns a copy of the environment where the maps and functions
+// defined within each of the provided Bloblang files are available to all
+// mappings parsed with it, without the mappings needing to import the files.
+func (e *Environment) WithImports(paths ...string) (*Environment, error) {
+	env := *e
+	for _, p := range paths {
+		var err error
+		if env.pCtx, err = env.pCtx.ImportDefinitions(p); err != nil {
+			return nil, err
+		}
+	}
+	return &env, nil
+}
+
+// WithCacheAccess returns a copy of the environment where the cache_get and
+// cache_set functions access cache resources with the provided function.
+func (e *Environment) WithCacheAccess(fn query.CacheAccessFunc) *Environment {
+	env := *e
+	env.pCtx.Functions = env.pCtx.Functions.WithCacheAccess(fn)
+	return &env
+}
+
+// WithSecretAccess returns a copy of the environment where the secret function
+// obtains the values of secret resources with the provided function.
+func (e *Environment) WithSecretAccess(fn query.SecretAccessFunc) *Environment {
+	env := *e
+	env.pCtx.Functions = env.pCtx.Functions.WithSecretAccess(fn)
+	return &env
+}
+
 // WithoutMethods returns a copy of the environment but with a variadic list of
 // method names removed. Instantiation of these removed methods within a mapping
 // will cause errors at parse time.
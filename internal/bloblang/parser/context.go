@@ -16,6 +16,9 @@ type Context struct {
 	Methods      *query.MethodSet
 	namedContext *namedContext
 	importer     Importer
+
+	// Maps that are available to all mappings parsed with this context.
+	maps map[string]query.Function
 }
 
 // EmptyContext returns a parser context with no functions, methods or import
@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
@@ -39,20 +40,74 @@ func ParseMapping(pCtx Context, expr string) (*mapping.Executor, *Error) {
 	return res.Payload.(*mapping.Executor), nil
 }
 
+// ImportDefinitions returns a version of the parser context where the maps and
+// functions defined within a Bloblang file are available to all mappings parsed
+// with it, as if each mapping had imported the file.
+func (pCtx Context) ImportDefinitions(fpath string) (Context, error) {
+	contents, err := pCtx.importer.Import(fpath)
+	if err != nil {
+		return pCtx, fmt.Errorf("failed to read import: %w", err)
+	}
+
+	funcs := map[string]userFunction{}
+	importContent := []rune(string(contents))
+	execRes := parseExecutorWithFuncs(pCtx.WithImporterRelativeToFile(fpath), funcs)(importContent)
+	if execRes.Err != nil {
+		return pCtx, fmt.Errorf("failed to parse import '%v': %v", fpath, execRes.Err.ErrorAtPosition(importContent))
+	}
+
+	nextCtx := pCtx
+	nextCtx.Functions = pCtx.Functions.Without()
+	for k, fn := range funcs {
+		if err := nextCtx.Functions.Add(fn.spec, fn.ctor); err != nil {
+			return pCtx, fmt.Errorf("function name collision from import '%v': %v", fpath, k)
+		}
+	}
+
+	nextCtx.maps = make(map[string]query.Function, len(pCtx.maps))
+	for k, v := range pCtx.maps {
+		nextCtx.maps[k] = v
+	}
+	for k, v := range execRes.Payload.(*mapping.Executor).Maps() {
+		if existing, exists := nextCtx.maps[k]; exists && existing != v {
+			return pCtx, fmt.Errorf("map name collision from import '%v': %v", fpath, k)
+		}
+		nextCtx.maps[k] = v
+	}
+	return nextCtx, nil
+}
+
 //------------------------------------------------------------------------------'
 
 func parseExecutor(pCtx Context) Func {
+	return parseExecutorWithFuncs(pCtx, map[string]userFunction{})
+}
+
+// parseExecutorWithFuncs parses a mapping, where the functions declared or
+// imported by the mapping are added to funcs.
+func parseExecutorWithFuncs(pCtx Context, funcs map[string]userFunction) Func {
 	newline := NewlineAllowComment()
 	whitespace := SpacesAndTabs()
 	allWhitespace := DiscardAll(OneOf(whitespace, newline))
 
 	return func(input []rune) Result {
-		maps := map[string]query.Function{}
+		maps := make(map[string]query.Function, len(pCtx.maps))
+		for k, v := range pCtx.maps {
+			maps[k] = v
+		}
 		statements := []mapping.Statement{}
 
+		// Functions declared by the mapping are added to a copy of the
+		// function set, which makes them available to the statements that
+		// follow the declaration. Imported files are parsed without them.
+		importCtx := pCtx
+		pCtx := pCtx
+		pCtx.Functions = pCtx.Functions.Without()
+
 		statement := OneOf(
-			importParser(maps, pCtx),
+			importParser(maps, funcs, pCtx.Functions, importCtx),
 			mapParser(maps, pCtx),
+			funcParser(maps, funcs, pCtx),
 			letStatementParser(pCtx),
 			metaStatementParser(false, pCtx),
 			plainMappingStatementParser(pCtx),
@@ -160,8 +215,13 @@ func singleRootMapping(pCtx Context) Func {
 			return Fail(NewError(res.Remaining, expStr), input)
 		}
 
+		maps := make(map[string]query.Function, len(pCtx.maps))
+		for k, v := range pCtx.maps {
+			maps[k] = v
+		}
+
 		stmt := mapping.NewStatement(input, mapping.NewJSONAssignment(), fn)
-		return Success(mapping.NewExecutor("", input, map[string]query.Function{}, stmt), nil)
+		return Success(mapping.NewExecutor("", input, maps, stmt), nil)
 	}
 }
 
@@ -180,7 +240,7 @@ func varNameParser() Func {
 	)
 }
 
-func importParser(maps map[string]query.Function, pCtx Context) Func {
+func importParser(maps map[string]query.Function, funcs map[string]userFunction, functions *query.FunctionSet, pCtx Context) Func {
 	p := Sequence(
 		Term("import"),
 		SpacesAndTabs(),
@@ -206,20 +266,29 @@ func importParser(maps map[string]query.Function, pCtx Context) Func {
 
 		nextCtx := pCtx.WithImporterRelativeToFile(fpath)
 
+		importedFuncs := map[string]userFunction{}
 		importContent := []rune(string(contents))
-		execRes := parseExecutor(nextCtx)(importContent)
+		execRes := parseExecutorWithFuncs(nextCtx, importedFuncs)(importContent)
 		if execRes.Err != nil {
 			return Fail(NewFatalError(input, NewImportError(fpath, importContent, execRes.Err)), input)
 		}
 
-		exec := execRes.Payload.(*mapping.Executor)
-		if len(exec.Maps()) == 0 {
-			err := fmt.Errorf("no maps to import from '%v'", fpath)
+		importedMaps := map[string]query.Function{}
+		for k, v := range execRes.Payload.(*mapping.Executor).Maps() {
+			// Maps provided by the parser context are available to both
+			// mappings and therefore aren't imported.
+			if existing, exists := pCtx.maps[k]; exists && existing == v {
+				continue
+			}
+			importedMaps[k] = v
+		}
+		if len(importedMaps) == 0 && len(importedFuncs) == 0 {
+			err := fmt.Errorf("no maps or functions to import from '%v'", fpath)
 			return Fail(NewFatalError(input, err), input)
 		}
 
 		collisions := []string{}
-		for k, v := range exec.Maps() {
+		for k, v := range importedMaps {
 			if _, exists := maps[k]; exists {
 				collisions = append(collisions, k)
 			} else {
@@ -231,6 +300,19 @@ func importParser(maps map[string]query.Function, pCtx Context) Func {
 			return Fail(NewFatalError(input, err), input)
 		}
 
+		for k, fn := range importedFuncs {
+			if err := functions.Add(fn.spec, fn.ctor); err != nil {
+				collisions = append(collisions, k)
+			} else {
+				funcs[k] = fn
+			}
+		}
+		if len(collisions) > 0 {
+			sort.Strings(collisions)
+			err := fmt.Errorf("function name collisions from import '%v': %v", fpath, collisions)
+			return Fail(NewFatalError(input, err), input)
+		}
+
 		return Success(fpath, res.Remaining)
 	}
 }
@@ -302,6 +384,106 @@ func mapParser(maps map[string]query.Function, pCtx Context) Func {
 	}
 }
 
+// userFunction is a function declared within a mapping.
+type userFunction struct {
+	spec query.FunctionSpec
+	ctor query.FunctionCtor
+}
+
+func funcParser(maps map[string]query.Function, funcs map[string]userFunction, pCtx Context) Func {
+	newline := NewlineAllowComment()
+	whitespace := SpacesAndTabs()
+	allWhitespace := DiscardAll(OneOf(whitespace, newline))
+
+	p := Sequence(
+		Term("func"),
+		whitespace,
+		// Prevents a missing name from being captured by the next parser
+		MustBe(Expect(SnakeCase(), "function name")),
+		MustBe(DelimitedPattern(
+			Expect(Sequence(Char('('), allWhitespace), "function parameters"),
+			Expect(SnakeCase(), "parameter name"),
+			Sequence(Discard(whitespace), Char(','), allWhitespace),
+			Sequence(allWhitespace, Char(')')),
+			false,
+		)),
+		SpacesAndTabs(),
+		DelimitedPattern(
+			Sequence(
+				Char('{'),
+				allWhitespace,
+			),
+			OneOf(
+				letStatementParser(pCtx),
+				metaStatementParser(true, pCtx),
+				plainMappingStatementParser(pCtx),
+			),
+			Sequence(
+				Discard(whitespace),
+				newline,
+				allWhitespace,
+			),
+			Sequence(
+				allWhitespace,
+				Char('}'),
+			),
+			true,
+		),
+	)
+
+	return func(input []rune) Result {
+		res := p(input)
+		if res.Err != nil {
+			return res
+		}
+
+		seqSlice := res.Payload.([]interface{})
+		name := seqSlice[2].(string)
+		paramSlice := seqSlice[3].([]interface{})
+		stmtSlice := seqSlice[5].([]interface{})
+
+		spec := query.NewFunctionSpec(query.FunctionCategoryGeneral, name, "")
+		params := make([]string, len(paramSlice))
+		for i, v := range paramSlice {
+			params[i] = v.(string)
+			for _, p := range params[:i] {
+				if p == params[i] {
+					return Fail(NewFatalError(input, fmt.Errorf("duplicate parameter name: %v", p)), input)
+				}
+			}
+			spec = spec.Param(query.ParamAny(params[i], ""))
+		}
+
+		statements := make([]mapping.Statement, len(stmtSlice))
+		for i, v := range stmtSlice {
+			statements[i] = v.(mapping.Statement)
+		}
+		exec := mapping.NewExecutor("func "+name, input, maps, statements...)
+
+		ctor := func(args *query.ParsedParams) (query.Function, error) {
+			values := args.Raw()
+			return query.ClosureFunction("function "+name, func(ctx query.FunctionContext) (interface{}, error) {
+				// The body of a function only has access to its parameters as
+				// variables, and to the maps of the mapping declaring it.
+				vars := make(map[string]interface{}, len(params))
+				for i, p := range params {
+					vars[p] = values[i]
+				}
+				ctx.Vars = vars
+				ctx.Maps = maps
+				return exec.Exec(ctx)
+			}, exec.QueryTargets), nil
+		}
+
+		if err := pCtx.Functions.Add(spec, ctor); err != nil {
+			return Fail(NewFatalError(input, err), input)
+		}
+		funcs[name] = userFunction{spec: spec, ctor: ctor}
+
+		return Success(name, res.Remaining)
+	}
+}
+
 func letStatementParser(pCtx Context) Func {
 	p := Sequence(
 		Expect(Term("let"), "assignment"),
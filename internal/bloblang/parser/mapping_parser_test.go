@@ -22,6 +22,9 @@ func TestMappingErrors(t *testing.T) {
 	require.NoError(t, os.WriteFile(noMapsFile, []byte(`foo = "this is valid but has no maps"`), 0o777))
 	require.NoError(t, os.WriteFile(goodMapFile, []byte(`map foo { foo = "this is valid" }`), 0o777))
 
+	goodFuncFile := filepath.Join(dir, "good_func.blobl")
+	require.NoError(t, os.WriteFile(goodFuncFile, []byte(`func double(v) { root = $v * 2 }`), 0o777))
+
 	tests := map[string]struct {
 		mapping     string
 		errContains string
@@ -36,12 +39,12 @@ func TestMappingErrors(t *testing.T) {
 		},
 		"no mappings": {
 			mapping:     ``,
-			errContains: `line 1 char 1: expected import, map, or assignment`,
+			errContains: `line 1 char 1: expected import, map, func, or assignment`,
 		},
 		"no mappings 2": {
 			mapping: `
    `,
-			errContains: `line 2 char 4: expected import, map, or assignment`,
+			errContains: `line 2 char 4: expected import, map, func, or assignment`,
 		},
 		"double mapping": {
 			mapping:     `foo = bar bar = baz`,
@@ -82,13 +85,13 @@ meta foo = "bar"`,
 		"bad char 2": {
 			mapping: `let foo = bar
 !foo = bar`,
-			errContains: `line 2 char 1: expected import, map, or assignment`,
+			errContains: `line 2 char 1: expected import, map, func, or assignment`,
 		},
 		"bad char 3": {
 			mapping: `let foo = bar
 !foo = bar
 this = that`,
-			errContains: `line 2 char 1: expected import, map, or assignment`,
+			errContains: `line 2 char 1: expected import, map, func, or assignment`,
 		},
 		"bad query": {
 			mapping:     `foo = blah.`,
@@ -138,7 +141,7 @@ foo = bar.apply("from_import")`, badMapFile),
 			mapping: fmt.Sprintf(`import "%v"
 
 foo = bar.apply("from_import")`, noMapsFile),
-			errContains: fmt.Sprintf(`line 1 char 1: no maps to import from '%v'`, noMapsFile),
+			errContains: fmt.Sprintf(`line 1 char 1: no maps or functions to import from '%v'`, noMapsFile),
 		},
 		"colliding maps file import": {
 			mapping: fmt.Sprintf(`map "foo" { this = that }			
@@ -151,7 +154,51 @@ foo = bar.apply("foo")`, goodMapFile),
 		"quotes at root": {
 			mapping: `
 "root.something" = 5 + 2`,
-			errContains: "line 2 char 1: expected import, map, or assignment",
+			errContains: "line 2 char 1: expected import, map, func, or assignment",
+		},
+		"function name collision": {
+			mapping: `func uuid_v4() {
+  root = "nope"
+}
+root = uuid_v4()`,
+			errContains: `line 1 char 1: conflicting function name: uuid_v4`,
+		},
+		"duplicate function parameter": {
+			mapping: `func foo(a, a) {
+  root = $a
+}
+root = foo(1, 2)`,
+			errContains: `line 1 char 1: duplicate parameter name: a`,
+		},
+		"no function parameters": {
+			mapping: `func foo {
+  root = "foo"
+}`,
+			errContains: `line 1 char 9: required: expected function parameters`,
+		},
+		"function used before declaration": {
+			mapping: `root = foo()
+func foo() {
+  root = "foo"
+}`,
+			errContains: `unrecognised function 'foo'`,
+		},
+		"function contains meta assignment": {
+			mapping: `func foo() {
+  meta foo = "bar"
+}
+root = foo()`,
+			errContains: `line 2 char 3: setting meta fields from within a map is not allowed`,
+		},
+		"colliding functions file import": {
+			mapping: fmt.Sprintf(`func double(v) {
+  root = $v * 2
+}
+
+import "%v"
+
+root = double(2)`, goodFuncFile),
+			errContains: fmt.Sprintf(`line 5 char 1: function name collisions from import '%v': [double]`, goodFuncFile),
 		},
 	}
 
@@ -178,6 +225,15 @@ func TestMappings(t *testing.T) {
 	directMapFile := filepath.Join(dir, "direct_map.blobl")
 	require.NoError(t, os.WriteFile(directMapFile, []byte(`root.nested = this`), 0o777))
 
+	funcFile := filepath.Join(dir, "funcs.blobl")
+	require.NoError(t, os.WriteFile(funcFile, []byte(`map tag {
+  root = "tag:" + this
+}
+
+func tag_all(values) {
+  root = $values.map_each(v -> v.apply("tag"))
+}`), 0o777))
+
 	type part struct {
 		Content string
 		Meta    map[string]string
@@ -456,6 +512,52 @@ root = this.apply("foo")`, goodMapFile),
 				Content: `{"foo":"this is valid","nested":{"outter":{"inner":"hello world"}}}`,
 			},
 		},
+		"test functions": {
+			mapping: `func greet(name, greeting) {
+  let punctuation = "!"
+  root = "%v %v%v".format($greeting, $name, $punctuation)
+}
+
+root.a = greet("world", "hello")
+root.b = greet(name: this.who, greeting: "hey")
+root.c = $punctuation | "unset"`,
+			input: []part{
+				{Content: `{"who":"you"}`},
+			},
+			output: part{
+				Content: `{"a":"hello world!","b":"hey you!","c":"unset"}`,
+			},
+		},
+		"test functions calling functions": {
+			mapping: `map prefixed {
+  root = "> " + this
+}
+func prefix(value) {
+  root = $value.apply("prefixed")
+}
+func prefix_twice(value) {
+  root = prefix(prefix($value))
+}
+root = prefix_twice(this.value)`,
+			input: []part{
+				{Content: `{"value":"foo"}`},
+			},
+			output: part{
+				Content: `> > foo`,
+			},
+		},
+		"test imported functions": {
+			mapping: fmt.Sprintf(`import "%v"
+
+root.tags = tag_all(this.tags)
+root.first = this.tags.index(0).apply("tag")`, funcFile),
+			input: []part{
+				{Content: `{"tags":["foo","bar"]}`},
+			},
+			output: part{
+				Content: `{"first":"tag:foo","tags":["tag:foo","tag:bar"]}`,
+			},
+		},
 		"test directly imported map": {
 			mapping: fmt.Sprintf(`from "%v"`, directMapFile),
 			input: []part{
@@ -501,3 +603,49 @@ root = this.apply("foo")`, goodMapFile),
 		})
 	}
 }
+
+func TestMappingImportDefinitions(t *testing.T) {
+	dir := t.TempDir()
+
+	defsFile := filepath.Join(dir, "defs.blobl")
+	require.NoError(t, os.WriteFile(defsFile, []byte(`map upper {
+  root = this.uppercase()
+}
+
+func shout(value) {
+  root = $value.apply("upper") + "!"
+}`), 0o777))
+
+	otherFile := filepath.Join(dir, "other.blobl")
+	require.NoError(t, os.WriteFile(otherFile, []byte(`func whisper(value) {
+  root = $value.lowercase() + "..."
+}`), 0o777))
+
+	pCtx, err := GlobalContext().ImportDefinitions(defsFile)
+	require.NoError(t, err)
+
+	exec, perr := ParseMapping(pCtx, fmt.Sprintf(`import "%v"
+
+root.a = shout(this.value)
+root.b = whisper(this.value)
+root.c = this.value.apply("upper")`, otherFile))
+	require.Nil(t, perr)
+
+	resPart, err := exec.MapPart(0, message.New([][]byte{[]byte(`{"value":"Foo"}`)}))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"FOO!","b":"foo...","c":"FOO"}`, string(resPart.Get()))
+
+	exec, perr = ParseMapping(pCtx, `shout(this.value)`)
+	require.Nil(t, perr)
+
+	resPart, err = exec.MapPart(0, message.New([][]byte{[]byte(`{"value":"bar"}`)}))
+	require.NoError(t, err)
+	assert.Equal(t, `BAR!`, string(resPart.Get()))
+
+	// The source context is unchanged.
+	_, perr = ParseMapping(GlobalContext(), `shout(this.value)`)
+	require.NotNil(t, perr)
+
+	_, err = pCtx.ImportDefinitions(defsFile)
+	require.EqualError(t, err, fmt.Sprintf("failed to parse import '%v': line 1 char 1: map name collision: upper", defsFile))
+}
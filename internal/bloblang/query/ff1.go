@@ -0,0 +1,157 @@
+package query
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ff1MinDomain is the minimum number of possible values of a numeral string
+// that can be encrypted, as recommended by NIST SP 800-38G Revision 1.
+const ff1MinDomain = 1000000
+
+// ff1Cipher implements the FF1 format-preserving encryption mode of NIST SP
+// 800-38G for numeral strings of a given radix.
+type ff1Cipher struct {
+	block cipher.Block
+	radix int
+	tweak []byte
+}
+
+func newFF1Cipher(key []byte, radix int, tweak []byte) (*ff1Cipher, error) {
+	if radix < 2 || radix > 1<<16 {
+		return nil, fmt.Errorf("radix must be between 2 and %v, got %v", 1<<16, radix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ff1Cipher{block: block, radix: radix, tweak: tweak}, nil
+}
+
+func (f *ff1Cipher) encrypt(numerals []uint16) ([]uint16, error) {
+	return f.apply(numerals, true)
+}
+
+func (f *ff1Cipher) decrypt(numerals []uint16) ([]uint16, error) {
+	return f.apply(numerals, false)
+}
+
+func (f *ff1Cipher) apply(numerals []uint16, encrypt bool) ([]uint16, error) {
+	n := len(numerals)
+	radix := big.NewInt(int64(f.radix))
+	if new(big.Int).Exp(radix, big.NewInt(int64(n)), nil).Cmp(big.NewInt(ff1MinDomain)) < 0 {
+		return nil, errors.New("value is too short to be encrypted with the given alphabet")
+	}
+
+	u := n / 2
+	v := n - u
+	a := append([]uint16(nil), numerals[:u]...)
+	b := append([]uint16(nil), numerals[u:]...)
+
+	radixV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+	bLen := (new(big.Int).Sub(radixV, big.NewInt(1)).BitLen() + 7) / 8
+	dLen := 4*((bLen+3)/4) + 4
+
+	p := make([]byte, 16)
+	p[0], p[1], p[2] = 1, 2, 1
+	p[3], p[4], p[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	p[6] = 10
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(len(f.tweak)))
+
+	padLen := (16 - (len(f.tweak)+bLen+1)%16) % 16
+	q := make([]byte, len(f.tweak)+padLen+1+bLen)
+	copy(q, f.tweak)
+
+	radixU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	blocks := (dLen + 15) / 16
+	s := make([]byte, blocks*16)
+	r := make([]byte, 16)
+	y, c := new(big.Int), new(big.Int)
+
+	for step := 0; step < 10; step++ {
+		i := step
+		if !encrypt {
+			i = 9 - step
+		}
+
+		// The half that feeds the round function is B when encrypting and A
+		// when decrypting.
+		src, dst := b, a
+		if !encrypt {
+			src, dst = a, b
+		}
+
+		q[len(f.tweak)+padLen] = byte(i)
+		numRadix(src, radix).FillBytes(q[len(q)-bLen:])
+		f.prf(r, p, q)
+
+		copy(s, r)
+		for j := 1; j < blocks; j++ {
+			var x [16]byte
+			copy(x[:], r)
+			binary.BigEndian.PutUint32(x[12:], binary.BigEndian.Uint32(x[12:])^uint32(j))
+			f.block.Encrypt(s[j*16:], x[:])
+		}
+		y.SetBytes(s[:dLen])
+
+		m, modulus := u, radixU
+		if i%2 == 1 {
+			m, modulus = v, radixV
+		}
+
+		c.Set(numRadix(dst, radix))
+		if encrypt {
+			c.Add(c, y)
+		} else {
+			c.Sub(c, y)
+		}
+		c.Mod(c, modulus)
+
+		out := strRadix(c, radix, m)
+		if encrypt {
+			a, b = b, out
+		} else {
+			b, a = a, out
+		}
+	}
+	return append(a, b...), nil
+}
+
+// prf computes the CBC-MAC of P || Q with a zero initialization vector.
+func (f *ff1Cipher) prf(dst, p, q []byte) {
+	var x [16]byte
+	f.block.Encrypt(x[:], p)
+	for i := 0; i < len(q); i += 16 {
+		for j := 0; j < 16; j++ {
+			x[j] ^= q[i+j]
+		}
+		f.block.Encrypt(x[:], x[:])
+	}
+	copy(dst, x[:])
+}
+
+func numRadix(numerals []uint16, radix *big.Int) *big.Int {
+	x := new(big.Int)
+	for _, n := range numerals {
+		x.Mul(x, radix)
+		x.Add(x, big.NewInt(int64(n)))
+	}
+	return x
+}
+
+func strRadix(x *big.Int, radix *big.Int, m int) []uint16 {
+	numerals := make([]uint16, m)
+	x = new(big.Int).Set(x)
+	rem := new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		x.DivMod(x, radix, rem)
+		numerals[i] = uint16(rem.Int64())
+	}
+	return numerals
+}
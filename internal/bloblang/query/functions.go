@@ -837,6 +837,40 @@ var _ = registerSimpleFunction(
 	},
 )
 
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryGeneral, "ulid",
+		"Generates a new [ULID](https://github.com/ulid/spec) each time it is invoked and prints a string representation. ULIDs are lexicographically sortable by the time at which they were generated, and ULIDs generated within the same millisecond by the same process are also sortable in the order in which they were generated. The timestamp of a ULID can be extracted with the method [`parse_ulid`](/docs/guides/bloblang/methods#parse_ulid).",
+		NewExampleSpec("", `root.id = ulid()`),
+	),
+	func(_ FunctionContext) (interface{}, error) {
+		return globalULIDGenerator.next(time.Now())
+	},
+)
+
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryGeneral, "snowflake_id",
+		"Generates a new 64-bit snowflake ID each time it is invoked, which is composed of a timestamp in milliseconds since the Twitter snowflake epoch (`2010-11-04T01:42:54.657Z`), a node ID and a sequence number. Snowflake IDs are sortable by the time at which they were generated, and are unique across processes as long as each process generating them is configured with a different node ID. The timestamp of a snowflake ID can be extracted with the method [`parse_snowflake_id`](/docs/guides/bloblang/methods#parse_snowflake_id).",
+		NewExampleSpec("", `root.id = snowflake_id()`),
+		NewExampleSpec("The node ID can be taken from the environment in order to configure each instance of a deployment differently.", `root.id = snowflake_id(env("NODE_ID").number())`),
+	).
+		Param(ParamInt64("node_id", "The node ID to embed within IDs, which must be between 0 and 1023.").Default(0)),
+	func(args *ParsedParams) (Function, error) {
+		nodeID, err := args.FieldInt64("node_id")
+		if err != nil {
+			return nil, err
+		}
+		gen, err := getSnowflakeGenerator(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return ClosureFunction("function snowflake_id", func(_ FunctionContext) (interface{}, error) {
+			return gen.next(time.Now()), nil
+		}, nil), nil
+	},
+)
+
 //------------------------------------------------------------------------------
 
 var _ = registerFunction(
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// CacheAccessFunc attempts to access a cache resource by its name and executes
+// a closure function with the cache as an argument. Returns an error if the
+// cache does not exist (or is otherwise inaccessible).
+type CacheAccessFunc func(name string, fn func(c types.Cache)) error
+
+var errCacheAccessUnavailable = errors.New("cache resources are not available within this context")
+
+func accessCache(access CacheAccessFunc, name string, fn func(c types.Cache)) error {
+	if access == nil {
+		return errCacheAccessUnavailable
+	}
+	return access(name, fn)
+}
+
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryEnvironment, "cache_get",
+		"Returns the value of a key from a [cache resource](/docs/components/caches/about) as bytes, or an error if the key does not exist, which can be caught with the method [`catch`](/docs/guides/bloblang/methods#catch) in order to provide a fallback. The cache is queried each time the function is executed and so, as with the [`cache` processor](/docs/components/processors/cache), a cache with low latency is recommended for use within busy pipelines.",
+		NewExampleSpec("",
+			`root = this
+root.user = cache_get("users", this.user_id).parse_json().catch(null)`,
+		),
+		NewExampleSpec("Use the method `string` in order to coerce the result into a string.",
+			`root.region = cache_get("regions", this.zone).string().catch("unknown")`,
+		),
+	).
+		MarkImpure().
+		Param(ParamString("resource", "The name of a cache resource.")).
+		Param(ParamString("key", "The key to obtain.")),
+	cacheGetFunction(nil),
+)
+
+func cacheGetFunction(access CacheAccessFunc) FunctionCtor {
+	return func(args *ParsedParams) (Function, error) {
+		resource, err := args.FieldString("resource")
+		if err != nil {
+			return nil, err
+		}
+		key, err := args.FieldString("key")
+		if err != nil {
+			return nil, err
+		}
+		return ClosureFunction("function cache_get", func(_ FunctionContext) (interface{}, error) {
+			var value []byte
+			var cErr error
+			if err := accessCache(access, resource, func(c types.Cache) {
+				value, cErr = c.Get(key)
+			}); err != nil {
+				return nil, err
+			}
+			if cErr != nil {
+				return nil, fmt.Errorf("failed to get key '%v' from cache '%v': %w", key, resource, cErr)
+			}
+			return value, nil
+		}, nil), nil
+	}
+}
+
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryEnvironment, "cache_set",
+		`Sets the value of a key within a [cache resource](/docs/components/caches/about) and returns the value. Strings and bytes are stored as they are, and all other values are stored in their JSON serialized form.
+
+The key is set each time the function is executed, which is at the point it is reached within the mapping, and is not reverted when a later part of the mapping fails or when the message is dropped, retried or rejected downstream. A function that is within a branch of an `+"`if`"+` or `+"`match`"+` expression that isn't taken is not executed, and a mapping that is executed multiple times for each message, such as a `+"`check`"+` of a `+"`switch`"+` output, sets the key each time. Caches are not transactional, and therefore parallel pipelines setting the same key can overwrite each other in any order.`,
+		NewExampleSpec("",
+			`root = this
+root.first_seen = cache_get("first_seen", this.user_id).string().catch(cache_set("first_seen", this.user_id, now()))`,
+		),
+	).
+		MarkImpure().
+		Param(ParamString("resource", "The name of a cache resource.")).
+		Param(ParamString("key", "The key to set.")).
+		Param(ParamAny("value", "The value to set.")),
+	cacheSetFunction(nil),
+)
+
+func cacheSetFunction(access CacheAccessFunc) FunctionCtor {
+	return func(args *ParsedParams) (Function, error) {
+		resource, err := args.FieldString("resource")
+		if err != nil {
+			return nil, err
+		}
+		key, err := args.FieldString("key")
+		if err != nil {
+			return nil, err
+		}
+		value, err := args.Field("value")
+		if err != nil {
+			return nil, err
+		}
+		valueBytes := IToBytes(value)
+		return ClosureFunction("function cache_set", func(_ FunctionContext) (interface{}, error) {
+			var cErr error
+			if err := accessCache(access, resource, func(c types.Cache) {
+				cErr = c.Set(key, valueBytes)
+			}); err != nil {
+				return nil, err
+			}
+			if cErr != nil {
+				return nil, fmt.Errorf("failed to set key '%v' of cache '%v': %w", key, resource, cErr)
+			}
+			return value, nil
+		}, nil), nil
+	}
+}
+
+// WithCacheAccess creates a clone of the function set that can be mutated in
+// isolation, where the cache functions, if present, access cache resources
+// with the provided function.
+func (f *FunctionSet) WithCacheAccess(access CacheAccessFunc) *FunctionSet {
+	newSet := f.Without()
+	if _, exists := newSet.constructors["cache_get"]; exists {
+		newSet.constructors["cache_get"] = cacheGetFunction(access)
+	}
+	if _, exists := newSet.constructors["cache_set"]; exists {
+		newSet.constructors["cache_set"] = cacheSetFunction(access)
+	}
+	return newSet
+}
@@ -0,0 +1,54 @@
+package query
+
+import (
+	"errors"
+)
+
+// SecretAccessFunc attempts to obtain the value of a secret resource by its
+// name. Returns an error if the secret does not exist.
+type SecretAccessFunc func(name string) ([]byte, error)
+
+var errSecretAccessUnavailable = errors.New("secret resources are not available within this context")
+
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryEnvironment, "secret",
+		"Returns the value of a [secret resource](/docs/configuration/resources#secrets) as bytes, which is useful for providing key material to methods such as [`encrypt_aes_gcm`](/docs/guides/bloblang/methods#encrypt_aes_gcm) and [`hmac`](/docs/guides/bloblang/methods#hmac) without including it within the config. Secrets are read once when the mapping is parsed, and an error is returned at that point if the secret does not exist.",
+		NewExampleSpec("",
+			`root.card = this.card.encrypt_aes_ff1(secret("card_key"))`,
+		),
+	).
+		MarkImpure().
+		Param(ParamString("name", "The label of a secret resource.")),
+	secretFunction(nil),
+)
+
+func secretFunction(access SecretAccessFunc) FunctionCtor {
+	return func(args *ParsedParams) (Function, error) {
+		name, err := args.FieldString("name")
+		if err != nil {
+			return nil, err
+		}
+		if access == nil {
+			return ClosureFunction("function secret", func(_ FunctionContext) (interface{}, error) {
+				return nil, errSecretAccessUnavailable
+			}, nil), nil
+		}
+		value, err := access(name)
+		if err != nil {
+			return nil, err
+		}
+		return NewLiteralFunction("secret "+name, value), nil
+	}
+}
+
+// WithSecretAccess creates a clone of the function set that can be mutated in
+// isolation, where the secret function, if present, obtains secrets with the
+// provided function.
+func (f *FunctionSet) WithSecretAccess(access SecretAccessFunc) *FunctionSet {
+	newSet := f.Without()
+	if _, exists := newSet.constructors["secret"]; exists {
+		newSet.constructors["secret"] = secretFunction(access)
+	}
+	return newSet
+}
@@ -5,8 +5,10 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -276,6 +278,67 @@ func TestKsuidFunction(t *testing.T) {
 	assert.NotEmpty(t, res)
 }
 
+func TestULIDFunction(t *testing.T) {
+	e, err := InitFunctionHelper("ulid")
+	require.Nil(t, err)
+
+	var last string
+	for i := 0; i < 1000; i++ {
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+
+		id, ok := res.(string)
+		require.True(t, ok)
+		require.Len(t, id, 26)
+		require.Greater(t, id, last)
+		last = id
+	}
+
+	ts, _, err := decodeULID(last)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+}
+
+func TestULIDEncoding(t *testing.T) {
+	entropy := [10]byte{0xd6, 0x76, 0x4c, 0x61, 0xef, 0xb9, 0x93, 0x02, 0xbd, 0x5b}
+	id := encodeULID(1469922850259, entropy)
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", id)
+
+	ts, decoded, err := decodeULID(id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1469922850259), ts.UnixNano()/int64(time.Millisecond))
+	assert.Equal(t, entropy[:], decoded)
+
+	_, _, err = decodeULID("81ARZ3NDEKTSV4RRFFQ69G5FAV")
+	require.EqualError(t, err, "ulid timestamp overflows 48 bits")
+
+	_, _, err = decodeULID("01ARZ3NDEKTSV4RRFFQ69G5FAU")
+	require.EqualError(t, err, "invalid ulid character: 'U'")
+}
+
+func TestSnowflakeIDFunction(t *testing.T) {
+	e, err := InitFunctionHelper("snowflake_id", int64(5))
+	require.Nil(t, err)
+
+	var last int64
+	for i := 0; i < 10000; i++ {
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+
+		id, ok := res.(int64)
+		require.True(t, ok)
+		require.Greater(t, id, last)
+		last = id
+	}
+
+	ts, nodeID, _ := decodeSnowflakeID(last)
+	assert.Equal(t, int64(5), nodeID)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+
+	_, err = InitFunctionHelper("snowflake_id", int64(1024))
+	require.EqualError(t, err, "node id must be between 0 and 1023, got 1024")
+}
+
 func TestEnvFunction(t *testing.T) {
 	key := "BENTHOS_TEST_BLOBLANG_FUNCTION"
 	os.Setenv(key, "foobar")
@@ -291,6 +354,99 @@ func TestEnvFunction(t *testing.T) {
 	assert.Equal(t, "foobar", res)
 }
 
+type fakeCache struct {
+	values map[string][]byte
+}
+
+func (f *fakeCache) Get(key string) ([]byte, error) {
+	v, exists := f.values[key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (f *fakeCache) Set(key string, value []byte) error {
+	f.values[key] = value
+	return nil
+}
+
+func (f *fakeCache) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		f.values[k] = v
+	}
+	return nil
+}
+
+func (f *fakeCache) Add(key string, value []byte) error {
+	if _, exists := f.values[key]; exists {
+		return types.ErrKeyAlreadyExists
+	}
+	f.values[key] = value
+	return nil
+}
+
+func (f *fakeCache) Delete(key string) error {
+	delete(f.values, key)
+	return nil
+}
+
+func (f *fakeCache) CloseAsync() {}
+
+func (f *fakeCache) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestCacheFunctions(t *testing.T) {
+	cache := &fakeCache{values: map[string][]byte{
+		"foo": []byte("bar"),
+	}}
+
+	fnSet := AllFunctions.WithCacheAccess(func(name string, fn func(types.Cache)) error {
+		if name != "things" {
+			return fmt.Errorf("cache %v not found", name)
+		}
+		fn(cache)
+		return nil
+	})
+
+	initFn := func(name string, args ...interface{}) Function {
+		t.Helper()
+		parsedArgs, err := fnSet.specs[name].Params.PopulateNameless(args...)
+		require.NoError(t, err)
+		fn, err := fnSet.Init(name, parsedArgs)
+		require.NoError(t, err)
+		return fn
+	}
+
+	res, err := initFn("cache_get", "things", "foo").Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), res)
+
+	_, err = initFn("cache_get", "things", "nope").Exec(FunctionContext{})
+	require.EqualError(t, err, "failed to get key 'nope' from cache 'things': key does not exist")
+
+	_, err = initFn("cache_get", "nope", "foo").Exec(FunctionContext{})
+	require.EqualError(t, err, "cache nope not found")
+
+	res, err = initFn("cache_set", "things", "baz", map[string]interface{}{"a": "b"}).Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, res)
+	assert.Equal(t, `{"a":"b"}`, string(cache.values["baz"]))
+
+	res, err = initFn("cache_set", "things", "foo", "qux").Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "qux", res)
+	assert.Equal(t, "qux", string(cache.values["foo"]))
+
+	// Functions of the global set are not bound to any caches.
+	fn, err := InitFunctionHelper("cache_get", "things", "foo")
+	require.NoError(t, err)
+
+	_, err = fn.Exec(FunctionContext{})
+	require.EqualError(t, err, "cache resources are not available within this context")
+}
+
 func TestRandomInt(t *testing.T) {
 	e, err := InitFunctionHelper("random_int")
 	require.Nil(t, err)
@@ -0,0 +1,188 @@
+package query
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idTimestampString formats the embedded timestamp of an ID in the same format
+// as other timestamps within Bloblang.
+func idTimestampString(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+//------------------------------------------------------------------------------
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator generates ULIDs that are monotonically increasing within the
+// process, where ULIDs generated within the same millisecond increment the
+// random component of the previous ULID.
+type ulidGenerator struct {
+	mut     sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+var globalULIDGenerator = &ulidGenerator{}
+
+func (g *ulidGenerator) next(now time.Time) (string, error) {
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if ms <= g.lastMs {
+		// Either within the same millisecond or the clock has moved backwards,
+		// in both cases we increment the previous ULID.
+		ms = g.lastMs
+		overflowed := true
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				overflowed = false
+				break
+			}
+		}
+		if overflowed {
+			return "", errors.New("ulid random component exhausted for the current millisecond")
+		}
+	} else {
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", fmt.Errorf("failed to generate ulid random component: %w", err)
+		}
+		g.lastMs = ms
+	}
+	return encodeULID(ms, g.entropy), nil
+}
+
+func encodeULID(ms uint64, entropy [10]byte) string {
+	var dst [26]byte
+	for i := 9; i >= 0; i-- {
+		dst[i] = crockfordAlphabet[ms&31]
+		ms >>= 5
+	}
+	for chunk := 0; chunk < 2; chunk++ {
+		var v uint64
+		for _, b := range entropy[chunk*5 : chunk*5+5] {
+			v = v<<8 | uint64(b)
+		}
+		for i := 7; i >= 0; i-- {
+			dst[10+chunk*8+i] = crockfordAlphabet[v&31]
+			v >>= 5
+		}
+	}
+	return string(dst[:])
+}
+
+// decodeULID returns the timestamp and random component of a ULID string.
+func decodeULID(s string) (time.Time, []byte, error) {
+	if len(s) != 26 {
+		return time.Time{}, nil, fmt.Errorf("expected ulid to be 26 characters, got %v", len(s))
+	}
+	var values [26]uint64
+	for i, c := range strings.ToUpper(s) {
+		v := strings.IndexRune(crockfordAlphabet, c)
+		if v < 0 {
+			return time.Time{}, nil, fmt.Errorf("invalid ulid character: %q", c)
+		}
+		values[i] = uint64(v)
+	}
+	if values[0] > 7 {
+		return time.Time{}, nil, errors.New("ulid timestamp overflows 48 bits")
+	}
+
+	var ms uint64
+	for _, v := range values[:10] {
+		ms = ms<<5 | v
+	}
+
+	entropy := make([]byte, 0, 10)
+	for chunk := 0; chunk < 2; chunk++ {
+		var v uint64
+		for _, cv := range values[10+chunk*8 : 18+chunk*8] {
+			v = v<<5 | cv
+		}
+		for i := 4; i >= 0; i-- {
+			entropy = append(entropy, byte(v>>(uint(i)*8)))
+		}
+	}
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)), entropy, nil
+}
+
+//------------------------------------------------------------------------------
+
+// snowflakeEpochMs is the epoch of snowflake IDs in milliseconds, which is the
+// same epoch as Twitter snowflake IDs.
+const snowflakeEpochMs int64 = 1288834974657
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNodeID    = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeGenerator generates snowflake IDs for a single node ID.
+type snowflakeGenerator struct {
+	mut      sync.Mutex
+	nodeID   int64
+	lastMs   int64
+	sequence int64
+}
+
+var (
+	snowflakeGeneratorsMut sync.Mutex
+	snowflakeGenerators    = map[int64]*snowflakeGenerator{}
+)
+
+// getSnowflakeGenerator returns the generator of a node ID, which is shared
+// across all mappings of the process in order for IDs to remain unique.
+func getSnowflakeGenerator(nodeID int64) (*snowflakeGenerator, error) {
+	if nodeID < 0 || nodeID > snowflakeMaxNodeID {
+		return nil, fmt.Errorf("node id must be between 0 and %v, got %v", snowflakeMaxNodeID, nodeID)
+	}
+
+	snowflakeGeneratorsMut.Lock()
+	defer snowflakeGeneratorsMut.Unlock()
+
+	g, exists := snowflakeGenerators[nodeID]
+	if !exists {
+		g = &snowflakeGenerator{nodeID: nodeID}
+		snowflakeGenerators[nodeID] = g
+	}
+	return g, nil
+}
+
+func (g *snowflakeGenerator) next(now time.Time) int64 {
+	ms := now.UnixNano()/int64(time.Millisecond) - snowflakeEpochMs
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		if g.sequence = (g.sequence + 1) & snowflakeMaxSequence; g.sequence == 0 {
+			// The sequence of the current millisecond is exhausted, so we
+			// borrow the next millisecond rather than block.
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+	return ms<<(snowflakeNodeBits+snowflakeSequenceBits) | g.nodeID<<snowflakeSequenceBits | g.sequence
+}
+
+// decodeSnowflakeID returns the timestamp, node ID and sequence of a snowflake
+// ID.
+func decodeSnowflakeID(id int64) (t time.Time, nodeID, sequence int64) {
+	ms := id>>(snowflakeNodeBits+snowflakeSequenceBits) + snowflakeEpochMs
+	nodeID = (id >> snowflakeSequenceBits) & snowflakeMaxNodeID
+	sequence = id & snowflakeMaxSequence
+	return time.Unix(0, ms*int64(time.Millisecond)), nodeID, sequence
+}
@@ -0,0 +1,387 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// aggregationKey converts a value emitted by a key query into a string that
+// can be used for grouping or matching elements.
+func aggregationKey(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case int64, uint64, float64, json.Number, bool:
+		return IToString(t), nil
+	}
+	return "", NewTypeError(v, ValueString, ValueNumber, ValueBool)
+}
+
+// compareAggregationValues returns whether a value is less than another, where
+// both values must either be numbers or strings.
+func compareAggregationValues(left, right interface{}) (bool, error) {
+	switch left.(type) {
+	case float64, int, int64, uint64, json.Number:
+		lhs, err := IGetNumber(left)
+		if err != nil {
+			return false, err
+		}
+		rhs, err := IGetNumber(right)
+		if err != nil {
+			return false, err
+		}
+		return lhs < rhs, nil
+	case string, []byte:
+		lhs, err := IGetString(left)
+		if err != nil {
+			return false, err
+		}
+		rhs, err := IGetString(right)
+		if err != nil {
+			return false, err
+		}
+		return lhs < rhs, nil
+	}
+	return false, NewTypeError(left, ValueNumber, ValueString)
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"group_by_key", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Groups the elements of an array into an object of arrays, keyed by the value emitted by a query applied to each element, which can be a string, number or boolean. The order of elements is preserved within each group.",
+		NewExampleSpec("",
+			`root = this.events.group_by_key(ev -> ev.type)`,
+			`{"events":[{"type":"click","id":1},{"type":"view","id":2},{"type":"click","id":3}]}`,
+			`{"click":[{"id":1,"type":"click"},{"id":3,"type":"click"}],"view":[{"id":2,"type":"view"}]}`,
+		),
+		NewExampleSpec("Groups can be aggregated further with the method [`map_each`](#map_each).",
+			`root = this.events.group_by_key(ev -> ev.type).map_each(group -> group.value.length())`,
+			`{"events":[{"type":"click","id":1},{"type":"view","id":2},{"type":"click","id":3}]}`,
+			`{"click":2,"view":1}`,
+		),
+	).Param(ParamQuery("query", "A query to apply to each element that yields the key of its group.", false)),
+	func(args *ParsedParams) (simpleMethod, error) {
+		keyFn, err := args.FieldQuery("query")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			groups := map[string]interface{}{}
+			for i, ele := range arr {
+				keyV, err := keyFn.Exec(ctx.WithValue(ele))
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				key, err := aggregationKey(keyV)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, ErrFrom(err, keyFn))
+				}
+				group, _ := groups[key].([]interface{})
+				groups[key] = append(group, ele)
+			}
+			return groups, nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"sum_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Sums the numerical values emitted by a query applied to each element of an array.",
+		NewExampleSpec("",
+			`root.total = this.items.sum_by(item -> item.price * item.quantity)`,
+			`{"items":[{"price":5,"quantity":2},{"price":1.5,"quantity":4}]}`,
+			`{"total":16}`,
+		),
+	).Param(ParamQuery("query", "A query to apply to each element that yields a number.", false)),
+	func(args *ParsedParams) (simpleMethod, error) {
+		mapFn, err := args.FieldQuery("query")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			var total float64
+			for i, ele := range arr {
+				nV, err := mapFn.Exec(ctx.WithValue(ele))
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				n, err := IGetNumber(nV)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, ErrFrom(err, mapFn))
+				}
+				total += n
+			}
+			return total, nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"max_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the element of an array with the largest value emitted by a query applied to each element. The values must either all be numbers or all be strings, and when multiple elements share the largest value the first is returned. An error is returned if the array is empty.",
+		NewExampleSpec("",
+			`root.latest = this.versions.max_by(v -> v.released_at)`,
+			`{"versions":[{"id":"v1","released_at":"2021-01-04"},{"id":"v3","released_at":"2021-06-21"},{"id":"v2","released_at":"2021-03-15"}]}`,
+			`{"latest":{"id":"v3","released_at":"2021-06-21"}}`,
+		),
+	).Param(ParamQuery("query", "A query to apply to each element that yields a number or string used for comparisons.", false)),
+	elementByMethod(false),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"min_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the element of an array with the smallest value emitted by a query applied to each element. The values must either all be numbers or all be strings, and when multiple elements share the smallest value the first is returned. An error is returned if the array is empty.",
+		NewExampleSpec("",
+			`root.cheapest = this.items.min_by(item -> item.price).name`,
+			`{"items":[{"name":"foo","price":5},{"name":"bar","price":1.5},{"name":"baz","price":3}]}`,
+			`{"cheapest":"bar"}`,
+		),
+	).Param(ParamQuery("query", "A query to apply to each element that yields a number or string used for comparisons.", false)),
+	elementByMethod(true),
+)
+
+// elementByMethod returns a method constructor that selects the element of an
+// array with either the smallest or largest value emitted by a query.
+func elementByMethod(smallest bool) simpleMethodConstructor {
+	return func(args *ParsedParams) (simpleMethod, error) {
+		mapFn, err := args.FieldQuery("query")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			if len(arr) == 0 {
+				return nil, errors.New("the array is empty")
+			}
+			var result, resultValue interface{}
+			for i, ele := range arr {
+				eleValue, err := mapFn.Exec(ctx.WithValue(ele))
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				if i == 0 {
+					result, resultValue = ele, eleValue
+					continue
+				}
+				var replace bool
+				if smallest {
+					replace, err = compareAggregationValues(eleValue, resultValue)
+				} else {
+					replace, err = compareAggregationValues(resultValue, eleValue)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, ErrFrom(err, mapFn))
+				}
+				if replace {
+					result, resultValue = ele, eleValue
+				}
+			}
+			return result, nil
+		}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"pivot", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Converts an array into an object, where each element provides a key and a value emitted by two queries applied to it. Keys can be strings, numbers or booleans, and when multiple elements emit the same key the value of the last element is kept.",
+		NewExampleSpec("",
+			`root.metrics = this.samples.pivot(s -> s.metric, s -> s.value)`,
+			`{"samples":[{"metric":"cpu","value":0.7},{"metric":"mem","value":1024},{"metric":"disk","value":0.2}]}`,
+			`{"metrics":{"cpu":0.7,"disk":0.2,"mem":1024}}`,
+		),
+		NewExampleSpec("Combined with the method [`group_by_key`](#group_by_key) a pivot table can be built.",
+			`root = this.samples.group_by_key(s -> s.host).map_each(group -> group.value.pivot(s -> s.metric, s -> s.value))`,
+			`{"samples":[{"host":"a","metric":"cpu","value":0.7},{"host":"b","metric":"cpu","value":0.1},{"host":"a","metric":"mem","value":1024}]}`,
+			`{"a":{"cpu":0.7,"mem":1024},"b":{"cpu":0.1}}`,
+		),
+	).
+		Param(ParamQuery("key", "A query to apply to each element that yields its key.", false)).
+		Param(ParamQuery("value", "A query to apply to each element that yields its value.", false)),
+	func(args *ParsedParams) (simpleMethod, error) {
+		keyFn, err := args.FieldQuery("key")
+		if err != nil {
+			return nil, err
+		}
+		valueFn, err := args.FieldQuery("value")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			result := make(map[string]interface{}, len(arr))
+			for i, ele := range arr {
+				eleCtx := ctx.WithValue(ele)
+				keyV, err := keyFn.Exec(eleCtx)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				key, err := aggregationKey(keyV)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, ErrFrom(err, keyFn))
+				}
+				value, err := valueFn.Exec(eleCtx)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				result[key] = value
+			}
+			return result, nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"join_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Performs a hash join between an array of objects and a second array of objects, where elements are matched by the values emitted by key queries applied to each of them. For each pair of matching elements the result contains a copy of the element of the target array with the fields of the element of the `right` array added to it, overwriting fields of the same name. Elements with a `null` key never match.",
+		NewExampleSpec("",
+			`root.orders = this.orders.join_by(this.users, o -> o.user, u -> u.user_id)`,
+			`{"orders":[{"id":1,"user":"a"},{"id":2,"user":"b"},{"id":3,"user":"c"}],"users":[{"user_id":"a","name":"ash"},{"user_id":"b","name":"bo"}]}`,
+			`{"orders":[{"id":1,"name":"ash","user":"a","user_id":"a"},{"id":2,"name":"bo","user":"b","user_id":"b"}]}`,
+		),
+		NewExampleSpec("With the mode `left` the elements of the target array without a match are also kept.",
+			`root.orders = this.orders.join_by(right: this.users, key: o -> o.user, right_key: u -> u.user_id, mode: "left")`,
+			`{"orders":[{"id":1,"user":"a"},{"id":3,"user":"c"}],"users":[{"user_id":"a","name":"ash"}]}`,
+			`{"orders":[{"id":1,"name":"ash","user":"a","user_id":"a"},{"id":3,"user":"c"}]}`,
+		),
+	).
+		Param(ParamArray("right", "An array of objects to join with.")).
+		Param(ParamQuery("key", "A query to apply to each element of the target array that yields a key to match on.", false)).
+		Param(ParamQuery("right_key", "An optional query to apply to each element of the `right` array that yields a key to match on, otherwise the `key` query is used.", false).Optional()).
+		Param(ParamString("mode", "The type of join, either `inner`, where only elements with a match are kept, or `left`, where elements of the target array without a match are also kept.").Default("inner")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		right, err := args.FieldArray("right")
+		if err != nil {
+			return nil, err
+		}
+		keyFn, err := args.FieldQuery("key")
+		if err != nil {
+			return nil, err
+		}
+		rightKeyFn, err := args.FieldOptionalQuery("right_key")
+		if err != nil {
+			return nil, err
+		}
+		if rightKeyFn == nil {
+			rightKeyFn = keyFn
+		}
+		mode, err := args.FieldString("mode")
+		if err != nil {
+			return nil, err
+		}
+		var keepUnmatched bool
+		switch mode {
+		case "inner":
+		case "left":
+			keepUnmatched = true
+		default:
+			return nil, fmt.Errorf("unrecognised join mode: %v", mode)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			left, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+
+			index := map[string][]map[string]interface{}{}
+			for i, ele := range right {
+				obj, ok := ele.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("right element %v: %w", i, NewTypeError(ele, ValueObject))
+				}
+				keyV, err := rightKeyFn.Exec(ctx.WithValue(ele))
+				if err != nil {
+					return nil, fmt.Errorf("right element %v: %w", i, err)
+				}
+				if keyV == nil {
+					continue
+				}
+				key, err := aggregationKey(keyV)
+				if err != nil {
+					return nil, fmt.Errorf("right element %v: %w", i, ErrFrom(err, rightKeyFn))
+				}
+				index[key] = append(index[key], obj)
+			}
+
+			result := make([]interface{}, 0, len(left))
+			for i, ele := range left {
+				obj, ok := ele.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("element %v: %w", i, NewTypeError(ele, ValueObject))
+				}
+				keyV, err := keyFn.Exec(ctx.WithValue(ele))
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				var matches []map[string]interface{}
+				if keyV != nil {
+					key, err := aggregationKey(keyV)
+					if err != nil {
+						return nil, fmt.Errorf("element %v: %w", i, ErrFrom(err, keyFn))
+					}
+					matches = index[key]
+				}
+				if len(matches) == 0 {
+					if keepUnmatched {
+						result = append(result, obj)
+					}
+					continue
+				}
+				for _, match := range matches {
+					joined := make(map[string]interface{}, len(obj)+len(match))
+					for k, v := range obj {
+						joined[k] = v
+					}
+					for k, v := range match {
+						joined[k] = v
+					}
+					result = append(result, joined)
+				}
+			}
+			return result, nil
+		}, nil
+	},
+)
@@ -0,0 +1,243 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregationMethods(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		target  interface{}
+		args    []interface{}
+		exp     interface{}
+		err     string
+		initErr string
+	}{
+		{
+			name:   "group by key",
+			method: "group_by_key",
+			target: []interface{}{
+				map[string]interface{}{"type": "a", "id": int64(1)},
+				map[string]interface{}{"type": int64(5), "id": int64(2)},
+				map[string]interface{}{"type": "a", "id": int64(3)},
+				map[string]interface{}{"type": true, "id": int64(4)},
+			},
+			args: []interface{}{NewFieldFunction("type")},
+			exp: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"type": "a", "id": int64(1)},
+					map[string]interface{}{"type": "a", "id": int64(3)},
+				},
+				"5": []interface{}{
+					map[string]interface{}{"type": int64(5), "id": int64(2)},
+				},
+				"true": []interface{}{
+					map[string]interface{}{"type": true, "id": int64(4)},
+				},
+			},
+		},
+		{
+			name:   "group by key bad key",
+			method: "group_by_key",
+			target: []interface{}{
+				map[string]interface{}{"type": "a"},
+				map[string]interface{}{"id": int64(2)},
+			},
+			args: []interface{}{NewFieldFunction("type")},
+			err:  "element 1: expected string, number or bool value, got null",
+		},
+		{
+			name:   "group by key not array",
+			method: "group_by_key",
+			target: map[string]interface{}{},
+			args:   []interface{}{NewFieldFunction("type")},
+			err:    "expected array value, got object",
+		},
+		{
+			name:   "sum by",
+			method: "sum_by",
+			target: []interface{}{
+				map[string]interface{}{"v": int64(3)},
+				map[string]interface{}{"v": 1.5},
+				map[string]interface{}{"v": int64(-1)},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			exp:  3.5,
+		},
+		{
+			name:   "sum by empty",
+			method: "sum_by",
+			target: []interface{}{},
+			args:   []interface{}{NewFieldFunction("v")},
+			exp:    float64(0),
+		},
+		{
+			name:   "sum by not a number",
+			method: "sum_by",
+			target: []interface{}{
+				map[string]interface{}{"v": int64(3)},
+				map[string]interface{}{"v": "nope"},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			err:  "element 1: expected number value, got string",
+		},
+		{
+			name:   "min by numbers",
+			method: "min_by",
+			target: []interface{}{
+				map[string]interface{}{"v": int64(3), "id": "a"},
+				map[string]interface{}{"v": 1.5, "id": "b"},
+				map[string]interface{}{"v": 1.5, "id": "c"},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			exp:  map[string]interface{}{"v": 1.5, "id": "b"},
+		},
+		{
+			name:   "max by numbers",
+			method: "max_by",
+			target: []interface{}{
+				map[string]interface{}{"v": int64(3), "id": "a"},
+				map[string]interface{}{"v": int64(7), "id": "b"},
+				map[string]interface{}{"v": int64(7), "id": "c"},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			exp:  map[string]interface{}{"v": int64(7), "id": "b"},
+		},
+		{
+			name:   "max by strings",
+			method: "max_by",
+			target: []interface{}{
+				map[string]interface{}{"v": "bar"},
+				map[string]interface{}{"v": "foo"},
+				map[string]interface{}{"v": "baz"},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			exp:  map[string]interface{}{"v": "foo"},
+		},
+		{
+			name:   "min by mixed types",
+			method: "min_by",
+			target: []interface{}{
+				map[string]interface{}{"v": "bar"},
+				map[string]interface{}{"v": int64(5)},
+			},
+			args: []interface{}{NewFieldFunction("v")},
+			err:  "element 1: expected number value, got string",
+		},
+		{
+			name:   "min by empty",
+			method: "min_by",
+			target: []interface{}{},
+			args:   []interface{}{NewFieldFunction("v")},
+			err:    "the array is empty",
+		},
+		{
+			name:   "pivot",
+			method: "pivot",
+			target: []interface{}{
+				map[string]interface{}{"k": "a", "v": int64(1)},
+				map[string]interface{}{"k": "b", "v": int64(2)},
+				map[string]interface{}{"k": "a", "v": int64(3)},
+			},
+			args: []interface{}{NewFieldFunction("k"), NewFieldFunction("v")},
+			exp:  map[string]interface{}{"a": int64(3), "b": int64(2)},
+		},
+		{
+			name:   "join by inner",
+			method: "join_by",
+			target: []interface{}{
+				map[string]interface{}{"id": int64(1), "user": "a"},
+				map[string]interface{}{"id": int64(2), "user": "b"},
+				map[string]interface{}{"id": int64(3)},
+			},
+			args: []interface{}{
+				[]interface{}{
+					map[string]interface{}{"user": "a", "name": "first"},
+					map[string]interface{}{"user": "a", "name": "second"},
+					map[string]interface{}{"name": "nobody"},
+					map[string]interface{}{"user": "c", "name": "third"},
+				},
+				NewFieldFunction("user"),
+			},
+			exp: []interface{}{
+				map[string]interface{}{"id": int64(1), "user": "a", "name": "first"},
+				map[string]interface{}{"id": int64(1), "user": "a", "name": "second"},
+			},
+		},
+		{
+			name:   "join by left with right key",
+			method: "join_by",
+			target: []interface{}{
+				map[string]interface{}{"id": int64(1), "user": int64(10)},
+				map[string]interface{}{"id": int64(2), "user": int64(20)},
+			},
+			args: []interface{}{
+				[]interface{}{
+					map[string]interface{}{"user_id": int64(20), "id": "overwritten"},
+				},
+				NewFieldFunction("user"),
+				NewFieldFunction("user_id"),
+				"left",
+			},
+			exp: []interface{}{
+				map[string]interface{}{"id": int64(1), "user": int64(10)},
+				map[string]interface{}{"id": "overwritten", "user": int64(20), "user_id": int64(20)},
+			},
+		},
+		{
+			name:   "join by bad right element",
+			method: "join_by",
+			target: []interface{}{},
+			args: []interface{}{
+				[]interface{}{"nope"},
+				NewFieldFunction("user"),
+			},
+			err: "right element 0: expected object value, got string",
+		},
+		{
+			name:   "join by bad mode",
+			method: "join_by",
+			target: []interface{}{},
+			args: []interface{}{
+				[]interface{}{},
+				NewFieldFunction("user"),
+				NewFieldFunction("user"),
+				"outer",
+			},
+			initErr: "unrecognised join mode: outer",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			targetClone := IClone(test.target)
+
+			fn, err := InitMethodHelper(test.method, NewLiteralFunction("", targetClone), test.args...)
+			if test.initErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.initErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Maps:     map[string]Function{},
+				Index:    0,
+				MsgBatch: nil,
+			})
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.exp, res)
+			}
+			assert.Equal(t, test.target, targetClone)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"coerce", "",
+	).InCategory(
+		MethodCategoryCoercion,
+		`Coerces the fields of an object target to a schema, where each key of the schema is the name of a field and each value is an object describing that field with the following optional keys:
+
+- `+"`type`"+`: one of `+"`any`, `string`, `number`, `integer`, `bool`, `timestamp`, `object` or `array`"+`, defaults to `+"`any`"+`.
+- `+"`required`"+`: whether the field must be present and not null, defaults to `+"`false`"+`.
+- `+"`default`"+`: a value to use when the field is absent or null.
+- `+"`enum`"+`: an array of values that the field must match after it has been coerced.
+- `+"`fields`"+`: a schema of the fields of an `+"`object`"+` field.
+- `+"`items`"+`: a description of the elements of an `+"`array`"+` field.
+
+Values are converted to the declared type where possible, e.g. a string `+"`\"10\"`"+` becomes the number `+"`10`"+` and a unix timestamp becomes an RFC 3339 string, and fields of the target that are not within the schema are kept as they are. If any of the fields fail then an error is returned that lists every failed field by its path, which can be caught with the method [`+"`catch`"+`](#catch), or when the mapping is executed by a processor, read within error handling with the function [`+"`error`"+`](/docs/guides/bloblang/functions#error).`,
+		NewExampleSpec("",
+			`root = this.coerce({
+  "id": {"type": "string", "required": true},
+  "age": {"type": "integer"},
+  "active": {"type": "bool", "default": true},
+  "status": {"type": "string", "enum": ["pending", "shipped"], "default": "pending"}
+})`,
+			`{"id":123,"age":"42","extra":"kept"}`,
+			`{"active":true,"age":42,"extra":"kept","id":"123","status":"pending"}`,
+			`{"id":"abc","age":"old","status":"lost"}`,
+			`Error("failed assignment (line 1): field `+"`this`"+`: failed to coerce fields: age: expected integer value, got string ("old"); status: value "lost" is not one of the allowed values: ["pending","shipped"]")`,
+		),
+		NewExampleSpec("Nested objects and the elements of arrays can also be described.",
+			`root = this.coerce({
+  "user": {"type": "object", "required": true, "fields": {
+    "name": {"type": "string", "required": true},
+    "created_at": {"type": "timestamp"}
+  }},
+  "scores": {"type": "array", "items": {"type": "number"}}
+}).catch({"invalid": true})`,
+			`{"user":{"name":"foo","created_at":1628000000},"scores":["1.5",2]}`,
+			`{"scores":[1.5,2],"user":{"created_at":"2021-08-03T14:13:20Z","name":"foo"}}`,
+			`{"user":{"created_at":1628000000},"scores":["1.5",2]}`,
+			`{"invalid":true}`,
+		),
+	).
+		Beta().
+		Param(ParamObject("schema", "An object describing the fields of the target.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		schemaV, err := args.Field("schema")
+		if err != nil {
+			return nil, err
+		}
+		schemaObj, ok := schemaV.(map[string]interface{})
+		if !ok {
+			return nil, NewTypeError(schemaV, ValueObject)
+		}
+		schema, err := parseCoerceSchema("", schemaObj)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueObject)
+			}
+			var errs coerceErrors
+			res := schema.coerceObject("", obj, &errs)
+			if len(errs) > 0 {
+				return nil, errs
+			}
+			return res, nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+// coerceErrors lists the failed fields of a coercion in the order that they
+// were checked, where each entry is prefixed with the path of the field.
+type coerceErrors []string
+
+func (c coerceErrors) Error() string {
+	return "failed to coerce fields: " + strings.Join(c, "; ")
+}
+
+func (c *coerceErrors) add(path string, err error) {
+	*c = append(*c, path+": "+err.Error())
+}
+
+type coerceSchema struct {
+	keys   []string
+	fields map[string]*coerceField
+}
+
+type coerceField struct {
+	valueType    string
+	required     bool
+	defaultValue interface{}
+	hasDefault   bool
+	enum         []interface{}
+	fields       *coerceSchema
+	items        *coerceField
+}
+
+func coercePath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func parseCoerceSchema(path string, obj map[string]interface{}) (*coerceSchema, error) {
+	s := &coerceSchema{
+		fields: make(map[string]*coerceField, len(obj)),
+	}
+	for k, v := range obj {
+		fieldPath := coercePath(path, k)
+		fieldObj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %v: %w", fieldPath, NewTypeError(v, ValueObject))
+		}
+		f, err := parseCoerceField(fieldPath, fieldObj)
+		if err != nil {
+			return nil, err
+		}
+		s.keys = append(s.keys, k)
+		s.fields[k] = f
+	}
+	sort.Strings(s.keys)
+	return s, nil
+}
+
+func parseCoerceField(path string, obj map[string]interface{}) (*coerceField, error) {
+	f := &coerceField{valueType: "any"}
+	for k, v := range obj {
+		var err error
+		switch k {
+		case "type":
+			if f.valueType, err = IGetString(v); err == nil {
+				switch f.valueType {
+				case "any", "string", "number", "integer", "bool", "timestamp", "object", "array":
+				default:
+					err = fmt.Errorf("unrecognised type: %v", f.valueType)
+				}
+			}
+		case "required":
+			f.required, err = IGetBool(v)
+		case "default":
+			f.defaultValue, f.hasDefault = v, true
+		case "enum":
+			var ok bool
+			if f.enum, ok = v.([]interface{}); !ok {
+				err = NewTypeError(v, ValueArray)
+			}
+		case "fields":
+			fieldsObj, ok := v.(map[string]interface{})
+			if !ok {
+				err = NewTypeError(v, ValueObject)
+				break
+			}
+			if f.fields, err = parseCoerceSchema(path, fieldsObj); err != nil {
+				return nil, err
+			}
+		case "items":
+			itemsObj, ok := v.(map[string]interface{})
+			if !ok {
+				err = NewTypeError(v, ValueObject)
+				break
+			}
+			if f.items, err = parseCoerceField(path+".*", itemsObj); err != nil {
+				return nil, err
+			}
+		default:
+			err = errors.New("unrecognised key")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v: %w", path, k, err)
+		}
+	}
+	if f.fields != nil && f.valueType != "object" {
+		return nil, fmt.Errorf("field %v: fields can only be specified for the type object", path)
+	}
+	if f.items != nil && f.valueType != "array" {
+		return nil, fmt.Errorf("field %v: items can only be specified for the type array", path)
+	}
+	return f, nil
+}
+
+func (s *coerceSchema) coerceObject(path string, obj map[string]interface{}, errs *coerceErrors) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		res[k] = v
+	}
+	for _, k := range s.keys {
+		v, exists := res[k]
+		if v, exists = s.fields[k].coerce(coercePath(path, k), v, exists, errs); exists {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// coerce returns the coerced form of a value and whether the value should be
+// set, errors are added to the provided list rather than returned in order
+// that all fields are checked.
+func (f *coerceField) coerce(path string, v interface{}, exists bool, errs *coerceErrors) (interface{}, bool) {
+	if !exists || v == nil {
+		if f.hasDefault {
+			return IClone(f.defaultValue), true
+		}
+		if f.required {
+			errs.add(path, errors.New("field is required"))
+		}
+		return v, exists
+	}
+
+	v, err := coerceValue(f.valueType, v)
+	if err != nil {
+		errs.add(path, err)
+		return v, true
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if f.fields != nil {
+			v = f.fields.coerceObject(path, t, errs)
+		}
+	case []interface{}:
+		if f.items != nil {
+			arr := make([]interface{}, len(t))
+			for i, ele := range t {
+				arr[i], _ = f.items.coerce(path+"."+strconv.Itoa(i), ele, true, errs)
+			}
+			v = arr
+		}
+	}
+
+	if len(f.enum) > 0 && !coerceEnumContains(f.enum, v) {
+		errs.add(path, fmt.Errorf("value %v is not one of the allowed values: %v", coerceEnumString(v), IToString(f.enum)))
+	}
+	return v, true
+}
+
+func coerceValue(valueType string, v interface{}) (interface{}, error) {
+	switch valueType {
+	case "string":
+		switch t := v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, NewTypeError(v, ValueString)
+		case string:
+			return t, nil
+		}
+		return IToString(v), nil
+	case "number":
+		if b, isBool := v.(bool); isBool {
+			return nil, NewTypeError(b, ValueNumber)
+		}
+		n, err := IToNumber(v)
+		if err != nil {
+			return nil, NewTypeError(v, ValueNumber)
+		}
+		return n, nil
+	case "integer":
+		switch t := v.(type) {
+		case string:
+			if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+				return i, nil
+			}
+		case []byte:
+			if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+				return i, nil
+			}
+		case float64:
+			if t != math.Trunc(t) {
+				return nil, NewTypeError(v, ValueInt)
+			}
+		case bool:
+			return nil, NewTypeError(v, ValueInt)
+		}
+		i, err := IGetInt(v)
+		if err != nil {
+			return nil, NewTypeError(v, ValueInt)
+		}
+		return i, nil
+	case "bool":
+		b, err := IToBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "timestamp":
+		ts, err := IGetTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		return ts.UTC().Format(time.RFC3339Nano), nil
+	case "object":
+		if _, ok := v.(map[string]interface{}); !ok {
+			return nil, NewTypeError(v, ValueObject)
+		}
+	case "array":
+		if _, ok := v.([]interface{}); !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+	}
+	return v, nil
+}
+
+func coerceEnumContains(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if en, err := IGetNumber(e); err == nil {
+			if vn, err := IGetNumber(v); err == nil && en == vn {
+				return true
+			}
+			continue
+		}
+		if IToString(e) == IToString(v) && ITypeOf(e) == ITypeOf(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func coerceEnumString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return IToString(v)
+}
@@ -0,0 +1,176 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoerceMethod(t *testing.T) {
+	testCases := []struct {
+		name    string
+		target  interface{}
+		schema  interface{}
+		exp     interface{}
+		err     string
+		initErr string
+	}{
+		{
+			name:   "scalar conversions",
+			target: map[string]interface{}{"a": "10", "b": "1.5", "c": "true", "d": float64(5), "e": int64(1628000000)},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "integer"},
+				"b": map[string]interface{}{"type": "number"},
+				"c": map[string]interface{}{"type": "bool"},
+				"d": map[string]interface{}{"type": "string"},
+				"e": map[string]interface{}{"type": "timestamp"},
+			},
+			exp: map[string]interface{}{"a": int64(10), "b": 1.5, "c": true, "d": "5", "e": "2021-08-03T14:13:20Z"},
+		},
+		{
+			name:   "unknown fields kept",
+			target: map[string]interface{}{"a": "foo", "b": "bar"},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "string"},
+			},
+			exp: map[string]interface{}{"a": "foo", "b": "bar"},
+		},
+		{
+			name:   "defaults",
+			target: map[string]interface{}{"a": nil},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "string", "default": "foo"},
+				"b": map[string]interface{}{"type": "array", "required": true, "default": []interface{}{}},
+				"c": map[string]interface{}{"type": "string"},
+			},
+			exp: map[string]interface{}{"a": "foo", "b": []interface{}{}},
+		},
+		{
+			name:   "enum numbers",
+			target: map[string]interface{}{"a": "2"},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "integer", "enum": []interface{}{float64(1), float64(2)}},
+			},
+			exp: map[string]interface{}{"a": int64(2)},
+		},
+		{
+			name: "nested fields and items",
+			target: map[string]interface{}{
+				"a": map[string]interface{}{"b": "5"},
+				"c": []interface{}{"1", nil, float64(3)},
+			},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "object", "fields": map[string]interface{}{
+					"b": map[string]interface{}{"type": "integer"},
+				}},
+				"c": map[string]interface{}{"type": "array", "items": map[string]interface{}{
+					"type": "number", "default": float64(0),
+				}},
+			},
+			exp: map[string]interface{}{
+				"a": map[string]interface{}{"b": int64(5)},
+				"c": []interface{}{float64(1), float64(0), float64(3)},
+			},
+		},
+		{
+			name: "all errors listed",
+			target: map[string]interface{}{
+				"a": map[string]interface{}{"b": "nope"},
+				"c": []interface{}{"1", "two"},
+				"d": "lost",
+				"f": 1.5,
+				"g": true,
+			},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "object", "fields": map[string]interface{}{
+					"b": map[string]interface{}{"type": "bool"},
+				}},
+				"c": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "number"}},
+				"d": map[string]interface{}{"enum": []interface{}{"pending", "shipped"}},
+				"e": map[string]interface{}{"required": true},
+				"f": map[string]interface{}{"type": "integer"},
+				"g": map[string]interface{}{"type": "number"},
+			},
+			err: `failed to coerce fields: a.b: expected bool value, got string ("nope"); c.1: expected number value, got string ("two"); d: value "lost" is not one of the allowed values: ["pending","shipped"]; e: field is required; f: expected integer value, got number (1.5); g: expected number value, got bool (true)`,
+		},
+		{
+			name:   "wrong type for object",
+			target: map[string]interface{}{"a": "foo"},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "object"},
+			},
+			err: `failed to coerce fields: a: expected object value, got string ("foo")`,
+		},
+		{
+			name:   "target not an object",
+			target: "foo",
+			schema: map[string]interface{}{},
+			err:    "expected object value, got string",
+		},
+		{
+			name:   "bad type",
+			target: map[string]interface{}{},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"type": "nope"},
+			},
+			initErr: "field a: type: unrecognised type: nope",
+		},
+		{
+			name:   "bad key",
+			target: map[string]interface{}{},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"nope": true},
+			},
+			initErr: "field a: nope: unrecognised key",
+		},
+		{
+			name:   "bad field description",
+			target: map[string]interface{}{},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"fields": map[string]interface{}{
+					"b": "string",
+				}},
+			},
+			initErr: `field a.b: expected object value, got string ("string")`,
+		},
+		{
+			name:   "fields without object type",
+			target: map[string]interface{}{},
+			schema: map[string]interface{}{
+				"a": map[string]interface{}{"fields": map[string]interface{}{}},
+			},
+			initErr: "field a: fields can only be specified for the type object",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			targetClone := IClone(test.target)
+			schemaClone := IClone(test.schema)
+
+			fn, err := InitMethodHelper("coerce", NewLiteralFunction("", targetClone), schemaClone)
+			if test.initErr != "" {
+				require.EqualError(t, err, test.initErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Maps:     map[string]Function{},
+				Index:    0,
+				MsgBatch: nil,
+			})
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.exp, res)
+			}
+			assert.Equal(t, test.target, targetClone)
+			assert.Equal(t, test.schema, schemaClone)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package query
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"encrypt_aes_gcm", "",
+	).InCategory(
+		MethodCategoryEncoding,
+		"Encrypts and authenticates a string or byte array target with AES in Galois/Counter Mode and returns the result as a byte array. A random nonce is generated for each encryption and is prepended to the result, which therefore contains everything except the key required by [`decrypt_aes_gcm`](#decrypt_aes_gcm). The key must be 16, 24 or 32 bytes long in order to select AES-128, AES-192 or AES-256, and can be obtained from an environment variable, a file or a secret resource with the functions [`env`](/docs/guides/bloblang/functions#env), [`file`](/docs/guides/bloblang/functions#file) and [`secret`](/docs/guides/bloblang/functions#secret).",
+		NewExampleSpec("",
+			`root.card = this.card.encrypt_aes_gcm(secret("pii_key")).encode("base64")`,
+		),
+		NewExampleSpec("",
+			`let key = "2b7e151628aed2a6abf7158809cf4f3c".decode("hex")
+root.value = this.value.encrypt_aes_gcm($key).decrypt_aes_gcm($key).string()`,
+			`{"value":"hello world!"}`,
+			`{"value":"hello world!"}`,
+		),
+	).
+		Param(ParamString("key", "A key to encrypt with.")).
+		Param(ParamString("associated_data", "Optional data that is authenticated but not encrypted, which must also be provided in order to decrypt the result.").Optional()),
+	func(args *ParsedParams) (simpleMethod, error) {
+		aead, additional, err := aesGCMFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			b, err := IGetBytes(v)
+			if err != nil {
+				return nil, err
+			}
+			nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(b)+aead.Overhead())
+			if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+				return nil, fmt.Errorf("failed to generate nonce: %w", err)
+			}
+			return aead.Seal(nonce, nonce, b, additional), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"decrypt_aes_gcm", "",
+	).InCategory(
+		MethodCategoryEncoding,
+		"Decrypts and authenticates a string or byte array target that was encrypted with the method [`encrypt_aes_gcm`](#encrypt_aes_gcm), where the nonce is prepended to the encrypted data, and returns the result as a byte array. An error is returned if the key or associated data do not match those used for encryption, or if the data has been modified.",
+		NewExampleSpec("",
+			`root.card = this.card.decode("base64").decrypt_aes_gcm(secret("pii_key")).string()`,
+		),
+	).
+		Param(ParamString("key", "A key to decrypt with.")).
+		Param(ParamString("associated_data", "Optional data that was authenticated during encryption.").Optional()),
+	func(args *ParsedParams) (simpleMethod, error) {
+		aead, additional, err := aesGCMFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			b, err := IGetBytes(v)
+			if err != nil {
+				return nil, err
+			}
+			if len(b) < aead.NonceSize() {
+				return nil, errors.New("encrypted data is shorter than the nonce")
+			}
+			plaintext, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], additional)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt: %w", err)
+			}
+			return plaintext, nil
+		}, nil
+	},
+)
+
+func aesGCMFromArgs(args *ParsedParams) (cipher.AEAD, []byte, error) {
+	keyStr, err := args.FieldString("key")
+	if err != nil {
+		return nil, nil, err
+	}
+	additionalStr, err := args.FieldOptionalString("associated_data")
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher([]byte(keyStr))
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	var additional []byte
+	if additionalStr != nil {
+		additional = []byte(*additionalStr)
+	}
+	return aead, additional, nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"encrypt_aes_ff1", "",
+	).InCategory(
+		MethodCategoryEncoding,
+		"Encrypts a string with the FF1 mode of format-preserving encryption as specified by NIST SP 800-38G, and returns a string of the same length and format. The characters of the target that are within the alphabet are encrypted together and those that are not, such as separators, are kept at their positions. The alphabet characters must have at least a million possible combinations, e.g. at least six digits, and the result is deterministic for a given key and tweak, which makes it suitable for tokenizing values that must still be matched or validated downstream. The key must be 16, 24 or 32 bytes long in order to select AES-128, AES-192 or AES-256.",
+		NewExampleSpec("",
+			`let key = "2b7e151628aed2a6abf7158809cf4f3c".decode("hex")
+root.card = this.card.encrypt_aes_ff1($key)`,
+			`{"card":"4111-1111-1111-1111"}`,
+			`{"card":"3662-3112-3979-7070"}`,
+		),
+		NewExampleSpec("A custom alphabet can be used in order to encrypt other types of values, and a tweak can be used in order to produce different results for the same value in different contexts.",
+			`let key = "2b7e151628aed2a6abf7158809cf4f3c".decode("hex")
+let user = this.user.encrypt_aes_ff1(key: $key, tweak: "users", alphabet: "abcdefghijklmnopqrstuvwxyz")
+root.user = $user
+root.decrypted = $user.decrypt_aes_ff1(key: $key, tweak: "users", alphabet: "abcdefghijklmnopqrstuvwxyz")`,
+			`{"user":"alice.smith"}`,
+			`{"decrypted":"alice.smith","user":"mnxoc.qialr"}`,
+		),
+	).
+		Param(ParamString("key", "A key to encrypt with.")).
+		Param(ParamString("tweak", "An optional tweak, which must also be provided in order to decrypt the result.").Default("")).
+		Param(ParamString("alphabet", "The characters that are encrypted.").Default("0123456789")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		return ff1MethodFromArgs(args, true)
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"decrypt_aes_ff1", "",
+	).InCategory(
+		MethodCategoryEncoding,
+		"Decrypts a string that was encrypted with the method [`encrypt_aes_ff1`](#encrypt_aes_ff1), where the key, tweak and alphabet must match those used for encryption.",
+		NewExampleSpec("",
+			`let key = "2b7e151628aed2a6abf7158809cf4f3c".decode("hex")
+root.card = this.card.decrypt_aes_ff1($key)`,
+			`{"card":"3662-3112-3979-7070"}`,
+			`{"card":"4111-1111-1111-1111"}`,
+		),
+	).
+		Param(ParamString("key", "A key to decrypt with.")).
+		Param(ParamString("tweak", "An optional tweak that was used for encryption.").Default("")).
+		Param(ParamString("alphabet", "The characters that were encrypted.").Default("0123456789")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		return ff1MethodFromArgs(args, false)
+	},
+)
+
+func ff1MethodFromArgs(args *ParsedParams, encrypt bool) (simpleMethod, error) {
+	keyStr, err := args.FieldString("key")
+	if err != nil {
+		return nil, err
+	}
+	tweakStr, err := args.FieldString("tweak")
+	if err != nil {
+		return nil, err
+	}
+	alphabetStr, err := args.FieldString("alphabet")
+	if err != nil {
+		return nil, err
+	}
+
+	alphabet := []rune(alphabetStr)
+	alphabetIndex := make(map[rune]uint16, len(alphabet))
+	for i, r := range alphabet {
+		if _, exists := alphabetIndex[r]; exists {
+			return nil, fmt.Errorf("alphabet contains duplicate character: %q", r)
+		}
+		alphabetIndex[r] = uint16(i)
+	}
+
+	ff1, err := newFF1Cipher([]byte(keyStr), len(alphabet), []byte(tweakStr))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		s, err := IGetString(v)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(s)
+
+		var positions []int
+		var numerals []uint16
+		for i, r := range runes {
+			if n, exists := alphabetIndex[r]; exists {
+				positions = append(positions, i)
+				numerals = append(numerals, n)
+			}
+		}
+
+		if encrypt {
+			numerals, err = ff1.encrypt(numerals)
+		} else {
+			numerals, err = ff1.decrypt(numerals)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, pos := range positions {
+			runes[pos] = alphabet[numerals[i]]
+		}
+		return string(runes), nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"hmac", "",
+	).InCategory(
+		MethodCategoryEncoding,
+		"Calculates the HMAC of a string or byte array target with a chosen hash algorithm and a key, and returns the result as a byte array. Since the result is deterministic for a given key it can be used in order to replace identifying values with tokens that can still be matched with each other. Available algorithms are: `sha1`, `sha256`, `sha512`.",
+		NewExampleSpec("",
+			`root.h = this.value.hmac("sha256", "static-key").encode("hex")`,
+			`{"value":"hello world"}`,
+			`{"h":"b1cdce8b2add1f96135b2506f8ab748ae8ef15c49c0320357a6d168c42e20746"}`,
+		),
+		NewExampleSpec("",
+			`root.user_token = this.user_id.hmac("sha256", secret("token_key")).encode("base64")`,
+		),
+	).
+		Param(ParamString("algorithm", "The hash algorithm to use.")).
+		Param(ParamString("key", "A key to use.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		algorithmStr, err := args.FieldString("algorithm")
+		if err != nil {
+			return nil, err
+		}
+		keyStr, err := args.FieldString("key")
+		if err != nil {
+			return nil, err
+		}
+		var hashFn func() hash.Hash
+		switch algorithmStr {
+		case "sha1":
+			hashFn = sha1.New
+		case "sha256":
+			hashFn = sha256.New
+		case "sha512":
+			hashFn = sha512.New
+		default:
+			return nil, fmt.Errorf("unrecognized hash algorithm: %v", algorithmStr)
+		}
+		key := []byte(keyStr)
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			b, err := IGetBytes(v)
+			if err != nil {
+				return nil, err
+			}
+			mac := hmac.New(hashFn, key)
+			_, _ = mac.Write(b)
+			return mac.Sum(nil), nil
+		}, nil
+	},
+)
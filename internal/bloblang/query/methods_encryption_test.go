@@ -0,0 +1,232 @@
+package query
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptionMethods(t *testing.T) {
+	key := string(mustDecodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	tweak := string(mustDecodeHex(t, "39383736353433323130"))
+	tweak36 := string(mustDecodeHex(t, "3737373770717273373737"))
+
+	testCases := []struct {
+		name    string
+		method  string
+		target  interface{}
+		args    []interface{}
+		exp     interface{}
+		err     string
+		initErr string
+	}{
+		{
+			name:   "ff1 nist sample 1",
+			method: "encrypt_aes_ff1",
+			target: "0123456789",
+			args:   []interface{}{key},
+			exp:    "2433477484",
+		},
+		{
+			name:   "ff1 nist sample 2",
+			method: "encrypt_aes_ff1",
+			target: "0123456789",
+			args:   []interface{}{key, tweak},
+			exp:    "6124200773",
+		},
+		{
+			name:   "ff1 nist sample 3",
+			method: "encrypt_aes_ff1",
+			target: "0123456789abcdefghi",
+			args:   []interface{}{key, tweak36, "0123456789abcdefghijklmnopqrstuvwxyz"},
+			exp:    "a9tv40mll9kdu509eum",
+		},
+		{
+			name:   "ff1 decrypt nist sample 2",
+			method: "decrypt_aes_ff1",
+			target: "6124200773",
+			args:   []interface{}{key, tweak},
+			exp:    "0123456789",
+		},
+		{
+			name:   "ff1 keeps separators",
+			method: "encrypt_aes_ff1",
+			target: "4111-1111-1111-1111",
+			args:   []interface{}{key},
+			exp:    "3662-3112-3979-7070",
+		},
+		{
+			name:   "ff1 decrypt keeps separators",
+			method: "decrypt_aes_ff1",
+			target: "3662-3112-3979-7070",
+			args:   []interface{}{key},
+			exp:    "4111-1111-1111-1111",
+		},
+		{
+			name:   "ff1 too short",
+			method: "encrypt_aes_ff1",
+			target: "12-34",
+			args:   []interface{}{key},
+			err:    "value is too short to be encrypted with the given alphabet",
+		},
+		{
+			name:    "ff1 duplicate alphabet",
+			method:  "encrypt_aes_ff1",
+			target:  "0123456789",
+			args:    []interface{}{key, "", "01234567890"},
+			initErr: "alphabet contains duplicate character: '0'",
+		},
+		{
+			name:    "ff1 bad key",
+			method:  "encrypt_aes_ff1",
+			target:  "0123456789",
+			args:    []interface{}{"nope"},
+			initErr: "invalid key size",
+		},
+		{
+			name:   "gcm decrypt bad data",
+			method: "decrypt_aes_gcm",
+			target: "nope",
+			args:   []interface{}{key},
+			err:    "encrypted data is shorter than the nonce",
+		},
+		{
+			name:    "gcm bad key",
+			method:  "encrypt_aes_gcm",
+			target:  "hello world",
+			args:    []interface{}{"nope"},
+			initErr: "invalid key size",
+		},
+		{
+			name:   "hmac sha256",
+			method: "hmac",
+			target: "hello world",
+			args:   []interface{}{"sha256", "static-key"},
+			exp:    mustDecodeHex(t, "b1cdce8b2add1f96135b2506f8ab748ae8ef15c49c0320357a6d168c42e20746"),
+		},
+		{
+			name:   "hmac bad target",
+			method: "hmac",
+			target: int64(10),
+			args:   []interface{}{"sha256", "static-key"},
+			err:    "expected bytes value",
+		},
+		{
+			name:    "hmac bad algorithm",
+			method:  "hmac",
+			target:  "hello world",
+			args:    []interface{}{"md5", "static-key"},
+			initErr: "unrecognized hash algorithm: md5",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			targetClone := IClone(test.target)
+			argsClone := IClone(test.args).([]interface{})
+
+			fn, err := InitMethodHelper(test.method, NewLiteralFunction("", targetClone), argsClone...)
+			if test.initErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.initErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Maps:     map[string]Function{},
+				Index:    0,
+				MsgBatch: nil,
+			})
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.exp, res)
+			}
+			assert.Equal(t, test.target, targetClone)
+			assert.Equal(t, test.args, argsClone)
+		})
+	}
+}
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	key := string(mustDecodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	ctx := FunctionContext{Maps: map[string]Function{}}
+
+	encFn, err := InitMethodHelper("encrypt_aes_gcm", NewLiteralFunction("", "hello world"), key, "foo")
+	require.NoError(t, err)
+
+	encrypted, err := encFn.Exec(ctx)
+	require.NoError(t, err)
+
+	encryptedAgain, err := encFn.Exec(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, encryptedAgain, "nonces should be unique")
+
+	decFn, err := InitMethodHelper("decrypt_aes_gcm", NewLiteralFunction("", encrypted), key, "foo")
+	require.NoError(t, err)
+
+	decrypted, err := decFn.Exec(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello world"), decrypted)
+
+	badFn, err := InitMethodHelper("decrypt_aes_gcm", NewLiteralFunction("", encrypted), key, "bar")
+	require.NoError(t, err)
+
+	_, err = badFn.Exec(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt")
+}
+
+func TestSecretFunction(t *testing.T) {
+	ctx := FunctionContext{Maps: map[string]Function{}}
+
+	fn, err := InitFunctionHelper("secret", "foo")
+	require.NoError(t, err)
+
+	_, err = fn.Exec(ctx)
+	require.EqualError(t, err, errSecretAccessUnavailable.Error())
+
+	var accessed []string
+	fSet := AllFunctions.WithSecretAccess(func(name string) ([]byte, error) {
+		accessed = append(accessed, name)
+		if name == "foo" {
+			return []byte("foo value"), nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	params, err := fSet.Params("secret")
+	require.NoError(t, err)
+
+	args, err := params.PopulateNameless("foo")
+	require.NoError(t, err)
+
+	fn, err = fSet.Init("secret", args)
+	require.NoError(t, err)
+
+	res, err := fn.Exec(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo value"), res)
+
+	args, err = params.PopulateNameless("bar")
+	require.NoError(t, err)
+
+	_, err = fSet.Init("secret", args)
+	require.EqualError(t, err, "nope")
+
+	assert.Equal(t, []string{"foo", "bar"}, accessed)
+}
+
+func mustDecodeHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
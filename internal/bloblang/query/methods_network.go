@@ -0,0 +1,328 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ip_in_cidr", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Checks whether an IPv4 or IPv6 address string is within the range of a CIDR block.",
+		NewExampleSpec("",
+			`root.internal = this.ip.ip_in_cidr("10.0.0.0/8")`,
+			`{"ip":"10.1.2.3"}`,
+			`{"internal":true}`,
+			`{"ip":"192.168.0.1"}`,
+			`{"internal":false}`,
+		),
+	).Param(ParamString("cidr", "The CIDR block to check against.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		cidrStr, err := args.FieldString("cidr")
+		if err != nil {
+			return nil, err
+		}
+		_, cidr, err := net.ParseCIDR(cidrStr)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			ip, err := ipFromValue(v)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.Contains(ip), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"cidr_contains", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Checks whether a CIDR block string contains an IPv4 or IPv6 address, or the entire range of another CIDR block.",
+		NewExampleSpec("",
+			`root.matched = this.subnet.cidr_contains(this.ip)`,
+			`{"ip":"192.168.0.12","subnet":"192.168.0.0/24"}`,
+			`{"matched":true}`,
+			`{"ip":"192.168.1.12","subnet":"192.168.0.0/24"}`,
+			`{"matched":false}`,
+		),
+		NewExampleSpec("",
+			`root.matched = "10.0.0.0/8".cidr_contains(this.subnet)`,
+			`{"subnet":"10.20.0.0/16"}`,
+			`{"matched":true}`,
+			`{"subnet":"10.0.0.0/7"}`,
+			`{"matched":false}`,
+		),
+	).Param(ParamString("value", "An IP address or CIDR block to check for.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		valueStr, err := args.FieldString("value")
+		if err != nil {
+			return nil, err
+		}
+		start, end, err := ipRangeFromString(valueStr)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			s, err := IGetString(v)
+			if err != nil {
+				return nil, err
+			}
+			_, cidr, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.Contains(start) && cidr.Contains(end), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ip_subnet", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Returns the CIDR block of a given prefix length that contains an IPv4 or IPv6 address, which is useful for grouping addresses by their subnet.",
+		NewExampleSpec("",
+			`root.subnet = this.ip.ip_subnet(24)`,
+			`{"ip":"192.168.0.12"}`,
+			`{"subnet":"192.168.0.0/24"}`,
+		),
+		NewExampleSpec("",
+			`root.subnet = this.ip.ip_subnet(64)`,
+			`{"ip":"2001:db8:0:1:2:3:4:5"}`,
+			`{"subnet":"2001:db8:0:1::/64"}`,
+		),
+	).Param(ParamInt64("prefix_length", "The prefix length of the subnet, which can be up to 32 for IPv4 addresses and up to 128 for IPv6 addresses.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		prefixLen, err := args.FieldInt64("prefix_length")
+		if err != nil {
+			return nil, err
+		}
+		if prefixLen < 0 || prefixLen > 128 {
+			return nil, fmt.Errorf("prefix length must be between 0 and 128, got %v", prefixLen)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			ip, err := ipFromValue(v)
+			if err != nil {
+				return nil, err
+			}
+			bits := 8 * len(ip)
+			if int(prefixLen) > bits {
+				return nil, fmt.Errorf("prefix length %v exceeds the %v bits of the address", prefixLen, bits)
+			}
+			mask := net.CIDRMask(int(prefixLen), bits)
+			subnet := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+			return subnet.String(), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"cidr_merge", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Aggregates an array of IP addresses and CIDR blocks into the smallest array of CIDR blocks that covers exactly the same addresses, where overlapping and adjacent ranges are merged. IPv4 blocks are returned before IPv6 blocks, and each are sorted by their address.",
+		NewExampleSpec("",
+			`root.cidrs = this.addresses.cidr_merge()`,
+			`{"addresses":["10.0.1.0/24","10.0.0.0/24","10.0.0.5","192.168.1.1","2001:db8:8000::/33","2001:db8::/33"]}`,
+			`{"cidrs":["10.0.0.0/23","192.168.1.1/32","2001:db8::/32"]}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			var v4Ranges, v6Ranges []ipIntRange
+			for i, e := range arr {
+				s, err := IGetString(e)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				start, end, err := ipRangeFromString(s)
+				if err != nil {
+					return nil, fmt.Errorf("element %v: %w", i, err)
+				}
+				r := ipIntRange{start: ipToBigInt(start), end: ipToBigInt(end)}
+				if len(start) == net.IPv4len {
+					v4Ranges = append(v4Ranges, r)
+				} else {
+					v6Ranges = append(v6Ranges, r)
+				}
+			}
+			cidrs := make([]interface{}, 0, len(arr))
+			for _, r := range mergeIPRanges(v4Ranges) {
+				cidrs = appendRangeCIDRs(cidrs, r, net.IPv4len)
+			}
+			for _, r := range mergeIPRanges(v6Ranges) {
+				cidrs = appendRangeCIDRs(cidrs, r, net.IPv6len)
+			}
+			return cidrs, nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ip_to_int", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Converts an IPv4 address string into its integer representation.",
+		NewExampleSpec("",
+			`root.ip_int = this.ip.ip_to_int()`,
+			`{"ip":"192.168.0.1"}`,
+			`{"ip_int":3232235521}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			ip, err := ipFromValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if len(ip) != net.IPv4len {
+				return nil, errors.New("only IPv4 addresses can be converted to an integer")
+			}
+			return ipToBigInt(ip).Int64(), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"int_to_ip", "",
+	).InCategory(
+		MethodCategoryNetwork,
+		"Converts an integer into the IPv4 address string it represents.",
+		NewExampleSpec("",
+			`root.ip = this.ip_int.int_to_ip()`,
+			`{"ip_int":3232235521}`,
+			`{"ip":"192.168.0.1"}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			i, err := IGetInt(v)
+			if err != nil {
+				return nil, err
+			}
+			if i < 0 || i > 0xFFFFFFFF {
+				return nil, fmt.Errorf("value %v is out of the range of IPv4 addresses", i)
+			}
+			return net.IPv4(byte(i>>24), byte(i>>16), byte(i>>8), byte(i)).String(), nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+// ipFromValue parses an IP address string, where IPv4 addresses are returned
+// in their 4 byte form.
+func ipFromValue(v interface{}) (net.IP, error) {
+	s, err := IGetString(v)
+	if err != nil {
+		return nil, err
+	}
+	return parseIP(s)
+}
+
+func parseIP(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("value %v does not appear to be a valid v4 or v6 IP address", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return ip, nil
+}
+
+// ipRangeFromString returns the first and last addresses of either a CIDR
+// block or a single IP address.
+func ipRangeFromString(s string) (start, end net.IP, err error) {
+	if _, cidr, cErr := net.ParseCIDR(s); cErr == nil {
+		start = cidr.IP
+		if ip4 := start.To4(); ip4 != nil {
+			start = ip4
+		}
+		end = make(net.IP, len(start))
+		for i := range start {
+			end[i] = start[i] | ^cidr.Mask[i]
+		}
+		return start, end, nil
+	}
+	if start, err = parseIP(s); err != nil {
+		return nil, nil, fmt.Errorf("value %v does not appear to be a valid IP address or CIDR block", s)
+	}
+	return start, start, nil
+}
+
+type ipIntRange struct {
+	start, end *big.Int
+}
+
+func ipToBigInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+// mergeIPRanges sorts ranges of addresses and merges those that overlap or are
+// adjacent.
+func mergeIPRanges(ranges []ipIntRange) []ipIntRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Cmp(ranges[j].start) < 0
+	})
+	one := big.NewInt(1)
+	var merged []ipIntRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if new(big.Int).Add(last.end, one).Cmp(r.start) >= 0 {
+				if r.end.Cmp(last.end) > 0 {
+					last.end = r.end
+				}
+				continue
+			}
+		}
+		merged = append(merged, ipIntRange{start: new(big.Int).Set(r.start), end: r.end})
+	}
+	return merged
+}
+
+// appendRangeCIDRs appends the smallest list of CIDR blocks that exactly cover
+// a range of addresses.
+func appendRangeCIDRs(cidrs []interface{}, r ipIntRange, ipLen int) []interface{} {
+	bits := 8 * ipLen
+	one := big.NewInt(1)
+	start := new(big.Int).Set(r.start)
+	for start.Cmp(r.end) <= 0 {
+		// The largest block that is aligned to the start of the range.
+		size := bits
+		if start.Sign() != 0 {
+			size = int(start.TrailingZeroBits())
+		}
+		// Limited to the largest block that fits within the remaining range.
+		remaining := new(big.Int).Sub(r.end, start)
+		remaining.Add(remaining, one)
+		if maxSize := remaining.BitLen() - 1; maxSize < size {
+			size = maxSize
+		}
+
+		ip := make(net.IP, ipLen)
+		start.FillBytes(ip)
+		cidrs = append(cidrs, (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits-size, bits)}).String())
+
+		start.Add(start, new(big.Int).Lsh(one, uint(size)))
+	}
+	return cidrs
+}
@@ -0,0 +1,193 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkMethods(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		target interface{}
+		args   []interface{}
+		exp    interface{}
+		err    string
+	}{
+		{
+			name:   "ip in cidr v4",
+			method: "ip_in_cidr",
+			target: "10.1.2.3",
+			args:   []interface{}{"10.0.0.0/8"},
+			exp:    true,
+		},
+		{
+			name:   "ip not in cidr v4",
+			method: "ip_in_cidr",
+			target: "11.1.2.3",
+			args:   []interface{}{"10.0.0.0/8"},
+			exp:    false,
+		},
+		{
+			name:   "ip in cidr v6",
+			method: "ip_in_cidr",
+			target: "2001:db8::1",
+			args:   []interface{}{"2001:db8::/32"},
+			exp:    true,
+		},
+		{
+			name:   "ip v4 not in cidr v6",
+			method: "ip_in_cidr",
+			target: "10.1.2.3",
+			args:   []interface{}{"2001:db8::/32"},
+			exp:    false,
+		},
+		{
+			name:   "ip in cidr bad ip",
+			method: "ip_in_cidr",
+			target: "nope",
+			args:   []interface{}{"10.0.0.0/8"},
+			err:    "value nope does not appear to be a valid v4 or v6 IP address",
+		},
+		{
+			name:   "cidr contains ip",
+			method: "cidr_contains",
+			target: "192.168.0.0/24",
+			args:   []interface{}{"192.168.0.255"},
+			exp:    true,
+		},
+		{
+			name:   "cidr contains cidr",
+			method: "cidr_contains",
+			target: "10.0.0.0/8",
+			args:   []interface{}{"10.20.0.0/16"},
+			exp:    true,
+		},
+		{
+			name:   "cidr does not contain larger cidr",
+			method: "cidr_contains",
+			target: "10.0.0.0/8",
+			args:   []interface{}{"10.0.0.0/7"},
+			exp:    false,
+		},
+		{
+			name:   "cidr contains bad target",
+			method: "cidr_contains",
+			target: "10.0.0.1",
+			args:   []interface{}{"10.0.0.1"},
+			err:    "invalid CIDR address: 10.0.0.1",
+		},
+		{
+			name:   "ip subnet v4",
+			method: "ip_subnet",
+			target: "192.168.7.12",
+			args:   []interface{}{int64(20)},
+			exp:    "192.168.0.0/20",
+		},
+		{
+			name:   "ip subnet v6",
+			method: "ip_subnet",
+			target: "2001:db8:0:1:2:3:4:5",
+			args:   []interface{}{int64(48)},
+			exp:    "2001:db8::/48",
+		},
+		{
+			name:   "ip subnet v4 too long",
+			method: "ip_subnet",
+			target: "192.168.7.12",
+			args:   []interface{}{int64(33)},
+			err:    "prefix length 33 exceeds the 32 bits of the address",
+		},
+		{
+			name:   "ip to int",
+			method: "ip_to_int",
+			target: "255.255.255.255",
+			args:   []interface{}{},
+			exp:    int64(4294967295),
+		},
+		{
+			name:   "ip to int v6",
+			method: "ip_to_int",
+			target: "2001:db8::1",
+			args:   []interface{}{},
+			err:    "only IPv4 addresses can be converted to an integer",
+		},
+		{
+			name:   "int to ip",
+			method: "int_to_ip",
+			target: int64(167772161),
+			args:   []interface{}{},
+			exp:    "10.0.0.1",
+		},
+		{
+			name:   "int to ip out of range",
+			method: "int_to_ip",
+			target: int64(4294967296),
+			args:   []interface{}{},
+			err:    "value 4294967296 is out of the range of IPv4 addresses",
+		},
+		{
+			name:   "cidr merge adjacent",
+			method: "cidr_merge",
+			target: []interface{}{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			args:   []interface{}{},
+			exp:    []interface{}{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/32"},
+		},
+		{
+			name:   "cidr merge overlapping",
+			method: "cidr_merge",
+			target: []interface{}{"11.0.0.0/8", "10.5.0.0/16", "10.0.0.0/8", "255.255.255.255"},
+			args:   []interface{}{},
+			exp:    []interface{}{"10.0.0.0/7", "255.255.255.255/32"},
+		},
+		{
+			name:   "cidr merge everything",
+			method: "cidr_merge",
+			target: []interface{}{"1.2.3.4", "0.0.0.0/0", "::1", "::/0"},
+			args:   []interface{}{},
+			exp:    []interface{}{"0.0.0.0/0", "::/0"},
+		},
+		{
+			name:   "cidr merge empty",
+			method: "cidr_merge",
+			target: []interface{}{},
+			args:   []interface{}{},
+			exp:    []interface{}{},
+		},
+		{
+			name:   "cidr merge bad element",
+			method: "cidr_merge",
+			target: []interface{}{"10.0.0.0/8", "nope"},
+			args:   []interface{}{},
+			err:    "element 1: value nope does not appear to be a valid IP address or CIDR block",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			targetClone := IClone(test.target)
+			argsClone := IClone(test.args).([]interface{})
+
+			fn, err := InitMethodHelper(test.method, NewLiteralFunction("", targetClone), argsClone...)
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Maps:     map[string]Function{},
+				Index:    0,
+				MsgBatch: nil,
+			})
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.exp, res)
+			}
+			assert.Equal(t, test.target, targetClone)
+			assert.Equal(t, test.args, argsClone)
+		})
+	}
+}
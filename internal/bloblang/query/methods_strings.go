@@ -30,6 +30,7 @@ import (
 	"github.com/itchyny/timefmt-go"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/rickb777/date/period"
+	"github.com/segmentio/ksuid"
 	"github.com/tilinna/z85"
 	"gopkg.in/yaml.v3"
 )
@@ -1003,6 +1004,108 @@ var _ = registerSimpleMethod(
 	},
 )
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"parse_ksuid", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Attempts to parse a string as a [KSUID](https://github.com/segmentio/ksuid) and returns an object containing the timestamp embedded within it, in ISO 8601 format, and its random payload as a hex encoded string.",
+		NewExampleSpec("",
+			`root.created_at = this.id.parse_ksuid().timestamp`,
+			`{"id":"0ujtsYcgvSTl8PAuAdqWYSMnLOv"}`,
+			`{"created_at":"2017-10-10T04:00:47Z"}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			s, err := IGetString(v)
+			if err != nil {
+				return nil, err
+			}
+			id, err := ksuid.Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value as ksuid: %w", err)
+			}
+			return map[string]interface{}{
+				"timestamp": idTimestampString(id.Time()),
+				"payload":   hex.EncodeToString(id.Payload()),
+			}, nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"parse_snowflake_id", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Attempts to parse a number, or a string containing a number, as a snowflake ID generated with the Twitter snowflake epoch, such as those generated with the function [`snowflake_id`](/docs/guides/bloblang/functions#snowflake_id), and returns an object containing the timestamp embedded within it, in ISO 8601 format, its node ID and its sequence number.",
+		NewExampleSpec("",
+			`root = this.id.parse_snowflake_id()`,
+			`{"id":1212161087258406912}`,
+			`{"node_id":363,"sequence":0,"timestamp":"2019-12-31T23:58:18.723Z"}`,
+			`{"id":"1212161087258406912"}`,
+			`{"node_id":363,"sequence":0,"timestamp":"2019-12-31T23:58:18.723Z"}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			var id int64
+			var err error
+			switch t := v.(type) {
+			case string:
+				id, err = strconv.ParseInt(t, 10, 64)
+			case []byte:
+				id, err = strconv.ParseInt(string(t), 10, 64)
+			default:
+				id, err = IGetInt(v)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value as snowflake id: %w", err)
+			}
+			if id < 0 {
+				return nil, fmt.Errorf("failed to parse value as snowflake id: expected a positive number, got %v", id)
+			}
+			ts, nodeID, sequence := decodeSnowflakeID(id)
+			return map[string]interface{}{
+				"timestamp": idTimestampString(ts),
+				"node_id":   nodeID,
+				"sequence":  sequence,
+			}, nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"parse_ulid", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Attempts to parse a string as a [ULID](https://github.com/ulid/spec) and returns an object containing the timestamp embedded within it, in ISO 8601 format, and its random component as a hex encoded string.",
+		NewExampleSpec("",
+			`root.created_at = this.id.parse_ulid().timestamp`,
+			`{"id":"01ARZ3NDEKTSV4RRFFQ69G5FAV"}`,
+			`{"created_at":"2016-07-30T23:54:10.259Z"}`,
+		),
+	),
+	func(*ParsedParams) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			s, err := IGetString(v)
+			if err != nil {
+				return nil, err
+			}
+			ts, entropy, err := decodeULID(s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value as ulid: %w", err)
+			}
+			return map[string]interface{}{
+				"timestamp": idTimestampString(ts),
+				"entropy":   hex.EncodeToString(entropy),
+			}, nil
+		}, nil
+	},
+)
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"parse_xml", "",
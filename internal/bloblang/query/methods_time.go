@@ -0,0 +1,302 @@
+package query
+
+import (
+	"fmt"
+	"time"
+)
+
+func loadOptionalTimezone(args *ParsedParams) (*time.Location, error) {
+	tzOpt, err := args.FieldOptionalString("tz")
+	if err != nil {
+		return nil, err
+	}
+	if tzOpt == nil {
+		return nil, nil
+	}
+	timezone, err := time.LoadLocation(*tzOpt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timezone location name: %w", err)
+	}
+	return timezone, nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_tz", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Converts a timestamp value into a given timezone and returns it as a string following ISO 8601. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.created_at_ny = this.created_at.ts_tz("America/New_York")`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"created_at_ny":"2021-02-03T11:05:06-05:00"}`,
+		),
+	).Beta().Param(ParamString("tz", "The IANA name of the timezone to convert to, or `UTC` or `Local`.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		tzStr, err := args.FieldString("tz")
+		if err != nil {
+			return nil, err
+		}
+		timezone, err := time.LoadLocation(tzStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse timezone location name: %w", err)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			return target.In(timezone).Format(time.RFC3339Nano), nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_truncate", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Truncates a timestamp value down to a multiple of a duration and returns it as a string following ISO 8601. The multiples are relative to the wall clock of the timezone of the timestamp, and therefore truncating to `24h` returns the midnight of that timezone, which can be changed beforehand with [`ts_tz`](#ts_tz). Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.created_hour = this.created_at.ts_truncate("1h")`,
+			`{"created_at":"2021-02-03T16:05:06.123Z"}`,
+			`{"created_hour":"2021-02-03T16:00:00Z"}`,
+		),
+		NewExampleSpec("",
+			`root.created_day = this.created_at.ts_tz("Asia/Tokyo").ts_truncate("24h")`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"created_day":"2021-02-04T00:00:00+09:00"}`,
+		),
+	).Beta().Param(ParamString("duration", "A duration string such as `15m` or `1h30m`, with valid time units being `ns`, `us` (or `µs`), `ms`, `s`, `m` and `h`.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		durationStr, err := args.FieldString("duration")
+		if err != nil {
+			return nil, err
+		}
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return nil, err
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration must be greater than zero, got %v", durationStr)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			return truncateWallClock(target, duration).Format(time.RFC3339Nano), nil
+		}, nil
+	},
+)
+
+// truncateWallClock truncates a timestamp relative to the wall clock of its
+// location rather than the absolute time since the zero time.
+func truncateWallClock(t time.Time, d time.Duration) time.Time {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Truncate(d)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), t.Location())
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_bucket", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Returns the start of the fixed size window that a timestamp value falls within as a string following ISO 8601 in UTC, which is useful as a key for grouping messages into windows. Windows are aligned to the unix epoch, and the alignment can be shifted with the `offset` parameter. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.window = this.created_at.ts_bucket("15m")`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"window":"2021-02-03T16:00:00Z"}`,
+			`{"created_at":"2021-02-03T18:20:00+02:00"}`,
+			`{"window":"2021-02-03T16:15:00Z"}`,
+		),
+		NewExampleSpec("",
+			`root.window = this.created_at.ts_bucket(size: "1h", offset: "30m")`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"window":"2021-02-03T15:30:00Z"}`,
+		),
+	).Beta().
+		Param(ParamString("size", "The size of each window as a duration string such as `15m` or `1h30m`, with valid time units being `ns`, `us` (or `µs`), `ms`, `s`, `m` and `h`.")).
+		Param(ParamString("offset", "A duration to shift the start of windows by.").Default("0s")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		sizeStr, err := args.FieldString("size")
+		if err != nil {
+			return nil, err
+		}
+		size, err := time.ParseDuration(sizeStr)
+		if err != nil {
+			return nil, err
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("size must be greater than zero, got %v", sizeStr)
+		}
+		offsetStr, err := args.FieldString("offset")
+		if err != nil {
+			return nil, err
+		}
+		offset, err := time.ParseDuration(offsetStr)
+		if err != nil {
+			return nil, err
+		}
+		offset %= size
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			ns := target.UnixNano() - int64(offset)
+			rem := ns % int64(size)
+			if rem < 0 {
+				rem += int64(size)
+			}
+			return time.Unix(0, ns-rem+int64(offset)).UTC().Format(time.RFC3339Nano), nil
+		}, nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_add_months", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Adds a number of months to a timestamp value and returns it as a string following ISO 8601. When the day of the month does not exist within the resulting month it is clamped to the last day of that month, and therefore adding one month to January 31st returns the last day of February rather than a date in March. Negative numbers subtract months. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.renews_at = this.created_at.ts_add_months(1)`,
+			`{"created_at":"2021-01-31T10:00:00Z"}`,
+			`{"renews_at":"2021-02-28T10:00:00Z"}`,
+			`{"created_at":"2021-03-15T10:00:00+01:00"}`,
+			`{"renews_at":"2021-04-15T10:00:00+01:00"}`,
+		),
+	).Beta().Param(ParamInt64("months", "The number of months to add.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		months, err := args.FieldInt64("months")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			return addMonthsClamped(target, int(months)).Format(time.RFC3339Nano), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_add_years", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Adds a number of years to a timestamp value and returns it as a string following ISO 8601. When the resulting year is not a leap year February 29th is clamped to February 28th. Negative numbers subtract years. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.expires_at = this.created_at.ts_add_years(1)`,
+			`{"created_at":"2020-02-29T10:00:00Z"}`,
+			`{"expires_at":"2021-02-28T10:00:00Z"}`,
+		),
+	).Beta().Param(ParamInt64("years", "The number of years to add.")),
+	func(args *ParsedParams) (simpleMethod, error) {
+		years, err := args.FieldInt64("years")
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			return addMonthsClamped(target, int(years)*12).Format(time.RFC3339Nano), nil
+		}, nil
+	},
+)
+
+// addMonthsClamped adds months to a timestamp, clamping the day of the month to
+// the last day of the resulting month rather than overflowing into the next.
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	lastDay := time.Date(year, month+time.Month(months)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month+time.Month(months), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_iso_week", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Returns the ISO 8601 week number, from 1 to 53, of a timestamp value. The first days of January can belong to the last week of the previous year and the last days of December can belong to the first week of the next year, and therefore the method [`format_timestamp_strftime`](#format_timestamp_strftime) with the format `%G-W%V` should be used for keys that include the year. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.week = this.created_at.ts_iso_week()`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"week":5}`,
+			`{"created_at":"2021-01-01T16:05:06Z"}`,
+			`{"week":53}`,
+		),
+	).Beta().Param(ParamString("tz", "An optional timezone to calculate the week within, otherwise the timezone of the input string is used, or in the case of unix timestamps the local timezone is used.").Optional()),
+	func(args *ParsedParams) (simpleMethod, error) {
+		timezone, err := loadOptionalTimezone(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			if timezone != nil {
+				target = target.In(timezone)
+			}
+			_, week := target.ISOWeek()
+			return int64(week), nil
+		}, nil
+	},
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ts_quarter", "",
+	).InCategory(
+		MethodCategoryTime,
+		"Returns the quarter of the year, from 1 to 4, of a timestamp value. Timestamp values can either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format.",
+		NewExampleSpec("",
+			`root.quarter = this.created_at.ts_quarter()`,
+			`{"created_at":"2021-02-03T16:05:06Z"}`,
+			`{"quarter":1}`,
+			`{"created_at":"2021-12-31T23:30:00-01:00"}`,
+			`{"quarter":4}`,
+		),
+		NewExampleSpec("",
+			`root.quarter = this.created_at.ts_quarter("UTC")`,
+			`{"created_at":"2021-12-31T23:30:00-01:00"}`,
+			`{"quarter":1}`,
+		),
+	).Beta().Param(ParamString("tz", "An optional timezone to calculate the quarter within, otherwise the timezone of the input string is used, or in the case of unix timestamps the local timezone is used.").Optional()),
+	func(args *ParsedParams) (simpleMethod, error) {
+		timezone, err := loadOptionalTimezone(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			target, err := IGetTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			if timezone != nil {
+				target = target.In(timezone)
+			}
+			return int64(target.Month()-1)/3 + 1, nil
+		}, nil
+	},
+)
@@ -0,0 +1,178 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampMethods(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		target  interface{}
+		args    []interface{}
+		exp     interface{}
+		err     string
+		initErr string
+	}{
+		{
+			name:   "ts tz",
+			method: "ts_tz",
+			target: "2021-07-03T16:05:06Z",
+			args:   []interface{}{"Europe/London"},
+			exp:    "2021-07-03T17:05:06+01:00",
+		},
+		{
+			name:    "ts tz bad timezone",
+			method:  "ts_tz",
+			target:  "2021-07-03T16:05:06Z",
+			args:    []interface{}{"Nope/Nope"},
+			initErr: "failed to parse timezone location name",
+		},
+		{
+			name:   "ts truncate minutes",
+			method: "ts_truncate",
+			target: "2021-02-03T16:07:06.123456Z",
+			args:   []interface{}{"5m"},
+			exp:    "2021-02-03T16:05:00Z",
+		},
+		{
+			name:   "ts truncate day with offset",
+			method: "ts_truncate",
+			target: "2021-02-03T01:07:06-05:00",
+			args:   []interface{}{"24h"},
+			exp:    "2021-02-03T00:00:00-05:00",
+		},
+		{
+			name:    "ts truncate zero duration",
+			method:  "ts_truncate",
+			target:  "2021-02-03T16:07:06Z",
+			args:    []interface{}{"0s"},
+			initErr: "duration must be greater than zero",
+		},
+		{
+			name:   "ts truncate bad target",
+			method: "ts_truncate",
+			target: "not a timestamp",
+			args:   []interface{}{"1h"},
+			err:    "cannot parse",
+		},
+		{
+			name:   "ts bucket",
+			method: "ts_bucket",
+			target: "2021-02-03T16:07:06Z",
+			args:   []interface{}{"7m", "0s"},
+			exp:    "2021-02-03T16:04:00Z",
+		},
+		{
+			name:   "ts bucket before epoch",
+			method: "ts_bucket",
+			target: "1969-12-31T23:59:59Z",
+			args:   []interface{}{"1h", "0s"},
+			exp:    "1969-12-31T23:00:00Z",
+		},
+		{
+			name:   "ts bucket offset larger than size",
+			method: "ts_bucket",
+			target: "2021-02-03T16:07:06+01:00",
+			args:   []interface{}{"1h", "90m"},
+			exp:    "2021-02-03T14:30:00Z",
+		},
+		{
+			name:   "ts add months end of month",
+			method: "ts_add_months",
+			target: "2021-03-31T10:00:00Z",
+			args:   []interface{}{int64(-1)},
+			exp:    "2021-02-28T10:00:00Z",
+		},
+		{
+			name:   "ts add months into leap year",
+			method: "ts_add_months",
+			target: "2019-12-31T10:00:00Z",
+			args:   []interface{}{int64(2)},
+			exp:    "2020-02-29T10:00:00Z",
+		},
+		{
+			name:   "ts add months across years",
+			method: "ts_add_months",
+			target: "2021-11-15T10:00:00Z",
+			args:   []interface{}{int64(14)},
+			exp:    "2023-01-15T10:00:00Z",
+		},
+		{
+			name:   "ts add years",
+			method: "ts_add_years",
+			target: "2020-02-29T10:00:00Z",
+			args:   []interface{}{int64(4)},
+			exp:    "2024-02-29T10:00:00Z",
+		},
+		{
+			name:   "ts add years negative",
+			method: "ts_add_years",
+			target: "2020-02-29T10:00:00Z",
+			args:   []interface{}{int64(-1)},
+			exp:    "2019-02-28T10:00:00Z",
+		},
+		{
+			name:   "ts iso week last week of year",
+			method: "ts_iso_week",
+			target: "2020-12-31T10:00:00Z",
+			args:   []interface{}{},
+			exp:    int64(53),
+		},
+		{
+			name:   "ts iso week with timezone",
+			method: "ts_iso_week",
+			target: "2021-01-03T23:30:00Z",
+			args:   []interface{}{"Asia/Tokyo"},
+			exp:    int64(1),
+		},
+		{
+			name:   "ts quarter",
+			method: "ts_quarter",
+			target: "2021-07-01T00:00:00Z",
+			args:   []interface{}{},
+			exp:    int64(3),
+		},
+		{
+			name:   "ts quarter with timezone",
+			method: "ts_quarter",
+			target: "2021-07-01T00:00:00Z",
+			args:   []interface{}{"America/New_York"},
+			exp:    int64(2),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			targetClone := IClone(test.target)
+			argsClone := IClone(test.args).([]interface{})
+
+			fn, err := InitMethodHelper(test.method, NewLiteralFunction("", targetClone), argsClone...)
+			if test.initErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.initErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Maps:     map[string]Function{},
+				Index:    0,
+				MsgBatch: nil,
+			})
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.exp, res)
+			}
+			assert.Equal(t, test.target, targetClone)
+			assert.Equal(t, test.args, argsClone)
+		})
+	}
+}
@@ -86,15 +86,20 @@ func (s *OutputSet) Init(
 	mgr NewManagement,
 	pipelines ...types.PipelineConstructorFunc,
 ) (types.Output, error) {
-	spec, exists := s.specs[conf.Type]
-	if !exists {
+	var out types.Output
+	var err error
+	if spec, exists := s.specs[conf.Type]; exists {
+		out, err = spec.constructor(conf, mgr, pipelines...)
+	} else if ctor, exists := output.GetDeprecatedPlugin(conf.Type); exists {
 		// TODO: V4 Remove this
-		if ctor, exists := output.GetDeprecatedPlugin(conf.Type); exists {
-			return ctor(conf, mgr, mgr.Logger(), mgr.Metrics(), pipelines...)
-		}
+		out, err = ctor(conf, mgr, mgr.Logger(), mgr.Metrics(), pipelines...)
+	} else {
 		return nil, types.ErrInvalidOutputType
 	}
-	return spec.constructor(conf, mgr, pipelines...)
+	if err != nil {
+		return nil, err
+	}
+	return output.WrapWithDeadLetter(conf, out, mgr, mgr.Logger(), mgr.Metrics())
 }
 
 // Docs returns a slice of output specs, which document each method.
@@ -0,0 +1,121 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// ErrCacheModified is returned when a checkpoint cannot be swapped as it was
+// modified by another consumer since it was read.
+var ErrCacheModified = errors.New("checkpoint was modified by another consumer")
+
+// CacheKV is the subset of cache operations used for storing checkpoints, where
+// errors are reported with the cache errors of the types package. Caches that
+// support compare-and-swap operations should also implement the method
+// CompareAndSwap.
+type CacheKV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type cacheCompareAndSwap interface {
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error
+}
+
+// CacheAccessFunc provides access to a cache resource for the duration of a
+// closure.
+type CacheAccessFunc func(ctx context.Context, fn func(c CacheKV)) error
+
+// Cache reads and conditionally writes the checkpoints of any number of keys
+// within a cache resource, which allows inputs that coordinate through shared
+// checkpoints to store them within any cache that supports compare-and-swap
+// operations.
+//
+// This component is safe to use concurrently across goroutines.
+type Cache struct {
+	name   string
+	access CacheAccessFunc
+}
+
+// NewCache creates a checkpoint cache from the name of a cache resource and a
+// func for accessing it.
+func NewCache(name string, access CacheAccessFunc) *Cache {
+	return &Cache{
+		name:   name,
+		access: access,
+	}
+}
+
+// Name returns the name of the cache resource.
+func (c *Cache) Name() string {
+	return c.name
+}
+
+func (c *Cache) with(ctx context.Context, fn func(kv CacheKV) error) error {
+	var err error
+	if cerr := c.access(ctx, func(kv CacheKV) {
+		err = fn(kv)
+	}); cerr != nil {
+		return fmt.Errorf("unable to access cache '%v': %w", c.name, cerr)
+	}
+	return err
+}
+
+// Get returns the checkpoint of a key, or nil if the key does not exist.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := c.with(ctx, func(kv CacheKV) error {
+		var gerr error
+		value, gerr = kv.Get(ctx, key)
+		return gerr
+	})
+	if errors.Is(err, types.ErrKeyNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Set writes the checkpoint of a key unconditionally.
+func (c *Cache) Set(ctx context.Context, key string, value []byte) error {
+	return c.with(ctx, func(kv CacheKV) error {
+		return kv.Set(ctx, key, value, nil)
+	})
+}
+
+// Delete removes the checkpoint of a key, keys that do not exist are ignored.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	err := c.with(ctx, func(kv CacheKV) error {
+		return kv.Delete(ctx, key)
+	})
+	if errors.Is(err, types.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Swap writes the checkpoint of a key only if its current value matches old,
+// where a nil old value requires that the key does not exist. Returns
+// ErrCacheModified if the current value does not match.
+func (c *Cache) Swap(ctx context.Context, key string, old, value []byte) error {
+	return c.with(ctx, func(kv CacheKV) error {
+		cas, ok := kv.(cacheCompareAndSwap)
+		if !ok {
+			return fmt.Errorf("cache resource '%v' does not support compare-and-swap operations", c.name)
+		}
+		err := cas.CompareAndSwap(ctx, key, old, value, nil)
+		switch {
+		case errors.Is(err, types.ErrNotSupported):
+			return fmt.Errorf("cache resource '%v' does not support compare-and-swap operations", c.name)
+		case errors.Is(err, types.ErrKeyValueMismatch),
+			errors.Is(err, types.ErrKeyAlreadyExists),
+			errors.Is(err, types.ErrKeyNotFound):
+			return ErrCacheModified
+		}
+		return err
+	})
+}
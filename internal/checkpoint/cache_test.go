@@ -0,0 +1,84 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCacheKV struct {
+	items map[string][]byte
+}
+
+func (c *testCacheKV) Get(ctx context.Context, key string) ([]byte, error) {
+	v, exists := c.items[key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *testCacheKV) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.items[key] = value
+	return nil
+}
+
+func (c *testCacheKV) Delete(ctx context.Context, key string) error {
+	delete(c.items, key)
+	return nil
+}
+
+func (c *testCacheKV) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	current, exists := c.items[key]
+	if old == nil {
+		if exists {
+			return types.ErrKeyAlreadyExists
+		}
+	} else if !exists {
+		return types.ErrKeyNotFound
+	} else if !bytes.Equal(current, old) {
+		return types.ErrKeyValueMismatch
+	}
+	c.items[key] = value
+	return nil
+}
+
+func TestCacheSwap(t *testing.T) {
+	ctx := context.Background()
+	kv := &testCacheKV{items: map[string][]byte{}}
+	c := NewCache("foo", func(ctx context.Context, fn func(c CacheKV)) error {
+		fn(kv)
+		return nil
+	})
+
+	v, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	require.NoError(t, c.Swap(ctx, "a", nil, []byte("first")))
+	assert.Equal(t, ErrCacheModified, c.Swap(ctx, "a", nil, []byte("nope")))
+	assert.Equal(t, ErrCacheModified, c.Swap(ctx, "a", []byte("stale"), []byte("nope")))
+	require.NoError(t, c.Swap(ctx, "a", []byte("first"), []byte("second")))
+
+	v, err = c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(v))
+
+	require.NoError(t, c.Delete(ctx, "a"))
+	assert.Equal(t, ErrCacheModified, c.Swap(ctx, "a", []byte("second"), []byte("nope")))
+}
+
+func TestCacheAccessError(t *testing.T) {
+	c := NewCache("foo", func(ctx context.Context, fn func(c CacheKV)) error {
+		return errors.New("cache not found")
+	})
+
+	_, err := c.Get(context.Background(), "a")
+	require.EqualError(t, err, "unable to access cache 'foo': cache not found")
+}
@@ -1,4 +1,4 @@
 // Package checkpoint implements a mechanism for tracking checkpointed integer
 // offsets for sequential read at-least-once queue systems such as Kafka or
-// Kinesis.
+// Kinesis, and for storing checkpoints within cache resources.
 package checkpoint
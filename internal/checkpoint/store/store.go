@@ -0,0 +1,338 @@
+// Package store implements a shared mechanism for inputs to persist their
+// checkpoints within a cache resource, with optional fencing tokens and commit
+// intervals.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	intervalField = "checkpoint_interval"
+	fencingField  = "checkpoint_fencing"
+)
+
+// IntervalField returns a config field for specifying the interval at which
+// checkpoints are written to the cache.
+func IntervalField() *service.ConfigField {
+	return service.NewStringField(intervalField).
+		Description("An optional period at which checkpoints are written to the cache, where only the latest checkpoint reached within each period is written. When empty checkpoints are written as soon as they are reached. The latest checkpoint is always written when the input is closed.").
+		Example("5s").
+		Advanced().
+		Version("3.64.0").
+		Default("")
+}
+
+// FencingField returns a config field for enabling fencing tokens.
+func FencingField() *service.ConfigField {
+	return service.NewBoolField(fencingField).
+		Description("Whether to store checkpoints along with a fencing token that is incremented each time the input connects, which prevents a stale instance of the input sharing the same checkpoint key from overwriting the checkpoints of a newer instance. Requires a cache that supports compare-and-swap operations such as `memory`, `memcached` or `redis`.").
+		Advanced().
+		Version("3.64.0").
+		Default(false)
+}
+
+// ErrFenced is returned when a checkpoint cannot be written as the key has
+// been acquired by a newer consumer.
+var ErrFenced = errors.New("checkpoint was acquired by a newer consumer")
+
+// CacheAccessor provides access to a cache resource by name, matching the
+// signature of service.Resources.AccessCache.
+type CacheAccessor func(ctx context.Context, name string, fn func(c service.Cache)) error
+
+// NewCache creates a checkpoint cache for a cache resource, which inputs can
+// use to store the checkpoints of several keys with optimistic concurrency.
+func NewCache(access CacheAccessor, name string) *checkpoint.Cache {
+	return checkpoint.NewCache(name, func(ctx context.Context, fn func(c checkpoint.CacheKV)) error {
+		return access(ctx, name, func(c service.Cache) {
+			fn(serviceCacheKV{c: c})
+		})
+	})
+}
+
+// serviceCacheKV adapts a plugin cache to the errors of the types package.
+type serviceCacheKV struct {
+	c service.Cache
+}
+
+func libCacheErr(err error) error {
+	switch {
+	case errors.Is(err, service.ErrKeyNotFound):
+		return types.ErrKeyNotFound
+	case errors.Is(err, service.ErrKeyAlreadyExists):
+		return types.ErrKeyAlreadyExists
+	case errors.Is(err, service.ErrKeyValueMismatch):
+		return types.ErrKeyValueMismatch
+	}
+	return err
+}
+
+func (s serviceCacheKV) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := s.c.Get(ctx, key)
+	return v, libCacheErr(err)
+}
+
+func (s serviceCacheKV) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return libCacheErr(s.c.Set(ctx, key, value, ttl))
+}
+
+func (s serviceCacheKV) Delete(ctx context.Context, key string) error {
+	return libCacheErr(s.c.Delete(ctx, key))
+}
+
+func (s serviceCacheKV) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	cas, ok := s.c.(interface {
+		CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error
+	})
+	if !ok {
+		return types.ErrNotSupported
+	}
+	return libCacheErr(cas.CompareAndSwap(ctx, key, old, value, ttl))
+}
+
+// Config contains the fields of a checkpoint store.
+type Config struct {
+	Cache    string
+	Key      string
+	Interval time.Duration
+	Fencing  bool
+}
+
+// ConfigFromParsed extracts the config of a checkpoint store from a parsed
+// config, where the cache and key are read from the provided field names and
+// the interval and fencing fields are read when they are part of the spec.
+func ConfigFromParsed(conf *service.ParsedConfig, cacheField, keyField string) (c Config, err error) {
+	if c.Cache, err = conf.FieldString(cacheField); err != nil {
+		return
+	}
+	if c.Key, err = conf.FieldString(keyField); err != nil {
+		return
+	}
+	if conf.Contains(intervalField) {
+		var intervalStr string
+		if intervalStr, err = conf.FieldString(intervalField); err != nil {
+			return
+		}
+		if intervalStr != "" {
+			if c.Interval, err = time.ParseDuration(intervalStr); err != nil {
+				err = fmt.Errorf("failed to parse %v: %w", intervalField, err)
+				return
+			}
+		}
+	}
+	if conf.Contains(fencingField) {
+		if c.Fencing, err = conf.FieldBool(fencingField); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// fencedRecord is the format of checkpoints stored with a fencing token.
+type fencedRecord struct {
+	Fence uint64 `json:"fence"`
+	Value []byte `json:"value"`
+}
+
+// Store reads and writes the checkpoint of an input within a cache resource.
+// When no cache is configured reads never find a checkpoint and writes are
+// ignored.
+//
+// This component is safe to use concurrently across goroutines.
+type Store struct {
+	conf  Config
+	cache *checkpoint.Cache
+	log   *service.Logger
+
+	mut        sync.Mutex
+	fence      uint64
+	stored     []byte
+	pending    []byte
+	hasPending bool
+
+	loopOnce   sync.Once
+	loopCancel func()
+	loopDone   chan struct{}
+}
+
+// New creates a checkpoint store from a config.
+func New(conf Config, access CacheAccessor, log *service.Logger) *Store {
+	s := &Store{
+		conf: conf,
+		log:  log,
+	}
+	if conf.Cache != "" {
+		s.cache = NewCache(access, conf.Cache)
+	}
+	return s
+}
+
+// Key returns the key that checkpoints are stored under.
+func (s *Store) Key() string {
+	return s.conf.Key
+}
+
+// Enabled returns whether a cache has been configured for the store.
+func (s *Store) Enabled() bool {
+	return s.conf.Cache != ""
+}
+
+func (s *Store) swap(ctx context.Context, old, value []byte) error {
+	err := s.cache.Swap(ctx, s.conf.Key, old, value)
+	if errors.Is(err, checkpoint.ErrCacheModified) {
+		return ErrFenced
+	}
+	return err
+}
+
+// Read returns the last checkpoint written to the cache, and returns false if
+// no checkpoint exists. When fencing is enabled this also acquires a new
+// fencing token, after which writes from other consumers of the same key are
+// rejected.
+func (s *Store) Read(ctx context.Context) (value []byte, exists bool, err error) {
+	if !s.Enabled() {
+		return nil, false, nil
+	}
+
+	raw, err := s.cache.Get(ctx, s.conf.Key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var record fencedRecord
+	if raw != nil {
+		// Checkpoints written without fencing are stored unmodified.
+		if jerr := json.Unmarshal(raw, &record); jerr != nil || record.Fence == 0 {
+			record = fencedRecord{Value: raw}
+		}
+		value, exists = record.Value, true
+	}
+	if !s.conf.Fencing {
+		return
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	record.Fence++
+	acquired, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = s.swap(ctx, raw, acquired); err != nil {
+		if errors.Is(err, ErrFenced) {
+			err = errors.New("checkpoint was modified while acquiring a fencing token")
+		}
+		return nil, false, err
+	}
+	s.fence = record.Fence
+	s.stored = acquired
+	return
+}
+
+// Commit writes a checkpoint to the cache. When an interval is configured the
+// checkpoint is instead written at the end of the current interval, unless it
+// is replaced by a later call, and an error writing it is logged.
+//
+// When fencing is enabled and the key has been acquired by another consumer
+// since the last call to Read then ErrFenced is returned.
+func (s *Store) Commit(ctx context.Context, value []byte) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.conf.Interval > 0 {
+		s.pending, s.hasPending = value, true
+		s.loopOnce.Do(s.startLoop)
+		return nil
+	}
+	return s.write(ctx, value)
+}
+
+// write a checkpoint, must be called with the lock held.
+func (s *Store) write(ctx context.Context, value []byte) error {
+	if !s.conf.Fencing {
+		return s.cache.Set(ctx, s.conf.Key, value)
+	}
+
+	if s.fence == 0 {
+		return errors.New("a fencing token must be acquired by reading the checkpoint before committing")
+	}
+	record, err := json.Marshal(fencedRecord{Fence: s.fence, Value: value})
+	if err != nil {
+		return err
+	}
+	if err := s.swap(ctx, s.stored, record); err != nil {
+		return err
+	}
+	s.stored = record
+	return nil
+}
+
+// flush writes the pending checkpoint, must be called with the lock held.
+func (s *Store) flush(ctx context.Context) error {
+	if !s.hasPending {
+		return nil
+	}
+	if err := s.write(ctx, s.pending); err != nil {
+		return err
+	}
+	s.pending, s.hasPending = nil, false
+	return nil
+}
+
+func (s *Store) startLoop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.loopCancel = cancel
+	s.loopDone = make(chan struct{})
+
+	go func() {
+		defer close(s.loopDone)
+
+		ticker := time.NewTicker(s.conf.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			s.mut.Lock()
+			if err := s.flush(ctx); err != nil && ctx.Err() == nil {
+				s.log.Errorf("Failed to write checkpoint: %v", err)
+			}
+			s.mut.Unlock()
+		}
+	}()
+}
+
+// Close stops any background writes and writes the latest pending checkpoint.
+func (s *Store) Close(ctx context.Context) error {
+	s.mut.Lock()
+	cancel, done := s.loopCancel, s.loopDone
+	s.mut.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.flush(ctx)
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCache struct {
+	items map[string][]byte
+}
+
+func (c *testCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, exists := c.items[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *testCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.items[key] = value
+	return nil
+}
+
+func (c *testCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if _, exists := c.items[key]; exists {
+		return service.ErrKeyAlreadyExists
+	}
+	c.items[key] = value
+	return nil
+}
+
+func (c *testCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	current, exists := c.items[key]
+	if old == nil {
+		if exists {
+			return service.ErrKeyAlreadyExists
+		}
+	} else if !exists {
+		return service.ErrKeyNotFound
+	} else if !bytes.Equal(current, old) {
+		return service.ErrKeyValueMismatch
+	}
+	c.items[key] = value
+	return nil
+}
+
+func (c *testCache) Delete(ctx context.Context, key string) error {
+	delete(c.items, key)
+	return nil
+}
+
+func (c *testCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func testAccessor(cache *testCache) CacheAccessor {
+	return func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		if name != "foo" {
+			return errors.New("cache not found")
+		}
+		fn(cache)
+		return nil
+	}
+}
+
+func TestStoreConfig(t *testing.T) {
+	spec := service.NewConfigSpec().
+		Field(service.NewStringField("cache")).
+		Field(service.NewStringField("key")).
+		Field(IntervalField()).
+		Field(FencingField())
+
+	conf, err := spec.ParseYAML(`
+cache: foo
+key: bar
+checkpoint_interval: 5s
+checkpoint_fencing: true
+`, nil)
+	require.NoError(t, err)
+
+	c, err := ConfigFromParsed(conf, "cache", "key")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Cache:    "foo",
+		Key:      "bar",
+		Interval: time.Second * 5,
+		Fencing:  true,
+	}, c)
+}
+
+func TestStoreDisabled(t *testing.T) {
+	s := New(Config{}, nil, nil)
+	assert.False(t, s.Enabled())
+
+	_, exists, err := s.Read(context.Background())
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, s.Commit(context.Background(), []byte("foo")))
+	require.NoError(t, s.Close(context.Background()))
+}
+
+func TestStoreUnfenced(t *testing.T) {
+	ctx := context.Background()
+	cache := &testCache{items: map[string][]byte{}}
+
+	s := New(Config{Cache: "foo", Key: "bar"}, testAccessor(cache), nil)
+
+	_, exists, err := s.Read(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, s.Commit(ctx, []byte("first")))
+	assert.Equal(t, "first", string(cache.items["bar"]))
+
+	value, exists, err := s.Read(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "first", string(value))
+}
+
+func TestStoreFencing(t *testing.T) {
+	ctx := context.Background()
+	cache := &testCache{items: map[string][]byte{
+		"bar": []byte("legacy"),
+	}}
+	conf := Config{Cache: "foo", Key: "bar", Fencing: true}
+
+	first := New(conf, testAccessor(cache), nil)
+	require.EqualError(t, first.Commit(ctx, []byte("nope")), "a fencing token must be acquired by reading the checkpoint before committing")
+
+	value, exists, err := first.Read(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "legacy", string(value))
+
+	require.NoError(t, first.Commit(ctx, []byte("from first")))
+
+	second := New(conf, testAccessor(cache), nil)
+	value, exists, err = second.Read(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "from first", string(value))
+
+	require.NoError(t, second.Commit(ctx, []byte("from second")))
+
+	// The first consumer is now stale and must not overwrite the checkpoint.
+	assert.Equal(t, ErrFenced, first.Commit(ctx, []byte("stale")))
+
+	value, _, err = New(Config{Cache: "foo", Key: "bar"}, testAccessor(cache), nil).Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "from second", string(value))
+}
+
+func TestStoreInterval(t *testing.T) {
+	ctx := context.Background()
+	cache := &testCache{items: map[string][]byte{}}
+
+	s := New(Config{Cache: "foo", Key: "bar", Interval: time.Hour}, testAccessor(cache), nil)
+
+	require.NoError(t, s.Commit(ctx, []byte("first")))
+	require.NoError(t, s.Commit(ctx, []byte("second")))
+	assert.NotContains(t, cache.items, "bar")
+
+	require.NoError(t, s.Close(ctx))
+	assert.Equal(t, "second", string(cache.items["bar"]))
+}
@@ -0,0 +1,131 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// CompressionDocs is a static field documentation for the compression of data
+// written by outputs.
+var CompressionDocs = docs.FieldAdvanced(
+	"compression", "An optional compression algorithm to apply to the data written, which can be reversed when consuming it with the codec of the same name.",
+).HasAnnotatedOptions(
+	"gzip", "Compress data with gzip.",
+	"lz4", "Compress data with the lz4 frame format.",
+	"none", "Write data uncompressed.",
+	"snappy", "Compress data with the snappy framing format.",
+	"zstd", "Compress data with zstd.",
+)
+
+//------------------------------------------------------------------------------
+
+// CompressionWriter compresses the data written to it into an underlying
+// writer, which is also closed when the compression writer is closed.
+type CompressionWriter interface {
+	io.WriteCloser
+
+	// Flush writes any data that has been buffered by the compression
+	// algorithm into the underlying writer.
+	Flush() error
+}
+
+// CompressionWriterConstructor creates a compression writer from an
+// io.WriteCloser.
+type CompressionWriterConstructor func(io.WriteCloser) (CompressionWriter, error)
+
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+type compressionWriter struct {
+	comp flushWriteCloser
+	w    io.WriteCloser
+}
+
+func (c *compressionWriter) Write(p []byte) (int, error) {
+	return c.comp.Write(p)
+}
+
+func (c *compressionWriter) Flush() error {
+	return c.comp.Flush()
+}
+
+func (c *compressionWriter) Close() error {
+	err := c.comp.Close()
+	if cerr := c.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// GetCompressionWriter returns a constructor that creates compression writers
+// for an algorithm, or nil if the algorithm is `none`.
+func GetCompressionWriter(algorithm string) (CompressionWriterConstructor, error) {
+	var ctor func(io.Writer) (flushWriteCloser, error)
+	switch algorithm {
+	case "", "none":
+		return nil, nil
+	case "gzip":
+		ctor = func(w io.Writer) (flushWriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}
+	case "lz4":
+		ctor = func(w io.Writer) (flushWriteCloser, error) {
+			return lz4.NewWriter(w), nil
+		}
+	case "snappy":
+		ctor = func(w io.Writer) (flushWriteCloser, error) {
+			return snappy.NewBufferedWriter(w), nil
+		}
+	case "zstd":
+		ctor = func(w io.Writer) (flushWriteCloser, error) {
+			return zstd.NewWriter(w)
+		}
+	default:
+		return nil, fmt.Errorf("compression algorithm not recognised: %v", algorithm)
+	}
+	return func(w io.WriteCloser) (CompressionWriter, error) {
+		comp, err := ctor(w)
+		if err != nil {
+			return nil, err
+		}
+		return &compressionWriter{comp: comp, w: w}, nil
+	}, nil
+}
+
+type nopCloserBuffer struct {
+	bytes.Buffer
+}
+
+func (n *nopCloserBuffer) Close() error {
+	return nil
+}
+
+// CompressBytes compresses a slice of bytes in full with a compression writer
+// constructor, returning the bytes unchanged if the constructor is nil.
+func CompressBytes(ctor CompressionWriterConstructor, b []byte) ([]byte, error) {
+	if ctor == nil {
+		return b, nil
+	}
+	buf := &nopCloserBuffer{}
+	w, err := ctor(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
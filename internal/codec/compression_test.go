@@ -0,0 +1,110 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func compressTestData(t *testing.T, algorithm, data string) []byte {
+	t.Helper()
+
+	ctor, err := GetCompressionWriter(algorithm)
+	require.NoError(t, err)
+	require.NotNil(t, ctor)
+
+	b, err := CompressBytes(ctor, []byte(data))
+	require.NoError(t, err)
+	assert.NotEqual(t, data, string(b))
+	return b
+}
+
+func TestCompressionReaders(t *testing.T) {
+	for _, algorithm := range []string{"gzip", "lz4", "snappy", "zstd"} {
+		algorithm := algorithm
+		t.Run(algorithm, func(t *testing.T) {
+			data := compressTestData(t, algorithm, "foo\nbar\nbaz")
+			testReaderSuite(t, algorithm+"/lines", "", data, "foo", "bar", "baz")
+		})
+	}
+}
+
+func TestCompressionWriterFlush(t *testing.T) {
+	for _, algorithm := range []string{"gzip", "lz4", "snappy", "zstd"} {
+		algorithm := algorithm
+		t.Run(algorithm, func(t *testing.T) {
+			ctor, err := GetCompressionWriter(algorithm)
+			require.NoError(t, err)
+
+			buf := &closeRecorder{}
+			w, err := ctor(buf)
+			require.NoError(t, err)
+
+			_, err = w.Write([]byte("foo\n"))
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+
+			// Flushed data must be readable before the writer is closed.
+			flushed := append([]byte(nil), buf.Bytes()...)
+			rCtor, err := GetReader(algorithm+"/lines", NewReaderConfig())
+			require.NoError(t, err)
+
+			r, err := rCtor("", noopCloser{bytes.NewReader(flushed), false}, func(ctx context.Context, err error) error {
+				return nil
+			})
+			require.NoError(t, err)
+
+			p, _, err := r.Next(context.Background())
+			require.NoError(t, err)
+			require.Len(t, p, 1)
+			assert.Equal(t, "foo", string(p[0].Get()))
+			require.NoError(t, r.Close(context.Background()))
+
+			_, err = w.Write([]byte("bar\n"))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			assert.True(t, buf.closed)
+		})
+	}
+}
+
+func TestCompressionNone(t *testing.T) {
+	ctor, err := GetCompressionWriter("none")
+	require.NoError(t, err)
+	assert.Nil(t, ctor)
+
+	data, err := CompressBytes(ctor, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(data))
+
+	_, err = GetCompressionWriter("nope")
+	require.EqualError(t, err, "compression algorithm not recognised: nope")
+}
+
+func TestDecompressionClosesSource(t *testing.T) {
+	source := &closeRecorder{}
+	_, _ = source.Write(compressTestData(t, "gzip", "foo"))
+
+	ctor, err := GetReader("gzip/all-bytes", NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor("", source, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.Close(context.Background()))
+	assert.True(t, source.closed)
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -19,6 +20,11 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/linkedin/goavro/v2"
+	"github.com/pierrec/lz4/v4"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 // ReaderDocs is a static field documentation for input codecs.
@@ -27,15 +33,21 @@ var ReaderDocs = docs.FieldCommon(
 ).HasAnnotatedOptions(
 	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the `gzip/tar` codec. Defaults to all-bytes.",
 	"all-bytes", "Consume the entire file as a single binary message.",
+	"avro-ocf", "Consume each record of an [Avro Object Container File](https://avro.apache.org/docs/current/spec.html#Object+Container+Files) as a JSON document in the Avro JSON encoding, using the schema embedded within the file.",
 	"chunker:x", "Consume the file in chunks of a given number of bytes.",
 	"csv", "Consume structured rows as comma separated values, the first row must be a header row.",
 	"csv:x", "Consume structured rows as values separated by a custom delimiter, the first row must be a header row. The custom delimiter must be a single character, e.g. the codec `csv:|` would consume a pipe delimited file.",
 	"delim:x", "Consume the file in segments divided by a custom delimiter.",
 	"gzip", "Decompress a gzip file, this codec should precede another codec, e.g. `gzip/all-bytes`, `gzip/tar`, `gzip/csv`, etc.",
 	"lines", "Consume the file in segments divided by linebreaks.",
+	"lz4", "Decompress an lz4 file in the frame format, this codec should precede another codec, e.g. `lz4/lines`.",
+	"msgpack", "Consume a stream of concatenated [MessagePack](https://msgpack.org/) values, where each value is converted into a JSON document.",
 	"multipart", "Consumes the output of another codec and batches messages together. A batch ends when an empty message is consumed. For example, the codec `lines/multipart` could be used to consume multipart messages where an empty line indicates the end of each batch.",
+	"protobuf-delimited", "Consume a stream of protobuf messages that are each prefixed with their length as a varint, as written by `writeDelimitedTo` in the Java protobuf library, where each message is consumed in its binary form and can be decoded with the [`protobuf` processor](/docs/components/processors/protobuf).",
 	"regex:(?m)^\\d\\d:\\d\\d:\\d\\d", "Consume the file in segments divided by regular expression.",
+	"snappy", "Decompress a snappy file in the framing format, this codec should precede another codec, e.g. `snappy/lines`.",
 	"tar", "Parse the file as a tar archive, and consume each file of the archive as a message.",
+	"zstd", "Decompress a zstd file, this codec should precede another codec, e.g. `zstd/lines`, `zstd/csv`, etc.",
 )
 
 //------------------------------------------------------------------------------
@@ -180,15 +192,52 @@ func chainedReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 	return partCtor, nil
 }
 
+// decompressReader reads decompressed data from a source, closing both the
+// decompressor and the source when it is closed.
+type decompressReader struct {
+	io.Reader
+	closeFn func()
+	source  io.Closer
+}
+
+func (d *decompressReader) Close() error {
+	if d.closeFn != nil {
+		d.closeFn()
+	}
+	return d.source.Close()
+}
+
 func ioReader(codec string, conf ReaderConfig) (ioReaderConstructor, bool) {
-	if codec == "gzip" {
+	switch codec {
+	case "gzip":
 		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
 			g, err := gzip.NewReader(r)
 			if err != nil {
 				r.Close()
 				return nil, err
 			}
-			return g, nil
+			return &decompressReader{
+				Reader:  g,
+				closeFn: func() { g.Close() },
+				source:  r,
+			}, nil
+		}, true
+	case "lz4":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			return &decompressReader{Reader: lz4.NewReader(r), source: r}, nil
+		}, true
+	case "snappy":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			return &decompressReader{Reader: snappy.NewReader(r), source: r}, nil
+		}, true
+	case "zstd":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			z, err := zstd.NewReader(r)
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			return &decompressReader{Reader: z, closeFn: z.Close, source: r}, nil
 		}, true
 	}
 	return nil, false
@@ -219,6 +268,18 @@ func partReader(codec string, conf ReaderConfig) (ReaderConstructor, bool, error
 		}, true, nil
 	case "tar":
 		return newTarReader, true, nil
+	case "avro-ocf":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newAvroOCFReader(r, fn)
+		}, true, nil
+	case "msgpack":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newMsgPackReader(r, fn)
+		}, true, nil
+	case "protobuf-delimited":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newProtobufDelimitedReader(conf, r, fn)
+		}, true, nil
 	}
 	if strings.HasPrefix(codec, "delim:") {
 		by := strings.TrimPrefix(codec, "delim:")
@@ -295,6 +356,8 @@ func autoCodec(conf ReaderConfig) ReaderConstructor {
 			codec = "tar"
 		case ".tgz":
 			codec = "gzip/tar"
+		case ".avro":
+			codec = "avro-ocf"
 		}
 		if strings.HasSuffix(path, ".tar.gzip") {
 			codec = "gzip/tar"
@@ -924,3 +987,244 @@ func (a *regexReader) Close(ctx context.Context) error {
 	}
 	return a.r.Close()
 }
+
+//------------------------------------------------------------------------------
+
+type avroOCFReader struct {
+	ocf       *goavro.OCFReader
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newAvroOCFReader(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	ocf, err := goavro.NewOCFReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avro container header: %w", err)
+	}
+	return &avroOCFReader{
+		ocf:       ocf,
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *avroOCFReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *avroOCFReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	scanned := a.ocf.Scan()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	var err error
+	if scanned {
+		var datum interface{}
+		if datum, err = a.ocf.Read(); err == nil {
+			var textual []byte
+			if textual, err = a.ocf.Codec().TextualFromNative(nil, datum); err == nil {
+				a.pending++
+				return []types.Part{message.NewPart(textual)}, a.ack, nil
+			}
+		}
+	} else if err = a.ocf.Err(); err == nil {
+		err = io.EOF
+		a.finished = true
+		return nil, nil, err
+	}
+
+	err = fmt.Errorf("failed to read avro record: %w", err)
+	_ = a.sourceAck(ctx, err)
+	return nil, nil, err
+}
+
+func (a *avroOCFReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		_ = a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		_ = a.sourceAck(ctx, nil)
+	}
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type msgPackReader struct {
+	dec       *msgpack.Decoder
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newMsgPackReader(r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	return &msgPackReader{
+		dec:       msgpack.NewDecoder(r),
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *msgPackReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *msgPackReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	v, err := a.dec.DecodeInterface()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if err == nil {
+		p := message.NewPart(nil)
+		if err = p.SetJSON(v); err == nil {
+			a.pending++
+			return []types.Part{p}, a.ack, nil
+		}
+	}
+
+	if err == io.EOF {
+		a.finished = true
+		return nil, nil, err
+	}
+	err = fmt.Errorf("failed to decode msgpack value: %w", err)
+	_ = a.sourceAck(ctx, err)
+	return nil, nil, err
+}
+
+func (a *msgPackReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		_ = a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		_ = a.sourceAck(ctx, nil)
+	}
+	return a.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type protobufDelimitedReader struct {
+	buf       *bufio.Reader
+	maxSize   uint64
+	r         io.ReadCloser
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newProtobufDelimitedReader(conf ReaderConfig, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	return &protobufDelimitedReader{
+		buf:       bufio.NewReader(r),
+		maxSize:   uint64(conf.MaxScanTokenSize),
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *protobufDelimitedReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *protobufDelimitedReader) readMessage() ([]byte, error) {
+	size, err := binary.ReadUvarint(a.buf)
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read message length: %w", err)
+	}
+	if size > a.maxSize {
+		return nil, fmt.Errorf("message length %v exceeds the max buffer size of %v", size, a.maxSize)
+	}
+
+	msgBytes := make([]byte, size)
+	if _, err = io.ReadFull(a.buf, msgBytes); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return msgBytes, nil
+}
+
+func (a *protobufDelimitedReader) Next(ctx context.Context) ([]types.Part, ReaderAckFn, error) {
+	msgBytes, err := a.readMessage()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if err == nil {
+		a.pending++
+		return []types.Part{message.NewPart(msgBytes)}, a.ack, nil
+	}
+
+	if err == io.EOF {
+		a.finished = true
+	} else {
+		_ = a.sourceAck(ctx, err)
+	}
+	return nil, nil, err
+}
+
+func (a *protobufDelimitedReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		_ = a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		_ = a.sourceAck(ctx, nil)
+	}
+	return a.r.Close()
+}
@@ -5,15 +5,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/linkedin/goavro/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type noopCloser struct {
@@ -769,3 +773,115 @@ func TestRegexpSplitReader(t *testing.T) {
 	data = []byte("")
 	testReaderSuite(t, "regex:split", "", data)
 }
+
+func TestAvroOCFReader(t *testing.T) {
+	var ocfBuf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W: &ocfBuf,
+		Schema: `{
+  "type": "record",
+  "name": "user",
+  "fields": [
+    { "name": "name", "type": "string" },
+    { "name": "age", "type": ["null", "int"] }
+  ]
+}`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Append([]interface{}{
+		map[string]interface{}{"name": "foo", "age": goavro.Union("int", 21)},
+		map[string]interface{}{"name": "bar", "age": nil},
+	}))
+
+	testReaderSuite(
+		t, "avro-ocf", "", ocfBuf.Bytes(),
+		`{"name":"foo","age":{"int":21}}`,
+		`{"name":"bar","age":null}`,
+	)
+	testReaderSuite(
+		t, "auto", "users.avro", ocfBuf.Bytes(),
+		`{"name":"foo","age":{"int":21}}`,
+		`{"name":"bar","age":null}`,
+	)
+
+	_, err = newAvroOCFReader(noopCloser{bytes.NewReader([]byte("not avro")), false}, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read avro container header")
+}
+
+func TestMsgPackReader(t *testing.T) {
+	var data []byte
+	for _, v := range []interface{}{
+		map[string]interface{}{"foo": "bar"},
+		[]interface{}{"a", 1},
+		"baz",
+	} {
+		b, err := msgpack.Marshal(v)
+		require.NoError(t, err)
+		data = append(data, b...)
+	}
+	testReaderSuite(t, "msgpack", "", data, `{"foo":"bar"}`, `["a",1]`, `"baz"`)
+
+	testReaderSuite(t, "msgpack", "", nil)
+}
+
+func TestProtobufDelimitedReader(t *testing.T) {
+	var data []byte
+	for _, msg := range []string{"foo", "", strings.Repeat("x", 200)} {
+		lenBytes := make([]byte, binary.MaxVarintLen64)
+		data = append(data, lenBytes[:binary.PutUvarint(lenBytes, uint64(len(msg)))]...)
+		data = append(data, msg...)
+	}
+	testReaderSuite(t, "protobuf-delimited", "", data, "foo", "", strings.Repeat("x", 200))
+
+	testReaderSuite(t, "protobuf-delimited", "", nil)
+}
+
+func TestProtobufDelimitedReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "truncated message",
+			data: []byte{5, 'f', 'o', 'o'},
+			err:  "failed to read message: unexpected EOF",
+		},
+		{
+			name: "truncated length",
+			data: []byte{0x80},
+			err:  "failed to read message length: unexpected EOF",
+		},
+		{
+			name: "too large",
+			data: []byte{0x80, 0x02},
+			err:  "message length 256 exceeds the max buffer size of 100",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewReaderConfig()
+			conf.MaxScanTokenSize = 100
+
+			ctor, err := GetReader("protobuf-delimited", conf)
+			require.NoError(t, err)
+
+			var ackErr error
+			r, err := ctor("", noopCloser{bytes.NewReader(test.data), false}, func(ctx context.Context, err error) error {
+				ackErr = err
+				return nil
+			})
+			require.NoError(t, err)
+
+			_, _, err = r.Next(context.Background())
+			require.EqualError(t, err, test.err)
+			require.EqualError(t, ackErr, test.err)
+			require.NoError(t, r.Close(context.Background()))
+		})
+	}
+}
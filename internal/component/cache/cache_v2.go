@@ -39,11 +39,46 @@ type V2 interface {
 	Close(ctx context.Context) error
 }
 
+// V2GetMulti is an optional interface that can be implemented by a V2 cache in
+// order to retrieve multiple keys within as few requests as possible.
+type V2GetMulti interface {
+	// GetMulti attempts to obtain the values of multiple keys. Keys that do not
+	// exist are omitted from the resulting map.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// V2GetWithTTL is an optional interface that can be implemented by a V2 cache
+// in order to report the remaining TTL of items.
+type V2GetWithTTL interface {
+	// GetWithTTL attempts to obtain the value of a key along with its remaining
+	// TTL, which is nil if the item does not expire.
+	GetWithTTL(ctx context.Context, key string) ([]byte, *time.Duration, error)
+}
+
+// V2CompareAndSwap is an optional interface that can be implemented by a V2
+// cache in order to support atomic compare and swap operations.
+type V2CompareAndSwap interface {
+	// CompareAndSwap attempts to set the value of a key only if its current
+	// value matches old. If old is nil then the key must not already exist.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error
+}
+
+// V2GetAndDelete is an optional interface that can be implemented by a V2 cache
+// in order to support atomically retrieving and removing a key.
+type V2GetAndDelete interface {
+	// GetAndDelete attempts to obtain the value of a key and then removes it.
+	GetAndDelete(ctx context.Context, key string) ([]byte, error)
+}
+
 //------------------------------------------------------------------------------
 
 // Implements types.Cache
 type v2ToV1Cache struct {
 	c   V2
+	cgm V2GetMulti
+	cgt V2GetWithTTL
+	cas V2CompareAndSwap
+	cgd V2GetAndDelete
 	sig *shutdown.Signaller
 
 	mGetNotFound metrics.StatCounter
@@ -51,10 +86,17 @@ type v2ToV1Cache struct {
 	mGetSuccess  metrics.StatCounter
 	mGetLatency  metrics.StatTimer
 
+	// Batch sizes are recorded as timings so that their distribution is kept.
+	mGetMultiLatency   metrics.StatTimer
+	mGetMultiBatchSize metrics.StatTimer
+
 	mSetFailed  metrics.StatCounter
 	mSetSuccess metrics.StatCounter
 	mSetLatency metrics.StatTimer
 
+	mSetMultiLatency   metrics.StatTimer
+	mSetMultiBatchSize metrics.StatTimer
+
 	mAddDupe    metrics.StatCounter
 	mAddFailed  metrics.StatCounter
 	mAddSuccess metrics.StatCounter
@@ -63,22 +105,37 @@ type v2ToV1Cache struct {
 	mDelFailed  metrics.StatCounter
 	mDelSuccess metrics.StatCounter
 	mDelLatency metrics.StatTimer
+
+	mCASMismatch metrics.StatCounter
+	mCASFailed   metrics.StatCounter
+	mCASSuccess  metrics.StatCounter
+	mCASLatency  metrics.StatTimer
 }
 
 // NewV2ToV1Cache wraps a cache.V2 with a struct that implements types.Cache.
 func NewV2ToV1Cache(c V2, stats metrics.Type) types.Cache {
+	cgm, _ := c.(V2GetMulti)
+	cgt, _ := c.(V2GetWithTTL)
+	cas, _ := c.(V2CompareAndSwap)
+	cgd, _ := c.(V2GetAndDelete)
 	return &v2ToV1Cache{
-		c: c, sig: shutdown.NewSignaller(),
+		c: c, cgm: cgm, cgt: cgt, cas: cas, cgd: cgd, sig: shutdown.NewSignaller(),
 
 		mGetNotFound: stats.GetCounter("get.not_found"),
 		mGetFailed:   stats.GetCounter("get.failed"),
 		mGetSuccess:  stats.GetCounter("get.success"),
 		mGetLatency:  stats.GetTimer("get.latency"),
 
+		mGetMultiLatency:   stats.GetTimer("get_multi.latency"),
+		mGetMultiBatchSize: stats.GetTimer("get_multi.batch_size"),
+
 		mSetFailed:  stats.GetCounter("set.failed"),
 		mSetSuccess: stats.GetCounter("set.success"),
 		mSetLatency: stats.GetTimer("set.latency"),
 
+		mSetMultiLatency:   stats.GetTimer("set_multi.latency"),
+		mSetMultiBatchSize: stats.GetTimer("set_multi.batch_size"),
+
 		mAddDupe:    stats.GetCounter("add.duplicate"),
 		mAddFailed:  stats.GetCounter("add.failed"),
 		mAddSuccess: stats.GetCounter("add.success"),
@@ -87,12 +144,17 @@ func NewV2ToV1Cache(c V2, stats metrics.Type) types.Cache {
 		mDelFailed:  stats.GetCounter("delete.failed"),
 		mDelSuccess: stats.GetCounter("delete.success"),
 		mDelLatency: stats.GetTimer("delete.latency"),
+
+		mCASMismatch: stats.GetCounter("cas.mismatch"),
+		mCASFailed:   stats.GetCounter("cas.failed"),
+		mCASSuccess:  stats.GetCounter("cas.success"),
+		mCASLatency:  stats.GetTimer("cas.latency"),
 	}
 }
 
-func (a *v2ToV1Cache) Get(key string) ([]byte, error) {
-	started := time.Now()
-	b, err := a.c.Get(context.Background(), key)
+// recordGet updates the metrics of a get operation based on its result, where
+// a missing key is counted as not found rather than as a failure.
+func (a *v2ToV1Cache) recordGet(started time.Time, err error) {
 	a.mGetLatency.Timing(int64(time.Since(started)))
 	if err != nil {
 		if errors.Is(err, types.ErrKeyNotFound) {
@@ -103,9 +165,61 @@ func (a *v2ToV1Cache) Get(key string) ([]byte, error) {
 	} else {
 		a.mGetSuccess.Incr(1)
 	}
+}
+
+func (a *v2ToV1Cache) Get(key string) ([]byte, error) {
+	started := time.Now()
+	b, err := a.c.Get(context.Background(), key)
+	a.recordGet(started, err)
 	return b, err
 }
 
+func (a *v2ToV1Cache) GetWithTTL(key string) ([]byte, *time.Duration, error) {
+	if a.cgt == nil {
+		b, err := a.Get(key)
+		return b, nil, err
+	}
+	started := time.Now()
+	b, ttl, err := a.cgt.GetWithTTL(context.Background(), key)
+	a.recordGet(started, err)
+	return b, ttl, err
+}
+
+func (a *v2ToV1Cache) GetMulti(keys ...string) (map[string][]byte, error) {
+	a.mGetMultiBatchSize.Timing(int64(len(keys)))
+	started := time.Now()
+	var values map[string][]byte
+	var err error
+	if a.cgm != nil {
+		values, err = a.cgm.GetMulti(context.Background(), keys...)
+	} else {
+		values = make(map[string][]byte, len(keys))
+		for _, k := range keys {
+			var v []byte
+			if v, err = a.c.Get(context.Background(), k); err != nil {
+				if !errors.Is(err, types.ErrKeyNotFound) {
+					break
+				}
+				err = nil
+				continue
+			}
+			values[k] = v
+		}
+	}
+	latency := int64(time.Since(started))
+	a.mGetLatency.Timing(latency)
+	a.mGetMultiLatency.Timing(latency)
+	if err != nil {
+		// A failed batch is a single failed request rather than a failure of
+		// each key.
+		a.mGetFailed.Incr(1)
+		return nil, err
+	}
+	a.mGetSuccess.Incr(int64(len(values)))
+	a.mGetNotFound.Incr(int64(len(keys) - len(values)))
+	return values, nil
+}
+
 func (a *v2ToV1Cache) Set(key string, value []byte) error {
 	started := time.Now()
 	err := a.c.Set(context.Background(), key, value, nil)
@@ -138,21 +252,16 @@ func (a *v2ToV1Cache) SetMulti(items map[string][]byte) error {
 		}
 	}
 
-	started := time.Now()
-	err := a.c.SetMulti(context.Background(), bItems)
-	a.mSetLatency.Timing(int64(time.Since(started)))
-	if err != nil {
-		a.mSetFailed.Incr(int64(len(items)))
-	} else {
-		a.mSetSuccess.Incr(int64(len(items)))
-	}
-	return err
+	return a.SetMultiWithTTL(bItems)
 }
 
 func (a *v2ToV1Cache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	a.mSetMultiBatchSize.Timing(int64(len(items)))
 	started := time.Now()
 	err := a.c.SetMulti(context.Background(), items)
-	a.mSetLatency.Timing(int64(time.Since(started)))
+	latency := int64(time.Since(started))
+	a.mSetLatency.Timing(latency)
+	a.mSetMultiLatency.Timing(latency)
 	if err != nil {
 		a.mSetFailed.Incr(int64(len(items)))
 	} else {
@@ -205,6 +314,44 @@ func (a *v2ToV1Cache) Delete(key string) error {
 	return err
 }
 
+func (a *v2ToV1Cache) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	if a.cas == nil {
+		return types.ErrNotSupported
+	}
+	started := time.Now()
+	err := a.cas.CompareAndSwap(context.Background(), key, old, value, ttl)
+	a.mCASLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		if errors.Is(err, types.ErrKeyValueMismatch) || errors.Is(err, types.ErrKeyNotFound) || errors.Is(err, types.ErrKeyAlreadyExists) {
+			a.mCASMismatch.Incr(1)
+		} else {
+			a.mCASFailed.Incr(1)
+		}
+	} else {
+		a.mCASSuccess.Incr(1)
+	}
+	return err
+}
+
+// GetAndDelete obtains the value of a key and then removes it, which is only
+// atomic when the underlying cache implements V2GetAndDelete.
+func (a *v2ToV1Cache) GetAndDelete(key string) ([]byte, error) {
+	if a.cgd == nil {
+		b, err := a.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		return b, a.Delete(key)
+	}
+	started := time.Now()
+	b, err := a.cgd.GetAndDelete(context.Background(), key)
+	a.recordGet(started, err)
+	if err == nil {
+		a.mDelSuccess.Incr(1)
+	}
+	return b, err
+}
+
 func (a *v2ToV1Cache) CloseAsync() {
 	go func() {
 		if err := a.c.Close(context.Background()); err == nil {
@@ -359,3 +359,92 @@ func (c *closableCacheType) CloseAsync() {
 func (c *closableCacheType) WaitForClose(t time.Duration) error {
 	return nil
 }
+
+func TestCacheAirGapMetrics(t *testing.T) {
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {
+				b: []byte("bar"),
+			},
+		},
+	}
+	stats := metrics.NewLocal()
+	agrl := NewV2ToV1Cache(rl, stats)
+
+	_, err := agrl.Get("foo")
+	assert.NoError(t, err)
+
+	_, err = agrl.Get("not exist")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	_, err = agrl.(types.CacheWithGetMulti).GetMulti("foo", "not exist", "also not exist")
+	assert.NoError(t, err)
+
+	err = agrl.SetMulti(map[string][]byte{
+		"first":  []byte("bar"),
+		"second": []byte("baz"),
+	})
+	assert.NoError(t, err)
+
+	counters := stats.GetCounters()
+	assert.Equal(t, int64(2), counters["get.success"])
+	assert.Equal(t, int64(3), counters["get.not_found"])
+	assert.Equal(t, int64(2), counters["set.success"])
+
+	timings := stats.GetTimings()
+	assert.Contains(t, timings, "get_multi.latency")
+	assert.Contains(t, timings, "set_multi.latency")
+	assert.Equal(t, int64(3), timings["get_multi.batch_size"])
+	assert.Equal(t, int64(2), timings["set_multi.batch_size"])
+}
+
+type timingsRecorder struct {
+	metrics.Type
+
+	timings map[string][]int64
+}
+
+type recordedTimer struct {
+	path string
+	rec  *timingsRecorder
+}
+
+func (r recordedTimer) Timing(delta int64) error {
+	r.rec.timings[r.path] = append(r.rec.timings[r.path], delta)
+	return nil
+}
+
+func (r *timingsRecorder) GetTimer(path string) metrics.StatTimer {
+	return recordedTimer{path: path, rec: r}
+}
+
+func TestCacheAirGapBatchSizeMetrics(t *testing.T) {
+	rl := &closableCache{
+		m: map[string]testCacheItem{},
+	}
+	stats := &timingsRecorder{
+		Type:    metrics.Noop(),
+		timings: map[string][]int64{},
+	}
+	agrl := NewV2ToV1Cache(rl, stats)
+
+	_, err := agrl.(types.CacheWithGetMulti).GetMulti("foo", "bar", "baz")
+	assert.NoError(t, err)
+
+	_, err = agrl.(types.CacheWithGetMulti).GetMulti("foo")
+	assert.NoError(t, err)
+
+	err = agrl.SetMulti(map[string][]byte{
+		"first":  []byte("bar"),
+		"second": []byte("baz"),
+	})
+	assert.NoError(t, err)
+
+	err = agrl.SetMulti(map[string][]byte{
+		"first": []byte("bar"),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{3, 1}, stats.timings["get_multi.batch_size"])
+	assert.Equal(t, []int64{2, 1}, stats.timings["set_multi.batch_size"])
+}
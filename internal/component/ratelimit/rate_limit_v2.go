@@ -23,6 +23,29 @@ type V2 interface {
 	Close(ctx context.Context) error
 }
 
+// V2AccessN is an optional interface that can be implemented by a V2 rate limit
+// in order to consume multiple units of access within a single request.
+type V2AccessN interface {
+	// AccessN attempts to consume n units of access. Either all units are
+	// consumed and a zero duration is returned, or no units are consumed and a
+	// reasonable length of time to wait before requesting again is returned.
+	AccessN(ctx context.Context, n int) (time.Duration, error)
+}
+
+// V2Feedback is an optional interface that can be implemented by a V2 rate
+// limit in order to adapt according to the responses of the protected resource.
+// Components that are able to observe those responses, such as the http_client
+// output and processor, call one of its methods after each request.
+type V2Feedback interface {
+	// Throttled reports that the protected resource rejected a request due to
+	// being overloaded, along with the duration it asked callers to wait before
+	// trying again, which is zero when it was not specified.
+	Throttled(retryAfter time.Duration)
+
+	// Succeeded reports that the protected resource accepted a request.
+	Succeeded()
+}
+
 //------------------------------------------------------------------------------
 
 // Implements types.RateLimit
@@ -36,26 +59,43 @@ type v2ToV1RateLimit struct {
 }
 
 // NewV2ToV1RateLimit wraps a ratelimit.V2 with a struct that implements
-// types.RateLimit.
+// types.RateLimit. If the rate limit implements V2AccessN or V2Feedback then
+// the result also implements types.RateLimitWithAccessN or
+// types.RateLimitWithFeedback respectively.
 func NewV2ToV1RateLimit(r V2, stats metrics.Type) types.RateLimit {
-	return &v2ToV1RateLimit{
+	rl := &v2ToV1RateLimit{
 		r: r, sig: shutdown.NewSignaller(),
 
 		mChecked: stats.GetCounter("checked"),
 		mLimited: stats.GetCounter("limited"),
 		mErr:     stats.GetCounter("error"),
 	}
+	rn, isN := r.(V2AccessN)
+	rf, isF := r.(V2Feedback)
+	switch {
+	case isN && isF:
+		return &v2ToV1RateLimitNF{&v2ToV1RateLimitN{v2ToV1RateLimit: rl, rn: rn}, rf}
+	case isN:
+		return &v2ToV1RateLimitN{v2ToV1RateLimit: rl, rn: rn}
+	case isF:
+		return &v2ToV1RateLimitF{rl, rf}
+	}
+	return rl
 }
 
 func (r *v2ToV1RateLimit) Access() (time.Duration, error) {
 	r.mChecked.Incr(1)
 	tout, err := r.r.Access(context.Background())
+	r.recordAccess(tout, err)
+	return tout, err
+}
+
+func (r *v2ToV1RateLimit) recordAccess(tout time.Duration, err error) {
 	if err != nil {
 		r.mErr.Incr(1)
 	} else if tout > 0 {
 		r.mLimited.Incr(1)
 	}
-	return tout, err
 }
 
 func (r *v2ToV1RateLimit) CloseAsync() {
@@ -74,3 +114,30 @@ func (r *v2ToV1RateLimit) WaitForClose(tout time.Duration) error {
 	}
 	return nil
 }
+
+//------------------------------------------------------------------------------
+
+// Implements types.RateLimitWithAccessN
+type v2ToV1RateLimitN struct {
+	*v2ToV1RateLimit
+	rn V2AccessN
+}
+
+func (r *v2ToV1RateLimitN) AccessN(n int) (time.Duration, error) {
+	r.mChecked.Incr(1)
+	tout, err := r.rn.AccessN(context.Background(), n)
+	r.recordAccess(tout, err)
+	return tout, err
+}
+
+// Implements types.RateLimitWithFeedback
+type v2ToV1RateLimitF struct {
+	*v2ToV1RateLimit
+	V2Feedback
+}
+
+// Implements types.RateLimitWithAccessN and types.RateLimitWithFeedback
+type v2ToV1RateLimitNF struct {
+	*v2ToV1RateLimitN
+	V2Feedback
+}
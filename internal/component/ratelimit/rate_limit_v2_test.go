@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type closableRateLimit struct {
@@ -35,3 +37,91 @@ func TestRateLimitAirGapShutdown(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, rl.closed)
 }
+
+type accessNRateLimit struct {
+	closableRateLimit
+	lastN int
+}
+
+func (a *accessNRateLimit) AccessN(ctx context.Context, n int) (time.Duration, error) {
+	a.lastN = n
+	if n > 5 {
+		return time.Second, nil
+	}
+	return 0, nil
+}
+
+func TestRateLimitAirGapAccessN(t *testing.T) {
+	agrl := NewV2ToV1RateLimit(&closableRateLimit{}, metrics.Noop())
+	_, ok := agrl.(types.RateLimitWithAccessN)
+	assert.False(t, ok)
+
+	stats := metrics.NewLocal()
+
+	rl := &accessNRateLimit{}
+	agrl = NewV2ToV1RateLimit(rl, stats)
+	rln, ok := agrl.(types.RateLimitWithAccessN)
+	require.True(t, ok)
+
+	tout, err := rln.AccessN(3)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), tout)
+	assert.Equal(t, 3, rl.lastN)
+
+	tout, err = rln.AccessN(10)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, tout)
+	assert.Equal(t, 10, rl.lastN)
+
+	assert.Equal(t, map[string]int64{
+		"checked": 2,
+		"limited": 1,
+		"error":   0,
+	}, stats.GetCounters())
+}
+
+type feedbackRateLimit struct {
+	closableRateLimit
+	throttled []time.Duration
+	succeeded int
+}
+
+func (f *feedbackRateLimit) Throttled(retryAfter time.Duration) {
+	f.throttled = append(f.throttled, retryAfter)
+}
+
+func (f *feedbackRateLimit) Succeeded() {
+	f.succeeded++
+}
+
+type feedbackAccessNRateLimit struct {
+	accessNRateLimit
+}
+
+func (f *feedbackAccessNRateLimit) Throttled(retryAfter time.Duration) {}
+
+func (f *feedbackAccessNRateLimit) Succeeded() {}
+
+func TestRateLimitAirGapFeedback(t *testing.T) {
+	rl := &feedbackRateLimit{}
+	agrl := NewV2ToV1RateLimit(rl, metrics.Noop())
+
+	_, ok := agrl.(types.RateLimitWithAccessN)
+	assert.False(t, ok)
+
+	rlf, ok := agrl.(types.RateLimitWithFeedback)
+	require.True(t, ok)
+
+	rlf.Throttled(time.Second)
+	rlf.Succeeded()
+	assert.Equal(t, []time.Duration{time.Second}, rl.throttled)
+	assert.Equal(t, 1, rl.succeeded)
+
+	rlNF := &feedbackAccessNRateLimit{}
+	agrl = NewV2ToV1RateLimit(rlNF, metrics.Noop())
+
+	_, ok = agrl.(types.RateLimitWithAccessN)
+	assert.True(t, ok)
+	_, ok = agrl.(types.RateLimitWithFeedback)
+	assert.True(t, ok)
+}
@@ -357,7 +357,8 @@ func (r *Reader) readMain(conf *config.Type) (lints []string, err error) {
 		if r.mainPath != "" {
 			lintFilePrefix = fmt.Sprintf("%v: ", r.mainPath)
 		}
-		for _, lint := range confSpec.LintYAML(docs.NewLintContext(), &rawNode) {
+		lintCtx := config.LintContextWithImports(docs.NewLintContext(), &rawNode)
+		for _, lint := range confSpec.LintYAML(lintCtx, &rawNode) {
 			lints = append(lints, fmt.Sprintf("%vline %v: %v", lintFilePrefix, lint.Line, lint.What))
 		}
 	}
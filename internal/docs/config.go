@@ -74,6 +74,24 @@ func reservedFieldsByType(t Type) map[string]FieldSpec {
 			return "", false
 		})
 	}
+	if t == TypeOutput {
+		m["rate_limit"] = FieldString("rate_limit", "").OmitWhen(func(field, _ interface{}) (string, bool) {
+			if str, ok := field.(string); ok && str == "" {
+				return "field rate_limit is empty and can be removed", true
+			}
+			return "", false
+		})
+		m["dead_letter"] = FieldAdvanced("dead_letter", "").WithChildren(
+			FieldCommon("output", "").HasType(FieldTypeOutput),
+			FieldString("check", "").HasDefault(""),
+			FieldInt("max_retries", "").HasDefault(3),
+			FieldAdvanced("backoff", "").WithChildren(
+				FieldString("initial_interval", "").HasDefault("500ms"),
+				FieldString("max_interval", "").HasDefault("3s"),
+				FieldString("max_elapsed_time", "").HasDefault("0s"),
+			),
+		)
+	}
 	if _, isLabelType := map[Type]struct{}{
 		TypeInput:     {},
 		TypeProcessor: {},
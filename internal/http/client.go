@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"mime"
@@ -40,7 +41,9 @@ type MultipartExpressions struct {
 
 // Client is a component able to send and receive Benthos messages over HTTP.
 type Client struct {
-	client *http.Client
+	client     *http.Client
+	transport  *http.Transport
+	hedgeAfter time.Duration
 
 	backoffOn map[int]struct{}
 	dropOn    map[int]struct{}
@@ -52,6 +55,7 @@ type Client struct {
 	host              *field.Expression
 	metaInsertFilter  *metadata.IncludeFilter
 	metaExtractFilter *metadata.IncludeFilter
+	requestHook       func(*http.Request)
 
 	conf          client.Config
 	retryThrottle *throttle.Type
@@ -69,13 +73,14 @@ type Client struct {
 	mLimitFor      metrics.StatCounter
 	mLimitErr      metrics.StatCounter
 	mSucc          metrics.StatCounter
+	mHedged        metrics.StatCounter
 	mLatency       metrics.StatTimer
 
 	mCodes   map[int]metrics.StatCounter
 	codesMut sync.RWMutex
 
-	oauthClientCtx    context.Context
-	oauthClientCancel func()
+	clientCtx    context.Context
+	clientCancel func()
 }
 
 // NewClient creates a new http client that sends and receives Benthos messages.
@@ -91,8 +96,8 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		headers:   map[string]*field.Expression{},
 		host:      nil,
 	}
-	h.oauthClientCtx, h.oauthClientCancel = context.WithCancel(context.Background())
-	h.client = conf.OAuth2.Client(h.oauthClientCtx)
+	h.clientCtx, h.clientCancel = context.WithCancel(context.Background())
+	h.client = &http.Client{}
 
 	if tout := conf.Timeout; len(tout) > 0 {
 		var err error
@@ -137,6 +142,16 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		}
 	}
 
+	dnsRefresh, err := h.applyTransportConfig()
+	if err != nil {
+		return nil, err
+	}
+	if conf.HedgeAfter != "" {
+		if h.hedgeAfter, err = time.ParseDuration(conf.HedgeAfter); err != nil {
+			return nil, fmt.Errorf("failed to parse hedge_after duration string: %v", err)
+		}
+	}
+
 	for _, c := range conf.BackoffOn {
 		h.backoffOn[c] = struct{}{}
 	}
@@ -151,7 +166,9 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		opt(&h)
 	}
 
-	var err error
+	if h.client, err = conf.OAuth2.WrapClient(h.clientCtx, h.client); err != nil {
+		return nil, fmt.Errorf("failed to configure oauth2: %w", err)
+	}
 	if h.url, err = interop.NewBloblangField(h.mgr, conf.URL); err != nil {
 		return nil, fmt.Errorf("failed to parse URL expression: %v", err)
 	}
@@ -186,6 +203,7 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 	h.mLimitErr = h.stats.GetCounter("rate_limit.error")
 	h.mLatency = h.stats.GetTimer("latency")
 	h.mSucc = h.stats.GetCounter("success")
+	h.mHedged = h.stats.GetCounter("hedged")
 	h.mCodes = map[int]metrics.StatCounter{}
 
 	var retry, maxBackoff time.Duration
@@ -214,9 +232,145 @@ func NewClient(conf client.Config, opts ...func(*Client)) (*Client, error) {
 		throttle.OptMaxExponentPeriod(maxBackoff),
 	)
 
+	if dnsRefresh > 0 {
+		go h.refreshLoop(dnsRefresh)
+	}
 	return &h, nil
 }
 
+// applyTransportConfig applies the transport fields of the config to the
+// transport of the client, and returns the parsed DNS refresh interval.
+func (h *Client) applyTransportConfig() (time.Duration, error) {
+	tConf := h.conf.Transport
+
+	var dnsRefresh time.Duration
+	if tConf.DNSRefreshInterval != "" {
+		var err error
+		if dnsRefresh, err = time.ParseDuration(tConf.DNSRefreshInterval); err != nil {
+			return 0, fmt.Errorf("failed to parse dns_refresh_interval duration string: %v", err)
+		}
+	}
+	if tConf == client.NewTransportConfig() {
+		return 0, nil
+	}
+
+	if h.client.Transport == nil {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			h.client.Transport = c.Clone()
+		} else {
+			h.client.Transport = &http.Transport{}
+		}
+	}
+	tr, ok := h.client.Transport.(*http.Transport)
+	if !ok {
+		return 0, fmt.Errorf("unable to apply transport settings, unexpected type %T", h.client.Transport)
+	}
+	if tConf.MaxIdleConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = tConf.MaxIdleConnsPerHost
+		if tr.MaxIdleConns > 0 && tr.MaxIdleConns < tConf.MaxIdleConnsPerHost {
+			tr.MaxIdleConns = tConf.MaxIdleConnsPerHost
+		}
+	}
+	if !tConf.HTTP2 {
+		// A non-nil empty map disables the automatic upgrade to HTTP/2.
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	h.transport = tr
+	return dnsRefresh, nil
+}
+
+// refreshLoop periodically closes idle connections so that new connections
+// resolve hosts again.
+func (h *Client) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.transport.CloseIdleConnections()
+		case <-h.clientCtx.Done():
+			return
+		}
+	}
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c cancelOnCloseBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// do performs a request, and when hedging is configured performs a second
+// request created with newReq once the first has not completed within the
+// hedging period, returning the first successful response.
+func (h *Client) do(ctx context.Context, req *http.Request, newReq func() (*http.Request, error)) (*http.Response, error) {
+	if h.hedgeAfter <= 0 {
+		return h.client.Do(req.WithContext(ctx))
+	}
+
+	type result struct {
+		res    *http.Response
+		err    error
+		cancel func()
+	}
+	results := make(chan result, 2)
+	send := func(r *http.Request) {
+		rctx, cancel := context.WithCancel(ctx)
+		res, err := h.client.Do(r.WithContext(rctx))
+		results <- result{res: res, err: err, cancel: cancel}
+	}
+
+	go send(req)
+	pending := 1
+
+	hedgeTimer := time.NewTimer(h.hedgeAfter)
+	defer hedgeTimer.Stop()
+
+	var first *result
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Discard the response of any request still in flight.
+				if pending > 0 {
+					go func() {
+						l := <-results
+						if l.err == nil {
+							l.res.Body.Close()
+						}
+						l.cancel()
+					}()
+				}
+				r.res.Body = cancelOnCloseBody{ReadCloser: r.res.Body, cancel: r.cancel}
+				return r.res, nil
+			}
+			r.cancel()
+			if first == nil {
+				first = &r
+			}
+		case <-hedgeTimer.C:
+			if first != nil {
+				continue
+			}
+			hReq, err := newReq()
+			if err != nil {
+				continue
+			}
+			h.mHedged.Incr(1)
+			go send(hReq)
+			pending++
+		}
+	}
+	return nil, first.err
+}
+
 //------------------------------------------------------------------------------
 
 // OptSetLogger sets the logger to use.
@@ -255,6 +409,14 @@ func OptSetRoundTripper(rt http.RoundTripper) func(*Client) {
 	}
 }
 
+// OptSetRequestHook sets a function that is called with each request before it
+// is signed, allowing components to modify requests according to their state.
+func OptSetRequestHook(fn func(*http.Request)) func(*Client) {
+	return func(t *Client) {
+		t.requestHook = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (h *Client) incrCode(code int) {
@@ -308,9 +470,60 @@ func (h *Client) waitForAccess(ctx context.Context) bool {
 	}
 }
 
+// rateLimitFeedback informs the rate limit, when it is able to adapt, of the
+// outcome of a request. The status codes 429 and 503 are reported as the
+// resource being overloaded, along with any Retry-After header of the response.
+func (h *Client) rateLimitFeedback(ctx context.Context, res *http.Response, succeeded bool) {
+	if h.conf.RateLimit == "" {
+		return
+	}
+	throttled := res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+	if !throttled && !succeeded {
+		return
+	}
+	_ = interop.AccessRateLimit(ctx, h.mgr, h.conf.RateLimit, func(rl types.RateLimit) {
+		rf, ok := rl.(types.RateLimitWithFeedback)
+		if !ok {
+			return
+		}
+		if throttled {
+			rf.Throttled(parseRetryAfter(res.Header.Get("Retry-After")))
+		} else {
+			rf.Succeeded()
+		}
+	})
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date, returning zero if the value is empty,
+// invalid or in the past.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 // CreateRequest forms an *http.Request from a message to be sent as the body,
 // and also a message used to form headers (they can be the same).
 func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request, err error) {
+	return h.createRequest(sendMsg, refMsg, "")
+}
+
+// createRequest forms an *http.Request, where the configured URL is replaced
+// with overrideURL when it is not empty.
+func (h *Client) createRequest(sendMsg, refMsg types.Message, overrideURL string) (req *http.Request, err error) {
 	var overrideContentType string
 	var body io.Reader
 	if len(h.multipart) > 0 {
@@ -368,7 +581,10 @@ func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request
 		body = buf
 	}
 
-	url := h.url.String(0, refMsg)
+	url := overrideURL
+	if url == "" {
+		url = h.url.String(0, refMsg)
+	}
 	if req, err = http.NewRequest(h.conf.Verb, url, body); err != nil {
 		return
 	}
@@ -390,6 +606,9 @@ func (h *Client) CreateRequest(sendMsg, refMsg types.Message) (req *http.Request
 		req.Header.Del("Content-Type")
 		req.Header.Add("Content-Type", overrideContentType)
 	}
+	if h.requestHook != nil {
+		h.requestHook(req)
+	}
 
 	err = h.conf.Config.Sign(req)
 	return
@@ -512,6 +731,10 @@ func (h *Client) checkStatus(code int) (succeeded bool, retStrat retryStrategy)
 // performs it, and then returns the *http.Response, allowing the raw response
 // to be consumed.
 func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Message) (res *http.Response, err error) {
+	return h.sendToResponse(ctx, sendMsg, refMsg, "")
+}
+
+func (h *Client) sendToResponse(ctx context.Context, sendMsg, refMsg types.Message, overrideURL string) (res *http.Response, err error) {
 	h.mCount.Incr(1)
 
 	var spans []*tracing.Span
@@ -534,8 +757,12 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 		}
 	}
 
+	newReq := func() (*http.Request, error) {
+		return h.createRequest(sendMsg, refMsg, overrideURL)
+	}
+
 	var req *http.Request
-	if req, err = h.CreateRequest(sendMsg, refMsg); err != nil {
+	if req, err = newReq(); err != nil {
 		logErr(err)
 		return nil, err
 	}
@@ -555,14 +782,16 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 	rateLimited := false
 	numRetries := h.conf.NumRetries
 
-	res, err = h.client.Do(req.WithContext(ctx))
+	res, err = h.do(ctx, req, newReq)
 	if err != nil {
 		if err, ok := err.(net.Error); ok && err.Timeout() {
 			h.mErrReqTimeout.Incr(1)
 		}
 	} else {
 		h.incrCode(res.StatusCode)
-		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
+		resolved, retryStrat := h.checkStatus(res.StatusCode)
+		h.rateLimitFeedback(ctx, res, resolved)
+		if !resolved {
 			rateLimited = retryStrat == retryBackoff
 			if retryStrat == noRetry {
 				numRetries = 0
@@ -577,7 +806,7 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 	i, j := 0, numRetries
 	for i < j && err != nil {
 		logErr(err)
-		if req, err = h.CreateRequest(sendMsg, refMsg); err != nil {
+		if req, err = newReq(); err != nil {
 			continue
 		}
 		if rateLimited {
@@ -593,9 +822,11 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg, refMsg types.Messa
 			return nil, types.ErrTypeClosed
 		}
 		rateLimited = false
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		if res, err = h.do(ctx, req, newReq); err == nil {
 			h.incrCode(res.StatusCode)
-			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
+			resolved, retryStrat := h.checkStatus(res.StatusCode)
+			h.rateLimitFeedback(ctx, res, resolved)
+			if !resolved {
 				rateLimited = retryStrat == retryBackoff
 				if retryStrat == noRetry {
 					j = 0
@@ -647,6 +878,6 @@ func (h *Client) Send(ctx context.Context, sendMsg, refMsg types.Message) (types
 
 // Close the client.
 func (h *Client) Close(ctx context.Context) error {
-	h.oauthClientCancel()
+	h.clientCancel()
 	return nil
 }
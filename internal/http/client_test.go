@@ -3,6 +3,10 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"mime"
@@ -10,6 +14,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -21,6 +27,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -574,3 +581,245 @@ func TestHTTPClientReceiveMultipartWithHeaders(t *testing.T) {
 		assert.Equal(t, "201", resMsg.Get(1).Metadata().Get("http_status_code"))
 	}
 }
+
+type feedbackRateLimit struct {
+	throttled   []time.Duration
+	succeeded   int
+	accessCount int
+}
+
+func (f *feedbackRateLimit) Access() (time.Duration, error) {
+	f.accessCount++
+	return 0, nil
+}
+
+func (f *feedbackRateLimit) Throttled(retryAfter time.Duration) {
+	f.throttled = append(f.throttled, retryAfter)
+}
+
+func (f *feedbackRateLimit) Succeeded() {
+	f.succeeded++
+}
+
+func (f *feedbackRateLimit) CloseAsync() {}
+
+func (f *feedbackRateLimit) WaitForClose(time.Duration) error {
+	return nil
+}
+
+type rateLimitMgr struct {
+	types.DudMgr
+	rl types.RateLimit
+}
+
+func (m rateLimitMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	if name == "foo" {
+		return m.rl, nil
+	}
+	return nil, types.ErrRateLimitNotFound
+}
+
+func TestHTTPClientRateLimitFeedback(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddUint32(&reqCount, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "2")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case 3:
+			http.Error(w, "nope", http.StatusForbidden)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+
+	rl := &feedbackRateLimit{}
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.MaxBackoff = "1ms"
+	conf.NumRetries = 3
+	conf.RateLimit = "foo"
+
+	h, err := NewClient(conf, OptSetManager(rateLimitMgr{rl: rl}))
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	out := message.New([][]byte{[]byte("test")})
+	_, err = h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(4), atomic.LoadUint32(&reqCount))
+	assert.Equal(t, 4, rl.accessCount)
+	assert.Equal(t, []time.Duration{time.Second * 2, 0}, rl.throttled)
+	assert.Equal(t, 1, rl.succeeded)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("nope"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-5"))
+	assert.Equal(t, time.Second*30, parseRetryAfter("30"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.Greater(t, int64(d), int64(time.Second*50))
+	assert.LessOrEqual(t, int64(d), int64(time.Minute))
+}
+
+func TestHTTPClientOAuth2(t *testing.T) {
+	var tokenReqs uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			atomic.AddUint32(&tokenReqs, 1)
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"footoken","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		assert.Equal(t, "Bearer footoken", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.OAuth2.Enabled = true
+	conf.OAuth2.ClientKey = "fookey"
+	conf.OAuth2.ClientSecret = "foosecret"
+	conf.OAuth2.TokenURL = ts.URL + "/token"
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	for i := 0; i < 3; i++ {
+		out := message.New([][]byte{[]byte("test")})
+		_, err = h.Send(context.Background(), out, out)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&tokenReqs))
+}
+
+func TestHTTPClientOAuth2JWTBearer(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privKey),
+	}), 0o600))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.Form.Get("grant_type"))
+
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) {
+				return &privKey.PublicKey, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "fookey", claims["iss"])
+			assert.Equal(t, "foosubject", claims["sub"])
+			assert.Equal(t, "fooaudience", claims["aud"])
+			assert.Equal(t, "bar", claims["foo"])
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"footoken","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		assert.Equal(t, "Bearer footoken", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.OAuth2.Enabled = true
+	conf.OAuth2.GrantType = "jwt_bearer"
+	conf.OAuth2.ClientKey = "fookey"
+	conf.OAuth2.TokenURL = ts.URL + "/token"
+	conf.OAuth2.JWTBearer.PrivateKeyFile = keyFile
+	conf.OAuth2.JWTBearer.Subject = "foosubject"
+	conf.OAuth2.JWTBearer.Audience = "fooaudience"
+	conf.OAuth2.JWTBearer.Claims = map[string]interface{}{"foo": "bar"}
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	out := message.New([][]byte{[]byte("test")})
+	_, err = h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+
+	conf.OAuth2.GrantType = "nope"
+	_, err = NewClient(conf)
+	require.Error(t, err)
+}
+
+func TestHTTPClientHedging(t *testing.T) {
+	var reqCount uint32
+	slowDone := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second * 5):
+			}
+			close(slowDone)
+			_, _ = w.Write([]byte("slow"))
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.HedgeAfter = "10ms"
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	out := message.New([][]byte{[]byte("test")})
+	res, err := h.Send(context.Background(), out, out)
+	require.NoError(t, err)
+	assert.Equal(t, "fast", string(res.Get(0).Get()))
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+
+	// The slow request should be cancelled.
+	select {
+	case <-slowDone:
+	case <-time.After(time.Second * 2):
+		t.Error("expected slow request to be cancelled")
+	}
+}
+
+func TestHTTPClientTransport(t *testing.T) {
+	conf := client.NewConfig()
+	conf.Transport.MaxIdleConnsPerHost = 50
+	conf.Transport.HTTP2 = false
+	conf.Transport.DNSRefreshInterval = "1m"
+
+	h, err := NewClient(conf)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	require.NotNil(t, h.transport)
+	assert.Equal(t, 50, h.transport.MaxIdleConnsPerHost)
+	assert.False(t, h.transport.ForceAttemptHTTP2)
+	assert.NotNil(t, h.transport.TLSNextProto)
+
+	conf.Transport.DNSRefreshInterval = "nope"
+	_, err = NewClient(conf)
+	require.Error(t, err)
+}
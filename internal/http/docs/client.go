@@ -41,6 +41,12 @@ func ClientFieldSpec(forOutput bool, extraChildren ...docs.FieldSpec) docs.Field
 		docs.FieldInt("drop_on", "A list of status codes whereby the request should be considered to have failed but retries should not be attempted. This is useful for preventing wasted retries for requests that will never succeed. Note that with these status codes the _request_ is dropped, but _message_ that caused the request will not be dropped.").Array().Advanced(),
 		docs.FieldInt("successful_on", "A list of status codes whereby the attempt should be considered successful, this is useful for dropping requests that return non-2XX codes indicating that the message has been dealt with, such as a 303 See Other or a 409 Conflict. All 2XX codes are considered successful unless they are present within `backoff_on` or `drop_on`, regardless of this field.").Array().Advanced(),
 		docs.FieldString("proxy_url", "An optional HTTP proxy URL.").Advanced(),
+		docs.FieldAdvanced("transport", "Customise the connections made by the client.").WithChildren(
+			docs.FieldInt("max_idle_conns_per_host", "The maximum number of idle connections to keep open for each host, where zero uses the default of 2. Increasing this allows more concurrent requests to reuse connections rather than opening new ones."),
+			docs.FieldBool("http2", "Whether to attempt HTTP/2 connections when the server supports it."),
+			docs.FieldString("dns_refresh_interval", "An optional period at which idle connections are closed, which causes new connections to resolve the host again and therefore allows changes to DNS records to be picked up.", "60s"),
+		).AtVersion("3.64.0"),
+		docs.FieldString("hedge_after", "An optional period after which a second identical request is sent when no response has been received for the first, where the first successful response is used and the other request is cancelled. This reduces tail latencies at the cost of extra requests, and should only be used for requests that are safe to perform more than once.", "100ms").Advanced().AtVersion("3.64.0"),
 	)
 	httpSpecs = append(httpSpecs, extraChildren...)
 
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+)
+
+// Paginator performs requests with a client and follows the pages of the
+// responses according to a pagination config.
+type Paginator struct {
+	client   *Client
+	nextURL  *mapping.Executor
+	items    *mapping.Executor
+	maxPages int
+}
+
+// NewPaginator creates a paginator from a config, returns nil when pagination
+// is disabled.
+func NewPaginator(conf client.PaginationConfig, c *Client, mgr types.Manager) (*Paginator, error) {
+	if conf.NextURL == "" {
+		return nil, nil
+	}
+	p := &Paginator{
+		client:   c,
+		maxPages: conf.MaxPages,
+	}
+	var err error
+	if p.nextURL, err = interop.NewBloblangMapping(mgr, conf.NextURL); err != nil {
+		return nil, fmt.Errorf("failed to parse next_url mapping: %w", err)
+	}
+	if conf.Items != "" {
+		if p.items, err = interop.NewBloblangMapping(mgr, conf.Items); err != nil {
+			return nil, fmt.Errorf("failed to parse items mapping: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Page performs a request for a single page, where the first page is requested
+// from the configured URL when pageURL is empty, and page is the index of the
+// page starting from zero. Returns the page, or the items of the page when an
+// items mapping is configured, and the URL of the next page, which is empty
+// once pagination is exhausted.
+func (p *Paginator) Page(ctx context.Context, sendMsg, refMsg types.Message, pageURL string, page int) (types.Message, string, error) {
+	res, err := p.client.sendToResponse(ctx, sendMsg, refMsg, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	linkNext := parseLinkNext(res)
+	pageMsg, err := p.client.ParseResponse(res)
+	if err != nil {
+		return nil, "", err
+	}
+	pageMsg.Iter(func(i int, part types.Part) error {
+		part.Metadata().Set("http_page", strconv.Itoa(page))
+		if linkNext != "" {
+			part.Metadata().Set("http_link_next", linkNext)
+		}
+		return nil
+	})
+
+	var nextURL string
+	if pageMsg.Len() > 0 && (p.maxPages <= 0 || page+1 < p.maxPages) {
+		if nextURL, err = p.execNextURL(pageMsg); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if p.items == nil {
+		return pageMsg, nextURL, nil
+	}
+	itemsMsg := message.New(nil)
+	for i := 0; i < pageMsg.Len(); i++ {
+		items, err := p.execItems(i, pageMsg)
+		if err != nil {
+			return nil, "", err
+		}
+		itemsMsg.Append(items...)
+	}
+	return itemsMsg, nextURL, nil
+}
+
+// All performs requests for all pages until pagination is exhausted, and
+// returns each page, or each item when an items mapping is configured, as a
+// message of the resulting batch.
+func (p *Paginator) All(ctx context.Context, sendMsg, refMsg types.Message) (types.Message, error) {
+	resMsg := message.New(nil)
+
+	var pageURL string
+	for page := 0; ; page++ {
+		pageMsg, nextURL, err := p.Page(ctx, sendMsg, refMsg, pageURL, page)
+		if err != nil {
+			return nil, err
+		}
+		pageMsg.Iter(func(i int, part types.Part) error {
+			resMsg.Append(part)
+			return nil
+		})
+		if nextURL == "" {
+			return resMsg, nil
+		}
+		pageURL = nextURL
+	}
+}
+
+func execContext(index int, msg types.Message) query.FunctionContext {
+	return query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	})
+}
+
+func (p *Paginator) execNextURL(pageMsg types.Message) (string, error) {
+	v, err := p.nextURL.Exec(execContext(0, pageMsg))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute next_url mapping: %w", err)
+	}
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case nil, query.Delete, query.Nothing:
+		return "", nil
+	}
+	return "", fmt.Errorf("next_url mapping yielded a non-string result: %T", v)
+}
+
+func (p *Paginator) execItems(index int, pageMsg types.Message) ([]types.Part, error) {
+	v, err := p.items.Exec(execContext(index, pageMsg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute items mapping: %w", err)
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items mapping yielded a non-array result: %T", v)
+	}
+
+	meta := pageMsg.Get(index).Metadata()
+	parts := make([]types.Part, 0, len(items))
+	for _, item := range items {
+		part := message.NewPart(nil)
+		switch t := item.(type) {
+		case string:
+			part.Set([]byte(t))
+		case []byte:
+			part.Set(t)
+		default:
+			if err := part.SetJSON(item); err != nil {
+				return nil, err
+			}
+		}
+		part.SetMetadata(meta.Copy())
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// parseLinkNext returns the URL of a Link header entry with the relation type
+// next, resolved against the URL of the request.
+func parseLinkNext(res *http.Response) string {
+	for _, header := range res.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			segments := strings.Split(link, ";")
+			target := strings.TrimSpace(segments[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			for _, param := range segments[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(strings.ToLower(param), "rel=") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(param[4:], `"`)) {
+					if !strings.EqualFold(rel, "next") {
+						continue
+					}
+					if res.Request != nil && res.Request.URL != nil {
+						if u, err := url.Parse(target); err == nil {
+							return res.Request.URL.ResolveReference(u).String()
+						}
+					}
+					return target
+				}
+			}
+		}
+	}
+	return ""
+}
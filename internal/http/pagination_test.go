@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginatorCursor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			_, _ = w.Write([]byte(`{"items":["a","b"],"next":"foo"}`))
+		case "foo":
+			_, _ = w.Write([]byte(`{"items":[{"id":"c"}],"next":"bar"}`))
+		case "bar":
+			_, _ = w.Write([]byte(`{"items":["d"],"next":null}`))
+		default:
+			http.Error(w, "unexpected cursor", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/things"
+	conf.Verb = "GET"
+
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close(context.Background())
+
+	pConf := client.NewPaginationConfig()
+	pConf.NextURL = fmt.Sprintf(`root = if this.next != null { "%v/things?cursor=" + this.next }`, ts.URL)
+	pConf.Items = `root = this.items`
+
+	p, err := NewPaginator(pConf, c, types.NoopMgr())
+	require.NoError(t, err)
+
+	msg := message.New(nil)
+	res, err := p.All(context.Background(), msg, msg)
+	require.NoError(t, err)
+
+	require.Equal(t, 4, res.Len())
+	assert.Equal(t, "a", string(res.Get(0).Get()))
+	assert.Equal(t, "b", string(res.Get(1).Get()))
+	assert.Equal(t, `{"id":"c"}`, string(res.Get(2).Get()))
+	assert.Equal(t, "d", string(res.Get(3).Get()))
+
+	assert.Equal(t, "0", res.Get(0).Metadata().Get("http_page"))
+	assert.Equal(t, "1", res.Get(2).Metadata().Get("http_page"))
+	assert.Equal(t, "2", res.Get(3).Metadata().Get("http_page"))
+}
+
+func TestPaginatorLinkHeader(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</things?page=3>; rel="last", </things?page=%v>; rel="next"`, page+1))
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf("page %v", page)))
+	}))
+	defer ts.Close()
+
+	conf := client.NewConfig()
+	conf.URL = ts.URL + "/things"
+	conf.Verb = "GET"
+
+	c, err := NewClient(conf)
+	require.NoError(t, err)
+	defer c.Close(context.Background())
+
+	pConf := client.NewPaginationConfig()
+	pConf.NextURL = `root = meta("http_link_next")`
+	pConf.MaxPages = 2
+
+	p, err := NewPaginator(pConf, c, types.NoopMgr())
+	require.NoError(t, err)
+
+	msg := message.New(nil)
+	res, err := p.All(context.Background(), msg, msg)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, res.Len())
+	assert.Equal(t, "page 0", string(res.Get(0).Get()))
+	assert.Equal(t, ts.URL+"/things?page=1", res.Get(0).Metadata().Get("http_link_next"))
+	assert.Equal(t, "page 1", string(res.Get(1).Get()))
+	assert.Equal(t, 2, requests)
+}
+
+func TestPaginatorDisabled(t *testing.T) {
+	p, err := NewPaginator(client.NewPaginationConfig(), nil, types.NoopMgr())
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
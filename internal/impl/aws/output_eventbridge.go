@@ -0,0 +1,344 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/cenkalti/backoff/v4"
+)
+
+const (
+	// The maximum number of entries and total size of entries of a single
+	// PutEvents request.
+	eventBridgeMaxEntries = 10
+	eventBridgeMaxBytes   = 256 * 1024
+)
+
+func eventBridgeOutputConfig() *service.ConfigSpec {
+	defaultBackOff := backoff.NewExponentialBackOff()
+	defaultBackOff.InitialInterval = time.Second
+	defaultBackOff.MaxInterval = 5 * time.Second
+	defaultBackOff.MaxElapsedTime = 30 * time.Second
+
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services", "AWS").
+		Summary("Sends messages as events to an AWS EventBridge event bus.").
+		Description(output.Description(true, true, `
+Each message is sent as an event with a source and detail type obtained from the interpolated fields `+"`source`"+` and `+"`detail_type`"+`. The detail of each event is the contents of the message, which must be a JSON object, unless the field `+"`detail`"+` is set to a [Bloblang mapping](/docs/guides/bloblang/about) that creates it.
+
+Batches are sent with as few PutEvents requests as possible within the API limits of ten entries and 256KB per request. Entries that fail within a request are retried individually according to the field `+"`backoff`"+`, and if any entries are still failing once the retries have been exhausted the whole batch is rejected.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`)).
+		Field(service.NewInterpolatedStringField("event_bus").
+			Description("The name or ARN of the event bus to send events to. When empty the default event bus of the account is used.").
+			Default("")).
+		Field(service.NewInterpolatedStringField("source").
+			Description("The source of each event.").
+			Example("com.example.orders")).
+		Field(service.NewInterpolatedStringField("detail_type").
+			Description("The detail type of each event.").
+			Example("OrderCreated").
+			Example(`${! meta("kafka_topic") }`)).
+		Field(service.NewBloblangField("detail").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that creates the detail of each event from the message. When empty the contents of the message are used.").
+			Example(`root = this.without("metadata")`).
+			Optional()).
+		Field(service.NewStringListField("resources").
+			Description("A list of interpolated resource ARNs that each event primarily concerns. Resources that resolve to an empty string are omitted.").
+			Default([]string{}).
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Field(service.NewBackOffField("backoff", false, defaultBackOff).
+			Description("Determines how failed entries of a request are retried.").
+			Advanced()).
+		Example("Order Events", `
+Forward order documents consumed from Kafka to an event bus, using the topic as the detail type and removing internal fields from the detail:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ order_created, order_shipped ]
+    consumer_group: benthos_orders
+
+output:
+  aws_eventbridge:
+    event_bus: orders
+    source: com.example.orders
+    detail_type: ${! meta("kafka_topic") }
+    detail: 'root = this.without("internal")'
+    batching:
+      count: 10
+      period: 1s
+`,
+		)
+
+	for _, f := range SessionFields() {
+		spec = spec.Field(f)
+	}
+	return spec
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"aws_eventbridge", eventBridgeOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newEventBridgeWriterFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventBridgeWriter struct {
+	conf *service.ParsedConfig
+	log  *service.Logger
+
+	eventBus   *service.InterpolatedString
+	source     *service.InterpolatedString
+	detailType *service.InterpolatedString
+	detail     *bloblang.Executor
+	resources  []*service.InterpolatedString
+	boffCtor   func() backoff.BackOff
+
+	client  eventbridgeiface.EventBridgeAPI
+	connMut sync.RWMutex
+}
+
+func newEventBridgeWriterFromConfig(conf *service.ParsedConfig, log *service.Logger) (*eventBridgeWriter, error) {
+	e := &eventBridgeWriter{
+		conf: conf,
+		log:  log,
+	}
+
+	var err error
+	if e.eventBus, err = conf.FieldInterpolatedString("event_bus"); err != nil {
+		return nil, err
+	}
+	if e.source, err = conf.FieldInterpolatedString("source"); err != nil {
+		return nil, err
+	}
+	if e.detailType, err = conf.FieldInterpolatedString("detail_type"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("detail") {
+		if e.detail, err = conf.FieldBloblang("detail"); err != nil {
+			return nil, err
+		}
+	}
+
+	resourceStrs, err := conf.FieldStringList("resources")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resourceStrs {
+		resource, err := service.NewInterpolatedString(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse resource '%v': %w", r, err)
+		}
+		e.resources = append(e.resources, resource)
+	}
+
+	boff, err := conf.FieldBackOff("backoff")
+	if err != nil {
+		return nil, err
+	}
+	e.boffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+	return e, nil
+}
+
+func (e *eventBridgeWriter) Connect(ctx context.Context) error {
+	e.connMut.Lock()
+	defer e.connMut.Unlock()
+	if e.client != nil {
+		return nil
+	}
+
+	sess, err := GetSession(e.conf)
+	if err != nil {
+		return err
+	}
+	e.client = eventbridge.New(sess)
+	e.log.Infof("Sending messages as events to AWS EventBridge")
+	return nil
+}
+
+// eventBridgeEntrySize returns the size of an entry as calculated by
+// EventBridge when enforcing the request size limit.
+func eventBridgeEntrySize(entry *eventbridge.PutEventsRequestEntry) int {
+	size := 14 // The size of the time field, which is always calculated.
+	size += len(aws.StringValue(entry.Source))
+	size += len(aws.StringValue(entry.DetailType))
+	size += len(aws.StringValue(entry.Detail))
+	for _, r := range entry.Resources {
+		size += len(aws.StringValue(r))
+	}
+	return size
+}
+
+func (e *eventBridgeWriter) toEntries(batch service.MessageBatch) ([]*eventbridge.PutEventsRequestEntry, error) {
+	entries := make([]*eventbridge.PutEventsRequestEntry, len(batch))
+	for i, msg := range batch {
+		detailMsg := msg
+		if e.detail != nil {
+			var err error
+			if detailMsg, err = batch.BloblangQuery(i, e.detail); err != nil {
+				return nil, fmt.Errorf("detail mapping failed: %w", err)
+			}
+		}
+		detailBytes, err := detailMsg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &eventbridge.PutEventsRequestEntry{
+			Source:     aws.String(batch.InterpolatedString(i, e.source)),
+			DetailType: aws.String(batch.InterpolatedString(i, e.detailType)),
+			Detail:     aws.String(string(detailBytes)),
+		}
+		if bus := batch.InterpolatedString(i, e.eventBus); bus != "" {
+			entry.EventBusName = aws.String(bus)
+		}
+		for _, r := range e.resources {
+			if resource := batch.InterpolatedString(i, r); resource != "" {
+				entry.Resources = append(entry.Resources, aws.String(resource))
+			}
+		}
+
+		if size := eventBridgeEntrySize(entry); size > eventBridgeMaxBytes {
+			return nil, fmt.Errorf("event size %v exceeds the maximum of %v bytes", size, eventBridgeMaxBytes)
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// nextEventBridgeRequest returns the longest prefix of entries that fits within
+// a single PutEvents request.
+func nextEventBridgeRequest(entries []*eventbridge.PutEventsRequestEntry) (req, remaining []*eventbridge.PutEventsRequestEntry) {
+	size := 0
+	for i, entry := range entries {
+		entrySize := eventBridgeEntrySize(entry)
+		if i == eventBridgeMaxEntries || (i > 0 && size+entrySize > eventBridgeMaxBytes) {
+			return entries[:i], entries[i:]
+		}
+		size += entrySize
+	}
+	return entries, nil
+}
+
+func (e *eventBridgeWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	e.connMut.RLock()
+	client := e.client
+	e.connMut.RUnlock()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	entries, err := e.toEntries(batch)
+	if err != nil {
+		return err
+	}
+
+	boff := e.boffCtor()
+	for len(entries) > 0 {
+		var req []*eventbridge.PutEventsRequestEntry
+		req, entries = nextEventBridgeRequest(entries)
+
+		failed, err := e.putEvents(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 {
+			continue
+		}
+
+		// Requeue failed entries ahead of the remaining entries so that they
+		// are retried within the next request.
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return fmt.Errorf("failed to send %v events: %w", len(failed), failed[0].err)
+		}
+		e.log.Warnf("Scheduling retry of %v failed events: %v\n", len(failed), failed[0].err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		retries := make([]*eventbridge.PutEventsRequestEntry, 0, len(failed)+len(entries))
+		for _, f := range failed {
+			retries = append(retries, f.entry)
+		}
+		entries = append(retries, entries...)
+	}
+	return nil
+}
+
+type eventBridgeFailedEntry struct {
+	entry *eventbridge.PutEventsRequestEntry
+	err   error
+}
+
+// putEvents sends a single request and returns the entries that failed.
+func (e *eventBridgeWriter) putEvents(ctx context.Context, client eventbridgeiface.EventBridgeAPI, entries []*eventbridge.PutEventsRequestEntry) ([]eventBridgeFailedEntry, error) {
+	res, err := client.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+		Entries: entries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.Int64Value(res.FailedEntryCount) == 0 {
+		return nil, nil
+	}
+
+	var failed []eventBridgeFailedEntry
+	for i, result := range res.Entries {
+		if result.ErrorCode == nil || i >= len(entries) {
+			continue
+		}
+		failed = append(failed, eventBridgeFailedEntry{
+			entry: entries[i],
+			err:   fmt.Errorf("%v: %v", *result.ErrorCode, aws.StringValue(result.ErrorMessage)),
+		})
+	}
+	if len(failed) == 0 {
+		return nil, errors.New("request reported failed entries without identifying them")
+	}
+	return failed, nil
+}
+
+func (e *eventBridgeWriter) Close(ctx context.Context) error {
+	e.connMut.Lock()
+	e.client = nil
+	e.connMut.Unlock()
+	return nil
+}
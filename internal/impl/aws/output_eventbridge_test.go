@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEventBridge struct {
+	eventbridgeiface.EventBridgeAPI
+	putFn func(*eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error)
+}
+
+func (m *mockEventBridge) PutEventsWithContext(_ context.Context, input *eventbridge.PutEventsInput, _ ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	return m.putFn(input)
+}
+
+func TestEventBridgeWriteEntries(t *testing.T) {
+	var reqs []*eventbridge.PutEventsInput
+	conf, err := eventBridgeOutputConfig().ParseYAML(`
+event_bus: ${! meta("bus") }
+source: com.example
+detail_type: ${! meta("type") }
+detail: 'root.id = this.id'
+resources: [ 'arn:aws:foo:${! json("id") }', '${! meta("nope") }' ]
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	w.client = &mockEventBridge{
+		putFn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			reqs = append(reqs, input)
+			return &eventbridge.PutEventsOutput{}, nil
+		},
+	}
+
+	msgA := service.NewMessage([]byte(`{"id":"a","secret":"foo"}`))
+	msgA.MetaSet("type", "created")
+	msgB := service.NewMessage([]byte(`{"id":"b","secret":"bar"}`))
+	msgB.MetaSet("type", "deleted")
+	msgB.MetaSet("bus", "other")
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	require.Len(t, reqs, 1)
+	assert.Equal(t, []*eventbridge.PutEventsRequestEntry{
+		{
+			Source:     aws.String("com.example"),
+			DetailType: aws.String("created"),
+			Detail:     aws.String(`{"id":"a"}`),
+			Resources:  []*string{aws.String("arn:aws:foo:a")},
+		},
+		{
+			Source:       aws.String("com.example"),
+			DetailType:   aws.String("deleted"),
+			Detail:       aws.String(`{"id":"b"}`),
+			EventBusName: aws.String("other"),
+			Resources:    []*string{aws.String("arn:aws:foo:b")},
+		},
+	}, reqs[0].Entries)
+}
+
+func TestEventBridgeWriteLimits(t *testing.T) {
+	var reqSizes []int
+	conf, err := eventBridgeOutputConfig().ParseYAML(`
+source: foo
+detail_type: bar
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	w.client = &mockEventBridge{
+		putFn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			reqSizes = append(reqSizes, len(input.Entries))
+			return &eventbridge.PutEventsOutput{}, nil
+		},
+	}
+
+	var batch service.MessageBatch
+	for i := 0; i < 25; i++ {
+		batch = append(batch, service.NewMessage([]byte(fmt.Sprintf(`{"id":%v}`, i))))
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []int{10, 10, 5}, reqSizes)
+
+	reqSizes = nil
+	largeDoc := []byte(`{"data":"` + strings.Repeat("x", 100*1024) + `"}`)
+	batch = service.MessageBatch{
+		service.NewMessage(largeDoc),
+		service.NewMessage(largeDoc),
+		service.NewMessage(largeDoc),
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []int{2, 1}, reqSizes)
+
+	tooLargeDoc := []byte(`{"data":"` + strings.Repeat("x", 300*1024) + `"}`)
+	err = w.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage(tooLargeDoc)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum")
+}
+
+func TestEventBridgeWritePartialFailures(t *testing.T) {
+	var reqs [][]string
+	conf, err := eventBridgeOutputConfig().ParseYAML(`
+source: foo
+detail_type: bar
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	w.client = &mockEventBridge{
+		putFn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			var details []string
+			res := &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}
+			for _, e := range input.Entries {
+				details = append(details, *e.Detail)
+				result := &eventbridge.PutEventsResultEntry{}
+				// Fail each entry the first time it is seen.
+				if len(reqs) == 0 && *e.Detail != `"a"` {
+					result.ErrorCode = aws.String("ThrottlingException")
+					result.ErrorMessage = aws.String("slow down")
+					*res.FailedEntryCount++
+				}
+				res.Entries = append(res.Entries, result)
+			}
+			reqs = append(reqs, details)
+			return res, nil
+		},
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"a"`)),
+		service.NewMessage([]byte(`"b"`)),
+		service.NewMessage([]byte(`"c"`)),
+	}))
+	assert.Equal(t, [][]string{
+		{`"a"`, `"b"`, `"c"`},
+		{`"b"`, `"c"`},
+	}, reqs)
+}
+
+func TestEventBridgeWriteFailuresExhausted(t *testing.T) {
+	conf, err := eventBridgeOutputConfig().ParseYAML(`
+source: foo
+detail_type: bar
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	w.client = &mockEventBridge{
+		putFn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			res := &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(int64(len(input.Entries)))}
+			for range input.Entries {
+				res.Entries = append(res.Entries, &eventbridge.PutEventsResultEntry{
+					ErrorCode:    aws.String("InternalFailure"),
+					ErrorMessage: aws.String("nope"),
+				})
+			}
+			return res, nil
+		},
+	}
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"a"`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InternalFailure: nope")
+}
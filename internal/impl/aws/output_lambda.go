@@ -0,0 +1,527 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+const (
+	// The maximum payload sizes of synchronous and asynchronous invocations.
+	lambdaMaxSyncPayloadSize  = 6 * 1024 * 1024
+	lambdaMaxAsyncPayloadSize = 256 * 1024
+)
+
+func lambdaOutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services", "AWS").
+		Summary("Invokes an AWS Lambda function with messages as the payloads of invocations.").
+		Description(output.Description(true, true, `
+By default each message of a batch is sent as the payload of an individual invocation. When `+"`batch_payloads`"+` is enabled the messages of a batch are instead sent as a JSON array, where messages that are not valid JSON are encoded as JSON strings. Batches that do not fit within `+"`max_payload_size`"+` are split into several invocations, which by default is the limit of the invocation type, 6MB for `+"`RequestResponse`"+` invocations and 256KB for `+"`Event`"+` invocations.
+
+Setting the `+"`invocation_type`"+` to `+"`Event`"+` queues payloads for asynchronous processing by the function, in which case the output does not wait for the function to finish executing.
+
+### Error Handling
+
+When Benthos is unable to invoke the function it will retry the invocation according to the field `+"`retries`"+`, after which the messages of the invocation are rejected and retried by the output as a whole.
+
+When a `+"`RequestResponse`"+` invocation is successful but the function itself throws an error the messages of the invocation are treated as failed. With `+"`batch_payloads`"+` enabled a function may also respond with a partial batch response of the form `+"`{\"batchItemFailures\":[{\"itemIdentifier\":\"1\"}]}`"+`, where each identifier is the index of a failed record within the payload. Failed records are then invoked once more individually, and only the records that still fail are rejected, allowing the rest of the batch to be acknowledged.
+
+### Responses
+
+When `+"`propagate_response`"+` is enabled the results of invocations are returned to the input as a synchronous response, which is only supported for `+"`RequestResponse`"+` invocations without `+"`batch_payloads`"+`. You can read more about synchronous responses [in this document](/docs/guides/sync_responses).
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`)).
+		Field(service.NewStringField("function").
+			Description("The function to invoke.")).
+		Field(service.NewStringEnumField("invocation_type", lambda.InvocationTypeRequestResponse, lambda.InvocationTypeEvent, lambda.InvocationTypeDryRun).
+			Description("The type of invocation to perform. `RequestResponse` invocations wait for the function to return a result, `Event` invocations queue the payload for asynchronous processing and return immediately, and `DryRun` invocations only validate the parameters and permissions of the request.").
+			Default(lambda.InvocationTypeRequestResponse).
+			Advanced()).
+		Field(service.NewInterpolatedStringField("client_context").
+			Description("An optional JSON object to pass to the function as its client context, which is base64 encoded before being sent. The client context is only available to functions invoked with the `RequestResponse` invocation type.").
+			Example(`{"custom":{"topic":"${! meta("kafka_topic") }"}}`).
+			Default("").
+			Advanced()).
+		Field(service.NewBoolField("batch_payloads").
+			Description("Whether to send the messages of a batch as a JSON array within the payloads of invocations, rather than invoking the function for each message individually.").
+			Default(false)).
+		Field(service.NewIntField("max_payload_size").
+			Description("The maximum size in bytes of the payload of an invocation, where batches that exceed it are split into several invocations. Set to `0` in order to use the limit of the invocation type.").
+			Default(0).
+			Advanced()).
+		Field(service.NewBoolField("propagate_response").
+			Description("Whether the results of invocations should be propagated back to the input as a synchronous response.").
+			Default(false).
+			Advanced()).
+		Field(service.NewStringField("rate_limit").
+			Description("An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle invocations by.").
+			Default("").
+			Advanced()).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period of time to wait before abandoning an invocation.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewIntField("retries").
+			Description("The maximum number of retry attempts for each invocation.").
+			Default(3).
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(1)).
+		Field(service.NewBatchPolicyField("batching"))
+
+	for _, f := range SessionFields() {
+		spec = spec.Field(f)
+	}
+	return spec
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"aws_lambda", lambdaOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			var w *lambdaWriter
+			if w, err = newLambdaWriterFromConfig(conf, mgr.Logger(), mgr.Metrics()); err != nil {
+				return
+			}
+			w.mgr = mgr
+			out = w
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lambdaWriter struct {
+	conf *service.ParsedConfig
+	log  *service.Logger
+	mgr  *service.Resources
+
+	function          string
+	invocationType    string
+	clientContext     *service.InterpolatedString
+	batchPayloads     bool
+	maxPayloadSize    int
+	propagateResponse bool
+	rateLimit         string
+	timeout           time.Duration
+	retries           int
+
+	mFunctionErr *service.MetricCounter
+	mRequeued    *service.MetricCounter
+
+	client  lambdaiface.LambdaAPI
+	connMut sync.RWMutex
+}
+
+func newLambdaWriterFromConfig(conf *service.ParsedConfig, log *service.Logger, stats *service.Metrics) (*lambdaWriter, error) {
+	l := &lambdaWriter{
+		conf:         conf,
+		log:          log,
+		mFunctionErr: stats.NewCounter("output_aws_lambda_function_error"),
+		mRequeued:    stats.NewCounter("output_aws_lambda_requeued"),
+	}
+
+	var err error
+	if l.function, err = conf.FieldString("function"); err != nil {
+		return nil, err
+	}
+	if l.function == "" {
+		return nil, errors.New("lambda function must not be empty")
+	}
+	if l.invocationType, err = conf.FieldString("invocation_type"); err != nil {
+		return nil, err
+	}
+	switch l.invocationType {
+	case lambda.InvocationTypeRequestResponse, lambda.InvocationTypeEvent, lambda.InvocationTypeDryRun:
+	default:
+		return nil, fmt.Errorf("invocation type %v not recognised, try RequestResponse, Event or DryRun", l.invocationType)
+	}
+	if l.clientContext, err = conf.FieldInterpolatedString("client_context"); err != nil {
+		return nil, err
+	}
+	if l.batchPayloads, err = conf.FieldBool("batch_payloads"); err != nil {
+		return nil, err
+	}
+	if l.maxPayloadSize, err = conf.FieldInt("max_payload_size"); err != nil {
+		return nil, err
+	}
+	if l.propagateResponse, err = conf.FieldBool("propagate_response"); err != nil {
+		return nil, err
+	}
+	if l.rateLimit, err = conf.FieldString("rate_limit"); err != nil {
+		return nil, err
+	}
+	if l.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+	if l.retries, err = conf.FieldInt("retries"); err != nil {
+		return nil, err
+	}
+
+	if l.propagateResponse {
+		if l.batchPayloads {
+			return nil, errors.New("propagate_response cannot be enabled along with batch_payloads")
+		}
+		if l.invocationType != lambda.InvocationTypeRequestResponse {
+			return nil, fmt.Errorf("propagate_response requires the invocation type RequestResponse, got %v", l.invocationType)
+		}
+	}
+
+	if l.maxPayloadSize <= 0 {
+		l.maxPayloadSize = lambdaMaxSyncPayloadSize
+		if l.invocationType == lambda.InvocationTypeEvent {
+			l.maxPayloadSize = lambdaMaxAsyncPayloadSize
+		}
+	}
+	return l, nil
+}
+
+func (l *lambdaWriter) Connect(ctx context.Context) error {
+	l.connMut.Lock()
+	defer l.connMut.Unlock()
+	if l.client != nil {
+		return nil
+	}
+
+	sess, err := GetSession(l.conf)
+	if err != nil {
+		return err
+	}
+	l.client = lambda.New(sess)
+	l.log.Infof("Invoking AWS Lambda function: %v\n", l.function)
+	return nil
+}
+
+func (l *lambdaWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	l.connMut.RLock()
+	client := l.client
+	l.connMut.RUnlock()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	if l.batchPayloads {
+		return l.writeBatched(ctx, client, batch)
+	}
+	return l.writeEach(ctx, client, batch)
+}
+
+func (l *lambdaWriter) writeEach(ctx context.Context, client lambdaiface.LambdaAPI, batch service.MessageBatch) error {
+	var resBatch service.MessageBatch
+	if l.propagateResponse {
+		resBatch = batch.Copy()
+	}
+
+	var batchErr *service.BatchError
+	for i, msg := range batch {
+		if err := l.invokeMessage(ctx, client, batch, i, msg, resBatch); err != nil {
+			if len(batch) == 1 {
+				return err
+			}
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+
+	if resBatch != nil {
+		if err := resBatch.AddSyncResponse(); err != nil {
+			return fmt.Errorf("failed to propagate response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l *lambdaWriter) invokeMessage(ctx context.Context, client lambdaiface.LambdaAPI, batch service.MessageBatch, i int, msg *service.Message, resBatch service.MessageBatch) error {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	if len(payload) > l.maxPayloadSize {
+		return fmt.Errorf("message of size %v exceeds the maximum payload size of %v", len(payload), l.maxPayloadSize)
+	}
+
+	result, err := l.invoke(ctx, client, payload, batch.InterpolatedString(i, l.clientContext))
+	if err != nil {
+		return err
+	}
+	if err := l.functionError(result); err != nil {
+		return err
+	}
+	if resBatch != nil {
+		resBatch[i].SetBytes(result.Payload)
+	}
+	return nil
+}
+
+func (l *lambdaWriter) writeBatched(ctx context.Context, client lambdaiface.LambdaAPI, batch service.MessageBatch) error {
+	items := make([][]byte, len(batch))
+	for i, msg := range batch {
+		b, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+		if items[i], err = lambdaPayloadItem(b); err != nil {
+			return err
+		}
+		if size := len(items[i]) + 2; size > l.maxPayloadSize {
+			return fmt.Errorf("message of size %v exceeds the maximum payload size of %v", size, l.maxPayloadSize)
+		}
+	}
+
+	var batchErr *service.BatchError
+	setFailed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var requeue []int
+	for start := 0; start < len(items); {
+		end := lambdaWindowEnd(items, start, l.maxPayloadSize)
+
+		indexes := make([]int, 0, end-start)
+		for i := start; i < end; i++ {
+			indexes = append(indexes, i)
+		}
+		start = end
+
+		failed, err := l.invokeWindow(ctx, client, batch, items, indexes)
+		if err != nil {
+			for _, i := range indexes {
+				setFailed(i, err)
+			}
+			continue
+		}
+		for _, i := range indexes {
+			if _, exists := failed[i]; exists {
+				requeue = append(requeue, i)
+			}
+		}
+	}
+
+	// Records that the function failed to process are attempted once more with
+	// an invocation each, so that a single bad record does not cause the rest
+	// of its window to be reprocessed.
+	if len(requeue) > 0 {
+		l.mRequeued.Incr(int64(len(requeue)))
+		l.log.Warnf("Requeuing %v records that failed to be processed individually\n", len(requeue))
+	}
+	for _, i := range requeue {
+		failed, err := l.invokeWindow(ctx, client, batch, items, []int{i})
+		if err != nil {
+			setFailed(i, err)
+		} else if ferr, exists := failed[i]; exists {
+			setFailed(i, ferr)
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// invokeWindow invokes the function with the messages at a set of indexes of a
+// batch as a JSON array payload, and returns the errors of the records that the
+// function failed to process.
+func (l *lambdaWriter) invokeWindow(ctx context.Context, client lambdaiface.LambdaAPI, batch service.MessageBatch, items [][]byte, indexes []int) (map[int]error, error) {
+	payload := []byte{'['}
+	for j, i := range indexes {
+		if j > 0 {
+			payload = append(payload, ',')
+		}
+		payload = append(payload, items[i]...)
+	}
+	payload = append(payload, ']')
+
+	result, err := l.invoke(ctx, client, payload, batch.InterpolatedString(indexes[0], l.clientContext))
+	if err != nil {
+		return nil, err
+	}
+
+	failed := map[int]error{}
+	if ferr := l.functionError(result); ferr != nil {
+		for _, i := range indexes {
+			failed[i] = ferr
+		}
+		return failed, nil
+	}
+
+	identifiers, err := lambdaBatchItemFailures(result.Payload)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range identifiers {
+		if id < 0 || id >= len(indexes) {
+			return nil, fmt.Errorf("batch item failure identifier %v is out of range of the payload of %v records", id, len(indexes))
+		}
+		failed[indexes[id]] = errors.New("record reported as a batch item failure by the function")
+	}
+	return failed, nil
+}
+
+// invoke attempts to invoke the function with a raw payload and an optional
+// client context. Failed invocations are retried according to the configured
+// number of retries, where function errors are not considered failed
+// invocations.
+func (l *lambdaWriter) invoke(ctx context.Context, client lambdaiface.LambdaAPI, payload []byte, clientContext string) (*lambda.InvokeOutput, error) {
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(l.function),
+		InvocationType: aws.String(l.invocationType),
+		Payload:        payload,
+	}
+	if clientContext != "" {
+		input.ClientContext = aws.String(base64.StdEncoding.EncodeToString([]byte(clientContext)))
+	}
+
+	remainingRetries := l.retries
+	for {
+		if err := l.waitForAccess(ctx); err != nil {
+			return nil, err
+		}
+
+		ictx, done := context.WithTimeout(ctx, l.timeout)
+		result, err := client.InvokeWithContext(ictx, input)
+		done()
+		if err == nil {
+			return result, nil
+		}
+
+		remainingRetries--
+		if remainingRetries < 0 || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+func (l *lambdaWriter) waitForAccess(ctx context.Context) error {
+	if l.rateLimit == "" {
+		return nil
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := l.mgr.AccessRateLimit(ctx, l.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			l.log.Errorf("Rate limit error: %v\n", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *lambdaWriter) functionError(result *lambda.InvokeOutput) error {
+	if result.FunctionError == nil {
+		return nil
+	}
+	l.mFunctionErr.Incr(1)
+	return fmt.Errorf("function error (%v): %s", *result.FunctionError, result.Payload)
+}
+
+func (l *lambdaWriter) Close(ctx context.Context) error {
+	l.connMut.Lock()
+	l.client = nil
+	l.connMut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// lambdaPayloadItem returns the raw contents of a message when they are valid
+// JSON, otherwise the contents are encoded as a JSON string.
+func lambdaPayloadItem(b []byte) ([]byte, error) {
+	if json.Valid(b) {
+		return b, nil
+	}
+	return json.Marshal(string(b))
+}
+
+// lambdaWindowEnd returns the end index of a window of items starting at an
+// index, where the window fits within a maximum payload size once encoded as a
+// JSON array. A window always contains at least one item.
+func lambdaWindowEnd(items [][]byte, start, maxSize int) int {
+	size := 2
+	for i := start; i < len(items); i++ {
+		itemSize := len(items[i])
+		if i > start {
+			itemSize++
+		}
+		if i > start && size+itemSize > maxSize {
+			return i
+		}
+		size += itemSize
+	}
+	return len(items)
+}
+
+// lambdaBatchItemFailures parses the item identifiers of a partial batch
+// response from a function, where each identifier is the index of a record
+// within the payload of the invocation. Responses that are not partial batch
+// responses yield no identifiers.
+func lambdaBatchItemFailures(payload []byte) ([]int, error) {
+	var res struct {
+		BatchItemFailures []struct {
+			ItemIdentifier json.RawMessage `json:"itemIdentifier"`
+		} `json:"batchItemFailures"`
+	}
+	if err := json.Unmarshal(payload, &res); err != nil {
+		return nil, nil
+	}
+
+	ids := make([]int, 0, len(res.BatchItemFailures))
+	for _, f := range res.BatchItemFailures {
+		raw := string(f.ItemIdentifier)
+		if unquoted, err := strconv.Unquote(raw); err == nil {
+			raw = unquoted
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse batch item failure identifier %s: %v", f.ItemIdentifier, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
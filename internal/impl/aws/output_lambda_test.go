@@ -0,0 +1,187 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lambdaTestInvocation struct {
+	invocationType string
+	clientContext  string
+	payload        string
+}
+
+type mockLambda struct {
+	lambdaiface.LambdaAPI
+	invokeFn func(payload string) *lambda.InvokeOutput
+
+	mut         sync.Mutex
+	invocations []lambdaTestInvocation
+}
+
+func (m *mockLambda) InvokeWithContext(_ context.Context, input *lambda.InvokeInput, _ ...request.Option) (*lambda.InvokeOutput, error) {
+	var clientContext string
+	if input.ClientContext != nil {
+		b, err := base64.StdEncoding.DecodeString(*input.ClientContext)
+		if err != nil {
+			return nil, err
+		}
+		clientContext = string(b)
+	}
+
+	m.mut.Lock()
+	m.invocations = append(m.invocations, lambdaTestInvocation{
+		invocationType: aws.StringValue(input.InvocationType),
+		clientContext:  clientContext,
+		payload:        string(input.Payload),
+	})
+	m.mut.Unlock()
+
+	return m.invokeFn(string(input.Payload)), nil
+}
+
+func TestLambdaWriterPropagateResponse(t *testing.T) {
+	client := &mockLambda{
+		invokeFn: func(payload string) *lambda.InvokeOutput {
+			if payload == `"fail"` {
+				return &lambda.InvokeOutput{
+					FunctionError: aws.String("Unhandled"),
+					Payload:       []byte(`{"errorMessage":"nope"}`),
+				}
+			}
+			return &lambda.InvokeOutput{Payload: []byte("echo: " + payload)}
+		},
+	}
+
+	conf, err := lambdaOutputConfig().ParseYAML(`
+function: foo
+client_context: '{"custom":{"topic":"${! meta("topic") }"}}'
+propagate_response: true
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newLambdaWriterFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+	w.client = client
+
+	resultStore := roundtrip.NewResultStore()
+	storeCtx := context.WithValue(context.Background(), roundtrip.ResultStoreKey, resultStore)
+
+	msgA := service.NewMessage([]byte(`"a"`)).WithContext(storeCtx)
+	msgA.MetaSet("topic", "foo")
+	msgB := service.NewMessage([]byte(`"b"`)).WithContext(storeCtx)
+	msgB.MetaSet("topic", "bar")
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	resMsgs := resultStore.Get()
+	require.Len(t, resMsgs, 1)
+	require.Equal(t, 2, resMsgs[0].Len())
+	assert.Equal(t, `echo: "a"`, string(resMsgs[0].Get(0).Get()))
+	assert.Equal(t, `echo: "b"`, string(resMsgs[0].Get(1).Get()))
+
+	assert.Equal(t, []lambdaTestInvocation{
+		{invocationType: "RequestResponse", clientContext: `{"custom":{"topic":"foo"}}`, payload: `"a"`},
+		{invocationType: "RequestResponse", clientContext: `{"custom":{"topic":"bar"}}`, payload: `"b"`},
+	}, client.invocations)
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`"fail"`))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "function error (Unhandled)")
+}
+
+func TestLambdaWriterBatchPayloads(t *testing.T) {
+	client := &mockLambda{
+		invokeFn: func(payload string) *lambda.InvokeOutput {
+			switch payload {
+			case `[{"id":0},"not json",{"id":2}]`:
+				return &lambda.InvokeOutput{Payload: []byte(`{"batchItemFailures":[{"itemIdentifier":"2"}]}`)}
+			case `[{"id":2}]`:
+				return &lambda.InvokeOutput{
+					FunctionError: aws.String("Unhandled"),
+					Payload:       []byte(`{"errorMessage":"nope"}`),
+				}
+			}
+			return &lambda.InvokeOutput{Payload: []byte(`{}`)}
+		},
+	}
+
+	conf, err := lambdaOutputConfig().ParseYAML(`
+function: foo
+invocation_type: Event
+batch_payloads: true
+max_payload_size: 30
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newLambdaWriterFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+	w.client = client
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":0}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+		service.NewMessage([]byte(`{"id":3}`)),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.IndexedErrors())
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if i == 2 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		return true
+	})
+
+	assert.Equal(t, []lambdaTestInvocation{
+		{invocationType: "Event", payload: `[{"id":0},"not json",{"id":2}]`},
+		{invocationType: "Event", payload: `[{"id":3}]`},
+		{invocationType: "Event", payload: `[{"id":2}]`},
+	}, client.invocations)
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"this message is too large for the payload"`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum payload size")
+}
+
+func TestLambdaWriterConfigErrors(t *testing.T) {
+	for _, yamlStr := range []string{
+		`
+function: foo
+batch_payloads: true
+propagate_response: true
+`,
+		`
+function: foo
+invocation_type: Event
+propagate_response: true
+`,
+		`
+function: ""
+`,
+	} {
+		conf, err := lambdaOutputConfig().ParseYAML(yamlStr, nil)
+		require.NoError(t, err)
+
+		_, err = newLambdaWriterFromConfig(conf, nil, nil)
+		assert.Error(t, err, yamlStr)
+	}
+}
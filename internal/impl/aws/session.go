@@ -1,14 +1,15 @@
 package aws
 
 import (
+	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
 	"github.com/Jeffail/benthos/v3/public/service"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
-func sessionFields() []*service.ConfigField {
+// SessionFields returns the config fields used to configure an AWS session,
+// which can be shared with components of other packages.
+func SessionFields() []*service.ConfigField {
 	return []*service.ConfigField{
 		service.NewStringField("region").
 			Description("The AWS region to target.").
@@ -29,59 +30,63 @@ func sessionFields() []*service.ConfigField {
 			service.NewStringField("token").
 				Description("The token for the credentials being used, required when using short term credentials.").
 				Default("").Advanced(),
+			service.NewStringField("web_identity_token_file").
+				Description("A path to a web identity token file, such as a Kubernetes service account token, which is exchanged for credentials of the role specified with `role`. When running within EKS with IAM roles for service accounts it is not necessary to set this field as the token file is detected from the environment.").
+				Version("3.64.0").
+				Default("").Advanced(),
 			service.NewStringField("role").
 				Description("A role ARN to assume.").
 				Default("").Advanced(),
 			service.NewStringField("role_external_id").
 				Description("An external ID to provide when assuming a role.").
-				Default("").Advanced()).
+				Default("").Advanced(),
+			service.NewObjectListField("role_chain",
+				service.NewStringField("role").
+					Description("A role ARN to assume.").
+					Default(""),
+				service.NewStringField("role_external_id").
+					Description("An external ID to provide when assuming the role.").
+					Default("")).
+				Description("A list of roles to assume in order after `role`, where each role is assumed with the credentials of the previous one.").
+				Version("3.64.0").
+				Default([]interface{}{}).Advanced(),
+			service.NewStringField("sts_endpoint").
+				Description("Allows you to specify a custom endpoint for STS requests made in order to obtain credentials.").
+				Version("3.64.0").
+				Default("").Advanced(),
+			service.NewBoolField("sts_regional_endpoint").
+				Description("Whether STS requests made in order to obtain credentials should target the endpoint of the configured region rather than the global endpoint.").
+				Version("3.64.0").
+				Default(false).Advanced()).
 			Description("Optional manual configuration of AWS credentials to use. More information can be found [in this document](/docs/guides/cloud/aws)."),
 	}
 }
 
-func getSession(parsedConf *service.ParsedConfig, opts ...func(*aws.Config)) (*session.Session, error) {
-	awsConf := aws.NewConfig()
-
-	if region, _ := parsedConf.FieldString("region"); region != "" {
-		awsConf = awsConf.WithRegion(region)
-	}
-	if endpoint, _ := parsedConf.FieldString("endpoint"); endpoint != "" {
-		awsConf = awsConf.WithEndpoint(endpoint)
-	}
-	if profile, _ := parsedConf.FieldString("credentials", "profile"); profile != "" {
-		awsConf = awsConf.WithCredentials(credentials.NewSharedCredentials(
-			"", profile,
-		))
-	} else if id, _ := parsedConf.FieldString("credentials", "id"); id != "" {
-		secret, _ := parsedConf.FieldString("credentials", "secret")
-		token, _ := parsedConf.FieldString("credentials", "token")
-		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
-			id, secret, token,
-		))
-	}
-
-	for _, opt := range opts {
-		opt(awsConf)
-	}
+// GetSession creates an AWS session from a parsed config containing the fields
+// of SessionFields.
+func GetSession(parsedConf *service.ParsedConfig, opts ...func(*aws.Config)) (*session.Session, error) {
+	conf := sess.NewConfig()
+	conf.Region, _ = parsedConf.FieldString("region")
+	conf.Endpoint, _ = parsedConf.FieldString("endpoint")
 
-	sess, err := session.NewSession(awsConf)
-	if err != nil {
-		return nil, err
-	}
+	creds := &conf.Credentials
+	creds.Profile, _ = parsedConf.FieldString("credentials", "profile")
+	creds.ID, _ = parsedConf.FieldString("credentials", "id")
+	creds.Secret, _ = parsedConf.FieldString("credentials", "secret")
+	creds.Token, _ = parsedConf.FieldString("credentials", "token")
+	creds.WebIdentityTokenFile, _ = parsedConf.FieldString("credentials", "web_identity_token_file")
+	creds.Role, _ = parsedConf.FieldString("credentials", "role")
+	creds.ExternalID, _ = parsedConf.FieldString("credentials", "role_external_id")
+	creds.STSEndpoint, _ = parsedConf.FieldString("credentials", "sts_endpoint")
+	creds.STSRegionalEndpoint, _ = parsedConf.FieldBool("credentials", "sts_regional_endpoint")
 
-	if role, _ := parsedConf.FieldString("credentials", "role"); role != "" {
-		var opts []func(*stscreds.AssumeRoleProvider)
-		if externalID, _ := parsedConf.FieldString("credentials", "role_external_id"); externalID != "" {
-			opts = []func(*stscreds.AssumeRoleProvider){
-				func(p *stscreds.AssumeRoleProvider) {
-					p.ExternalID = &externalID
-				},
-			}
-		}
-		sess.Config = sess.Config.WithCredentials(
-			stscreds.NewCredentials(sess, role, opts...),
-		)
+	chain, _ := parsedConf.FieldObjectList("credentials", "role_chain")
+	for _, r := range chain {
+		var role sess.RoleConfig
+		role.Role, _ = r.FieldString("role")
+		role.ExternalID, _ = r.FieldString("role_external_id")
+		creds.RoleChain = append(creds.RoleChain, role)
 	}
 
-	return sess, nil
+	return conf.GetSession(opts...)
 }
@@ -0,0 +1,151 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-amqp"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/gofrs/uuid"
+)
+
+func eventHubsConnectionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("connection_string").
+			Description("A connection string of an Event Hubs namespace or event hub, as obtained from a shared access policy of the Azure portal.").
+			Example("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=${ACCESS_KEY}"),
+		service.NewStringField("event_hub").
+			Description("The name of the event hub. This field can be left empty when the connection string specifies an `EntityPath`.").
+			Default(""),
+	}
+}
+
+// eventHubsConnConfig describes how to connect to an event hub.
+type eventHubsConnConfig struct {
+	host     string
+	keyName  string
+	key      string
+	eventHub string
+}
+
+func eventHubsConnConfigFromParsed(conf *service.ParsedConfig) (c eventHubsConnConfig, err error) {
+	var connStr string
+	if connStr, err = conf.FieldString("connection_string"); err != nil {
+		return
+	}
+	if c, err = parseEventHubsConnectionString(connStr); err != nil {
+		return
+	}
+	var eventHub string
+	if eventHub, err = conf.FieldString("event_hub"); err != nil {
+		return
+	}
+	if eventHub != "" {
+		c.eventHub = eventHub
+	}
+	if c.eventHub == "" {
+		err = errors.New("an event hub must be specified either with the event_hub field or the EntityPath of the connection string")
+	}
+	return
+}
+
+func parseEventHubsConnectionString(connStr string) (c eventHubsConnConfig, err error) {
+	for _, part := range strings.Split(connStr, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.ToLower(kv[0]) {
+		case "endpoint":
+			var u *url.URL
+			if u, err = url.Parse(kv[1]); err != nil {
+				err = fmt.Errorf("failed to parse endpoint: %w", err)
+				return
+			}
+			c.host = u.Host
+		case "sharedaccesskeyname":
+			c.keyName = kv[1]
+		case "sharedaccesskey":
+			c.key = kv[1]
+		case "entitypath":
+			c.eventHub = kv[1]
+		}
+	}
+	if c.host == "" {
+		err = errors.New("connection string does not contain an Endpoint")
+	} else if c.keyName == "" || c.key == "" {
+		err = errors.New("connection string does not contain a SharedAccessKeyName and SharedAccessKey")
+	}
+	return
+}
+
+// dial opens a connection to the Event Hubs namespace, authenticating with the
+// shared access key over SASL.
+func (c eventHubsConnConfig) dial() (*amqp.Client, error) {
+	return amqp.Dial("amqps://"+c.host, amqp.ConnSASLPlain(c.keyName, c.key))
+}
+
+func (c eventHubsConnConfig) partitionAddress(partitionID string) string {
+	return c.eventHub + "/Partitions/" + partitionID
+}
+
+// getPartitionIDs requests the partition identifiers of the event hub from
+// the management node of the namespace.
+func (c eventHubsConnConfig) getPartitionIDs(ctx context.Context, session *amqp.Session) ([]string, error) {
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	replyTo := "benthos-" + u4.String()
+
+	sender, err := session.NewSender(amqp.LinkTargetAddress("$management"))
+	if err != nil {
+		return nil, err
+	}
+	defer sender.Close(ctx)
+
+	receiver, err := session.NewReceiver(
+		amqp.LinkSourceAddress("$management"),
+		amqp.LinkTargetAddress(replyTo),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer receiver.Close(ctx)
+
+	if err = sender.Send(ctx, &amqp.Message{
+		Properties: &amqp.MessageProperties{
+			MessageID: u4.String(),
+			ReplyTo:   &replyTo,
+		},
+		ApplicationProperties: map[string]interface{}{
+			"operation": "READ",
+			"name":      c.eventHub,
+			"type":      "com.microsoft:eventhub",
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	result, err := receiver.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = receiver.AcceptMessage(ctx, result)
+
+	if statusCode, ok := result.ApplicationProperties["status-code"].(int32); !ok || statusCode != 200 {
+		return nil, fmt.Errorf("unsuccessful status code %v, message %v", result.ApplicationProperties["status-code"], result.ApplicationProperties["status-description"])
+	}
+	values, ok := result.Value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("missing value in response message")
+	}
+	ids, ok := values["partition_ids"].([]string)
+	if !ok {
+		return nil, errors.New("missing partition_ids in response message")
+	}
+	return ids, nil
+}
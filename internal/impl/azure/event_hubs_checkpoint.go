@@ -0,0 +1,268 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+)
+
+// eventHubsPartitionState is the ownership and checkpoint of a partition that
+// is shared between consumers of a consumer group.
+type eventHubsPartitionState struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+	Offset  string    `json:"offset"`
+}
+
+func (s eventHubsPartitionState) ownedAt(t time.Time) bool {
+	return s.Owner != "" && t.Before(s.Expires)
+}
+
+var errEventHubsStateModified = errors.New("partition state was modified by another consumer")
+
+// eventHubsCheckpointStore stores the state of partitions with optimistic
+// concurrency, which is used in order to coordinate partition ownership.
+type eventHubsCheckpointStore interface {
+	// Get returns the state of a partition along with an opaque version,
+	// the version is empty when the partition does not yet have a state.
+	Get(ctx context.Context, partitionID string) (eventHubsPartitionState, string, error)
+
+	// Swap writes the state of a partition only if its version still matches,
+	// and otherwise returns errEventHubsStateModified.
+	Swap(ctx context.Context, partitionID, version string, state eventHubsPartitionState) error
+}
+
+//------------------------------------------------------------------------------
+
+// eventHubsCacheStore stores partition states within a cache resource that
+// supports compare-and-swap operations.
+type eventHubsCacheStore struct {
+	cache  *checkpoint.Cache
+	prefix string
+}
+
+func newEventHubsCacheStore(accessCache store.CacheAccessor, name, prefix string) *eventHubsCacheStore {
+	return &eventHubsCacheStore{
+		cache:  store.NewCache(accessCache, name),
+		prefix: prefix,
+	}
+}
+
+func (c *eventHubsCacheStore) Get(ctx context.Context, partitionID string) (state eventHubsPartitionState, version string, err error) {
+	var value []byte
+	if value, err = c.cache.Get(ctx, c.prefix+partitionID); err != nil || value == nil {
+		return
+	}
+	if err = json.Unmarshal(value, &state); err != nil {
+		err = fmt.Errorf("failed to parse partition state: %w", err)
+		return
+	}
+	version = string(value)
+	return
+}
+
+func (c *eventHubsCacheStore) Swap(ctx context.Context, partitionID, version string, state eventHubsPartitionState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	var old []byte
+	if version != "" {
+		old = []byte(version)
+	}
+	if err = c.cache.Swap(ctx, c.prefix+partitionID, old, value); errors.Is(err, checkpoint.ErrCacheModified) {
+		return errEventHubsStateModified
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// eventHubsBlobStore stores partition states as blobs within an Azure Blob
+// Storage container, using ETags for optimistic concurrency.
+type eventHubsBlobStore struct {
+	container *storage.Container
+	prefix    string
+}
+
+func newEventHubsBlobStore(connectionString, containerName, prefix string) (*eventHubsBlobStore, error) {
+	var client storage.Client
+	var err error
+	if strings.Contains(connectionString, "UseDevelopmentStorage=true;") {
+		client, err = storage.NewEmulatorClient()
+	} else {
+		client, err = storage.NewClientFromConnectionString(connectionString)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure storage account credentials: %v", err)
+	}
+	return &eventHubsBlobStore{
+		container: client.GetBlobService().GetContainerReference(containerName),
+		prefix:    prefix,
+	}, nil
+}
+
+func (b *eventHubsBlobStore) Get(ctx context.Context, partitionID string) (state eventHubsPartitionState, version string, err error) {
+	blob := b.container.GetBlobReference(b.prefix + partitionID)
+
+	var r io.ReadCloser
+	if r, err = blob.Get(nil); err != nil {
+		if serr, ok := err.(storage.AzureStorageServiceError); ok && serr.StatusCode == http.StatusNotFound {
+			err = nil
+		}
+		return
+	}
+	defer r.Close()
+
+	if err = json.NewDecoder(r).Decode(&state); err != nil {
+		err = fmt.Errorf("failed to parse partition state: %w", err)
+		return
+	}
+	version = blob.Properties.Etag
+	return
+}
+
+func (b *eventHubsBlobStore) Swap(ctx context.Context, partitionID, version string, state eventHubsPartitionState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	opts := &storage.PutBlobOptions{}
+	if version == "" {
+		opts.IfNoneMatch = "*"
+	} else {
+		opts.IfMatch = version
+	}
+
+	blob := b.container.GetBlobReference(b.prefix + partitionID)
+	if err = blob.CreateBlockBlobFromReader(bytes.NewReader(value), opts); err != nil {
+		if containerNotFound(err) {
+			if _, err = b.container.CreateIfNotExists(&storage.CreateContainerOptions{}); err != nil {
+				return fmt.Errorf("failed to create container: %w", err)
+			}
+			err = blob.CreateBlockBlobFromReader(bytes.NewReader(value), opts)
+		}
+	}
+	if serr, ok := err.(storage.AzureStorageServiceError); ok &&
+		(serr.StatusCode == http.StatusPreconditionFailed || serr.StatusCode == http.StatusConflict) {
+		return errEventHubsStateModified
+	}
+	return err
+}
+
+func containerNotFound(err error) bool {
+	if serr, ok := err.(storage.AzureStorageServiceError); ok {
+		return serr.Code == "ContainerNotFound"
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+// eventHubsMemoryStore stores partition states in memory, which is used when a
+// checkpoint store is not configured and therefore offsets are not persisted.
+type eventHubsMemoryStore struct {
+	mut     sync.Mutex
+	seq     int
+	states  map[string]eventHubsPartitionState
+	version map[string]string
+}
+
+func newEventHubsMemoryStore() *eventHubsMemoryStore {
+	return &eventHubsMemoryStore{
+		states:  map[string]eventHubsPartitionState{},
+		version: map[string]string{},
+	}
+}
+
+func (m *eventHubsMemoryStore) Get(ctx context.Context, partitionID string) (eventHubsPartitionState, string, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.states[partitionID], m.version[partitionID], nil
+}
+
+func (m *eventHubsMemoryStore) Swap(ctx context.Context, partitionID, version string, state eventHubsPartitionState) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.version[partitionID] != version {
+		return errEventHubsStateModified
+	}
+	m.seq++
+	m.states[partitionID] = state
+	m.version[partitionID] = fmt.Sprintf("%v", m.seq)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// eventHubsPartitionsToClaim determines which partitions a consumer should
+// attempt to claim in order to balance partitions evenly across all
+// consumers with active ownership. Partitions that are not owned are claimed
+// first, and only when there are none a single partition is stolen from the
+// consumer with the most partitions.
+func eventHubsPartitionsToClaim(clientID string, states map[string]eventHubsPartitionState, now time.Time) []string {
+	owned := map[string][]string{clientID: nil}
+	var unowned []string
+	for id, s := range states {
+		if s.ownedAt(now) {
+			owned[s.Owner] = append(owned[s.Owner], id)
+		} else {
+			unowned = append(unowned, id)
+		}
+	}
+
+	minPer, extra := len(states)/len(owned), len(states)%len(owned)
+	mine := len(owned[clientID])
+
+	desired := minPer
+	if extra > 0 {
+		othersAboveMin := 0
+		for owner, ids := range owned {
+			if owner != clientID && len(ids) > minPer {
+				othersAboveMin++
+			}
+		}
+		if mine > minPer || othersAboveMin < extra {
+			desired++
+		}
+	}
+
+	need := desired - mine
+	if need <= 0 {
+		return nil
+	}
+
+	if len(unowned) > 0 {
+		rand.Shuffle(len(unowned), func(i, j int) {
+			unowned[i], unowned[j] = unowned[j], unowned[i]
+		})
+		if len(unowned) > need {
+			unowned = unowned[:need]
+		}
+		return unowned
+	}
+
+	var victim []string
+	for owner, ids := range owned {
+		if owner != clientID && len(ids) > desired && len(ids) > len(victim) {
+			victim = ids
+		}
+	}
+	if len(victim) == 0 {
+		return nil
+	}
+	return []string{victim[rand.Intn(len(victim))]}
+}
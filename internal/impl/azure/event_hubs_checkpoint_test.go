@@ -0,0 +1,238 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type casCache struct {
+	mut    sync.Mutex
+	values map[string][]byte
+}
+
+func (c *casCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	v, exists := c.values[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *casCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *casCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if _, exists := c.values[key]; exists {
+		return service.ErrKeyAlreadyExists
+	}
+	c.values[key] = value
+	return nil
+}
+
+func (c *casCache) Delete(ctx context.Context, key string) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *casCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	current, exists := c.values[key]
+	if old == nil {
+		if exists {
+			return service.ErrKeyAlreadyExists
+		}
+	} else if !exists || !bytes.Equal(current, old) {
+		return service.ErrKeyValueMismatch
+	}
+	c.values[key] = value
+	return nil
+}
+
+func (c *casCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func testCacheAccessor(cache service.Cache) store.CacheAccessor {
+	return func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		if name != "foo" {
+			return service.ErrKeyNotFound
+		}
+		fn(cache)
+		return nil
+	}
+}
+
+func testCheckpointStore(t *testing.T, cs eventHubsCheckpointStore) {
+	t.Helper()
+
+	ctx := context.Background()
+	expires := time.Now().Add(time.Minute).Round(0).UTC()
+
+	state, version, err := cs.Get(ctx, "0")
+	require.NoError(t, err)
+	assert.Equal(t, eventHubsPartitionState{}, state)
+	assert.Equal(t, "", version)
+
+	stateA := eventHubsPartitionState{Owner: "a", Expires: expires, Offset: "10"}
+	require.NoError(t, cs.Swap(ctx, "0", version, stateA))
+
+	// A second claim of the same version must fail.
+	err = cs.Swap(ctx, "0", version, eventHubsPartitionState{Owner: "b", Expires: expires})
+	assert.ErrorIs(t, err, errEventHubsStateModified)
+
+	state, version, err = cs.Get(ctx, "0")
+	require.NoError(t, err)
+	assert.Equal(t, stateA, state)
+	assert.NotEqual(t, "", version)
+
+	stateB := eventHubsPartitionState{Owner: "b", Expires: expires, Offset: "10"}
+	require.NoError(t, cs.Swap(ctx, "0", version, stateB))
+
+	err = cs.Swap(ctx, "0", version, stateA)
+	assert.ErrorIs(t, err, errEventHubsStateModified)
+
+	state, _, err = cs.Get(ctx, "0")
+	require.NoError(t, err)
+	assert.Equal(t, stateB, state)
+
+	state, version, err = cs.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, eventHubsPartitionState{}, state)
+	assert.Equal(t, "", version)
+}
+
+func TestEventHubsMemoryStore(t *testing.T) {
+	testCheckpointStore(t, newEventHubsMemoryStore())
+}
+
+func TestEventHubsCacheStore(t *testing.T) {
+	cache := &casCache{values: map[string][]byte{}}
+	testCheckpointStore(t, newEventHubsCacheStore(testCacheAccessor(cache), "foo", "bar/"))
+
+	keys := []string{}
+	for k := range cache.values {
+		keys = append(keys, k)
+	}
+	assert.Equal(t, []string{"bar/0"}, keys)
+}
+
+func TestEventHubsCacheStoreNoCAS(t *testing.T) {
+	cacheStore := newEventHubsCacheStore(testCacheAccessor(struct{ service.Cache }{&casCache{values: map[string][]byte{}}}), "foo", "")
+	err := cacheStore.Swap(context.Background(), "0", "", eventHubsPartitionState{Owner: "a"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support compare-and-swap")
+}
+
+func TestEventHubsPartitionsToClaim(t *testing.T) {
+	now := time.Now()
+	owned := func(owner string) eventHubsPartitionState {
+		return eventHubsPartitionState{Owner: owner, Expires: now.Add(time.Minute)}
+	}
+	expired := func(owner string) eventHubsPartitionState {
+		return eventHubsPartitionState{Owner: owner, Expires: now.Add(-time.Minute)}
+	}
+
+	tests := map[string]struct {
+		states map[string]eventHubsPartitionState
+		claims int
+		from   []string
+	}{
+		"no owners": {
+			states: map[string]eventHubsPartitionState{"0": {}, "1": {}, "2": {}},
+			claims: 3,
+			from:   []string{"0", "1", "2"},
+		},
+		"one other owner": {
+			states: map[string]eventHubsPartitionState{"0": owned("b"), "1": {}, "2": {}},
+			claims: 2,
+			from:   []string{"1", "2"},
+		},
+		"expired ownership": {
+			states: map[string]eventHubsPartitionState{"0": owned("b"), "1": expired("b"), "2": expired("c"), "3": owned("b")},
+			claims: 2,
+			from:   []string{"1", "2"},
+		},
+		"already balanced": {
+			states: map[string]eventHubsPartitionState{"0": owned("a"), "1": owned("b"), "2": owned("b")},
+			claims: 0,
+		},
+		"already balanced even": {
+			states: map[string]eventHubsPartitionState{"0": owned("a"), "1": owned("b"), "2": owned("a"), "3": owned("b")},
+			claims: 0,
+		},
+		"steal from greedy owner": {
+			states: map[string]eventHubsPartitionState{"0": owned("b"), "1": owned("b"), "2": owned("b"), "3": owned("c")},
+			claims: 1,
+			from:   []string{"0", "1", "2"},
+		},
+		"steal one at a time": {
+			states: map[string]eventHubsPartitionState{"0": owned("b"), "1": owned("b"), "2": owned("b"), "3": owned("b")},
+			claims: 1,
+			from:   []string{"0", "1", "2", "3"},
+		},
+		"more consumers than partitions": {
+			states: map[string]eventHubsPartitionState{"0": owned("b"), "1": owned("c")},
+			claims: 0,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			claims := eventHubsPartitionsToClaim("a", test.states, now)
+			require.Len(t, claims, test.claims)
+			for _, c := range claims {
+				assert.Contains(t, test.from, c)
+			}
+		})
+	}
+}
+
+func TestEventHubsPartitionsBalanceConverges(t *testing.T) {
+	now := time.Now()
+	states := map[string]eventHubsPartitionState{}
+	for i := 0; i < 10; i++ {
+		states[fmt.Sprintf("%v", i)] = eventHubsPartitionState{}
+	}
+
+	consumers := []string{"a", "b", "c"}
+	for round := 0; round < 20; round++ {
+		for _, c := range consumers {
+			for _, id := range eventHubsPartitionsToClaim(c, states, now) {
+				states[id] = eventHubsPartitionState{Owner: c, Expires: now.Add(time.Minute)}
+			}
+		}
+	}
+
+	counts := map[string]int{}
+	for _, s := range states {
+		counts[s.Owner]++
+	}
+	sizes := []int{}
+	for _, c := range consumers {
+		sizes = append(sizes, counts[c])
+	}
+	sort.Ints(sizes)
+	assert.Equal(t, []int{3, 3, 4}, sizes)
+}
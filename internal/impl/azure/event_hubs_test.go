@@ -0,0 +1,78 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventHubsConnectionString(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		output      eventHubsConnConfig
+		errContains string
+	}{
+		"namespace": {
+			input: "Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=abc=",
+			output: eventHubsConnConfig{
+				host:    "foo.servicebus.windows.net",
+				keyName: "RootManageSharedAccessKey",
+				key:     "abc=",
+			},
+		},
+		"event hub": {
+			input: "Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz;EntityPath=buz",
+			output: eventHubsConnConfig{
+				host:     "foo.servicebus.windows.net",
+				keyName:  "bar",
+				key:      "baz",
+				eventHub: "buz",
+			},
+		},
+		"missing endpoint": {
+			input:       "SharedAccessKeyName=bar;SharedAccessKey=baz",
+			errContains: "Endpoint",
+		},
+		"missing key": {
+			input:       "Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar",
+			errContains: "SharedAccessKey",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			c, err := parseEventHubsConnectionString(test.input)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, c)
+		})
+	}
+}
+
+func TestEventHubsConnConfigEventHub(t *testing.T) {
+	conf, err := eventHubsOutputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz;EntityPath=buz
+event_hub: qux
+`, nil)
+	require.NoError(t, err)
+
+	c, err := eventHubsConnConfigFromParsed(conf)
+	require.NoError(t, err)
+	assert.Equal(t, "qux", c.eventHub)
+	assert.Equal(t, "qux/Partitions/3", c.partitionAddress("3"))
+
+	conf, err = eventHubsOutputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz
+`, nil)
+	require.NoError(t, err)
+
+	_, err = eventHubsConnConfigFromParsed(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "event hub must be specified")
+}
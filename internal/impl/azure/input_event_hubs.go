@@ -0,0 +1,600 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-amqp"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/gofrs/uuid"
+)
+
+func eventHubsInputConfigSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services", "Azure").
+		Summary("Consumes events from the partitions of an Azure Event Hub.").
+		Description(`
+Events are consumed from every partition of the event hub, or only the partitions listed in ` + "`partition_ids`" + `, as a member of the consumer group ` + "`consumer_group`" + `.
+
+### Checkpoints
+
+The offset of each partition is stored once its events have been acknowledged, either within a [cache resource](/docs/components/caches/about) identified by ` + "`checkpoint_cache`" + `, or as blobs within an Azure Blob Storage container configured with the ` + "`checkpoint_blob`" + ` fields. When a partition is claimed consumption resumes after its stored offset, and otherwise starts from either the oldest or the newest event according to ` + "`start_from_oldest`" + `.
+
+The checkpoint store is also used in order to balance the ownership of partitions across all instances of this input that share a consumer group, where each partition is owned by a single instance at a time. Ownership expires when it has not been renewed within the ` + "`lease_period`" + `, at which point the partition can be claimed by another instance. Cache resources used for checkpoints must support compare-and-swap operations, such as the ` + "`memory`" + `, ` + "`redis`" + ` and ` + "`memcached`" + ` caches.
+
+When neither a checkpoint cache nor a blob container is configured offsets are not persisted, and every instance of this input consumes all partitions.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- eventhubs_partition_id
+- eventhubs_offset
+- eventhubs_sequence_number
+- eventhubs_enqueued_time
+- eventhubs_partition_key
+- All application properties of the event
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`)
+
+	for _, f := range eventHubsConnectionFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringField("consumer_group").
+			Description("The consumer group to consume events as.").
+			Default("$Default")).
+		Field(service.NewStringListField("partition_ids").
+			Description("An optional list of partitions to consume from. When empty all partitions of the event hub are consumed.").
+			Default([]string{}).
+			Advanced()).
+		Field(service.NewBoolField("start_from_oldest").
+			Description("Whether to consume from the oldest available event of a partition when it does not yet have a stored offset, otherwise only events enqueued after the partition is claimed are consumed.").
+			Default(true)).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("The name of a cache resource to store partition offsets and ownership within.").
+			Default("")).
+		Field(service.NewObjectField("checkpoint_blob",
+			service.NewStringField("storage_connection_string").
+				Description("The connection string of an Azure storage account.").
+				Default(""),
+			service.NewStringField("container").
+				Description("The name of a container to store partition offsets and ownership within, which is created if it does not already exist.").
+				Default(""),
+		).Description("Optionally store partition offsets and ownership as blobs within an Azure Blob Storage container instead of a cache resource.")).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of events of a partition that can be in flight awaiting acknowledgement at a given time. Increasing this limit enables parallel processing of the events of a partition.").
+			Default(1024).
+			Advanced()).
+		Field(service.NewDurationField("commit_period").
+			Description("The period of time between each update to the stored offsets and ownership of claimed partitions.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewDurationField("rebalance_period").
+			Description("The period of time between each attempt to balance partitions across instances.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewDurationField("lease_period").
+			Description("The period of time after which the ownership of a partition that has not been renewed expires.").
+			Default("30s").
+			Advanced()).
+		Example("Checkpoints in Redis", `
+Consume events with multiple instances sharing partitions, storing offsets within a Redis cache:`,
+			`
+input:
+  azure_event_hubs:
+    connection_string: ${EVENT_HUBS_CONNECTION_STRING}
+    event_hub: orders
+    consumer_group: benthos
+    checkpoint_cache: checkpoints
+
+cache_resources:
+  - label: checkpoints
+    redis:
+      url: redis://localhost:6379
+      prefix: event_hubs_
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"azure_event_hubs", eventHubsInputConfigSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newEventHubsInput(conf, mgr.AccessCache, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventHubsMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+// eventHubsPartition is a partition claimed by this input.
+type eventHubsPartition struct {
+	id           string
+	checkpointer *checkpoint.Capped
+
+	mut            sync.Mutex
+	receivedOffset string
+	ackedOffset    string
+	running        bool
+	cancel         func()
+}
+
+type eventHubsInput struct {
+	conn            eventHubsConnConfig
+	consumerGroup   string
+	partitionIDs    []string
+	startFromOldest bool
+	checkpointLimit int
+	commitPeriod    time.Duration
+	rebalancePeriod time.Duration
+	leasePeriod     time.Duration
+	clientID        string
+	store           eventHubsCheckpointStore
+	log             *service.Logger
+
+	cMut    sync.Mutex
+	client  *amqp.Client
+	session *amqp.Session
+	msgChan chan eventHubsMessage
+
+	// Only accessed by the balancing goroutine.
+	claimed map[string]*eventHubsPartition
+
+	ctx      context.Context
+	done     func()
+	loopDone chan struct{}
+}
+
+func newEventHubsInput(conf *service.ParsedConfig, accessCache store.CacheAccessor, log *service.Logger) (*eventHubsInput, error) {
+	e := &eventHubsInput{
+		log:      log,
+		claimed:  map[string]*eventHubsPartition{},
+		loopDone: make(chan struct{}),
+	}
+	e.ctx, e.done = context.WithCancel(context.Background())
+
+	var err error
+	if e.conn, err = eventHubsConnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if e.consumerGroup, err = conf.FieldString("consumer_group"); err != nil {
+		return nil, err
+	}
+	if e.partitionIDs, err = conf.FieldStringList("partition_ids"); err != nil {
+		return nil, err
+	}
+	if e.startFromOldest, err = conf.FieldBool("start_from_oldest"); err != nil {
+		return nil, err
+	}
+	if e.checkpointLimit, err = conf.FieldInt("checkpoint_limit"); err != nil {
+		return nil, err
+	}
+	if e.checkpointLimit <= 0 {
+		return nil, errors.New("checkpoint_limit must be larger than zero")
+	}
+	if e.commitPeriod, err = conf.FieldDuration("commit_period"); err != nil {
+		return nil, err
+	}
+	if e.rebalancePeriod, err = conf.FieldDuration("rebalance_period"); err != nil {
+		return nil, err
+	}
+	if e.leasePeriod, err = conf.FieldDuration("lease_period"); err != nil {
+		return nil, err
+	}
+
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	e.clientID = u4.String()
+
+	prefix := e.conn.host + "/" + e.conn.eventHub + "/" + e.consumerGroup + "/"
+
+	cacheName, err := conf.FieldString("checkpoint_cache")
+	if err != nil {
+		return nil, err
+	}
+	blobConnStr, err := conf.FieldString("checkpoint_blob", "storage_connection_string")
+	if err != nil {
+		return nil, err
+	}
+	blobContainer, err := conf.FieldString("checkpoint_blob", "container")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cacheName != "" && blobContainer != "":
+		return nil, errors.New("only one of checkpoint_cache and checkpoint_blob can be specified")
+	case cacheName != "":
+		e.store = newEventHubsCacheStore(accessCache, cacheName, prefix)
+	case blobContainer != "":
+		if blobConnStr == "" {
+			return nil, errors.New("a storage connection string must be specified in order to store checkpoints within a blob container")
+		}
+		if e.store, err = newEventHubsBlobStore(blobConnStr, blobContainer, prefix); err != nil {
+			return nil, err
+		}
+	default:
+		e.store = newEventHubsMemoryStore()
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (e *eventHubsInput) Connect(ctx context.Context) error {
+	e.cMut.Lock()
+	defer e.cMut.Unlock()
+	if e.msgChan != nil {
+		return nil
+	}
+
+	if err := e.dialLocked(); err != nil {
+		return err
+	}
+
+	if len(e.partitionIDs) == 0 {
+		ids, err := e.conn.getPartitionIDs(ctx, e.session)
+		if err != nil {
+			e.closeConnLocked(ctx)
+			return fmt.Errorf("failed to obtain partitions of event hub: %w", err)
+		}
+		e.partitionIDs = ids
+	}
+
+	e.msgChan = make(chan eventHubsMessage)
+	go e.loop()
+
+	e.log.Infof("Receiving events from Azure Event Hub %v as consumer %v\n", e.conn.eventHub, e.clientID)
+	return nil
+}
+
+func (e *eventHubsInput) dialLocked() error {
+	client, err := e.conn.dial()
+	if err != nil {
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return err
+	}
+	e.client, e.session = client, session
+	return nil
+}
+
+func (e *eventHubsInput) closeConnLocked(ctx context.Context) {
+	if e.session != nil {
+		_ = e.session.Close(ctx)
+		e.session = nil
+	}
+	if e.client != nil {
+		_ = e.client.Close()
+		e.client = nil
+	}
+}
+
+// loop periodically balances, renews and commits the partitions claimed by
+// this input, and starts consuming partitions that are claimed.
+func (e *eventHubsInput) loop() {
+	defer func() {
+		for id, p := range e.claimed {
+			p.stop()
+			// Release ownership so that other consumers can claim the partition
+			// immediately.
+			e.commit(context.Background(), p, true)
+			delete(e.claimed, id)
+		}
+		close(e.loopDone)
+	}()
+
+	commitTicker := time.NewTicker(e.commitPeriod)
+	defer commitTicker.Stop()
+	rebalanceTicker := time.NewTicker(e.rebalancePeriod)
+	defer rebalanceTicker.Stop()
+
+	e.rebalance()
+	for {
+		select {
+		case <-commitTicker.C:
+			for id, p := range e.claimed {
+				if !e.commit(e.ctx, p, false) {
+					p.stop()
+					delete(e.claimed, id)
+				}
+			}
+			e.startPartitions()
+		case <-rebalanceTicker.C:
+			e.rebalance()
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *eventHubsInput) rebalance() {
+	states := make(map[string]eventHubsPartitionState, len(e.partitionIDs))
+	versions := make(map[string]string, len(e.partitionIDs))
+	for _, id := range e.partitionIDs {
+		state, version, err := e.store.Get(e.ctx, id)
+		if err != nil {
+			if e.ctx.Err() == nil {
+				e.log.Errorf("Failed to obtain state of partition %v: %v\n", id, err)
+			}
+			return
+		}
+		states[id], versions[id] = state, version
+	}
+
+	for _, id := range eventHubsPartitionsToClaim(e.clientID, states, time.Now()) {
+		state := states[id]
+		if state.Owner != "" && state.Owner != e.clientID {
+			e.log.Debugf("Attempting to claim partition %v from consumer %v\n", id, state.Owner)
+		}
+		state.Owner = e.clientID
+		state.Expires = time.Now().Add(e.leasePeriod)
+		if err := e.store.Swap(e.ctx, id, versions[id], state); err != nil {
+			if !errors.Is(err, errEventHubsStateModified) && e.ctx.Err() == nil {
+				e.log.Errorf("Failed to claim partition %v: %v\n", id, err)
+			}
+			continue
+		}
+		e.log.Debugf("Claimed partition %v\n", id)
+		e.claimed[id] = &eventHubsPartition{
+			id:             id,
+			checkpointer:   checkpoint.NewCapped(int64(e.checkpointLimit)),
+			receivedOffset: state.Offset,
+			ackedOffset:    state.Offset,
+		}
+	}
+	e.startPartitions()
+}
+
+// commit renews the ownership of a partition and stores its latest
+// acknowledged offset, returns false if the partition is no longer owned.
+func (e *eventHubsInput) commit(ctx context.Context, p *eventHubsPartition, release bool) bool {
+	state, version, err := e.store.Get(ctx, p.id)
+	if err != nil {
+		e.log.Errorf("Failed to obtain state of partition %v: %v\n", p.id, err)
+		return true
+	}
+	if state.Owner != e.clientID {
+		e.log.Debugf("Partition %v has been claimed by consumer %v\n", p.id, state.Owner)
+		return false
+	}
+
+	p.mut.Lock()
+	state.Offset = p.ackedOffset
+	p.mut.Unlock()
+
+	state.Expires = time.Now().Add(e.leasePeriod)
+	if release {
+		state.Owner = ""
+		state.Expires = time.Time{}
+	}
+	if err := e.store.Swap(ctx, p.id, version, state); err != nil {
+		if errors.Is(err, errEventHubsStateModified) {
+			e.log.Debugf("Partition %v has been claimed by another consumer\n", p.id)
+			return false
+		}
+		e.log.Errorf("Failed to store state of partition %v: %v\n", p.id, err)
+	}
+	return true
+}
+
+// startPartitions starts consuming claimed partitions that are not currently
+// being consumed.
+func (e *eventHubsInput) startPartitions() {
+	for _, p := range e.claimed {
+		p.mut.Lock()
+		running := p.running
+		p.mut.Unlock()
+		if running {
+			continue
+		}
+		if err := e.startPartition(p); err != nil {
+			if e.ctx.Err() == nil {
+				e.log.Errorf("Failed to consume partition %v: %v\n", p.id, err)
+			}
+			return
+		}
+	}
+}
+
+func (e *eventHubsInput) startPartition(p *eventHubsPartition) error {
+	p.mut.Lock()
+	offset := p.receivedOffset
+	p.mut.Unlock()
+
+	filter := "amqp.annotation.x-opt-offset > '" + offset + "'"
+	if offset == "" {
+		if e.startFromOldest {
+			filter = "amqp.annotation.x-opt-offset > '-1'"
+		} else {
+			filter = "amqp.annotation.x-opt-enqueued-time > '" + strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10) + "'"
+		}
+	}
+
+	e.cMut.Lock()
+	receiver, err := e.newReceiverLocked(p.id, filter)
+	if err != nil {
+		// The connection may have been lost, in which case we try once more
+		// with a new connection.
+		e.closeConnLocked(e.ctx)
+		if err = e.dialLocked(); err == nil {
+			receiver, err = e.newReceiverLocked(p.id, filter)
+		}
+	}
+	e.cMut.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(e.ctx)
+	p.mut.Lock()
+	p.running = true
+	p.cancel = cancel
+	p.mut.Unlock()
+
+	go e.consumePartition(ctx, p, receiver)
+	return nil
+}
+
+func (e *eventHubsInput) newReceiverLocked(partitionID, filter string) (*amqp.Receiver, error) {
+	if e.session == nil {
+		return nil, service.ErrNotConnected
+	}
+	return e.session.NewReceiver(
+		amqp.LinkSourceAddress(e.conn.eventHub+"/ConsumerGroups/"+e.consumerGroup+"/Partitions/"+partitionID),
+		amqp.LinkSenderSettle(amqp.ModeSettled),
+		amqp.LinkReceiverSettle(amqp.ModeFirst),
+		amqp.LinkCredit(uint32(e.checkpointLimit)),
+		amqp.LinkSelectorFilter(filter),
+	)
+}
+
+func (p *eventHubsPartition) stop() {
+	p.mut.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.mut.Unlock()
+}
+
+func (e *eventHubsInput) consumePartition(ctx context.Context, p *eventHubsPartition, receiver *amqp.Receiver) {
+	defer func() {
+		_ = receiver.Close(context.Background())
+		p.mut.Lock()
+		p.running = false
+		p.cancel()
+		p.mut.Unlock()
+	}()
+
+	e.log.Debugf("Consuming partition %v\n", p.id)
+	for {
+		amqpMsg, err := receiver.Receive(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.log.Errorf("Failed to receive events from partition %v: %v\n", p.id, err)
+			}
+			return
+		}
+
+		msg := eventHubsMessageFromAMQP(p.id, amqpMsg)
+		offset, _ := msg.MetaGet("eventhubs_offset")
+
+		resolveFn, err := p.checkpointer.Track(ctx, offset, 1)
+		if err != nil {
+			return
+		}
+		p.mut.Lock()
+		p.receivedOffset = offset
+		p.mut.Unlock()
+
+		select {
+		case e.msgChan <- eventHubsMessage{
+			msg: msg,
+			ackFn: func(ctx context.Context, err error) error {
+				if err != nil {
+					// Nacks are handled by AutoRetryNacks.
+					return nil
+				}
+				if highest, ok := resolveFn().(string); ok {
+					p.mut.Lock()
+					p.ackedOffset = highest
+					p.mut.Unlock()
+				}
+				return nil
+			},
+		}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func eventHubsMessageFromAMQP(partitionID string, amqpMsg *amqp.Message) *service.Message {
+	msg := service.NewMessage(amqpMsg.GetData())
+	msg.MetaSet("eventhubs_partition_id", partitionID)
+
+	for k, v := range amqpMsg.Annotations {
+		key, _ := k.(string)
+		switch key {
+		case "x-opt-offset":
+			msg.MetaSet("eventhubs_offset", fmt.Sprint(v))
+		case "x-opt-sequence-number":
+			msg.MetaSet("eventhubs_sequence_number", fmt.Sprint(v))
+		case "x-opt-enqueued-time":
+			if t, ok := v.(time.Time); ok {
+				msg.MetaSet("eventhubs_enqueued_time", t.Format(time.RFC3339Nano))
+			}
+		case "x-opt-partition-key":
+			msg.MetaSet("eventhubs_partition_key", fmt.Sprint(v))
+		}
+	}
+	for k, v := range amqpMsg.ApplicationProperties {
+		msg.MetaSet(k, fmt.Sprint(v))
+	}
+	return msg
+}
+
+func (e *eventHubsInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	e.cMut.Lock()
+	msgChan := e.msgChan
+	e.cMut.Unlock()
+	if msgChan == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m := <-msgChan:
+		return m.msg, m.ackFn, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (e *eventHubsInput) Close(ctx context.Context) error {
+	e.done()
+
+	e.cMut.Lock()
+	started := e.msgChan != nil
+	e.cMut.Unlock()
+
+	if started {
+		select {
+		case <-e.loopDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	e.cMut.Lock()
+	e.closeConnLocked(ctx)
+	e.cMut.Unlock()
+	return nil
+}
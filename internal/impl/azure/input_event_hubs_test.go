@@ -0,0 +1,71 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHubsInputConfig(t *testing.T) {
+	conf, err := eventHubsInputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz;EntityPath=buz
+consumer_group: qux
+checkpoint_cache: foo
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newEventHubsInput(conf, testCacheAccessor(nil), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "buz", i.conn.eventHub)
+	assert.Equal(t, "qux", i.consumerGroup)
+	assert.True(t, i.startFromOldest)
+	assert.NotEmpty(t, i.clientID)
+	require.IsType(t, &eventHubsCacheStore{}, i.store)
+	assert.Equal(t, "foo.servicebus.windows.net/buz/qux/", i.store.(*eventHubsCacheStore).prefix)
+}
+
+func TestEventHubsInputConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"cache and blob": {
+			conf: `
+checkpoint_cache: foo
+checkpoint_blob:
+  storage_connection_string: UseDevelopmentStorage=true;
+  container: bar
+`,
+			errContains: "only one of",
+		},
+		"blob without connection string": {
+			conf: `
+checkpoint_blob:
+  container: bar
+`,
+			errContains: "storage connection string must be specified",
+		},
+		"bad checkpoint limit": {
+			conf: `
+checkpoint_limit: 0
+`,
+			errContains: "checkpoint_limit",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := eventHubsInputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz;EntityPath=buz
+`+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newEventHubsInput(conf, testCacheAccessor(nil), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
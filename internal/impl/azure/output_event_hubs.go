@@ -0,0 +1,299 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/go-amqp"
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	// The message format of an AMQP message that carries a batch of events
+	// within its data sections.
+	eventHubsBatchMessageFormat uint32 = 0x80013700
+
+	eventHubsPartitionKeyAnnotation = "x-opt-partition-key"
+)
+
+func eventHubsOutputConfigSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services", "Azure").
+		Summary("Sends messages as events to an Azure Event Hub.").
+		Description(output.Description(true, true, `
+Events are sent to a partition chosen by Event Hubs, or when `+"`partition_key`"+` is set then events that share a partition key are sent to the same partition. Alternatively, events can be sent to a specific partition by setting `+"`partition_id`"+`, in which case the field `+"`partition_key`"+` is ignored.
+
+The messages of a batch are sent with as few requests as possible, where each request contains events that share a partition and partition key and does not exceed `+"`max_batch_bytes`"+` in size.`))
+
+	for _, f := range eventHubsConnectionFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField("partition_key").
+			Description("An optional key that determines the partition of each event, events with the same key are sent to the same partition.").
+			Example(`${! meta("kafka_key") }`).
+			Example(`${! json("user.id") }`).
+			Default("")).
+		Field(service.NewInterpolatedStringField("partition_id").
+			Description("An optional partition to send each event to.").
+			Default("").
+			Advanced()).
+		Field(service.NewIntField("max_batch_bytes").
+			Description("The maximum size in bytes of each request, which must not exceed the maximum message size of the Event Hubs tier.").
+			Default(1048576).
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching"))
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"azure_event_hubs", eventHubsOutputConfigSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newEventHubsWriterFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventHubsWriter struct {
+	conn          eventHubsConnConfig
+	partitionKey  *service.InterpolatedString
+	partitionID   *service.InterpolatedString
+	maxBatchBytes int
+	log           *service.Logger
+
+	connMut sync.Mutex
+	client  *amqp.Client
+	session *amqp.Session
+	senders map[string]*amqp.Sender
+}
+
+func newEventHubsWriterFromConfig(conf *service.ParsedConfig, log *service.Logger) (*eventHubsWriter, error) {
+	e := &eventHubsWriter{
+		log:     log,
+		senders: map[string]*amqp.Sender{},
+	}
+
+	var err error
+	if e.conn, err = eventHubsConnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if e.partitionKey, err = conf.FieldInterpolatedString("partition_key"); err != nil {
+		return nil, err
+	}
+	if e.partitionID, err = conf.FieldInterpolatedString("partition_id"); err != nil {
+		return nil, err
+	}
+	if e.maxBatchBytes, err = conf.FieldInt("max_batch_bytes"); err != nil {
+		return nil, err
+	}
+	if e.maxBatchBytes <= 0 {
+		return nil, errors.New("max_batch_bytes must be larger than zero")
+	}
+	return e, nil
+}
+
+func (e *eventHubsWriter) Connect(ctx context.Context) error {
+	e.connMut.Lock()
+	defer e.connMut.Unlock()
+	if e.client != nil {
+		return nil
+	}
+
+	client, err := e.conn.dial()
+	if err != nil {
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return err
+	}
+	e.client, e.session = client, session
+
+	e.log.Infof("Sending messages as events to Azure Event Hub %v\n", e.conn.eventHub)
+	return nil
+}
+
+// eventHubsRequest is a batch of events destined for a single partition, or
+// for the partition chosen by a partition key.
+type eventHubsRequest struct {
+	partitionID string
+	msg         *amqp.Message
+}
+
+func (e *eventHubsWriter) toRequests(batch service.MessageBatch) ([]eventHubsRequest, error) {
+	type group struct {
+		partitionID, key string
+		events           [][]byte
+		size             int
+	}
+
+	var groups []*group
+	var reqs []eventHubsRequest
+	flush := func(g *group) {
+		if len(g.events) == 0 {
+			return
+		}
+		reqs = append(reqs, eventHubsRequest{
+			partitionID: g.partitionID,
+			msg:         newEventHubsBatchMessage(g.key, g.events),
+		})
+		g.events, g.size = nil, 0
+	}
+
+	for i, msg := range batch {
+		partitionID := batch.InterpolatedString(i, e.partitionID)
+		var key string
+		if partitionID == "" {
+			key = batch.InterpolatedString(i, e.partitionKey)
+		}
+
+		body, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		event := &amqp.Message{Data: [][]byte{body}}
+		if key != "" {
+			event.Annotations = amqp.Annotations{eventHubsPartitionKeyAnnotation: key}
+		}
+		eventBytes, err := event.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		if len(eventBytes) > e.maxBatchBytes {
+			return nil, fmt.Errorf("event size %v exceeds the maximum of %v bytes", len(eventBytes), e.maxBatchBytes)
+		}
+
+		var g *group
+		for _, existing := range groups {
+			if existing.partitionID == partitionID && existing.key == key {
+				g = existing
+				break
+			}
+		}
+		if g == nil {
+			g = &group{partitionID: partitionID, key: key}
+			groups = append(groups, g)
+		}
+		if g.size+len(eventBytes) > e.maxBatchBytes {
+			flush(g)
+		}
+		g.events = append(g.events, eventBytes)
+		g.size += len(eventBytes)
+	}
+
+	for _, g := range groups {
+		flush(g)
+	}
+	return reqs, nil
+}
+
+func newEventHubsBatchMessage(key string, events [][]byte) *amqp.Message {
+	msg := &amqp.Message{
+		Format: eventHubsBatchMessageFormat,
+		Data:   events,
+	}
+	if key != "" {
+		msg.Annotations = amqp.Annotations{eventHubsPartitionKeyAnnotation: key}
+	}
+	return msg
+}
+
+func (e *eventHubsWriter) getSender(partitionID string) (*amqp.Sender, error) {
+	address := e.conn.eventHub
+	if partitionID != "" {
+		address = e.conn.partitionAddress(partitionID)
+	}
+
+	e.connMut.Lock()
+	defer e.connMut.Unlock()
+	if e.session == nil {
+		return nil, service.ErrNotConnected
+	}
+	if sender, exists := e.senders[address]; exists {
+		return sender, nil
+	}
+	sender, err := e.session.NewSender(amqp.LinkTargetAddress(address))
+	if err != nil {
+		return nil, err
+	}
+	e.senders[address] = sender
+	return sender, nil
+}
+
+func (e *eventHubsWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	e.connMut.Lock()
+	connected := e.client != nil
+	e.connMut.Unlock()
+	if !connected {
+		return service.ErrNotConnected
+	}
+
+	reqs, err := e.toRequests(batch)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		sender, err := e.getSender(req.partitionID)
+		if err != nil {
+			return err
+		}
+		if err = sender.Send(ctx, req.msg); err != nil {
+			if _, isRejected := err.(*amqp.Error); isRejected || err == amqp.ErrTimeout {
+				return err
+			}
+			if dErr, isDetachError := err.(*amqp.DetachError); isDetachError && dErr.RemoteError != nil {
+				e.log.Errorf("Lost connection due to: %v\n", dErr.RemoteError)
+			} else {
+				e.log.Errorf("Lost connection due to: %v\n", err)
+			}
+			e.disconnect(ctx)
+			return service.ErrNotConnected
+		}
+	}
+	return nil
+}
+
+func (e *eventHubsWriter) disconnect(ctx context.Context) {
+	e.connMut.Lock()
+	defer e.connMut.Unlock()
+
+	for address, sender := range e.senders {
+		_ = sender.Close(ctx)
+		delete(e.senders, address)
+	}
+	if e.session != nil {
+		_ = e.session.Close(ctx)
+		e.session = nil
+	}
+	if e.client != nil {
+		_ = e.client.Close()
+		e.client = nil
+	}
+}
+
+func (e *eventHubsWriter) Close(ctx context.Context) error {
+	e.disconnect(ctx)
+	return nil
+}
@@ -0,0 +1,115 @@
+package azure
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-amqp"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eventHubsRequestBodies(t *testing.T, req eventHubsRequest) (bodies []string) {
+	t.Helper()
+
+	assert.Equal(t, eventHubsBatchMessageFormat, req.msg.Format)
+	for _, data := range req.msg.Data {
+		var event amqp.Message
+		require.NoError(t, event.UnmarshalBinary(data))
+		assert.Equal(t, req.msg.Annotations[eventHubsPartitionKeyAnnotation], event.Annotations[eventHubsPartitionKeyAnnotation])
+		bodies = append(bodies, string(event.GetData()))
+	}
+	return
+}
+
+func TestEventHubsWriterRequests(t *testing.T) {
+	conf, err := eventHubsOutputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz
+event_hub: buz
+partition_key: ${! json("key") }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventHubsWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	reqs, err := w.toRequests(service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"a","n":0}`)),
+		service.NewMessage([]byte(`{"key":"b","n":1}`)),
+		service.NewMessage([]byte(`{"key":"a","n":2}`)),
+		service.NewMessage([]byte(`{"n":3}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+
+	assert.Equal(t, "", reqs[0].partitionID)
+	assert.Equal(t, "a", reqs[0].msg.Annotations[eventHubsPartitionKeyAnnotation])
+	assert.Equal(t, []string{`{"key":"a","n":0}`, `{"key":"a","n":2}`}, eventHubsRequestBodies(t, reqs[0]))
+
+	assert.Equal(t, "b", reqs[1].msg.Annotations[eventHubsPartitionKeyAnnotation])
+	assert.Equal(t, []string{`{"key":"b","n":1}`}, eventHubsRequestBodies(t, reqs[1]))
+
+	assert.Nil(t, reqs[2].msg.Annotations)
+	assert.Equal(t, []string{`{"n":3}`}, eventHubsRequestBodies(t, reqs[2]))
+}
+
+func TestEventHubsWriterPartitionID(t *testing.T) {
+	conf, err := eventHubsOutputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz
+event_hub: buz
+partition_key: foo
+partition_id: ${! meta("partition") }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventHubsWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	msgA := service.NewMessage([]byte(`a`))
+	msgA.MetaSet("partition", "1")
+	msgB := service.NewMessage([]byte(`b`))
+	msgB.MetaSet("partition", "0")
+	msgC := service.NewMessage([]byte(`c`))
+	msgC.MetaSet("partition", "1")
+
+	reqs, err := w.toRequests(service.MessageBatch{msgA, msgB, msgC})
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+
+	assert.Equal(t, "1", reqs[0].partitionID)
+	assert.Nil(t, reqs[0].msg.Annotations)
+	assert.Equal(t, []string{"a", "c"}, eventHubsRequestBodies(t, reqs[0]))
+
+	assert.Equal(t, "0", reqs[1].partitionID)
+	assert.Equal(t, []string{"b"}, eventHubsRequestBodies(t, reqs[1]))
+}
+
+func TestEventHubsWriterMaxBatchBytes(t *testing.T) {
+	conf, err := eventHubsOutputConfigSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=bar;SharedAccessKey=baz
+event_hub: buz
+max_batch_bytes: 2500
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventHubsWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	body := []byte(strings.Repeat("x", 1000))
+	reqs, err := w.toRequests(service.MessageBatch{
+		service.NewMessage(body),
+		service.NewMessage(body),
+		service.NewMessage(body),
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	assert.Len(t, reqs[0].msg.Data, 2)
+	assert.Len(t, reqs[1].msg.Data, 1)
+
+	_, err = w.toRequests(service.MessageBatch{
+		service.NewMessage([]byte(strings.Repeat("x", 3000))),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum")
+}
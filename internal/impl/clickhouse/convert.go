@@ -0,0 +1,259 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clickhouseConvertValue converts a value obtained from a structured message
+// into the Go type expected by the driver for a column of the given type.
+func clickhouseConvertValue(chType string, v interface{}) (interface{}, error) {
+	if inner, ok := clickhouseUnwrapType(chType, "LowCardinality"); ok {
+		return clickhouseConvertValue(inner, v)
+	}
+	if inner, ok := clickhouseUnwrapType(chType, "Nullable"); ok {
+		if v == nil {
+			return nil, nil
+		}
+		return clickhouseConvertValue(inner, v)
+	}
+	if inner, ok := clickhouseUnwrapType(chType, "Array"); ok {
+		if v == nil {
+			return []interface{}{}, nil
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array value, got %T", v)
+		}
+		res := make([]interface{}, len(arr))
+		for i, e := range arr {
+			var err error
+			if res[i], err = clickhouseConvertValue(inner, e); err != nil {
+				return nil, fmt.Errorf("index %v: %w", i, err)
+			}
+		}
+		return res, nil
+	}
+
+	baseType := chType
+	if i := strings.IndexByte(baseType, '('); i > 0 {
+		baseType = baseType[:i]
+	}
+
+	switch baseType {
+	case "Int8":
+		i, err := clickhouseInt(v, math.MinInt8, math.MaxInt8)
+		return int8(i), err
+	case "Int16":
+		i, err := clickhouseInt(v, math.MinInt16, math.MaxInt16)
+		return int16(i), err
+	case "Int32":
+		i, err := clickhouseInt(v, math.MinInt32, math.MaxInt32)
+		return int32(i), err
+	case "Int64":
+		return clickhouseInt(v, math.MinInt64, math.MaxInt64)
+	case "UInt8":
+		if b, ok := v.(bool); ok {
+			if b {
+				return uint8(1), nil
+			}
+			return uint8(0), nil
+		}
+		u, err := clickhouseUint(v, math.MaxUint8)
+		return uint8(u), err
+	case "UInt16":
+		u, err := clickhouseUint(v, math.MaxUint16)
+		return uint16(u), err
+	case "UInt32":
+		u, err := clickhouseUint(v, math.MaxUint32)
+		return uint32(u), err
+	case "UInt64":
+		return clickhouseUint(v, math.MaxUint64)
+	case "Float32":
+		f, err := clickhouseFloat(v)
+		return float32(f), err
+	case "Float64", "Decimal":
+		return clickhouseFloat(v)
+	case "String", "FixedString", "UUID", "Enum8", "Enum16":
+		return clickhouseString(v), nil
+	case "Date", "DateTime", "DateTime64":
+		return clickhouseTime(v)
+	case "IPv4", "IPv6":
+		if v == nil {
+			return net.IP{}, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string value for IP address, got %T", v)
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("failed to parse IP address: %v", s)
+		}
+		return ip, nil
+	}
+	return v, nil
+}
+
+// clickhouseUnwrapType returns the inner type of a wrapper type such as
+// Nullable(String).
+func clickhouseUnwrapType(chType, wrapper string) (string, bool) {
+	if !strings.HasPrefix(chType, wrapper+"(") || !strings.HasSuffix(chType, ")") {
+		return "", false
+	}
+	return chType[len(wrapper)+1 : len(chType)-1], true
+}
+
+func clickhouseInt(v interface{}, min, max int64) (int64, error) {
+	var i int64
+	switch t := v.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		var err error
+		if i, err = t.Int64(); err != nil {
+			return 0, err
+		}
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected integer value, got %v", t)
+		}
+		i = int64(t)
+	case int64:
+		i = t
+	case int:
+		i = int64(t)
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v exceeds the range of the column", t)
+		}
+		i = int64(t)
+	case bool:
+		if t {
+			i = 1
+		}
+	case string:
+		var err error
+		if i, err = strconv.ParseInt(t, 10, 64); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("expected integer value, got %T", v)
+	}
+	if i < min || i > max {
+		return 0, fmt.Errorf("value %v exceeds the range of the column", i)
+	}
+	return i, nil
+}
+
+func clickhouseUint(v interface{}, max uint64) (uint64, error) {
+	var u uint64
+	switch t := v.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		var err error
+		if u, err = strconv.ParseUint(t.String(), 10, 64); err != nil {
+			return 0, err
+		}
+	case float64:
+		if t < 0 || t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected unsigned integer value, got %v", t)
+		}
+		u = uint64(t)
+	case int64:
+		if t < 0 {
+			return 0, fmt.Errorf("expected unsigned integer value, got %v", t)
+		}
+		u = uint64(t)
+	case int:
+		if t < 0 {
+			return 0, fmt.Errorf("expected unsigned integer value, got %v", t)
+		}
+		u = uint64(t)
+	case uint64:
+		u = t
+	case bool:
+		if t {
+			u = 1
+		}
+	case string:
+		var err error
+		if u, err = strconv.ParseUint(t, 10, 64); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("expected unsigned integer value, got %T", v)
+	}
+	if u > max {
+		return 0, fmt.Errorf("value %v exceeds the range of the column", u)
+	}
+	return u, nil
+}
+
+func clickhouseFloat(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		return t.Float64()
+	case float64:
+		return t, nil
+	case int64:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case string:
+		return strconv.ParseFloat(t, 64)
+	}
+	return 0, fmt.Errorf("expected number value, got %T", v)
+}
+
+func clickhouseString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func clickhouseTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case nil:
+		return time.Unix(0, 0).UTC(), nil
+	case time.Time:
+		return t, nil
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return ts, nil
+		}
+		if ts, err := time.Parse("2006-01-02 15:04:05", t); err == nil {
+			return ts, nil
+		}
+		ts, err := time.Parse("2006-01-02", t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse timestamp: %v", t)
+		}
+		return ts, nil
+	}
+	f, err := clickhouseFloat(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected timestamp string or unix seconds, got %T", v)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+}
@@ -0,0 +1,187 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickhouseConvertValue(t *testing.T) {
+	tests := map[string]struct {
+		chType string
+		input  interface{}
+		output interface{}
+	}{
+		"int8": {
+			chType: "Int8",
+			input:  float64(-5),
+			output: int8(-5),
+		},
+		"int64 json number": {
+			chType: "Int64",
+			input:  json.Number("9007199254740993"),
+			output: int64(9007199254740993),
+		},
+		"int32 string": {
+			chType: "Int32",
+			input:  "42",
+			output: int32(42),
+		},
+		"uint8 bool": {
+			chType: "UInt8",
+			input:  true,
+			output: uint8(1),
+		},
+		"uint64": {
+			chType: "UInt64",
+			input:  float64(10),
+			output: uint64(10),
+		},
+		"float32": {
+			chType: "Float32",
+			input:  1.5,
+			output: float32(1.5),
+		},
+		"decimal": {
+			chType: "Decimal(18, 4)",
+			input:  "1.25",
+			output: 1.25,
+		},
+		"string": {
+			chType: "String",
+			input:  "foo",
+			output: "foo",
+		},
+		"string from object": {
+			chType: "String",
+			input:  map[string]interface{}{"a": float64(1)},
+			output: `{"a":1}`,
+		},
+		"fixed string from number": {
+			chType: "FixedString(4)",
+			input:  float64(12),
+			output: "12",
+		},
+		"missing string": {
+			chType: "String",
+			input:  nil,
+			output: "",
+		},
+		"nullable null": {
+			chType: "Nullable(Int32)",
+			input:  nil,
+			output: nil,
+		},
+		"nullable value": {
+			chType: "Nullable(Int32)",
+			input:  float64(3),
+			output: int32(3),
+		},
+		"low cardinality": {
+			chType: "LowCardinality(Nullable(String))",
+			input:  "foo",
+			output: "foo",
+		},
+		"array": {
+			chType: "Array(UInt16)",
+			input:  []interface{}{float64(1), "2"},
+			output: []interface{}{uint16(1), uint16(2)},
+		},
+		"nested array": {
+			chType: "Array(Array(String))",
+			input:  []interface{}{[]interface{}{"a"}, []interface{}{}},
+			output: []interface{}{[]interface{}{"a"}, []interface{}{}},
+		},
+		"datetime string": {
+			chType: "DateTime('UTC')",
+			input:  "2021-10-01T12:30:00Z",
+			output: time.Date(2021, 10, 1, 12, 30, 0, 0, time.UTC),
+		},
+		"datetime64 unix": {
+			chType: "DateTime64(3)",
+			input:  1.5,
+			output: time.Unix(1, 500000000).UTC(),
+		},
+		"date": {
+			chType: "Date",
+			input:  "2021-10-01",
+			output: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"ipv4": {
+			chType: "IPv4",
+			input:  "127.0.0.1",
+			output: net.ParseIP("127.0.0.1"),
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			res, err := clickhouseConvertValue(test.chType, test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestClickhouseConvertValueErrors(t *testing.T) {
+	tests := map[string]struct {
+		chType      string
+		input       interface{}
+		errContains string
+	}{
+		"int8 overflow": {
+			chType:      "Int8",
+			input:       float64(200),
+			errContains: "exceeds the range",
+		},
+		"negative unsigned": {
+			chType:      "UInt32",
+			input:       float64(-1),
+			errContains: "expected unsigned integer",
+		},
+		"fractional integer": {
+			chType:      "Int64",
+			input:       1.5,
+			errContains: "expected integer",
+		},
+		"bad float": {
+			chType:      "Float64",
+			input:       true,
+			errContains: "expected number",
+		},
+		"not an array": {
+			chType:      "Array(String)",
+			input:       "foo",
+			errContains: "expected array",
+		},
+		"bad array element": {
+			chType:      "Array(Int8)",
+			input:       []interface{}{float64(1), "nope"},
+			errContains: "index 1",
+		},
+		"bad timestamp": {
+			chType:      "DateTime",
+			input:       "yesterday",
+			errContains: "failed to parse timestamp",
+		},
+		"bad ip": {
+			chType:      "IPv6",
+			input:       "nope",
+			errContains: "failed to parse IP address",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			_, err := clickhouseConvertValue(test.chType, test.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
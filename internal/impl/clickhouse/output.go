@@ -0,0 +1,333 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go"
+	"github.com/ClickHouse/clickhouse-go/lib/data"
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/gofrs/uuid"
+)
+
+func clickhouseOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Inserts message batches into a ClickHouse table as columnar blocks over the native protocol.").
+		Description(output.Description(true, true, `
+Each message batch is inserted into the table as a single block over the native TCP protocol, which is considerably more efficient than inserting rows individually.
+
+### Columns
+
+The names and types of the columns are obtained from the server when an insert begins, and therefore the output does not need to be configured with the schema of the table. When the field `+"`columns`"+` is empty all columns of the table are inserted, otherwise only the listed columns are inserted and the server populates the remaining columns with their default values.
+
+By default each message must be a JSON object, and the value of each column is obtained from the field of the object with the same name. Fields that are missing or null are inserted as null for nullable columns and as the zero value of the column type otherwise. Alternatively, the field `+"`args_mapping`"+` can be used in order to map each message to an array of values in the order of the columns.
+
+Values are converted to the type of their column where possible, including timestamp strings in RFC 3339 format for `+"`Date`"+` and `+"`DateTime`"+` columns.
+
+### Async Inserts
+
+When `+"`async_insert`"+` is enabled inserts are buffered by the server and combined with other inserts before being written. Batches are only acknowledged once the server has written the rows. Async inserts require ClickHouse version 21.11 or newer.`)).
+		Field(service.NewStringField("url").
+			Description("The URL of a ClickHouse server using the native protocol, which can include [connection parameters](https://github.com/ClickHouse/clickhouse-go/tree/v1#dsn) such as credentials and the database.").
+			Example("tcp://localhost:9000?username=benthos&password=foo&database=events")).
+		Field(service.NewStringField("table").
+			Description("The table to insert rows into.").
+			Example("events")).
+		Field(service.NewStringListField("columns").
+			Description("An optional list of columns to insert. When empty all columns of the table are inserted.").
+			Example([]string{"id", "name", "created_at"}).
+			Default([]string{})).
+		Field(service.NewBloblangField("args_mapping").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) which should evaluate to an array of values matching in size to the number of columns inserted. When omitted the values of each row are obtained from the fields of the message.").
+			Example("root = [ this.id, this.user.name, meta(\"kafka_timestamp\") ]").
+			Optional()).
+		Field(service.NewBoolField("async_insert").
+			Description("Whether to insert rows with [async inserts](https://clickhouse.com/docs/en/operations/settings/settings/#async-insert).").
+			Default(false).
+			Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to insert in parallel, each over its own connection.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Events from Kafka", `
+Insert JSON events consumed from Kafka, where the fields of each event match the columns of the table:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos_clickhouse
+
+output:
+  clickhouse:
+    url: tcp://localhost:9000?database=analytics
+    table: events
+    batching:
+      count: 10000
+      period: 5s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"clickhouse", clickhouseOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newClickhouseOutputFromConfig(conf, maxInFlight, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type clickhouseOutput struct {
+	dsn         string
+	query       string
+	argsMapping *bloblang.Executor
+	log         *service.Logger
+
+	connMut sync.Mutex
+	idle    chan clickhouse.Clickhouse
+	closed  bool
+}
+
+func newClickhouseOutputFromConfig(conf *service.ParsedConfig, maxInFlight int, log *service.Logger) (*clickhouseOutput, error) {
+	c := &clickhouseOutput{
+		log:  log,
+		idle: make(chan clickhouse.Clickhouse, maxInFlight),
+	}
+
+	urlStr, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	dsnURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		u4, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		tlsKey := "benthos_" + u4.String()
+		if err := clickhouse.RegisterTLSConfig(tlsKey, tlsConf); err != nil {
+			return nil, err
+		}
+		query := dsnURL.Query()
+		query.Set("secure", "true")
+		query.Set("tls_config", tlsKey)
+		dsnURL.RawQuery = query.Encode()
+	}
+	c.dsn = dsnURL.String()
+
+	table, err := conf.FieldString("table")
+	if err != nil {
+		return nil, err
+	}
+	if table == "" {
+		return nil, errors.New("a table must be specified")
+	}
+	columns, err := conf.FieldStringList("columns")
+	if err != nil {
+		return nil, err
+	}
+	asyncInsert, err := conf.FieldBool("async_insert")
+	if err != nil {
+		return nil, err
+	}
+	c.query = clickhouseInsertQuery(table, columns, asyncInsert)
+
+	if conf.Contains("args_mapping") {
+		if c.argsMapping, err = conf.FieldBloblang("args_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func clickhouseInsertQuery(table string, columns []string, asyncInsert bool) string {
+	var query strings.Builder
+	query.WriteString("INSERT INTO ")
+	query.WriteString(table)
+	if len(columns) > 0 {
+		query.WriteString(" (")
+		query.WriteString(strings.Join(columns, ", "))
+		query.WriteString(")")
+	}
+	if asyncInsert {
+		query.WriteString(" SETTINGS async_insert=1, wait_for_async_insert=1")
+	}
+	query.WriteString(" VALUES")
+	return query.String()
+}
+
+func (c *clickhouseOutput) Connect(ctx context.Context) error {
+	conn, err := c.getConn()
+	if err != nil {
+		return err
+	}
+	c.putConn(conn)
+
+	c.log.Infof("Inserting message batches into ClickHouse with: %v\n", c.query)
+	return nil
+}
+
+// getConn returns an idle connection or opens a new one.
+func (c *clickhouseOutput) getConn() (clickhouse.Clickhouse, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+	return clickhouse.OpenDirect(c.dsn)
+}
+
+// putConn returns a connection that can be reused to the idle pool.
+func (c *clickhouseOutput) putConn(conn clickhouse.Clickhouse) {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+	if c.closed {
+		_ = conn.Close()
+		return
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+func (c *clickhouseOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	conn, err := c.getConn()
+	if err != nil {
+		return err
+	}
+	if err = c.insert(conn, batch); err != nil {
+		// The state of the connection is unknown after a failed insert.
+		_ = conn.Close()
+		return err
+	}
+	c.putConn(conn)
+	return nil
+}
+
+func (c *clickhouseOutput) insert(conn clickhouse.Clickhouse, batch service.MessageBatch) error {
+	if _, err := conn.Begin(); err != nil {
+		return err
+	}
+	if _, err := conn.Prepare(c.query); err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+
+	block, err := conn.Block()
+	if err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+	block.Reserve()
+
+	if err := c.appendRows(block, batch); err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+	if err := conn.WriteBlock(block); err != nil {
+		_ = conn.Rollback()
+		return err
+	}
+	return conn.Commit()
+}
+
+func (c *clickhouseOutput) appendRows(block *data.Block, batch service.MessageBatch) error {
+	columnTypes := make([]string, len(block.Columns))
+	for i, col := range block.Columns {
+		columnTypes[i] = col.CHType()
+	}
+
+	row := make([]driver.Value, len(block.Columns))
+	for i, msg := range batch {
+		var values []interface{}
+		if c.argsMapping != nil {
+			resMsg, err := batch.BloblangQuery(i, c.argsMapping)
+			if err != nil {
+				return err
+			}
+			iargs, err := resMsg.AsStructured()
+			if err != nil {
+				return err
+			}
+			var ok bool
+			if values, ok = iargs.([]interface{}); !ok {
+				return fmt.Errorf("mapping returned non-array result: %T", iargs)
+			}
+			if len(values) != len(block.Columns) {
+				return fmt.Errorf("mapping returned %v values, expected %v", len(values), len(block.Columns))
+			}
+		} else {
+			structured, err := msg.AsStructured()
+			if err != nil {
+				return err
+			}
+			obj, ok := structured.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("expected message to be an object, got %T", structured)
+			}
+			values = make([]interface{}, len(block.Columns))
+			for j, col := range block.Columns {
+				values[j] = obj[col.Name()]
+			}
+		}
+
+		for j, v := range values {
+			converted, err := clickhouseConvertValue(columnTypes[j], v)
+			if err != nil {
+				return fmt.Errorf("column %v: %w", block.Columns[j].Name(), err)
+			}
+			row[j] = converted
+		}
+		if err := block.AppendRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *clickhouseOutput) Close(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+	c.closed = true
+	for {
+		select {
+		case conn := <-c.idle:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package clickhouse
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickhouseOutputConfig(t *testing.T) {
+	conf, err := clickhouseOutputConfig().ParseYAML(`
+url: tcp://localhost:9000?database=foo
+table: events
+columns: [ id, name ]
+async_insert: true
+tls:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newClickhouseOutputFromConfig(conf, 1, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "INSERT INTO events (id, name) SETTINGS async_insert=1, wait_for_async_insert=1 VALUES", out.query)
+
+	dsn, err := url.Parse(out.dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:9000", dsn.Host)
+	assert.Equal(t, "foo", dsn.Query().Get("database"))
+	assert.Equal(t, "true", dsn.Query().Get("secure"))
+	assert.NotEmpty(t, dsn.Query().Get("tls_config"))
+}
+
+func TestClickhouseInsertQuery(t *testing.T) {
+	assert.Equal(t, "INSERT INTO foo VALUES", clickhouseInsertQuery("foo", nil, false))
+	assert.Equal(t, "INSERT INTO foo (a) VALUES", clickhouseInsertQuery("foo", []string{"a"}, false))
+}
+
+func TestClickhouseOutputConfigErrors(t *testing.T) {
+	_, err := clickhouseOutputConfig().ParseYAML(`
+url: tcp://localhost:9000
+`, nil)
+	require.Error(t, err)
+
+	conf, err := clickhouseOutputConfig().ParseYAML(`
+url: tcp://localhost:9000
+table: ""
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newClickhouseOutputFromConfig(conf, 1, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a table must be specified")
+}
@@ -4,20 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"path"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/service"
-	"github.com/linkedin/goavro/v2"
 )
 
 func schemaRegistryDecoderConfig() *service.ConfigSpec {
@@ -28,25 +24,44 @@ func schemaRegistryDecoderConfig() *service.ConfigSpec {
 		Description(`
 Decodes messages automatically from a schema stored within a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html) by extracting a schema ID from the message and obtaining the associated schema from the registry. If a message fails to match against the schema then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
 
-Currently only Avro schemas are supported.
+Avro, Protobuf and JSON schemas are supported, including the schemas they reference.
+
+- Avro messages are decoded into [Avro JSON](#avro-json-format) documents.
+- Protobuf messages are decoded into JSON documents using the message type identified by the message indexes of the payload.
+- JSON messages are validated against the schema and otherwise left unchanged.
+
+Schemas are cached by their ID and are removed from the cache after ten minutes without use.
 
 ### Avro JSON Format
 
 This processor creates documents formatted as [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding) when decoding Avro schemas. In this format the value of a union is encoded in JSON as follows:
 
-- if its type is ` + "`null`, then it is encoded as a JSON `null`" + `;
+- if its type is `+"`null`, then it is encoded as a JSON `null`"+`;
 - otherwise it is encoded as a JSON object with one name/value pair whose name is the type's name and whose value is the recursively encoded value. For Avro's named types (record, fixed or enum) the user-specified name is used, for other types the type name is used.
 
-For example, the union schema ` + "`[\"null\",\"string\",\"Foo\"]`, where `Foo`" + ` is a record name, would encode:
+For example, the union schema `+"`[\"null\",\"string\",\"Foo\"]`, where `Foo`"+` is a record name, would encode:
 
-- ` + "`null` as `null`" + `;
-- the string ` + "`\"a\"` as `{\"string\": \"a\"}`" + `; and
-- a ` + "`Foo` instance as `{\"Foo\": {...}}`, where `{...}` indicates the JSON encoding of a `Foo`" + ` instance.`).
+- `+"`null` as `null`"+`;
+- the string `+"`\"a\"` as `{\"string\": \"a\"}`"+`; and
+- a `+"`Foo` instance as `{\"Foo\": {...}}`, where `{...}` indicates the JSON encoding of a `Foo`"+` instance.`).
 		// Field(service.NewBoolField("avro_raw_json").
 		// 	Description("Whether Avro messages should be decoded into raw JSON documents rather than [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding). Avro JSON contains namespaced objects for any typed or non-nil union values, e.g. a union `[\"null\",\"string\"]` field with a string value would be represented as `{\"string\":\"foo\"}`.").
 		// 	Advanced().Default(false)).
 		Field(service.NewStringField("url").Description("The base URL of the schema registry service.")).
-		Field(service.NewTLSField("tls"))
+		Field(service.NewTLSField("tls")).
+		Example("Consuming From Kafka", `
+Records consumed from Kafka that were produced by Confluent serializers can be decoded by referencing the schema registry that the producers use:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: benthos_orders
+  processors:
+    - schema_registry_decode:
+        url: http://localhost:8081
+`,
+		)
 }
 
 func init() {
@@ -242,97 +257,27 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 		return c.decoder, nil
 	}
 
-	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
-	defer done()
-
-	reqURL := *s.schemaRegistryBaseURL
-	reqURL.Path = path.Join(reqURL.Path, fmt.Sprintf("/schemas/ids/%v", id))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), http.NoBody)
+	info, err := getSchemaInfo(
+		s.client, s.schemaRegistryBaseURL, "GET", fmt.Sprintf("/schemas/ids/%v", id), nil,
+		fmt.Sprintf("schema '%v'", id), s.logger,
+	)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-
-	var resBytes []byte
-	for i := 0; i < 3; i++ {
-		var res *http.Response
-		if res, err = s.client.Do(req); err != nil {
-			s.logger.Errorf("request failed for schema '%v': %v", id, err)
-			continue
-		}
-
-		if res.StatusCode == http.StatusNotFound {
-			err = fmt.Errorf("schema '%v' not found by registry", id)
-			s.logger.Errorf(err.Error())
-			break
-		}
 
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("request failed for schema '%v'", id)
-			s.logger.Errorf(err.Error())
-			// TODO: Best attempt at parsing out the body
-			continue
+	var refs map[string]string
+	if len(info.References) > 0 {
+		if refs, err = resolveReferences(s.client, s.schemaRegistryBaseURL, info.References, s.logger); err != nil {
+			return nil, err
 		}
-
-		if res.Body == nil {
-			s.logger.Errorf("request for schema '%v' returned an empty body", id)
-			err = errors.New("schema request returned an empty body")
-			continue
-		}
-
-		resBytes, err = io.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			s.logger.Errorf("failed to read response for schema '%v': %v", id, err)
-			continue
-		}
-
-		break
-	}
-	if err != nil {
-		return nil, err
 	}
 
-	resPayload := struct {
-		Schema string `json:"schema"`
-	}{}
-	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
-		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
-		return nil, err
-	}
-
-	var codec *goavro.Codec
-	if codec, err = goavro.NewCodecForStandardJSON(resPayload.Schema); err != nil {
+	decoder, err := newSchemaDecoder(info, refs, s.avroRawJSON)
+	if err != nil {
 		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
 		return nil, err
 	}
 
-	decoder := func(m *service.Message) error {
-		b, err := m.AsBytes()
-		if err != nil {
-			return err
-		}
-
-		native, _, err := codec.NativeFromBinary(b)
-		if err != nil {
-			return err
-		}
-
-		if s.avroRawJSON {
-			// TODO: This still encodes with Avro JSON format, needs
-			// investigation as to whether this is possible.
-			jb, err := codec.TextualFromNative(nil, native)
-			if err != nil {
-				return err
-			}
-			m.SetBytes(jb)
-		} else {
-			m.SetStructured(native)
-		}
-		return nil
-	}
-
 	s.cacheMut.Lock()
 	s.schemas[id] = &cachedSchemaDecoder{
 		lastUsedUnixSeconds: time.Now().Unix(),
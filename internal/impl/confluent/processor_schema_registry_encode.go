@@ -7,17 +7,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"path"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/service"
-	"github.com/linkedin/goavro/v2"
 )
 
 func schemaRegistryEncoderConfig() *service.ConfigSpec {
@@ -30,24 +27,37 @@ Encodes messages automatically from schemas obtains from a [Confluent Schema Reg
 
 If a message fails to encode under the schema then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
 
-Currently only Avro schemas are supported.
+Avro, Protobuf and JSON schemas are supported, including the schemas they reference.
+
+- Avro schemas encode documents formatted as [Avro JSON](#avro-json-format), or raw JSON when `+"`avro_raw_json`"+` is enabled.
+- Protobuf schemas encode JSON documents as the message type `+"`protobuf_message`"+`, or the first message of the schema when it is empty.
+- JSON schemas validate documents, which are otherwise left unchanged.
+
+### Subject Name Strategies
+
+By default the schema subject of each message is determined by the field `+"`subject`"+`. Alternatively the field `+"`subject_name_strategy`"+` can be used in order to derive subjects the same way as Confluent clients do, from the topic of each message and the record name of the schema. Strategies that use the record name require a locally configured `+"`schema`"+`.
+
+### Local Schemas
+
+When the field `+"`schema`"+` is set messages are encoded with that schema rather than the latest schema of each subject. The ID of the schema is obtained by looking it up under each subject, and when `+"`auto_register`"+` is enabled the schema is registered under subjects where it does not already exist.
 
 ### Avro JSON Format
 
 By default this processor expects documents formatted as [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding) when encoding Avro schemas. In this format the value of a union is encoded in JSON as follows:
 
-- if its type is ` + "`null`, then it is encoded as a JSON `null`" + `;
+- if its type is `+"`null`, then it is encoded as a JSON `null`"+`;
 - otherwise it is encoded as a JSON object with one name/value pair whose name is the type's name and whose value is the recursively encoded value. For Avro's named types (record, fixed or enum) the user-specified name is used, for other types the type name is used.
 
-For example, the union schema ` + "`[\"null\",\"string\",\"Foo\"]`, where `Foo`" + ` is a record name, would encode:
+For example, the union schema `+"`[\"null\",\"string\",\"Foo\"]`, where `Foo`"+` is a record name, would encode:
 
-- ` + "`null` as `null`" + `;
-- the string ` + "`\"a\"` as `{\"string\": \"a\"}`" + `; and
-- a ` + "`Foo` instance as `{\"Foo\": {...}}`, where `{...}` indicates the JSON encoding of a `Foo`" + ` instance.
+- `+"`null` as `null`"+`;
+- the string `+"`\"a\"` as `{\"string\": \"a\"}`"+`; and
+- a `+"`Foo` instance as `{\"Foo\": {...}}`, where `{...}` indicates the JSON encoding of a `Foo`"+` instance.
 
-However, it is possible to instead consume documents in raw JSON format (that match the schema) by setting the field ` + "[`avro_raw_json`](#avro_raw_json) to `true`" + `.`).
+However, it is possible to instead consume documents in raw JSON format (that match the schema) by setting the field `+"[`avro_raw_json`](#avro_raw_json) to `true`"+`.`).
 		Field(service.NewStringField("url").Description("The base URL of the schema registry service.")).
-		Field(service.NewInterpolatedStringField("subject").Description("The schema subject to derive schemas from.").
+		Field(service.NewInterpolatedStringField("subject").Description("The schema subject to derive schemas from. This field is required when `subject_name_strategy` is `none`.").
+			Default("").
 			Example("foo").
 			Example(`${! meta("kafka_topic") }`)).
 		Field(service.NewStringField("refresh_period").
@@ -58,7 +68,59 @@ However, it is possible to instead consume documents in raw JSON format (that ma
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether messages encoded in Avro format should be parsed as raw JSON documents rather than [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding).").
 			Advanced().Default(false).Version("3.59.0")).
+		Field(service.NewStringAnnotatedEnumField("subject_name_strategy", map[string]string{
+			subjectStrategyNone:            "The field `subject` is used as the subject.",
+			subjectStrategyTopicName:       "The subject is the `topic` followed by `-value`, or `-key` when `key_subject` is enabled.",
+			subjectStrategyRecordName:      "The subject is the fully qualified record name of the `schema`.",
+			subjectStrategyTopicRecordName: "The subject is the `topic` followed by a hyphen and the fully qualified record name of the `schema`.",
+		}).
+			Description("The strategy used to determine the schema subject of each message.").
+			Advanced().Default(subjectStrategyNone).Version("3.64.0")).
+		Field(service.NewInterpolatedStringField("topic").
+			Description("The topic of each message, which is used by the `topic_name` and `topic_record_name` subject name strategies.").
+			Advanced().Default(`${! meta("kafka_topic") }`).Version("3.64.0")).
+		Field(service.NewBoolField("key_subject").
+			Description("Whether messages are record keys rather than record values, which determines the suffix of subjects derived with the `topic_name` subject name strategy.").
+			Advanced().Default(false).Version("3.64.0")).
+		Field(service.NewStringField("schema").
+			Description("An optional schema to encode messages with instead of the latest schema registered under each subject.").
+			Advanced().Default("").Version("3.64.0")).
+		Field(service.NewStringEnumField("schema_type", schemaTypeAvro, schemaTypeProtobuf, schemaTypeJSON).
+			Description("The type of the `schema`.").
+			Advanced().Default(schemaTypeAvro).Version("3.64.0")).
+		Field(service.NewBoolField("auto_register").
+			Description("Whether to register the `schema` under subjects where it does not already exist.").
+			Advanced().Default(false).Version("3.64.0")).
+		Field(service.NewStringField("protobuf_message").
+			Description("The fully qualified name of the message to encode documents as with Protobuf schemas. When empty the first message of the schema is used.").
+			Advanced().Default("").Version("3.64.0")).
 		Field(service.NewTLSField("tls")).
+		Example("Auto Registered Protobuf Schema", `
+Records written to Kafka can be encoded with a local Protobuf schema, which is registered under subjects derived from the topic the records are written to:`,
+			`
+pipeline:
+  processors:
+    - schema_registry_encode:
+        url: http://localhost:8081
+        subject_name_strategy: topic_name
+        topic: orders
+        schema_type: PROTOBUF
+        auto_register: true
+        schema: |
+          syntax = "proto3";
+          package shop;
+
+          message Order {
+            string id = 1;
+            int64 total = 2;
+          }
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+`,
+		).
 		Version("3.58.0")
 }
 
@@ -82,6 +144,15 @@ type schemaRegistryEncoder struct {
 	avroRawJSON        bool
 	schemaRefreshAfter time.Duration
 
+	subjectStrategy string
+	topic           *service.InterpolatedString
+	keySubject      bool
+	protobufMessage string
+	autoRegister    bool
+	localSchema     *schemaInfo
+	localEncoder    schemaEncoder
+	recordName      string
+
 	schemaRegistryBaseURL *url.URL
 
 	schemas    map[string]*cachedSchemaEncoder
@@ -122,7 +193,77 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, logger *serv
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryEncoder(urlStr, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, logger)
+	s, err := newSchemaRegistryEncoder(urlStr, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setOptionsFromConfig(conf); err != nil {
+		_ = s.Close(context.Background())
+		return nil, err
+	}
+	return s, nil
+}
+
+const (
+	subjectStrategyNone            = "none"
+	subjectStrategyTopicName       = "topic_name"
+	subjectStrategyRecordName      = "record_name"
+	subjectStrategyTopicRecordName = "topic_record_name"
+)
+
+func (s *schemaRegistryEncoder) setOptionsFromConfig(conf *service.ParsedConfig) (err error) {
+	if s.subjectStrategy, err = conf.FieldString("subject_name_strategy"); err != nil {
+		return
+	}
+	if s.topic, err = conf.FieldInterpolatedString("topic"); err != nil {
+		return
+	}
+	if s.keySubject, err = conf.FieldBool("key_subject"); err != nil {
+		return
+	}
+	if s.protobufMessage, err = conf.FieldString("protobuf_message"); err != nil {
+		return
+	}
+	if s.autoRegister, err = conf.FieldBool("auto_register"); err != nil {
+		return
+	}
+
+	var localSchema, localSchemaType string
+	if localSchema, err = conf.FieldString("schema"); err != nil {
+		return
+	}
+	if localSchemaType, err = conf.FieldString("schema_type"); err != nil {
+		return
+	}
+	if localSchema != "" {
+		s.localSchema = &schemaInfo{
+			Type:   localSchemaType,
+			Schema: localSchema,
+		}
+		if s.localEncoder, s.recordName, err = newSchemaEncoder(*s.localSchema, nil, s.avroRawJSON, s.protobufMessage); err != nil {
+			return fmt.Errorf("failed to parse schema: %w", err)
+		}
+	} else if s.autoRegister {
+		return errors.New("a schema must be specified in order to auto register it")
+	}
+
+	switch s.subjectStrategy {
+	case subjectStrategyNone:
+		if subjectStr, _ := conf.FieldString("subject"); subjectStr == "" {
+			return errors.New("a subject must be specified when the subject name strategy is none")
+		}
+	case subjectStrategyTopicName:
+	case subjectStrategyRecordName, subjectStrategyTopicRecordName:
+		if s.localSchema == nil {
+			return fmt.Errorf("a schema must be specified in order to use the %v subject name strategy", s.subjectStrategy)
+		}
+		if s.recordName == "" {
+			return errors.New("unable to determine the record name of the schema")
+		}
+	default:
+		return fmt.Errorf("subject name strategy %v not recognised", s.subjectStrategy)
+	}
+	return nil
 }
 
 func newSchemaRegistryEncoder(
@@ -179,7 +320,7 @@ func newSchemaRegistryEncoder(
 func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
 	batch = batch.Copy()
 	for i, msg := range batch {
-		encoder, id, err := s.getEncoder(batch.InterpolatedString(i, s.subject))
+		encoder, id, err := s.getEncoder(s.subjectFor(batch, i))
 		if err != nil {
 			msg.SetError(err)
 			continue
@@ -285,96 +426,84 @@ func (s *schemaRegistryEncoder) refreshEncoders() {
 }
 
 func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder, int, error) {
-	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
-	defer done()
-
-	reqURL := *s.schemaRegistryBaseURL
-	reqURL.Path = path.Join(reqURL.Path, fmt.Sprintf("/subjects/%s/versions/latest", subject))
+	if s.localEncoder != nil {
+		return s.getLocalEncoder(subject)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), http.NoBody)
+	info, err := getSchemaInfo(
+		s.client, s.schemaRegistryBaseURL, "GET", fmt.Sprintf("/subjects/%s/versions/latest", subject), nil,
+		fmt.Sprintf("schema subject '%v'", subject), s.logger,
+	)
 	if err != nil {
 		return nil, 0, err
 	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-
-	var resBytes []byte
-	for i := 0; i < 3; i++ {
-		var res *http.Response
-		if res, err = s.client.Do(req); err != nil {
-			s.logger.Errorf("request failed for schema subject '%v': %v", subject, err)
-			continue
-		}
-
-		if res.StatusCode == http.StatusNotFound {
-			err = fmt.Errorf("schema subject '%v' not found by registry", subject)
-			s.logger.Errorf(err.Error())
-			break
-		}
-
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("request failed for schema subject '%v'", subject)
-			s.logger.Errorf(err.Error())
-			// TODO: Best attempt at parsing out the body
-			continue
-		}
-
-		if res.Body == nil {
-			s.logger.Errorf("request for schema subject latest '%v' returned an empty body", subject)
-			err = errors.New("schema request returned an empty body")
-			continue
-		}
 
-		resBytes, err = io.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			s.logger.Errorf("failed to read response for schema subject '%v': %v", subject, err)
-			continue
+	var refs map[string]string
+	if len(info.References) > 0 {
+		if refs, err = resolveReferences(s.client, s.schemaRegistryBaseURL, info.References, s.logger); err != nil {
+			return nil, 0, err
 		}
-
-		break
 	}
+
+	encoder, _, err := newSchemaEncoder(info, refs, s.avroRawJSON, s.protobufMessage)
 	if err != nil {
+		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 		return nil, 0, err
 	}
+	return encoder, info.ID, nil
+}
 
-	resPayload := struct {
+// getLocalEncoder obtains the ID of the locally configured schema under a
+// subject, registering the schema first when auto registration is enabled.
+func (s *schemaRegistryEncoder) getLocalEncoder(subject string) (schemaEncoder, int, error) {
+	reqBody := struct {
+		Type   string `json:"schemaType,omitempty"`
 		Schema string `json:"schema"`
-		ID     int    `json:"id"`
-	}{}
-	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
-		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
-		return nil, 0, err
+	}{
+		Schema: s.localSchema.Schema,
 	}
-
-	var codec *goavro.Codec
-	if codec, err = goavro.NewCodecForStandardJSON(resPayload.Schema); err != nil {
-		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
+	if t := s.localSchema.normalisedType(); t != schemaTypeAvro {
+		reqBody.Type = t
+	}
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
 		return nil, 0, err
 	}
 
-	return func(m *service.Message) error {
-		var datum interface{}
-		if s.avroRawJSON {
-			b, err := m.AsBytes()
-			if err != nil {
-				return err
-			}
+	reqPath := fmt.Sprintf("/subjects/%s", subject)
+	if s.autoRegister {
+		reqPath = fmt.Sprintf("/subjects/%s/versions", subject)
+	}
 
-			if datum, _, err = codec.NativeFromTextual(b); err != nil {
-				return err
-			}
-		} else if datum, err = m.AsStructured(); err != nil {
-			return err
-		}
+	info, err := getSchemaInfo(
+		s.client, s.schemaRegistryBaseURL, "POST", reqPath, reqBytes,
+		fmt.Sprintf("schema subject '%v'", subject), s.logger,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.localEncoder, info.ID, nil
+}
 
-		binary, err := codec.BinaryFromNative(nil, datum)
-		if err != nil {
-			return err
-		}
+// subjectFor returns the subject to obtain the schema of a message from
+// according to the subject name strategy.
+func (s *schemaRegistryEncoder) subjectFor(batch service.MessageBatch, i int) string {
+	switch s.subjectStrategy {
+	case subjectStrategyTopicName:
+		return batch.InterpolatedString(i, s.topic) + s.subjectSuffix()
+	case subjectStrategyRecordName:
+		return s.recordName
+	case subjectStrategyTopicRecordName:
+		return batch.InterpolatedString(i, s.topic) + "-" + s.recordName
+	}
+	return batch.InterpolatedString(i, s.subject)
+}
 
-		m.SetBytes(binary)
-		return nil
-	}, resPayload.ID, nil
+func (s *schemaRegistryEncoder) subjectSuffix() string {
+	if s.keySubject {
+		return "-key"
+	}
+	return "-value"
 }
 
 func (s *schemaRegistryEncoder) getEncoder(subject string) (schemaEncoder, int, error) {
@@ -386,3 +386,196 @@ func TestSchemaRegistryEncodeRefresh(t *testing.T) {
 	assert.Equal(t, int32(1), atomic.LoadInt32(&fooReqs))
 	assert.Equal(t, int32(1), atomic.LoadInt32(&barReqs))
 }
+
+func TestSchemaRegistryEncoderStrategyConfigErrors(t *testing.T) {
+	configTests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "no subject",
+			config: `
+url: http://example.com
+`,
+			errContains: "a subject must be specified",
+		},
+		{
+			name: "record name without schema",
+			config: `
+url: http://example.com
+subject_name_strategy: record_name
+`,
+			errContains: "a schema must be specified in order to use the record_name subject name strategy",
+		},
+		{
+			name: "auto register without schema",
+			config: `
+url: http://example.com
+subject: foo
+auto_register: true
+`,
+			errContains: "a schema must be specified in order to auto register it",
+		},
+		{
+			name: "bad protobuf schema",
+			config: `
+url: http://example.com
+subject: foo
+schema_type: PROTOBUF
+schema: 'message {'
+`,
+			errContains: "failed to parse schema",
+		},
+		{
+			name: "unknown protobuf message",
+			config: `
+url: http://example.com
+subject: foo
+schema_type: PROTOBUF
+protobuf_message: testing.Nope
+schema: 'syntax = "proto3"; package testing; message Person { string name = 1; }'
+`,
+			errContains: "unable to find message 'testing.Nope'",
+		},
+	}
+
+	spec := schemaRegistryEncoderConfig()
+	env := service.NewEnvironment()
+	for _, test := range configTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML(test.config, env)
+			require.NoError(t, err)
+
+			_, err = newSchemaRegistryEncoderFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestSchemaRegistryEncodeLocalSchemas(t *testing.T) {
+	registered, err := json.Marshal(struct {
+		ID int `json:"id"`
+	}{ID: 9})
+	require.NoError(t, err)
+
+	found, err := json.Marshal(struct {
+		ID int `json:"id"`
+	}{ID: 4})
+	require.NoError(t, err)
+
+	var registerReqs int32
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/orders-value/versions":
+			atomic.AddInt32(&registerReqs, 1)
+			return registered, nil
+		case "/subjects/testing.Wrapper.Inner", "/subjects/person":
+			return found, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	tests := []struct {
+		name        string
+		config      string
+		input       string
+		output      string
+		errContains string
+	}{
+		{
+			name: "protobuf topic name auto registered",
+			config: `
+subject_name_strategy: topic_name
+topic: orders
+schema_type: PROTOBUF
+auto_register: true
+schema: 'syntax = "proto3"; package testing; message Person { string name = 1; int32 age = 2; }'
+`,
+			input:  `{"name":"foo","age":10}`,
+			output: "\x00\x00\x00\x00\x09\x00\x0a\x03foo\x10\x0a",
+		},
+		{
+			name: "protobuf nested record name",
+			config: `
+subject_name_strategy: record_name
+schema_type: PROTOBUF
+protobuf_message: testing.Wrapper.Inner
+schema: 'syntax = "proto3"; package testing; message Person { string name = 1; } message Wrapper { message Inner { string value = 1; } Inner inner = 1; }'
+`,
+			input:  `{"value":"bar"}`,
+			output: "\x00\x00\x00\x00\x04\x04\x02\x00\x0a\x03bar",
+		},
+		{
+			name: "json schema",
+			config: `
+subject_name_strategy: record_name
+schema_type: JSON
+schema: '{"title":"person","type":"object","properties":{"name":{"type":"string"}}}'
+`,
+			input:  `{"name":"foo"}`,
+			output: "\x00\x00\x00\x00\x04" + `{"name":"foo"}`,
+		},
+		{
+			name: "json schema invalid",
+			config: `
+subject_name_strategy: record_name
+schema_type: JSON
+schema: '{"title":"person","type":"object","properties":{"name":{"type":"string"}}}'
+`,
+			input:       `{"name":5}`,
+			errContains: "expected: string",
+		},
+		{
+			name: "topic name no registration",
+			config: `
+subject_name_strategy: topic_name
+topic: nope
+schema_type: JSON
+schema: '{"type":"object"}'
+`,
+			input:       `{"name":"foo"}`,
+			errContains: "request failed for schema subject 'nope-value'",
+		},
+	}
+
+	spec := schemaRegistryEncoderConfig()
+	env := service.NewEnvironment()
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML("url: "+urlStr+"\n"+test.config, env)
+			require.NoError(t, err)
+
+			encoder, err := newSchemaRegistryEncoderFromConfig(conf, nil)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = encoder.Close(context.Background())
+			})
+
+			outBatches, err := encoder.ProcessBatch(
+				context.Background(),
+				service.MessageBatch{service.NewMessage([]byte(test.input))},
+			)
+			require.NoError(t, err)
+			require.Len(t, outBatches, 1)
+			require.Len(t, outBatches[0], 1)
+
+			err = outBatches[0][0].GetError()
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			} else {
+				require.NoError(t, err)
+
+				b, err := outBatches[0][0].AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, test.output, string(b))
+			}
+		})
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&registerReqs))
+}
@@ -0,0 +1,169 @@
+package confluent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+)
+
+// schemaInfo describes a schema as returned by the schema registry service.
+type schemaInfo struct {
+	ID         int               `json:"id"`
+	Type       string            `json:"schemaType,omitempty"`
+	Schema     string            `json:"schema"`
+	References []schemaReference `json:"references,omitempty"`
+}
+
+// schemaReference describes a schema that is referenced by another schema,
+// such as an imported protobuf file.
+type schemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// normalisedType returns the type of the schema, schemas without an explicit
+// type are Avro.
+func (s schemaInfo) normalisedType() string {
+	if s.Type == "" {
+		return schemaTypeAvro
+	}
+	return strings.ToUpper(s.Type)
+}
+
+// schemaRegistryRequest performs a request against the schema registry service
+// with retries. The description is used for logs and errors and should
+// identify the resource being requested, e.g. "schema '5'".
+func schemaRegistryRequest(
+	client *http.Client,
+	baseURL *url.URL,
+	method, reqPath string,
+	body []byte,
+	description string,
+	logger *service.Logger,
+) ([]byte, error) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	reqURL := *baseURL
+	reqURL.Path = path.Join(reqURL.Path, reqPath)
+
+	var resBytes []byte
+	var err error
+	for i := 0; i < 3; i++ {
+		var bodyReader io.Reader = http.NoBody
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, method, reqURL.String(), bodyReader); err != nil {
+			return nil, err
+		}
+		req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+		if body != nil {
+			req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
+		}
+
+		var res *http.Response
+		if res, err = client.Do(req); err != nil {
+			logger.Errorf("request failed for %v: %v", description, err)
+			continue
+		}
+
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			err = fmt.Errorf("%v not found by registry", description)
+			logger.Errorf(err.Error())
+			break
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			err = fmt.Errorf("request failed for %v", description)
+			logger.Errorf(err.Error())
+			// TODO: Best attempt at parsing out the body
+			continue
+		}
+
+		if res.Body == nil {
+			logger.Errorf("request for %v returned an empty body", description)
+			err = errors.New("schema request returned an empty body")
+			continue
+		}
+
+		resBytes, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			logger.Errorf("failed to read response for %v: %v", description, err)
+			continue
+		}
+
+		break
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resBytes, nil
+}
+
+// getSchemaInfo performs a request for a schema and parses the response.
+func getSchemaInfo(client *http.Client, baseURL *url.URL, method, reqPath string, body []byte, description string, logger *service.Logger) (info schemaInfo, err error) {
+	var resBytes []byte
+	if resBytes, err = schemaRegistryRequest(client, baseURL, method, reqPath, body, description, logger); err != nil {
+		return
+	}
+	if err = json.Unmarshal(resBytes, &info); err != nil {
+		logger.Errorf("failed to parse response for %v: %v", description, err)
+	}
+	return
+}
+
+// resolveReferences obtains the schemas of all references, including those
+// referenced by references, keyed by their reference name.
+func resolveReferences(client *http.Client, baseURL *url.URL, refs []schemaReference, logger *service.Logger) (map[string]string, error) {
+	resolved := map[string]string{}
+
+	var resolveFn func(refs []schemaReference) error
+	resolveFn = func(refs []schemaReference) error {
+		for _, ref := range refs {
+			if _, exists := resolved[ref.Name]; exists {
+				continue
+			}
+			info, err := getSchemaInfo(
+				client, baseURL, "GET",
+				fmt.Sprintf("/subjects/%s/versions/%v", ref.Subject, ref.Version), nil,
+				fmt.Sprintf("schema subject '%v' version '%v'", ref.Subject, ref.Version), logger,
+			)
+			if err != nil {
+				return err
+			}
+			resolved[ref.Name] = info.Schema
+			if err := resolveFn(info.References); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := resolveFn(refs); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
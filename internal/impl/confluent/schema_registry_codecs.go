@@ -0,0 +1,367 @@
+package confluent
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// newSchemaDecoder creates a decoder from a schema and the schemas of its
+// resolved references.
+func newSchemaDecoder(info schemaInfo, refs map[string]string, avroRawJSON bool) (schemaDecoder, error) {
+	switch t := info.normalisedType(); t {
+	case schemaTypeAvro:
+		codec, err := newAvroCodec(info.Schema, refs)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+
+			native, _, err := codec.NativeFromBinary(b)
+			if err != nil {
+				return err
+			}
+
+			if avroRawJSON {
+				// TODO: This still encodes with Avro JSON format, needs
+				// investigation as to whether this is possible.
+				jb, err := codec.TextualFromNative(nil, native)
+				if err != nil {
+					return err
+				}
+				m.SetBytes(jb)
+			} else {
+				m.SetStructured(native)
+			}
+			return nil
+		}, nil
+	case schemaTypeProtobuf:
+		fd, err := parseProtobufSchema(info.Schema, refs)
+		if err != nil {
+			return nil, err
+		}
+		marshaller := &jsonpb.Marshaler{
+			AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fd),
+		}
+		return func(m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+
+			indexes, remaining, err := readMessageIndexes(b)
+			if err != nil {
+				return err
+			}
+			md, err := protobufMessageByIndexes(fd, indexes)
+			if err != nil {
+				return err
+			}
+
+			msg := dynamic.NewMessage(md)
+			if err := proto.Unmarshal(remaining, msg); err != nil {
+				return fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+			}
+
+			data, err := msg.MarshalJSONPB(marshaller)
+			if err != nil {
+				return fmt.Errorf("failed to marshal protobuf message: %w", err)
+			}
+			m.SetBytes(data)
+			return nil
+		}, nil
+	case schemaTypeJSON:
+		schema, err := compileJSONSchema(info.Schema, refs)
+		if err != nil {
+			return nil, err
+		}
+		return func(m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+			return validateJSONSchema(schema, b)
+		}, nil
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", t)
+	}
+}
+
+// newSchemaEncoder creates an encoder from a schema and the schemas of its
+// resolved references, and also returns the fully qualified name of the
+// record that the encoder writes.
+func newSchemaEncoder(info schemaInfo, refs map[string]string, avroRawJSON bool, protobufMessage string) (schemaEncoder, string, error) {
+	switch t := info.normalisedType(); t {
+	case schemaTypeAvro:
+		codec, err := newAvroCodec(info.Schema, refs)
+		if err != nil {
+			return nil, "", err
+		}
+		recordName := avroRecordName(info.Schema)
+		return func(m *service.Message) error {
+			var datum interface{}
+			var err error
+			if avroRawJSON {
+				b, err := m.AsBytes()
+				if err != nil {
+					return err
+				}
+
+				if datum, _, err = codec.NativeFromTextual(b); err != nil {
+					return err
+				}
+			} else if datum, err = m.AsStructured(); err != nil {
+				return err
+			}
+
+			encoded, err := codec.BinaryFromNative(nil, datum)
+			if err != nil {
+				return err
+			}
+
+			m.SetBytes(encoded)
+			return nil
+		}, recordName, nil
+	case schemaTypeProtobuf:
+		fd, err := parseProtobufSchema(info.Schema, refs)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var md *desc.MessageDescriptor
+		if protobufMessage != "" {
+			if md = fd.FindMessage(protobufMessage); md == nil {
+				return nil, "", fmt.Errorf("unable to find message '%v' definition within schema", protobufMessage)
+			}
+		} else if msgs := fd.GetMessageTypes(); len(msgs) > 0 {
+			md = msgs[0]
+		} else {
+			return nil, "", errors.New("protobuf schema does not define any messages")
+		}
+		indexes := appendMessageIndexes(nil, protobufMessageIndexes(md))
+
+		unmarshaler := &jsonpb.Unmarshaler{
+			AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fd),
+		}
+		return func(m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+
+			msg := dynamic.NewMessage(md)
+			if err := msg.UnmarshalJSONPB(unmarshaler, b); err != nil {
+				return fmt.Errorf("failed to unmarshal JSON message: %w", err)
+			}
+
+			data, err := msg.Marshal()
+			if err != nil {
+				return fmt.Errorf("failed to marshal protobuf message: %w", err)
+			}
+
+			m.SetBytes(append(append([]byte{}, indexes...), data...))
+			return nil
+		}, md.GetFullyQualifiedName(), nil
+	case schemaTypeJSON:
+		schema, err := compileJSONSchema(info.Schema, refs)
+		if err != nil {
+			return nil, "", err
+		}
+		var schemaObj struct {
+			Title string `json:"title"`
+		}
+		_ = json.Unmarshal([]byte(info.Schema), &schemaObj)
+		return func(m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+			return validateJSONSchema(schema, b)
+		}, schemaObj.Title, nil
+	default:
+		return nil, "", fmt.Errorf("schema type %v not supported", t)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newAvroCodec(schema string, refs map[string]string) (*goavro.Codec, error) {
+	if len(refs) > 0 {
+		return nil, errors.New("schema references are not supported for avro schemas")
+	}
+	return goavro.NewCodecForStandardJSON(schema)
+}
+
+// avroRecordName returns the fully qualified name of the top level type of an
+// avro schema.
+func avroRecordName(schema string) string {
+	var schemaObj struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+	if err := json.Unmarshal([]byte(schema), &schemaObj); err != nil {
+		// Schemas of primitive types are not objects and have no name.
+		return ""
+	}
+	if schemaObj.Namespace == "" || strings.Contains(schemaObj.Name, ".") {
+		return schemaObj.Name
+	}
+	return schemaObj.Namespace + "." + schemaObj.Name
+}
+
+//------------------------------------------------------------------------------
+
+const protobufSchemaFileName = "schema_registry_schema.proto"
+
+func parseProtobufSchema(schema string, refs map[string]string) (*desc.FileDescriptor, error) {
+	files := map[string]string{
+		protobufSchemaFileName: schema,
+	}
+	for name, refSchema := range refs {
+		files[name] = refSchema
+	}
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(protobufSchemaFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse protobuf schema: %w", err)
+	}
+	return fds[0], nil
+}
+
+// readMessageIndexes reads the message indexes that prefix the payload of a
+// protobuf message, which identify the message type within its schema.
+func readMessageIndexes(b []byte) (indexes []int, remaining []byte, err error) {
+	count, n := binary.Varint(b)
+	if n <= 0 {
+		err = errors.New("failed to read protobuf message indexes")
+		return
+	}
+	b = b[n:]
+
+	// A count of zero is shorthand for the first message of the schema.
+	if count == 0 {
+		return []int{0}, b, nil
+	}
+	if count < 0 || count > int64(len(b)) {
+		err = fmt.Errorf("invalid protobuf message index count: %v", count)
+		return
+	}
+
+	indexes = make([]int, count)
+	for i := range indexes {
+		var index int64
+		if index, n = binary.Varint(b); n <= 0 {
+			err = errors.New("failed to read protobuf message indexes")
+			return
+		}
+		indexes[i] = int(index)
+		b = b[n:]
+	}
+	remaining = b
+	return
+}
+
+// appendMessageIndexes appends the protobuf message indexes prefix to b.
+func appendMessageIndexes(b []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(b, 0)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	b = append(b, buf[:binary.PutVarint(buf, int64(len(indexes)))]...)
+	for _, index := range indexes {
+		b = append(b, buf[:binary.PutVarint(buf, int64(index))]...)
+	}
+	return b
+}
+
+func protobufMessageByIndexes(fd *desc.FileDescriptor, indexes []int) (*desc.MessageDescriptor, error) {
+	var md *desc.MessageDescriptor
+	msgs := fd.GetMessageTypes()
+	for _, index := range indexes {
+		if index < 0 || index >= len(msgs) {
+			return nil, fmt.Errorf("protobuf message index %v is out of range", index)
+		}
+		md = msgs[index]
+		msgs = md.GetNestedMessageTypes()
+	}
+	if md == nil {
+		return nil, errors.New("protobuf message indexes are empty")
+	}
+	return md, nil
+}
+
+// protobufMessageIndexes returns the indexes that locate a message within the
+// file that defines it.
+func protobufMessageIndexes(md *desc.MessageDescriptor) []int {
+	var indexes []int
+	for {
+		var siblings []*desc.MessageDescriptor
+		parent := md.GetParent()
+		switch p := parent.(type) {
+		case *desc.MessageDescriptor:
+			siblings = p.GetNestedMessageTypes()
+		case *desc.FileDescriptor:
+			siblings = p.GetMessageTypes()
+		}
+		for i, sibling := range siblings {
+			if sibling.GetFullyQualifiedName() == md.GetFullyQualifiedName() {
+				indexes = append([]int{i}, indexes...)
+				break
+			}
+		}
+		parentMsg, ok := parent.(*desc.MessageDescriptor)
+		if !ok {
+			return indexes
+		}
+		md = parentMsg
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func compileJSONSchema(schema string, refs map[string]string) (*gojsonschema.Schema, error) {
+	loader := gojsonschema.NewSchemaLoader()
+	for name, refSchema := range refs {
+		if err := loader.AddSchema(name, gojsonschema.NewStringLoader(refSchema)); err != nil {
+			return nil, fmt.Errorf("failed to add referenced schema '%v': %w", name, err)
+		}
+	}
+	compiled, err := loader.Compile(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile json schema: %w", err)
+	}
+	return compiled, nil
+}
+
+func validateJSONSchema(schema *gojsonschema.Schema, b []byte) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	var errStrs []string
+	for _, desc := range result.Errors() {
+		errStrs = append(errStrs, desc.Field()+" "+strings.ToLower(desc.Description()))
+	}
+	return errors.New(strings.Join(errStrs, ", "))
+}
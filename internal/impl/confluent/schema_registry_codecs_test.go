@@ -0,0 +1,220 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProtobufSchema = `
+syntax = "proto3";
+package testing;
+
+message Person {
+  string name = 1;
+  int32 age = 2;
+}
+
+message Wrapper {
+  message Inner {
+    string value = 1;
+  }
+  Inner inner = 1;
+}
+`
+
+const testJSONSchema = `{
+	"title": "person",
+	"type": "object",
+	"properties": {
+		"name": { "type": "string" }
+	},
+	"required": [ "name" ]
+}`
+
+func TestMessageIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []int
+		encoded string
+	}{
+		{name: "first message", indexes: []int{0}, encoded: "\x00"},
+		{name: "second message", indexes: []int{1}, encoded: "\x02\x02"},
+		{name: "nested message", indexes: []int{1, 0}, encoded: "\x04\x02\x00"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b := appendMessageIndexes(nil, test.indexes)
+			assert.Equal(t, test.encoded, string(b))
+
+			indexes, remaining, err := readMessageIndexes(append(b, "foo"...))
+			require.NoError(t, err)
+			assert.Equal(t, test.indexes, indexes)
+			assert.Equal(t, "foo", string(remaining))
+		})
+	}
+
+	_, _, err := readMessageIndexes([]byte("\x08\x02"))
+	require.Error(t, err)
+}
+
+func TestProtobufMessageIndexes(t *testing.T) {
+	fd, err := parseProtobufSchema(testProtobufSchema, nil)
+	require.NoError(t, err)
+
+	for _, name := range []string{"testing.Person", "testing.Wrapper", "testing.Wrapper.Inner"} {
+		md := fd.FindMessage(name)
+		require.NotNil(t, md, name)
+
+		found, err := protobufMessageByIndexes(fd, protobufMessageIndexes(md))
+		require.NoError(t, err)
+		assert.Equal(t, name, found.GetFullyQualifiedName())
+	}
+
+	_, err = protobufMessageByIndexes(fd, []int{5})
+	require.Error(t, err)
+}
+
+func TestSchemaRegistryDecodeProtobufAndJSON(t *testing.T) {
+	protoPayload, err := json.Marshal(schemaInfo{
+		Type:   "PROTOBUF",
+		Schema: testProtobufSchema,
+	})
+	require.NoError(t, err)
+
+	jsonPayload, err := json.Marshal(schemaInfo{
+		Type:   "JSON",
+		Schema: testJSONSchema,
+	})
+	require.NoError(t, err)
+
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/schemas/ids/7":
+			return protoPayload, nil
+		case "/schemas/ids/8":
+			return jsonPayload, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, nil, false, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		input       string
+		output      string
+		errContains string
+	}{
+		{
+			name:   "protobuf first message",
+			input:  "\x00\x00\x00\x00\x07\x00\x0a\x03foo\x10\x0a",
+			output: `{"name":"foo","age":10}`,
+		},
+		{
+			name:   "protobuf nested message",
+			input:  "\x00\x00\x00\x00\x07\x04\x02\x00\x0a\x03bar",
+			output: `{"value":"bar"}`,
+		},
+		{
+			name:        "protobuf bad message index",
+			input:       "\x00\x00\x00\x00\x07\x02\x0a\x0a\x03bar",
+			errContains: "out of range",
+		},
+		{
+			name:   "json valid",
+			input:  "\x00\x00\x00\x00\x08" + `{"name":"foo"}`,
+			output: `{"name":"foo"}`,
+		},
+		{
+			name:        "json invalid",
+			input:       "\x00\x00\x00\x00\x08" + `{"name":5}`,
+			errContains: "expected: string",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			outMsgs, err := decoder.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, outMsgs, 1)
+
+				b, err := outMsgs[0].AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, test.output, string(b))
+			}
+		})
+	}
+
+	require.NoError(t, decoder.Close(context.Background()))
+}
+
+func TestSchemaRegistryDecodeProtobufReferences(t *testing.T) {
+	mainPayload, err := json.Marshal(schemaInfo{
+		Type: "PROTOBUF",
+		Schema: `
+syntax = "proto3";
+package testing;
+
+import "person.proto";
+
+message Team {
+  Person lead = 1;
+}
+`,
+		References: []schemaReference{
+			{Name: "person.proto", Subject: "person", Version: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	refPayload, err := json.Marshal(schemaInfo{
+		Type: "PROTOBUF",
+		Schema: `
+syntax = "proto3";
+package testing;
+
+message Person {
+  string name = 1;
+}
+`,
+	})
+	require.NoError(t, err)
+
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/schemas/ids/3":
+			return mainPayload, nil
+		case "/subjects/person/versions/2":
+			return refPayload, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, nil, false, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, decoder.Close(context.Background()))
+	})
+
+	outMsgs, err := decoder.Process(context.Background(), service.NewMessage([]byte("\x00\x00\x00\x00\x03\x00\x0a\x05\x0a\x03foo")))
+	require.NoError(t, err)
+	require.Len(t, outMsgs, 1)
+
+	b, err := outMsgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"lead":{"name":"foo"}}`, string(b))
+}
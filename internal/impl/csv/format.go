@@ -0,0 +1,214 @@
+package csv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func delimiterField() *service.ConfigField {
+	return service.NewStringField("delimiter").
+		Description("The character that separates the fields of records, tab delimited data can be handled by setting this to `\"\\t\"`.").
+		Example(",").
+		Example("\t").
+		Example("|").
+		Default(",")
+}
+
+func quoteField() *service.ConfigField {
+	return service.NewStringField("quote").
+		Description("The character that encloses fields containing delimiters, quotes or line breaks. A quote within a quoted field is escaped by repeating it.").
+		Default(`"`)
+}
+
+// csvFormat describes the dialect of delimited data, which unlike
+// encoding/csv allows the quote character to be customised.
+type csvFormat struct {
+	delim      rune
+	quote      rune
+	lazyQuotes bool
+}
+
+func singleRune(field, str string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(str)
+	if size == 0 || size != len(str) || r == utf8.RuneError {
+		return 0, fmt.Errorf("%v must be a single character, got: %q", field, str)
+	}
+	if r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("%v cannot be a line break", field)
+	}
+	return r, nil
+}
+
+func csvFormatFromConfig(conf *service.ParsedConfig) (f csvFormat, err error) {
+	var delimStr, quoteStr string
+	if delimStr, err = conf.FieldString("delimiter"); err != nil {
+		return
+	}
+	if f.delim, err = singleRune("delimiter", delimStr); err != nil {
+		return
+	}
+	if quoteStr, err = conf.FieldString("quote"); err != nil {
+		return
+	}
+	if f.quote, err = singleRune("quote", quoteStr); err != nil {
+		return
+	}
+	if f.delim == f.quote {
+		err = errors.New("delimiter and quote must be different characters")
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// readRecords parses all records of a document, skipping empty lines.
+func (f csvFormat) readRecords(data string) ([][]string, error) {
+	var records [][]string
+	pos, line := 0, 1
+	for pos < len(data) {
+		if data[pos] == '\n' {
+			pos++
+			line++
+			continue
+		}
+		if strings.HasPrefix(data[pos:], "\r\n") {
+			pos += 2
+			line++
+			continue
+		}
+
+		var record []string
+		var err error
+		if record, pos, line, err = f.readRecord(data, pos, line); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func isLineEnd(data string, pos int) bool {
+	return pos >= len(data) || data[pos] == '\n' || strings.HasPrefix(data[pos:], "\r\n")
+}
+
+// readRecord parses the record starting at pos, returning the position and
+// line number following it.
+func (f csvFormat) readRecord(data string, pos, line int) ([]string, int, int, error) {
+	startLine := line
+
+	var fields []string
+	for {
+		var field strings.Builder
+		if r, size := utf8.DecodeRuneInString(data[pos:]); pos < len(data) && r == f.quote {
+			pos += size
+			closed := false
+			for pos < len(data) {
+				r, size = utf8.DecodeRuneInString(data[pos:])
+				if r == f.quote {
+					pos += size
+					if next, nextSize := utf8.DecodeRuneInString(data[pos:]); pos < len(data) && next == f.quote {
+						field.WriteRune(f.quote)
+						pos += nextSize
+						continue
+					}
+					if next, _ := utf8.DecodeRuneInString(data[pos:]); isLineEnd(data, pos) || next == f.delim {
+						closed = true
+						break
+					}
+					if !f.lazyQuotes {
+						return nil, 0, 0, fmt.Errorf("line %v: extraneous %q in quoted field", line, f.quote)
+					}
+					field.WriteRune(f.quote)
+					continue
+				}
+				if strings.HasPrefix(data[pos:], "\r\n") {
+					pos++
+					continue
+				}
+				if r == '\n' {
+					line++
+				}
+				field.WriteRune(r)
+				pos += size
+			}
+			if !closed && !f.lazyQuotes {
+				return nil, 0, 0, fmt.Errorf("line %v: quoted field starting on line %v is not closed", line, startLine)
+			}
+		} else {
+			for !isLineEnd(data, pos) {
+				r, size = utf8.DecodeRuneInString(data[pos:])
+				if r == f.delim {
+					break
+				}
+				if r == f.quote && !f.lazyQuotes {
+					return nil, 0, 0, fmt.Errorf("line %v: bare %q in non-quoted field", line, f.quote)
+				}
+				field.WriteRune(r)
+				pos += size
+			}
+		}
+		fields = append(fields, field.String())
+
+		if pos >= len(data) {
+			return fields, pos, line, nil
+		}
+		if r, size := utf8.DecodeRuneInString(data[pos:]); r == f.delim {
+			pos += size
+			continue
+		}
+		if data[pos] == '\r' {
+			pos++
+		}
+		return fields, pos + 1, line + 1, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (f csvFormat) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if strings.ContainsRune(field, f.delim) || strings.ContainsRune(field, f.quote) || strings.ContainsAny(field, "\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// writeRecord writes a record followed by a line break, quoting fields only
+// when necessary.
+func (f csvFormat) writeRecord(buf *bytes.Buffer, fields []string) {
+	if len(fields) == 1 && fields[0] == "" {
+		// A lone empty field would otherwise be written as an empty line,
+		// which is skipped when read.
+		buf.WriteRune(f.quote)
+		buf.WriteRune(f.quote)
+		buf.WriteByte('\n')
+		return
+	}
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteRune(f.delim)
+		}
+		if !f.fieldNeedsQuotes(field) {
+			buf.WriteString(field)
+			continue
+		}
+		buf.WriteRune(f.quote)
+		for _, r := range field {
+			if r == f.quote {
+				buf.WriteRune(f.quote)
+			}
+			buf.WriteRune(r)
+		}
+		buf.WriteRune(f.quote)
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,123 @@
+package csv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func csvDecodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Parsing").
+		Summary("Decodes messages containing delimited data such as CSV or TSV into a batch of structured messages, one for each record.").
+		Description(`
+Each message is expected to contain an entire document of records, which is expanded into a message for each record. When `+"`parse_header_row`"+` is true the first record is used as the header row and each following record becomes a JSON object with the fields of the header row as keys, otherwise each record becomes an array of strings.
+
+Empty lines are skipped, records with a different number of fields than the header row are rejected, and the metadata of the consumed message is copied to each message of the resulting batch. A document without any records results in the message being dropped.`).
+		Field(delimiterField()).
+		Field(quoteField()).
+		Field(service.NewBoolField("lazy_quotes").
+			Description("Whether to allow quotes to appear within non-quoted fields, and non-doubled quotes to appear within quoted fields.").
+			Default(false)).
+		Field(service.NewBoolField("parse_header_row").
+			Description("Whether to reference the first record as a header row, in which case each following record is converted into an object with the header fields as keys.").
+			Default(true)).
+		Example(
+			"Processing CSV Files from AWS S3",
+			"In this example we consume CSV files from AWS S3 as they're written, where each file is read into memory in full with the `all-bytes` codec and expanded into a batch of JSON objects, one per row.",
+			`
+input:
+  aws_s3:
+    bucket: TODO
+    prefix: foos/
+    codec: all-bytes
+    sqs:
+      url: TODO
+  processors:
+    - csv_decode: {}
+`).
+		Version("3.64.0")
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"csv_decode", csvDecodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newCSVDecodeProcessorFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type csvDecodeProcessor struct {
+	format      csvFormat
+	parseHeader bool
+}
+
+func newCSVDecodeProcessorFromConfig(conf *service.ParsedConfig) (*csvDecodeProcessor, error) {
+	format, err := csvFormatFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	if format.lazyQuotes, err = conf.FieldBool("lazy_quotes"); err != nil {
+		return nil, err
+	}
+	parseHeader, err := conf.FieldBool("parse_header_row")
+	if err != nil {
+		return nil, err
+	}
+	return &csvDecodeProcessor{
+		format:      format,
+		parseHeader: parseHeader,
+	}, nil
+}
+
+func (p *csvDecodeProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message contents: %w", err)
+	}
+
+	records, err := p.format.readRecords(string(mBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse delimited data: %w", err)
+	}
+
+	var header []string
+	if p.parseHeader && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+
+	outBatch := make(service.MessageBatch, 0, len(records))
+	for i, record := range records {
+		outMsg := msg.Copy()
+		if header == nil {
+			row := make([]interface{}, len(record))
+			for j, v := range record {
+				row[j] = v
+			}
+			outMsg.SetStructured(row)
+		} else {
+			if len(record) != len(header) {
+				return nil, fmt.Errorf("record %v has %v fields but the header row has %v", i+1, len(record), len(header))
+			}
+			row := make(map[string]interface{}, len(record))
+			for j, v := range record {
+				row[header[j]] = v
+			}
+			outMsg.SetStructured(row)
+		}
+		outBatch = append(outBatch, outMsg)
+	}
+	return outBatch, nil
+}
+
+func (p *csvDecodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
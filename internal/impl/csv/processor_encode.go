@@ -0,0 +1,213 @@
+package csv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func csvEncodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Parsing").
+		Summary("Encodes a batch of structured messages as a document of delimited data such as CSV or TSV, which replaces the batch as a single message.").
+		Description(`
+Each message of a batch is written as a record of the document. Messages that are JSON objects are written with a field for each column, where columns missing from an object are written empty, and messages that are JSON arrays are written with a field for each element of the array. Strings are written as they are, null values are written empty and objects and arrays nested within a message are written as JSON.
+
+When `+"`columns`"+` is empty the columns are derived from the keys of the objects of each batch, in the order in which they first appear. Since each batch is encoded as a single document this processor is typically used within the `+"`batching`"+` config of an output, where the size of documents can be controlled with the batching policy.`).
+		Field(delimiterField()).
+		Field(quoteField()).
+		Field(service.NewStringListField("columns").
+			Description("An explicit list of columns to write, in order. When empty the columns are derived from the keys of the objects of each batch.").
+			Example([]string{"id", "name", "created_at"}).
+			Default([]string{})).
+		Field(service.NewBoolField("write_header_row").
+			Description("Whether to write the columns as a header row at the beginning of each document. A header row is not written when no columns are specified or derived.").
+			Default(true)).
+		Example(
+			"Writing TSV Files to S3",
+			"In this example we batch documents into tab delimited files of at most 1000 rows with a fixed order of columns, which are uploaded to S3.",
+			`
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'data/${! timestamp_unix() }-${! uuid_v4() }.tsv'
+    batching:
+      count: 1000
+      period: 10s
+      processors:
+        - csv_encode:
+            delimiter: "\t"
+            columns: [ id, name, created_at ]
+`).
+		Version("3.64.0")
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"csv_encode", csvEncodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newCSVEncodeProcessorFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type csvEncodeProcessor struct {
+	format      csvFormat
+	columns     []string
+	writeHeader bool
+}
+
+func newCSVEncodeProcessorFromConfig(conf *service.ParsedConfig) (*csvEncodeProcessor, error) {
+	format, err := csvFormatFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := conf.FieldStringList("columns")
+	if err != nil {
+		return nil, err
+	}
+	writeHeader, err := conf.FieldBool("write_header_row")
+	if err != nil {
+		return nil, err
+	}
+	return &csvEncodeProcessor{
+		format:      format,
+		columns:     columns,
+		writeHeader: writeHeader,
+	}, nil
+}
+
+// jsonObjectKeys returns the keys of a JSON object in the order in which they
+// appear, or false if the document is not an object.
+func jsonObjectKeys(b []byte) ([]string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, false
+	}
+	var keys []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, false
+		}
+		keys = append(keys, key)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, false
+		}
+	}
+	return keys, true
+}
+
+func csvFieldString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (p *csvEncodeProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	rows := make([]interface{}, len(batch))
+	for i, m := range batch {
+		v, err := m.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message %v as structured: %w", i, err)
+		}
+		rows[i] = v
+	}
+
+	columns := p.columns
+	if len(columns) == 0 {
+		seen := map[string]struct{}{}
+		for i, m := range batch {
+			if _, isObj := rows[i].(map[string]interface{}); !isObj {
+				continue
+			}
+			b, err := m.AsBytes()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read message %v contents: %w", i, err)
+			}
+			keys, _ := jsonObjectKeys(b)
+			for _, k := range keys {
+				if _, exists := seen[k]; !exists {
+					seen[k] = struct{}{}
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if p.writeHeader && len(columns) > 0 {
+		p.format.writeRecord(&buf, columns)
+	}
+
+	for i, row := range rows {
+		var fields []string
+		switch t := row.(type) {
+		case map[string]interface{}:
+			fields = make([]string, len(columns))
+			for j, c := range columns {
+				field, err := csvFieldString(t[c])
+				if err != nil {
+					return nil, fmt.Errorf("failed to write column %v of message %v: %w", c, i, err)
+				}
+				fields[j] = field
+			}
+		case []interface{}:
+			fields = make([]string, len(t))
+			for j, e := range t {
+				field, err := csvFieldString(e)
+				if err != nil {
+					return nil, fmt.Errorf("failed to write element %v of message %v: %w", j, i, err)
+				}
+				fields[j] = field
+			}
+		default:
+			return nil, fmt.Errorf("expected message %v to be an object or array, got %T", i, row)
+		}
+		p.format.writeRecord(&buf, fields)
+	}
+
+	outMsg := batch[0].Copy()
+	outMsg.SetBytes(buf.Bytes())
+	return []service.MessageBatch{{outMsg}}, nil
+}
+
+func (p *csvEncodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
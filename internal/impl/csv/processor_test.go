@@ -0,0 +1,249 @@
+package csv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVDecode(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		input  string
+		output []string
+		err    string
+	}{
+		"header row": {
+			config: `{}`,
+			input:  "id,name\n1,foo\n\n2,\"bar, \"\"baz\"\"\"\r\n",
+			output: []string{
+				`{"id":"1","name":"foo"}`,
+				`{"id":"2","name":"bar, \"baz\""}`,
+			},
+		},
+		"no header row": {
+			config: `parse_header_row: false`,
+			input:  "1,foo\n2,bar",
+			output: []string{
+				`["1","foo"]`,
+				`["2","bar"]`,
+			},
+		},
+		"tabs and custom quote": {
+			config: `
+delimiter: "\t"
+quote: "'"
+`,
+			input: "id\tdesc\n1\t'multi\nline'\n2\t'it''s\there'\n",
+			output: []string{
+				`{"desc":"multi\nline","id":"1"}`,
+				`{"desc":"it's\there","id":"2"}`,
+			},
+		},
+		"trailing empty field": {
+			config: `{}`,
+			input:  "a,b\nfoo,",
+			output: []string{
+				`{"a":"foo","b":""}`,
+			},
+		},
+		"lazy quotes": {
+			config: `lazy_quotes: true`,
+			input:  "a,b\nfo\"o,\"b\"ar\"",
+			output: []string{
+				`{"a":"fo\"o","b":"b\"ar"}`,
+			},
+		},
+		"bare quote": {
+			config: `{}`,
+			input:  "a,b\nfo\"o,bar",
+			err:    `failed to parse delimited data: line 2: bare '"' in non-quoted field`,
+		},
+		"unclosed quote": {
+			config: `{}`,
+			input:  "a,b\nfoo,\"bar\n",
+			err:    `failed to parse delimited data: line 3: quoted field starting on line 2 is not closed`,
+		},
+		"wrong field count": {
+			config: `{}`,
+			input:  "a,b\nfoo,bar,baz",
+			err:    `record 1 has 3 fields but the header row has 2`,
+		},
+		"empty document": {
+			config: `{}`,
+			input:  "",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := csvDecodeProcessorConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			proc, err := newCSVDecodeProcessorFromConfig(conf)
+			require.NoError(t, err)
+
+			inMsg := service.NewMessage([]byte(test.input))
+			inMsg.MetaSet("foo", "bar")
+
+			batch, err := proc.Process(context.Background(), inMsg)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var actual []string
+			for _, m := range batch {
+				b, err := m.AsBytes()
+				require.NoError(t, err)
+				actual = append(actual, string(b))
+
+				v, _ := m.MetaGet("foo")
+				assert.Equal(t, "bar", v)
+			}
+			assert.Equal(t, test.output, actual)
+		})
+	}
+}
+
+func TestCSVEncode(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		input  []string
+		output string
+		err    string
+	}{
+		"derived columns": {
+			config: `{}`,
+			input: []string{
+				`{"name":"foo","id":1}`,
+				`{"id":2,"extra":true,"name":"bar, \"baz\""}`,
+				`{"id":3,"name":null,"tags":["a","b"]}`,
+			},
+			output: "name,id,extra,tags\nfoo,1,,\n\"bar, \"\"baz\"\"\",2,true,\n,3,,\"[\"\"a\"\",\"\"b\"\"]\"\n",
+		},
+		"explicit columns": {
+			config: `
+columns: [ id, name ]
+delimiter: "\t"
+quote: "'"
+`,
+			input: []string{
+				`{"name":"it's","id":1,"ignored":"yes"}`,
+				`{"id":2}`,
+			},
+			output: "id\tname\n1\t'it''s'\n2\t\n",
+		},
+		"no header row": {
+			config: `write_header_row: false`,
+			input: []string{
+				`{"id":1,"name":" foo"}`,
+			},
+			output: "1,\" foo\"\n",
+		},
+		"arrays": {
+			config: `{}`,
+			input: []string{
+				`["a","b\nc"]`,
+				`[""]`,
+			},
+			output: "a,\"b\nc\"\n\"\"\n",
+		},
+		"scalar message": {
+			config: `{}`,
+			input:  []string{`"foo"`},
+			err:    "expected message 0 to be an object or array, got string",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := csvEncodeProcessorConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			proc, err := newCSVEncodeProcessorFromConfig(conf)
+			require.NoError(t, err)
+
+			var inBatch service.MessageBatch
+			for _, doc := range test.input {
+				inBatch = append(inBatch, service.NewMessage([]byte(doc)))
+			}
+
+			batches, err := proc.ProcessBatch(context.Background(), inBatch)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, batches, 1)
+			require.Len(t, batches[0], 1)
+
+			b, err := batches[0][0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+}
+
+func TestCSVEncodeDecodeRoundTrip(t *testing.T) {
+	encConf, err := csvEncodeProcessorConfig().ParseYAML(`delimiter: "|"`, nil)
+	require.NoError(t, err)
+	enc, err := newCSVEncodeProcessorFromConfig(encConf)
+	require.NoError(t, err)
+
+	decConf, err := csvDecodeProcessorConfig().ParseYAML(`delimiter: "|"`, nil)
+	require.NoError(t, err)
+	dec, err := newCSVDecodeProcessorFromConfig(decConf)
+	require.NoError(t, err)
+
+	inputDocs := []string{
+		`{"a":"foo|bar","b":"line\r\nbreak"}`,
+		`{"a":"\"quoted\"","b":"  padded"}`,
+	}
+
+	var inBatch service.MessageBatch
+	for _, doc := range inputDocs {
+		inBatch = append(inBatch, service.NewMessage([]byte(doc)))
+	}
+
+	batches, err := enc.ProcessBatch(context.Background(), inBatch)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+
+	outBatch, err := dec.Process(context.Background(), batches[0][0])
+	require.NoError(t, err)
+	require.Len(t, outBatch, 2)
+
+	assert.Equal(t, map[string]interface{}{"a": "foo|bar", "b": "line\nbreak"}, mustStructured(t, outBatch[0]))
+	assert.Equal(t, map[string]interface{}{"a": `"quoted"`, "b": "  padded"}, mustStructured(t, outBatch[1]))
+}
+
+func TestCSVBadFormat(t *testing.T) {
+	for config, errStr := range map[string]string{
+		`delimiter: ",,"`:                `delimiter must be a single character, got: ",,"`,
+		`delimiter: ""`:                  `delimiter must be a single character, got: ""`,
+		`quote: "\n"`:                    `quote cannot be a line break`,
+		"delimiter: \"'\"\nquote: \"'\"": `delimiter and quote must be different characters`,
+	} {
+		conf, err := csvDecodeProcessorConfig().ParseYAML(config, nil)
+		require.NoError(t, err)
+
+		_, err = newCSVDecodeProcessorFromConfig(conf)
+		require.EqualError(t, err, errStr, config)
+	}
+}
+
+func mustStructured(t *testing.T, m *service.Message) interface{} {
+	t.Helper()
+
+	v, err := m.AsStructured()
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,364 @@
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("site").
+			Description("The [Datadog site](https://docs.datadoghq.com/getting_started/site/) of the account to send data to.").
+			Example("datadoghq.eu").
+			Example("us3.datadoghq.com").
+			Default("datadoghq.com"),
+		service.NewStringField("api_key").
+			Description("The API key to authenticate with."),
+		service.NewStringField("url").
+			Description("An optional URL to send requests to instead of the intake API of the site, which is useful for sending data via a proxy.").
+			Advanced().
+			Optional(),
+		service.NewStringAnnotatedEnumField("compression", map[string]string{
+			"none":    "Requests are not compressed.",
+			"gzip":    "Requests are compressed with gzip.",
+			"deflate": "Requests are compressed with deflate.",
+		}).
+			Description("The compression algorithm to apply to the body of requests.").
+			Advanced().
+			Default("gzip"),
+		service.NewTLSToggledField("tls"),
+		service.NewDurationField("timeout").
+			Description("The maximum period to wait for each request to complete.").
+			Default("5s").
+			Advanced(),
+		service.NewBackOffField("backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying requests that were rate limited (status 429) or failed with a server error.").
+			Advanced(),
+	}
+}
+
+func tagsMappingField() *service.ConfigField {
+	return service.NewBloblangField("tags_mapping").
+		Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that determines the tags of each message, which must result in either an array of tags of the form `key:value`, or an object where each key and value becomes a tag.").
+		Example(`root.env = "production"
+root.topic = meta("kafka_topic")`).
+		Example(`root = [ "env:production", "team:" + this.team ]`).
+		Optional()
+}
+
+//------------------------------------------------------------------------------
+
+// ddClient sends requests to an intake API of Datadog.
+type ddClient struct {
+	url         string
+	apiKey      string
+	compression string
+	http        *http.Client
+	backoff     *backoff.ExponentialBackOff
+	log         *service.Logger
+}
+
+// ddClientFromConfig creates a client for an intake API, where the host of the
+// API is the subdomain of the site.
+func ddClientFromConfig(conf *service.ParsedConfig, subdomain, path string, log *service.Logger) (*ddClient, error) {
+	c := &ddClient{log: log}
+
+	if conf.Contains("url") {
+		var err error
+		if c.url, err = conf.FieldString("url"); err != nil {
+			return nil, err
+		}
+	} else {
+		site, err := conf.FieldString("site")
+		if err != nil {
+			return nil, err
+		}
+		if site == "" {
+			return nil, errors.New("a site must be specified")
+		}
+		c.url = "https://" + subdomain + "." + site + path
+	}
+
+	var err error
+	if c.apiKey, err = conf.FieldString("api_key"); err != nil {
+		return nil, err
+	}
+	if c.apiKey == "" {
+		return nil, errors.New("an api_key must be specified")
+	}
+	if c.compression, err = conf.FieldString("compression"); err != nil {
+		return nil, err
+	}
+	switch c.compression {
+	case "none", "gzip", "deflate":
+	default:
+		return nil, fmt.Errorf("unrecognised compression: %v", c.compression)
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	c.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	if c.backoff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ddClient) compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c.compression {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	default:
+		return body, nil
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ddRequestError is returned when the intake API rejects a request.
+type ddRequestError struct {
+	status int
+	body   string
+}
+
+func (e *ddRequestError) Error() string {
+	var res struct {
+		Errors []interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(e.body), &res); err == nil && len(res.Errors) > 0 {
+		msgs := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			if s, ok := e.(string); ok {
+				msgs = append(msgs, s)
+			} else {
+				b, _ := json.Marshal(e)
+				msgs = append(msgs, string(b))
+			}
+		}
+		return fmt.Sprintf("request failed with status %v: %v", e.status, strings.Join(msgs, ", "))
+	}
+	if e.body != "" {
+		return fmt.Sprintf("request failed with status %v: %v", e.status, e.body)
+	}
+	return fmt.Sprintf("request failed with status %v", e.status)
+}
+
+func (e *ddRequestError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+func (c *ddClient) do(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", c.apiKey)
+	if c.compression != "none" {
+		req.Header.Set("Content-Encoding", c.compression)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(res.Body)
+		return &ddRequestError{
+			status: res.StatusCode,
+			body:   string(bytes.TrimSpace(resBody)),
+		}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// send posts a JSON payload to the intake API, retrying requests that were
+// rate limited or failed with a server error.
+func (c *ddClient) send(ctx context.Context, payload []byte) error {
+	body, err := c.compress(payload)
+	if err != nil {
+		return err
+	}
+
+	boff := *c.backoff
+	boff.Reset()
+
+	for {
+		err := c.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var reqErr *ddRequestError
+		if errors.As(err, &reqErr) && !reqErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		c.log.Warnf("Retrying failed request: %v\n", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ddTags executes a tags mapping against a message of a batch and returns the
+// resulting tags.
+func ddTags(batch service.MessageBatch, i int, mapping *bloblang.Executor) ([]string, error) {
+	if mapping == nil {
+		return nil, nil
+	}
+
+	res, err := batch.BloblangQuery(i, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("tags mapping failed: %w", err)
+	}
+	if res == nil {
+		return nil, nil
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("tags mapping failed: %w", err)
+	}
+
+	switch t := v.(type) {
+	case []interface{}:
+		tags := make([]string, 0, len(t))
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("tags mapping resulted in a non-string tag: %v", e)
+			}
+			tags = append(tags, s)
+		}
+		return tags, nil
+	case map[string]interface{}:
+		tags := make([]string, 0, len(t))
+		for k, e := range t {
+			switch ev := e.(type) {
+			case string:
+				tags = append(tags, k+":"+ev)
+			case nil:
+				tags = append(tags, k)
+			default:
+				b, err := json.Marshal(ev)
+				if err != nil {
+					return nil, err
+				}
+				tags = append(tags, k+":"+string(b))
+			}
+		}
+		sort.Strings(tags)
+		return tags, nil
+	}
+	return nil, fmt.Errorf("tags mapping must result in an array or object, got %T", v)
+}
+
+//------------------------------------------------------------------------------
+
+// ddEntry is an encoded item of a payload, such as a log or a series, created
+// from a message of a batch.
+type ddEntry struct {
+	index int
+	item  []byte
+}
+
+// sendEntries sends entries within payloads of at most maxCount entries and
+// approximately maxBytes bytes before compression. Each payload consists of
+// the comma separated items of its entries between a prefix and a suffix.
+// Messages of entries that could not be sent are marked as failed within the
+// batch error.
+func (c *ddClient) sendEntries(ctx context.Context, batchErr *service.BatchError, entries []ddEntry, maxCount, maxBytes int, prefix, suffix string) error {
+	var chunks [][]ddEntry
+	var chunk []ddEntry
+	chunkBytes := 0
+	for _, e := range entries {
+		if len(chunk) > 0 && (len(chunk) >= maxCount || chunkBytes+len(e.item)+1 > maxBytes) {
+			chunks = append(chunks, chunk)
+			chunk, chunkBytes = nil, 0
+		}
+		chunk = append(chunk, e)
+		chunkBytes += len(e.item) + 1
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	for _, chunk := range chunks {
+		var payload bytes.Buffer
+		payload.WriteString(prefix)
+		for i, e := range chunk {
+			if i > 0 {
+				payload.WriteByte(',')
+			}
+			payload.Write(e.item)
+		}
+		payload.WriteString(suffix)
+
+		if err := c.send(ctx, payload.Bytes()); err != nil {
+			if ctx.Err() != nil || (len(chunks) == 1 && batchErr.IndexedErrors() == 0) {
+				return err
+			}
+			for _, e := range chunk {
+				batchErr.Failed(e.index, err)
+			}
+		}
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package datadog
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDDRequest struct {
+	encoding string
+	body     string
+}
+
+// readDDRequest reads the decompressed body of a request to the API.
+func readDDRequest(t *testing.T, r *http.Request) testDDRequest {
+	t.Helper()
+
+	assert.Equal(t, "foo", r.Header.Get("DD-API-KEY"))
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+	encoding := r.Header.Get("Content-Encoding")
+	var body io.Reader = r.Body
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body = zr
+	case "deflate":
+		zr, err := zlib.NewReader(r.Body)
+		require.NoError(t, err)
+		body = zr
+	}
+	bodyBytes, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	return testDDRequest{encoding: encoding, body: string(bodyBytes)}
+}
+
+func TestDDTags(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		tags    []string
+		errStr  string
+	}{
+		"array": {
+			mapping: `root = [ "env:prod", "team:" + this.team ]`,
+			tags:    []string{"env:prod", "team:core"},
+		},
+		"object": {
+			mapping: `root.team = this.team
+root.count = 5
+root.flag = null`,
+			tags: []string{"count:5", "flag", "team:core"},
+		},
+		"deleted": {
+			mapping: `root = deleted()`,
+		},
+		"non-string element": {
+			mapping: `root = [ 5 ]`,
+			errStr:  "tags mapping resulted in a non-string tag: 5",
+		},
+		"scalar": {
+			mapping: `root = "nope"`,
+			errStr:  "tags mapping must result in an array or object, got string",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			mapping, err := bloblang.Parse(test.mapping)
+			require.NoError(t, err)
+
+			tags, err := ddTags(service.MessageBatch{
+				service.NewMessage([]byte(`{"team":"core"}`)),
+			}, 0, mapping)
+			if test.errStr != "" {
+				require.EqualError(t, err, test.errStr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.tags, tags)
+		})
+	}
+}
+
+func TestDDClientBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   string
+		errStr string
+	}{
+		"no api key": {
+			conf: `
+name: foo
+api_key: ""
+`,
+			errStr: "an api_key must be specified",
+		},
+		"no site": {
+			conf: `
+name: foo
+api_key: foo
+site: ""
+`,
+			errStr: "a site must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			parsed, err := metricsOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newMetricsOutputFromConfig(parsed, nil)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+
+	parsed, err := metricsOutputConfig().ParseYAML(`
+name: foo
+api_key: foo
+site: datadoghq.eu
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newMetricsOutputFromConfig(parsed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.datadoghq.eu/api/v2/series", out.client.url)
+}
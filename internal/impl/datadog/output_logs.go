@@ -0,0 +1,193 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	// The limits of a single request to the logs intake API.
+	ddLogsMaxCount = 1000
+	ddLogsMaxBytes = 5 * 1024 * 1024
+)
+
+func logsOutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Sends message batches as logs to the Datadog [logs intake API](https://docs.datadoghq.com/api/latest/logs/#send-logs).").
+		Description(output.Description(true, true, `
+Each message becomes a log, where the field `+"`message`"+` determines the content of the log. Datadog parses log messages that are JSON objects into attributes of the log.
+
+Batches are sent in as few requests as possible within the limits of the API, which accepts at most 1000 logs and 5MB of uncompressed data per request.
+
+### Tags
+
+Tags are added to each log with the field `+"`tags_mapping`"+`, which is a [Bloblang mapping](/docs/guides/bloblang/about) that is executed for each message.
+
+### Errors
+
+Requests that are rate limited (status 429) or fail with a server error are retried according to the field `+"`backoff`"+`. Requests that are rejected for any other reason, such as an invalid API key, are not retried by this output.`))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField("message").
+			Description("The content of each log.").
+			Default("${! content() }")).
+		Field(service.NewInterpolatedStringField("service").
+			Description("The name of the service that generated each log.").
+			Example("payments").
+			Default("")).
+		Field(service.NewInterpolatedStringField("source").
+			Description("The technology the logs originated from, which is used by Datadog to select an integration pipeline for processing logs.").
+			Example("nginx").
+			Default("")).
+		Field(service.NewInterpolatedStringField("hostname").
+			Description("The name of the host that generated each log.").
+			Default("")).
+		Field(tagsMappingField()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Application Logs", `
+Send the JSON logs of an application consumed from Kafka to Datadog, tagged with the environment and partition of each message:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ app_logs ]
+    consumer_group: benthos_datadog
+
+output:
+  datadog_logs:
+    site: datadoghq.eu
+    api_key: ${DD_API_KEY}
+    service: ${! json("service") }
+    source: benthos
+    tags_mapping: |
+      root.env = "production"
+      root.partition = meta("kafka_partition")
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"datadog_logs", logsOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newLogsOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type logsOutput struct {
+	client *ddClient
+	log    *service.Logger
+
+	message     *service.InterpolatedString
+	service     *service.InterpolatedString
+	source      *service.InterpolatedString
+	hostname    *service.InterpolatedString
+	tagsMapping *bloblang.Executor
+}
+
+func newLogsOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*logsOutput, error) {
+	l := &logsOutput{log: log}
+
+	var err error
+	if l.client, err = ddClientFromConfig(conf, "http-intake.logs", "/api/v2/logs", log); err != nil {
+		return nil, err
+	}
+	if l.message, err = conf.FieldInterpolatedString("message"); err != nil {
+		return nil, err
+	}
+	if l.service, err = conf.FieldInterpolatedString("service"); err != nil {
+		return nil, err
+	}
+	if l.source, err = conf.FieldInterpolatedString("source"); err != nil {
+		return nil, err
+	}
+	if l.hostname, err = conf.FieldInterpolatedString("hostname"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("tags_mapping") {
+		if l.tagsMapping, err = conf.FieldBloblang("tags_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *logsOutput) Connect(ctx context.Context) error {
+	l.log.Infof("Sending logs to Datadog intake API: %v\n", l.client.url)
+	return nil
+}
+
+// logEntry creates the log of a message of a batch.
+func (l *logsOutput) logEntry(batch service.MessageBatch, i int) ([]byte, error) {
+	entry := map[string]string{
+		"message": batch.InterpolatedString(i, l.message),
+	}
+	if s := batch.InterpolatedString(i, l.service); s != "" {
+		entry["service"] = s
+	}
+	if s := batch.InterpolatedString(i, l.source); s != "" {
+		entry["ddsource"] = s
+	}
+	if s := batch.InterpolatedString(i, l.hostname); s != "" {
+		entry["hostname"] = s
+	}
+
+	tags, err := ddTags(batch, i, l.tagsMapping)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		entry["ddtags"] = strings.Join(tags, ",")
+	}
+	return json.Marshal(entry)
+}
+
+func (l *logsOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to send logs"))
+
+	entries := make([]ddEntry, 0, len(batch))
+	for i := range batch {
+		item, err := l.logEntry(batch, i)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		entries = append(entries, ddEntry{index: i, item: item})
+	}
+	return l.client.sendEntries(ctx, batchErr, entries, ddLogsMaxCount, ddLogsMaxBytes, "[", "]")
+}
+
+func (l *logsOutput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,186 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsOutput(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "deflate"} {
+		compression := compression
+		t.Run(compression, func(t *testing.T) {
+			var reqMut sync.Mutex
+			var reqs []testDDRequest
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := readDDRequest(t, r)
+				reqMut.Lock()
+				reqs = append(reqs, req)
+				reqMut.Unlock()
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer ts.Close()
+
+			conf, err := logsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+compression: `+compression+`
+service: ${! meta("service") }
+source: benthos
+tags_mapping: 'root.env = "prod"'
+`, nil)
+			require.NoError(t, err)
+
+			out, err := newLogsOutputFromConfig(conf, nil)
+			require.NoError(t, err)
+			require.NoError(t, out.Connect(context.Background()))
+
+			first := service.NewMessage([]byte(`{"level":"info"}`))
+			first.MetaSet("service", "payments")
+			second := service.NewMessage([]byte(`hello world`))
+
+			require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{first, second}))
+
+			encoding := compression
+			if encoding == "none" {
+				encoding = ""
+			}
+
+			reqMut.Lock()
+			defer reqMut.Unlock()
+			assert.Equal(t, []testDDRequest{
+				{
+					encoding: encoding,
+					body:     `[{"ddsource":"benthos","ddtags":"env:prod","message":"{\"level\":\"info\"}","service":"payments"},{"ddsource":"benthos","ddtags":"env:prod","message":"hello world"}]`,
+				},
+			}, reqs)
+		})
+	}
+}
+
+func TestLogsOutputChunks(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs []testDDRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := readDDRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, req)
+		n := len(reqs)
+		reqMut.Unlock()
+		if n == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["bad request"]}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	conf, err := logsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLogsOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	batch := make(service.MessageBatch, ddLogsMaxCount+10)
+	for i := range batch {
+		batch[i] = service.NewMessage([]byte(strconv.Itoa(i)))
+	}
+
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	reqMut.Lock()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, ddLogsMaxCount, strings.Count(reqs[0].body, `"message"`))
+	assert.Equal(t, 10, strings.Count(reqs[1].body, `"message"`))
+	reqMut.Unlock()
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			assert.EqualError(t, err, "request failed with status 400: bad request")
+			failed = append(failed, i)
+		}
+		return true
+	})
+	require.Len(t, failed, 10)
+	assert.Equal(t, ddLogsMaxCount, failed[0])
+}
+
+func TestLogsOutputRetries(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddUint32(&reqCount, 1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors":["rate limited"]}`))
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf, err := logsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLogsOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`hello world`)),
+	}))
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&reqCount))
+}
+
+func TestLogsOutputForbidden(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["Forbidden"]}`))
+	}))
+	defer ts.Close()
+
+	conf, err := logsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLogsOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`hello world`)),
+	})
+	require.EqualError(t, err, "request failed with status 403: Forbidden")
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
@@ -0,0 +1,259 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+// The limit of a single request to the metrics intake API is 512000 bytes of
+// compressed data, which payloads are kept within regardless of compression.
+const ddMetricsMaxBytes = 512000
+
+// The metric types of the series API.
+var ddMetricTypes = map[string]int{
+	"count": 1,
+	"rate":  2,
+	"gauge": 3,
+}
+
+func metricsOutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Sends message batches as metric points to the Datadog [metrics intake API](https://docs.datadoghq.com/api/latest/metrics/#submit-metrics).").
+		Description(output.Description(true, true, `
+Each message becomes a point of a metric series, where the name, value and time of the point are determined by the fields `+"`name`"+`, `+"`value`"+` and `+"`timestamp`"+`. Messages where the value or timestamp is not a number are rejected.
+
+The type of each metric is set by the field `+"`type`"+`. The value of `+"`count`"+` and `+"`rate`"+` metrics is the number of events that occurred over the period set by the field `+"`interval`"+`.
+
+### Tags
+
+Tags are added to each point with the field `+"`tags_mapping`"+`, which is a [Bloblang mapping](/docs/guides/bloblang/about) that is executed for each message.
+
+### Errors
+
+Requests that are rate limited (status 429) or fail with a server error are retried according to the field `+"`backoff`"+`. Requests that are rejected for any other reason, such as an invalid API key, are not retried by this output.`))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField("name").
+			Description("The name of the metric of each point.").
+			Example(`benthos.orders.${! json("status") }`)).
+		Field(service.NewInterpolatedStringField("value").
+			Description("The value of each point, which must resolve to a number.").
+			Example(`${! json("duration_ms") }`).
+			Default("${! content() }")).
+		Field(service.NewInterpolatedStringField("timestamp").
+			Description("The time of each point as a unix timestamp in seconds, which must resolve to an integer.").
+			Example(`${! meta("kafka_timestamp_unix") }`).
+			Default("${! timestamp_unix() }")).
+		Field(service.NewStringAnnotatedEnumField("type", map[string]string{
+			"gauge": "The value is the latest value of the metric.",
+			"count": "The value is the number of events that occurred within the interval.",
+			"rate":  "The value is the number of events that occurred within the interval, which is displayed as a rate per second.",
+		}).
+			Description("The type of the metric of each point.").
+			Default("gauge")).
+		Field(service.NewIntField("interval").
+			Description("The period in seconds that the values of `count` and `rate` metrics cover.").
+			Default(0)).
+		Field(service.NewInterpolatedStringField("host").
+			Description("The name of the host that the metric of each point relates to.").
+			Default("")).
+		Field(tagsMappingField()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Order Totals", `
+Record the total of each order consumed from Kafka as a gauge, tagged with the currency of the order:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: benthos_datadog
+
+output:
+  datadog_metrics:
+    api_key: ${DD_API_KEY}
+    name: orders.total
+    value: ${! json("total") }
+    tags_mapping: |
+      root.currency = this.currency.lowercase()
+    batching:
+      count: 100
+      period: 10s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"datadog_metrics", metricsOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newMetricsOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type metricsOutput struct {
+	client *ddClient
+	log    *service.Logger
+
+	name        *service.InterpolatedString
+	value       *service.InterpolatedString
+	timestamp   *service.InterpolatedString
+	metricType  int
+	interval    int
+	host        *service.InterpolatedString
+	tagsMapping *bloblang.Executor
+}
+
+func newMetricsOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*metricsOutput, error) {
+	m := &metricsOutput{log: log}
+
+	var err error
+	if m.client, err = ddClientFromConfig(conf, "api", "/api/v2/series", log); err != nil {
+		return nil, err
+	}
+	if m.name, err = conf.FieldInterpolatedString("name"); err != nil {
+		return nil, err
+	}
+	if m.value, err = conf.FieldInterpolatedString("value"); err != nil {
+		return nil, err
+	}
+	if m.timestamp, err = conf.FieldInterpolatedString("timestamp"); err != nil {
+		return nil, err
+	}
+
+	typeStr, err := conf.FieldString("type")
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if m.metricType, exists = ddMetricTypes[typeStr]; !exists {
+		return nil, fmt.Errorf("unrecognised type: %v", typeStr)
+	}
+	if m.interval, err = conf.FieldInt("interval"); err != nil {
+		return nil, err
+	}
+	if m.interval < 0 {
+		return nil, fmt.Errorf("interval must not be negative, got %v", m.interval)
+	}
+
+	if m.host, err = conf.FieldInterpolatedString("host"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("tags_mapping") {
+		if m.tagsMapping, err = conf.FieldBloblang("tags_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *metricsOutput) Connect(ctx context.Context) error {
+	m.log.Infof("Sending metrics to Datadog intake API: %v\n", m.client.url)
+	return nil
+}
+
+type ddPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type ddResource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ddSeries struct {
+	Metric    string       `json:"metric"`
+	Type      int          `json:"type"`
+	Interval  int          `json:"interval,omitempty"`
+	Points    []ddPoint    `json:"points"`
+	Tags      []string     `json:"tags,omitempty"`
+	Resources []ddResource `json:"resources,omitempty"`
+}
+
+// seriesEntry creates the series of a message of a batch.
+func (m *metricsOutput) seriesEntry(batch service.MessageBatch, i int) ([]byte, error) {
+	series := ddSeries{
+		Metric: batch.InterpolatedString(i, m.name),
+		Type:   m.metricType,
+	}
+	if series.Metric == "" {
+		return nil, errors.New("metric name is empty")
+	}
+	if m.metricType != ddMetricTypes["gauge"] {
+		series.Interval = m.interval
+	}
+
+	valueStr := strings.TrimSpace(batch.InterpolatedString(i, m.value))
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%v' as a number", valueStr)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("value '%v' is not a finite number", valueStr)
+	}
+
+	tsStr := strings.TrimSpace(batch.InterpolatedString(i, m.timestamp))
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp '%v' as an integer", tsStr)
+	}
+	series.Points = []ddPoint{{Timestamp: ts, Value: value}}
+
+	if host := batch.InterpolatedString(i, m.host); host != "" {
+		series.Resources = []ddResource{{Name: host, Type: "host"}}
+	}
+	if series.Tags, err = ddTags(batch, i, m.tagsMapping); err != nil {
+		return nil, err
+	}
+	return json.Marshal(series)
+}
+
+func (m *metricsOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to send metrics"))
+
+	entries := make([]ddEntry, 0, len(batch))
+	for i := range batch {
+		item, err := m.seriesEntry(batch, i)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		entries = append(entries, ddEntry{index: i, item: item})
+	}
+	return m.client.sendEntries(ctx, batchErr, entries, math.MaxInt32, ddMetricsMaxBytes, `{"series":[`, `]}`)
+}
+
+func (m *metricsOutput) Close(ctx context.Context) error {
+	return nil
+}
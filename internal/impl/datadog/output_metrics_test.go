@@ -0,0 +1,120 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs []testDDRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := readDDRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, req)
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}))
+	defer ts.Close()
+
+	conf, err := metricsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+compression: none
+name: orders.${! json("status") }
+value: ${! json("total") }
+timestamp: ${! json("ts") }
+type: count
+interval: 10
+host: ${! meta("host") }
+tags_mapping: 'root = [ "currency:" + this.currency ]'
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newMetricsOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	first := service.NewMessage([]byte(`{"status":"paid","total":12.5,"ts":1650000000,"currency":"gbp"}`))
+	first.MetaSet("host", "foo")
+
+	batch := service.MessageBatch{
+		first,
+		service.NewMessage([]byte(`{"status":"refunded","total":3,"ts":1650000001,"currency":"eur"}`)),
+		service.NewMessage([]byte(`{"status":"paid","total":"nope","ts":1650000002,"currency":"gbp"}`)),
+		service.NewMessage([]byte(`{"status":"paid","total":1,"ts":"later","currency":"gbp"}`)),
+	}
+
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	reqMut.Lock()
+	assert.Equal(t, []testDDRequest{
+		{
+			body: `{"series":[` +
+				`{"metric":"orders.paid","type":1,"interval":10,"points":[{"timestamp":1650000000,"value":12.5}],"tags":["currency:gbp"],"resources":[{"name":"foo","type":"host"}]},` +
+				`{"metric":"orders.refunded","type":1,"interval":10,"points":[{"timestamp":1650000001,"value":3}],"tags":["currency:eur"]}` +
+				`]}`,
+		},
+	}, reqs)
+	reqMut.Unlock()
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	failed := map[int]string{}
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	assert.Equal(t, map[int]string{
+		2: "failed to parse value 'nope' as a number",
+		3: "failed to parse timestamp 'later' as an integer",
+	}, failed)
+}
+
+func TestMetricsOutputGauge(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs []testDDRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := readDDRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, req)
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+	}))
+	defer ts.Close()
+
+	conf, err := metricsOutputConfig().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+name: queue.depth
+timestamp: 1650000000
+interval: 10
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newMetricsOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(` 42 `)),
+	}))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "gzip", reqs[0].encoding)
+	assert.Equal(t, `{"series":[{"metric":"queue.depth","type":3,"points":[{"timestamp":1650000000,"value":42}]}]}`, reqs[0].body)
+}
@@ -0,0 +1,254 @@
+package deltalake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+const deltaLogDir = "_delta_log"
+
+// deltaMaxWriterVersion is the highest writer version of the Delta protocol
+// that is supported, which excludes tables with check constraints, generated
+// columns and other features that require enforcement by writers.
+const deltaMaxWriterVersion = 2
+
+type deltaProtocol struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type deltaMetadata struct {
+	ID               string            `json:"id"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+}
+
+type deltaAdd struct {
+	Path             string             `json:"path"`
+	PartitionValues  map[string]*string `json:"partitionValues"`
+	Size             int64              `json:"size"`
+	ModificationTime int64              `json:"modificationTime"`
+	DataChange       bool               `json:"dataChange"`
+	Stats            string             `json:"stats,omitempty"`
+}
+
+// deltaAction is a single action of a commit to the transaction log, where
+// only one of the fields is set. Actions that are irrelevant to appending
+// data files are ignored when reading commits.
+type deltaAction struct {
+	Protocol   *deltaProtocol         `json:"protocol,omitempty"`
+	MetaData   *deltaMetadata         `json:"metaData,omitempty"`
+	Add        *deltaAdd              `json:"add,omitempty"`
+	CommitInfo map[string]interface{} `json:"commitInfo,omitempty"`
+}
+
+func deltaCommitName(version int64) string {
+	return fmt.Sprintf("%v/%020d.json", deltaLogDir, version)
+}
+
+func parseDeltaCommit(data []byte) ([]deltaAction, error) {
+	var actions []deltaAction
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var a deltaAction
+		if err := json.Unmarshal(line, &a); err != nil {
+			return nil, fmt.Errorf("failed to parse action %v: %w", i, err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+func encodeDeltaCommit(actions []deltaAction) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, a := range actions {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// deltaTableState is the state of a table that is relevant for appending data
+// files as of a version of the table.
+type deltaTableState struct {
+	version  int64
+	protocol *deltaProtocol
+	metadata *deltaMetadata
+}
+
+func (s *deltaTableState) checkWritable() error {
+	if s.protocol.MinWriterVersion > deltaMaxWriterVersion {
+		return fmt.Errorf("table writer version %v is not supported, only tables with a writer version of %v or lower can be written to", s.protocol.MinWriterVersion, deltaMaxWriterVersion)
+	}
+	if mode := s.metadata.Configuration["delta.columnMapping.mode"]; mode != "" && mode != "none" {
+		return fmt.Errorf("tables with column mapping mode %v are not supported", mode)
+	}
+	return nil
+}
+
+type deltaLastCheckpoint struct {
+	Version int64 `json:"version"`
+	Parts   int   `json:"parts"`
+}
+
+// loadDeltaTable reads the latest state of a table from its transaction log,
+// starting from its last checkpoint when one exists.
+func loadDeltaTable(ctx context.Context, store deltaStore) (*deltaTableState, error) {
+	var checkpoint *deltaLastCheckpoint
+	data, err := store.read(ctx, deltaLogDir+"/_last_checkpoint")
+	if err == nil {
+		checkpoint = &deltaLastCheckpoint{}
+		if err := json.Unmarshal(data, checkpoint); err != nil {
+			return nil, fmt.Errorf("failed to parse last checkpoint: %w", err)
+		}
+	} else if !errors.Is(err, errDeltaNotFound) {
+		return nil, fmt.Errorf("failed to read last checkpoint: %w", err)
+	}
+
+	state := &deltaTableState{version: -1}
+	if checkpoint != nil {
+		state.version = checkpoint.Version
+	}
+
+	// Commits are read until the first version that does not exist, which is
+	// the version that the next commit is written to.
+	var commits [][]deltaAction
+	for {
+		data, err := store.read(ctx, deltaCommitName(state.version+1))
+		if errors.Is(err, errDeltaNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit %v: %w", state.version+1, err)
+		}
+		actions, err := parseDeltaCommit(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit %v: %w", state.version+1, err)
+		}
+		commits = append(commits, actions)
+		state.version++
+	}
+	if state.version < 0 {
+		return nil, errors.New("table does not exist, a Delta table must be created before it can be written to")
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		for _, a := range commits[i] {
+			if a.Protocol != nil && state.protocol == nil {
+				state.protocol = a.Protocol
+			}
+			if a.MetaData != nil && state.metadata == nil {
+				state.metadata = a.MetaData
+			}
+		}
+	}
+	if (state.protocol == nil || state.metadata == nil) && checkpoint != nil {
+		protocol, metadata, err := readDeltaCheckpoint(ctx, store, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if state.protocol == nil {
+			state.protocol = protocol
+		}
+		if state.metadata == nil {
+			state.metadata = metadata
+		}
+	}
+	if state.protocol == nil || state.metadata == nil {
+		return nil, errors.New("transaction log does not contain the protocol and metadata of the table")
+	}
+	return state, nil
+}
+
+// deltaCheckpointSchema is the parquet schema of the columns of checkpoints
+// that contain the protocol and metadata of a table.
+const deltaCheckpointSchema = `{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=protocol, repetitiontype=OPTIONAL", "Fields": [
+      {"Tag": "name=minReaderVersion, type=INT32, repetitiontype=OPTIONAL"},
+      {"Tag": "name=minWriterVersion, type=INT32, repetitiontype=OPTIONAL"}
+    ]},
+    {"Tag": "name=metaData, repetitiontype=OPTIONAL", "Fields": [
+      {"Tag": "name=id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+      {"Tag": "name=schemaString, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+      {"Tag": "name=partitionColumns, type=LIST, repetitiontype=OPTIONAL", "Fields": [
+        {"Tag": "name=element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}
+      ]}
+    ]}
+  ]
+}`
+
+func readDeltaCheckpoint(ctx context.Context, store deltaStore, checkpoint *deltaLastCheckpoint) (protocol *deltaProtocol, metadata *deltaMetadata, err error) {
+	var names []string
+	if checkpoint.Parts <= 1 {
+		names = append(names, fmt.Sprintf("%v/%020d.checkpoint.parquet", deltaLogDir, checkpoint.Version))
+	} else {
+		for i := 1; i <= checkpoint.Parts; i++ {
+			names = append(names, fmt.Sprintf("%v/%020d.checkpoint.%010d.%010d.parquet", deltaLogDir, checkpoint.Version, i, checkpoint.Parts))
+		}
+	}
+
+	for _, name := range names {
+		data, err := store.read(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+
+		pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(data), deltaCheckpointSchema, 1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create checkpoint reader: %w", err)
+		}
+
+		numRows := int(pr.GetNumRows())
+		for read := 0; read < numRows; {
+			rows, err := pr.ReadByNumber(1000)
+			if err != nil {
+				pr.ReadStop()
+				return nil, nil, fmt.Errorf("failed to read checkpoint: %w", err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			read += len(rows)
+
+			for _, row := range rows {
+				// Rows are structs generated from the schema, which are
+				// converted into actions by their JSON representation.
+				rowBytes, err := json.Marshal(row)
+				if err != nil {
+					pr.ReadStop()
+					return nil, nil, err
+				}
+				var a deltaAction
+				if err := json.Unmarshal(rowBytes, &a); err != nil {
+					pr.ReadStop()
+					return nil, nil, fmt.Errorf("failed to parse checkpoint action: %w", err)
+				}
+				if a.Protocol != nil && a.Protocol.MinWriterVersion > 0 {
+					protocol = a.Protocol
+				}
+				if a.MetaData != nil && a.MetaData.SchemaString != "" {
+					metadata = a.MetaData
+				}
+			}
+		}
+		pr.ReadStop()
+	}
+	return protocol, metadata, nil
+}
@@ -0,0 +1,76 @@
+package deltalake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDeltaCreateCommit = `{"commitInfo":{"timestamp":1641031200000,"operation":"CREATE TABLE"}}
+{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}
+{"metaData":{"id":"3e4c8e36-1a3b-4d2f-9f2b-1c1b9d2a6f0e","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[{\"name\":\"id\",\"type\":\"long\",\"nullable\":false,\"metadata\":{}},{\"name\":\"region\",\"type\":\"string\",\"nullable\":true,\"metadata\":{}}]}","partitionColumns":["region"],"configuration":{},"createdTime":1641031200000}}
+`
+
+func writeTestDeltaCommits(t *testing.T, root string, commits ...string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, deltaLogDir), 0o755))
+	for i, c := range commits {
+		require.NoError(t, os.WriteFile(filepath.Join(root, filepath.FromSlash(deltaCommitName(int64(i)))), []byte(c), 0o644))
+	}
+}
+
+func TestDeltaLoadTable(t *testing.T) {
+	ctx := context.Background()
+
+	root := t.TempDir()
+	store := &deltaFileStore{root: root}
+
+	_, err := loadDeltaTable(ctx, store)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "table does not exist")
+
+	writeTestDeltaCommits(t, root,
+		testDeltaCreateCommit,
+		`{"add":{"path":"region=eu/part-00000-a.parquet","partitionValues":{"region":"eu"},"size":10,"modificationTime":1641031200000,"dataChange":true}}`+"\n",
+		`{"metaData":{"id":"3e4c8e36-1a3b-4d2f-9f2b-1c1b9d2a6f0e","schemaString":"{\"type\":\"struct\",\"fields\":[]}","partitionColumns":[],"configuration":{"delta.appendOnly":"true"}}}`+"\n",
+	)
+
+	state, err := loadDeltaTable(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), state.version)
+	assert.Equal(t, &deltaProtocol{MinReaderVersion: 1, MinWriterVersion: 2}, state.protocol)
+	assert.Equal(t, []string{}, state.metadata.PartitionColumns)
+	assert.Equal(t, "true", state.metadata.Configuration["delta.appendOnly"])
+	require.NoError(t, state.checkWritable())
+
+	state.protocol.MinWriterVersion = 4
+	assert.Error(t, state.checkWritable())
+
+	state.protocol.MinWriterVersion = 2
+	state.metadata.Configuration["delta.columnMapping.mode"] = "name"
+	assert.Error(t, state.checkWritable())
+}
+
+func TestDeltaFileStoreWriteIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	store := &deltaFileStore{root: t.TempDir()}
+
+	require.NoError(t, store.writeIfAbsent(ctx, deltaCommitName(0), []byte("foo")))
+	assert.Equal(t, errDeltaExists, store.writeIfAbsent(ctx, deltaCommitName(0), []byte("bar")))
+
+	data, err := store.read(ctx, deltaCommitName(0))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(data))
+
+	files, err := os.ReadDir(filepath.Join(store.root, deltaLogDir))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	_, err = store.read(ctx, deltaCommitName(1))
+	assert.Equal(t, errDeltaNotFound, err)
+}
@@ -0,0 +1,533 @@
+package deltalake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	baws "github.com/Jeffail/benthos/v3/internal/impl/aws"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gofrs/uuid"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func deltaLakeOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Appends message batches to a [Delta Lake](https://delta.io/) table as Parquet data files.").
+		Description(output.Description(true, true, `
+The rows of each message batch are written as Parquet data files within the table, and then committed to the transaction log of the table as a new version, after which they become visible to readers of the table. Message batches are only acknowledged once their data files have been committed.
+
+Tables can be stored within S3 (`+"`s3://bucket/path`"+`), Google Cloud Storage (`+"`gs://bucket/path`"+`), Azure Blob Storage (`+"`az://container/path`"+` or `+"`abfss://container@account.dfs.core.windows.net/path`"+`) or a local filesystem. The table must already exist, and tables that use features requiring a writer version above 2, such as check constraints, generated columns or column mapping, are not supported.
+
+### Columns
+
+Each message must be a JSON object, and the value of each column is obtained from the field of the object with the same name, which may be nested for struct columns. Fields that are missing or null are written as null, and messages that are missing values for non-nullable columns are rejected.
+
+The types `+"`boolean`"+`, `+"`byte`"+`, `+"`short`"+`, `+"`integer`"+`, `+"`long`"+`, `+"`float`"+`, `+"`double`"+`, `+"`string`"+`, `+"`binary`"+`, `+"`date`"+` and `+"`timestamp`"+` are supported, as well as nested `+"`struct`"+`, `+"`array`"+` and `+"`map`"+` types with string keys. Dates and timestamps can be written from RFC 3339 strings or numbers of seconds since the unix epoch.
+
+### Partitioning
+
+The values of the partition columns of the table are also taken from the fields of each message with the same name, and a data file is written for each partition of a batch. Partition columns must have primitive types, and values can be derived from other fields of a message with a `+"[`mapping`](/docs/components/processors/mapping)"+` processor.
+
+### Concurrency
+
+Commits use optimistic concurrency, where commits that conflict with a version written concurrently by another writer are retried as the following version according to `+"`commit_backoff`"+`. Changes to the schema or protocol of the table by a concurrent commit cause the batch to be rejected, and the table is reloaded before the rejected batch is written again.
+
+Since S3 does not support writing objects only when absent, commits to tables within S3 are only safe from multiple writers when a DynamoDB table is configured with `+"`aws.lock_table`"+`. The lock table follows the same scheme as the `+"`S3DynamoDBLogStore`"+` of Delta Lake, and therefore commits are also coordinated with other engines that are configured with the same table. It must have a partition key `+"`tablePath`"+` and a sort key `+"`fileName`"+`, both of type string, and optionally a TTL attribute `+"`expireTime`"+`.
+
+This output does not write checkpoints of the transaction log, and therefore tables that are only written to by this output should be checkpointed periodically by other means in order to keep the log efficient to read.
+
+Rows that are rejected, for example due to missing non-nullable columns, do not prevent the remaining rows of the batch from being written, and only the messages of the rejected rows are reported as failed so that they can be handled with [error handling patterns](/docs/configuration/error_handling).`)).
+		Field(service.NewStringField("table_path").
+			Description("The location of the root directory of the table.").
+			Example("s3://bucket/tables/events").
+			Example("gs://bucket/tables/events").
+			Example("abfss://container@account.dfs.core.windows.net/tables/events").
+			Example("./tables/events")).
+		Field(service.NewBackOffField("commit_backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 100,
+			MaxInterval:     time.Second * 5,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying commits that conflict with versions written concurrently by other writers.").
+			Advanced()).
+		Field(service.NewStringEnumField("compression", "uncompressed", "snappy", "gzip", "lz4", "zstd").
+			Description("The type of compression to use when writing Parquet data files.").
+			Default("snappy").
+			Advanced()).
+		Field(service.NewObjectField("aws", append(baws.SessionFields(),
+			service.NewStringField("lock_table").
+				Description("The name of a DynamoDB table used to coordinate commits to tables within S3 between concurrent writers.").
+				Default(""),
+		)...).
+			Description("The AWS configuration used for tables within S3.").
+			Advanced()).
+		Field(service.NewObjectField("azure",
+			service.NewStringField("storage_account").
+				Description("The storage account of tables with paths of the form `az://container/path`.").
+				Default(""),
+			service.NewStringField("storage_access_key").
+				Description("The storage account access key, which is used when a connection string is not specified.").
+				Default(""),
+			service.NewStringField("storage_sas_token").
+				Description("The storage account SAS token, which is used when neither a connection string or access key are specified.").
+				Default(""),
+			service.NewStringField("storage_connection_string").
+				Description("A storage account connection string, which takes precedence over the other credentials.").
+				Default(""),
+		).
+			Description("The Azure configuration used for tables within Azure Blob Storage.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Partitioned Events", `
+Append JSON events consumed from Kafka to a table within S3 that is partitioned by a column `+"`date`"+`, which is derived from the timestamp of each event:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos_delta
+
+pipeline:
+  processors:
+    - mapping: |
+        root = this
+        root.date = this.timestamp.format_timestamp("2006-01-02", "UTC")
+
+output:
+  delta_lake:
+    table_path: s3://bucket/tables/events
+    aws:
+      region: eu-west-1
+      lock_table: delta_log
+    batching:
+      count: 10000
+      period: 30s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"delta_lake", deltaLakeOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newDeltaLakeOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// deltaWriter writes rows of a table schema as data files.
+type deltaWriter struct {
+	schema           *deltaSchema
+	partitionNames   []string
+	partitionColumns []*deltaField
+	parquetSchema    string
+}
+
+func newDeltaWriter(state *deltaTableState) (*deltaWriter, error) {
+	if err := state.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	schema, err := parseDeltaSchema(state.metadata.SchemaString)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &deltaWriter{
+		schema:         schema,
+		partitionNames: state.metadata.PartitionColumns,
+	}
+	for _, name := range state.metadata.PartitionColumns {
+		f := schema.field(name)
+		if f == nil {
+			return nil, fmt.Errorf("partition column %v does not exist in the table schema", name)
+		}
+		switch f.Type.Primitive {
+		case "boolean", "byte", "short", "integer", "long", "float", "double", "string", "binary", "date", "timestamp":
+		default:
+			return nil, fmt.Errorf("partition column %v of type %v is not supported", name, f.Type.Primitive)
+		}
+		w.partitionColumns = append(w.partitionColumns, f)
+	}
+
+	if w.parquetSchema, err = schema.parquetSchema(state.metadata.PartitionColumns); err != nil {
+		return nil, fmt.Errorf("unsupported table schema: %w", err)
+	}
+	return w, nil
+}
+
+// partition removes the partition columns from a row, and returns the
+// partition values of the row along with the relative path of the partition.
+func (w *deltaWriter) partition(row map[string]interface{}) (map[string]*string, string, error) {
+	if len(w.partitionColumns) == 0 {
+		return nil, "", nil
+	}
+
+	values := make(map[string]*string, len(w.partitionColumns))
+	segments := make([]string, 0, len(w.partitionColumns))
+	for _, f := range w.partitionColumns {
+		v, err := deltaPartitionValue(f.Type, row[f.Name])
+		if err != nil {
+			return nil, "", fmt.Errorf("partition column %v: %w", f.Name, err)
+		}
+		delete(row, f.Name)
+
+		// Empty strings are treated as null partition values.
+		dirValue := "__HIVE_DEFAULT_PARTITION__"
+		if v != nil && *v == "" {
+			v = nil
+		}
+		if v != nil {
+			dirValue = deltaEscapePartitionValue(*v)
+		}
+		values[f.Name] = v
+		segments = append(segments, deltaEscapePartitionValue(f.Name)+"="+dirValue)
+	}
+	return values, strings.Join(segments, "/"), nil
+}
+
+type deltaLakeOutput struct {
+	store         deltaStore
+	log           *service.Logger
+	compression   parquet.CompressionCodec
+	codecName     string
+	commitBackoff *backoff.ExponentialBackOff
+
+	// Commits are serialised within the output so that only commits of other
+	// writers can conflict.
+	commitMut sync.Mutex
+
+	mut    sync.Mutex
+	state  *deltaTableState
+	writer *deltaWriter
+}
+
+func newDeltaLakeOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*deltaLakeOutput, error) {
+	o := &deltaLakeOutput{log: log}
+
+	tablePath, err := conf.FieldString("table_path")
+	if err != nil {
+		return nil, err
+	}
+	if tablePath == "" {
+		return nil, errors.New("a table path must be specified")
+	}
+
+	var storeConf deltaStoreConfig
+	if strings.HasPrefix(tablePath, "s3") {
+		if storeConf.sess, err = baws.GetSession(conf.Namespace("aws")); err != nil {
+			return nil, err
+		}
+	}
+	if storeConf.lockTable, err = conf.FieldString("aws", "lock_table"); err != nil {
+		return nil, err
+	}
+	azConf := conf.Namespace("azure")
+	if storeConf.azureAccount, err = azConf.FieldString("storage_account"); err != nil {
+		return nil, err
+	}
+	if storeConf.azureAccessKey, err = azConf.FieldString("storage_access_key"); err != nil {
+		return nil, err
+	}
+	if storeConf.azureSASToken, err = azConf.FieldString("storage_sas_token"); err != nil {
+		return nil, err
+	}
+	if storeConf.azureConnectionString, err = azConf.FieldString("storage_connection_string"); err != nil {
+		return nil, err
+	}
+	if o.store, err = newDeltaStore(tablePath, storeConf); err != nil {
+		return nil, err
+	}
+
+	if o.commitBackoff, err = conf.FieldBackOff("commit_backoff"); err != nil {
+		return nil, err
+	}
+
+	compression, err := conf.FieldString("compression")
+	if err != nil {
+		return nil, err
+	}
+	if o.compression, err = parquet.CompressionCodecFromString(strings.ToUpper(compression)); err != nil {
+		return nil, err
+	}
+	if compression != "uncompressed" {
+		o.codecName = compression
+	}
+	return o, nil
+}
+
+func (o *deltaLakeOutput) loadTable(ctx context.Context) error {
+	state, err := loadDeltaTable(ctx, o.store)
+	if err != nil {
+		return err
+	}
+	w, err := newDeltaWriter(state)
+	if err != nil {
+		return err
+	}
+
+	o.mut.Lock()
+	o.state, o.writer = state, w
+	o.mut.Unlock()
+	return nil
+}
+
+func (o *deltaLakeOutput) Connect(ctx context.Context) error {
+	if err := o.loadTable(ctx); err != nil {
+		return err
+	}
+	o.log.Infof("Appending message batches to Delta Lake table at version %v\n", o.state.version)
+	return nil
+}
+
+type deltaPartitionRows struct {
+	values map[string]*string
+	rows   [][]byte
+	stats  *deltaStats
+}
+
+func (o *deltaLakeOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.Lock()
+	w := o.writer
+	o.mut.Unlock()
+	if w == nil {
+		return service.ErrNotConnected
+	}
+
+	batchErr := service.NewBatchError(batch, errors.New("failed to write rows"))
+
+	partitions := map[string]*deltaPartitionRows{}
+	var partitionPaths []string
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		row, err := w.schema.deltaRow(structured)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+
+		values, partitionPath, err := w.partition(row)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		rowBytes, err := json.Marshal(row)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+
+		p, exists := partitions[partitionPath]
+		if !exists {
+			p = &deltaPartitionRows{
+				values: values,
+				stats:  newDeltaStats(w.schema, w.partitionNames),
+			}
+			partitions[partitionPath] = p
+			partitionPaths = append(partitionPaths, partitionPath)
+		}
+		p.rows = append(p.rows, rowBytes)
+		p.stats.add(row)
+	}
+
+	var actions []deltaAction
+	for _, partitionPath := range partitionPaths {
+		p := partitions[partitionPath]
+		data, err := o.encodeParquet(w, p.rows)
+		if err != nil {
+			return err
+		}
+
+		name := "part-00000-" + uuid.Must(uuid.NewV4()).String() + "-c000"
+		if o.codecName != "" {
+			name += "." + o.codecName
+		}
+		name += ".parquet"
+		if partitionPath != "" {
+			name = partitionPath + "/" + name
+		}
+		if err := o.store.write(ctx, name, data); err != nil {
+			return fmt.Errorf("failed to write data file: %w", err)
+		}
+
+		stats, err := p.stats.json()
+		if err != nil {
+			return err
+		}
+		partitionValues := p.values
+		if partitionValues == nil {
+			partitionValues = map[string]*string{}
+		}
+		actions = append(actions, deltaAction{
+			Add: &deltaAdd{
+				Path:             (&url.URL{Path: name}).EscapedPath(),
+				PartitionValues:  partitionValues,
+				Size:             int64(len(data)),
+				ModificationTime: time.Now().UnixNano() / int64(time.Millisecond),
+				DataChange:       true,
+				Stats:            stats,
+			},
+		})
+	}
+
+	if len(actions) > 0 {
+		if err := o.commit(ctx, actions); err != nil {
+			return err
+		}
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *deltaLakeOutput) encodeParquet(w *deltaWriter, rows [][]byte) ([]byte, error) {
+	buf := buffer.NewBufferFile()
+
+	pw, err := writer.NewJSONWriter(w.parquetSchema, buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = o.compression
+
+	for _, row := range rows {
+		if err := pw.Write(string(row)); err != nil {
+			return nil, fmt.Errorf("failed to write row to data file: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+var errDeltaMetadataChanged = errors.New("table schema or protocol was changed by a concurrent commit")
+
+// commit writes actions to the transaction log as the next version of the
+// table, retrying as the following version when a concurrent writer commits
+// the same version first.
+func (o *deltaLakeOutput) commit(ctx context.Context, actions []deltaAction) error {
+	o.commitMut.Lock()
+	defer o.commitMut.Unlock()
+
+	o.mut.Lock()
+	version := o.state.version + 1
+	partitionColumns := o.state.metadata.PartitionColumns
+	o.mut.Unlock()
+
+	if partitionColumns == nil {
+		partitionColumns = []string{}
+	}
+	partitionBy, err := json.Marshal(partitionColumns)
+	if err != nil {
+		return err
+	}
+
+	commitInfo := deltaAction{
+		CommitInfo: map[string]interface{}{
+			"timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+			"operation": "WRITE",
+			"operationParameters": map[string]interface{}{
+				"mode":        "Append",
+				"partitionBy": string(partitionBy),
+			},
+			"isBlindAppend": true,
+			"engineInfo":    "benthos",
+		},
+	}
+	data, err := encodeDeltaCommit(append([]deltaAction{commitInfo}, actions...))
+	if err != nil {
+		return err
+	}
+
+	boff := *o.commitBackoff
+	boff.Reset()
+	for {
+		err := o.store.writeIfAbsent(ctx, deltaCommitName(version), data)
+		if err == nil {
+			o.mut.Lock()
+			o.state.version = version
+			o.mut.Unlock()
+			return nil
+		}
+		if !errors.Is(err, errDeltaExists) {
+			return fmt.Errorf("failed to commit version %v: %w", version, err)
+		}
+
+		// Appends only conflict with concurrent commits that change the
+		// schema or protocol of the table.
+		winning, err := o.store.read(ctx, deltaCommitName(version))
+		if err != nil {
+			return fmt.Errorf("failed to read commit %v: %w", version, err)
+		}
+		winningActions, err := parseDeltaCommit(winning)
+		if err != nil {
+			return fmt.Errorf("failed to parse commit %v: %w", version, err)
+		}
+		for _, a := range winningActions {
+			if a.MetaData != nil || a.Protocol != nil {
+				if err := o.loadTable(ctx); err != nil {
+					o.log.Errorf("Failed to reload table: %v\n", err)
+				}
+				return errDeltaMetadataChanged
+			}
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return fmt.Errorf("failed to commit version %v: %w", version, errDeltaExists)
+		}
+		o.log.Debugf("Version %v was committed concurrently, retrying as version %v\n", version, version+1)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		o.mut.Lock()
+		if o.state.version < version {
+			o.state.version = version
+		}
+		version = o.state.version + 1
+		o.mut.Unlock()
+	}
+}
+
+func (o *deltaLakeOutput) Close(ctx context.Context) error {
+	return o.store.close()
+}
@@ -0,0 +1,125 @@
+package deltalake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestDeltaLakeOutput(t *testing.T) {
+	root := t.TempDir()
+	writeTestDeltaCommits(t, root, testDeltaCreateCommit)
+
+	conf, err := deltaLakeOutputConfig().ParseYAML(fmt.Sprintf(`
+table_path: %v
+commit_backoff:
+  initial_interval: 1ms
+  max_interval: 10ms
+`, root), nil)
+	require.NoError(t, err)
+
+	out, err := newDeltaLakeOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	require.Equal(t, service.ErrNotConnected, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+	}))
+	require.NoError(t, out.Connect(ctx))
+
+	// A blind append committed concurrently by another writer.
+	writeTestDeltaCommits(t, root, testDeltaCreateCommit,
+		`{"add":{"path":"part-00000-a.parquet","partitionValues":{"region":null},"size":10,"modificationTime":1641031200000,"dataChange":true}}`+"\n",
+	)
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"region":"eu"}`)),
+		service.NewMessage([]byte(`{"region":"us"}`)),
+		service.NewMessage([]byte(`{"id":3,"region":"us/west"}`)),
+		service.NewMessage([]byte(`{"id":4,"region":"eu"}`)),
+		service.NewMessage([]byte(`{"id":5}`)),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+	require.Equal(t, 1, batchErr.IndexedErrors())
+
+	data, err := out.store.read(ctx, deltaCommitName(2))
+	require.NoError(t, err)
+
+	actions, err := parseDeltaCommit(data)
+	require.NoError(t, err)
+	require.Len(t, actions, 4)
+
+	require.NotNil(t, actions[0].CommitInfo)
+	assert.Equal(t, "WRITE", actions[0].CommitInfo["operation"])
+
+	adds := map[string]*deltaAdd{}
+	for _, a := range actions[1:] {
+		require.NotNil(t, a.Add)
+		require.Contains(t, a.Add.PartitionValues, "region")
+
+		region := "null"
+		if v := a.Add.PartitionValues["region"]; v != nil {
+			region = *v
+		}
+		adds[region] = a.Add
+	}
+	require.Len(t, adds, 3)
+
+	assert.Regexp(t, `^region=eu/part-00000-[0-9a-f-]{36}-c000\.snappy\.parquet$`, adds["eu"].Path)
+	assert.Regexp(t, `^region=us%252Fwest/part-00000-`, adds["us/west"].Path)
+	assert.Regexp(t, `^region=__HIVE_DEFAULT_PARTITION__/part-00000-`, adds["null"].Path)
+	assert.JSONEq(t, `{"numRecords":2,"minValues":{"id":1},"maxValues":{"id":4},"nullCount":{"id":0}}`, adds["eu"].Stats)
+
+	files, err := os.ReadDir(filepath.Join(root, "region=eu"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	fileBytes, err := os.ReadFile(filepath.Join(root, "region=eu", files[0].Name()))
+	require.NoError(t, err)
+	assert.Equal(t, adds["eu"].Size, int64(len(fileBytes)))
+
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(fileBytes), out.writer.parquetSchema, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pr.GetNumRows())
+	pr.ReadStop()
+
+	// A concurrent commit that changes the schema of the table rejects the
+	// batch, after which the table is reloaded.
+	require.NoError(t, os.WriteFile(filepath.Join(root, filepath.FromSlash(deltaCommitName(3))), []byte(testDeltaCreateCommit), 0o644))
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":6,"region":"eu"}`)),
+	})
+	require.Equal(t, errDeltaMetadataChanged, err)
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":6,"region":"eu"}`)),
+	}))
+
+	data, err = out.store.read(ctx, deltaCommitName(4))
+	require.NoError(t, err)
+
+	actions, err = parseDeltaCommit(data)
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	require.NotNil(t, actions[1].Add)
+	assert.Equal(t, "eu", *actions[1].Add.PartitionValues["region"])
+}
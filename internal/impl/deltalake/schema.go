@@ -0,0 +1,495 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+)
+
+// deltaType describes the type of a column of a Delta table schema, which is
+// either a primitive type or a nested struct, array or map.
+type deltaType struct {
+	Primitive string
+
+	// Struct types.
+	Fields []*deltaField
+
+	// Array types.
+	Element      *deltaType
+	ContainsNull bool
+
+	// Map types.
+	Key               *deltaType
+	Value             *deltaType
+	ValueContainsNull bool
+}
+
+type deltaField struct {
+	Name     string     `json:"name"`
+	Type     *deltaType `json:"type"`
+	Nullable bool       `json:"nullable"`
+}
+
+type deltaSchema struct {
+	Fields []*deltaField `json:"fields"`
+}
+
+func (t *deltaType) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &t.Primitive); err == nil {
+		return nil
+	}
+
+	var nested struct {
+		Type              string        `json:"type"`
+		Fields            []*deltaField `json:"fields"`
+		ElementType       *deltaType    `json:"elementType"`
+		ContainsNull      bool          `json:"containsNull"`
+		KeyType           *deltaType    `json:"keyType"`
+		ValueType         *deltaType    `json:"valueType"`
+		ValueContainsNull bool          `json:"valueContainsNull"`
+	}
+	if err := json.Unmarshal(b, &nested); err != nil {
+		return err
+	}
+
+	switch nested.Type {
+	case "struct":
+		t.Fields = nested.Fields
+	case "array":
+		if nested.ElementType == nil {
+			return errors.New("array type is missing an element type")
+		}
+		t.Element, t.ContainsNull = nested.ElementType, nested.ContainsNull
+	case "map":
+		if nested.KeyType == nil || nested.ValueType == nil {
+			return errors.New("map type is missing a key or value type")
+		}
+		t.Key, t.Value, t.ValueContainsNull = nested.KeyType, nested.ValueType, nested.ValueContainsNull
+	default:
+		return fmt.Errorf("unrecognised type: %v", nested.Type)
+	}
+	t.Primitive = nested.Type
+	return nil
+}
+
+func parseDeltaSchema(schemaString string) (*deltaSchema, error) {
+	var s deltaSchema
+	if err := json.Unmarshal([]byte(schemaString), &s); err != nil {
+		return nil, fmt.Errorf("failed to parse table schema: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *deltaSchema) field(name string) *deltaField {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// parquetSchema returns a parquet schema in the JSON format of the parquet
+// writer for the columns of the schema that are not partition columns, which
+// are not stored within data files.
+func (s *deltaSchema) parquetSchema(partitionColumns []string) (string, error) {
+	isPartition := map[string]bool{}
+	for _, c := range partitionColumns {
+		isPartition[c] = true
+	}
+
+	root := parquetschema.NewRoot()
+	for _, f := range s.Fields {
+		if isPartition[f.Name] {
+			continue
+		}
+		child, err := parquetNode(f.Name, f.Nullable, f.Type)
+		if err != nil {
+			return "", fmt.Errorf("column %v: %w", f.Name, err)
+		}
+		root.Fields = append(root.Fields, child)
+	}
+	return root.JSON()
+}
+
+func parquetNode(name string, nullable bool, t *deltaType) (*parquetschema.Node, error) {
+	tag := parquetschema.ColumnTag(name, nullable)
+
+	switch t.Primitive {
+	case "struct":
+		node := &parquetschema.Node{Tag: tag}
+		for _, f := range t.Fields {
+			child, err := parquetNode(f.Name, f.Nullable, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %v: %w", f.Name, err)
+			}
+			node.Fields = append(node.Fields, child)
+		}
+		return node, nil
+	case "array":
+		element, err := parquetNode("element", t.ContainsNull, t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.List(tag, element), nil
+	case "map":
+		if t.Key.Primitive != "string" {
+			return nil, fmt.Errorf("map keys of type %v are not supported", t.Key.Primitive)
+		}
+		key, err := parquetNode("key", false, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parquetNode("value", t.ValueContainsNull, t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.Map(tag, key, value), nil
+	}
+
+	var typeTag string
+	switch t.Primitive {
+	case "boolean":
+		typeTag = "type=BOOLEAN"
+	case "byte":
+		typeTag = "type=INT32, convertedtype=INT_8"
+	case "short":
+		typeTag = "type=INT32, convertedtype=INT_16"
+	case "integer":
+		typeTag = "type=INT32"
+	case "long":
+		typeTag = "type=INT64"
+	case "float":
+		typeTag = "type=FLOAT"
+	case "double":
+		typeTag = "type=DOUBLE"
+	case "string":
+		typeTag = "type=BYTE_ARRAY, convertedtype=UTF8"
+	case "binary":
+		typeTag = "type=BYTE_ARRAY"
+	case "date":
+		typeTag = "type=INT32, convertedtype=DATE"
+	case "timestamp":
+		typeTag = "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"
+	default:
+		return nil, fmt.Errorf("type %v is not supported", t.Primitive)
+	}
+	return &parquetschema.Node{Tag: tag + ", " + typeTag}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// deltaRow converts a structured message into a row of the schema, where the
+// values of the row are in the physical representation of their types.
+func (s *deltaSchema) deltaRow(v interface{}) (map[string]interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object message, got %T", v)
+	}
+	return deltaStruct(s.Fields, obj)
+}
+
+func deltaStruct(fields []*deltaField, obj map[string]interface{}) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, exists := obj[f.Name]
+		if !exists || v == nil {
+			if !f.Nullable {
+				return nil, fmt.Errorf("non-nullable column %v is missing", f.Name)
+			}
+			continue
+		}
+		cv, err := deltaConvertValue(f.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %w", f.Name, err)
+		}
+		row[f.Name] = cv
+	}
+	return row, nil
+}
+
+func deltaConvertValue(t *deltaType, v interface{}) (interface{}, error) {
+	switch t.Primitive {
+	case "struct":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object value, got %T", v)
+		}
+		return deltaStruct(t.Fields, obj)
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array value, got %T", v)
+		}
+		res := make([]interface{}, 0, len(arr))
+		for i, e := range arr {
+			if e == nil {
+				if !t.ContainsNull {
+					return nil, fmt.Errorf("element %v: null elements are not allowed", i)
+				}
+				res = append(res, nil)
+				continue
+			}
+			ce, err := deltaConvertValue(t.Element, e)
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			res = append(res, ce)
+		}
+		return res, nil
+	case "map":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object value, got %T", v)
+		}
+		res := make(map[string]interface{}, len(obj))
+		for k, e := range obj {
+			if e == nil {
+				if !t.ValueContainsNull {
+					return nil, fmt.Errorf("key %v: null values are not allowed", k)
+				}
+				res[k] = nil
+				continue
+			}
+			ce, err := deltaConvertValue(t.Value, e)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", k, err)
+			}
+			res[k] = ce
+		}
+		return res, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean value, got %T", v)
+		}
+		return b, nil
+	case "byte", "short", "integer":
+		var min, max int64 = math.MinInt32, math.MaxInt32
+		switch t.Primitive {
+		case "byte":
+			min, max = math.MinInt8, math.MaxInt8
+		case "short":
+			min, max = math.MinInt16, math.MaxInt16
+		}
+		i, err := parquetschema.IntInRange(v, min, max, t.Primitive)
+		if err != nil {
+			return nil, err
+		}
+		return int32(i), nil
+	case "long":
+		return parquetschema.Int(v)
+	case "float":
+		f, err := parquetschema.Float(v)
+		if err != nil {
+			return nil, err
+		}
+		return float32(f), nil
+	case "double":
+		return parquetschema.Float(v)
+	case "string", "binary":
+		return parquetschema.String(v)
+	case "date":
+		ts, err := parquetschema.Time(v)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.Date(ts), nil
+	case "timestamp":
+		ts, err := parquetschema.Time(v)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.TimestampMicros(ts), nil
+	}
+	return nil, fmt.Errorf("type %v is not supported", t.Primitive)
+}
+
+//------------------------------------------------------------------------------
+
+// deltaPartitionValue returns the serialized form of a partition value as it
+// is recorded within the transaction log, where nil represents null.
+func deltaPartitionValue(t *deltaType, v interface{}) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	var s string
+	switch t.Primitive {
+	case "date":
+		s = parquetschema.MicrosTime(int64(v.(int32)) * 86400 * 1e6).Format("2006-01-02")
+	case "timestamp":
+		s = parquetschema.MicrosTime(v.(int64)).Format("2006-01-02 15:04:05.000000")
+	case "boolean", "byte", "short", "integer", "long", "string", "binary":
+		s = fmt.Sprintf("%v", v)
+	case "float":
+		s = strconv.FormatFloat(float64(v.(float32)), 'g', -1, 32)
+	case "double":
+		s = strconv.FormatFloat(v.(float64), 'g', -1, 64)
+	default:
+		return nil, fmt.Errorf("partition columns of type %v are not supported", t.Primitive)
+	}
+	return &s, nil
+}
+
+// deltaEscapePartitionValue escapes a partition value for use within the
+// directory name of a partition, following the escaping rules of Hive.
+func deltaEscapePartitionValue(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7F || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			b = append(b, fmt.Sprintf("%%%02X", c)...)
+			continue
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+//------------------------------------------------------------------------------
+
+// deltaMaxStatsStringLength is the maximum length of string values that are
+// recorded as the minimum and maximum of a column.
+const deltaMaxStatsStringLength = 32
+
+// deltaStats collects the statistics of a data file that are recorded within
+// its add action, which allows readers to skip files when filtering rows.
+type deltaStats struct {
+	columns    []*deltaField
+	numRecords int64
+	min        map[string]interface{}
+	max        map[string]interface{}
+	nullCount  map[string]int64
+	noBounds   map[string]bool
+}
+
+func newDeltaStats(schema *deltaSchema, partitionColumns []string) *deltaStats {
+	isPartition := map[string]bool{}
+	for _, c := range partitionColumns {
+		isPartition[c] = true
+	}
+
+	s := &deltaStats{
+		min:       map[string]interface{}{},
+		max:       map[string]interface{}{},
+		nullCount: map[string]int64{},
+		noBounds:  map[string]bool{},
+	}
+	for _, f := range schema.Fields {
+		switch f.Type.Primitive {
+		case "struct", "array", "map":
+			continue
+		}
+		if !isPartition[f.Name] {
+			s.columns = append(s.columns, f)
+		}
+	}
+	return s
+}
+
+func deltaLess(a, b interface{}) bool {
+	switch ta := a.(type) {
+	case int32:
+		return ta < b.(int32)
+	case int64:
+		return ta < b.(int64)
+	case float32:
+		return ta < b.(float32)
+	case float64:
+		return ta < b.(float64)
+	case string:
+		return ta < b.(string)
+	}
+	return false
+}
+
+func (s *deltaStats) add(row map[string]interface{}) {
+	s.numRecords++
+	for _, f := range s.columns {
+		v, exists := row[f.Name]
+		if !exists || v == nil {
+			s.nullCount[f.Name]++
+			continue
+		}
+		if _, exists := s.nullCount[f.Name]; !exists {
+			s.nullCount[f.Name] = 0
+		}
+
+		switch f.Type.Primitive {
+		case "boolean", "binary":
+			continue
+		case "float":
+			if math.IsNaN(float64(v.(float32))) {
+				s.noBounds[f.Name] = true
+			}
+		case "double":
+			if math.IsNaN(v.(float64)) {
+				s.noBounds[f.Name] = true
+			}
+		case "string":
+			if len(v.(string)) > deltaMaxStatsStringLength {
+				s.noBounds[f.Name] = true
+			}
+		}
+		if s.noBounds[f.Name] {
+			continue
+		}
+
+		if min, exists := s.min[f.Name]; !exists || deltaLess(v, min) {
+			s.min[f.Name] = v
+		}
+		if max, exists := s.max[f.Name]; !exists || deltaLess(max, v) {
+			s.max[f.Name] = v
+		}
+	}
+}
+
+func deltaStatsValue(t *deltaType, v interface{}, isMax bool) interface{} {
+	switch t.Primitive {
+	case "date":
+		return parquetschema.MicrosTime(int64(v.(int32)) * 86400 * 1e6).Format("2006-01-02")
+	case "timestamp":
+		// Statistics are recorded with millisecond precision, and therefore
+		// the bounds are widened to the nearest millisecond.
+		micros := v.(int64)
+		millis := parquetschema.FloorDiv(micros, 1000)
+		if isMax && micros%1000 != 0 {
+			millis++
+		}
+		return parquetschema.MicrosTime(millis * 1000).Format("2006-01-02T15:04:05.000Z")
+	}
+	return v
+}
+
+func (s *deltaStats) json() (string, error) {
+	minValues := map[string]interface{}{}
+	maxValues := map[string]interface{}{}
+	for _, f := range s.columns {
+		if s.noBounds[f.Name] {
+			continue
+		}
+		if v, exists := s.min[f.Name]; exists {
+			minValues[f.Name] = deltaStatsValue(f.Type, v, false)
+		}
+		if v, exists := s.max[f.Name]; exists {
+			maxValues[f.Name] = deltaStatsValue(f.Type, v, true)
+		}
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"numRecords": s.numRecords,
+		"minValues":  minValues,
+		"maxValues":  maxValues,
+		"nullCount":  s.nullCount,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
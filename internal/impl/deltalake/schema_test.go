@@ -0,0 +1,200 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDeltaSchema = `{
+  "type": "struct",
+  "fields": [
+    {"name": "id", "type": "long", "nullable": false, "metadata": {}},
+    {"name": "name", "type": "string", "nullable": true, "metadata": {}},
+    {"name": "ts", "type": "timestamp", "nullable": true, "metadata": {}},
+    {"name": "date", "type": "date", "nullable": true, "metadata": {}},
+    {"name": "tags", "type": {"type": "array", "elementType": "string", "containsNull": false}, "nullable": true, "metadata": {}},
+    {"name": "attrs", "type": {"type": "map", "keyType": "string", "valueType": "double", "valueContainsNull": true}, "nullable": true, "metadata": {}},
+    {"name": "location", "type": {"type": "struct", "fields": [
+      {"name": "lat", "type": "float", "nullable": false, "metadata": {}},
+      {"name": "level", "type": "short", "nullable": true, "metadata": {}}
+    ]}, "nullable": true, "metadata": {}}
+  ]
+}`
+
+func TestDeltaParquetSchema(t *testing.T) {
+	schema, err := parseDeltaSchema(testDeltaSchema)
+	require.NoError(t, err)
+
+	pSchema, err := schema.parquetSchema([]string{"date"})
+	require.NoError(t, err)
+
+	var root parquetschema.Node
+	require.NoError(t, json.Unmarshal([]byte(pSchema), &root))
+	assert.Equal(t, parquetschema.Node{
+		Tag: "name=root, repetitiontype=REQUIRED",
+		Fields: []*parquetschema.Node{
+			{Tag: "name=id, repetitiontype=REQUIRED, type=INT64"},
+			{Tag: "name=name, repetitiontype=OPTIONAL, type=BYTE_ARRAY, convertedtype=UTF8"},
+			{Tag: "name=ts, repetitiontype=OPTIONAL, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"},
+			{Tag: "name=tags, repetitiontype=OPTIONAL, type=LIST", Fields: []*parquetschema.Node{
+				{Tag: "name=element, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+			}},
+			{Tag: "name=attrs, repetitiontype=OPTIONAL, type=MAP", Fields: []*parquetschema.Node{
+				{Tag: "name=key, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+				{Tag: "name=value, repetitiontype=OPTIONAL, type=DOUBLE"},
+			}},
+			{Tag: "name=location, repetitiontype=OPTIONAL", Fields: []*parquetschema.Node{
+				{Tag: "name=lat, repetitiontype=REQUIRED, type=FLOAT"},
+				{Tag: "name=level, repetitiontype=OPTIONAL, type=INT32, convertedtype=INT_16"},
+			}},
+		},
+	}, root)
+
+	schema, err = parseDeltaSchema(`{"type":"struct","fields":[{"name":"d","type":"decimal(10,2)","nullable":true}]}`)
+	require.NoError(t, err)
+
+	_, err = schema.parquetSchema(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "type decimal(10,2) is not supported")
+}
+
+func TestDeltaRow(t *testing.T) {
+	schema, err := parseDeltaSchema(testDeltaSchema)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		input       string
+		output      map[string]interface{}
+		errContains string
+	}{
+		"all columns": {
+			input: `{
+  "id": 5,
+  "name": "foo",
+  "ts": "2022-01-01T10:00:00.5Z",
+  "date": "2022-01-02",
+  "tags": ["a", "b"],
+  "attrs": { "x": 1.5, "y": null },
+  "location": { "lat": 51.5, "level": 3 },
+  "ignored": true
+}`,
+			output: map[string]interface{}{
+				"id":   int64(5),
+				"name": "foo",
+				"ts":   int64(1641031200500000),
+				"date": int32(18994),
+				"tags": []interface{}{"a", "b"},
+				"attrs": map[string]interface{}{
+					"x": 1.5,
+					"y": nil,
+				},
+				"location": map[string]interface{}{
+					"lat":   float32(51.5),
+					"level": int32(3),
+				},
+			},
+		},
+		"unix timestamp": {
+			input: `{"id":1,"ts":1641031200}`,
+			output: map[string]interface{}{
+				"id": int64(1),
+				"ts": int64(1641031200000000),
+			},
+		},
+		"missing non-nullable": {
+			input:       `{"name":"foo"}`,
+			errContains: "non-nullable column id is missing",
+		},
+		"null array element": {
+			input:       `{"id":1,"tags":["a",null]}`,
+			errContains: "element 1: null elements are not allowed",
+		},
+		"short overflow": {
+			input:       `{"id":1,"location":{"lat":1,"level":40000}}`,
+			errContains: "overflows short",
+		},
+		"not an object": {
+			input:       `[1,2]`,
+			errContains: "expected object message",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var v interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &v))
+
+			row, err := schema.deltaRow(v)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, row)
+		})
+	}
+}
+
+func TestDeltaPartitionValues(t *testing.T) {
+	tests := map[string]struct {
+		primitive string
+		input     interface{}
+		output    string
+	}{
+		"date":      {primitive: "date", input: int32(18994), output: "2022-01-02"},
+		"timestamp": {primitive: "timestamp", input: int64(1641031200500000), output: "2022-01-01 10:00:00.500000"},
+		"long":      {primitive: "long", input: int64(-5), output: "-5"},
+		"boolean":   {primitive: "boolean", input: true, output: "true"},
+		"double":    {primitive: "double", input: 1.25, output: "1.25"},
+		"string":    {primitive: "string", input: "a/b", output: "a/b"},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			v, err := deltaPartitionValue(&deltaType{Primitive: test.primitive}, test.input)
+			require.NoError(t, err)
+			require.NotNil(t, v)
+			assert.Equal(t, test.output, *v)
+		})
+	}
+
+	v, err := deltaPartitionValue(&deltaType{Primitive: "string"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	assert.Equal(t, "a%2Fb c%3D%25", deltaEscapePartitionValue("a/b c=%"))
+}
+
+func TestDeltaStats(t *testing.T) {
+	schema, err := parseDeltaSchema(testDeltaSchema)
+	require.NoError(t, err)
+
+	stats := newDeltaStats(schema, []string{"date"})
+	for _, input := range []string{
+		`{"id":3,"name":"b","ts":"2022-01-01T10:00:00.0005Z"}`,
+		`{"id":1,"name":"this is a name that is longer than thirty two characters"}`,
+		`{"id":2,"ts":"2022-01-01T09:00:00Z"}`,
+	} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(input), &v))
+		row, err := schema.deltaRow(v)
+		require.NoError(t, err)
+		stats.add(row)
+	}
+
+	statsJSON, err := stats.json()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "numRecords": 3,
+  "minValues": {"id": 1, "ts": "2022-01-01T09:00:00.000Z"},
+  "maxValues": {"id": 3, "ts": "2022-01-01T10:00:00.001Z"},
+  "nullCount": {"id": 0, "name": 1, "ts": 1}
+}`, statsJSON)
+}
@@ -0,0 +1,449 @@
+package deltalake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	azstorage "github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gofrs/uuid"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	// errDeltaNotFound is returned by stores when reading a file that does not
+	// exist.
+	errDeltaNotFound = errors.New("file does not exist")
+
+	// errDeltaExists is returned by stores when a file written with
+	// writeIfAbsent already exists.
+	errDeltaExists = errors.New("file already exists")
+)
+
+// deltaStore reads and writes the files of a table, where paths are relative
+// to the root of the table.
+type deltaStore interface {
+	read(ctx context.Context, name string) ([]byte, error)
+	write(ctx context.Context, name string, data []byte) error
+
+	// writeIfAbsent writes a file only if it does not already exist, and
+	// otherwise returns errDeltaExists. Commits to the transaction log rely on
+	// this operation being atomic.
+	writeIfAbsent(ctx context.Context, name string, data []byte) error
+
+	close() error
+}
+
+type deltaStoreConfig struct {
+	sess      *session.Session
+	lockTable string
+
+	azureAccount          string
+	azureAccessKey        string
+	azureSASToken         string
+	azureConnectionString string
+}
+
+func newDeltaStore(tablePath string, conf deltaStoreConfig) (deltaStore, error) {
+	u, err := url.Parse(tablePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse table path: %w", err)
+	}
+
+	switch u.Scheme {
+	case "s3", "s3a", "s3n":
+		return newDeltaS3Store(conf.sess, strings.TrimSuffix(tablePath, "/"), u, conf.lockTable), nil
+	case "gs":
+		return newDeltaGCSStore(u)
+	case "az", "abfs", "abfss", "wasb", "wasbs":
+		return newDeltaAzureStore(u, conf)
+	case "file", "":
+		return &deltaFileStore{root: u.Path}, nil
+	}
+	return nil, fmt.Errorf("table path scheme '%v' is not supported", u.Scheme)
+}
+
+//------------------------------------------------------------------------------
+
+type deltaFileStore struct {
+	root string
+}
+
+func (f *deltaFileStore) read(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.root, filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errDeltaNotFound
+	}
+	return data, err
+}
+
+func (f *deltaFileStore) write(ctx context.Context, name string, data []byte) error {
+	fullPath := filepath.Join(f.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath, data, 0o644)
+}
+
+func (f *deltaFileStore) writeIfAbsent(ctx context.Context, name string, data []byte) error {
+	fullPath := filepath.Join(f.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and then link it to the target, which fails
+	// when the target exists and never exposes a partially written file.
+	tmpPath := filepath.Join(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+"."+uuid.Must(uuid.NewV4()).String()+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	if err := os.Link(tmpPath, fullPath); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return errDeltaExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *deltaFileStore) close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// deltaS3Store accesses tables within S3, which does not support writing
+// objects only when absent. Commits are therefore coordinated with a DynamoDB
+// table when configured, using the same scheme as the S3DynamoDBLogStore of
+// Delta Lake so that writers of other engines can safely commit concurrently.
+type deltaS3Store struct {
+	s3        *s3.S3
+	uploader  *s3manager.Uploader
+	dynamo    *dynamodb.DynamoDB
+	tablePath string
+	bucket    string
+	prefix    string
+	lockTable string
+}
+
+func newDeltaS3Store(sess *session.Session, tablePath string, u *url.URL, lockTable string) *deltaS3Store {
+	client := s3.New(sess)
+	s := &deltaS3Store{
+		s3:        client,
+		uploader:  s3manager.NewUploaderWithClient(client),
+		tablePath: tablePath,
+		bucket:    u.Host,
+		prefix:    strings.Trim(u.Path, "/"),
+		lockTable: lockTable,
+	}
+	if lockTable != "" {
+		s.dynamo = dynamodb.New(sess)
+	}
+	return s
+}
+
+func (s *deltaS3Store) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+func (s *deltaS3Store) getObject(ctx context.Context, name string) ([]byte, error) {
+	obj, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errDeltaNotFound
+		}
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
+
+func (s *deltaS3Store) read(ctx context.Context, name string) ([]byte, error) {
+	data, err := s.getObject(ctx, name)
+	if errors.Is(err, errDeltaNotFound) && s.dynamo != nil && strings.HasPrefix(name, deltaLogDir+"/") {
+		// The commit may have been claimed within the lock table by a writer
+		// that failed before copying it into place, in which case we complete
+		// the commit on its behalf.
+		return s.recoverCommit(ctx, name)
+	}
+	return data, err
+}
+
+func (s *deltaS3Store) write(ctx context.Context, name string, data []byte) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *deltaS3Store) writeIfAbsent(ctx context.Context, name string, data []byte) error {
+	if s.dynamo == nil {
+		// Without a lock table this is only safe when there are no other
+		// writers committing to the table concurrently.
+		if _, err := s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(s.key(name)),
+		}); err == nil {
+			return errDeltaExists
+		} else if aerr, ok := err.(awserr.RequestFailure); !ok || aerr.StatusCode() != http.StatusNotFound {
+			return err
+		}
+		return s.write(ctx, name, data)
+	}
+
+	logDir, fileName := path.Split(name)
+	tempName := ".tmp/" + fileName + "." + uuid.Must(uuid.NewV4()).String()
+	if err := s.write(ctx, logDir+tempName, data); err != nil {
+		return err
+	}
+
+	if _, err := s.dynamo.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.lockTable),
+		Item: map[string]*dynamodb.AttributeValue{
+			"tablePath": {S: aws.String(s.tablePath)},
+			"fileName":  {S: aws.String(fileName)},
+			"tempPath":  {S: aws.String(tempName)},
+			"complete":  {S: aws.String("false")},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(fileName)"),
+	}); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return errDeltaExists
+		}
+		return err
+	}
+
+	if err := s.write(ctx, name, data); err != nil {
+		return err
+	}
+	return s.completeCommit(ctx, fileName)
+}
+
+func (s *deltaS3Store) completeCommit(ctx context.Context, fileName string) error {
+	_, err := s.dynamo.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.lockTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"tablePath": {S: aws.String(s.tablePath)},
+			"fileName":  {S: aws.String(fileName)},
+		},
+		UpdateExpression: aws.String("SET complete = :complete, expireTime = :expire"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":complete": {S: aws.String("true")},
+			":expire":   {N: aws.String(strconv.FormatInt(time.Now().Add(time.Hour*24).Unix(), 10))},
+		},
+	})
+	return err
+}
+
+func (s *deltaS3Store) recoverCommit(ctx context.Context, name string) ([]byte, error) {
+	logDir, fileName := path.Split(name)
+	res, err := s.dynamo.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.lockTable),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"tablePath": {S: aws.String(s.tablePath)},
+			"fileName":  {S: aws.String(fileName)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tempPath, exists := res.Item["tempPath"]
+	if !exists || tempPath.S == nil {
+		return nil, errDeltaNotFound
+	}
+
+	data, err := s.getObject(ctx, logDir+*tempPath.S)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover commit %v: %w", fileName, err)
+	}
+	if err := s.write(ctx, name, data); err != nil {
+		return nil, fmt.Errorf("failed to recover commit %v: %w", fileName, err)
+	}
+	if err := s.completeCommit(ctx, fileName); err != nil {
+		return nil, fmt.Errorf("failed to recover commit %v: %w", fileName, err)
+	}
+	return data, nil
+}
+
+func (s *deltaS3Store) close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type deltaGCSStore struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newDeltaGCSStore(u *url.URL) (*deltaGCSStore, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &deltaGCSStore{
+		client: client,
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+func (g *deltaGCSStore) object(name string) *storage.ObjectHandle {
+	if g.prefix != "" {
+		name = g.prefix + "/" + name
+	}
+	return g.client.Bucket(g.bucket).Object(name)
+}
+
+func (g *deltaGCSStore) read(ctx context.Context, name string) ([]byte, error) {
+	r, err := g.object(name).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, errDeltaNotFound
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func writeGCSObject(ctx context.Context, obj *storage.ObjectHandle, data []byte) error {
+	w := obj.NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (g *deltaGCSStore) write(ctx context.Context, name string, data []byte) error {
+	return writeGCSObject(ctx, g.object(name), data)
+}
+
+func (g *deltaGCSStore) writeIfAbsent(ctx context.Context, name string, data []byte) error {
+	err := writeGCSObject(ctx, g.object(name).If(storage.Conditions{DoesNotExist: true}), data)
+	if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusPreconditionFailed {
+		return errDeltaExists
+	}
+	return err
+}
+
+func (g *deltaGCSStore) close() error {
+	return g.client.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type deltaAzureStore struct {
+	container *azstorage.Container
+	prefix    string
+}
+
+// newDeltaAzureStore creates a store from paths of the form
+// az://container/path, where the storage account is taken from the config,
+// or abfss://container@account.dfs.core.windows.net/path.
+func newDeltaAzureStore(u *url.URL, conf deltaStoreConfig) (*deltaAzureStore, error) {
+	containerName, account := u.Host, conf.azureAccount
+	if u.User != nil {
+		containerName = u.User.Username()
+		account = strings.SplitN(u.Host, ".", 2)[0]
+	}
+
+	var client azstorage.Client
+	var err error
+	if conf.azureConnectionString != "" {
+		if strings.Contains(conf.azureConnectionString, "UseDevelopmentStorage=true;") {
+			client, err = azstorage.NewEmulatorClient()
+		} else {
+			client, err = azstorage.NewClientFromConnectionString(conf.azureConnectionString)
+		}
+	} else if account == "" {
+		return nil, errors.New("an azure storage account must be specified")
+	} else if conf.azureAccessKey != "" {
+		client, err = azstorage.NewBasicClient(account, conf.azureAccessKey)
+	} else {
+		// The SAS token in the Azure UI is provided as an URL query string with
+		// the '?' prepended to it which confuses url.ParseQuery
+		token, perr := url.ParseQuery(strings.TrimPrefix(conf.azureSASToken, "?"))
+		if perr != nil {
+			return nil, fmt.Errorf("invalid azure storage SAS token: %v", perr)
+		}
+		client = azstorage.NewAccountSASClient(account, token, azure.PublicCloud)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure storage account credentials: %v", err)
+	}
+
+	return &deltaAzureStore{
+		container: client.GetBlobService().GetContainerReference(containerName),
+		prefix:    strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+func (a *deltaAzureStore) blob(name string) *azstorage.Blob {
+	if a.prefix != "" {
+		name = a.prefix + "/" + name
+	}
+	return a.container.GetBlobReference(name)
+}
+
+func (a *deltaAzureStore) read(ctx context.Context, name string) ([]byte, error) {
+	r, err := a.blob(name).Get(nil)
+	if err != nil {
+		if serr, ok := err.(azstorage.AzureStorageServiceError); ok && serr.StatusCode == http.StatusNotFound {
+			return nil, errDeltaNotFound
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (a *deltaAzureStore) write(ctx context.Context, name string, data []byte) error {
+	return a.blob(name).CreateBlockBlobFromReader(bytes.NewReader(data), nil)
+}
+
+func (a *deltaAzureStore) writeIfAbsent(ctx context.Context, name string, data []byte) error {
+	err := a.blob(name).CreateBlockBlobFromReader(bytes.NewReader(data), &azstorage.PutBlobOptions{
+		IfNoneMatch: "*",
+	})
+	if serr, ok := err.(azstorage.AzureStorageServiceError); ok &&
+		(serr.StatusCode == http.StatusPreconditionFailed || serr.StatusCode == http.StatusConflict) {
+		return errDeltaExists
+	}
+	return err
+}
+
+func (a *deltaAzureStore) close() error {
+	return nil
+}
@@ -53,6 +53,9 @@ func (client *wrappedBQClient) RunQuery(ctx context.Context, options *bqQueryBui
 	if err != nil {
 		return nil, fmt.Errorf("failed to read rows: %w", err)
 	}
+	if options.pageSize > 0 {
+		it.PageInfo().MaxSize = options.pageSize
+	}
 
 	return it, nil
 }
@@ -73,6 +76,7 @@ type bqQueryBuilderOptions struct {
 	queryParts *bqQueryParts
 	jobLabels  map[string]string
 	args       []interface{}
+	pageSize   int
 }
 
 func buildBQQuery(client *bigquery.Client, options *bqQueryBuilderOptions) (*bigquery.Query, error) {
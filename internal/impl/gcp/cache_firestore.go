@@ -0,0 +1,247 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The maximum number of writes permitted within a single Firestore batch.
+const firestoreMaxBatchWrites = 500
+
+func firestoreCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Summary("Stores key/value pairs as documents within a Google Cloud Firestore collection.").
+		Description(`
+Each key is used as the ID of a document within the target collection, and the value is stored as a bytes field of that document with the name specified by `+"`value_field`"+`. Since document IDs cannot contain forward slashes keys containing them will be rejected by Firestore.
+
+### Expiry
+
+When a `+"`ttl_field`"+` is specified the expiry of each document is written to that field as a timestamp, calculated from either the TTL provided with a set command or the `+"`default_ttl`"+` of the cache. A [Firestore TTL policy](https://cloud.google.com/firestore/docs/ttl) can then be configured on that field in order to have expired documents removed automatically. Since Firestore removes expired documents lazily, documents that have passed their expiry are treated as missing by get and add commands.
+
+This cache type supports setting the TTL individually per key by using the dynamic `+"`ttl`"+` field of a cache processor or output in order to override the general TTL configured at the cache resource level.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to GCP services. You can find out more [in this document](/docs/guides/cloud/gcp).`).
+		Field(service.NewStringField("project").
+			Description("The GCP project containing the Firestore database.")).
+		Field(service.NewStringField("collection").
+			Description("The name of the collection to store documents within.")).
+		Field(service.NewStringField("value_field").
+			Description("The document field to store item values within.").
+			Default("value")).
+		Field(service.NewStringField("ttl_field").
+			Description("An optional document field to store the expiry timestamp of items within. TTLs are only written when this field is set.").
+			Example("expires_at").
+			Default("")).
+		Field(service.NewStringField("default_ttl").
+			Description("An optional default TTL to set for items, calculated from the moment the item is cached.").
+			Example("60s").Example("24h").
+			Default("")).
+		Example("Deduplication", `
+Deduplicate messages by their ID using a Firestore collection, where a TTL policy is set on the field `+"`expires_at`"+` so that entries are removed after a day:`,
+			`
+pipeline:
+  processors:
+    - dedupe:
+        cache: dedupe_cache
+        key: ${! json("id") }
+
+cache_resources:
+  - label: dedupe_cache
+    gcp_firestore:
+      project: TODO
+      collection: dedupe
+      ttl_field: expires_at
+      default_ttl: 24h
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"gcp_firestore", firestoreCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newFirestoreCacheFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type firestoreCache struct {
+	client     *firestore.Client
+	collection *firestore.CollectionRef
+
+	valueField string
+	ttlField   string
+	defaultTTL time.Duration
+}
+
+func newFirestoreCacheFromConfig(conf *service.ParsedConfig) (*firestoreCache, error) {
+	project, err := conf.FieldString("project")
+	if err != nil {
+		return nil, err
+	}
+	collection, err := conf.FieldString("collection")
+	if err != nil {
+		return nil, err
+	}
+	if collection == "" {
+		return nil, errors.New("a collection must be specified")
+	}
+	valueField, err := conf.FieldString("value_field")
+	if err != nil {
+		return nil, err
+	}
+	if valueField == "" {
+		return nil, errors.New("a value_field must be specified")
+	}
+	ttlField, err := conf.FieldString("ttl_field")
+	if err != nil {
+		return nil, err
+	}
+
+	var defaultTTL time.Duration
+	ttlStr, err := conf.FieldString("default_ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ttlStr != "" {
+		if defaultTTL, err = time.ParseDuration(ttlStr); err != nil {
+			return nil, fmt.Errorf("failed to parse default_ttl: %w", err)
+		}
+	}
+
+	client, err := firestore.NewClient(context.Background(), project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &firestoreCache{
+		client:     client,
+		collection: client.Collection(collection),
+		valueField: valueField,
+		ttlField:   ttlField,
+		defaultTTL: defaultTTL,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// docData creates the fields of a document for a given value and optional TTL,
+// where a nil TTL results in the default TTL of the cache.
+func (f *firestoreCache) docData(value []byte, ttl *time.Duration) map[string]interface{} {
+	data := map[string]interface{}{
+		f.valueField: value,
+	}
+	t := f.defaultTTL
+	if ttl != nil {
+		t = *ttl
+	}
+	if t > 0 && f.ttlField != "" {
+		data[f.ttlField] = time.Now().Add(t)
+	}
+	return data
+}
+
+// isExpired returns true if the document has an expiry timestamp that has been
+// passed.
+func (f *firestoreCache) isExpired(snap *firestore.DocumentSnapshot) bool {
+	if f.ttlField == "" {
+		return false
+	}
+	v, err := snap.DataAt(f.ttlField)
+	if err != nil {
+		return false
+	}
+	expiry, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	return !time.Now().Before(expiry)
+}
+
+func (f *firestoreCache) Get(ctx context.Context, key string) ([]byte, error) {
+	snap, err := f.collection.Doc(key).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, service.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	if f.isExpired(snap) {
+		return nil, service.ErrKeyNotFound
+	}
+
+	v, err := snap.DataAt(f.valueField)
+	if err != nil {
+		return nil, service.ErrKeyNotFound
+	}
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	}
+	return nil, fmt.Errorf("expected field %v of document %v to be bytes, found %T", f.valueField, key, v)
+}
+
+func (f *firestoreCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	_, err := f.collection.Doc(key).Set(ctx, f.docData(value, ttl))
+	return err
+}
+
+func (f *firestoreCache) SetMulti(ctx context.Context, keyValues ...service.CacheItem) error {
+	for len(keyValues) > 0 {
+		chunk := keyValues
+		if len(chunk) > firestoreMaxBatchWrites {
+			chunk = chunk[:firestoreMaxBatchWrites]
+		}
+		keyValues = keyValues[len(chunk):]
+
+		batch := f.client.Batch()
+		for _, item := range chunk {
+			batch.Set(f.collection.Doc(item.Key), f.docData(item.Value, item.TTL))
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *firestoreCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	doc := f.collection.Doc(key)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil && snap.Exists() && !f.isExpired(snap) {
+			return service.ErrKeyAlreadyExists
+		}
+		return tx.Set(doc, f.docData(value, ttl))
+	})
+}
+
+func (f *firestoreCache) Delete(ctx context.Context, key string) error {
+	_, err := f.collection.Doc(key).Delete(ctx)
+	return err
+}
+
+func (f *firestoreCache) Close(ctx context.Context) error {
+	return f.client.Close()
+}
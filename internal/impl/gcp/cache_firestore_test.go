@@ -0,0 +1,56 @@
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirestoreCacheConfigErrors(t *testing.T) {
+	spec := firestoreCacheConfig()
+
+	tests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "empty collection",
+			config: `
+project: foo
+collection: ""
+`,
+			errContains: "a collection must be specified",
+		},
+		{
+			name: "empty value field",
+			config: `
+project: foo
+collection: bar
+value_field: ""
+`,
+			errContains: "a value_field must be specified",
+		},
+		{
+			name: "bad default ttl",
+			config: `
+project: foo
+collection: bar
+default_ttl: nope
+`,
+			errContains: "failed to parse default_ttl",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newFirestoreCacheFromConfig(conf)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
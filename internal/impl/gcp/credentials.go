@@ -0,0 +1,57 @@
+package gcp
+
+import (
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+// credentialsFields returns the config fields used to configure the
+// credentials and endpoint of GCP clients.
+func credentialsFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("credentials_json").
+			Description("The contents of a service account key file, or an external account configuration file for [workload identity federation](https://cloud.google.com/iam/docs/workload-identity-federation), in JSON format to authenticate with. More information can be found [in this document](/docs/guides/cloud/gcp).").
+			Version("3.64.0").
+			Default("").Advanced(),
+		service.NewStringField("credentials_file").
+			Description("The path of a service account key file, or an external account configuration file for [workload identity federation](https://cloud.google.com/iam/docs/workload-identity-federation), in JSON format to authenticate with.").
+			Version("3.64.0").
+			Default("").Advanced(),
+		service.NewStringField("impersonate_service_account").
+			Description("The email of a service account to impersonate, where short lived credentials of the service account are obtained with the configured credentials.").
+			Example("foo@bar.iam.gserviceaccount.com").
+			Version("3.64.0").
+			Default("").Advanced(),
+		service.NewStringListField("impersonate_delegates").
+			Description("An optional chain of service accounts that each have permission to impersonate the next, ending with `impersonate_service_account`.").
+			Version("3.64.0").
+			Default([]interface{}{}).Advanced(),
+		service.NewStringField("emulator_endpoint").
+			Description("The endpoint of an emulator to target instead of GCP, in which case requests are made without authentication and gRPC connections are made without TLS.").
+			Example("localhost:8085").
+			Example("http://localhost:4443/storage/v1/").
+			Version("3.64.0").
+			Default("").Advanced(),
+	}
+}
+
+// credentialsFromParsed extracts a credentials config from a parsed config
+// containing the fields of credentialsFields.
+func credentialsFromParsed(conf *service.ParsedConfig) (creds gcpcreds.Config, err error) {
+	if creds.CredentialsJSON, err = conf.FieldString("credentials_json"); err != nil {
+		return
+	}
+	if creds.CredentialsFile, err = conf.FieldString("credentials_file"); err != nil {
+		return
+	}
+	if creds.ImpersonateServiceAccount, err = conf.FieldString("impersonate_service_account"); err != nil {
+		return
+	}
+	if creds.ImpersonateDelegates, err = conf.FieldStringList("impersonate_delegates"); err != nil {
+		return
+	}
+	if creds.EmulatorEndpoint, err = conf.FieldString("emulator_endpoint"); err != nil {
+		return
+	}
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"cloud.google.com/go/bigquery"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/input"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
 	"github.com/Jeffail/benthos/v3/public/service"
 	"google.golang.org/api/iterator"
@@ -15,9 +16,11 @@ import (
 
 type bigQuerySelectInputConfig struct {
 	project     string
+	credentials gcpcreds.Config
 	queryParts  *bqQueryParts
 	argsMapping *bloblang.Executor
 	jobLabels   map[string]string
+	pageSize    int
 }
 
 func bigQuerySelectInputConfigFromParsed(inConf *service.ParsedConfig) (conf bigQuerySelectInputConfig, err error) {
@@ -28,6 +31,10 @@ func bigQuerySelectInputConfigFromParsed(inConf *service.ParsedConfig) (conf big
 		return
 	}
 
+	if conf.credentials, err = credentialsFromParsed(inConf); err != nil {
+		return
+	}
+
 	if inConf.Contains("args_mapping") {
 		if conf.argsMapping, err = inConf.FieldBloblang("args_mapping"); err != nil {
 			return
@@ -38,6 +45,10 @@ func bigQuerySelectInputConfigFromParsed(inConf *service.ParsedConfig) (conf big
 		return
 	}
 
+	if conf.pageSize, err = inConf.FieldInt("page_size"); err != nil {
+		return
+	}
+
 	if queryParts.table, err = inConf.FieldString("table"); err != nil {
 		return
 	}
@@ -70,7 +81,7 @@ func bigQuerySelectInputConfigFromParsed(inConf *service.ParsedConfig) (conf big
 }
 
 func newBigQuerySelectInputConfig() *service.ConfigSpec {
-	return service.NewConfigSpec().
+	spec := service.NewConfigSpec().
 		// Beta().
 		Version("3.63.0").
 		Categories(
@@ -89,6 +100,10 @@ func newBigQuerySelectInputConfig() *service.ConfigSpec {
 			Optional(),
 		).
 		Field(service.NewStringMapField("job_labels").Description("A list of labels to add to the query job.").Default(map[string]string{})).
+		Field(service.NewIntField("page_size").
+			Description("The maximum number of rows to fetch within each page of query results. When zero the page size is chosen by BigQuery.").
+			Default(0).
+			Advanced()).
 		Field(service.NewBloblangField("args_mapping").
 			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) which should evaluate to an array of values matching in size to the number of placeholder arguments in the field `where`.").
 			Example(`root = [ "article", now().format_timestamp("2006-01-02") ]`).
@@ -119,6 +134,11 @@ input:
       root = [ 3 ]
 `,
 		)
+
+	for _, f := range credentialsFields() {
+		spec = spec.Field(f)
+	}
+	return spec
 }
 
 type bigQuerySelectInput struct {
@@ -152,7 +172,11 @@ func (inp *bigQuerySelectInput) Connect(ctx context.Context) error {
 	jobctx, _ := inp.shutdownSig.CloseAtLeisureCtx(context.Background())
 
 	if inp.client == nil {
-		client, err := bigquery.NewClient(jobctx, inp.config.project)
+		opts, err := inp.config.credentials.ClientOptions(jobctx)
+		if err != nil {
+			return err
+		}
+		client, err := bigquery.NewClient(jobctx, inp.config.project, opts...)
 		if err != nil {
 			return fmt.Errorf("failed to create bigquery client: %w", err)
 		}
@@ -180,6 +204,7 @@ func (inp *bigQuerySelectInput) Connect(ctx context.Context) error {
 		queryParts: inp.config.queryParts,
 		jobLabels:  inp.config.jobLabels,
 		args:       args,
+		pageSize:   inp.config.pageSize,
 	})
 	if err != nil {
 		return err
@@ -164,3 +164,25 @@ func TestGCPBigQuerySelectInput_ConnectError(t *testing.T) {
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestGCPBigQuerySelectInput_PageSize(t *testing.T) {
+	spec := newBigQuerySelectInputConfig()
+
+	parsed, err := spec.ParseYAML(testBQInputYAML+`
+page_size: 500
+`, nil)
+	require.NoError(t, err)
+
+	inp, err := newBigQuerySelectInput(parsed, nil)
+	require.NoError(t, err)
+
+	mockClient := &mockBQClient{}
+	mockClient.On("RunQuery", mock.Anything, mock.Anything).Return(&mockBQIterator{}, nil)
+	inp.client = mockClient
+
+	err = inp.Connect(context.Background())
+	require.NoError(t, err)
+
+	call := mockClient.Calls[0]
+	require.Equal(t, 500, call.Arguments[1].(*bqQueryBuilderOptions).pageSize)
+}
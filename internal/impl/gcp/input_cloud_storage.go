@@ -20,6 +20,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"google.golang.org/api/iterator"
 )
 
@@ -75,7 +76,7 @@ services. You can find out more [in this document](/docs/guides/cloud/gcp).`,
 			docs.FieldCommon("prefix", "An optional path prefix, if set only objects with the prefix are consumed."),
 			codec.ReaderDocs,
 			docs.FieldAdvanced("delete_objects", "Whether to delete downloaded objects from the bucket once they are processed."),
-		).ChildDefaultAndTypesFromStruct(input.NewGCPCloudStorageConfig()),
+		).WithChildren(gcpcreds.FieldSpecs()...).ChildDefaultAndTypesFromStruct(input.NewGCPCloudStorageConfig()),
 	})
 }
 
@@ -234,8 +235,11 @@ func newGCPCloudStorageInput(conf input.GCPCloudStorageConfig, log log.Modular,
 // ConnectWithContext attempts to establish a connection to the target Google
 // Cloud Storage bucket.
 func (g *gcpCloudStorageInput) ConnectWithContext(ctx context.Context) error {
-	var err error
-	g.client, err = storage.NewClient(context.Background())
+	opts, err := g.conf.ClientOptions(context.Background())
+	if err != nil {
+		return err
+	}
+	g.client, err = storage.NewClient(context.Background(), opts...)
 	if err != nil {
 		return err
 	}
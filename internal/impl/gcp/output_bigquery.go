@@ -10,6 +10,7 @@ import (
 
 	"cloud.google.com/go/bigquery"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"github.com/Jeffail/benthos/v3/public/service"
 	"golang.org/x/text/encoding/charmap"
 	"google.golang.org/api/googleapi"
@@ -60,6 +61,12 @@ type gcpBigQueryOutputConfig struct {
 
 	// CSV options
 	CSVOptions gcpBigQueryCSVConfig
+
+	// Storage Write API options
+	StorageWriteAPI gcpBigQueryStorageWriteConfig
+
+	// Credentials and emulator endpoint options
+	Credentials gcpcreds.Config
 }
 
 func gcpBigQueryOutputConfigFromParsed(conf *service.ParsedConfig) (gconf gcpBigQueryOutputConfig, err error) {
@@ -96,20 +103,26 @@ func gcpBigQueryOutputConfigFromParsed(conf *service.ParsedConfig) (gconf gcpBig
 	if gconf.CSVOptions, err = gcpBigQueryCSVConfigFromParsed(conf.Namespace("csv")); err != nil {
 		return
 	}
+	if gconf.StorageWriteAPI, err = gcpBigQueryStorageWriteConfigFromParsed(conf.Namespace("storage_write_api")); err != nil {
+		return
+	}
+	if gconf.Credentials, err = credentialsFromParsed(conf); err != nil {
+		return
+	}
 	return
 }
 
 type gcpBQClientURL string
 
-func (g gcpBQClientURL) NewClient(ctx context.Context, projectID string) (*bigquery.Client, error) {
+func (g gcpBQClientURL) NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*bigquery.Client, error) {
 	if g == "" {
-		return bigquery.NewClient(ctx, projectID)
+		return bigquery.NewClient(ctx, projectID, opts...)
 	}
 	return bigquery.NewClient(ctx, projectID, option.WithoutAuthentication(), option.WithEndpoint(string(g)))
 }
 
 func gcpBigQueryConfig() *service.ConfigSpec {
-	return service.NewConfigSpec().
+	spec := service.NewConfigSpec().
 		// Stable(). TODO
 		Categories("GCP", "Services").
 		Version("3.55.0").
@@ -148,7 +161,15 @@ The same is true for the CSV format.
 
 ### CSV
 
-For the CSV format when the field `+"`csv.header`"+` is specified a header row will be inserted as the first line of each message batch. If this field is not provided then the first message of each message batch must include a header line.`)).
+For the CSV format when the field `+"`csv.header`"+` is specified a header row will be inserted as the first line of each message batch. If this field is not provided then the first message of each message batch must include a header line.
+
+## Storage Write API
+
+By default rows are inserted with a load job per message batch. When the field `+"`storage_write_api.enabled`"+` is set to `+"`true`"+` rows are instead appended with the [Storage Write API](https://cloud.google.com/bigquery/docs/write-api), which only supports the `+"`NEWLINE_DELIMITED_JSON`"+` format and requires the table to already exist.
+
+The fields of each JSON document are mapped onto the columns of the table schema, where columns of the type `+"`TIMESTAMP`"+` can be provided as RFC 3339 strings or as microseconds since the epoch, and columns of the type `+"`DATE`"+` can be provided as strings of the form `+"`2006-01-02`"+` or as days since the epoch. Fields that do not match a column are rejected unless `+"`ignore_unknown_values`"+` is `+"`true`"+`.
+
+With `+"`storage_write_api.exactly_once`"+` enabled rows are appended to a committed stream with explicit offsets, and retries of rows that were already written are not duplicated.`)).
 		Field(service.NewStringField("project").Description("The project ID of the dataset to insert data to. If not set, it will be inferred from the credentials or read from the GOOGLE_CLOUD_PROJECT environment variable.").Default("")).
 		Field(service.NewStringField("dataset").Description("The BigQuery Dataset ID.")).
 		Field(service.NewStringField("table").Description("The table to insert messages to.")).
@@ -203,7 +224,12 @@ For the CSV format when the field `+"`csv.header`"+` is specified a header row w
 				Advanced().
 				Default(1),
 		).Description("Specify how CSV data should be interpretted.")).
-		Field(service.NewBatchPolicyField("batching"))
+		Field(gcpBigQueryStorageWriteField())
+
+	for _, f := range credentialsFields() {
+		spec = spec.Field(f)
+	}
+	return spec.Field(service.NewBatchPolicyField("batching"))
 }
 
 func init() {
@@ -233,8 +259,9 @@ type gcpBigQueryOutput struct {
 	conf      gcpBigQueryOutputConfig
 	clientURL gcpBQClientURL
 
-	client  *bigquery.Client
-	connMut sync.RWMutex
+	client        *bigquery.Client
+	storageWriter *bigQueryStorageWriter
+	connMut       sync.RWMutex
 
 	fieldDelimiterBytes []byte
 	csvHeaderBytes      []byte
@@ -252,6 +279,10 @@ func newGCPBigQueryOutput(
 		log:  log,
 	}
 
+	if conf.StorageWriteAPI.Enabled && conf.Format != string(bigquery.JSON) {
+		return nil, fmt.Errorf("the storage write API only supports the %v format", bigquery.JSON)
+	}
+
 	g.newLineBytes = []byte("\n")
 	if conf.Format != string(bigquery.CSV) {
 		return g, nil
@@ -296,8 +327,13 @@ func (g *gcpBigQueryOutput) Connect(ctx context.Context) (err error) {
 	g.connMut.Lock()
 	defer g.connMut.Unlock()
 
+	var opts []option.ClientOption
+	if opts, err = g.conf.Credentials.ClientOptions(context.Background()); err != nil {
+		return
+	}
+
 	var client *bigquery.Client
-	if client, err = g.clientURL.NewClient(context.Background(), g.conf.ProjectID); err != nil {
+	if client, err = g.clientURL.NewClient(context.Background(), g.conf.ProjectID, opts...); err != nil {
 		err = fmt.Errorf("error creating big query client: %w", err)
 		return
 	}
@@ -317,9 +353,10 @@ func (g *gcpBigQueryOutput) Connect(ctx context.Context) (err error) {
 		return
 	}
 
-	if g.conf.CreateDisposition == string(bigquery.CreateNever) {
+	if g.conf.CreateDisposition == string(bigquery.CreateNever) || g.conf.StorageWriteAPI.Enabled {
 		table := dataset.Table(g.conf.TableID)
-		if _, err = table.Metadata(ctx); err != nil {
+		var meta *bigquery.TableMetadata
+		if meta, err = table.Metadata(ctx); err != nil {
 			if hasStatusCode(err, http.StatusNotFound) {
 				err = fmt.Errorf("table does not exist: %v", g.conf.TableID)
 			} else {
@@ -327,6 +364,14 @@ func (g *gcpBigQueryOutput) Connect(ctx context.Context) (err error) {
 			}
 			return
 		}
+		if g.conf.StorageWriteAPI.Enabled {
+			if g.storageWriter, err = newBigQueryStorageWriter(
+				context.Background(), g.conf.StorageWriteAPI, g.conf.IgnoreUnknownValues,
+				client.Project(), g.conf.DatasetID, g.conf.TableID, meta.Schema, g.log, opts...,
+			); err != nil {
+				return
+			}
+		}
 	}
 
 	g.client = client
@@ -343,11 +388,14 @@ func hasStatusCode(err error, code int) bool {
 
 func (g *gcpBigQueryOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
 	g.connMut.RLock()
-	client := g.client
+	client, storageWriter := g.client, g.storageWriter
 	g.connMut.RUnlock()
 	if client == nil {
 		return service.ErrNotConnected
 	}
+	if storageWriter != nil {
+		return storageWriter.writeBatch(ctx, batch)
+	}
 
 	var data bytes.Buffer
 
@@ -409,6 +457,10 @@ func (g *gcpBigQueryOutput) createTableLoader(data *[]byte) *bigquery.Loader {
 
 func (g *gcpBigQueryOutput) Close(ctx context.Context) error {
 	g.connMut.Lock()
+	if g.storageWriter != nil {
+		_ = g.storageWriter.close()
+		g.storageWriter = nil
+	}
 	if g.client != nil {
 		g.client.Close()
 		g.client = nil
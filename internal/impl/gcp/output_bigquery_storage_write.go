@@ -0,0 +1,486 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The maximum size of the rows of a single append request, which is a little
+// below the API limit of 10MB in order to leave room for the request itself.
+const bqStorageMaxRequestBytes = 9 * 1024 * 1024
+
+type gcpBigQueryStorageWriteConfig struct {
+	Enabled     bool
+	ExactlyOnce bool
+	BackOff     *backoff.ExponentialBackOff
+}
+
+func gcpBigQueryStorageWriteConfigFromParsed(conf *service.ParsedConfig) (swconf gcpBigQueryStorageWriteConfig, err error) {
+	if swconf.Enabled, err = conf.FieldBool("enabled"); err != nil {
+		return
+	}
+	if swconf.ExactlyOnce, err = conf.FieldBool("exactly_once"); err != nil {
+		return
+	}
+	if swconf.BackOff, err = conf.FieldBackOff("backoff"); err != nil {
+		return
+	}
+	return
+}
+
+func gcpBigQueryStorageWriteField() *service.ConfigField {
+	defaultBackOff := backoff.NewExponentialBackOff()
+	defaultBackOff.InitialInterval = time.Second
+	defaultBackOff.MaxInterval = 10 * time.Second
+	defaultBackOff.MaxElapsedTime = time.Minute
+
+	return service.NewObjectField("storage_write_api",
+		service.NewBoolField("enabled").
+			Description("Whether to append rows with the Storage Write API instead of load jobs.").
+			Default(false),
+		service.NewBoolField("exactly_once").
+			Description("Whether to append rows to a committed stream with explicit offsets, where rows that are retried after being written are not duplicated. When disabled rows are appended to the default stream of the table with at-least-once delivery.").
+			Default(true),
+		service.NewBackOffField("backoff", false, defaultBackOff).
+			Description("Determines how failed append requests are retried before rejecting a batch.").
+			Advanced(),
+	).Description("Optionally append rows with the [BigQuery Storage Write API](https://cloud.google.com/bigquery/docs/write-api), which requires the format `NEWLINE_DELIMITED_JSON` and a table that already exists.").
+		Advanced()
+}
+
+//------------------------------------------------------------------------------
+
+// bigQueryStorageWriter appends rows to a table with the BigQuery Storage Write
+// API, converting JSON documents to protobuf rows according to the schema of
+// the table.
+type bigQueryStorageWriter struct {
+	conf          gcpBigQueryStorageWriteConfig
+	ignoreUnknown bool
+	log           *service.Logger
+
+	tablePath string
+	schema    bigquery.Schema
+	rowDesc   protoreflect.MessageDescriptor
+
+	client *managedwriter.Client
+
+	streamMut  sync.Mutex
+	stream     *managedwriter.ManagedStream
+	generation int
+	nextOffset int64
+}
+
+func newBigQueryStorageWriter(
+	ctx context.Context,
+	conf gcpBigQueryStorageWriteConfig,
+	ignoreUnknown bool,
+	projectID, datasetID, tableID string,
+	schema bigquery.Schema,
+	log *service.Logger,
+	opts ...option.ClientOption,
+) (*bigQueryStorageWriter, error) {
+	rowDesc, err := bqStorageRowDescriptor(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := managedwriter.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating big query storage write client: %w", err)
+	}
+
+	return &bigQueryStorageWriter{
+		conf:          conf,
+		ignoreUnknown: ignoreUnknown,
+		log:           log,
+		tablePath:     fmt.Sprintf("projects/%s/datasets/%s/tables/%s", projectID, datasetID, tableID),
+		schema:        schema,
+		rowDesc:       rowDesc,
+		client:        client,
+	}, nil
+}
+
+// bqStorageRowDescriptor creates a protobuf message descriptor for the rows of
+// a table schema.
+func bqStorageRowDescriptor(schema bigquery.Schema) (protoreflect.MessageDescriptor, error) {
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert table schema: %w", err)
+	}
+	desc, err := adapt.StorageSchemaToProto2Descriptor(storageSchema, "root")
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert table schema: %w", err)
+	}
+	rowDesc, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.New("converted table schema is not a message descriptor")
+	}
+	return rowDesc, nil
+}
+
+// getStreamLocked returns the current stream, creating a new stream if needed.
+func (w *bigQueryStorageWriter) getStreamLocked() (*managedwriter.ManagedStream, error) {
+	if w.stream != nil {
+		return w.stream, nil
+	}
+
+	descProto, err := adapt.NormalizeDescriptor(w.rowDesc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize row descriptor: %w", err)
+	}
+
+	streamType := managedwriter.DefaultStream
+	if w.conf.ExactlyOnce {
+		streamType = managedwriter.CommittedStream
+	}
+
+	// The stream outlives the context of any single write.
+	if w.stream, err = w.client.NewManagedStream(context.Background(),
+		managedwriter.WithDestinationTable(w.tablePath),
+		managedwriter.WithType(streamType),
+		managedwriter.WithSchemaDescriptor(descProto),
+	); err != nil {
+		return nil, fmt.Errorf("error creating write stream: %w", err)
+	}
+	w.nextOffset = 0
+	return w.stream, nil
+}
+
+// resetStream closes the stream of a generation so that subsequent writes are
+// appended to a new stream.
+func (w *bigQueryStorageWriter) resetStream(generation int) {
+	w.streamMut.Lock()
+	defer w.streamMut.Unlock()
+	if w.generation != generation || w.stream == nil {
+		return
+	}
+	_ = w.stream.Close()
+	w.stream = nil
+	w.generation++
+}
+
+func (w *bigQueryStorageWriter) writeBatch(ctx context.Context, batch service.MessageBatch) error {
+	var rows [][]byte
+	for _, msg := range batch {
+		msgBytes, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+		for _, line := range bytes.Split(msgBytes, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			row, err := bqStorageEncodeRow(w.schema, w.rowDesc, w.ignoreUnknown, line)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	for _, chunk := range bqStorageChunks(rows, bqStorageMaxRequestBytes) {
+		if err := w.appendChunk(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *bigQueryStorageWriter) appendChunk(ctx context.Context, rows [][]byte) error {
+	// Appends are made whilst holding the lock so that they are sent in the
+	// order of their offsets.
+	w.streamMut.Lock()
+	stream, err := w.getStreamLocked()
+	if err != nil {
+		w.streamMut.Unlock()
+		return err
+	}
+	generation, offset := w.generation, managedwriter.NoStreamOffset
+	if w.conf.ExactlyOnce {
+		offset = w.nextOffset
+		w.nextOffset += int64(len(rows))
+	}
+	result, err := w.appendRows(ctx, stream, rows, offset)
+	w.streamMut.Unlock()
+
+	boff := *w.conf.BackOff
+	boff.Reset()
+	for {
+		if err == nil {
+			_, err = result.GetResult(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		if offset != managedwriter.NoStreamOffset && status.Code(err) == codes.AlreadyExists {
+			// The rows at this offset were written by a previous attempt.
+			return nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			// Appends at later offsets of the stream are unable to succeed
+			// once we give up on these rows, and therefore subsequent writes
+			// are made to a new stream.
+			w.resetStream(generation)
+			return fmt.Errorf("error appending rows to bigquery: %w", err)
+		}
+		w.log.Warnf("Retrying failed append of %v rows: %v\n", len(rows), err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			w.resetStream(generation)
+			return ctx.Err()
+		}
+		result, err = w.appendRows(ctx, stream, rows, offset)
+	}
+}
+
+func (w *bigQueryStorageWriter) appendRows(ctx context.Context, stream *managedwriter.ManagedStream, rows [][]byte, offset int64) (*managedwriter.AppendResult, error) {
+	if offset == managedwriter.NoStreamOffset {
+		return stream.AppendRows(ctx, rows)
+	}
+	return stream.AppendRows(ctx, rows, managedwriter.WithOffset(offset))
+}
+
+func (w *bigQueryStorageWriter) close() error {
+	w.streamMut.Lock()
+	defer w.streamMut.Unlock()
+	if w.stream != nil {
+		_ = w.stream.Close()
+		w.stream = nil
+	}
+	return w.client.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// bqStorageChunks splits encoded rows into chunks that fit within a single
+// append request.
+func bqStorageChunks(rows [][]byte, maxBytes int) (chunks [][][]byte) {
+	start, size := 0, 0
+	for i, row := range rows {
+		if i > start && size+len(row) > maxBytes {
+			chunks = append(chunks, rows[start:i])
+			start, size = i, 0
+		}
+		size += len(row)
+	}
+	if start < len(rows) {
+		chunks = append(chunks, rows[start:])
+	}
+	return
+}
+
+// bqStorageEncodeRow maps a JSON document onto the schema of a table and
+// encodes it as a protobuf row.
+func bqStorageEncodeRow(schema bigquery.Schema, desc protoreflect.MessageDescriptor, ignoreUnknown bool, doc []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to parse row: %w", err)
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := bqStorageSetRecord(msg, schema, ignoreUnknown, obj); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+func bqStorageFieldDesc(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := desc.Fields().ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return desc.Fields().ByName(protoreflect.Name(strings.ToLower(name)))
+}
+
+func bqStorageSetRecord(msg protoreflect.Message, schema bigquery.Schema, ignoreUnknown bool, obj map[string]interface{}) error {
+	for k, v := range obj {
+		var field *bigquery.FieldSchema
+		for _, f := range schema {
+			// Column names are case insensitive.
+			if strings.EqualFold(f.Name, k) {
+				field = f
+				break
+			}
+		}
+		if field == nil {
+			if ignoreUnknown {
+				continue
+			}
+			return fmt.Errorf("field '%v' does not match any column of the table", k)
+		}
+		if v == nil {
+			continue
+		}
+
+		fd := bqStorageFieldDesc(msg.Descriptor(), field.Name)
+		if fd == nil {
+			return fmt.Errorf("column '%v' is missing from the row descriptor", field.Name)
+		}
+
+		if field.Repeated {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return fmt.Errorf("field '%v': expected array value, got %T", k, v)
+			}
+			list := msg.Mutable(fd).List()
+			for i, e := range arr {
+				if fd.Kind() == protoreflect.MessageKind {
+					eObj, ok := e.(map[string]interface{})
+					if !ok {
+						return fmt.Errorf("field '%v.%v': expected object value, got %T", k, i, e)
+					}
+					elem := list.NewElement()
+					if err := bqStorageSetRecord(elem.Message(), field.Schema, ignoreUnknown, eObj); err != nil {
+						return fmt.Errorf("field '%v.%v': %w", k, i, err)
+					}
+					list.Append(elem)
+					continue
+				}
+				val, err := bqStorageScalar(fd, field, e)
+				if err != nil {
+					return fmt.Errorf("field '%v.%v': %w", k, i, err)
+				}
+				list.Append(val)
+			}
+			continue
+		}
+
+		if fd.Kind() == protoreflect.MessageKind {
+			vObj, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("field '%v': expected object value, got %T", k, v)
+			}
+			if err := bqStorageSetRecord(msg.Mutable(fd).Message(), field.Schema, ignoreUnknown, vObj); err != nil {
+				return fmt.Errorf("field '%v': %w", k, err)
+			}
+			continue
+		}
+
+		val, err := bqStorageScalar(fd, field, v)
+		if err != nil {
+			return fmt.Errorf("field '%v': %w", k, err)
+		}
+		msg.Set(fd, val)
+	}
+	return nil
+}
+
+// bqStorageScalar converts a JSON value to the protobuf representation of a
+// column, where timestamps and dates may be provided as strings.
+func bqStorageScalar(fd protoreflect.FieldDescriptor, field *bigquery.FieldSchema, v interface{}) (protoreflect.Value, error) {
+	switch field.Type {
+	case bigquery.TimestampFieldType:
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt64(t.UnixNano() / int64(time.Microsecond)), nil
+		}
+	case bigquery.DateFieldType:
+		if s, ok := v.(string); ok {
+			t, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt32(int32(t.Unix() / int64(24*time.Hour/time.Second))), nil
+		}
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch t := v.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(t), nil
+		case string:
+			b, err := strconv.ParseBool(t)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Int32Kind:
+		var i int64
+		var err error
+		switch t := v.(type) {
+		case json.Number:
+			i, err = t.Int64()
+		case string:
+			i, err = strconv.ParseInt(t, 10, 64)
+		default:
+			err = fmt.Errorf("expected number value, got %T", v)
+		}
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if fd.Kind() == protoreflect.Int32Kind {
+			return protoreflect.ValueOfInt32(int32(i)), nil
+		}
+		return protoreflect.ValueOfInt64(i), nil
+	case protoreflect.DoubleKind:
+		var s string
+		switch t := v.(type) {
+		case json.Number:
+			s = t.String()
+		case string:
+			s = t
+		default:
+			return protoreflect.Value{}, fmt.Errorf("expected number value, got %T", v)
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	case protoreflect.StringKind:
+		switch t := v.(type) {
+		case string:
+			return protoreflect.ValueOfString(t), nil
+		case json.Number:
+			return protoreflect.ValueOfString(t.String()), nil
+		case bool:
+			return protoreflect.ValueOfString(strconv.FormatBool(t)), nil
+		default:
+			// Structured values of JSON columns are stored serialised.
+			b, err := json.Marshal(t)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfString(string(b)), nil
+		}
+	case protoreflect.BytesKind:
+		if s, ok := v.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return protoreflect.Value{}, fmt.Errorf("expected base64 encoded value: %w", err)
+			}
+			return protoreflect.ValueOfBytes(b), nil
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("unable to convert %T value to column type %v", v, field.Type)
+}
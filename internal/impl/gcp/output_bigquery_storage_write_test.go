@@ -0,0 +1,150 @@
+package gcp
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var testBQStorageSchema = bigquery.Schema{
+	{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "Name", Type: bigquery.StringFieldType},
+	{Name: "score", Type: bigquery.FloatFieldType},
+	{Name: "active", Type: bigquery.BooleanFieldType},
+	{Name: "created_at", Type: bigquery.TimestampFieldType},
+	{Name: "birthday", Type: bigquery.DateFieldType},
+	{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+	{Name: "attributes", Type: bigquery.StringFieldType},
+	{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+		{Name: "city", Type: bigquery.StringFieldType},
+		{Name: "zip", Type: bigquery.IntegerFieldType},
+	}},
+}
+
+func TestGCPBigQueryStorageEncodeRow(t *testing.T) {
+	desc, err := bqStorageRowDescriptor(testBQStorageSchema)
+	require.NoError(t, err)
+
+	rowBytes, err := bqStorageEncodeRow(testBQStorageSchema, desc, false, []byte(`{
+  "id": 9007199254740993,
+  "name": "foo",
+  "score": 1.5,
+  "active": true,
+  "created_at": "1970-01-01T00:00:01.5Z",
+  "birthday": "1970-01-11",
+  "tags": ["a", "b"],
+  "attributes": {"nested": [1, 2]},
+  "address": {"city": "London", "zip": "123"}
+}`))
+	require.NoError(t, err)
+
+	row := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(rowBytes, row))
+
+	fields := desc.Fields()
+	assert.Equal(t, int64(9007199254740993), row.Get(fields.ByName("id")).Int())
+	assert.Equal(t, "foo", row.Get(bqStorageFieldDesc(desc, "Name")).String())
+	assert.Equal(t, 1.5, row.Get(fields.ByName("score")).Float())
+	assert.True(t, row.Get(fields.ByName("active")).Bool())
+	assert.Equal(t, int64(1500000), row.Get(fields.ByName("created_at")).Int())
+	assert.Equal(t, int64(10), row.Get(fields.ByName("birthday")).Int())
+	assert.Equal(t, `{"nested":[1,2]}`, row.Get(fields.ByName("attributes")).String())
+
+	tags := row.Get(fields.ByName("tags")).List()
+	require.Equal(t, 2, tags.Len())
+	assert.Equal(t, "b", tags.Get(1).String())
+
+	address := row.Get(fields.ByName("address")).Message()
+	addressFields := address.Descriptor().Fields()
+	assert.Equal(t, "London", address.Get(addressFields.ByName("city")).String())
+	assert.Equal(t, int64(123), address.Get(addressFields.ByName("zip")).Int())
+
+	_, err = protojson.Marshal(row)
+	require.NoError(t, err)
+}
+
+func TestGCPBigQueryStorageEncodeRowErrors(t *testing.T) {
+	desc, err := bqStorageRowDescriptor(testBQStorageSchema)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		input       string
+		errContains string
+	}{
+		"not json": {
+			input:       `nope`,
+			errContains: "failed to parse row",
+		},
+		"unknown field": {
+			input:       `{"id":1,"nope":true}`,
+			errContains: "does not match any column",
+		},
+		"bad timestamp": {
+			input:       `{"id":1,"created_at":"yesterday"}`,
+			errContains: "created_at",
+		},
+		"bad integer": {
+			input:       `{"id":"one"}`,
+			errContains: "id",
+		},
+		"bad array": {
+			input:       `{"id":1,"tags":"a"}`,
+			errContains: "expected array value",
+		},
+		"bad record": {
+			input:       `{"id":1,"address":"London"}`,
+			errContains: "expected object value",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			_, err := bqStorageEncodeRow(testBQStorageSchema, desc, false, []byte(test.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+
+	_, err = bqStorageEncodeRow(testBQStorageSchema, desc, true, []byte(`{"id":1,"nope":true}`))
+	require.NoError(t, err)
+}
+
+func TestGCPBigQueryStorageChunks(t *testing.T) {
+	rows := [][]byte{
+		make([]byte, 4),
+		make([]byte, 4),
+		make([]byte, 4),
+		make([]byte, 12),
+		make([]byte, 1),
+	}
+
+	chunks := bqStorageChunks(rows, 10)
+	var sizes []int
+	for _, c := range chunks {
+		sizes = append(sizes, len(c))
+	}
+	assert.Equal(t, []int{2, 1, 1, 1}, sizes)
+
+	assert.Empty(t, bqStorageChunks(nil, 10))
+}
+
+func TestNewGCPBigQueryOutputStorageWriteCSVError(t *testing.T) {
+	config := gcpBigQueryConfFromYAML(t, `
+project: foo
+dataset: bar
+table: baz
+format: CSV
+storage_write_api:
+  enabled: true
+`)
+
+	_, err := newGCPBigQueryOutput(config, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only supports the NEWLINE_DELIMITED_JSON format")
+}
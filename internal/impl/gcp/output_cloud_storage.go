@@ -10,6 +10,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/bundle"
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	ioutput "github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/input"
@@ -19,6 +20,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"github.com/gofrs/uuid"
 )
 
@@ -118,10 +120,11 @@ output:
 					"ignore", "Do not modify the original file, the new data will be dropped.",
 				).AtVersion("3.53.0"),
 			docs.FieldAdvanced("content_encoding", "An optional content encoding to set for each object.").IsInterpolated(),
+			codec.CompressionDocs.AtVersion("3.64.0"),
 			docs.FieldAdvanced("chunk_size", "An optional chunk size which controls the maximum number of bytes of the object that the Writer will attempt to send to the server in a single request. If ChunkSize is set to zero, chunking will be disabled."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			batch.FieldSpec(),
-		).ChildDefaultAndTypesFromStruct(output.NewGCPCloudStorageConfig()),
+		).WithChildren(gcpcreds.FieldSpecs()...).ChildDefaultAndTypesFromStruct(output.NewGCPCloudStorageConfig()),
 	})
 }
 
@@ -133,6 +136,7 @@ type gcpCloudStorageOutput struct {
 	path            *field.Expression
 	contentType     *field.Expression
 	contentEncoding *field.Expression
+	compress        codec.CompressionWriterConstructor
 
 	client  *storage.Client
 	connMut sync.RWMutex
@@ -166,6 +170,9 @@ func newGCPCloudStorageOutput(
 	if g.contentEncoding, err = bEnv.NewField(conf.ContentEncoding); err != nil {
 		return nil, fmt.Errorf("failed to parse content encoding expression: %v", err)
 	}
+	if g.compress, err = codec.GetCompressionWriter(conf.Compression); err != nil {
+		return nil, err
+	}
 
 	return g, nil
 }
@@ -176,8 +183,11 @@ func (g *gcpCloudStorageOutput) ConnectWithContext(ctx context.Context) error {
 	g.connMut.Lock()
 	defer g.connMut.Unlock()
 
-	var err error
-	g.client, err = storage.NewClient(context.Background())
+	opts, err := g.conf.ClientOptions(context.Background())
+	if err != nil {
+		return err
+	}
+	g.client, err = storage.NewClient(context.Background(), opts...)
 	if err != nil {
 		return err
 	}
@@ -204,8 +214,12 @@ func (g *gcpCloudStorageOutput) WriteWithContext(ctx context.Context, msg types.
 			return nil
 		})
 
+		body, err := codec.CompressBytes(g.compress, p.Get())
+		if err != nil {
+			return fmt.Errorf("failed to compress object: %w", err)
+		}
+
 		outputPath := g.path.String(i, msg)
-		var err error
 		if g.conf.CollisionMode != output.GCPCloudStorageOverwriteCollisionMode {
 			_, err = client.Bucket(g.conf.Bucket).Object(outputPath).Attrs(ctx)
 		}
@@ -242,7 +256,7 @@ func (g *gcpCloudStorageOutput) WriteWithContext(ctx context.Context, msg types.
 		w.ContentType = g.contentType.String(i, msg)
 		w.ContentEncoding = g.contentEncoding.String(i, msg)
 		w.Metadata = metadata
-		if _, err = w.Write(p.Get()); err != nil {
+		if _, err = w.Write(body); err != nil {
 			return err
 		}
 
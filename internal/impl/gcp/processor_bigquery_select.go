@@ -7,6 +7,7 @@ import (
 
 	"cloud.google.com/go/bigquery"
 	"github.com/Jeffail/benthos/v3/lib/processor"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
 	"github.com/Jeffail/benthos/v3/public/service"
 	"google.golang.org/api/iterator"
@@ -14,7 +15,8 @@ import (
 )
 
 type bigQuerySelectProcessorConfig struct {
-	project string
+	project     string
+	credentials gcpcreds.Config
 
 	queryParts  *bqQueryParts
 	jobLabels   map[string]string
@@ -29,6 +31,10 @@ func bigQuerySelectProcessorConfigFromParsed(inConf *service.ParsedConfig) (conf
 		return
 	}
 
+	if conf.credentials, err = credentialsFromParsed(inConf); err != nil {
+		return
+	}
+
 	if inConf.Contains("args_mapping") {
 		if conf.argsMapping, err = inConf.FieldBloblang("args_mapping"); err != nil {
 			return
@@ -71,7 +77,7 @@ func bigQuerySelectProcessorConfigFromParsed(inConf *service.ParsedConfig) (conf
 }
 
 func newBigQuerySelectProcessorConfig() *service.ConfigSpec {
-	return service.NewConfigSpec().
+	spec := service.NewConfigSpec().
 		Version("3.64.0").
 		Categories(
 			string(processor.CategoryIntegration),
@@ -121,6 +127,11 @@ pipeline:
           root.count = this.get("0.total_count")
 `,
 		)
+
+	for _, f := range credentialsFields() {
+		spec = spec.Field(f)
+	}
+	return spec
 }
 
 type bigQueryProcessorOptions struct {
@@ -147,7 +158,14 @@ func newBigQuerySelectProcessor(inConf *service.ParsedConfig, options *bigQueryP
 
 	closeCtx, closeF := context.WithCancel(context.Background())
 
-	wrapped, err := bigquery.NewClient(closeCtx, conf.project, options.clientOptions...)
+	clientOptions, err := conf.credentials.ClientOptions(closeCtx)
+	if err != nil {
+		closeF()
+		return nil, err
+	}
+	clientOptions = append(clientOptions, options.clientOptions...)
+
+	wrapped, err := bigquery.NewClient(closeCtx, conf.project, clientOptions...)
 	if err != nil {
 		closeF()
 		return nil, fmt.Errorf("failed to create bigquery client: %w", err)
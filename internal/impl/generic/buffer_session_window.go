@@ -0,0 +1,382 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	watermarkProcessingTime = "processing_time"
+	watermarkEventTime      = "event_time"
+)
+
+func sessionWindowBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Windowing").
+		Summary("Groups a stream of messages into session windows, where a window remains open for as long as messages continue to arrive within a gap of each other.").
+		Description(`
+A session window is a grouping of messages where each message has a timestamp within a specified `+"[`gap`](#gap)"+` of the previous message. Unlike tumbling and sliding windows the size of a session window is not fixed, and therefore this buffer is useful for grouping bursts of activity such as user sessions, where the period of activity isn't known in advance.
+
+Messages are allocated to a window either by the processing time (the time at which they're ingested) or by the event time, and this is controlled via the `+"[`timestamp_mapping` field](#timestamp_mapping)"+`. When a message arrives that fits between two existing windows the windows are merged.
+
+A window is flushed once the watermark passes the timestamp of its latest message plus the gap. If an `+"[`allowed_lateness`](#allowed_lateness)"+` is specified then the window will not be flushed until that length of time after. Messages with a timestamp older than the watermark minus the gap and allowed lateness are dropped, as the windows they would belong to have already been flushed.
+
+## Watermarks
+
+The watermark is the measure of time that determines when windows are complete, and is controlled via the `+"[`watermark` field](#watermark)"+`. With `+"`processing_time`"+` (default) the system clock is used, and therefore windows are flushed once no messages have arrived for the length of the gap. With `+"`event_time`"+` the watermark is instead the latest timestamp produced by the timestamp mapping of all messages so far, meaning windows are only flushed once messages with later timestamps arrive, regardless of the system clock.
+
+## Grouping Keys
+
+By specifying a `+"[`group_key`](#group_key)"+` windows are tracked separately for each unique key, such that a session of one key does not keep the session of another open. Each message of a flushed window has the metadata field `+"`window_key`"+` containing the key of its window.
+
+## Metadata
+
+When a window is flushed its messages have the metadata fields `+"`window_start_timestamp` and `window_end_timestamp`"+` added to them containing the timestamps of the earliest and latest messages of the window as RFC3339 strings.
+
+## Delivery Guarantees
+
+This buffer honours the transaction model within Benthos in order to ensure that messages are not acknowledged until they are either intentionally dropped or successfully delivered to outputs. However, since messages that arrive too late for their window are intentionally dropped there are circumstances where not all messages entering the system will be delivered.
+
+During graceful termination any windows that have not yet been flushed will be nacked such that they are re-consumed the next time the service starts.
+`).
+		Field(service.NewBloblangField("timestamp_mapping").
+			Description(`
+A [Bloblang mapping](/docs/guides/bloblang/about) applied to each message during ingestion that provides the timestamp to use for allocating it a window. By default the function `+"`now()`"+` is used in order to generate a fresh timestamp at the time of ingestion (the processing time), whereas this mapping can instead extract a timestamp from the message itself (the event time).
+
+The timestamp value assigned to `+"`root`"+` must either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format. If the mapping fails or provides an invalid result the message will be dropped (with logging to describe the problem).
+`).
+			Default("root = now()").
+			Example("root = this.created_at").Example(`root = meta("kafka_timestamp_unix").number()`)).
+		Field(service.NewStringField("gap").
+			Description("A duration string describing the maximum period between the timestamps of messages within the same window.").
+			Example("30s").Example("10m")).
+		Field(service.NewStringField("allowed_lateness").
+			Description("An optional duration string describing the length of time to wait after a window is complete before flushing it, allowing late arrivals to be included.").
+			Default("").
+			Example("10s").Example("1m")).
+		Field(service.NewStringAnnotatedEnumField("watermark", map[string]string{
+			watermarkProcessingTime: "The watermark follows the system clock.",
+			watermarkEventTime:      "The watermark is the latest timestamp produced by the timestamp mapping.",
+		}).
+			Description("The measure of time used in order to determine when windows are complete.").
+			Default(watermarkProcessingTime)).
+		Field(service.NewInterpolatedStringField("group_key").
+			Description("An optional key to evaluate for each message, where windows are tracked separately for each unique key.").
+			Default("").
+			Example(`${! json("user_id") }`)).
+		Example("Summarising User Sessions", `Given a stream of user interactions of the form:
+
+`+"```json"+`
+{
+  "user_id": "bd2b4a97",
+  "created_at": "2021-08-07T09:49:35Z",
+  "page": "/checkout"
+}
+`+"```"+`
+
+We can use a session window buffer in order to create a message summarising each session of user activity, where a session ends once a user has been inactive for ten minutes:`,
+			`
+buffer:
+  session_window:
+    timestamp_mapping: root = this.created_at
+    gap: 10m
+    group_key: '${! json("user_id") }'
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = if batch_index() == 0 {
+          {
+            "user_id": meta("window_key"),
+            "started_at": meta("window_start_timestamp"),
+            "ended_at": meta("window_end_timestamp"),
+            "pages": json("page").from_all(),
+          }
+        } else { deleted() }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"session_window", sessionWindowBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			gap, err := getDuration(conf, true, "gap")
+			if err != nil {
+				return nil, err
+			}
+			if gap <= 0 {
+				return nil, fmt.Errorf("invalid gap '%v' must be greater than zero", gap)
+			}
+			allowedLateness, err := getDuration(conf, false, "allowed_lateness")
+			if err != nil {
+				return nil, err
+			}
+			watermark, err := conf.FieldString("watermark")
+			if err != nil {
+				return nil, err
+			}
+			tsMapping, err := conf.FieldBloblang("timestamp_mapping")
+			if err != nil {
+				return nil, err
+			}
+			groupKey, err := getGroupKey(conf)
+			if err != nil {
+				return nil, err
+			}
+			return newSessionWindowBuffer(tsMapping, groupKey, func() time.Time {
+				return time.Now().UTC()
+			}, gap, allowedLateness, watermark == watermarkEventTime, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type session struct {
+	start, end time.Time
+	pending    []*tsMessage
+}
+
+type sessionWindowBuffer struct {
+	logger *service.Logger
+
+	tsMapping          *bloblang.Executor
+	groupKey           *service.InterpolatedString
+	clock              utcNowProvider
+	gap, lateness      time.Duration
+	eventTimeWatermark bool
+
+	latestTS  time.Time
+	sessions  map[string][]*session
+	ready     []*windowBatch
+	writeChan chan struct{}
+	mut       sync.Mutex
+
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+}
+
+func newSessionWindowBuffer(
+	tsMapping *bloblang.Executor,
+	groupKey *service.InterpolatedString,
+	clock utcNowProvider,
+	gap, allowedLateness time.Duration,
+	eventTimeWatermark bool,
+	logger *service.Logger,
+) (*sessionWindowBuffer, error) {
+	return &sessionWindowBuffer{
+		logger:             logger,
+		tsMapping:          tsMapping,
+		groupKey:           groupKey,
+		clock:              clock,
+		gap:                gap,
+		lateness:           allowedLateness,
+		eventTimeWatermark: eventTimeWatermark,
+		sessions:           map[string][]*session{},
+		writeChan:          make(chan struct{}, 1),
+		endOfInputChan:     make(chan struct{}),
+	}, nil
+}
+
+// watermarkLocked returns the current watermark, must be called whilst holding
+// the lock.
+func (w *sessionWindowBuffer) watermarkLocked() time.Time {
+	if w.eventTimeWatermark {
+		return w.latestTS
+	}
+	return w.clock()
+}
+
+// addLocked adds a message to the sessions of its key, merging any sessions
+// that it bridges, must be called whilst holding the lock.
+func (w *sessionWindowBuffer) addLocked(m *tsMessage) {
+	existing := w.sessions[m.key]
+
+	merged := &session{start: m.ts, end: m.ts, pending: []*tsMessage{m}}
+	remaining := make([]*session, 0, len(existing)+1)
+	for _, s := range existing {
+		if m.ts.Before(s.start.Add(-w.gap)) || m.ts.After(s.end.Add(w.gap)) {
+			remaining = append(remaining, s)
+			continue
+		}
+		if s.start.Before(merged.start) {
+			merged.start = s.start
+		}
+		if s.end.After(merged.end) {
+			merged.end = s.end
+		}
+		merged.pending = append(merged.pending, s.pending...)
+	}
+	remaining = append(remaining, merged)
+	w.sessions[m.key] = remaining
+}
+
+func (w *sessionWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	messageAdded := false
+	aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+
+	for i, msg := range msgBatch {
+		ts, err := getWindowTimestamp(w.logger, w.tsMapping, i, msgBatch)
+		if err != nil {
+			return err
+		}
+
+		// Don't add messages that are too late to belong to a session that
+		// hasn't been flushed.
+		if ts.Add(w.gap + w.lateness).Before(w.watermarkLocked()) {
+			continue
+		}
+		if ts.After(w.latestTS) {
+			w.latestTS = ts
+		}
+
+		var key string
+		if w.groupKey != nil {
+			key = msgBatch.InterpolatedString(i, w.groupKey)
+		}
+
+		messageAdded = true
+		w.addLocked(&tsMessage{
+			ts: ts, key: key, m: msg, ackFn: service.AckFunc(aggregatedAck.Derive()),
+		})
+	}
+
+	if !messageAdded {
+		// If none of the messages have fit into a window we reject them by
+		// acknowledging the batch.
+		_ = aFn(ctx, nil)
+	}
+
+	select {
+	case w.writeChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// flushLocked moves all complete sessions into the ready queue, and returns
+// the period to wait until the next session is complete, or zero if there are
+// no sessions. Must be called whilst holding the lock.
+func (w *sessionWindowBuffer) flushLocked() (waitFor time.Duration) {
+	watermark := w.watermarkLocked()
+
+	type keyedSession struct {
+		key string
+		s   *session
+	}
+
+	var complete []keyedSession
+	for key, sessions := range w.sessions {
+		remaining := make([]*session, 0, len(sessions))
+		for _, s := range sessions {
+			closesAt := s.end.Add(w.gap + w.lateness)
+			if !closesAt.After(watermark) {
+				complete = append(complete, keyedSession{key: key, s: s})
+				continue
+			}
+			if until := closesAt.Sub(watermark); waitFor == 0 || until < waitFor {
+				waitFor = until
+			}
+			remaining = append(remaining, s)
+		}
+		if len(remaining) == 0 {
+			delete(w.sessions, key)
+		} else {
+			w.sessions[key] = remaining
+		}
+	}
+
+	// Flush the sessions that ended first before the rest.
+	sort.Slice(complete, func(i, j int) bool {
+		return complete[i].s.end.Before(complete[j].s.end)
+	})
+
+	for _, c := range complete {
+		s := c.s
+		sort.SliceStable(s.pending, func(i, j int) bool {
+			return s.pending[i].ts.Before(s.pending[j].ts)
+		})
+
+		b := &windowBatch{}
+		for _, pending := range s.pending {
+			tmpMsg := pending.m.Copy()
+			tmpMsg.MetaSet("window_start_timestamp", s.start.Format(time.RFC3339Nano))
+			tmpMsg.MetaSet("window_end_timestamp", s.end.Format(time.RFC3339Nano))
+			if w.groupKey != nil {
+				tmpMsg.MetaSet("window_key", c.key)
+			}
+			b.batch = append(b.batch, tmpMsg)
+			b.acks = append(b.acks, pending.ackFn)
+		}
+		w.ready = append(w.ready, b)
+	}
+	return
+}
+
+func (w *sessionWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		w.mut.Lock()
+		waitFor := w.flushLocked()
+		if len(w.ready) > 0 {
+			next := w.ready[0]
+			w.ready[0] = nil
+			w.ready = w.ready[1:]
+			w.mut.Unlock()
+			return next.batch, next.ackFn, nil
+		}
+		w.mut.Unlock()
+
+		// With an event time watermark sessions are only completed by writes.
+		var timerChan <-chan time.Time
+		if waitFor > 0 && !w.eventTimeWatermark {
+			timerChan = time.After(waitFor)
+		}
+
+		select {
+		case <-timerChan:
+		case <-w.writeChan:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-w.endOfInputChan:
+			// Nack all pending messages so that we re-consume them on the next
+			// start up.
+			w.mut.Lock()
+			for _, sessions := range w.sessions {
+				for _, s := range sessions {
+					for _, pending := range s.pending {
+						_ = pending.ackFn(ctx, errWindowClosed)
+					}
+				}
+			}
+			w.sessions = map[string][]*session{}
+			w.mut.Unlock()
+			return nil, nil, service.ErrEndOfBuffer
+		}
+	}
+}
+
+func (w *sessionWindowBuffer) EndOfInput() {
+	w.closeEndOfInputOnce.Do(func() {
+		close(w.endOfInputChan)
+	})
+}
+
+func (w *sessionWindowBuffer) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionWindowEventTime(t *testing.T) {
+	mapping, err := bloblang.Parse(`root = this.ts`)
+	require.NoError(t, err)
+
+	groupKey, err := service.NewInterpolatedString(`${! json("user") }`)
+	require.NoError(t, err)
+
+	w, err := newSessionWindowBuffer(mapping, groupKey, func() time.Time {
+		t.Error("clock should not be used with an event time watermark")
+		return time.Time{}
+	}, time.Second, 0, true, nil)
+	require.NoError(t, err)
+
+	var acked []string
+	ackFn := func(id string) service.AckFunc {
+		return func(context.Context, error) error {
+			acked = append(acked, id)
+			return nil
+		}
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","ts":1}`)),
+		service.NewMessage([]byte(`{"user":"b","ts":1.5}`)),
+		service.NewMessage([]byte(`{"user":"a","ts":3}`)),
+	}, ackFn("first")))
+
+	// Bridges the two sessions of user a.
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","ts":2}`)),
+	}, ackFn("second")))
+	require.Len(t, w.sessions["a"], 1)
+
+	// Moves the watermark past the end of the session of user b only.
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"c","ts":3.5}`)),
+	}, ackFn("third")))
+
+	resBatch, aFn, err := w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	msgBytes, err := resBatch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"user":"b","ts":1.5}`, string(msgBytes))
+
+	v, _ := resBatch[0].MetaGet("window_key")
+	assert.Equal(t, "b", v)
+	v, _ = resBatch[0].MetaGet("window_start_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:01.5Z", v)
+
+	require.NoError(t, aFn(context.Background(), nil))
+	assert.Empty(t, acked)
+
+	// Too late for any session and is therefore dropped.
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"b","ts":1}`)),
+	}, ackFn("late")))
+	assert.Equal(t, []string{"late"}, acked)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"c","ts":10}`)),
+	}, ackFn("fourth")))
+
+	resBatch, aFn, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 3)
+
+	var ids []string
+	for _, m := range resBatch {
+		msgBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		ids = append(ids, string(msgBytes))
+	}
+	assert.Equal(t, []string{
+		`{"user":"a","ts":1}`,
+		`{"user":"a","ts":2}`,
+		`{"user":"a","ts":3}`,
+	}, ids)
+
+	v, _ = resBatch[0].MetaGet("window_end_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:03Z", v)
+
+	require.NoError(t, aFn(context.Background(), nil))
+	assert.Equal(t, []string{"late", "second", "first"}, acked)
+
+	resBatch, _, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+	v, _ = resBatch[0].MetaGet("window_key")
+	assert.Equal(t, "c", v)
+
+	w.EndOfInput()
+	_, _, err = w.ReadBatch(context.Background())
+	assert.Equal(t, service.ErrEndOfBuffer, err)
+	assert.Equal(t, []string{"late", "second", "first", "fourth"}, acked)
+}
+
+func TestSessionWindowProcessingTime(t *testing.T) {
+	mapping, err := bloblang.Parse(`root = now()`)
+	require.NoError(t, err)
+
+	w, err := newSessionWindowBuffer(mapping, nil, func() time.Time {
+		return time.Now().UTC()
+	}, time.Millisecond*50, 0, false, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`foo`)),
+		service.NewMessage([]byte(`bar`)),
+	}, noopAck))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+
+	resBatch, _, err := w.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, resBatch, 2)
+
+	_, exists := resBatch[0].MetaGet("window_key")
+	assert.False(t, exists)
+}
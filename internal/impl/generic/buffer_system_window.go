@@ -26,7 +26,11 @@ In tumbling mode (default) the beginning of a window immediately follows the end
 
 A window is flushed only once the system clock surpasses its scheduled end. If an `+"[`allowed_lateness`](#allowed_lateness)"+` is specified then the window will not be flushed until the scheduled end plus that length of time.
 
-When a message is added to a window it has a metadata field `+"`window_end_timestamp`"+` added to it containing the timestamp of the end of the window as an RFC3339 string.
+When a window is flushed its messages have the metadata fields `+"`window_start_timestamp` and `window_end_timestamp`"+` added to them containing the timestamps of the beginning and end of the window as RFC3339 strings.
+
+## Grouping Keys
+
+By specifying a `+"[`group_key`](#group_key)"+` the messages of each window are flushed as separate batches for each unique key, where each message has the metadata field `+"`window_key`"+` containing the key of its batch. This is useful for aggregating windows per entity without the need for a `+"`group_by_value`"+` processor.
 
 ## Sliding Windows
 
@@ -69,6 +73,11 @@ The timestamp value assigned to `+"`root`"+` must either be a numerical unix tim
 			Description("An optional duration string describing the length of time to wait after a window has ended before flushing it, allowing late arrivals to be included. Since this windowing buffer uses the system clock an allowed lateness can improve the matching of messages when using event time.").
 			Default("").
 			Example("10s").Example("1m")).
+		Field(service.NewInterpolatedStringField("group_key").
+			Description("An optional key to evaluate for each message, where the messages of a window are flushed as a separate batch for each unique key.").
+			Default("").
+			Example(`${! json("traffic_light") }`).
+			Version("3.64.0")).
 		Example("Counting Passengers at Traffic", `Given a stream of messages relating to cars passing through various traffic lights of the form:
 
 `+"```json"+`
@@ -171,9 +180,16 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return newSystemWindowBuffer(tsMapping, func() time.Time {
+			w, err := newSystemWindowBuffer(tsMapping, func() time.Time {
 				return time.Now().UTC()
 			}, size, slide, offset, allowedLateness, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			if w.groupKey, err = getGroupKey(conf); err != nil {
+				return nil, err
+			}
+			return w, nil
 		})
 
 	if err != nil {
@@ -181,26 +197,72 @@ func init() {
 	}
 }
 
+func getGroupKey(conf *service.ParsedConfig) (*service.InterpolatedString, error) {
+	keyStr, err := conf.FieldString("group_key")
+	if err != nil || keyStr == "" {
+		return nil, err
+	}
+	return conf.FieldInterpolatedString("group_key")
+}
+
 //------------------------------------------------------------------------------
 
 type tsMessage struct {
 	ts    time.Time
+	key   string
 	m     *service.Message
 	ackFn service.AckFunc
 }
 
+// windowBatch is a batch of messages of a flushed window that share a group
+// key.
+type windowBatch struct {
+	batch service.MessageBatch
+	acks  []service.AckFunc
+}
+
+func (b *windowBatch) ackFn(ctx context.Context, err error) error {
+	for _, aFn := range b.acks {
+		_ = aFn(ctx, err)
+	}
+	return nil
+}
+
+// windowGrouper collects the messages of a flushed window into batches by
+// their group key, preserving the order in which keys were first seen.
+type windowGrouper struct {
+	batches []*windowBatch
+	indexes map[string]int
+}
+
+func (g *windowGrouper) add(key string, m *service.Message, ackFn service.AckFunc) {
+	if g.indexes == nil {
+		g.indexes = map[string]int{}
+	}
+	i, exists := g.indexes[key]
+	if !exists {
+		i = len(g.batches)
+		g.indexes[key] = i
+		g.batches = append(g.batches, &windowBatch{})
+	}
+	g.batches[i].batch = append(g.batches[i].batch, m)
+	g.batches[i].acks = append(g.batches[i].acks, ackFn)
+}
+
 type utcNowProvider func() time.Time
 
 type systemWindowBuffer struct {
 	logger *service.Logger
 
 	tsMapping                            *bloblang.Executor
+	groupKey                             *service.InterpolatedString
 	clock                                utcNowProvider
 	size, slide, offset, allowedLateness time.Duration
 
 	latestFlushedWindowEnd time.Time
 	oldestTS               time.Time
 	pending                []*tsMessage
+	ready                  []*windowBatch
 	pendingMut             sync.Mutex
 
 	closedTimerChan <-chan time.Time
@@ -268,9 +330,15 @@ func (w *systemWindowBuffer) nextSystemWindow() (prevStart, prevEnd, start, end
 }
 
 func (w *systemWindowBuffer) getTimestamp(i int, batch service.MessageBatch) (ts time.Time, err error) {
+	return getWindowTimestamp(w.logger, w.tsMapping, i, batch)
+}
+
+// getWindowTimestamp executes a timestamp mapping on a message of a batch and
+// parses the result as a timestamp.
+func getWindowTimestamp(logger *service.Logger, tsMapping *bloblang.Executor, i int, batch service.MessageBatch) (ts time.Time, err error) {
 	var tsValueMsg *service.Message
-	if tsValueMsg, err = batch.BloblangQuery(i, w.tsMapping); err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: %v", err)
+	if tsValueMsg, err = batch.BloblangQuery(i, tsMapping); err != nil {
+		logger.Errorf("Timestamp mapping failed for message: %v", err)
 		err = fmt.Errorf("timestamp mapping failed: %w", err)
 		return
 	}
@@ -283,13 +351,13 @@ func (w *systemWindowBuffer) getTimestamp(i int, batch service.MessageBatch) (ts
 		}
 	}
 	if err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: unable to parse result as structured value: %v", err)
+		logger.Errorf("Timestamp mapping failed for message: unable to parse result as structured value: %v", err)
 		err = fmt.Errorf("unable to parse result of timestamp mapping as structured value: %w", err)
 		return
 	}
 
 	if ts, err = query.IGetTimestamp(tsValue); err != nil {
-		w.logger.Errorf("Timestamp mapping failed for message: %v", err)
+		logger.Errorf("Timestamp mapping failed for message: %v", err)
 		err = fmt.Errorf("unable to parse result of timestamp mapping as timestamp: %w", err)
 	}
 	return
@@ -335,9 +403,14 @@ func (w *systemWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.Me
 			continue
 		}
 
+		var key string
+		if w.groupKey != nil {
+			key = msgBatch.InterpolatedString(i, w.groupKey)
+		}
+
 		messageAdded = true
 		w.pending = append(w.pending, &tsMessage{
-			ts: ts, m: msg, ackFn: service.AckFunc(aggregatedAck.Derive()),
+			ts: ts, key: key, m: msg, ackFn: service.AckFunc(aggregatedAck.Derive()),
 		})
 		if ts.Before(w.oldestTS) {
 			w.oldestTS = ts
@@ -362,8 +435,7 @@ func (w *systemWindowBuffer) flushWindow(ctx context.Context, start, end time.Ti
 		nextStart = start.Add(w.slide)
 	}
 
-	var flushBatch service.MessageBatch
-	var flushAcks []service.AckFunc
+	var grouper windowGrouper
 
 	newPending := make([]*tsMessage, 0, len(w.pending))
 	newOldest := w.clock()
@@ -373,9 +445,12 @@ func (w *systemWindowBuffer) flushWindow(ctx context.Context, start, end time.Ti
 
 		if flush {
 			tmpMsg := pending.m.Copy()
+			tmpMsg.MetaSet("window_start_timestamp", start.Add(-1).Format(time.RFC3339Nano))
 			tmpMsg.MetaSet("window_end_timestamp", end.Format(time.RFC3339Nano))
-			flushBatch = append(flushBatch, tmpMsg)
-			flushAcks = append(flushAcks, pending.ackFn)
+			if w.groupKey != nil {
+				tmpMsg.MetaSet("window_key", pending.key)
+			}
+			grouper.add(pending.key, tmpMsg, pending.ackFn)
 		}
 		if preserve {
 			if pending.ts.Before(newOldest) {
@@ -389,20 +464,37 @@ func (w *systemWindowBuffer) flushWindow(ctx context.Context, start, end time.Ti
 	}
 
 	w.pending = newPending
+	w.ready = append(w.ready, grouper.batches...)
 	w.latestFlushedWindowEnd = end
 	w.oldestTS = newOldest
 
-	return flushBatch, func(ctx context.Context, err error) error {
-		for _, aFn := range flushAcks {
-			_ = aFn(ctx, err)
-		}
-		return nil
-	}, nil
+	msgBatch, aFn := w.popReadyLocked()
+	return msgBatch, aFn, nil
+}
+
+// popReadyLocked returns the next batch of a flushed window that has not yet
+// been read, must be called whilst holding the pending lock.
+func (w *systemWindowBuffer) popReadyLocked() (service.MessageBatch, service.AckFunc) {
+	if len(w.ready) == 0 {
+		return nil, nil
+	}
+	next := w.ready[0]
+	w.ready[0] = nil
+	w.ready = w.ready[1:]
+	return next.batch, next.ackFn
 }
 
 var errWindowClosed = errors.New("message rejected as window did not complete")
 
 func (w *systemWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	// Batches of other keys from the last flushed window are read first.
+	w.pendingMut.Lock()
+	msgBatch, aFn := w.popReadyLocked()
+	w.pendingMut.Unlock()
+	if len(msgBatch) > 0 {
+		return msgBatch, aFn, nil
+	}
+
 	prevStart, prevEnd, nextStart, nextEnd := w.nextSystemWindow()
 
 	// We haven't been read since the previous window ended, so create that one
@@ -436,7 +528,10 @@ func (w *systemWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatc
 			for _, pending := range w.pending {
 				_ = pending.ackFn(ctx, errWindowClosed)
 			}
-			w.pending = nil
+			for _, ready := range w.ready {
+				_ = ready.ackFn(ctx, errWindowClosed)
+			}
+			w.pending, w.ready = nil, nil
 			w.pendingMut.Unlock()
 			return nil, nil, service.ErrEndOfBuffer
 		}
@@ -589,3 +589,50 @@ func TestSystemWindowParallelReadAndWrites(t *testing.T) {
 	close(startChan)
 	wg.Wait()
 }
+
+func TestSystemWindowGroupKey(t *testing.T) {
+	mapping, err := bloblang.Parse(`root = this.ts`)
+	require.NoError(t, err)
+
+	currentTS := time.Unix(10, 1).UTC()
+	w, err := newSystemWindowBuffer(mapping, func() time.Time {
+		return currentTS
+	}, time.Second, 0, 0, 0, nil)
+	require.NoError(t, err)
+
+	w.groupKey, err = service.NewInterpolatedString(`${! json("key") }`)
+	require.NoError(t, err)
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"1","key":"foo","ts":9.1}`)),
+		service.NewMessage([]byte(`{"id":"2","key":"bar","ts":9.2}`)),
+		service.NewMessage([]byte(`{"id":"3","key":"foo","ts":9.3}`)),
+		service.NewMessage([]byte(`{"id":"4","key":"foo","ts":10.5}`)),
+	}, noopAck)
+	require.NoError(t, err)
+
+	expectBatch := func(key string, ids ...string) {
+		t.Helper()
+
+		resBatch, _, err := w.ReadBatch(context.Background())
+		require.NoError(t, err)
+		require.Len(t, resBatch, len(ids))
+
+		for i, m := range resBatch {
+			msgBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			assert.Contains(t, string(msgBytes), fmt.Sprintf(`"id":"%v"`, ids[i]))
+
+			v, _ := m.MetaGet("window_key")
+			assert.Equal(t, key, v)
+			v, _ = m.MetaGet("window_start_timestamp")
+			assert.Equal(t, "1970-01-01T00:00:09Z", v)
+			v, _ = m.MetaGet("window_end_timestamp")
+			assert.Equal(t, "1970-01-01T00:00:10Z", v)
+		}
+	}
+
+	expectBatch("foo", "1", "3")
+	expectBatch("bar", "2")
+	assert.Len(t, w.pending, 1)
+}
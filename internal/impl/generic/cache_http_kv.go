@@ -0,0 +1,463 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+)
+
+func httpKVCacheOperationField(name, verb string) *service.ConfigField {
+	return service.NewObjectField(name,
+		service.NewStringField("verb").
+			Description("The HTTP verb to use for this operation.").
+			Default(verb),
+		service.NewStringField("url").
+			Description("An optional URL template that overrides the top level `url` for this operation.").
+			Default("").
+			Advanced(),
+		service.NewStringMapField("headers").
+			Description("A map of headers to add to requests of this operation, in addition to the top level `headers`.").
+			Default(map[string]string{}).
+			Advanced(),
+	).Description(fmt.Sprintf("Describes the request made for %v operations.", name))
+}
+
+func httpKVCacheConfig() *service.ConfigSpec {
+	defaultBackOff := backoff.NewExponentialBackOff()
+	defaultBackOff.InitialInterval = time.Second
+	defaultBackOff.MaxInterval = 5 * time.Second
+	defaultBackOff.MaxElapsedTime = 30 * time.Second
+
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Summary("Uses a key/value store with an HTTP REST API as a cache, where each cache operation is mapped to an HTTP request.").
+		Description(`
+Each operation is sent as an HTTP request to a URL derived from a template, where the placeholder `+"`{key}`"+` is replaced with the path escaped key of the operation. The URL and verb of each operation can be configured individually, allowing most REST key/value stores to be used without writing a plugin.
+
+A get operation returns the body of a successful response as the value of the key, and set operations send the value as the request body. Responses with a status code listed in `+"`not_found_codes`"+` result in a key not found error, and responses with a status code listed in `+"`conflict_codes`"+` result in a key already exists error for add operations.
+
+If the `+"`verb`"+` of the add operation is empty then adds are performed as a get operation followed by a set operation when the key is not found, which is not atomic.
+
+### TTLs
+
+When either `+"`ttl_header`"+` or `+"`ttl_query_param`"+` is set the TTL of set and add operations, in seconds, is sent within the header or query parameter of that name respectively. This cache type supports setting the TTL individually per key by using the dynamic `+"`ttl`"+` field of a cache processor or output in order to override the general TTL configured at the cache resource level.
+
+### Retries
+
+Requests that fail due to a connection error, or that receive a response with a status code of 429 or 5XX, are retried according to the `+"`max_retries`"+` and `+"`backoff`"+` fields.`).
+		Field(service.NewStringField("url").
+			Description("A URL template to send requests to, where `{key}` is replaced with the key of an operation.").
+			Example("http://localhost:8080/keys/{key}")).
+		Field(httpKVCacheOperationField("get", "GET")).
+		Field(httpKVCacheOperationField("set", "PUT")).
+		Field(httpKVCacheOperationField("add", "")).
+		Field(httpKVCacheOperationField("delete", "DELETE")).
+		Field(service.NewStringMapField("headers").
+			Description("A map of headers to add to all requests.").
+			Default(map[string]string{}).
+			Example(map[string]string{
+				"Content-Type": "application/octet-stream",
+			})).
+		Field(service.NewObjectField("basic_auth",
+			service.NewBoolField("enabled").
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField("username").
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default(""),
+		).Description("Allows you to specify basic authentication.").Advanced()).
+		Field(service.NewStringField("bearer_token").
+			Description("An optional token to send within an `Authorization` header as a bearer token.").
+			Default("").
+			Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewStringField("ttl_header").
+			Description("An optional header to send the TTL of set and add operations within, in seconds.").
+			Example("X-TTL").
+			Default("")).
+		Field(service.NewStringField("ttl_query_param").
+			Description("An optional query parameter to send the TTL of set and add operations within, in seconds.").
+			Example("expiration_ttl").
+			Default("")).
+		Field(service.NewStringField("default_ttl").
+			Description("An optional default TTL to send with set and add operations that do not specify one.").
+			Example("60s").
+			Default("")).
+		Field(service.NewIntListField("not_found_codes").
+			Description("A list of response status codes that indicate a key does not exist.").
+			Default([]int{404}).
+			Advanced()).
+		Field(service.NewIntListField("conflict_codes").
+			Description("A list of response status codes that indicate a key already exists during add operations.").
+			Default([]int{409, 412}).
+			Advanced()).
+		Field(service.NewDurationField("timeout").
+			Description("A static timeout to apply to requests.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewIntField("max_retries").
+			Description("The maximum number of retry attempts to make before abandoning a request.").
+			Default(3).
+			Advanced()).
+		Field(service.NewBackOffField("backoff", false, defaultBackOff).Advanced()).
+		Example("Cloudflare Workers KV", `
+Use a Cloudflare Workers KV namespace as a cache, where keys expire after an hour:`,
+			`
+cache_resources:
+  - label: workers_kv
+    http_kv:
+      url: https://api.cloudflare.com/client/v4/accounts/${ACCOUNT_ID}/storage/kv/namespaces/${NAMESPACE_ID}/values/{key}
+      bearer_token: ${CLOUDFLARE_API_TOKEN}
+      ttl_query_param: expiration_ttl
+      default_ttl: 1h
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"http_kv", httpKVCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newHTTPKVCacheFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type httpKVOperation struct {
+	verb    string
+	url     string
+	headers map[string]string
+}
+
+type httpKVCache struct {
+	client *http.Client
+
+	get, set, add, del httpKVOperation
+
+	headers       map[string]string
+	username      string
+	password      string
+	useBasicAuth  bool
+	bearerToken   string
+	ttlHeader     string
+	ttlQueryParam string
+	defaultTTL    *time.Duration
+
+	notFoundCodes map[int]struct{}
+	conflictCodes map[int]struct{}
+
+	maxRetries int
+	boff       *backoff.ExponentialBackOff
+}
+
+func httpKVOperationFromConfig(conf *service.ParsedConfig, name, defaultURL string) (op httpKVOperation, err error) {
+	if op.verb, err = conf.FieldString(name, "verb"); err != nil {
+		return
+	}
+	if op.url, err = conf.FieldString(name, "url"); err != nil {
+		return
+	}
+	if op.url == "" {
+		op.url = defaultURL
+	}
+	if op.url == "" && op.verb != "" {
+		err = fmt.Errorf("a url must be specified for %v operations", name)
+		return
+	}
+	op.headers, err = conf.FieldStringMap(name, "headers")
+	return
+}
+
+func httpKVCodeSet(conf *service.ParsedConfig, name string) (map[int]struct{}, error) {
+	codes, err := conf.FieldIntList(name)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]struct{}, len(codes))
+	for _, c := range codes {
+		set[c] = struct{}{}
+	}
+	return set, nil
+}
+
+func newHTTPKVCacheFromConfig(conf *service.ParsedConfig) (*httpKVCache, error) {
+	c := &httpKVCache{}
+
+	defaultURL, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	if c.get, err = httpKVOperationFromConfig(conf, "get", defaultURL); err != nil {
+		return nil, err
+	}
+	if c.get.verb == "" {
+		return nil, errors.New("a verb must be specified for get operations")
+	}
+	if c.set, err = httpKVOperationFromConfig(conf, "set", defaultURL); err != nil {
+		return nil, err
+	}
+	if c.set.verb == "" {
+		return nil, errors.New("a verb must be specified for set operations")
+	}
+	if c.add, err = httpKVOperationFromConfig(conf, "add", defaultURL); err != nil {
+		return nil, err
+	}
+	if c.del, err = httpKVOperationFromConfig(conf, "delete", defaultURL); err != nil {
+		return nil, err
+	}
+	if c.del.verb == "" {
+		return nil, errors.New("a verb must be specified for delete operations")
+	}
+
+	if c.headers, err = conf.FieldStringMap("headers"); err != nil {
+		return nil, err
+	}
+	if c.useBasicAuth, err = conf.FieldBool("basic_auth", "enabled"); err != nil {
+		return nil, err
+	}
+	if c.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+		return nil, err
+	}
+	if c.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+		return nil, err
+	}
+	if c.bearerToken, err = conf.FieldString("bearer_token"); err != nil {
+		return nil, err
+	}
+	if c.ttlHeader, err = conf.FieldString("ttl_header"); err != nil {
+		return nil, err
+	}
+	if c.ttlQueryParam, err = conf.FieldString("ttl_query_param"); err != nil {
+		return nil, err
+	}
+
+	ttl, err := getDuration(conf, false, "default_ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		c.defaultTTL = &ttl
+	}
+
+	if c.notFoundCodes, err = httpKVCodeSet(conf, "not_found_codes"); err != nil {
+		return nil, err
+	}
+	if c.conflictCodes, err = httpKVCodeSet(conf, "conflict_codes"); err != nil {
+		return nil, err
+	}
+
+	if c.maxRetries, err = conf.FieldInt("max_retries"); err != nil {
+		return nil, err
+	}
+	if c.boff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	c.client = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+var errHTTPKVRetryable = errors.New("retryable request failure")
+
+func (h *httpKVCache) newRequest(ctx context.Context, op httpKVOperation, key string, body []byte, ttl *time.Duration) (*http.Request, error) {
+	if ttl == nil {
+		ttl = h.defaultTTL
+	}
+
+	rawURL := strings.ReplaceAll(op.url, "{key}", url.PathEscape(key))
+	if ttl != nil && h.ttlQueryParam != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		q.Set(h.ttlQueryParam, strconv.FormatInt(int64(ttl.Seconds()), 10))
+		u.RawQuery = q.Encode()
+		rawURL = u.String()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, op.verb, rawURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range op.headers {
+		req.Header.Set(k, v)
+	}
+	if ttl != nil && h.ttlHeader != "" {
+		req.Header.Set(h.ttlHeader, strconv.FormatInt(int64(ttl.Seconds()), 10))
+	}
+	if h.useBasicAuth {
+		req.SetBasicAuth(h.username, h.password)
+	}
+	if h.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+h.bearerToken)
+	}
+	return req, nil
+}
+
+// do performs a request for an operation with retries, and returns the status
+// code and body of the final response.
+func (h *httpKVCache) do(ctx context.Context, op httpKVOperation, key string, body []byte, ttl *time.Duration) (int, []byte, error) {
+	boff := *h.boff
+	boff.Reset()
+
+	for attempt := 0; ; attempt++ {
+		code, resBody, err := h.doOnce(ctx, op, key, body, ttl)
+		if err == nil || !errors.Is(err, errHTTPKVRetryable) || attempt >= h.maxRetries {
+			return code, resBody, err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return code, resBody, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return code, resBody, ctx.Err()
+		}
+	}
+}
+
+func (h *httpKVCache) doOnce(ctx context.Context, op httpKVOperation, key string, body []byte, ttl *time.Duration) (int, []byte, error) {
+	req, err := h.newRequest(ctx, op, key, body, ttl)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %v", errHTTPKVRetryable, err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, fmt.Errorf("%w: %v", errHTTPKVRetryable, err)
+	}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return res.StatusCode, resBody, fmt.Errorf("%w: %v", errHTTPKVRetryable, res.Status)
+	}
+	return res.StatusCode, resBody, nil
+}
+
+func (h *httpKVCache) unexpectedStatus(op httpKVOperation, key string, code int) error {
+	return fmt.Errorf("%v request for key '%v' returned unexpected status code: %v", op.verb, key, code)
+}
+
+func isHTTPSuccess(code int) bool {
+	return code >= 200 && code < 300
+}
+
+func (h *httpKVCache) Get(ctx context.Context, key string) ([]byte, error) {
+	code, body, err := h.do(ctx, h.get, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, notFound := h.notFoundCodes[code]; notFound {
+		return nil, service.ErrKeyNotFound
+	}
+	if !isHTTPSuccess(code) {
+		return nil, h.unexpectedStatus(h.get, key, code)
+	}
+	return body, nil
+}
+
+func (h *httpKVCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	code, _, err := h.do(ctx, h.set, key, value, ttl)
+	if err != nil {
+		return err
+	}
+	if !isHTTPSuccess(code) {
+		return h.unexpectedStatus(h.set, key, code)
+	}
+	return nil
+}
+
+func (h *httpKVCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if h.add.verb == "" {
+		_, err := h.Get(ctx, key)
+		if err == nil {
+			return service.ErrKeyAlreadyExists
+		}
+		if !errors.Is(err, service.ErrKeyNotFound) {
+			return err
+		}
+		return h.Set(ctx, key, value, ttl)
+	}
+
+	code, _, err := h.do(ctx, h.add, key, value, ttl)
+	if err != nil {
+		return err
+	}
+	if _, conflict := h.conflictCodes[code]; conflict {
+		return service.ErrKeyAlreadyExists
+	}
+	if !isHTTPSuccess(code) {
+		return h.unexpectedStatus(h.add, key, code)
+	}
+	return nil
+}
+
+func (h *httpKVCache) Delete(ctx context.Context, key string) error {
+	code, _, err := h.do(ctx, h.del, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, notFound := h.notFoundCodes[code]; notFound {
+		return nil
+	}
+	if !isHTTPSuccess(code) {
+		return h.unexpectedStatus(h.del, key, code)
+	}
+	return nil
+}
+
+func (h *httpKVCache) Close(ctx context.Context) error {
+	h.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,168 @@
+package generic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type httpKVTestServer struct {
+	mut      sync.Mutex
+	items    map[string]string
+	ttls     map[string]string
+	failures int
+}
+
+func (s *httpKVTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer meow" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/keys/")
+	switch r.Method {
+	case "GET":
+		v, exists := s.items[key]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(v))
+	case "PUT", "POST":
+		if _, exists := s.items[key]; exists && r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		s.items[key] = string(b)
+		s.ttls[key] = r.Header.Get("X-TTL")
+	case "DELETE":
+		delete(s.items, key)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHTTPKVCacheBasic(t *testing.T) {
+	srv := &httpKVTestServer{items: map[string]string{}, ttls: map[string]string{}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := httpKVCacheConfig().ParseYAML(`
+url: `+ts.URL+`/keys/{key}
+bearer_token: meow
+ttl_header: X-TTL
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+default_ttl: 1m
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newHTTPKVCacheFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("bar"), nil))
+	assert.Equal(t, "60", srv.ttls["foo"])
+
+	ttl := time.Second * 10
+	require.NoError(t, c.Set(ctx, "baz", []byte("buz"), &ttl))
+	assert.Equal(t, "10", srv.ttls["baz"])
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("nope"), nil))
+	require.NoError(t, c.Add(ctx, "new", []byte("yep"), nil))
+	assert.Equal(t, "yep", srv.items["new"])
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Close(ctx))
+}
+
+func TestHTTPKVCacheAddVerb(t *testing.T) {
+	srv := &httpKVTestServer{items: map[string]string{"foo": "bar"}, ttls: map[string]string{}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := httpKVCacheConfig().ParseYAML(`
+url: `+ts.URL+`/keys/{key}
+bearer_token: meow
+ttl_header: X-TTL
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+add:
+  verb: POST
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newHTTPKVCacheFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("nope"), nil))
+	require.NoError(t, c.Add(ctx, "baz", []byte("buz"), nil))
+	assert.Equal(t, "buz", srv.items["baz"])
+}
+
+func TestHTTPKVCacheRetries(t *testing.T) {
+	srv := &httpKVTestServer{items: map[string]string{"foo": "bar"}, ttls: map[string]string{}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := httpKVCacheConfig().ParseYAML(`
+url: `+ts.URL+`/keys/{key}
+bearer_token: meow
+ttl_header: X-TTL
+max_retries: 2
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newHTTPKVCacheFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	srv.failures = 2
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+
+	srv.failures = 3
+	_, err = c.Get(ctx, "foo")
+	require.Error(t, err)
+}
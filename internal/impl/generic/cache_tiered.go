@@ -0,0 +1,237 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func tieredCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Summary("Layers an ordered list of cache resources as tiers, performing read-through and write-through operations across them.").
+		Description(`
+Tiers are listed from the fastest (or closest) first to the slowest (or most authoritative) last, for example an in-memory cache in front of a remote Redis cache.
+
+When reading a key each tier is checked in order and the first value found is returned. If the key was found in a slower tier then all faster tiers before it are back-filled with the value, using the TTL override of each tier when specified.
+
+When writing a key with the set or delete commands the operation is performed across all tiers, starting from the slowest. The add command checks each tier except the last for an existing key, then adds the key to the last tier and, if successful, sets the key in all faster tiers.
+
+Each tier can specify a TTL override, which replaces any TTL provided with an operation for that tier only. This is useful for keeping items in a small local cache for a short period of time whilst retaining them in a remote cache for much longer.`).
+		Field(service.NewObjectListField("tiers",
+			service.NewStringField("resource").
+				Description("The name of a [cache resource](/docs/components/caches/about) to use as this tier."),
+			service.NewStringField("ttl").
+				Description("An optional TTL override for items written to this tier, which replaces any TTL specified by the caller.").
+				Example("60s").Example("1h").
+				Default(""),
+		).Description("An ordered list of cache tiers, from the fastest to the slowest.")).
+		Example("Hot Local Cache", `
+Using an in-memory cache with a short TTL in front of a Redis cache, where items are kept for much longer:`,
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: tiered_cache
+              operator: get
+              key: ${! json("user_id") }
+        result_map: root.user = this
+
+cache_resources:
+  - label: tiered_cache
+    tiered:
+      tiers:
+        - resource: hot
+          ttl: 60s
+        - resource: cold
+
+  - label: hot
+    memory:
+      ttl: 300
+
+  - label: cold
+    redis:
+      url: tcp://TODO:6379
+      expiration: 24h
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"tiered", tieredCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newTieredCacheFromConfig(conf, mgr.AccessCache, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type cacheTier struct {
+	resource string
+	ttl      *time.Duration
+}
+
+type tieredCache struct {
+	tiers  []cacheTier
+	access store.CacheAccessor
+	log    *service.Logger
+}
+
+func newTieredCacheFromConfig(conf *service.ParsedConfig, access store.CacheAccessor, log *service.Logger) (*tieredCache, error) {
+	tierConfs, err := conf.FieldObjectList("tiers")
+	if err != nil {
+		return nil, err
+	}
+
+	var tiers []cacheTier
+	for i, tConf := range tierConfs {
+		var tier cacheTier
+		if tier.resource, err = tConf.FieldString("resource"); err != nil {
+			return nil, err
+		}
+		if tier.resource == "" {
+			return nil, fmt.Errorf("tier %v: a cache resource must be specified", i)
+		}
+		ttl, err := getDuration(tConf, false, "ttl")
+		if err != nil {
+			return nil, fmt.Errorf("tier %v: %w", i, err)
+		}
+		if ttl > 0 {
+			tier.ttl = &ttl
+		}
+		tiers = append(tiers, tier)
+	}
+	return newTieredCache(tiers, access, log)
+}
+
+func newTieredCache(tiers []cacheTier, access store.CacheAccessor, log *service.Logger) (*tieredCache, error) {
+	if len(tiers) < 2 {
+		return nil, fmt.Errorf("expected at least two cache tiers, found %v", len(tiers))
+	}
+	return &tieredCache{
+		tiers:  tiers,
+		access: access,
+		log:    log,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *tieredCache) withTier(ctx context.Context, tier cacheTier, fn func(c service.Cache) error) error {
+	var err error
+	if cerr := t.access(ctx, tier.resource, func(c service.Cache) {
+		err = fn(c)
+	}); cerr != nil {
+		return fmt.Errorf("unable to access cache '%v': %w", tier.resource, cerr)
+	}
+	return err
+}
+
+func (t *tieredCache) tierTTL(tier cacheTier, ttl *time.Duration) *time.Duration {
+	if tier.ttl != nil {
+		return tier.ttl
+	}
+	return ttl
+}
+
+// backFill sets a key in all tiers faster than the tier index provided. Errors
+// are logged rather than returned as the value has already been obtained.
+func (t *tieredCache) backFill(ctx context.Context, upTo int, key string, value []byte) {
+	for _, tier := range t.tiers[:upTo] {
+		if err := t.withTier(ctx, tier, func(c service.Cache) error {
+			return c.Set(ctx, key, value, tier.ttl)
+		}); err != nil {
+			t.log.Errorf("Unable to back-fill key '%v' for cache '%v': %v\n", key, tier.resource, err)
+		}
+	}
+}
+
+func (t *tieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	for i, tier := range t.tiers {
+		var value []byte
+		err := t.withTier(ctx, tier, func(c service.Cache) (err error) {
+			value, err = c.Get(ctx, key)
+			return
+		})
+		if err != nil {
+			if errors.Is(err, service.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		t.backFill(ctx, i, key, value)
+		return value, nil
+	}
+	return nil, service.ErrKeyNotFound
+}
+
+func (t *tieredCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	for i := len(t.tiers) - 1; i >= 0; i-- {
+		tier := t.tiers[i]
+		if err := t.withTier(ctx, tier, func(c service.Cache) error {
+			return c.Set(ctx, key, value, t.tierTTL(tier, ttl))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tieredCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	last := len(t.tiers) - 1
+	for _, tier := range t.tiers[:last] {
+		err := t.withTier(ctx, tier, func(c service.Cache) error {
+			_, err := c.Get(ctx, key)
+			return err
+		})
+		if err == nil {
+			return service.ErrKeyAlreadyExists
+		}
+		if !errors.Is(err, service.ErrKeyNotFound) {
+			return err
+		}
+	}
+
+	if err := t.withTier(ctx, t.tiers[last], func(c service.Cache) error {
+		return c.Add(ctx, key, value, t.tierTTL(t.tiers[last], ttl))
+	}); err != nil {
+		return err
+	}
+
+	for i := last - 1; i >= 0; i-- {
+		tier := t.tiers[i]
+		if err := t.withTier(ctx, tier, func(c service.Cache) error {
+			return c.Set(ctx, key, value, t.tierTTL(tier, ttl))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tieredCache) Delete(ctx context.Context, key string) error {
+	for i := len(t.tiers) - 1; i >= 0; i-- {
+		if err := t.withTier(ctx, t.tiers[i], func(c service.Cache) error {
+			return c.Delete(ctx, key)
+		}); err != nil && !errors.Is(err, service.ErrKeyNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tieredCache) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,177 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tieredTestItem struct {
+	value []byte
+	ttl   *time.Duration
+}
+
+type tieredTestCache struct {
+	items map[string]tieredTestItem
+}
+
+func (c *tieredTestCache) Get(ctx context.Context, key string) ([]byte, error) {
+	i, exists := c.items[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return i.value, nil
+}
+
+func (c *tieredTestCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.items[key] = tieredTestItem{value: value, ttl: ttl}
+	return nil
+}
+
+func (c *tieredTestCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if _, exists := c.items[key]; exists {
+		return service.ErrKeyAlreadyExists
+	}
+	c.items[key] = tieredTestItem{value: value, ttl: ttl}
+	return nil
+}
+
+func (c *tieredTestCache) Delete(ctx context.Context, key string) error {
+	delete(c.items, key)
+	return nil
+}
+
+func (c *tieredTestCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func tieredTestAccessor(caches map[string]*tieredTestCache) store.CacheAccessor {
+	return func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		c, exists := caches[name]
+		if !exists {
+			return errors.New("cache not found")
+		}
+		fn(c)
+		return nil
+	}
+}
+
+func TestTieredCacheConfigs(t *testing.T) {
+	spec := tieredCacheConfig()
+
+	_, err := spec.ParseYAML(`
+tiers:
+  - resource: foo
+    ttl: 10s
+  - resource: bar
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := spec.ParseYAML(`
+tiers:
+  - resource: foo
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newTieredCacheFromConfig(conf, nil, nil)
+	require.EqualError(t, err, "expected at least two cache tiers, found 1")
+
+	conf, err = spec.ParseYAML(`
+tiers:
+  - resource: foo
+    ttl: nope
+  - resource: bar
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newTieredCacheFromConfig(conf, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tier 0")
+}
+
+func TestTieredCacheGetBackFill(t *testing.T) {
+	hot := &tieredTestCache{items: map[string]tieredTestItem{}}
+	cold := &tieredTestCache{items: map[string]tieredTestItem{
+		"foo": {value: []byte("bar")},
+	}}
+
+	hotTTL := time.Second
+	c, err := newTieredCache([]cacheTier{
+		{resource: "hot", ttl: &hotTTL},
+		{resource: "cold"},
+	}, tieredTestAccessor(map[string]*tieredTestCache{
+		"hot":  hot,
+		"cold": cold,
+	}), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(v))
+	assert.Equal(t, tieredTestItem{value: []byte("bar"), ttl: &hotTTL}, hot.items["foo"])
+
+	_, err = c.Get(ctx, "baz")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+}
+
+func TestTieredCacheSetTTLOverrides(t *testing.T) {
+	hot := &tieredTestCache{items: map[string]tieredTestItem{}}
+	cold := &tieredTestCache{items: map[string]tieredTestItem{}}
+
+	hotTTL, reqTTL := time.Second, time.Hour
+	c, err := newTieredCache([]cacheTier{
+		{resource: "hot", ttl: &hotTTL},
+		{resource: "cold"},
+	}, tieredTestAccessor(map[string]*tieredTestCache{
+		"hot":  hot,
+		"cold": cold,
+	}), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("bar"), &reqTTL))
+	assert.Equal(t, tieredTestItem{value: []byte("bar"), ttl: &hotTTL}, hot.items["foo"])
+	assert.Equal(t, tieredTestItem{value: []byte("bar"), ttl: &reqTTL}, cold.items["foo"])
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	assert.Empty(t, hot.items)
+	assert.Empty(t, cold.items)
+}
+
+func TestTieredCacheAdd(t *testing.T) {
+	hot := &tieredTestCache{items: map[string]tieredTestItem{
+		"exists_hot": {value: []byte("a")},
+	}}
+	cold := &tieredTestCache{items: map[string]tieredTestItem{
+		"exists_cold": {value: []byte("b")},
+	}}
+
+	c, err := newTieredCache([]cacheTier{
+		{resource: "hot"},
+		{resource: "cold"},
+	}, tieredTestAccessor(map[string]*tieredTestCache{
+		"hot":  hot,
+		"cold": cold,
+	}), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "exists_hot", []byte("c"), nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "exists_cold", []byte("c"), nil))
+	_, exists := hot.items["exists_cold"]
+	assert.False(t, exists)
+
+	require.NoError(t, c.Add(ctx, "new", []byte("c"), nil))
+	assert.Equal(t, "c", string(hot.items["new"].value))
+	assert.Equal(t, "c", string(cold.items["new"].value))
+}
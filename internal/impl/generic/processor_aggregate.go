@@ -0,0 +1,668 @@
+package generic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/OneOfOne/xxhash"
+)
+
+func aggregateProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Utility").
+		Summary("Maintains keyed accumulators across messages, such as counts, sums and distinct estimates, persisting their state within a cache resource and emitting the results as new messages.").
+		Description(`
+Each message is allocated a group by resolving the `+"[`key` field](#key)"+`, and every group has its own set of accumulators as defined by the `+"[`aggregations` field](#aggregations)"+`. The state of each group is stored within the configured `+"[cache resource](/docs/components/caches/about)"+` after each batch is processed, and therefore when a persisted cache is used the aggregations survive restarts of the service.
+
+Messages that are successfully aggregated are removed from the pipeline, and are therefore acknowledged once the state that includes them has been written to the cache. Messages that fail to be aggregated, for example when a value mapping fails, are flagged as failed and continue through the pipeline unchanged, where they can be handled using [error handling patterns](/docs/configuration/error_handling).
+
+### Emitting Aggregates
+
+When a group satisfies any of the conditions within the `+"[`emit` field](#emit)"+` a new message is created containing an object with a field for each aggregation, and the group is reset. Emitted messages contain the metadata fields `+"`aggregate_key`"+`, containing the key of the group, and `+"`aggregate_count`"+`, containing the number of messages aggregated.
+
+Since processors only execute when messages arrive the `+"`period`"+` of a group is checked each time a batch is processed, and therefore aggregates are not emitted during periods of inactivity. The `+"`end_of_batch`"+` condition can be combined with a [windowed buffer](/docs/components/buffers/system_window) in order to emit aggregates each time a window closes.
+
+### Aggregation Types
+
+- `+"`count`"+`: The number of messages aggregated.
+- `+"`sum`"+`: The sum of the numerical values.
+- `+"`min`"+`: The lowest numerical value.
+- `+"`max`"+`: The highest numerical value.
+- `+"`distinct`"+`: An estimate of the number of distinct values, calculated using a HyperLogLog with a standard error of roughly 3%.
+- `+"`fold`"+`: The result of a custom `+"[`fold` mapping](#aggregationsfold)"+`, which is executed for each value.
+
+### Concurrency
+
+The state of a group is read from and written to the cache without locks spanning processor instances, and therefore this processor should be run within a single pipeline thread, or with keys partitioned such that each group is only ever aggregated by one processor instance.`).
+		Field(service.NewStringField("resource").
+			Description("The name of a [cache resource](/docs/components/caches/about) used for storing the state of each group.")).
+		Field(service.NewInterpolatedStringField("key").
+			Description("An interpolated string that resolves the group key of each message. By default all messages are aggregated within a single group.").
+			Example(`${! json("user_id") }`).
+			Default("")).
+		Field(service.NewStringField("key_prefix").
+			Description("A prefix added to each group key before it is used as a cache key, which allows multiple aggregate processors to share a cache resource.").
+			Default("aggregate_").
+			Advanced()).
+		Field(service.NewStringField("ttl").
+			Description("An optional TTL for the state of each group within the cache, which allows abandoned groups to expire for caches that support it.").
+			Example("24h").
+			Default("").
+			Advanced()).
+		Field(service.NewObjectListField("aggregations",
+			service.NewStringField("name").
+				Description("The name of the field within emitted messages that contains the result of this aggregation."),
+			service.NewStringAnnotatedEnumField("type", map[string]string{
+				"count":    "Count the number of values.",
+				"sum":      "Sum the numerical values.",
+				"min":      "Track the lowest numerical value.",
+				"max":      "Track the highest numerical value.",
+				"distinct": "Estimate the number of distinct values.",
+				"fold":     "Fold values into a custom result using the `fold` mapping.",
+			}).Description("The type of aggregation."),
+			service.NewBloblangField("value").
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that extracts the value to aggregate from each message. If the mapping deletes the root of the result the message is skipped by this aggregation. This field is ignored by the `count` type.").
+				Example("root = this.price").
+				Default("root = this"),
+			service.NewBloblangField("fold").
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) for the `fold` type that is executed for each value, where `this.tally` is the current result (which is `null` for the first value) and `this.value` is the extracted value. The result of the mapping becomes the new tally.").
+				Example("root = (this.tally | []).append(this.value).sort().slice(-3)").
+				Default(""),
+		).Description("A list of aggregations to perform for each group.")).
+		Field(service.NewObjectField("emit",
+			service.NewIntField("count").
+				Description("Emit a group once it has aggregated this number of messages. Set to 0 in order to disable.").
+				Default(0),
+			service.NewStringField("period").
+				Description("Emit a group once this period of time has elapsed since its first message was aggregated. Set to an empty string in order to disable.").
+				Example("1m").
+				Default(""),
+			service.NewBloblangField("check").
+				Description("A [Bloblang query](/docs/guides/bloblang/about) executed against the aggregate of a group after each message is added, which should return a boolean indicating whether the group should be emitted. Set to an empty string in order to disable.").
+				Example("this.total > 1000").
+				Default(""),
+			service.NewBoolField("end_of_batch").
+				Description("Emit all groups that were modified once each batch has been processed.").
+				Default(false),
+		).Description("Conditions under which the aggregate of a group is emitted, at least one must be enabled.")).
+		Example("Tumbling Sums", `
+Here we aggregate the total spend and number of distinct products of each customer, emitting an aggregate every 100 purchases or whenever an hour has passed since the first purchase:`,
+			`
+pipeline:
+  processors:
+    - aggregate:
+        resource: aggregate_state
+        key: ${! json("customer_id") }
+        aggregations:
+          - name: purchases
+            type: count
+          - name: total_spend
+            type: sum
+            value: root = this.price
+          - name: products
+            type: distinct
+            value: root = this.product_id
+        emit:
+          count: 100
+          period: 1h
+
+cache_resources:
+  - label: aggregate_state
+    redis:
+      url: tcp://TODO:6379
+`,
+		).
+		Example("Windowed Top Scores", `
+Here we combine a window buffer with a custom fold in order to emit the top three scores of each game every minute:`,
+			`
+buffer:
+  system_window:
+    timestamp_mapping: root = this.played_at
+    size: 1m
+
+pipeline:
+  threads: 1
+  processors:
+    - aggregate:
+        resource: aggregate_state
+        key: ${! json("game") }
+        aggregations:
+          - name: top_scores
+            type: fold
+            value: root = this.score
+            fold: root = (this.tally | []).append(this.value).sort().slice(-3)
+        emit:
+          end_of_batch: true
+
+cache_resources:
+  - label: aggregate_state
+    memory: {}
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"aggregate", aggregateProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newAggregateProcessorFromConfig(conf, mgr.AccessCache, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type aggregation struct {
+	name  string
+	typ   string
+	value *bloblang.Executor
+	fold  *bloblang.Executor
+}
+
+type aggregateProcessor struct {
+	resource  string
+	key       *service.InterpolatedString
+	keyPrefix string
+	ttl       *time.Duration
+
+	aggs []aggregation
+
+	emitCount      int64
+	emitPeriod     time.Duration
+	emitCheck      *bloblang.Executor
+	emitEndOfBatch bool
+
+	// The start time of each group known to this processor, which is used for
+	// checking the emit period without reading every group from the cache.
+	startedMut sync.Mutex
+	started    map[string]time.Time
+
+	now    func() time.Time
+	access store.CacheAccessor
+	log    *service.Logger
+}
+
+func optionalBloblang(conf *service.ParsedConfig, name string) (*bloblang.Executor, error) {
+	str, err := conf.FieldString(name)
+	if err != nil || str == "" {
+		return nil, err
+	}
+	return conf.FieldBloblang(name)
+}
+
+func newAggregateProcessorFromConfig(conf *service.ParsedConfig, access store.CacheAccessor, log *service.Logger) (*aggregateProcessor, error) {
+	a := &aggregateProcessor{
+		started: map[string]time.Time{},
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+		access: access,
+		log:    log,
+	}
+
+	var err error
+	if a.resource, err = conf.FieldString("resource"); err != nil {
+		return nil, err
+	}
+	if a.resource == "" {
+		return nil, errors.New("a cache resource must be specified")
+	}
+	if a.key, err = conf.FieldInterpolatedString("key"); err != nil {
+		return nil, err
+	}
+	if a.keyPrefix, err = conf.FieldString("key_prefix"); err != nil {
+		return nil, err
+	}
+	ttl, err := getDuration(conf, false, "ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		a.ttl = &ttl
+	}
+
+	aggConfs, err := conf.FieldObjectList("aggregations")
+	if err != nil {
+		return nil, err
+	}
+	if len(aggConfs) == 0 {
+		return nil, errors.New("at least one aggregation must be specified")
+	}
+	names := map[string]struct{}{}
+	for i, aConf := range aggConfs {
+		var agg aggregation
+		if agg.name, err = aConf.FieldString("name"); err != nil {
+			return nil, err
+		}
+		if agg.name == "" {
+			return nil, fmt.Errorf("aggregation %v: a name must be specified", i)
+		}
+		if _, exists := names[agg.name]; exists {
+			return nil, fmt.Errorf("aggregation %v: duplicate name '%v'", i, agg.name)
+		}
+		names[agg.name] = struct{}{}
+		if agg.typ, err = aConf.FieldString("type"); err != nil {
+			return nil, err
+		}
+		switch agg.typ {
+		case "count", "sum", "min", "max", "distinct", "fold":
+		default:
+			return nil, fmt.Errorf("aggregation %v: type not recognised: %v", i, agg.typ)
+		}
+		if agg.typ != "count" {
+			if agg.value, err = optionalBloblang(aConf, "value"); err != nil {
+				return nil, fmt.Errorf("aggregation %v: %w", i, err)
+			}
+		}
+		if agg.fold, err = optionalBloblang(aConf, "fold"); err != nil {
+			return nil, fmt.Errorf("aggregation %v: %w", i, err)
+		}
+		if agg.typ == "fold" && agg.fold == nil {
+			return nil, fmt.Errorf("aggregation %v: a fold mapping must be specified for the fold type", i)
+		}
+		a.aggs = append(a.aggs, agg)
+	}
+
+	emitCount, err := conf.FieldInt("emit", "count")
+	if err != nil {
+		return nil, err
+	}
+	a.emitCount = int64(emitCount)
+	if a.emitPeriod, err = getDuration(conf.Namespace("emit"), false, "period"); err != nil {
+		return nil, err
+	}
+	if a.emitCheck, err = optionalBloblang(conf.Namespace("emit"), "check"); err != nil {
+		return nil, err
+	}
+	if a.emitEndOfBatch, err = conf.FieldBool("emit", "end_of_batch"); err != nil {
+		return nil, err
+	}
+	if a.emitCount <= 0 && a.emitPeriod <= 0 && a.emitCheck == nil && !a.emitEndOfBatch {
+		return nil, errors.New("at least one emit condition must be enabled")
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// aggregateValue is the persisted state of a single aggregation within a
+// group.
+type aggregateValue struct {
+	Value     interface{} `json:"value,omitempty"`
+	Registers []byte      `json:"registers,omitempty"`
+}
+
+// aggregateState is the persisted state of a group.
+type aggregateState struct {
+	Count   int64                     `json:"count"`
+	Started int64                     `json:"started"`
+	Values  map[string]aggregateValue `json:"values"`
+}
+
+func newAggregateState(now time.Time) *aggregateState {
+	return &aggregateState{
+		Started: now.UnixNano(),
+		Values:  map[string]aggregateValue{},
+	}
+}
+
+func (a *aggregateProcessor) loadState(ctx context.Context, key string) (*aggregateState, error) {
+	var b []byte
+	var err error
+	if cerr := a.access(ctx, a.resource, func(c service.Cache) {
+		b, err = c.Get(ctx, a.keyPrefix+key)
+	}); cerr != nil {
+		return nil, fmt.Errorf("unable to access cache '%v': %w", a.resource, cerr)
+	}
+	if errors.Is(err, service.ErrKeyNotFound) {
+		return newAggregateState(a.now()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := newAggregateState(a.now())
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state of group '%v': %w", key, err)
+	}
+	if state.Values == nil {
+		state.Values = map[string]aggregateValue{}
+	}
+	return state, nil
+}
+
+func (a *aggregateProcessor) storeState(ctx context.Context, key string, state *aggregateState) error {
+	var b []byte
+	if state.Count > 0 {
+		var err error
+		if b, err = json.Marshal(state); err != nil {
+			return err
+		}
+	}
+	var err error
+	if cerr := a.access(ctx, a.resource, func(c service.Cache) {
+		if state.Count == 0 {
+			if err = c.Delete(ctx, a.keyPrefix+key); errors.Is(err, service.ErrKeyNotFound) {
+				err = nil
+			}
+			return
+		}
+		err = c.Set(ctx, a.keyPrefix+key, b, a.ttl)
+	}); cerr != nil {
+		return fmt.Errorf("unable to access cache '%v': %w", a.resource, cerr)
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+func (a *aggregateProcessor) add(batch service.MessageBatch, index int, state *aggregateState) error {
+	values := make([]interface{}, len(a.aggs))
+	skip := make([]bool, len(a.aggs))
+	for i, agg := range a.aggs {
+		if agg.value == nil {
+			continue
+		}
+		res, err := batch.BloblangQuery(index, agg.value)
+		if err != nil {
+			return fmt.Errorf("aggregation '%v': value mapping failed: %w", agg.name, err)
+		}
+		if res == nil {
+			skip[i] = true
+			continue
+		}
+		if values[i], err = res.AsStructured(); err != nil {
+			return fmt.Errorf("aggregation '%v': %w", agg.name, err)
+		}
+	}
+
+	// Calculate every new value before modifying the state so that a failed
+	// message leaves the group untouched.
+	newValues := make([]aggregateValue, len(a.aggs))
+	for i, agg := range a.aggs {
+		current := state.Values[agg.name]
+		if skip[i] {
+			newValues[i] = current
+			continue
+		}
+		next, err := agg.apply(current, values[i])
+		if err != nil {
+			return fmt.Errorf("aggregation '%v': %w", agg.name, err)
+		}
+		newValues[i] = next
+	}
+
+	for i, agg := range a.aggs {
+		state.Values[agg.name] = newValues[i]
+	}
+	state.Count++
+	return nil
+}
+
+func (agg aggregation) apply(current aggregateValue, v interface{}) (aggregateValue, error) {
+	switch agg.typ {
+	case "count":
+		n, _ := current.Value.(float64)
+		return aggregateValue{Value: n + 1}, nil
+	case "sum", "min", "max":
+		f, err := query.IGetNumber(v)
+		if err != nil {
+			return current, err
+		}
+		c, exists := current.Value.(float64)
+		switch {
+		case !exists:
+		case agg.typ == "sum":
+			f += c
+		case agg.typ == "min" && c < f:
+			f = c
+		case agg.typ == "max" && c > f:
+			f = c
+		}
+		return aggregateValue{Value: f}, nil
+	case "distinct":
+		registers := make([]byte, hllRegisters)
+		copy(registers, current.Registers)
+		hllAdd(registers, query.IToBytes(v))
+		return aggregateValue{Registers: registers}, nil
+	case "fold":
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]interface{}{
+			"tally": current.Value,
+			"value": v,
+		})
+		res, err := msg.BloblangQuery(agg.fold)
+		if err != nil {
+			return current, fmt.Errorf("fold mapping failed: %w", err)
+		}
+		if res == nil {
+			return aggregateValue{}, nil
+		}
+		tally, err := res.AsStructured()
+		if err != nil {
+			return current, err
+		}
+		// Normalise the tally into its persisted form so that the result of a
+		// fold is consistent regardless of whether the state was reloaded.
+		b, err := json.Marshal(tally)
+		if err != nil {
+			return current, err
+		}
+		var normalised interface{}
+		if err := json.Unmarshal(b, &normalised); err != nil {
+			return current, err
+		}
+		return aggregateValue{Value: normalised}, nil
+	}
+	return current, fmt.Errorf("type not recognised: %v", agg.typ)
+}
+
+func (agg aggregation) result(v aggregateValue) interface{} {
+	switch agg.typ {
+	case "count":
+		n, _ := v.Value.(float64)
+		return int64(n)
+	case "distinct":
+		if len(v.Registers) == 0 {
+			return int64(0)
+		}
+		return hllEstimate(v.Registers)
+	}
+	return v.Value
+}
+
+func (a *aggregateProcessor) aggregateMessage(key string, state *aggregateState) *service.Message {
+	obj := make(map[string]interface{}, len(a.aggs))
+	for _, agg := range a.aggs {
+		obj[agg.name] = agg.result(state.Values[agg.name])
+	}
+	msg := service.NewMessage(nil)
+	msg.SetStructured(obj)
+	msg.MetaSet("aggregate_key", key)
+	msg.MetaSet("aggregate_count", strconv.FormatInt(state.Count, 10))
+	return msg
+}
+
+func (a *aggregateProcessor) checkEmit(msg *service.Message) (bool, error) {
+	res, err := msg.BloblangQuery(a.emitCheck)
+	if err != nil {
+		return false, err
+	}
+	if res == nil {
+		return false, nil
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected boolean value, got %T", v)
+	}
+	return b, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (a *aggregateProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	a.startedMut.Lock()
+	defer a.startedMut.Unlock()
+
+	now := a.now()
+	states := map[string]*aggregateState{}
+	getState := func(key string) (*aggregateState, error) {
+		if state, exists := states[key]; exists {
+			return state, nil
+		}
+		state, err := a.loadState(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		states[key] = state
+		return state, nil
+	}
+
+	var out service.MessageBatch
+	emit := func(key string, state *aggregateState, msg *service.Message) {
+		if msg == nil {
+			msg = a.aggregateMessage(key, state)
+		}
+		out = append(out, msg)
+		states[key] = newAggregateState(now)
+		delete(a.started, key)
+	}
+
+	for i, msg := range batch {
+		key := batch.InterpolatedString(i, a.key)
+		state, err := getState(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.add(batch, i, state); err != nil {
+			a.log.Debugf("Failed to aggregate message: %v", err)
+			failed := msg.Copy()
+			failed.SetError(err)
+			out = append(out, failed)
+			continue
+		}
+		if _, exists := a.started[key]; !exists {
+			a.started[key] = time.Unix(0, state.Started)
+		}
+
+		if a.emitCount > 0 && state.Count >= a.emitCount {
+			emit(key, state, nil)
+			continue
+		}
+		if a.emitCheck != nil {
+			aggMsg := a.aggregateMessage(key, state)
+			doEmit, err := a.checkEmit(aggMsg)
+			if err != nil {
+				a.log.Errorf("Failed to execute emit check for group '%v': %v", key, err)
+			} else if doEmit {
+				emit(key, state, aggMsg)
+			}
+		}
+	}
+
+	if a.emitPeriod > 0 {
+		var expired []string
+		for key, started := range a.started {
+			if now.Sub(started) >= a.emitPeriod {
+				expired = append(expired, key)
+			}
+		}
+		sort.Strings(expired)
+		for _, key := range expired {
+			state, err := getState(key)
+			if err != nil {
+				return nil, err
+			}
+			if state.Count > 0 {
+				emit(key, state, nil)
+			} else {
+				delete(a.started, key)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(states))
+	for key := range states {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if a.emitEndOfBatch {
+		for _, key := range keys {
+			if state := states[key]; state.Count > 0 {
+				emit(key, state, nil)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		if err := a.storeState(ctx, key, states[key]); err != nil {
+			return nil, fmt.Errorf("failed to store state of group '%v': %w", key, err)
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (a *aggregateProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+)
+
+// hllAdd adds a value to a set of HyperLogLog registers.
+func hllAdd(registers []byte, v []byte) {
+	h := xxhash.Checksum64(v)
+	idx := h >> (64 - hllPrecision)
+	w := h<<hllPrecision | 1<<(hllPrecision-1)
+	if rank := byte(bits.LeadingZeros64(w) + 1); rank > registers[idx] {
+		registers[idx] = rank
+	}
+}
+
+// hllEstimate returns the estimated cardinality of a set of HyperLogLog
+// registers, using linear counting for small cardinalities.
+func hllEstimate(registers []byte) int64 {
+	m := float64(len(registers))
+	var sum float64
+	var zeros int
+	for _, r := range registers {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := (0.7213 / (1 + 1.079/m)) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(est))
+}
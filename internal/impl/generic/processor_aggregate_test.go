@@ -0,0 +1,268 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateProcessorBadConfigs(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		errStr string
+	}{
+		"no emit conditions": {
+			config: `
+resource: foo
+aggregations:
+  - name: count
+    type: count
+`,
+			errStr: "at least one emit condition must be enabled",
+		},
+		"no aggregations": {
+			config: `
+resource: foo
+aggregations: []
+emit:
+  count: 10
+`,
+			errStr: "at least one aggregation must be specified",
+		},
+		"fold without mapping": {
+			config: `
+resource: foo
+aggregations:
+  - name: things
+    type: fold
+emit:
+  count: 10
+`,
+			errStr: "aggregation 0: a fold mapping must be specified for the fold type",
+		},
+		"duplicate names": {
+			config: `
+resource: foo
+aggregations:
+  - name: foo
+    type: count
+  - name: foo
+    type: sum
+emit:
+  count: 10
+`,
+			errStr: "aggregation 1: duplicate name 'foo'",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := aggregateProcessorConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newAggregateProcessorFromConfig(conf, nil, nil)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func aggregateTestBatch(contents ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	return batch
+}
+
+func TestAggregateProcessorCount(t *testing.T) {
+	cache := &tieredTestCache{items: map[string]tieredTestItem{}}
+	conf, err := aggregateProcessorConfig().ParseYAML(`
+resource: state
+key: ${! json("user") }
+aggregations:
+  - name: hits
+    type: count
+  - name: total
+    type: sum
+    value: root = this.n
+  - name: lowest
+    type: min
+    value: root = this.n
+  - name: highest
+    type: max
+    value: root = this.n
+  - name: names
+    type: distinct
+    value: root = this.name
+emit:
+  count: 3
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newAggregateProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+		"state": cache,
+	}), nil)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"user":"a","n":5,"name":"foo"}`,
+		`{"user":"b","n":1,"name":"foo"}`,
+		`{"user":"a","n":2,"name":"bar"}`,
+	))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Contains(t, cache.items, "aggregate_a")
+	assert.Contains(t, cache.items, "aggregate_b")
+
+	// A new processor resumes from the state within the cache.
+	proc, err = newAggregateProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+		"state": cache,
+	}), nil)
+	require.NoError(t, err)
+
+	res, err = proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"user":"a","n":9,"name":"foo"}`,
+		`{"user":"b","n":"nope","name":"foo"}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	assert.EqualError(t, res[0][1].GetError(), "aggregation 'total': expected number value, got string (\"nope\")")
+
+	v, err := res[0][0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"hits":    int64(3),
+		"total":   float64(16),
+		"lowest":  float64(2),
+		"highest": float64(9),
+		"names":   int64(2),
+	}, v)
+
+	key, _ := res[0][0].MetaGet("aggregate_key")
+	assert.Equal(t, "a", key)
+	count, _ := res[0][0].MetaGet("aggregate_count")
+	assert.Equal(t, "3", count)
+
+	assert.NotContains(t, cache.items, "aggregate_a")
+	assert.Contains(t, cache.items, "aggregate_b")
+}
+
+func TestAggregateProcessorFoldAndCheck(t *testing.T) {
+	cache := &tieredTestCache{items: map[string]tieredTestItem{}}
+	conf, err := aggregateProcessorConfig().ParseYAML(`
+resource: state
+aggregations:
+  - name: words
+    type: fold
+    value: root = this.word
+    fold: root = (this.tally | []).append(this.value)
+emit:
+  check: this.words.length() >= 2
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newAggregateProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+		"state": cache,
+	}), nil)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"word":"foo"}`,
+		`{"word":"bar"}`,
+		`{"word":"baz"}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	v, err := res[0][0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"words": []interface{}{"foo", "bar"},
+	}, v)
+
+	assert.Contains(t, cache.items, "aggregate_")
+}
+
+func TestAggregateProcessorPeriodAndEndOfBatch(t *testing.T) {
+	cache := &tieredTestCache{items: map[string]tieredTestItem{}}
+	conf, err := aggregateProcessorConfig().ParseYAML(`
+resource: state
+key: ${! json("user") }
+aggregations:
+  - name: hits
+    type: count
+emit:
+  period: 1m
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newAggregateProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+		"state": cache,
+	}), nil)
+	require.NoError(t, err)
+
+	now := time.Unix(100, 0)
+	proc.now = func() time.Time { return now }
+
+	res, err := proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"user":"a"}`,
+		`{"user":"a"}`,
+	))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	now = now.Add(time.Minute)
+
+	res, err = proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"user":"b"}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	key, _ := res[0][0].MetaGet("aggregate_key")
+	assert.Equal(t, "a", key)
+	v, err := res[0][0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"hits": int64(2)}, v)
+
+	proc.emitPeriod = 0
+	proc.emitEndOfBatch = true
+
+	res, err = proc.ProcessBatch(context.Background(), aggregateTestBatch(
+		`{"user":"b"}`,
+		`{"user":"c"}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	var keys []string
+	for _, msg := range res[0] {
+		key, _ := msg.MetaGet("aggregate_key")
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"b", "c"}, keys)
+
+	v, err = res[0][0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"hits": int64(2)}, v)
+	assert.Empty(t, cache.items)
+}
+
+func TestAggregateDistinctEstimate(t *testing.T) {
+	registers := make([]byte, hllRegisters)
+	for i := 0; i < 10000; i++ {
+		hllAdd(registers, []byte(time.Unix(int64(i), 0).String()))
+		hllAdd(registers, []byte(time.Unix(int64(i), 0).String()))
+	}
+	assert.InDelta(t, 10000, hllEstimate(registers), 1000)
+}
@@ -0,0 +1,342 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func joinProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Integration").
+		Summary("Enriches messages by joining them with the latest state of a secondary stream, which is consumed from an input and stored within a cache resource by key.").
+		Description(`
+The messages consumed from the `+"[`secondary.input`](#secondaryinput)"+` are written to the configured [cache resource](/docs/components/caches/about) by resolving the `+"[`secondary.key`](#secondarykey)"+` of each message, and are acknowledged once written. Each message flowing through this processor (the primary stream) then resolves the `+"[`key` field](#key)"+` and, if the secondary stream has provided state for that key, the `+"[`result_map`](#result_map)"+` is executed in order to merge the state into the message.
+
+Secondary messages provide the latest state of a key, and therefore a message with the same key as a prior one replaces its state. When the `+"[`secondary.value`](#secondaryvalue)"+` mapping deletes the root of the result the state of the key is removed, which allows tombstone messages to be handled. State can also be aged out by specifying a `+"[`secondary.ttl`](#secondaryttl)"+`, for caches that support it.
+
+### Ordering
+
+There are no ordering guarantees between the primary and secondary streams, and therefore a primary message may arrive before the secondary message that provides its state. The behaviour for primary messages without state is determined by the `+"[`missing` field](#missing)"+`.
+
+### Pipeline Threads
+
+Each instance of this processor consumes its own secondary input, and a processor instance is created for each pipeline thread. When running with multiple threads the secondary input should therefore either be able to share consumption with other instances, such as a Kafka consumer group, or tolerate consuming duplicate messages.`).
+		Field(service.NewStringField("resource").
+			Description("The name of a [cache resource](/docs/components/caches/about) used for storing the state of the secondary stream by key.")).
+		Field(service.NewInterpolatedStringField("key").
+			Description("An interpolated string that resolves the key of each primary message, which is used to look up the state provided by the secondary stream.").
+			Example(`${! json("user_id") }`)).
+		Field(service.NewBloblangField("result_map").
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that describes how the state of a key is merged into a primary message, where `this` refers to the state and `root` begins as the primary message contents. Metadata of the primary message cannot be queried or modified by this mapping.").
+			Example("root.user = this").
+			Example(`root.user_name = this.name
+root.user_tier = this.tier | "free"`)).
+		Field(service.NewStringAnnotatedEnumField("missing", map[string]string{
+			"pass": "Pass the message through unchanged.",
+			"fail": "Flag the message as having failed, allowing it to be handled using [error handling patterns](/docs/configuration/error_handling).",
+			"drop": "Drop the message.",
+		}).Description("The behaviour of primary messages for which no state exists.").
+			Default("pass")).
+		Field(service.NewObjectField("secondary",
+			service.NewInputField("input").
+				Description("An [input](/docs/components/inputs/about) to consume the secondary stream from."),
+			service.NewInterpolatedStringField("key").
+				Description("An interpolated string that resolves the key of each secondary message.").
+				Example(`${! json("id") }`),
+			service.NewBloblangField("value").
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that provides the state to store for each secondary message. If the mapping deletes the root of the result the state of the key is removed.").
+				Example(`root = if this.deleted == true { deleted() } else { this }`).
+				Default("root = this"),
+			service.NewStringField("ttl").
+				Description("An optional TTL for the state of each key, which allows stale state to expire for caches that support it.").
+				Example("24h").
+				Default(""),
+		).Description("The secondary stream that provides state for joining.")).
+		Example("Enriching Clicks", `
+Here we consume a clickstream from Kafka and enrich each click event with the latest profile of its user, which is consumed from a separate topic of user updates:`,
+			`
+input:
+  kafka:
+    addresses: [ TODO ]
+    topics: [ clicks ]
+    consumer_group: benthos_clicks
+
+pipeline:
+  processors:
+    - join:
+        resource: users
+        key: ${! json("user_id") }
+        result_map: root.user = this
+        missing: fail
+        secondary:
+          input:
+            kafka:
+              addresses: [ TODO ]
+              topics: [ user_updates ]
+              consumer_group: benthos_users
+          key: ${! json("id") }
+          ttl: 168h
+
+cache_resources:
+  - label: users
+    redis:
+      url: tcp://TODO:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"join", joinProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			j, err := newJoinProcessorFromConfig(conf, mgr.AccessCache, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			secondary, err := conf.FieldInput("secondary", "input")
+			if err != nil {
+				return nil, err
+			}
+			j.start(secondary)
+			return j, nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type joinReader interface {
+	ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error)
+	Close(ctx context.Context) error
+}
+
+type joinProcessor struct {
+	resource  string
+	key       *service.InterpolatedString
+	resultMap *bloblang.Executor
+	missing   string
+
+	secondaryKey   *service.InterpolatedString
+	secondaryValue *bloblang.Executor
+	secondaryTTL   *time.Duration
+
+	reader     joinReader
+	loopCancel func()
+	loopWG     sync.WaitGroup
+
+	access store.CacheAccessor
+	log    *service.Logger
+}
+
+func newJoinProcessorFromConfig(conf *service.ParsedConfig, access store.CacheAccessor, log *service.Logger) (*joinProcessor, error) {
+	j := &joinProcessor{
+		access: access,
+		log:    log,
+	}
+
+	var err error
+	if j.resource, err = conf.FieldString("resource"); err != nil {
+		return nil, err
+	}
+	if j.resource == "" {
+		return nil, errors.New("a cache resource must be specified")
+	}
+	if j.key, err = conf.FieldInterpolatedString("key"); err != nil {
+		return nil, err
+	}
+	if j.resultMap, err = conf.FieldBloblang("result_map"); err != nil {
+		return nil, err
+	}
+	if j.missing, err = conf.FieldString("missing"); err != nil {
+		return nil, err
+	}
+	switch j.missing {
+	case "pass", "fail", "drop":
+	default:
+		return nil, fmt.Errorf("missing behaviour not recognised: %v", j.missing)
+	}
+
+	if j.secondaryKey, err = conf.FieldInterpolatedString("secondary", "key"); err != nil {
+		return nil, err
+	}
+	if j.secondaryValue, err = optionalBloblang(conf.Namespace("secondary"), "value"); err != nil {
+		return nil, err
+	}
+	ttl, err := getDuration(conf.Namespace("secondary"), false, "ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		j.secondaryTTL = &ttl
+	}
+	return j, nil
+}
+
+func (j *joinProcessor) withCache(ctx context.Context, fn func(c service.Cache) error) error {
+	var err error
+	if cerr := j.access(ctx, j.resource, func(c service.Cache) {
+		err = fn(c)
+	}); cerr != nil {
+		return fmt.Errorf("unable to access cache '%v': %w", j.resource, cerr)
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+func (j *joinProcessor) start(reader joinReader) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.reader = reader
+	j.loopCancel = cancel
+
+	j.loopWG.Add(1)
+	go func() {
+		defer j.loopWG.Done()
+		j.loopSecondary(ctx)
+	}()
+}
+
+func (j *joinProcessor) loopSecondary(ctx context.Context) {
+	for {
+		batch, ackFn, err := j.reader.ReadBatch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, service.ErrEndOfInput) {
+				j.log.Info("Secondary input of join has finished, state will no longer be updated")
+				return
+			}
+			j.log.Errorf("Failed to read secondary input of join: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		err = j.storeBatch(ctx, batch)
+		if err != nil {
+			j.log.Errorf("Failed to store state from secondary input of join: %v", err)
+		}
+		_ = ackFn(ctx, err)
+	}
+}
+
+func (j *joinProcessor) storeBatch(ctx context.Context, batch service.MessageBatch) error {
+	for i := range batch {
+		key := batch.InterpolatedString(i, j.secondaryKey)
+
+		res := batch[i]
+		if j.secondaryValue != nil {
+			var err error
+			if res, err = batch.BloblangQuery(i, j.secondaryValue); err != nil {
+				return fmt.Errorf("value mapping failed for key '%v': %w", key, err)
+			}
+		}
+
+		if res == nil {
+			if err := j.withCache(ctx, func(c service.Cache) error {
+				if err := c.Delete(ctx, key); !errors.Is(err, service.ErrKeyNotFound) {
+					return err
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := res.AsBytes()
+		if err != nil {
+			return err
+		}
+		if err := j.withCache(ctx, func(c service.Cache) error {
+			return c.Set(ctx, key, b, j.secondaryTTL)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (j *joinProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	newBatch := make(service.MessageBatch, 0, len(batch))
+	for i, msg := range batch {
+		key := batch.InterpolatedString(i, j.key)
+
+		var stateBytes []byte
+		err := j.withCache(ctx, func(c service.Cache) error {
+			var cerr error
+			stateBytes, cerr = c.Get(ctx, key)
+			return cerr
+		})
+		if errors.Is(err, service.ErrKeyNotFound) {
+			switch j.missing {
+			case "fail":
+				failed := msg.Copy()
+				failed.SetError(fmt.Errorf("no state found for key '%v'", key))
+				newBatch = append(newBatch, failed)
+			case "pass":
+				newBatch = append(newBatch, msg)
+			}
+			continue
+		}
+
+		joined := msg.Copy()
+		if err == nil {
+			err = j.merge(joined, stateBytes)
+		}
+		if err != nil {
+			j.log.Debugf("Failed to join message with key '%v': %v", key, err)
+			joined.SetError(err)
+		}
+		newBatch = append(newBatch, joined)
+	}
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (j *joinProcessor) merge(msg *service.Message, stateBytes []byte) error {
+	var state interface{}
+	var err error
+	if state, err = service.NewMessage(stateBytes).AsStructured(); err != nil {
+		state = string(stateBytes)
+	}
+
+	onto, err := msg.AsStructuredMut()
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	if err := j.resultMap.Overlay(state, &onto); err != nil {
+		return fmt.Errorf("result_map failed: %w", err)
+	}
+	msg.SetStructured(onto)
+	return nil
+}
+
+func (j *joinProcessor) Close(ctx context.Context) error {
+	if j.loopCancel == nil {
+		return nil
+	}
+	j.loopCancel()
+	j.loopWG.Wait()
+	return j.reader.Close(ctx)
+}
@@ -0,0 +1,163 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type joinTestReader struct {
+	batches chan service.MessageBatch
+	acks    chan error
+	closed  bool
+}
+
+func (r *joinTestReader) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	select {
+	case b, open := <-r.batches:
+		if !open {
+			return nil, nil, service.ErrEndOfInput
+		}
+		return b, func(ctx context.Context, err error) error {
+			r.acks <- err
+			return nil
+		}, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (r *joinTestReader) Close(ctx context.Context) error {
+	r.closed = true
+	return nil
+}
+
+func TestJoinProcessor(t *testing.T) {
+	conf, err := joinProcessorConfig().ParseYAML(`
+resource: state
+key: ${! json("user_id") }
+result_map: root.user = this
+missing: fail
+secondary:
+  input:
+    generate:
+      mapping: root = {}
+  key: ${! json("id") }
+  value: 'root = if this.deleted.or(false) { deleted() } else { this.without("id") }'
+  ttl: 1h
+`, nil)
+	require.NoError(t, err)
+
+	cache := &tieredTestCache{items: map[string]tieredTestItem{}}
+	j, err := newJoinProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+		"state": cache,
+	}), nil)
+	require.NoError(t, err)
+
+	reader := &joinTestReader{
+		batches: make(chan service.MessageBatch),
+		acks:    make(chan error),
+	}
+	j.start(reader)
+
+	sendSecondary := func(contents ...string) {
+		t.Helper()
+		var batch service.MessageBatch
+		for _, c := range contents {
+			batch = append(batch, service.NewMessage([]byte(c)))
+		}
+		select {
+		case reader.batches <- batch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case err := <-reader.acks:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	sendSecondary(
+		`{"id":"a","name":"alice"}`,
+		`{"id":"b","name":"bob"}`,
+	)
+	sendSecondary(
+		`{"id":"a","name":"alison"}`,
+		`{"id":"b","deleted":true}`,
+	)
+
+	assert.Equal(t, `{"name":"alison"}`, string(cache.items["a"].value))
+	require.NotNil(t, cache.items["a"].ttl)
+	assert.Equal(t, time.Hour, *cache.items["a"].ttl)
+	assert.NotContains(t, cache.items, "b")
+
+	res, err := j.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user_id":"a","page":"/home"}`)),
+		service.NewMessage([]byte(`{"user_id":"b","page":"/about"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	b, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"page":"/home","user":{"name":"alison"},"user_id":"a"}`, string(b))
+	assert.NoError(t, res[0][0].GetError())
+
+	b, err = res[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"user_id":"b","page":"/about"}`, string(b))
+	assert.EqualError(t, res[0][1].GetError(), "no state found for key 'b'")
+
+	require.NoError(t, j.Close(context.Background()))
+	assert.True(t, reader.closed)
+}
+
+func TestJoinProcessorMissing(t *testing.T) {
+	for _, test := range []struct {
+		missing  string
+		expected int
+	}{
+		{missing: "pass", expected: 1},
+		{missing: "drop", expected: 0},
+	} {
+		test := test
+		t.Run(test.missing, func(t *testing.T) {
+			conf, err := joinProcessorConfig().ParseYAML(`
+resource: state
+key: ${! json("user_id") }
+result_map: root.user = this
+missing: `+test.missing+`
+secondary:
+  input:
+    generate:
+      mapping: root = {}
+  key: ${! json("id") }
+`, nil)
+			require.NoError(t, err)
+
+			j, err := newJoinProcessorFromConfig(conf, tieredTestAccessor(map[string]*tieredTestCache{
+				"state": {items: map[string]tieredTestItem{}},
+			}), nil)
+			require.NoError(t, err)
+
+			res, err := j.ProcessBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte(`{"user_id":"a"}`)),
+			})
+			require.NoError(t, err)
+
+			var msgs int
+			for _, b := range res {
+				msgs += len(b)
+			}
+			assert.Equal(t, test.expected, msgs)
+			require.NoError(t, j.Close(context.Background()))
+		})
+	}
+}
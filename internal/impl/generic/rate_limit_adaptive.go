@@ -0,0 +1,250 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func adaptiveRateLimitConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Summary("A rate limit that automatically tightens and loosens the allowed rate according to the responses of the resource it protects.").
+		Description(`
+This rate limit works like the `+"[`local`](/docs/components/rate_limits/local)"+` rate limit, allowing a number of requests within each interval, but the number of requests allowed is adapted at runtime. Components that are able to observe the responses of the resource, such as the `+"[`http_client`](/docs/components/outputs/http_client)"+` output and the `+"[`http`](/docs/components/processors/http)"+` processor, report back to the rate limit whenever a request succeeds or is rejected with a status code of 429 (Too Many Requests) or 503 (Service Unavailable).
+
+When a request is rejected the allowed count is multiplied by `+"`decrease_factor`"+`, which happens at most once per interval in order to avoid collapsing the rate when many requests in flight are rejected at the same time. If the response contained a `+"`Retry-After`"+` header then all access is paused until the period it specifies has passed, limited to `+"`max_retry_after`"+`.
+
+For each interval that passes with successful requests and without any rejections the allowed count is increased by `+"`increase_step`"+`, until it reaches `+"`count`"+` again.
+
+### Metrics
+
+The current allowed count is exposed as the gauge `+"`adaptive_rate_limit_count`"+`, and the number of rejections reported is exposed as the counter `+"`adaptive_rate_limit_throttled`"+`.`).
+		Field(service.NewIntField("count").
+			Description("The maximum number of requests to allow for a given period of time, which is also the initial count.").
+			Default(1000)).
+		Field(service.NewIntField("min_count").
+			Description("The minimum number of requests to allow for a given period of time, the count is never decreased below this value.").
+			Default(1)).
+		Field(service.NewStringField("interval").
+			Description("The time window to limit requests by.").
+			Default("1s")).
+		Field(service.NewFloatField("decrease_factor").
+			Description("A factor between zero and one to multiply the count by when a request is rejected.").
+			Default(0.5)).
+		Field(service.NewIntField("increase_step").
+			Description("The number of requests to add to the count for each interval that passes with successful requests and no rejections.").
+			Default(10)).
+		Field(service.NewStringField("max_retry_after").
+			Description("The maximum length of time to pause access from a `Retry-After` header. Set to an empty string or zero in order to ignore `Retry-After` headers.").
+			Advanced().
+			Default("5m")).
+		Example("HTTP Backpressure", `
+Sending messages to an HTTP API that has an undocumented rate limit, where the rate is reduced whenever it responds with a 429:`,
+			`
+output:
+  http_client:
+    url: http://example.com/post
+    verb: POST
+    rate_limit: api_limit
+
+rate_limit_resources:
+  - label: api_limit
+    adaptive:
+      count: 500
+      min_count: 10
+      interval: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterRateLimit(
+		"adaptive", adaptiveRateLimitConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.RateLimit, error) {
+			return newAdaptiveRateLimitFromConfig(conf, mgr.Metrics())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type adaptiveRateLimit struct {
+	mut sync.Mutex
+
+	maxCount       int
+	minCount       int
+	period         time.Duration
+	decreaseFactor float64
+	increaseStep   int
+	maxRetryAfter  time.Duration
+
+	count        int
+	bucket       int
+	lastRefresh  time.Time
+	lastDecrease time.Time
+	pausedUntil  time.Time
+	succeeded    bool
+	throttled    bool
+
+	mCount     *service.MetricGauge
+	mThrottled *service.MetricCounter
+}
+
+func newAdaptiveRateLimitFromConfig(conf *service.ParsedConfig, stats *service.Metrics) (*adaptiveRateLimit, error) {
+	count, err := conf.FieldInt("count")
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	minCount, err := conf.FieldInt("min_count")
+	if err != nil {
+		return nil, err
+	}
+	if minCount <= 0 || minCount > count {
+		return nil, errors.New("min_count must be larger than zero and no larger than count")
+	}
+
+	intervalStr, err := conf.FieldString("interval")
+	if err != nil {
+		return nil, err
+	}
+	period, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %w", err)
+	}
+	if period <= 0 {
+		return nil, errors.New("interval must be larger than zero")
+	}
+
+	decreaseFactor, err := conf.FieldFloat("decrease_factor")
+	if err != nil {
+		return nil, err
+	}
+	if decreaseFactor <= 0 || decreaseFactor >= 1 {
+		return nil, errors.New("decrease_factor must be between zero and one")
+	}
+	increaseStep, err := conf.FieldInt("increase_step")
+	if err != nil {
+		return nil, err
+	}
+	if increaseStep < 0 {
+		return nil, errors.New("increase_step must not be negative")
+	}
+
+	var maxRetryAfter time.Duration
+	retryAfterStr, err := conf.FieldString("max_retry_after")
+	if err != nil {
+		return nil, err
+	}
+	if retryAfterStr != "" {
+		if maxRetryAfter, err = time.ParseDuration(retryAfterStr); err != nil {
+			return nil, fmt.Errorf("failed to parse max_retry_after: %w", err)
+		}
+	}
+
+	a := &adaptiveRateLimit{
+		maxCount:       count,
+		minCount:       minCount,
+		period:         period,
+		decreaseFactor: decreaseFactor,
+		increaseStep:   increaseStep,
+		maxRetryAfter:  maxRetryAfter,
+
+		count:       count,
+		bucket:      count,
+		lastRefresh: time.Now(),
+
+		mCount:     stats.NewGauge("adaptive_rate_limit_count"),
+		mThrottled: stats.NewCounter("adaptive_rate_limit_throttled"),
+	}
+	a.mCount.Set(int64(count))
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// refresh adapts the count according to the feedback received during the last
+// interval and then refills the bucket. Must be called with the lock held.
+func (a *adaptiveRateLimit) refresh(now time.Time) {
+	if a.succeeded && !a.throttled && a.count < a.maxCount && now.Sub(a.lastDecrease) >= a.period {
+		if a.count += a.increaseStep; a.count > a.maxCount {
+			a.count = a.maxCount
+		}
+		a.mCount.Set(int64(a.count))
+	}
+	a.succeeded, a.throttled = false, false
+	a.bucket = a.count
+	a.lastRefresh = now
+}
+
+func (a *adaptiveRateLimit) Access(ctx context.Context) (time.Duration, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	now := time.Now()
+	if now.Before(a.pausedUntil) {
+		return a.pausedUntil.Sub(now), nil
+	}
+	if now.Sub(a.lastRefresh) >= a.period {
+		a.refresh(now)
+	}
+	if a.bucket <= 0 {
+		return a.period - now.Sub(a.lastRefresh), nil
+	}
+	a.bucket--
+	return 0, nil
+}
+
+// Throttled decreases the count, at most once per interval, and pauses access
+// for the period of retryAfter.
+func (a *adaptiveRateLimit) Throttled(retryAfter time.Duration) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.mThrottled.Incr(1)
+	a.throttled = true
+
+	now := time.Now()
+	if a.lastDecrease.IsZero() || now.Sub(a.lastDecrease) >= a.period {
+		if a.count = int(float64(a.count) * a.decreaseFactor); a.count < a.minCount {
+			a.count = a.minCount
+		}
+		if a.bucket > a.count {
+			a.bucket = a.count
+		}
+		a.lastDecrease = now
+		a.mCount.Set(int64(a.count))
+	}
+
+	if retryAfter > 0 && a.maxRetryAfter > 0 {
+		if retryAfter > a.maxRetryAfter {
+			retryAfter = a.maxRetryAfter
+		}
+		if until := now.Add(retryAfter); until.After(a.pausedUntil) {
+			a.pausedUntil = until
+		}
+	}
+}
+
+// Succeeded marks the current interval as successful, which allows the count
+// to increase at the next refresh.
+func (a *adaptiveRateLimit) Succeeded() {
+	a.mut.Lock()
+	a.succeeded = true
+	a.mut.Unlock()
+}
+
+func (a *adaptiveRateLimit) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveRateLimitConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		config      string
+		errContains string
+	}{
+		"bad count": {
+			config:      `count: 0`,
+			errContains: "count must be larger than zero",
+		},
+		"bad min count": {
+			config: `
+count: 10
+min_count: 20
+`,
+			errContains: "min_count must be larger than zero",
+		},
+		"bad interval": {
+			config:      `interval: nope`,
+			errContains: "failed to parse interval",
+		},
+		"bad decrease factor": {
+			config:      `decrease_factor: 1.5`,
+			errContains: "decrease_factor must be between zero and one",
+		},
+		"bad max retry after": {
+			config:      `max_retry_after: nope`,
+			errContains: "failed to parse max_retry_after",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := adaptiveRateLimitConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newAdaptiveRateLimitFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestAdaptiveRateLimitAdapts(t *testing.T) {
+	conf, err := adaptiveRateLimitConfig().ParseYAML(`
+count: 10
+min_count: 2
+interval: 10ms
+decrease_factor: 0.5
+increase_step: 3
+`, nil)
+	require.NoError(t, err)
+
+	rl, err := newAdaptiveRateLimitFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	accessCount := func() int {
+		n := 0
+		for {
+			tout, err := rl.Access(ctx)
+			require.NoError(t, err)
+			if tout > 0 {
+				return n
+			}
+			n++
+		}
+	}
+
+	assert.Equal(t, 10, accessCount())
+
+	// Multiple rejections within an interval only decrease the count once.
+	rl.Throttled(0)
+	rl.Throttled(0)
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 5, accessCount())
+
+	rl.Throttled(0)
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 2, accessCount())
+
+	rl.Throttled(0)
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 2, accessCount())
+
+	// Successes within an interval that also has rejections don't increase the
+	// count.
+	rl.Succeeded()
+	rl.Throttled(0)
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 2, accessCount())
+
+	rl.Succeeded()
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 5, accessCount())
+
+	rl.Succeeded()
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 8, accessCount())
+
+	rl.Succeeded()
+	<-time.After(time.Millisecond * 15)
+	assert.Equal(t, 10, accessCount())
+}
+
+func TestAdaptiveRateLimitRetryAfter(t *testing.T) {
+	conf, err := adaptiveRateLimitConfig().ParseYAML(`
+count: 10
+interval: 10ms
+max_retry_after: 1s
+`, nil)
+	require.NoError(t, err)
+
+	rl, err := newAdaptiveRateLimitFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	rl.Throttled(time.Hour)
+
+	tout, err := rl.Access(context.Background())
+	require.NoError(t, err)
+	assert.Greater(t, int64(tout), int64(time.Millisecond*900))
+	assert.LessOrEqual(t, int64(tout), int64(time.Second))
+}
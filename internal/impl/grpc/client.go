@@ -0,0 +1,334 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+
+	// nolint:staticcheck // Ignore SA1019 deprecation warning until we can switch to "google.golang.org/protobuf/types/dynamicpb"
+	"github.com/golang/protobuf/jsonpb"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	grpcClientAddressField = service.NewStringField("address").
+				Description("The address of the server to connect to, which can be any target supported by gRPC name resolution.").
+				Example("localhost:50051").
+				Example("dns:///api.example.com:443")
+
+	grpcClientMethodField = service.NewStringField("method").
+				Description("The fully qualified name of the method to call, in the form `package.Service/Method`.").
+				Example("acme.telemetry.v1.Collector/Collect")
+
+	grpcClientImportPathsField = service.NewStringListField("import_paths").
+					Description("A list of directories containing .proto files, including all definitions required for parsing the service of the method. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported. This field is ignored when `descriptor_set_file` is set.").
+					Default([]string{})
+
+	grpcClientDescriptorSetField = service.NewStringField("descriptor_set_file").
+					Description("An optional path of a binary `FileDescriptorSet` containing the service of the method along with all of its imports, as produced by `protoc --include_imports --descriptor_set_out` or `buf build`. When set the .proto files of `import_paths` are not parsed.").
+					Example("./protos.binpb").
+					Advanced().
+					Optional()
+
+	grpcClientPayloadFormatField = service.NewStringAnnotatedEnumField("payload_format", map[string]string{
+		"json":     "Messages are the JSON mapping of requests and responses.",
+		"protobuf": "Messages are serialised protobuf requests and responses.",
+	}).
+		Description("The format of messages, which are converted into requests and from responses in this format.").
+		Advanced().
+		Default("json")
+
+	grpcClientMetadataField = service.NewStringMapField("metadata").
+				Description("A map of metadata to send with each call. The values of this field support [interpolation functions](/docs/configuration/interpolation#bloblang-queries).").
+				Example(map[string]string{"authorization": "Bearer ${! env(\"API_TOKEN\") }"}).
+				Default(map[string]string{})
+
+	grpcClientTimeoutField = service.NewDurationField("timeout").
+				Description("The maximum period to wait for a call to complete.").
+				Default("5s")
+
+	grpcClientTLSField = service.NewTLSToggledField("tls")
+)
+
+// grpcClient calls a method of a remote service, converting messages into
+// requests and responses into messages.
+type grpcClient struct {
+	address    string
+	methodName string
+	method     *desc.MethodDescriptor
+	metadata   map[string]*service.InterpolatedString
+	timeout    time.Duration
+	tlsConf    *tls.Config
+
+	toRequest    func(b []byte) (*dynamic.Message, error)
+	fromResponse func(res *dynamic.Message) ([]byte, error)
+
+	connMut sync.RWMutex
+	conn    *grpc.ClientConn
+}
+
+func grpcClientFromConfig(conf *service.ParsedConfig) (*grpcClient, error) {
+	c := &grpcClient{}
+
+	var err error
+	if c.address, err = conf.FieldString("address"); err != nil {
+		return nil, err
+	}
+	if c.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	var tlsEnabled bool
+	if c.tlsConf, tlsEnabled, err = conf.FieldTLSToggled("tls"); err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		c.tlsConf = nil
+	}
+
+	metadataStrs, err := conf.FieldStringMap("metadata")
+	if err != nil {
+		return nil, err
+	}
+	c.metadata = map[string]*service.InterpolatedString{}
+	for k, v := range metadataStrs {
+		if c.metadata[strings.ToLower(k)], err = service.NewInterpolatedString(v); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata %v expression: %v", k, err)
+		}
+	}
+
+	var fds []*desc.FileDescriptor
+	if conf.Contains("descriptor_set_file") {
+		descriptorSetFile, err := conf.FieldString("descriptor_set_file")
+		if err != nil {
+			return nil, err
+		}
+		if fds, err = loadDescriptorSet(descriptorSetFile); err != nil {
+			return nil, err
+		}
+	} else {
+		importPaths, err := conf.FieldStringList("import_paths")
+		if err != nil {
+			return nil, err
+		}
+		if fds, err = loadDescriptors(importPaths); err != nil {
+			return nil, err
+		}
+	}
+
+	methodStr, err := conf.FieldString("method")
+	if err != nil {
+		return nil, err
+	}
+	methodParts := strings.Split(strings.TrimPrefix(methodStr, "/"), "/")
+	if len(methodParts) != 2 || methodParts[0] == "" || methodParts[1] == "" {
+		return nil, fmt.Errorf("method '%v' must be of the form package.Service/Method", methodStr)
+	}
+	serviceName, methodName := methodParts[0], methodParts[1]
+	sd := findService(serviceName, fds)
+	if sd == nil {
+		return nil, fmt.Errorf("unable to find service '%v' definition", serviceName)
+	}
+	if c.method = sd.FindMethodByName(methodName); c.method == nil {
+		return nil, fmt.Errorf("unable to find method '%v' of service '%v'", methodName, serviceName)
+	}
+	c.methodName = "/" + serviceName + "/" + methodName
+
+	payloadFormat, err := conf.FieldString("payload_format")
+	if err != nil {
+		return nil, err
+	}
+	switch payloadFormat {
+	case "json":
+		resolver := dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fds...)
+		unmarshaler := &jsonpb.Unmarshaler{AnyResolver: resolver}
+		marshaller := &jsonpb.Marshaler{AnyResolver: resolver}
+		c.toRequest = func(b []byte) (*dynamic.Message, error) {
+			req := dynamic.NewMessage(c.method.GetInputType())
+			if err := req.UnmarshalJSONPB(unmarshaler, b); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal JSON message: %w", err)
+			}
+			return req, nil
+		}
+		c.fromResponse = func(res *dynamic.Message) ([]byte, error) {
+			return res.MarshalJSONPB(marshaller)
+		}
+	case "protobuf":
+		c.toRequest = func(b []byte) (*dynamic.Message, error) {
+			req := dynamic.NewMessage(c.method.GetInputType())
+			if err := req.Unmarshal(b); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+			}
+			return req, nil
+		}
+		c.fromResponse = func(res *dynamic.Message) ([]byte, error) {
+			return res.Marshal()
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised payload_format: %v", payloadFormat)
+	}
+	return c, nil
+}
+
+func (c *grpcClient) connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if c.tlsConf != nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(c.tlsConf))}
+	}
+
+	conn, err := grpc.DialContext(ctx, c.address, opts...)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// callContext returns a context for a call with the metadata of a message.
+func (c *grpcClient) callContext(ctx context.Context, msg *service.Message) (context.Context, context.CancelFunc) {
+	if len(c.metadata) > 0 {
+		pairs := make([]string, 0, len(c.metadata)*2)
+		for k, v := range c.metadata {
+			pairs = append(pairs, k, v.String(msg))
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	}
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// invoke calls a unary or server streaming method with a message as the
+// request, and returns the contents of each response.
+func (c *grpcClient) invoke(ctx context.Context, msg *service.Message) ([][]byte, error) {
+	c.connMut.RLock()
+	conn := c.conn
+	c.connMut.RUnlock()
+	if conn == nil {
+		return nil, service.ErrNotConnected
+	}
+
+	b, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.toRequest(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := c.callContext(ctx, msg)
+	defer done()
+
+	if !c.method.IsServerStreaming() {
+		res := dynamic.NewMessage(c.method.GetOutputType())
+		if err := conn.Invoke(ctx, c.methodName, req, res); err != nil {
+			return nil, err
+		}
+		resBytes, err := c.fromResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{resBytes}, nil
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, c.methodName)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var results [][]byte
+	for {
+		res := dynamic.NewMessage(c.method.GetOutputType())
+		if err := stream.RecvMsg(res); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		resBytes, err := c.fromResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, resBytes)
+	}
+	return results, nil
+}
+
+// invokeStream calls a client streaming method with each message of a batch as
+// a request of the stream.
+func (c *grpcClient) invokeStream(ctx context.Context, batch service.MessageBatch) error {
+	c.connMut.RLock()
+	conn := c.conn
+	c.connMut.RUnlock()
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	ctx, done := c.callContext(ctx, batch[0])
+	defer done()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, c.methodName)
+	if err != nil {
+		return err
+	}
+	for _, msg := range batch {
+		b, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+		req, err := c.toRequest(b)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(req); err != nil {
+			// The status of the call is only returned by RecvMsg.
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	return stream.RecvMsg(dynamic.NewMessage(c.method.GetOutputType()))
+}
+
+func (c *grpcClient) close() error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,480 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+
+	// nolint:staticcheck // Ignore SA1019 deprecation warning until we can switch to "google.golang.org/protobuf/types/dynamicpb"
+	"github.com/golang/protobuf/jsonpb"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func grpcServerInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Network").
+		Version("3.64.0").
+		Summary("Receive messages delivered over gRPC by unary calls and client streams.").
+		Description(`
+This input runs a gRPC server exposing a service, where each request of a unary call or a client stream is consumed as a message. A call is only responded to once its messages have been acknowledged, where the response is an empty message of the response type of the method. When a message is rejected the call fails with the status ` + "`UNAVAILABLE`" + `, which indicates that the client can safely retry it.
+
+### Services
+
+When ` + "`service`" + ` is omitted a generic service ` + "`" + ingestServiceName + "`" + ` is exposed with the following definition:
+
+` + "```protobuf" + `
+` + ingestServiceDef + "```" + `
+
+Where the value of each ` + "`google.protobuf.BytesValue`" + ` request is consumed as the raw contents of a message, and each ` + "`google.protobuf.Struct`" + ` request is consumed as a JSON document.
+
+Alternatively, a service defined within the .proto files found in ` + "`import_paths`" + ` can be exposed by setting ` + "`service`" + ` to its fully qualified name. The requests of its methods are consumed in the format of ` + "`payload_format`" + `, which is either the JSON mapping of the request or the request in its serialised protobuf form. Methods that stream responses are not supported.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- grpc_method
+- All request metadata (only the first value of each key)
+` + "```" + `
+
+Where ` + "`grpc_method`" + ` is the full name of the method called, of the form ` + "`/package.Service/Method`" + `.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringField("address").
+			Description("The address to listen from.").
+			Default("0.0.0.0:50051")).
+		Field(service.NewStringField("service").
+			Description("The fully qualified name of a service defined within the .proto files of `import_paths` to expose. When omitted the generic service `" + ingestServiceName + "` is exposed.").
+			Example("acme.telemetry.v1.Collector").
+			Optional()).
+		Field(service.NewStringListField("import_paths").
+			Description("A list of directories containing .proto files, including all definitions required for parsing the service. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported. This field is only used when `service` is set.").
+			Default([]string{})).
+		Field(service.NewStringAnnotatedEnumField("payload_format", map[string]string{
+			"json":     "Consume requests as their JSON mapping.",
+			"protobuf": "Consume requests in their serialised protobuf form.",
+		}).
+			Description("The format to consume requests of the methods of `service` in.").
+			Advanced().
+			Default("json")).
+		Field(service.NewStringField("cert_file").
+			Description("An optional certificate file for enabling TLS.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField("key_file").
+			Description("An optional key file for enabling TLS.").
+			Advanced().
+			Default(""))
+}
+
+func init() {
+	err := service.RegisterInput(
+		"grpc_server", grpcServerInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newGRPCServerInputFromConfig(conf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// grpcServerMethod is a method of the exposed service along with the means of
+// converting its requests into message contents.
+type grpcServerMethod struct {
+	fullName string
+	input    *desc.MessageDescriptor
+	output   *desc.MessageDescriptor
+	toBytes  func(req *dynamic.Message) ([]byte, error)
+}
+
+type grpcServerMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type grpcServerInput struct {
+	address  string
+	certFile string
+	keyFile  string
+	svcDesc  grpc.ServiceDesc
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	server   *grpc.Server
+	listener net.Listener
+
+	msgChan chan grpcServerMessage
+	shutSig *shutdown.Signaller
+}
+
+func newGRPCServerInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*grpcServerInput, error) {
+	g := &grpcServerInput{
+		log:     log,
+		msgChan: make(chan grpcServerMessage),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if g.address, err = conf.FieldString("address"); err != nil {
+		return nil, err
+	}
+	if g.certFile, err = conf.FieldString("cert_file"); err != nil {
+		return nil, err
+	}
+	if g.keyFile, err = conf.FieldString("key_file"); err != nil {
+		return nil, err
+	}
+	if (g.certFile == "") != (g.keyFile == "") {
+		return nil, errors.New("both cert_file and key_file must be specified in order to enable TLS")
+	}
+
+	var sd *desc.ServiceDescriptor
+	var methods []*grpcServerMethod
+	if conf.Contains("service") {
+		serviceName, err := conf.FieldString("service")
+		if err != nil {
+			return nil, err
+		}
+		importPaths, err := conf.FieldStringList("import_paths")
+		if err != nil {
+			return nil, err
+		}
+		payloadFormat, err := conf.FieldString("payload_format")
+		if err != nil {
+			return nil, err
+		}
+
+		fds, err := loadDescriptors(importPaths)
+		if err != nil {
+			return nil, err
+		}
+		if sd = findService(serviceName, fds); sd == nil {
+			return nil, fmt.Errorf("unable to find service '%v' definition within '%v'", serviceName, importPaths)
+		}
+
+		var toBytes func(req *dynamic.Message) ([]byte, error)
+		switch payloadFormat {
+		case "json":
+			marshaller := &jsonpb.Marshaler{
+				AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fds...),
+			}
+			toBytes = func(req *dynamic.Message) ([]byte, error) {
+				return req.MarshalJSONPB(marshaller)
+			}
+		case "protobuf":
+			toBytes = func(req *dynamic.Message) ([]byte, error) {
+				return req.Marshal()
+			}
+		default:
+			return nil, fmt.Errorf("unrecognised payload_format: %v", payloadFormat)
+		}
+
+		for _, md := range sd.GetMethods() {
+			if md.IsServerStreaming() {
+				return nil, fmt.Errorf("method %v streams responses, which is not supported", md.GetFullyQualifiedName())
+			}
+			methods = append(methods, &grpcServerMethod{
+				fullName: "/" + sd.GetFullyQualifiedName() + "/" + md.GetName(),
+				input:    md.GetInputType(),
+				output:   md.GetOutputType(),
+				toBytes:  toBytes,
+			})
+		}
+	} else {
+		if sd, err = ingestServiceDescriptor(); err != nil {
+			return nil, err
+		}
+		marshaller := &jsonpb.Marshaler{}
+		for _, md := range sd.GetMethods() {
+			m := &grpcServerMethod{
+				fullName: "/" + sd.GetFullyQualifiedName() + "/" + md.GetName(),
+				input:    md.GetInputType(),
+				output:   md.GetOutputType(),
+			}
+			if md.GetInputType().GetFullyQualifiedName() == "google.protobuf.BytesValue" {
+				m.toBytes = func(req *dynamic.Message) ([]byte, error) {
+					b, _ := req.GetFieldByName("value").([]byte)
+					return b, nil
+				}
+			} else {
+				m.toBytes = func(req *dynamic.Message) ([]byte, error) {
+					return req.MarshalJSONPB(marshaller)
+				}
+			}
+			methods = append(methods, m)
+		}
+	}
+
+	g.svcDesc = grpc.ServiceDesc{
+		ServiceName: sd.GetFullyQualifiedName(),
+		HandlerType: (*interface{})(nil),
+		Metadata:    sd.GetFile().GetName(),
+	}
+	for i, md := range sd.GetMethods() {
+		if md.IsClientStreaming() {
+			g.svcDesc.Streams = append(g.svcDesc.Streams, grpc.StreamDesc{
+				StreamName:    md.GetName(),
+				Handler:       g.streamHandler(methods[i]),
+				ClientStreams: true,
+			})
+		} else {
+			g.svcDesc.Methods = append(g.svcDesc.Methods, grpc.MethodDesc{
+				MethodName: md.GetName(),
+				Handler:    g.unaryHandler(methods[i]),
+			})
+		}
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func contextStatusErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+	}
+	return status.Error(codes.Canceled, ctx.Err().Error())
+}
+
+func nackStatusErr(err error) error {
+	return status.Errorf(codes.Unavailable, "failed to deliver message: %v", err)
+}
+
+var errShuttingDown = status.Error(codes.Unavailable, "server is shutting down")
+
+func (g *grpcServerInput) toMessage(ctx context.Context, m *grpcServerMethod, req *dynamic.Message) (*service.Message, error) {
+	b, err := m.toBytes(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to convert request: %v", err)
+	}
+
+	msg := service.NewMessage(b)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range md {
+			if len(v) > 0 {
+				msg.MetaSet(k, v[0])
+			}
+		}
+	}
+	msg.MetaSet("grpc_method", m.fullName)
+	return msg, nil
+}
+
+func (g *grpcServerInput) unaryHandler(m *grpcServerMethod) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := dynamic.NewMessage(m.input)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			msg, err := g.toMessage(ctx, m, req.(*dynamic.Message))
+			if err != nil {
+				return nil, err
+			}
+
+			resChan := make(chan error, 1)
+			select {
+			case g.msgChan <- grpcServerMessage{
+				msg: msg,
+				ackFn: func(ctx context.Context, err error) error {
+					resChan <- err
+					return nil
+				},
+			}:
+			case <-ctx.Done():
+				return nil, contextStatusErr(ctx)
+			case <-g.shutSig.CloseAtLeisureChan():
+				return nil, errShuttingDown
+			}
+
+			select {
+			case err := <-resChan:
+				if err != nil {
+					return nil, nackStatusErr(err)
+				}
+			case <-ctx.Done():
+				return nil, contextStatusErr(ctx)
+			}
+			return dynamic.NewMessage(m.output), nil
+		}
+
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: m.fullName,
+		}, handler)
+	}
+}
+
+func (g *grpcServerInput) streamHandler(m *grpcServerMethod) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		ctx := stream.Context()
+
+		var pending sync.WaitGroup
+		var nackOnce sync.Once
+		var nackErr error
+		nackChan := make(chan struct{})
+
+		ackFn := func(ctx context.Context, err error) error {
+			if err != nil {
+				nackOnce.Do(func() {
+					nackErr = err
+					close(nackChan)
+				})
+			}
+			pending.Done()
+			return nil
+		}
+
+	recvLoop:
+		for {
+			req := dynamic.NewMessage(m.input)
+			if err := stream.RecvMsg(req); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return err
+			}
+
+			msg, err := g.toMessage(ctx, m, req)
+			if err != nil {
+				return err
+			}
+
+			pending.Add(1)
+			select {
+			case g.msgChan <- grpcServerMessage{msg: msg, ackFn: ackFn}:
+			case <-nackChan:
+				pending.Done()
+				break recvLoop
+			case <-ctx.Done():
+				pending.Done()
+				return contextStatusErr(ctx)
+			case <-g.shutSig.CloseAtLeisureChan():
+				pending.Done()
+				return errShuttingDown
+			}
+		}
+
+		// The response is only sent once all messages of the stream have been
+		// acknowledged.
+		pendingChan := make(chan struct{})
+		go func() {
+			pending.Wait()
+			close(pendingChan)
+		}()
+		select {
+		case <-pendingChan:
+		case <-nackChan:
+		case <-ctx.Done():
+			return contextStatusErr(ctx)
+		}
+
+		select {
+		case <-nackChan:
+			return nackStatusErr(nackErr)
+		default:
+		}
+		return stream.SendMsg(dynamic.NewMessage(m.output))
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (g *grpcServerInput) Connect(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.server != nil {
+		return nil
+	}
+	if g.shutSig.ShouldCloseAtLeisure() {
+		return service.ErrEndOfInput
+	}
+
+	var opts []grpc.ServerOption
+	if g.certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(g.certFile, g.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS credentials: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", g.address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&g.svcDesc, struct{}{})
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			g.log.Errorf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	g.server, g.listener = server, listener
+	g.log.Infof("Receiving gRPC calls to service %v at: %v", g.svcDesc.ServiceName, listener.Addr())
+	return nil
+}
+
+func (g *grpcServerInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case m := <-g.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-g.shutSig.CloseAtLeisureChan():
+		return nil, nil, service.ErrEndOfInput
+	}
+}
+
+func (g *grpcServerInput) Close(ctx context.Context) error {
+	g.shutSig.CloseAtLeisure()
+
+	g.connMut.Lock()
+	server := g.server
+	g.server = nil
+	g.connMut.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+	return nil
+}
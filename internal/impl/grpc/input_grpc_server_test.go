@@ -0,0 +1,241 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func readGRPCServerMessage(t *testing.T, g *grpcServerInput) (*service.Message, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := g.Read(ctx)
+	require.NoError(t, err)
+	return msg, ackFn
+}
+
+func TestGRPCServerUnary(t *testing.T) {
+	conf, err := grpcServerInputConfig().ParseYAML(`address: 127.0.0.1:0`, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	defer g.Close(context.Background())
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "foo", "bar")
+		errChan <- conn.Invoke(ctx, "/benthos.ingest.v1.Ingest/Send", wrapperspb.Bytes([]byte("hello world")), &emptypb.Empty{})
+	}()
+
+	msg, ackFn := readGRPCServerMessage(t, g)
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+
+	v, _ := msg.MetaGet("grpc_method")
+	assert.Equal(t, "/benthos.ingest.v1.Ingest/Send", v)
+	v, _ = msg.MetaGet("foo")
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	require.NoError(t, <-errChan)
+}
+
+func TestGRPCServerUnaryNack(t *testing.T) {
+	conf, err := grpcServerInputConfig().ParseYAML(`address: 127.0.0.1:0`, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	defer g.Close(context.Background())
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		req, _ := structpb.NewStruct(map[string]interface{}{"foo": "bar"})
+		errChan <- conn.Invoke(context.Background(), "/benthos.ingest.v1.Ingest/SendStruct", req, &emptypb.Empty{})
+	}()
+
+	msg, ackFn := readGRPCServerMessage(t, g)
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo":"bar"}`, string(b))
+
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+
+	err = <-errChan
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), "nope")
+}
+
+func TestGRPCServerClientStream(t *testing.T) {
+	conf, err := grpcServerInputConfig().ParseYAML(`address: 127.0.0.1:0`, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	defer g.Close(context.Background())
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, "/benthos.ingest.v1.Ingest/SendStream")
+	require.NoError(t, err)
+
+	resChan := make(chan error, 1)
+	go func() {
+		for _, v := range []string{"foo", "bar", "baz"} {
+			if err := stream.SendMsg(wrapperspb.Bytes([]byte(v))); err != nil {
+				resChan <- err
+				return
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			resChan <- err
+			return
+		}
+		resChan <- stream.RecvMsg(&emptypb.Empty{})
+	}()
+
+	var ackFns []service.AckFunc
+	for _, exp := range []string{"foo", "bar", "baz"} {
+		msg, ackFn := readGRPCServerMessage(t, g)
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+		ackFns = append(ackFns, ackFn)
+	}
+
+	// The stream is only responded to once all messages are acknowledged.
+	select {
+	case err := <-resChan:
+		t.Fatalf("Unexpected response: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	for _, ackFn := range ackFns {
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	require.NoError(t, <-resChan)
+}
+
+func TestGRPCServerCustomService(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "collector.proto"), []byte(`
+syntax = "proto3";
+
+package acme.v1;
+
+message Reading {
+  string sensor_id = 1;
+  double value = 2;
+}
+
+message Receipt {}
+
+service Collector {
+  rpc Collect(Reading) returns (Receipt);
+  rpc Watch(Reading) returns (stream Receipt);
+}
+`), 0644))
+
+	conf, err := grpcServerInputConfig().ParseYAML(`
+service: acme.v1.Collector
+import_paths: [ `+tmpDir+` ]
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newGRPCServerInputFromConfig(conf, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "streams responses")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "collector.proto"), []byte(`
+syntax = "proto3";
+
+package acme.v1;
+
+message Reading {
+  string sensor_id = 1;
+  double value = 2;
+}
+
+message Receipt {}
+
+service Collector {
+  rpc Collect(Reading) returns (Receipt);
+}
+`), 0644))
+
+	conf, err = grpcServerInputConfig().ParseYAML(`
+address: 127.0.0.1:0
+service: acme.v1.Collector
+import_paths: [ `+tmpDir+` ]
+`, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	defer g.Close(context.Background())
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fds, err := loadDescriptors([]string{tmpDir})
+	require.NoError(t, err)
+
+	req := dynamic.NewMessage(fds[0].FindMessage("acme.v1.Reading"))
+	req.SetFieldByName("sensor_id", "foo")
+	req.SetFieldByName("value", 1.5)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- conn.Invoke(context.Background(), "/acme.v1.Collector/Collect", req, dynamic.NewMessage(fds[0].FindMessage("acme.v1.Receipt")))
+	}()
+
+	msg, ackFn := readGRPCServerMessage(t, g)
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sensorId":"foo","value":1.5}`, string(b))
+
+	v, _ := msg.MetaGet("grpc_method")
+	assert.Equal(t, "/acme.v1.Collector/Collect", v)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	require.NoError(t, <-errChan)
+}
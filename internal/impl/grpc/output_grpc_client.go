@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func grpcClientOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Network").
+		Version("3.64.0").
+		Summary("Sends messages as requests of a method of a remote gRPC service.").
+		Description(output.Description(true, true, `
+The method is resolved from the .proto files found in `+"`import_paths`"+`, or from a compiled `+"`descriptor_set_file`"+`, and each message is converted into a request of the method in the format of `+"`payload_format`"+`, which is either the JSON mapping of the request or the request in its serialised protobuf form.
+
+When the method is unary, or streams responses, a call is made for each message of a batch and any responses are discarded. When the method is client streaming each batch is sent as the requests of a single stream, and the batch is only acknowledged once the stream has been responded to successfully. Methods that stream both requests and responses are not supported.
+
+The contents of `+"`metadata`"+` are sent with each call, or with each stream, where they are resolved from the first message of the batch.`)).
+		Field(grpcClientAddressField).
+		Field(grpcClientMethodField).
+		Field(grpcClientImportPathsField).
+		Field(grpcClientDescriptorSetField).
+		Field(grpcClientPayloadFormatField).
+		Field(grpcClientMetadataField).
+		Field(grpcClientTimeoutField).
+		Field(grpcClientTLSField).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching"))
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"grpc_client", grpcClientOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newGRPCClientOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type grpcClientOutput struct {
+	log    *service.Logger
+	client *grpcClient
+}
+
+func newGRPCClientOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*grpcClientOutput, error) {
+	client, err := grpcClientFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	if client.method.IsClientStreaming() && client.method.IsServerStreaming() {
+		return nil, fmt.Errorf("method '%v' streams both requests and responses, which is not supported", client.methodName)
+	}
+	return &grpcClientOutput{
+		log:    log,
+		client: client,
+	}, nil
+}
+
+func (g *grpcClientOutput) Connect(ctx context.Context) error {
+	if err := g.client.connect(ctx); err != nil {
+		return err
+	}
+	g.log.Infof("Sending messages as gRPC requests of %v to %v", g.client.methodName, g.client.address)
+	return nil
+}
+
+func (g *grpcClientOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if g.client.method.IsClientStreaming() {
+		return g.client.invokeStream(ctx, batch)
+	}
+
+	var batchErr *service.BatchError
+	for i, msg := range batch {
+		_, err := g.client.invoke(ctx, msg)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, service.ErrNotConnected) {
+			return err
+		}
+		if len(batch) == 1 {
+			return err
+		}
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, fmt.Errorf("failed to send requests: %w", err))
+		}
+		batchErr.Failed(i, err)
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (g *grpcClientOutput) Close(ctx context.Context) error {
+	return g.client.close()
+}
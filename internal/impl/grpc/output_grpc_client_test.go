@@ -0,0 +1,214 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// writeIngestProto writes the definition of the generic service of the
+// grpc_server input to a directory for use with import_paths.
+func writeIngestProto(t *testing.T) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	protoPath := filepath.Join(tmpDir, ingestServiceFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(protoPath), 0755))
+	require.NoError(t, os.WriteFile(protoPath, []byte(ingestServiceDef), 0644))
+	return tmpDir
+}
+
+// writeIngestDescriptorSet writes a descriptor set of the generic service of
+// the grpc_server input, including its imports.
+func writeIngestDescriptorSet(t *testing.T) string {
+	t.Helper()
+
+	sd, err := ingestServiceDescriptor()
+	require.NoError(t, err)
+
+	var fdSet descriptorpb.FileDescriptorSet
+	seen := map[string]struct{}{}
+	var addFile func(fd *desc.FileDescriptor)
+	addFile = func(fd *desc.FileDescriptor) {
+		if _, exists := seen[fd.GetName()]; exists {
+			return
+		}
+		seen[fd.GetName()] = struct{}{}
+		for _, dep := range fd.GetDependencies() {
+			addFile(dep)
+		}
+		fdSet.File = append(fdSet.File, fd.AsFileDescriptorProto())
+	}
+	addFile(sd.GetFile())
+
+	b, err := proto.Marshal(&fdSet)
+	require.NoError(t, err)
+
+	setPath := filepath.Join(t.TempDir(), "ingest.binpb")
+	require.NoError(t, os.WriteFile(setPath, b, 0644))
+	return setPath
+}
+
+func TestGRPCClientOutputUnary(t *testing.T) {
+	inConf, err := grpcServerInputConfig().ParseYAML(`address: 127.0.0.1:0`, nil)
+	require.NoError(t, err)
+
+	in, err := newGRPCServerInputFromConfig(inConf, nil)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+	defer in.Close(context.Background())
+
+	conf, err := grpcClientOutputConfig().ParseYAML(`
+address: `+in.listener.Addr().String()+`
+method: benthos.ingest.v1.Ingest/SendStruct
+import_paths: [ `+writeIngestProto(t)+` ]
+metadata:
+  foo: ${! meta("foo") }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newGRPCClientOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	defer out.Close(context.Background())
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"1"}`)),
+		service.NewMessage([]byte(`{"id":"2"}`)),
+	}
+	batch[0].MetaSet("foo", "first")
+	batch[1].MetaSet("foo", "second")
+
+	errChan := make(chan error, 1)
+	go func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		errChan <- out.WriteBatch(ctx, batch)
+	}()
+
+	for _, exp := range []struct {
+		content, foo string
+		err          error
+	}{
+		{content: `{"id":"1"}`, foo: "first"},
+		{content: `{"id":"2"}`, foo: "second", err: errors.New("nope")},
+	} {
+		msg, ackFn := readGRPCServerMessage(t, in)
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp.content, string(b))
+
+		v, _ := msg.MetaGet("foo")
+		assert.Equal(t, exp.foo, v)
+		v, _ = msg.MetaGet("grpc_method")
+		assert.Equal(t, "/benthos.ingest.v1.Ingest/SendStruct", v)
+
+		require.NoError(t, ackFn(context.Background(), exp.err))
+	}
+
+	err = <-errChan
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+}
+
+func TestGRPCClientOutputClientStream(t *testing.T) {
+	inConf, err := grpcServerInputConfig().ParseYAML(`address: 127.0.0.1:0`, nil)
+	require.NoError(t, err)
+
+	in, err := newGRPCServerInputFromConfig(inConf, nil)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+	defer in.Close(context.Background())
+
+	conf, err := grpcClientOutputConfig().ParseYAML(`
+address: `+in.listener.Addr().String()+`
+method: /benthos.ingest.v1.Ingest/SendStream
+descriptor_set_file: `+writeIngestDescriptorSet(t)+`
+payload_format: protobuf
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newGRPCClientOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	defer out.Close(context.Background())
+
+	var batch service.MessageBatch
+	for _, v := range []string{"foo", "bar"} {
+		b, err := proto.Marshal(wrapperspb.Bytes([]byte(v)))
+		require.NoError(t, err)
+		batch = append(batch, service.NewMessage(b))
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		errChan <- out.WriteBatch(ctx, batch)
+	}()
+
+	for _, exp := range []string{"foo", "bar"} {
+		msg, ackFn := readGRPCServerMessage(t, in)
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+
+	require.NoError(t, <-errChan)
+}
+
+func TestGRPCClientOutputBadConfig(t *testing.T) {
+	for name, test := range map[string]struct {
+		conf   string
+		errStr string
+	}{
+		"bad method": {
+			conf:   `method: benthos.ingest.v1.Ingest`,
+			errStr: "must be of the form",
+		},
+		"unknown service": {
+			conf:   `method: benthos.ingest.v1.Nope/Send`,
+			errStr: "unable to find service",
+		},
+		"unknown method": {
+			conf:   `method: benthos.ingest.v1.Ingest/Nope`,
+			errStr: "unable to find method",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := grpcClientOutputConfig().ParseYAML(`
+address: localhost:50051
+descriptor_set_file: `+writeIngestDescriptorSet(t)+`
+`+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newGRPCClientOutputFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+		})
+	}
+}
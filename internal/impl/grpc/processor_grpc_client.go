@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func grpcClientProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Integration").
+		Version("3.64.0").
+		Summary("Calls a method of a remote gRPC service with each message as the request, and replaces the message with the response.").
+		Description(`
+The method is resolved from the .proto files found in `+"`import_paths`"+`, or from a compiled `+"`descriptor_set_file`"+`, and each message is converted into a request of the method in the format of `+"`payload_format`"+`, which is either the JSON mapping of the request or the request in its serialised protobuf form. The contents of the message are then replaced with the response in the same format, and the metadata of the message is kept.
+
+When the method streams responses the message is replaced with a message for each response received, and is removed when no responses are received. Methods that stream requests are not supported.
+
+In order to enrich a message with the response rather than replacing it you can wrap this processor within a `+"[`branch` processor](/docs/components/processors/branch)"+`, which also allows the request to be constructed from parts of the message.
+
+### Error Handling
+
+When a call fails the message is left unchanged and flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Field(grpcClientAddressField).
+		Field(grpcClientMethodField).
+		Field(grpcClientImportPathsField).
+		Field(grpcClientDescriptorSetField).
+		Field(grpcClientPayloadFormatField).
+		Field(grpcClientMetadataField).
+		Field(grpcClientTimeoutField).
+		Field(grpcClientTLSField).
+		Example("Enrichment", `
+Look up the profile of the user of each document from a remote service, and add it to the document in the field `+"`profile`"+`:`,
+			`
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.user_id = this.user.id'
+        processors:
+          - grpc_client:
+              address: localhost:50051
+              method: acme.users.v1.Profiles/GetProfile
+              import_paths: [ ./protos ]
+        result_map: 'root.profile = this'
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"grpc_client", grpcClientProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newGRPCClientProcessorFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type grpcClientProcessor struct {
+	log    *service.Logger
+	client *grpcClient
+}
+
+func newGRPCClientProcessorFromConfig(conf *service.ParsedConfig, log *service.Logger) (*grpcClientProcessor, error) {
+	client, err := grpcClientFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	if client.method.IsClientStreaming() {
+		return nil, fmt.Errorf("method '%v' streams requests, which is not supported", client.methodName)
+	}
+	if err := client.connect(context.Background()); err != nil {
+		return nil, err
+	}
+	return &grpcClientProcessor{
+		log:    log,
+		client: client,
+	}, nil
+}
+
+func (g *grpcClientProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	results, err := g.client.invoke(ctx, msg)
+	if err != nil {
+		g.log.Debugf("Failed to call %v: %v", g.client.methodName, err)
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(results))
+	for _, res := range results {
+		resMsg := msg.Copy()
+		resMsg.SetBytes(res)
+		batch = append(batch, resMsg)
+	}
+	return batch, nil
+}
+
+func (g *grpcClientProcessor) Close(ctx context.Context) error {
+	return g.client.close()
+}
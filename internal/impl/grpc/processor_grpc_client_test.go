@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testProfilesDef = `syntax = "proto3";
+
+package acme.v1;
+
+message ProfileRequest {
+  string user_id = 1;
+}
+
+message Profile {
+  string user_id = 1;
+  string name = 2;
+  string tenant = 3;
+}
+
+service Profiles {
+  rpc GetProfile(ProfileRequest) returns (Profile);
+  rpc ListProfiles(ProfileRequest) returns (stream Profile);
+  rpc PutProfiles(stream Profile) returns (ProfileRequest);
+}
+`
+
+// testProfilesServer runs a server of the Profiles service, where the tenant
+// of each response is obtained from the metadata of the call.
+func testProfilesServer(t *testing.T) (addr, importPath string) {
+	t.Helper()
+
+	importPath = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(importPath, "profiles.proto"), []byte(testProfilesDef), 0644))
+
+	fds, err := loadDescriptors([]string{importPath})
+	require.NoError(t, err)
+	sd := findService("acme.v1.Profiles", fds)
+	require.NotNil(t, sd)
+
+	profile := func(ctx context.Context, req *dynamic.Message, name string) *dynamic.Message {
+		res := dynamic.NewMessage(sd.FindMethodByName("GetProfile").GetOutputType())
+		res.SetFieldByName("user_id", req.GetFieldByName("user_id"))
+		res.SetFieldByName("name", name)
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("tenant")) > 0 {
+			res.SetFieldByName("tenant", md.Get("tenant")[0])
+		}
+		return res
+	}
+
+	newReq := func(md *desc.MethodDescriptor) *dynamic.Message {
+		return dynamic.NewMessage(md.GetInputType())
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: sd.GetFullyQualifiedName(),
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "GetProfile",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := newReq(sd.FindMethodByName("GetProfile"))
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if req.GetFieldByName("user_id") == "" {
+					return nil, status.Error(codes.InvalidArgument, "user_id is required")
+				}
+				return profile(ctx, req, "Ash"), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "ListProfiles",
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				req := newReq(sd.FindMethodByName("ListProfiles"))
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				for i := 0; i < 3; i++ {
+					if err := stream.SendMsg(profile(stream.Context(), req, fmt.Sprintf("Ash %v", i))); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), importPath
+}
+
+func TestGRPCClientProcessorUnary(t *testing.T) {
+	addr, importPath := testProfilesServer(t)
+
+	conf, err := grpcClientProcessorConfig().ParseYAML(`
+address: `+addr+`
+method: acme.v1.Profiles/GetProfile
+import_paths: [ `+importPath+` ]
+metadata:
+  tenant: ${! meta("tenant") }
+`, nil)
+	require.NoError(t, err)
+
+	p, err := newGRPCClientProcessorFromConfig(conf, nil)
+	require.NoError(t, err)
+	defer p.Close(context.Background())
+
+	msg := service.NewMessage([]byte(`{"userId":"foo"}`))
+	msg.MetaSet("tenant", "acme")
+
+	batch, err := p.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"userId":"foo","name":"Ash","tenant":"acme"}`, string(b))
+
+	v, _ := batch[0].MetaGet("tenant")
+	assert.Equal(t, "acme", v)
+
+	// The original message is left unchanged.
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"userId":"foo"}`, string(b))
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = p.Process(context.Background(), service.NewMessage([]byte(`{"nope":"foo"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to unmarshal JSON message")
+}
+
+func TestGRPCClientProcessorServerStream(t *testing.T) {
+	addr, importPath := testProfilesServer(t)
+
+	conf, err := grpcClientProcessorConfig().ParseYAML(`
+address: `+addr+`
+method: acme.v1.Profiles/ListProfiles
+import_paths: [ `+importPath+` ]
+`, nil)
+	require.NoError(t, err)
+
+	p, err := newGRPCClientProcessorFromConfig(conf, nil)
+	require.NoError(t, err)
+	defer p.Close(context.Background())
+
+	batch, err := p.Process(context.Background(), service.NewMessage([]byte(`{"userId":"foo"}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	for i, msg := range batch {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, fmt.Sprintf(`{"userId":"foo","name":"Ash %v"}`, i), string(b))
+	}
+}
+
+func TestGRPCClientProcessorClientStream(t *testing.T) {
+	_, importPath := testProfilesServer(t)
+
+	conf, err := grpcClientProcessorConfig().ParseYAML(`
+address: localhost:50051
+method: acme.v1.Profiles/PutProfiles
+import_paths: [ `+importPath+` ]
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newGRPCClientProcessorFromConfig(conf, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "streams requests")
+}
@@ -0,0 +1,127 @@
+package grpc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The name and definition of the generic service exposed by the grpc_server
+// input when a service is not specified.
+const (
+	ingestServiceName = "benthos.ingest.v1.Ingest"
+	ingestServiceFile = "benthos/ingest/v1/ingest.proto"
+	ingestServiceDef  = `syntax = "proto3";
+
+package benthos.ingest.v1;
+
+import "google/protobuf/empty.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/wrappers.proto";
+
+service Ingest {
+  // Send delivers the value of the request as a message.
+  rpc Send(google.protobuf.BytesValue) returns (google.protobuf.Empty);
+
+  // SendStream delivers the value of each request of a stream as a message.
+  rpc SendStream(stream google.protobuf.BytesValue) returns (google.protobuf.Empty);
+
+  // SendStruct delivers the request as a JSON document.
+  rpc SendStruct(google.protobuf.Struct) returns (google.protobuf.Empty);
+
+  // SendStructStream delivers each request of a stream as a JSON document.
+  rpc SendStructStream(stream google.protobuf.Struct) returns (google.protobuf.Empty);
+}
+`
+)
+
+func ingestServiceDescriptor() (*desc.ServiceDescriptor, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			ingestServiceFile: ingestServiceDef,
+		}),
+	}
+	fds, err := parser.ParseFiles(ingestServiceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generic service definition: %w", err)
+	}
+	return fds[0].FindService(ingestServiceName), nil
+}
+
+// loadDescriptors parses all .proto files found by walking a list of import
+// paths, where the current directory is used when the list is empty.
+func loadDescriptors(importPaths []string) ([]*desc.FileDescriptor, error) {
+	var parser protoparse.Parser
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	} else {
+		parser.ImportPaths = importPaths
+	}
+
+	var files []string
+	for _, importPath := range importPaths {
+		if err := filepath.Walk(importPath, func(path string, info os.FileInfo, ferr error) error {
+			if ferr != nil || info.IsDir() {
+				return ferr
+			}
+			if filepath.Ext(info.Name()) == ".proto" {
+				rPath, ferr := filepath.Rel(importPath, path)
+				if ferr != nil {
+					return fmt.Errorf("failed to get relative path: %v", ferr)
+				}
+				files = append(files, rPath)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .proto file: %v", err)
+	}
+	if len(fds) == 0 {
+		return nil, fmt.Errorf("no .proto files were found in the paths '%v'", importPaths)
+	}
+	return fds, nil
+}
+
+// loadDescriptorSet reads a binary FileDescriptorSet, which must include the
+// imports of each file within it.
+func loadDescriptorSet(path string) ([]*desc.FileDescriptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+
+	var fdSet descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &fdSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %v", err)
+	}
+
+	fdMap, err := desc.CreateFileDescriptorsFromSet(&fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create descriptors from set: %v", err)
+	}
+
+	fds := make([]*desc.FileDescriptor, 0, len(fdSet.File))
+	for _, fdProto := range fdSet.File {
+		fds = append(fds, fdMap[fdProto.GetName()])
+	}
+	return fds, nil
+}
+
+func findService(name string, fds []*desc.FileDescriptor) *desc.ServiceDescriptor {
+	for _, fd := range fds {
+		if sd := fd.FindService(name); sd != nil {
+			return sd
+		}
+	}
+	return nil
+}
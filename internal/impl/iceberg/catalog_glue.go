@@ -0,0 +1,224 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/gofrs/uuid"
+)
+
+// icebergGlueCatalog accesses a table of the AWS Glue Data Catalog, where the
+// catalog references the current metadata file of the table, which is
+// replaced with each commit.
+type icebergGlueCatalog struct {
+	client    *glue.Glue
+	fileIO    *icebergFileIO
+	catalogID string
+	database  string
+	table     string
+}
+
+func newIcebergGlueCatalog(sess *session.Session, fileIO *icebergFileIO, catalogID, database, table string) *icebergGlueCatalog {
+	return &icebergGlueCatalog{
+		client:    glue.New(sess),
+		fileIO:    fileIO,
+		catalogID: catalogID,
+		database:  database,
+		table:     table,
+	}
+}
+
+func (c *icebergGlueCatalog) getTable(ctx context.Context) (*glue.TableData, error) {
+	input := &glue.GetTableInput{
+		DatabaseName: aws.String(c.database),
+		Name:         aws.String(c.table),
+	}
+	if c.catalogID != "" {
+		input.CatalogId = aws.String(c.catalogID)
+	}
+	res, err := c.client.GetTableWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table: %w", err)
+	}
+	return res.Table, nil
+}
+
+func (c *icebergGlueCatalog) loadTable(ctx context.Context) (*icebergTableMetadata, error) {
+	table, err := c.getTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tableType := aws.StringValue(table.Parameters["table_type"]); !strings.EqualFold(tableType, "iceberg") {
+		return nil, fmt.Errorf("table type %v is not an iceberg table", tableType)
+	}
+	location := aws.StringValue(table.Parameters["metadata_location"])
+	if location == "" {
+		return nil, errors.New("table does not reference a metadata location")
+	}
+
+	raw, err := c.fileIO.read(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table metadata: %w", err)
+	}
+	m, err := parseIcebergTableMetadata(raw)
+	if err != nil {
+		return nil, err
+	}
+	m.fileLocation = location
+	m.versionID = aws.StringValue(table.VersionId)
+	return m, nil
+}
+
+func (c *icebergGlueCatalog) commitSnapshot(ctx context.Context, base *icebergTableMetadata, snapshot *icebergSnapshot) (*icebergTableMetadata, error) {
+	raw, err := icebergApplySnapshot(base, snapshot, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	location := base.metadataLocation() + "/" + icebergNextMetadataFileName(base.fileLocation)
+	if err := c.fileIO.write(ctx, location, raw); err != nil {
+		return nil, fmt.Errorf("failed to write table metadata: %w", err)
+	}
+
+	table, err := c.getTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(table.Parameters["metadata_location"]) != base.fileLocation {
+		return nil, errIcebergCommitConflict
+	}
+
+	params := make(map[string]*string, len(table.Parameters)+1)
+	for k, v := range table.Parameters {
+		params[k] = v
+	}
+	params["metadata_location"] = aws.String(location)
+	params["previous_metadata_location"] = aws.String(base.fileLocation)
+
+	input := &glue.UpdateTableInput{
+		DatabaseName: aws.String(c.database),
+		VersionId:    table.VersionId,
+		TableInput: &glue.TableInput{
+			Name:              table.Name,
+			Description:       table.Description,
+			Owner:             table.Owner,
+			Parameters:        params,
+			PartitionKeys:     table.PartitionKeys,
+			Retention:         table.Retention,
+			StorageDescriptor: table.StorageDescriptor,
+			TableType:         table.TableType,
+		},
+	}
+	if c.catalogID != "" {
+		input.CatalogId = aws.String(c.catalogID)
+	}
+	if _, err := c.client.UpdateTableWithContext(ctx, input); err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == glue.ErrCodeConcurrentModificationException {
+			return nil, errIcebergCommitConflict
+		}
+		return nil, fmt.Errorf("failed to update table: %w", err)
+	}
+
+	m, err := parseIcebergTableMetadata(raw)
+	if err != nil {
+		return nil, err
+	}
+	m.fileLocation = location
+	return m, nil
+}
+
+// icebergNextMetadataFileName returns the name of the metadata file that
+// follows a metadata file, which are prefixed with an incrementing version.
+func icebergNextMetadataFileName(previous string) string {
+	version := 0
+	name := path.Base(previous)
+	if i := strings.Index(name, "-"); i > 0 {
+		version, _ = strconv.Atoi(name[:i])
+	}
+	return fmt.Sprintf("%05d-%v.metadata.json", version+1, uuid.Must(uuid.NewV4()))
+}
+
+// icebergApplySnapshot returns the raw table metadata of a table with a
+// snapshot added as the current snapshot of the main branch, preserving all
+// other fields of the metadata.
+func icebergApplySnapshot(base *icebergTableMetadata, snapshot *icebergSnapshot, now time.Time) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(base.raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse table metadata: %w", err)
+	}
+
+	appendTo := func(key string, v interface{}) error {
+		var list []json.RawMessage
+		if raw, exists := doc[key]; exists {
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return fmt.Errorf("failed to parse %v of table metadata: %w", key, err)
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		list = append(list, b)
+		if doc[key], err = json.Marshal(list); err != nil {
+			return err
+		}
+		return nil
+	}
+	set := func(key string, v interface{}) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		doc[key] = b
+		return nil
+	}
+
+	var lastUpdated int64
+	if raw, exists := doc["last-updated-ms"]; exists {
+		_ = json.Unmarshal(raw, &lastUpdated)
+	}
+
+	refs := map[string]map[string]interface{}{}
+	if raw, exists := doc["refs"]; exists {
+		if err := json.Unmarshal(raw, &refs); err != nil {
+			return nil, fmt.Errorf("failed to parse refs of table metadata: %w", err)
+		}
+	}
+	mainRef := refs["main"]
+	if mainRef == nil {
+		mainRef = map[string]interface{}{"type": "branch"}
+	}
+	mainRef["snapshot-id"] = snapshot.SnapshotID
+	refs["main"] = mainRef
+
+	for _, err := range []error{
+		appendTo("snapshots", snapshot),
+		appendTo("snapshot-log", map[string]interface{}{
+			"timestamp-ms": snapshot.TimestampMs,
+			"snapshot-id":  snapshot.SnapshotID,
+		}),
+		appendTo("metadata-log", map[string]interface{}{
+			"timestamp-ms":  lastUpdated,
+			"metadata-file": base.fileLocation,
+		}),
+		set("refs", refs),
+		set("current-snapshot-id", snapshot.SnapshotID),
+		set("last-sequence-number", snapshot.SequenceNumber),
+		set("last-updated-ms", now.UnixNano()/int64(time.Millisecond)),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,229 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// icebergRESTCatalog accesses a table of a catalog that implements the Iceberg
+// REST catalog API.
+type icebergRESTCatalog struct {
+	uri        string
+	warehouse  string
+	credential string
+	scope      string
+	namespace  []string
+	table      string
+	http       *http.Client
+
+	mut    sync.Mutex
+	token  string
+	prefix string
+	loaded bool
+}
+
+func (c *icebergRESTCatalog) url(parts ...string) string {
+	path := c.uri + "/v1"
+	if c.prefix != "" {
+		path += "/" + c.prefix
+	}
+	for _, p := range parts {
+		path += "/" + url.PathEscape(p)
+	}
+	return path
+}
+
+// init obtains an OAuth2 token when credentials are configured, and the
+// configuration of the catalog for the warehouse.
+func (c *icebergRESTCatalog) init(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.loaded {
+		return nil
+	}
+
+	if c.credential != "" {
+		id, secret := "", c.credential
+		if i := strings.Index(c.credential, ":"); i >= 0 {
+			id, secret = c.credential[:i], c.credential[i+1:]
+		}
+		form := url.Values{
+			"grant_type":    []string{"client_credentials"},
+			"client_id":     []string{id},
+			"client_secret": []string{secret},
+			"scope":         []string{c.scope},
+		}
+		var res struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err := c.do(ctx, "POST", c.uri+"/v1/oauth/tokens", "application/x-www-form-urlencoded", []byte(form.Encode()), &res); err != nil {
+			return fmt.Errorf("failed to obtain catalog token: %w", err)
+		}
+		c.token = res.AccessToken
+	}
+
+	configURL := c.uri + "/v1/config"
+	if c.warehouse != "" {
+		configURL += "?" + url.Values{"warehouse": []string{c.warehouse}}.Encode()
+	}
+	var config struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := c.do(ctx, "GET", configURL, "", nil, &config); err != nil {
+		return fmt.Errorf("failed to obtain catalog config: %w", err)
+	}
+	c.prefix = config.Defaults["prefix"]
+	if p, exists := config.Overrides["prefix"]; exists {
+		c.prefix = p
+	}
+	c.loaded = true
+	return nil
+}
+
+type icebergRESTError struct {
+	status  int
+	message string
+}
+
+func (e *icebergRESTError) Error() string {
+	return fmt.Sprintf("request failed with status %v: %v", e.status, e.message)
+}
+
+func (c *icebergRESTCatalog) do(ctx context.Context, method, reqURL, contentType string, body []byte, v interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var errRes struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		msg := string(bytes.TrimSpace(resBody))
+		if err := json.Unmarshal(resBody, &errRes); err == nil && errRes.Error.Message != "" {
+			msg = errRes.Error.Type + ": " + errRes.Error.Message
+		}
+		return &icebergRESTError{status: res.StatusCode, message: msg}
+	}
+	if v != nil {
+		if err := json.Unmarshal(resBody, v); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *icebergRESTCatalog) tableURL() string {
+	return c.url("namespaces", strings.Join(c.namespace, "\x1f"), "tables", c.table)
+}
+
+func (c *icebergRESTCatalog) loadTable(ctx context.Context) (*icebergTableMetadata, error) {
+	if err := c.init(ctx); err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := c.do(ctx, "GET", c.tableURL(), "", nil, &res); err != nil {
+		return nil, fmt.Errorf("failed to load table: %w", err)
+	}
+	return parseIcebergTableMetadata(res.Metadata)
+}
+
+func (c *icebergRESTCatalog) commitSnapshot(ctx context.Context, base *icebergTableMetadata, snapshot *icebergSnapshot) (*icebergTableMetadata, error) {
+	if err := c.init(ctx); err != nil {
+		return nil, err
+	}
+
+	var parentID interface{}
+	if parent := base.currentSnapshot(); parent != nil {
+		parentID = parent.SnapshotID
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"requirements": []interface{}{
+			map[string]interface{}{
+				"type": "assert-table-uuid",
+				"uuid": base.TableUUID,
+			},
+			map[string]interface{}{
+				"type":        "assert-ref-snapshot-id",
+				"ref":         "main",
+				"snapshot-id": parentID,
+			},
+		},
+		"updates": []interface{}{
+			map[string]interface{}{
+				"action":   "add-snapshot",
+				"snapshot": snapshot,
+			},
+			map[string]interface{}{
+				"action":      "set-snapshot-ref",
+				"ref-name":    "main",
+				"type":        "branch",
+				"snapshot-id": snapshot.SnapshotID,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := c.do(ctx, "POST", c.tableURL(), "application/json", reqBody, &res); err != nil {
+		if restErr, ok := err.(*icebergRESTError); ok && restErr.status == http.StatusConflict {
+			return nil, errIcebergCommitConflict
+		}
+		return nil, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return parseIcebergTableMetadata(res.Metadata)
+}
+
+func newIcebergRESTCatalog(uri, warehouse, token, credential, scope string, namespace []string, table string) *icebergRESTCatalog {
+	return &icebergRESTCatalog{
+		uri:        strings.TrimSuffix(uri, "/"),
+		warehouse:  warehouse,
+		token:      token,
+		credential: credential,
+		scope:      scope,
+		namespace:  namespace,
+		table:      table,
+		http:       &http.Client{Timeout: time.Minute},
+	}
+}
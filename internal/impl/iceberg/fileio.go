@@ -0,0 +1,157 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// icebergFileIO reads and writes the files of a table, where the storage
+// service is determined by the scheme of each path.
+type icebergFileIO struct {
+	sess *session.Session
+
+	mut      sync.Mutex
+	s3       *s3.S3
+	uploader *s3manager.Uploader
+	gcs      *storage.Client
+}
+
+func newIcebergFileIO(sess *session.Session) *icebergFileIO {
+	return &icebergFileIO{sess: sess}
+}
+
+func (f *icebergFileIO) s3Clients() (*s3.S3, *s3manager.Uploader, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.sess == nil {
+		return nil, nil, fmt.Errorf("an AWS session is required in order to access s3 paths")
+	}
+	if f.s3 == nil {
+		f.s3 = s3.New(f.sess)
+		f.uploader = s3manager.NewUploaderWithClient(f.s3)
+	}
+	return f.s3, f.uploader, nil
+}
+
+func (f *icebergFileIO) gcsClient(ctx context.Context) (*storage.Client, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.gcs == nil {
+		var err error
+		if f.gcs, err = storage.NewClient(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return f.gcs, nil
+}
+
+func splitObjectPath(u *url.URL) (bucket, key string) {
+	return u.Host, strings.TrimPrefix(u.Path, "/")
+}
+
+func (f *icebergFileIO) write(ctx context.Context, path string, data []byte) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse path '%v': %w", path, err)
+	}
+
+	switch u.Scheme {
+	case "s3", "s3a", "s3n":
+		_, uploader, err := f.s3Clients()
+		if err != nil {
+			return err
+		}
+		bucket, key := splitObjectPath(u)
+		_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(data),
+		})
+		return err
+	case "gs":
+		client, err := f.gcsClient(ctx)
+		if err != nil {
+			return err
+		}
+		bucket, key := splitObjectPath(u)
+		w := client.Bucket(bucket).Object(key).NewWriter(ctx)
+		if _, err := w.Write(data); err != nil {
+			_ = w.Close()
+			return err
+		}
+		return w.Close()
+	case "file", "":
+		if err := os.MkdirAll(filepath.Dir(u.Path), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(u.Path, data, 0o644)
+	}
+	return fmt.Errorf("path scheme '%v' is not supported", u.Scheme)
+}
+
+func (f *icebergFileIO) read(ctx context.Context, path string) ([]byte, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse path '%v': %w", path, err)
+	}
+
+	switch u.Scheme {
+	case "s3", "s3a", "s3n":
+		client, _, err := f.s3Clients()
+		if err != nil {
+			return nil, err
+		}
+		bucket, key := splitObjectPath(u)
+		obj, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer obj.Body.Close()
+		return io.ReadAll(obj.Body)
+	case "gs":
+		client, err := f.gcsClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		bucket, key := splitObjectPath(u)
+		r, err := client.Bucket(bucket).Object(key).NewReader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case "file", "":
+		return os.ReadFile(u.Path)
+	}
+	return nil, fmt.Errorf("path scheme '%v' is not supported", u.Scheme)
+}
+
+func (f *icebergFileIO) close() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.gcs != nil {
+		err := f.gcs.Close()
+		f.gcs = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// icebergDataFile describes a data file written to a table.
+type icebergDataFile struct {
+	path        string
+	partition   []interface{}
+	recordCount int64
+	sizeBytes   int64
+}
+
+// icebergManifestFile describes a manifest file, which is an entry of the
+// manifest list of a snapshot.
+type icebergManifestFile struct {
+	Path               string
+	Length             int64
+	SpecID             int32
+	Content            int32
+	SequenceNumber     int64
+	MinSequenceNumber  int64
+	AddedSnapshotID    int64
+	AddedFilesCount    int32
+	ExistingFilesCount int32
+	DeletedFilesCount  int32
+	AddedRowsCount     int64
+	ExistingRowsCount  int64
+	DeletedRowsCount   int64
+
+	// The remaining fields of manifests read from an existing manifest list,
+	// which are preserved as they are.
+	partitions  interface{}
+	keyMetadata interface{}
+}
+
+func icebergAvroType(primitive string) (string, error) {
+	switch primitive {
+	case "boolean", "int", "long", "float", "double", "string":
+		return primitive, nil
+	case "date":
+		return "int", nil
+	case "timestamp", "timestamptz":
+		return "long", nil
+	case "binary":
+		return "bytes", nil
+	}
+	return "", fmt.Errorf("partition values of type %v are not supported", primitive)
+}
+
+func (p *icebergPartitioner) avroPartitionSchema() ([]interface{}, error) {
+	fields := []interface{}{}
+	for _, f := range p.fields {
+		avroType, err := icebergAvroType(f.resultType)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, map[string]interface{}{
+			"name":     f.field.Name,
+			"type":     []interface{}{"null", avroType},
+			"default":  nil,
+			"field-id": f.field.FieldID,
+		})
+	}
+	return fields, nil
+}
+
+func (p *icebergPartitioner) avroPartitionRecord(values []interface{}) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(p.fields))
+	for i, f := range p.fields {
+		if values[i] == nil {
+			record[f.field.Name] = nil
+			continue
+		}
+		avroType, err := icebergAvroType(f.resultType)
+		if err != nil {
+			return nil, err
+		}
+		v := values[i]
+		if avroType == "bytes" {
+			v = []byte(v.(string))
+		}
+		record[f.field.Name] = goavro.Union(avroType, v)
+	}
+	return record, nil
+}
+
+// writeManifest encodes a manifest file in Avro format listing data files that
+// are added to a table.
+func writeManifest(schemaJSON []byte, p *icebergPartitioner, files []icebergDataFile) ([]byte, error) {
+	partitionFields, err := p.avroPartitionSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	optionalLong := []interface{}{"null", "long"}
+	avroSchema := map[string]interface{}{
+		"type": "record",
+		"name": "manifest_entry",
+		"fields": []interface{}{
+			map[string]interface{}{"name": "status", "type": "int", "field-id": 0},
+			map[string]interface{}{"name": "snapshot_id", "type": optionalLong, "default": nil, "field-id": 1},
+			map[string]interface{}{"name": "sequence_number", "type": optionalLong, "default": nil, "field-id": 3},
+			map[string]interface{}{"name": "file_sequence_number", "type": optionalLong, "default": nil, "field-id": 4},
+			map[string]interface{}{
+				"name":     "data_file",
+				"field-id": 2,
+				"type": map[string]interface{}{
+					"type": "record",
+					"name": "r2",
+					"fields": []interface{}{
+						map[string]interface{}{"name": "content", "type": "int", "field-id": 134},
+						map[string]interface{}{"name": "file_path", "type": "string", "field-id": 100},
+						map[string]interface{}{"name": "file_format", "type": "string", "field-id": 101},
+						map[string]interface{}{
+							"name":     "partition",
+							"field-id": 102,
+							"type": map[string]interface{}{
+								"type":   "record",
+								"name":   "r102",
+								"fields": partitionFields,
+							},
+						},
+						map[string]interface{}{"name": "record_count", "type": "long", "field-id": 103},
+						map[string]interface{}{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+					},
+				},
+			},
+		},
+	}
+
+	specJSON, err := json.Marshal(p.spec.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if p.spec.Fields == nil {
+		specJSON = []byte("[]")
+	}
+
+	records := make([]interface{}, 0, len(files))
+	for _, f := range files {
+		partition, err := p.avroPartitionRecord(f.partition)
+		if err != nil {
+			return nil, err
+		}
+		// The snapshot ID and sequence numbers are inherited from the manifest
+		// list, which allows the manifest to be committed within any snapshot.
+		records = append(records, map[string]interface{}{
+			"status":               int32(1),
+			"snapshot_id":          nil,
+			"sequence_number":      nil,
+			"file_sequence_number": nil,
+			"data_file": map[string]interface{}{
+				"content":            int32(0),
+				"file_path":          f.path,
+				"file_format":        "PARQUET",
+				"partition":          partition,
+				"record_count":       f.recordCount,
+				"file_size_in_bytes": f.sizeBytes,
+			},
+		})
+	}
+
+	return writeAvroFile(avroSchema, map[string][]byte{
+		"schema":            schemaJSON,
+		"partition-spec":    specJSON,
+		"partition-spec-id": []byte(strconv.Itoa(p.spec.SpecID)),
+		"format-version":    []byte("2"),
+		"content":           []byte("data"),
+	}, records)
+}
+
+func manifestListAvroSchema() map[string]interface{} {
+	optionalBytes := []interface{}{"null", "bytes"}
+	return map[string]interface{}{
+		"type": "record",
+		"name": "manifest_file",
+		"fields": []interface{}{
+			map[string]interface{}{"name": "manifest_path", "type": "string", "field-id": 500},
+			map[string]interface{}{"name": "manifest_length", "type": "long", "field-id": 501},
+			map[string]interface{}{"name": "partition_spec_id", "type": "int", "field-id": 502},
+			map[string]interface{}{"name": "content", "type": "int", "field-id": 517},
+			map[string]interface{}{"name": "sequence_number", "type": "long", "field-id": 515},
+			map[string]interface{}{"name": "min_sequence_number", "type": "long", "field-id": 516},
+			map[string]interface{}{"name": "added_snapshot_id", "type": "long", "field-id": 503},
+			map[string]interface{}{"name": "added_files_count", "type": "int", "field-id": 504},
+			map[string]interface{}{"name": "existing_files_count", "type": "int", "field-id": 505},
+			map[string]interface{}{"name": "deleted_files_count", "type": "int", "field-id": 506},
+			map[string]interface{}{"name": "added_rows_count", "type": "long", "field-id": 512},
+			map[string]interface{}{"name": "existing_rows_count", "type": "long", "field-id": 513},
+			map[string]interface{}{"name": "deleted_rows_count", "type": "long", "field-id": 514},
+			map[string]interface{}{
+				"name":     "partitions",
+				"field-id": 507,
+				"default":  nil,
+				"type": []interface{}{"null", map[string]interface{}{
+					"type":       "array",
+					"element-id": 508,
+					"items": map[string]interface{}{
+						"type": "record",
+						"name": "r508",
+						"fields": []interface{}{
+							map[string]interface{}{"name": "contains_null", "type": "boolean", "field-id": 509},
+							map[string]interface{}{"name": "contains_nan", "type": []interface{}{"null", "boolean"}, "default": nil, "field-id": 518},
+							map[string]interface{}{"name": "lower_bound", "type": optionalBytes, "default": nil, "field-id": 510},
+							map[string]interface{}{"name": "upper_bound", "type": optionalBytes, "default": nil, "field-id": 511},
+						},
+					},
+				}},
+			},
+			map[string]interface{}{"name": "key_metadata", "type": optionalBytes, "default": nil, "field-id": 519},
+		},
+	}
+}
+
+// writeManifestList encodes the manifest list of a snapshot in Avro format.
+func writeManifestList(snapshotID int64, parentID *int64, sequenceNumber int64, manifests []icebergManifestFile) ([]byte, error) {
+	meta := map[string][]byte{
+		"snapshot-id":     []byte(strconv.FormatInt(snapshotID, 10)),
+		"sequence-number": []byte(strconv.FormatInt(sequenceNumber, 10)),
+		"format-version":  []byte("2"),
+	}
+	if parentID != nil {
+		meta["parent-snapshot-id"] = []byte(strconv.FormatInt(*parentID, 10))
+	}
+
+	records := make([]interface{}, 0, len(manifests))
+	for _, m := range manifests {
+		records = append(records, map[string]interface{}{
+			"manifest_path":        m.Path,
+			"manifest_length":      m.Length,
+			"partition_spec_id":    m.SpecID,
+			"content":              m.Content,
+			"sequence_number":      m.SequenceNumber,
+			"min_sequence_number":  m.MinSequenceNumber,
+			"added_snapshot_id":    m.AddedSnapshotID,
+			"added_files_count":    m.AddedFilesCount,
+			"existing_files_count": m.ExistingFilesCount,
+			"deleted_files_count":  m.DeletedFilesCount,
+			"added_rows_count":     m.AddedRowsCount,
+			"existing_rows_count":  m.ExistingRowsCount,
+			"deleted_rows_count":   m.DeletedRowsCount,
+			"partitions":           m.partitions,
+			"key_metadata":         m.keyMetadata,
+		})
+	}
+	return writeAvroFile(manifestListAvroSchema(), meta, records)
+}
+
+// readManifestList decodes the manifests of an existing manifest list.
+func readManifestList(data []byte) ([]icebergManifestFile, error) {
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []icebergManifestFile
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return nil, err
+		}
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected manifest record, got %T", datum)
+		}
+
+		m := icebergManifestFile{
+			partitions:  record["partitions"],
+			keyMetadata: record["key_metadata"],
+		}
+		m.Path, _ = record["manifest_path"].(string)
+		m.Length = avroLong(record["manifest_length"])
+		m.SpecID = int32(avroLong(record["partition_spec_id"]))
+		m.Content = int32(avroLong(record["content"]))
+		m.SequenceNumber = avroLong(record["sequence_number"])
+		m.MinSequenceNumber = avroLong(record["min_sequence_number"])
+		m.AddedSnapshotID = avroLong(record["added_snapshot_id"])
+		m.AddedFilesCount = int32(avroLong(record["added_files_count"]))
+		m.ExistingFilesCount = int32(avroLong(record["existing_files_count"]))
+		m.DeletedFilesCount = int32(avroLong(record["deleted_files_count"]))
+		m.AddedRowsCount = avroLong(record["added_rows_count"])
+		m.ExistingRowsCount = avroLong(record["existing_rows_count"])
+		m.DeletedRowsCount = avroLong(record["deleted_rows_count"])
+		if m.Path == "" {
+			return nil, fmt.Errorf("manifest record is missing a path")
+		}
+		manifests = append(manifests, m)
+	}
+	if err := ocfr.Err(); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
+
+// avroLong extracts an integer from a decoded Avro value, which may be wrapped
+// within a union.
+func avroLong(v interface{}) int64 {
+	switch tv := v.(type) {
+	case int32:
+		return int64(tv)
+	case int64:
+		return tv
+	case map[string]interface{}:
+		for _, uv := range tv {
+			return avroLong(uv)
+		}
+	}
+	return 0
+}
+
+func writeAvroFile(schema map[string]interface{}, meta map[string][]byte, records []interface{}) ([]byte, error) {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(string(schemaBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+	}
+
+	var buf bytes.Buffer
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Codec:           codec,
+		CompressionName: goavro.CompressionDeflateLabel,
+		MetaData:        meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		if err := ocfw.Append(records); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,637 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	baws "github.com/Jeffail/benthos/v3/internal/impl/aws"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gofrs/uuid"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func icebergOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Appends message batches to an [Apache Iceberg](https://iceberg.apache.org/) table as Parquet data files.").
+		Description(output.Description(true, true, `
+The rows of each message batch are written as Parquet data files to the location of the table, which can be within S3 (`+"`s3://`"+`), Google Cloud Storage (`+"`gs://`"+`) or a local filesystem. The data files are then committed to the table as a new snapshot via its catalog, after which they become visible to readers of the table. Message batches are only acknowledged once their data files have been committed.
+
+The table must already exist and use format version 2. Both REST catalogs and the AWS Glue Data Catalog are supported, and tables of a Hive metastore can be written to via a REST catalog service that is backed by the metastore.
+
+### Columns
+
+Each message must be a JSON object, and the value of each column is obtained from the field of the object with the same name, which may be nested for struct columns. Fields that are missing or null are written as null, and messages that are missing values for required columns are rejected.
+
+The primitive types `+"`boolean`"+`, `+"`int`"+`, `+"`long`"+`, `+"`float`"+`, `+"`double`"+`, `+"`string`"+`, `+"`binary`"+`, `+"`date`"+`, `+"`timestamp`"+` and `+"`timestamptz`"+` are supported, as well as nested `+"`struct`"+`, `+"`list`"+` and `+"`map`"+` types with string keys. Dates and timestamps can be written from RFC 3339 strings or numbers of seconds since the unix epoch.
+
+### Partitioning
+
+Rows are partitioned according to the default partition spec of the table, where the partition values of each row are computed from the fields of the message with the transforms of the spec. The transforms `+"`identity`"+`, `+"`bucket`"+`, `+"`truncate`"+`, `+"`year`"+`, `+"`month`"+`, `+"`day`"+`, `+"`hour`"+` and `+"`void`"+` are supported, and a data file is written for each partition of a batch.
+
+### Commits
+
+The data files of batches that are written within the period `+"`commit_period`"+` are committed together as a single snapshot, which reduces the number of snapshots created for tables that receive a continuous stream of data. Commits that conflict with concurrent changes to the table are retried from the latest snapshot of the table according to `+"`commit_backoff`"+`.
+
+Rows that are rejected, for example due to missing required columns, do not prevent the remaining rows of the batch from being written, and only the messages of the rejected rows are reported as failed so that they can be handled with [error handling patterns](/docs/configuration/error_handling).`)).
+		Field(service.NewObjectField("catalog",
+			service.NewStringEnumField("type", "rest", "glue").
+				Description("The type of the catalog.").
+				Default("rest"),
+			service.NewStringField("uri").
+				Description("The URI of a REST catalog.").
+				Example("http://localhost:8181").
+				Default(""),
+			service.NewStringField("warehouse").
+				Description("An optional warehouse location or identifier to request from a REST catalog.").
+				Default(""),
+			service.NewStringField("token").
+				Description("An optional bearer token used to authenticate with a REST catalog.").
+				Default(""),
+			service.NewStringField("credential").
+				Description("Optional OAuth2 client credentials of the form `client_id:client_secret` that are exchanged for a token in order to authenticate with a REST catalog.").
+				Default(""),
+			service.NewStringField("scope").
+				Description("The scope of tokens obtained with the field `credential`.").
+				Default("catalog").
+				Advanced(),
+			service.NewStringField("catalog_id").
+				Description("The ID of a Glue catalog, which defaults to the catalog of the AWS account.").
+				Default("").
+				Advanced(),
+		).Description("The catalog of the table.")).
+		Field(service.NewStringField("namespace").
+			Description("The namespace of the table, where nested namespaces are separated with dots. For Glue catalogs this is the database of the table.").
+			Example("analytics")).
+		Field(service.NewStringField("table").
+			Description("The name of the table to append rows to.").
+			Example("events")).
+		Field(service.NewDurationField("commit_period").
+			Description("The period of time to collect the data files of batches before committing them as a single snapshot. When zero the data files of each batch are committed immediately.").
+			Default("10s")).
+		Field(service.NewBackOffField("commit_backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 100,
+			MaxInterval:     time.Second * 5,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying commits that conflict with concurrent changes to the table.").
+			Advanced()).
+		Field(service.NewStringEnumField("compression", "uncompressed", "snappy", "gzip", "lz4", "zstd").
+			Description("The type of compression to use when writing Parquet data files.").
+			Default("snappy").
+			Advanced()).
+		Field(service.NewObjectField("aws", baws.SessionFields()...).
+			Description("The AWS configuration used for Glue catalogs and for accessing table files within S3.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput, and in order for more batches to be combined within each commit.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Events from Kafka", `
+Append JSON events consumed from Kafka to a table of a REST catalog, committing the data files written every minute as a single snapshot:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos_iceberg
+
+output:
+  iceberg:
+    catalog:
+      uri: http://localhost:8181
+    namespace: analytics
+    table: events
+    commit_period: 1m
+    aws:
+      region: eu-west-1
+    batching:
+      count: 10000
+      period: 10s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"iceberg", icebergOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newIcebergOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// icebergWriter writes rows of a schema as data files partitioned by a spec.
+type icebergWriter struct {
+	schema        *icebergSchema
+	schemaJSON    []byte
+	parquetSchema string
+	partitioner   *icebergPartitioner
+}
+
+func newIcebergWriter(table *icebergTableMetadata) (*icebergWriter, error) {
+	schema, schemaJSON, err := table.currentSchema()
+	if err != nil {
+		return nil, err
+	}
+	parquetSchema, err := schema.parquetSchema()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported table schema: %w", err)
+	}
+	spec, err := table.defaultSpec()
+	if err != nil {
+		return nil, err
+	}
+	partitioner, err := newIcebergPartitioner(schema, spec)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported partition spec: %w", err)
+	}
+	return &icebergWriter{
+		schema:        schema,
+		schemaJSON:    schemaJSON,
+		parquetSchema: parquetSchema,
+		partitioner:   partitioner,
+	}, nil
+}
+
+type icebergCommitRequest struct {
+	manifest  icebergManifestFile
+	fileBytes int64
+	resChan   chan error
+}
+
+type icebergOutput struct {
+	catalog       icebergCatalog
+	fileIO        *icebergFileIO
+	log           *service.Logger
+	compression   parquet.CompressionCodec
+	commitPeriod  time.Duration
+	commitBackoff *backoff.ExponentialBackOff
+
+	tableMut sync.Mutex
+	table    *icebergTableMetadata
+	writer   *icebergWriter
+
+	// The manifests of the last snapshot committed by this output, which saves
+	// reading them from the manifest list for the following commit.
+	lastSnapshotID int64
+	lastManifests  []icebergManifestFile
+
+	commitChan chan icebergCommitRequest
+	shutSig    *shutdown.Signaller
+}
+
+func newIcebergOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*icebergOutput, error) {
+	o := &icebergOutput{
+		log:        log,
+		commitChan: make(chan icebergCommitRequest),
+		shutSig:    shutdown.NewSignaller(),
+	}
+
+	sess, err := baws.GetSession(conf.Namespace("aws"))
+	if err != nil {
+		return nil, err
+	}
+	o.fileIO = newIcebergFileIO(sess)
+
+	namespace, err := conf.FieldString("namespace")
+	if err != nil {
+		return nil, err
+	}
+	table, err := conf.FieldString("table")
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" || table == "" {
+		return nil, errors.New("a namespace and table must be specified")
+	}
+
+	catConf := conf.Namespace("catalog")
+	catType, err := catConf.FieldString("type")
+	if err != nil {
+		return nil, err
+	}
+	switch catType {
+	case "rest":
+		uri, err := catConf.FieldString("uri")
+		if err != nil {
+			return nil, err
+		}
+		if uri == "" {
+			return nil, errors.New("a uri must be specified for rest catalogs")
+		}
+		var warehouse, token, credential, scope string
+		if warehouse, err = catConf.FieldString("warehouse"); err != nil {
+			return nil, err
+		}
+		if token, err = catConf.FieldString("token"); err != nil {
+			return nil, err
+		}
+		if credential, err = catConf.FieldString("credential"); err != nil {
+			return nil, err
+		}
+		if scope, err = catConf.FieldString("scope"); err != nil {
+			return nil, err
+		}
+		o.catalog = newIcebergRESTCatalog(uri, warehouse, token, credential, scope, strings.Split(namespace, "."), table)
+	case "glue":
+		catalogID, err := catConf.FieldString("catalog_id")
+		if err != nil {
+			return nil, err
+		}
+		o.catalog = newIcebergGlueCatalog(sess, o.fileIO, catalogID, namespace, table)
+	default:
+		return nil, fmt.Errorf("catalog type %v is not supported", catType)
+	}
+
+	if o.commitPeriod, err = conf.FieldDuration("commit_period"); err != nil {
+		return nil, err
+	}
+	if o.commitBackoff, err = conf.FieldBackOff("commit_backoff"); err != nil {
+		return nil, err
+	}
+
+	compression, err := conf.FieldString("compression")
+	if err != nil {
+		return nil, err
+	}
+	if o.compression, err = parquet.CompressionCodecFromString(strings.ToUpper(compression)); err != nil {
+		return nil, err
+	}
+
+	go o.commitLoop()
+	return o, nil
+}
+
+func (o *icebergOutput) Connect(ctx context.Context) error {
+	table, err := o.catalog.loadTable(ctx)
+	if err != nil {
+		return err
+	}
+	w, err := newIcebergWriter(table)
+	if err != nil {
+		return err
+	}
+
+	o.tableMut.Lock()
+	o.table, o.writer = table, w
+	o.tableMut.Unlock()
+
+	o.log.Infof("Appending message batches to Iceberg table: %v\n", table.Location)
+	return nil
+}
+
+type icebergPartitionRows struct {
+	values []interface{}
+	rows   [][]byte
+}
+
+func (o *icebergOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.tableMut.Lock()
+	table, w := o.table, o.writer
+	o.tableMut.Unlock()
+	if w == nil {
+		return service.ErrNotConnected
+	}
+
+	batchErr := service.NewBatchError(batch, errors.New("failed to write rows"))
+
+	partitions := map[string]*icebergPartitionRows{}
+	var partitionPaths []string
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		row, err := w.schema.icebergRow(structured)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		values, partitionPath, err := w.partitioner.partition(row)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		rowBytes, err := json.Marshal(row)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+
+		p, exists := partitions[partitionPath]
+		if !exists {
+			p = &icebergPartitionRows{values: values}
+			partitions[partitionPath] = p
+			partitionPaths = append(partitionPaths, partitionPath)
+		}
+		p.rows = append(p.rows, rowBytes)
+	}
+
+	var files []icebergDataFile
+	var fileBytes int64
+	for _, partitionPath := range partitionPaths {
+		p := partitions[partitionPath]
+		data, err := o.encodeParquet(w, p.rows)
+		if err != nil {
+			return err
+		}
+
+		filePath := table.dataLocation() + "/"
+		if partitionPath != "" {
+			filePath += partitionPath + "/"
+		}
+		filePath += uuid.Must(uuid.NewV4()).String() + ".parquet"
+		if err := o.fileIO.write(ctx, filePath, data); err != nil {
+			return fmt.Errorf("failed to write data file: %w", err)
+		}
+
+		files = append(files, icebergDataFile{
+			path:        filePath,
+			partition:   p.values,
+			recordCount: int64(len(p.rows)),
+			sizeBytes:   int64(len(data)),
+		})
+		fileBytes += int64(len(data))
+	}
+
+	if len(files) > 0 {
+		manifest, err := writeManifest(w.schemaJSON, w.partitioner, files)
+		if err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		manifestPath := table.metadataLocation() + "/" + uuid.Must(uuid.NewV4()).String() + "-m0.avro"
+		if err := o.fileIO.write(ctx, manifestPath, manifest); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+
+		var rows int64
+		for _, f := range files {
+			rows += f.recordCount
+		}
+		req := icebergCommitRequest{
+			manifest: icebergManifestFile{
+				Path:            manifestPath,
+				Length:          int64(len(manifest)),
+				SpecID:          int32(w.partitioner.spec.SpecID),
+				AddedFilesCount: int32(len(files)),
+				AddedRowsCount:  rows,
+			},
+			fileBytes: fileBytes,
+			resChan:   make(chan error, 1),
+		}
+
+		select {
+		case o.commitChan <- req:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case err := <-req.resChan:
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *icebergOutput) encodeParquet(w *icebergWriter, rows [][]byte) ([]byte, error) {
+	buf := buffer.NewBufferFile()
+
+	pw, err := writer.NewJSONWriter(w.parquetSchema, buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = o.compression
+
+	for _, row := range rows {
+		if err := pw.Write(string(row)); err != nil {
+			return nil, fmt.Errorf("failed to write row to data file: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *icebergOutput) commitLoop() {
+	defer o.shutSig.ShutdownComplete()
+
+	var pending []icebergCommitRequest
+	var flushChan <-chan time.Time
+	for {
+		select {
+		case req := <-o.commitChan:
+			pending = append(pending, req)
+			if o.commitPeriod > 0 {
+				if flushChan == nil {
+					flushChan = time.After(o.commitPeriod)
+				}
+				continue
+			}
+		drain:
+			for {
+				select {
+				case req := <-o.commitChan:
+					pending = append(pending, req)
+				default:
+					break drain
+				}
+			}
+		case <-flushChan:
+		case <-o.shutSig.CloseAtLeisureChan():
+			for _, req := range pending {
+				req.resChan <- errors.New("output closed before data files were committed")
+			}
+			return
+		}
+
+		err := o.commit(pending)
+		for _, req := range pending {
+			req.resChan <- err
+		}
+		pending, flushChan = nil, nil
+	}
+}
+
+func newIcebergSnapshotID() int64 {
+	u := uuid.Must(uuid.NewV4())
+	return int64(binary.BigEndian.Uint64(u[:8]) & 0x7FFFFFFFFFFFFFFF)
+}
+
+// commit appends the manifests of commit requests to the table as a single
+// snapshot, retrying when the commit conflicts with concurrent changes.
+func (o *icebergOutput) commit(reqs []icebergCommitRequest) error {
+	ctx, done := o.shutSig.CloseNowCtx(context.Background())
+	defer done()
+
+	boff := *o.commitBackoff
+	boff.Reset()
+
+	snapshotID := newIcebergSnapshotID()
+	for attempt := 0; ; attempt++ {
+		o.tableMut.Lock()
+		base, w := o.table, o.writer
+		o.tableMut.Unlock()
+
+		updated, manifests, err := o.commitAttempt(ctx, base, w, snapshotID, attempt, reqs)
+		if err == nil {
+			o.tableMut.Lock()
+			o.table = updated
+			o.lastSnapshotID, o.lastManifests = snapshotID, manifests
+			o.tableMut.Unlock()
+			return nil
+		}
+		if !errors.Is(err, errIcebergCommitConflict) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return fmt.Errorf("failed to commit snapshot: %w", err)
+		}
+		o.log.Debugf("Retrying commit of snapshot %v: %v\n", snapshotID, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		latest, err := o.catalog.loadTable(ctx)
+		if err != nil {
+			return err
+		}
+		o.tableMut.Lock()
+		o.table = latest
+		o.tableMut.Unlock()
+	}
+}
+
+func (o *icebergOutput) parentManifests(ctx context.Context, parent *icebergSnapshot) ([]icebergManifestFile, error) {
+	o.tableMut.Lock()
+	if o.lastManifests != nil && o.lastSnapshotID == parent.SnapshotID {
+		manifests := o.lastManifests
+		o.tableMut.Unlock()
+		return manifests, nil
+	}
+	o.tableMut.Unlock()
+
+	data, err := o.fileIO.read(ctx, parent.ManifestList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest list: %w", err)
+	}
+	return readManifestList(data)
+}
+
+func (o *icebergOutput) commitAttempt(
+	ctx context.Context,
+	base *icebergTableMetadata,
+	w *icebergWriter,
+	snapshotID int64,
+	attempt int,
+	reqs []icebergCommitRequest,
+) (*icebergTableMetadata, []icebergManifestFile, error) {
+	sequenceNumber := base.LastSequenceNumber + 1
+
+	var addedFiles, addedRows, addedBytes int64
+	var manifests []icebergManifestFile
+	for _, req := range reqs {
+		m := req.manifest
+		m.SequenceNumber = sequenceNumber
+		m.MinSequenceNumber = sequenceNumber
+		m.AddedSnapshotID = snapshotID
+		manifests = append(manifests, m)
+
+		addedFiles += int64(m.AddedFilesCount)
+		addedRows += m.AddedRowsCount
+		addedBytes += req.fileBytes
+	}
+
+	var parentID *int64
+	if parent := base.currentSnapshot(); parent != nil {
+		parentID = &parent.SnapshotID
+		existing, err := o.parentManifests(ctx, parent)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifests = append(manifests, existing...)
+	}
+
+	list, err := writeManifestList(snapshotID, parentID, sequenceNumber, manifests)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode manifest list: %w", err)
+	}
+	listPath := fmt.Sprintf("%v/snap-%v-%v-%v.avro", base.metadataLocation(), snapshotID, attempt, uuid.Must(uuid.NewV4()))
+	if err := o.fileIO.write(ctx, listPath, list); err != nil {
+		return nil, nil, fmt.Errorf("failed to write manifest list: %w", err)
+	}
+
+	schemaID := w.schema.SchemaID
+	snapshot := &icebergSnapshot{
+		SnapshotID:       snapshotID,
+		ParentSnapshotID: parentID,
+		SequenceNumber:   sequenceNumber,
+		TimestampMs:      time.Now().UnixNano() / int64(time.Millisecond),
+		ManifestList:     listPath,
+		SchemaID:         &schemaID,
+		Summary: map[string]string{
+			"operation":        "append",
+			"added-data-files": strconv.FormatInt(addedFiles, 10),
+			"added-records":    strconv.FormatInt(addedRows, 10),
+			"added-files-size": strconv.FormatInt(addedBytes, 10),
+		},
+	}
+
+	updated, err := o.catalog.commitSnapshot(ctx, base, snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, manifests, nil
+}
+
+func (o *icebergOutput) Close(ctx context.Context) error {
+	o.shutSig.CloseAtLeisure()
+	select {
+	case <-o.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return o.fileIO.close()
+}
@@ -0,0 +1,257 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRESTCatalog is a minimal REST catalog holding a single table, which
+// rejects the first commit it receives as a conflict.
+type testRESTCatalog struct {
+	t *testing.T
+
+	mut       sync.Mutex
+	metadata  *icebergTableMetadata
+	commits   int
+	conflicts int
+}
+
+func newTestRESTCatalog(t *testing.T, location string) *testRESTCatalog {
+	t.Helper()
+
+	m, err := parseIcebergTableMetadata([]byte(fmt.Sprintf(`{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": %q,
+  "last-sequence-number": 0,
+  "last-updated-ms": 1641031200000,
+  "last-column-id": 3,
+  "current-schema-id": 0,
+  "schemas": [{ "schema-id": 0, "type": "struct", "fields": [
+    { "id": 1, "name": "id", "required": true, "type": "long" },
+    { "id": 2, "name": "ts", "required": false, "type": "timestamptz" },
+    { "id": 3, "name": "region", "required": false, "type": "string" }
+  ]}],
+  "default-spec-id": 0,
+  "partition-specs": [{ "spec-id": 0, "fields": [
+    { "source-id": 3, "field-id": 1000, "name": "region", "transform": "identity" }
+  ]}],
+  "last-partition-id": 1000,
+  "properties": {},
+  "current-snapshot-id": -1,
+  "snapshots": []
+}`, location)))
+	require.NoError(t, err)
+
+	return &testRESTCatalog{t: t, metadata: m}
+}
+
+func (c *testRESTCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/config":
+		_, _ = w.Write([]byte(`{"defaults":{},"overrides":{}}`))
+		return
+	case "/v1/namespaces/analytics/tables/events":
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			Requirements []struct {
+				Type       string `json:"type"`
+				SnapshotID *int64 `json:"snapshot-id"`
+			} `json:"requirements"`
+			Updates []struct {
+				Action   string           `json:"action"`
+				Snapshot *icebergSnapshot `json:"snapshot"`
+			} `json:"updates"`
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(c.t, err)
+		require.NoError(c.t, json.Unmarshal(body, &req))
+
+		var current *int64
+		if s := c.metadata.currentSnapshot(); s != nil {
+			current = &s.SnapshotID
+		}
+		for _, r := range req.Requirements {
+			if r.Type == "assert-ref-snapshot-id" {
+				assert.Equal(c.t, current, r.SnapshotID)
+			}
+		}
+
+		if c.conflicts == 0 {
+			c.conflicts++
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"message":"branch main has changed","type":"CommitFailedException","code":409}}`))
+			return
+		}
+
+		require.Len(c.t, req.Updates, 2)
+		raw, err := icebergApplySnapshot(c.metadata, req.Updates[0].Snapshot, time.Now())
+		require.NoError(c.t, err)
+		c.metadata, err = parseIcebergTableMetadata(raw)
+		require.NoError(c.t, err)
+		c.commits++
+	}
+
+	resBody, err := json.Marshal(map[string]interface{}{
+		"metadata": json.RawMessage(c.metadata.raw),
+	})
+	require.NoError(c.t, err)
+	_, _ = w.Write(resBody)
+}
+
+func TestIcebergOutputREST(t *testing.T) {
+	location := "file://" + filepath.Join(t.TempDir(), "events")
+	catalog := newTestRESTCatalog(t, location)
+
+	server := httptest.NewServer(catalog)
+	t.Cleanup(server.Close)
+
+	conf, err := icebergOutputConfig().ParseYAML(fmt.Sprintf(`
+catalog:
+  uri: %v
+namespace: analytics
+table: events
+commit_period: 0s
+commit_backoff:
+  initial_interval: 1ms
+  max_interval: 10ms
+`, server.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newIcebergOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	require.Equal(t, service.ErrNotConnected, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+	}))
+	require.NoError(t, out.Connect(ctx))
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"ts":"2022-01-01T10:00:00Z","region":"eu"}`)),
+		service.NewMessage([]byte(`{"region":"us"}`)),
+		service.NewMessage([]byte(`{"id":3,"region":"us"}`)),
+		service.NewMessage([]byte(`{"id":4,"region":"eu"}`)),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+	require.Equal(t, 1, batchErr.IndexedErrors())
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":5}`)),
+	}))
+
+	catalog.mut.Lock()
+	defer catalog.mut.Unlock()
+
+	assert.Equal(t, 1, catalog.conflicts)
+	assert.Equal(t, 2, catalog.commits)
+	assert.Equal(t, int64(2), catalog.metadata.LastSequenceNumber)
+
+	snapshot := catalog.metadata.currentSnapshot()
+	require.NotNil(t, snapshot)
+	require.NotNil(t, snapshot.ParentSnapshotID)
+	assert.Equal(t, "append", snapshot.Summary["operation"])
+	assert.Equal(t, "1", snapshot.Summary["added-records"])
+
+	list, err := out.fileIO.read(ctx, snapshot.ManifestList)
+	require.NoError(t, err)
+
+	manifests, err := readManifestList(list)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	assert.Equal(t, int64(2), manifests[0].SequenceNumber)
+	assert.Equal(t, int32(1), manifests[0].AddedFilesCount)
+	assert.Equal(t, int64(1), manifests[0].AddedRowsCount)
+
+	assert.Equal(t, int64(1), manifests[1].SequenceNumber)
+	assert.Equal(t, int32(2), manifests[1].AddedFilesCount)
+	assert.Equal(t, int64(3), manifests[1].AddedRowsCount)
+
+	for _, dir := range []string{"region=eu", "region=us", "region=null"} {
+		files, err := os.ReadDir(filepath.Join(location[len("file://"):], "data", dir))
+		require.NoError(t, err, dir)
+		assert.Len(t, files, 1, dir)
+	}
+}
+
+func TestIcebergApplySnapshot(t *testing.T) {
+	base, err := parseIcebergTableMetadata([]byte(`{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "s3://foo/bar",
+  "last-sequence-number": 3,
+  "last-updated-ms": 1641031200000,
+  "current-schema-id": 0,
+  "schemas": [],
+  "current-snapshot-id": 1,
+  "snapshots": [{ "snapshot-id": 1, "sequence-number": 3, "timestamp-ms": 1641031200000, "manifest-list": "s3://foo/bar/metadata/snap-1.avro", "summary": { "operation": "append" } }],
+  "refs": { "main": { "snapshot-id": 1, "type": "branch", "max-ref-age-ms": 1000 } },
+  "sort-orders": [{ "order-id": 0, "fields": [] }]
+}`))
+	require.NoError(t, err)
+	base.fileLocation = "s3://foo/bar/metadata/00003-a.metadata.json"
+
+	parentID := int64(1)
+	raw, err := icebergApplySnapshot(base, &icebergSnapshot{
+		SnapshotID:       2,
+		ParentSnapshotID: &parentID,
+		SequenceNumber:   4,
+		TimestampMs:      1641031300000,
+		ManifestList:     "s3://foo/bar/metadata/snap-2.avro",
+		Summary:          map[string]string{"operation": "append"},
+	}, time.Unix(1641031400, 0))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "s3://foo/bar",
+  "last-sequence-number": 4,
+  "last-updated-ms": 1641031400000,
+  "current-schema-id": 0,
+  "schemas": [],
+  "current-snapshot-id": 2,
+  "snapshots": [
+    { "snapshot-id": 1, "sequence-number": 3, "timestamp-ms": 1641031200000, "manifest-list": "s3://foo/bar/metadata/snap-1.avro", "summary": { "operation": "append" } },
+    { "snapshot-id": 2, "parent-snapshot-id": 1, "sequence-number": 4, "timestamp-ms": 1641031300000, "manifest-list": "s3://foo/bar/metadata/snap-2.avro", "summary": { "operation": "append" } }
+  ],
+  "snapshot-log": [{ "snapshot-id": 2, "timestamp-ms": 1641031300000 }],
+  "metadata-log": [{ "metadata-file": "s3://foo/bar/metadata/00003-a.metadata.json", "timestamp-ms": 1641031200000 }],
+  "refs": { "main": { "snapshot-id": 2, "type": "branch", "max-ref-age-ms": 1000 } },
+  "sort-orders": [{ "order-id": 0, "fields": [] }]
+}`, string(raw))
+
+	name := icebergNextMetadataFileName(base.fileLocation)
+	assert.Regexp(t, `^00004-[0-9a-f-]{36}\.metadata\.json$`, name)
+}
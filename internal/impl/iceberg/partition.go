@@ -0,0 +1,245 @@
+package iceberg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+	"github.com/spaolacci/murmur3"
+)
+
+type icebergPartitionField struct {
+	SourceID  int    `json:"source-id"`
+	FieldID   int    `json:"field-id"`
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+}
+
+type icebergPartitionSpec struct {
+	SpecID int                      `json:"spec-id"`
+	Fields []*icebergPartitionField `json:"fields"`
+}
+
+// icebergTransform is a parsed partition transform.
+type icebergTransform struct {
+	name  string
+	param int
+}
+
+func parseIcebergTransform(s string) (icebergTransform, error) {
+	switch s {
+	case "identity", "year", "month", "day", "hour", "void":
+		return icebergTransform{name: s}, nil
+	}
+	for _, name := range []string{"bucket", "truncate"} {
+		if !strings.HasPrefix(s, name+"[") || !strings.HasSuffix(s, "]") {
+			continue
+		}
+		n, err := strconv.Atoi(s[len(name)+1 : len(s)-1])
+		if err != nil || n <= 0 {
+			return icebergTransform{}, fmt.Errorf("invalid transform parameter: %v", s)
+		}
+		return icebergTransform{name: name, param: n}, nil
+	}
+	return icebergTransform{}, fmt.Errorf("transform %v is not supported", s)
+}
+
+// resultType returns the primitive type of the values produced by the
+// transform for a source type.
+func (t icebergTransform) resultType(source *icebergType) (string, error) {
+	switch t.name {
+	case "identity", "truncate", "void":
+		if source.isNested() {
+			return "", fmt.Errorf("transform %v cannot be applied to %v types", t.name, source.Primitive)
+		}
+		return source.Primitive, nil
+	case "bucket":
+		return "int", nil
+	}
+	switch source.Primitive {
+	case "date", "timestamp", "timestamptz":
+	default:
+		return "", fmt.Errorf("transform %v cannot be applied to %v types", t.name, source.Primitive)
+	}
+	if t.name == "hour" && source.Primitive == "date" {
+		return "", fmt.Errorf("transform hour cannot be applied to date types")
+	}
+	return "int", nil
+}
+
+// apply transforms a value in the physical representation of the source type.
+func (t icebergTransform) apply(source *icebergType, v interface{}) (interface{}, error) {
+	if v == nil || t.name == "void" {
+		return nil, nil
+	}
+
+	switch t.name {
+	case "identity":
+		return v, nil
+	case "bucket":
+		var b []byte
+		switch tv := v.(type) {
+		case int32:
+			b = make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, uint64(int64(tv)))
+		case int64:
+			b = make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, uint64(tv))
+		case string:
+			b = []byte(tv)
+		default:
+			return nil, fmt.Errorf("transform bucket cannot be applied to %v types", source.Primitive)
+		}
+		return int32((murmur3.Sum32(b) & 0x7FFFFFFF) % uint32(t.param)), nil
+	case "truncate":
+		switch tv := v.(type) {
+		case int32:
+			w := int32(t.param)
+			return tv - (((tv % w) + w) % w), nil
+		case int64:
+			w := int64(t.param)
+			return tv - (((tv % w) + w) % w), nil
+		case string:
+			if source.Primitive == "binary" {
+				if len(tv) > t.param {
+					return tv[:t.param], nil
+				}
+				return tv, nil
+			}
+			runes := []rune(tv)
+			if len(runes) > t.param {
+				return string(runes[:t.param]), nil
+			}
+			return tv, nil
+		}
+		return nil, fmt.Errorf("transform truncate cannot be applied to %v types", source.Primitive)
+	}
+
+	var micros int64
+	switch tv := v.(type) {
+	case int32:
+		micros = int64(tv) * 86400 * 1e6
+	case int64:
+		micros = tv
+	default:
+		return nil, fmt.Errorf("transform %v cannot be applied to %v types", t.name, source.Primitive)
+	}
+
+	switch t.name {
+	case "year", "month":
+		ts := parquetschema.MicrosTime(micros)
+		if t.name == "year" {
+			return int32(ts.Year() - 1970), nil
+		}
+		return int32((ts.Year()-1970)*12 + int(ts.Month()) - 1), nil
+	case "day":
+		return int32(parquetschema.FloorDiv(micros, 86400*1e6)), nil
+	case "hour":
+		return int32(parquetschema.FloorDiv(micros, 3600*1e6)), nil
+	}
+	return nil, fmt.Errorf("transform %v is not supported", t.name)
+}
+
+// humanString returns the representation of a transformed value used within
+// the paths of data files.
+func (t icebergTransform) humanString(resultType string, v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	switch t.name {
+	case "year":
+		return strconv.Itoa(1970 + int(v.(int32)))
+	case "month":
+		m := int(v.(int32))
+		year, month := 1970+icebergFloorDivInt(m, 12), m-icebergFloorDivInt(m, 12)*12+1
+		return fmt.Sprintf("%04d-%02d", year, month)
+	case "day":
+		return parquetschema.MicrosTime(int64(v.(int32)) * 86400 * 1e6).Format("2006-01-02")
+	case "hour":
+		return parquetschema.MicrosTime(int64(v.(int32)) * 3600 * 1e6).Format("2006-01-02-15")
+	}
+	switch resultType {
+	case "date":
+		return parquetschema.MicrosTime(int64(v.(int32)) * 86400 * 1e6).Format("2006-01-02")
+	case "timestamp":
+		return parquetschema.MicrosTime(v.(int64)).Format("2006-01-02T15:04:05.999999")
+	case "timestamptz":
+		return parquetschema.MicrosTime(v.(int64)).Format("2006-01-02T15:04:05.999999Z07:00")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func icebergFloorDivInt(a, b int) int {
+	return int(parquetschema.FloorDiv(int64(a), int64(b)))
+}
+
+//------------------------------------------------------------------------------
+
+// icebergPartitioner computes the partition values of rows according to a
+// partition spec.
+type icebergPartitioner struct {
+	spec   *icebergPartitionSpec
+	fields []icebergPartitionerField
+}
+
+type icebergPartitionerField struct {
+	field      *icebergPartitionField
+	transform  icebergTransform
+	sourcePath []string
+	sourceType *icebergType
+	resultType string
+}
+
+func newIcebergPartitioner(schema *icebergSchema, spec *icebergPartitionSpec) (*icebergPartitioner, error) {
+	p := &icebergPartitioner{spec: spec}
+	for _, f := range spec.Fields {
+		transform, err := parseIcebergTransform(f.Transform)
+		if err != nil {
+			return nil, fmt.Errorf("partition field %v: %w", f.Name, err)
+		}
+		path, source := icebergFieldByID(schema.Fields, f.SourceID)
+		if source == nil {
+			return nil, fmt.Errorf("partition field %v: source field %v does not exist in the schema", f.Name, f.SourceID)
+		}
+		resultType, err := transform.resultType(source.Type)
+		if err != nil {
+			return nil, fmt.Errorf("partition field %v: %w", f.Name, err)
+		}
+		p.fields = append(p.fields, icebergPartitionerField{
+			field:      f,
+			transform:  transform,
+			sourcePath: path,
+			sourceType: source.Type,
+			resultType: resultType,
+		})
+	}
+	return p, nil
+}
+
+// partition returns the partition values of a row along with the relative
+// path of the partition.
+func (p *icebergPartitioner) partition(row map[string]interface{}) ([]interface{}, string, error) {
+	if len(p.fields) == 0 {
+		return nil, "", nil
+	}
+
+	values := make([]interface{}, len(p.fields))
+	pathSegments := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		var v interface{} = row
+		for _, k := range f.sourcePath {
+			obj, _ := v.(map[string]interface{})
+			v = obj[k]
+		}
+		pv, err := f.transform.apply(f.sourceType, v)
+		if err != nil {
+			return nil, "", fmt.Errorf("partition field %v: %w", f.field.Name, err)
+		}
+		values[i] = pv
+		pathSegments[i] = url.QueryEscape(f.field.Name) + "=" + url.QueryEscape(f.transform.humanString(f.resultType, pv))
+	}
+	return values, strings.Join(pathSegments, "/"), nil
+}
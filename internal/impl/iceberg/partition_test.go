@@ -0,0 +1,220 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIcebergTransforms(t *testing.T) {
+	tests := map[string]struct {
+		transform string
+		source    string
+		input     interface{}
+		output    interface{}
+		human     string
+	}{
+		"bucket int": {
+			transform: "bucket[2147483647]",
+			source:    "int",
+			input:     int32(34),
+			output:    int32(2017239379),
+			human:     "2017239379",
+		},
+		"bucket long": {
+			transform: "bucket[2147483647]",
+			source:    "long",
+			input:     int64(34),
+			output:    int32(2017239379),
+			human:     "2017239379",
+		},
+		"bucket string": {
+			transform: "bucket[2147483647]",
+			source:    "string",
+			input:     "iceberg",
+			output:    int32(1210000089),
+			human:     "1210000089",
+		},
+		"bucket date": {
+			transform: "bucket[2147483647]",
+			source:    "date",
+			input:     int32(17486),
+			output:    int32(1494153226),
+			human:     "1494153226",
+		},
+		"bucket small": {
+			transform: "bucket[16]",
+			source:    "long",
+			input:     int64(34),
+			output:    int32(3),
+			human:     "3",
+		},
+		"truncate int": {
+			transform: "truncate[10]",
+			source:    "int",
+			input:     int32(-1),
+			output:    int32(-10),
+			human:     "-10",
+		},
+		"truncate long": {
+			transform: "truncate[10]",
+			source:    "long",
+			input:     int64(19),
+			output:    int64(10),
+			human:     "10",
+		},
+		"truncate string": {
+			transform: "truncate[3]",
+			source:    "string",
+			input:     "iceberg",
+			output:    "ice",
+			human:     "ice",
+		},
+		"identity string": {
+			transform: "identity",
+			source:    "string",
+			input:     "foo bar",
+			output:    "foo bar",
+			human:     "foo bar",
+		},
+		"year timestamp": {
+			transform: "year",
+			source:    "timestamp",
+			input:     int64(1640995200000000), // 2022-01-01T00:00:00
+			output:    int32(52),
+			human:     "2022",
+		},
+		"month date": {
+			transform: "month",
+			source:    "date",
+			input:     int32(19082), // 2022-03-31
+			output:    int32(626),
+			human:     "2022-03",
+		},
+		"month before epoch": {
+			transform: "month",
+			source:    "date",
+			input:     int32(-1), // 1969-12-31
+			output:    int32(-1),
+			human:     "1969-12",
+		},
+		"day timestamptz": {
+			transform: "day",
+			source:    "timestamptz",
+			input:     int64(1641031200000000), // 2022-01-01T10:00:00Z
+			output:    int32(18993),
+			human:     "2022-01-01",
+		},
+		"hour timestamp": {
+			transform: "hour",
+			source:    "timestamp",
+			input:     int64(1641031200000000), // 2022-01-01T10:00:00
+			output:    int32(455842),
+			human:     "2022-01-01-10",
+		},
+		"void": {
+			transform: "void",
+			source:    "string",
+			input:     "foo",
+			output:    nil,
+			human:     "null",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			transform, err := parseIcebergTransform(test.transform)
+			require.NoError(t, err)
+
+			source := &icebergType{Primitive: test.source}
+			resultType, err := transform.resultType(source)
+			require.NoError(t, err)
+
+			v, err := transform.apply(source, test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, v)
+			assert.Equal(t, test.human, transform.humanString(resultType, v))
+		})
+	}
+}
+
+func TestIcebergTransformErrors(t *testing.T) {
+	for _, s := range []string{"bucket", "bucket[0]", "truncate[foo]", "zorder"} {
+		_, err := parseIcebergTransform(s)
+		assert.Error(t, err, s)
+	}
+
+	for _, test := range []struct {
+		transform string
+		source    string
+	}{
+		{transform: "year", source: "string"},
+		{transform: "hour", source: "date"},
+		{transform: "identity", source: "struct"},
+	} {
+		transform, err := parseIcebergTransform(test.transform)
+		require.NoError(t, err)
+		_, err = transform.resultType(&icebergType{Primitive: test.source})
+		assert.Error(t, err, test.transform)
+	}
+}
+
+func TestIcebergPartitioner(t *testing.T) {
+	var schema icebergSchema
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schema-id": 0,
+  "fields": [
+    { "id": 1, "name": "id", "required": true, "type": "long" },
+    { "id": 2, "name": "ts", "required": false, "type": "timestamptz" },
+    { "id": 3, "name": "meta", "required": false, "type": {
+      "type": "struct",
+      "fields": [
+        { "id": 4, "name": "region", "required": false, "type": "string" }
+      ]
+    }}
+  ]
+}`), &schema))
+
+	var spec icebergPartitionSpec
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "spec-id": 1,
+  "fields": [
+    { "source-id": 2, "field-id": 1000, "name": "ts_day", "transform": "day" },
+    { "source-id": 4, "field-id": 1001, "name": "region", "transform": "identity" }
+  ]
+}`), &spec))
+
+	p, err := newIcebergPartitioner(&schema, &spec)
+	require.NoError(t, err)
+
+	row, err := schema.icebergRow(map[string]interface{}{
+		"id": json.Number("1"),
+		"ts": "2022-01-01T10:00:00Z",
+		"meta": map[string]interface{}{
+			"region": "eu west",
+		},
+	})
+	require.NoError(t, err)
+
+	values, path, err := p.partition(row)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int32(18993), "eu west"}, values)
+	assert.Equal(t, "ts_day=2022-01-01/region=eu+west", path)
+
+	row, err = schema.icebergRow(map[string]interface{}{
+		"id": json.Number("2"),
+	})
+	require.NoError(t, err)
+
+	values, path, err = p.partition(row)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, nil}, values)
+	assert.Equal(t, "ts_day=null/region=null", path)
+
+	spec.Fields[0].SourceID = 10
+	_, err = newIcebergPartitioner(&schema, &spec)
+	assert.Error(t, err)
+}
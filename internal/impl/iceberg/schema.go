@@ -0,0 +1,312 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+)
+
+// icebergType describes the type of a field of an Iceberg schema, which is
+// either a primitive type or a nested struct, list or map.
+type icebergType struct {
+	Primitive string
+
+	// Struct types.
+	Fields []*icebergField
+
+	// List types.
+	ElementID       int
+	Element         *icebergType
+	ElementRequired bool
+
+	// Map types.
+	KeyID         int
+	Key           *icebergType
+	ValueID       int
+	Value         *icebergType
+	ValueRequired bool
+}
+
+type icebergField struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Required bool         `json:"required"`
+	Type     *icebergType `json:"type"`
+}
+
+type icebergSchema struct {
+	SchemaID int             `json:"schema-id"`
+	Fields   []*icebergField `json:"fields"`
+}
+
+func (t *icebergType) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &t.Primitive); err == nil {
+		return nil
+	}
+
+	var nested struct {
+		Type            string          `json:"type"`
+		Fields          []*icebergField `json:"fields"`
+		ElementID       int             `json:"element-id"`
+		Element         *icebergType    `json:"element"`
+		ElementRequired bool            `json:"element-required"`
+		KeyID           int             `json:"key-id"`
+		Key             *icebergType    `json:"key"`
+		ValueID         int             `json:"value-id"`
+		Value           *icebergType    `json:"value"`
+		ValueRequired   bool            `json:"value-required"`
+	}
+	if err := json.Unmarshal(b, &nested); err != nil {
+		return err
+	}
+
+	switch nested.Type {
+	case "struct":
+		t.Fields = nested.Fields
+	case "list":
+		if nested.Element == nil {
+			return errors.New("list type is missing an element type")
+		}
+		t.ElementID, t.Element, t.ElementRequired = nested.ElementID, nested.Element, nested.ElementRequired
+	case "map":
+		if nested.Key == nil || nested.Value == nil {
+			return errors.New("map type is missing a key or value type")
+		}
+		t.KeyID, t.Key = nested.KeyID, nested.Key
+		t.ValueID, t.Value, t.ValueRequired = nested.ValueID, nested.Value, nested.ValueRequired
+	default:
+		return fmt.Errorf("unrecognised type: %v", nested.Type)
+	}
+	t.Primitive = nested.Type
+	return nil
+}
+
+func (t *icebergType) isNested() bool {
+	switch t.Primitive {
+	case "struct", "list", "map":
+		return true
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+// parquetSchema returns a parquet schema in the JSON format of the parquet
+// writer, where each column is annotated with its Iceberg field ID.
+func (s *icebergSchema) parquetSchema() (string, error) {
+	root := parquetschema.NewRoot()
+	if err := parquetFields(root, s.Fields); err != nil {
+		return "", err
+	}
+	return root.JSON()
+}
+
+func parquetFields(node *parquetschema.Node, fields []*icebergField) error {
+	for _, f := range fields {
+		child, err := parquetNode(f.Name, f.ID, f.Required, f.Type)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", f.Name, err)
+		}
+		node.Fields = append(node.Fields, child)
+	}
+	return nil
+}
+
+func parquetNode(name string, id int, required bool, t *icebergType) (*parquetschema.Node, error) {
+	tag := fmt.Sprintf("name=%v, fieldid=%v, repetitiontype=%v", name, id, parquetschema.Repetition(!required))
+
+	switch t.Primitive {
+	case "struct":
+		node := &parquetschema.Node{Tag: tag}
+		if err := parquetFields(node, t.Fields); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "list":
+		element, err := parquetNode("element", t.ElementID, t.ElementRequired, t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.List(tag, element), nil
+	case "map":
+		if t.Key.Primitive != "string" {
+			return nil, fmt.Errorf("map keys of type %v are not supported", t.Key.Primitive)
+		}
+		key, err := parquetNode("key", t.KeyID, true, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parquetNode("value", t.ValueID, t.ValueRequired, t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.Map(tag, key, value), nil
+	}
+
+	var typeTag string
+	switch t.Primitive {
+	case "boolean":
+		typeTag = "type=BOOLEAN"
+	case "int":
+		typeTag = "type=INT32"
+	case "long":
+		typeTag = "type=INT64"
+	case "float":
+		typeTag = "type=FLOAT"
+	case "double":
+		typeTag = "type=DOUBLE"
+	case "string":
+		typeTag = "type=BYTE_ARRAY, convertedtype=UTF8"
+	case "binary":
+		typeTag = "type=BYTE_ARRAY"
+	case "date":
+		typeTag = "type=INT32, convertedtype=DATE"
+	case "timestamp":
+		typeTag = "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS"
+	case "timestamptz":
+		typeTag = "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"
+	default:
+		return nil, fmt.Errorf("type %v is not supported", t.Primitive)
+	}
+	return &parquetschema.Node{Tag: tag + ", " + typeTag}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// icebergRow converts a structured message into a row of the schema, where
+// the values of the row are in the physical representation of their types.
+func (s *icebergSchema) icebergRow(v interface{}) (map[string]interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object message, got %T", v)
+	}
+	return icebergStruct(s.Fields, obj)
+}
+
+func icebergStruct(fields []*icebergField, obj map[string]interface{}) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, exists := obj[f.Name]
+		if !exists || v == nil {
+			if f.Required {
+				return nil, fmt.Errorf("required field %v is missing", f.Name)
+			}
+			continue
+		}
+		cv, err := icebergConvertValue(f.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.Name, err)
+		}
+		row[f.Name] = cv
+	}
+	return row, nil
+}
+
+func icebergConvertValue(t *icebergType, v interface{}) (interface{}, error) {
+	switch t.Primitive {
+	case "struct":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object value, got %T", v)
+		}
+		return icebergStruct(t.Fields, obj)
+	case "list":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array value, got %T", v)
+		}
+		res := make([]interface{}, 0, len(arr))
+		for i, e := range arr {
+			if e == nil {
+				if t.ElementRequired {
+					return nil, fmt.Errorf("element %v: required element is null", i)
+				}
+				res = append(res, nil)
+				continue
+			}
+			ce, err := icebergConvertValue(t.Element, e)
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			res = append(res, ce)
+		}
+		return res, nil
+	case "map":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object value, got %T", v)
+		}
+		res := make(map[string]interface{}, len(obj))
+		for k, e := range obj {
+			if e == nil {
+				if t.ValueRequired {
+					return nil, fmt.Errorf("key %v: required value is null", k)
+				}
+				res[k] = nil
+				continue
+			}
+			ce, err := icebergConvertValue(t.Value, e)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", k, err)
+			}
+			res[k] = ce
+		}
+		return res, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean value, got %T", v)
+		}
+		return b, nil
+	case "int":
+		i, err := parquetschema.IntInRange(v, math.MinInt32, math.MaxInt32, "int")
+		if err != nil {
+			return nil, err
+		}
+		return int32(i), nil
+	case "long":
+		return parquetschema.Int(v)
+	case "float":
+		f, err := parquetschema.Float(v)
+		if err != nil {
+			return nil, err
+		}
+		return float32(f), nil
+	case "double":
+		return parquetschema.Float(v)
+	case "string", "binary":
+		return parquetschema.String(v)
+	case "date":
+		ts, err := parquetschema.Time(v)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.Date(ts), nil
+	case "timestamp", "timestamptz":
+		ts, err := parquetschema.Time(v)
+		if err != nil {
+			return nil, err
+		}
+		return parquetschema.TimestampMicros(ts), nil
+	}
+	return nil, fmt.Errorf("type %v is not supported", t.Primitive)
+}
+
+// icebergFieldByID returns the primitive field of a schema with an ID, which
+// may be nested within structs.
+func icebergFieldByID(fields []*icebergField, id int) ([]string, *icebergField) {
+	for _, f := range fields {
+		if f.ID == id {
+			return []string{f.Name}, f
+		}
+		if f.Type.Primitive == "struct" {
+			if path, nested := icebergFieldByID(f.Type.Fields, id); nested != nil {
+				return append([]string{f.Name}, path...), nested
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,151 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIcebergSchema = `{
+  "schema-id": 1,
+  "fields": [
+    { "id": 1, "name": "id", "required": true, "type": "long" },
+    { "id": 2, "name": "name", "required": false, "type": "string" },
+    { "id": 3, "name": "ts", "required": false, "type": "timestamptz" },
+    { "id": 4, "name": "tags", "required": false, "type": {
+      "type": "list", "element-id": 5, "element-required": true, "element": "string"
+    }},
+    { "id": 6, "name": "attrs", "required": false, "type": {
+      "type": "map", "key-id": 7, "key": "string", "value-id": 8, "value-required": false, "value": "double"
+    }},
+    { "id": 9, "name": "location", "required": false, "type": {
+      "type": "struct",
+      "fields": [
+        { "id": 10, "name": "lat", "required": true, "type": "float" },
+        { "id": 11, "name": "day", "required": false, "type": "date" }
+      ]
+    }}
+  ]
+}`
+
+func TestIcebergParquetSchema(t *testing.T) {
+	var schema icebergSchema
+	require.NoError(t, json.Unmarshal([]byte(testIcebergSchema), &schema))
+	assert.Equal(t, 1, schema.SchemaID)
+
+	pSchema, err := schema.parquetSchema()
+	require.NoError(t, err)
+
+	var root parquetschema.Node
+	require.NoError(t, json.Unmarshal([]byte(pSchema), &root))
+	assert.Equal(t, parquetschema.Node{
+		Tag: "name=root, repetitiontype=REQUIRED",
+		Fields: []*parquetschema.Node{
+			{Tag: "name=id, fieldid=1, repetitiontype=REQUIRED, type=INT64"},
+			{Tag: "name=name, fieldid=2, repetitiontype=OPTIONAL, type=BYTE_ARRAY, convertedtype=UTF8"},
+			{Tag: "name=ts, fieldid=3, repetitiontype=OPTIONAL, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"},
+			{Tag: "name=tags, fieldid=4, repetitiontype=OPTIONAL, type=LIST", Fields: []*parquetschema.Node{
+				{Tag: "name=element, fieldid=5, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+			}},
+			{Tag: "name=attrs, fieldid=6, repetitiontype=OPTIONAL, type=MAP", Fields: []*parquetschema.Node{
+				{Tag: "name=key, fieldid=7, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+				{Tag: "name=value, fieldid=8, repetitiontype=OPTIONAL, type=DOUBLE"},
+			}},
+			{Tag: "name=location, fieldid=9, repetitiontype=OPTIONAL", Fields: []*parquetschema.Node{
+				{Tag: "name=lat, fieldid=10, repetitiontype=REQUIRED, type=FLOAT"},
+				{Tag: "name=day, fieldid=11, repetitiontype=OPTIONAL, type=INT32, convertedtype=DATE"},
+			}},
+		},
+	}, root)
+}
+
+func TestIcebergRow(t *testing.T) {
+	var schema icebergSchema
+	require.NoError(t, json.Unmarshal([]byte(testIcebergSchema), &schema))
+
+	tests := map[string]struct {
+		input       string
+		output      map[string]interface{}
+		errContains string
+	}{
+		"all fields": {
+			input: `{
+  "id": 5,
+  "name": "foo",
+  "ts": "2022-01-01T10:00:00.5Z",
+  "tags": ["a", "b"],
+  "attrs": { "x": 1.5, "y": null },
+  "location": { "lat": 51.5, "day": "2022-01-02" },
+  "ignored": true
+}`,
+			output: map[string]interface{}{
+				"id":   int64(5),
+				"name": "foo",
+				"ts":   int64(1641031200500000),
+				"tags": []interface{}{"a", "b"},
+				"attrs": map[string]interface{}{
+					"x": 1.5,
+					"y": nil,
+				},
+				"location": map[string]interface{}{
+					"lat": float32(51.5),
+					"day": int32(18994),
+				},
+			},
+		},
+		"unix timestamp": {
+			input: `{"id":1,"ts":1641031200}`,
+			output: map[string]interface{}{
+				"id": int64(1),
+				"ts": int64(1641031200000000),
+			},
+		},
+		"non string name": {
+			input: `{"id":1,"name":{"foo":"bar"}}`,
+			output: map[string]interface{}{
+				"id":   int64(1),
+				"name": `{"foo":"bar"}`,
+			},
+		},
+		"missing required": {
+			input:       `{"name":"foo"}`,
+			errContains: "required field id is missing",
+		},
+		"missing nested required": {
+			input:       `{"id":1,"location":{}}`,
+			errContains: "field location: required field lat is missing",
+		},
+		"null list element": {
+			input:       `{"id":1,"tags":["a",null]}`,
+			errContains: "element 1: required element is null",
+		},
+		"bad long": {
+			input:       `{"id":1.5}`,
+			errContains: "field id",
+		},
+		"bad timestamp": {
+			input:       `{"id":1,"ts":"not a time"}`,
+			errContains: "failed to parse timestamp",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var v interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &v))
+
+			row, err := schema.icebergRow(v)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, row)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// icebergSnapshot is a snapshot of the table metadata.
+type icebergSnapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+// icebergTableMetadata contains the fields of the table metadata that are
+// required in order to append data files to a table.
+type icebergTableMetadata struct {
+	FormatVersion      int                     `json:"format-version"`
+	TableUUID          string                  `json:"table-uuid"`
+	Location           string                  `json:"location"`
+	LastSequenceNumber int64                   `json:"last-sequence-number"`
+	CurrentSchemaID    int                     `json:"current-schema-id"`
+	Schemas            []json.RawMessage       `json:"schemas"`
+	DefaultSpecID      int                     `json:"default-spec-id"`
+	PartitionSpecs     []*icebergPartitionSpec `json:"partition-specs"`
+	CurrentSnapshotID  *int64                  `json:"current-snapshot-id"`
+	Snapshots          []*icebergSnapshot      `json:"snapshots"`
+	Properties         map[string]string       `json:"properties"`
+
+	// The raw metadata and the location of the metadata file, which is only
+	// known for catalogs that store table metadata files themselves.
+	raw          []byte
+	fileLocation string
+	versionID    string
+}
+
+func parseIcebergTableMetadata(b []byte) (*icebergTableMetadata, error) {
+	var m icebergTableMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse table metadata: %w", err)
+	}
+	if m.FormatVersion != 2 {
+		return nil, fmt.Errorf("table format version %v is not supported, only version 2 tables can be written to", m.FormatVersion)
+	}
+	if m.Location == "" {
+		return nil, errors.New("table metadata does not contain a location")
+	}
+	m.raw = b
+	return &m, nil
+}
+
+// currentSchema returns the current schema of the table along with its raw
+// JSON representation.
+func (m *icebergTableMetadata) currentSchema() (*icebergSchema, []byte, error) {
+	for _, raw := range m.Schemas {
+		var s icebergSchema
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse table schema: %w", err)
+		}
+		if s.SchemaID == m.CurrentSchemaID {
+			return &s, raw, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("current schema %v not found in table metadata", m.CurrentSchemaID)
+}
+
+func (m *icebergTableMetadata) defaultSpec() (*icebergPartitionSpec, error) {
+	for _, s := range m.PartitionSpecs {
+		if s.SpecID == m.DefaultSpecID {
+			return s, nil
+		}
+	}
+	if m.DefaultSpecID == 0 && len(m.PartitionSpecs) == 0 {
+		return &icebergPartitionSpec{}, nil
+	}
+	return nil, fmt.Errorf("default partition spec %v not found in table metadata", m.DefaultSpecID)
+}
+
+func (m *icebergTableMetadata) currentSnapshot() *icebergSnapshot {
+	if m.CurrentSnapshotID == nil || *m.CurrentSnapshotID == -1 {
+		return nil
+	}
+	for _, s := range m.Snapshots {
+		if s.SnapshotID == *m.CurrentSnapshotID {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *icebergTableMetadata) dataLocation() string {
+	if p := m.Properties["write.data.path"]; p != "" {
+		return strings.TrimSuffix(p, "/")
+	}
+	return strings.TrimSuffix(m.Location, "/") + "/data"
+}
+
+func (m *icebergTableMetadata) metadataLocation() string {
+	if p := m.Properties["write.metadata.path"]; p != "" {
+		return strings.TrimSuffix(p, "/")
+	}
+	return strings.TrimSuffix(m.Location, "/") + "/metadata"
+}
+
+//------------------------------------------------------------------------------
+
+// errIcebergCommitConflict is returned by catalogs when a commit failed due to
+// the table being modified concurrently, in which case the commit can be
+// attempted again from the latest table metadata.
+var errIcebergCommitConflict = errors.New("table was modified concurrently")
+
+// icebergCatalog loads and commits snapshots to tables of a catalog.
+type icebergCatalog interface {
+	// loadTable returns the latest metadata of the table.
+	loadTable(ctx context.Context) (*icebergTableMetadata, error)
+
+	// commitSnapshot adds a snapshot to the table and makes it the current
+	// snapshot of the main branch, where base is the metadata the snapshot
+	// was created from.
+	commitSnapshot(ctx context.Context, base *icebergTableMetadata, snapshot *icebergSnapshot) (*icebergTableMetadata, error)
+}
@@ -0,0 +1,381 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+// TestFailedError is returned when the test operation of a JSON Patch document
+// does not match the document being patched.
+type TestFailedError struct {
+	Path string
+}
+
+func (e *TestFailedError) Error() string {
+	return fmt.Sprintf("test operation failed: value at path '%v' does not match", e.Path)
+}
+
+type patchOp struct {
+	op    string
+	path  []string
+	from  []string
+	value interface{}
+
+	rawPath string
+}
+
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("json pointer '%v' must begin with '/'", ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// parsePatch parses a structured RFC 6902 JSON Patch document.
+func parsePatch(v interface{}) ([]patchOp, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected patch to be an array of operations, got %T", v)
+	}
+
+	ops := make([]patchOp, len(arr))
+	for i, e := range arr {
+		obj, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %v: expected object, got %T", i, e)
+		}
+
+		var op patchOp
+		if op.op, ok = obj["op"].(string); !ok {
+			return nil, fmt.Errorf("operation %v: missing op", i)
+		}
+		if op.rawPath, ok = obj["path"].(string); !ok {
+			return nil, fmt.Errorf("operation %v: missing path", i)
+		}
+
+		var err error
+		if op.path, err = parsePointer(op.rawPath); err != nil {
+			return nil, fmt.Errorf("operation %v: %w", i, err)
+		}
+
+		switch op.op {
+		case "add", "replace", "test":
+			if op.value, ok = obj["value"]; !ok {
+				return nil, fmt.Errorf("operation %v: missing value", i)
+			}
+		case "move", "copy":
+			fromStr, ok := obj["from"].(string)
+			if !ok {
+				return nil, fmt.Errorf("operation %v: missing from", i)
+			}
+			if op.from, err = parsePointer(fromStr); err != nil {
+				return nil, fmt.Errorf("operation %v: %w", i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %v: unrecognised op: %v", i, op.op)
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+//------------------------------------------------------------------------------
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index: %v", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index: %v", token)
+	}
+	if i > length || (!allowEnd && i == length) {
+		return 0, fmt.Errorf("array index %v out of bounds", i)
+	}
+	return i, nil
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, t := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, exists := node[t]
+			if !exists {
+				return nil, fmt.Errorf("key '%v' does not exist", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("unable to traverse '%v' of %T", t, doc)
+		}
+	}
+	return doc, nil
+}
+
+// modifyParent calls fn with the container of the last token of a path and
+// returns the document with the container replaced by the result of fn, which
+// is necessary as inserting into an array reallocates it.
+func modifyParent(doc interface{}, path []string, fn func(container interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, exists := node[path[0]]
+		if !exists {
+			return nil, fmt.Errorf("key '%v' does not exist", path[0])
+		}
+		newChild, err := modifyParent(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = newChild
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := modifyParent(node[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = newChild
+		return node, nil
+	}
+	return nil, fmt.Errorf("unable to traverse '%v' of %T", path[0], doc)
+}
+
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modifyParent(doc, path, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("unable to add '%v' to %T", key, container)
+	})
+}
+
+func removeValue(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("unable to remove the root of a document")
+	}
+	return modifyParent(doc, path, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, exists := c[key]; !exists {
+				return nil, fmt.Errorf("key '%v' does not exist", key)
+			}
+			delete(c, key)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("unable to remove '%v' from %T", key, container)
+	})
+}
+
+func replaceValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modifyParent(doc, path, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, exists := c[key]; !exists {
+				return nil, fmt.Errorf("key '%v' does not exist", key)
+			}
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("unable to replace '%v' of %T", key, container)
+	})
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, t := range prefix {
+		if path[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// applyPatch applies a parsed JSON Patch to a document, which is modified in
+// place and may therefore be left partially patched when an error is returned.
+func applyPatch(doc interface{}, ops []patchOp) (interface{}, error) {
+	for i, op := range ops {
+		var err error
+		switch op.op {
+		case "add":
+			var value interface{}
+			if value, err = message.CopyJSON(op.value); err == nil {
+				doc, err = addValue(doc, op.path, value)
+			}
+		case "remove":
+			doc, err = removeValue(doc, op.path)
+		case "replace":
+			var value interface{}
+			if value, err = message.CopyJSON(op.value); err == nil {
+				doc, err = replaceValue(doc, op.path, value)
+			}
+		case "move":
+			if isPrefix(op.from, op.path) && len(op.from) < len(op.path) {
+				err = errors.New("unable to move a value into one of its children")
+				break
+			}
+			var value interface{}
+			if value, err = getValue(doc, op.from); err == nil {
+				if doc, err = removeValue(doc, op.from); err == nil {
+					doc, err = addValue(doc, op.path, value)
+				}
+			}
+		case "copy":
+			var value interface{}
+			if value, err = getValue(doc, op.from); err == nil {
+				if value, err = message.CopyJSON(value); err == nil {
+					doc, err = addValue(doc, op.path, value)
+				}
+			}
+		case "test":
+			var value interface{}
+			if value, err = getValue(doc, op.path); err == nil && !jsonEqual(value, op.value) {
+				return nil, &TestFailedError{Path: op.rawPath}
+			}
+		}
+		if err != nil {
+			if op.op == "test" {
+				return nil, &TestFailedError{Path: op.rawPath}
+			}
+			return nil, fmt.Errorf("operation %v (%v): %w", i, op.op, err)
+		}
+	}
+	return doc, nil
+}
+
+//------------------------------------------------------------------------------
+
+// mergePatch applies an RFC 7386 JSON Merge Patch to a document.
+func mergePatch(doc, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(docObj, k)
+			continue
+		}
+		docObj[k] = mergePatch(docObj[k], v)
+	}
+	return docObj
+}
+
+//------------------------------------------------------------------------------
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+// jsonEqual compares two structured values, where numbers are equal when their
+// values are equal regardless of their representation.
+func jsonEqual(a, b interface{}) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	switch at := a.(type) {
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, av := range at {
+			bv, exists := bt[k]
+			if !exists || !jsonEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !jsonEqual(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
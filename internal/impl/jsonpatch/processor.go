@@ -0,0 +1,141 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func processorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Mapping").
+		Summary("Applies an [RFC 6902](https://datatracker.ietf.org/doc/html/rfc6902) JSON Patch or an [RFC 7386](https://datatracker.ietf.org/doc/html/rfc7386) JSON Merge Patch document to messages.").
+		Description(`
+The patch document can either be static or taken from the metadata or a field of each message with [interpolation functions](/docs/configuration/interpolation#bloblang-queries). The contents of a message are replaced with the patched document only when the patch is applied in full, if any operation fails the message is left unchanged and flagged as having failed.
+
+When a `+"`test`"+` operation of a JSON Patch does not match the document the error of the message begins with `+"`test operation failed`"+`, which allows these messages to be routed separately from other errors using [error handling methods](/docs/configuration/error_handling).`).
+		Field(service.NewStringAnnotatedEnumField("format", map[string]string{
+			"json_patch":  "An RFC 6902 JSON Patch document, which is an array of operations.",
+			"merge_patch": "An RFC 7386 JSON Merge Patch document, which is an object merged into the message where null values remove fields.",
+		}).Description("The format of the patch document.").
+			Default("json_patch")).
+		Field(service.NewInterpolatedStringField("patch").
+			Description("The patch document to apply, which can be interpolated in order to take it from the metadata or a field of each message.").
+			Example(`[{"op":"replace","path":"/status","value":"processed"}]`).
+			Example(`${! meta("patch") }`).
+			Example(`${! json("patch") }`)).
+		Example(
+			"Routing Failed Tests",
+			"In this example we only update the status of documents that are currently pending, and messages where the `test` operation fails are routed to a separate output along with their error.",
+			`
+pipeline:
+  processors:
+    - json_patch:
+        patch: |
+          [
+            { "op": "test", "path": "/status", "value": "pending" },
+            { "op": "replace", "path": "/status", "value": "processed" },
+            { "op": "add", "path": "/tags/-", "value": "patched" }
+          ]
+
+output:
+  switch:
+    cases:
+      - check: errored() && error().has_prefix("test operation failed")
+        output:
+          file:
+            path: ./rejected.jsonl
+            codec: lines
+          processors:
+            - bloblang: 'root = this.merge({"error": error()})'
+      - output:
+          stdout: {}
+`).
+		Example(
+			"Merge Patch From Metadata",
+			"Merge patches are convenient for setting and removing fields of objects, here the patch is taken from the metadata of each message.",
+			`
+pipeline:
+  processors:
+    - json_patch:
+        format: merge_patch
+        patch: ${! meta("update") }
+`).
+		Version("3.64.0")
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"json_patch", processorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newProcessorFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type processor struct {
+	mergePatch bool
+	patch      *service.InterpolatedString
+}
+
+func newProcessorFromConfig(conf *service.ParsedConfig) (*processor, error) {
+	format, err := conf.FieldString("format")
+	if err != nil {
+		return nil, err
+	}
+	if format != "json_patch" && format != "merge_patch" {
+		return nil, fmt.Errorf("format not recognised: %v", format)
+	}
+	patch, err := conf.FieldInterpolatedString("patch")
+	if err != nil {
+		return nil, err
+	}
+	return &processor{
+		mergePatch: format == "merge_patch",
+		patch:      patch,
+	}, nil
+}
+
+func (p *processor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	dec := json.NewDecoder(bytes.NewReader(p.patch.Bytes(msg)))
+	dec.UseNumber()
+
+	var patch interface{}
+	if err := dec.Decode(&patch); err != nil {
+		return nil, fmt.Errorf("failed to parse patch document: %w", err)
+	}
+
+	doc, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	if p.mergePatch {
+		doc = mergePatch(doc, patch)
+	} else {
+		ops, err := parsePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse patch document: %w", err)
+		}
+		if doc, err = applyPatch(doc, ops); err != nil {
+			return nil, err
+		}
+	}
+
+	resMsg := msg.Copy()
+	resMsg.SetStructured(doc)
+	return service.MessageBatch{resMsg}, nil
+}
+
+func (p *processor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,201 @@
+package jsonpatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := map[string]struct {
+		patch  string
+		input  string
+		output string
+		err    string
+	}{
+		"add and remove": {
+			patch:  `[{"op":"add","path":"/b","value":{"c":1}},{"op":"remove","path":"/a"}]`,
+			input:  `{"a":"foo"}`,
+			output: `{"b":{"c":1}}`,
+		},
+		"array operations": {
+			patch: `[
+				{"op":"add","path":"/items/1","value":"x"},
+				{"op":"add","path":"/items/-","value":"z"},
+				{"op":"remove","path":"/items/0"},
+				{"op":"replace","path":"/items/0","value":"y"}
+			]`,
+			input:  `{"items":["a","b"]}`,
+			output: `{"items":["y","b","z"]}`,
+		},
+		"move and copy": {
+			patch:  `[{"op":"copy","from":"/a","path":"/b"},{"op":"move","from":"/a/x","path":"/c"}]`,
+			input:  `{"a":{"x":1}}`,
+			output: `{"a":{},"b":{"x":1},"c":1}`,
+		},
+		"escaped pointers": {
+			patch:  `[{"op":"replace","path":"/a~1b/c~0d","value":true}]`,
+			input:  `{"a/b":{"c~d":false}}`,
+			output: `{"a/b":{"c~d":true}}`,
+		},
+		"replace root": {
+			patch:  `[{"op":"replace","path":"","value":[1,2]}]`,
+			input:  `{"a":"foo"}`,
+			output: `[1,2]`,
+		},
+		"passing test": {
+			patch:  `[{"op":"test","path":"/a","value":{"n":1.0,"l":[true]}},{"op":"add","path":"/b","value":null}]`,
+			input:  `{"a":{"l":[true],"n":1}}`,
+			output: `{"a":{"l":[true],"n":1},"b":null}`,
+		},
+		"failing test": {
+			patch: `[{"op":"replace","path":"/a","value":"bar"},{"op":"test","path":"/a","value":"baz"}]`,
+			input: `{"a":"foo"}`,
+			err:   `test operation failed: value at path '/a' does not match`,
+		},
+		"test of missing path": {
+			patch: `[{"op":"test","path":"/b","value":"foo"}]`,
+			input: `{"a":"foo"}`,
+			err:   `test operation failed: value at path '/b' does not match`,
+		},
+		"replace missing key": {
+			patch: `[{"op":"replace","path":"/b","value":"foo"}]`,
+			input: `{"a":"foo"}`,
+			err:   `operation 0 (replace): key 'b' does not exist`,
+		},
+		"index out of bounds": {
+			patch: `[{"op":"add","path":"/items/3","value":"foo"}]`,
+			input: `{"items":[]}`,
+			err:   `operation 0 (add): array index 3 out of bounds`,
+		},
+		"move into child": {
+			patch: `[{"op":"move","from":"/a","path":"/a/b"}]`,
+			input: `{"a":{}}`,
+			err:   `operation 0 (move): unable to move a value into one of its children`,
+		},
+		"bad op": {
+			patch: `[{"op":"nope","path":"/a"}]`,
+			input: `{"a":"foo"}`,
+			err:   `failed to parse patch document: operation 0: unrecognised op: nope`,
+		},
+		"not an array": {
+			patch: `{"op":"add","path":"/a","value":1}`,
+			input: `{}`,
+			err:   `failed to parse patch document: expected patch to be an array of operations, got map[string]interface {}`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := processorConfig().ParseYAML(`patch: '`+test.patch+`'`, nil)
+			require.NoError(t, err)
+
+			proc, err := newProcessorFromConfig(conf)
+			require.NoError(t, err)
+
+			inMsg := service.NewMessage([]byte(test.input))
+			batch, err := proc.Process(context.Background(), inMsg)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+
+				b, err := inMsg.AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, test.input, string(b))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			b, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+}
+
+func TestJSONPatchTestFailedError(t *testing.T) {
+	conf, err := processorConfig().ParseYAML(`patch: '[{"op":"test","path":"/a","value":1}]'`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProcessorFromConfig(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"a":2}`)))
+
+	var testErr *TestFailedError
+	require.True(t, errors.As(err, &testErr))
+	assert.Equal(t, "/a", testErr.Path)
+}
+
+func TestJSONMergePatch(t *testing.T) {
+	tests := map[string]struct {
+		patch  string
+		input  string
+		output string
+	}{
+		"rfc example": {
+			patch:  `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`,
+			input:  `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`,
+			output: `{"author":{"givenName":"John"},"content":"This will be unchanged","phoneNumber":"+01-123-456-7890","tags":["example"],"title":"Hello!"}`,
+		},
+		"nested object created": {
+			patch:  `{"a":{"b":{"c":null,"d":1}}}`,
+			input:  `{"a":"foo"}`,
+			output: `{"a":{"b":{"d":1}}}`,
+		},
+		"non object patch": {
+			patch:  `["foo"]`,
+			input:  `{"a":"foo"}`,
+			output: `["foo"]`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := processorConfig().ParseYAML(`
+format: merge_patch
+patch: '`+test.patch+`'
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := newProcessorFromConfig(conf)
+			require.NoError(t, err)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			b, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+}
+
+func TestJSONPatchFromMetadata(t *testing.T) {
+	conf, err := processorConfig().ParseYAML(`patch: '${! meta("patch") }'`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProcessorFromConfig(conf)
+	require.NoError(t, err)
+
+	inMsg := service.NewMessage([]byte(`{"a":"foo"}`))
+	inMsg.MetaSet("patch", `[{"op":"add","path":"/b","value":"bar"}]`)
+
+	batch, err := proc.Process(context.Background(), inMsg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"foo","b":"bar"}`, string(b))
+
+	v, _ := batch[0].MetaGet("patch")
+	assert.Equal(t, `[{"op":"add","path":"/b","value":"bar"}]`, v)
+}
@@ -129,6 +129,39 @@ input:
 		}),
 		integration.StreamTestOptPort(kafkaPortStr),
 	)
+
+	t.Run("with transactions", func(t *testing.T) {
+		t.Parallel()
+
+		txnTemplate := `
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:$PORT ]
+    topic: topic-$ID
+    max_in_flight: $MAX_IN_FLIGHT
+    metadata:
+      include_patterns: [ .* ]
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+    transaction:
+      id: txn-$ID
+
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:$PORT ]
+    topics: [ topic-$ID$VAR1 ]
+    consumer_group: "$VAR4"
+    checkpoint_limit: 100
+`
+		suite.Run(
+			t, txnTemplate,
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, testID string, vars *integration.StreamTestConfigVars) {
+				vars.Var4 = "group" + testID
+				require.NoError(t, createKafkaTopic("localhost:"+kafkaPortStr, testID, 4))
+			}),
+			integration.StreamTestOptPort(kafkaPortStr),
+		)
+	})
 }
 
 func createKafkaTopicSasl(address, id string, partitions int32) error {
@@ -3,14 +3,19 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/service"
 	"github.com/dustin/go-humanize"
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 )
 
@@ -28,6 +33,14 @@ This input is new and experimental, and the existing ` + "`kafka`" + ` input is
 - You like shiny new stuff
 - You are exeriencing issues with the existing ` + "`kafka`" + ` input
 - Someone told you to
+
+### Transactions
+
+When ` + "`transaction.id`" + ` is set each batch is written within a Kafka transaction, which is committed once all messages of the batch have been written and aborted otherwise. Consumers of the output topics must use a ` + "`read_committed`" + ` isolation level in order to ignore messages of aborted transactions. When transactions are enabled ` + "`max_in_flight`" + ` is ignored and batches are written one at a time.
+
+When ` + "`transaction.consumer_group`" + ` is also set the offsets of consumed messages, obtained from the ` + "`kafka_topic`" + `, ` + "`kafka_partition`" + ` and ` + "`kafka_offset`" + ` metadata fields added by the ` + "`kafka`" + ` and ` + "`kafka_franz`" + ` inputs, are committed for that consumer group within the same transaction. This results in exactly-once delivery for pipelines that consume from and write to Kafka, provided that the input consumes as the same consumer group and that no batch mixes messages from multiple inputs.
+
+When ` + "`transaction.defer_commit`" + ` is enabled the transaction of each batch is left open after its messages have been written, and is only committed once the input that the messages originated from has acknowledged them with its source, such as by committing an offset. If the input fails to acknowledge the messages the transaction is aborted instead. The next batch is not written until the open transaction has ended, and inputs that do not support commit callbacks, or messages created by processors, cause the transaction to be committed immediately.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -61,6 +74,28 @@ This input is new and experimental, and the existing ` + "`kafka`" + ` input is
 			Description("Optionally set an explicit compression type. The default preference is to use snappy when the broker supports it, and fall back to none if not.").
 			Optional().
 			Advanced()).
+		Field(service.NewBoolField("idempotent_write").
+			Description("Enable the idempotent write producer option, which prevents retried writes from producing duplicate messages. This requires the `IDEMPOTENT_WRITE` permission on `CLUSTER` and can be disabled if this permission is not available.").
+			Default(true).
+			Advanced().
+			Version("3.64.0")).
+		Field(service.NewObjectField("transaction",
+			service.NewStringField("id").
+				Description("A transactional ID that uniquely identifies this producer across restarts. When set batches are written within transactions. Each instance of a pipeline must use a distinct ID.").
+				Default(""),
+			service.NewStringField("timeout").
+				Description("The maximum time that a transaction may remain open before it is aborted by the broker.").
+				Default("40s"),
+			service.NewStringField("consumer_group").
+				Description("An optional consumer group to commit the offsets of consumed messages for within each transaction.").
+				Default(""),
+			service.NewBoolField("defer_commit").
+				Description("Whether the transaction of each batch should be committed only once the input that the messages originated from has acknowledged them with its source.").
+				Default(false),
+		).
+			Description("Optionally write batches within Kafka transactions.").
+			Advanced().
+			Version("3.64.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField)
 }
@@ -79,7 +114,15 @@ func init() {
 			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 				return
 			}
-			output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger())
+			var w *franzKafkaWriter
+			if w, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger()); err != nil {
+				return
+			}
+			if w.txnID != "" {
+				// Transactions of a producer can't overlap.
+				maxInFlight = 1
+			}
+			output = w
 			return
 		})
 
@@ -101,9 +144,19 @@ type franzKafkaWriter struct {
 	partitioner      kgo.Partitioner
 	produceMaxBytes  int32
 	compressionPrefs []kgo.CompressionCodec
+	idempotentWrite  bool
+	txnID            string
+	txnTimeout       time.Duration
+	txnGroup         string
+	txnDeferCommit   bool
 
 	client *kgo.Client
 
+	// Closed once the deferred end of the open transaction has been resolved,
+	// with the error of ending it.
+	txnPending    chan struct{}
+	txnPendingErr error
+
 	log     *service.Logger
 	shutSig *shutdown.Signaller
 }
@@ -192,6 +245,37 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		}
 	}
 
+	if f.idempotentWrite, err = conf.FieldBool("idempotent_write"); err != nil {
+		return nil, err
+	}
+
+	txnConf := conf.Namespace("transaction")
+	if f.txnID, err = txnConf.FieldString("id"); err != nil {
+		return nil, err
+	}
+	if f.txnGroup, err = txnConf.FieldString("consumer_group"); err != nil {
+		return nil, err
+	}
+	if f.txnDeferCommit, err = txnConf.FieldBool("defer_commit"); err != nil {
+		return nil, err
+	}
+	if f.txnID != "" {
+		if !f.idempotentWrite {
+			return nil, errors.New("idempotent_write must be enabled in order to use transactions")
+		}
+		timeoutStr, err := txnConf.FieldString("timeout")
+		if err != nil {
+			return nil, err
+		}
+		if f.txnTimeout, err = time.ParseDuration(timeoutStr); err != nil {
+			return nil, fmt.Errorf("failed to parse transaction timeout: %w", err)
+		}
+	} else if f.txnGroup != "" {
+		return nil, errors.New("a transaction id must be specified in order to commit consumer offsets")
+	} else if f.txnDeferCommit {
+		return nil, errors.New("a transaction id must be specified in order to defer commits")
+	}
+
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
 		return nil, err
@@ -229,6 +313,12 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
+	if !f.idempotentWrite {
+		clientOpts = append(clientOpts, kgo.DisableIdempotentWrite())
+	}
+	if f.txnID != "" {
+		clientOpts = append(clientOpts, kgo.TransactionalID(f.txnID), kgo.TransactionTimeout(f.txnTimeout))
+	}
 
 	cl, err := kgo.NewClient(clientOpts...)
 	if err != nil {
@@ -264,12 +354,192 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		records = append(records, record)
 	}
 
+	if f.txnID != "" {
+		var offsets map[string]map[int32]int64
+		if f.txnGroup != "" {
+			if offsets, err = txnOffsetsFromBatch(b); err != nil {
+				return
+			}
+		}
+		err = f.writeTransaction(ctx, b, records, offsets)
+		return
+	}
+
 	// TODO: This is very cool and allows us to easily return granular errors,
 	// so we should honor travis by doing it.
 	err = f.client.ProduceSync(ctx, records...).FirstErr()
 	return
 }
 
+// txnOffsetsFromBatch returns the next offset to consume of each topic
+// partition that messages of a batch were consumed from, according to the
+// metadata added by the kafka inputs.
+func txnOffsetsFromBatch(b service.MessageBatch) (map[string]map[int32]int64, error) {
+	offsets := map[string]map[int32]int64{}
+	for _, msg := range b {
+		topic, _ := msg.MetaGet("kafka_topic")
+		partStr, _ := msg.MetaGet("kafka_partition")
+		offsetStr, _ := msg.MetaGet("kafka_offset")
+		if topic == "" || partStr == "" || offsetStr == "" {
+			continue
+		}
+		partition, err := strconv.ParseInt(partStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kafka_partition metadata: %w", err)
+		}
+		offset, err := strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kafka_offset metadata: %w", err)
+		}
+		partOffsets := offsets[topic]
+		if partOffsets == nil {
+			partOffsets = map[int32]int64{}
+			offsets[topic] = partOffsets
+		}
+		if current, exists := partOffsets[int32(partition)]; !exists || offset+1 > current {
+			partOffsets[int32(partition)] = offset + 1
+		}
+	}
+	return offsets, nil
+}
+
+// writeTransaction writes records and commits consumer offsets within a single
+// transaction, which is aborted if any step fails. When commits are deferred
+// the transaction is left open until the sources of the batch have been
+// acknowledged.
+func (f *franzKafkaWriter) writeTransaction(ctx context.Context, b service.MessageBatch, records []*kgo.Record, offsets map[string]map[int32]int64) error {
+	if err := f.awaitPendingTransaction(ctx); err != nil {
+		return err
+	}
+
+	if err := f.client.BeginTransaction(); err != nil {
+		f.disconnect()
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err := f.client.ProduceSync(ctx, records...).FirstErr()
+	if err == nil && len(offsets) > 0 {
+		// Produced records carry the producer ID and epoch of the transaction.
+		err = f.commitTxnOffsets(ctx, records[0].ProducerID, records[0].ProducerEpoch, offsets)
+	}
+
+	if err == nil && f.txnDeferCommit && f.deferTransactionEnd(b.OnCommit, f.client.EndTransaction) {
+		return nil
+	}
+
+	commit := kgo.TryCommit
+	if err != nil {
+		commit = kgo.TryAbort
+	}
+	if endErr := f.client.EndTransaction(ctx, commit); endErr != nil {
+		// The producer may be left in an unusable state so we reconnect.
+		f.disconnect()
+		if err == nil {
+			err = fmt.Errorf("failed to commit transaction: %w", endErr)
+		}
+	}
+	return err
+}
+
+// deferTransactionEnd registers a commit hook that ends the open transaction
+// once the sources of a batch have been acknowledged, committing it when they
+// were acknowledged successfully and aborting it otherwise. Returns false if
+// the hook could not be registered, in which case the transaction should be
+// ended immediately.
+func (f *franzKafkaWriter) deferTransactionEnd(
+	onCommit func(fn func(ctx context.Context, err error) error) bool,
+	endTxn func(ctx context.Context, commit kgo.TransactionEndTry) error,
+) bool {
+	pending := make(chan struct{})
+	f.txnPending, f.txnPendingErr = pending, nil
+
+	if !onCommit(func(ctx context.Context, err error) error {
+		defer close(pending)
+
+		commit := kgo.TryCommit
+		if err != nil {
+			f.log.Debugf("Aborting deferred transaction as the source was not acknowledged: %v", err)
+			commit = kgo.TryAbort
+		}
+		if endErr := endTxn(ctx, commit); endErr != nil {
+			f.txnPendingErr = endErr
+			return fmt.Errorf("failed to end deferred transaction: %w", endErr)
+		}
+		return nil
+	}) {
+		f.txnPending = nil
+		return false
+	}
+	return true
+}
+
+// awaitPendingTransaction blocks until the deferred end of the open
+// transaction, if any, has been resolved, as transactions of a producer can't
+// overlap.
+func (f *franzKafkaWriter) awaitPendingTransaction(ctx context.Context) error {
+	if f.txnPending == nil {
+		return nil
+	}
+	select {
+	case <-f.txnPending:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	f.txnPending = nil
+	if f.txnPendingErr != nil {
+		// The producer may be left in an unusable state so we reconnect.
+		f.disconnect()
+		return fmt.Errorf("failed to end deferred transaction: %w", f.txnPendingErr)
+	}
+	return nil
+}
+
+func (f *franzKafkaWriter) commitTxnOffsets(ctx context.Context, producerID int64, producerEpoch int16, offsets map[string]map[int32]int64) error {
+	addReq := kmsg.NewPtrAddOffsetsToTxnRequest()
+	addReq.TransactionalID = f.txnID
+	addReq.ProducerID = producerID
+	addReq.ProducerEpoch = producerEpoch
+	addReq.Group = f.txnGroup
+
+	addRes, err := addReq.RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	if err := kerr.ErrorForCode(addRes.ErrorCode); err != nil {
+		return fmt.Errorf("failed to add offsets to transaction: %w", err)
+	}
+
+	commitReq := kmsg.NewPtrTxnOffsetCommitRequest()
+	commitReq.TransactionalID = f.txnID
+	commitReq.Group = f.txnGroup
+	commitReq.ProducerID = producerID
+	commitReq.ProducerEpoch = producerEpoch
+	for topic, partitions := range offsets {
+		reqTopic := kmsg.NewTxnOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+		for partition, offset := range partitions {
+			reqPart := kmsg.NewTxnOffsetCommitRequestTopicPartition()
+			reqPart.Partition = partition
+			reqPart.Offset = offset
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPart)
+		}
+		commitReq.Topics = append(commitReq.Topics, reqTopic)
+	}
+
+	commitRes, err := commitReq.RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	for _, t := range commitRes.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("failed to commit offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (f *franzKafkaWriter) disconnect() {
 	if f.client == nil {
 		return
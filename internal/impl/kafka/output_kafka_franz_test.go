@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestFranzKafkaWriterTransactionConfig(t *testing.T) {
+	spec := franzKafkaOutputConfig()
+
+	conf, err := spec.ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: foo
+transaction:
+  id: foo-txn
+  timeout: 10s
+  consumer_group: bar
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo-txn", w.txnID)
+	assert.Equal(t, "10s", w.txnTimeout.String())
+	assert.Equal(t, "bar", w.txnGroup)
+	assert.True(t, w.idempotentWrite)
+}
+
+func TestFranzKafkaWriterTransactionConfigErrors(t *testing.T) {
+	spec := franzKafkaOutputConfig()
+
+	tests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "group without id",
+			config: `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+transaction:
+  consumer_group: bar
+`,
+			errContains: "a transaction id must be specified",
+		},
+		{
+			name: "defer commit without id",
+			config: `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+transaction:
+  defer_commit: true
+`,
+			errContains: "a transaction id must be specified in order to defer commits",
+		},
+		{
+			name: "idempotency disabled",
+			config: `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+idempotent_write: false
+transaction:
+  id: foo-txn
+`,
+			errContains: "idempotent_write must be enabled",
+		},
+		{
+			name: "bad timeout",
+			config: `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+transaction:
+  id: foo-txn
+  timeout: nope
+`,
+			errContains: "failed to parse transaction timeout",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := spec.ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newFranzKafkaWriterFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestTxnOffsetsFromBatch(t *testing.T) {
+	newMsg := func(topic, partition, offset string) *service.Message {
+		msg := service.NewMessage(nil)
+		msg.MetaSet("kafka_topic", topic)
+		msg.MetaSet("kafka_partition", partition)
+		msg.MetaSet("kafka_offset", offset)
+		return msg
+	}
+
+	offsets, err := txnOffsetsFromBatch(service.MessageBatch{
+		newMsg("foo", "0", "10"),
+		newMsg("foo", "0", "12"),
+		newMsg("foo", "0", "11"),
+		newMsg("foo", "1", "3"),
+		newMsg("bar", "2", "0"),
+		service.NewMessage(nil),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]map[int32]int64{
+		"foo": {0: 13, 1: 4},
+		"bar": {2: 1},
+	}, offsets)
+
+	_, err = txnOffsetsFromBatch(service.MessageBatch{
+		newMsg("foo", "0", "nope"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kafka_offset")
+}
+
+func TestFranzKafkaWriterDeferTransactionEnd(t *testing.T) {
+	w := &franzKafkaWriter{}
+
+	var hook func(ctx context.Context, err error) error
+	onCommit := func(fn func(ctx context.Context, err error) error) bool {
+		hook = fn
+		return true
+	}
+
+	var ended []kgo.TransactionEndTry
+	endTxn := func(ctx context.Context, commit kgo.TransactionEndTry) error {
+		ended = append(ended, commit)
+		return nil
+	}
+
+	ctx := context.Background()
+
+	require.True(t, w.deferTransactionEnd(onCommit, endTxn))
+	assert.Empty(t, ended)
+	require.NoError(t, hook(ctx, nil))
+	require.NoError(t, w.awaitPendingTransaction(ctx))
+	assert.Equal(t, []kgo.TransactionEndTry{kgo.TryCommit}, ended)
+
+	require.True(t, w.deferTransactionEnd(onCommit, endTxn))
+	require.NoError(t, hook(ctx, errors.New("ack failed")))
+	require.NoError(t, w.awaitPendingTransaction(ctx))
+	assert.Equal(t, []kgo.TransactionEndTry{kgo.TryCommit, kgo.TryAbort}, ended)
+
+	require.True(t, w.deferTransactionEnd(onCommit, func(ctx context.Context, commit kgo.TransactionEndTry) error {
+		return errors.New("broker gone")
+	}))
+	require.EqualError(t, hook(ctx, nil), "failed to end deferred transaction: broker gone")
+	require.EqualError(t, w.awaitPendingTransaction(ctx), "failed to end deferred transaction: broker gone")
+	require.NoError(t, w.awaitPendingTransaction(ctx))
+
+	assert.False(t, w.deferTransactionEnd(func(fn func(ctx context.Context, err error) error) bool {
+		return false
+	}, endTxn))
+	require.NoError(t, w.awaitPendingTransaction(ctx))
+	assert.Len(t, ended, 2)
+}
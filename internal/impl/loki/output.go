@@ -0,0 +1,520 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/golang/snappy"
+)
+
+const (
+	// The default limits of label names and values enforced by Loki.
+	lokiMaxLabelNameLength  = 1024
+	lokiMaxLabelValueLength = 2048
+)
+
+var lokiLabelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func lokiOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Pushes message batches as log entries to [Grafana Loki](https://grafana.com/oss/loki/).").
+		Description(output.Description(true, true, `
+Each message becomes a log entry of the stream identified by its labels, which are resolved from the field `+"`labels`"+`. Labels that resolve to an empty string are omitted. Batches are sent as a single request to the push API, encoded as snappy compressed protobuf.
+
+### Timestamps
+
+The timestamp of each entry is set by the field `+"`timestamp`"+`, which must resolve to a timestamp in RFC 3339 format, and when omitted the time at which the entry is written is used instead. Messages where the timestamp cannot be parsed are rejected.
+
+### Ordering
+
+Unless Loki is configured to accept unordered writes, which is the default since version 2.4, it rejects entries that are older than the latest entry of their stream. When `+"`out_of_order`"+` is set to `+"`drop`"+` or `+"`clamp`"+` the entries of each stream are sorted by their timestamps before being sent, and the latest timestamp written to each stream is tracked in order to either drop older entries or replace their timestamps with the latest timestamp. Ordering is only guaranteed between batches when `+"`max_in_flight`"+` is 1.
+
+### Cardinality
+
+Every distinct combination of label values creates a new stream, and a large number of streams degrades the performance of Loki. The field `+"`max_streams`"+` limits the number of distinct streams that this output writes to, where entries of new streams beyond this limit are dropped. Entries with a label value longer than 2048 characters, or without any labels, are also dropped as they would be rejected by Loki.
+
+### Errors
+
+Requests that are rate limited (status 429) or fail with a server error are retried according to the field `+"`backoff`"+`. When Loki rejects the entries of a request (status 400), such as when they are too old, the entries are dropped as retrying them would not succeed.
+
+### Metrics
+
+The counter `+"`output_loki_dropped`"+` is incremented for each dropped entry, with the label `+"`reason`"+` set to one of `+"`out_of_order`"+`, `+"`max_streams`"+`, `+"`invalid_labels`"+` or `+"`rejected`"+`.`)).
+		Field(service.NewStringField("url").
+			Description("The base URL of the Loki server.").
+			Example("http://localhost:3100")).
+		Field(service.NewStringMapField("labels").
+			Description("A map of label names to values that identify the stream of each message. The values of this field support [interpolation functions](/docs/configuration/interpolation#bloblang-queries).").
+			Example(map[string]string{
+				"app":   "benthos",
+				"topic": `${! meta("kafka_topic") }`,
+			})).
+		Field(service.NewInterpolatedStringField("line").
+			Description("The log line of each entry.").
+			Default("${! content() }")).
+		Field(service.NewInterpolatedStringField("timestamp").
+			Description("An optional timestamp of each entry in RFC 3339 format. When omitted the time at which entries are written is used.").
+			Example(`${! json("time") }`).
+			Optional()).
+		Field(service.NewStringAnnotatedEnumField("out_of_order", map[string]string{
+			"allow": "Entries are sent in the order they are received, which requires Loki to accept unordered writes.",
+			"drop":  "Entries are sorted, and entries older than the latest entry written to their stream are dropped.",
+			"clamp": "Entries are sorted, and entries older than the latest entry written to their stream are given the timestamp of the latest entry.",
+		}).
+			Description("How to handle entries that are older than the latest entry of their stream.").
+			Default("drop")).
+		Field(service.NewIntField("max_streams").
+			Description("The maximum number of distinct streams to write to, where entries of new streams beyond this limit are dropped. Set to zero in order to disable the limit.").
+			Default(10000)).
+		Field(service.NewStringField("tenant_id").
+			Description("An optional tenant ID to send with requests with the `X-Scope-OrgID` header, which is required when Loki is configured with multi-tenancy.").
+			Default("").
+			Advanced()).
+		Field(service.NewObjectField("basic_auth",
+			service.NewBoolField("enabled").
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField("username").
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default(""),
+		).Description("Allows you to specify basic authentication.").Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to wait for each request to complete.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewBackOffField("backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying requests that were rate limited (status 429) or failed with a server error.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increasing this improves throughput, but entries are only guaranteed to be written in order when it is 1.").
+			Default(1)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Kubernetes Logs", `
+Push JSON logs consumed from Kafka to Loki, where the stream of each log is identified by its namespace and app:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ k8s_logs ]
+    consumer_group: benthos_loki
+
+output:
+  loki:
+    url: http://loki:3100
+    labels:
+      namespace: ${! json("kubernetes.namespace") }
+      app: ${! json("kubernetes.labels.app") }
+    line: ${! json("log") }
+    timestamp: ${! json("time") }
+    batching:
+      count: 1000
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"loki", lokiOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newLokiOutputFromConfig(conf, mgr.Logger(), mgr.Metrics())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lokiLabel struct {
+	name  string
+	value *service.InterpolatedString
+}
+
+type lokiOutput struct {
+	url      string
+	http     *http.Client
+	backoff  *backoff.ExponentialBackOff
+	tenantID string
+	username string
+	password string
+	log      *service.Logger
+
+	labels     []lokiLabel
+	line       *service.InterpolatedString
+	timestamp  *service.InterpolatedString
+	outOfOrder string
+	maxStreams int
+
+	mDropped *service.MetricCounter
+
+	// The latest timestamp written to each stream.
+	streamsMut sync.Mutex
+	streams    map[string]time.Time
+}
+
+func newLokiOutputFromConfig(conf *service.ParsedConfig, log *service.Logger, metrics *service.Metrics) (*lokiOutput, error) {
+	l := &lokiOutput{
+		log:      log,
+		mDropped: metrics.NewCounter("output_loki_dropped", "reason"),
+		streams:  map[string]time.Time{},
+	}
+
+	var err error
+	if l.url, err = conf.FieldString("url"); err != nil {
+		return nil, err
+	}
+	if l.url = strings.TrimSuffix(l.url, "/"); l.url == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	l.url += "/loki/api/v1/push"
+
+	labelStrs, err := conf.FieldStringMap("labels")
+	if err != nil {
+		return nil, err
+	}
+	if len(labelStrs) == 0 {
+		return nil, errors.New("at least one label must be specified")
+	}
+	for k, v := range labelStrs {
+		if len(k) > lokiMaxLabelNameLength || !lokiLabelNameRegexp.MatchString(k) {
+			return nil, fmt.Errorf("label name '%v' is invalid", k)
+		}
+		value, err := service.NewInterpolatedString(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse label %v expression: %v", k, err)
+		}
+		l.labels = append(l.labels, lokiLabel{name: k, value: value})
+	}
+	sort.Slice(l.labels, func(i, j int) bool {
+		return l.labels[i].name < l.labels[j].name
+	})
+
+	if l.line, err = conf.FieldInterpolatedString("line"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("timestamp") {
+		if l.timestamp, err = conf.FieldInterpolatedString("timestamp"); err != nil {
+			return nil, err
+		}
+	}
+	if l.outOfOrder, err = conf.FieldString("out_of_order"); err != nil {
+		return nil, err
+	}
+	switch l.outOfOrder {
+	case "allow", "drop", "clamp":
+	default:
+		return nil, fmt.Errorf("unrecognised out_of_order: %v", l.outOfOrder)
+	}
+	if l.maxStreams, err = conf.FieldInt("max_streams"); err != nil {
+		return nil, err
+	}
+	if l.tenantID, err = conf.FieldString("tenant_id"); err != nil {
+		return nil, err
+	}
+
+	authEnabled, err := conf.FieldBool("basic_auth", "enabled")
+	if err != nil {
+		return nil, err
+	}
+	if authEnabled {
+		if l.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if l.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	l.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		l.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	if l.backoff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lokiOutput) Connect(ctx context.Context) error {
+	l.log.Infof("Pushing log entries to Loki: %v\n", l.url)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// streamsFromBatch groups the messages of a batch into streams by their
+// labels, in the order that each stream first appears within the batch.
+func (l *lokiOutput) streamsFromBatch(batch service.MessageBatch, batchErr *service.BatchError) []*lokiStream {
+	var streams []*lokiStream
+	streamsByLabels := map[string]*lokiStream{}
+
+	now := time.Now()
+	for i := range batch {
+		labels := map[string]string{}
+		validLabels := true
+		for _, label := range l.labels {
+			value := batch.InterpolatedString(i, label.value)
+			if value == "" {
+				continue
+			}
+			if len(value) > lokiMaxLabelValueLength {
+				validLabels = false
+				break
+			}
+			labels[label.name] = value
+		}
+		if !validLabels || len(labels) == 0 {
+			l.mDropped.Incr(1, "invalid_labels")
+			l.log.Debugf("Dropping entry with invalid labels: %v\n", labels)
+			continue
+		}
+
+		entry := lokiEntry{
+			index: i,
+			ts:    now,
+			line:  batch.InterpolatedString(i, l.line),
+		}
+		if l.timestamp != nil {
+			tsStr := batch.InterpolatedString(i, l.timestamp)
+			ts, err := time.Parse(time.RFC3339Nano, tsStr)
+			if err != nil {
+				batchErr.Failed(i, fmt.Errorf("failed to parse timestamp '%v': %w", tsStr, err))
+				continue
+			}
+			entry.ts = ts
+		}
+
+		labelsStr := lokiLabelsString(labels)
+		stream, exists := streamsByLabels[labelsStr]
+		if !exists {
+			stream = &lokiStream{labels: labelsStr}
+			streamsByLabels[labelsStr] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, entry)
+	}
+	return streams
+}
+
+// enforceLimits removes streams that exceed the maximum number of streams and
+// entries that are out of order, and returns the latest timestamp of each
+// remaining stream.
+func (l *lokiOutput) enforceLimits(streams []*lokiStream) ([]*lokiStream, map[string]time.Time) {
+	l.streamsMut.Lock()
+	defer l.streamsMut.Unlock()
+
+	latest := make(map[string]time.Time, len(streams))
+	remaining := streams[:0]
+	newStreams := 0
+	for _, s := range streams {
+		last, known := l.streams[s.labels]
+		if !known {
+			if l.maxStreams > 0 && len(l.streams)+newStreams >= l.maxStreams {
+				l.mDropped.Incr(int64(len(s.entries)), "max_streams")
+				l.log.Debugf("Dropping %v entries of stream %v as the maximum number of streams has been reached\n", len(s.entries), s.labels)
+				continue
+			}
+			newStreams++
+		}
+
+		if l.outOfOrder != "allow" {
+			sort.SliceStable(s.entries, func(i, j int) bool {
+				return s.entries[i].ts.Before(s.entries[j].ts)
+			})
+			if known {
+				entries := s.entries[:0]
+				for _, e := range s.entries {
+					if e.ts.Before(last) {
+						if l.outOfOrder == "drop" {
+							l.mDropped.Incr(1, "out_of_order")
+							continue
+						}
+						e.ts = last
+					}
+					entries = append(entries, e)
+				}
+				s.entries = entries
+			}
+		}
+		if len(s.entries) == 0 {
+			continue
+		}
+
+		streamLatest := last
+		for _, e := range s.entries {
+			if e.ts.After(streamLatest) {
+				streamLatest = e.ts
+			}
+		}
+		latest[s.labels] = streamLatest
+		remaining = append(remaining, s)
+	}
+	return remaining, latest
+}
+
+// commitLatest records the latest timestamps of streams that were written.
+func (l *lokiOutput) commitLatest(latest map[string]time.Time) {
+	l.streamsMut.Lock()
+	defer l.streamsMut.Unlock()
+
+	for labels, ts := range latest {
+		if last, known := l.streams[labels]; known && last.After(ts) {
+			continue
+		}
+		if _, known := l.streams[labels]; !known && l.maxStreams > 0 && len(l.streams) >= l.maxStreams {
+			continue
+		}
+		l.streams[labels] = ts
+	}
+}
+
+// lokiRequestError is returned when Loki rejects a request.
+type lokiRequestError struct {
+	status int
+	body   string
+}
+
+func (e *lokiRequestError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("request failed with status %v", e.status)
+	}
+	return fmt.Sprintf("request failed with status %v: %v", e.status, e.body)
+}
+
+func (l *lokiOutput) push(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if l.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.tenantID)
+	}
+	if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+
+	res, err := l.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, _ := io.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &lokiRequestError{
+			status: res.StatusCode,
+			body:   string(bytes.TrimSpace(resBody)),
+		}
+	}
+	return nil
+}
+
+func (l *lokiOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to push entries"))
+
+	streams, latest := l.enforceLimits(l.streamsFromBatch(batch, batchErr))
+	if len(streams) > 0 {
+		if err := l.send(ctx, streams); err != nil {
+			return err
+		}
+		l.commitLatest(latest)
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// send pushes streams to Loki, retrying requests that were rate limited or
+// failed with a server error.
+func (l *lokiOutput) send(ctx context.Context, streams []*lokiStream) error {
+	body := snappy.Encode(nil, encodePushRequest(streams))
+
+	boff := *l.backoff
+	boff.Reset()
+
+	for {
+		err := l.push(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var reqErr *lokiRequestError
+		if errors.As(err, &reqErr) {
+			if reqErr.status == http.StatusBadRequest {
+				count := 0
+				for _, s := range streams {
+					count += len(s.entries)
+				}
+				l.mDropped.Incr(int64(count), "rejected")
+				l.log.Warnf("Dropping %v entries rejected by Loki: %v\n", count, reqErr.body)
+				return nil
+			}
+			if reqErr.status != http.StatusTooManyRequests && reqErr.status < 500 {
+				return err
+			}
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		l.log.Warnf("Retrying failed request: %v\n", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *lokiOutput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,457 @@
+package loki
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testLokiEntry struct {
+	ts   time.Time
+	line string
+}
+
+type testLokiStream struct {
+	labels  string
+	entries []testLokiEntry
+}
+
+// consumeMessages walks the fields of an encoded protobuf message, calling fn
+// with the number and content of each length delimited field, and with the
+// value of each varint field.
+func consumeMessages(t *testing.T, b []byte, fn func(num protowire.Number, content []byte, v uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fn(num, v, 0)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fn(num, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type: %v", typ)
+		}
+	}
+}
+
+func decodePushRequest(t *testing.T, b []byte) []testLokiStream {
+	t.Helper()
+
+	var streams []testLokiStream
+	consumeMessages(t, b, func(_ protowire.Number, sb []byte, _ uint64) {
+		var s testLokiStream
+		consumeMessages(t, sb, func(num protowire.Number, content []byte, _ uint64) {
+			if num == 1 {
+				s.labels = string(content)
+				return
+			}
+			var e testLokiEntry
+			consumeMessages(t, content, func(num protowire.Number, content []byte, _ uint64) {
+				if num == 2 {
+					e.line = string(content)
+					return
+				}
+				var secs, nanos uint64
+				consumeMessages(t, content, func(num protowire.Number, _ []byte, v uint64) {
+					if num == 1 {
+						secs = v
+					} else {
+						nanos = v
+					}
+				})
+				e.ts = time.Unix(int64(secs), int64(nanos)).UTC()
+			})
+			s.entries = append(s.entries, e)
+		})
+		streams = append(streams, s)
+	})
+	return streams
+}
+
+// readPushRequest reads and decodes the streams of a snappy compressed push
+// request.
+func readPushRequest(t *testing.T, r *http.Request) []testLokiStream {
+	t.Helper()
+
+	assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+	assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	decoded, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+	return decodePushRequest(t, decoded)
+}
+
+func testTime(secs int) time.Time {
+	return time.Unix(1650000000+int64(secs), 0).UTC()
+}
+
+func TestLokiOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs [][]testLokiStream
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams := readPushRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, streams)
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`/
+labels:
+  app: benthos
+  level: ${! json("level") }
+line: ${! json("msg") }
+timestamp: ${! json("ts") }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLokiOutputFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"level":"info","msg":"first","ts":"2022-04-15T05:20:01Z"}`)),
+		service.NewMessage([]byte(`{"level":"error","msg":"second","ts":"2022-04-15T05:20:02.5Z"}`)),
+		service.NewMessage([]byte(`{"level":"info","msg":"third","ts":"2022-04-15T05:20:00Z"}`)),
+		service.NewMessage([]byte(`{"level":"","msg":"fourth","ts":"2022-04-15T05:20:03Z"}`)),
+		service.NewMessage([]byte(`{"level":"info","msg":"fifth","ts":"nope"}`)),
+	})
+	require.Error(t, err)
+
+	parseTime := func(s string) time.Time {
+		tt, err := time.Parse(time.RFC3339Nano, s)
+		require.NoError(t, err)
+		return tt.UTC()
+	}
+
+	reqMut.Lock()
+	assert.Equal(t, [][]testLokiStream{
+		{
+			{
+				labels: `{app="benthos", level="info"}`,
+				entries: []testLokiEntry{
+					{ts: parseTime("2022-04-15T05:20:00Z"), line: "third"},
+					{ts: parseTime("2022-04-15T05:20:01Z"), line: "first"},
+				},
+			},
+			{
+				labels: `{app="benthos", level="error"}`,
+				entries: []testLokiEntry{
+					{ts: parseTime("2022-04-15T05:20:02.5Z"), line: "second"},
+				},
+			},
+			{
+				labels: `{app="benthos"}`,
+				entries: []testLokiEntry{
+					{ts: parseTime("2022-04-15T05:20:03Z"), line: "fourth"},
+				},
+			},
+		},
+	}, reqs)
+	reqMut.Unlock()
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	failed := map[int]string{}
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed[4], "failed to parse timestamp 'nope'")
+}
+
+func TestLokiOutputOutOfOrder(t *testing.T) {
+	tests := map[string]struct {
+		outOfOrder string
+		second     []testLokiEntry
+	}{
+		"drop": {
+			outOfOrder: "drop",
+			second: []testLokiEntry{
+				{ts: testTime(10), line: "d"},
+				{ts: testTime(11), line: "e"},
+			},
+		},
+		"clamp": {
+			outOfOrder: "clamp",
+			second: []testLokiEntry{
+				{ts: testTime(10), line: "c"},
+				{ts: testTime(10), line: "d"},
+				{ts: testTime(11), line: "e"},
+			},
+		},
+		"allow": {
+			outOfOrder: "allow",
+			second: []testLokiEntry{
+				{ts: testTime(11), line: "e"},
+				{ts: testTime(5), line: "c"},
+				{ts: testTime(10), line: "d"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var reqMut sync.Mutex
+			var reqs [][]testLokiStream
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				streams := readPushRequest(t, r)
+				reqMut.Lock()
+				reqs = append(reqs, streams)
+				reqMut.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer ts.Close()
+
+			conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+labels:
+  app: benthos
+line: ${! json("line") }
+timestamp: ${! json("ts") }
+out_of_order: `+test.outOfOrder+`
+`, nil)
+			require.NoError(t, err)
+
+			out, err := newLokiOutputFromConfig(conf, nil, nil)
+			require.NoError(t, err)
+
+			require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte(`{"line":"a","ts":"2022-04-15T05:20:00Z"}`)),
+				service.NewMessage([]byte(`{"line":"b","ts":"2022-04-15T05:20:10Z"}`)),
+			}))
+			require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte(`{"line":"e","ts":"2022-04-15T05:20:11Z"}`)),
+				service.NewMessage([]byte(`{"line":"c","ts":"2022-04-15T05:20:05Z"}`)),
+				service.NewMessage([]byte(`{"line":"d","ts":"2022-04-15T05:20:10Z"}`)),
+			}))
+
+			reqMut.Lock()
+			defer reqMut.Unlock()
+			require.Len(t, reqs, 2)
+			require.Len(t, reqs[1], 1)
+			assert.Equal(t, test.second, reqs[1][0].entries)
+		})
+	}
+}
+
+func TestLokiOutputMaxStreams(t *testing.T) {
+	var reqMut sync.Mutex
+	var labels [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqLabels []string
+		for _, s := range readPushRequest(t, r) {
+			reqLabels = append(reqLabels, s.labels)
+		}
+		reqMut.Lock()
+		labels = append(labels, reqLabels)
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+labels:
+  app: ${! content() }
+max_streams: 2
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLokiOutputFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("b")),
+		service.NewMessage([]byte("c")),
+		service.NewMessage([]byte("a")),
+	}))
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("d")),
+		service.NewMessage([]byte("b")),
+	}))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	assert.Equal(t, [][]string{
+		{`{app="a"}`, `{app="b"}`},
+		{`{app="b"}`},
+	}, labels)
+}
+
+func TestLokiOutputRejected(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+labels:
+  app: benthos
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLokiOutputFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	}))
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
+
+func TestLokiOutputRetries(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "foo", r.Header.Get("X-Scope-OrgID"))
+		switch atomic.AddUint32(&reqCount, 1) {
+		case 1:
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+labels:
+  app: benthos
+timestamp: ${! json("ts") }
+tenant_id: foo
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLokiOutputFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"ts":"2022-04-15T05:20:00Z"}`)),
+	}))
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&reqCount))
+}
+
+func TestLokiOutputFailedNotCommitted(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs [][]testLokiStream
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams := readPushRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, streams)
+		n := len(reqs)
+		reqMut.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("no org id"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf, err := lokiOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+labels:
+  app: benthos
+line: ${! json("line") }
+timestamp: ${! json("ts") }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLokiOutputFromConfig(conf, nil, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"line":"a","ts":"2022-04-15T05:20:10Z"}`)),
+	})
+	require.EqualError(t, err, "request failed with status 401: no org id")
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"line":"b","ts":"2022-04-15T05:20:00Z"}`)),
+	}))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, []testLokiEntry{{ts: testTime(0), line: "b"}}, reqs[1][0].entries)
+}
+
+func TestLokiOutputBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   string
+		errStr string
+	}{
+		"no labels": {
+			conf: `
+url: http://localhost:3100
+labels: {}
+`,
+			errStr: "at least one label must be specified",
+		},
+		"bad label name": {
+			conf: `
+url: http://localhost:3100
+labels:
+  foo-bar: baz
+`,
+			errStr: "label name 'foo-bar' is invalid",
+		},
+		"no url": {
+			conf: `
+url: ""
+labels:
+  app: benthos
+`,
+			errStr: "a url must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			parsed, err := lokiOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newLokiOutputFromConfig(parsed, nil, nil)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package loki
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// lokiEntry is a log line of a stream, created from a message of a batch.
+type lokiEntry struct {
+	index int
+	ts    time.Time
+	line  string
+}
+
+// lokiStream is the set of entries of a batch that share the same labels.
+type lokiStream struct {
+	labels  string
+	entries []lokiEntry
+}
+
+// lokiLabelsString formats labels in the form expected by the push API, which
+// is the Prometheus text format of a label set with the names sorted.
+func lokiLabelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// encodePushRequest encodes streams as the protobuf PushRequest message of the
+// push API, where each stream is a StreamAdapter message with entries of the
+// EntryAdapter message.
+func encodePushRequest(streams []*lokiStream) []byte {
+	var b []byte
+	for _, s := range streams {
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+		sb = protowire.AppendString(sb, s.labels)
+		for _, e := range s.entries {
+			var tb []byte
+			tb = protowire.AppendTag(tb, 1, protowire.VarintType)
+			tb = protowire.AppendVarint(tb, uint64(e.ts.Unix()))
+			tb = protowire.AppendTag(tb, 2, protowire.VarintType)
+			tb = protowire.AppendVarint(tb, uint64(e.ts.Nanosecond()))
+
+			var eb []byte
+			eb = protowire.AppendTag(eb, 1, protowire.BytesType)
+			eb = protowire.AppendBytes(eb, tb)
+			eb = protowire.AppendTag(eb, 2, protowire.BytesType)
+			eb = protowire.AppendString(eb, e.line)
+
+			sb = protowire.AppendTag(sb, 2, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, eb)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+	return b
+}
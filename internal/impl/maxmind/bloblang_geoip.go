@@ -15,14 +15,14 @@ func registerMaxmindMethodSpec(name, entity string, fn func(*geoip2.Reader, net.
 	if err := bloblang.RegisterMethodV2(name,
 		bloblang.NewPluginSpec().
 			Category(string(query.MethodCategoryGeoIP)).
-			Description(fmt.Sprintf("EXPERIMENTAL: Looks up an IP address against a [MaxMind database file](https://www.maxmind.com/en/home) and, if found, returns an object describing the %v associated with it.", entity)).
+			Description(fmt.Sprintf("EXPERIMENTAL: Looks up an IP address against a [MaxMind database file](https://www.maxmind.com/en/home) and, if found, returns an object describing the %v associated with it. The database is reopened when the file is modified, which allows it to be updated without restarting the pipeline. Updates should replace the file atomically, e.g. by moving a new file over it, as modifying the file in place whilst it is being read is not safe.", entity)).
 			Param(bloblang.NewStringParam("path").Description("A path to an mmdb (maxmind) file.")),
 		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
 			path, err := args.GetString("path")
 			if err != nil {
 				return nil, err
 			}
-			db, err := geoip2.Open(path)
+			dbf, err := openDBFile(path)
 			if err != nil {
 				return nil, err
 			}
@@ -31,19 +31,21 @@ func registerMaxmindMethodSpec(name, entity string, fn func(*geoip2.Reader, net.
 				if ip == nil {
 					return nil, fmt.Errorf("value %v does not appear to be a valid v4 or v6 IP address", s)
 				}
-				v, err := fn(db, ip)
-				if err != nil {
-					return nil, err
-				}
-				jBytes, err := json.Marshal(v)
-				if err != nil {
-					return nil, err
-				}
-				dec := json.NewDecoder(bytes.NewReader(jBytes))
-				dec.UseNumber()
-				var gV interface{}
-				err = dec.Decode(&gV)
-				return gV, err
+				return dbf.lookup(func(db *geoip2.Reader) (interface{}, error) {
+					v, err := fn(db, ip)
+					if err != nil {
+						return nil, err
+					}
+					jBytes, err := json.Marshal(v)
+					if err != nil {
+						return nil, err
+					}
+					dec := json.NewDecoder(bytes.NewReader(jBytes))
+					dec.UseNumber()
+					var gV interface{}
+					err = dec.Decode(&gV)
+					return gV, err
+				})
 			}), nil
 		}); err != nil {
 		panic(err)
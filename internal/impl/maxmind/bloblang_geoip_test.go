@@ -1,6 +1,8 @@
 package maxmind
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/public/bloblang"
@@ -69,3 +71,44 @@ func TestGeoIPCity(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoIPReload(t *testing.T) {
+	oldPeriod := reloadCheckPeriod
+	reloadCheckPeriod = 0
+	t.Cleanup(func() {
+		reloadCheckPeriod = oldPeriod
+	})
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "geoip.mmdb")
+
+	replaceDB := func(src string) {
+		t.Helper()
+		b, err := os.ReadFile(src)
+		require.NoError(t, err)
+
+		tmpPath := filepath.Join(dir, "geoip.mmdb.tmp")
+		require.NoError(t, os.WriteFile(tmpPath, b, 0o644))
+		require.NoError(t, os.Rename(tmpPath, dbPath))
+	}
+
+	replaceDB("./testdata/GeoIP2-City-Test.mmdb")
+
+	exec, err := bloblang.Parse(`root = "81.2.69.192".geoip_city("` + dbPath + `").City.Names.en`)
+	require.NoError(t, err)
+
+	res, err := exec.Query(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "London", res)
+
+	replaceDB("./testdata/GeoLite2-ASN-Test.mmdb")
+
+	_, err = exec.Query(nil)
+	require.Error(t, err)
+
+	replaceDB("./testdata/GeoIP2-City-Test.mmdb")
+
+	res, err = exec.Query(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "London", res)
+}
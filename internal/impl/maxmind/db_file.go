@@ -0,0 +1,111 @@
+package maxmind
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// reloadCheckPeriod is the minimum period between checks of whether a
+// database file has been modified.
+var reloadCheckPeriod = time.Second
+
+var (
+	dbFilesMut sync.Mutex
+	dbFiles    = map[string]*dbFile{}
+)
+
+// dbFile is a MaxMind database file that is shared by all methods referencing
+// the same path, and is reopened when the file is modified.
+type dbFile struct {
+	path      string
+	nextCheck int64
+
+	mut     sync.RWMutex
+	db      *geoip2.Reader
+	modTime time.Time
+	size    int64
+}
+
+// openDBFile returns the shared database file of a path, opening it if it
+// hasn't been opened already.
+func openDBFile(path string) (*dbFile, error) {
+	key := filepath.Clean(path)
+
+	dbFilesMut.Lock()
+	defer dbFilesMut.Unlock()
+
+	if f, exists := dbFiles[key]; exists {
+		return f, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &dbFile{
+		path:      path,
+		nextCheck: time.Now().Add(reloadCheckPeriod).UnixNano(),
+		db:        db,
+		modTime:   info.ModTime(),
+		size:      info.Size(),
+	}
+	dbFiles[key] = f
+	return f, nil
+}
+
+// reloadIfModified reopens the database when the modification time or size of
+// the file has changed since it was last opened. If the new file cannot be
+// opened, which can happen when it is only partially written, the current
+// database continues to be used and the reload is attempted again after the
+// next check period.
+func (f *dbFile) reloadIfModified() {
+	now := time.Now().UnixNano()
+	next := atomic.LoadInt64(&f.nextCheck)
+	if now < next || !atomic.CompareAndSwapInt64(&f.nextCheck, next, now+int64(reloadCheckPeriod)) {
+		return
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return
+	}
+
+	f.mut.RLock()
+	unchanged := info.ModTime().Equal(f.modTime) && info.Size() == f.size
+	f.mut.RUnlock()
+	if unchanged {
+		return
+	}
+
+	db, err := geoip2.Open(f.path)
+	if err != nil {
+		return
+	}
+
+	f.mut.Lock()
+	oldDB := f.db
+	f.db, f.modTime, f.size = db, info.ModTime(), info.Size()
+	f.mut.Unlock()
+
+	_ = oldDB.Close()
+}
+
+// lookup calls fn with the current database, which must not be retained after
+// fn returns.
+func (f *dbFile) lookup(fn func(db *geoip2.Reader) (interface{}, error)) (interface{}, error) {
+	f.reloadIfModified()
+
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return fn(f.db)
+}
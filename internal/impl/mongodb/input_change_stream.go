@@ -0,0 +1,384 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/internal/impl/mongodb/client"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func mongoChangeStreamConfigSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Consumes change events from a MongoDB change stream.").
+		Description(`
+Change events can be consumed from a single collection, all collections of a database when `+"`collection`"+` is empty, or the entire deployment when both `+"`database`"+` and `+"`collection`"+` are empty. Change streams are only available for replica sets and sharded clusters.
+
+Each message is a change event document marshalled as extended JSON, in the mode specified by `+"`json_marshal_mode`"+`.
+
+### Resuming
+
+The resume token of each event is written to the cache resource `+"`checkpoint_cache`"+` once the event, and all events before it, have been acknowledged. When this input connects it resumes the change stream after the token stored within the cache, or starts from the current time when no token is found.
+
+Resume tokens can be written periodically rather than after every acknowledgement by setting `+"`checkpoint_interval`"+`, and `+"`checkpoint_fencing`"+` can be enabled in order to prevent a stale instance of this input from overwriting the tokens of a newer one.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"``` text"+`
+- mongodb_operation_type
+- mongodb_database
+- mongodb_collection
+`+"```"+``).
+		Field(urlField).
+		Field(service.NewStringField("database").
+			Description("The name of the database to watch. When empty the entire deployment is watched.").
+			Default("")).
+		Field(service.NewStringField("collection").
+			Description("The name of the collection to watch. When empty all collections of the database are watched.").
+			Default("")).
+		Field(service.NewStringField("username").Description("The username to connect to the database.").Default("")).
+		Field(service.NewStringField("password").Description("The password to connect to the database.").Default("")).
+		Field(service.NewBloblangField("pipeline").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an array of aggregation pipeline stages, which are applied to change events by the server. This can be used in order to filter events or remove fields from them.").
+			Example(`root = [ { "$match": { "operationType": { "$in": [ "insert", "update" ] } } } ]`).
+			Optional()).
+		Field(service.NewStringEnumField("full_document", "default", "update_lookup").
+			Description("Determines the contents of the `fullDocument` field of update events. When set to `update_lookup` the current state of the updated document is looked up and included.").
+			Default("default")).
+		Field(service.NewStringEnumField("json_marshal_mode", string(client.JSONMarshalModeCanonical), string(client.JSONMarshalModeRelaxed)).
+			Description("The [extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/) mode to marshal change events with.").
+			Advanced().
+			Default(string(client.JSONMarshalModeRelaxed))).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("An optional [cache resource](/docs/components/caches/about) to store resume tokens within.").
+			Default("")).
+		Field(service.NewStringField("checkpoint_key").
+			Description("The key to store resume tokens under within the cache.").
+			Advanced().
+			Default("mongodb_change_stream")).
+		Field(store.IntervalField()).
+		Field(store.FencingField()).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of change events that can be pending acknowledgement before back pressure is applied.").
+			Advanced().
+			Default(1024)).
+		Example("Watch Inserts", `
+Here we consume only the inserted documents of a collection, resuming from a token stored within a Redis cache:`,
+			`
+input:
+  mongodb_change_stream:
+    url: mongodb://localhost:27017
+    database: foodb
+    collection: foocollection
+    pipeline: 'root = [ { "$match": { "operationType": "insert" } } ]'
+    checkpoint_cache: checkpoints
+  processors:
+    - bloblang: root = this.fullDocument
+
+cache_resources:
+  - label: checkpoints
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mongodb_change_stream", mongoChangeStreamConfigSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newMongoChangeStreamInput(conf, mgr.AccessCache, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// mongoResumePoint is the payload tracked for each change event.
+type mongoResumePoint struct {
+	seq   int64
+	token bson.Raw
+}
+
+type mongoChangeStreamInput struct {
+	config       client.Config
+	pipeline     []interface{}
+	fullDocument options.FullDocument
+	canonical    bool
+	store        *store.Store
+
+	mut    sync.Mutex
+	client *mongo.Client
+	stream *mongo.ChangeStream
+
+	checkpointer *checkpoint.Capped
+	seq          int64
+	commitMut    sync.Mutex
+	committedSeq int64
+}
+
+func newMongoChangeStreamInput(conf *service.ParsedConfig, accessCache store.CacheAccessor, logger *service.Logger) (*mongoChangeStreamInput, error) {
+	m := &mongoChangeStreamInput{
+		pipeline:     []interface{}{},
+		fullDocument: options.Default,
+	}
+
+	var err error
+	if m.config.URL, err = conf.FieldString("url"); err != nil {
+		return nil, err
+	}
+	if m.config.Database, err = conf.FieldString("database"); err != nil {
+		return nil, err
+	}
+	if m.config.Collection, err = conf.FieldString("collection"); err != nil {
+		return nil, err
+	}
+	if m.config.Collection != "" && m.config.Database == "" {
+		return nil, errors.New("a database must be specified in order to watch a collection")
+	}
+	if m.config.Username, err = conf.FieldString("username"); err != nil {
+		return nil, err
+	}
+	if m.config.Password, err = conf.FieldString("password"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("pipeline") {
+		pipelineExec, err := conf.FieldBloblang("pipeline")
+		if err != nil {
+			return nil, err
+		}
+		pipeline, err := pipelineExec.Query(struct{}{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute pipeline mapping: %w", err)
+		}
+		var ok bool
+		if m.pipeline, ok = pipeline.([]interface{}); !ok {
+			return nil, fmt.Errorf("pipeline mapping returned non-array result: %T", pipeline)
+		}
+	}
+
+	fullDocument, err := conf.FieldString("full_document")
+	if err != nil {
+		return nil, err
+	}
+	switch fullDocument {
+	case "default":
+	case "update_lookup":
+		m.fullDocument = options.UpdateLookup
+	default:
+		return nil, fmt.Errorf("full_document value not recognised: %v", fullDocument)
+	}
+
+	marshalMode, err := conf.FieldString("json_marshal_mode")
+	if err != nil {
+		return nil, err
+	}
+	m.canonical = client.JSONMarshalMode(marshalMode) == client.JSONMarshalModeCanonical
+
+	storeConf, err := store.ConfigFromParsed(conf, "checkpoint_cache", "checkpoint_key")
+	if err != nil {
+		return nil, err
+	}
+	m.store = store.New(storeConf, store.CacheAccessor(accessCache), logger)
+
+	limit, err := conf.FieldInt("checkpoint_limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, errors.New("checkpoint_limit must be larger than zero")
+	}
+	m.checkpointer = checkpoint.NewCapped(int64(limit))
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// readResumeToken obtains the last acknowledged resume token from the cache,
+// returns nil if no token is stored.
+func (m *mongoChangeStreamInput) readResumeToken(ctx context.Context) (bson.Raw, error) {
+	value, exists, err := m.store.Read(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+	var token bson.Raw
+	if err := bson.UnmarshalExtJSON(value, true, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse resume token: %w", err)
+	}
+	return token, nil
+}
+
+func (m *mongoChangeStreamInput) writeResumeToken(ctx context.Context, p mongoResumePoint) error {
+	m.commitMut.Lock()
+	defer m.commitMut.Unlock()
+
+	if p.seq <= m.committedSeq {
+		return nil
+	}
+	value, err := bson.MarshalExtJSON(p.token, true, false)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Commit(ctx, value); err != nil {
+		return err
+	}
+	m.committedSeq = p.seq
+	return nil
+}
+
+func (m *mongoChangeStreamInput) Connect(ctx context.Context) (err error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.stream != nil {
+		return nil
+	}
+
+	token, err := m.readResumeToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read resume token: %w", err)
+	}
+
+	var c *mongo.Client
+	if c, err = m.config.Client(); err != nil {
+		return err
+	}
+	if err = c.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = c.Disconnect(ctx)
+		}
+	}()
+
+	opts := options.ChangeStream().SetFullDocument(m.fullDocument)
+	if token != nil {
+		opts = opts.SetResumeAfter(token)
+	}
+
+	var stream *mongo.ChangeStream
+	switch {
+	case m.config.Collection != "":
+		stream, err = c.Database(m.config.Database).Collection(m.config.Collection).Watch(ctx, m.pipeline, opts)
+	case m.config.Database != "":
+		stream, err = c.Database(m.config.Database).Watch(ctx, m.pipeline, opts)
+	default:
+		stream, err = c.Watch(ctx, m.pipeline, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+
+	m.client = c
+	m.stream = stream
+	return nil
+}
+
+func (m *mongoChangeStreamInput) disconnect(ctx context.Context) error {
+	var err error
+	if m.stream != nil {
+		err = m.stream.Close(ctx)
+		m.stream = nil
+	}
+	if m.client != nil {
+		if cerr := m.client.Disconnect(ctx); cerr != nil && err == nil {
+			err = cerr
+		}
+		m.client = nil
+	}
+	return err
+}
+
+func (m *mongoChangeStreamInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.stream == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if !m.stream.Next(ctx) {
+		err := m.stream.Err()
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		_ = m.disconnect(ctx)
+		if err == nil {
+			// The stream has been invalidated, for example by the collection
+			// being dropped.
+			return nil, nil, service.ErrEndOfInput
+		}
+		return nil, nil, service.ErrNotConnected
+	}
+
+	event := m.stream.Current
+	jsonBytes, err := bson.MarshalExtJSON(event, m.canonical, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal change event: %w", err)
+	}
+
+	token := make(bson.Raw, len(m.stream.ResumeToken()))
+	copy(token, m.stream.ResumeToken())
+
+	m.seq++
+	resolveFn, err := m.checkpointer.Track(ctx, mongoResumePoint{seq: m.seq, token: token}, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := service.NewMessage(jsonBytes)
+	if v, ok := event.Lookup("operationType").StringValueOK(); ok {
+		msg.MetaSet("mongodb_operation_type", v)
+	}
+	if v, ok := event.Lookup("ns", "db").StringValueOK(); ok {
+		msg.MetaSet("mongodb_database", v)
+	}
+	if v, ok := event.Lookup("ns", "coll").StringValueOK(); ok {
+		msg.MetaSet("mongodb_collection", v)
+	}
+
+	return msg, func(ctx context.Context, err error) error {
+		if err != nil {
+			// Nacks are handled by AutoRetryNacks.
+			return nil
+		}
+		highest := resolveFn()
+		if !m.store.Enabled() {
+			return nil
+		}
+		if p, ok := highest.(mongoResumePoint); ok && len(p.token) > 0 {
+			return m.writeResumeToken(ctx, p)
+		}
+		return nil
+	}, nil
+}
+
+func (m *mongoChangeStreamInput) Close(ctx context.Context) error {
+	m.mut.Lock()
+	err := m.disconnect(ctx)
+	m.mut.Unlock()
+	if serr := m.store.Close(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoChangeStreamInputConfig(t *testing.T) {
+	conf, err := mongoChangeStreamConfigSpec().ParseYAML(`
+url: "mongodb://localhost:27017"
+database: foo
+collection: bar
+full_document: update_lookup
+pipeline: 'root = [ { "$match": { "operationType": "insert" } } ]'
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newMongoChangeStreamInput(conf, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, options.UpdateLookup, i.fullDocument)
+	assert.False(t, i.canonical)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"$match": map[string]interface{}{"operationType": "insert"},
+		},
+	}, i.pipeline)
+	require.NoError(t, i.Close(context.Background()))
+}
+
+func TestMongoChangeStreamInputConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"collection without database": {
+			conf: `
+url: "mongodb://localhost:27017"
+collection: bar
+`,
+			errContains: "database must be specified",
+		},
+		"non array pipeline": {
+			conf: `
+url: "mongodb://localhost:27017"
+pipeline: 'root = { "$match": {} }'
+`,
+			errContains: "non-array",
+		},
+		"bad checkpoint limit": {
+			conf: `
+url: "mongodb://localhost:27017"
+checkpoint_limit: 0
+`,
+			errContains: "checkpoint_limit",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := mongoChangeStreamConfigSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newMongoChangeStreamInput(conf, nil, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+type mapCache map[string][]byte
+
+func (m mapCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, exists := m[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (m mapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if _, exists := m[key]; exists {
+		return service.ErrKeyAlreadyExists
+	}
+	m[key] = value
+	return nil
+}
+
+func (m mapCache) Delete(ctx context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestMongoChangeStreamResumeTokens(t *testing.T) {
+	cache := mapCache{}
+	access := func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		if name != "foo" {
+			return errors.New("cache not found")
+		}
+		fn(cache)
+		return nil
+	}
+
+	conf, err := mongoChangeStreamConfigSpec().ParseYAML(`
+url: "mongodb://localhost:27017"
+checkpoint_cache: foo
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newMongoChangeStreamInput(conf, access, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	token, err := i.readResumeToken(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	first, err := bson.Marshal(bson.M{"_data": "first"})
+	require.NoError(t, err)
+	second, err := bson.Marshal(bson.M{"_data": "second"})
+	require.NoError(t, err)
+
+	require.NoError(t, i.writeResumeToken(ctx, mongoResumePoint{seq: 2, token: second}))
+
+	// Older resume points are never written over newer ones.
+	require.NoError(t, i.writeResumeToken(ctx, mongoResumePoint{seq: 1, token: first}))
+
+	token, err = i.readResumeToken(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second", token.Lookup("_data").StringValue())
+}
@@ -3,7 +3,10 @@ package nats
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,11 +31,20 @@ This input adds the following metadata fields to each message:
 
 ` + "```text" + `
 - nats_subject
+- nats_sequence_stream
+- nats_sequence_consumer
+- nats_num_delivered
 ` + "```" + `
 
 You can access these metadata fields using
 [function interpolation](/docs/configuration/interpolation#metadata).
 
+### Consumers
+
+By default messages are pushed to this input by the server. When ` + "`pull`" + ` is enabled messages are instead fetched in batches by a pull consumer, which requires a ` + "`durable`" + ` name and allows many instances of Benthos to share the work of a single consumer.
+
+Messages are acknowledged once they have been delivered by Benthos. Messages that fail to be delivered are nacked, which results in them being redelivered, unless the error matches one of the ` + "`term_patterns`" + `, in which case they are terminated and never redelivered.
+
 ` + auth.Description()).
 		Field(service.NewStringListField("urls").
 			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
@@ -63,6 +75,27 @@ You can access these metadata fields using
 			Description("The maximum number of outstanding acks to be allowed before consuming is halted.").
 			Advanced().
 			Default(1024)).
+		Field(service.NewIntField("max_deliver").
+			Description("The maximum number of times a message is delivered before it is abandoned. When zero or less messages are redelivered indefinitely.").
+			Advanced().
+			Version("3.64.0").
+			Default(0)).
+		Field(service.NewBoolField("pull").
+			Description("Whether to consume messages with a pull consumer, which requires `durable` to be set.").
+			Version("3.64.0").
+			Default(false)).
+		Field(service.NewIntField("pull_batch_size").
+			Description("The maximum number of messages to fetch at a time when consuming with a pull consumer.").
+			Advanced().
+			Version("3.64.0").
+			Default(64)).
+		Field(service.NewStringListField("term_patterns").
+			Description("A list of regular expression patterns whereby if a message that has failed to be delivered by Benthos has an error that matches it will be terminated, and therefore never redelivered. By default failed messages are nacked and redelivered.").
+			Example([]string{"^reject me please:.+$"}).
+			Advanced().
+			Version("3.64.0").
+			Default([]string{})).
+		Field(jetStreamStreamField().Version("3.64.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(service.NewInternalField(auth.FieldSpec()))
 }
@@ -81,6 +114,9 @@ func init() {
 
 //------------------------------------------------------------------------------
 
+// The maximum period of time to wait for a single fetch of a pull consumer.
+const jetStreamFetchWait = time.Second * 5
+
 type jetStreamReader struct {
 	urls          string
 	deliverOpt    nats.SubOpt
@@ -89,6 +125,11 @@ type jetStreamReader struct {
 	durable       string
 	ackWait       time.Duration
 	maxAckPending int
+	maxDeliver    int
+	pull          bool
+	pullBatchSize int
+	termPatterns  []*regexp.Regexp
+	stream        jetStreamStreamConfig
 	authConf      auth.Config
 	tlsConf       *tls.Config
 
@@ -98,6 +139,9 @@ type jetStreamReader struct {
 	natsConn *nats.Conn
 	natsSub  *nats.Subscription
 
+	pullMut     sync.Mutex
+	pullPending []*nats.Msg
+
 	shutSig *shutdown.Signaller
 }
 
@@ -154,6 +198,41 @@ func newJetStreamReaderFromConfig(conf *service.ParsedConfig, log *service.Logge
 	if j.maxAckPending, err = conf.FieldInt("max_ack_pending"); err != nil {
 		return nil, err
 	}
+	if j.maxDeliver, err = conf.FieldInt("max_deliver"); err != nil {
+		return nil, err
+	}
+
+	if j.pull, err = conf.FieldBool("pull"); err != nil {
+		return nil, err
+	}
+	if j.pull && j.durable == "" {
+		return nil, errors.New("a durable name must be specified in order to consume with a pull consumer")
+	}
+	if j.pull && j.queue != "" {
+		return nil, errors.New("a queue cannot be specified when consuming with a pull consumer")
+	}
+	if j.pullBatchSize, err = conf.FieldInt("pull_batch_size"); err != nil {
+		return nil, err
+	}
+	if j.pullBatchSize <= 0 {
+		return nil, errors.New("pull_batch_size must be larger than zero")
+	}
+
+	termPatterns, err := conf.FieldStringList("term_patterns")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range termPatterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile term pattern: %w", err)
+		}
+		j.termPatterns = append(j.termPatterns, r)
+	}
+
+	if j.stream, err = jetStreamStreamFromParsed(conf.Namespace("stream"), j.subject); err != nil {
+		return nil, err
+	}
 
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
@@ -209,10 +288,17 @@ func (j *jetStreamReader) Connect(ctx context.Context) error {
 		return err
 	}
 
+	if err = j.stream.ensure(jCtx); err != nil {
+		return err
+	}
+
 	options := []nats.SubOpt{
 		nats.ManualAck(),
 	}
-	if j.durable != "" {
+	if j.stream.name != "" {
+		options = append(options, nats.BindStream(j.stream.name))
+	}
+	if j.durable != "" && !j.pull {
 		options = append(options, nats.Durable(j.durable))
 	}
 	options = append(options, j.deliverOpt)
@@ -222,10 +308,16 @@ func (j *jetStreamReader) Connect(ctx context.Context) error {
 	if j.maxAckPending != 0 {
 		options = append(options, nats.MaxAckPending(j.maxAckPending))
 	}
+	if j.maxDeliver > 0 {
+		options = append(options, nats.MaxDeliver(j.maxDeliver))
+	}
 
-	if j.queue == "" {
+	switch {
+	case j.pull:
+		natsSub, err = jCtx.PullSubscribe(j.subject, j.durable, options...)
+	case j.queue == "":
 		natsSub, err = jCtx.SubscribeSync(j.subject, options...)
-	} else {
+	default:
 		natsSub, err = jCtx.QueueSubscribeSync(j.subject, j.queue, options...)
 	}
 	if err != nil {
@@ -261,7 +353,13 @@ func (j *jetStreamReader) Read(ctx context.Context) (*service.Message, service.A
 		return nil, nil, service.ErrNotConnected
 	}
 
-	nmsg, err := natsSub.NextMsgWithContext(ctx)
+	var nmsg *nats.Msg
+	var err error
+	if j.pull {
+		nmsg, err = j.nextPullMsg(ctx, natsSub)
+	} else {
+		nmsg, err = natsSub.NextMsgWithContext(ctx)
+	}
 	if err != nil {
 		// TODO: Any errors need capturing here to signal a lost connection?
 		return nil, nil, err
@@ -269,15 +367,51 @@ func (j *jetStreamReader) Read(ctx context.Context) (*service.Message, service.A
 
 	msg := service.NewMessage(nmsg.Data)
 	msg.MetaSet("nats_subject", nmsg.Subject)
+	if meta, err := nmsg.Metadata(); err == nil {
+		msg.MetaSet("nats_sequence_stream", strconv.FormatUint(meta.Sequence.Stream, 10))
+		msg.MetaSet("nats_sequence_consumer", strconv.FormatUint(meta.Sequence.Consumer, 10))
+		msg.MetaSet("nats_num_delivered", strconv.FormatUint(meta.NumDelivered, 10))
+	}
 
 	return msg, func(ctx context.Context, res error) error {
 		if res == nil {
 			return nmsg.Ack()
 		}
+		for _, p := range j.termPatterns {
+			if p.MatchString(res.Error()) {
+				return nmsg.Term()
+			}
+		}
 		return nmsg.Nak()
 	}, nil
 }
 
+// nextPullMsg returns the next message from a pull subscription, fetching a
+// new batch of messages when those previously fetched have been consumed.
+func (j *jetStreamReader) nextPullMsg(ctx context.Context, natsSub *nats.Subscription) (*nats.Msg, error) {
+	j.pullMut.Lock()
+	defer j.pullMut.Unlock()
+
+	for len(j.pullPending) == 0 {
+		// Fetch requires a deadline, and therefore we wait for a limited period
+		// at a time until either messages arrive or our context is done.
+		fetchCtx, done := context.WithTimeout(ctx, jetStreamFetchWait)
+		msgs, err := natsSub.Fetch(j.pullBatchSize, nats.Context(fetchCtx))
+		done()
+		if err != nil {
+			if ctx.Err() == nil && (errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded)) {
+				continue
+			}
+			return nil, err
+		}
+		j.pullPending = msgs
+	}
+
+	nmsg := j.pullPending[0]
+	j.pullPending = j.pullPending[1:]
+	return nmsg, nil
+}
+
 func (j *jetStreamReader) Close(ctx context.Context) error {
 	go func() {
 		j.disconnect()
@@ -2,8 +2,10 @@ package nats
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -31,3 +33,108 @@ auth:
 	assert.Equal(t, "test auth n key file", e.authConf.NKeyFile)
 	assert.Equal(t, "test auth user creds file", e.authConf.UserCredentialsFile)
 }
+
+func TestInputJetStreamConfigPull(t *testing.T) {
+	spec := natsJetStreamInputConfig()
+	env := service.NewEnvironment()
+
+	inputConfig := `
+urls: [ url1 ]
+subject: foo.>
+durable: foodurable
+pull: true
+pull_batch_size: 10
+max_deliver: 5
+term_patterns: [ '^poison:' ]
+stream:
+  name: foostream
+  create: true
+  storage: memory
+  max_age: 1h
+  duplicate_window: 10m
+`
+
+	conf, err := spec.ParseYAML(inputConfig, env)
+	require.NoError(t, err)
+
+	e, err := newJetStreamReaderFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	assert.True(t, e.pull)
+	assert.Equal(t, 10, e.pullBatchSize)
+	assert.Equal(t, 5, e.maxDeliver)
+	require.Len(t, e.termPatterns, 1)
+	assert.True(t, e.termPatterns[0].MatchString("poison: bad message"))
+
+	assert.Equal(t, "foostream", e.stream.name)
+	assert.True(t, e.stream.create)
+	assert.Equal(t, []string{"foo.>"}, e.stream.conf.Subjects)
+	assert.Equal(t, nats.MemoryStorage, e.stream.conf.Storage)
+	assert.Equal(t, 1, e.stream.conf.Replicas)
+	assert.Equal(t, time.Hour, e.stream.conf.MaxAge)
+	assert.Equal(t, time.Minute*10, e.stream.conf.Duplicates)
+}
+
+func TestInputJetStreamConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"pull without durable": {
+			conf: `
+urls: [ url1 ]
+subject: foo
+pull: true
+`,
+			errContains: "durable name must be specified",
+		},
+		"pull with queue": {
+			conf: `
+urls: [ url1 ]
+subject: foo
+durable: foo
+queue: bar
+pull: true
+`,
+			errContains: "queue cannot be specified",
+		},
+		"bad term pattern": {
+			conf: `
+urls: [ url1 ]
+subject: foo
+term_patterns: [ 'foo[' ]
+`,
+			errContains: "term pattern",
+		},
+		"create stream without name": {
+			conf: `
+urls: [ url1 ]
+subject: foo
+stream:
+  create: true
+`,
+			errContains: "stream name must be specified",
+		},
+		"bad stream max age": {
+			conf: `
+urls: [ url1 ]
+subject: foo
+stream:
+  max_age: nope
+`,
+			errContains: "max_age",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := natsJetStreamInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newJetStreamReaderFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
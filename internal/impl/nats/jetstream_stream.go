@@ -0,0 +1,130 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/nats-io/nats.go"
+)
+
+func jetStreamStreamField() *service.ConfigField {
+	return service.NewObjectField("stream",
+		service.NewStringField("name").
+			Description("The name of the stream. When set the stream is looked up by name rather than by the subject.").
+			Default(""),
+		service.NewBoolField("create").
+			Description("Whether to create the stream when it does not already exist.").
+			Default(false),
+		service.NewStringListField("subjects").
+			Description("The subjects to capture when the stream is created. When empty the subject of this component is used.").
+			Default([]string{}),
+		service.NewStringEnumField("storage", "file", "memory").
+			Description("The storage type of the stream when it is created.").
+			Default("file"),
+		service.NewIntField("replicas").
+			Description("The number of replicas of the stream when it is created.").
+			Default(1),
+		service.NewStringField("max_age").
+			Description("The maximum age of messages within the stream when it is created. When empty messages are not expired by age.").
+			Example("24h").
+			Default(""),
+		service.NewStringField("duplicate_window").
+			Description("The window within which messages with the same `Nats-Msg-Id` header are deduplicated by the stream when it is created. When empty the server default of two minutes is used.").
+			Example("10m").
+			Default(""),
+	).
+		Description("Optionally identify and provision a stream.").
+		Advanced()
+}
+
+// jetStreamStreamConfig describes a stream to be bound to or created.
+type jetStreamStreamConfig struct {
+	name   string
+	create bool
+	conf   nats.StreamConfig
+}
+
+func jetStreamStreamFromParsed(conf *service.ParsedConfig, defaultSubject string) (s jetStreamStreamConfig, err error) {
+	if s.name, err = conf.FieldString("name"); err != nil {
+		return
+	}
+	if s.create, err = conf.FieldBool("create"); err != nil {
+		return
+	}
+	if s.create && s.name == "" {
+		err = errors.New("a stream name must be specified in order to create it")
+		return
+	}
+	s.conf.Name = s.name
+
+	if s.conf.Subjects, err = conf.FieldStringList("subjects"); err != nil {
+		return
+	}
+	if len(s.conf.Subjects) == 0 && defaultSubject != "" {
+		s.conf.Subjects = []string{defaultSubject}
+	}
+	if s.create && len(s.conf.Subjects) == 0 {
+		err = errors.New("stream subjects must be specified in order to create it")
+		return
+	}
+
+	var storage string
+	if storage, err = conf.FieldString("storage"); err != nil {
+		return
+	}
+	switch storage {
+	case "file":
+		s.conf.Storage = nats.FileStorage
+	case "memory":
+		s.conf.Storage = nats.MemoryStorage
+	default:
+		err = fmt.Errorf("stream storage type %v was not recognised", storage)
+		return
+	}
+
+	if s.conf.Replicas, err = conf.FieldInt("replicas"); err != nil {
+		return
+	}
+
+	if s.conf.MaxAge, err = parseOptionalDuration(conf, "max_age"); err != nil {
+		return
+	}
+	if s.conf.Duplicates, err = parseOptionalDuration(conf, "duplicate_window"); err != nil {
+		return
+	}
+	return
+}
+
+func parseOptionalDuration(conf *service.ParsedConfig, name string) (time.Duration, error) {
+	str, err := conf.FieldString(name)
+	if err != nil || str == "" {
+		return 0, err
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %v: %v", name, err)
+	}
+	return d, nil
+}
+
+// ensure creates the stream if it is configured to be created and does not
+// already exist.
+func (s jetStreamStreamConfig) ensure(jCtx nats.JetStreamContext) error {
+	if !s.create {
+		return nil
+	}
+	_, err := jCtx.StreamInfo(s.name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return err
+	}
+	conf := s.conf
+	if _, err = jCtx.AddStream(&conf); err != nil {
+		return fmt.Errorf("failed to create stream %v: %v", s.name, err)
+	}
+	return nil
+}
@@ -19,7 +19,12 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 		Categories("Services").
 		Version("3.46.0").
 		Summary("Write messages to a NATS JetStream subject.").
-		Description(auth.Description()).
+		Description(`
+### Deduplication
+
+When ` + "`msg_id`" + ` is set each message is published with a ` + "`Nats-Msg-Id`" + ` header, and the server discards any message with an ID that matches another message published within the duplicate window of the stream. This allows messages to be safely published again after an error without creating duplicates within the stream.
+
+` + auth.Description()).
 		Field(service.NewStringListField("urls").
 			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
 			Example([]string{"nats://127.0.0.1:4222"}).
@@ -29,9 +34,16 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 			Example("foo.bar.baz").
 			Example(`${! meta("kafka_topic") }`).
 			Example(`foo.${! json("meta.type") }`)).
+		Field(service.NewInterpolatedStringField("msg_id").
+			Description("An optional ID to publish each message with as a `Nats-Msg-Id` header, which is used by the server in order to deduplicate messages.").
+			Example(`${! meta("kafka_topic") }-${! meta("kafka_partition") }-${! meta("kafka_offset") }`).
+			Example(`${! json("id") }`).
+			Version("3.64.0").
+			Optional()).
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
 			Default(1024)).
+		Field(jetStreamStreamField().Version("3.64.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(service.NewInternalField(auth.FieldSpec()))
 }
@@ -59,6 +71,8 @@ type jetStreamOutput struct {
 	urls       string
 	conf       output.NATSJetStreamConfig
 	subjectStr *service.InterpolatedString
+	msgIDStr   *service.InterpolatedString
+	stream     jetStreamStreamConfig
 	authConf   auth.Config
 	tlsConf    *tls.Config
 
@@ -86,6 +100,24 @@ func newJetStreamWriterFromConfig(conf *service.ParsedConfig, log *service.Logge
 	if j.subjectStr, err = conf.FieldInterpolatedString("subject"); err != nil {
 		return nil, err
 	}
+	if conf.Contains("msg_id") {
+		if j.msgIDStr, err = conf.FieldInterpolatedString("msg_id"); err != nil {
+			return nil, err
+		}
+	}
+
+	// A subject containing interpolations cannot be used as the subject of a
+	// provisioned stream.
+	defaultStreamSubject, err := conf.FieldString("subject")
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(defaultStreamSubject, "${!") {
+		defaultStreamSubject = ""
+	}
+	if j.stream, err = jetStreamStreamFromParsed(conf.Namespace("stream"), defaultStreamSubject); err != nil {
+		return nil, err
+	}
 
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
@@ -134,6 +166,10 @@ func (j *jetStreamOutput) Connect(ctx context.Context) error {
 		return err
 	}
 
+	if err = j.stream.ensure(jCtx); err != nil {
+		return err
+	}
+
 	j.log.Infof("Sending NATS messages to JetStream subject: %v", j.conf.Subject)
 
 	j.natsConn = natsConn
@@ -168,7 +204,18 @@ func (j *jetStreamOutput) Write(ctx context.Context, msg *service.Message) error
 		return err
 	}
 
-	_, err = jCtx.Publish(subject, msgBytes)
+	var msgID string
+	if j.msgIDStr != nil {
+		msgID = j.msgIDStr.String(msg)
+	}
+	if msgID == "" {
+		_, err = jCtx.Publish(subject, msgBytes)
+		return err
+	}
+
+	nmsg := nats.NewMsg(subject)
+	nmsg.Data = msgBytes
+	_, err = jCtx.PublishMsg(nmsg, nats.MsgId(msgID))
 	return err
 }
 
@@ -31,3 +31,53 @@ auth:
 	assert.Equal(t, "test auth n key file", e.authConf.NKeyFile)
 	assert.Equal(t, "test auth user creds file", e.authConf.UserCredentialsFile)
 }
+
+func TestOutputJetStreamConfigDedupe(t *testing.T) {
+	spec := natsJetStreamOutputConfig()
+	env := service.NewEnvironment()
+
+	outputConfig := `
+urls: [ url1 ]
+subject: foo.${! meta("type") }
+msg_id: ${! json("id") }
+stream:
+  name: foostream
+  create: true
+  subjects: [ foo.> ]
+`
+
+	conf, err := spec.ParseYAML(outputConfig, env)
+	require.NoError(t, err)
+
+	e, err := newJetStreamWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"id":"bar"}`))
+	msg.MetaSet("type", "baz")
+
+	assert.Equal(t, "foo.baz", e.subjectStr.String(msg))
+	require.NotNil(t, e.msgIDStr)
+	assert.Equal(t, "bar", e.msgIDStr.String(msg))
+	assert.Equal(t, "foostream", e.stream.name)
+	assert.Equal(t, []string{"foo.>"}, e.stream.conf.Subjects)
+}
+
+func TestOutputJetStreamConfigInterpolatedStreamSubject(t *testing.T) {
+	spec := natsJetStreamOutputConfig()
+	env := service.NewEnvironment()
+
+	outputConfig := `
+urls: [ url1 ]
+subject: foo.${! meta("type") }
+stream:
+  name: foostream
+  create: true
+`
+
+	conf, err := spec.ParseYAML(outputConfig, env)
+	require.NoError(t, err)
+
+	_, err = newJetStreamWriterFromConfig(conf, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stream subjects must be specified")
+}
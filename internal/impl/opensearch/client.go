@@ -0,0 +1,243 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	baws "github.com/Jeffail/benthos/v3/internal/impl/aws"
+	"github.com/Jeffail/benthos/v3/public/service"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+func clientFields() []*service.ConfigField {
+	awsFields := []*service.ConfigField{
+		service.NewBoolField("enabled").
+			Description("Whether to sign requests with AWS Signature Version 4, which is required by Amazon OpenSearch Service domains that use IAM based access policies.").
+			Default(false),
+		service.NewStringField("service").
+			Description("The AWS service name requests are signed for, which should be `es` for Amazon OpenSearch Service domains and `aoss` for Amazon OpenSearch Serverless collections.").
+			Default("es"),
+	}
+	awsFields = append(awsFields, baws.SessionFields()...)
+
+	return []*service.ConfigField{
+		service.NewStringListField("urls").
+			Description("A list of URLs of OpenSearch nodes to connect to. Requests are distributed across the nodes in a round robin fashion.").
+			Example([]string{"http://localhost:9200"}),
+		service.NewObjectField("basic_auth",
+			service.NewBoolField("enabled").
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField("username").
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default(""),
+		).Description("Allows you to specify basic authentication.").Advanced(),
+		service.NewTLSToggledField("tls"),
+		service.NewObjectField("aws", awsFields...).
+			Description("Enables and customises request signing for Amazon OpenSearch Service.").
+			Advanced(),
+		service.NewDurationField("timeout").
+			Description("The maximum period to wait for each request to complete.").
+			Default("5s").
+			Advanced(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// osClient performs requests against the REST API of an OpenSearch cluster,
+// signing them with AWS credentials when configured.
+type osClient struct {
+	urls []*url.URL
+	next uint64
+	http *http.Client
+
+	username string
+	password string
+
+	signer  *v4.Signer
+	service string
+	region  string
+}
+
+func osClientFromConfig(conf *service.ParsedConfig) (*osClient, error) {
+	c := &osClient{}
+
+	urlStrs, err := conf.FieldStringList("urls")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urlStrs {
+		for _, splitURL := range strings.Split(u, ",") {
+			if splitURL = strings.TrimSpace(splitURL); splitURL == "" {
+				continue
+			}
+			parsed, err := url.Parse(strings.TrimSuffix(splitURL, "/"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse url '%v': %w", splitURL, err)
+			}
+			c.urls = append(c.urls, parsed)
+		}
+	}
+	if len(c.urls) == 0 {
+		return nil, errors.New("at least one url must be specified")
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	c.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	authEnabled, err := conf.FieldBool("basic_auth", "enabled")
+	if err != nil {
+		return nil, err
+	}
+	if authEnabled {
+		if c.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if c.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+
+	awsConf := conf.Namespace("aws")
+	awsEnabled, err := awsConf.FieldBool("enabled")
+	if err != nil {
+		return nil, err
+	}
+	if awsEnabled {
+		if c.service, err = awsConf.FieldString("service"); err != nil {
+			return nil, err
+		}
+		sess, err := baws.GetSession(awsConf)
+		if err != nil {
+			return nil, err
+		}
+		if sess.Config.Region == nil || *sess.Config.Region == "" {
+			return nil, errors.New("an AWS region must be specified in order to sign requests")
+		}
+		c.region = *sess.Config.Region
+		c.signer = v4.NewSigner(sess.Config.Credentials)
+	}
+	return c, nil
+}
+
+// do performs a request against the next node of the cluster and returns the
+// status code and body of the response.
+func (c *osClient) do(ctx context.Context, method, path string, query url.Values, body []byte) (int, []byte, error) {
+	base := c.urls[int(atomic.AddUint64(&c.next, 1)-1)%len(c.urls)]
+
+	reqURL := *base
+	reqURL.Path = base.Path + path
+	if query != nil {
+		reqURL.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	if c.signer != nil {
+		if c.service == "aoss" {
+			// OpenSearch Serverless requires the hash of the payload to be
+			// provided explicitly.
+			sum := sha256.Sum256(body)
+			req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+		}
+		if _, err := c.signer.Sign(req, bytes.NewReader(body), c.service, c.region, time.Now()); err != nil {
+			return 0, nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// doJSON performs a request and decodes the body of a successful response into
+// the provided value, returning an error for any unsuccessful status code.
+func (c *osClient) doJSON(ctx context.Context, method, path string, query url.Values, body []byte, v interface{}) error {
+	status, resBody, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return osResponseError(status, resBody)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resBody, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// osErrorDetails is the error object of an OpenSearch response.
+type osErrorDetails struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+func (e *osErrorDetails) String() string {
+	if e == nil {
+		return "unknown error"
+	}
+	if e.Reason == "" {
+		return e.Type
+	}
+	return fmt.Sprintf("%v: %v", e.Type, e.Reason)
+}
+
+func osResponseError(status int, body []byte) error {
+	var res struct {
+		Error *osErrorDetails `json:"error"`
+	}
+	if err := json.Unmarshal(body, &res); err == nil && res.Error != nil {
+		return fmt.Errorf("request failed with status %v: %v", status, res.Error)
+	}
+	return fmt.Errorf("request failed with status %v: %s", status, bytes.TrimSpace(body))
+}
@@ -0,0 +1,73 @@
+package opensearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpensearchClientSigning(t *testing.T) {
+	var authHeader, contentHash string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		contentHash = r.Header.Get("X-Amz-Content-Sha256")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ `+server.URL+` ]
+index: foo
+aws:
+  enabled: true
+  service: aoss
+  region: eu-west-1
+  credentials:
+    id: foo
+    secret: bar
+`, nil)
+	require.NoError(t, err)
+
+	client, err := osClientFromConfig(conf)
+	require.NoError(t, err)
+	require.NoError(t, client.doJSON(context.Background(), "POST", "/_bulk", nil, []byte(`{}`), nil))
+
+	assert.True(t, strings.HasPrefix(authHeader, "AWS4-HMAC-SHA256 Credential=foo/"), authHeader)
+	assert.Contains(t, authHeader, "/eu-west-1/aoss/aws4_request")
+	assert.Equal(t, "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", contentHash)
+}
+
+func TestOpensearchClientBasicAuth(t *testing.T) {
+	var user, pass string
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ = r.BasicAuth()
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ "`+server.URL+`/a,`+server.URL+`/b/" ]
+index: foo
+basic_auth:
+  enabled: true
+  username: foo
+  password: bar
+`, nil)
+	require.NoError(t, err)
+
+	client, err := osClientFromConfig(conf)
+	require.NoError(t, err)
+	require.NoError(t, client.doJSON(context.Background(), "GET", "/", nil, nil, nil))
+	require.NoError(t, client.doJSON(context.Background(), "GET", "/", nil, nil, nil))
+
+	assert.Equal(t, "foo", user)
+	assert.Equal(t, "bar", pass)
+	assert.Equal(t, []string{"/a/", "/b/"}, paths)
+}
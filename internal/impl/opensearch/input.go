@@ -0,0 +1,288 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func opensearchInputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Reads all documents of an OpenSearch index that match a query from a point in time.").
+		Description(`A [point in time](https://opensearch.org/docs/latest/search-plugins/point-in-time/) is created when the input connects, which provides a consistent view of the index for the duration of the read regardless of documents being written to it in the meantime. This makes the input suitable for re-indexing jobs. Documents are read in pages ordered by the fields of ` + "`sort`" + `, where each page is emitted as a batch of messages.
+
+Once all matching documents have been read the point in time is deleted and the input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+Points in time require OpenSearch version 2.4 or newer.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- opensearch_index
+- opensearch_id
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).
+
+### AWS
+
+When ` + "`aws.enabled`" + ` is set requests are signed with [AWS Signature Version 4](https://docs.aws.amazon.com/general/latest/gr/signature-version-4.html) using the credentials described [in this document](/docs/guides/cloud/aws).`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringField("index").
+			Description("The index, or comma separated list of indexes, to read documents from.").
+			Example("events")).
+		Field(service.NewStringField("query").
+			Description("A JSON [query](https://opensearch.org/docs/latest/query-dsl/) that documents must match.").
+			Example(`{"range":{"created_at":{"gte":"now-1d"}}}`).
+			Default(`{"match_all":{}}`)).
+		Field(service.NewStringListField("sort").
+			Description("A list of fields to sort documents by in ascending order. The values of the fields must uniquely identify each document in order for documents to be paged through reliably, and therefore the last field should be unique.").
+			Example([]string{"created_at", "_id"}).
+			Default([]string{"_id"}).
+			Advanced()).
+		Field(service.NewIntField("page_size").
+			Description("The maximum number of documents to read within each page, which determines the size of each batch.").
+			Default(1000).
+			Advanced()).
+		Field(service.NewStringField("keep_alive").
+			Description("The period of time to keep the point in time alive for between pages, using [OpenSearch time units](https://opensearch.org/docs/latest/api-reference/units/).").
+			Default("5m").
+			Advanced()).
+		Example("Re-indexing", `
+Copy all documents of an index to a new index within an Amazon OpenSearch Service domain, preserving document IDs:`,
+			`
+input:
+  opensearch:
+    urls: [ https://search-benthos-abcdefg.eu-west-1.es.amazonaws.com ]
+    index: events
+    aws:
+      enabled: true
+      region: eu-west-1
+
+output:
+  opensearch:
+    urls: [ https://search-benthos-abcdefg.eu-west-1.es.amazonaws.com ]
+    index: events_v2
+    id: ${! meta("opensearch_id") }
+    aws:
+      enabled: true
+      region: eu-west-1
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"opensearch", opensearchInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newOpensearchInputFromConfig(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type opensearchInput struct {
+	client *osClient
+	log    *service.Logger
+
+	index     string
+	query     json.RawMessage
+	sort      []map[string]string
+	pageSize  int
+	keepAlive string
+
+	mut         sync.Mutex
+	pitID       string
+	searchAfter []interface{}
+	done        bool
+}
+
+func newOpensearchInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*opensearchInput, error) {
+	i := &opensearchInput{log: log}
+
+	var err error
+	if i.client, err = osClientFromConfig(conf); err != nil {
+		return nil, err
+	}
+	if i.index, err = conf.FieldString("index"); err != nil {
+		return nil, err
+	}
+	if i.index == "" {
+		return nil, errors.New("an index must be specified")
+	}
+
+	queryStr, err := conf.FieldString("query")
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(queryStr)) {
+		return nil, errors.New("query must be a valid JSON object")
+	}
+	i.query = json.RawMessage(queryStr)
+
+	sortFields, err := conf.FieldStringList("sort")
+	if err != nil {
+		return nil, err
+	}
+	if len(sortFields) == 0 {
+		return nil, errors.New("at least one sort field must be specified")
+	}
+	for _, f := range sortFields {
+		i.sort = append(i.sort, map[string]string{f: "asc"})
+	}
+
+	if i.pageSize, err = conf.FieldInt("page_size"); err != nil {
+		return nil, err
+	}
+	if i.pageSize < 1 {
+		return nil, errors.New("page_size must be greater than zero")
+	}
+	if i.keepAlive, err = conf.FieldString("keep_alive"); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *opensearchInput) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.pitID != "" || i.done {
+		return nil
+	}
+
+	var res struct {
+		PitID string `json:"pit_id"`
+	}
+	path := "/" + i.index + "/_search/point_in_time"
+	if err := i.client.doJSON(ctx, "POST", path, url.Values{
+		"keep_alive": []string{i.keepAlive},
+	}, nil, &res); err != nil {
+		return fmt.Errorf("failed to create point in time: %w", err)
+	}
+	if res.PitID == "" {
+		return errors.New("failed to create point in time: response did not contain an ID")
+	}
+	i.pitID = res.PitID
+
+	i.log.Infof("Reading documents from OpenSearch index: %v\n", i.index)
+	return nil
+}
+
+type osSearchResponse struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []struct {
+			Index  string          `json:"_index"`
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+			Sort   []interface{}   `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (i *opensearchInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.done {
+		return nil, nil, service.ErrEndOfInput
+	}
+	if i.pitID == "" {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	req := map[string]interface{}{
+		"size":  i.pageSize,
+		"query": i.query,
+		"sort":  i.sort,
+		"pit": map[string]interface{}{
+			"id":         i.pitID,
+			"keep_alive": i.keepAlive,
+		},
+	}
+	if i.searchAfter != nil {
+		req["search_after"] = i.searchAfter
+	}
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res osSearchResponse
+	if err := i.client.doJSON(ctx, "POST", "/_search", nil, reqBytes, &res); err != nil {
+		return nil, nil, err
+	}
+	if res.PitID != "" {
+		i.pitID = res.PitID
+	}
+
+	hits := res.Hits.Hits
+	if len(hits) == 0 {
+		i.done = true
+		if err := i.deletePIT(ctx); err != nil {
+			i.log.Warnf("Failed to delete point in time: %v\n", err)
+		}
+		return nil, nil, service.ErrEndOfInput
+	}
+	i.searchAfter = hits[len(hits)-1].Sort
+
+	batch := make(service.MessageBatch, len(hits))
+	for j, hit := range hits {
+		msg := service.NewMessage(hit.Source)
+		msg.MetaSet("opensearch_index", hit.Index)
+		msg.MetaSet("opensearch_id", hit.ID)
+		batch[j] = msg
+	}
+	return batch, func(ctx context.Context, err error) error {
+		// Nacks are handled by AutoRetryNacksBatched because documents can be
+		// read again from the point in time.
+		return nil
+	}, nil
+}
+
+func (i *opensearchInput) deletePIT(ctx context.Context) error {
+	if i.pitID == "" {
+		return nil
+	}
+	reqBytes, err := json.Marshal(map[string]interface{}{
+		"pit_id": []string{i.pitID},
+	})
+	if err != nil {
+		return err
+	}
+	if err := i.client.doJSON(ctx, "DELETE", "/_search/point_in_time", nil, reqBytes, nil); err != nil {
+		return err
+	}
+	i.pitID = ""
+	return nil
+}
+
+func (i *opensearchInput) Close(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	return i.deletePIT(ctx)
+}
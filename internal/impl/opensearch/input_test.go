@@ -0,0 +1,172 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpensearchInputPointInTime(t *testing.T) {
+	docs := []string{"a", "b", "c"}
+
+	var reqMut sync.Mutex
+	var searchAfters []interface{}
+	var deletedPIT interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/foo/_search/point_in_time":
+			assert.Equal(t, "2m", r.URL.Query().Get("keep_alive"))
+			_, _ = w.Write([]byte(`{"pit_id":"pit1"}`))
+
+		case r.Method == "POST" && r.URL.Path == "/_search":
+			var req map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			assert.Equal(t, map[string]interface{}{"id": "pit1", "keep_alive": "2m"}, req["pit"])
+			assert.Equal(t, map[string]interface{}{"term": map[string]interface{}{"type": "foo"}}, req["query"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"_id": "asc"}}, req["sort"])
+			searchAfters = append(searchAfters, req["search_after"])
+
+			start := 0
+			if after, ok := req["search_after"].([]interface{}); ok {
+				for i, d := range docs {
+					if d == after[0] {
+						start = i + 1
+					}
+				}
+			}
+			end := start + int(req["size"].(float64))
+			if end > len(docs) {
+				end = len(docs)
+			}
+
+			var hits []interface{}
+			for _, d := range docs[start:end] {
+				hits = append(hits, map[string]interface{}{
+					"_index":  "foo",
+					"_id":     d,
+					"_source": map[string]interface{}{"doc": d},
+					"sort":    []interface{}{d},
+				})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"pit_id": "pit1",
+				"hits":   map[string]interface{}{"hits": hits},
+			})
+
+		case r.Method == "DELETE" && r.URL.Path == "/_search/point_in_time":
+			var req map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			deletedPIT = req["pit_id"]
+			_, _ = w.Write([]byte(`{"pits":[{"successful":true,"pit_id":"pit1"}]}`))
+
+		default:
+			t.Errorf("unexpected request: %v %v", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conf, err := opensearchInputConfig().ParseYAML(`
+urls: [ `+server.URL+` ]
+index: foo
+query: '{"term":{"type":"foo"}}'
+page_size: 2
+keep_alive: 2m
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newOpensearchInputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, in.Connect(ctx))
+
+	var ids, contents []string
+	for {
+		batch, _, err := in.ReadBatch(ctx)
+		if err == service.ErrEndOfInput {
+			break
+		}
+		require.NoError(t, err)
+		for _, msg := range batch {
+			id, _ := msg.MetaGet("opensearch_id")
+			ids = append(ids, id)
+			b, err := msg.AsBytes()
+			require.NoError(t, err)
+			contents = append(contents, string(b))
+		}
+	}
+	require.NoError(t, in.Close(ctx))
+
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+	assert.Equal(t, []string{`{"doc":"a"}`, `{"doc":"b"}`, `{"doc":"c"}`}, contents)
+
+	reqMut.Lock()
+	assert.Equal(t, []interface{}{nil, []interface{}{"b"}, []interface{}{"c"}}, searchAfters)
+	assert.Equal(t, []interface{}{"pit1"}, deletedPIT)
+	reqMut.Unlock()
+}
+
+func TestOpensearchInputConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"no urls": {
+			conf: `
+urls: []
+index: foo
+`,
+			errContains: "at least one url",
+		},
+		"invalid query": {
+			conf: `
+urls: [ http://localhost:9200 ]
+index: foo
+query: '{'
+`,
+			errContains: "valid JSON",
+		},
+		"no sort": {
+			conf: `
+urls: [ http://localhost:9200 ]
+index: foo
+sort: []
+`,
+			errContains: "sort field",
+		},
+		"aws without region": {
+			conf: `
+urls: [ http://localhost:9200 ]
+index: foo
+aws:
+  enabled: true
+`,
+			errContains: "region",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := opensearchInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newOpensearchInputFromConfig(conf, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
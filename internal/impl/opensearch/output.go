@@ -0,0 +1,334 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+)
+
+func opensearchOutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Publishes message batches to an OpenSearch index with the bulk API.").
+		Description(output.Description(true, true, `
+Each message batch is written with a single request to the [bulk API](https://opensearch.org/docs/latest/api-reference/document-apis/bulk/), where each message must be a JSON document. The requests are made directly over HTTP and therefore this output supports OpenSearch clusters of any version, including Amazon OpenSearch Service domains and Amazon OpenSearch Serverless collections.
+
+### Actions
+
+The field `+"`action`"+` determines the bulk action applied to each message:
+
+- `+"`index`"+` writes the document, replacing any existing document with the same ID.
+- `+"`create`"+` writes the document, failing if a document with the same ID already exists.
+- `+"`update`"+` merges the document into an existing document, failing if it does not exist.
+- `+"`upsert`"+` merges the document into an existing document, creating it if it does not exist.
+- `+"`delete`"+` removes the document with the ID, where the contents of the message are ignored. Deleting a document that does not exist is not considered a failure.
+
+All actions other than `+"`index`"+` and `+"`create`"+` require an ID.
+
+### Errors
+
+Documents that are rejected by the cluster due to back pressure (status 429) or server errors are retried according to the field `+"`backoff`"+`. Documents that are rejected for any other reason, such as mapping errors, are not retried by this output. Instead, only the failed messages of the batch are reported as failed, and they can be handled with [error handling patterns](/docs/configuration/error_handling) without duplicating the documents that were written successfully.
+
+### AWS
+
+When `+"`aws.enabled`"+` is set requests are signed with [AWS Signature Version 4](https://docs.aws.amazon.com/general/latest/gr/signature-version-4.html) using the credentials described [in this document](/docs/guides/cloud/aws).`))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField("index").
+			Description("The index to place documents in.").
+			Example("benthos-${! timestamp(\"2006-01-02\") }")).
+		Field(service.NewInterpolatedStringField("action").
+			Description("The bulk action to apply to each document, which must resolve to one of `index`, `create`, `update`, `upsert` or `delete`.").
+			Default("index")).
+		Field(service.NewInterpolatedStringField("id").
+			Description("The ID of each document. When empty an ID is generated by the cluster, which is only possible with the `index` and `create` actions.").
+			Example("${! json(\"id\") }").
+			Default("")).
+		Field(service.NewInterpolatedStringField("pipeline").
+			Description("An optional ingest pipeline to process documents with, which only applies to the `index` and `create` actions.").
+			Default("").
+			Advanced()).
+		Field(service.NewInterpolatedStringField("routing").
+			Description("An optional routing value to determine the shard of each document.").
+			Default("").
+			Advanced()).
+		Field(service.NewBackOffField("backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 5,
+			MaxElapsedTime:  time.Second * 30,
+		}).
+			Description("Determine time intervals and cut offs for retrying documents that were rejected due to back pressure or server errors.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Amazon OpenSearch Service", `
+Write documents to an Amazon OpenSearch Service domain, where requests are signed with the credentials of the environment, using a field of each document as its ID:`,
+			`
+output:
+  opensearch:
+    urls: [ https://search-benthos-abcdefg.eu-west-1.es.amazonaws.com ]
+    index: events
+    id: ${! json("id") }
+    aws:
+      enabled: true
+      region: eu-west-1
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"opensearch", opensearchOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newOpensearchOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type opensearchOutput struct {
+	client  *osClient
+	log     *service.Logger
+	backoff *backoff.ExponentialBackOff
+
+	index    *service.InterpolatedString
+	action   *service.InterpolatedString
+	id       *service.InterpolatedString
+	pipeline *service.InterpolatedString
+	routing  *service.InterpolatedString
+}
+
+func newOpensearchOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*opensearchOutput, error) {
+	o := &opensearchOutput{log: log}
+
+	var err error
+	if o.client, err = osClientFromConfig(conf); err != nil {
+		return nil, err
+	}
+	if o.backoff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+	if o.index, err = conf.FieldInterpolatedString("index"); err != nil {
+		return nil, err
+	}
+	if o.action, err = conf.FieldInterpolatedString("action"); err != nil {
+		return nil, err
+	}
+	if o.id, err = conf.FieldInterpolatedString("id"); err != nil {
+		return nil, err
+	}
+	if o.pipeline, err = conf.FieldInterpolatedString("pipeline"); err != nil {
+		return nil, err
+	}
+	if o.routing, err = conf.FieldInterpolatedString("routing"); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *opensearchOutput) Connect(ctx context.Context) error {
+	if err := o.client.doJSON(ctx, "GET", "/", nil, nil, nil); err != nil {
+		return err
+	}
+	o.log.Infof("Writing message batches to OpenSearch nodes: %v\n", o.client.urls)
+	return nil
+}
+
+// osBulkItem is a single action of a bulk request.
+type osBulkItem struct {
+	index  int
+	action string
+	meta   []byte
+	doc    []byte
+	err    error
+}
+
+// bulkItem creates the bulk action of a message of a batch.
+func (o *opensearchOutput) bulkItem(batch service.MessageBatch, i int) (*osBulkItem, error) {
+	item := &osBulkItem{index: i}
+
+	action := batch.InterpolatedString(i, o.action)
+	id := batch.InterpolatedString(i, o.id)
+
+	meta := map[string]interface{}{
+		"_index": batch.InterpolatedString(i, o.index),
+	}
+	if id != "" {
+		meta["_id"] = id
+	}
+	if routing := batch.InterpolatedString(i, o.routing); routing != "" {
+		meta["routing"] = routing
+	}
+
+	switch action {
+	case "index", "create":
+		if pipeline := batch.InterpolatedString(i, o.pipeline); pipeline != "" {
+			meta["pipeline"] = pipeline
+		}
+		item.action = action
+	case "update", "upsert", "delete":
+		if id == "" {
+			return nil, fmt.Errorf("an id is required for the %v action", action)
+		}
+		item.action = "update"
+		if action == "delete" {
+			item.action = "delete"
+		}
+	default:
+		return nil, fmt.Errorf("action '%v' is not supported", action)
+	}
+
+	var err error
+	if item.meta, err = json.Marshal(map[string]interface{}{item.action: meta}); err != nil {
+		return nil, err
+	}
+	if action == "delete" {
+		return item, nil
+	}
+
+	msgBytes, err := batch[i].AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each document must occupy a single line of the bulk request.
+	var doc bytes.Buffer
+	if err := json.Compact(&doc, msgBytes); err != nil {
+		return nil, fmt.Errorf("message is not a valid JSON document: %w", err)
+	}
+
+	switch action {
+	case "update":
+		item.doc = []byte(`{"doc":` + doc.String() + `}`)
+	case "upsert":
+		item.doc = []byte(`{"doc":` + doc.String() + `,"doc_as_upsert":true}`)
+	default:
+		item.doc = doc.Bytes()
+	}
+	return item, nil
+}
+
+type osBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Result string          `json:"result"`
+		Error  *osErrorDetails `json:"error"`
+	} `json:"items"`
+}
+
+func osShouldRetry(status int) bool {
+	return status == 429 || status >= 500
+}
+
+func (o *opensearchOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to write documents"))
+
+	pending := make([]*osBulkItem, 0, len(batch))
+	for i := range batch {
+		item, err := o.bulkItem(batch, i)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	boff := *o.backoff
+	boff.Reset()
+
+	for len(pending) > 0 {
+		var body bytes.Buffer
+		for _, item := range pending {
+			body.Write(item.meta)
+			body.WriteByte('\n')
+			if item.doc != nil {
+				body.Write(item.doc)
+				body.WriteByte('\n')
+			}
+		}
+
+		var res osBulkResponse
+		if err := o.client.doJSON(ctx, "POST", "/_bulk", nil, body.Bytes(), &res); err != nil {
+			return err
+		}
+		if len(res.Items) != len(pending) {
+			return fmt.Errorf("expected %v items in bulk response, received %v", len(pending), len(res.Items))
+		}
+
+		var retry []*osBulkItem
+		for j, resItem := range res.Items {
+			item := pending[j]
+			for _, r := range resItem {
+				if r.Status >= 200 && r.Status <= 299 {
+					continue
+				}
+				if item.action == "delete" && r.Status == 404 {
+					continue
+				}
+				item.err = fmt.Errorf("failed to %v document: %v", item.action, r.Error)
+				if osShouldRetry(r.Status) {
+					retry = append(retry, item)
+				} else {
+					batchErr.Failed(item.index, item.err)
+				}
+			}
+		}
+		if len(retry) == 0 {
+			break
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			for _, item := range retry {
+				batchErr.Failed(item.index, item.err)
+			}
+			break
+		}
+		o.log.Warnf("Retrying %v rejected documents: %v\n", len(retry), retry[0].err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		pending = retry
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *opensearchOutput) Close(ctx context.Context) error {
+	return nil
+}
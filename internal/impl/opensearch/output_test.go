@@ -0,0 +1,229 @@
+package opensearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpensearchBulkItems(t *testing.T) {
+	tests := map[string]struct {
+		action string
+		doc    string
+		meta   string
+		body   string
+	}{
+		"index": {
+			action: "index",
+			doc:    `{"id":"1", "foo":"bar"}`,
+			meta:   `{"index":{"_id":"1","_index":"foo","pipeline":"baz","routing":"buz"}}`,
+			body:   `{"id":"1","foo":"bar"}`,
+		},
+		"create": {
+			action: "create",
+			doc:    `{"id":"1"}`,
+			meta:   `{"create":{"_id":"1","_index":"foo","pipeline":"baz","routing":"buz"}}`,
+			body:   `{"id":"1"}`,
+		},
+		"update": {
+			action: "update",
+			doc:    "{\n  \"id\": \"1\"\n}",
+			meta:   `{"update":{"_id":"1","_index":"foo","routing":"buz"}}`,
+			body:   `{"doc":{"id":"1"}}`,
+		},
+		"upsert": {
+			action: "upsert",
+			doc:    `{"id":"1"}`,
+			meta:   `{"update":{"_id":"1","_index":"foo","routing":"buz"}}`,
+			body:   `{"doc":{"id":"1"},"doc_as_upsert":true}`,
+		},
+		"delete": {
+			action: "delete",
+			doc:    `not json`,
+			meta:   `{"delete":{"_id":"1","_index":"foo","routing":"buz"}}`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ http://localhost:9200 ]
+index: foo
+action: `+test.action+`
+id: '1'
+pipeline: baz
+routing: buz
+`, nil)
+			require.NoError(t, err)
+
+			out, err := newOpensearchOutputFromConfig(conf, nil)
+			require.NoError(t, err)
+
+			item, err := out.bulkItem(service.MessageBatch{service.NewMessage([]byte(test.doc))}, 0)
+			require.NoError(t, err)
+			assert.Equal(t, test.meta, string(item.meta))
+			assert.Equal(t, test.body, string(item.doc))
+		})
+	}
+}
+
+func TestOpensearchBulkItemErrors(t *testing.T) {
+	conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ http://localhost:9200 ]
+index: foo
+action: ${! meta("action") }
+id: ${! meta("id") }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOpensearchOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	newMsg := func(action, id, doc string) *service.Message {
+		msg := service.NewMessage([]byte(doc))
+		msg.MetaSet("action", action)
+		msg.MetaSet("id", id)
+		return msg
+	}
+
+	batch := service.MessageBatch{
+		newMsg("nope", "1", `{}`),
+		newMsg("update", "", `{}`),
+		newMsg("index", "1", `not json`),
+	}
+
+	_, err = out.bulkItem(batch, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "action 'nope' is not supported")
+
+	_, err = out.bulkItem(batch, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "an id is required")
+
+	_, err = out.bulkItem(batch, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid JSON document")
+}
+
+func TestOpensearchOutputItemFailures(t *testing.T) {
+	var reqMut sync.Mutex
+	var bulkIDs [][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			_, _ = w.Write([]byte(`{"version":{"number":"2.4.0"}}`))
+			return
+		}
+
+		var ids []string
+		var items []interface{}
+
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var meta map[string]map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &meta))
+			require.True(t, scanner.Scan())
+
+			id, _ := meta["index"]["_id"].(string)
+			ids = append(ids, id)
+
+			item := map[string]interface{}{"_index": "foo", "_id": id, "status": 201}
+			switch {
+			case id == "b" && len(bulkIDs) == 0:
+				item["status"] = 429
+				item["error"] = map[string]interface{}{"type": "rejected_execution_exception", "reason": "too busy"}
+			case id == "c":
+				item["status"] = 400
+				item["error"] = map[string]interface{}{"type": "mapper_parsing_exception", "reason": "bad doc"}
+			}
+			items = append(items, map[string]interface{}{"index": item})
+		}
+
+		reqMut.Lock()
+		bulkIDs = append(bulkIDs, ids)
+		reqMut.Unlock()
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":   1,
+			"errors": true,
+			"items":  items,
+		})
+	}))
+	defer server.Close()
+
+	conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ `+server.URL+` ]
+index: foo
+id: ${! json("id") }
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOpensearchOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b"}`)),
+		service.NewMessage([]byte(`{"id":"c"}`)),
+		service.NewMessage([]byte(`not json`)),
+	}
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	reqMut.Lock()
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"b"}}, bulkIDs)
+	reqMut.Unlock()
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{2, 3}, failed)
+}
+
+func TestOpensearchOutputRequestFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"type":"security_exception","reason":"no permissions"},"status":403}`))
+	}))
+	defer server.Close()
+
+	conf, err := opensearchOutputConfig().ParseYAML(`
+urls: [ `+server.URL+` ]
+index: foo
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOpensearchOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "security_exception: no permissions"), err.Error())
+
+	var batchErr *service.BatchError
+	assert.False(t, errors.As(err, &batchErr))
+}
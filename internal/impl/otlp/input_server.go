@@ -0,0 +1,442 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	// Allows exporters to compress requests with gzip.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+func serverInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Network").
+		Summary("Receive logs, metrics and traces exported with the [OpenTelemetry Protocol](https://opentelemetry.io/docs/specs/otlp/) (OTLP) over gRPC and HTTP.").
+		Description(`
+This input runs a gRPC server and an HTTP server that implement the collector services of OTLP, allowing applications and OpenTelemetry collectors to export telemetry to Benthos in the same way as they would to a collector. Requests over HTTP can be encoded either as protobuf or JSON, and compressed with gzip.
+
+Each log record, metric and span of an export request becomes a message, where the records of a request are consumed as a batch and the request is only responded to once the batch has been acknowledged. When the batch is rejected the request fails with the gRPC status `+"`UNAVAILABLE`"+`, or HTTP status 503, which indicates to exporters that they can safely retry it.
+
+### Message Format
+
+Messages are JSON documents in the [OTLP JSON encoding](https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding) of a resource containing a single record, which is a `+"`ResourceLogs`"+`, `+"`ResourceMetrics`"+` or `+"`ResourceSpans`"+` object with one instrumentation library containing one log record, metric or span. For example, the log record of a request is consumed as:
+
+`+"```json"+`
+{
+  "resource": {
+    "attributes": [ { "key": "service.name", "value": { "stringValue": "checkout" } } ]
+  },
+  "instrumentationLibraryLogs": [
+    {
+      "instrumentationLibrary": { "name": "checkout.logger" },
+      "logs": [
+        {
+          "timeUnixNano": "1650000000000000000",
+          "severityText": "INFO",
+          "body": { "stringValue": "order placed" },
+          "traceId": "5b8efff798038103d269b633813fc60c"
+        }
+      ]
+    }
+  ]
+}
+`+"```"+`
+
+Messages in this format can be sent on to an OTLP endpoint with the `+"[`otlp` output](/docs/components/outputs/otlp)"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- otlp_signal
+`+"```"+`
+
+Where `+"`otlp_signal`"+` is the signal of the record, which is one of `+"`logs`"+`, `+"`metrics`"+` or `+"`traces`"+`. You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringField("grpc_address").
+			Description("The address to listen for gRPC requests from. Set to an empty string in order to disable the gRPC server.").
+			Default("0.0.0.0:4317")).
+		Field(service.NewStringField("http_address").
+			Description("The address to listen for HTTP requests from, which are served on the paths `/v1/logs`, `/v1/metrics` and `/v1/traces`. Set to an empty string in order to disable the HTTP server.").
+			Default("0.0.0.0:4318")).
+		Field(service.NewStringField("cert_file").
+			Description("An optional certificate file for enabling TLS on both servers.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField("key_file").
+			Description("An optional key file for enabling TLS on both servers.").
+			Advanced().
+			Default("")).
+		Example("Filtering Logs", `
+Receive telemetry from applications, drop debug logs, and export everything else to a collector:`,
+			`
+input:
+  otlp_server: {}
+
+pipeline:
+  processors:
+    - switch:
+        - check: meta("otlp_signal") == "logs"
+          processors:
+            - bloblang: |
+                root = if this.instrumentationLibraryLogs.0.logs.0.severityText == "DEBUG" {
+                  deleted()
+                } else {
+                  this
+                }
+
+output:
+  otlp:
+    endpoint: otel-collector:4317
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"otlp_server", serverInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newServerInputFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type serverBatch struct {
+	batch service.MessageBatch
+	ackFn service.AckFunc
+}
+
+type serverInput struct {
+	grpcAddress string
+	httpAddress string
+	certFile    string
+	keyFile     string
+
+	log *service.Logger
+
+	connMut    sync.Mutex
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	grpcAddr   net.Addr
+	httpAddr   net.Addr
+
+	batchChan chan serverBatch
+	shutSig   *shutdown.Signaller
+}
+
+func newServerInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*serverInput, error) {
+	s := &serverInput{
+		log:       log,
+		batchChan: make(chan serverBatch),
+		shutSig:   shutdown.NewSignaller(),
+	}
+
+	var err error
+	if s.grpcAddress, err = conf.FieldString("grpc_address"); err != nil {
+		return nil, err
+	}
+	if s.httpAddress, err = conf.FieldString("http_address"); err != nil {
+		return nil, err
+	}
+	if s.grpcAddress == "" && s.httpAddress == "" {
+		return nil, errors.New("at least one of grpc_address or http_address must be specified")
+	}
+	if s.certFile, err = conf.FieldString("cert_file"); err != nil {
+		return nil, err
+	}
+	if s.keyFile, err = conf.FieldString("key_file"); err != nil {
+		return nil, err
+	}
+	if (s.certFile == "") != (s.keyFile == "") {
+		return nil, errors.New("both cert_file and key_file must be specified in order to enable TLS")
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+var errShuttingDown = errors.New("server is shutting down")
+
+// export delivers the records of a request as a batch, blocking until the
+// batch has been acknowledged.
+func (s *serverInput) export(ctx context.Context, signal *otlpSignal, req proto.Message) error {
+	batch, err := requestToBatch(signal, req)
+	if err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	resChan := make(chan error, 1)
+	select {
+	case s.batchChan <- serverBatch{
+		batch: batch,
+		ackFn: func(ctx context.Context, err error) error {
+			resChan <- err
+			return nil
+		},
+	}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.shutSig.CloseAtLeisureChan():
+		return errShuttingDown
+	}
+
+	select {
+	case err := <-resChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *serverInput) grpcServiceDesc(signal *otlpSignal) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: signal.service,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Export",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := signal.newRequest()
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					if err := s.export(ctx, signal, req.(proto.Message)); err != nil {
+						return nil, grpcStatusErr(ctx, err)
+					}
+					return signal.newResponse(), nil
+				}
+				if interceptor == nil {
+					return handler(ctx, req)
+				}
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{
+					Server:     srv,
+					FullMethod: "/" + signal.service + "/Export",
+				}, handler)
+			},
+		}},
+	}
+}
+
+func grpcStatusErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+		}
+		return status.Error(codes.Canceled, ctx.Err().Error())
+	}
+	return status.Errorf(codes.Unavailable, "failed to deliver request: %v", err)
+}
+
+func (s *serverInput) httpHandler(signal *otlpSignal) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to decompress request: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			body = zr
+		}
+		reqBytes, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		isJSON := false
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-protobuf":
+		case "application/json":
+			isJSON = true
+		default:
+			http.Error(w, fmt.Sprintf("unsupported content type: %v", mediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		req := signal.newRequest()
+		if isJSON {
+			err = unmarshalOTLPJSON(reqBytes, req)
+		} else {
+			err = proto.Unmarshal(reqBytes, req)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.export(r.Context(), signal, req); err != nil {
+			http.Error(w, fmt.Sprintf("failed to deliver request: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+
+		var resBytes []byte
+		if isJSON {
+			resBytes, err = protojson.Marshal(signal.newResponse())
+		} else {
+			resBytes, err = proto.Marshal(signal.newResponse())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
+		_, _ = w.Write(resBytes)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (s *serverInput) Connect(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.grpcServer != nil || s.httpServer != nil {
+		return nil
+	}
+	if s.shutSig.ShouldCloseAtLeisure() {
+		return service.ErrEndOfInput
+	}
+
+	var grpcListener, httpListener net.Listener
+	closeListeners := func() {
+		if grpcListener != nil {
+			grpcListener.Close()
+		}
+		if httpListener != nil {
+			httpListener.Close()
+		}
+	}
+
+	var err error
+	if s.grpcAddress != "" {
+		if grpcListener, err = net.Listen("tcp", s.grpcAddress); err != nil {
+			return err
+		}
+	}
+	if s.httpAddress != "" {
+		if httpListener, err = net.Listen("tcp", s.httpAddress); err != nil {
+			closeListeners()
+			return err
+		}
+	}
+
+	if grpcListener != nil {
+		var opts []grpc.ServerOption
+		if s.certFile != "" {
+			creds, err := credentials.NewServerTLSFromFile(s.certFile, s.keyFile)
+			if err != nil {
+				closeListeners()
+				return fmt.Errorf("failed to load TLS credentials: %w", err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+
+		s.grpcServer = grpc.NewServer(opts...)
+		for _, signal := range otlpSignals {
+			s.grpcServer.RegisterService(s.grpcServiceDesc(signal), struct{}{})
+		}
+		s.grpcAddr = grpcListener.Addr()
+
+		go func(server *grpc.Server) {
+			if err := server.Serve(grpcListener); err != nil {
+				s.log.Errorf("OTLP gRPC server stopped: %v", err)
+			}
+		}(s.grpcServer)
+		s.log.Infof("Receiving OTLP requests over gRPC at: %v", grpcListener.Addr())
+	}
+
+	if httpListener != nil {
+		mux := http.NewServeMux()
+		for _, signal := range otlpSignals {
+			mux.HandleFunc(signal.httpPath, s.httpHandler(signal))
+		}
+		s.httpServer = &http.Server{Handler: mux}
+		s.httpAddr = httpListener.Addr()
+
+		go func(server *http.Server) {
+			var err error
+			if s.certFile != "" {
+				err = server.ServeTLS(httpListener, s.certFile, s.keyFile)
+			} else {
+				err = server.Serve(httpListener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.log.Errorf("OTLP HTTP server stopped: %v", err)
+			}
+		}(s.httpServer)
+		s.log.Infof("Receiving OTLP requests over HTTP at: %v", httpListener.Addr())
+	}
+	return nil
+}
+
+func (s *serverInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	select {
+	case b := <-s.batchChan:
+		return b.batch, b.ackFn, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-s.shutSig.CloseAtLeisureChan():
+		return nil, nil, service.ErrEndOfInput
+	}
+}
+
+func (s *serverInput) Close(ctx context.Context) error {
+	s.shutSig.CloseAtLeisure()
+
+	s.connMut.Lock()
+	grpcServer, httpServer := s.grpcServer, s.httpServer
+	s.grpcServer, s.httpServer = nil, nil
+	s.connMut.Unlock()
+
+	var err error
+	if httpServer != nil {
+		err = httpServer.Shutdown(ctx)
+	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+			return ctx.Err()
+		}
+	}
+	return err
+}
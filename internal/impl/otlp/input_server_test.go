@@ -0,0 +1,159 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func readServerBatch(t *testing.T, s *serverInput) (service.MessageBatch, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := s.ReadBatch(ctx)
+	require.NoError(t, err)
+	return batch, ackFn
+}
+
+func TestServerInputGRPC(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+grpc_address: 127.0.0.1:0
+http_address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+
+	conn, err := grpc.Dial(s.grpcAddr.String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- conn.Invoke(context.Background(), "/opentelemetry.proto.collector.logs.v1.LogsService/Export", testLogsRequest(), &collogs.ExportLogsServiceResponse{})
+	}()
+
+	batch, ackFn := readServerBatch(t, s)
+	require.Len(t, batch, 2)
+	v, _ := batch[0].MetaGet("otlp_signal")
+	assert.Equal(t, "logs", v)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	require.NoError(t, <-errChan)
+
+	go func() {
+		errChan <- conn.Invoke(context.Background(), "/opentelemetry.proto.collector.trace.v1.TraceService/Export", testTraceRequest(), &coltrace.ExportTraceServiceResponse{})
+	}()
+
+	batch, ackFn = readServerBatch(t, s)
+	require.Len(t, batch, 1)
+	v, _ = batch[0].MetaGet("otlp_signal")
+	assert.Equal(t, "traces", v)
+
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+	err = <-errChan
+	assert.Equal(t, codes.Unavailable, status.Code(err), "%v", err)
+}
+
+func TestServerInputHTTP(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+grpc_address: 127.0.0.1:0
+http_address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+	baseURL := "http://" + s.httpAddr.String()
+
+	reqBytes, err := proto.Marshal(testTraceRequest())
+	require.NoError(t, err)
+
+	type result struct {
+		status      int
+		contentType string
+		err         error
+	}
+	post := func(path, contentType string, body []byte) <-chan result {
+		resChan := make(chan result, 1)
+		go func() {
+			res, err := http.Post(baseURL+path, contentType, bytes.NewReader(body))
+			if err != nil {
+				resChan <- result{err: err}
+				return
+			}
+			res.Body.Close()
+			resChan <- result{status: res.StatusCode, contentType: res.Header.Get("Content-Type")}
+		}()
+		return resChan
+	}
+
+	resChan := post("/v1/traces", "application/x-protobuf", reqBytes)
+	batch, ackFn := readServerBatch(t, s)
+	require.Len(t, batch, 1)
+	v, _ := batch[0].MetaGet("otlp_signal")
+	assert.Equal(t, "traces", v)
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	res := <-resChan
+	require.NoError(t, res.err)
+	assert.Equal(t, 200, res.status)
+	assert.Equal(t, "application/x-protobuf", res.contentType)
+
+	resChan = post("/v1/logs", "application/json", []byte(`{
+  "resourceLogs": [{
+    "instrumentationLibraryLogs": [{
+      "logs": [{ "severityText": "WARN", "severityNumber": 13, "traceId": "5b8efff798038103d269b633813fc60c" }]
+    }]
+  }]
+}`))
+	batch, ackFn = readServerBatch(t, s)
+	require.Len(t, batch, 1)
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"instrumentationLibraryLogs":[{"logs":[{"severityNumber":"SEVERITY_NUMBER_WARN","severityText":"WARN","traceId":"5b8efff798038103d269b633813fc60c"}]}]}`, string(b))
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+
+	res = <-resChan
+	require.NoError(t, res.err)
+	assert.Equal(t, 503, res.status)
+
+	res = <-post("/v1/metrics", "text/plain", []byte(`nope`))
+	require.NoError(t, res.err)
+	assert.Equal(t, 415, res.status)
+
+	res = <-post("/v1/metrics", "application/json", []byte(`nope`))
+	require.NoError(t, res.err)
+	assert.Equal(t, 400, res.status)
+}
+
+func TestServerInputBadConfig(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+grpc_address: ""
+http_address: ""
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newServerInputFromConfig(conf, nil)
+	require.EqualError(t, err, "at least one of grpc_address or http_address must be specified")
+}
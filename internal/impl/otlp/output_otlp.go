@@ -0,0 +1,314 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func otlpOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Network").
+		Summary("Exports logs, metrics and traces with the [OpenTelemetry Protocol](https://opentelemetry.io/docs/specs/otlp/) (OTLP) over gRPC or HTTP.").
+		Description(output.Description(true, true, `
+Messages must be JSON documents in the [OTLP JSON encoding](https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding) of a `+"`ResourceLogs`"+`, `+"`ResourceMetrics`"+` or `+"`ResourceSpans`"+` object, which is the format of messages consumed by the `+"[`otlp_server` input](/docs/components/inputs/otlp_server)"+`. The signal of each message is determined by the field `+"`signal`"+`, and the messages of each signal within a batch are sent as a single export request.
+
+Messages that cannot be parsed as a resource of their signal are rejected.`)).
+		Field(service.NewStringField("endpoint").
+			Description("The endpoint to export to, which is the address of the server when `protocol` is `grpc`, and the base URL of the server when `protocol` is `http`.").
+			Example("localhost:4317").
+			Example("http://localhost:4318")).
+		Field(service.NewStringAnnotatedEnumField("protocol", map[string]string{
+			"grpc": "Export requests are sent with gRPC.",
+			"http": "Export requests are sent over HTTP encoded as protobuf, to the paths `/v1/logs`, `/v1/metrics` and `/v1/traces` of the endpoint.",
+		}).
+			Description("The protocol to export with.").
+			Default("grpc")).
+		Field(service.NewInterpolatedStringField("signal").
+			Description("The signal of each message, which must resolve to one of `logs`, `metrics` or `traces`.").
+			Default(`${! meta("otlp_signal") }`)).
+		Field(service.NewStringMapField("headers").
+			Description("A map of headers to add to all requests, which are sent as metadata when `protocol` is `grpc`.").
+			Default(map[string]string{}).
+			Example(map[string]string{
+				"api-key": "${OTLP_API_KEY}",
+			}).
+			Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to wait for each export request to complete.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Sampling Traces", `
+Receive traces from applications and export only the spans that took longer than a second, along with all logs and metrics, to a collector over HTTP:`,
+			`
+input:
+  otlp_server: {}
+
+pipeline:
+  processors:
+    - switch:
+        - check: meta("otlp_signal") == "traces"
+          processors:
+            - bloblang: |
+                let span = this.instrumentationLibrarySpans.0.spans.0
+                root = if $span.endTimeUnixNano.number() - $span.startTimeUnixNano.number() < 1000000000 {
+                  deleted()
+                } else {
+                  this
+                }
+
+output:
+  otlp:
+    endpoint: http://otel-collector:4318
+    protocol: http
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"otlp", otlpOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newOTLPOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type otlpOutput struct {
+	endpoint string
+	protocol string
+	signal   *service.InterpolatedString
+	headers  map[string]string
+	timeout  time.Duration
+	log      *service.Logger
+
+	grpcOpts []grpc.DialOption
+	http     *http.Client
+
+	connMut sync.Mutex
+	conn    *grpc.ClientConn
+}
+
+func newOTLPOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*otlpOutput, error) {
+	o := &otlpOutput{log: log}
+
+	var err error
+	if o.endpoint, err = conf.FieldString("endpoint"); err != nil {
+		return nil, err
+	}
+	if o.endpoint == "" {
+		return nil, errors.New("an endpoint must be specified")
+	}
+	if o.protocol, err = conf.FieldString("protocol"); err != nil {
+		return nil, err
+	}
+	if o.signal, err = conf.FieldInterpolatedString("signal"); err != nil {
+		return nil, err
+	}
+	if o.headers, err = conf.FieldStringMap("headers"); err != nil {
+		return nil, err
+	}
+	if o.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.protocol {
+	case "grpc":
+		o.grpcOpts = []grpc.DialOption{grpc.WithInsecure()}
+		if tlsEnabled {
+			o.grpcOpts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))}
+		}
+	case "http":
+		o.endpoint = strings.TrimSuffix(o.endpoint, "/")
+		o.http = &http.Client{Timeout: o.timeout}
+		if tlsEnabled {
+			o.http.Transport = &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConf,
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised protocol: %v", o.protocol)
+	}
+	return o, nil
+}
+
+func (o *otlpOutput) Connect(ctx context.Context) error {
+	if o.protocol != "grpc" {
+		o.log.Infof("Exporting OTLP requests over HTTP to: %v\n", o.endpoint)
+		return nil
+	}
+
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.conn != nil {
+		return nil
+	}
+	conn, err := grpc.DialContext(ctx, o.endpoint, o.grpcOpts...)
+	if err != nil {
+		return err
+	}
+	o.conn = conn
+	o.log.Infof("Exporting OTLP requests over gRPC to: %v\n", o.endpoint)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *otlpOutput) exportGRPC(ctx context.Context, signal *otlpSignal, req proto.Message) error {
+	o.connMut.Lock()
+	conn := o.conn
+	o.connMut.Unlock()
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	ctx, done := context.WithTimeout(ctx, o.timeout)
+	defer done()
+	if len(o.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.headers))
+	}
+	return conn.Invoke(ctx, "/"+signal.service+"/Export", req, signal.newResponse())
+}
+
+func (o *otlpOutput) exportHTTP(ctx context.Context, signal *otlpSignal, req proto.Message) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.endpoint+signal.httpPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range o.headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+
+	res, err := o.http.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("request failed with status %v", res.StatusCode)
+	}
+	return nil
+}
+
+func (o *otlpOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to export records"))
+
+	type signalRequest struct {
+		signal  *otlpSignal
+		req     proto.Message
+		indexes []int
+	}
+	var requests []*signalRequest
+	requestsBySignal := map[string]*signalRequest{}
+
+	for i, msg := range batch {
+		signal, err := otlpSignalByName(batch.InterpolatedString(i, o.signal))
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+
+		b, err := msg.AsBytes()
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+		res := signal.newResource()
+		if err := unmarshalOTLPJSON(b, res); err != nil {
+			batchErr.Failed(i, fmt.Errorf("failed to parse %v resource: %w", signal.name, err))
+			continue
+		}
+
+		r, exists := requestsBySignal[signal.name]
+		if !exists {
+			r = &signalRequest{signal: signal, req: signal.newRequest()}
+			requestsBySignal[signal.name] = r
+			requests = append(requests, r)
+		}
+		signal.appendResource(r.req, res)
+		r.indexes = append(r.indexes, i)
+	}
+
+	for _, r := range requests {
+		var err error
+		if o.protocol == "grpc" {
+			err = o.exportGRPC(ctx, r.signal, r.req)
+		} else {
+			err = o.exportHTTP(ctx, r.signal, r.req)
+		}
+		if err != nil {
+			if len(r.indexes) == len(batch) {
+				return err
+			}
+			for _, i := range r.indexes {
+				batchErr.Failed(i, err)
+			}
+		}
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *otlpOutput) Close(ctx context.Context) error {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
@@ -0,0 +1,157 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchDocs(t *testing.T, batch service.MessageBatch) []string {
+	t.Helper()
+
+	var docs []string
+	for _, msg := range batch {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(b))
+	}
+	return docs
+}
+
+func TestOTLPOutput(t *testing.T) {
+	for _, protocol := range []string{"grpc", "http"} {
+		protocol := protocol
+		t.Run(protocol, func(t *testing.T) {
+			inConf, err := serverInputConfig().ParseYAML(`
+grpc_address: 127.0.0.1:0
+http_address: 127.0.0.1:0
+`, nil)
+			require.NoError(t, err)
+
+			s, err := newServerInputFromConfig(inConf, nil)
+			require.NoError(t, err)
+			require.NoError(t, s.Connect(context.Background()))
+			defer s.Close(context.Background())
+			endpoint := s.grpcAddr.String()
+			if protocol == "http" {
+				endpoint = "http://" + s.httpAddr.String()
+			}
+
+			conf, err := otlpOutputConfig().ParseYAML(`
+endpoint: `+endpoint+`
+protocol: `+protocol+`
+`, nil)
+			require.NoError(t, err)
+
+			out, err := newOTLPOutputFromConfig(conf, nil)
+			require.NoError(t, err)
+			require.NoError(t, out.Connect(context.Background()))
+			defer out.Close(context.Background())
+
+			logsSignal, err := otlpSignalByName("logs")
+			require.NoError(t, err)
+			logs, err := requestToBatch(logsSignal, testLogsRequest())
+			require.NoError(t, err)
+
+			traceSignal, err := otlpSignalByName("traces")
+			require.NoError(t, err)
+			traces, err := requestToBatch(traceSignal, testTraceRequest())
+			require.NoError(t, err)
+
+			batch := service.MessageBatch{logs[0], traces[0], logs[1]}
+			expected := batchDocs(t, batch)
+
+			errChan := make(chan error, 1)
+			go func() {
+				errChan <- out.WriteBatch(context.Background(), batch)
+			}()
+
+			logsRes, ackFn := readServerBatch(t, s)
+			assert.Equal(t, []string{expected[0], expected[2]}, batchDocs(t, logsRes))
+			require.NoError(t, ackFn(context.Background(), nil))
+
+			tracesRes, ackFn := readServerBatch(t, s)
+			assert.Equal(t, []string{expected[1]}, batchDocs(t, tracesRes))
+			require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+
+			err = <-errChan
+			var batchErr *service.BatchError
+			require.True(t, errors.As(err, &batchErr), "%T: %v", err, err)
+
+			var failed []int
+			batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+				if err != nil {
+					failed = append(failed, i)
+				}
+				return true
+			})
+			assert.Equal(t, []int{1}, failed)
+		})
+	}
+}
+
+func TestOTLPOutputBadMessages(t *testing.T) {
+	inConf, err := serverInputConfig().ParseYAML(`
+grpc_address: 127.0.0.1:0
+http_address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(inConf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+
+	conf, err := otlpOutputConfig().ParseYAML(`
+endpoint: `+s.grpcAddr.String()+`
+signal: ${! meta("signal") }
+timeout: 5s
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOTLPOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	defer out.Close(context.Background())
+
+	good := service.NewMessage([]byte(`{"instrumentationLibraryLogs":[{"logs":[{"severityText":"INFO"}]}]}`))
+	good.MetaSet("signal", "logs")
+	unknown := service.NewMessage([]byte(`{}`))
+	unknown.MetaSet("signal", "profiles")
+	invalid := service.NewMessage([]byte(`{"instrumentationLibraryLogs":"nope"}`))
+	invalid.MetaSet("signal", "logs")
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- out.WriteBatch(context.Background(), service.MessageBatch{good, unknown, invalid})
+	}()
+
+	batch, ackFn := readServerBatch(t, s)
+	require.Len(t, batch, 1)
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	select {
+	case err = <-errChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T: %v", err, err)
+
+	failed := map[int]string{}
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	require.Len(t, failed, 2)
+	assert.Equal(t, "unrecognised signal: profiles", failed[1])
+	assert.Contains(t, failed[2], "failed to parse logs resource")
+}
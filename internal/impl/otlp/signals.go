@@ -0,0 +1,255 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpSignal describes how the export requests of a signal are split into
+// messages and merged back from them.
+type otlpSignal struct {
+	name     string
+	service  string
+	httpPath string
+
+	newRequest  func() proto.Message
+	newResponse func() proto.Message
+
+	// split returns each record of a request as its own resource.
+	split func(req proto.Message) []proto.Message
+
+	// newResource returns an empty resource of the signal, and appendResource
+	// adds a resource to a request.
+	newResource    func() proto.Message
+	appendResource func(req, res proto.Message)
+}
+
+var otlpSignals = []*otlpSignal{
+	{
+		name:        "logs",
+		service:     "opentelemetry.proto.collector.logs.v1.LogsService",
+		httpPath:    "/v1/logs",
+		newRequest:  func() proto.Message { return &collogs.ExportLogsServiceRequest{} },
+		newResponse: func() proto.Message { return &collogs.ExportLogsServiceResponse{} },
+		split: func(req proto.Message) (res []proto.Message) {
+			for _, rl := range req.(*collogs.ExportLogsServiceRequest).ResourceLogs {
+				for _, ill := range rl.InstrumentationLibraryLogs {
+					for _, l := range ill.Logs {
+						res = append(res, &logspb.ResourceLogs{
+							Resource:  rl.Resource,
+							SchemaUrl: rl.SchemaUrl,
+							InstrumentationLibraryLogs: []*logspb.InstrumentationLibraryLogs{{
+								InstrumentationLibrary: ill.InstrumentationLibrary,
+								SchemaUrl:              ill.SchemaUrl,
+								Logs:                   []*logspb.LogRecord{l},
+							}},
+						})
+					}
+				}
+			}
+			return
+		},
+		newResource: func() proto.Message { return &logspb.ResourceLogs{} },
+		appendResource: func(req, res proto.Message) {
+			r := req.(*collogs.ExportLogsServiceRequest)
+			r.ResourceLogs = append(r.ResourceLogs, res.(*logspb.ResourceLogs))
+		},
+	},
+	{
+		name:        "metrics",
+		service:     "opentelemetry.proto.collector.metrics.v1.MetricsService",
+		httpPath:    "/v1/metrics",
+		newRequest:  func() proto.Message { return &colmetrics.ExportMetricsServiceRequest{} },
+		newResponse: func() proto.Message { return &colmetrics.ExportMetricsServiceResponse{} },
+		split: func(req proto.Message) (res []proto.Message) {
+			for _, rm := range req.(*colmetrics.ExportMetricsServiceRequest).ResourceMetrics {
+				for _, ilm := range rm.InstrumentationLibraryMetrics {
+					for _, m := range ilm.Metrics {
+						res = append(res, &metricspb.ResourceMetrics{
+							Resource:  rm.Resource,
+							SchemaUrl: rm.SchemaUrl,
+							InstrumentationLibraryMetrics: []*metricspb.InstrumentationLibraryMetrics{{
+								InstrumentationLibrary: ilm.InstrumentationLibrary,
+								SchemaUrl:              ilm.SchemaUrl,
+								Metrics:                []*metricspb.Metric{m},
+							}},
+						})
+					}
+				}
+			}
+			return
+		},
+		newResource: func() proto.Message { return &metricspb.ResourceMetrics{} },
+		appendResource: func(req, res proto.Message) {
+			r := req.(*colmetrics.ExportMetricsServiceRequest)
+			r.ResourceMetrics = append(r.ResourceMetrics, res.(*metricspb.ResourceMetrics))
+		},
+	},
+	{
+		name:        "traces",
+		service:     "opentelemetry.proto.collector.trace.v1.TraceService",
+		httpPath:    "/v1/traces",
+		newRequest:  func() proto.Message { return &coltrace.ExportTraceServiceRequest{} },
+		newResponse: func() proto.Message { return &coltrace.ExportTraceServiceResponse{} },
+		split: func(req proto.Message) (res []proto.Message) {
+			for _, rs := range req.(*coltrace.ExportTraceServiceRequest).ResourceSpans {
+				for _, ils := range rs.InstrumentationLibrarySpans {
+					for _, s := range ils.Spans {
+						res = append(res, &tracepb.ResourceSpans{
+							Resource:  rs.Resource,
+							SchemaUrl: rs.SchemaUrl,
+							InstrumentationLibrarySpans: []*tracepb.InstrumentationLibrarySpans{{
+								InstrumentationLibrary: ils.InstrumentationLibrary,
+								SchemaUrl:              ils.SchemaUrl,
+								Spans:                  []*tracepb.Span{s},
+							}},
+						})
+					}
+				}
+			}
+			return
+		},
+		newResource: func() proto.Message { return &tracepb.ResourceSpans{} },
+		appendResource: func(req, res proto.Message) {
+			r := req.(*coltrace.ExportTraceServiceRequest)
+			r.ResourceSpans = append(r.ResourceSpans, res.(*tracepb.ResourceSpans))
+		},
+	},
+}
+
+func otlpSignalByName(name string) (*otlpSignal, error) {
+	for _, s := range otlpSignals {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognised signal: %v", name)
+}
+
+//------------------------------------------------------------------------------
+
+// The OTLP JSON encoding differs from the canonical JSON mapping of protobuf in
+// that trace and span IDs are hex encoded rather than base64.
+var otlpIDFields = map[string]struct{}{
+	"traceId":      {},
+	"spanId":       {},
+	"parentSpanId": {},
+}
+
+func convertIDs(v interface{}, fn func(string) (string, error)) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if _, isID := otlpIDFields[k]; isID {
+				if s, ok := e.(string); ok {
+					converted, err := fn(s)
+					if err != nil {
+						return fmt.Errorf("failed to decode %v: %w", k, err)
+					}
+					t[k] = converted
+					continue
+				}
+			}
+			if err := convertIDs(e, fn); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, e := range t {
+			if err := convertIDs(e, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func base64ToHex(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hexToBase64(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// marshalOTLPJSON returns the OTLP JSON encoding of a message as a structured
+// value.
+func marshalOTLPJSON(m proto.Message) (interface{}, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := convertIDs(v, base64ToHex); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// unmarshalOTLPJSON parses the OTLP JSON encoding of a message.
+func unmarshalOTLPJSON(b []byte, m proto.Message) error {
+	v, err := decodeJSON(b)
+	if err != nil {
+		return err
+	}
+	if err := convertIDs(v, hexToBase64); err != nil {
+		return err
+	}
+	if b, err = json.Marshal(v); err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, m)
+}
+
+// requestToBatch converts an export request into a message for each of its
+// records.
+func requestToBatch(signal *otlpSignal, req proto.Message) (service.MessageBatch, error) {
+	resources := signal.split(req)
+	batch := make(service.MessageBatch, 0, len(resources))
+	for _, r := range resources {
+		v, err := marshalOTLPJSON(r)
+		if err != nil {
+			return nil, err
+		}
+		msg := service.NewMessage(nil)
+		msg.SetStructured(v)
+		msg.MetaSet("otlp_signal", signal.name)
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
@@ -0,0 +1,120 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var testTraceID = []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+
+func testStringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func testResource() *resourcepb.Resource {
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: testStringValue("checkout")},
+		},
+	}
+}
+
+func testLogsRequest() *collogs.ExportLogsServiceRequest {
+	return &collogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: testResource(),
+			InstrumentationLibraryLogs: []*logspb.InstrumentationLibraryLogs{{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{Name: "checkout.logger"},
+				Logs: []*logspb.LogRecord{
+					{
+						TimeUnixNano: 1650000000000000000,
+						SeverityText: "INFO",
+						Body:         testStringValue("order placed"),
+						TraceId:      testTraceID,
+					},
+					{
+						TimeUnixNano: 1650000001000000000,
+						SeverityText: "DEBUG",
+						Body:         testStringValue("cart loaded"),
+					},
+				},
+			}},
+		}},
+	}
+}
+
+func testTraceRequest() *coltrace.ExportTraceServiceRequest {
+	return &coltrace.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: testResource(),
+			InstrumentationLibrarySpans: []*tracepb.InstrumentationLibrarySpans{{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{Name: "checkout.http"},
+				Spans: []*tracepb.Span{{
+					TraceId:           testTraceID,
+					SpanId:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+					Name:              "POST /orders",
+					StartTimeUnixNano: 1650000000000000000,
+					EndTimeUnixNano:   1650000002000000000,
+				}},
+			}},
+		}},
+	}
+}
+
+func TestRequestToBatch(t *testing.T) {
+	signal, err := otlpSignalByName("logs")
+	require.NoError(t, err)
+
+	batch, err := requestToBatch(signal, testLogsRequest())
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	var docs []string
+	for _, msg := range batch {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(b))
+
+		v, _ := msg.MetaGet("otlp_signal")
+		assert.Equal(t, "logs", v)
+	}
+	assert.Equal(t, []string{
+		`{"instrumentationLibraryLogs":[{"instrumentationLibrary":{"name":"checkout.logger"},"logs":[{"body":{"stringValue":"order placed"},"severityText":"INFO","timeUnixNano":"1650000000000000000","traceId":"5b8efff798038103d269b633813fc60c"}]}],"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]}}`,
+		`{"instrumentationLibraryLogs":[{"instrumentationLibrary":{"name":"checkout.logger"},"logs":[{"body":{"stringValue":"cart loaded"},"severityText":"DEBUG","timeUnixNano":"1650000001000000000"}]}],"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]}}`,
+	}, docs)
+
+	// Merging the messages back must result in the records of the original
+	// request, split into a resource each.
+	req := signal.newRequest()
+	for _, doc := range docs {
+		res := signal.newResource()
+		require.NoError(t, unmarshalOTLPJSON([]byte(doc), res))
+		signal.appendResource(req, res)
+	}
+
+	expected := testLogsRequest()
+	records := expected.ResourceLogs[0].InstrumentationLibraryLogs[0].Logs
+	merged := req.(*collogs.ExportLogsServiceRequest)
+	require.Len(t, merged.ResourceLogs, 2)
+	for i, rl := range merged.ResourceLogs {
+		assert.True(t, proto.Equal(expected.ResourceLogs[0].Resource, rl.Resource))
+		require.Len(t, rl.InstrumentationLibraryLogs, 1)
+		require.Len(t, rl.InstrumentationLibraryLogs[0].Logs, 1)
+		assert.True(t, proto.Equal(records[i], rl.InstrumentationLibraryLogs[0].Logs[0]), "%v", rl)
+	}
+}
+
+func TestUnmarshalOTLPJSONBadID(t *testing.T) {
+	err := unmarshalOTLPJSON([]byte(`{"instrumentationLibrarySpans":[{"spans":[{"traceId":"nope"}]}]}`), &tracepb.ResourceSpans{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode traceId")
+}
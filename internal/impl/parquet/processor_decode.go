@@ -0,0 +1,223 @@
+package parquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func parquetDecodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Parsing").
+		Summary("Decodes [Parquet files](https://parquet.apache.org/documentation/latest/) into a batch of structured messages.").
+		Description(`
+Each message is expected to contain an entire Parquet file, which is expanded into a message for each row of the file containing the row as a JSON object. The schema of the file is read from the file itself, and therefore does not need to be specified.
+
+Groups of nested columns are decoded as objects, lists and repeated columns are decoded as arrays and maps are decoded as objects. Values of `+"`BYTE_ARRAY`"+` columns are decoded as strings regardless of whether they are annotated as UTF8, and the metadata of the consumed message is copied to each message of the resulting batch.`).
+		Example(
+			"Reading Parquet Files from AWS S3",
+			"In this example we consume files from AWS S3 as they're written by listening onto an SQS queue for upload events. We make sure to use the `all-bytes` codec which means files are read into memory in full, which then allows us to use a `parquet_decode` processor to expand each file into a batch of messages. Finally, we write the data out to local files as newline delimited JSON.",
+			`
+input:
+  aws_s3:
+    bucket: TODO
+    prefix: foos/
+    codec: all-bytes
+    sqs:
+      url: TODO
+  processors:
+    - parquet_decode: {}
+
+output:
+  file:
+    codec: lines
+    path: './foos/${! meta("s3_key") }.jsonl'
+`).
+		Version("3.64.0")
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"parquet_decode", parquetDecodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newParquetDecodeProcessorFromConfig(conf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newParquetDecodeProcessorFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*parquetDecodeProcessor, error) {
+	return &parquetDecodeProcessor{logger: logger}, nil
+}
+
+type parquetDecodeProcessor struct {
+	logger *service.Logger
+}
+
+const parquetDecodeReadSize = 1000
+
+func (p *parquetDecodeProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message contents: %w", err)
+	}
+
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(mBytes), nil, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet reader: %w", err)
+	}
+	defer pr.ReadStop()
+
+	root, err := parquetSchemaTree(pr.Footer.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	numRows := int(pr.GetNumRows())
+	outBatch := make(service.MessageBatch, 0, numRows)
+	for len(outBatch) < numRows {
+		size := numRows - len(outBatch)
+		if size > parquetDecodeReadSize {
+			size = parquetDecodeReadSize
+		}
+		rows, err := pr.ReadByNumber(size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parquet rows: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			outMsg := msg.Copy()
+			outMsg.SetStructured(root.decode(reflect.ValueOf(row)))
+			outBatch = append(outBatch, outMsg)
+		}
+	}
+	return outBatch, nil
+}
+
+func (p *parquetDecodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// parquetSchemaTreeNode is an element of the schema of a parquet file with
+// its children, which is used to recover the original names of columns since
+// rows are read as structs with exported field names.
+type parquetSchemaTreeNode struct {
+	element  *parquet.SchemaElement
+	children []*parquetSchemaTreeNode
+}
+
+func parquetSchemaTree(elements []*parquet.SchemaElement) (*parquetSchemaTreeNode, error) {
+	if len(elements) == 0 {
+		return nil, errors.New("file has an empty schema")
+	}
+	root, next := parquetSchemaTreeFrom(elements, 0)
+	if next != len(elements) {
+		return nil, errors.New("file has a malformed schema")
+	}
+	return root, nil
+}
+
+func parquetSchemaTreeFrom(elements []*parquet.SchemaElement, i int) (*parquetSchemaTreeNode, int) {
+	node := &parquetSchemaTreeNode{element: elements[i]}
+	i++
+	for c := int32(0); c < node.element.GetNumChildren() && i < len(elements); c++ {
+		var child *parquetSchemaTreeNode
+		child, i = parquetSchemaTreeFrom(elements, i)
+		node.children = append(node.children, child)
+	}
+	return node, i
+}
+
+// listElement returns the node of the elements of a list, following the
+// backward compatibility rules of the parquet format for the structure of
+// lists.
+func (n *parquetSchemaTreeNode) listElement() *parquetSchemaTreeNode {
+	if n.element.GetConvertedType() != parquet.ConvertedType_LIST || len(n.children) != 1 {
+		return n
+	}
+	repeated := n.children[0]
+	if len(repeated.children) != 1 || repeated.element.GetName() == "array" || repeated.element.GetName() == n.element.GetName()+"_tuple" {
+		return repeated
+	}
+	return repeated.children[0]
+}
+
+func (n *parquetSchemaTreeNode) decode(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		obj := make(map[string]interface{}, len(n.children))
+		for i, c := range n.children {
+			if i >= v.NumField() {
+				break
+			}
+			obj[c.element.GetName()] = c.decode(v.Field(i))
+		}
+		return obj
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		element := n.listElement()
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = element.decode(v.Index(i))
+		}
+		return arr
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		value := n
+		if len(n.children) == 1 && len(n.children[0].children) == 2 {
+			value = n.children[0].children[1]
+		}
+		obj := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			obj[fmt.Sprintf("%v", iter.Key().Interface())] = value.decode(iter.Value())
+		}
+		return obj
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32:
+		// Formatting with the precision of a float32 avoids artifacts of the
+		// conversion to a float64, e.g. 60.1 becoming 60.099998474121094.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		return f
+	case reflect.Float64:
+		return v.Float()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,185 @@
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+func parquetEncodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Parsing").
+		Summary("Encodes a batch of documents as a [Parquet file](https://parquet.apache.org/documentation/latest/), which replaces the batch as a single message.").
+		Description(`
+Each message of a batch must be a JSON object, which is written as a row of the file. Since each batch is encoded as a single file this processor is typically used within the `+"`batching`"+` config of an output, where the size of files can be controlled with the batching policy.
+
+### Schema
+
+The columns of the file are described with the field `+"`schema`"+`, where columns that are a group of nested columns are specified with `+"`fields`"+` and no `+"`type`"+`. Columns that are `+"`repeated`"+` are written as lists, and columns of type `+"`MAP`"+` are written as maps with string keys.
+
+When a schema is not specified it is inferred from the documents of the first batch that is processed, and the same schema is then used for all subsequent batches. All inferred columns are optional, numbers are inferred as `+"`INT64`"+` unless a value with a fractional part is found, in which case they are inferred as `+"`DOUBLE`"+`, and fields that only have null values or empty arrays within the first batch are omitted. Fields of documents that are not present within the schema are ignored.`).
+		Field(parquetSchemaField()).
+		Field(service.NewStringEnumField("compression", "uncompressed", "snappy", "gzip", "lz4", "zstd").
+			Description("The type of compression to use when writing parquet files.").
+			Default("snappy")).
+		Example(
+			"Writing Parquet Files to S3",
+			"In this example we batch documents into Parquet files of 1000 rows, which are uploaded to S3.",
+			`
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'stuff/${! timestamp_unix() }-${! uuid_v4() }.parquet'
+    batching:
+      count: 1000
+      period: 10s
+      processors:
+        - parquet_encode:
+            schema:
+              - name: id
+                type: INT64
+              - name: weight
+                type: DOUBLE
+                optional: true
+              - name: tags
+                type: UTF8
+                repeated: true
+              - name: owner
+                optional: true
+                fields:
+                  - name: name
+                    type: UTF8
+`).
+		Version("3.64.0")
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"parquet_encode", parquetEncodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newParquetEncodeProcessorFromConfig(conf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newParquetEncodeProcessorFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*parquetEncodeProcessor, error) {
+	p := &parquetEncodeProcessor{logger: logger}
+
+	if conf.Contains("schema") {
+		schemaConfs, err := conf.FieldObjectList("schema")
+		if err != nil {
+			return nil, err
+		}
+		columns, err := parquetColumnsFromConfig(schemaConfs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema: %w", err)
+		}
+		if len(columns) > 0 {
+			if p.schema, err = parquetSchemaJSON(columns); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cCodec, err := conf.FieldString("compression")
+	if err != nil {
+		return nil, err
+	}
+	if p.cCodec, err = getCompressionType(cCodec); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type parquetEncodeProcessor struct {
+	logger *service.Logger
+	cCodec parquet.CompressionCodec
+
+	schemaMut sync.Mutex
+	schema    string
+}
+
+// getSchema returns the schema of the processor, inferring it from the batch
+// when it has not yet been established.
+func (p *parquetEncodeProcessor) getSchema(batch service.MessageBatch) (string, error) {
+	p.schemaMut.Lock()
+	defer p.schemaMut.Unlock()
+
+	if p.schema != "" {
+		return p.schema, nil
+	}
+
+	docs := make([]interface{}, len(batch))
+	for i, m := range batch {
+		v, err := m.AsStructured()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse message %v as structured: %w", i, err)
+		}
+		docs[i] = v
+	}
+
+	columns, err := parquetInferColumns(docs)
+	if err != nil {
+		return "", fmt.Errorf("failed to infer schema: %w", err)
+	}
+	schema, err := parquetSchemaJSON(columns)
+	if err != nil {
+		return "", err
+	}
+
+	p.logger.Debugf("Inferred parquet schema: %v", schema)
+	p.schema = schema
+	return schema, nil
+}
+
+func (p *parquetEncodeProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	schema, err := p.getSchema(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := buffer.NewBufferFile()
+
+	pw, err := writer.NewJSONWriter(schema, buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = p.cCodec
+
+	for _, m := range batch {
+		b, err := m.AsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message contents: %w", err)
+		}
+		if err = pw.Write(string(b)); err != nil {
+			return nil, fmt.Errorf("failed to write document to parquet file: %w", err)
+		}
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+
+	outMsg := batch[0].Copy()
+	outMsg.SetBytes(buf.Bytes())
+	return []service.MessageBatch{{outMsg}}, nil
+}
+
+func (p *parquetEncodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetEncodeDecodeRoundTrip(t *testing.T) {
+	inputDocs := []string{
+		`{"id":1,"name":"foo","weight":60.1,"tags":["a","b"],"owner":{"name":"x","age":20},"attrs":{"k":1.5}}`,
+		`{"id":2,"weight":60.2,"owner":{"name":"y"}}`,
+		`{"id":3,"name":"bar","attrs":{"k":2,"j":null}}`,
+	}
+
+	tests := map[string]struct {
+		config string
+		output []string
+	}{
+		"explicit schema": {
+			config: `
+schema:
+  - name: id
+    type: INT64
+  - name: name
+    type: UTF8
+    optional: true
+  - name: weight
+    type: FLOAT
+    optional: true
+  - name: tags
+    type: UTF8
+    repeated: true
+    optional: true
+  - name: owner
+    optional: true
+    fields:
+      - name: name
+        type: UTF8
+      - name: age
+        type: INT32
+        optional: true
+  - name: attrs
+    type: MAP
+    optional: true
+    fields:
+      - name: key
+        type: UTF8
+      - name: value
+        type: DOUBLE
+        optional: true
+`,
+			output: []string{
+				`{"id":1,"name":"foo","weight":60.1,"tags":["a","b"],"owner":{"name":"x","age":20},"attrs":{"k":1.5}}`,
+				`{"id":2,"name":null,"weight":60.2,"tags":null,"owner":{"name":"y","age":null},"attrs":null}`,
+				`{"id":3,"name":"bar","weight":null,"tags":null,"owner":null,"attrs":{"k":2,"j":null}}`,
+			},
+		},
+		"inferred schema": {
+			config: `{}`,
+			output: []string{
+				`{"id":1,"name":"foo","weight":60.1,"tags":["a","b"],"owner":{"name":"x","age":20},"attrs":{"k":1.5}}`,
+				`{"id":2,"name":null,"weight":60.2,"tags":null,"owner":{"name":"y","age":null},"attrs":null}`,
+				`{"id":3,"name":"bar","weight":null,"tags":null,"owner":null,"attrs":{"k":2}}`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			// Test every compression codec
+			for _, c := range []string{"uncompressed", "snappy", "gzip", "lz4", "zstd"} {
+				conf, err := parquetEncodeProcessorConfig().ParseYAML(test.config, nil)
+				require.NoError(t, err)
+
+				encoder, err := newParquetEncodeProcessorFromConfig(conf, nil)
+				require.NoError(t, err)
+				encoder.cCodec, err = getCompressionType(c)
+				require.NoError(t, err)
+
+				decoder, err := newParquetDecodeProcessorFromConfig(nil, nil)
+				require.NoError(t, err)
+
+				var inputBatch service.MessageBatch
+				for _, d := range inputDocs {
+					msg := service.NewMessage([]byte(d))
+					msg.MetaSet("foo", "bar")
+					inputBatch = append(inputBatch, msg)
+				}
+
+				encoded, err := encoder.ProcessBatch(context.Background(), inputBatch)
+				require.NoError(t, err, c)
+				require.Len(t, encoded, 1, c)
+				require.Len(t, encoded[0], 1, c)
+
+				decoded, err := decoder.Process(context.Background(), encoded[0][0])
+				require.NoError(t, err, c)
+				require.Len(t, decoded, len(test.output), c)
+
+				for i, m := range decoded {
+					mBytes, err := m.AsBytes()
+					require.NoError(t, err)
+					assert.JSONEq(t, test.output[i], string(mBytes), fmt.Sprintf("%v: %v", c, i))
+
+					v, exists := m.MetaGet("foo")
+					assert.True(t, exists)
+					assert.Equal(t, "bar", v)
+				}
+			}
+		})
+	}
+}
+
+func TestParquetDecodeInvalidFile(t *testing.T) {
+	decoder, err := newParquetDecodeProcessorFromConfig(nil, nil)
+	require.NoError(t, err)
+
+	_, err = decoder.Process(context.Background(), service.NewMessage([]byte(`not a parquet file`)))
+	require.Error(t, err)
+}
@@ -0,0 +1,337 @@
+package parquet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/parquetschema"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+const (
+	parquetTypeBoolean   = "BOOLEAN"
+	parquetTypeInt32     = "INT32"
+	parquetTypeInt64     = "INT64"
+	parquetTypeFloat     = "FLOAT"
+	parquetTypeDouble    = "DOUBLE"
+	parquetTypeByteArray = "BYTE_ARRAY"
+	parquetTypeUTF8      = "UTF8"
+	parquetTypeMap       = "MAP"
+)
+
+var parquetColumnTypes = []string{
+	parquetTypeBoolean,
+	parquetTypeInt32,
+	parquetTypeInt64,
+	parquetTypeFloat,
+	parquetTypeDouble,
+	parquetTypeByteArray,
+	parquetTypeUTF8,
+	parquetTypeMap,
+}
+
+// parquetColumn describes a column of a parquet schema, which is a group of
+// nested columns when it has fields and no type.
+type parquetColumn struct {
+	name     string
+	typ      string
+	optional bool
+	repeated bool
+	fields   []*parquetColumn
+
+	// Set when the element of a repeated column can be null.
+	optionalElement bool
+}
+
+func parquetSchemaField() *service.ConfigField {
+	return service.NewObjectListField("schema",
+		service.NewStringField("name").
+			Description("The name of the column."),
+		service.NewStringEnumField("type", parquetColumnTypes...).
+			Description("The type of the column, which must be omitted for columns that are a group of nested `fields`. Columns of type `MAP` must have exactly two `fields`, named `key` and `value`, describing the keys and values of the map.").
+			Optional(),
+		service.NewBoolField("optional").
+			Description("Whether the column is optional, in which case documents can omit it or set it to `null`.").
+			Default(false),
+		service.NewBoolField("repeated").
+			Description("Whether the column is a list of values of its type, in which case `optional` applies to the list and its elements are required.").
+			Default(false),
+		service.NewObjectListField("fields").
+			Description("A list of nested columns, each having the same fields as a column of the schema.").
+			Optional(),
+	).
+		Description("A list of columns describing the parquet schema. When omitted the schema is inferred from the documents of the first batch.").
+		Optional()
+}
+
+// parquetColumnsFromConfig parses a list of columns from a config. Since
+// nested columns are not described by the config spec, defaults are resolved
+// here for all levels.
+func parquetColumnsFromConfig(confs []*service.ParsedConfig) ([]*parquetColumn, error) {
+	columns := make([]*parquetColumn, 0, len(confs))
+	for i, conf := range confs {
+		c := &parquetColumn{}
+
+		var err error
+		if c.name, err = conf.FieldString("name"); err != nil {
+			return nil, fmt.Errorf("column %v: %w", i, err)
+		}
+		if c.name == "" {
+			return nil, fmt.Errorf("column %v: a name must be specified", i)
+		}
+		if conf.Contains("type") {
+			if c.typ, err = conf.FieldString("type"); err != nil {
+				return nil, fmt.Errorf("column %v: %w", c.name, err)
+			}
+		}
+		if conf.Contains("optional") {
+			if c.optional, err = conf.FieldBool("optional"); err != nil {
+				return nil, fmt.Errorf("column %v: %w", c.name, err)
+			}
+		}
+		if conf.Contains("repeated") {
+			if c.repeated, err = conf.FieldBool("repeated"); err != nil {
+				return nil, fmt.Errorf("column %v: %w", c.name, err)
+			}
+		}
+		if conf.Contains("fields") {
+			fieldConfs, err := conf.FieldObjectList("fields")
+			if err != nil {
+				return nil, fmt.Errorf("column %v: %w", c.name, err)
+			}
+			if c.fields, err = parquetColumnsFromConfig(fieldConfs); err != nil {
+				return nil, fmt.Errorf("column %v: %w", c.name, err)
+			}
+		}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("column %v: %w", c.name, err)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+func (c *parquetColumn) validate() error {
+	switch c.typ {
+	case "":
+		if len(c.fields) == 0 {
+			return errors.New("either a type or nested fields must be specified")
+		}
+	case parquetTypeMap:
+		if len(c.fields) != 2 || c.fields[0].name != "key" || c.fields[1].name != "value" {
+			return errors.New("map columns must have exactly two fields named key and value")
+		}
+		if c.fields[0].typ != parquetTypeUTF8 || c.fields[0].optional || c.fields[0].repeated {
+			return errors.New("map keys must be non-optional UTF8 columns")
+		}
+	default:
+		for _, t := range parquetColumnTypes {
+			if t == c.typ {
+				if len(c.fields) > 0 {
+					return fmt.Errorf("columns of type %v cannot have nested fields", c.typ)
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("unrecognised type: %v", c.typ)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// parquetSchemaJSON returns the schema of a list of columns in the JSON format
+// of the parquet writer.
+func parquetSchemaJSON(columns []*parquetColumn) (string, error) {
+	root := parquetschema.NewRoot()
+	for _, c := range columns {
+		root.Fields = append(root.Fields, c.schemaNode())
+	}
+	return root.JSON()
+}
+
+func (c *parquetColumn) schemaNode() *parquetschema.Node {
+	tag := parquetschema.ColumnTag(c.name, c.optional)
+	if c.repeated {
+		element := *c
+		element.name, element.optional, element.repeated = "element", c.optionalElement, false
+		return parquetschema.List(tag, element.schemaNode())
+	}
+
+	switch c.typ {
+	case "":
+		node := &parquetschema.Node{Tag: tag}
+		for _, f := range c.fields {
+			node.Fields = append(node.Fields, f.schemaNode())
+		}
+		return node
+	case parquetTypeMap:
+		return parquetschema.Map(tag, c.fields[0].schemaNode(), c.fields[1].schemaNode())
+	case parquetTypeUTF8:
+		return &parquetschema.Node{Tag: tag + ", type=BYTE_ARRAY, convertedtype=UTF8"}
+	}
+	return &parquetschema.Node{Tag: tag + ", type=" + c.typ}
+}
+
+//------------------------------------------------------------------------------
+
+// parquetInferColumns infers a schema from a list of documents, where all
+// columns are optional since documents are not required to contain every
+// field. Fields that only have null values or empty arrays cannot be inferred
+// and are omitted.
+func parquetInferColumns(docs []interface{}) ([]*parquetColumn, error) {
+	root := &parquetColumn{}
+	for i, doc := range docs {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %v: expected object, got %T", i, doc)
+		}
+		if err := root.inferObject(obj); err != nil {
+			return nil, fmt.Errorf("document %v: %w", i, err)
+		}
+	}
+	columns := root.prune()
+	if len(columns) == 0 {
+		return nil, errors.New("no columns could be inferred from the documents")
+	}
+	return columns, nil
+}
+
+func (c *parquetColumn) inferObject(obj map[string]interface{}) error {
+	if c.typ != "" {
+		return fmt.Errorf("expected %v value, got object", c.typ)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if strings.ContainsAny(k, ",=") {
+			return fmt.Errorf("field name '%v' contains characters that are not supported", k)
+		}
+
+		var field *parquetColumn
+		for _, f := range c.fields {
+			if f.name == k {
+				field = f
+				break
+			}
+		}
+		if field == nil {
+			field = &parquetColumn{name: k, optional: true}
+			c.fields = append(c.fields, field)
+		}
+		if err := field.inferValue(obj[k], false); err != nil {
+			return fmt.Errorf("field %v: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// inferValue merges the type of a value into the column, where element is set
+// when the value is an element of a repeated column.
+func (c *parquetColumn) inferValue(v interface{}, element bool) error {
+	if v == nil {
+		if element {
+			c.optionalElement = true
+		}
+		return nil
+	}
+
+	if arr, ok := v.([]interface{}); ok {
+		if element {
+			return errors.New("nested arrays are not supported")
+		}
+		if c.typ != "" || len(c.fields) > 0 {
+			if !c.repeated {
+				return fmt.Errorf("expected %v value, got array", c.describe())
+			}
+		}
+		c.repeated = true
+		for _, e := range arr {
+			if err := c.inferValue(e, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if c.repeated && !element {
+		return fmt.Errorf("expected array value, got %T", v)
+	}
+
+	var typ string
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		return c.inferObject(tv)
+	case bool:
+		typ = parquetTypeBoolean
+	case string:
+		typ = parquetTypeUTF8
+	case []byte:
+		typ = parquetTypeByteArray
+	case int, int32, int64, uint, uint32, uint64:
+		typ = parquetTypeInt64
+	case float32:
+		typ = parquetTypeDouble
+		if float64(tv) == math.Trunc(float64(tv)) {
+			typ = parquetTypeInt64
+		}
+	case float64:
+		typ = parquetTypeDouble
+		if tv == math.Trunc(tv) {
+			typ = parquetTypeInt64
+		}
+	case json.Number:
+		typ = parquetTypeDouble
+		if _, err := tv.Int64(); err == nil {
+			typ = parquetTypeInt64
+		}
+	default:
+		return fmt.Errorf("values of type %T are not supported", v)
+	}
+
+	if len(c.fields) > 0 {
+		return fmt.Errorf("expected object value, got %T", v)
+	}
+	switch {
+	case c.typ == "" || c.typ == typ:
+		c.typ = typ
+	case c.typ == parquetTypeInt64 && typ == parquetTypeDouble:
+		c.typ = parquetTypeDouble
+	case c.typ == parquetTypeDouble && typ == parquetTypeInt64:
+	case c.typ == parquetTypeByteArray && typ == parquetTypeUTF8:
+	case c.typ == parquetTypeUTF8 && typ == parquetTypeByteArray:
+		c.typ = parquetTypeByteArray
+	default:
+		return fmt.Errorf("expected %v value, got %T", c.typ, v)
+	}
+	return nil
+}
+
+func (c *parquetColumn) describe() string {
+	if c.typ == "" {
+		return "object"
+	}
+	return c.typ
+}
+
+// prune removes the nested columns of an inferred group that have no type.
+func (c *parquetColumn) prune() []*parquetColumn {
+	var fields []*parquetColumn
+	for _, f := range c.fields {
+		if f.typ == "" {
+			if f.fields = f.prune(); len(f.fields) == 0 {
+				continue
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
@@ -0,0 +1,182 @@
+package parquet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetSchemaFromConfig(t *testing.T) {
+	tests := map[string]struct {
+		config      string
+		schema      string
+		errContains string
+	}{
+		"nested columns": {
+			config: `
+schema:
+  - name: id
+    type: INT64
+  - name: tags
+    type: UTF8
+    repeated: true
+    optional: true
+  - name: owner
+    optional: true
+    fields:
+      - name: name
+        type: UTF8
+      - name: age
+        type: INT32
+        optional: true
+  - name: attrs
+    type: MAP
+    fields:
+      - name: key
+        type: UTF8
+      - name: value
+        type: DOUBLE
+        optional: true
+`,
+			schema: `{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=id, repetitiontype=REQUIRED, type=INT64"},
+    {"Tag": "name=tags, repetitiontype=OPTIONAL, type=LIST", "Fields": [
+      {"Tag": "name=element, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"}
+    ]},
+    {"Tag": "name=owner, repetitiontype=OPTIONAL", "Fields": [
+      {"Tag": "name=name, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+      {"Tag": "name=age, repetitiontype=OPTIONAL, type=INT32"}
+    ]},
+    {"Tag": "name=attrs, repetitiontype=REQUIRED, type=MAP", "Fields": [
+      {"Tag": "name=key, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"},
+      {"Tag": "name=value, repetitiontype=OPTIONAL, type=DOUBLE"}
+    ]}
+  ]
+}`,
+		},
+		"missing type": {
+			config: `
+schema:
+  - name: id
+`,
+			errContains: "either a type or nested fields must be specified",
+		},
+		"nested missing name": {
+			config: `
+schema:
+  - name: owner
+    fields:
+      - type: UTF8
+`,
+			errContains: "column owner: column 0",
+		},
+		"bad map": {
+			config: `
+schema:
+  - name: attrs
+    type: MAP
+    fields:
+      - name: value
+        type: DOUBLE
+`,
+			errContains: "map columns must have exactly two fields named key and value",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := parquetEncodeProcessorConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			proc, err := newParquetEncodeProcessorFromConfig(conf, nil)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, test.schema, proc.schema)
+		})
+	}
+}
+
+func TestParquetInferColumns(t *testing.T) {
+	tests := map[string]struct {
+		docs        []string
+		schema      string
+		errContains string
+	}{
+		"merged documents": {
+			docs: []string{
+				`{"id":1,"name":"foo","tags":["a"],"owner":{"age":20},"empty":null,"none":[]}`,
+				`{"id":2,"score":1,"tags":["b",null],"owner":{"name":"bar","meta":{}}}`,
+				`{"id":3,"score":1.5,"ok":true}`,
+			},
+			schema: `{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=id, repetitiontype=OPTIONAL, type=INT64"},
+    {"Tag": "name=name, repetitiontype=OPTIONAL, type=BYTE_ARRAY, convertedtype=UTF8"},
+    {"Tag": "name=owner, repetitiontype=OPTIONAL", "Fields": [
+      {"Tag": "name=age, repetitiontype=OPTIONAL, type=INT64"},
+      {"Tag": "name=name, repetitiontype=OPTIONAL, type=BYTE_ARRAY, convertedtype=UTF8"}
+    ]},
+    {"Tag": "name=tags, repetitiontype=OPTIONAL, type=LIST", "Fields": [
+      {"Tag": "name=element, repetitiontype=OPTIONAL, type=BYTE_ARRAY, convertedtype=UTF8"}
+    ]},
+    {"Tag": "name=score, repetitiontype=OPTIONAL, type=DOUBLE"},
+    {"Tag": "name=ok, repetitiontype=OPTIONAL, type=BOOLEAN"}
+  ]
+}`,
+		},
+		"conflicting types": {
+			docs:        []string{`{"id":1}`, `{"id":"foo"}`},
+			errContains: "document 1: field id: expected INT64 value, got string",
+		},
+		"array and scalar": {
+			docs:        []string{`{"tags":["a"]}`, `{"tags":"b"}`},
+			errContains: "expected array value",
+		},
+		"nested arrays": {
+			docs:        []string{`{"tags":[["a"]]}`},
+			errContains: "nested arrays are not supported",
+		},
+		"not an object": {
+			docs:        []string{`[1]`},
+			errContains: "expected object",
+		},
+		"nothing to infer": {
+			docs:        []string{`{"a":null}`},
+			errContains: "no columns could be inferred",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var docs []interface{}
+			for _, d := range test.docs {
+				var v interface{}
+				require.NoError(t, json.Unmarshal([]byte(d), &v))
+				docs = append(docs, v)
+			}
+
+			columns, err := parquetInferColumns(docs)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+
+			schema, err := parquetSchemaJSON(columns)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.schema, schema)
+		})
+	}
+}
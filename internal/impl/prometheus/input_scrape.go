@@ -0,0 +1,509 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func scrapeInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Periodically scrapes metrics from endpoints that serve the Prometheus [text exposition format](https://prometheus.io/docs/instrumenting/exposition_formats/).").
+		Description(`
+The targets to scrape are the URLs of the field `+"`urls`"+` and the hosts resolved from the names of the field `+"`dns_sd`"+`, which are resolved again every `+"`dns_sd.refresh_interval`"+`. Each target is scraped once every `+"`interval`"+`, where the samples of each scrape are emitted as a batch with a message for each sample. Scrapes that fail are logged and skipped until the next interval.
+
+Each message is a JSON document of the form:
+
+`+"```json"+`
+{
+  "name": "http_requests_total",
+  "type": "counter",
+  "labels": { "code": "200", "method": "get" },
+  "value": 1027,
+  "timestamp_ms": 1650000000000
+}
+`+"```"+`
+
+Where `+"`timestamp_ms`"+` is the timestamp of the sample when one is exposed, and otherwise the time of the scrape. Values that are not finite numbers are strings of the form `+"`NaN`"+`, `+"`+Inf`"+` or `+"`-Inf`"+`. Histograms and summaries are expanded into a message for each of their series in the same way as Prometheus, with `+"`_bucket`"+`, `+"`_sum`"+` and `+"`_count`"+` suffixes on the name and `+"`le`"+` or `+"`quantile`"+` labels.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- prometheus_scrape_url
+- prometheus_scrape_instance
+`+"```"+`
+
+Where the instance is the host and port of the target, which is the value Prometheus would give to the label `+"`instance`"+`. You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringListField("urls").
+			Description("A list of URLs to scrape.").
+			Example([]string{"http://localhost:9100/metrics"}).
+			Default([]string{})).
+		Field(service.NewObjectField("dns_sd",
+			service.NewStringListField("names").
+				Description("A list of DNS names to resolve into targets.").
+				Example([]string{"node-exporter.monitoring.svc.cluster.local"}).
+				Default([]string{}),
+			service.NewStringAnnotatedEnumField("type", map[string]string{
+				"A":    "Names are resolved to IPv4 addresses, which are scraped on the port of the field `port`.",
+				"AAAA": "Names are resolved to IPv6 addresses, which are scraped on the port of the field `port`.",
+				"SRV":  "Names are resolved to the hosts and ports of SRV records.",
+			}).
+				Description("The type of DNS records to resolve.").
+				Default("A"),
+			service.NewIntField("port").
+				Description("The port to scrape targets on when resolving A or AAAA records.").
+				Default(9090),
+			service.NewStringAnnotatedEnumField("scheme", map[string]string{
+				"http":  "Targets are scraped over HTTP.",
+				"https": "Targets are scraped over HTTPS.",
+			}).
+				Description("The scheme of the URLs of resolved targets.").
+				Default("http"),
+			service.NewStringField("path").
+				Description("The path of the URLs of resolved targets.").
+				Default("/metrics"),
+			service.NewDurationField("refresh_interval").
+				Description("The period to wait between resolving names.").
+				Default("30s"),
+		).Description("Allows you to discover targets to scrape by resolving DNS names.")).
+		Field(service.NewDurationField("interval").
+			Description("The period to wait between scrapes of each target.").
+			Default("15s")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to wait for each scrape to complete.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewObjectField("basic_auth",
+			service.NewBoolField("enabled").
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField("username").
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default(""),
+		).Description("Allows you to specify basic authentication.").Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Example("Metrics Relay", `
+Scrape node exporters discovered through DNS and write their metrics to a remote write endpoint, adding the instance and job labels that Prometheus would add:`,
+			`
+input:
+  prometheus_scrape:
+    dns_sd:
+      names: [ node-exporter.monitoring.svc.cluster.local ]
+      port: 9100
+    interval: 30s
+
+pipeline:
+  processors:
+    - bloblang: |
+        root = this
+        root.labels.instance = meta("prometheus_scrape_instance")
+        root.labels.job = "node"
+
+output:
+  prometheus_remote_write:
+    url: http://mimir:8080/api/v1/push
+    name: ${! json("name") }
+    value: ${! json("value") }
+    timestamp: ${! json("timestamp_ms") }
+    labels_mapping: root = this.labels
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"prometheus_scrape", scrapeInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newScrapeInputFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// scrapeResolver resolves DNS names into targets, which is satisfied by
+// net.Resolver.
+type scrapeResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+type scrapeInput struct {
+	http     *http.Client
+	resolver scrapeResolver
+	username string
+	password string
+	log      *service.Logger
+
+	urls            []string
+	dnsNames        []string
+	dnsType         string
+	dnsPort         int
+	dnsScheme       string
+	dnsPath         string
+	refreshInterval time.Duration
+	interval        time.Duration
+
+	mut         sync.Mutex
+	resolved    []string
+	nextResolve time.Time
+	nextScrape  time.Time
+	pending     []string
+
+	shutSig *shutdown.Signaller
+}
+
+func newScrapeInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*scrapeInput, error) {
+	s := &scrapeInput{
+		resolver: net.DefaultResolver,
+		log:      log,
+		shutSig:  shutdown.NewSignaller(),
+	}
+
+	var err error
+	if s.urls, err = conf.FieldStringList("urls"); err != nil {
+		return nil, err
+	}
+	for _, u := range s.urls {
+		if _, err := url.Parse(u); err != nil {
+			return nil, fmt.Errorf("failed to parse url '%v': %w", u, err)
+		}
+	}
+	if s.dnsNames, err = conf.FieldStringList("dns_sd", "names"); err != nil {
+		return nil, err
+	}
+	if len(s.urls) == 0 && len(s.dnsNames) == 0 {
+		return nil, errors.New("at least one url or dns_sd name must be specified")
+	}
+	if s.dnsType, err = conf.FieldString("dns_sd", "type"); err != nil {
+		return nil, err
+	}
+	switch s.dnsType {
+	case "A", "AAAA", "SRV":
+	default:
+		return nil, fmt.Errorf("unrecognised dns_sd type: %v", s.dnsType)
+	}
+	if s.dnsPort, err = conf.FieldInt("dns_sd", "port"); err != nil {
+		return nil, err
+	}
+	if s.dnsScheme, err = conf.FieldString("dns_sd", "scheme"); err != nil {
+		return nil, err
+	}
+	if s.dnsPath, err = conf.FieldString("dns_sd", "path"); err != nil {
+		return nil, err
+	}
+	if s.refreshInterval, err = conf.FieldDuration("dns_sd", "refresh_interval"); err != nil {
+		return nil, err
+	}
+	if s.interval, err = conf.FieldDuration("interval"); err != nil {
+		return nil, err
+	}
+	if s.interval <= 0 {
+		return nil, errors.New("interval must be greater than zero")
+	}
+
+	authEnabled, err := conf.FieldBool("basic_auth", "enabled")
+	if err != nil {
+		return nil, err
+	}
+	if authEnabled {
+		if s.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if s.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	s.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		s.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return s, nil
+}
+
+func (s *scrapeInput) Connect(ctx context.Context) error {
+	s.log.Infof("Scraping Prometheus metrics every %v\n", s.interval)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// resolve returns the URLs of targets discovered through DNS.
+func (s *scrapeInput) resolve(ctx context.Context) ([]string, error) {
+	var hosts []string
+	for _, name := range s.dnsNames {
+		if s.dnsType == "SRV" {
+			_, records, err := s.resolver.LookupSRV(ctx, "", "", name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve SRV records of %v: %w", name, err)
+			}
+			for _, r := range records {
+				hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+			}
+			continue
+		}
+
+		addrs, err := s.resolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %v records of %v: %w", s.dnsType, name, err)
+		}
+		for _, addr := range addrs {
+			if isV4 := addr.IP.To4() != nil; isV4 != (s.dnsType == "A") {
+				continue
+			}
+			hosts = append(hosts, net.JoinHostPort(addr.IP.String(), strconv.Itoa(s.dnsPort)))
+		}
+	}
+
+	urls := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		urls = append(urls, (&url.URL{Scheme: s.dnsScheme, Host: h, Path: s.dnsPath}).String())
+	}
+	return urls, nil
+}
+
+// targets returns the URLs of all targets, resolving DNS names again when the
+// refresh interval has passed. When resolving fails the targets of the last
+// successful resolve are used.
+func (s *scrapeInput) targets(ctx context.Context) []string {
+	if len(s.dnsNames) > 0 && !time.Now().Before(s.nextResolve) {
+		resolved, err := s.resolve(ctx)
+		if err != nil {
+			s.log.Errorf("Failed to discover targets: %v\n", err)
+		} else {
+			s.resolved = resolved
+			s.nextResolve = time.Now().Add(s.refreshInterval)
+		}
+	}
+
+	targets := make([]string, 0, len(s.urls)+len(s.resolved))
+	targets = append(targets, s.urls...)
+	return append(targets, s.resolved...)
+}
+
+func (s *scrapeInput) scrape(ctx context.Context, target string) (service.MessageBatch, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	scrapeTime := time.Now()
+	res, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("request failed with status %v", res.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	instance := req.URL.Host
+	batch := samplesFromFamilies(families, scrapeTime)
+	for _, msg := range batch {
+		msg.MetaSet("prometheus_scrape_url", target)
+		msg.MetaSet("prometheus_scrape_instance", instance)
+	}
+	return batch, nil
+}
+
+func (s *scrapeInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		target, err := s.nextTarget(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		batch, err := s.scrape(ctx, target)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			s.log.Errorf("Failed to scrape %v: %v\n", target, err)
+			continue
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		return batch, func(context.Context, error) error {
+			return nil
+		}, nil
+	}
+}
+
+// nextTarget returns the next target to scrape, waiting for the next interval
+// once all targets of the current interval have been scraped. The lock is not
+// held while waiting so that the input can be closed in the meantime.
+func (s *scrapeInput) nextTarget(ctx context.Context) (string, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for len(s.pending) == 0 {
+		wait := time.Until(s.nextScrape)
+
+		s.mut.Unlock()
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			s.mut.Lock()
+			return "", ctx.Err()
+		case <-s.shutSig.CloseAtLeisureChan():
+			s.mut.Lock()
+			return "", service.ErrEndOfInput
+		}
+		s.mut.Lock()
+
+		if len(s.pending) > 0 || time.Now().Before(s.nextScrape) {
+			continue
+		}
+		s.nextScrape = time.Now().Add(s.interval)
+		if s.pending = s.targets(ctx); len(s.pending) == 0 {
+			s.log.Warn("No targets to scrape")
+		}
+	}
+
+	target := s.pending[0]
+	s.pending = s.pending[1:]
+	return target, nil
+}
+
+func (s *scrapeInput) Close(ctx context.Context) error {
+	s.shutSig.CloseAtLeisure()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func formatPromFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// promSampleValue returns a value that can be encoded as JSON, where values
+// that are not finite are formatted as strings.
+func promSampleValue(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return formatPromFloat(v)
+	}
+	return v
+}
+
+// samplesFromFamilies converts metric families into a message for each
+// sample, with families ordered by name.
+func samplesFromFamilies(families map[string]*dto.MetricFamily, scrapeTime time.Time) service.MessageBatch {
+	names := make([]string, 0, len(families))
+	for k := range families {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var batch service.MessageBatch
+	for _, name := range names {
+		family := families[name]
+		typeStr := strings.ToLower(family.GetType().String())
+
+		for _, m := range family.Metric {
+			ts := scrapeTime.UnixNano() / int64(time.Millisecond)
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+
+			add := func(name string, value float64, extraName, extraValue string) {
+				labels := make(map[string]interface{}, len(m.Label)+1)
+				for _, l := range m.Label {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if extraName != "" {
+					labels[extraName] = extraValue
+				}
+
+				msg := service.NewMessage(nil)
+				msg.SetStructured(map[string]interface{}{
+					"name":         name,
+					"type":         typeStr,
+					"labels":       labels,
+					"value":        promSampleValue(value),
+					"timestamp_ms": ts,
+				})
+				batch = append(batch, msg)
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue(), "", "")
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue(), "", "")
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					add(name, q.GetValue(), "quantile", formatPromFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", s.GetSampleSum(), "", "")
+				add(name+"_count", float64(s.GetSampleCount()), "", "")
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				hasInf := false
+				for _, b := range h.Bucket {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						hasInf = true
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatPromFloat(b.GetUpperBound()))
+				}
+				if !hasInf {
+					add(name+"_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				}
+				add(name+"_sum", h.GetSampleSum(), "", "")
+				add(name+"_count", float64(h.GetSampleCount()), "", "")
+			default:
+				add(name, m.GetUntyped().GetValue(), "", "")
+			}
+		}
+	}
+	return batch
+}
@@ -0,0 +1,209 @@
+package prometheus
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExposition = `# HELP http_requests_total The total number of requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="get"} 1027 1650000000000
+http_requests_total{code="500",method="get"} 3 1650000000000
+# TYPE temperature gauge
+temperature NaN 1650000000000
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.5"} 4 1650000000000
+latency_seconds_bucket{le="1"} 6 1650000000000
+latency_seconds_bucket{le="+Inf"} 7 1650000000000
+latency_seconds_sum 5.5 1650000000000
+latency_seconds_count 7 1650000000000
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.99"} 0.25 1650000000000
+rpc_seconds_sum 12 1650000000000
+rpc_seconds_count 40 1650000000000
+`
+
+func testScrapeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(testExposition))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeInput(t *testing.T) {
+	server := testScrapeServer(t)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	conf, err := scrapeInputConfig().ParseYAML(`
+urls:
+  - `+server.URL+`/nope
+  - `+server.URL+`/metrics
+interval: 1h
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newScrapeInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+
+	batch, ackFn, err := in.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	var docs []string
+	for _, msg := range batch {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(b))
+
+		v, _ := msg.MetaGet("prometheus_scrape_url")
+		assert.Equal(t, server.URL+"/metrics", v)
+		v, _ = msg.MetaGet("prometheus_scrape_instance")
+		assert.Equal(t, serverURL.Host, v)
+	}
+	assert.Equal(t, []string{
+		`{"labels":{"code":"200","method":"get"},"name":"http_requests_total","timestamp_ms":1650000000000,"type":"counter","value":1027}`,
+		`{"labels":{"code":"500","method":"get"},"name":"http_requests_total","timestamp_ms":1650000000000,"type":"counter","value":3}`,
+		`{"labels":{"le":"0.5"},"name":"latency_seconds_bucket","timestamp_ms":1650000000000,"type":"histogram","value":4}`,
+		`{"labels":{"le":"1"},"name":"latency_seconds_bucket","timestamp_ms":1650000000000,"type":"histogram","value":6}`,
+		`{"labels":{"le":"+Inf"},"name":"latency_seconds_bucket","timestamp_ms":1650000000000,"type":"histogram","value":7}`,
+		`{"labels":{},"name":"latency_seconds_sum","timestamp_ms":1650000000000,"type":"histogram","value":5.5}`,
+		`{"labels":{},"name":"latency_seconds_count","timestamp_ms":1650000000000,"type":"histogram","value":7}`,
+		`{"labels":{"quantile":"0.99"},"name":"rpc_seconds","timestamp_ms":1650000000000,"type":"summary","value":0.25}`,
+		`{"labels":{},"name":"rpc_seconds_sum","timestamp_ms":1650000000000,"type":"summary","value":12}`,
+		`{"labels":{},"name":"rpc_seconds_count","timestamp_ms":1650000000000,"type":"summary","value":40}`,
+		`{"labels":{},"name":"temperature","timestamp_ms":1650000000000,"type":"gauge","value":"NaN"}`,
+	}, docs)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	_, _, err = in.ReadBatch(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+type testScrapeResolver struct {
+	addrs []net.IPAddr
+	srvs  []*net.SRV
+}
+
+func (r *testScrapeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r.addrs, nil
+}
+
+func (r *testScrapeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return name, r.srvs, nil
+}
+
+func TestScrapeInputDNS(t *testing.T) {
+	server := testScrapeServer(t)
+	_, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	conf, err := scrapeInputConfig().ParseYAML(`
+dns_sd:
+  names: [ exporters.local ]
+  port: `+portStr+`
+interval: 1h
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newScrapeInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	in.resolver = &testScrapeResolver{
+		addrs: []net.IPAddr{
+			{IP: net.ParseIP("::1")},
+			{IP: net.ParseIP("127.0.0.1")},
+		},
+	}
+
+	batch, _, err := in.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 11)
+
+	v, _ := batch[0].MetaGet("prometheus_scrape_url")
+	assert.Equal(t, "http://127.0.0.1:"+portStr+"/metrics", v)
+}
+
+func TestScrapeInputSRV(t *testing.T) {
+	conf, err := scrapeInputConfig().ParseYAML(`
+dns_sd:
+  names: [ _metrics._tcp.exporters.local ]
+  type: SRV
+  scheme: https
+  path: /stats
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newScrapeInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	in.resolver = &testScrapeResolver{
+		srvs: []*net.SRV{
+			{Target: "a.exporters.local.", Port: 9100},
+			{Target: "b.exporters.local.", Port: 9200},
+		},
+	}
+
+	urls, err := in.resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://a.exporters.local:9100/stats",
+		"https://b.exporters.local:9200/stats",
+	}, urls)
+}
+
+func TestScrapeInputBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   string
+		errStr string
+	}{
+		"no targets": {
+			conf:   `interval: 1s`,
+			errStr: "at least one url or dns_sd name must be specified",
+		},
+		"no interval": {
+			conf: `
+urls: [ http://localhost:9100/metrics ]
+interval: 0s
+`,
+			errStr: "interval must be greater than zero",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			parsed, err := scrapeInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newScrapeInputFromConfig(parsed, nil)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestPromSampleValue(t *testing.T) {
+	for _, v := range []string{"NaN", "+Inf", "-Inf"} {
+		f, err := strconv.ParseFloat(v, 64)
+		require.NoError(t, err)
+		assert.Equal(t, v, promSampleValue(f))
+	}
+	assert.Equal(t, 1.5, promSampleValue(1.5))
+}
@@ -0,0 +1,430 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/golang/snappy"
+)
+
+var (
+	promMetricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+func remoteWriteOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Sends message batches as samples to a Prometheus [remote write](https://prometheus.io/docs/concepts/remote_write_spec/) endpoint.").
+		Description(output.Description(true, true, `
+Each message becomes a sample of the series identified by its metric name and labels, where the name, value and time of the sample are determined by the fields `+"`name`"+`, `+"`value`"+` and `+"`timestamp`"+`. Messages where the name or a label is not valid, or where the value or timestamp is not a number, are rejected.
+
+Batches are sent as a single request encoded as snappy compressed protobuf, with the samples of each series sorted by their timestamps. This output is compatible with any system that accepts remote write requests, including Prometheus, Cortex, Mimir, Thanos and VictoriaMetrics.
+
+### Labels
+
+Labels are added to each sample with the field `+"`labels_mapping`"+`, which is a [Bloblang mapping](/docs/guides/bloblang/about) that is executed for each message and must result in an object, where each key and value becomes a label. Labels with an empty or null value are omitted, and values that are not strings are encoded as JSON.
+
+### Errors
+
+Requests that are rate limited (status 429) or fail with a server error are retried according to the field `+"`backoff`"+`. Requests that are rejected for any other reason, such as samples that are out of order, are not retried by this output.`)).
+		Field(service.NewStringField("url").
+			Description("The URL of the remote write endpoint.").
+			Example("http://localhost:9090/api/v1/write").
+			Example("http://mimir:8080/api/v1/push")).
+		Field(service.NewInterpolatedStringField("name").
+			Description("The metric name of each sample.").
+			Example(`benthos_orders_${! json("status") }`).
+			Example(`${! json("name") }`)).
+		Field(service.NewInterpolatedStringField("value").
+			Description("The value of each sample, which must resolve to a number.").
+			Example(`${! json("value") }`).
+			Default("${! content() }")).
+		Field(service.NewInterpolatedStringField("timestamp").
+			Description("An optional time of each sample as a unix timestamp in milliseconds, which must resolve to an integer. When omitted the time at which samples are written is used.").
+			Example(`${! json("timestamp_ms") }`).
+			Optional()).
+		Field(service.NewBloblangField("labels_mapping").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that determines the labels of each sample, which must result in an object where each key and value becomes a label.").
+			Example(`root = this.labels`).
+			Example(`root.job = "benthos"
+root.topic = meta("kafka_topic")`).
+			Optional()).
+		Field(service.NewStringMapField("headers").
+			Description("A map of headers to add to all requests.").
+			Default(map[string]string{}).
+			Example(map[string]string{
+				"X-Scope-OrgID": "tenant-1",
+			}).
+			Advanced()).
+		Field(service.NewObjectField("basic_auth",
+			service.NewBoolField("enabled").
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField("username").
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default(""),
+		).Description("Allows you to specify basic authentication.").Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to wait for each request to complete.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewBackOffField("backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 30,
+			MaxInterval:     time.Second * 5,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying requests that were rate limited (status 429) or failed with a server error.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increasing this improves throughput, but samples of the same series sent in parallel may arrive out of order and be rejected.").
+			Default(1)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Metrics Relay", `
+Consume metrics as JSON documents of the form `+"`"+`{"name":"http_requests_total","labels":{"code":"200"},"value":1027,"timestamp_ms":1650000000000}`+"`"+` from Kafka, add a label identifying the source cluster, and write them to Prometheus:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ metrics ]
+    consumer_group: benthos_prometheus
+
+output:
+  prometheus_remote_write:
+    url: http://prometheus:9090/api/v1/write
+    name: ${! json("name") }
+    value: ${! json("value") }
+    timestamp: ${! json("timestamp_ms") }
+    labels_mapping: |
+      root = this.labels
+      root.cluster = "eu-west-1"
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"prometheus_remote_write", remoteWriteOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newRemoteWriteOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type remoteWriteOutput struct {
+	url      string
+	http     *http.Client
+	backoff  *backoff.ExponentialBackOff
+	headers  map[string]string
+	username string
+	password string
+	log      *service.Logger
+
+	name          *service.InterpolatedString
+	value         *service.InterpolatedString
+	timestamp     *service.InterpolatedString
+	labelsMapping *bloblang.Executor
+}
+
+func newRemoteWriteOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*remoteWriteOutput, error) {
+	r := &remoteWriteOutput{log: log}
+
+	var err error
+	if r.url, err = conf.FieldString("url"); err != nil {
+		return nil, err
+	}
+	if r.url == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	if r.name, err = conf.FieldInterpolatedString("name"); err != nil {
+		return nil, err
+	}
+	if r.value, err = conf.FieldInterpolatedString("value"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("timestamp") {
+		if r.timestamp, err = conf.FieldInterpolatedString("timestamp"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("labels_mapping") {
+		if r.labelsMapping, err = conf.FieldBloblang("labels_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	if r.headers, err = conf.FieldStringMap("headers"); err != nil {
+		return nil, err
+	}
+
+	authEnabled, err := conf.FieldBool("basic_auth", "enabled")
+	if err != nil {
+		return nil, err
+	}
+	if authEnabled {
+		if r.username, err = conf.FieldString("basic_auth", "username"); err != nil {
+			return nil, err
+		}
+		if r.password, err = conf.FieldString("basic_auth", "password"); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	r.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		r.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	if r.backoff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *remoteWriteOutput) Connect(ctx context.Context) error {
+	r.log.Infof("Sending samples to Prometheus remote write endpoint: %v\n", r.url)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// labels returns the labels of a message sorted by name, including the metric
+// name as the label __name__.
+func (r *remoteWriteOutput) labels(batch service.MessageBatch, i int) ([]promLabel, error) {
+	name := batch.InterpolatedString(i, r.name)
+	if !promMetricNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("metric name '%v' is invalid", name)
+	}
+	labels := []promLabel{{name: "__name__", value: name}}
+	if r.labelsMapping == nil {
+		return labels, nil
+	}
+
+	res, err := batch.BloblangQuery(i, r.labelsMapping)
+	if err != nil {
+		return nil, fmt.Errorf("labels mapping failed: %w", err)
+	}
+	if res == nil {
+		return labels, nil
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("labels mapping failed: %w", err)
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("labels mapping must result in an object, got %T", v)
+	}
+
+	for k, e := range obj {
+		if !promLabelNameRegexp.MatchString(k) || strings.HasPrefix(k, "__") {
+			return nil, fmt.Errorf("label name '%v' is invalid", k)
+		}
+		var value string
+		switch ev := e.(type) {
+		case string:
+			value = ev
+		case nil:
+		default:
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		}
+		if value != "" {
+			labels = append(labels, promLabel{name: k, value: value})
+		}
+	}
+	sortPromLabels(labels)
+	return labels, nil
+}
+
+// seriesFromBatch groups the samples of a batch into series by their labels,
+// in the order that each series first appears within the batch, with the
+// samples of each series sorted by their timestamps.
+func (r *remoteWriteOutput) seriesFromBatch(batch service.MessageBatch, batchErr *service.BatchError) []*promSeries {
+	var series []*promSeries
+	seriesByKey := map[string]*promSeries{}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for i := range batch {
+		labels, err := r.labels(batch, i)
+		if err != nil {
+			batchErr.Failed(i, err)
+			continue
+		}
+
+		valueStr := batch.InterpolatedString(i, r.value)
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+		if err != nil {
+			batchErr.Failed(i, fmt.Errorf("failed to parse value '%v' as a number", valueStr))
+			continue
+		}
+
+		ts := now
+		if r.timestamp != nil {
+			tsStr := batch.InterpolatedString(i, r.timestamp)
+			if ts, err = strconv.ParseInt(strings.TrimSpace(tsStr), 10, 64); err != nil {
+				batchErr.Failed(i, fmt.Errorf("failed to parse timestamp '%v' as an integer", tsStr))
+				continue
+			}
+		}
+
+		key := promSeriesKey(labels)
+		s, exists := seriesByKey[key]
+		if !exists {
+			s = &promSeries{labels: labels}
+			seriesByKey[key] = s
+			series = append(series, s)
+		}
+		s.samples = append(s.samples, promSample{value: value, ts: ts})
+	}
+
+	for _, s := range series {
+		samples := s.samples
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].ts < samples[j].ts
+		})
+	}
+	return series
+}
+
+// remoteWriteRequestError is returned when a request is rejected.
+type remoteWriteRequestError struct {
+	status int
+	body   string
+}
+
+func (e *remoteWriteRequestError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("request failed with status %v", e.status)
+	}
+	return fmt.Sprintf("request failed with status %v: %v", e.status, e.body)
+}
+
+func (r *remoteWriteOutput) write(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	res, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, _ := io.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &remoteWriteRequestError{
+			status: res.StatusCode,
+			body:   string(bytes.TrimSpace(resBody)),
+		}
+	}
+	return nil
+}
+
+func (r *remoteWriteOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	batchErr := service.NewBatchError(batch, errors.New("failed to write samples"))
+
+	if series := r.seriesFromBatch(batch, batchErr); len(series) > 0 {
+		if err := r.send(ctx, snappy.Encode(nil, encodeWriteRequest(series))); err != nil {
+			return err
+		}
+	}
+
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// send writes a request body, retrying requests that were rate limited or
+// failed with a server error.
+func (r *remoteWriteOutput) send(ctx context.Context, body []byte) error {
+	boff := *r.backoff
+	boff.Reset()
+
+	for {
+		err := r.write(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var reqErr *remoteWriteRequestError
+		if errors.As(err, &reqErr) && reqErr.status != http.StatusTooManyRequests && reqErr.status < 500 {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		r.log.Warnf("Retrying failed request: %v\n", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *remoteWriteOutput) Close(ctx context.Context) error {
+	return nil
+}
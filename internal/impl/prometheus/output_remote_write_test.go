@@ -0,0 +1,290 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// consumeFields walks the fields of an encoded protobuf message, calling fn
+// with the number of each field and either its content or its value.
+func consumeFields(t *testing.T, b []byte, fn func(num protowire.Number, content []byte, v uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		var v uint64
+		var content []byte
+		switch typ {
+		case protowire.BytesType:
+			content, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		default:
+			t.Fatalf("unexpected wire type: %v", typ)
+		}
+		require.GreaterOrEqual(t, n, 0)
+		fn(num, content, v)
+		b = b[n:]
+	}
+}
+
+func decodeWriteRequest(t *testing.T, b []byte) []promSeries {
+	t.Helper()
+
+	var series []promSeries
+	consumeFields(t, b, func(_ protowire.Number, sb []byte, _ uint64) {
+		var s promSeries
+		consumeFields(t, sb, func(num protowire.Number, content []byte, _ uint64) {
+			if num == 1 {
+				var l promLabel
+				consumeFields(t, content, func(num protowire.Number, content []byte, _ uint64) {
+					if num == 1 {
+						l.name = string(content)
+					} else {
+						l.value = string(content)
+					}
+				})
+				s.labels = append(s.labels, l)
+				return
+			}
+			var p promSample
+			consumeFields(t, content, func(num protowire.Number, _ []byte, v uint64) {
+				if num == 1 {
+					p.value = math.Float64frombits(v)
+				} else {
+					p.ts = int64(v)
+				}
+			})
+			s.samples = append(s.samples, p)
+		})
+		series = append(series, s)
+	})
+	return series
+}
+
+// readWriteRequest reads and decodes the series of a snappy compressed write
+// request.
+func readWriteRequest(t *testing.T, r *http.Request) []promSeries {
+	t.Helper()
+
+	assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	decoded, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+	return decodeWriteRequest(t, decoded)
+}
+
+func TestRemoteWriteOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs [][]promSeries
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		series := readWriteRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, series)
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf, err := remoteWriteOutputConfig().ParseYAML(`
+url: `+ts.URL+`/api/v1/write
+name: ${! json("name") }
+value: ${! json("value") }
+timestamp: ${! json("ts") }
+labels_mapping: |
+  root = this.labels.or({})
+  root.job = "benthos"
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	var batch service.MessageBatch
+	for _, doc := range []string{
+		`{"name":"http_requests_total","labels":{"code":"200"},"value":10,"ts":1650000001000}`,
+		`{"name":"http_requests_total","labels":{"code":"500"},"value":2,"ts":1650000000000}`,
+		`{"name":"http_requests_total","labels":{"code":"200"},"value":8,"ts":1650000000000}`,
+		`{"name":"up","labels":{"port":8080,"zone":""},"value":"1","ts":1650000000000}`,
+		`{"name":"bad-name","value":1,"ts":1650000000000}`,
+		`{"name":"up","labels":{"__meta":"foo"},"value":1,"ts":1650000000000}`,
+		`{"name":"up","value":"nope","ts":1650000000000}`,
+		`{"name":"up","value":1,"ts":"later"}`,
+	} {
+		batch = append(batch, service.NewMessage([]byte(doc)))
+	}
+
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	reqMut.Lock()
+	assert.Equal(t, [][]promSeries{
+		{
+			{
+				labels: []promLabel{
+					{name: "__name__", value: "http_requests_total"},
+					{name: "code", value: "200"},
+					{name: "job", value: "benthos"},
+				},
+				samples: []promSample{
+					{value: 8, ts: 1650000000000},
+					{value: 10, ts: 1650000001000},
+				},
+			},
+			{
+				labels: []promLabel{
+					{name: "__name__", value: "http_requests_total"},
+					{name: "code", value: "500"},
+					{name: "job", value: "benthos"},
+				},
+				samples: []promSample{
+					{value: 2, ts: 1650000000000},
+				},
+			},
+			{
+				labels: []promLabel{
+					{name: "__name__", value: "up"},
+					{name: "job", value: "benthos"},
+					{name: "port", value: "8080"},
+				},
+				samples: []promSample{
+					{value: 1, ts: 1650000000000},
+				},
+			},
+		},
+	}, reqs)
+	reqMut.Unlock()
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	failed := map[int]string{}
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	assert.Equal(t, map[int]string{
+		4: "metric name 'bad-name' is invalid",
+		5: "label name '__meta' is invalid",
+		6: "failed to parse value 'nope' as a number",
+		7: "failed to parse timestamp 'later' as an integer",
+	}, failed)
+}
+
+func TestRemoteWriteOutputHeaders(t *testing.T) {
+	var reqMut sync.Mutex
+	var tenant, user, pass string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		tenant = r.Header.Get("X-Scope-OrgID")
+		user, pass, _ = r.BasicAuth()
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	conf, err := remoteWriteOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+name: up
+headers:
+  X-Scope-OrgID: foo
+basic_auth:
+  enabled: true
+  username: bar
+  password: baz
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`1`)),
+	}))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	assert.Equal(t, "foo", tenant)
+	assert.Equal(t, "bar", user)
+	assert.Equal(t, "baz", pass)
+}
+
+func TestRemoteWriteOutputRetries(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddUint32(&reqCount, 1) {
+		case 1:
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	conf, err := remoteWriteOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+name: up
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`1`)),
+	}))
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&reqCount))
+}
+
+func TestRemoteWriteOutputRejected(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	conf, err := remoteWriteOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+name: up
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`1`)),
+	})
+	require.EqualError(t, err, "request failed with status 400: out of order sample")
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
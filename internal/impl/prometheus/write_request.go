@@ -0,0 +1,77 @@
+package prometheus
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type promLabel struct {
+	name  string
+	value string
+}
+
+type promSample struct {
+	value float64
+	ts    int64
+}
+
+// promSeries is the set of samples of a batch that share the same labels.
+type promSeries struct {
+	labels  []promLabel
+	samples []promSample
+}
+
+// promSeriesKey returns a key that uniquely identifies a set of labels, which
+// must already be sorted by name.
+func promSeriesKey(labels []promLabel) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0xff)
+		b.WriteString(l.value)
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+func sortPromLabels(labels []promLabel) {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+}
+
+// encodeWriteRequest encodes series as the protobuf WriteRequest message of the
+// remote write protocol, where each series is a TimeSeries message with Label
+// and Sample messages.
+func encodeWriteRequest(series []*promSeries) []byte {
+	var b []byte
+	for _, s := range series {
+		var sb []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+
+			sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, lb)
+		}
+		for _, p := range s.samples {
+			var pb []byte
+			pb = protowire.AppendTag(pb, 1, protowire.Fixed64Type)
+			pb = protowire.AppendFixed64(pb, math.Float64bits(p.value))
+			pb = protowire.AppendTag(pb, 2, protowire.VarintType)
+			pb = protowire.AppendVarint(pb, uint64(p.ts))
+
+			sb = protowire.AppendTag(sb, 2, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, pb)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+	return b
+}
@@ -6,8 +6,9 @@ import (
 
 // Config contains configuration params for Pulsar authentication.
 type Config struct {
-	OAuth2 OAuth2Config `json:"oauth2" yaml:"oauth2"`
-	Token  TokenConfig  `json:"token" yaml:"token"`
+	OAuth2 OAuth2Config  `json:"oauth2" yaml:"oauth2"`
+	Token  TokenConfig   `json:"token" yaml:"token"`
+	TLS    TLSAuthConfig `json:"tls" yaml:"tls"`
 }
 
 // OAuth2Config contains configuration params for Pulsar OAuth2 authentication.
@@ -24,11 +25,28 @@ type TokenConfig struct {
 	Token   string `json:"token" yaml:"token"`
 }
 
+// TLSAuthConfig contains configuration params for Pulsar TLS client
+// certificate authentication.
+type TLSAuthConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+}
+
+// TLSConfig contains configuration params for the TLS connection to a Pulsar
+// server.
+type TLSConfig struct {
+	RootCAsFile      string `json:"root_cas_file" yaml:"root_cas_file"`
+	SkipCertVerify   bool   `json:"skip_cert_verify" yaml:"skip_cert_verify"`
+	ValidateHostname bool   `json:"validate_hostname" yaml:"validate_hostname"`
+}
+
 // New creates a new Config instance.
 func New() Config {
 	return Config{
 		OAuth2: NewOAuth(),
 		Token:  NewToken(),
+		TLS:    NewTLSAuth(),
 	}
 }
 
@@ -50,9 +68,33 @@ func NewToken() TokenConfig {
 	}
 }
 
+// NewTLSAuth creates a new TLSAuthConfig instance.
+func NewTLSAuth() TLSAuthConfig {
+	return TLSAuthConfig{
+		Enabled:  false,
+		CertFile: "",
+		KeyFile:  "",
+	}
+}
+
+// NewTLS creates a new TLSConfig instance.
+func NewTLS() TLSConfig {
+	return TLSConfig{
+		RootCAsFile:      "",
+		SkipCertVerify:   false,
+		ValidateHostname: false,
+	}
+}
+
 // Validate checks whether Config is valid.
 func (c *Config) Validate() error {
-	if c.OAuth2.Enabled && c.Token.Enabled {
+	enabled := 0
+	for _, e := range []bool{c.OAuth2.Enabled, c.Token.Enabled, c.TLS.Enabled} {
+		if e {
+			enabled++
+		}
+	}
+	if enabled > 1 {
 		return errors.New("only one auth method can be enabled at once")
 	}
 	if c.OAuth2.Enabled {
@@ -61,6 +103,9 @@ func (c *Config) Validate() error {
 	if c.Token.Enabled {
 		return c.Token.Validate()
 	}
+	if c.TLS.Enabled {
+		return c.TLS.Validate()
+	}
 	return nil
 }
 
@@ -96,3 +141,14 @@ func (c *TokenConfig) Validate() error {
 	}
 	return nil
 }
+
+// Validate checks whether TLSAuthConfig is valid.
+func (c *TLSAuthConfig) Validate() error {
+	if c.CertFile == "" {
+		return errors.New("tls cert file is empty")
+	}
+	if c.KeyFile == "" {
+		return errors.New("tls key file is empty")
+	}
+	return nil
+}
@@ -15,5 +15,20 @@ func FieldSpec() docs.FieldSpec {
 			docs.FieldBool("enabled", "Whether Token Auth is enabled.", true),
 			docs.FieldString("token", "Actual base64 encoded token."),
 		),
+		docs.FieldAdvanced("tls", "Parameters for Pulsar TLS client certificate authentication.").WithChildren(
+			docs.FieldBool("enabled", "Whether TLS client certificate authentication is enabled.", true),
+			docs.FieldString("cert_file", "File containing the client certificate."),
+			docs.FieldString("key_file", "File containing the client private key."),
+		).AtVersion("3.64.0"),
 	).AtVersion("3.60.0")
 }
+
+// TLSFieldSpec returns documentation specs for the TLS connection settings of
+// Pulsar components.
+func TLSFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("tls", "Custom TLS settings for connecting to a server with a `pulsar+ssl` URL.").WithChildren(
+		docs.FieldString("root_cas_file", "An optional path of a root certificate authority file to use for verifying the server certificate. When empty the system defaults are used.", "./root_cas.pem"),
+		docs.FieldBool("skip_cert_verify", "Whether to skip server side certificate verification."),
+		docs.FieldBool("validate_hostname", "Whether to verify that the hostname of the server matches its certificate."),
+	).AtVersion("3.64.0")
+}
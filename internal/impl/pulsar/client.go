@@ -0,0 +1,32 @@
+package pulsar
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/impl/pulsar/auth"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// clientOptions returns the options for creating a Pulsar client shared by
+// both the input and output.
+func clientOptions(url string, authConf auth.Config, tlsConf auth.TLSConfig, logger log.Modular) pulsar.ClientOptions {
+	opts := pulsar.ClientOptions{
+		Logger:            DefaultLogger(logger),
+		ConnectionTimeout: time.Second * 3,
+		URL:               url,
+
+		TLSTrustCertsFilePath:      tlsConf.RootCAsFile,
+		TLSAllowInsecureConnection: tlsConf.SkipCertVerify,
+		TLSValidateHostname:        tlsConf.ValidateHostname,
+	}
+
+	if authConf.OAuth2.Enabled {
+		opts.Authentication = pulsar.NewAuthenticationOAuth2(authConf.OAuth2.ToMap())
+	} else if authConf.Token.Enabled {
+		opts.Authentication = pulsar.NewAuthenticationToken(authConf.Token.Token)
+	} else if authConf.TLS.Enabled {
+		opts.Authentication = pulsar.NewAuthenticationTLS(authConf.TLS.CertFile, authConf.TLS.KeyFile)
+	}
+	return opts
+}
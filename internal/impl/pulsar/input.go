@@ -57,7 +57,15 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 
 You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+### Batching
+
+By default each message is read and acknowledged on its own. Setting ` + "`max_batch_count`" + ` to a value greater than one allows messages that have already been received by the consumer to be read as a single batch, where the entire batch is acknowledged or negatively acknowledged together. A batch is never held back waiting for more messages to arrive.
+
+### Dead Letter Topics
+
+When ` + "`dead_letter_policy.max_redeliveries`" + ` is set to a value greater than zero messages that have been negatively acknowledged more than that number of times are sent to a dead letter topic and acknowledged, instead of being redelivered again. Dead letter topics are only supported with ` + "`shared`" + ` and ` + "`key_shared`" + ` subscription types.`,
 		Categories: []string{
 			string(input.CategoryServices),
 		},
@@ -73,6 +81,13 @@ You can access these metadata fields using
 			docs.FieldCommon("subscription_type", "Specify the subscription type for this consumer.\n\n> NOTE: Using a `key_shared` subscription type will __allow out-of-order delivery__ since nack-ing messages sets non-zero nack delivery delay - this can potentially cause consumers to stall. See [Pulsar documentation](https://pulsar.apache.org/docs/en/2.8.1/concepts-messaging/#negative-acknowledgement) and [this Github issue](https://github.com/apache/pulsar/issues/12208) for more details.").
 				HasOptions("shared", "key_shared", "failover", "exclusive").
 				HasDefault(defaultSubscriptionType),
+			docs.FieldAdvanced("max_batch_count", "The maximum number of messages that have already been received to read as a single batch.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("nack_redelivery_delay", "The delay after which a negatively acknowledged message is redelivered.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("dead_letter_policy", "Optionally send messages that are repeatedly negatively acknowledged to a dead letter topic.").WithChildren(
+				docs.FieldInt("max_redeliveries", "The maximum number of times a message is redelivered before it is sent to the dead letter topic. Set to zero in order to disable the dead letter topic."),
+				docs.FieldString("topic", "The topic to send dead letter messages to. When empty the topic `<topic>-<subscription_name>-DLQ` is used."),
+			).AtVersion("3.64.0"),
+			auth.TLSFieldSpec(),
 			auth.FieldSpec(),
 		).ChildDefaultAndTypesFromStruct(input.NewPulsarConfig()),
 	})
@@ -88,6 +103,8 @@ type pulsarReader struct {
 	stats metrics.Type
 	log   log.Modular
 
+	nackDelay time.Duration
+
 	m       sync.RWMutex
 	shutSig *shutdown.Signaller
 }
@@ -108,6 +125,12 @@ func newPulsarReader(conf input.PulsarConfig, log log.Modular, stats metrics.Typ
 	if _, err := parseSubscriptionType(conf.SubscriptionType); err != nil {
 		return nil, fmt.Errorf("field subscription_type is invalid: %v", err)
 	}
+	if conf.MaxBatchCount < 1 {
+		return nil, errors.New("field max_batch_count must be greater than zero")
+	}
+	if conf.DeadLetterPolicy.MaxRedeliveries < 0 {
+		return nil, errors.New("field dead_letter_policy.max_redeliveries must not be negative")
+	}
 	if err := conf.Auth.Validate(); err != nil {
 		return nil, fmt.Errorf("field auth is invalid: %v", err)
 	}
@@ -118,6 +141,12 @@ func newPulsarReader(conf input.PulsarConfig, log log.Modular, stats metrics.Typ
 		log:     log,
 		shutSig: shutdown.NewSignaller(),
 	}
+	if conf.NackRedeliveryDelay != "" {
+		var err error
+		if p.nackDelay, err = time.ParseDuration(conf.NackRedeliveryDelay); err != nil {
+			return nil, fmt.Errorf("failed to parse nack_redelivery_delay: %v", err)
+		}
+	}
 	return &p, nil
 }
 
@@ -154,19 +183,7 @@ func (p *pulsarReader) ConnectWithContext(ctx context.Context) error {
 		err      error
 	)
 
-	opts := pulsar.ClientOptions{
-		Logger:            DefaultLogger(p.log),
-		ConnectionTimeout: time.Second * 3,
-		URL:               p.conf.URL,
-	}
-
-	if p.conf.Auth.OAuth2.Enabled {
-		opts.Authentication = pulsar.NewAuthenticationOAuth2(p.conf.Auth.OAuth2.ToMap())
-	} else if p.conf.Auth.Token.Enabled {
-		opts.Authentication = pulsar.NewAuthenticationToken(p.conf.Auth.Token.Token)
-	}
-
-	if client, err = pulsar.NewClient(opts); err != nil {
+	if client, err = pulsar.NewClient(clientOptions(p.conf.URL, p.conf.Auth, p.conf.TLS, p.log)); err != nil {
 		return err
 	}
 
@@ -174,14 +191,23 @@ func (p *pulsarReader) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	if consumer, err = client.Subscribe(pulsar.ConsumerOptions{
-		Topics:           p.conf.Topics,
-		SubscriptionName: p.conf.SubscriptionName,
-		Type:             subType,
+	consumerOpts := pulsar.ConsumerOptions{
+		Topics:              p.conf.Topics,
+		SubscriptionName:    p.conf.SubscriptionName,
+		Type:                subType,
+		NackRedeliveryDelay: p.nackDelay,
 		KeySharedPolicy: &pulsar.KeySharedPolicy{
 			AllowOutOfOrderDelivery: true,
 		},
-	}); err != nil {
+	}
+	if dlq := p.conf.DeadLetterPolicy; dlq.MaxRedeliveries > 0 {
+		consumerOpts.DLQ = &pulsar.DLQPolicy{
+			MaxDeliveries:   uint32(dlq.MaxRedeliveries),
+			DeadLetterTopic: dlq.Topic,
+		}
+	}
+
+	if consumer, err = client.Subscribe(consumerOpts); err != nil {
 		client.Close()
 		return err
 	}
@@ -242,8 +268,48 @@ func (p *pulsarReader) ReadWithContext(ctx context.Context) (types.Message, read
 		return nil, nil, err
 	}
 
+	// Add any messages that have already been received to the batch without
+	// waiting for more to arrive.
+	pulMsgs := []pulsar.Message{pulMsg}
+batchLoop:
+	for len(pulMsgs) < p.conf.MaxBatchCount {
+		select {
+		case cMsg, open := <-r.Chan():
+			if !open {
+				break batchLoop
+			}
+			pulMsgs = append(pulMsgs, cMsg.Message)
+		default:
+			break batchLoop
+		}
+	}
+
 	msg := message.New(nil)
+	for _, pulMsg := range pulMsgs {
+		msg.Append(pulsarMsgToPart(pulMsg))
+	}
 
+	return msg, func(ctx context.Context, res types.Response) error {
+		var r pulsar.Consumer
+		p.m.RLock()
+		if p.consumer != nil {
+			r = p.consumer
+		}
+		p.m.RUnlock()
+		if r != nil {
+			for _, pulMsg := range pulMsgs {
+				if res.Error() != nil {
+					r.Nack(pulMsg)
+				} else {
+					r.Ack(pulMsg)
+				}
+			}
+		}
+		return nil
+	}, nil
+}
+
+func pulsarMsgToPart(pulMsg pulsar.Message) types.Part {
 	part := message.NewPart(pulMsg.Payload())
 
 	part.Metadata().Set("pulsar_message_id", string(pulMsg.ID().Serialize()))
@@ -265,25 +331,7 @@ func (p *pulsarReader) ReadWithContext(ctx context.Context) (types.Message, read
 	for k, v := range pulMsg.Properties() {
 		part.Metadata().Set(k, v)
 	}
-
-	msg.Append(part)
-
-	return msg, func(ctx context.Context, res types.Response) error {
-		var r pulsar.Consumer
-		p.m.RLock()
-		if p.consumer != nil {
-			r = p.consumer
-		}
-		p.m.RUnlock()
-		if r != nil {
-			if res.Error() != nil {
-				r.Nack(pulMsg)
-			} else {
-				r.Ack(pulMsg)
-			}
-		}
-		return nil
-	}, nil
+	return part
 }
 
 // CloseAsync shuts down the Pulsar input and stops processing requests.
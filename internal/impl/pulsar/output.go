@@ -50,8 +50,9 @@ func init() {
 			),
 			docs.FieldCommon("topic", "A topic to publish to."),
 			docs.FieldCommon("key", "The key to publish messages with.").IsInterpolated(),
-			docs.FieldCommon("ordering_key", "The ordering key to publish messages with.").IsInterpolated(),
+			docs.FieldCommon("ordering_key", "The ordering key to publish messages with. Messages with the same ordering key are delivered in order to consumers of a `key_shared` subscription.").IsInterpolated(),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			auth.TLSFieldSpec(),
 			auth.FieldSpec(),
 		).ChildDefaultAndTypesFromStruct(output.NewPulsarConfig()),
 	})
@@ -84,6 +85,9 @@ func newPulsarWriter(conf output.PulsarConfig, mgr types.Manager, log log.Modula
 	if conf.Topic == "" {
 		return nil, errors.New("field topic must not be empty")
 	}
+	if err = conf.Auth.Validate(); err != nil {
+		return nil, fmt.Errorf("field auth is invalid: %v", err)
+	}
 	if key, err = interop.NewBloblangField(mgr, conf.Key); err != nil {
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
@@ -119,19 +123,7 @@ func (p *pulsarWriter) ConnectWithContext(ctx context.Context) error {
 		err      error
 	)
 
-	opts := pulsar.ClientOptions{
-		Logger:            DefaultLogger(p.log),
-		ConnectionTimeout: time.Second * 3,
-		URL:               p.conf.URL,
-	}
-
-	if p.conf.Auth.OAuth2.Enabled {
-		opts.Authentication = pulsar.NewAuthenticationOAuth2(p.conf.Auth.OAuth2.ToMap())
-	} else if p.conf.Auth.Token.Enabled {
-		opts.Authentication = pulsar.NewAuthenticationToken(p.conf.Auth.Token.Token)
-	}
-
-	if client, err = pulsar.NewClient(opts); err != nil {
+	if client, err = pulsar.NewClient(clientOptions(p.conf.URL, p.conf.Auth, p.conf.TLS, p.log)); err != nil {
 		return err
 	}
 
@@ -0,0 +1,61 @@
+// Package redis contains the redis components that are implemented with the
+// public plugin APIs.
+package redis
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/impl/redis/old"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/go-redis/redis/v7"
+)
+
+// clientFields returns the fields of a redis connection, which match the
+// fields of the legacy redis components.
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("url").
+			Description("The URL of the target Redis server. Database is optional and is supplied as the URL path. The scheme `tcp` is equivalent to `redis`.").
+			Example(":6397").
+			Example("localhost:6397").
+			Example("redis://localhost:6379").
+			Example("redis://:foopassword@redisplace:6379").
+			Example("redis://localhost:6379/1").
+			Example("redis://localhost:6379/1,redis://localhost:6380/1").
+			Default("tcp://localhost:6379"),
+		service.NewStringEnumField("kind", "simple", "cluster", "failover").
+			Description("Specifies a simple, cluster-aware, or failover-aware redis client.").
+			Default("simple").
+			Advanced(),
+		service.NewStringField("master").
+			Description("Name of the redis master when `kind` is `failover`").
+			Default("").
+			Example("mymaster").
+			Advanced(),
+		service.NewTLSToggledField("tls").
+			Description("Custom TLS settings can be used to override system defaults." + old.TLSTroubleshootingDocs),
+	}
+}
+
+// getClient returns a redis client from a parsed config containing the fields
+// of clientFields.
+func getClient(conf *service.ParsedConfig) (redis.UniversalClient, error) {
+	url, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	kind, err := conf.FieldString("kind")
+	if err != nil {
+		return nil, err
+	}
+	master, err := conf.FieldString("master")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		tlsConf = nil
+	}
+	return old.NewClient(url, kind, master, tlsConf)
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/integration"
+	"github.com/go-redis/redis/v7"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationRedisRateLimit(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.Run("redis", "latest", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	url := fmt.Sprintf("tcp://localhost:%v", resource.GetPort("6379/tcp"))
+
+	resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		client := redis.NewClient(&redis.Options{
+			Addr: fmt.Sprintf("localhost:%v", resource.GetPort("6379/tcp")),
+		})
+		defer client.Close()
+		return client.Ping().Err()
+	}))
+
+	conf, err := redisRateLimitConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+key: benthos_test_rate_limit
+count: 10
+interval: 1s
+jitter: ""
+`, url), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	rlA, err := newRedisRateLimitFromConfig(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = rlA.Close(ctx)
+	})
+
+	rlB, err := newRedisRateLimitFromConfig(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = rlB.Close(ctx)
+	})
+
+	for i := 0; i < 5; i++ {
+		tout, err := rlA.Access(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), tout)
+
+		tout, err = rlB.Access(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), tout)
+	}
+
+	tout, err := rlA.Access(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, int64(tout), int64(0))
+	assert.LessOrEqual(t, int64(tout), int64(time.Second))
+
+	<-time.After(time.Millisecond * 500)
+
+	tout, err = rlB.AccessN(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), tout)
+
+	tout, err = rlB.AccessN(ctx, 4)
+	require.NoError(t, err)
+	assert.Greater(t, int64(tout), int64(0))
+}
@@ -1,4 +1,6 @@
-package redis
+// Package old contains the configuration of redis connections used by the
+// legacy redis components.
+package old
 
 import (
 	"crypto/tls"
@@ -30,14 +32,27 @@ func NewConfig() Config {
 
 // Client returns a new redis client based on the configuration parameters.
 func (r Config) Client() (redis.UniversalClient, error) {
+	var tlsConf *tls.Config
+	if r.TLS.Enabled {
+		var err error
+		if tlsConf, err = r.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return NewClient(r.URL, r.Kind, r.Master, tlsConf)
+}
 
+// NewClient returns a new redis client for a comma-separated list of URLs, a
+// kind of client, the name of the master when the kind is failover, and an
+// optional TLS config.
+func NewClient(urls, kind, master string, tlsConf *tls.Config) (redis.UniversalClient, error) {
 	// We default to Redis DB 0 for backward compatibility
 	var redisDB int
 	var pass string
 	var addrs []string
 
 	// handle comma-separated urls
-	for _, v := range strings.Split(r.URL, ",") {
+	for _, v := range strings.Split(urls, ",") {
 		url, err := url.Parse(v)
 		if err != nil {
 			return nil, err
@@ -57,14 +72,6 @@ func (r Config) Client() (redis.UniversalClient, error) {
 		pass = rurl.Password
 	}
 
-	var tlsConf *tls.Config
-	if r.TLS.Enabled {
-		var err error
-		if tlsConf, err = r.TLS.Get(); err != nil {
-			return nil, err
-		}
-	}
-
 	var client redis.UniversalClient
 	var err error
 
@@ -75,29 +82,33 @@ func (r Config) Client() (redis.UniversalClient, error) {
 		TLSConfig: tlsConf,
 	}
 
-	switch r.Kind {
+	switch kind {
 	case "simple":
 		client = redis.NewClient(opts.Simple())
 	case "cluster":
 		client = redis.NewClusterClient(opts.Cluster())
 	case "failover":
-		opts.MasterName = r.Master
+		opts.MasterName = master
 		client = redis.NewFailoverClient(opts.Failover())
 	default:
-		err = fmt.Errorf("invalid redis kind: %s", r.Kind)
+		err = fmt.Errorf("invalid redis kind: %s", kind)
 	}
 
 	return client, err
 }
 
-// ConfigDocs returns a documentation field spec for fields within a Config.
-func ConfigDocs() docs.FieldSpecs {
-	tlsSpec := btls.FieldSpec()
-	tlsSpec.Description = tlsSpec.Description + `
+// TLSTroubleshootingDocs is appended to the description of the TLS field of
+// redis components.
+const TLSTroubleshootingDocs = `
 
 **Troubleshooting**
 
 Some cloud hosted instances of Redis (such as Azure Cache) might need some hand holding in order to establish stable connections. Unfortunately, it is often the case that TLS issues will manifest as generic error messages such as "i/o timeout". If you're using TLS and are seeing connectivity problems consider setting ` + "`enable_renegotiation` to `true`" + `, and ensuring that the server supports at least TLS version 1.2.`
+
+// ConfigDocs returns a documentation field spec for fields within a Config.
+func ConfigDocs() docs.FieldSpecs {
+	tlsSpec := btls.FieldSpec()
+	tlsSpec.Description += TLSTroubleshootingDocs
 	return docs.FieldSpecs{
 		docs.FieldCommon(
 			"url", "The URL of the target Redis server. Database is optional and is supplied as the URL path. The scheme `tcp` is equivalent to `redis`.",
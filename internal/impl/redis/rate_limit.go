@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/go-redis/redis/v7"
+)
+
+func redisRateLimitConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Summary(`A token bucket rate limit stored within Redis, which allows any number of Benthos instances to share a single quota.`).
+		Description(`
+The bucket holds up to ` + "`count`" + ` tokens and is refilled continuously at a rate of ` + "`count`" + ` tokens per ` + "`interval`" + `. Each access consumes a token, and when the bucket is empty callers are told how long to wait until enough tokens are available.
+
+The state of the bucket is read and updated atomically with a Lua script, using the clock of the Redis server, and therefore all rate limits that are configured with the same ` + "`key`" + ` and Redis server share one quota regardless of which instance of Benthos they belong to.
+
+When many instances are limited at the same time they are each told to wait a similar length of time, which would result in them all retrying at once. In order to spread these retries out a random duration of up to ` + "`jitter`" + ` is added to each wait period.`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringField("key").
+			Description("The key used to store the state of the rate limit within Redis. Rate limits that share a key share a single quota.").
+			Default("benthos_rate_limit")).
+		Field(service.NewIntField("count").
+			Description("The maximum number of requests to allow for a given period of time.").
+			Default(1000)).
+		Field(service.NewStringField("interval").
+			Description("The time window to limit requests by.").
+			Default("1s")).
+		Field(service.NewStringField("jitter").
+			Description("A maximum random duration to add to the wait period reported to rate limited callers, in order to prevent many instances from retrying simultaneously. Set to an empty string or zero in order to disable jitter.").
+			Example("10ms").
+			Example("100ms").
+			Advanced().
+			Default("50ms"))
+}
+
+func init() {
+	err := service.RegisterRateLimit(
+		"redis", redisRateLimitConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.RateLimit, error) {
+			return newRedisRateLimitFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// redisTokenBucketScript refills the bucket according to the time elapsed since
+// it was last accessed, and then attempts to consume the requested number of
+// tokens. Returns zero when the tokens were consumed, otherwise the number of
+// microseconds to wait until enough tokens are available. Requests for more
+// tokens than the size of the bucket are treated as a request for the entire
+// bucket.
+var redisTokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local requested = math.min(tonumber(ARGV[3]), capacity)
+
+local now = redis.call("TIME")
+now = tonumber(now[1]) * 1000000 + tonumber(now[2])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+if now > ts then
+  tokens = math.min(capacity, tokens + ((now - ts) * capacity / interval))
+  ts = now
+end
+
+local wait = 0
+if tokens >= requested then
+  tokens = tokens - requested
+else
+  wait = math.ceil((requested - tokens) * interval / capacity)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], math.ceil(interval / 1000) * 2)
+return wait
+`)
+
+type redisRateLimit struct {
+	client redis.UniversalClient
+	key    string
+	size   int
+	period time.Duration
+	jitter time.Duration
+}
+
+func newRedisRateLimitFromConfig(conf *service.ParsedConfig) (*redisRateLimit, error) {
+	key, err := conf.FieldString("key")
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, errors.New("a key must be specified")
+	}
+
+	count, err := conf.FieldInt("count")
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+
+	intervalStr, err := conf.FieldString("interval")
+	if err != nil {
+		return nil, err
+	}
+	period, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %w", err)
+	}
+	if period <= 0 {
+		return nil, errors.New("interval must be larger than zero")
+	}
+
+	var jitter time.Duration
+	jitterStr, err := conf.FieldString("jitter")
+	if err != nil {
+		return nil, err
+	}
+	if jitterStr != "" {
+		if jitter, err = time.ParseDuration(jitterStr); err != nil {
+			return nil, fmt.Errorf("failed to parse jitter: %w", err)
+		}
+	}
+
+	client, err := getClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisRateLimit{
+		client: client,
+		key:    key,
+		size:   count,
+		period: period,
+		jitter: jitter,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *redisRateLimit) Access(ctx context.Context) (time.Duration, error) {
+	return r.AccessN(ctx, 1)
+}
+
+// AccessN attempts to consume n units of access from the rate limited
+// resource. Either all n units are consumed and a zero duration is returned, or
+// none are consumed and a reasonable length of time to wait before requesting
+// again is returned.
+func (r *redisRateLimit) AccessN(ctx context.Context, n int) (time.Duration, error) {
+	waitMicros, err := redisTokenBucketScript.Run(
+		r.client, []string{r.key},
+		r.size, r.period.Microseconds(), n,
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if waitMicros <= 0 {
+		return 0, nil
+	}
+
+	wait := time.Duration(waitMicros) * time.Microsecond
+	if r.jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(r.jitter)))
+	}
+	return wait, nil
+}
+
+func (r *redisRateLimit) Close(ctx context.Context) error {
+	return r.client.Close()
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRateLimitConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		config      string
+		errContains string
+	}{
+		"bad count": {
+			config:      `count: -1`,
+			errContains: "count must be larger than zero",
+		},
+		"bad interval": {
+			config:      `interval: nope`,
+			errContains: "failed to parse interval",
+		},
+		"zero interval": {
+			config:      `interval: 0s`,
+			errContains: "interval must be larger than zero",
+		},
+		"bad jitter": {
+			config:      `jitter: nope`,
+			errContains: "failed to parse jitter",
+		},
+		"empty key": {
+			config:      `key: ""`,
+			errContains: "a key must be specified",
+		},
+		"bad kind": {
+			config:      `kind: nope`,
+			errContains: "invalid redis kind",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := redisRateLimitConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newRedisRateLimitFromConfig(conf)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
@@ -0,0 +1,563 @@
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gofrs/uuid"
+)
+
+func hecOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Services").
+		Summary("Publishes message batches to a Splunk HTTP Event Collector (HEC).").
+		Description(output.Description(true, true, `
+Each message batch is written with a single request to either the event or the raw endpoint of the collector, which is determined by the field `+"`endpoint`"+`.
+
+With the `+"`event`"+` endpoint each message becomes the event of a JSON object, where messages that are valid JSON documents are sent as structured events and other messages are sent as strings. The fields `+"`index`"+`, `+"`source`"+`, `+"`sourcetype`"+` and `+"`host`"+` are resolved for each message and set on its event when they are not empty.
+
+With the `+"`raw`"+` endpoint the contents of the messages are sent unchanged, separated by line breaks, and Splunk breaks them into events according to the configuration of the source type. Since the fields `+"`index`"+`, `+"`source`"+`, `+"`sourcetype`"+` and `+"`host`"+` apply to a whole request, messages of a batch are grouped by their values into separate requests.
+
+### Acknowledgements
+
+A request succeeds once the collector has received its events, at which point they have not necessarily been indexed. For guaranteed delivery enable `+"`acknowledgements`"+`, which requires [indexer acknowledgement](https://docs.splunk.com/Documentation/Splunk/latest/Data/AboutHECIDXAck) to be enabled for the token. Each request is then only considered successful once the ack endpoint of the collector reports that its events have been indexed, and when this does not happen within `+"`acknowledgements.timeout`"+` the batch is failed and sent again.
+
+Requests are made over the channel set by the field `+"`channel`"+`, which is a random UUID generated on start up unless specified.
+
+### Errors
+
+Requests that are rejected because the indexers are busy (status 503), are rate limited (status 429) or fail with a server error are retried according to the field `+"`backoff`"+`. Requests that are rejected for any other reason, such as an invalid token or index, are not retried by this output.`)).
+		Field(service.NewStringField("url").
+			Description("The base URL of the collector.").
+			Example("https://localhost:8088")).
+		Field(service.NewStringField("token").
+			Description("The HEC token to authenticate with.")).
+		Field(service.NewStringAnnotatedEnumField("endpoint", map[string]string{
+			"event": "Each message is sent as an event to the `/services/collector/event` endpoint.",
+			"raw":   "Messages are sent unchanged to the `/services/collector/raw` endpoint.",
+		}).
+			Description("The endpoint of the collector to write to.").
+			Default("event")).
+		Field(service.NewInterpolatedStringField("index").
+			Description("The index to write events to. When empty the default index of the token is used.").
+			Example(`${! meta("kafka_topic") }`).
+			Default("")).
+		Field(service.NewInterpolatedStringField("source").
+			Description("The source of events. When empty the default source of the token is used.").
+			Default("")).
+		Field(service.NewInterpolatedStringField("sourcetype").
+			Description("The source type of events. When empty the default source type of the token is used.").
+			Example("_json").
+			Default("")).
+		Field(service.NewInterpolatedStringField("host").
+			Description("The host of events. When empty the host of the collector is used.").
+			Default("")).
+		Field(service.NewBoolField("gzip").
+			Description("Whether to compress the body of requests with gzip.").
+			Default(false)).
+		Field(service.NewStringField("channel").
+			Description("The channel to make requests over, which must be a UUID. When empty a random UUID is generated.").
+			Default("").
+			Advanced()).
+		Field(service.NewObjectField("acknowledgements",
+			service.NewBoolField("enabled").
+				Description("Whether to wait for the events of each request to be indexed.").
+				Default(false),
+			service.NewDurationField("poll_interval").
+				Description("The period to wait between polls of the ack endpoint.").
+				Default("1s"),
+			service.NewDurationField("timeout").
+				Description("The maximum period to wait for the events of a request to be indexed.").
+				Default("60s"),
+		).Description("Enables waiting for the events of requests to be indexed.")).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to wait for each request to complete.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewBackOffField("backoff", false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("Determine time intervals and cut offs for retrying requests that were rejected because the indexers are busy.").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of batches to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching")).
+		Example("Guaranteed Delivery", `
+Write JSON events consumed from Kafka to an index named after their topic, where batches are only acknowledged once their events have been indexed:`,
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ app_logs, audit_logs ]
+    consumer_group: benthos_splunk
+
+output:
+  splunk_hec:
+    url: https://splunk.example.com:8088
+    token: ${SPLUNK_HEC_TOKEN}
+    index: ${! meta("kafka_topic") }
+    sourcetype: _json
+    gzip: true
+    acknowledgements:
+      enabled: true
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"splunk_hec", hecOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newHECOutputFromConfig(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type hecOutput struct {
+	url      string
+	token    string
+	endpoint string
+	channel  string
+	gzip     bool
+	http     *http.Client
+	log      *service.Logger
+	backoff  *backoff.ExponentialBackOff
+
+	index      *service.InterpolatedString
+	source     *service.InterpolatedString
+	sourceType *service.InterpolatedString
+	host       *service.InterpolatedString
+
+	ackEnabled      bool
+	ackPollInterval time.Duration
+	ackTimeout      time.Duration
+}
+
+func newHECOutputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*hecOutput, error) {
+	h := &hecOutput{log: log}
+
+	var err error
+	if h.url, err = conf.FieldString("url"); err != nil {
+		return nil, err
+	}
+	if h.url = strings.TrimSuffix(h.url, "/"); h.url == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	if _, err := url.Parse(h.url); err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if h.token, err = conf.FieldString("token"); err != nil {
+		return nil, err
+	}
+	if h.endpoint, err = conf.FieldString("endpoint"); err != nil {
+		return nil, err
+	}
+	switch h.endpoint {
+	case "event", "raw":
+	default:
+		return nil, fmt.Errorf("unrecognised endpoint: %v", h.endpoint)
+	}
+
+	if h.channel, err = conf.FieldString("channel"); err != nil {
+		return nil, err
+	}
+	if h.channel == "" {
+		u4, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		h.channel = u4.String()
+	} else if _, err := uuid.FromString(h.channel); err != nil {
+		return nil, fmt.Errorf("channel must be a UUID: %w", err)
+	}
+
+	if h.gzip, err = conf.FieldBool("gzip"); err != nil {
+		return nil, err
+	}
+	if h.index, err = conf.FieldInterpolatedString("index"); err != nil {
+		return nil, err
+	}
+	if h.source, err = conf.FieldInterpolatedString("source"); err != nil {
+		return nil, err
+	}
+	if h.sourceType, err = conf.FieldInterpolatedString("sourcetype"); err != nil {
+		return nil, err
+	}
+	if h.host, err = conf.FieldInterpolatedString("host"); err != nil {
+		return nil, err
+	}
+
+	ackConf := conf.Namespace("acknowledgements")
+	if h.ackEnabled, err = ackConf.FieldBool("enabled"); err != nil {
+		return nil, err
+	}
+	if h.ackPollInterval, err = ackConf.FieldDuration("poll_interval"); err != nil {
+		return nil, err
+	}
+	if h.ackTimeout, err = ackConf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	h.http = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		h.http.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	if h.backoff, err = conf.FieldBackOff("backoff"); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+//------------------------------------------------------------------------------
+
+// hecResponse is the body of a response from the collector.
+type hecResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	AckID              *int64 `json:"ackId"`
+	InvalidEventNumber *int   `json:"invalid-event-number"`
+}
+
+// hecRequestError is returned when the collector rejects a request.
+type hecRequestError struct {
+	status int
+	res    hecResponse
+}
+
+func (e *hecRequestError) Error() string {
+	msg := fmt.Sprintf("request failed with status %v", e.status)
+	if e.res.Text != "" {
+		msg += fmt.Sprintf(": %v (code %v)", e.res.Text, e.res.Code)
+	}
+	if e.res.InvalidEventNumber != nil {
+		msg += fmt.Sprintf(", invalid event number %v", *e.res.InvalidEventNumber)
+	}
+	return msg
+}
+
+// retryable returns whether the request was rejected because the indexers are
+// unable to accept events at the moment.
+func (e *hecRequestError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// do performs a request against the collector and decodes the body of a
+// successful response into the provided value.
+func (h *hecOutput) do(ctx context.Context, method, path string, query url.Values, body []byte, gzipped bool, v interface{}) error {
+	reqURL := h.url + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+h.token)
+	req.Header.Set("X-Splunk-Request-Channel", h.channel)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := h.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		reqErr := &hecRequestError{status: res.StatusCode}
+		if err := json.Unmarshal(resBody, &reqErr.res); err != nil || reqErr.res.Text == "" {
+			reqErr.res.Text = string(bytes.TrimSpace(resBody))
+		}
+		return reqErr
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resBody, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+func (h *hecOutput) Connect(ctx context.Context) error {
+	if err := h.do(ctx, "GET", "/services/collector/health", nil, nil, false, nil); err != nil {
+		return err
+	}
+	h.log.Infof("Writing message batches to Splunk HEC %v endpoint: %v\n", h.endpoint, h.url)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// hecRequest is a request containing the events of a set of messages from a
+// batch.
+type hecRequest struct {
+	indexes []int
+	query   url.Values
+	body    bytes.Buffer
+}
+
+// eventFields resolves the non-empty fields of the event of a message.
+func (h *hecOutput) eventFields(batch service.MessageBatch, i int) map[string]string {
+	fields := map[string]string{}
+	for k, v := range map[string]*service.InterpolatedString{
+		"index":      h.index,
+		"source":     h.source,
+		"sourcetype": h.sourceType,
+		"host":       h.host,
+	} {
+		if s := batch.InterpolatedString(i, v); s != "" {
+			fields[k] = s
+		}
+	}
+	return fields
+}
+
+// events creates a request to the event endpoint for a batch.
+func (h *hecOutput) events(batch service.MessageBatch) (*hecRequest, error) {
+	req := &hecRequest{}
+	for i, msg := range batch {
+		event := map[string]interface{}{}
+		for k, v := range h.eventFields(batch, i) {
+			event[k] = v
+		}
+
+		if structured, err := msg.AsStructured(); err == nil {
+			event["event"] = structured
+		} else {
+			msgBytes, err := msg.AsBytes()
+			if err != nil {
+				return nil, err
+			}
+			event["event"] = string(msgBytes)
+		}
+
+		eventBytes, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		req.indexes = append(req.indexes, i)
+		req.body.Write(eventBytes)
+	}
+	return req, nil
+}
+
+// raw creates requests to the raw endpoint for a batch, where messages are
+// grouped into requests by their fields.
+func (h *hecOutput) raw(batch service.MessageBatch) ([]*hecRequest, error) {
+	var reqs []*hecRequest
+	groups := map[string]*hecRequest{}
+	for i, msg := range batch {
+		query := url.Values{}
+		for k, v := range h.eventFields(batch, i) {
+			query.Set(k, v)
+		}
+
+		key := query.Encode()
+		req, exists := groups[key]
+		if !exists {
+			req = &hecRequest{query: query}
+			groups[key] = req
+			reqs = append(reqs, req)
+		}
+
+		msgBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(req.indexes) > 0 {
+			req.body.WriteByte('\n')
+		}
+		req.indexes = append(req.indexes, i)
+		req.body.Write(msgBytes)
+	}
+	return reqs, nil
+}
+
+func (h *hecOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var reqs []*hecRequest
+	if h.endpoint == "raw" {
+		var err error
+		if reqs, err = h.raw(batch); err != nil {
+			return err
+		}
+	} else {
+		req, err := h.events(batch)
+		if err != nil {
+			return err
+		}
+		reqs = []*hecRequest{req}
+	}
+
+	if len(reqs) == 1 {
+		return h.send(ctx, reqs[0])
+	}
+
+	batchErr := service.NewBatchError(batch, errors.New("failed to send events"))
+	for _, req := range reqs {
+		if err := h.send(ctx, req); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			for _, i := range req.indexes {
+				batchErr.Failed(i, err)
+			}
+		}
+	}
+	if batchErr.IndexedErrors() > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// send writes a request to the collector, retrying while the indexers are
+// busy, and then waits for its events to be indexed when acknowledgements are
+// enabled.
+func (h *hecOutput) send(ctx context.Context, req *hecRequest) error {
+	body := req.body.Bytes()
+	if h.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	boff := *h.backoff
+	boff.Reset()
+
+	for {
+		var res hecResponse
+		err := h.do(ctx, "POST", "/services/collector/"+h.endpoint, req.query, body, h.gzip, &res)
+		if err == nil {
+			if !h.ackEnabled {
+				return nil
+			}
+			if res.AckID == nil {
+				return errors.New("acknowledgements are not enabled for the token")
+			}
+			return h.waitForAck(ctx, *res.AckID)
+		}
+
+		var reqErr *hecRequestError
+		if !errors.As(err, &reqErr) || !reqErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		h.log.Warnf("Retrying rejected request: %v\n", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// waitForAck polls the ack endpoint until the events of a request have been
+// indexed.
+func (h *hecOutput) waitForAck(ctx context.Context, ackID int64) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"acks": []int64{ackID},
+	})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(h.ackTimeout)
+	for {
+		select {
+		case <-time.After(h.ackPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		var res struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		err := h.do(ctx, "POST", "/services/collector/ack", nil, body, false, &res)
+		if err == nil {
+			if res.Acks[strconv.FormatInt(ackID, 10)] {
+				return nil
+			}
+		} else {
+			var reqErr *hecRequestError
+			if !errors.As(err, &reqErr) || !reqErr.retryable() {
+				return fmt.Errorf("failed to poll acknowledgement: %w", err)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for events to be indexed with ack id %v", ackID)
+		}
+	}
+}
+
+func (h *hecOutput) Close(ctx context.Context) error {
+	return nil
+}
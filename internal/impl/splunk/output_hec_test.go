@@ -0,0 +1,353 @@
+package splunk
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHECChannel = "0b6c3d6e-2f2a-4a46-9e3e-5bb5c2d1d0e1"
+
+type testHECRequest struct {
+	path  string
+	query string
+	body  string
+}
+
+// readHECRequest reads a request to the event or raw endpoints, decompressing
+// the body when it is gzipped.
+func readHECRequest(t *testing.T, r *http.Request) testHECRequest {
+	t.Helper()
+
+	assert.Equal(t, "Splunk foo", r.Header.Get("Authorization"))
+	assert.Equal(t, testHECChannel, r.Header.Get("X-Splunk-Request-Channel"))
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body = zr
+	}
+	bodyBytes, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	return testHECRequest{
+		path:  r.URL.Path,
+		query: r.URL.RawQuery,
+		body:  string(bodyBytes),
+	}
+}
+
+func TestHECOutputEvents(t *testing.T) {
+	for _, gzipped := range []string{"false", "true"} {
+		gzipped := gzipped
+		t.Run("gzip "+gzipped, func(t *testing.T) {
+			var reqMut sync.Mutex
+			var reqs []testHECRequest
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/services/collector/health" {
+					_, _ = w.Write([]byte(`{"text":"HEC is healthy","code":17}`))
+					return
+				}
+				req := readHECRequest(t, r)
+				reqMut.Lock()
+				reqs = append(reqs, req)
+				reqMut.Unlock()
+				_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+			}))
+			defer ts.Close()
+
+			conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+channel: `+testHECChannel+`
+index: ${! meta("index") }
+sourcetype: _json
+gzip: `+gzipped+`
+`, nil)
+			require.NoError(t, err)
+
+			out, err := newHECOutputFromConfig(conf, nil)
+			require.NoError(t, err)
+			require.NoError(t, out.Connect(context.Background()))
+
+			first := service.NewMessage([]byte(`{"id":"a"}`))
+			first.MetaSet("index", "foo")
+			second := service.NewMessage([]byte(`not json`))
+
+			require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{first, second}))
+
+			reqMut.Lock()
+			defer reqMut.Unlock()
+			assert.Equal(t, []testHECRequest{
+				{
+					path: "/services/collector/event",
+					body: `{"event":{"id":"a"},"index":"foo","sourcetype":"_json"}{"event":"not json","sourcetype":"_json"}`,
+				},
+			}, reqs)
+		})
+	}
+}
+
+func TestHECOutputRaw(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs []testHECRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := readHECRequest(t, r)
+		reqMut.Lock()
+		reqs = append(reqs, req)
+		reqMut.Unlock()
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`/
+token: foo
+channel: `+testHECChannel+`
+endpoint: raw
+index: ${! meta("index") }
+source: benthos
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newHECOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	newMsg := func(content, index string) *service.Message {
+		msg := service.NewMessage([]byte(content))
+		msg.MetaSet("index", index)
+		return msg
+	}
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		newMsg("first", "foo"),
+		newMsg("second", "bar"),
+		newMsg("third", "foo"),
+	}))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	assert.Equal(t, []testHECRequest{
+		{
+			path:  "/services/collector/raw",
+			query: "index=foo&source=benthos",
+			body:  "first\nthird",
+		},
+		{
+			path:  "/services/collector/raw",
+			query: "index=bar&source=benthos",
+			body:  "second",
+		},
+	}, reqs)
+}
+
+func TestHECOutputRawPartialFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") == "bar" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"text":"Incorrect index","code":7,"invalid-event-number":0}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+channel: `+testHECChannel+`
+endpoint: raw
+index: ${! content() }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newHECOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+		service.NewMessage([]byte("foo")),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr), "%T", err)
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			assert.EqualError(t, err, "request failed with status 400: Incorrect index (code 7), invalid event number 0")
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+}
+
+func TestHECOutputServerBusy(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+channel: `+testHECChannel+`
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newHECOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}))
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&reqCount))
+}
+
+func TestHECOutputRequestFailure(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer ts.Close()
+
+	conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+channel: `+testHECChannel+`
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newHECOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	})
+	require.EqualError(t, err, "request failed with status 403: Invalid token (code 4)")
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
+
+func TestHECOutputAcknowledgements(t *testing.T) {
+	var ackMut sync.Mutex
+	var ackPolls int
+	var reqCount uint32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/collector/ack" {
+			var req struct {
+				Acks []int64 `json:"acks"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []int64{7}, req.Acks)
+
+			ackMut.Lock()
+			ackPolls++
+			acked := ackPolls > 2
+			ackMut.Unlock()
+
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"acks": map[string]bool{"7": acked},
+			})
+			return
+		}
+		readHECRequest(t, r)
+		atomic.AddUint32(&reqCount, 1)
+		_, _ = w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+	}))
+	defer ts.Close()
+
+	conf, err := hecOutputConfig().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+channel: `+testHECChannel+`
+gzip: true
+acknowledgements:
+  enabled: true
+  poll_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newHECOutputFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}))
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+
+	ackMut.Lock()
+	assert.Equal(t, 3, ackPolls)
+	ackMut.Unlock()
+
+	out.ackTimeout = 0
+	ackMut.Lock()
+	ackPolls = -10
+	ackMut.Unlock()
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"b"}`)),
+	})
+	require.EqualError(t, err, "timed out waiting for events to be indexed with ack id 7")
+}
+
+func TestHECOutputBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		conf   string
+		errStr string
+	}{
+		"no url": {
+			conf: `
+url: ""
+token: foo
+`,
+			errStr: "a url must be specified",
+		},
+		"bad channel": {
+			conf: `
+url: http://localhost:8088
+token: foo
+channel: nope
+`,
+			errStr: "channel must be a UUID",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			parsed, err := hecOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newHECOutputFromConfig(parsed, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+		})
+	}
+}
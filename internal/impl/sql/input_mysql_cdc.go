@@ -0,0 +1,632 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
+	"github.com/Jeffail/benthos/v3/public/service"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func mysqlCDCInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Services").
+		Version("3.64.0").
+		Summary("Streams row changes from the binary log of a MySQL server.").
+		Description(`
+This input connects to the server as a replica and requires the server to be configured with `+"`binlog_format = ROW`"+` and GTIDs enabled (`+"`gtid_mode = ON`"+` for MySQL). Each inserted, updated or deleted row results in a message of the following form:
+
+`+"```json"+`
+{
+  "schema": "foodb",
+  "table": "footable",
+  "operation": "update",
+  "before": { "id": 1, "name": "foo" },
+  "after": { "id": 1, "name": "bar" }
+}
+`+"```"+`
+
+Where `+"`before`"+` is null for inserts and `+"`after`"+` is null for deletes.
+
+### Checkpoints
+
+The set of GTIDs of all transactions that have been fully acknowledged is written to the cache resource `+"`checkpoint_cache`"+` under the key `+"`checkpoint_key`"+`, and when this input starts it resumes from the stored set. When no checkpoint is found streaming begins from the current position of the server, which is the value of `+"`gtid_executed`"+`.
+
+Checkpoints can be written periodically rather than as soon as they are reached by setting `+"`checkpoint_interval`"+`, and when multiple instances of this input may share the same checkpoint key `+"`checkpoint_fencing`"+` can be enabled in order to prevent a stale instance from overwriting the checkpoints of a newer one.
+
+### Schema Tracking
+
+The binary log only contains column names when the server is configured with `+"`binlog_row_metadata = FULL`"+` (MySQL 8.0.1 and later). Otherwise the column names of each table are read from `+"`information_schema`"+` the first time a change of that table is seen, and read again after any DDL statement that alters tables. Since the columns are read from the current schema it is possible for changes that were written before a schema change to be given the wrong column names when a large backlog is being consumed.
+
+### Filtering
+
+The fields `+"`include_tables`"+` and `+"`exclude_tables`"+` are lists of regular expressions that are matched against the fully qualified name of each table in the form `+"`schema.table`"+`. A change is emitted when its table matches any include pattern (or no include patterns are set) and matches none of the exclude patterns.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"``` text"+`
+- mysql_cdc_schema
+- mysql_cdc_table
+- mysql_cdc_operation
+- mysql_cdc_timestamp_unix
+`+"```"+``).
+		Field(service.NewStringField("dsn").
+			Description("A Data Source Name to identify the target server, the database name is ignored.").
+			Example("foouser:foopassword@tcp(localhost:3306)/")).
+		Field(service.NewIntField("server_id").
+			Description("A server ID to identify this input as a replica, which must be unique amongst all replicas of the server.").
+			Default(1001)).
+		Field(service.NewStringEnumField("flavor", gomysql.MySQLFlavor, gomysql.MariaDBFlavor).
+			Description("The flavor of the server.").
+			Advanced().
+			Default(gomysql.MySQLFlavor)).
+		Field(service.NewStringListField("include_tables").
+			Description("An optional list of regular expressions, changes are only emitted for tables that match at least one of them.").
+			Example([]string{`foodb\..*`}).
+			Example([]string{`foodb\.users`, `foodb\.orders`}).
+			Default([]string{})).
+		Field(service.NewStringListField("exclude_tables").
+			Description("An optional list of regular expressions, changes are not emitted for tables that match any of them.").
+			Example([]string{`.*\.tmp_.*`}).
+			Default([]string{})).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("A [cache resource](/docs/components/caches/about) to store the GTID set of acknowledged transactions within.")).
+		Field(service.NewStringField("checkpoint_key").
+			Description("The key to store checkpoints under within the cache.").
+			Advanced().
+			Default("mysql_cdc")).
+		Field(store.IntervalField()).
+		Field(store.FencingField()).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of changes that can be pending acknowledgement before back pressure is applied.").
+			Advanced().
+			Default(1024)).
+		Example("Replicate Tables", `
+Here we stream changes to all tables of a database, excluding the table `+"`audit`"+`, storing checkpoints within a Redis cache:`,
+			`
+input:
+  mysql_cdc:
+    dsn: foouser:foopassword@tcp(localhost:3306)/
+    include_tables: [ 'foodb\..*' ]
+    exclude_tables: [ 'foodb\.audit' ]
+    checkpoint_cache: checkpoints
+
+cache_resources:
+  - label: checkpoints
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mysql_cdc", mysqlCDCInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newMySQLCDCInputFromConfig(conf, mgr.AccessCache, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlChange struct {
+	schema    string
+	table     string
+	op        string
+	before    map[string]interface{}
+	after     map[string]interface{}
+	timestamp uint32
+}
+
+func (c *mysqlChange) structured() map[string]interface{} {
+	obj := map[string]interface{}{
+		"schema":    c.schema,
+		"table":     c.table,
+		"operation": c.op,
+		"before":    nil,
+		"after":     nil,
+	}
+	if c.before != nil {
+		obj["before"] = c.before
+	}
+	if c.after != nil {
+		obj["after"] = c.after
+	}
+	return obj
+}
+
+// mysqlCheckpoint is the payload tracked for each change, where only the last
+// change of a transaction carries the GTID set to commit.
+type mysqlCheckpoint struct {
+	seq  int64
+	gset string
+}
+
+// mysqlTableFilter determines whether changes of a table should be emitted.
+type mysqlTableFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func newMySQLTableFilter(include, exclude []string) (*mysqlTableFilter, error) {
+	f := &mysqlTableFilter{}
+	for _, p := range include {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile include pattern '%v': %w", p, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, p := range exclude {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile exclude pattern '%v': %w", p, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+func (f *mysqlTableFilter) matches(schema, table string) bool {
+	name := schema + "." + table
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mysqlRowToMap zips the values of a row with column names, values of columns
+// without a known name are given the name col_<index>.
+func mysqlRowToMap(columns []string, row []interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		name := "col_" + strconv.Itoa(i)
+		if i < len(columns) {
+			name = columns[i]
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		obj[name] = v
+	}
+	return obj
+}
+
+// mysqlRowsToChanges converts the rows of a rows event into changes. Update
+// events contain pairs of rows, the state before and then after the change.
+func mysqlRowsToChanges(op string, schema, table string, columns []string, rows [][]interface{}) []mysqlChange {
+	var changes []mysqlChange
+	step := 1
+	if op == "update" {
+		step = 2
+	}
+	for i := 0; i+step <= len(rows); i += step {
+		c := mysqlChange{schema: schema, table: table, op: op}
+		switch op {
+		case "insert":
+			c.after = mysqlRowToMap(columns, rows[i])
+		case "delete":
+			c.before = mysqlRowToMap(columns, rows[i])
+		case "update":
+			c.before = mysqlRowToMap(columns, rows[i])
+			c.after = mysqlRowToMap(columns, rows[i+1])
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+var mysqlDDLPrefixes = []string{"ALTER", "CREATE", "DROP", "RENAME", "TRUNCATE"}
+
+func isMySQLDDL(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	for _, p := range mysqlDDLPrefixes {
+		if strings.HasPrefix(query, p) {
+			return true
+		}
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type mysqlCDCInput struct {
+	dsn      *mysqldriver.Config
+	serverID uint32
+	flavor   string
+	filter   *mysqlTableFilter
+	store    *store.Store
+
+	connMut  sync.Mutex
+	db       *sql.DB
+	syncer   *replication.BinlogSyncer
+	streamer *replication.BinlogStreamer
+	columns  map[string][]string
+
+	txn     []mysqlChange
+	pending []mysqlChange
+	gset    string
+
+	checkpointer *checkpoint.Capped
+	unacked      int64
+	seq          int64
+	commitMut    sync.Mutex
+	committedSeq int64
+
+	logger *service.Logger
+}
+
+func newMySQLCDCInputFromConfig(conf *service.ParsedConfig, accessCache store.CacheAccessor, logger *service.Logger) (*mysqlCDCInput, error) {
+	m := &mysqlCDCInput{
+		columns: map[string][]string{},
+		logger:  logger,
+	}
+
+	dsnStr, err := conf.FieldString("dsn")
+	if err != nil {
+		return nil, err
+	}
+	if m.dsn, err = mysqldriver.ParseDSN(dsnStr); err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+	m.dsn.DBName = ""
+
+	serverID, err := conf.FieldInt("server_id")
+	if err != nil {
+		return nil, err
+	}
+	if serverID <= 0 {
+		return nil, errors.New("server_id must be larger than zero")
+	}
+	m.serverID = uint32(serverID)
+
+	if m.flavor, err = conf.FieldString("flavor"); err != nil {
+		return nil, err
+	}
+
+	include, err := conf.FieldStringList("include_tables")
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := conf.FieldStringList("exclude_tables")
+	if err != nil {
+		return nil, err
+	}
+	if m.filter, err = newMySQLTableFilter(include, exclude); err != nil {
+		return nil, err
+	}
+
+	storeConf, err := store.ConfigFromParsed(conf, "checkpoint_cache", "checkpoint_key")
+	if err != nil {
+		return nil, err
+	}
+	if storeConf.Cache == "" {
+		return nil, errors.New("a checkpoint_cache must be specified")
+	}
+	m.store = store.New(storeConf, store.CacheAccessor(accessCache), logger)
+
+	limit, err := conf.FieldInt("checkpoint_limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, errors.New("checkpoint_limit must be larger than zero")
+	}
+	m.checkpointer = checkpoint.NewCapped(int64(limit))
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mysqlCDCInput) readCheckpoint(ctx context.Context) (string, error) {
+	value, _, err := m.store.Read(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func (m *mysqlCDCInput) Connect(ctx context.Context) (err error) {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.syncer != nil {
+		return nil
+	}
+
+	var db *sql.DB
+	if db, err = sql.Open("mysql", m.dsn.FormatDSN()); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = db.Close()
+		}
+	}()
+
+	gsetStr, err := m.readCheckpoint(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if gsetStr == "" {
+		query := "SELECT @@GLOBAL.gtid_executed"
+		if m.flavor == gomysql.MariaDBFlavor {
+			query = "SELECT @@GLOBAL.gtid_current_pos"
+		}
+		if err = db.QueryRowContext(ctx, query).Scan(&gsetStr); err != nil {
+			return fmt.Errorf("failed to obtain current GTID set: %w", err)
+		}
+	}
+	gset, err := gomysql.ParseGTIDSet(m.flavor, gsetStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse GTID set: %w", err)
+	}
+
+	host, portStr, err := net.SplitHostPort(m.dsn.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse address: %w", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("failed to parse port: %w", err)
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:  m.serverID,
+		Flavor:    m.flavor,
+		Host:      host,
+		Port:      uint16(port),
+		User:      m.dsn.User,
+		Password:  m.dsn.Passwd,
+		ParseTime: true,
+	})
+	streamer, err := syncer.StartSyncGTID(gset)
+	if err != nil {
+		syncer.Close()
+		return fmt.Errorf("failed to start binlog sync: %w", err)
+	}
+
+	m.logger.Infof("Streaming binlog from GTID set %v", gsetStr)
+	m.db = db
+	m.syncer = syncer
+	m.streamer = streamer
+	m.columns = map[string][]string{}
+	m.txn, m.pending = nil, nil
+	return nil
+}
+
+func (m *mysqlCDCInput) disconnect() {
+	if m.syncer != nil {
+		m.syncer.Close()
+		m.syncer = nil
+		m.streamer = nil
+	}
+	if m.db != nil {
+		_ = m.db.Close()
+		m.db = nil
+	}
+}
+
+// tableColumns returns the column names of a table, preferring those contained
+// within the binlog. Must be called with the lock held.
+func (m *mysqlCDCInput) tableColumns(ctx context.Context, tableEv *replication.TableMapEvent) ([]string, error) {
+	if len(tableEv.ColumnName) > 0 {
+		columns := make([]string, len(tableEv.ColumnName))
+		for i, c := range tableEv.ColumnName {
+			columns[i] = string(c)
+		}
+		return columns, nil
+	}
+
+	schema, table := string(tableEv.Schema), string(tableEv.Table)
+	key := schema + "." + table
+	if columns, exists := m.columns[key]; exists {
+		return columns, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %v: %w", key, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if uint64(len(columns)) != tableEv.ColumnCount {
+		m.logger.Warnf("Table %v has %v columns within the binlog but %v within the current schema", key, tableEv.ColumnCount, len(columns))
+	}
+	m.columns[key] = columns
+	return columns, nil
+}
+
+// commitEmpty commits a GTID set directly when a transaction without changes of
+// interest is seen and nothing is pending acknowledgement.
+func (m *mysqlCDCInput) commitEmpty(ctx context.Context, gset string) error {
+	if atomic.LoadInt64(&m.unacked) > 0 {
+		return nil
+	}
+	return m.commit(ctx, mysqlCheckpoint{seq: atomic.AddInt64(&m.seq, 1), gset: gset})
+}
+
+func (m *mysqlCDCInput) commit(ctx context.Context, cp mysqlCheckpoint) error {
+	m.commitMut.Lock()
+	defer m.commitMut.Unlock()
+
+	if cp.seq <= m.committedSeq {
+		return nil
+	}
+	if err := m.store.Commit(ctx, []byte(cp.gset)); err != nil {
+		return err
+	}
+	m.committedSeq = cp.seq
+	return nil
+}
+
+// readTransaction reads events from the binlog until the end of a transaction
+// containing changes of interest. Must be called with the lock held.
+func (m *mysqlCDCInput) readTransaction(ctx context.Context) error {
+	for len(m.pending) == 0 {
+		ev, err := m.streamer.GetEvent(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch e := ev.Event.(type) {
+		case *replication.RowsEvent:
+			var op string
+			switch ev.Header.EventType {
+			case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+				op = "insert"
+			case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+				op = "update"
+			case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+				op = "delete"
+			default:
+				continue
+			}
+			schema, table := string(e.Table.Schema), string(e.Table.Table)
+			if !m.filter.matches(schema, table) {
+				continue
+			}
+			columns, err := m.tableColumns(ctx, e.Table)
+			if err != nil {
+				return err
+			}
+			changes := mysqlRowsToChanges(op, schema, table, columns, e.Rows)
+			for i := range changes {
+				changes[i].timestamp = ev.Header.Timestamp
+			}
+			m.txn = append(m.txn, changes...)
+
+		case *replication.XIDEvent:
+			gset := ""
+			if e.GSet != nil {
+				gset = e.GSet.String()
+			}
+			if len(m.txn) == 0 {
+				if gset != "" {
+					if err := m.commitEmpty(ctx, gset); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			m.pending, m.txn = m.txn, nil
+			m.gset = gset
+
+		case *replication.QueryEvent:
+			if !isMySQLDDL(string(e.Query)) {
+				continue
+			}
+			// Column names are read again the next time each table is seen.
+			m.columns = map[string][]string{}
+			if e.GSet != nil {
+				if err := m.commitEmpty(ctx, e.GSet.String()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mysqlCDCInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.streamer == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if err := m.readTransaction(ctx); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, err
+		}
+		m.logger.Errorf("Failed to read binlog: %v", err)
+		m.disconnect()
+		return nil, nil, service.ErrNotConnected
+	}
+
+	change := m.pending[0]
+	m.pending = m.pending[1:]
+
+	var cp mysqlCheckpoint
+	if len(m.pending) == 0 && m.gset != "" {
+		cp = mysqlCheckpoint{seq: atomic.AddInt64(&m.seq, 1), gset: m.gset}
+	}
+	resolveFn, err := m.checkpointer.Track(ctx, cp, 1)
+	if err != nil {
+		m.pending = append([]mysqlChange{change}, m.pending...)
+		return nil, nil, err
+	}
+	atomic.AddInt64(&m.unacked, 1)
+
+	msg := service.NewMessage(nil)
+	msg.SetStructured(change.structured())
+	msg.MetaSet("mysql_cdc_schema", change.schema)
+	msg.MetaSet("mysql_cdc_table", change.table)
+	msg.MetaSet("mysql_cdc_operation", change.op)
+	msg.MetaSet("mysql_cdc_timestamp_unix", strconv.FormatUint(uint64(change.timestamp), 10))
+
+	return msg, func(ctx context.Context, err error) error {
+		if err != nil {
+			// Nacks are handled by AutoRetryNacks.
+			return nil
+		}
+		highest := resolveFn()
+		atomic.AddInt64(&m.unacked, -1)
+		if c, ok := highest.(mysqlCheckpoint); ok && c.gset != "" {
+			return m.commit(ctx, c)
+		}
+		return nil
+	}, nil
+}
+
+func (m *mysqlCDCInput) Close(ctx context.Context) error {
+	m.connMut.Lock()
+	m.disconnect()
+	m.connMut.Unlock()
+	return m.store.Close(ctx)
+}
@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLCDCInputConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"bad dsn": {
+			conf: `
+dsn: "not a dsn"
+checkpoint_cache: foo
+`,
+			errContains: "dsn",
+		},
+		"no cache": {
+			conf: `
+dsn: foo:bar@tcp(localhost:3306)/
+checkpoint_cache: ""
+`,
+			errContains: "checkpoint_cache",
+		},
+		"bad server id": {
+			conf: `
+dsn: foo:bar@tcp(localhost:3306)/
+checkpoint_cache: foo
+server_id: 0
+`,
+			errContains: "server_id",
+		},
+		"bad include pattern": {
+			conf: `
+dsn: foo:bar@tcp(localhost:3306)/
+checkpoint_cache: foo
+include_tables: [ 'foo[' ]
+`,
+			errContains: "include pattern",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pConf, err := mysqlCDCInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newMySQLCDCInputFromConfig(pConf, nil, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestMySQLTableFilter(t *testing.T) {
+	f, err := newMySQLTableFilter([]string{`foodb\..*`, `bardb\.users`}, []string{`foodb\.audit`})
+	require.NoError(t, err)
+
+	assert.True(t, f.matches("foodb", "orders"))
+	assert.True(t, f.matches("bardb", "users"))
+	assert.False(t, f.matches("foodb", "audit"))
+	assert.False(t, f.matches("bardb", "orders"))
+	assert.False(t, f.matches("bazdb", "users"))
+
+	// Patterns are anchored to the entire table name.
+	assert.False(t, f.matches("xbardb", "users"))
+
+	f, err = newMySQLTableFilter(nil, []string{`.*\.tmp_.*`})
+	require.NoError(t, err)
+
+	assert.True(t, f.matches("foodb", "orders"))
+	assert.False(t, f.matches("foodb", "tmp_orders"))
+}
+
+func TestMySQLRowsToChanges(t *testing.T) {
+	columns := []string{"id", "name"}
+
+	changes := mysqlRowsToChanges("insert", "foodb", "users", columns, [][]interface{}{
+		{int32(1), []byte("foo")},
+		{int32(2), "bar"},
+	})
+	assert.Equal(t, []mysqlChange{
+		{schema: "foodb", table: "users", op: "insert", after: map[string]interface{}{"id": int32(1), "name": "foo"}},
+		{schema: "foodb", table: "users", op: "insert", after: map[string]interface{}{"id": int32(2), "name": "bar"}},
+	}, changes)
+
+	changes = mysqlRowsToChanges("update", "foodb", "users", columns, [][]interface{}{
+		{int32(1), "foo"}, {int32(1), "baz"},
+	})
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]interface{}{
+		"schema":    "foodb",
+		"table":     "users",
+		"operation": "update",
+		"before":    map[string]interface{}{"id": int32(1), "name": "foo"},
+		"after":     map[string]interface{}{"id": int32(1), "name": "baz"},
+	}, changes[0].structured())
+
+	changes = mysqlRowsToChanges("delete", "foodb", "users", []string{"id"}, [][]interface{}{
+		{int32(1), "foo"},
+	})
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]interface{}{
+		"schema":    "foodb",
+		"table":     "users",
+		"operation": "delete",
+		"before":    map[string]interface{}{"id": int32(1), "col_1": "foo"},
+		"after":     nil,
+	}, changes[0].structured())
+}
+
+func TestIsMySQLDDL(t *testing.T) {
+	assert.True(t, isMySQLDDL("ALTER TABLE foo ADD COLUMN bar INT"))
+	assert.True(t, isMySQLDDL("  create table foo (id int)"))
+	assert.True(t, isMySQLDDL("DROP TABLE foo"))
+	assert.False(t, isMySQLDDL("BEGIN"))
+	assert.False(t, isMySQLDDL("INSERT INTO foo VALUES (1)"))
+}
@@ -0,0 +1,319 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgCDCInputConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"no slot": {
+			conf: `
+dsn: woof
+slot_name: ""
+`,
+			errContains: "slot_name",
+		},
+		"snapshot without tables": {
+			conf: `
+dsn: woof
+slot_name: foo
+snapshot: true
+`,
+			errContains: "tables must be specified",
+		},
+		"bad batch size": {
+			conf: `
+dsn: woof
+slot_name: foo
+batch_size: 0
+`,
+			errContains: "batch_size",
+		},
+		"pgoutput without publication": {
+			conf: `
+dsn: woof
+slot_name: foo
+plugin: pgoutput
+`,
+			errContains: "a publication must be specified",
+		},
+		"bad plugin": {
+			conf: `
+dsn: woof
+slot_name: foo
+plugin: nope
+`,
+			errContains: "unrecognised plugin",
+		},
+		"bad poll interval": {
+			conf: `
+dsn: woof
+slot_name: foo
+poll_interval: nope
+`,
+			errContains: "poll_interval",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pConf, err := pgCDCInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newPgCDCInputFromConfig(pConf, nil, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestPgCDCInputEmptyShutdown(t *testing.T) {
+	pConf, err := pgCDCInputConfig().ParseYAML(`
+dsn: woof
+slot_name: foo
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newPgCDCInputFromConfig(pConf, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", i.store.Key())
+	require.NoError(t, i.Close(context.Background()))
+}
+
+func TestPgPositionParse(t *testing.T) {
+	pos, err := parsePgPosition("16/B374D848:3")
+	require.NoError(t, err)
+	assert.Equal(t, pgPosition{commit: 0x16B374D848, seq: 3}, pos)
+	assert.Equal(t, "16/B374D848:3", pos.String())
+
+	pos, err = parsePgPosition("0/0:-1")
+	require.NoError(t, err)
+	assert.Equal(t, pgPosition{seq: -1}, pos)
+
+	for _, bad := range []string{"", "16/B374D848", "16:3", "zz/0:1", "0/0:x"} {
+		_, err = parsePgPosition(bad)
+		assert.Error(t, err, bad)
+	}
+
+	assert.True(t, pgPosition{commit: 2}.after(pgPosition{commit: 1, seq: 5}))
+	assert.True(t, pgPosition{commit: 1, seq: 1}.after(pgPosition{commit: 1}))
+	assert.False(t, pgPosition{commit: 1}.after(pgPosition{commit: 1}))
+}
+
+func TestDecodeWal2JSONRows(t *testing.T) {
+	rows := []pgWalRow{
+		{lsn: "0/10", xid: "500", data: `{"action":"B"}`},
+		{lsn: "0/11", xid: "500", data: `{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"a"}]}`},
+		{lsn: "0/12", xid: "500", data: `{"action":"U","schema":"public","table":"foo","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"b"}],"identity":[{"name":"id","type":"integer","value":1}]}`},
+		{lsn: "0/20", xid: "500", data: `{"action":"C"}`},
+		{lsn: "0/21", xid: "501", data: `{"action":"B"}`},
+		{lsn: "0/22", xid: "501", data: `{"action":"M","transactional":true,"prefix":"foo","content":"bar"}`},
+		{lsn: "0/23", xid: "501", data: `{"action":"D","schema":"public","table":"foo","identity":[{"name":"id","type":"integer","value":1}]}`},
+		{lsn: "0/24", xid: "501", data: `{"action":"T","schema":"public","table":"bar"}`},
+		{lsn: "0/30", xid: "501", data: `{"action":"C"}`},
+		{lsn: "0/31", xid: "502", data: `{"action":"B"}`},
+		{lsn: "0/32", xid: "502", data: `{"action":"I","schema":"public","table":"foo","columns":[]}`},
+	}
+
+	changes, lastCommit, err := decodeWal2JSONRows(rows, pgPosition{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x30), lastCommit)
+	assert.Equal(t, []pgChange{
+		{
+			pos: pgPosition{commit: 0x20, seq: 0}, xid: "500", schema: "public", table: "foo", op: "insert",
+			columns: map[string]interface{}{"id": float64(1), "name": "a"},
+		},
+		{
+			pos: pgPosition{commit: 0x20, seq: 1}, final: true, xid: "500", schema: "public", table: "foo", op: "update",
+			columns: map[string]interface{}{"id": float64(1), "name": "b"},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 0}, xid: "501", schema: "public", table: "foo", op: "delete",
+			columns: map[string]interface{}{"id": float64(1)},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 1}, final: true, xid: "501", schema: "public", table: "bar", op: "truncate",
+			columns: map[string]interface{}{},
+		},
+	}, changes)
+
+	changes, _, err = decodeWal2JSONRows(rows, pgPosition{commit: 0x20, seq: 1})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "delete", changes[0].op)
+
+	changes, _, err = decodeWal2JSONRows(rows, pgPosition{commit: 0x30, seq: 0})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "truncate", changes[0].op)
+
+	_, _, err = decodeWal2JSONRows([]pgWalRow{{lsn: "0/1", data: `not json`}}, pgPosition{})
+	require.Error(t, err)
+}
+
+type pgOutputBuilder struct {
+	bytes.Buffer
+}
+
+func (b *pgOutputBuilder) uint8(v uint8) *pgOutputBuilder {
+	b.WriteByte(v)
+	return b
+}
+
+func (b *pgOutputBuilder) uint16(v uint16) *pgOutputBuilder {
+	_ = binary.Write(b, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgOutputBuilder) uint32(v uint32) *pgOutputBuilder {
+	_ = binary.Write(b, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgOutputBuilder) string(v string) *pgOutputBuilder {
+	b.WriteString(v)
+	b.WriteByte(0)
+	return b
+}
+
+// tuple writes text values, where nil values are written as nulls and "-"
+// values as unchanged TOAST values.
+func (b *pgOutputBuilder) tuple(values ...interface{}) *pgOutputBuilder {
+	b.uint16(uint16(len(values)))
+	for _, v := range values {
+		switch v {
+		case nil:
+			b.uint8('n')
+		case "-":
+			b.uint8('u')
+		default:
+			s := v.(string)
+			b.uint8('t').uint32(uint32(len(s)))
+			b.WriteString(s)
+		}
+	}
+	return b
+}
+
+func (b *pgOutputBuilder) row(lsn, xid string) pgWalRow {
+	return pgWalRow{lsn: lsn, xid: xid, data: b.String()}
+}
+
+func newPgOutputMsg(kind byte) *pgOutputBuilder {
+	b := &pgOutputBuilder{}
+	return b.uint8(kind)
+}
+
+func TestDecodePgOutputRows(t *testing.T) {
+	fooRel := newPgOutputMsg('R').uint32(1).string("public").string("foo").uint8('d').uint16(3).
+		uint8(1).string("id").uint32(23).uint32(0xFFFFFFFF).
+		uint8(0).string("name").uint32(25).uint32(0xFFFFFFFF).
+		uint8(0).string("doc").uint32(3802).uint32(0xFFFFFFFF)
+	barRel := newPgOutputMsg('R').uint32(2).string("public").string("bar").uint8('d').uint16(1).
+		uint8(1).string("id").uint32(20).uint32(0xFFFFFFFF)
+
+	rows := []pgWalRow{
+		newPgOutputMsg('B').row("0/10", "500"),
+		fooRel.row("0/11", "500"),
+		newPgOutputMsg('I').uint32(1).uint8('N').tuple("1", "a", `{"a":[1,2]}`).row("0/11", "500"),
+		newPgOutputMsg('U').uint32(1).uint8('N').tuple("1", "b", "-").row("0/12", "500"),
+		newPgOutputMsg('C').uint8(0).row("0/20", "500"),
+		newPgOutputMsg('B').row("0/21", "501"),
+		newPgOutputMsg('O').row("0/21", "501"),
+		barRel.row("0/22", "501"),
+		newPgOutputMsg('U').uint32(1).uint8('K').tuple("1", nil, nil).uint8('N').tuple("2", nil, nil).row("0/22", "501"),
+		newPgOutputMsg('D').uint32(1).uint8('K').tuple("2", nil, nil).row("0/23", "501"),
+		newPgOutputMsg('T').uint32(2).uint8(0).uint32(1).uint32(2).row("0/24", "501"),
+		newPgOutputMsg('C').uint8(0).row("0/30", "501"),
+		newPgOutputMsg('B').row("0/31", "502"),
+		newPgOutputMsg('I').uint32(2).uint8('N').tuple("5").row("0/32", "502"),
+	}
+
+	changes, lastCommit, err := newPgOutputDecoder(nil).decodeRows(rows, pgPosition{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x30), lastCommit)
+	assert.Equal(t, []pgChange{
+		{
+			pos: pgPosition{commit: 0x20, seq: 0}, xid: "500", schema: "public", table: "foo", op: "insert",
+			columns: map[string]interface{}{"id": int64(1), "name": "a", "doc": map[string]interface{}{"a": []interface{}{float64(1), float64(2)}}},
+		},
+		{
+			pos: pgPosition{commit: 0x20, seq: 1}, final: true, xid: "500", schema: "public", table: "foo", op: "update",
+			columns: map[string]interface{}{"id": int64(1), "name": "b"},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 0}, xid: "501", schema: "public", table: "foo", op: "update",
+			columns: map[string]interface{}{"id": int64(2), "name": nil, "doc": nil},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 1}, xid: "501", schema: "public", table: "foo", op: "delete",
+			columns: map[string]interface{}{"id": int64(2)},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 2}, xid: "501", schema: "public", table: "foo", op: "truncate",
+			columns: map[string]interface{}{},
+		},
+		{
+			pos: pgPosition{commit: 0x30, seq: 3}, final: true, xid: "501", schema: "public", table: "bar", op: "truncate",
+			columns: map[string]interface{}{},
+		},
+	}, changes)
+
+	changes, _, err = newPgOutputDecoder([]string{"bar"}).decodeRows(rows, pgPosition{commit: 0x20, seq: 1})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "bar", changes[0].table)
+	assert.True(t, changes[0].final)
+
+	_, _, err = newPgOutputDecoder(nil).decodeRows([]pgWalRow{
+		newPgOutputMsg('B').row("0/1", "1"),
+		newPgOutputMsg('I').uint32(7).uint8('N').tuple("1").row("0/2", "1"),
+	}, pgPosition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown relation 7")
+
+	_, _, err = newPgOutputDecoder(nil).decodeRows([]pgWalRow{
+		fooRel.row("0/1", "1"),
+		newPgOutputMsg('I').uint32(1).uint8('N').uint16(1).row("0/2", "1"),
+	}, pgPosition{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected end of message")
+}
+
+func TestPgOutputValue(t *testing.T) {
+	assert.Equal(t, true, pgOutputValue(16, "t"))
+	assert.Equal(t, false, pgOutputValue(16, "f"))
+	assert.Equal(t, int64(-3), pgOutputValue(21, "-3"))
+	assert.Equal(t, 1.5, pgOutputValue(701, "1.5"))
+	assert.Equal(t, json.Number("12.50"), pgOutputValue(1700, "12.50"))
+	assert.Equal(t, "NaN", pgOutputValue(1700, "NaN"))
+	assert.Equal(t, "foo", pgOutputValue(25, "foo"))
+	assert.Equal(t, "{nope", pgOutputValue(114, "{nope"))
+}
+
+func TestQuoteTable(t *testing.T) {
+	assert.Equal(t, `"public"."foo"`, quoteTable("public.foo"))
+	assert.Equal(t, `"foo"`, quoteTable("foo"))
+
+	schema, table := splitTable("foo")
+	assert.Equal(t, "public", schema)
+	assert.Equal(t, "foo", table)
+
+	schema, table = splitTable("bar.foo")
+	assert.Equal(t, "bar", schema)
+	assert.Equal(t, "foo", table)
+}
+
+var _ service.Input = &pgCDCInput{}
@@ -1,11 +1,18 @@
 package sql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/checkpoint/store"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
 	"github.com/Jeffail/benthos/v3/public/service"
@@ -17,7 +24,13 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 		// Stable(). TODO
 		Categories("Services").
 		Summary("Executes a select query and creates a message for each row received.").
-		Description(`Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).`).
+		Description(`Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+### Watermarks
+
+When the field `+"`watermark_column`"+` is set this input instead continuously selects rows where the value of the column is greater than the highest value read so far. Rows are selected in pages of `+"`page_size`"+` ordered by the watermark column, and once a page returns fewer rows than the page size the input waits for `+"`poll_interval`"+` before selecting again. The column should therefore increase monotonically as rows are inserted or updated, such as an auto incrementing ID or a last modified timestamp, and values should be unique as rows that share the value of the last row of a page are not read.
+
+When `+"`watermark_cache`"+` is set the highest value of the column of all acknowledged rows is stored within the [cache resource](/docs/components/caches/about) under the key `+"`watermark_key`"+`, and when this input starts it resumes from the stored value. The watermark can be written periodically rather than after every acknowledgement by setting `+"`checkpoint_interval`"+`, and `+"`checkpoint_fencing`"+` can be enabled in order to prevent a stale instance of this input from overwriting the watermark of a newer one.`).
 		Field(driverField).
 		Field(dsnField).
 		Field(service.NewStringField("table").
@@ -44,6 +57,38 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 			Description("An optional suffix to append to the select query.").
 			Optional().
 			Advanced()).
+		Field(service.NewStringField("watermark_column").
+			Description("An optional column to track the highest value of, which causes rows to be selected continuously. The column must be included in the selected columns.").
+			Example("id").
+			Example("updated_at").
+			Version("3.64.0").
+			Optional()).
+		Field(service.NewStringField("watermark_cache").
+			Description("An optional [cache resource](/docs/components/caches/about) to store the watermark of acknowledged rows within. When empty the watermark is only held in memory.").
+			Version("3.64.0").
+			Default("")).
+		Field(service.NewStringField("watermark_key").
+			Description("The key to store the watermark under within the cache. When empty the name of the table is used.").
+			Version("3.64.0").
+			Advanced().
+			Default("")).
+		Field(store.IntervalField()).
+		Field(store.FencingField()).
+		Field(service.NewIntField("page_size").
+			Description("The maximum number of rows to select at a time when a watermark column is set. When zero all rows above the watermark are selected at once.").
+			Version("3.64.0").
+			Advanced().
+			Default(1000)).
+		Field(service.NewStringField("poll_interval").
+			Description("The period of time to wait before selecting rows again once a page of rows is not full.").
+			Version("3.64.0").
+			Advanced().
+			Default("5s")).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of rows that can be pending acknowledgement at a time when a watermark column is set.").
+			Version("3.64.0").
+			Advanced().
+			Default(1024)).
 		Version("3.59.0").
 		Example("Consume a Table (PostgreSQL)",
 			`
@@ -60,6 +105,25 @@ input:
       root = [
         now().format_timestamp_unix() - 3600
       ]
+`,
+		).
+		Example("Continuously Consume New Rows (MySQL)",
+			`
+Here we continuously consume rows as they are added to a table with an auto incrementing column "id", storing the highest ID of acknowledged rows within a Redis cache:`,
+			`
+input:
+  sql_select:
+    driver: mysql
+    dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+    table: footable
+    columns: [ '*' ]
+    watermark_column: id
+    watermark_cache: watermarks
+
+cache_resources:
+  - label: watermarks
+    redis:
+      url: tcp://localhost:6379
 `,
 		)
 }
@@ -68,7 +132,7 @@ func init() {
 	err := service.RegisterInput(
 		"sql_select", sqlSelectInputConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-			i, err := newSQLSelectInputFromConfig(conf, mgr.Logger())
+			i, err := newSQLSelectInputFromConfig(conf, mgr.AccessCache, mgr.Logger())
 			if err != nil {
 				return nil, err
 			}
@@ -82,22 +146,42 @@ func init() {
 
 //------------------------------------------------------------------------------
 
+type sqlWatermark struct {
+	seq   int64
+	value interface{}
+}
+
 type sqlSelectInput struct {
 	driver  string
 	dsn     string
 	db      *sql.DB
 	rows    *sql.Rows
 	builder squirrel.SelectBuilder
+	suffix  string
 	dbMut   sync.Mutex
 
 	where       string
 	argsMapping *bloblang.Executor
 
+	watermarkColumn string
+	store           *store.Store
+	pageSize        int
+	pollInterval    time.Duration
+
+	watermark    interface{}
+	pageRows     int
+	pollWait     bool
+	seq          int64
+	checkpointer *checkpoint.Capped
+
+	commitMut    sync.Mutex
+	committedSeq int64
+
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
 }
 
-func newSQLSelectInputFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*sqlSelectInput, error) {
+func newSQLSelectInputFromConfig(conf *service.ParsedConfig, accessCache store.CacheAccessor, logger *service.Logger) (*sqlSelectInput, error) {
 	s := &sqlSelectInput{
 		logger:  logger,
 		shutSig: shutdown.NewSignaller(),
@@ -149,16 +233,149 @@ func newSQLSelectInputFromConfig(conf *service.ParsedConfig, logger *service.Log
 	}
 
 	if conf.Contains("suffix") {
-		suffixStr, err := conf.FieldString("suffix")
-		if err != nil {
+		if s.suffix, err = conf.FieldString("suffix"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("watermark_column") {
+		if s.watermarkColumn, err = conf.FieldString("watermark_column"); err != nil {
 			return nil, err
 		}
-		s.builder = s.builder.Suffix(suffixStr)
+	}
+	storeConf, err := store.ConfigFromParsed(conf, "watermark_cache", "watermark_key")
+	if err != nil {
+		return nil, err
+	}
+	if storeConf.Key == "" {
+		storeConf.Key = tableStr
+	}
+	s.store = store.New(storeConf, store.CacheAccessor(accessCache), logger)
+	if s.pageSize, err = conf.FieldInt("page_size"); err != nil {
+		return nil, err
+	}
+	if s.pageSize < 0 {
+		return nil, errors.New("page_size must not be negative")
+	}
+
+	pollStr, err := conf.FieldString("poll_interval")
+	if err != nil {
+		return nil, err
+	}
+	if s.pollInterval, err = time.ParseDuration(pollStr); err != nil {
+		return nil, fmt.Errorf("failed to parse poll_interval: %w", err)
 	}
 
+	limit, err := conf.FieldInt("checkpoint_limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, errors.New("checkpoint_limit must be larger than zero")
+	}
+	s.checkpointer = checkpoint.NewCapped(int64(limit))
+
 	return s, nil
 }
 
+//------------------------------------------------------------------------------
+
+// buildQuery returns the select query to execute with the current watermark.
+func (s *sqlSelectInput) buildQuery() (squirrel.SelectBuilder, error) {
+	queryBuilder := s.builder
+	if s.where != "" {
+		var args []interface{}
+		if s.argsMapping != nil {
+			iargs, err := s.argsMapping.Query(nil)
+			if err != nil {
+				return queryBuilder, err
+			}
+
+			var ok bool
+			if args, ok = iargs.([]interface{}); !ok {
+				return queryBuilder, fmt.Errorf("mapping returned non-array result: %T", iargs)
+			}
+		}
+		queryBuilder = queryBuilder.Where(s.where, args...)
+	}
+
+	if s.watermarkColumn != "" {
+		if s.watermark != nil {
+			queryBuilder = queryBuilder.Where(squirrel.Gt{s.watermarkColumn: s.watermark})
+		}
+		queryBuilder = queryBuilder.OrderBy(s.watermarkColumn)
+		if s.pageSize > 0 {
+			if s.driver == "mssql" {
+				queryBuilder = queryBuilder.Suffix(fmt.Sprintf("OFFSET 0 ROWS FETCH NEXT %v ROWS ONLY", s.pageSize))
+			} else {
+				queryBuilder = queryBuilder.Limit(uint64(s.pageSize))
+			}
+		}
+	}
+
+	if s.suffix != "" {
+		queryBuilder = queryBuilder.Suffix(s.suffix)
+	}
+	return queryBuilder, nil
+}
+
+func (s *sqlSelectInput) readWatermark(ctx context.Context) (value interface{}, err error) {
+	raw, exists, err := s.store.Read(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return sqlDecodeWatermark(raw)
+}
+
+func (s *sqlSelectInput) commit(ctx context.Context, wm sqlWatermark) error {
+	if !s.store.Enabled() {
+		return nil
+	}
+
+	s.commitMut.Lock()
+	defer s.commitMut.Unlock()
+
+	if wm.seq <= s.committedSeq {
+		return nil
+	}
+	raw, err := json.Marshal(wm.value)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Commit(ctx, raw); err != nil {
+		return err
+	}
+	s.committedSeq = wm.seq
+	return nil
+}
+
+// sqlDecodeWatermark parses a watermark stored as JSON back into a value that
+// can be used as a query argument, where numbers are parsed as integers when
+// possible and strings that are timestamps are parsed as times.
+func sqlDecodeWatermark(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to parse watermark: %w", err)
+	}
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return ts, nil
+		}
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
 func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	s.dbMut.Lock()
 	defer s.dbMut.Unlock()
@@ -177,26 +394,15 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 		}
 	}()
 
-	var args []interface{}
-	if s.argsMapping != nil {
-		var iargs interface{}
-		if iargs, err = s.argsMapping.Query(nil); err != nil {
-			return err
-		}
-
-		var ok bool
-		if args, ok = iargs.([]interface{}); !ok {
-			err = fmt.Errorf("mapping returned non-array result: %T", iargs)
+	if s.watermarkColumn != "" {
+		if s.watermark, err = s.readWatermark(ctx); err != nil {
+			err = fmt.Errorf("failed to read watermark: %w", err)
 			return
 		}
 	}
 
-	queryBuilder := s.builder
-	if s.where != "" {
-		queryBuilder = queryBuilder.Where(s.where, args...)
-	}
 	var rows *sql.Rows
-	if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
+	if rows, err = s.query(db); err != nil {
 		return
 	}
 
@@ -221,6 +427,36 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	return nil
 }
 
+func (s *sqlSelectInput) query(db *sql.DB) (*sql.Rows, error) {
+	queryBuilder, err := s.buildQuery()
+	if err != nil {
+		return nil, err
+	}
+	s.pageRows = 0
+	return queryBuilder.RunWith(db).Query()
+}
+
+// nextPage selects the next page of rows above the watermark, waiting for the
+// poll interval when the previous page was not full. Must be called with the
+// lock held.
+func (s *sqlSelectInput) nextPage(ctx context.Context) error {
+	if s.pollWait {
+		select {
+		case <-time.After(s.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.shutSig.CloseAtLeisureChan():
+			return service.ErrEndOfInput
+		}
+	}
+	rows, err := s.query(s.db)
+	if err != nil {
+		return err
+	}
+	s.rows = rows
+	return nil
+}
+
 func (s *sqlSelectInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
 	s.dbMut.Lock()
 	defer s.dbMut.Unlock()
@@ -229,18 +465,28 @@ func (s *sqlSelectInput) Read(ctx context.Context) (*service.Message, service.Ac
 		return nil, nil, service.ErrNotConnected
 	}
 
-	if s.rows == nil {
-		return nil, nil, service.ErrEndOfInput
-	}
-
-	if !s.rows.Next() {
-		err := s.rows.Err()
-		if err == nil {
-			err = service.ErrEndOfInput
+	for {
+		if s.rows == nil {
+			if s.watermarkColumn == "" || s.shutSig.ShouldCloseAtLeisure() {
+				return nil, nil, service.ErrEndOfInput
+			}
+			if err := s.nextPage(ctx); err != nil {
+				return nil, nil, err
+			}
 		}
+		if s.rows.Next() {
+			break
+		}
+		err := s.rows.Err()
 		_ = s.rows.Close()
 		s.rows = nil
-		return nil, nil, err
+		if err != nil {
+			return nil, nil, err
+		}
+		if s.watermarkColumn == "" {
+			return nil, nil, service.ErrEndOfInput
+		}
+		s.pollWait = s.pageSize == 0 || s.pageRows < s.pageSize
 	}
 
 	obj, err := sqlRowToMap(s.rows)
@@ -252,9 +498,39 @@ func (s *sqlSelectInput) Read(ctx context.Context) (*service.Message, service.Ac
 
 	msg := service.NewMessage(nil)
 	msg.SetStructured(obj)
+
+	if s.watermarkColumn == "" {
+		return msg, func(ctx context.Context, err error) error {
+			// Nacks are handled by AutoRetryNacks because we don't have an explicit
+			// ack mechanism right now.
+			return nil
+		}, nil
+	}
+
+	value, exists := obj[s.watermarkColumn]
+	if !exists || value == nil {
+		_ = s.rows.Close()
+		s.rows = nil
+		return nil, nil, fmt.Errorf("watermark column %v not found within row", s.watermarkColumn)
+	}
+
+	resolveFn, err := s.checkpointer.Track(ctx, sqlWatermark{seq: atomic.AddInt64(&s.seq, 1), value: value}, 1)
+	if err != nil {
+		_ = s.rows.Close()
+		s.rows = nil
+		return nil, nil, err
+	}
+	s.watermark = value
+	s.pageRows++
+
 	return msg, func(ctx context.Context, err error) error {
-		// Nacks are handled by AutoRetryNacks because we don't have an explicit
-		// ack mechanism right now.
+		if err != nil {
+			// Nacks are handled by AutoRetryNacks.
+			return nil
+		}
+		if wm, ok := resolveFn().(sqlWatermark); ok {
+			return s.commit(ctx, wm)
+		}
 		return nil
 	}, nil
 }
@@ -264,13 +540,12 @@ func (s *sqlSelectInput) Close(ctx context.Context) error {
 	s.dbMut.Lock()
 	isNil := s.db == nil
 	s.dbMut.Unlock()
-	if isNil {
-		return nil
-	}
-	select {
-	case <-s.shutSig.HasClosedChan():
-	case <-ctx.Done():
-		return ctx.Err()
+	if !isNil {
+		select {
+		case <-s.shutSig.HasClosedChan():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	return nil
+	return s.store.Close(ctx)
 }
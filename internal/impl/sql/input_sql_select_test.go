@@ -3,8 +3,10 @@ package sql
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,7 +26,179 @@ args_mapping: 'root = [ this.id ]'
 	selectConfig, err := spec.ParseYAML(conf, env)
 	require.NoError(t, err)
 
-	selectInput, err := newSQLSelectInputFromConfig(selectConfig, nil)
+	selectInput, err := newSQLSelectInputFromConfig(selectConfig, nil, nil)
 	require.NoError(t, err)
 	require.NoError(t, selectInput.Close(context.Background()))
 }
+
+func TestSQLSelectInputWatermarkQuery(t *testing.T) {
+	tests := map[string]struct {
+		conf      string
+		watermark interface{}
+		query     string
+		args      []interface{}
+	}{
+		"no watermark": {
+			conf: `
+driver: mysql
+dsn: woof
+table: quack
+columns: [ id, name ]
+watermark_column: id
+`,
+			query: "SELECT id, name FROM quack ORDER BY id LIMIT 1000",
+		},
+		"mysql": {
+			conf: `
+driver: mysql
+dsn: woof
+table: quack
+columns: [ id, name ]
+where: name = ?
+args_mapping: 'root = [ "foo" ]'
+suffix: FOR UPDATE
+watermark_column: id
+page_size: 10
+`,
+			watermark: int64(5),
+			query:     "SELECT id, name FROM quack WHERE name = ? AND id > ? ORDER BY id LIMIT 10 FOR UPDATE",
+			args:      []interface{}{"foo", int64(5)},
+		},
+		"postgres": {
+			conf: `
+driver: postgres
+dsn: woof
+table: quack
+columns: [ '*' ]
+watermark_column: updated_at
+page_size: 0
+`,
+			watermark: "2021-10-01",
+			query:     "SELECT * FROM quack WHERE updated_at > $1 ORDER BY updated_at",
+			args:      []interface{}{"2021-10-01"},
+		},
+		"mssql": {
+			conf: `
+driver: mssql
+dsn: woof
+table: quack
+columns: [ id ]
+watermark_column: id
+page_size: 5
+`,
+			watermark: int64(1),
+			query:     "SELECT id FROM quack WHERE id > ? ORDER BY id OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY",
+			args:      []interface{}{int64(1)},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			selectConfig, err := sqlSelectInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			selectInput, err := newSQLSelectInputFromConfig(selectConfig, nil, nil)
+			require.NoError(t, err)
+
+			selectInput.watermark = test.watermark
+			builder, err := selectInput.buildQuery()
+			require.NoError(t, err)
+
+			query, args, err := builder.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.query, query)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestSQLDecodeWatermark(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output interface{}
+	}{
+		"integer": {
+			input:  `9007199254740993`,
+			output: int64(9007199254740993),
+		},
+		"float": {
+			input:  `1.5`,
+			output: 1.5,
+		},
+		"timestamp": {
+			input:  `"2021-10-01T12:30:00.5Z"`,
+			output: time.Date(2021, 10, 1, 12, 30, 0, 500000000, time.UTC),
+		},
+		"string": {
+			input:  `"2021-10-01 12:30:00"`,
+			output: "2021-10-01 12:30:00",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			v, err := sqlDecodeWatermark([]byte(test.input))
+			require.NoError(t, err)
+			assert.Equal(t, test.output, v)
+		})
+	}
+
+	_, err := sqlDecodeWatermark([]byte(`nope`))
+	require.Error(t, err)
+}
+
+type sqlTestCache struct {
+	service.Cache
+	items map[string][]byte
+}
+
+func (c *sqlTestCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, exists := c.items[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *sqlTestCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.items[key] = value
+	return nil
+}
+
+func TestSQLSelectInputWatermarkCommit(t *testing.T) {
+	cache := &sqlTestCache{items: map[string][]byte{}}
+	accessCache := func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		require.Equal(t, "foocache", name)
+		fn(cache)
+		return nil
+	}
+
+	selectConfig, err := sqlSelectInputConfig().ParseYAML(`
+driver: mysql
+dsn: woof
+table: quack
+columns: [ id ]
+watermark_column: id
+watermark_cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	selectInput, err := newSQLSelectInputFromConfig(selectConfig, accessCache, nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	v, err := selectInput.readWatermark(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	require.NoError(t, selectInput.commit(ctx, sqlWatermark{seq: 2, value: int64(20)}))
+	require.NoError(t, selectInput.commit(ctx, sqlWatermark{seq: 1, value: int64(10)}))
+	assert.Equal(t, "20", string(cache.items["quack"]))
+
+	v, err = selectInput.readWatermark(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), v)
+}
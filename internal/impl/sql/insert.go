@@ -0,0 +1,377 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/Masterminds/squirrel"
+)
+
+var transactionField = service.NewBoolField("transaction").
+	Description("Whether to insert the rows of each batch within a transaction, where either all rows of the batch are inserted or none of them are. When disabled the rows that fail to be inserted are identified individually without failing the remaining rows of the batch.").
+	Version("3.64.0").
+	Advanced().
+	Default(false)
+
+var upsertField = service.NewObjectField("upsert",
+	service.NewStringListField("key_columns").
+		Description("A list of columns that uniquely identify a row. When any columns are listed rows that conflict with an existing row are updated instead of inserted.").
+		Example([]string{"id"}).
+		Default([]string{}),
+	service.NewStringListField("update_columns").
+		Description("A list of columns to update when a row already exists. When empty all columns that are not key columns are updated.").
+		Default([]string{}),
+).Description("Optionally update rows that already exist. Upserts are supported by the `postgres` (`INSERT ... ON CONFLICT`), `mysql` (`INSERT ... ON DUPLICATE KEY UPDATE`) and `mssql` (`MERGE`) drivers. The `mysql` driver determines conflicts from the unique indexes of the table rather than the key columns.").
+	Version("3.64.0").
+	Advanced()
+
+// sqlInsertBuilder builds and executes the statements that insert the rows of
+// a message batch into a table.
+type sqlInsertBuilder struct {
+	driver  string
+	table   string
+	columns []string
+	prefix  string
+	suffix  string
+
+	keyColumns    []string
+	updateColumns []string
+
+	// Whether the rows of a batch are inserted within a transaction where any
+	// failed row rolls back the batch.
+	transaction bool
+
+	// Whether the driver only supports inserts with a prepared statement
+	// executed for each row within a transaction.
+	useTxStmt bool
+}
+
+func sqlInsertBuilderFromConfig(conf *service.ParsedConfig) (*sqlInsertBuilder, error) {
+	b := &sqlInsertBuilder{}
+
+	var err error
+	if b.driver, err = conf.FieldString("driver"); err != nil {
+		return nil, err
+	}
+	if _, in := map[string]struct{}{
+		"clickhouse": {},
+	}[b.driver]; in {
+		b.useTxStmt = true
+	}
+
+	if b.table, err = conf.FieldString("table"); err != nil {
+		return nil, err
+	}
+	if b.columns, err = conf.FieldStringList("columns"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("prefix") {
+		if b.prefix, err = conf.FieldString("prefix"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("suffix") {
+		if b.suffix, err = conf.FieldString("suffix"); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.transaction, err = conf.FieldBool("transaction"); err != nil {
+		return nil, err
+	}
+
+	upsertConf := conf.Namespace("upsert")
+	if b.keyColumns, err = upsertConf.FieldStringList("key_columns"); err != nil {
+		return nil, err
+	}
+	if b.updateColumns, err = upsertConf.FieldStringList("update_columns"); err != nil {
+		return nil, err
+	}
+	if len(b.keyColumns) == 0 {
+		if len(b.updateColumns) > 0 {
+			return nil, errors.New("upsert key_columns must be specified in order to use update_columns")
+		}
+		return b, nil
+	}
+
+	switch b.driver {
+	case "postgres", "mysql", "mssql":
+	default:
+		return nil, fmt.Errorf("upserts are not supported by the %v driver", b.driver)
+	}
+
+	columnSet := map[string]struct{}{}
+	for _, c := range b.columns {
+		columnSet[c] = struct{}{}
+	}
+	keySet := map[string]struct{}{}
+	for _, k := range b.keyColumns {
+		if _, exists := columnSet[k]; !exists {
+			return nil, fmt.Errorf("upsert key column %v is not one of the inserted columns", k)
+		}
+		keySet[k] = struct{}{}
+	}
+	for _, u := range b.updateColumns {
+		if _, exists := columnSet[u]; !exists {
+			return nil, fmt.Errorf("upsert update column %v is not one of the inserted columns", u)
+		}
+	}
+	if len(b.updateColumns) == 0 {
+		for _, c := range b.columns {
+			if _, isKey := keySet[c]; !isKey {
+				b.updateColumns = append(b.updateColumns, c)
+			}
+		}
+	}
+	return b, nil
+}
+
+// query returns an insert statement for the provided rows of arguments.
+func (b *sqlInsertBuilder) query(rows [][]interface{}) (string, []interface{}, error) {
+	if b.driver == "mssql" && len(b.keyColumns) > 0 {
+		return b.mergeQuery(rows)
+	}
+
+	builder := squirrel.Insert(b.table).Columns(b.columns...)
+	if b.driver == "postgres" {
+		builder = builder.PlaceholderFormat(squirrel.Dollar)
+	}
+	if b.prefix != "" {
+		builder = builder.Prefix(b.prefix)
+	}
+	for _, row := range rows {
+		builder = builder.Values(row...)
+	}
+	if len(b.keyColumns) > 0 {
+		builder = builder.Suffix(b.upsertClause())
+	}
+	if b.suffix != "" {
+		builder = builder.Suffix(b.suffix)
+	}
+	return builder.ToSql()
+}
+
+func (b *sqlInsertBuilder) upsertClause() string {
+	var sets []string
+	switch b.driver {
+	case "postgres":
+		if len(b.updateColumns) == 0 {
+			return fmt.Sprintf("ON CONFLICT (%v) DO NOTHING", strings.Join(b.keyColumns, ", "))
+		}
+		for _, c := range b.updateColumns {
+			sets = append(sets, fmt.Sprintf("%v = EXCLUDED.%v", c, c))
+		}
+		return fmt.Sprintf("ON CONFLICT (%v) DO UPDATE SET %v", strings.Join(b.keyColumns, ", "), strings.Join(sets, ", "))
+	default:
+		updateColumns := b.updateColumns
+		if len(updateColumns) == 0 {
+			// A row that already exists is left unchanged.
+			updateColumns = b.keyColumns[:1]
+		}
+		for _, c := range updateColumns {
+			sets = append(sets, fmt.Sprintf("%v = VALUES(%v)", c, c))
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	}
+}
+
+// mergeQuery returns a MERGE statement that upserts the provided rows, which is
+// used for drivers that do not support an upsert clause within inserts.
+func (b *sqlInsertBuilder) mergeQuery(rows [][]interface{}) (string, []interface{}, error) {
+	var query strings.Builder
+	var args []interface{}
+
+	if b.prefix != "" {
+		query.WriteString(b.prefix)
+		query.WriteString(" ")
+	}
+	fmt.Fprintf(&query, "MERGE INTO %v AS target USING (VALUES ", b.table)
+	for i, row := range rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("row has %v values, expected %v", len(row), len(b.columns))
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		query.WriteString(strings.TrimSuffix(strings.Repeat("?, ", len(row)), ", "))
+		query.WriteString(")")
+		args = append(args, row...)
+	}
+	fmt.Fprintf(&query, ") AS source (%v) ON ", strings.Join(b.columns, ", "))
+
+	conds := make([]string, len(b.keyColumns))
+	for i, k := range b.keyColumns {
+		conds[i] = fmt.Sprintf("target.%v = source.%v", k, k)
+	}
+	query.WriteString(strings.Join(conds, " AND "))
+
+	if len(b.updateColumns) > 0 {
+		sets := make([]string, len(b.updateColumns))
+		for i, c := range b.updateColumns {
+			sets[i] = fmt.Sprintf("target.%v = source.%v", c, c)
+		}
+		query.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		query.WriteString(strings.Join(sets, ", "))
+	}
+
+	sources := make([]string, len(b.columns))
+	for i, c := range b.columns {
+		sources[i] = "source." + c
+	}
+	fmt.Fprintf(&query, " WHEN NOT MATCHED THEN INSERT (%v) VALUES (%v)", strings.Join(b.columns, ", "), strings.Join(sources, ", "))
+
+	if b.suffix != "" {
+		query.WriteString(" ")
+		query.WriteString(b.suffix)
+	}
+	query.WriteString(";")
+	return query.String(), args, nil
+}
+
+// exec inserts the rows of a batch, returning a slice containing an error for
+// each message that failed to be inserted. A non-nil error is returned when the
+// batch failed as a whole.
+func (b *sqlInsertBuilder) exec(ctx context.Context, db *sql.DB, batch service.MessageBatch, argsMapping *bloblang.Executor) ([]error, error) {
+	rowErrs := make([]error, len(batch))
+	rows := make([][]interface{}, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+
+	for i := range batch {
+		args, err := sqlArgsFromMapping(batch, i, argsMapping)
+		if err != nil {
+			if b.transaction {
+				return nil, err
+			}
+			rowErrs[i] = err
+			continue
+		}
+		rows = append(rows, args)
+		indexes = append(indexes, i)
+	}
+	if len(rows) == 0 {
+		return rowErrs, nil
+	}
+
+	if b.transaction || b.useTxStmt {
+		return rowErrs, b.execTx(ctx, db, rows, indexes)
+	}
+
+	query, args, err := b.query(rows)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.ExecContext(ctx, query, args...); err == nil {
+		return rowErrs, nil
+	}
+	if ctx.Err() != nil || len(rows) == 1 {
+		return nil, err
+	}
+
+	// The rows were rejected as a whole, and therefore we insert them
+	// individually in order to identify the rows that failed.
+	for j, row := range rows {
+		if query, args, err = b.query([][]interface{}{row}); err != nil {
+			return nil, err
+		}
+		if _, err = db.ExecContext(ctx, query, args...); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			rowErrs[indexes[j]] = err
+		}
+	}
+	return rowErrs, nil
+}
+
+// execDeferred inserts the rows of a batch within a transaction and returns
+// the transaction without committing it, or a nil transaction when the batch
+// has no rows. Any row that fails causes the whole batch to fail.
+func (b *sqlInsertBuilder) execDeferred(ctx context.Context, db *sql.DB, batch service.MessageBatch, argsMapping *bloblang.Executor) (*sql.Tx, error) {
+	rows := make([][]interface{}, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+	for i := range batch {
+		args, err := sqlArgsFromMapping(batch, i, argsMapping)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, args)
+		indexes = append(indexes, i)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	// The transaction outlives the write, and therefore must not be rolled
+	// back when the context of the write is cancelled.
+	return b.beginTx(ctx, context.Background(), db, rows, indexes)
+}
+
+// execTx inserts rows within a transaction by executing a prepared statement
+// for each row.
+func (b *sqlInsertBuilder) execTx(ctx context.Context, db *sql.DB, rows [][]interface{}, indexes []int) error {
+	tx, err := b.beginTx(ctx, ctx, db, rows, indexes)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// beginTx begins a transaction bound to txCtx and inserts rows within it by
+// executing a prepared statement for each row, returning the transaction
+// uncommitted.
+func (b *sqlInsertBuilder) beginTx(ctx, txCtx context.Context, db *sql.DB, rows [][]interface{}, indexes []int) (*sql.Tx, error) {
+	// The clickhouse driver expects the prepared statement of a batch insert
+	// without any values.
+	var placeholders []interface{}
+	if !b.useTxStmt {
+		placeholders = make([]interface{}, len(b.columns))
+	}
+	sqlStr, _, err := b.query([][]interface{}{placeholders})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(txCtx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.PrepareContext(ctx, sqlStr)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for i, args := range rows {
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to insert message %v: %w", indexes[i], err)
+		}
+	}
+	return tx, nil
+}
+
+func sqlArgsFromMapping(batch service.MessageBatch, i int, argsMapping *bloblang.Executor) ([]interface{}, error) {
+	resMsg, err := batch.BloblangQuery(i, argsMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	iargs, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("mapping returned non-structured result: %w", err)
+	}
+
+	args, ok := iargs.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+	return args, nil
+}
@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLInsertBuilderQuery(t *testing.T) {
+	tests := map[string]struct {
+		conf  string
+		rows  [][]interface{}
+		query string
+		args  []interface{}
+	}{
+		"plain insert": {
+			conf: `
+driver: mysql
+dsn: woof
+table: foo
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+`,
+			rows:  [][]interface{}{{1, "a"}, {2, "b"}},
+			query: "INSERT INTO foo (id,name) VALUES (?,?),(?,?)",
+			args:  []interface{}{1, "a", 2, "b"},
+		},
+		"postgres upsert": {
+			conf: `
+driver: postgres
+dsn: woof
+table: foo
+columns: [ id, name, age ]
+args_mapping: 'root = [ this.id, this.name, this.age ]'
+suffix: RETURNING id
+upsert:
+  key_columns: [ id ]
+`,
+			rows:  [][]interface{}{{1, "a", 10}},
+			query: "INSERT INTO foo (id,name,age) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age RETURNING id",
+			args:  []interface{}{1, "a", 10},
+		},
+		"postgres upsert nothing to update": {
+			conf: `
+driver: postgres
+dsn: woof
+table: foo
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+upsert:
+  key_columns: [ id ]
+`,
+			rows:  [][]interface{}{{1}},
+			query: "INSERT INTO foo (id) VALUES ($1) ON CONFLICT (id) DO NOTHING",
+			args:  []interface{}{1},
+		},
+		"mysql upsert": {
+			conf: `
+driver: mysql
+dsn: woof
+table: foo
+columns: [ id, name, age ]
+args_mapping: 'root = [ this.id, this.name, this.age ]'
+upsert:
+  key_columns: [ id ]
+  update_columns: [ age ]
+`,
+			rows:  [][]interface{}{{1, "a", 10}},
+			query: "INSERT INTO foo (id,name,age) VALUES (?,?,?) ON DUPLICATE KEY UPDATE age = VALUES(age)",
+			args:  []interface{}{1, "a", 10},
+		},
+		"mssql merge": {
+			conf: `
+driver: mssql
+dsn: woof
+table: foo
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+upsert:
+  key_columns: [ id ]
+`,
+			rows:  [][]interface{}{{1, "a"}, {2, "b"}},
+			query: "MERGE INTO foo AS target USING (VALUES (?, ?), (?, ?)) AS source (id, name) ON target.id = source.id WHEN MATCHED THEN UPDATE SET target.name = source.name WHEN NOT MATCHED THEN INSERT (id, name) VALUES (source.id, source.name);",
+			args:  []interface{}{1, "a", 2, "b"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := sqlInsertOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			builder, err := sqlInsertBuilderFromConfig(conf)
+			require.NoError(t, err)
+
+			query, args, err := builder.query(test.rows)
+			require.NoError(t, err)
+			assert.Equal(t, test.query, query)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestSQLInsertBuilderConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"unsupported driver": {
+			conf: `
+driver: clickhouse
+dsn: woof
+table: foo
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+upsert:
+  key_columns: [ id ]
+`,
+			errContains: "not supported by the clickhouse driver",
+		},
+		"unknown key column": {
+			conf: `
+driver: postgres
+dsn: woof
+table: foo
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+upsert:
+  key_columns: [ nope ]
+`,
+			errContains: "key column nope",
+		},
+		"unknown update column": {
+			conf: `
+driver: postgres
+dsn: woof
+table: foo
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+upsert:
+  key_columns: [ id ]
+  update_columns: [ nope ]
+`,
+			errContains: "update column nope",
+		},
+		"update columns without keys": {
+			conf: `
+driver: postgres
+dsn: woof
+table: foo
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+upsert:
+  update_columns: [ id ]
+`,
+			errContains: "key_columns must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := sqlInsertProcessorConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = sqlInsertBuilderFromConfig(conf)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
@@ -27,3 +27,20 @@ args_mapping: 'root = [ this.id ]'
 	require.NoError(t, err)
 	require.NoError(t, insertOutput.Close(context.Background()))
 }
+
+func TestSQLInsertOutputDeferCommitRequiresTransaction(t *testing.T) {
+	conf := `
+driver: meow
+dsn: woof
+table: quack
+columns: [ foo ]
+args_mapping: 'root = [ this.id ]'
+defer_commit: true
+`
+
+	insertConfig, err := sqlInsertOutputConfig().ParseYAML(conf, service.NewEnvironment())
+	require.NoError(t, err)
+
+	_, err = newSQLInsertOutputFromConfig(insertConfig, nil)
+	require.EqualError(t, err, "transaction must be enabled in order to use defer_commit")
+}
@@ -3,13 +3,11 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"sync"
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
 	"github.com/Jeffail/benthos/v3/public/service"
-	"github.com/Masterminds/squirrel"
 )
 
 func sqlInsertProcessorConfig() *service.ConfigSpec {
@@ -18,7 +16,9 @@ func sqlInsertProcessorConfig() *service.ConfigSpec {
 		Categories("Integration").
 		Summary("Inserts rows into an SQL database for each message, and leaves the message unchanged.").
 		Description(`
-If the insert fails to execute then the message will still remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).`).
+If the insert fails to execute then the message will still remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
+
+Rows that fail to be inserted are identified individually, and only the messages of those rows are flagged with an error. When `+"`transaction`"+` is enabled the rows of each batch are instead inserted within a transaction, and any failure causes all messages of the batch to be flagged with the error.`).
 		Field(driverField).
 		Field(dsnField).
 		Field(service.NewStringField("table").
@@ -39,6 +39,8 @@ If the insert fails to execute then the message will still remain unchanged and
 			Description("An optional suffix to append to the insert query.").
 			Optional().
 			Advanced()).
+		Field(transactionField).
+		Field(upsertField).
 		Version("3.59.0").
 		Example("Table Insert (MySQL)",
 			`
@@ -77,10 +79,9 @@ func init() {
 
 type sqlInsertProcessor struct {
 	db      *sql.DB
-	builder squirrel.InsertBuilder
+	builder *sqlInsertBuilder
 	dbMut   sync.RWMutex
 
-	useTxStmt   bool
 	argsMapping *bloblang.Executor
 
 	logger  *service.Logger
@@ -97,52 +98,20 @@ func newSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 	if err != nil {
 		return nil, err
 	}
-	if _, in := map[string]struct{}{
-		"clickhouse": {},
-	}[driverStr]; in {
-		s.useTxStmt = true
-	}
 
 	dsnStr, err := conf.FieldString("dsn")
 	if err != nil {
 		return nil, err
 	}
 
-	tableStr, err := conf.FieldString("table")
-	if err != nil {
-		return nil, err
-	}
-
-	columns, err := conf.FieldStringList("columns")
-	if err != nil {
-		return nil, err
-	}
-
 	if conf.Contains("args_mapping") {
 		if s.argsMapping, err = conf.FieldBloblang("args_mapping"); err != nil {
 			return nil, err
 		}
 	}
 
-	s.builder = squirrel.Insert(tableStr).Columns(columns...)
-	if driverStr == "postgres" {
-		s.builder = s.builder.PlaceholderFormat(squirrel.Dollar)
-	}
-
-	if conf.Contains("prefix") {
-		prefixStr, err := conf.FieldString("prefix")
-		if err != nil {
-			return nil, err
-		}
-		s.builder = s.builder.Prefix(prefixStr)
-	}
-
-	if conf.Contains("suffix") {
-		suffixStr, err := conf.FieldString("suffix")
-		if err != nil {
-			return nil, err
-		}
-		s.builder = s.builder.Suffix(suffixStr)
+	if s.builder, err = sqlInsertBuilderFromConfig(conf); err != nil {
+		return nil, err
 	}
 
 	if s.db, err = sql.Open(driverStr, dsnStr); err != nil {
@@ -165,61 +134,16 @@ func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 	s.dbMut.RLock()
 	defer s.dbMut.RUnlock()
 
-	insertBuilder := s.builder
-
-	var tx *sql.Tx
-	var stmt *sql.Stmt
-	if s.useTxStmt {
-		var err error
-		if tx, err = s.db.Begin(); err != nil {
-			return nil, err
-		}
-		sqlStr, _, err := insertBuilder.Values().ToSql()
-		if err != nil {
-			return nil, err
-		}
-		if stmt, err = tx.Prepare(sqlStr); err != nil {
-			_ = tx.Rollback()
-			return nil, err
-		}
+	rowErrs, err := s.builder.exec(ctx, s.db, batch, s.argsMapping)
+	if err != nil {
+		return nil, err
 	}
 
 	batch = batch.Copy()
-	for i, msg := range batch {
-		var args []interface{}
-		resMsg, err := batch.BloblangQuery(i, s.argsMapping)
-		if err != nil {
-			msg.SetError(err)
-			continue
-		}
-
-		iargs, err := resMsg.AsStructured()
-		if err != nil {
-			msg.SetError(fmt.Errorf("mapping returned non-structured result: %w", err))
-			continue
+	for i, rowErr := range rowErrs {
+		if rowErr != nil {
+			batch[i].SetError(rowErr)
 		}
-
-		var ok bool
-		if args, ok = iargs.([]interface{}); !ok {
-			msg.SetError(fmt.Errorf("mapping returned non-array result: %T", iargs))
-			continue
-		}
-
-		if tx == nil {
-			insertBuilder = insertBuilder.Values(args...)
-		} else if _, err = stmt.Exec(args...); err != nil {
-			return nil, err
-		}
-	}
-
-	var err error
-	if tx == nil {
-		_, err = insertBuilder.RunWith(s.db).ExecContext(ctx)
-	} else {
-		err = tx.Commit()
-	}
-	if err != nil {
-		return nil, err
 	}
 	return []service.MessageBatch{batch}, nil
 }
@@ -0,0 +1,411 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/public/service"
+)
+
+func serverInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Version("3.64.0").
+		Categories("Network").
+		Summary("Receive syslog messages over UDP, TCP or TLS.").
+		Description(`
+Messages can be formatted either as [RFC 5424](https://tools.ietf.org/html/rfc5424) or [RFC 3164](https://tools.ietf.org/html/rfc3164), and when the `+"`format`"+` is `+"`auto`"+` the format of each message is detected from whether its priority is followed by a version number.
+
+Over UDP each datagram is consumed as a single message. Over TCP and TLS messages are framed as described by [RFC 6587](https://tools.ietf.org/html/rfc6587), either with octet counting, where each message is prefixed with its length, or with non-transparent framing, where each message is terminated with a line feed. When the `+"`framing`"+` is `+"`auto`"+` the framing of each message is detected from its first character.
+
+The contents of each message is the free-form message of the syslog message, and the header fields are added as metadata. Messages that cannot be parsed are consumed with the raw syslog message as their contents and flagged with an error, which can be handled with [error handling patterns](/docs/configuration/error_handling).
+
+Syslog provides no way of acknowledging messages, and therefore messages that are in flight when Benthos stops may be lost.
+
+### Metadata
+
+This input adds the following metadata fields to each message when they are present in the syslog message:
+
+`+"```text"+`
+- syslog_facility
+- syslog_severity
+- syslog_priority
+- syslog_timestamp
+- syslog_hostname
+- syslog_appname
+- syslog_procid
+- syslog_msgid
+- syslog_version
+- syslog_structured_data
+- syslog_remote_addr
+`+"```"+`
+
+Where `+"`syslog_timestamp`"+` is formatted as RFC 3339, `+"`syslog_version`"+` and `+"`syslog_structured_data`"+` are only present in RFC 5424 messages, with the structured data encoded as a JSON object, and `+"`syslog_remote_addr`"+` is the address of the sender. You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringAnnotatedEnumField("network", map[string]string{
+			"udp": "Receive messages as UDP datagrams.",
+			"tcp": "Receive messages over TCP connections.",
+			"tls": "Receive messages over TCP connections secured with TLS, which requires both `cert_file` and `key_file`.",
+		}).
+			Description("The network to receive messages over.").
+			Default("udp")).
+		Field(service.NewStringField("address").
+			Description("The address to listen from.").
+			Example("0.0.0.0:514").
+			Example("0.0.0.0:6514")).
+		Field(service.NewStringAnnotatedEnumField("format", map[string]string{
+			"auto":    "Detect the format of each message.",
+			"rfc5424": "Parse messages as RFC 5424.",
+			"rfc3164": "Parse messages as RFC 3164.",
+		}).
+			Description("The format of messages.").
+			Default("auto")).
+		Field(service.NewStringAnnotatedEnumField("framing", map[string]string{
+			"auto":            "Detect the framing of each message.",
+			"octet_counting":  "Messages are prefixed with their length in bytes followed by a space.",
+			"non_transparent": "Messages are terminated with a line feed.",
+		}).
+			Description("The framing of messages received over TCP and TLS.").
+			Default("auto")).
+		Field(service.NewBoolField("best_effort").
+			Description("Whether to consume messages that are only partially valid with the fields that could be parsed, rather than flagging them with an error.").
+			Advanced().
+			Default(true)).
+		Field(service.NewStringField("default_timezone").
+			Description("The timezone of RFC 3164 timestamps, which do not contain a timezone. This value should follow the [time.LoadLocation](https://golang.org/pkg/time/#LoadLocation) format.").
+			Advanced().
+			Default("UTC")).
+		Field(service.NewStringField("cert_file").
+			Description("A certificate file, required when `network` is `tls`.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField("key_file").
+			Description("A key file, required when `network` is `tls`.").
+			Advanced().
+			Default("")).
+		Field(service.NewIntField("max_message_size").
+			Description("The maximum size in bytes of a message. Connections that send a message larger than this are closed, and larger UDP datagrams are truncated.").
+			Advanced().
+			Default(65536)).
+		Example("Routing Logs", `
+Receive syslog messages from hosts over TCP, and write errors and worse to a separate topic from all other logs:`,
+			`
+input:
+  syslog_server:
+    network: tcp
+    address: 0.0.0.0:514
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: ${! if meta("syslog_severity").number(7) <= 3 { "logs_errors" } else { "logs" } }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"syslog_server", serverInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newServerInputFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type serverInput struct {
+	network    string
+	address    string
+	format     string
+	framing    string
+	bestEffort bool
+	location   *time.Location
+	tlsConf    *tls.Config
+	maxMsgSize int
+
+	log *service.Logger
+
+	connMut    sync.Mutex
+	listener   net.Listener
+	packetConn net.PacketConn
+	addr       net.Addr
+	conns      map[net.Conn]struct{}
+	connWG     sync.WaitGroup
+
+	msgChan chan *service.Message
+	shutSig *shutdown.Signaller
+}
+
+func newServerInputFromConfig(conf *service.ParsedConfig, log *service.Logger) (*serverInput, error) {
+	s := &serverInput{
+		log:     log,
+		conns:   map[net.Conn]struct{}{},
+		msgChan: make(chan *service.Message),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if s.network, err = conf.FieldString("network"); err != nil {
+		return nil, err
+	}
+	if s.address, err = conf.FieldString("address"); err != nil {
+		return nil, err
+	}
+	if s.format, err = conf.FieldString("format"); err != nil {
+		return nil, err
+	}
+	switch s.format {
+	case "auto", "rfc5424", "rfc3164":
+	default:
+		return nil, fmt.Errorf("unrecognised format: %v", s.format)
+	}
+	if s.framing, err = conf.FieldString("framing"); err != nil {
+		return nil, err
+	}
+	switch s.framing {
+	case "auto", "octet_counting", "non_transparent":
+	default:
+		return nil, fmt.Errorf("unrecognised framing: %v", s.framing)
+	}
+	if s.bestEffort, err = conf.FieldBool("best_effort"); err != nil {
+		return nil, err
+	}
+
+	tz, err := conf.FieldString("default_timezone")
+	if err != nil {
+		return nil, err
+	}
+	if s.location, err = time.LoadLocation(tz); err != nil {
+		return nil, fmt.Errorf("failed to load default_timezone: %w", err)
+	}
+
+	if s.maxMsgSize, err = conf.FieldInt("max_message_size"); err != nil {
+		return nil, err
+	}
+	if s.maxMsgSize <= 0 {
+		return nil, errors.New("max_message_size must be greater than zero")
+	}
+
+	certFile, err := conf.FieldString("cert_file")
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := conf.FieldString("key_file")
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.network {
+	case "udp", "tcp":
+		if certFile != "" || keyFile != "" {
+			return nil, errors.New("cert_file and key_file can only be specified when network is tls")
+		}
+	case "tls":
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("both cert_file and key_file must be specified in order to enable TLS")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		s.tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}}
+	default:
+		return nil, fmt.Errorf("unrecognised network: %v", s.network)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// deliver blocks until a message is consumed, returning false if the input is
+// shutting down.
+func (s *serverInput) deliver(msg *service.Message, remoteAddr net.Addr) bool {
+	if remoteAddr != nil {
+		msg.MetaSet("syslog_remote_addr", remoteAddr.String())
+	}
+	select {
+	case s.msgChan <- msg:
+		return true
+	case <-s.shutSig.CloseAtLeisureChan():
+		return false
+	}
+}
+
+func (s *serverInput) loopPackets(conn net.PacketConn) {
+	defer s.connWG.Done()
+
+	parser := newSyslogParser(s.format, s.bestEffort, s.location)
+	buf := make([]byte, s.maxMsgSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !s.shutSig.ShouldCloseAtLeisure() {
+				s.log.Errorf("Failed to read syslog datagram: %v\n", err)
+			}
+			return
+		}
+
+		frame := trimTrailer(buf[:n])
+		if len(frame) == 0 {
+			continue
+		}
+		frameCopy := make([]byte, len(frame))
+		copy(frameCopy, frame)
+		if !s.deliver(parser.parse(frameCopy), addr) {
+			return
+		}
+	}
+}
+
+func (s *serverInput) loopConn(conn net.Conn) {
+	defer func() {
+		s.connMut.Lock()
+		delete(s.conns, conn)
+		s.connMut.Unlock()
+		conn.Close()
+		s.connWG.Done()
+	}()
+
+	parser := newSyslogParser(s.format, s.bestEffort, s.location)
+	r := bufio.NewReaderSize(conn, s.maxMsgSize+2)
+	for {
+		frame, err := readFrame(r, s.framing, s.maxMsgSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.shutSig.ShouldCloseAtLeisure() {
+				s.log.Errorf("Closing syslog connection from %v: %v\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if !s.deliver(parser.parse(frame), conn.RemoteAddr()) {
+			return
+		}
+	}
+}
+
+func (s *serverInput) loopListener(listener net.Listener) {
+	defer s.connWG.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !s.shutSig.ShouldCloseAtLeisure() {
+				s.log.Errorf("Failed to accept syslog connection: %v\n", err)
+			}
+			return
+		}
+
+		s.connMut.Lock()
+		if s.shutSig.ShouldCloseAtLeisure() {
+			s.connMut.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.connWG.Add(1)
+		s.connMut.Unlock()
+
+		go s.loopConn(conn)
+	}
+}
+
+func trimTrailer(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r' || b[len(b)-1] == 0) {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+func (s *serverInput) Connect(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.listener != nil || s.packetConn != nil {
+		return nil
+	}
+	if s.shutSig.ShouldCloseAtLeisure() {
+		return service.ErrEndOfInput
+	}
+
+	switch s.network {
+	case "udp":
+		conn, err := net.ListenPacket("udp", s.address)
+		if err != nil {
+			return err
+		}
+		s.packetConn = conn
+		s.addr = conn.LocalAddr()
+		s.connWG.Add(1)
+		go s.loopPackets(conn)
+	default:
+		listener, err := net.Listen("tcp", s.address)
+		if err != nil {
+			return err
+		}
+		if s.tlsConf != nil {
+			listener = tls.NewListener(listener, s.tlsConf)
+		}
+		s.listener = listener
+		s.addr = listener.Addr()
+		s.connWG.Add(1)
+		go s.loopListener(listener)
+	}
+
+	go func() {
+		s.connWG.Wait()
+		s.shutSig.ShutdownComplete()
+	}()
+
+	s.log.Infof("Receiving syslog messages over %v at: %v\n", s.network, s.addr)
+	return nil
+}
+
+func (s *serverInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case msg := <-s.msgChan:
+		return msg, func(context.Context, error) error {
+			return nil
+		}, nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-s.shutSig.CloseAtLeisureChan():
+		return nil, nil, service.ErrEndOfInput
+	}
+}
+
+func (s *serverInput) Close(ctx context.Context) error {
+	s.connMut.Lock()
+	s.shutSig.CloseAtLeisure()
+	connected := s.listener != nil || s.packetConn != nil
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMut.Unlock()
+
+	if !connected {
+		return nil
+	}
+	select {
+	case <-s.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
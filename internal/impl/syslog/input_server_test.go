@@ -0,0 +1,204 @@
+package syslog
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readServerMessage(t *testing.T, s *serverInput) *service.Message {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := s.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+	return msg
+}
+
+func assertSyslogMessage(t *testing.T, msg *service.Message, content, appname string) {
+	t.Helper()
+
+	require.NoError(t, msg.GetError())
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, content, string(b))
+
+	v, _ := msg.MetaGet("syslog_appname")
+	assert.Equal(t, appname, v)
+
+	v, _ = msg.MetaGet("syslog_remote_addr")
+	assert.NotEmpty(t, v)
+}
+
+func TestServerInputUDP(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+network: udp
+address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+
+	conn, err := net.Dial("udp", s.addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(testRFC5424Msg + "\n"))
+	require.NoError(t, err)
+	assertSyslogMessage(t, readServerMessage(t, s), "An application event log entry", "evntslog")
+
+	_, err = conn.Write([]byte(testRFC3164Msg))
+	require.NoError(t, err)
+	assertSyslogMessage(t, readServerMessage(t, s), "'su root' failed for lonvick on /dev/pts/8", "su")
+}
+
+func TestServerInputTCP(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+network: tcp
+address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	go func() {
+		_, _ = conn.Write([]byte(testRFC3164Msg + "\n"))
+		_, _ = conn.Write([]byte("154 " + testRFC5424Msg))
+		_, _ = conn.Write([]byte("<13>Oct 11 22:14:15 mymachine cron: job\nstarted\n"))
+	}()
+
+	assertSyslogMessage(t, readServerMessage(t, s), "'su root' failed for lonvick on /dev/pts/8", "su")
+	assertSyslogMessage(t, readServerMessage(t, s), "An application event log entry", "evntslog")
+	assertSyslogMessage(t, readServerMessage(t, s), "job", "cron")
+
+	// Lines that are not syslog messages are consumed raw and flagged.
+	msg := readServerMessage(t, s)
+	assert.Error(t, msg.GetError())
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "started", string(b))
+}
+
+func TestServerInputTCPOctetCounting(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+network: tcp
+address: 127.0.0.1:0
+framing: octet_counting
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	defer s.Close(context.Background())
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	multiline := "<13>Oct 11 22:14:15 mymachine cron: job\nstarted"
+	go func() {
+		_, _ = conn.Write([]byte("47 " + multiline + "154 " + testRFC5424Msg))
+	}()
+
+	assertSyslogMessage(t, readServerMessage(t, s), "job\nstarted", "cron")
+	assertSyslogMessage(t, readServerMessage(t, s), "An application event log entry", "evntslog")
+}
+
+func TestServerInputClose(t *testing.T) {
+	conf, err := serverInputConfig().ParseYAML(`
+network: tcp
+address: 127.0.0.1:0
+`, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(testRFC3164Msg + "\n"))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	require.NoError(t, s.Close(ctx))
+
+	_, _, err = s.Read(ctx)
+	assert.Equal(t, service.ErrEndOfInput, err)
+}
+
+func TestServerInputBadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf string
+		err  string
+	}{
+		{
+			name: "tls without cert",
+			conf: `
+network: tls
+address: 127.0.0.1:0
+`,
+			err: "both cert_file and key_file must be specified in order to enable TLS",
+		},
+		{
+			name: "cert without tls",
+			conf: `
+network: tcp
+address: 127.0.0.1:0
+cert_file: foo.pem
+key_file: foo.key
+`,
+			err: "cert_file and key_file can only be specified when network is tls",
+		},
+		{
+			name: "bad timezone",
+			conf: `
+address: 127.0.0.1:0
+default_timezone: Nowhere/Nope
+`,
+			err: "failed to load default_timezone: unknown time zone Nowhere/Nope",
+		},
+		{
+			name: "bad max message size",
+			conf: `
+address: 127.0.0.1:0
+max_message_size: 0
+`,
+			err: "max_message_size must be greater than zero",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := serverInputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newServerInputFromConfig(conf, nil)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
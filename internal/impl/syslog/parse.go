@@ -0,0 +1,212 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	syslog "github.com/influxdata/go-syslog/v3"
+	"github.com/influxdata/go-syslog/v3/rfc3164"
+	"github.com/influxdata/go-syslog/v3/rfc5424"
+)
+
+// readFrame reads a single syslog message from a stream as described by RFC
+// 6587, where messages are either prefixed with their length (octet counting)
+// or terminated with a line feed (non-transparent framing). When the framing is
+// auto it is detected for each message from its first character, which is
+// always a digit for octet counting and the start of a priority otherwise.
+func readFrame(r *bufio.Reader, framing string, maxSize int) ([]byte, error) {
+	for {
+		if framing == "auto" {
+			b, err := r.Peek(1)
+			if err != nil {
+				return nil, err
+			}
+			if b[0] >= '0' && b[0] <= '9' {
+				return readOctetCountingFrame(r, maxSize)
+			}
+		} else if framing == "octet_counting" {
+			return readOctetCountingFrame(r, maxSize)
+		}
+
+		frame, err := readNonTransparentFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(frame) > 0 {
+			return frame, nil
+		}
+	}
+}
+
+func readOctetCountingFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	lenStr, err := r.ReadSlice(' ')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, errors.New("invalid octet counting frame length")
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(string(lenStr[:len(lenStr)-1]))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid octet counting frame length: %q", lenStr[:len(lenStr)-1])
+	}
+	if length > maxSize {
+		return nil, fmt.Errorf("message of %v bytes exceeds the max_message_size of %v", length, maxSize)
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+func readNonTransparentFrame(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, errors.New("message exceeds the max_message_size")
+		}
+		if !errors.Is(err, io.EOF) || len(line) == 0 {
+			return nil, err
+		}
+	}
+
+	frame := make([]byte, len(line))
+	copy(frame, line)
+	return bytes.TrimRight(frame, "\r\n"), nil
+}
+
+//------------------------------------------------------------------------------
+
+// syslogParser parses messages of a configured format, where the parsers of
+// go-syslog are not safe for concurrent use and therefore a parser is created
+// for each connection.
+type syslogParser struct {
+	format     string
+	bestEffort bool
+	rfc5424    syslog.Machine
+	rfc3164    syslog.Machine
+}
+
+func newSyslogParser(format string, bestEffort bool, loc *time.Location) *syslogParser {
+	p := &syslogParser{format: format, bestEffort: bestEffort}
+
+	var opts5424 []syslog.MachineOption
+	opts3164 := []syslog.MachineOption{
+		rfc3164.WithRFC3339(),
+		rfc3164.WithYear(rfc3164.CurrentYear{}),
+		rfc3164.WithTimezone(loc),
+	}
+	if bestEffort {
+		opts5424 = append(opts5424, rfc5424.WithBestEffort())
+		opts3164 = append(opts3164, rfc3164.WithBestEffort())
+	}
+
+	p.rfc5424 = rfc5424.NewParser(opts5424...)
+	p.rfc3164 = rfc3164.NewParser(opts3164...)
+	return p
+}
+
+// isRFC5424 returns whether a message begins with a priority followed by a
+// version, which distinguishes RFC 5424 messages from RFC 3164 messages.
+func isRFC5424(frame []byte) bool {
+	if len(frame) < 3 || frame[0] != '<' {
+		return false
+	}
+	for i := 1; i < len(frame) && i <= 4; i++ {
+		if frame[i] == '>' {
+			return i+1 < len(frame) && frame[i+1] >= '1' && frame[i+1] <= '9'
+		}
+	}
+	return false
+}
+
+// parse converts a syslog message into a Benthos message, where the contents
+// are the free-form message and the header fields are added as metadata. Messages
+// that cannot be parsed are returned with the raw frame as their contents and
+// flagged with an error.
+func (p *syslogParser) parse(frame []byte) *service.Message {
+	format := p.format
+	if format == "auto" {
+		format = "rfc3164"
+		if isRFC5424(frame) {
+			format = "rfc5424"
+		}
+	}
+
+	var res syslog.Message
+	var err error
+	if format == "rfc5424" {
+		res, err = p.rfc5424.Parse(frame)
+	} else {
+		res, err = p.rfc3164.Parse(frame)
+	}
+	if res == nil || (err != nil && !p.bestEffort) {
+		if err == nil {
+			err = errors.New("empty message")
+		}
+		msg := service.NewMessage(frame)
+		msg.SetError(fmt.Errorf("failed to parse %v message: %w", format, err))
+		return msg
+	}
+
+	var base *syslog.Base
+	msg := service.NewMessage(nil)
+	switch t := res.(type) {
+	case *rfc5424.SyslogMessage:
+		base = &t.Base
+		if t.Version != 0 {
+			msg.MetaSet("syslog_version", strconv.Itoa(int(t.Version)))
+		}
+		if t.StructuredData != nil {
+			if sdBytes, err := json.Marshal(*t.StructuredData); err == nil {
+				msg.MetaSet("syslog_structured_data", string(sdBytes))
+			}
+		}
+	case *rfc3164.SyslogMessage:
+		base = &t.Base
+	default:
+		return service.NewMessage(frame)
+	}
+
+	if base.Message != nil {
+		msg.SetBytes([]byte(*base.Message))
+	}
+	if base.Facility != nil {
+		msg.MetaSet("syslog_facility", strconv.Itoa(int(*base.Facility)))
+	}
+	if base.Severity != nil {
+		msg.MetaSet("syslog_severity", strconv.Itoa(int(*base.Severity)))
+	}
+	if base.Priority != nil {
+		msg.MetaSet("syslog_priority", strconv.Itoa(int(*base.Priority)))
+	}
+	if base.Timestamp != nil {
+		msg.MetaSet("syslog_timestamp", base.Timestamp.Format(time.RFC3339Nano))
+	}
+	if base.Hostname != nil {
+		msg.MetaSet("syslog_hostname", *base.Hostname)
+	}
+	if base.Appname != nil {
+		msg.MetaSet("syslog_appname", *base.Appname)
+	}
+	if base.ProcID != nil {
+		msg.MetaSet("syslog_procid", *base.ProcID)
+	}
+	if base.MsgID != nil {
+		msg.MetaSet("syslog_msgid", *base.MsgID)
+	}
+	return msg
+}
@@ -0,0 +1,169 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRFC5424Msg = `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event log entry`
+	testRFC3164Msg = `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`
+)
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		framing  string
+		input    string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "auto mixed",
+			framing:  "auto",
+			input:    "12 <13>1 - - -\n<13>hello\r\n\n5 <13>a",
+			expected: []string{"<13>1 - - -\n", "<13>hello", "<13>a"},
+		},
+		{
+			name:     "octet counting",
+			framing:  "octet_counting",
+			input:    "3 abc4 de\nf",
+			expected: []string{"abc", "de\nf"},
+		},
+		{
+			name:     "non transparent without trailer",
+			framing:  "non_transparent",
+			input:    "<13>foo\n<13>bar",
+			expected: []string{"<13>foo", "<13>bar"},
+		},
+		{
+			name:    "octet counting too large",
+			framing: "octet_counting",
+			input:   "100 abc",
+			err:     "message of 100 bytes exceeds the max_message_size of 20",
+		},
+		{
+			name:    "octet counting bad length",
+			framing: "octet_counting",
+			input:   "1a abc",
+			err:     `invalid octet counting frame length: "1a"`,
+		},
+		{
+			name:    "octet counting truncated",
+			framing: "octet_counting",
+			input:   "10 abc",
+			err:     "unexpected EOF",
+		},
+		{
+			name:    "non transparent too large",
+			framing: "non_transparent",
+			input:   strings.Repeat("a", 30) + "\n",
+			err:     "message exceeds the max_message_size",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(test.input), 20)
+
+			var frames []string
+			for {
+				frame, err := readFrame(r, test.framing, 20)
+				if err == io.EOF {
+					break
+				}
+				if test.err != "" {
+					require.EqualError(t, err, test.err)
+					return
+				}
+				require.NoError(t, err)
+				frames = append(frames, string(frame))
+			}
+			require.Empty(t, test.err)
+			assert.Equal(t, test.expected, frames)
+		})
+	}
+}
+
+func TestParseRFC5424(t *testing.T) {
+	p := newSyslogParser("auto", true, time.UTC)
+
+	msg := p.parse([]byte(testRFC5424Msg))
+	require.NoError(t, msg.GetError())
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "An application event log entry", string(b))
+
+	meta := map[string]string{}
+	require.NoError(t, msg.MetaWalk(func(k, v string) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]string{
+		"syslog_facility":        "20",
+		"syslog_severity":        "5",
+		"syslog_priority":        "165",
+		"syslog_version":         "1",
+		"syslog_timestamp":       "2003-10-11T22:14:15.003Z",
+		"syslog_hostname":        "mymachine.example.com",
+		"syslog_appname":         "evntslog",
+		"syslog_msgid":           "ID47",
+		"syslog_structured_data": `{"exampleSDID@32473":{"eventSource":"Application","iut":"3"}}`,
+	}, meta)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	p := newSyslogParser("auto", true, loc)
+
+	msg := p.parse([]byte(testRFC3164Msg))
+	require.NoError(t, msg.GetError())
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(b))
+
+	for k, v := range map[string]string{
+		"syslog_facility": "4",
+		"syslog_severity": "2",
+		"syslog_priority": "34",
+		"syslog_hostname": "mymachine",
+		"syslog_appname":  "su",
+	} {
+		actual, exists := msg.MetaGet(k)
+		assert.True(t, exists, k)
+		assert.Equal(t, v, actual, k)
+	}
+
+	_, exists := msg.MetaGet("syslog_version")
+	assert.False(t, exists)
+
+	ts, _ := msg.MetaGet("syslog_timestamp")
+	tsTime, err := time.Parse(time.RFC3339Nano, ts)
+	require.NoError(t, err)
+	assert.Equal(t, time.October, tsTime.Month())
+	assert.Equal(t, 22, tsTime.In(loc).Hour())
+	assert.Equal(t, time.Now().Year(), tsTime.Year())
+}
+
+func TestParseFailure(t *testing.T) {
+	p := newSyslogParser("rfc5424", false, time.UTC)
+
+	msg := p.parse([]byte(testRFC3164Msg))
+	require.Error(t, msg.GetError())
+	assert.Contains(t, msg.GetError().Error(), "failed to parse rfc5424 message")
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, testRFC3164Msg, string(b))
+}
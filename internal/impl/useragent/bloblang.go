@@ -0,0 +1,75 @@
+package useragent
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+)
+
+var (
+	defaultParserOnce sync.Once
+	defaultParser     *parser
+	defaultParserErr  error
+)
+
+func getDefaultParser() (*parser, error) {
+	defaultParserOnce.Do(func() {
+		defaultParser, defaultParserErr = newParser(defaultRegexes)
+	})
+	return defaultParser, defaultParserErr
+}
+
+func init() {
+	parseUserAgentSpec := bloblang.NewPluginSpec().
+		Category(string(query.MethodCategoryParsing)).
+		Description("EXPERIMENTAL: Parses a user agent string into an object describing the browser, operating system and device of the client, and whether the client is a bot. Values that cannot be determined are `null`, and families that cannot be determined are `Other`. The rules used are an embedded subset of the [uap-core](https://github.com/ua-parser/uap-core) ruleset, which covers common browsers, operating systems, devices and crawlers, and a complete or custom ruleset can be used instead with the `rules_path` parameter.").
+		Param(bloblang.NewStringParam("rules_path").Description("An optional path to a YAML file of rules in the uap-core `regexes.yaml` format, which replaces the embedded rules. Rules with expressions that are not supported by the Go regular expression syntax are ignored.").Optional()).
+		Example("",
+			`root = this.ua.parse_user_agent()`,
+			[2]string{
+				`{"ua":"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"}`,
+				`{"browser":{"family":"Chrome","major":"91","minor":"0","patch":"4472"},"device":{"brand":"Apple","family":"Mac","model":"Mac"},"is_bot":false,"os":{"family":"Mac OS X","major":"10","minor":"15","patch":"7","patch_minor":null}}`,
+			}).
+		Example("Bots can be detected in order to filter them out of a pipeline.",
+			`root = if this.ua.parse_user_agent().is_bot { deleted() }`,
+			[2]string{
+				`{"ua":"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}`,
+				`<Message deleted>`,
+			},
+			[2]string{
+				`{"ua":"curl/7.64.1"}`,
+				`{"ua":"curl/7.64.1"}`,
+			})
+
+	if err := bloblang.RegisterMethodV2(
+		"parse_user_agent", parseUserAgentSpec,
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			rulesPath, err := args.GetOptionalString("rules_path")
+			if err != nil {
+				return nil, err
+			}
+
+			var p *parser
+			if rulesPath != nil {
+				rulesBytes, err := os.ReadFile(*rulesPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read rules file: %w", err)
+				}
+				if p, err = newParser(rulesBytes); err != nil {
+					return nil, fmt.Errorf("failed to load rules file '%v': %w", *rulesPath, err)
+				}
+			} else if p, err = getDefaultParser(); err != nil {
+				return nil, err
+			}
+
+			return bloblang.StringMethod(func(s string) (interface{}, error) {
+				return p.parse(s), nil
+			}), nil
+		},
+	); err != nil {
+		panic(err)
+	}
+}
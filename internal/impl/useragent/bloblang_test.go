@@ -0,0 +1,169 @@
+package useragent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ua      string
+		browser []interface{}
+		os      []interface{}
+		device  []interface{}
+		isBot   bool
+	}{
+		{
+			name:    "chrome on mac",
+			ua:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+			browser: []interface{}{"Chrome", "91", "0", "4472"},
+			os:      []interface{}{"Mac OS X", "10", "15", "7", nil},
+			device:  []interface{}{"Mac", "Apple", "Mac"},
+		},
+		{
+			name:    "firefox on windows",
+			ua:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
+			browser: []interface{}{"Firefox", "89", "0", nil},
+			os:      []interface{}{"Windows", "10", nil, nil, nil},
+			device:  []interface{}{"Other", nil, nil},
+		},
+		{
+			name:    "edge on windows",
+			ua:      "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 Edg/91.0.864.59",
+			browser: []interface{}{"Edge", "91", "0", "864"},
+			os:      []interface{}{"Windows", "7", nil, nil, nil},
+			device:  []interface{}{"Other", nil, nil},
+		},
+		{
+			name:    "safari on iphone",
+			ua:      "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1",
+			browser: []interface{}{"Mobile Safari", "14", "1", "1"},
+			os:      []interface{}{"iOS", "14", "6", nil, nil},
+			device:  []interface{}{"iPhone", "Apple", "iPhone"},
+		},
+		{
+			name:    "chrome on android",
+			ua:      "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.91 Mobile Safari/537.36",
+			browser: []interface{}{"Chrome Mobile", "90", "0", "4430"},
+			os:      []interface{}{"Android", "11", nil, nil, nil},
+			device:  []interface{}{"Pixel 5", "Google", "Pixel 5"},
+		},
+		{
+			name:    "samsung internet",
+			ua:      "Mozilla/5.0 (Linux; Android 10; SM-G973F) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/14.2 Chrome/87.0.4280.141 Mobile Safari/537.36",
+			browser: []interface{}{"Samsung Internet", "14", "2", nil},
+			os:      []interface{}{"Android", "10", nil, nil, nil},
+			device:  []interface{}{"Samsung SM-G973F", "Samsung", "SM-G973F"},
+		},
+		{
+			name:    "googlebot",
+			ua:      "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			browser: []interface{}{"Googlebot", "2", "1", nil},
+			os:      []interface{}{"Other", nil, nil, nil, nil},
+			device:  []interface{}{"Spider", "Spider", "Desktop"},
+			isBot:   true,
+		},
+		{
+			name:    "curl",
+			ua:      "curl/7.64.1",
+			browser: []interface{}{"curl", "7", "64", "1"},
+			os:      []interface{}{"Other", nil, nil, nil, nil},
+			device:  []interface{}{"Other", nil, nil},
+		},
+		{
+			name:    "unknown",
+			ua:      "nope",
+			browser: []interface{}{"Other", nil, nil, nil},
+			os:      []interface{}{"Other", nil, nil, nil, nil},
+			device:  []interface{}{"Other", nil, nil},
+		},
+	}
+
+	exec, err := bloblang.Parse(`root = this.parse_user_agent()`)
+	require.NoError(t, err)
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := exec.Query(test.ua)
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]interface{}{
+				"browser": map[string]interface{}{
+					"family": test.browser[0],
+					"major":  test.browser[1],
+					"minor":  test.browser[2],
+					"patch":  test.browser[3],
+				},
+				"os": map[string]interface{}{
+					"family":      test.os[0],
+					"major":       test.os[1],
+					"minor":       test.os[2],
+					"patch":       test.os[3],
+					"patch_minor": test.os[4],
+				},
+				"device": map[string]interface{}{
+					"family": test.device[0],
+					"brand":  test.device[1],
+					"model":  test.device[2],
+				},
+				"is_bot": test.isBot,
+			}, res)
+		})
+	}
+}
+
+func TestParseUserAgentRulesPath(t *testing.T) {
+	rulesPath := filepath.Join(t.TempDir(), "regexes.yaml")
+	require.NoError(t, os.WriteFile(rulesPath, []byte(`
+user_agent_parsers:
+  - regex: '(FooBrowser)/(\d+)\.(\d+)'
+    family_replacement: 'Foo $1'
+    v1_replacement: 'v$2'
+os_parsers:
+  - regex: '(?:foo|bar)os'
+    regex_flag: 'i'
+    os_replacement: 'FooOS'
+  - regex: 'unsupported (?=lookahead)'
+device_parsers:
+  - regex: 'FooBot'
+    device_replacement: 'Spider'
+`), 0o644))
+
+	exec, err := bloblang.Parse(`root = this.parse_user_agent("` + rulesPath + `")`)
+	require.NoError(t, err)
+
+	res, err := exec.Query("FooBrowser/1.2 (BarOS) FooBot")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"browser": map[string]interface{}{
+			"family": "Foo FooBrowser",
+			"major":  "v1",
+			"minor":  "2",
+			"patch":  nil,
+		},
+		"os": map[string]interface{}{
+			"family":      "FooOS",
+			"major":       nil,
+			"minor":       nil,
+			"patch":       nil,
+			"patch_minor": nil,
+		},
+		"device": map[string]interface{}{
+			"family": "Spider",
+			"brand":  nil,
+			"model":  nil,
+		},
+		"is_bot": true,
+	}, res)
+
+	_, err = bloblang.Parse(`root = this.parse_user_agent("` + filepath.Join(t.TempDir(), "nope.yaml") + `")`)
+	require.Error(t, err)
+}
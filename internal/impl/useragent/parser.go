@@ -0,0 +1,181 @@
+package useragent
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed regexes.yaml
+var defaultRegexes []byte
+
+// spiderDeviceFamily is the device family that uap-core rules assign to
+// crawlers and other automated clients.
+const spiderDeviceFamily = "Spider"
+
+type rawRule struct {
+	Regex     string `yaml:"regex"`
+	RegexFlag string `yaml:"regex_flag"`
+
+	FamilyReplacement string `yaml:"family_replacement"`
+	V1Replacement     string `yaml:"v1_replacement"`
+	V2Replacement     string `yaml:"v2_replacement"`
+	V3Replacement     string `yaml:"v3_replacement"`
+
+	OSReplacement   string `yaml:"os_replacement"`
+	OSV1Replacement string `yaml:"os_v1_replacement"`
+	OSV2Replacement string `yaml:"os_v2_replacement"`
+	OSV3Replacement string `yaml:"os_v3_replacement"`
+	OSV4Replacement string `yaml:"os_v4_replacement"`
+
+	DeviceReplacement string `yaml:"device_replacement"`
+	BrandReplacement  string `yaml:"brand_replacement"`
+	ModelReplacement  string `yaml:"model_replacement"`
+}
+
+type rawRuleset struct {
+	UserAgentParsers []rawRule `yaml:"user_agent_parsers"`
+	OSParsers        []rawRule `yaml:"os_parsers"`
+	DeviceParsers    []rawRule `yaml:"device_parsers"`
+}
+
+// rule is a compiled uap-core rule where each field is either a replacement,
+// which may reference capture groups of the expression with $1 to $9, or
+// empty in order to use the capture group of its default position.
+type rule struct {
+	re     *regexp.Regexp
+	fields []string
+}
+
+// parser extracts the browser, operating system and device of user agent
+// strings with a ruleset in the uap-core format.
+type parser struct {
+	userAgents []rule
+	oses       []rule
+	devices    []rule
+}
+
+// newParser compiles a ruleset in the uap-core YAML format. Rules with
+// expressions that aren't supported by the Go regular expression syntax are
+// skipped.
+func newParser(rulesetBytes []byte) (*parser, error) {
+	var raw rawRuleset
+	if err := yaml.Unmarshal(rulesetBytes, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ruleset: %w", err)
+	}
+	if len(raw.UserAgentParsers) == 0 && len(raw.OSParsers) == 0 && len(raw.DeviceParsers) == 0 {
+		return nil, errors.New("ruleset does not contain any rules")
+	}
+
+	p := &parser{}
+	for _, r := range raw.UserAgentParsers {
+		p.userAgents = appendRule(p.userAgents, r, r.FamilyReplacement, r.V1Replacement, r.V2Replacement, r.V3Replacement)
+	}
+	for _, r := range raw.OSParsers {
+		p.oses = appendRule(p.oses, r, r.OSReplacement, r.OSV1Replacement, r.OSV2Replacement, r.OSV3Replacement, r.OSV4Replacement)
+	}
+	for _, r := range raw.DeviceParsers {
+		p.devices = appendRule(p.devices, r, r.DeviceReplacement, r.BrandReplacement, r.ModelReplacement)
+	}
+	return p, nil
+}
+
+func appendRule(rules []rule, r rawRule, fields ...string) []rule {
+	expr := r.Regex
+	if strings.Contains(r.RegexFlag, "i") {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rules
+	}
+	return append(rules, rule{re: re, fields: fields})
+}
+
+var groupRefRegex = regexp.MustCompile(`\$(\d)`)
+
+// match returns the values of a user agent string for the first matching rule
+// of a list, where a nil value means the value could not be extracted.
+func match(rules []rule, ua string, defaultGroups []int) ([]interface{}, bool) {
+	for _, r := range rules {
+		groups := r.re.FindStringSubmatch(ua)
+		if groups == nil {
+			continue
+		}
+
+		values := make([]interface{}, len(r.fields))
+		for i, replacement := range r.fields {
+			var v string
+			if replacement != "" {
+				v = groupRefRegex.ReplaceAllStringFunc(replacement, func(ref string) string {
+					if n, _ := strconv.Atoi(ref[1:]); n < len(groups) {
+						return groups[n]
+					}
+					return ""
+				})
+			} else if n := defaultGroups[i]; n > 0 && n < len(groups) {
+				v = groups[n]
+			}
+			if v = strings.TrimSpace(v); v != "" {
+				values[i] = v
+			}
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// parse returns a structured description of a user agent string.
+func (p *parser) parse(ua string) map[string]interface{} {
+	browser := map[string]interface{}{
+		"family": "Other",
+		"major":  nil,
+		"minor":  nil,
+		"patch":  nil,
+	}
+	if v, ok := match(p.userAgents, ua, []int{1, 2, 3, 4}); ok {
+		setValues(browser, v, "family", "major", "minor", "patch")
+	}
+
+	osInfo := map[string]interface{}{
+		"family":      "Other",
+		"major":       nil,
+		"minor":       nil,
+		"patch":       nil,
+		"patch_minor": nil,
+	}
+	if v, ok := match(p.oses, ua, []int{1, 2, 3, 4, 5}); ok {
+		setValues(osInfo, v, "family", "major", "minor", "patch", "patch_minor")
+	}
+
+	device := map[string]interface{}{
+		"family": "Other",
+		"brand":  nil,
+		"model":  nil,
+	}
+	// The model of a device defaults to the first capture group, as does the
+	// family, whereas the brand is only ever taken from a replacement.
+	if v, ok := match(p.devices, ua, []int{1, 0, 1}); ok {
+		setValues(device, v, "family", "brand", "model")
+	}
+
+	return map[string]interface{}{
+		"browser": browser,
+		"os":      osInfo,
+		"device":  device,
+		"is_bot":  device["family"] == spiderDeviceFamily,
+	}
+}
+
+func setValues(obj map[string]interface{}, values []interface{}, keys ...string) {
+	for i, k := range keys {
+		if values[i] != nil {
+			obj[k] = values[i]
+		}
+	}
+}
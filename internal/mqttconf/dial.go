@@ -0,0 +1,98 @@
+package mqttconf
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// Protocol versions supported by the MQTT components.
+const (
+	ProtocolVersion311 = "3.1.1"
+	ProtocolVersion5   = "5"
+)
+
+// ProtocolVersionFieldSpec defines the protocol version field shared by the
+// MQTT components.
+func ProtocolVersionFieldSpec() docs.FieldSpec {
+	return docs.FieldString(
+		"protocol_version", "The version of the MQTT protocol to connect with.",
+	).HasAnnotatedOptions(
+		ProtocolVersion311, "MQTT 3.1.1, which is supported by most brokers.",
+		ProtocolVersion5, "MQTT 5.0, which enables session expiry intervals, user properties and acknowledgements of messages that are tied to their delivery.",
+	).HasDefault(ProtocolVersion311).Advanced().AtVersion("3.64.0")
+}
+
+// ValidateProtocolVersion returns an error if a protocol version is not
+// supported.
+func ValidateProtocolVersion(v string) error {
+	switch v {
+	case ProtocolVersion311, ProtocolVersion5:
+		return nil
+	}
+	return fmt.Errorf("unsupported protocol_version: %v", v)
+}
+
+// Dial opens a connection to the first broker of a list of URLs that can be
+// reached, where the URLs schemes ssl, tls, tcps and mqtts are connected to
+// with TLS. Websocket URLs are not supported.
+func Dial(ctx context.Context, urls []string, tlsConf *tls.Config) (net.Conn, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no broker URLs were provided")
+	}
+
+	var err error
+	for _, u := range urls {
+		var conn net.Conn
+		if conn, err = dialURL(ctx, u, tlsConf); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func dialURL(ctx context.Context, urlStr string, tlsConf *tls.Config) (net.Conn, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse broker URL '%v': %w", urlStr, err)
+	}
+
+	var dialer net.Dialer
+	switch strings.ToLower(u.Scheme) {
+	case "tcp", "mqtt":
+		return dialer.DialContext(ctx, "tcp", hostWithPort(u, "1883"))
+	case "ssl", "tls", "tcps", "mqtts":
+		tlsDialer := tls.Dialer{
+			NetDialer: &dialer,
+			Config:    tlsConf,
+		}
+		return tlsDialer.DialContext(ctx, "tcp", hostWithPort(u, "8883"))
+	}
+	return nil, fmt.Errorf("unsupported scheme of broker URL '%v'", urlStr)
+}
+
+func hostWithPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// SharedTopics returns a list of topics as shared subscriptions of a group,
+// or the topics unchanged if the group is empty.
+func SharedTopics(group string, topics []string) []string {
+	if group == "" {
+		return topics
+	}
+	shared := make([]string, 0, len(topics))
+	for _, t := range topics {
+		shared = append(shared, "$share/"+group+"/"+t)
+	}
+	return shared
+}
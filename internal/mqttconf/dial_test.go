@@ -0,0 +1,71 @@
+package mqttconf
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialFirstReachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	accepted := make(chan struct{})
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+		close(accepted)
+	}()
+
+	// Reserve a port that nothing is listening on.
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closedLn.Addr().String()
+	closedLn.Close()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	conn, err := Dial(ctx, []string{
+		"tcp://" + closedAddr,
+		"mqtt://" + ln.Addr().String(),
+	}, nil)
+	require.NoError(t, err)
+	conn.Close()
+
+	select {
+	case <-accepted:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, err := Dial(ctx, nil, nil)
+	require.Error(t, err)
+
+	_, err = Dial(ctx, []string{"ws://localhost:1883"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported scheme")
+}
+
+func TestSharedTopics(t *testing.T) {
+	topics := []string{"foo", "bar/#"}
+	assert.Equal(t, topics, SharedTopics("", topics))
+	assert.Equal(t, []string{"$share/baz/foo", "$share/baz/bar/#"}, SharedTopics("baz", topics))
+}
+
+func TestValidateProtocolVersion(t *testing.T) {
+	assert.NoError(t, ValidateProtocolVersion(ProtocolVersion311))
+	assert.NoError(t, ValidateProtocolVersion(ProtocolVersion5))
+	assert.Error(t, ValidateProtocolVersion("4"))
+}
@@ -0,0 +1,64 @@
+// Package parquetschema contains the schema and value representations shared
+// by components that write parquet files, such as the parquet processors and
+// the table format outputs.
+package parquetschema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Node is a column of a parquet schema in the JSON format of the parquet
+// writer, where the column is described by a tag and groups have nested
+// fields.
+type Node struct {
+	Tag    string  `json:"Tag"`
+	Fields []*Node `json:"Fields,omitempty"`
+}
+
+// NewRoot returns the root node of a parquet schema.
+func NewRoot() *Node {
+	return &Node{Tag: "name=root, repetitiontype=REQUIRED"}
+}
+
+// Repetition returns the repetition type of a column.
+func Repetition(optional bool) string {
+	if optional {
+		return "OPTIONAL"
+	}
+	return "REQUIRED"
+}
+
+// ColumnTag returns the tag of a column with a name and repetition type, which
+// is extended with the type of the column.
+func ColumnTag(name string, optional bool) string {
+	return fmt.Sprintf("name=%v, repetitiontype=%v", name, Repetition(optional))
+}
+
+// List returns a list column with a tag and the node of its elements, which
+// must be named element.
+func List(tag string, element *Node) *Node {
+	return &Node{
+		Tag:    tag + ", type=LIST",
+		Fields: []*Node{element},
+	}
+}
+
+// Map returns a map column with a tag and the nodes of its keys and values,
+// which must be named key and value.
+func Map(tag string, key, value *Node) *Node {
+	return &Node{
+		Tag:    tag + ", type=MAP",
+		Fields: []*Node{key, value},
+	}
+}
+
+// JSON returns the schema of a root node in the JSON format of the parquet
+// writer.
+func (n *Node) JSON() (string, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
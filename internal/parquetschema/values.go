@@ -0,0 +1,144 @@
+package parquetschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Int converts a structured value into an integer, accepting numbers without
+// a fractional part and numeric strings.
+func Int(v interface{}) (int64, error) {
+	switch tv := v.(type) {
+	case int:
+		return int64(tv), nil
+	case int32:
+		return int64(tv), nil
+	case int64:
+		return tv, nil
+	case uint64:
+		if tv > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v overflows long", tv)
+		}
+		return int64(tv), nil
+	case float64:
+		if tv != math.Trunc(tv) {
+			return 0, fmt.Errorf("expected integer value, got %v", tv)
+		}
+		return int64(tv), nil
+	case json.Number:
+		return tv.Int64()
+	case string:
+		return strconv.ParseInt(tv, 10, 64)
+	}
+	return 0, fmt.Errorf("expected number value, got %T", v)
+}
+
+// IntInRange converts a structured value into an integer and returns an error
+// naming the type if it is outside of the range min to max.
+func IntInRange(v interface{}, min, max int64, typeName string) (int64, error) {
+	i, err := Int(v)
+	if err != nil {
+		return 0, err
+	}
+	if i < min || i > max {
+		return 0, fmt.Errorf("value %v overflows %v", i, typeName)
+	}
+	return i, nil
+}
+
+// Float converts a structured value into a float, accepting any number and
+// numeric strings.
+func Float(v interface{}) (float64, error) {
+	switch tv := v.(type) {
+	case int:
+		return float64(tv), nil
+	case int32:
+		return float64(tv), nil
+	case int64:
+		return float64(tv), nil
+	case uint64:
+		return float64(tv), nil
+	case float32:
+		return float64(tv), nil
+	case float64:
+		return tv, nil
+	case json.Number:
+		return tv.Float64()
+	case string:
+		return strconv.ParseFloat(tv, 64)
+	}
+	return 0, fmt.Errorf("expected number value, got %T", v)
+}
+
+// String converts a structured value into a string, where values that are not
+// strings or bytes are serialized as JSON.
+func String(v interface{}) (string, error) {
+	switch tv := v.(type) {
+	case string:
+		return tv, nil
+	case []byte:
+		return string(tv), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Time parses timestamps from RFC 3339 strings, dates and numbers of seconds
+// since the unix epoch.
+func Time(v interface{}) (time.Time, error) {
+	switch tv := v.(type) {
+	case time.Time:
+		return tv.UTC(), nil
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, tv); err == nil {
+			return ts.UTC(), nil
+		}
+		if ts, err := time.Parse("2006-01-02", tv); err == nil {
+			return ts, nil
+		}
+		if ts, err := time.Parse("2006-01-02T15:04:05.999999999", tv); err == nil {
+			return ts, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to parse timestamp '%v'", tv)
+	}
+	f, err := Float(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected timestamp value, got %T", v)
+	}
+	secs := math.Floor(f)
+	return time.Unix(int64(secs), int64(math.Round((f-secs)*1e9))).UTC(), nil
+}
+
+// Date returns the physical representation of a date column, which is the
+// number of days since the unix epoch.
+func Date(ts time.Time) int32 {
+	return int32(FloorDiv(ts.Unix(), 86400))
+}
+
+// TimestampMicros returns the physical representation of a timestamp column,
+// which is the number of microseconds since the unix epoch.
+func TimestampMicros(ts time.Time) int64 {
+	return ts.Unix()*1e6 + int64(ts.Nanosecond()/1e3)
+}
+
+// MicrosTime returns the time of a number of microseconds since the unix
+// epoch.
+func MicrosTime(micros int64) time.Time {
+	secs := FloorDiv(micros, 1e6)
+	return time.Unix(secs, (micros-secs*1e6)*1e3).UTC()
+}
+
+// FloorDiv divides a by b and rounds the result towards negative infinity.
+func FloorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,50 @@
+package parquetschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValues(t *testing.T) {
+	i, err := Int(json.Number("10"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), i)
+
+	_, err = Int(1.5)
+	assert.Error(t, err)
+
+	_, err = IntInRange(200, -128, 127, "byte")
+	assert.EqualError(t, err, "value 200 overflows byte")
+
+	s, err := String(map[string]interface{}{"a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, s)
+
+	ts, err := Time("1969-12-31T23:59:59.5Z")
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), Date(ts))
+	assert.Equal(t, int64(-500000), TimestampMicros(ts))
+	assert.True(t, ts.Equal(MicrosTime(-500000)))
+
+	ts, err = Time(1.5)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1, 5e8).UTC(), ts)
+
+	assert.Equal(t, int64(-2), FloorDiv(-3, 2))
+	assert.Equal(t, int64(1), FloorDiv(3, 2))
+}
+
+func TestNodeJSON(t *testing.T) {
+	root := NewRoot()
+	root.Fields = append(root.Fields,
+		List(ColumnTag("tags", true), &Node{Tag: ColumnTag("element", false) + ", type=BYTE_ARRAY, convertedtype=UTF8"}),
+	)
+
+	s, err := root.JSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"Tag":"name=root, repetitiontype=REQUIRED","Fields":[{"Tag":"name=tags, repetitiontype=OPTIONAL, type=LIST","Fields":[{"Tag":"name=element, repetitiontype=REQUIRED, type=BYTE_ARRAY, convertedtype=UTF8"}]}]}`, s)
+}
@@ -0,0 +1,204 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// DeliveryHookFunc is a function that is called once the delivery of a
+// message has been resolved, with a nil error if the message was delivered
+// successfully, or the error that caused it to be rejected otherwise.
+type DeliveryHookFunc func(ctx context.Context, err error) error
+
+// CommitHookFunc is a function that is called once the source of a message has
+// been acknowledged, with a nil error if the message was delivered and its
+// source acknowledged successfully, or the error that prevented it otherwise.
+type CommitHookFunc func(ctx context.Context, err error) error
+
+// DeliveryHooks is a set of functions registered by components that handle the
+// messages of a transaction, which are called once the transaction has been
+// resolved and before the source of the messages is acknowledged.
+//
+// Commit hooks can also be registered, which are called after the source of
+// the messages has been acknowledged. These allow outputs to defer the final
+// commit of a write until the input has durably stored its checkpoint.
+type DeliveryHooks struct {
+	mut      sync.Mutex
+	hooks    []DeliveryHookFunc
+	resolved bool
+
+	commitHooks []CommitHookFunc
+	committed   bool
+}
+
+type deliveryHooksKey struct{}
+
+func getDeliveryHooks(p types.Part) (*DeliveryHooks, bool) {
+	hooks, ok := message.GetContext(p).Value(deliveryHooksKey{}).(*DeliveryHooks)
+	return hooks, ok
+}
+
+// AttachDeliveryHooks associates a new set of delivery hooks with each part of
+// a message batch, allowing components that handle the parts, or copies of
+// them, to register hooks with AddDeliveryHook.
+func AttachDeliveryHooks(msg types.Message) *DeliveryHooks {
+	hooks := &DeliveryHooks{}
+	hookedParts := make([]types.Part, msg.Len())
+	_ = msg.Iter(func(i int, p types.Part) error {
+		ctx := context.WithValue(message.GetContext(p), deliveryHooksKey{}, hooks)
+		hookedParts[i] = message.WithContext(ctx, p)
+		return nil
+	})
+	msg.SetAll(hookedParts)
+	return hooks
+}
+
+// AddDeliveryHook registers a function to be called once the delivery of the
+// transaction that a message part belongs to has been resolved. Returns false
+// if the part is not associated with delivery hooks, or if the transaction has
+// already been resolved, in which case the function will never be called.
+func AddDeliveryHook(p types.Part, fn DeliveryHookFunc) bool {
+	hooks, ok := getDeliveryHooks(p)
+	if !ok {
+		return false
+	}
+
+	hooks.mut.Lock()
+	defer hooks.mut.Unlock()
+	if hooks.resolved {
+		return false
+	}
+	hooks.hooks = append(hooks.hooks, fn)
+	return true
+}
+
+// Resolve calls each registered hook in the order that they were added with
+// the error of a response, and returns the first error returned by a hook.
+// Hooks are only called once, and any hooks added after Resolve has been called
+// are rejected.
+func (d *DeliveryHooks) Resolve(ctx context.Context, res types.Response) error {
+	d.mut.Lock()
+	hooks := d.hooks
+	d.hooks = nil
+	d.resolved = true
+	d.mut.Unlock()
+
+	var resErr error
+	if res != nil {
+		resErr = res.Error()
+	}
+
+	var hookErr error
+	for _, fn := range hooks {
+		if err := fn(ctx, resErr); err != nil && hookErr == nil {
+			hookErr = err
+		}
+	}
+	return hookErr
+}
+
+//------------------------------------------------------------------------------
+
+// ErrCommitUnavailable is provided to a commit hook when the source of a
+// message was acknowledged before the hook could be registered.
+var ErrCommitUnavailable = errors.New("the source of the message has already been acknowledged")
+
+func (d *DeliveryHooks) addCommitHook(fn CommitHookFunc) bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if d.committed {
+		return false
+	}
+	d.commitHooks = append(d.commitHooks, fn)
+	return true
+}
+
+// AddCommitHook registers a function to be called once the source of the
+// transaction that a message part belongs to has been acknowledged. Returns
+// false if the part is not associated with delivery hooks, or if the source
+// has already been acknowledged, in which case the function will never be
+// called.
+func AddCommitHook(p types.Part, fn CommitHookFunc) bool {
+	hooks, ok := getDeliveryHooks(p)
+	if !ok {
+		return false
+	}
+	return hooks.addCommitHook(fn)
+}
+
+// AddBatchCommitHook registers a function to be called once the sources of
+// all transactions that a slice of message parts belong to have been
+// acknowledged, with the first error encountered by any of them. Parts of a
+// batch may belong to any number of transactions, and the function is called
+// exactly once. If the source of a transaction was already acknowledged then
+// the function is called with ErrCommitUnavailable, which may happen before
+// this function returns.
+//
+// Returns false if any of the parts are not associated with delivery hooks, in
+// which case the function will never be called and the caller should not
+// depend on the acknowledgement of the sources.
+func AddBatchCommitHook(parts []types.Part, fn CommitHookFunc) bool {
+	var distinct []*DeliveryHooks
+	seen := map[*DeliveryHooks]struct{}{}
+	for _, p := range parts {
+		hooks, ok := getDeliveryHooks(p)
+		if !ok {
+			return false
+		}
+		if _, exists := seen[hooks]; !exists {
+			seen[hooks] = struct{}{}
+			distinct = append(distinct, hooks)
+		}
+	}
+	if len(distinct) == 0 {
+		return false
+	}
+
+	var mut sync.Mutex
+	remaining := len(distinct)
+	var firstErr error
+	hookFn := func(ctx context.Context, err error) error {
+		mut.Lock()
+		remaining--
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		done, resErr := remaining == 0, firstErr
+		mut.Unlock()
+		if !done {
+			return nil
+		}
+		return fn(ctx, resErr)
+	}
+
+	for _, hooks := range distinct {
+		if !hooks.addCommitHook(hookFn) {
+			_ = hookFn(context.Background(), ErrCommitUnavailable)
+		}
+	}
+	return true
+}
+
+// Commit calls each registered commit hook in the order that they were added
+// with the result of acknowledging the source of the transaction, and returns
+// the first error returned by a hook. Commit hooks are only called once, and
+// any hooks added after Commit has been called are rejected.
+func (d *DeliveryHooks) Commit(ctx context.Context, err error) error {
+	d.mut.Lock()
+	hooks := d.commitHooks
+	d.commitHooks = nil
+	d.committed = true
+	d.mut.Unlock()
+
+	var hookErr error
+	for _, fn := range hooks {
+		if herr := fn(ctx, err); herr != nil && hookErr == nil {
+			hookErr = herr
+		}
+	}
+	return hookErr
+}
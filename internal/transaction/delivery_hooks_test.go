@@ -0,0 +1,89 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryHooks(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	assert.False(t, AddDeliveryHook(msg.Get(0), func(ctx context.Context, err error) error {
+		return nil
+	}))
+
+	hooks := AttachDeliveryHooks(msg)
+
+	var results []string
+	assert.True(t, AddDeliveryHook(msg.Get(0), func(ctx context.Context, err error) error {
+		results = append(results, "first: "+err.Error())
+		return errors.New("first failed")
+	}))
+	assert.True(t, AddDeliveryHook(msg.Get(1).Copy(), func(ctx context.Context, err error) error {
+		results = append(results, "second: "+err.Error())
+		return errors.New("second failed")
+	}))
+
+	require.EqualError(t, hooks.Resolve(context.Background(), response.NewError(errors.New("nope"))), "first failed")
+	assert.Equal(t, []string{"first: nope", "second: nope"}, results)
+
+	assert.False(t, AddDeliveryHook(msg.Get(0), func(ctx context.Context, err error) error {
+		return nil
+	}))
+	require.NoError(t, hooks.Resolve(context.Background(), response.NewAck()))
+	assert.Len(t, results, 2)
+}
+
+func TestCommitHooks(t *testing.T) {
+	msgA := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msgB := message.New([][]byte{[]byte("baz")})
+	orphan := message.New([][]byte{[]byte("buz")})
+
+	assert.False(t, AddCommitHook(msgA.Get(0), func(ctx context.Context, err error) error {
+		return nil
+	}))
+
+	hooksA := AttachDeliveryHooks(msgA)
+	hooksB := AttachDeliveryHooks(msgB)
+
+	var single []error
+	assert.True(t, AddCommitHook(msgA.Get(0).Copy(), func(ctx context.Context, err error) error {
+		single = append(single, err)
+		return errors.New("single failed")
+	}))
+
+	assert.False(t, AddBatchCommitHook([]types.Part{msgA.Get(0), orphan.Get(0)}, func(ctx context.Context, err error) error {
+		t.Error("should not be called")
+		return nil
+	}))
+
+	var batched []error
+	assert.True(t, AddBatchCommitHook([]types.Part{msgA.Get(0), msgB.Get(0), msgA.Get(1)}, func(ctx context.Context, err error) error {
+		batched = append(batched, err)
+		return nil
+	}))
+
+	require.EqualError(t, hooksA.Commit(context.Background(), nil), "single failed")
+	assert.Equal(t, []error{nil}, single)
+	assert.Empty(t, batched)
+
+	require.NoError(t, hooksB.Commit(context.Background(), errors.New("ack failed")))
+	require.Len(t, batched, 1)
+	assert.EqualError(t, batched[0], "ack failed")
+
+	assert.False(t, AddCommitHook(msgA.Get(0), func(ctx context.Context, err error) error {
+		return nil
+	}))
+	assert.True(t, AddBatchCommitHook([]types.Part{msgA.Get(0)}, func(ctx context.Context, err error) error {
+		batched = append(batched, err)
+		return nil
+	}))
+	require.Len(t, batched, 2)
+	assert.Equal(t, ErrCommitUnavailable, batched[1])
+}
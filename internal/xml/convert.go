@@ -0,0 +1,452 @@
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// TextKey is the key of the text content of elements that also contain
+	// attributes or child elements.
+	TextKey = "#text"
+
+	// CDATAKey is the key of the contents of CDATA sections when they are
+	// preserved.
+	CDATAKey = "#cdata"
+)
+
+// Options configures the conversion of XML documents to and from generic
+// structures.
+type Options struct {
+	// Cast attempts to convert values that are numbers and booleans into the
+	// right type rather than strings.
+	Cast bool
+
+	// AttributePrefix is prefixed to the names of attributes in order to
+	// distinguish them from child elements.
+	AttributePrefix string
+
+	// IgnoreAttributes drops all attributes from parsed documents.
+	IgnoreAttributes bool
+
+	// ArrayPaths are dot separated paths of elements, starting with the root
+	// element, that are always parsed as arrays even when they appear only
+	// once. A path segment `*` matches any element name.
+	ArrayPaths []string
+
+	// PreserveCDATA parses the contents of CDATA sections under the key
+	// CDATAKey rather than merging them with text.
+	PreserveCDATA bool
+
+	// KeepNamespaces retains the namespace prefixes of element and attribute
+	// names rather than stripping them.
+	KeepNamespaces bool
+}
+
+// NewOptions returns the default options, which match the behaviour of ToMap.
+func NewOptions() Options {
+	return Options{
+		AttributePrefix: "-",
+	}
+}
+
+// Converter converts XML documents to and from generic structures.
+type Converter struct {
+	opts       Options
+	arrayPaths [][]string
+}
+
+// NewConverter creates a converter from options.
+func NewConverter(opts Options) *Converter {
+	c := &Converter{opts: opts}
+	for _, p := range opts.ArrayPaths {
+		if p = strings.Trim(p, "."); p != "" {
+			c.arrayPaths = append(c.arrayPaths, strings.Split(p, "."))
+		}
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+// recordingReader records all bytes consumed by a decoder, which allows us to
+// identify tokens that were parsed from CDATA sections.
+type recordingReader struct {
+	src io.Reader
+	br  io.ByteReader
+	rec *[]byte
+}
+
+func newRecordingReader(src io.Reader, br io.ByteReader, rec *[]byte) *recordingReader {
+	return &recordingReader{src: src, br: br, rec: rec}
+}
+
+func (r *recordingReader) ReadByte() (byte, error) {
+	b, err := r.br.ReadByte()
+	if err == nil {
+		*r.rec = append(*r.rec, b)
+	}
+	return b, err
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = b
+	return 1, nil
+}
+
+// charsetReader converts documents of other encodings into UTF-8 whilst
+// continuing to record the converted bytes.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	rr, ok := input.(*recordingReader)
+	if !ok {
+		return charset.NewReaderLabel(label, input)
+	}
+	conv, err := charset.NewReaderLabel(label, rr.src)
+	if err != nil {
+		return nil, err
+	}
+	return newRecordingReader(conv, bufio.NewReader(conv), rr.rec), nil
+}
+
+type decoder struct {
+	c   *Converter
+	dec *xml.Decoder
+	rec []byte
+
+	// Namespace declarations of the elements being parsed, from the outermost
+	// to the innermost, mapping namespace URLs to prefixes.
+	namespaces []map[string]string
+}
+
+// Decode parses a byte slice as an XML document and returns a generic
+// structure that can be serialized to JSON.
+func (c *Converter) Decode(xmlBytes []byte) (map[string]interface{}, error) {
+	d := &decoder{c: c}
+
+	src := bytes.NewReader(xmlBytes)
+	d.dec = xml.NewDecoder(newRecordingReader(src, src, &d.rec))
+	d.dec.Strict = false
+	d.dec.CharsetReader = charsetReader
+
+	for {
+		t, err := d.dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no root element found")
+			}
+			return nil, err
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			name := d.name(start, start.Name)
+			v, err := d.element(start, []string{name})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{name: v}, nil
+		}
+	}
+}
+
+func (c *Converter) isArrayPath(path []string) bool {
+pathLoop:
+	for _, p := range c.arrayPaths {
+		if len(p) != len(path) {
+			continue
+		}
+		for i, seg := range p {
+			if seg != "*" && seg != path[i] {
+				continue pathLoop
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (c *Converter) cast(s string) interface{} {
+	if !c.opts.Cast {
+		return s
+	}
+	switch strings.ToLower(s) {
+	case "nan", "inf", "+inf", "-inf":
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if len(s) > 0 {
+		switch s[0] {
+		case 't', 'T', 'f', 'F':
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return s
+}
+
+// name returns the key of an element or attribute name.
+func (d *decoder) name(start xml.StartElement, n xml.Name) string {
+	if n.Space == "" || n.Space == "xmlns" {
+		if n.Space == "xmlns" {
+			return "xmlns:" + n.Local
+		}
+		return n.Local
+	}
+	if !d.c.opts.KeepNamespaces {
+		return n.Local
+	}
+
+	// The decoder replaces the prefixes of names with the URL of their
+	// namespace, which is mapped back to the declared prefix.
+	prefix, found := "", false
+	for _, attr := range start.Attr {
+		if attr.Value != n.Space {
+			continue
+		}
+		if attr.Name.Space == "xmlns" {
+			prefix, found = attr.Name.Local, true
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			prefix, found = "", true
+		}
+	}
+	for i := len(d.namespaces) - 1; i >= 0 && !found; i-- {
+		prefix, found = d.namespaces[i][n.Space]
+	}
+	if !found {
+		// Prefixes that were never declared are left as they are.
+		prefix = n.Space
+	}
+	if prefix == "" {
+		return n.Local
+	}
+	return prefix + ":" + n.Local
+}
+
+func (d *decoder) element(start xml.StartElement, path []string) (interface{}, error) {
+	declared := map[string]string{}
+	n := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			declared[attr.Value] = attr.Name.Local
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			declared[attr.Value] = ""
+		}
+		if d.c.opts.IgnoreAttributes {
+			continue
+		}
+		n[d.c.opts.AttributePrefix+d.name(start, attr.Name)] = d.c.cast(attr.Value)
+	}
+	d.namespaces = append(d.namespaces, declared)
+	defer func() {
+		d.namespaces = d.namespaces[:len(d.namespaces)-1]
+	}()
+
+	var text, cdata strings.Builder
+	var hasCDATA bool
+	for {
+		offset := int(d.dec.InputOffset())
+		t, err := d.dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch tok := t.(type) {
+		case xml.StartElement:
+			name := d.name(tok, tok.Name)
+			childPath := append(path[:len(path):len(path)], name)
+			child, err := d.element(tok, childPath)
+			if err != nil {
+				return nil, err
+			}
+			if existing, exists := n[name]; exists {
+				if arr, isArr := existing.([]interface{}); isArr {
+					n[name] = append(arr, child)
+				} else {
+					n[name] = []interface{}{existing, child}
+				}
+			} else if d.c.isArrayPath(childPath) {
+				n[name] = []interface{}{child}
+			} else {
+				n[name] = child
+			}
+		case xml.EndElement:
+			if hasCDATA {
+				n[CDATAKey] = cdata.String()
+			}
+			if text.Len() > 0 {
+				if len(n) == 0 {
+					return d.c.cast(text.String()), nil
+				}
+				n[TextKey] = d.c.cast(text.String())
+			}
+			if len(n) == 0 {
+				return "", nil
+			}
+			return n, nil
+		case xml.CharData:
+			if d.c.opts.PreserveCDATA && offset < len(d.rec) && bytes.HasPrefix(d.rec[offset:], []byte("<![CDATA[")) {
+				hasCDATA = true
+				cdata.Write(tok)
+				continue
+			}
+			text.WriteString(strings.Trim(string(tok), "\t\r\b\n "))
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	textEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;")
+	attrEscaper = strings.NewReplacer(
+		`&`, "&amp;", `<`, "&lt;", `"`, "&quot;",
+		"\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;",
+	)
+)
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	return !strings.ContainsAny(name, " \t\r\n<>&\"'/=")
+}
+
+func scalarString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(t), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+	return "", fmt.Errorf("expected a scalar value, got %T", v)
+}
+
+// Encode converts a generic structure into an XML document, following the
+// same rules as Decode in reverse. The structure must be an object with a
+// single key, which is the name of the root element.
+func (c *Converter) Encode(root interface{}) ([]byte, error) {
+	obj, ok := root.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, errors.New("expected an object with a single key as the root element")
+	}
+
+	var buf bytes.Buffer
+	for k, v := range obj {
+		if _, isArr := v.([]interface{}); isArr {
+			return nil, errors.New("expected a single root element")
+		}
+		if err := c.encodeElement(&buf, k, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Converter) isAttribute(key string) bool {
+	return c.opts.AttributePrefix != "" && strings.HasPrefix(key, c.opts.AttributePrefix)
+}
+
+func (c *Converter) encodeElement(buf *bytes.Buffer, name string, v interface{}) error {
+	if !validName(name) {
+		return fmt.Errorf("invalid element name: %q", name)
+	}
+
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if err := c.encodeElement(buf, name, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("<" + name)
+		for _, k := range keys {
+			if !c.isAttribute(k) {
+				continue
+			}
+			attrName := strings.TrimPrefix(k, c.opts.AttributePrefix)
+			if !validName(attrName) {
+				return fmt.Errorf("invalid attribute name: %q", attrName)
+			}
+			value, err := scalarString(t[k])
+			if err != nil {
+				return fmt.Errorf("attribute %v: %w", attrName, err)
+			}
+			buf.WriteString(" " + attrName + `="` + attrEscaper.Replace(value) + `"`)
+		}
+		buf.WriteString(">")
+
+		if text, exists := t[TextKey]; exists {
+			value, err := scalarString(text)
+			if err != nil {
+				return fmt.Errorf("element %v text: %w", name, err)
+			}
+			buf.WriteString(textEscaper.Replace(value))
+		}
+		if cdata, exists := t[CDATAKey]; exists {
+			value, err := scalarString(cdata)
+			if err != nil {
+				return fmt.Errorf("element %v CDATA: %w", name, err)
+			}
+			buf.WriteString("<![CDATA[" + strings.ReplaceAll(value, "]]>", "]]]]><![CDATA[>") + "]]>")
+		}
+		for _, k := range keys {
+			if k == TextKey || k == CDATAKey || c.isAttribute(k) {
+				continue
+			}
+			if err := c.encodeElement(buf, k, t[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	}
+
+	value, err := scalarString(v)
+	if err != nil {
+		return fmt.Errorf("element %v: %w", name, err)
+	}
+	buf.WriteString("<" + name + ">" + textEscaper.Replace(value) + "</" + name + ">")
+	return nil
+}
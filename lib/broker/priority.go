@@ -0,0 +1,218 @@
+package broker
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type pendingTran struct {
+	priority int
+	seq      uint64
+	tran     types.Transaction
+}
+
+// pendingQueue is a heap of transactions ordered by highest priority first,
+// and then by their order of arrival.
+type pendingQueue []pendingTran
+
+func (q pendingQueue) Len() int {
+	return len(q)
+}
+
+func (q pendingQueue) Less(i, j int) bool {
+	if q[i].priority == q[j].priority {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].priority > q[j].priority
+}
+
+func (q pendingQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *pendingQueue) Push(x interface{}) {
+	*q = append(*q, x.(pendingTran))
+}
+
+func (q *pendingQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = pendingTran{}
+	*q = old[:n-1]
+	return item
+}
+
+//------------------------------------------------------------------------------
+
+// Priority is a broker that implements types.Consumer and sends each message
+// out to a single consumer, where consumers claim messages as soon as they are
+// able to process them. Whilst all consumers are busy messages are held in a
+// pending queue, and when a consumer becomes available the pending message
+// with the highest priority is sent first.
+type Priority struct {
+	running int32
+
+	stats      metrics.Type
+	prioritise func(msg types.Message) int
+	maxPending int
+
+	transactions <-chan types.Transaction
+
+	outputTSChan chan types.Transaction
+	outputs      []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewPriority creates a new Priority type by providing consumers, a function
+// that determines the priority of each message, where higher values are sent
+// first, and the maximum number of messages to hold whilst consumers are busy.
+func NewPriority(outputs []types.Output, prioritise func(msg types.Message) int, maxPending int, stats metrics.Type) (*Priority, error) {
+	if maxPending < 1 {
+		maxPending = 1
+	}
+	o := &Priority{
+		running:      1,
+		stats:        stats,
+		prioritise:   prioritise,
+		maxPending:   maxPending,
+		transactions: nil,
+		outputTSChan: make(chan types.Transaction),
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+	for _, out := range o.outputs {
+		if err := out.Consume(o.outputTSChan); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new messages channel for the broker to read.
+func (o *Priority) Consume(ts <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = ts
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *Priority) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (o *Priority) MaxInFlight() (int, bool) {
+	m := o.maxPending
+	for _, out := range o.outputs {
+		if mif, exists := output.GetMaxInFlight(out); exists {
+			m += mif
+		} else {
+			m++
+		}
+	}
+	return m, true
+}
+
+//------------------------------------------------------------------------------
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *Priority) loop() {
+	defer func() {
+		close(o.outputTSChan)
+		closeAllOutputs(o.outputs)
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd = o.stats.GetCounter("messages.received")
+		mPending  = o.stats.GetGauge("broker.priority.pending")
+	)
+
+	var seq uint64
+	pending := &pendingQueue{}
+	inChan := o.transactions
+
+	for atomic.LoadInt32(&o.running) == 1 {
+		if inChan == nil && pending.Len() == 0 {
+			return
+		}
+
+		// Stop reading new messages once the pending queue is full so that
+		// back pressure is applied upstream.
+		readChan := inChan
+		if pending.Len() >= o.maxPending {
+			readChan = nil
+		}
+
+		var writeChan chan types.Transaction
+		var next types.Transaction
+		if pending.Len() > 0 {
+			writeChan = o.outputTSChan
+			next = (*pending)[0].tran
+		}
+
+		select {
+		case ts, open := <-readChan:
+			if !open {
+				inChan = nil
+				continue
+			}
+			mMsgsRcvd.Incr(1)
+			heap.Push(pending, pendingTran{
+				priority: o.prioritise(ts.Payload),
+				seq:      seq,
+				tran:     ts,
+			})
+			seq++
+		case writeChan <- next:
+			heap.Pop(pending)
+		case <-o.closeChan:
+			return
+		}
+		mPending.Set(int64(pending.Len()))
+	}
+}
+
+// CloseAsync shuts down the Priority broker and stops processing requests.
+func (o *Priority) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Priority broker has closed down.
+func (o *Priority) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ types.Consumer = &Priority{}
+var _ types.Closable = &Priority{}
+
+func TestPriorityDoubleClose(t *testing.T) {
+	oTM, err := NewPriority([]types.Output{}, func(types.Message) int { return 0 }, 1, metrics.Noop())
+	require.NoError(t, err)
+
+	// This shouldn't cause a panic
+	oTM.CloseAsync()
+	oTM.CloseAsync()
+}
+
+func TestPriorityOrdering(t *testing.T) {
+	mockOutput := &MockOutputType{}
+
+	prioritise := func(msg types.Message) int {
+		p, _ := strconv.Atoi(string(msg.Get(0).Get()))
+		return p
+	}
+
+	oTM, err := NewPriority([]types.Output{mockOutput}, prioritise, 10, metrics.Noop())
+	require.NoError(t, err)
+
+	mif, ok := oTM.MaxInFlight()
+	require.True(t, ok)
+	assert.Equal(t, 11, mif)
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response, 10)
+	require.NoError(t, oTM.Consume(readChan))
+
+	sendMsg := func(content string) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	// The first message is claimed by the output straight away, the rest are
+	// held until the output is ready again.
+	sendMsg("0")
+	var first types.Transaction
+	select {
+	case first = <-mockOutput.TChan:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	assert.Equal(t, "0", string(first.Payload.Get(0).Get()))
+
+	for _, content := range []string{"1", "5", "2", "5", "9"} {
+		sendMsg(content)
+	}
+	first.ResponseChan <- response.NewAck()
+
+	var received []string
+	for i := 0; i < 5; i++ {
+		select {
+		case ts := <-mockOutput.TChan:
+			received = append(received, string(ts.Payload.Get(0).Get()))
+			ts.ResponseChan <- response.NewAck()
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	assert.Equal(t, []string{"9", "5", "5", "2", "1"}, received)
+
+	for i := 0; i < 6; i++ {
+		select {
+		case res := <-resChan:
+			assert.NoError(t, res.Error())
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	close(readChan)
+	require.NoError(t, oTM.WaitForClose(time.Second))
+}
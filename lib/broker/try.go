@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -158,6 +160,12 @@ func (t *Try) loop() {
 			var res types.Response
 			var lOpen bool
 
+			// When an output reports which messages of a batch failed only
+			// those messages are sent to the next output, and indexes maps
+			// them back to their positions within the original batch.
+			payload := tran.Payload
+			var indexes []int
+
 		triesLoop:
 			for i := 1; i <= len(t.outputTSChans); i++ {
 				select {
@@ -175,13 +183,20 @@ func (t *Try) loop() {
 				}
 
 				if i < len(t.outputTSChans) {
+					if failed, failedIndexes, ok := batch.FailedSubset(payload, res.Error()); ok {
+						payload = failed
+						indexes = remapIndexes(indexes, failedIndexes)
+					}
 					select {
-					case t.outputTSChans[i] <- types.NewTransaction(tran.Payload, rChan):
+					case t.outputTSChans[i] <- types.NewTransaction(payload, rChan):
 					case <-t.ctx.Done():
 						return
 					}
 				}
 			}
+			if res.Error() != nil && indexes != nil {
+				res = response.NewError(failedSubsetError(tran.Payload, payload, indexes, res.Error()))
+			}
 			select {
 			case tran.ResponseChan <- res:
 			case <-t.ctx.Done():
@@ -197,6 +212,34 @@ func (t *Try) loop() {
 	}
 }
 
+// remapIndexes converts the indexes of a subset of a batch, where the batch is
+// itself a subset described by indexes, into indexes of the original batch.
+func remapIndexes(indexes, subsetIndexes []int) []int {
+	if indexes == nil {
+		return subsetIndexes
+	}
+	remapped := make([]int, len(subsetIndexes))
+	for i, j := range subsetIndexes {
+		remapped[i] = indexes[j]
+	}
+	return remapped
+}
+
+// failedSubsetError creates a batch error for the original batch of a
+// transaction from the error returned for a subset of it.
+func failedSubsetError(source, subset types.Message, indexes []int, err error) error {
+	if _, failedIndexes, ok := batch.FailedSubset(subset, err); ok {
+		indexes = remapIndexes(indexes, failedIndexes)
+	}
+	bErr := batch.NewError(source, err)
+	for _, i := range indexes {
+		bErr.Failed(i, err)
+	}
+	return bErr
+}
+
+//------------------------------------------------------------------------------
+
 // CloseAsync shuts down the Try broker and stops processing requests.
 func (t *Try) CloseAsync() {
 	t.close()
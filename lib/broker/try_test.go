@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
@@ -283,6 +284,97 @@ func TestTryAllFail(t *testing.T) {
 	}
 }
 
+func TestTryBatchErrorSubset(t *testing.T) {
+	outputs := []types.Output{}
+	mockOutputs := []*MockOutputType{
+		{},
+		{},
+		{},
+	}
+
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewTry(outputs, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	content := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz"), []byte("buz")}
+	select {
+	case readChan <- types.NewTransaction(message.New(content), resChan):
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for broker send")
+	}
+
+	testErr := errors.New("test error")
+	go func() {
+		for j, exp := range [][][]byte{
+			content,
+			{[]byte("bar"), []byte("buz")},
+			{[]byte("buz")},
+		} {
+			var ts types.Transaction
+			select {
+			case ts = <-mockOutputs[j].TChan:
+			case <-time.After(time.Second):
+				t.Errorf("Timed out waiting for broker propagate")
+				return
+			}
+			if act := message.GetAllBytes(ts.Payload); fmt.Sprintf("%s", act) != fmt.Sprintf("%s", exp) {
+				t.Errorf("Wrong content sent to output %v: %s != %s", j, act, exp)
+			}
+
+			var res types.Response
+			switch j {
+			case 0:
+				res = response.NewError(batch.NewError(ts.Payload, testErr).Failed(1, testErr).Failed(3, testErr))
+			case 1:
+				res = response.NewError(batch.NewError(ts.Payload, testErr).Failed(1, testErr))
+			default:
+				res = response.NewError(testErr)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Errorf("Timed out responding to broker")
+			}
+		}
+	}()
+
+	select {
+	case res := <-resChan:
+		bErr, ok := res.Error().(*batch.Error)
+		if !ok {
+			t.Fatalf("Wrong error type returned: %T", res.Error())
+		}
+		var failed []int
+		bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+			if err != nil {
+				failed = append(failed, i)
+			}
+			return true
+		})
+		if exp, act := []int{3}, failed; fmt.Sprintf("%v", act) != fmt.Sprintf("%v", exp) {
+			t.Errorf("Wrong failed indexes: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestTryAllFailParallel(t *testing.T) {
 	outputs := []types.Output{}
 	mockOutputs := []*MockOutputType{
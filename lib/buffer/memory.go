@@ -35,12 +35,32 @@ significantly below the amount of RAM available.
 This buffer intentionally weakens the delivery guarantees of the pipeline and
 therefore should never be used in places where data loss is unacceptable.
 
+## Spilling to Disk
+
+When ` + "`spill.enabled`" + ` is set to ` + "`true`" + ` messages that would exceed the
+memory limit are written to files within ` + "`spill.directory`" + ` rather than
+applying back pressure, until the optional ` + "`spill.limit`" + ` of bytes on disk is
+reached. Messages are read in the order that they were written, and therefore
+spilled messages are read back from disk preferentially over newer messages held
+in memory. Messages spilled to disk are preserved during shutdown and read again
+when the buffer is next started with the same directory.
+
+The gauges ` + "`buffer.memory.backlog`" + ` and ` + "`buffer.disk.backlog`" + ` report the
+bytes held in memory and on disk respectively. Spilled messages that cannot be
+read back from disk are logged as errors, removed, and counted by the counter
+` + "`buffer.disk.dropped`" + `.
+
 ## Batching
 
 It is possible to batch up messages sent from this buffer using a
 [batch policy](/docs/configuration/batching#batch-policy).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("limit", "The maximum buffer size (in bytes) to allow before applying backpressure upstream."),
+			docs.FieldAdvanced("spill", "Optionally spill messages to disk once the memory limit is reached, rather than applying backpressure upstream.").WithChildren(
+				docs.FieldAdvanced("enabled", "Whether to spill messages to disk once the memory limit is reached."),
+				docs.FieldAdvanced("directory", "A directory to write spilled messages to, which is created if it does not exist."),
+				docs.FieldAdvanced("limit", "The maximum size (in bytes) of spilled messages to allow on disk before applying backpressure upstream. If `0` the size on disk is unlimited."),
+			).AtVersion("3.64.0"),
 			docs.FieldCommon("batch_policy", "Optionally configure a policy to flush buffered messages in batches.").WithChildren(
 				append(docs.FieldSpecs{
 					docs.FieldCommon("enabled", "Whether to batch messages as they are flushed."),
@@ -58,9 +78,18 @@ type EnabledBatchPolicyConfig struct {
 	batch.PolicyConfig `json:",inline" yaml:",inline"`
 }
 
+// MemorySpillConfig contains config fields for spilling messages from a memory
+// buffer to disk.
+type MemorySpillConfig struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Directory string `json:"directory" yaml:"directory"`
+	Limit     int    `json:"limit" yaml:"limit"`
+}
+
 // MemoryConfig is config values for a purely memory based ring buffer type.
 type MemoryConfig struct {
 	Limit       int                      `json:"limit" yaml:"limit"`
+	Spill       MemorySpillConfig        `json:"spill" yaml:"spill"`
 	BatchPolicy EnabledBatchPolicyConfig `json:"batch_policy" yaml:"batch_policy"`
 }
 
@@ -68,6 +97,11 @@ type MemoryConfig struct {
 func NewMemoryConfig() MemoryConfig {
 	return MemoryConfig{
 		Limit: 1024 * 1024 * 500, // 500MB
+		Spill: MemorySpillConfig{
+			Enabled:   false,
+			Directory: "",
+			Limit:     0,
+		},
 		BatchPolicy: EnabledBatchPolicyConfig{
 			Enabled:      false,
 			PolicyConfig: batch.NewPolicyConfig(),
@@ -79,7 +113,19 @@ func NewMemoryConfig() MemoryConfig {
 
 // NewMemory creates a buffer held in memory.
 func NewMemory(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	wrap := NewParallelWrapper(config, parallel.NewMemory(config.Memory.Limit), log, stats)
+	var buf Parallel = parallel.NewMemory(config.Memory.Limit)
+	if config.Memory.Spill.Enabled {
+		var err error
+		if buf, err = parallel.NewSpill(
+			config.Memory.Limit,
+			config.Memory.Spill.Directory,
+			config.Memory.Spill.Limit,
+			log, stats,
+		); err != nil {
+			return nil, err
+		}
+	}
+	wrap := NewParallelWrapper(config, buf, log, stats)
 	if !config.Memory.BatchPolicy.Enabled {
 		return wrap, nil
 	}
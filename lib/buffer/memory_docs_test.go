@@ -23,6 +23,10 @@ func TestMemorySanit(t *testing.T) {
 
 	expSanit := `memory:
     limit: 524288000
+    spill:
+        enabled: false
+        directory: ""
+        limit: 0
     batch_policy:
         enabled: false
         count: 0
@@ -0,0 +1,435 @@
+package parallel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+const spillFileExt = ".spill"
+
+type spillMemEntry struct {
+	seq  uint64
+	size int
+	msg  types.Message
+}
+
+type spillDiskEntry struct {
+	seq  uint64
+	size int
+}
+
+type spillPart struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Value    []byte            `json:"value"`
+}
+
+// Spill is a parallel buffer implementation that holds messages in memory up
+// to a capacity, and once that capacity is reached writes (spills) further
+// messages to files within a directory on disk.
+//
+// Messages are read in the order that they were written regardless of whether
+// they are held in memory or on disk, which means spilled messages are read
+// back preferentially over any newer messages held in memory.
+type Spill struct {
+	dir     string
+	memCap  int
+	diskCap int
+	log     log.Modular
+
+	messages    []spillMemEntry
+	memBytes    int
+	memPending  int
+	files       []spillDiskEntry
+	diskBytes   int
+	diskPending int
+	nextSeq     uint64
+
+	// Sequence numbers of spilled messages that are still being written to
+	// disk, in ascending order. Messages with a higher sequence number are not
+	// read until these writes have finished.
+	writing []uint64
+
+	mMemBacklog  metrics.StatGauge
+	mDiskBacklog metrics.StatGauge
+	mSpilled     metrics.StatCounter
+	mDropped     metrics.StatCounter
+
+	cond *sync.Cond
+
+	closed bool
+}
+
+// NewSpill creates a parallel buffer that holds messages in memory up to
+// memCapacity bytes and spills messages beyond that to files within dir. A
+// diskCapacity of zero or less means the bytes spilled to disk are unlimited.
+//
+// Files left in the directory by a previous instance are read back before any
+// new messages.
+func NewSpill(memCapacity int, dir string, diskCapacity int, log log.Modular, stats metrics.Type) (*Spill, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("a spill directory must be specified")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	s := &Spill{
+		dir:          dir,
+		memCap:       memCapacity,
+		diskCap:      diskCapacity,
+		log:          log,
+		mMemBacklog:  stats.GetGauge("memory.backlog"),
+		mDiskBacklog: stats.GetGauge("disk.backlog"),
+		mSpilled:     stats.GetCounter("disk.spilled"),
+		mDropped:     stats.GetCounter("disk.dropped"),
+		cond:         sync.NewCond(&sync.Mutex{}),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spill directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spillFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spillFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spill directory: %w", err)
+		}
+		s.files = append(s.files, spillDiskEntry{seq: seq, size: int(info.Size())})
+		s.diskBytes += int(info.Size())
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].seq < s.files[j].seq
+	})
+	s.updateBacklog()
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Spill) filePath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%v", seq, spillFileExt))
+}
+
+func (s *Spill) updateBacklog() {
+	s.mMemBacklog.Set(int64(s.memBytes))
+	s.mDiskBacklog.Set(int64(s.diskBytes))
+}
+
+func spillMessageToBytes(msg types.Message) ([]byte, error) {
+	parts := make([]spillPart, 0, msg.Len())
+	_ = msg.Iter(func(i int, p types.Part) error {
+		var meta map[string]string
+		_ = p.Metadata().Iter(func(k, v string) error {
+			if meta == nil {
+				meta = map[string]string{}
+			}
+			meta[k] = v
+			return nil
+		})
+		parts = append(parts, spillPart{
+			Metadata: meta,
+			Value:    p.Get(),
+		})
+		return nil
+	})
+	return json.Marshal(parts)
+}
+
+func spillMessageFromBytes(b []byte) (types.Message, error) {
+	var parts []spillPart
+	if err := json.Unmarshal(b, &parts); err != nil {
+		return nil, err
+	}
+	msg := message.New(nil)
+	for _, p := range parts {
+		part := message.NewPart(p.Value)
+		for k, v := range p.Metadata {
+			part.Metadata().Set(k, v)
+		}
+		msg.Append(part)
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// nextReady returns whether the next oldest message can be read, which is not
+// the case while an older message is still being spilled to disk. Must be
+// called with the lock held.
+func (s *Spill) nextReady() bool {
+	var seq uint64
+	switch {
+	case len(s.files) > 0 && (len(s.messages) == 0 || s.files[0].seq < s.messages[0].seq):
+		seq = s.files[0].seq
+	case len(s.messages) > 0:
+		seq = s.messages[0].seq
+	default:
+		return false
+	}
+	return len(s.writing) == 0 || s.writing[0] > seq
+}
+
+// NextMessage reads the next oldest message, the message is preserved until the
+// returned AckFunc is called.
+func (s *Spill) NextMessage() (types.Message, AckFunc, error) {
+	s.cond.L.Lock()
+	for !s.nextReady() && !s.closed {
+		s.cond.Wait()
+	}
+
+	if s.closed {
+		s.cond.L.Unlock()
+		return nil, nil, types.ErrTypeClosed
+	}
+
+	if len(s.files) > 0 && (len(s.messages) == 0 || s.files[0].seq < s.messages[0].seq) {
+		return s.nextFromDisk()
+	}
+
+	entry := s.messages[0]
+
+	s.messages[0] = spillMemEntry{}
+	s.messages = s.messages[1:]
+	s.memPending += entry.size
+
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+
+	return entry.msg, func(ack bool) (int, error) {
+		s.cond.L.Lock()
+		if s.closed {
+			s.cond.L.Unlock()
+			return 0, types.ErrTypeClosed
+		}
+		s.memPending -= entry.size
+		if ack {
+			s.memBytes -= entry.size
+			s.updateBacklog()
+		} else {
+			s.messages = append([]spillMemEntry{entry}, s.messages...)
+		}
+		s.cond.Broadcast()
+
+		backlog := s.memBytes + s.diskBytes
+		s.cond.L.Unlock()
+
+		return backlog, nil
+	}, nil
+}
+
+// nextFromDisk reads the oldest spilled message, and must be called with the
+// lock held. The lock is released before returning.
+func (s *Spill) nextFromDisk() (types.Message, AckFunc, error) {
+	entry := s.files[0]
+	s.files = s.files[1:]
+
+	path := s.filePath(entry.seq)
+	msg, err := s.readFile(path)
+	if err != nil {
+		// The file cannot be recovered and is therefore dropped in order to
+		// avoid repeatedly failing to read it.
+		s.log.Errorf("Dropping spilled message '%v' as it could not be read: %v\n", path, err)
+		s.mDropped.Incr(1)
+		_ = os.Remove(path)
+		s.diskBytes -= entry.size
+		s.updateBacklog()
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+		return nil, nil, fmt.Errorf("failed to read spilled message '%v': %w", path, err)
+	}
+	s.diskPending += entry.size
+
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+
+	return msg, func(ack bool) (int, error) {
+		s.cond.L.Lock()
+		if s.closed {
+			s.cond.L.Unlock()
+			return 0, types.ErrTypeClosed
+		}
+		s.diskPending -= entry.size
+		var err error
+		if ack {
+			if err = os.Remove(path); err != nil && os.IsNotExist(err) {
+				err = nil
+			}
+			s.diskBytes -= entry.size
+			s.updateBacklog()
+		} else {
+			s.files = append([]spillDiskEntry{entry}, s.files...)
+		}
+		s.cond.Broadcast()
+
+		backlog := s.memBytes + s.diskBytes
+		s.cond.L.Unlock()
+
+		if err != nil {
+			return backlog, fmt.Errorf("failed to remove spilled message '%v': %w", path, err)
+		}
+		return backlog, nil
+	}, nil
+}
+
+func (s *Spill) readFile(path string) (types.Message, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return spillMessageFromBytes(b)
+}
+
+// PushMessage adds a new message to the buffer, which is held in memory if
+// there is capacity and is otherwise spilled to disk. Returns the backlog in
+// bytes.
+func (s *Spill) PushMessage(msg types.Message) (int, error) {
+	extraBytes := 0
+	_ = msg.Iter(func(i int, b types.Part) error {
+		extraBytes += len(b.Get())
+		return nil
+	})
+
+	var spilled []byte
+	if extraBytes > s.memCap {
+		var err error
+		if spilled, err = spillMessageToBytes(msg); err != nil {
+			return 0, err
+		}
+		if s.diskCap > 0 && len(spilled) > s.diskCap {
+			return 0, types.ErrMessageTooLarge
+		}
+	}
+
+	s.cond.L.Lock()
+	for {
+		if s.closed {
+			s.cond.L.Unlock()
+			return 0, types.ErrTypeClosed
+		}
+		if spilled == nil && (s.memBytes+extraBytes) <= s.memCap {
+			s.messages = append(s.messages, spillMemEntry{
+				seq:  s.nextSeq,
+				size: extraBytes,
+				msg:  msg.DeepCopy(),
+			})
+			s.memBytes += extraBytes
+			s.nextSeq++
+			s.updateBacklog()
+
+			backlog := s.memBytes + s.diskBytes
+			s.cond.Broadcast()
+			s.cond.L.Unlock()
+			return backlog, nil
+		}
+		if spilled == nil {
+			s.cond.L.Unlock()
+			var err error
+			if spilled, err = spillMessageToBytes(msg); err != nil {
+				return 0, err
+			}
+			s.cond.L.Lock()
+			continue
+		}
+		if s.diskCap <= 0 || (s.diskBytes+len(spilled)) <= s.diskCap {
+			break
+		}
+		if extraBytes <= s.memCap {
+			// Memory may free up before the disk does.
+			spilled = nil
+		}
+		s.cond.Wait()
+	}
+
+	// The sequence and disk capacity are reserved before writing the file so
+	// that the lock isn't held during the write.
+	seq := s.nextSeq
+	s.nextSeq++
+	s.diskBytes += len(spilled)
+	s.writing = append(s.writing, seq)
+	s.cond.L.Unlock()
+
+	writeErr := os.WriteFile(s.filePath(seq), spilled, 0o644)
+
+	s.cond.L.Lock()
+	for i, w := range s.writing {
+		if w == seq {
+			s.writing = append(s.writing[:i], s.writing[i+1:]...)
+			break
+		}
+	}
+	if writeErr != nil {
+		s.diskBytes -= len(spilled)
+	} else {
+		// Concurrent writes may complete out of order.
+		i := sort.Search(len(s.files), func(i int) bool {
+			return s.files[i].seq > seq
+		})
+		s.files = append(s.files, spillDiskEntry{})
+		copy(s.files[i+1:], s.files[i:])
+		s.files[i] = spillDiskEntry{seq: seq, size: len(spilled)}
+		s.mSpilled.Incr(1)
+	}
+	s.updateBacklog()
+
+	backlog := s.memBytes + s.diskBytes
+
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+
+	if writeErr != nil {
+		return 0, fmt.Errorf("failed to spill message to disk: %w", writeErr)
+	}
+	return backlog, nil
+}
+
+// CloseOnceEmpty closes the Buffer once the buffer has been emptied, this is a
+// way for a writer to signal to a reader that it is finished writing messages,
+// and therefore the reader can close once it is caught up. This call blocks
+// until the close is completed.
+func (s *Spill) CloseOnceEmpty() {
+	s.cond.L.Lock()
+	for (s.memBytes-s.memPending > 0 || s.diskBytes-s.diskPending > 0) && !s.closed {
+		s.cond.Wait()
+	}
+	if !s.closed {
+		s.closed = true
+		s.cond.Broadcast()
+	}
+	s.cond.L.Unlock()
+}
+
+// Close closes the Buffer so that blocked readers or writers become
+// unblocked. Messages that remain spilled to disk are preserved and will be
+// read by the next buffer created with the same directory.
+func (s *Spill) Close() {
+	s.cond.L.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+}
+
+//------------------------------------------------------------------------------
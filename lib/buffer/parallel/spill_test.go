@@ -0,0 +1,250 @@
+package parallel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillOrdering(t *testing.T) {
+	dir := t.TempDir()
+	stats := metrics.NewLocal()
+
+	block, err := NewSpill(10, dir, 0, log.Noop(), stats)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("hello%v", i))})
+		msg.Get(0).Metadata().Set("index", fmt.Sprintf("%v", i))
+		_, err := block.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 9)
+
+	gauges := stats.GetCounters()
+	assert.Equal(t, int64(6), gauges["memory.backlog"])
+	assert.Greater(t, gauges["disk.backlog"], int64(0))
+	assert.Equal(t, int64(9), stats.GetCounters()["disk.spilled"])
+
+	// Free up memory and push a message that is held in memory, it should
+	// still be read after everything spilled to disk.
+	msg, ackFn, err := block.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "hello0", string(msg.Get(0).Get()))
+	_, err = ackFn(true)
+	require.NoError(t, err)
+
+	_, err = block.PushMessage(message.New([][]byte{[]byte("hello10")}))
+	require.NoError(t, err)
+
+	// A nacked spilled message should be read again.
+	msg, ackFn, err = block.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "hello1", string(msg.Get(0).Get()))
+	_, err = ackFn(false)
+	require.NoError(t, err)
+
+	for i := 1; i <= 10; i++ {
+		msg, ackFn, err := block.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("hello%v", i), string(msg.Get(0).Get()))
+		if i < 10 {
+			assert.Equal(t, fmt.Sprintf("%v", i), msg.Get(0).Metadata().Get("index"))
+		}
+		_, err = ackFn(true)
+		require.NoError(t, err)
+	}
+
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	gauges = stats.GetCounters()
+	assert.Equal(t, int64(0), gauges["memory.backlog"])
+	assert.Equal(t, int64(0), gauges["disk.backlog"])
+
+	block.CloseOnceEmpty()
+}
+
+func TestSpillDiskLimit(t *testing.T) {
+	dir := t.TempDir()
+
+	block, err := NewSpill(5, dir, 10, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = block.PushMessage(message.New([][]byte{[]byte("this is too large for the disk")}))
+	assert.Equal(t, types.ErrMessageTooLarge, err)
+
+	_, err = block.PushMessage(message.New([][]byte{[]byte("hello")}))
+	require.NoError(t, err)
+
+	pushed := make(chan error)
+	go func() {
+		_, perr := block.PushMessage(message.New([][]byte{[]byte("world")}))
+		pushed <- perr
+	}()
+
+	msg, ackFn, err := block.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(msg.Get(0).Get()))
+	_, err = ackFn(true)
+	require.NoError(t, err)
+
+	require.NoError(t, <-pushed)
+
+	msg, ackFn, err = block.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(msg.Get(0).Get()))
+	_, err = ackFn(true)
+	require.NoError(t, err)
+
+	block.Close()
+}
+
+func TestSpillConcurrentPushes(t *testing.T) {
+	dir := t.TempDir()
+
+	block, err := NewSpill(0, dir, 0, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, perr := block.PushMessage(message.New([][]byte{[]byte(fmt.Sprintf("hello%v", i))}))
+			assert.NoError(t, perr)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]struct{}{}
+	for i := 0; i < 10; i++ {
+		msg, ackFn, err := block.NextMessage()
+		require.NoError(t, err)
+		seen[string(msg.Get(0).Get())] = struct{}{}
+		_, err = ackFn(true)
+		require.NoError(t, err)
+	}
+	assert.Len(t, seen, 10)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	block.CloseOnceEmpty()
+}
+
+func TestSpillWaitsForPendingWrites(t *testing.T) {
+	dir := t.TempDir()
+
+	block, err := NewSpill(100, dir, 0, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	// Simulate an older message that is still being written to disk.
+	block.cond.L.Lock()
+	pendingSeq := block.nextSeq
+	block.nextSeq++
+	block.writing = append(block.writing, pendingSeq)
+	block.cond.L.Unlock()
+
+	_, err = block.PushMessage(message.New([][]byte{[]byte("newer")}))
+	require.NoError(t, err)
+
+	read := make(chan string)
+	go func() {
+		msg, ackFn, rerr := block.NextMessage()
+		if !assert.NoError(t, rerr) {
+			read <- ""
+			return
+		}
+		_, _ = ackFn(true)
+		read <- string(msg.Get(0).Get())
+	}()
+
+	select {
+	case v := <-read:
+		t.Fatalf("Message read before an older pending write: %v", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	block.cond.L.Lock()
+	require.NoError(t, os.WriteFile(block.filePath(pendingSeq), []byte(`[{"value":"b2xkZXI="}]`), 0o644))
+	block.writing = nil
+	block.files = append(block.files, spillDiskEntry{seq: pendingSeq, size: 10})
+	block.diskBytes += 10
+	block.cond.Broadcast()
+	block.cond.L.Unlock()
+
+	assert.Equal(t, "older", <-read)
+
+	msg, ackFn, err := block.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "newer", string(msg.Get(0).Get()))
+	_, err = ackFn(true)
+	require.NoError(t, err)
+
+	block.CloseOnceEmpty()
+}
+
+func TestSpillDropsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	stats := metrics.NewLocal()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%020d%v", 0, spillFileExt)), []byte("not json"), 0o644))
+
+	block, err := NewSpill(100, dir, 0, log.Noop(), stats)
+	require.NoError(t, err)
+
+	_, _, err = block.NextMessage()
+	require.Error(t, err)
+	assert.Equal(t, int64(1), stats.GetCounters()["disk.dropped"])
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	block.Close()
+}
+
+func TestSpillResume(t *testing.T) {
+	dir := t.TempDir()
+
+	block, err := NewSpill(0, dir, 0, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := block.PushMessage(message.New([][]byte{[]byte(fmt.Sprintf("hello%v", i))}))
+		require.NoError(t, err)
+	}
+	block.Close()
+
+	block, err = NewSpill(100, dir, 0, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = block.PushMessage(message.New([][]byte{[]byte("hello3")}))
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		msg, ackFn, err := block.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("hello%v", i), string(msg.Get(0).Get()))
+		_, err = ackFn(true)
+		require.NoError(t, err)
+	}
+
+	block.CloseOnceEmpty()
+}
@@ -24,8 +24,9 @@ import (
 
 func init() {
 	Constructors[TypeAWSDynamoDB] = TypeSpec{
-		constructor: NewAWSDynamoDB,
-		Version:     "3.36.0",
+		constructor:       NewAWSDynamoDB,
+		Version:           "3.36.0",
+		SupportsPerKeyTTL: true,
 		Summary: `
 Stores key/value pairs as a single document in a DynamoDB table. The key is
 stored as a string value and used as the table hash key. The value is stored as
@@ -35,6 +36,11 @@ A prefix can be specified to allow multiple cache types to share a single
 DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
 
+When a ` + "`ttl_key`" + ` is specified the expiry of each item is written to
+that column as a unix epoch in seconds, which is the format expected by
+DynamoDB TTL. Since DynamoDB removes expired items lazily, items that have
+passed their expiry are treated as missing by Get commands.
+
 Strong read consistency can be enabled using the ` + "`consistent_read`" + `
 configuration field.
 
@@ -49,14 +55,15 @@ allowing you to transfer data across accounts. You can find out more
 			docs.FieldCommon("hash_key", "The key of the table column to store item keys within."),
 			docs.FieldCommon("data_key", "The key of the table column to store item values within."),
 			docs.FieldAdvanced("consistent_read", "Whether to use strongly consistent reads on Get commands."),
-			docs.FieldAdvanced("ttl", "An optional TTL to set for items, calculated from the moment the item is cached."),
-			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within."),
+			docs.FieldAdvanced("ttl", "An optional default TTL to set for items, calculated from the moment the item is cached. This can be overridden per key by the `ttl` field of a cache processor or output."),
+			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within, as a unix epoch in seconds. TTLs are only written when this field is set."),
 		}.Merge(session.FieldSpecs()).Merge(retries.FieldSpecs()),
 	}
 
 	Constructors[TypeDynamoDB] = TypeSpec{
-		constructor: NewDynamoDB,
-		Status:      docs.StatusDeprecated,
+		constructor:       NewDynamoDB,
+		Status:            docs.StatusDeprecated,
+		SupportsPerKeyTTL: true,
 		Summary: `
 Stores key/value pairs as a single document in a DynamoDB table. The key is
 stored as a string value and used as the table hash key. The value is stored as
@@ -70,6 +77,11 @@ A prefix can be specified to allow multiple cache types to share a single
 DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
 
+When a ` + "`ttl_key`" + ` is specified the expiry of each item is written to
+that column as a unix epoch in seconds, which is the format expected by
+DynamoDB TTL. Since DynamoDB removes expired items lazily, items that have
+passed their expiry are treated as missing by Get commands.
+
 Strong read consistency can be enabled using the ` + "`consistent_read`" + `
 configuration field.
 
@@ -84,8 +96,8 @@ allowing you to transfer data across accounts. You can find out more
 			docs.FieldCommon("hash_key", "The key of the table column to store item keys within."),
 			docs.FieldCommon("data_key", "The key of the table column to store item values within."),
 			docs.FieldAdvanced("consistent_read", "Whether to use strongly consistent reads on Get commands."),
-			docs.FieldAdvanced("ttl", "An optional TTL to set for items, calculated from the moment the item is cached."),
-			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within."),
+			docs.FieldAdvanced("ttl", "An optional default TTL to set for items, calculated from the moment the item is cached. This can be overridden per key by the `ttl` field of a cache processor or output."),
+			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within, as a unix epoch in seconds. TTLs are only written when this field is set."),
 		}.Merge(session.FieldSpecs()).Merge(retries.FieldSpecs()),
 	}
 }
@@ -264,6 +276,13 @@ func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (d *DynamoDB) Get(key string) ([]byte, error) {
+	result, _, err := d.GetWithTTL(key)
+	return result, err
+}
+
+// GetWithTTL attempts to locate and return a cached value by its key along with
+// its remaining TTL, which is nil when no TTL is stored for the item.
+func (d *DynamoDB) GetWithTTL(key string) ([]byte, *time.Duration, error) {
 	d.mGetCount.Incr(1)
 
 	tStarted := time.Now()
@@ -273,7 +292,7 @@ func (d *DynamoDB) Get(key string) ([]byte, error) {
 		d.boffPool.Put(boff)
 	}()
 
-	result, err := d.get(key)
+	result, remaining, err := d.get(key)
 	for err != nil && err != types.ErrKeyNotFound {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -281,7 +300,7 @@ func (d *DynamoDB) Get(key string) ([]byte, error) {
 		}
 		time.Sleep(wait)
 		d.mGetRetry.Incr(1)
-		result, err = d.get(key)
+		result, remaining, err = d.get(key)
 	}
 	if err == nil {
 		d.mGetSuccess.Incr(1)
@@ -295,10 +314,10 @@ func (d *DynamoDB) Get(key string) ([]byte, error) {
 	d.mGetLatency.Timing(latency)
 	d.mLatency.Timing(latency)
 
-	return result, err
+	return result, remaining, err
 }
 
-func (d *DynamoDB) get(key string) ([]byte, error) {
+func (d *DynamoDB) get(key string) ([]byte, *time.Duration, error) {
 	res, err := d.client.GetItem(&dynamodb.GetItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -309,19 +328,44 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 		ConsistentRead: aws.Bool(d.conf.ConsistentRead),
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	val, ok := res.Item[d.conf.DataKey]
 	if !ok || val.B == nil {
 		d.log.Debugf("key not found: %s", key)
-		return nil, types.ErrKeyNotFound
+		return nil, nil, types.ErrKeyNotFound
+	}
+
+	var remaining *time.Duration
+	if d.conf.TTLKey != "" {
+		if ttlVal, ok := res.Item[d.conf.TTLKey]; ok && ttlVal.N != nil {
+			epoch, err := strconv.ParseInt(*ttlVal.N, 10, 64)
+			if err != nil {
+				d.log.Debugf("Failed to parse ttl of key '%s': %v", key, err)
+			} else {
+				r := time.Until(time.Unix(epoch, 0))
+				if r <= 0 {
+					// The item has expired but has not yet been removed by
+					// DynamoDB.
+					d.log.Debugf("key expired: %s", key)
+					return nil, nil, types.ErrKeyNotFound
+				}
+				remaining = &r
+			}
+		}
 	}
-	return val.B, nil
+	return val.B, remaining, nil
 }
 
 // Set attempts to set the value of a key.
 func (d *DynamoDB) Set(key string, value []byte) error {
+	return d.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key, with an optional TTL that
+// overrides the default TTL of the cache.
+func (d *DynamoDB) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mSetCount.Incr(1)
 
 	tStarted := time.Now()
@@ -331,7 +375,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		d.boffPool.Put(boff)
 	}()
 
-	_, err := d.client.PutItem(d.putItemInput(key, value))
+	_, err := d.client.PutItem(d.putItemInput(key, value, ttl))
 	for err != nil {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -339,7 +383,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mSetRetry.Incr(1)
-		_, err = d.client.PutItem(d.putItemInput(key, value))
+		_, err = d.client.PutItem(d.putItemInput(key, value, ttl))
 	}
 	if err == nil {
 		d.mSetSuccess.Incr(1)
@@ -357,6 +401,18 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 // SetMulti attempts to set the value of multiple keys, if any keys fail to be
 // set an error is returned.
 func (d *DynamoDB) SetMulti(items map[string][]byte) error {
+	sitems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		sitems[k] = types.CacheTTLItem{
+			Value: v,
+		}
+	}
+	return d.SetMultiWithTTL(sitems)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, each with an
+// optional TTL, if any keys fail to be set an error is returned.
+func (d *DynamoDB) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 	d.mSetMultiCount.Incr(1)
 
 	tStarted := time.Now()
@@ -370,7 +426,7 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	for k, v := range items {
 		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
 			PutRequest: &dynamodb.PutRequest{
-				Item: d.putItemInput(k, v).Item,
+				Item: d.putItemInput(k, v.Value, v.TTL).Item,
 			},
 		})
 	}
@@ -418,6 +474,13 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (d *DynamoDB) Add(key string, value []byte) error {
+	return d.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist, with an optional TTL that overrides the default TTL of the
+// cache. Returns an error if the key already exists.
+func (d *DynamoDB) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mAddCount.Incr(1)
 
 	tStarted := time.Now()
@@ -427,7 +490,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		d.boffPool.Put(boff)
 	}()
 
-	err := d.add(key, value)
+	err := d.add(key, value, ttl)
 	for err != nil && err != types.ErrKeyAlreadyExists {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -435,7 +498,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mAddRetry.Incr(1)
-		err = d.add(key, value)
+		err = d.add(key, value, ttl)
 	}
 	if err == nil {
 		d.mAddSuccess.Incr(1)
@@ -452,16 +515,26 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 	return err
 }
 
-func (d *DynamoDB) add(key string, value []byte) error {
-	input := d.putItemInput(key, value)
+func (d *DynamoDB) add(key string, value []byte, ttl *time.Duration) error {
+	input := d.putItemInput(key, value, ttl)
+
+	cond := expression.AttributeNotExists(expression.Name(d.conf.HashKey))
+	if d.conf.TTLKey != "" {
+		// Items that have expired but are yet to be removed by DynamoDB are
+		// considered absent.
+		cond = cond.Or(expression.Name(d.conf.TTLKey).LessThanEqual(
+			expression.Value(time.Now().Unix()),
+		))
+	}
 
 	expr, err := expression.NewBuilder().
-		WithCondition(expression.AttributeNotExists(expression.Name(d.conf.HashKey))).
+		WithCondition(cond).
 		Build()
 	if err != nil {
 		return err
 	}
 	input.ExpressionAttributeNames = expr.Names()
+	input.ExpressionAttributeValues = expr.Values()
 	input.ConditionExpression = expr.Condition()
 
 	if _, err = d.client.PutItem(input); err != nil {
@@ -521,8 +594,9 @@ func (d *DynamoDB) delete(key string) error {
 	return err
 }
 
-// putItemInput creates a generic put item input for use in Set and Add operations
-func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput {
+// putItemInput creates a generic put item input for use in Set and Add
+// operations, a non-nil ttl overrides the default TTL of the cache.
+func (d *DynamoDB) putItemInput(key string, value []byte, ttl *time.Duration) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
 		Item: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -535,9 +609,13 @@ func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput
 		TableName: d.table,
 	}
 
-	if d.ttl != 0 && d.conf.TTLKey != "" {
+	t := d.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+	if t != 0 && d.conf.TTLKey != "" {
 		input.Item[d.conf.TTLKey] = &dynamodb.AttributeValue{
-			N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
+			N: aws.String(strconv.FormatInt(time.Now().Add(t).Unix(), 10)),
 		}
 	}
 
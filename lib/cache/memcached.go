@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"strings"
@@ -190,6 +191,45 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	return item.Value, err
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys
+// within a single request. Keys that do not exist are omitted from the result.
+func (m *Memcached) GetMulti(keys ...string) (map[string][]byte, error) {
+	m.mGetCount.Incr(int64(len(keys)))
+	tStarted := time.Now()
+
+	pKeys := make([]string, len(keys))
+	for i, k := range keys {
+		pKeys[i] = m.conf.Memcached.Prefix + k
+	}
+
+	items, err := m.mc.GetMulti(pKeys)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+		m.log.Errorf("Get command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mGetRetry.Incr(1)
+		items, err = m.mc.GetMulti(pKeys)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mGetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if err != nil {
+		m.mGetFailed.Incr(1)
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(items))
+	for i, k := range pKeys {
+		if item, exists := items[k]; exists {
+			values[keys[i]] = item.Value
+		}
+	}
+	m.mGetSuccess.Incr(int64(len(values)))
+	m.mGetFailed.Incr(int64(len(keys) - len(values)))
+	return values, nil
+}
+
 // SetWithTTL attempts to set the value of a key.
 func (m *Memcached) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.mSetCount.Incr(1)
@@ -293,6 +333,42 @@ func (m *Memcached) Add(key string, value []byte) error {
 	return m.AddWithTTL(key, value, nil)
 }
 
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old, using memcached CAS identifiers to guarantee atomicity.
+func (m *Memcached) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	if old == nil {
+		err := m.mc.Add(m.getItemFor(key, value, ttl))
+		if errors.Is(err, memcache.ErrNotStored) {
+			return types.ErrKeyAlreadyExists
+		}
+		return err
+	}
+
+	item, err := m.mc.Get(m.conf.Memcached.Prefix + key)
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return types.ErrKeyNotFound
+		}
+		return err
+	}
+	if !bytes.Equal(item.Value, old) {
+		return types.ErrKeyValueMismatch
+	}
+
+	newItem := m.getItemFor(key, value, ttl)
+	item.Value = newItem.Value
+	item.Expiration = newItem.Expiration
+
+	err = m.mc.CompareAndSwap(item)
+	if errors.Is(err, memcache.ErrCASConflict) {
+		return types.ErrKeyValueMismatch
+	}
+	if errors.Is(err, memcache.ErrNotStored) {
+		return types.ErrKeyNotFound
+	}
+	return err
+}
+
 // Delete attempts to remove a key.
 func (m *Memcached) Delete(key string) error {
 	m.mDelCount.Incr(1)
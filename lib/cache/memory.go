@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -43,11 +45,29 @@ memory:
 ` + "```" + `
 
 These values can be overridden during execution, at which point the configured
-TTL is respected as usual.`,
+TTL is respected as usual.
+
+### Eviction
+
+By default the size of the cache is unbounded. Limits can be placed on the
+number of items held with ` + "`max_items`" + ` and on the total size in bytes
+of all keys and values held with ` + "`max_bytes`" + `, at which point items
+are evicted according to the ` + "`eviction_policy`" + `. When multiple shards
+are configured these limits are divided evenly across them.
+
+Evictions are reported with the ` + "`eviction`" + ` metric.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("ttl", "The TTL of each item in seconds. After this period an item will be eligible for removal during the next compaction."),
 			docs.FieldCommon("compaction_interval", "The period of time to wait before each compaction, at which point expired items are removed."),
 			docs.FieldAdvanced("shards", "A number of logical shards to spread keys across, increasing the shards can have a performance benefit when processing a large number of keys."),
+			docs.FieldAdvanced("eviction_policy", "The policy used to select items for eviction when a limit set by `max_items` or `max_bytes` is reached.").HasAnnotatedOptions(
+				MemoryEvictionNone, "Items are never evicted, and limits cannot be set.",
+				MemoryEvictionLRU, "Evict the least recently used item.",
+				MemoryEvictionLFU, "Evict the least frequently used item, with ties broken by the least recently used.",
+				MemoryEvictionARC, "Adaptive replacement, which balances between recently and frequently used items based on the access pattern.",
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("max_items", "The maximum number of items to hold in the cache, where zero means unlimited.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("max_bytes", "The maximum total size in bytes of the keys and values held in the cache, where zero means unlimited.").AtVersion("3.64.0"),
 			docs.FieldString(
 				"init_values", "A table of key/value pairs that should be present in the cache on initialization. This can be used to create static lookup tables.",
 				map[string]string{
@@ -68,6 +88,9 @@ type MemoryConfig struct {
 	CompactionInterval string            `json:"compaction_interval" yaml:"compaction_interval"`
 	InitValues         map[string]string `json:"init_values" yaml:"init_values"`
 	Shards             int               `json:"shards" yaml:"shards"`
+	EvictionPolicy     string            `json:"eviction_policy" yaml:"eviction_policy"`
+	MaxItems           int               `json:"max_items" yaml:"max_items"`
+	MaxBytes           int               `json:"max_bytes" yaml:"max_bytes"`
 }
 
 // NewMemoryConfig creates a MemoryConfig populated with default values.
@@ -77,6 +100,9 @@ func NewMemoryConfig() MemoryConfig {
 		CompactionInterval: "60s",
 		InitValues:         map[string]string{},
 		Shards:             1,
+		EvictionPolicy:     MemoryEvictionNone,
+		MaxItems:           0,
+		MaxBytes:           0,
 	}
 }
 
@@ -94,12 +120,90 @@ type shard struct {
 	compInterval   time.Duration
 	lastCompaction time.Time
 
+	policy   evictionPolicy
+	maxItems int
+	maxBytes int
+	bytes    int
+
 	mKeys        metrics.StatGauge
 	mCompactions metrics.StatCounter
+	mEvictions   metrics.StatCounter
 
 	sync.RWMutex
 }
 
+func itemSize(key string, i item) int {
+	return len(key) + len(i.value)
+}
+
+// get obtains an item, and when an eviction policy is set also records the
+// access.
+func (s *shard) get(key string) (item, bool) {
+	if s.policy == nil {
+		s.RLock()
+		k, exists := s.items[key]
+		s.RUnlock()
+		return k, exists
+	}
+	s.Lock()
+	k, exists := s.items[key]
+	if exists && !s.isExpired(k) {
+		s.policy.touch(key)
+	}
+	s.Unlock()
+	return k, exists
+}
+
+// put sets an item, evicting other items beforehand if storing it would exceed
+// the limits of the shard. The shard must be locked.
+func (s *shard) put(key string, i item) {
+	count, size := len(s.items), s.bytes+itemSize(key, i)
+	if old, exists := s.items[key]; exists {
+		size -= itemSize(key, old)
+	} else {
+		count++
+	}
+	if s.policy != nil {
+		for s.overLimits(count, size) {
+			victim, ok := s.policy.victim()
+			if !ok {
+				break
+			}
+			if victim == key {
+				// The key is being replaced anyway, it'll be tracked again
+				// by the policy as a new key.
+				continue
+			}
+			if old, exists := s.items[victim]; exists {
+				count--
+				size -= itemSize(victim, old)
+				delete(s.items, victim)
+				s.mEvictions.Incr(1)
+			}
+		}
+		s.policy.add(key)
+	}
+	s.items[key] = i
+	s.bytes = size
+	s.mKeys.Set(int64(len(s.items)))
+}
+
+// del removes an item, the shard must be locked.
+func (s *shard) del(key string) {
+	if old, exists := s.items[key]; exists {
+		s.bytes -= itemSize(key, old)
+		delete(s.items, key)
+		if s.policy != nil {
+			s.policy.remove(key)
+		}
+	}
+}
+
+func (s *shard) overLimits(count, size int) bool {
+	return (s.maxItems > 0 && count > s.maxItems) ||
+		(s.maxBytes > 0 && size > s.maxBytes)
+}
+
 func (s *shard) isExpired(i item) bool {
 	if s.compInterval == 0 {
 		return false
@@ -110,6 +214,7 @@ func (s *shard) isExpired(i item) bool {
 	return time.Since(i.ts) >= s.ttl
 }
 
+// compaction removes expired items, the shard must be locked.
 func (s *shard) compaction() {
 	if s.compInterval == 0 {
 		return
@@ -120,9 +225,10 @@ func (s *shard) compaction() {
 	s.mCompactions.Incr(1)
 	for k, v := range s.items {
 		if s.isExpired(v) {
-			delete(s.items, k)
+			s.del(k)
 		}
 	}
+	s.mKeys.Set(int64(len(s.items)))
 	s.lastCompaction = time.Now()
 }
 
@@ -142,39 +248,59 @@ func NewMemory(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 	if conf.Memory.Shards <= 0 {
 		return nil, fmt.Errorf("expected >=1 shards, found: %v", conf.Memory.Shards)
 	}
-	if conf.Memory.Shards == 1 {
-		m.shards = []*shard{
-			{
-				items: map[string]item{},
-				ttl:   time.Second * time.Duration(conf.Memory.TTL),
+	if conf.Memory.MaxItems < 0 || conf.Memory.MaxBytes < 0 {
+		return nil, errors.New("max_items and max_bytes must not be negative")
+	}
+	limited := conf.Memory.MaxItems > 0 || conf.Memory.MaxBytes > 0
+	if _, err := newEvictionPolicy(conf.Memory.EvictionPolicy, 0); err != nil {
+		return nil, err
+	}
+	if limited && (conf.Memory.EvictionPolicy == MemoryEvictionNone || conf.Memory.EvictionPolicy == "") {
+		return nil, errors.New("an eviction_policy must be set in order to use max_items or max_bytes")
+	}
+
+	// Limits are divided evenly across shards, rounding up.
+	shardLimit := func(v int) int {
+		return (v + conf.Memory.Shards - 1) / conf.Memory.Shards
+	}
+	newShard := func(keysPath, compactionPath, evictionPath string) *shard {
+		s := &shard{
+			items: map[string]item{},
+			ttl:   time.Second * time.Duration(conf.Memory.TTL),
+
+			compInterval:   interval,
+			lastCompaction: time.Now(),
 
-				compInterval:   interval,
-				lastCompaction: time.Now(),
+			maxItems: shardLimit(conf.Memory.MaxItems),
+			maxBytes: shardLimit(conf.Memory.MaxBytes),
 
-				mKeys:        stats.GetGauge("keys"),
-				mCompactions: stats.GetCounter("compaction"),
-			},
+			mKeys:        stats.GetGauge(keysPath),
+			mCompactions: stats.GetCounter(compactionPath),
+			mEvictions:   stats.GetCounter(evictionPath),
+		}
+		if limited {
+			s.policy, _ = newEvictionPolicy(conf.Memory.EvictionPolicy, s.maxItems)
 		}
+		return s
+	}
+
+	if conf.Memory.Shards == 1 {
+		m.shards = []*shard{newShard("keys", "compaction", "eviction")}
 	} else {
 		for i := 0; i < conf.Memory.Shards; i++ {
-			m.shards = append(m.shards, &shard{
-				items: map[string]item{},
-				ttl:   time.Second * time.Duration(conf.Memory.TTL),
-
-				compInterval:   interval,
-				lastCompaction: time.Now(),
-
-				mKeys:        stats.GetGauge(fmt.Sprintf("shard.%v.keys", i)),
-				mCompactions: stats.GetCounter(fmt.Sprintf("shard.%v.compaction", i)),
-			})
+			m.shards = append(m.shards, newShard(
+				fmt.Sprintf("shard.%v.keys", i),
+				fmt.Sprintf("shard.%v.compaction", i),
+				fmt.Sprintf("shard.%v.eviction", i),
+			))
 		}
 	}
 
 	for k, v := range conf.Memory.InitValues {
-		m.getShard(k).items[k] = item{
+		m.getShard(k).put(k, item{
 			value: []byte(v),
 			ts:    time.Time{},
-		}
+		})
 	}
 
 	return cache.NewV2ToV1Cache(m, stats), nil
@@ -195,9 +321,7 @@ func (m *memoryV2) getShard(key string) *shard {
 
 func (m *memoryV2) Get(_ context.Context, key string) ([]byte, error) {
 	shard := m.getShard(key)
-	shard.RLock()
-	k, exists := shard.items[key]
-	shard.RUnlock()
+	k, exists := shard.get(key)
 	if !exists {
 		return nil, types.ErrKeyNotFound
 	}
@@ -208,12 +332,24 @@ func (m *memoryV2) Get(_ context.Context, key string) ([]byte, error) {
 	return k.value, nil
 }
 
+func (m *memoryV2) GetWithTTL(_ context.Context, key string) ([]byte, *time.Duration, error) {
+	shard := m.getShard(key)
+	k, exists := shard.get(key)
+	if !exists || shard.isExpired(k) {
+		return nil, nil, types.ErrKeyNotFound
+	}
+	if shard.compInterval == 0 || k.ts.IsZero() {
+		return k.value, nil, nil
+	}
+	remaining := shard.ttl - time.Since(k.ts)
+	return k.value, &remaining, nil
+}
+
 func (m *memoryV2) Set(_ context.Context, key string, value []byte, _ *time.Duration) error {
 	shard := m.getShard(key)
 	shard.Lock()
 	shard.compaction()
-	shard.items[key] = item{value: value, ts: time.Now()}
-	shard.mKeys.Set(int64(len(shard.items)))
+	shard.put(key, item{value: value, ts: time.Now()})
 	shard.Unlock()
 	return nil
 }
@@ -235,17 +371,54 @@ func (m *memoryV2) Add(_ context.Context, key string, value []byte, _ *time.Dura
 		return types.ErrKeyAlreadyExists
 	}
 	shard.compaction()
-	shard.items[key] = item{value: value, ts: time.Now()}
-	shard.mKeys.Set(int64(len(shard.items)))
+	shard.put(key, item{value: value, ts: time.Now()})
 	shard.Unlock()
 	return nil
 }
 
+func (m *memoryV2) CompareAndSwap(_ context.Context, key string, old, value []byte, _ *time.Duration) error {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	k, exists := shard.items[key]
+	if exists && shard.isExpired(k) {
+		exists = false
+	}
+	if old == nil {
+		if exists {
+			return types.ErrKeyAlreadyExists
+		}
+	} else if !exists {
+		return types.ErrKeyNotFound
+	} else if !bytes.Equal(k.value, old) {
+		return types.ErrKeyValueMismatch
+	}
+
+	shard.compaction()
+	shard.put(key, item{value: value, ts: time.Now()})
+	return nil
+}
+
+func (m *memoryV2) GetAndDelete(_ context.Context, key string) ([]byte, error) {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	k, exists := shard.items[key]
+	if !exists || shard.isExpired(k) {
+		return nil, types.ErrKeyNotFound
+	}
+	shard.del(key)
+	shard.mKeys.Set(int64(len(shard.items)))
+	return k.value, nil
+}
+
 func (m *memoryV2) Delete(_ context.Context, key string) error {
 	shard := m.getShard(key)
 	shard.Lock()
 	shard.compaction()
-	delete(shard.items, key)
+	shard.del(key)
 	shard.mKeys.Set(int64(len(shard.items)))
 	shard.Unlock()
 	return nil
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// Eviction policies supported by the memory cache.
+const (
+	MemoryEvictionNone = "none"
+	MemoryEvictionLRU  = "lru"
+	MemoryEvictionLFU  = "lfu"
+	MemoryEvictionARC  = "arc"
+)
+
+// evictionPolicy tracks the keys of a memory cache shard in order to select
+// which key should be removed when the shard exceeds its limits. Policies are
+// not thread safe and are protected by the lock of the shard that owns them.
+type evictionPolicy interface {
+	// add is called when a key is set, which may be a key that already exists.
+	add(key string)
+
+	// touch is called when an existing key is read.
+	touch(key string)
+
+	// remove is called when a key is removed from the shard for any reason
+	// other than eviction.
+	remove(key string)
+
+	// victim selects a key to evict and removes it from the policy, returns
+	// false if there are no keys to evict.
+	victim() (string, bool)
+}
+
+func newEvictionPolicy(policy string, capacity int) (evictionPolicy, error) {
+	switch policy {
+	case MemoryEvictionNone, "":
+		return nil, nil
+	case MemoryEvictionLRU:
+		return newLRUPolicy(), nil
+	case MemoryEvictionLFU:
+		return newLFUPolicy(), nil
+	case MemoryEvictionARC:
+		return newARCPolicy(capacity), nil
+	}
+	return nil, fmt.Errorf("eviction policy not recognised: %v", policy)
+}
+
+//------------------------------------------------------------------------------
+
+type lruPolicy struct {
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (l *lruPolicy) add(key string) {
+	if e, exists := l.entries[key]; exists {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.entries[key] = l.order.PushFront(key)
+}
+
+func (l *lruPolicy) touch(key string) {
+	if e, exists := l.entries[key]; exists {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *lruPolicy) remove(key string) {
+	if e, exists := l.entries[key]; exists {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+func (l *lruPolicy) victim() (string, bool) {
+	e := l.order.Back()
+	if e == nil {
+		return "", false
+	}
+	key := l.order.Remove(e).(string)
+	delete(l.entries, key)
+	return key, true
+}
+
+//------------------------------------------------------------------------------
+
+type lfuEntry struct {
+	key  string
+	freq int
+	elem *list.Element
+}
+
+// lfuPolicy groups keys into lists by their access frequency, where keys with
+// the same frequency are evicted in least recently used order.
+type lfuPolicy struct {
+	entries map[string]*lfuEntry
+	freqs   map[int]*list.List
+	minFreq int
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		entries: map[string]*lfuEntry{},
+		freqs:   map[int]*list.List{},
+	}
+}
+
+func (l *lfuPolicy) pushFreq(e *lfuEntry) {
+	fl, exists := l.freqs[e.freq]
+	if !exists {
+		fl = list.New()
+		l.freqs[e.freq] = fl
+	}
+	e.elem = fl.PushFront(e)
+}
+
+func (l *lfuPolicy) removeFreq(e *lfuEntry) {
+	fl := l.freqs[e.freq]
+	fl.Remove(e.elem)
+	if fl.Len() == 0 {
+		delete(l.freqs, e.freq)
+	}
+}
+
+func (l *lfuPolicy) add(key string) {
+	if _, exists := l.entries[key]; exists {
+		l.touch(key)
+		return
+	}
+	e := &lfuEntry{key: key, freq: 1}
+	l.entries[key] = e
+	l.pushFreq(e)
+	l.minFreq = 1
+}
+
+func (l *lfuPolicy) touch(key string) {
+	e, exists := l.entries[key]
+	if !exists {
+		return
+	}
+	l.removeFreq(e)
+	if _, exists := l.freqs[e.freq]; !exists && l.minFreq == e.freq {
+		l.minFreq = e.freq + 1
+	}
+	e.freq++
+	l.pushFreq(e)
+}
+
+func (l *lfuPolicy) remove(key string) {
+	if e, exists := l.entries[key]; exists {
+		l.removeFreq(e)
+		delete(l.entries, key)
+	}
+}
+
+func (l *lfuPolicy) victim() (string, bool) {
+	if len(l.entries) == 0 {
+		return "", false
+	}
+	fl, exists := l.freqs[l.minFreq]
+	if !exists {
+		// The minimum frequency is stale after a removal, find the new one.
+		l.minFreq = 0
+		for f := range l.freqs {
+			if l.minFreq == 0 || f < l.minFreq {
+				l.minFreq = f
+			}
+		}
+		fl = l.freqs[l.minFreq]
+	}
+	e := fl.Back().Value.(*lfuEntry)
+	l.removeFreq(e)
+	delete(l.entries, e.key)
+	return e.key, true
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	arcT1 = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+type arcEntry struct {
+	key  string
+	list int
+	elem *list.Element
+}
+
+// arcPolicy implements adaptive replacement, where resident keys are split
+// between a list of keys seen once recently (t1) and a list of keys seen at
+// least twice recently (t2). Ghost lists of keys recently evicted from each
+// (b1 and b2) are used to adapt the target size of t1.
+type arcPolicy struct {
+	capacity int
+	p        int
+
+	lists   [4]*list.List
+	entries map[string]*arcEntry
+}
+
+func newARCPolicy(capacity int) *arcPolicy {
+	a := &arcPolicy{
+		capacity: capacity,
+		entries:  map[string]*arcEntry{},
+	}
+	for i := range a.lists {
+		a.lists[i] = list.New()
+	}
+	return a
+}
+
+// target returns the capacity used for adapting list sizes, which is either
+// the configured item limit or the current number of resident keys when only
+// a byte limit is set.
+func (a *arcPolicy) target() int {
+	if a.capacity > 0 {
+		return a.capacity
+	}
+	if c := a.lists[arcT1].Len() + a.lists[arcT2].Len(); c > 0 {
+		return c
+	}
+	return 1
+}
+
+func (a *arcPolicy) move(e *arcEntry, to int) {
+	a.lists[e.list].Remove(e.elem)
+	e.list = to
+	e.elem = a.lists[to].PushFront(e)
+}
+
+func (a *arcPolicy) add(key string) {
+	e, exists := a.entries[key]
+	if !exists {
+		e = &arcEntry{key: key, list: arcT1}
+		e.elem = a.lists[arcT1].PushFront(e)
+		a.entries[key] = e
+		return
+	}
+
+	b1Len, b2Len := a.lists[arcB1].Len(), a.lists[arcB2].Len()
+	switch e.list {
+	case arcB1:
+		delta := 1
+		if b1Len > 0 && b2Len/b1Len > delta {
+			delta = b2Len / b1Len
+		}
+		if a.p += delta; a.p > a.target() {
+			a.p = a.target()
+		}
+	case arcB2:
+		delta := 1
+		if b2Len > 0 && b1Len/b2Len > delta {
+			delta = b1Len / b2Len
+		}
+		if a.p -= delta; a.p < 0 {
+			a.p = 0
+		}
+	}
+	a.move(e, arcT2)
+}
+
+func (a *arcPolicy) touch(key string) {
+	if e, exists := a.entries[key]; exists && (e.list == arcT1 || e.list == arcT2) {
+		a.move(e, arcT2)
+	}
+}
+
+func (a *arcPolicy) remove(key string) {
+	if e, exists := a.entries[key]; exists {
+		a.lists[e.list].Remove(e.elem)
+		delete(a.entries, key)
+	}
+}
+
+func (a *arcPolicy) trimGhosts() {
+	c := a.target()
+	for _, l := range []*list.List{a.lists[arcB1], a.lists[arcB2]} {
+		for l.Len() > c {
+			e := l.Remove(l.Back()).(*arcEntry)
+			delete(a.entries, e.key)
+		}
+	}
+}
+
+func (a *arcPolicy) victim() (string, bool) {
+	t1, t2 := a.lists[arcT1], a.lists[arcT2]
+	if t1.Len() == 0 && t2.Len() == 0 {
+		return "", false
+	}
+
+	var e *arcEntry
+	if t1.Len() > 0 && (t1.Len() > a.p || t2.Len() == 0) {
+		e = t1.Back().Value.(*arcEntry)
+		a.move(e, arcB1)
+	} else {
+		e = t2.Back().Value.(*arcEntry)
+		a.move(e, arcB2)
+	}
+	a.trimGhosts()
+	return e.key, true
+}
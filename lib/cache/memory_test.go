@@ -246,3 +246,144 @@ func BenchmarkMemoryShards100(b *testing.B) {
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheEvictionConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.MaxItems = 10
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create cache 'memory': an eviction_policy must be set in order to use max_items or max_bytes")
+
+	conf.Memory.EvictionPolicy = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create cache 'memory': eviction policy not recognised: nope")
+}
+
+func TestMemoryCacheEvictionLRU(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.EvictionPolicy = MemoryEvictionLRU
+	conf.Memory.MaxItems = 2
+
+	stats := metrics.NewLocal()
+	c, err := New(conf, nil, log.Noop(), stats)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("foo", []byte("1")))
+	require.NoError(t, c.Set("bar", []byte("2")))
+
+	_, err = c.Get("foo")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("baz", []byte("3")))
+
+	_, err = c.Get("bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	for _, k := range []string{"foo", "baz"} {
+		_, err = c.Get(k)
+		assert.NoError(t, err, k)
+	}
+
+	assert.Equal(t, int64(1), stats.GetCounters()["eviction"])
+}
+
+func TestMemoryCacheEvictionLFU(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.EvictionPolicy = MemoryEvictionLFU
+	conf.Memory.MaxItems = 2
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("foo", []byte("1")))
+	require.NoError(t, c.Set("bar", []byte("2")))
+
+	for i := 0; i < 3; i++ {
+		_, err = c.Get("foo")
+		require.NoError(t, err)
+	}
+	_, err = c.Get("bar")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("baz", []byte("3")))
+
+	_, err = c.Get("bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	_, err = c.Get("foo")
+	assert.NoError(t, err)
+
+	// baz has the lowest frequency now and is evicted next, even though foo
+	// was used less recently.
+	_, err = c.Get("baz")
+	require.NoError(t, err)
+	require.NoError(t, c.Set("buz", []byte("4")))
+
+	_, err = c.Get("foo")
+	assert.NoError(t, err)
+	_, err = c.Get("baz")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestMemoryCacheEvictionARC(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.EvictionPolicy = MemoryEvictionARC
+	conf.Memory.MaxItems = 3
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	// Keys that are read repeatedly should survive a scan of keys that are
+	// only seen once.
+	require.NoError(t, c.Set("hot", []byte("1")))
+	_, err = c.Get("hot")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Set(fmt.Sprintf("scan%v", i), []byte("2")))
+	}
+
+	_, err = c.Get("hot")
+	assert.NoError(t, err)
+
+	_, err = c.Get("scan0")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	_, err = c.Get("scan9")
+	assert.NoError(t, err)
+}
+
+func TestMemoryCacheEvictionMaxBytes(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.EvictionPolicy = MemoryEvictionLRU
+	conf.Memory.MaxBytes = 10
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("foo", []byte("12")))
+	require.NoError(t, c.Set("bar", []byte("34")))
+
+	_, err = c.Get("foo")
+	require.NoError(t, err)
+
+	// Overwriting a key replaces its size rather than adding to it.
+	require.NoError(t, c.Set("bar", []byte("56")))
+	_, err = c.Get("foo")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("baz", []byte("78")))
+
+	_, err = c.Get("bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	for _, k := range []string{"foo", "baz"} {
+		_, err = c.Get(k)
+		assert.NoError(t, err, k)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
-	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis"
+	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis/old"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -195,6 +195,139 @@ func (r *Redis) Get(key string) ([]byte, error) {
 	return []byte(res), nil
 }
 
+// GetWithTTL attempts to locate and return a cached value by its key along with
+// its remaining TTL, which is nil if the key has no expiry.
+func (r *Redis) GetWithTTL(key string) ([]byte, *time.Duration, error) {
+	r.mGetCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+
+	var getCmd *redis.StringCmd
+	var ttlCmd *redis.DurationCmd
+	getFn := func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(key)
+		ttlCmd = pipe.PTTL(key)
+		return nil
+	}
+
+	_, err := r.client.Pipelined(getFn)
+	for i := 0; i < r.conf.Redis.Retries && err != nil && err != redis.Nil; i++ {
+		r.log.Errorf("Get command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mGetRetry.Incr(1)
+		_, err = r.client.Pipelined(getFn)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mGetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err == redis.Nil || (err == nil && getCmd.Err() == redis.Nil) {
+		r.mGetNotFound.Incr(1)
+		return nil, nil, types.ErrKeyNotFound
+	}
+	if err != nil {
+		r.mGetFailed.Incr(1)
+		return nil, nil, err
+	}
+
+	r.mGetSuccess.Incr(1)
+
+	var ttl *time.Duration
+	if d := ttlCmd.Val(); d > 0 {
+		ttl = &d
+	}
+	return []byte(getCmd.Val()), ttl, nil
+}
+
+// GetMulti attempts to locate and return the cached values of multiple keys
+// within a single MGET command, or a pipeline of GET commands when the client
+// is cluster aware. Keys that do not exist are omitted from the result.
+func (r *Redis) GetMulti(keys ...string) (map[string][]byte, error) {
+	r.mGetCount.Incr(int64(len(keys)))
+	tStarted := time.Now()
+
+	pKeys := make([]string, len(keys))
+	for i, k := range keys {
+		pKeys[i] = r.prefix + k
+	}
+
+	getFn := func() ([]interface{}, error) {
+		return r.client.MGet(pKeys...).Result()
+	}
+	// The keys of an MGET command must all belong to the same hash slot of a
+	// cluster, which is rarely the case for a batch.
+	if _, isCluster := r.client.(*redis.ClusterClient); isCluster {
+		getFn = func() ([]interface{}, error) {
+			return r.pipelinedGet(pKeys)
+		}
+	}
+
+	res, err := getFn()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Get command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mGetRetry.Incr(1)
+		res, err = getFn()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mGetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mGetFailed.Incr(1)
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for i, v := range res {
+		if i >= len(keys) {
+			break
+		}
+		switch t := v.(type) {
+		case string:
+			values[keys[i]] = []byte(t)
+		case []byte:
+			values[keys[i]] = t
+		default:
+			r.mGetNotFound.Incr(1)
+			continue
+		}
+		r.mGetSuccess.Incr(1)
+	}
+	return values, nil
+}
+
+// pipelinedGet obtains the values of multiple keys with a pipeline of GET
+// commands, which are routed to the node of each key individually. Values are
+// returned in the same form as an MGET command, where missing keys are nil.
+func (r *Redis) pipelinedGet(keys []string) ([]interface{}, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	if _, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = pipe.Get(k)
+		}
+		return nil
+	}); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	res := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		v, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
 // SetWithTTL attempts to set the value of a key.
 func (r *Redis) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mSetCount.Incr(1)
@@ -314,6 +447,105 @@ func (r *Redis) Add(key string, value []byte) error {
 	return r.AddWithTTL(key, value, nil)
 }
 
+var redisCASScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if ARGV[1] == "1" then
+  if current then return -1 end
+else
+  if not current then return -2 end
+  if current ~= ARGV[2] then return 0 end
+end
+if tonumber(ARGV[4]) > 0 then
+  redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+else
+  redis.call("SET", KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old, this is performed atomically with a Lua script.
+func (r *Redis) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	key = r.prefix + key
+
+	t := r.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+
+	expectAbsent := "0"
+	if old == nil {
+		expectAbsent = "1"
+	}
+	args := []interface{}{expectAbsent, old, value, t.Milliseconds()}
+
+	res, err := redisCASScript.Run(r.client, []string{key}, args...).Int64()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		res, err = redisCASScript.Run(r.client, []string{key}, args...).Int64()
+	}
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case -1:
+		return types.ErrKeyAlreadyExists
+	case -2:
+		return types.ErrKeyNotFound
+	case 0:
+		return types.ErrKeyValueMismatch
+	}
+	return nil
+}
+
+// GetAndDelete attempts to locate and return a cached value by its key and then
+// remove it within a single transaction, returns an error if the key does not
+// exist.
+func (r *Redis) GetAndDelete(key string) ([]byte, error) {
+	r.mGetCount.Incr(1)
+	r.mDelCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+
+	var getCmd *redis.StringCmd
+	getDelFn := func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	}
+
+	_, err := r.client.TxPipelined(getDelFn)
+	for i := 0; i < r.conf.Redis.Retries && err != nil && err != redis.Nil; i++ {
+		r.log.Errorf("Get and delete command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mGetRetry.Incr(1)
+		_, err = r.client.TxPipelined(getDelFn)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mGetLatency.Timing(latency)
+	r.mDelLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err == redis.Nil || (err == nil && getCmd.Err() == redis.Nil) {
+		r.mGetNotFound.Incr(1)
+		r.mDelNotFound.Incr(1)
+		return nil, types.ErrKeyNotFound
+	}
+	if err != nil {
+		r.mGetFailed.Incr(1)
+		r.mDelFailedErr.Incr(1)
+		return nil, err
+	}
+
+	r.mGetSuccess.Incr(1)
+	r.mDelSuccess.Incr(1)
+	return []byte(getCmd.Val()), nil
+}
+
 // Delete attempts to remove a key.
 func (r *Redis) Delete(key string) error {
 	r.mDelCount.Incr(1)
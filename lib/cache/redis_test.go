@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+// testRedisSlot returns the cluster hash slot of a key without a hash tag.
+func testRedisSlot(key string) int {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, l+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:l])
+	}
+	return args, nil
+}
+
+// testRedisClusterNode runs a fake node that owns every slot of a cluster,
+// and which rejects MGET commands of keys that belong to different slots in
+// the same way as a real cluster node.
+func testRedisClusterNode(t *testing.T, values map[string]string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	bulkString := func(k string) string {
+		v, exists := values[k]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%v\r\n%v\r\n", len(v), v)
+	}
+
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			args, err := readRESPCommand(r)
+			if err != nil {
+				return
+			}
+
+			var res string
+			switch strings.ToLower(args[0]) {
+			case "get":
+				res = bulkString(args[1])
+			case "mget":
+				res = fmt.Sprintf("*%v\r\n", len(args)-1)
+				for _, k := range args[1:] {
+					if testRedisSlot(k) != testRedisSlot(args[1]) {
+						res = "-CROSSSLOT Keys in request don't hash to the same slot\r\n"
+						break
+					}
+					res += bulkString(k)
+				}
+			default:
+				res = "-ERR unknown command\r\n"
+			}
+			if _, err = conn.Write([]byte(res)); err != nil {
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisClusterGetMulti(t *testing.T) {
+	require.NotEqual(t, testRedisSlot("foo"), testRedisSlot("bar"))
+
+	addr := testRedisClusterNode(t, map[string]string{
+		"foo": "foo value",
+		"bar": "bar value",
+	})
+
+	conf := NewConfig()
+	conf.Redis.URL = "tcp://" + addr
+	conf.Redis.Retries = 0
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer c.CloseAsync()
+
+	_, err = c.(types.CacheWithGetMulti).GetMulti("foo", "bar", "baz")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CROSSSLOT")
+
+	r := c.(*Redis)
+	require.NoError(t, r.client.Close())
+	r.client = redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func() ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{{
+				Start: 0,
+				End:   16383,
+				Nodes: []redis.ClusterNode{{Addr: addr}},
+			}}, nil
+		},
+	})
+
+	values, err := c.(types.CacheWithGetMulti).GetMulti("foo", "bar", "baz")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("foo value"),
+		"bar": []byte("bar value"),
+	}, values)
+}
@@ -29,10 +29,39 @@ func LintV2(ctx docs.LintContext, rawBytes []byte) ([]string, error) {
 	}
 
 	var lintStrs []string
-	for _, lint := range Spec().LintYAML(ctx, &rawNode) {
+	for _, lint := range Spec().LintYAML(LintContextWithImports(ctx, &rawNode), &rawNode) {
 		if lint.Level == docs.LintError {
 			lintStrs = append(lintStrs, fmt.Sprintf("line %v: %v", lint.Line, lint.What))
 		}
 	}
 	return lintStrs, nil
 }
+
+// LintContextWithImports returns a lint context where the Bloblang definitions
+// of the files listed in the bloblang_imports field of a config are available
+// to the mappings being linted. Files that fail to import are ignored, as the
+// error is reported when the config is executed.
+func LintContextWithImports(ctx docs.LintContext, rawNode *yaml.Node) docs.LintContext {
+	root := rawNode
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return ctx
+	}
+
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value != "bloblang_imports" {
+			continue
+		}
+		var paths []string
+		if err := root.Content[i+1].Decode(&paths); err != nil || len(paths) == 0 {
+			return ctx
+		}
+		if env, err := ctx.BloblangEnv.WithImports(paths...); err == nil {
+			ctx.BloblangEnv = env
+		}
+		return ctx
+	}
+	return ctx
+}
@@ -27,11 +27,39 @@ This input adds the following metadata fields to each message:
 - amqp_content_type
 - amqp_content_encoding
 - amqp_creation_time
-- All string typed message annotations
+- All message annotations
 ` + "```" + `
 
+Message annotations that are not strings, such as the enqueued time and
+sequence number annotations added by Azure Service Bus, are formatted as
+strings. Annotations of composite types are not added.
+
 You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+### Flow Control
+
+The ` + "`credit`" + ` field sets the link credit of the receiver, which is the
+maximum number of messages the server sends before they've been acknowledged.
+Increasing it can improve throughput at the cost of messages being held by
+Benthos whilst they wait to be processed.
+
+### Durable Subscriptions
+
+Setting a ` + "`durability`" + ` other than ` + "`none`" + ` creates a durable
+subscription, which brokers such as Solace and Apache ActiveMQ keep between
+connections so that messages sent whilst disconnected are not lost. Durable
+subscriptions are identified by the ` + "`link_name`" + ` and
+` + "`container_id`" + ` fields, which must be the same each time Benthos
+connects, and usually require an ` + "`expiry_policy`" + ` of ` + "`never`" + `.
+
+### Acknowledgements
+
+With a ` + "`settle_mode`" + ` of ` + "`unsettled`" + ` messages are accepted once
+they have been successfully delivered, and when delivery fails the action taken
+is set by the ` + "`nack_action`" + ` field. With a ` + "`settle_mode`" + ` of
+` + "`settled`" + ` the server considers messages delivered as soon as they are
+sent, and therefore messages can be lost in the event of a failure.`,
 		Categories: []Category{
 			CategoryServices,
 		},
@@ -43,6 +71,26 @@ You can access these metadata fields using
 			),
 			docs.FieldCommon("source_address", "The source address to consume from.", "/foo", "queue:/bar", "topic:/baz"),
 			docs.FieldAdvanced("azure_renew_lock", "Experimental: Azure service bus specific option to renew lock if processing takes more then configured lock time").AtVersion("3.45.0"),
+			docs.FieldAdvanced("credit", "The maximum number of unacknowledged messages the server is allowed to send.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("link_name", "An optional name of the receiver link, which identifies durable subscriptions.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("container_id", "An optional container ID of the connection, which identifies durable subscriptions. When empty a random ID is generated.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("durability", "The durability of the subscription, where any value other than `none` requires a `link_name`.").HasAnnotatedOptions(
+				"none", "The subscription is not kept between connections.",
+				"configuration", "The subscription is kept between connections.",
+				"unsettled-state", "The subscription as well as the state of unsettled messages are kept between connections.",
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("expiry_policy", "The event after which a subscription expires.").HasOptions(
+				"link-detach", "session-end", "connection-close", "never",
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("settle_mode", "Whether messages are sent by the server unsettled, and are therefore settled once acknowledged, or pre-settled.").HasAnnotatedOptions(
+				"unsettled", "Messages are accepted once delivered, or handled according to `nack_action` when delivery fails.",
+				"settled", "Messages are settled by the server when sent, and are not acknowledged.",
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("nack_action", "The disposition of messages that could not be delivered, only used with a `settle_mode` of `unsettled`.").HasAnnotatedOptions(
+				"modify", "The message is marked as failed for redelivery.",
+				"release", "The message is released for redelivery without counting as a failed delivery attempt.",
+				"reject", "The message is rejected and is usually moved to a dead letter queue.",
+			).AtVersion("3.64.0"),
 			tls.FieldSpec(),
 			sasl.FieldSpec(),
 		},
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/internal/tracing"
+	"github.com/Jeffail/benthos/v3/internal/transaction"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -174,6 +175,7 @@ func (r *AsyncReader) loop() {
 		}
 
 		resChan := make(chan types.Response)
+		hooks := transaction.AttachDeliveryHooks(msg)
 		tracing.InitSpans("input_"+r.typeStr, msg)
 		select {
 		case r.transactions <- types.NewTransaction(msg, resChan):
@@ -186,6 +188,7 @@ func (r *AsyncReader) loop() {
 			m types.Message,
 			aFn reader.AsyncAckFn,
 			rChan chan types.Response,
+			dHooks *transaction.DeliveryHooks,
 		) {
 			defer pendingAcks.Done()
 
@@ -196,9 +199,11 @@ func (r *AsyncReader) loop() {
 			case <-r.shutSig.CloseNowChan():
 				// Even if the pipeline is terminating we still want to attempt
 				// to propagate an acknowledgement from in-transit messages.
+				_ = dHooks.Commit(context.Background(), types.ErrTypeClosed)
 				return
 			}
 			if !open {
+				_ = dHooks.Commit(context.Background(), types.ErrTypeClosed)
 				return
 			}
 			if res.SkipAck() && !r.allowSkipAcks {
@@ -210,11 +215,24 @@ func (r *AsyncReader) loop() {
 			tracing.FinishSpans(m)
 
 			ackCtx, ackDone := r.shutSig.CloseNowCtx(context.Background())
+			if err = dHooks.Resolve(ackCtx, res); err != nil {
+				r.log.Errorf("Failed to execute delivery hooks, the message will be rejected: %v\n", err)
+				if res.Error() == nil {
+					res = response.NewError(err)
+				}
+			}
+			commitErr := res.Error()
 			if err = aFn(ackCtx, res); err != nil {
 				r.log.Errorf("Failed to acknowledge message: %v\n", err)
+				if commitErr == nil {
+					commitErr = err
+				}
+			}
+			if err = dHooks.Commit(ackCtx, commitErr); err != nil {
+				r.log.Errorf("Failed to execute commit hooks: %v\n", err)
 			}
 			ackDone()
-		}(msg, ackFn, resChan)
+		}(msg, ackFn, resChan, hooks)
 	}
 }
 
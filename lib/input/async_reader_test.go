@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/transaction"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -515,6 +516,130 @@ func TestAsyncReaderHappyPath(t *testing.T) {
 	}
 }
 
+func TestAsyncReaderDeliveryHooks(t *testing.T) {
+	readerImpl := newMockAsyncReader()
+	readerImpl.msgsToSnd = []types.Message{message.New([][]byte{[]byte("foo")})}
+
+	r, err := NewAsyncReader(
+		"foo", true, readerImpl,
+		log.Noop(), metrics.Noop(),
+	)
+	require.NoError(t, err)
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	go func() {
+		select {
+		case readerImpl.readChan <- nil:
+		case <-time.After(time.Second):
+		}
+		select {
+		case readerImpl.ackChan <- nil:
+		case <-time.After(time.Second):
+		}
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var hookResults []error
+	require.True(t, transaction.AddDeliveryHook(ts.Payload.Get(0), func(ctx context.Context, err error) error {
+		hookResults = append(hookResults, err)
+		return nil
+	}))
+	require.True(t, transaction.AddDeliveryHook(ts.Payload.Get(0).Copy(), func(ctx context.Context, err error) error {
+		hookResults = append(hookResults, err)
+		return errors.New("commit failed")
+	}))
+
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	r.CloseAsync()
+	close(readerImpl.readChan)
+	close(readerImpl.connChan)
+	require.NoError(t, r.WaitForClose(time.Second))
+
+	assert.Equal(t, []error{nil, nil}, hookResults)
+	assert.EqualError(t, readerImpl.ackRcvd[0], "commit failed")
+}
+
+func TestAsyncReaderCommitHooks(t *testing.T) {
+	readerImpl := newMockAsyncReader()
+	readerImpl.msgsToSnd = []types.Message{message.New([][]byte{[]byte("foo")})}
+
+	r, err := NewAsyncReader(
+		"foo", true, readerImpl,
+		log.Noop(), metrics.Noop(),
+	)
+	require.NoError(t, err)
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	go func() {
+		select {
+		case readerImpl.readChan <- nil:
+		case <-time.After(time.Second):
+		}
+		select {
+		case readerImpl.ackChan <- errors.New("checkpoint failed"):
+		case <-time.After(time.Second):
+		}
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	committed := make(chan error, 1)
+	require.True(t, transaction.AddCommitHook(ts.Payload.Get(0).Copy(), func(ctx context.Context, err error) error {
+		readerImpl.ackMut.Lock()
+		acked := readerImpl.ackRcvd[0]
+		readerImpl.ackMut.Unlock()
+		if acked != nil {
+			t.Errorf("Commit hook called before the source was acknowledged: %v", acked)
+		}
+		committed <- err
+		return nil
+	}))
+
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case err := <-committed:
+		assert.EqualError(t, err, "checkpoint failed")
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	r.CloseAsync()
+	close(readerImpl.readChan)
+	close(readerImpl.connChan)
+	require.NoError(t, r.WaitForClose(time.Second))
+}
+
 func TestAsyncReaderCloseWithPendingAcks(t *testing.T) {
 	exp := [][]byte{[]byte("hello world")}
 
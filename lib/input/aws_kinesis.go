@@ -41,10 +41,16 @@ func init() {
 		Summary: `
 Receive messages from one or more Kinesis streams.`,
 		Description: `
-Consumes messages from one or more Kinesis streams either by automatically balancing shards across other instances of this input, or by consuming shards listed explicitly. The latest message sequence consumed by this input is stored within a [DynamoDB table](#table-schema), which allows it to resume at the correct sequence of the shard during restarts. This table is also used for coordination across distributed inputs when shard balancing.
+Consumes messages from one or more Kinesis streams either by automatically balancing shards across other instances of this input, or by consuming shards listed explicitly. The latest message sequence consumed by this input is stored within a [DynamoDB table](#table-schema), which allows it to resume at the correct sequence of the shard during restarts. This table is also used for coordination across distributed inputs when shard balancing. Alternatively, checkpoints and claims can be stored within a [cache resource](/docs/components/caches/about) identified by the field ` + "`checkpoint_cache`" + `, which must support compare-and-swap operations.
 
 Benthos will not store a consumed sequence unless it is acknowledged at the output level, which ensures at-least-once delivery guarantees. However, this also means that by default messages of a given shard cannot be processed concurrently. In order to increase the number of shard messages that can be processed concurrently increase the field ` + "`checkpoint_limit`" + `.
 
+## Enhanced Fan-Out
+
+By default shards are consumed by polling them for records. Alternatively, when the field ` + "`enhanced_fan_out.enabled`" + ` is set to ` + "`true`" + ` this input registers a [stream consumer](https://docs.aws.amazon.com/streams/latest/dev/enhanced-consumers.html) with the name ` + "`enhanced_fan_out.consumer_name`" + ` for each stream and records are pushed to it over shard subscriptions, which provides each consumer with dedicated throughput and lower latency. Consumers are reused if they already exist, and subscriptions are renewed automatically.
+
+Shard balancing and checkpointing works the same way in both modes. When a stream is resharded the shards it produces are picked up once their parent shards have been consumed in full.
+
 ## Table Schema
 
 It's possible to configure Benthos to create the DynamoDB table required for coordination if it does not already exist. However, if you wish to create this yourself (recommended) then create a table with a string HASH key ` + "`StreamID`" + ` and a string RANGE key ` + "`ShardID`" + `. 
@@ -58,6 +64,9 @@ Use the ` + "`batching`" + ` fields to configure an optional [batching policy](/
 				docs.FieldCommon(
 					"dynamodb", "Determines the table used for storing and accessing the latest consumed sequence for shards, and for coordinating balanced consumers of streams.",
 				).WithChildren(dynamoDBCheckpointFields...),
+				docs.FieldAdvanced(
+					"checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store shard checkpoints and claims within instead of a DynamoDB table. The cache must support compare-and-swap operations, such as the `memory`, `redis` and `memcached` caches.",
+				).AtVersion("3.64.0"),
 				docs.FieldCommon(
 					"checkpoint_limit", "The maximum gap between the in flight sequence versus the latest acknowledged sequence at a given time. Increasing this limit enables parallel processing and batching at the output level to work on individual shards. Any given sequence will not be committed unless all messages under that offset are delivered in order to preserve at least once delivery guarantees.",
 				),
@@ -65,6 +74,9 @@ Use the ` + "`batching`" + ` fields to configure an optional [batching policy](/
 				docs.FieldAdvanced("rebalance_period", "The period of time between each attempt to rebalance shards across clients."),
 				docs.FieldAdvanced("lease_period", "The period of time after which a client that has failed to update a shard checkpoint is assumed to be inactive."),
 				docs.FieldCommon("start_from_oldest", "Whether to consume from the oldest message when a sequence does not yet exist for the stream."),
+				docs.FieldAdvanced(
+					"enhanced_fan_out", "Optionally consume shards with [enhanced fan-out](#enhanced-fan-out) subscriptions.",
+				).WithChildren(kinesisFanOutFields...).AtVersion("3.64.0"),
 			}, session.FieldSpecs()...),
 			batch.FieldSpec(),
 		),
@@ -82,11 +94,13 @@ type AWSKinesisConfig struct {
 	session.Config  `json:",inline" yaml:",inline"`
 	Streams         []string                 `json:"streams" yaml:"streams"`
 	DynamoDB        DynamoDBCheckpointConfig `json:"dynamodb" yaml:"dynamodb"`
+	CheckpointCache string                   `json:"checkpoint_cache" yaml:"checkpoint_cache"`
 	CheckpointLimit int                      `json:"checkpoint_limit" yaml:"checkpoint_limit"`
 	CommitPeriod    string                   `json:"commit_period" yaml:"commit_period"`
 	LeasePeriod     string                   `json:"lease_period" yaml:"lease_period"`
 	RebalancePeriod string                   `json:"rebalance_period" yaml:"rebalance_period"`
 	StartFromOldest bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	EnhancedFanOut  AWSKinesisFanOutConfig   `json:"enhanced_fan_out" yaml:"enhanced_fan_out"`
 	Batching        batch.PolicyConfig       `json:"batching" yaml:"batching"`
 }
 
@@ -96,11 +110,13 @@ func NewAWSKinesisConfig() AWSKinesisConfig {
 		Config:          session.NewConfig(),
 		Streams:         []string{},
 		DynamoDB:        NewDynamoDBCheckpointConfig(),
+		CheckpointCache: "",
 		CheckpointLimit: 1,
 		CommitPeriod:    "5s",
 		LeasePeriod:     "30s",
 		RebalancePeriod: "30s",
 		StartFromOldest: true,
+		EnhancedFanOut:  NewAWSKinesisFanOutConfig(),
 		Batching:        batch.NewPolicyConfig(),
 	}
 }
@@ -123,10 +139,12 @@ type kinesisReader struct {
 	boffPool    sync.Pool
 
 	svc          kinesisiface.KinesisAPI
-	checkpointer *awsKinesisCheckpointer
+	checkpointer kinesisCheckpointer
 
 	streamShards    map[string][]string
 	balancedStreams []string
+	consumerARNs    map[string]string
+	rebalanceChan   chan struct{}
 
 	commitPeriod    time.Duration
 	leasePeriod     time.Duration
@@ -154,13 +172,15 @@ func newKinesisReader(
 	}
 
 	k := kinesisReader{
-		conf:         conf,
-		stats:        stats,
-		log:          log,
-		mgr:          mgr,
-		mRebalanced:  stats.GetCounter("rebalanced"),
-		closedChan:   make(chan struct{}),
-		streamShards: map[string][]string{},
+		conf:          conf,
+		stats:         stats,
+		log:           log,
+		mgr:           mgr,
+		mRebalanced:   stats.GetCounter("rebalanced"),
+		closedChan:    make(chan struct{}),
+		streamShards:  map[string][]string{},
+		consumerARNs:  map[string]string{},
+		rebalanceChan: make(chan struct{}, 1),
 	}
 	k.ctx, k.done = context.WithCancel(context.Background())
 
@@ -213,6 +233,12 @@ func newKinesisReader(
 	if k.rebalancePeriod, err = time.ParseDuration(k.conf.RebalancePeriod); err != nil {
 		return nil, fmt.Errorf("failed to parse rebalance period string: %v", err)
 	}
+	if k.conf.CheckpointCache != "" && k.conf.DynamoDB.Table != "" {
+		return nil, errors.New("only one of dynamodb.table and checkpoint_cache can be specified")
+	}
+	if k.conf.EnhancedFanOut.Enabled && k.conf.EnhancedFanOut.ConsumerName == "" {
+		return nil, errors.New("a consumer name must be specified in order to use enhanced fan-out")
+	}
 	return &k, nil
 }
 
@@ -332,7 +358,15 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 	// Stores consumed records that have yet to be added to the batcher.
 	var pending []*kinesis.Record
 	var iter string
-	if iter, initErr = k.getIter(streamID, shardID, startingSequence); initErr != nil {
+
+	// When consuming with enhanced fan-out records are delivered by a shard
+	// subscription rather than pulled with a shard iterator.
+	var fanOutEvents <-chan awsKinesisFanOutEvent
+	subCtx, subCtxClose := context.WithCancel(k.ctx)
+	if k.conf.EnhancedFanOut.Enabled {
+		fanOutEvents = k.runFanOutSubscription(subCtx, streamID, shardID, startingSequence)
+	} else if iter, initErr = k.getIter(streamID, shardID, startingSequence); initErr != nil {
+		subCtxClose()
 		return initErr
 	}
 
@@ -356,11 +390,13 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 	var nextTimedBatchChan <-chan time.Time
 	var nextPullChan <-chan time.Time = unblockedChan
 	var nextFlushChan chan<- asyncMessage
+	var nextEventChan <-chan awsKinesisFanOutEvent
 	commitCtx, commitCtxClose := context.WithTimeout(k.ctx, k.commitPeriod)
 
 	go func() {
 		defer func() {
 			commitCtxClose()
+			subCtxClose()
 			recordBatcher.Close(state == awsKinesisConsumerFinished)
 			boff.Reset()
 			k.boffPool.Put(boff)
@@ -372,6 +408,7 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 				if err := k.checkpointer.Delete(k.ctx, streamID, shardID); err != nil {
 					k.log.Errorf("Failed to remove checkpoint for finished stream '%v' shard '%v': %v\n", streamID, shardID, err)
 				}
+				k.triggerRebalance()
 			case awsKinesisConsumerYielding:
 				reason = " because the shard has been claimed by another client"
 				if err := k.checkpointer.Yield(k.ctx, streamID, shardID, recordBatcher.GetSequence()); err != nil {
@@ -385,10 +422,10 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 			}
 
 			wg.Done()
-			k.log.Debugf("Closing stream '%v' shard '%v' as client '%v'%v\n", streamID, shardID, k.checkpointer.clientID, reason)
+			k.log.Debugf("Closing stream '%v' shard '%v' as client '%v'%v\n", streamID, shardID, k.clientID, reason)
 		}()
 
-		k.log.Debugf("Consuming stream '%v' shard '%v' as client '%v'\n", streamID, shardID, k.checkpointer.clientID)
+		k.log.Debugf("Consuming stream '%v' shard '%v' as client '%v'\n", streamID, shardID, k.clientID)
 
 		// Switches our pull chan to unblocked only if it's currently blocked,
 		// as otherwise it's set to a timed channel that we do not want to
@@ -401,7 +438,12 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 
 		for {
 			var err error
-			if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan {
+			if fanOutEvents != nil {
+				nextEventChan = nil
+				if state == awsKinesisConsumerConsuming && len(pending) == 0 {
+					nextEventChan = fanOutEvents
+				}
+			} else if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan {
 				if pending, iter, err = k.getRecords(streamID, shardID, iter); err != nil {
 					if !awsErrIsTimeout(err) {
 						nextPullChan = time.After(boff.NextBackOff())
@@ -502,6 +544,17 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 				pendingMsg = asyncMessage{}
 			case <-nextPullChan:
 				nextPullChan = unblockedChan
+			case event, open := <-nextEventChan:
+				if !open {
+					// The subscription only ends early when we're shutting
+					// down.
+					state = awsKinesisConsumerClosing
+					return
+				}
+				pending = event.records
+				if event.finished {
+					state = awsKinesisConsumerFinished
+				}
 			case <-k.ctx.Done():
 				state = awsKinesisConsumerClosing
 				return
@@ -541,7 +594,11 @@ func (k *kinesisReader) runBalancedShards() {
 
 			var clientClaims map[string][]awsKinesisClientClaim
 			if err == nil {
-				clientClaims, err = k.checkpointer.AllClaims(k.ctx, streamID)
+				shardIDs := make([]string, 0, len(shardsRes.Shards))
+				for _, s := range shardsRes.Shards {
+					shardIDs = append(shardIDs, *s.ShardId)
+				}
+				clientClaims, err = k.checkpointer.AllClaims(k.ctx, streamID, shardIDs)
 			}
 			if err != nil {
 				if k.ctx.Err() != nil {
@@ -558,8 +615,10 @@ func (k *kinesisReader) runBalancedShards() {
 					unclaimedShards[*s.ShardId] = ""
 				}
 			}
+			claimedShards := map[string]struct{}{}
 			for clientID, claims := range clientClaims {
 				for _, claim := range claims {
+					claimedShards[claim.ShardID] = struct{}{}
 					if time.Since(claim.LeaseTimeout) > k.leasePeriod*2 {
 						unclaimedShards[claim.ShardID] = clientID
 					} else {
@@ -567,6 +626,7 @@ func (k *kinesisReader) runBalancedShards() {
 					}
 				}
 			}
+			withoutPendingChildShards(unclaimedShards, shardsRes.Shards, claimedShards)
 
 			// Have a go at grabbing any unclaimed shards
 			if len(unclaimedShards) > 0 {
@@ -644,6 +704,7 @@ func (k *kinesisReader) runBalancedShards() {
 
 		select {
 		case <-time.After(k.rebalancePeriod):
+		case <-k.rebalanceChan:
 		case <-k.ctx.Done():
 			return
 		}
@@ -707,12 +768,27 @@ func (k *kinesisReader) ConnectWithContext(ctx context.Context) error {
 	}
 
 	svc := kinesis.New(sess)
-	checkpointer, err := newAWSKinesisCheckpointer(sess, k.clientID, k.conf.DynamoDB, k.leasePeriod, k.commitPeriod)
-	if err != nil {
+
+	var checkpointer kinesisCheckpointer
+	if k.conf.CheckpointCache != "" {
+		checkpointer = newAWSKinesisCacheCheckpointer(newLibCheckpointCache(k.mgr, k.conf.CheckpointCache), k.clientID, k.leasePeriod)
+	} else if checkpointer, err = newAWSKinesisCheckpointer(sess, k.clientID, k.conf.DynamoDB, k.leasePeriod, k.commitPeriod); err != nil {
 		return err
 	}
 
 	k.svc = svc
+	if k.conf.EnhancedFanOut.Enabled {
+		streams := append([]string{}, k.balancedStreams...)
+		for streamID := range k.streamShards {
+			streams = append(streams, streamID)
+		}
+		for _, streamID := range streams {
+			if k.consumerARNs[streamID], err = k.registerFanOutConsumer(ctx, streamID); err != nil {
+				return err
+			}
+		}
+	}
+
 	k.checkpointer = checkpointer
 	k.msgChan = make(chan asyncMessage)
 
@@ -55,8 +55,31 @@ var (
 	ErrLeaseNotAcquired = errors.New("the shard could not be leased due to a collision")
 )
 
+// kinesisCheckpointer manages the shard checkpointing of a client, and the
+// claims of shards that are used in order to balance them across clients.
+type kinesisCheckpointer interface {
+	// AllClaims returns a map of client IDs to the shards of a stream claimed
+	// by that client. Checkpointers that are unable to list checkpoints only
+	// consider the provided shards.
+	AllClaims(ctx context.Context, streamID string, shardIDs []string) (map[string][]awsKinesisClientClaim, error)
+
+	// Claim attempts to claim a shard, optionally stealing it from another
+	// client, and returns the sequence to resume from.
+	Claim(ctx context.Context, streamID, shardID, fromClientID string) (string, error)
+
+	// Checkpoint sets the sequence of a shard and returns whether the shard
+	// is still owned by the client.
+	Checkpoint(ctx context.Context, streamID, shardID, sequenceNumber string, final bool) (bool, error)
+
+	// Yield updates the sequence of a shard that has been stolen.
+	Yield(ctx context.Context, streamID, shardID, sequenceNumber string) error
+
+	// Delete removes the checkpoint of a shard.
+	Delete(ctx context.Context, streamID, shardID string) error
+}
+
 // awsKinesisCheckpointer manages the shard checkpointing for a given client
-// identifier.
+// identifier within a DynamoDB table.
 type awsKinesisCheckpointer struct {
 	conf DynamoDBCheckpointConfig
 
@@ -131,9 +154,9 @@ func (k *awsKinesisCheckpointer) ensureTableExists() error {
 
 // awsKinesisCheckpoint contains details of a shard checkpoint.
 type awsKinesisCheckpoint struct {
-	SequenceNumber string
-	ClientID       *string
-	LeaseTimeout   *time.Time
+	SequenceNumber string     `json:"sequence_number"`
+	ClientID       *string    `json:"client_id,omitempty"`
+	LeaseTimeout   *time.Time `json:"lease_timeout,omitempty"`
 }
 
 // Both checkpoint and err can be nil when the item does not exist.
@@ -190,8 +213,9 @@ type awsKinesisClientClaim struct {
 }
 
 // AllClaims returns a map of client IDs to shards claimed by that client,
-// including the lease timeout of the claim.
-func (k *awsKinesisCheckpointer) AllClaims(ctx context.Context, streamID string) (map[string][]awsKinesisClientClaim, error) {
+// including the lease timeout of the claim. All claims of the stream within
+// the table are returned regardless of the shard IDs provided.
+func (k *awsKinesisCheckpointer) AllClaims(ctx context.Context, streamID string, _ []string) (map[string][]awsKinesisClientClaim, error) {
 	clientClaims := make(map[string][]awsKinesisClientClaim)
 	var scanErr error
 
@@ -315,13 +339,8 @@ func (k *awsKinesisCheckpointer) Claim(ctx context.Context, streamID, shardID, f
 	// This allows the victim client to update the checkpoint with the final
 	// sequence as it yields the shard.
 	if len(fromClientID) > 0 && time.Since(currentLease) < k.leaseDuration {
-		// Wait for the estimated next checkpoint time plus a grace period of
-		// one second.
-		waitFor := k.leaseDuration - time.Since(currentLease) + time.Second
-		select {
-		case <-time.After(waitFor):
-		case <-ctx.Done():
-			return "", ctx.Err()
+		if err := awaitKinesisYield(ctx, currentLease, k.leaseDuration); err != nil {
+			return "", err
 		}
 
 		cp, err := k.getCheckpoint(ctx, streamID, shardID)
@@ -334,6 +353,18 @@ func (k *awsKinesisCheckpointer) Claim(ctx context.Context, streamID, shardID, f
 	return startingSequence, nil
 }
 
+// awaitKinesisYield blocks until the estimated next checkpoint time of a
+// client that a shard was stolen from plus a grace period of one second.
+func awaitKinesisYield(ctx context.Context, currentLease time.Time, leaseDuration time.Duration) error {
+	waitFor := leaseDuration - time.Since(currentLease) + time.Second
+	select {
+	case <-time.After(waitFor):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
 // Checkpoint attempts to set a sequence number for a stream shard. Returns a
 // boolean indicating whether this shard is still owned by the client.
 //
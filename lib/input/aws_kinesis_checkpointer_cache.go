@@ -0,0 +1,254 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// libCacheKV adapts a cache resource to the operations used for storing
+// checkpoints.
+type libCacheKV struct {
+	c types.Cache
+}
+
+func (l libCacheKV) Get(ctx context.Context, key string) ([]byte, error) {
+	return l.c.Get(key)
+}
+
+func (l libCacheKV) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if ttlCache, ok := l.c.(types.CacheWithTTL); ok && ttl != nil {
+		return ttlCache.SetWithTTL(key, value, ttl)
+	}
+	return l.c.Set(key, value)
+}
+
+func (l libCacheKV) Delete(ctx context.Context, key string) error {
+	return l.c.Delete(key)
+}
+
+func (l libCacheKV) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	cas, ok := l.c.(types.CacheWithCompareAndSwap)
+	if !ok {
+		return types.ErrNotSupported
+	}
+	return cas.CompareAndSwap(key, old, value, ttl)
+}
+
+func newLibCheckpointCache(mgr types.Manager, name string) *checkpoint.Cache {
+	return checkpoint.NewCache(name, func(ctx context.Context, fn func(c checkpoint.CacheKV)) error {
+		return interop.AccessCache(ctx, mgr, name, func(c types.Cache) {
+			fn(libCacheKV{c: c})
+		})
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// awsKinesisCacheCheckpointer manages the shard checkpointing for a given
+// client identifier within a cache resource, where each shard checkpoint is
+// stored under the key `<stream>:<shard>` and claims are made with
+// compare-and-swap operations.
+type awsKinesisCacheCheckpointer struct {
+	cache *checkpoint.Cache
+
+	clientID      string
+	leaseDuration time.Duration
+}
+
+func newAWSKinesisCacheCheckpointer(
+	cache *checkpoint.Cache,
+	clientID string,
+	leaseDuration time.Duration,
+) *awsKinesisCacheCheckpointer {
+	return &awsKinesisCacheCheckpointer{
+		cache:         cache,
+		clientID:      clientID,
+		leaseDuration: leaseDuration,
+	}
+}
+
+func (k *awsKinesisCacheCheckpointer) key(streamID, shardID string) string {
+	return streamID + ":" + shardID
+}
+
+// Both checkpoint and raw can be nil when the shard does not have a
+// checkpoint, raw is the current value used for swapping it.
+func (k *awsKinesisCacheCheckpointer) getCheckpoint(ctx context.Context, streamID, shardID string) (*awsKinesisCheckpoint, []byte, error) {
+	raw, err := k.cache.Get(ctx, k.key(streamID, shardID))
+	if err != nil || raw == nil {
+		return nil, nil, err
+	}
+	var c awsKinesisCheckpoint
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &c, raw, nil
+}
+
+func (k *awsKinesisCacheCheckpointer) swapCheckpoint(ctx context.Context, streamID, shardID string, old []byte, c awsKinesisCheckpoint) error {
+	value, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return k.cache.Swap(ctx, k.key(streamID, shardID), old, value)
+}
+
+// AllClaims returns a map of client IDs to shards claimed by that client,
+// including the lease timeout of the claim. Caches cannot be scanned and
+// therefore only the checkpoints of the provided shards are read.
+func (k *awsKinesisCacheCheckpointer) AllClaims(ctx context.Context, streamID string, shardIDs []string) (map[string][]awsKinesisClientClaim, error) {
+	clientClaims := make(map[string][]awsKinesisClientClaim)
+	for _, shardID := range shardIDs {
+		c, _, err := k.getCheckpoint(ctx, streamID, shardID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || c.ClientID == nil {
+			continue
+		}
+		if c.LeaseTimeout == nil || c.LeaseTimeout.IsZero() {
+			return nil, errors.New("failed to extract lease timeout from claim")
+		}
+		clientClaims[*c.ClientID] = append(clientClaims[*c.ClientID], awsKinesisClientClaim{
+			ShardID:      shardID,
+			LeaseTimeout: *c.LeaseTimeout,
+		})
+	}
+	return clientClaims, nil
+}
+
+// Claim attempts to claim a shard for a particular stream ID. If fromClientID
+// is specified the shard is stolen from that particular client, and the
+// operation fails if a different client ID has it claimed.
+//
+// If fromClientID is specified this call will claim the new shard but block
+// for a period of time before reacquiring the sequence ID. This allows the
+// client we're claiming from to gracefully update the sequence number before
+// stopping.
+func (k *awsKinesisCacheCheckpointer) Claim(ctx context.Context, streamID, shardID, fromClientID string) (string, error) {
+	c, raw, err := k.getCheckpoint(ctx, streamID, shardID)
+	if err != nil {
+		return "", err
+	}
+
+	var currentClientID string
+	if c != nil && c.ClientID != nil {
+		currentClientID = *c.ClientID
+	}
+	if currentClientID != fromClientID {
+		return "", ErrLeaseNotAcquired
+	}
+
+	var claimed awsKinesisCheckpoint
+	var currentLease time.Time
+	if c != nil {
+		claimed.SequenceNumber = c.SequenceNumber
+		if c.LeaseTimeout != nil {
+			currentLease = *c.LeaseTimeout
+		}
+	}
+	newLeaseTimeout := time.Now().Add(k.leaseDuration)
+	claimed.ClientID = &k.clientID
+	claimed.LeaseTimeout = &newLeaseTimeout
+
+	if err := k.swapCheckpoint(ctx, streamID, shardID, raw, claimed); err != nil {
+		if errors.Is(err, checkpoint.ErrCacheModified) {
+			return "", ErrLeaseNotAcquired
+		}
+		return "", err
+	}
+
+	startingSequence := claimed.SequenceNumber
+	if len(fromClientID) > 0 && time.Since(currentLease) < k.leaseDuration {
+		if err := awaitKinesisYield(ctx, currentLease, k.leaseDuration); err != nil {
+			return "", err
+		}
+
+		if c, _, err = k.getCheckpoint(ctx, streamID, shardID); err != nil {
+			return "", err
+		}
+		if c != nil {
+			startingSequence = c.SequenceNumber
+		}
+	}
+
+	return startingSequence, nil
+}
+
+// Checkpoint attempts to set a sequence number for a stream shard. Returns a
+// boolean indicating whether this shard is still owned by the client.
+//
+// If final is true the client ID is removed from the checkpoint, indicating
+// that this client is finished with the shard.
+func (k *awsKinesisCacheCheckpointer) Checkpoint(ctx context.Context, streamID, shardID, sequenceNumber string, final bool) (bool, error) {
+	c, raw, err := k.getCheckpoint(ctx, streamID, shardID)
+	if err != nil {
+		return false, err
+	}
+	if c == nil || c.ClientID == nil || *c.ClientID != k.clientID {
+		return false, nil
+	}
+
+	updated := awsKinesisCheckpoint{
+		SequenceNumber: sequenceNumber,
+	}
+	if !final {
+		leaseTimeout := time.Now().Add(k.leaseDuration)
+		updated.ClientID = &k.clientID
+		updated.LeaseTimeout = &leaseTimeout
+	}
+
+	if err := k.swapCheckpoint(ctx, streamID, shardID, raw, updated); err != nil {
+		if errors.Is(err, checkpoint.ErrCacheModified) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Yield updates an existing checkpoint sequence number and no other fields.
+// This should be done after a non-final checkpoint indicates that shard has
+// been stolen and allows the thief client to start with the latest sequence
+// rather than the sequence at the point of the theft.
+func (k *awsKinesisCacheCheckpointer) Yield(ctx context.Context, streamID, shardID, sequenceNumber string) error {
+	if sequenceNumber == "" {
+		// Nothing to present to the thief
+		return nil
+	}
+
+	for {
+		c, raw, err := k.getCheckpoint(ctx, streamID, shardID)
+		if err != nil {
+			return err
+		}
+
+		var updated awsKinesisCheckpoint
+		if c != nil {
+			updated = *c
+		}
+		updated.SequenceNumber = sequenceNumber
+
+		// The thief might update its lease concurrently, in which case we
+		// read the checkpoint again and retry.
+		if err = k.swapCheckpoint(ctx, streamID, shardID, raw, updated); !errors.Is(err, checkpoint.ErrCacheModified) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// Delete attempts to delete a checkpoint, this should be called when a shard is
+// emptied.
+func (k *awsKinesisCacheCheckpointer) Delete(ctx context.Context, streamID, shardID string) error {
+	return k.cache.Delete(ctx, k.key(streamID, shardID))
+}
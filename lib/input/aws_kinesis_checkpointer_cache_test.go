@@ -0,0 +1,145 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/checkpoint"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type kinesisTestCache struct {
+	types.Cache
+
+	mut   sync.Mutex
+	items map[string][]byte
+}
+
+func (c *kinesisTestCache) Get(key string) ([]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	v, exists := c.items[key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *kinesisTestCache) Set(key string, value []byte) error {
+	c.mut.Lock()
+	c.items[key] = value
+	c.mut.Unlock()
+	return nil
+}
+
+func (c *kinesisTestCache) Delete(key string) error {
+	c.mut.Lock()
+	delete(c.items, key)
+	c.mut.Unlock()
+	return nil
+}
+
+func (c *kinesisTestCache) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	current, exists := c.items[key]
+	if old == nil {
+		if exists {
+			return types.ErrKeyAlreadyExists
+		}
+	} else if !exists {
+		return types.ErrKeyNotFound
+	} else if !bytes.Equal(current, old) {
+		return types.ErrKeyValueMismatch
+	}
+	c.items[key] = value
+	return nil
+}
+
+func testKinesisCheckpointCache(c types.Cache) *checkpoint.Cache {
+	return checkpoint.NewCache("foo", func(ctx context.Context, fn func(c checkpoint.CacheKV)) error {
+		fn(libCacheKV{c: c})
+		return nil
+	})
+}
+
+func TestAWSKinesisCacheCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	cache := testKinesisCheckpointCache(&kinesisTestCache{items: map[string][]byte{}})
+
+	a := newAWSKinesisCacheCheckpointer(cache, "a", time.Millisecond)
+	b := newAWSKinesisCacheCheckpointer(cache, "b", time.Millisecond)
+
+	seq, err := a.Claim(ctx, "foo", "0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "", seq)
+
+	_, err = b.Claim(ctx, "foo", "0", "")
+	assert.Equal(t, ErrLeaseNotAcquired, err)
+
+	_, err = b.Claim(ctx, "foo", "0", "c")
+	assert.Equal(t, ErrLeaseNotAcquired, err)
+
+	owned, err := a.Checkpoint(ctx, "foo", "0", "10", false)
+	require.NoError(t, err)
+	assert.True(t, owned)
+
+	owned, err = b.Checkpoint(ctx, "foo", "0", "20", false)
+	require.NoError(t, err)
+	assert.False(t, owned)
+
+	claims, err := b.AllClaims(ctx, "foo", []string{"0", "1"})
+	require.NoError(t, err)
+	require.Len(t, claims, 1)
+	require.Len(t, claims["a"], 1)
+	assert.Equal(t, "0", claims["a"][0].ShardID)
+
+	// Ensure the lease of a has expired so that the theft does not wait for a
+	// yield.
+	<-time.After(time.Millisecond * 10)
+
+	seq, err = b.Claim(ctx, "foo", "0", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "10", seq)
+
+	owned, err = a.Checkpoint(ctx, "foo", "0", "15", false)
+	require.NoError(t, err)
+	assert.False(t, owned)
+
+	require.NoError(t, a.Yield(ctx, "foo", "0", "15"))
+
+	claims, err = a.AllClaims(ctx, "foo", []string{"0"})
+	require.NoError(t, err)
+	require.Len(t, claims, 1)
+	require.Len(t, claims["b"], 1)
+
+	owned, err = b.Checkpoint(ctx, "foo", "0", "20", true)
+	require.NoError(t, err)
+	assert.True(t, owned)
+
+	claims, err = a.AllClaims(ctx, "foo", []string{"0"})
+	require.NoError(t, err)
+	assert.Empty(t, claims)
+
+	seq, err = a.Claim(ctx, "foo", "0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "20", seq)
+
+	require.NoError(t, a.Delete(ctx, "foo", "0"))
+	seq, err = b.Claim(ctx, "foo", "0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "", seq)
+}
+
+func TestAWSKinesisCacheCheckpointerNoCAS(t *testing.T) {
+	noCAS := testKinesisCheckpointCache(struct{ types.Cache }{&kinesisTestCache{items: map[string][]byte{}}})
+
+	_, err := newAWSKinesisCacheCheckpointer(noCAS, "a", time.Second).Claim(context.Background(), "foo", "0", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support compare-and-swap")
+}
@@ -0,0 +1,224 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+var kinesisFanOutFields = docs.FieldSpecs{
+	docs.FieldCommon("enabled", "Whether to consume shards with enhanced fan-out subscriptions rather than by polling."),
+	docs.FieldCommon("consumer_name", "The name of the stream consumer to register and subscribe with. Inputs that share a consumer name also share its throughput, and therefore it should be the same for all inputs coordinating through the same DynamoDB table."),
+}
+
+// AWSKinesisFanOutConfig contains configuration parameters for consuming
+// Kinesis shards with enhanced fan-out.
+type AWSKinesisFanOutConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	ConsumerName string `json:"consumer_name" yaml:"consumer_name"`
+}
+
+// NewAWSKinesisFanOutConfig returns an AWSKinesisFanOutConfig with default
+// values.
+func NewAWSKinesisFanOutConfig() AWSKinesisFanOutConfig {
+	return AWSKinesisFanOutConfig{
+		Enabled:      false,
+		ConsumerName: "benthos",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// registerFanOutConsumer obtains the ARN of the configured stream consumer of
+// a stream, registering the consumer if it does not yet exist and waiting for
+// it to become active.
+func (k *kinesisReader) registerFanOutConsumer(ctx context.Context, streamID string) (string, error) {
+	summary, err := k.svc.DescribeStreamSummaryWithContext(ctx, &kinesis.DescribeStreamSummaryInput{
+		StreamName: aws.String(streamID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe stream '%v': %w", streamID, err)
+	}
+	streamARN := summary.StreamDescriptionSummary.StreamARN
+
+	var consumerARN, status string
+	regRes, err := k.svc.RegisterStreamConsumerWithContext(ctx, &kinesis.RegisterStreamConsumerInput{
+		ConsumerName: aws.String(k.conf.EnhancedFanOut.ConsumerName),
+		StreamARN:    streamARN,
+	})
+	if err == nil {
+		consumerARN = aws.StringValue(regRes.Consumer.ConsumerARN)
+		status = aws.StringValue(regRes.Consumer.ConsumerStatus)
+		k.log.Infof("Registered consumer '%v' of stream '%v'\n", k.conf.EnhancedFanOut.ConsumerName, streamID)
+	} else {
+		// Consumers that already exist are simply reused.
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) || aerr.Code() != kinesis.ErrCodeResourceInUseException {
+			return "", fmt.Errorf("failed to register consumer of stream '%v': %w", streamID, err)
+		}
+	}
+
+	for status != kinesis.ConsumerStatusActive {
+		if consumerARN != "" {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		descRes, err := k.svc.DescribeStreamConsumerWithContext(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerName: aws.String(k.conf.EnhancedFanOut.ConsumerName),
+			StreamARN:    streamARN,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to describe consumer of stream '%v': %w", streamID, err)
+		}
+		consumerARN = aws.StringValue(descRes.ConsumerDescription.ConsumerARN)
+		if status = aws.StringValue(descRes.ConsumerDescription.ConsumerStatus); status == kinesis.ConsumerStatusDeleting {
+			return "", fmt.Errorf("consumer '%v' of stream '%v' is being deleted", k.conf.EnhancedFanOut.ConsumerName, streamID)
+		}
+	}
+	return consumerARN, nil
+}
+
+// awsKinesisFanOutEvent is a batch of records delivered by a shard
+// subscription.
+type awsKinesisFanOutEvent struct {
+	records []*kinesis.Record
+
+	// Set when the shard has been closed and all of its records have been
+	// delivered.
+	finished bool
+}
+
+// runFanOutSubscription subscribes to a shard and delivers the records of each
+// subscription event to the returned channel until the context is cancelled or
+// the shard is finished. Subscriptions expire after five minutes, and are
+// renewed from the last delivered record.
+func (k *kinesisReader) runFanOutSubscription(ctx context.Context, streamID, shardID, startingSequence string) <-chan awsKinesisFanOutEvent {
+	eventsChan := make(chan awsKinesisFanOutEvent)
+
+	go func() {
+		defer close(eventsChan)
+
+		boff := k.boffPool.Get().(backoff.BackOff)
+		defer func() {
+			boff.Reset()
+			k.boffPool.Put(boff)
+		}()
+
+		sequence := startingSequence
+		for {
+			position := &kinesis.StartingPosition{
+				Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon),
+			}
+			if sequence != "" {
+				position.Type = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+				position.SequenceNumber = aws.String(sequence)
+			} else if !k.conf.StartFromOldest {
+				position.Type = aws.String(kinesis.ShardIteratorTypeLatest)
+			}
+
+			res, err := k.svc.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
+				ConsumerARN:      aws.String(k.consumerARNs[streamID]),
+				ShardId:          aws.String(shardID),
+				StartingPosition: position,
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				k.log.Errorf("Failed to subscribe to stream '%v' shard '%v': %v\n", streamID, shardID, err)
+				select {
+				case <-time.After(boff.NextBackOff()):
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			stream := res.GetStream()
+			for e := range stream.Events() {
+				event, ok := e.(*kinesis.SubscribeToShardEvent)
+				if !ok {
+					continue
+				}
+				boff.Reset()
+
+				fEvent := awsKinesisFanOutEvent{
+					records:  event.Records,
+					finished: event.ContinuationSequenceNumber == nil,
+				}
+				if len(fEvent.records) > 0 || fEvent.finished {
+					select {
+					case eventsChan <- fEvent:
+					case <-ctx.Done():
+						stream.Close()
+						return
+					}
+				}
+				if fEvent.finished {
+					stream.Close()
+					return
+				}
+				sequence = *event.ContinuationSequenceNumber
+			}
+			err = stream.Err()
+			stream.Close()
+
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				k.log.Errorf("Subscription to stream '%v' shard '%v' failed: %v\n", streamID, shardID, err)
+				select {
+				case <-time.After(boff.NextBackOff()):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return eventsChan
+}
+
+//------------------------------------------------------------------------------
+
+// withoutPendingChildShards removes shards from a set of unclaimed shards when
+// their parents are still being consumed, which ensures that records are
+// consumed in order after a stream is resharded.
+func withoutPendingChildShards(unclaimed map[string]string, shards []*kinesis.Shard, claimedShards map[string]struct{}) {
+	for _, s := range shards {
+		if _, exists := unclaimed[*s.ShardId]; !exists {
+			continue
+		}
+		for _, parentID := range []*string{s.ParentShardId, s.AdjacentParentShardId} {
+			if parentID == nil {
+				continue
+			}
+			if _, parentClaimed := claimedShards[*parentID]; parentClaimed {
+				delete(unclaimed, *s.ShardId)
+				break
+			}
+		}
+	}
+}
+
+// triggerRebalance prompts the balanced shards loop to look for new shards
+// without waiting for the next rebalance period.
+func (k *kinesisReader) triggerRebalance() {
+	select {
+	case k.rebalanceChan <- struct{}{}:
+	default:
+	}
+}
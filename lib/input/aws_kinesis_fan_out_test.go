@@ -0,0 +1,38 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinesisWithoutPendingChildShards(t *testing.T) {
+	shards := []*kinesis.Shard{
+		{ShardId: aws.String("a")},
+		{ShardId: aws.String("b")},
+		{ShardId: aws.String("c"), ParentShardId: aws.String("a")},
+		{ShardId: aws.String("d"), ParentShardId: aws.String("a")},
+		{ShardId: aws.String("e"), ParentShardId: aws.String("b")},
+		{ShardId: aws.String("f"), ParentShardId: aws.String("x"), AdjacentParentShardId: aws.String("b")},
+		{ShardId: aws.String("g")},
+	}
+
+	unclaimed := map[string]string{
+		"c": "",
+		"d": "",
+		"e": "",
+		"f": "",
+		"g": "foo",
+	}
+	withoutPendingChildShards(unclaimed, shards, map[string]struct{}{
+		"b": {},
+	})
+
+	assert.Equal(t, map[string]string{
+		"c": "",
+		"d": "",
+		"g": "foo",
+	}, unclaimed)
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -32,7 +33,7 @@ func init() {
 		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 			var r reader.Async
 			var err error
-			if r, err = newAmazonS3(conf.AWSS3, log, stats); err != nil {
+			if r, err = newAmazonS3(conf.AWSS3, mgr, log, stats); err != nil {
 				return nil, err
 			}
 			// If we're not pulling events directly from an SQS queue then
@@ -57,6 +58,12 @@ If your notification events are being routed to SQS via an SNS topic then the ev
 
 When using SQS please make sure you have sensible values for ` + "`sqs.max_messages`" + ` and also the visibility timeout of the queue itself. When Benthos consumes an S3 object the SQS message that triggered it is not deleted until the S3 object has been sent onwards. This ensures at-least-once crash resiliency, but also means that if the S3 object takes longer to process than the visibility timeout of your queue then the same objects might be processed multiple times.
 
+## Watching a Bucket Without SQS
+
+For buckets where it isn't possible to configure upload notifications the field ` + "`watcher.enabled`" + ` can be set, in which case the bucket is listed periodically according to ` + "`watcher.poll_interval`" + ` and only objects that are new or have been modified since they were last consumed are downloaded. The ETag of each consumed object is stored within the [cache resource](/docs/components/caches/about) specified with ` + "`watcher.cache`" + ` once the object has been sent onwards, and therefore a persisted cache allows consumption to resume where it left off after a restart.
+
+Listing a large bucket in full on each poll can be slow and expensive, and therefore when keys are written in lexicographical order, such as keys that are prefixed with a timestamp, the field ` + "`watcher.ordered_keys`" + ` can be set. When enabled the highest key up to which all objects have been consumed is also stored within the cache, and each listing resumes from that key. Objects that are written with keys lower than this marker are never consumed.
+
 ## Downloading Large Files
 
 When downloading large files it's often necessary to process it in streamed parts in order to avoid loading the entire file in memory at a given time. In order to do this a ` + "[`codec`](#codec)" + ` can be specified that determines how to break the input into smaller individual messages.
@@ -102,6 +109,13 @@ You can access these metadata fields using [function interpolation](/docs/config
 				),
 				docs.FieldAdvanced("max_messages", "The maximum number of SQS messages to consume from each request."),
 			),
+			docs.FieldCommon("watcher", "Periodically list the bucket and consume objects that are new or have been modified since they were last consumed, which can be used as an alternative to SQS notifications. This mode cannot be used with `sqs.url`.").WithChildren(
+				docs.FieldCommon("enabled", "Whether watcher mode is enabled."),
+				docs.FieldCommon("poll_interval", "The interval between each listing of the bucket.", "10s", "5m"),
+				docs.FieldCommon("cache", "A [cache resource](/docs/components/caches/about) for storing the ETags of objects already consumed."),
+				docs.FieldAdvanced("minimum_age", "The minimum period of time since an object was last modified before it is consumed.", "10s", "1m"),
+				docs.FieldAdvanced("ordered_keys", "Whether keys are written in lexicographical order, in which case each listing resumes from the highest key up to which all objects have been consumed."),
+			).AtVersion("3.64.0"),
 		),
 		Categories: []Category{
 			CategoryServices,
@@ -136,15 +150,37 @@ func NewAWSS3SQSConfig() AWSS3SQSConfig {
 	}
 }
 
+// AWSS3WatcherConfig contains configuration for periodically listing a bucket
+// for new and modified objects.
+type AWSS3WatcherConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	PollInterval string `json:"poll_interval" yaml:"poll_interval"`
+	Cache        string `json:"cache" yaml:"cache"`
+	MinimumAge   string `json:"minimum_age" yaml:"minimum_age"`
+	OrderedKeys  bool   `json:"ordered_keys" yaml:"ordered_keys"`
+}
+
+// NewAWSS3WatcherConfig creates a new AWSS3WatcherConfig with default values.
+func NewAWSS3WatcherConfig() AWSS3WatcherConfig {
+	return AWSS3WatcherConfig{
+		Enabled:      false,
+		PollInterval: "1m",
+		Cache:        "",
+		MinimumAge:   "0s",
+		OrderedKeys:  false,
+	}
+}
+
 // AWSS3Config contains configuration values for the aws_s3 input type.
 type AWSS3Config struct {
 	sess.Config        `json:",inline" yaml:",inline"`
-	Bucket             string         `json:"bucket" yaml:"bucket"`
-	Codec              string         `json:"codec" yaml:"codec"`
-	Prefix             string         `json:"prefix" yaml:"prefix"`
-	ForcePathStyleURLs bool           `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	DeleteObjects      bool           `json:"delete_objects" yaml:"delete_objects"`
-	SQS                AWSS3SQSConfig `json:"sqs" yaml:"sqs"`
+	Bucket             string             `json:"bucket" yaml:"bucket"`
+	Codec              string             `json:"codec" yaml:"codec"`
+	Prefix             string             `json:"prefix" yaml:"prefix"`
+	ForcePathStyleURLs bool               `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	DeleteObjects      bool               `json:"delete_objects" yaml:"delete_objects"`
+	SQS                AWSS3SQSConfig     `json:"sqs" yaml:"sqs"`
+	Watcher            AWSS3WatcherConfig `json:"watcher" yaml:"watcher"`
 }
 
 // NewAWSS3Config creates a new AWSS3Config with default values.
@@ -157,6 +193,7 @@ func NewAWSS3Config() AWSS3Config {
 		ForcePathStyleURLs: false,
 		DeleteObjects:      false,
 		SQS:                NewAWSS3SQSConfig(),
+		Watcher:            NewAWSS3WatcherConfig(),
 	}
 }
 
@@ -542,6 +579,224 @@ func (s *sqsTargetReader) ackSQSMessage(ctx context.Context, msg *sqs.Message) e
 
 //------------------------------------------------------------------------------
 
+// watcherTargetReader periodically lists a bucket for objects that are new or
+// have been modified since they were last consumed, where the ETags of
+// consumed objects are stored within a cache.
+type watcherTargetReader struct {
+	conf         AWSS3Config
+	log          log.Modular
+	mgr          types.Manager
+	s3           *s3.S3
+	pollInterval time.Duration
+	minAge       time.Duration
+
+	nextList time.Time
+	pending  []*s3ObjectTarget
+
+	// Keys that have been listed and not yet acknowledged, which prevents
+	// them from being listed again whilst they're in flight.
+	inFlightMut sync.Mutex
+	inFlight    map[string]*watcherListedKey
+
+	// When keys are ordered this tracks the keys of the last listing in order
+	// so that the marker only advances past keys that have been consumed.
+	listed []*watcherListedKey
+}
+
+type watcherListedKey struct {
+	key  string
+	done bool
+}
+
+func newWatcherTargetReader(
+	conf AWSS3Config,
+	mgr types.Manager,
+	log log.Modular,
+	s3Client *s3.S3,
+	pollInterval, minAge time.Duration,
+) *watcherTargetReader {
+	return &watcherTargetReader{
+		conf:         conf,
+		log:          log,
+		mgr:          mgr,
+		s3:           s3Client,
+		pollInterval: pollInterval,
+		minAge:       minAge,
+		inFlight:     map[string]*watcherListedKey{},
+	}
+}
+
+func (w *watcherTargetReader) cacheKey(key string) string {
+	return w.conf.Bucket + "/" + key
+}
+
+func (w *watcherTargetReader) markerKey() string {
+	return "benthos_s3_watcher_marker:" + w.conf.Bucket + "/" + w.conf.Prefix
+}
+
+func (w *watcherTargetReader) Pop(ctx context.Context) (*s3ObjectTarget, error) {
+	if len(w.pending) > 0 {
+		t := w.pending[0]
+		w.pending = w.pending[1:]
+		return t, nil
+	}
+
+	if !w.nextList.IsZero() {
+		if until := time.Until(w.nextList); until > 0 {
+			select {
+			case <-time.After(until):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	var err error
+	if w.pending, err = w.listObjects(ctx); err != nil {
+		return nil, err
+	}
+	w.nextList = time.Now().Add(w.pollInterval)
+	if len(w.pending) == 0 {
+		return nil, types.ErrTimeout
+	}
+	t := w.pending[0]
+	w.pending = w.pending[1:]
+	return t, nil
+}
+
+func (w *watcherTargetReader) listObjects(ctx context.Context) ([]*s3ObjectTarget, error) {
+	listInput := &s3.ListObjectsV2Input{
+		Bucket: aws.String(w.conf.Bucket),
+	}
+	if len(w.conf.Prefix) > 0 {
+		listInput.Prefix = aws.String(w.conf.Prefix)
+	}
+
+	var targets []*s3ObjectTarget
+	var listErr error
+	if cerr := interop.AccessCache(ctx, w.mgr, w.conf.Watcher.Cache, func(cache types.Cache) {
+		if w.conf.Watcher.OrderedKeys {
+			if marker, err := cache.Get(w.markerKey()); err == nil && len(marker) > 0 {
+				listInput.StartAfter = aws.String(string(marker))
+			}
+		}
+
+		w.inFlightMut.Lock()
+		defer w.inFlightMut.Unlock()
+
+		var listed []*watcherListedKey
+		err := w.s3.ListObjectsV2PagesWithContext(ctx, listInput, func(page *s3.ListObjectsV2Output, _ bool) bool {
+			for _, obj := range page.Contents {
+				if obj.Key == nil {
+					continue
+				}
+				key := *obj.Key
+				if lKey, exists := w.inFlight[key]; exists {
+					if w.conf.Watcher.OrderedKeys {
+						listed = append(listed, lKey)
+					}
+					continue
+				}
+				if w.minAge > 0 && obj.LastModified != nil && time.Since(*obj.LastModified) < w.minAge {
+					if w.conf.Watcher.OrderedKeys {
+						// Keys after an object that is too young are not
+						// consumed in order to prevent the marker from
+						// advancing past it.
+						return false
+					}
+					continue
+				}
+
+				etag := aws.StringValue(obj.ETag)
+				if consumed, err := cache.Get(w.cacheKey(key)); err == nil && string(consumed) == etag {
+					if w.conf.Watcher.OrderedKeys {
+						listed = append(listed, &watcherListedKey{key: key, done: true})
+					}
+					continue
+				}
+
+				lKey := &watcherListedKey{key: key}
+				if w.conf.Watcher.OrderedKeys {
+					listed = append(listed, lKey)
+				}
+				w.inFlight[key] = lKey
+				targets = append(targets, newS3ObjectTarget(key, w.conf.Bucket, time.Time{}, deleteS3ObjectAckFn(
+					w.s3, w.conf.Bucket, key, w.conf.DeleteObjects,
+					func(ctx context.Context, err error) error {
+						return w.ackObject(ctx, lKey, etag, err)
+					},
+				)))
+			}
+			return true
+		})
+		if err != nil {
+			for _, t := range targets {
+				delete(w.inFlight, t.key)
+			}
+			targets, listErr = nil, err
+			return
+		}
+		// Listing always resumes from the marker, and therefore the keys
+		// being tracked are replaced entirely.
+		w.listed = listed
+	}); cerr != nil {
+		return nil, fmt.Errorf("failed to access cache for watcher mode: %v", cerr)
+	}
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", listErr)
+	}
+	return targets, nil
+}
+
+func (w *watcherTargetReader) ackObject(ctx context.Context, lKey *watcherListedKey, etag string, err error) error {
+	if err != nil {
+		// The object will be listed again on the next poll.
+		w.inFlightMut.Lock()
+		delete(w.inFlight, lKey.key)
+		w.inFlightMut.Unlock()
+		return nil
+	}
+
+	var setErr error
+	if cerr := interop.AccessCache(ctx, w.mgr, w.conf.Watcher.Cache, func(cache types.Cache) {
+		w.inFlightMut.Lock()
+		defer w.inFlightMut.Unlock()
+
+		delete(w.inFlight, lKey.key)
+		if setErr = cache.Set(w.cacheKey(lKey.key), []byte(etag)); setErr != nil {
+			return
+		}
+		if !w.conf.Watcher.OrderedKeys {
+			return
+		}
+
+		lKey.done = true
+		var marker string
+		for len(w.listed) > 0 && w.listed[0].done {
+			marker = w.listed[0].key
+			w.listed = w.listed[1:]
+		}
+		if marker != "" {
+			setErr = cache.Set(w.markerKey(), []byte(marker))
+		}
+	}); cerr != nil {
+		w.inFlightMut.Lock()
+		delete(w.inFlight, lKey.key)
+		w.inFlightMut.Unlock()
+		return fmt.Errorf("failed to access cache for watcher mode: %v", cerr)
+	}
+	if setErr != nil {
+		return fmt.Errorf("failed to update cache for key %v: %w", lKey.key, setErr)
+	}
+	return nil
+}
+
+func (w *watcherTargetReader) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // AmazonS3 is a benthos reader.Type implementation that reads messages from an
 // Amazon S3 bucket.
 type awsS3 struct {
@@ -556,9 +811,13 @@ type awsS3 struct {
 
 	gracePeriod time.Duration
 
+	watcherPollInterval time.Duration
+	watcherMinAge       time.Duration
+
 	objectMut sync.Mutex
 	object    *s3PendingObject
 
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 }
@@ -573,6 +832,7 @@ type s3PendingObject struct {
 // NewAmazonS3 creates a new Amazon S3 bucket reader.Type.
 func newAmazonS3(
 	conf AWSS3Config,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) (*awsS3, error) {
@@ -582,8 +842,12 @@ func newAmazonS3(
 	if conf.Prefix != "" && conf.SQS.URL != "" {
 		return nil, errors.New("cannot specify both a prefix and sqs.url")
 	}
+	if conf.Watcher.Enabled && conf.SQS.URL != "" {
+		return nil, errors.New("cannot enable watcher mode and specify an sqs.url")
+	}
 	s := &awsS3{
 		conf:  conf,
+		mgr:   mgr,
 		log:   log,
 		stats: stats,
 	}
@@ -596,6 +860,25 @@ func newAmazonS3(
 			return nil, fmt.Errorf("failed to parse grace period: %w", err)
 		}
 	}
+	if conf.Watcher.Enabled {
+		if conf.Bucket == "" {
+			return nil, errors.New("a bucket must be specified when watcher mode is enabled")
+		}
+		if s.watcherPollInterval, err = time.ParseDuration(conf.Watcher.PollInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse watcher poll interval: %w", err)
+		}
+		if conf.Watcher.MinimumAge != "" {
+			if s.watcherMinAge, err = time.ParseDuration(conf.Watcher.MinimumAge); err != nil {
+				return nil, fmt.Errorf("failed to parse watcher minimum age: %w", err)
+			}
+		}
+		if conf.Watcher.Cache == "" {
+			return nil, errors.New("a cache must be specified when watcher mode is enabled")
+		}
+		if err := interop.ProbeCache(context.Background(), mgr, conf.Watcher.Cache); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -603,6 +886,9 @@ func (a *awsS3) getTargetReader(ctx context.Context) (s3ObjectTargetReader, erro
 	if a.sqs != nil {
 		return newSQSTargetReader(a.conf, a.log, a.s3, a.sqs), nil
 	}
+	if a.conf.Watcher.Enabled {
+		return newWatcherTargetReader(a.conf, a.mgr, a.log, a.s3, a.watcherPollInterval, a.watcherMinAge), nil
+	}
 	return newStaticTargetReader(ctx, a.conf, a.log, a.s3)
 }
 
@@ -637,7 +923,9 @@ func (a *awsS3) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	if a.conf.SQS.URL == "" {
+	if a.conf.Watcher.Enabled {
+		a.log.Infof("Watching S3 bucket for new objects: %s\n", a.conf.Bucket)
+	} else if a.conf.SQS.URL == "" {
 		a.log.Infof("Downloading S3 objects from bucket: %s\n", a.conf.Bucket)
 	} else {
 		a.log.Infof("Downloading S3 objects found in messages from SQS: %s\n", a.conf.SQS.URL)
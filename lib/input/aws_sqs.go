@@ -2,6 +2,8 @@ package input
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/cenkalti/backoff/v4"
 )
 
@@ -51,15 +54,49 @@ This input adds the following metadata fields to each message:
 - sqs_message_id
 - sqs_receipt_handle
 - sqs_approximate_receive_count
+- sqs_message_group_id (FIFO queues only)
 - All message attributes
 ` + "```" + `
 
+When ` + "`redrive_metadata`" + ` is enabled the following metadata fields are
+also added when present, which describe the redrive history of messages and are
+useful when consuming from a dead letter queue:
+
+` + "```text" + `
+- sqs_dead_letter_queue_source_arn
+- sqs_sent_timestamp
+- sqs_approximate_first_receive_timestamp
+` + "```" + `
+
 You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+### Long Running Processing
+
+Messages that take longer to process than the visibility timeout of the queue
+become visible to other consumers and are delivered again. Setting
+` + "`visibility_heartbeat`" + ` periodically extends the visibility timeout of
+messages to ` + "`visibility_timeout`" + ` until they are acknowledged, allowing
+the visibility timeout itself to remain short so that messages of a failed
+consumer are redelivered promptly.
+
+### FIFO Queues
+
+The messages of a single message group are received in order, but are otherwise
+processed concurrently and therefore may be delivered out of order. Enabling
+` + "`fifo_ordering`" + ` ensures that only one message of each message group is
+processed at a time, where the next message of a group is only dispatched once
+the previous one has been acknowledged. When a message is rejected the pending
+messages of its group are released back to the queue, so that the group is
+redelivered in its original order.`,
 		FieldSpecs: append(docs.FieldSpecs{
 			docs.FieldCommon("url", "The SQS URL to consume from."),
 			docs.FieldAdvanced("delete_message", "Whether to delete the consumed message once it is acked. Disabling allows you to handle the deletion using a different mechanism."),
 			docs.FieldAdvanced("reset_visibility", "Whether to set the visibility timeout of the consumed message to zero once it is nacked. Disabling honors the preset visibility timeout specified for the queue.").AtVersion("3.58.0"),
+			docs.FieldAdvanced("visibility_timeout", "An optional visibility timeout to set for received messages, overriding the visibility timeout of the queue. Required when `visibility_heartbeat` is set.", "30s").AtVersion("3.64.0"),
+			docs.FieldAdvanced("visibility_heartbeat", "An optional period at which the visibility timeout of messages that have not yet been acknowledged is extended to `visibility_timeout`. Must be shorter than `visibility_timeout`.", "10s").AtVersion("3.64.0"),
+			docs.FieldAdvanced("fifo_ordering", "Whether to process only one message of each FIFO message group at a time, preserving the order of messages within groups.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("redrive_metadata", "Whether to add metadata fields describing the redrive history of messages, such as the ARN of the source queue of messages consumed from a dead letter queue.").AtVersion("3.64.0"),
 		}, sess.FieldSpecs()...),
 		Categories: []Category{
 			CategoryServices,
@@ -72,19 +109,27 @@ You can access these metadata fields using
 
 // AWSSQSConfig contains configuration values for the input type.
 type AWSSQSConfig struct {
-	sess.Config     `json:",inline" yaml:",inline"`
-	URL             string `json:"url" yaml:"url"`
-	DeleteMessage   bool   `json:"delete_message" yaml:"delete_message"`
-	ResetVisibility bool   `json:"reset_visibility" yaml:"reset_visibility"`
+	sess.Config         `json:",inline" yaml:",inline"`
+	URL                 string `json:"url" yaml:"url"`
+	DeleteMessage       bool   `json:"delete_message" yaml:"delete_message"`
+	ResetVisibility     bool   `json:"reset_visibility" yaml:"reset_visibility"`
+	VisibilityTimeout   string `json:"visibility_timeout" yaml:"visibility_timeout"`
+	VisibilityHeartbeat string `json:"visibility_heartbeat" yaml:"visibility_heartbeat"`
+	FIFOOrdering        bool   `json:"fifo_ordering" yaml:"fifo_ordering"`
+	RedriveMetadata     bool   `json:"redrive_metadata" yaml:"redrive_metadata"`
 }
 
 // NewAWSSQSConfig creates a new Config with default values.
 func NewAWSSQSConfig() AWSSQSConfig {
 	return AWSSQSConfig{
-		Config:          sess.NewConfig(),
-		URL:             "",
-		DeleteMessage:   true,
-		ResetVisibility: true,
+		Config:              sess.NewConfig(),
+		URL:                 "",
+		DeleteMessage:       true,
+		ResetVisibility:     true,
+		VisibilityTimeout:   "",
+		VisibilityHeartbeat: "",
+		FIFOOrdering:        false,
+		RedriveMetadata:     false,
 	}
 }
 
@@ -94,7 +139,23 @@ type awsSQS struct {
 	conf AWSSQSConfig
 
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
+
+	visibilityTimeout int64
+	heartbeat         time.Duration
+
+	// Handles of messages that have been received but not yet acknowledged,
+	// which have their visibility timeout extended by the heartbeat.
+	trackedMut sync.Mutex
+	tracked    map[string]sqsMessageHandle
+
+	// Message groups that have a message being processed, and message groups
+	// that had a message rejected, which are used for dispatching messages of
+	// FIFO queues in order.
+	groupsMut      sync.Mutex
+	groupsInFlight map[string]struct{}
+	groupsNacked   map[string]struct{}
+	groupsChanged  chan struct{}
 
 	messagesChan     chan *sqs.Message
 	ackMessagesChan  chan sqsMessageHandle
@@ -106,15 +167,41 @@ type awsSQS struct {
 }
 
 func newAWSSQS(conf AWSSQSConfig, log log.Modular, stats metrics.Type) (*awsSQS, error) {
-	return &awsSQS{
+	a := &awsSQS{
 		conf:             conf,
 		log:              log,
 		stats:            stats,
+		tracked:          map[string]sqsMessageHandle{},
+		groupsInFlight:   map[string]struct{}{},
+		groupsNacked:     map[string]struct{}{},
+		groupsChanged:    make(chan struct{}, 1),
 		messagesChan:     make(chan *sqs.Message),
 		ackMessagesChan:  make(chan sqsMessageHandle),
 		nackMessagesChan: make(chan sqsMessageHandle),
 		closeSignal:      shutdown.NewSignaller(),
-	}, nil
+	}
+
+	var visibilityTimeout time.Duration
+	if conf.VisibilityTimeout != "" {
+		var err error
+		if visibilityTimeout, err = time.ParseDuration(conf.VisibilityTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse visibility timeout: %v", err)
+		}
+		a.visibilityTimeout = int64(visibilityTimeout / time.Second)
+	}
+	if conf.VisibilityHeartbeat != "" {
+		var err error
+		if a.heartbeat, err = time.ParseDuration(conf.VisibilityHeartbeat); err != nil {
+			return nil, fmt.Errorf("failed to parse visibility heartbeat: %v", err)
+		}
+		if conf.VisibilityTimeout == "" {
+			return nil, errors.New("a visibility_timeout must be set in order to use visibility_heartbeat")
+		}
+		if a.heartbeat <= 0 || a.heartbeat >= visibilityTimeout {
+			return nil, errors.New("visibility_heartbeat must be a positive period shorter than visibility_timeout")
+		}
+	}
+	return a, nil
 }
 
 // ConnectWithContext attempts to establish a connection to the target SQS
@@ -129,13 +216,19 @@ func (a *awsSQS) ConnectWithContext(ctx context.Context) error {
 		return err
 	}
 
-	a.sqs = sqs.New(sess)
+	if a.sqs == nil {
+		a.sqs = sqs.New(sess)
+	}
 	a.session = sess
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go a.readLoop(&wg)
 	go a.ackLoop(&wg)
+	if a.heartbeat > 0 {
+		wg.Add(1)
+		go a.heartbeatLoop(&wg)
+	}
 	go func() {
 		wg.Wait()
 		a.closeSignal.ShutdownComplete()
@@ -207,25 +300,52 @@ ackLoop:
 	flushNacks()
 }
 
+func (a *awsSQS) heartbeatLoop(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	heartbeatTicker := time.NewTicker(a.heartbeat)
+	defer heartbeatTicker.Stop()
+
+	for {
+		select {
+		case <-heartbeatTicker.C:
+		case <-a.closeSignal.CloseAtLeisureChan():
+			return
+		}
+
+		a.trackedMut.Lock()
+		handles := make([]sqsMessageHandle, 0, len(a.tracked))
+		for _, h := range a.tracked {
+			handles = append(handles, h)
+		}
+		a.trackedMut.Unlock()
+		if len(handles) == 0 {
+			continue
+		}
+
+		ctx, done := a.closeSignal.CloseAtLeisureCtx(context.Background())
+		failed, err := a.changeVisibility(ctx, a.visibilityTimeout, handles...)
+		done()
+		if err != nil {
+			a.log.Errorf("Failed to extend the visibility timeout of messages: %v", err)
+		}
+		for _, fail := range failed {
+			// Messages that were acknowledged since the handles were collected
+			// can no longer be extended, which is expected.
+			a.log.Debugf("Failed to extend the visibility timeout of SQS message '%v', response code: %v\n", *fail.Id, *fail.Code)
+		}
+	}
+}
+
 func (a *awsSQS) readLoop(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	var pendingMsgs []*sqs.Message
 	defer func() {
 		if len(pendingMsgs) > 0 {
-			tmpNacks := make([]sqsMessageHandle, 0, len(pendingMsgs))
-			for _, m := range pendingMsgs {
-				if m.MessageId == nil || m.ReceiptHandle == nil {
-					continue
-				}
-				tmpNacks = append(tmpNacks, sqsMessageHandle{
-					id:            *m.MessageId,
-					receiptHandle: *m.ReceiptHandle,
-				})
-			}
 			ctx, done := a.closeSignal.CloseNowCtx(context.Background())
 			defer done()
-			if err := a.resetMessages(ctx, tmpNacks...); err != nil {
+			if err := a.resetMessages(ctx, sqsMessageHandles(pendingMsgs)...); err != nil {
 				a.log.Errorf("Failed to reset visibility timeout for pending messages: %v", err)
 			}
 		}
@@ -238,12 +358,16 @@ func (a *awsSQS) readLoop(wg *sync.WaitGroup) {
 	getMsgs := func() {
 		ctx, done := a.closeSignal.CloseAtLeisureCtx(context.Background())
 		defer done()
-		res, err := a.sqs.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		input := &sqs.ReceiveMessageInput{
 			QueueUrl:              aws.String(a.conf.URL),
 			MaxNumberOfMessages:   aws.Int64(10),
 			AttributeNames:        []*string{aws.String("All")},
 			MessageAttributeNames: []*string{aws.String("All")},
-		})
+		}
+		if a.visibilityTimeout > 0 {
+			input.VisibilityTimeout = aws.Int64(a.visibilityTimeout)
+		}
+		res, err := a.sqs.ReceiveMessageWithContext(ctx, input)
 		if err != nil {
 			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != request.CanceledErrorCode {
 				a.log.Errorf("Failed to pull new SQS messages: %v", aerr)
@@ -251,6 +375,13 @@ func (a *awsSQS) readLoop(wg *sync.WaitGroup) {
 			return
 		}
 		if len(res.Messages) > 0 {
+			if a.heartbeat > 0 {
+				a.trackedMut.Lock()
+				for _, h := range sqsMessageHandles(res.Messages) {
+					a.tracked[h.id] = h
+				}
+				a.trackedMut.Unlock()
+			}
 			pendingMsgs = append(pendingMsgs, res.Messages...)
 			backoff.Reset()
 		}
@@ -268,17 +399,160 @@ func (a *awsSQS) readLoop(wg *sync.WaitGroup) {
 				continue
 			}
 		}
+
+		if !a.conf.FIFOOrdering {
+			select {
+			case a.messagesChan <- pendingMsgs[0]:
+				pendingMsgs = pendingMsgs[1:]
+			case <-a.closeSignal.CloseAtLeisureChan():
+				return
+			}
+			continue
+		}
+
+		if pendingMsgs = a.releaseNackedGroups(pendingMsgs); len(pendingMsgs) == 0 {
+			continue
+		}
+
+		i := a.claimGroup(pendingMsgs)
+		if i < 0 {
+			// All pending messages belong to groups that are being processed,
+			// and therefore we wait for a group to be released.
+			select {
+			case <-a.groupsChanged:
+			case <-a.closeSignal.CloseAtLeisureChan():
+				return
+			}
+			continue
+		}
+
 		select {
-		case a.messagesChan <- pendingMsgs[0]:
-			pendingMsgs = pendingMsgs[1:]
+		case a.messagesChan <- pendingMsgs[i]:
+			pendingMsgs = append(pendingMsgs[:i], pendingMsgs[i+1:]...)
 		case <-a.closeSignal.CloseAtLeisureChan():
+			a.groupsMut.Lock()
+			delete(a.groupsInFlight, sqsMessageGroup(pendingMsgs[i]))
+			a.groupsMut.Unlock()
 			return
 		}
 	}
 }
 
+// claimGroup returns the index of the first pending message that can be
+// dispatched, which is a message either without a message group or with a
+// message group that is neither being processed nor awaiting release after a
+// rejection, and marks its group as being processed. Returns -1 if no pending message can be dispatched.
+func (a *awsSQS) claimGroup(pendingMsgs []*sqs.Message) int {
+	a.groupsMut.Lock()
+	defer a.groupsMut.Unlock()
+
+	for i, m := range pendingMsgs {
+		group := sqsMessageGroup(m)
+		if group == "" {
+			return i
+		}
+		_, inFlight := a.groupsInFlight[group]
+		_, nacked := a.groupsNacked[group]
+		if !inFlight && !nacked {
+			a.groupsInFlight[group] = struct{}{}
+			return i
+		}
+	}
+	return -1
+}
+
+// releaseNackedGroups removes pending messages that belong to message groups
+// that had a message rejected, and resets their visibility timeout so that the
+// group is redelivered in order along with the rejected message.
+func (a *awsSQS) releaseNackedGroups(pendingMsgs []*sqs.Message) []*sqs.Message {
+	a.groupsMut.Lock()
+	if len(a.groupsNacked) == 0 {
+		a.groupsMut.Unlock()
+		return pendingMsgs
+	}
+
+	var released []*sqs.Message
+	remaining := pendingMsgs[:0]
+	for _, m := range pendingMsgs {
+		if _, exists := a.groupsNacked[sqsMessageGroup(m)]; exists {
+			released = append(released, m)
+		} else {
+			remaining = append(remaining, m)
+		}
+	}
+	a.groupsNacked = map[string]struct{}{}
+	a.groupsMut.Unlock()
+
+	if len(released) > 0 {
+		handles := sqsMessageHandles(released)
+		a.untrack(handles...)
+
+		ctx, done := a.closeSignal.CloseNowCtx(context.Background())
+		defer done()
+		if err := a.resetMessages(ctx, handles...); err != nil {
+			a.log.Errorf("Failed to reset visibility timeout for pending messages of rejected message groups: %v", err)
+		}
+	}
+	return remaining
+}
+
+// release is called once a message has been acknowledged or rejected, and
+// stops its visibility timeout from being extended and allows the next message
+// of its group to be dispatched.
+func (a *awsSQS) release(h sqsMessageHandle, nacked bool) {
+	a.untrack(h)
+	if !a.conf.FIFOOrdering || h.group == "" {
+		return
+	}
+
+	a.groupsMut.Lock()
+	delete(a.groupsInFlight, h.group)
+	if nacked {
+		a.groupsNacked[h.group] = struct{}{}
+	}
+	a.groupsMut.Unlock()
+
+	select {
+	case a.groupsChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (a *awsSQS) untrack(handles ...sqsMessageHandle) {
+	if a.heartbeat <= 0 {
+		return
+	}
+	a.trackedMut.Lock()
+	for _, h := range handles {
+		delete(a.tracked, h.id)
+	}
+	a.trackedMut.Unlock()
+}
+
 type sqsMessageHandle struct {
-	id, receiptHandle string
+	id, receiptHandle, group string
+}
+
+func sqsMessageGroup(m *sqs.Message) string {
+	if group := m.Attributes["MessageGroupId"]; group != nil {
+		return *group
+	}
+	return ""
+}
+
+func sqsMessageHandles(msgs []*sqs.Message) []sqsMessageHandle {
+	handles := make([]sqsMessageHandle, 0, len(msgs))
+	for _, m := range msgs {
+		if m.MessageId == nil || m.ReceiptHandle == nil {
+			continue
+		}
+		handles = append(handles, sqsMessageHandle{
+			id:            *m.MessageId,
+			receiptHandle: *m.ReceiptHandle,
+			group:         sqsMessageGroup(m),
+		})
+	}
+	return handles
 }
 
 func (a *awsSQS) deleteMessages(ctx context.Context, msgs ...sqsMessageHandle) error {
@@ -311,6 +585,15 @@ func (a *awsSQS) deleteMessages(ctx context.Context, msgs ...sqsMessageHandle) e
 }
 
 func (a *awsSQS) resetMessages(ctx context.Context, msgs ...sqsMessageHandle) error {
+	failed, err := a.changeVisibility(ctx, 0, msgs...)
+	for _, fail := range failed {
+		a.log.Errorf("Failed to delete consumed SQS message '%v', response code: %v\n", *fail.Id, *fail.Code)
+	}
+	return err
+}
+
+func (a *awsSQS) changeVisibility(ctx context.Context, timeout int64, msgs ...sqsMessageHandle) ([]*sqs.BatchResultErrorEntry, error) {
+	var failed []*sqs.BatchResultErrorEntry
 	for len(msgs) > 0 {
 		input := sqs.ChangeMessageVisibilityBatchInput{
 			QueueUrl: aws.String(a.conf.URL),
@@ -321,7 +604,7 @@ func (a *awsSQS) resetMessages(ctx context.Context, msgs ...sqsMessageHandle) er
 			input.Entries = append(input.Entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
 				Id:                aws.String(msg.id),
 				ReceiptHandle:     aws.String(msg.receiptHandle),
-				VisibilityTimeout: aws.Int64(0),
+				VisibilityTimeout: aws.Int64(timeout),
 			})
 			if len(input.Entries) == 10 {
 				break
@@ -331,22 +614,34 @@ func (a *awsSQS) resetMessages(ctx context.Context, msgs ...sqsMessageHandle) er
 		msgs = msgs[len(input.Entries):]
 		response, err := a.sqs.ChangeMessageVisibilityBatchWithContext(ctx, &input)
 		if err != nil {
-			return err
-		}
-		for _, fail := range response.Failed {
-			a.log.Errorf("Failed to delete consumed SQS message '%v', response code: %v\n", *fail.Id, *fail.Code)
+			return failed, err
 		}
+		failed = append(failed, response.Failed...)
 	}
-	return nil
+	return failed, nil
 }
 
-func addSQSMetadata(p types.Part, sqsMsg *sqs.Message) {
+func addSQSMetadata(p types.Part, sqsMsg *sqs.Message, redrive bool) {
 	meta := p.Metadata()
 	meta.Set("sqs_message_id", *sqsMsg.MessageId)
 	meta.Set("sqs_receipt_handle", *sqsMsg.ReceiptHandle)
 	if rCountStr := sqsMsg.Attributes["ApproximateReceiveCount"]; rCountStr != nil {
 		meta.Set("sqs_approximate_receive_count", *rCountStr)
 	}
+	if group := sqsMessageGroup(sqsMsg); group != "" {
+		meta.Set("sqs_message_group_id", group)
+	}
+	if redrive {
+		for k, attr := range map[string]string{
+			"sqs_dead_letter_queue_source_arn":        "DeadLetterQueueSourceArn",
+			"sqs_sent_timestamp":                      "SentTimestamp",
+			"sqs_approximate_first_receive_timestamp": "ApproximateFirstReceiveTimestamp",
+		} {
+			if v := sqsMsg.Attributes[attr]; v != nil {
+				meta.Set(k, *v)
+			}
+		}
+	}
 	for k, v := range sqsMsg.MessageAttributes {
 		if v.StringValue != nil {
 			meta.Set(k, *v.StringValue)
@@ -376,20 +671,23 @@ func (a *awsSQS) ReadWithContext(ctx context.Context) (types.Message, reader.Asy
 	msg := message.New(nil)
 	if next.Body != nil {
 		part := message.NewPart([]byte(*next.Body))
-		addSQSMetadata(part, next)
+		addSQSMetadata(part, next, a.conf.RedriveMetadata)
 		msg.Append(part)
 	}
-	if msg.Len() == 0 {
-		return nil, nil, types.ErrTimeout
-	}
 
 	mHandle := sqsMessageHandle{
-		id: *next.MessageId,
+		id:    *next.MessageId,
+		group: sqsMessageGroup(next),
 	}
 	if next.ReceiptHandle != nil {
 		mHandle.receiptHandle = *next.ReceiptHandle
 	}
+	if msg.Len() == 0 {
+		a.release(mHandle, false)
+		return nil, nil, types.ErrTimeout
+	}
 	return msg, func(rctx context.Context, res types.Response) error {
+		a.release(mHandle, res.Error() != nil)
 		if mHandle.receiptHandle == "" {
 			return nil
 		}
@@ -0,0 +1,278 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSQSInput struct {
+	sqsiface.SQSAPI
+
+	mut           sync.Mutex
+	batches       [][]*sqs.Message
+	receiveInputs []*sqs.ReceiveMessageInput
+	visibility    map[string][]int64
+	deleted       []string
+}
+
+func (m *mockSQSInput) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.receiveInputs = append(m.receiveInputs, input)
+	if len(m.batches) == 0 {
+		return &sqs.ReceiveMessageOutput{}, nil
+	}
+	msgs := m.batches[0]
+	m.batches = m.batches[1:]
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (m *mockSQSInput) ChangeMessageVisibilityBatchWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityBatchInput, _ ...request.Option) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for _, e := range input.Entries {
+		m.visibility[*e.Id] = append(m.visibility[*e.Id], *e.VisibilityTimeout)
+	}
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func (m *mockSQSInput) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, _ ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for _, e := range input.Entries {
+		m.deleted = append(m.deleted, *e.Id)
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (m *mockSQSInput) visibilityOf(id string) []int64 {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.visibility[id]
+}
+
+func sqsTestMessage(id, group string) *sqs.Message {
+	m := &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String("handle_" + id),
+		Body:          aws.String(id),
+		Attributes:    map[string]*string{},
+	}
+	if group != "" {
+		m.Attributes["MessageGroupId"] = aws.String(group)
+	}
+	return m
+}
+
+func sqsTestInput(t *testing.T, conf AWSSQSConfig, batches ...[]*sqs.Message) (*awsSQS, *mockSQSInput) {
+	t.Helper()
+
+	conf.URL = "http://localhost:4566/000000000000/queue.fifo"
+	conf.Region = "us-east-1"
+	conf.Credentials.ID = "xxxxx"
+	conf.Credentials.Secret = "xxxxx"
+
+	a, err := newAWSSQS(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mock := &mockSQSInput{
+		batches:    batches,
+		visibility: map[string][]int64{},
+	}
+	a.sqs = mock
+
+	require.NoError(t, a.ConnectWithContext(context.Background()))
+	t.Cleanup(func() {
+		a.CloseAsync()
+		assert.NoError(t, a.WaitForClose(time.Second*5))
+	})
+	return a, mock
+}
+
+func sqsTestRead(t *testing.T, a *awsSQS) (string, func(error)) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := a.ReadWithContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.Len())
+
+	return string(msg.Get(0).Get()), func(err error) {
+		if err == nil {
+			require.NoError(t, ackFn(context.Background(), response.NewAck()))
+		} else {
+			require.NoError(t, ackFn(context.Background(), response.NewError(err)))
+		}
+	}
+}
+
+func sqsTestReadNone(t *testing.T, a *awsSQS) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+
+	_, _, err := a.ReadWithContext(ctx)
+	require.Error(t, err)
+}
+
+func TestAWSSQSFIFOOrdering(t *testing.T) {
+	conf := NewAWSSQSConfig()
+	conf.FIFOOrdering = true
+
+	a, mock := sqsTestInput(t, conf, []*sqs.Message{
+		sqsTestMessage("a1", "a"),
+		sqsTestMessage("a2", "a"),
+		sqsTestMessage("b1", "b"),
+	})
+
+	id, ackA1 := sqsTestRead(t, a)
+	assert.Equal(t, "a1", id)
+
+	id, ackB1 := sqsTestRead(t, a)
+	assert.Equal(t, "b1", id)
+
+	// The next message of group a must not be dispatched until a1 is acked.
+	sqsTestReadNone(t, a)
+
+	ackA1(nil)
+	id, ackA2 := sqsTestRead(t, a)
+	assert.Equal(t, "a2", id)
+
+	ackA2(nil)
+	ackB1(nil)
+
+	assert.Eventually(t, func() bool {
+		mock.mut.Lock()
+		defer mock.mut.Unlock()
+		return len(mock.deleted) == 3
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestAWSSQSFIFOOrderingNack(t *testing.T) {
+	conf := NewAWSSQSConfig()
+	conf.FIFOOrdering = true
+
+	a, mock := sqsTestInput(t, conf, []*sqs.Message{
+		sqsTestMessage("a1", "a"),
+		sqsTestMessage("a2", "a"),
+		sqsTestMessage("a3", "a"),
+		sqsTestMessage("b1", "b"),
+	})
+
+	id, nackA1 := sqsTestRead(t, a)
+	assert.Equal(t, "a1", id)
+
+	nackA1(errors.New("nope"))
+
+	// The remaining messages of group a are released back to the queue rather
+	// than being processed ahead of a1.
+	id, ackB1 := sqsTestRead(t, a)
+	assert.Equal(t, "b1", id)
+	ackB1(nil)
+
+	sqsTestReadNone(t, a)
+
+	assert.Eventually(t, func() bool {
+		return len(mock.visibilityOf("a1")) == 1 &&
+			len(mock.visibilityOf("a2")) == 1 &&
+			len(mock.visibilityOf("a3")) == 1
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, []int64{0}, mock.visibilityOf("a2"))
+	assert.Empty(t, mock.visibilityOf("b1"))
+}
+
+func TestAWSSQSVisibilityHeartbeat(t *testing.T) {
+	conf := NewAWSSQSConfig()
+	conf.VisibilityTimeout = "10s"
+	conf.VisibilityHeartbeat = "10ms"
+
+	a, mock := sqsTestInput(t, conf, []*sqs.Message{
+		sqsTestMessage("foo", ""),
+	})
+
+	id, ackFoo := sqsTestRead(t, a)
+	assert.Equal(t, "foo", id)
+
+	assert.Eventually(t, func() bool {
+		return len(mock.visibilityOf("foo")) >= 2
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, int64(10), mock.visibilityOf("foo")[0])
+
+	ackFoo(nil)
+	assert.Eventually(t, func() bool {
+		mock.mut.Lock()
+		defer mock.mut.Unlock()
+		return len(mock.deleted) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	// Once acked the visibility timeout is no longer extended.
+	extensions := len(mock.visibilityOf("foo"))
+	<-time.After(time.Millisecond * 50)
+	assert.Equal(t, extensions, len(mock.visibilityOf("foo")))
+
+	mock.mut.Lock()
+	assert.Equal(t, int64(10), *mock.receiveInputs[0].VisibilityTimeout)
+	mock.mut.Unlock()
+}
+
+func TestAWSSQSRedriveMetadata(t *testing.T) {
+	conf := NewAWSSQSConfig()
+	conf.RedriveMetadata = true
+
+	m := sqsTestMessage("foo", "bar")
+	m.Attributes["DeadLetterQueueSourceArn"] = aws.String("arn:aws:sqs:us-east-1:000000000000:source.fifo")
+	m.Attributes["SentTimestamp"] = aws.String("1000")
+
+	a, _ := sqsTestInput(t, conf, []*sqs.Message{m})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := a.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	meta := msg.Get(0).Metadata()
+	assert.Equal(t, "bar", meta.Get("sqs_message_group_id"))
+	assert.Equal(t, "arn:aws:sqs:us-east-1:000000000000:source.fifo", meta.Get("sqs_dead_letter_queue_source_arn"))
+	assert.Equal(t, "1000", meta.Get("sqs_sent_timestamp"))
+	assert.Equal(t, "", meta.Get("sqs_approximate_first_receive_timestamp"))
+
+	require.NoError(t, ackFn(context.Background(), response.NewAck()))
+}
+
+func TestAWSSQSVisibilityConfigErrors(t *testing.T) {
+	conf := NewAWSSQSConfig()
+	conf.VisibilityHeartbeat = "10s"
+
+	_, err := newAWSSQS(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.VisibilityTimeout = "10s"
+
+	_, err = newAWSSQS(conf, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.VisibilityHeartbeat = "5s"
+
+	_, err = newAWSSQS(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+}
@@ -247,6 +247,7 @@ const (
 	TypeSocket            = "socket"
 	TypeSocketServer      = "socket_server"
 	TypeSQS               = "sqs"
+	TypeSSE               = "sse"
 	TypeSTDIN             = "stdin"
 	TypeSubprocess        = "subprocess"
 	TypeTCP               = "tcp"
@@ -308,6 +309,7 @@ type Config struct {
 	Socket            SocketConfig                 `json:"socket" yaml:"socket"`
 	SocketServer      SocketServerConfig           `json:"socket_server" yaml:"socket_server"`
 	SQS               reader.AmazonSQSConfig       `json:"sqs" yaml:"sqs"`
+	SSE               SSEConfig                    `json:"sse" yaml:"sse"`
 	STDIN             STDINConfig                  `json:"stdin" yaml:"stdin"`
 	Subprocess        SubprocessConfig             `json:"subprocess" yaml:"subprocess"`
 	TCP               TCPConfig                    `json:"tcp" yaml:"tcp"`
@@ -369,6 +371,7 @@ func NewConfig() Config {
 		Socket:            NewSocketConfig(),
 		SocketServer:      NewSocketServerConfig(),
 		SQS:               reader.NewAmazonSQSConfig(),
+		SSE:               NewSSEConfig(),
 		STDIN:             NewSTDINConfig(),
 		Subprocess:        NewSubprocessConfig(),
 		TCP:               NewTCPConfig(),
@@ -34,8 +34,19 @@ Consumes data from files on disk, emitting messages according to a chosen codec.
 			docs.FieldDeprecated("delimiter"),
 			docs.FieldDeprecated("multipart"),
 			docs.FieldAdvanced("delete_on_finish", "Whether to delete consumed files from the disk once they are fully consumed."),
+			docs.FieldCommon("tail", "Follow files as they are written to rather than consuming them once, where the target paths are expanded periodically in order to discover new files. This mode only supports the `lines` codec.").WithChildren(
+				docs.FieldCommon("enabled", "Whether tail mode is enabled."),
+				docs.FieldCommon("poll_interval", "The interval between each check of the target paths for new, rotated and truncated files, and of followed files for new lines.", "100ms", "1s"),
+				docs.FieldCommon("cache", "A [cache resource](/docs/components/caches/about) for storing the offsets of consumed lines within each file."),
+			).AtVersion("3.64.0"),
 		},
 		Description: `
+### Tailing Files
+
+When the field ` + "`tail.enabled`" + ` is set the input follows files live, consuming each line as it is written, and the target paths are expanded according to ` + "`tail.poll_interval`" + ` so that new files matching a glob pattern are also followed. Files are identified by their inode rather than their path, and therefore a file that is rotated by being renamed continues to be read to the end before the new file at its path is followed. A file that shrinks is assumed to have been truncated and is read again from the beginning.
+
+The offset of each file up to which all lines have been sent onwards is stored within the [cache resource](/docs/components/caches/about) specified with ` + "`tail.cache`" + `, and therefore a persisted cache allows consumption to resume where it left off after a restart.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -58,6 +69,23 @@ input:
   file:
     paths: [ ./data/*.csv ]
     codec: csv
+`,
+			},
+			{
+				Title:   "Tail Log Files",
+				Summary: "In order to follow a directory of log files as they are written to, including files created after Benthos has started, we can enable tail mode with a file cache for persisting the offsets reached within each file:",
+				Config: `
+input:
+  file:
+    paths: [ /var/log/app/*.log ]
+    tail:
+      enabled: true
+      cache: offsets
+
+cache_resources:
+  - label: offsets
+    file:
+      directory: /var/lib/benthos/offsets
 `,
 			},
 		},
@@ -68,13 +96,14 @@ input:
 
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
-	Path           string   `json:"path" yaml:"path"`
-	Paths          []string `json:"paths" yaml:"paths"`
-	Codec          string   `json:"codec" yaml:"codec"`
-	Multipart      bool     `json:"multipart" yaml:"multipart"`
-	MaxBuffer      int      `json:"max_buffer" yaml:"max_buffer"`
-	Delim          string   `json:"delimiter" yaml:"delimiter"`
-	DeleteOnFinish bool     `json:"delete_on_finish" yaml:"delete_on_finish"`
+	Path           string         `json:"path" yaml:"path"`
+	Paths          []string       `json:"paths" yaml:"paths"`
+	Codec          string         `json:"codec" yaml:"codec"`
+	Multipart      bool           `json:"multipart" yaml:"multipart"`
+	MaxBuffer      int            `json:"max_buffer" yaml:"max_buffer"`
+	Delim          string         `json:"delimiter" yaml:"delimiter"`
+	DeleteOnFinish bool           `json:"delete_on_finish" yaml:"delete_on_finish"`
+	Tail           FileTailConfig `json:"tail" yaml:"tail"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
@@ -88,6 +117,7 @@ func NewFileConfig() FileConfig {
 		MaxBuffer:      1000000,
 		Delim:          "",
 		DeleteOnFinish: false,
+		Tail:           NewFileTailConfig(),
 	}
 }
 
@@ -104,6 +134,13 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if conf.File.Multipart && !strings.HasSuffix(conf.File.Codec, "/multipart") {
 		conf.File.Codec += "/multipart"
 	}
+	if conf.File.Tail.Enabled {
+		rdr, err := newFileTailer(conf.File, mgr, log)
+		if err != nil {
+			return nil, err
+		}
+		return NewAsyncReader(TypeFile, true, reader.NewAsyncPreserver(rdr), log, stats)
+	}
 	rdr, err := newFileConsumer(conf.File, log)
 	if err != nil {
 		return nil, err
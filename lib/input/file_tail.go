@@ -0,0 +1,411 @@
+package input
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/filepath"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// FileTailConfig contains configuration for following files as they are
+// written to.
+type FileTailConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	PollInterval string `json:"poll_interval" yaml:"poll_interval"`
+	Cache        string `json:"cache" yaml:"cache"`
+}
+
+// NewFileTailConfig creates a new FileTailConfig with default values.
+func NewFileTailConfig() FileTailConfig {
+	return FileTailConfig{
+		Enabled:      false,
+		PollInterval: "1s",
+		Cache:        "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// tailedLine is a line that has been read from a file and not yet committed.
+type tailedLine struct {
+	end  int64
+	done bool
+}
+
+// tailedFile is a file that is being followed, which is identified by its
+// inode rather than its path so that it can be followed across renames.
+type tailedFile struct {
+	path string
+	id   string
+	file *os.File
+	info os.FileInfo
+
+	reader  *bufio.Reader
+	offset  int64
+	partial []byte
+
+	// Set when the path of the file no longer matches, in which case it is
+	// closed once it has been read to the end.
+	removed bool
+
+	// Lines in the order they were read, where the committed offset only
+	// advances past lines that have been acknowledged.
+	pending []*tailedLine
+}
+
+// fileTailer follows files as they are written to, periodically expanding the
+// target paths in order to discover new files and detect rotations and
+// truncations. The offsets of consumed lines are committed to a cache once they
+// have been acknowledged.
+type fileTailer struct {
+	conf      FileConfig
+	mgr       types.Manager
+	log       log.Modular
+	pollInter time.Duration
+	maxBuffer int
+
+	mut      sync.Mutex
+	files    []*tailedFile
+	nextFile int
+	nextPoll time.Time
+	closed   bool
+}
+
+func newFileTailer(conf FileConfig, mgr types.Manager, log log.Modular) (*fileTailer, error) {
+	if conf.Codec != "lines" {
+		return nil, errors.New("tail mode only supports the lines codec")
+	}
+	if conf.DeleteOnFinish {
+		return nil, errors.New("delete_on_finish cannot be used with tail mode")
+	}
+
+	pollInterval, err := time.ParseDuration(conf.Tail.PollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tail poll interval: %w", err)
+	}
+
+	if conf.Tail.Cache == "" {
+		return nil, errors.New("a cache must be specified when tail mode is enabled")
+	}
+	if err := interop.ProbeCache(context.Background(), mgr, conf.Tail.Cache); err != nil {
+		return nil, err
+	}
+
+	maxBuffer := conf.MaxBuffer
+	if maxBuffer < 16 {
+		maxBuffer = 16
+	}
+
+	return &fileTailer{
+		conf:      conf,
+		mgr:       mgr,
+		log:       log,
+		pollInter: pollInterval,
+		maxBuffer: maxBuffer,
+	}, nil
+}
+
+func fileTailCacheKey(id string) string {
+	return "benthos_file_tail:" + id
+}
+
+// ConnectWithContext discovers the files to follow.
+func (f *fileTailer) ConnectWithContext(ctx context.Context) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.closed {
+		return types.ErrTypeClosed
+	}
+	if f.nextPoll.IsZero() {
+		f.poll(ctx)
+	}
+	return nil
+}
+
+// openFile opens a newly discovered file, seeking to the offset that was last
+// committed for it.
+func (f *fileTailer) openFile(ctx context.Context, path string) (*tailedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil
+	}
+
+	tf := &tailedFile{
+		path: path,
+		id:   fileIdentity(path, info),
+		file: file,
+		info: info,
+	}
+
+	var offset int64
+	if cerr := interop.AccessCache(ctx, f.mgr, f.conf.Tail.Cache, func(cache types.Cache) {
+		if b, err := cache.Get(fileTailCacheKey(tf.id)); err == nil {
+			offset, _ = strconv.ParseInt(string(b), 10, 64)
+		}
+	}); cerr != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get the cache for file tail mode: %v", cerr)
+	}
+
+	// An offset beyond the end of the file means that either the file was
+	// truncated whilst we weren't watching, or that the inode has been reused
+	// by a different file.
+	if offset > info.Size() {
+		offset = 0
+	}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	tf.offset = offset
+	tf.reader = bufio.NewReaderSize(file, f.maxBuffer)
+
+	f.log.Infof("Tailing file '%v' from offset %v\n", path, offset)
+	return tf, nil
+}
+
+// poll expands the target paths, opening files that are new, following files
+// that have been renamed and flagging files that no longer match.
+func (f *fileTailer) poll(ctx context.Context) {
+	f.nextPoll = time.Now().Add(f.pollInter)
+
+	paths, err := filepath.Globs(f.conf.Paths)
+	if err != nil {
+		f.log.Errorf("Failed to expand tail paths: %v\n", err)
+		return
+	}
+
+	seen := make(map[*tailedFile]struct{}, len(f.files))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				f.log.Warnf("Failed to stat file '%v': %v\n", path, err)
+			}
+			continue
+		}
+
+		var tracked *tailedFile
+		for _, tf := range f.files {
+			if os.SameFile(tf.info, info) {
+				tracked = tf
+				break
+			}
+		}
+		if tracked != nil {
+			if tracked.path != path {
+				f.log.Debugf("File '%v' was renamed to '%v'\n", tracked.path, path)
+				tracked.path = path
+			}
+			tracked.removed = false
+			seen[tracked] = struct{}{}
+			continue
+		}
+
+		tf, err := f.openFile(ctx, path)
+		if err != nil {
+			f.log.Errorf("Failed to open file '%v': %v\n", path, err)
+			continue
+		}
+		if tf != nil {
+			f.files = append(f.files, tf)
+			seen[tf] = struct{}{}
+		}
+	}
+
+	for _, tf := range f.files {
+		if _, exists := seen[tf]; !exists {
+			tf.removed = true
+			continue
+		}
+
+		info, err := tf.file.Stat()
+		if err != nil {
+			continue
+		}
+		if info.Size() < tf.offset {
+			f.log.Infof("File '%v' was truncated, reading from the beginning\n", tf.path)
+			if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+				f.log.Errorf("Failed to seek truncated file '%v': %v\n", tf.path, err)
+				continue
+			}
+			tf.reader.Reset(tf.file)
+			tf.offset = 0
+			tf.partial = nil
+			tf.pending = nil
+		}
+	}
+}
+
+// nextLine reads the next complete line from any of the followed files,
+// returning nil if none of the files have a complete line available. Files
+// that no longer match the target paths are closed once they're read to the
+// end.
+func (f *fileTailer) nextLine() (*tailedFile, []byte, *tailedLine, error) {
+	for attempts := len(f.files); attempts > 0; attempts-- {
+		if f.nextFile >= len(f.files) {
+			f.nextFile = 0
+		}
+		tf := f.files[f.nextFile]
+
+		for {
+			chunk, err := tf.reader.ReadSlice('\n')
+			tf.offset += int64(len(chunk))
+
+			if err == nil || errors.Is(err, bufio.ErrBufferFull) || len(tf.partial)+len(chunk) >= f.maxBuffer {
+				line := append(tf.partial, chunk...)
+				tf.partial = nil
+				if err == nil {
+					line = line[:len(line)-1]
+					if len(line) > 0 && line[len(line)-1] == '\r' {
+						line = line[:len(line)-1]
+					}
+				}
+
+				if len(line) == 0 {
+					// Empty lines are skipped, but when lines are in flight
+					// the committed offset must not advance past them.
+					if len(tf.pending) > 0 {
+						tf.pending = append(tf.pending, &tailedLine{end: tf.offset, done: true})
+					}
+					continue
+				}
+
+				pending := &tailedLine{end: tf.offset}
+				tf.pending = append(tf.pending, pending)
+
+				lineCopy := make([]byte, len(line))
+				copy(lineCopy, line)
+				f.nextFile++
+				return tf, lineCopy, pending, nil
+			}
+
+			tf.partial = append(tf.partial, chunk...)
+			if !errors.Is(err, io.EOF) {
+				return nil, nil, nil, err
+			}
+			break
+		}
+
+		if tf.removed {
+			f.log.Infof("Finished tailing file '%v'\n", tf.path)
+			tf.file.Close()
+			f.files = append(f.files[:f.nextFile], f.files[f.nextFile+1:]...)
+			continue
+		}
+		f.nextFile++
+	}
+	return nil, nil, nil, nil
+}
+
+// commit marks a line as acknowledged and stores the offset up to which all
+// lines of its file have been acknowledged.
+func (f *fileTailer) commit(ctx context.Context, tf *tailedFile, line *tailedLine) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	line.done = true
+
+	var offset int64 = -1
+	for len(tf.pending) > 0 && tf.pending[0].done {
+		offset = tf.pending[0].end
+		tf.pending = tf.pending[1:]
+	}
+	if offset < 0 {
+		return nil
+	}
+
+	var setErr error
+	if cerr := interop.AccessCache(ctx, f.mgr, f.conf.Tail.Cache, func(cache types.Cache) {
+		setErr = cache.Set(fileTailCacheKey(tf.id), []byte(strconv.FormatInt(offset, 10)))
+	}); cerr != nil {
+		return fmt.Errorf("failed to get the cache for file tail mode: %v", cerr)
+	}
+	if setErr != nil {
+		return fmt.Errorf("failed to store offset of file '%v': %v", tf.path, setErr)
+	}
+	return nil
+}
+
+// ReadWithContext attempts to read a new line from the followed files.
+func (f *fileTailer) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	for {
+		f.mut.Lock()
+		if f.closed {
+			f.mut.Unlock()
+			return nil, nil, types.ErrTypeClosed
+		}
+		if !time.Now().Before(f.nextPoll) {
+			f.poll(ctx)
+		}
+		tf, line, pending, err := f.nextLine()
+		var path string
+		if tf != nil {
+			path = tf.path
+		}
+		nextPoll := f.nextPoll
+		f.mut.Unlock()
+
+		if err != nil {
+			return nil, nil, err
+		}
+		if line != nil {
+			msg := message.New([][]byte{line})
+			msg.Get(0).Metadata().Set("path", path)
+			return msg, func(rctx context.Context, res types.Response) error {
+				if res.Error() != nil {
+					return nil
+				}
+				return f.commit(rctx, tf, pending)
+			}, nil
+		}
+
+		select {
+		case <-time.After(time.Until(nextPoll)):
+		case <-ctx.Done():
+			return nil, nil, types.ErrTimeout
+		}
+	}
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (f *fileTailer) CloseAsync() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.closed = true
+	for _, tf := range f.files {
+		tf.file.Close()
+	}
+	f.files = nil
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (f *fileTailer) WaitForClose(time.Duration) error {
+	return nil
+}
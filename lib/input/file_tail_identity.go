@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package input
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file, which remain the same
+// when the file is renamed.
+func fileIdentity(path string, info os.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return path
+	}
+	return fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+}
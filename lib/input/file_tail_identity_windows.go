@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package input
+
+import (
+	"os"
+)
+
+// fileIdentity returns the path of a file, as inodes are not available on
+// Windows and therefore offsets are not retained across renames.
+func fileIdentity(path string, info os.FileInfo) string {
+	return path
+}
@@ -0,0 +1,252 @@
+package input
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTailMgr struct {
+	types.DudMgr
+	caches map[string]types.Cache
+}
+
+func (f *fakeTailMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+func newFakeTailMgr(t *testing.T) *fakeTailMgr {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return &fakeTailMgr{caches: map[string]types.Cache{"offsets": memCache}}
+}
+
+func testFileTailer(t *testing.T, mgr types.Manager, paths ...string) *fileTailer {
+	t.Helper()
+
+	conf := NewFileConfig()
+	conf.Paths = paths
+	conf.Tail.Enabled = true
+	conf.Tail.PollInterval = "10ms"
+	conf.Tail.Cache = "offsets"
+
+	f, err := newFileTailer(conf, mgr, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, f.ConnectWithContext(context.Background()))
+	t.Cleanup(f.CloseAsync)
+	return f
+}
+
+func readTailedLines(t *testing.T, f *fileTailer, n int) []string {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	var lines []string
+	for len(lines) < n {
+		msg, ackFn, err := f.ReadWithContext(ctx)
+		require.NoError(t, err)
+		lines = append(lines, string(msg.Get(0).Get())+"@"+filepath.Base(msg.Get(0).Metadata().Get("path")))
+		require.NoError(t, ackFn(ctx, response.NewAck()))
+	}
+	return lines
+}
+
+func assertNoTailedLines(t *testing.T, f *fileTailer) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	msg, _, err := f.ReadWithContext(ctx)
+	assert.Equal(t, types.ErrTimeout, err, "%v", msg)
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func TestFileTailFollow(t *testing.T) {
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.log")
+	appendFile(t, fooPath, "first\nsecond\n\nthi")
+
+	f := testFileTailer(t, newFakeTailMgr(t), filepath.Join(dir, "*.log"))
+
+	assert.Equal(t, []string{"first@foo.log", "second@foo.log"}, readTailedLines(t, f, 2))
+	assertNoTailedLines(t, f)
+
+	appendFile(t, fooPath, "rd\r\n")
+	appendFile(t, filepath.Join(dir, "bar.log"), "new file\n")
+	appendFile(t, filepath.Join(dir, "bar.txt"), "not matched\n")
+
+	lines := readTailedLines(t, f, 2)
+	assert.ElementsMatch(t, []string{"third@foo.log", "new file@bar.log"}, lines)
+	assertNoTailedLines(t, f)
+}
+
+func TestFileTailRotation(t *testing.T) {
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.log")
+	appendFile(t, fooPath, "first\n")
+
+	f := testFileTailer(t, newFakeTailMgr(t), fooPath)
+	assert.Equal(t, []string{"first@foo.log"}, readTailedLines(t, f, 1))
+
+	// Lines written to the rotated file before the rotation is noticed must
+	// still be consumed, followed by the lines of the new file.
+	rotatedPath := filepath.Join(dir, "foo.log.1")
+	require.NoError(t, os.Rename(fooPath, rotatedPath))
+	appendFile(t, rotatedPath, "second\n")
+	appendFile(t, fooPath, "third\n")
+
+	lines := readTailedLines(t, f, 2)
+	assert.ElementsMatch(t, []string{"second@foo.log", "third@foo.log"}, lines)
+	assertNoTailedLines(t, f)
+
+	appendFile(t, fooPath, "fourth\n")
+	assert.Equal(t, []string{"fourth@foo.log"}, readTailedLines(t, f, 1))
+}
+
+func TestFileTailTruncation(t *testing.T) {
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.log")
+	appendFile(t, fooPath, "first line\nsecond line\n")
+
+	f := testFileTailer(t, newFakeTailMgr(t), fooPath)
+	assert.Equal(t, []string{"first line@foo.log", "second line@foo.log"}, readTailedLines(t, f, 2))
+
+	require.NoError(t, os.Truncate(fooPath, 0))
+	appendFile(t, fooPath, "third\n")
+
+	assert.Equal(t, []string{"third@foo.log"}, readTailedLines(t, f, 1))
+	assertNoTailedLines(t, f)
+}
+
+func TestFileTailResume(t *testing.T) {
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.log")
+	appendFile(t, fooPath, "first\nsecond\n")
+
+	mgr := newFakeTailMgr(t)
+
+	f := testFileTailer(t, mgr, fooPath)
+	assert.Equal(t, []string{"first@foo.log", "second@foo.log"}, readTailedLines(t, f, 2))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	appendFile(t, fooPath, "third\n")
+	msg, _, err := f.ReadWithContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "third", string(msg.Get(0).Get()))
+	f.CloseAsync()
+
+	// The unacknowledged line is consumed again by a new tailer.
+	appendFile(t, fooPath, "fourth\n")
+	f = testFileTailer(t, mgr, fooPath)
+	assert.Equal(t, []string{"third@foo.log", "fourth@foo.log"}, readTailedLines(t, f, 2))
+}
+
+func TestFileTailOutOfOrderAcks(t *testing.T) {
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.log")
+	appendFile(t, fooPath, "first\nsecond\n")
+
+	mgr := newFakeTailMgr(t)
+	f := testFileTailer(t, mgr, fooPath)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, firstAckFn, err := f.ReadWithContext(ctx)
+	require.NoError(t, err)
+	_, secondAckFn, err := f.ReadWithContext(ctx)
+	require.NoError(t, err)
+
+	info, err := os.Stat(fooPath)
+	require.NoError(t, err)
+	key := fileTailCacheKey(fileIdentity(fooPath, info))
+
+	require.NoError(t, secondAckFn(ctx, response.NewAck()))
+	_, err = mgr.caches["offsets"].Get(key)
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, firstAckFn(ctx, response.NewAck()))
+	offset, err := mgr.caches["offsets"].Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "13", string(offset))
+}
+
+func TestFileTailBadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(conf *FileConfig)
+		err    string
+	}{
+		{
+			name: "codec",
+			mutate: func(conf *FileConfig) {
+				conf.Codec = "all-bytes"
+			},
+			err: "tail mode only supports the lines codec",
+		},
+		{
+			name: "delete on finish",
+			mutate: func(conf *FileConfig) {
+				conf.DeleteOnFinish = true
+			},
+			err: "delete_on_finish cannot be used with tail mode",
+		},
+		{
+			name: "no cache",
+			mutate: func(conf *FileConfig) {
+				conf.Tail.Cache = ""
+			},
+			err: "a cache must be specified when tail mode is enabled",
+		},
+		{
+			name: "missing cache",
+			mutate: func(conf *FileConfig) {
+				conf.Tail.Cache = "nope"
+			},
+			err: "cache resource 'nope' was not found",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewFileConfig()
+			conf.Paths = []string{"./foo.log"}
+			conf.Tail.Enabled = true
+			conf.Tail.Cache = "offsets"
+			test.mutate(&conf)
+
+			_, err := newFileTailer(conf, newFakeTailMgr(t), log.Noop())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 )
 
 //------------------------------------------------------------------------------
@@ -35,7 +36,7 @@ You can access these metadata fields using
 			CategoryServices,
 			CategoryGCP,
 		},
-		FieldSpecs: docs.FieldSpecs{
+		FieldSpecs: append(docs.FieldSpecs{
 			docs.FieldCommon("project", "The project ID of the target subscription."),
 			docs.FieldCommon("subscription", "The target subscription ID."),
 			docs.FieldCommon("sync", "Enable synchronous pull mode."),
@@ -47,7 +48,7 @@ You can access these metadata fields using
 				return b
 			}(),
 			docs.FieldDeprecated("max_batch_count"),
-		},
+		}, gcpcreds.FieldSpecs()...),
 	}
 }
 
@@ -40,6 +40,7 @@ func httpClientSpec() docs.FieldSpec {
 		docs.FieldCommon(
 			"stream", "Allows you to set streaming mode, where requests are kept open and messages are processed line-by-line.",
 		).WithChildren(streamSpecs...),
+		client.PaginationFieldSpec(),
 	)
 }
 
@@ -58,7 +59,11 @@ If you enable streaming then Benthos will consume the body of the response as a
 
 ### Pagination
 
-This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination. However, in cases where pagination depends on logic it is recommended that you use an ` + "[`http` processor](/docs/components/processors/http) instead, often combined with a [`generate` input](/docs/components/inputs/generate)" + ` in order to schedule the processor.`,
+This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination.
+
+Alternatively, the field ` + "`pagination.next_url`" + ` can be set to a [Bloblang mapping](/docs/guides/bloblang/about) that obtains the URL of the next page from each page, which allows following cursors within response bodies, ` + "`Link`" + ` headers, or incrementing offsets. Each page is consumed as a message, or when ` + "`pagination.items`" + ` is set each page is consumed as a batch of its items. Once pagination is exhausted the next request is made to the first page at ` + "`url`" + ` again, and therefore a ` + "`rate_limit`" + ` should be used in order to control how often the pages are polled.
+
+Each page has the metadata field ` + "`http_page`" + ` set to the index of the page starting from zero, and when the response has a ` + "`Link`" + ` header with a ` + "`next`" + ` relation the metadata field ` + "`http_link_next`" + ` is set to its URL. In cases where pagination depends on more complex logic it is recommended that you use an ` + "[`http` processor](/docs/components/processors/http) instead, often combined with a [`generate` input](/docs/components/inputs/generate)" + ` in order to schedule the processor.`,
 		config: httpClientSpec(),
 		Categories: []Category{
 			CategoryNetwork,
@@ -109,9 +114,10 @@ type StreamConfig struct {
 // HTTPClientConfig contains configuration for the HTTPClient output type.
 type HTTPClientConfig struct {
 	client.Config   `json:",inline" yaml:",inline"`
-	Payload         string       `json:"payload" yaml:"payload"`
-	DropEmptyBodies bool         `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
-	Stream          StreamConfig `json:"stream" yaml:"stream"`
+	Payload         string                  `json:"payload" yaml:"payload"`
+	DropEmptyBodies bool                    `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
+	Stream          StreamConfig            `json:"stream" yaml:"stream"`
+	Pagination      client.PaginationConfig `json:"pagination" yaml:"pagination"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -131,6 +137,7 @@ func NewHTTPClientConfig() HTTPClientConfig {
 			MaxBuffer: 1000000,
 			Delim:     "",
 		},
+		Pagination: client.NewPaginationConfig(),
 	}
 }
 
@@ -145,6 +152,10 @@ type HTTPClient struct {
 	payload      types.Message
 	prevResponse types.Message
 
+	paginator *http.Paginator
+	pageURL   string
+	page      int
+
 	codecCtor codec.ReaderConstructor
 
 	codecMut sync.Mutex
@@ -197,11 +208,20 @@ func newHTTPClient(conf HTTPClientConfig, mgr types.Manager, log log.Modular, st
 		return nil, err
 	}
 
+	paginator, err := http.NewPaginator(conf.Pagination, client, mgr)
+	if err != nil {
+		return nil, err
+	}
+	if paginator != nil && conf.Stream.Enabled {
+		return nil, errors.New("pagination cannot be combined with streaming mode")
+	}
+
 	return &HTTPClient{
 		conf:         conf,
 		payload:      payload,
 		prevResponse: message.New(nil),
 		client:       client,
+		paginator:    paginator,
 
 		codecCtor: codecCtor,
 	}, nil
@@ -312,7 +332,13 @@ func (h *HTTPClient) readStreamed(ctx context.Context) (types.Message, reader.As
 }
 
 func (h *HTTPClient) readNotStreamed(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
-	msg, err := h.client.Send(ctx, h.payload, h.prevResponse)
+	var msg types.Message
+	var err error
+	if h.paginator != nil {
+		msg, err = h.readPage(ctx)
+	} else {
+		msg, err = h.client.Send(ctx, h.payload, h.prevResponse)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
 			err = types.ErrTimeout
@@ -333,6 +359,21 @@ func (h *HTTPClient) readNotStreamed(ctx context.Context) (types.Message, reader
 	}, nil
 }
 
+// readPage requests the next page, and starts again from the first page once
+// pagination is exhausted.
+func (h *HTTPClient) readPage(ctx context.Context) (types.Message, error) {
+	msg, nextURL, err := h.paginator.Page(ctx, h.payload, h.prevResponse, h.pageURL, h.page)
+	if err != nil {
+		return nil, err
+	}
+	if nextURL == "" {
+		h.pageURL, h.page = "", 0
+	} else {
+		h.pageURL, h.page = nextURL, h.page+1
+	}
+	return msg, nil
+}
+
 // CloseAsync shuts down the HTTPClient input and stops processing requests.
 func (h *HTTPClient) CloseAsync() {
 	h.client.Close(context.Background())
@@ -2,6 +2,8 @@ package input
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"errors"
 	"fmt"
@@ -30,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/types"
 	httputil "github.com/Jeffail/benthos/v3/lib/util/http"
 	"github.com/Jeffail/benthos/v3/lib/util/throttle"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
@@ -55,6 +58,20 @@ When the rate limit is breached HTTP requests will have a 429 response returned
 
 It's possible to return a response for each message received using [synchronous responses](/docs/guides/sync_responses). When doing so you can customise headers with the ` + "`sync_response` field `headers`" + `, which can also use [function interpolation](/docs/configuration/interpolation#bloblang-queries) in the value based on the response message contents.
 
+#### Correlated Responses
+
+By default the response is built from messages routed to a ` + "[`sync_response` output](/docs/components/outputs/sync_response)" + ` as part of the same pipeline as the request. In order to build the response from the result of another system, such as a service that consumes requests from a Kafka topic and writes replies to another, set the field ` + "`sync_response.correlation.metadata_key`" + `.
+
+When set, each request message is given a unique ID within that metadata key, and once the request has been delivered by the outputs of the pipeline the request waits for up to ` + "`sync_response.correlation.timeout`" + ` for a message carrying the same ID within the same metadata key to reach a ` + "`sync_response`" + ` output, which can be part of any stream within the same Benthos process. The system processing requests must therefore preserve this metadata key when producing replies. Replies that arrive after their request has timed out are dropped. Correlated responses are only supported for the ` + "`path`" + ` endpoint.
+
+### Concurrency
+
+The field ` + "`max_in_flight`" + ` limits the number of requests that each endpoint processes at a time, where requests beyond the limit wait for up to ` + "`timeout`" + ` for an earlier request to finish and otherwise receive a 503 response. For the ` + "`ws_path`" + ` endpoint the limit applies to the number of open websocket connections.
+
+### Sharing an Address
+
+Multiple ` + "`http_server`" + ` inputs can be configured with the same custom ` + "`address`" + `, in which case they share a single server and each input receives the requests sent to its own endpoints. This allows requests of different paths to be routed to different streams. Inputs sharing an address must have matching ` + "`cert_file`" + ` and ` + "`key_file`" + ` fields, and must not register the same endpoint paths.
+
 ### Endpoints
 
 The following fields specify endpoints that are registered for sending messages, and support path parameters of the form ` + "`/{foo}`" + `, which are added to ingested messages as metadata:
@@ -63,7 +80,9 @@ The following fields specify endpoints that are registered for sending messages,
 
 This endpoint expects POST requests where the entire request body is consumed as a single message.
 
-If the request contains a multipart ` + "`content-type`" + ` header as per [rfc1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html) then the multiple parts are consumed as a batch of messages, where each body part is a message of the batch.
+If the request contains a multipart ` + "`content-type`" + ` header as per [rfc1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html) then the multiple parts are consumed as a batch of messages, where each body part is a message of the batch. The file name and form name of each part, when present, are added to its message as the metadata fields ` + "`http_server_filename`" + ` and ` + "`http_server_form_name`" + ` respectively.
+
+Request bodies with a ` + "`content-encoding`" + ` header of ` + "`gzip`" + ` or ` + "`deflate`" + ` are decompressed before being consumed.
 
 #### ` + "`ws_path` (defaults to `/post/ws`)" + `
 
@@ -81,6 +100,8 @@ This input adds the following metadata fields to each message:
 - http_server_user_agent
 - http_server_request_path
 - http_server_verb
+- http_server_filename (multipart requests only)
+- http_server_form_name (multipart requests only)
 - All headers (only first values are taken)
 - All query parameters
 - All path parameters
@@ -89,7 +110,7 @@ This input adds the following metadata fields to each message:
 
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("address", "An alternative address to host from. If left empty the service wide address is used."),
+			docs.FieldCommon("address", "An alternative address to host from. If left empty the service wide address is used. Inputs configured with the same address share a server."),
 			docs.FieldCommon("path", "The endpoint path to listen for POST requests."),
 			docs.FieldCommon("ws_path", "The endpoint path to create websocket connections from."),
 			docs.FieldAdvanced("ws_welcome_message", "An optional message to deliver to fresh websocket connections."),
@@ -97,6 +118,7 @@ You can access these metadata fields using [function interpolation](/docs/config
 			docs.FieldCommon("allowed_verbs", "An array of verbs that are allowed for the `path` endpoint.").AtVersion("3.33.0").Array(),
 			docs.FieldCommon("timeout", "Timeout for requests. If a consumed messages takes longer than this to be delivered the connection is closed, but the message may still be delivered."),
 			docs.FieldCommon("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by."),
+			docs.FieldAdvanced("max_in_flight", "The maximum number of requests that each endpoint processes at a time. When zero the number of requests is unlimited.").AtVersion("3.64.0"),
 			docs.FieldAdvanced("cert_file", "Enable TLS by specifying a certificate and key file. Only valid with a custom `address`."),
 			docs.FieldAdvanced("key_file", "Enable TLS by specifying a certificate and key file. Only valid with a custom `address`."),
 			corsSpec,
@@ -110,6 +132,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 					"Content-Type": "application/octet-stream",
 				}),
 				docs.FieldCommon("metadata_headers", "Specify criteria for which metadata values are added to the response as headers.").WithChildren(imetadata.IncludeFilterDocs()...),
+				docs.FieldAdvanced("correlation", "Build responses from messages that are correlated with requests by metadata, allowing the response to be the result of another system.").WithChildren(
+					docs.FieldString("metadata_key", "A metadata key to write a unique ID for each request to. When set the response is built from the first message with the same ID within this key to reach a `sync_response` output, rather than from the pipeline of the request.", "correlation_id"),
+					docs.FieldString("timeout", "The maximum period to wait for a correlated response once the request has been delivered. When empty the `timeout` of the input is used.", "30s"),
+				).AtVersion("3.64.0"),
 			),
 		},
 		Categories: []Category{
@@ -120,12 +146,20 @@ You can access these metadata fields using [function interpolation](/docs/config
 
 //------------------------------------------------------------------------------
 
+// HTTPServerCorrelationConfig provides config fields for building responses
+// from messages correlated with requests by metadata.
+type HTTPServerCorrelationConfig struct {
+	MetadataKey string `json:"metadata_key" yaml:"metadata_key"`
+	Timeout     string `json:"timeout" yaml:"timeout"`
+}
+
 // HTTPServerResponseConfig provides config fields for customising the response
 // given from successful requests.
 type HTTPServerResponseConfig struct {
 	Status          string                        `json:"status" yaml:"status"`
 	Headers         map[string]string             `json:"headers" yaml:"headers"`
 	ExtractMetadata imetadata.IncludeFilterConfig `json:"metadata_headers" yaml:"metadata_headers"`
+	Correlation     HTTPServerCorrelationConfig   `json:"correlation" yaml:"correlation"`
 }
 
 // NewHTTPServerResponseConfig creates a new HTTPServerConfig with default values.
@@ -136,6 +170,10 @@ func NewHTTPServerResponseConfig() HTTPServerResponseConfig {
 			"Content-Type": "application/octet-stream",
 		},
 		ExtractMetadata: imetadata.NewIncludeFilterConfig(),
+		Correlation: HTTPServerCorrelationConfig{
+			MetadataKey: "",
+			Timeout:     "",
+		},
 	}
 }
 
@@ -149,6 +187,7 @@ type HTTPServerConfig struct {
 	AllowedVerbs       []string                 `json:"allowed_verbs" yaml:"allowed_verbs"`
 	Timeout            string                   `json:"timeout" yaml:"timeout"`
 	RateLimit          string                   `json:"rate_limit" yaml:"rate_limit"`
+	MaxInFlight        int                      `json:"max_in_flight" yaml:"max_in_flight"`
 	CertFile           string                   `json:"cert_file" yaml:"cert_file"`
 	KeyFile            string                   `json:"key_file" yaml:"key_file"`
 	CORS               httpdocs.ServerCORS      `json:"cors" yaml:"cors"`
@@ -166,12 +205,13 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		AllowedVerbs: []string{
 			"POST",
 		},
-		Timeout:   "5s",
-		RateLimit: "",
-		CertFile:  "",
-		KeyFile:   "",
-		CORS:      httpdocs.NewServerCORS(),
-		Response:  NewHTTPServerResponseConfig(),
+		Timeout:     "5s",
+		RateLimit:   "",
+		MaxInFlight: 0,
+		CertFile:    "",
+		KeyFile:     "",
+		CORS:        httpdocs.NewServerCORS(),
+		Response:    NewHTTPServerResponseConfig(),
 	}
 }
 
@@ -188,13 +228,16 @@ type HTTPServer struct {
 	log   log.Modular
 	mgr   types.Manager
 
-	mux     *http.ServeMux
-	server  *http.Server
-	timeout time.Duration
+	listener *httpServerListener
+	timeout  time.Duration
+
+	responseStatus     *field.Expression
+	responseHeaders    map[string]*field.Expression
+	metaFilter         *imetadata.IncludeFilter
+	correlationTimeout time.Duration
 
-	responseStatus  *field.Expression
-	responseHeaders map[string]*field.Expression
-	metaFilter      *imetadata.IncludeFilter
+	postInFlight chan struct{}
+	wsInFlight   chan struct{}
 
 	handlerWG    sync.WaitGroup
 	transactions chan types.Transaction
@@ -218,22 +261,12 @@ type HTTPServer struct {
 	mWSSucc        metrics.StatCounter
 	mAsyncErr      metrics.StatCounter
 	mAsyncSucc     metrics.StatCounter
+	mInFlightLimit metrics.StatCounter
 }
 
 // NewHTTPServer creates a new HTTPServer input type.
 func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	var mux *http.ServeMux
-	var server *http.Server
-
 	var err error
-	if len(conf.HTTPServer.Address) > 0 {
-		mux = http.NewServeMux()
-		server = &http.Server{Addr: conf.HTTPServer.Address}
-		if server.Handler, err = conf.HTTPServer.CORS.WrapHandler(mux); err != nil {
-			return nil, fmt.Errorf("bad CORS configuration: %w", err)
-		}
-	}
-
 	var timeout time.Duration
 	if len(conf.HTTPServer.Timeout) > 0 {
 		if timeout, err = time.ParseDuration(conf.HTTPServer.Timeout); err != nil {
@@ -255,8 +288,6 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		stats:           stats,
 		log:             log,
 		mgr:             mgr,
-		mux:             mux,
-		server:          server,
 		timeout:         timeout,
 		responseHeaders: map[string]*field.Expression{},
 		transactions:    make(chan types.Transaction),
@@ -277,6 +308,21 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		mWSSucc:        stats.GetCounter("ws.send.success"),
 		mAsyncErr:      stats.GetCounter("send.async_error"),
 		mAsyncSucc:     stats.GetCounter("send.async_success"),
+		mInFlightLimit: stats.GetCounter("in_flight.limited"),
+	}
+
+	h.correlationTimeout = timeout
+	if h.conf.Response.Correlation.Timeout != "" {
+		if h.correlationTimeout, err = time.ParseDuration(h.conf.Response.Correlation.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse correlation timeout string: %v", err)
+		}
+	}
+	if h.conf.MaxInFlight < 0 {
+		return nil, errors.New("max_in_flight must not be negative")
+	}
+	if h.conf.MaxInFlight > 0 {
+		h.postInFlight = make(chan struct{}, h.conf.MaxInFlight)
+		h.wsInFlight = make(chan struct{}, h.conf.MaxInFlight)
 	}
 
 	if h.responseStatus, err = interop.NewBloblangField(mgr, h.conf.Response.Status); err != nil {
@@ -292,14 +338,29 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		return nil, fmt.Errorf("failed to construct metadata filter: %w", err)
 	}
 
+	if h.conf.RateLimit != "" {
+		if err := interop.ProbeRateLimit(context.Background(), h.mgr, h.conf.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+
 	postHdlr := httputil.GzipHandler(h.postHandler)
 	wsHdlr := httputil.GzipHandler(h.wsHandler)
-	if mux != nil {
+	if len(h.conf.Address) > 0 {
+		handlers := map[string]http.Handler{}
 		if len(h.conf.Path) > 0 {
-			mux.HandleFunc(h.conf.Path, postHdlr)
+			handlers[h.conf.Path] = postHdlr
 		}
 		if len(h.conf.WSPath) > 0 {
-			mux.HandleFunc(h.conf.WSPath, wsHdlr)
+			handlers[h.conf.WSPath] = wsHdlr
+		}
+		for path, hdlr := range handlers {
+			if handlers[path], err = h.conf.CORS.WrapHandler(hdlr); err != nil {
+				return nil, fmt.Errorf("bad CORS configuration: %w", err)
+			}
+		}
+		if h.listener, err = registerHTTPServerListener(h.conf, handlers, h.log); err != nil {
+			return nil, err
 		}
 	} else {
 		if len(h.conf.Path) > 0 {
@@ -314,12 +375,6 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		}
 	}
 
-	if h.conf.RateLimit != "" {
-		if err := interop.ProbeRateLimit(context.Background(), h.mgr, h.conf.RateLimit); err != nil {
-			return nil, err
-		}
-	}
-
 	go h.loop()
 	return &h, nil
 }
@@ -339,8 +394,27 @@ func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message,
 		return nil, err
 	}
 
+	var body io.Reader = r.Body
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "gzip":
+		var gr *gzip.Reader
+		if gr, err = gzip.NewReader(r.Body); err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		body = gr
+	case "deflate":
+		var zr io.ReadCloser
+		if zr, err = zlib.NewReader(r.Body); err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	var partNames [][2]string
 	if strings.HasPrefix(mediaType, "multipart/") {
-		mr := multipart.NewReader(r.Body, params["boundary"])
+		mr := multipart.NewReader(body, params["boundary"])
 		for {
 			var p *multipart.Part
 			if p, err = mr.NextPart(); err != nil {
@@ -354,10 +428,11 @@ func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message,
 				return nil, err
 			}
 			msg.Append(message.NewPart(msgBytes))
+			partNames = append(partNames, [2]string{p.FileName(), p.FormName()})
 		}
 	} else {
 		var msgBytes []byte
-		if msgBytes, err = io.ReadAll(r.Body); err != nil {
+		if msgBytes, err = io.ReadAll(body); err != nil {
 			return nil, err
 		}
 		msg.Append(message.NewPart(msgBytes))
@@ -385,6 +460,16 @@ func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message,
 	}
 	message.SetAllMetadata(msg, meta)
 
+	for i, names := range partNames {
+		partMeta := msg.Get(i).Metadata()
+		if names[0] != "" {
+			partMeta.Set("http_server_filename", names[0])
+		}
+		if names[1] != "" {
+			partMeta.Set("http_server_form_name", names[1])
+		}
+	}
+
 	textMapGeneric := map[string]interface{}{}
 	for k, vals := range r.Header {
 		for _, v := range vals {
@@ -396,6 +481,30 @@ func (h *HTTPServer) extractMessageFromRequest(r *http.Request) (types.Message,
 	return msg, nil
 }
 
+// acquireInFlight blocks until a slot of a concurrency limit is available, and
+// returns false if the timeout is reached, the request ends or the server closes
+// before then.
+func (h *HTTPServer) acquireInFlight(r *http.Request, slots chan struct{}) bool {
+	if slots == nil {
+		return true
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-time.After(h.timeout):
+	case <-r.Context().Done():
+	case <-h.shutSig.CloseAtLeisureChan():
+	}
+	h.mInFlightLimit.Incr(1)
+	return false
+}
+
+func (h *HTTPServer) releaseInFlight(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
 func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	h.handlerWG.Add(1)
 	defer h.handlerWG.Done()
@@ -406,6 +515,12 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.acquireInFlight(r, h.postInFlight) {
+		http.Error(w, "Too many requests in flight", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.releaseInFlight(h.postInFlight)
+
 	if h.conf.RateLimit != "" {
 		var tUntil time.Duration
 		var err error
@@ -439,6 +554,24 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	store := roundtrip.NewResultStore()
 	roundtrip.AddResultStore(msg, store)
 
+	var replies <-chan types.Message
+	if metaKey := h.conf.Response.Correlation.MetadataKey; metaKey != "" {
+		var u4 uuid.UUID
+		if u4, err = uuid.NewV4(); err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			h.log.Errorf("Failed to generate correlation ID: %v\n", err)
+			return
+		}
+		id := u4.String()
+		msg.Iter(func(i int, p types.Part) error {
+			p.Metadata().Set(metaKey, id)
+			return nil
+		})
+		var doneFn func()
+		replies, doneFn = roundtrip.AwaitCorrelated(metaKey, id)
+		defer doneFn()
+	}
+
 	h.mCount.Incr(1)
 	h.mPartsRcvd.Incr(int64(msg.Len()))
 	h.mRcvd.Incr(1)
@@ -493,6 +626,26 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			return nil
 		})
 	}
+	if responseMsg.Len() == 0 && replies != nil {
+		select {
+		case reply := <-replies:
+			reply.Iter(func(i int, part types.Part) error {
+				responseMsg.Append(part)
+				return nil
+			})
+		case <-time.After(h.correlationTimeout):
+			h.mTimeout.Incr(1)
+			http.Error(w, "Response timed out", http.StatusGatewayTimeout)
+			return
+		case <-r.Context().Done():
+			h.mTimeout.Incr(1)
+			http.Error(w, "Request timed out", http.StatusRequestTimeout)
+			return
+		case <-h.shutSig.CloseNowChan():
+			http.Error(w, "Server closing", http.StatusServiceUnavailable)
+			return
+		}
+	}
 	if responseMsg.Len() > 0 {
 		for k, v := range h.responseHeaders {
 			w.Header().Set(k, v.String(0, responseMsg))
@@ -571,6 +724,12 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 	h.handlerWG.Add(1)
 	defer h.handlerWG.Done()
 
+	if !h.acquireInFlight(r, h.wsInFlight) {
+		http.Error(w, "Too many connections in flight", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.releaseInFlight(h.wsInFlight)
+
 	var err error
 	defer func() {
 		if err != nil {
@@ -697,10 +856,8 @@ func (h *HTTPServer) loop() {
 	mRunning := h.stats.GetGauge("running")
 
 	defer func() {
-		if h.server != nil {
-			if err := h.server.Shutdown(context.Background()); err != nil {
-				h.log.Errorf("Failed to gracefully terminate http_server: %v\n", err)
-			}
+		if h.listener != nil {
+			h.listener.deregister(h.conf.Path, h.conf.WSPath)
 		} else {
 			if len(h.conf.Path) > 0 {
 				h.mgr.RegisterEndpoint(h.conf.Path, "Does nothing.", http.NotFound)
@@ -718,28 +875,18 @@ func (h *HTTPServer) loop() {
 	}()
 	mRunning.Incr(1)
 
-	if h.server != nil {
-		go func() {
-			if len(h.conf.KeyFile) > 0 || len(h.conf.CertFile) > 0 {
-				h.log.Infof(
-					"Receiving HTTPS messages at: https://%s\n",
-					h.conf.Address+h.conf.Path,
-				)
-				if err := h.server.ListenAndServeTLS(
-					h.conf.CertFile, h.conf.KeyFile,
-				); err != http.ErrServerClosed {
-					h.log.Errorf("Server error: %v\n", err)
-				}
-			} else {
-				h.log.Infof(
-					"Receiving HTTP messages at: http://%s\n",
-					h.conf.Address+h.conf.Path,
-				)
-				if err := h.server.ListenAndServe(); err != http.ErrServerClosed {
-					h.log.Errorf("Server error: %v\n", err)
-				}
-			}
-		}()
+	if h.listener != nil {
+		if len(h.conf.KeyFile) > 0 || len(h.conf.CertFile) > 0 {
+			h.log.Infof(
+				"Receiving HTTPS messages at: https://%s\n",
+				h.conf.Address+h.conf.Path,
+			)
+		} else {
+			h.log.Infof(
+				"Receiving HTTP messages at: http://%s\n",
+				h.conf.Address+h.conf.Path,
+			)
+		}
 	}
 
 	<-h.shutSig.CloseAtLeisureChan()
@@ -0,0 +1,124 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// httpServerListener is an HTTP server bound to a custom address, which is
+// shared by all http_server inputs configured with that address. Each input
+// registers its own endpoints, and therefore a single listener is able to route
+// requests to different streams by their path.
+type httpServerListener struct {
+	address  string
+	certFile string
+	keyFile  string
+
+	server *http.Server
+	log    log.Modular
+
+	refs     int
+	handlers map[string]http.Handler
+	mux      atomic.Value
+}
+
+var httpServerListeners = struct {
+	sync.Mutex
+	m map[string]*httpServerListener
+}{
+	m: map[string]*httpServerListener{},
+}
+
+// registerHTTPServerListener registers handlers with the listener of an
+// address, creating and starting the listener if it does not yet exist.
+func registerHTTPServerListener(conf HTTPServerConfig, handlers map[string]http.Handler, log log.Modular) (*httpServerListener, error) {
+	httpServerListeners.Lock()
+	defer httpServerListeners.Unlock()
+
+	l, exists := httpServerListeners.m[conf.Address]
+	if exists {
+		if l.certFile != conf.CertFile || l.keyFile != conf.KeyFile {
+			return nil, fmt.Errorf("address '%v' is already in use by an http_server input with a different TLS configuration", conf.Address)
+		}
+		for path := range handlers {
+			if _, exists := l.handlers[path]; exists {
+				return nil, fmt.Errorf("path '%v' is already registered at address '%v' by another http_server input", path, conf.Address)
+			}
+		}
+	} else {
+		l = &httpServerListener{
+			address:  conf.Address,
+			certFile: conf.CertFile,
+			keyFile:  conf.KeyFile,
+			log:      log,
+			handlers: map[string]http.Handler{},
+		}
+		l.server = &http.Server{Addr: conf.Address, Handler: l}
+	}
+
+	for path, h := range handlers {
+		l.handlers[path] = h
+	}
+	l.refs++
+	l.rebuildMux()
+
+	if !exists {
+		httpServerListeners.m[conf.Address] = l
+		go l.listen()
+	}
+	return l, nil
+}
+
+// deregister removes the handlers of paths from the listener, and once all
+// inputs sharing the listener have deregistered the server is shut down.
+func (l *httpServerListener) deregister(paths ...string) {
+	httpServerListeners.Lock()
+	defer httpServerListeners.Unlock()
+
+	for _, path := range paths {
+		delete(l.handlers, path)
+	}
+	l.rebuildMux()
+
+	if l.refs--; l.refs > 0 {
+		return
+	}
+	delete(httpServerListeners.m, l.address)
+	if err := l.server.Shutdown(context.Background()); err != nil {
+		l.log.Errorf("Failed to gracefully terminate http_server: %v\n", err)
+	}
+}
+
+// rebuildMux must be called with the listeners lock held.
+func (l *httpServerListener) rebuildMux() {
+	mux := http.NewServeMux()
+	for path, h := range l.handlers {
+		mux.Handle(path, h)
+	}
+	l.mux.Store(mux)
+}
+
+func (l *httpServerListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mux.Load().(*http.ServeMux).ServeHTTP(w, r)
+}
+
+func (l *httpServerListener) listen() {
+	var err error
+	if len(l.keyFile) > 0 || len(l.certFile) > 0 {
+		err = l.server.ListenAndServeTLS(l.certFile, l.keyFile)
+	} else {
+		err = l.server.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		l.log.Errorf("Server error: %v\n", err)
+	}
+}
+
+//------------------------------------------------------------------------------
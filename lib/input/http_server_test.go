@@ -2,6 +2,8 @@ package input_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"errors"
 	"fmt"
@@ -310,6 +312,200 @@ func TestHTTPServerLifecycle(t *testing.T) {
 	assert.NoError(t, serverTwo.WaitForClose(time.Second))
 }
 
+func TestHTTPServerSharedAddress(t *testing.T) {
+	freePort, err := getFreePort()
+	require.NoError(t, err)
+
+	mgr, err := manager.New(manager.NewConfig(), apiRegGorillaMutWrapper{mut: mux.NewRouter()}, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	newInput := func(path string) input.Type {
+		t.Helper()
+		conf := input.NewConfig()
+		conf.HTTPServer.Address = fmt.Sprintf("localhost:%v", freePort)
+		conf.HTTPServer.Path = path
+		conf.HTTPServer.WSPath = ""
+		in, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		return in
+	}
+
+	fooInput, barInput := newInput("/foo"), newInput("/bar")
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Address = fmt.Sprintf("localhost:%v", freePort)
+	conf.HTTPServer.Path = "/foo"
+	conf.HTTPServer.WSPath = ""
+	_, err = input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	baseURL := fmt.Sprintf("http://localhost:%v", freePort)
+	require.Eventually(t, func() bool {
+		res, err := http.Get(baseURL + "/nope")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == 404
+	}, time.Second*5, time.Millisecond*10)
+
+	postAndRead := func(in input.Type, path, data string) {
+		t.Helper()
+		go func() {
+			resp, cerr := http.Post(baseURL+path, "text/plain", bytes.NewReader([]byte(data)))
+			if assert.NoError(t, cerr) {
+				resp.Body.Close()
+			}
+		}()
+		select {
+		case tran := <-in.TransactionChan():
+			assert.Equal(t, data, string(tran.Payload.Get(0).Get()))
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second * 5):
+				t.Fatal("timed out waiting for response")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+
+	postAndRead(fooInput, "/foo", "foo data")
+	postAndRead(barInput, "/bar", "bar data")
+
+	fooInput.CloseAsync()
+	require.NoError(t, fooInput.WaitForClose(time.Second*5))
+
+	res, err := http.Post(baseURL+"/foo", "text/plain", bytes.NewReader([]byte("nope")))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 404, res.StatusCode)
+
+	postAndRead(barInput, "/bar", "more bar data")
+
+	barInput.CloseAsync()
+	require.NoError(t, barInput.WaitForClose(time.Second*5))
+}
+
+func TestHTTPServerMultipartFilenames(t *testing.T) {
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/upload"
+
+	server, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	defer func() {
+		server.CloseAsync()
+		assert.NoError(t, server.WaitForClose(time.Second))
+	}()
+
+	testServer := httptest.NewServer(reg.mut)
+	defer testServer.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("first", "foo.txt")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("foo contents"))
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteField("second", "bar contents"))
+	require.NoError(t, writer.Close())
+
+	go func() {
+		resp, cerr := http.Post(testServer.URL+"/upload", writer.FormDataContentType(), body)
+		if assert.NoError(t, cerr) {
+			resp.Body.Close()
+		}
+	}()
+
+	select {
+	case tran := <-server.TransactionChan():
+		require.Equal(t, 2, tran.Payload.Len())
+
+		assert.Equal(t, "foo contents", string(tran.Payload.Get(0).Get()))
+		assert.Equal(t, "foo.txt", tran.Payload.Get(0).Metadata().Get("http_server_filename"))
+		assert.Equal(t, "first", tran.Payload.Get(0).Metadata().Get("http_server_form_name"))
+
+		assert.Equal(t, "bar contents", string(tran.Payload.Get(1).Get()))
+		assert.Equal(t, "", tran.Payload.Get(1).Metadata().Get("http_server_filename"))
+		assert.Equal(t, "second", tran.Payload.Get(1).Metadata().Get("http_server_form_name"))
+
+		assert.Equal(t, "/upload", tran.Payload.Get(1).Metadata().Get("http_server_request_path"))
+
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for response")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestHTTPServerCompressedBody(t *testing.T) {
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/compressed"
+
+	server, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	defer func() {
+		server.CloseAsync()
+		assert.NoError(t, server.WaitForClose(time.Second))
+	}()
+
+	testServer := httptest.NewServer(reg.mut)
+	defer testServer.Close()
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		encoding := encoding
+		data := fmt.Sprintf("hello world compressed with %v", encoding)
+
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		if encoding == "gzip" {
+			w = gzip.NewWriter(&buf)
+		} else {
+			w = zlib.NewWriter(&buf)
+		}
+		_, err := w.Write([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		go func() {
+			req, cerr := http.NewRequest("POST", testServer.URL+"/compressed", &buf)
+			if !assert.NoError(t, cerr) {
+				return
+			}
+			req.Header.Set("Content-Encoding", encoding)
+			resp, cerr := http.DefaultClient.Do(req)
+			if assert.NoError(t, cerr) {
+				resp.Body.Close()
+			}
+		}()
+
+		select {
+		case tran := <-server.TransactionChan():
+			assert.Equal(t, data, string(tran.Payload.Get(0).Get()))
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second * 5):
+				t.Fatal("timed out waiting for response")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
 func TestHTTPServerMetadata(t *testing.T) {
 	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
 	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
@@ -860,6 +1056,181 @@ func TestHTTPSyncResponseHeaders(t *testing.T) {
 	wg.Wait()
 }
 
+func TestHTTPSyncResponseCorrelated(t *testing.T) {
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Response.Correlation.MetadataKey = "test_http_correlation_id"
+	conf.HTTPServer.Response.Correlation.Timeout = "5s"
+
+	h, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		res, err := http.Post(
+			server.URL+"/testpost",
+			"application/octet-stream",
+			bytes.NewBuffer([]byte("hello world")),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.StatusCode)
+
+		resBytes, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "HELLO WORLD", string(resBytes))
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	id := ts.Payload.Get(0).Metadata().Get("test_http_correlation_id")
+	require.NotEmpty(t, id)
+
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+
+	// The reply arrives without the result store of the request, as if it had
+	// been consumed from another system.
+	reply := message.New([][]byte{[]byte("HELLO WORLD")})
+	reply.Get(0).Metadata().Set("test_http_correlation_id", id)
+	require.NoError(t, roundtrip.Writer{}.Write(reply))
+
+	wg.Wait()
+
+	h.CloseAsync()
+	require.NoError(t, h.WaitForClose(time.Second*5))
+}
+
+func TestHTTPSyncResponseCorrelatedTimeout(t *testing.T) {
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Response.Correlation.MetadataKey = "test_http_correlation_id"
+	conf.HTTPServer.Response.Correlation.Timeout = "10ms"
+
+	h, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		res, err := http.Post(
+			server.URL+"/testpost",
+			"application/octet-stream",
+			bytes.NewBuffer([]byte("hello world")),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusGatewayTimeout, res.StatusCode)
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+
+	wg.Wait()
+
+	// A late reply is dropped rather than rejected.
+	reply := message.New([][]byte{[]byte("too late")})
+	reply.Get(0).Metadata().Set("test_http_correlation_id", ts.Payload.Get(0).Metadata().Get("test_http_correlation_id"))
+	require.NoError(t, roundtrip.Writer{}.Write(reply))
+
+	h.CloseAsync()
+	require.NoError(t, h.WaitForClose(time.Second*5))
+}
+
+func TestHTTPMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewConfig(), reg, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Timeout = "100ms"
+	conf.HTTPServer.MaxInFlight = 1
+	conf.HTTPServer.Response.Correlation.MetadataKey = "test_http_in_flight_id"
+	conf.HTTPServer.Response.Correlation.Timeout = "5s"
+
+	h, err := input.NewHTTPServer(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBuffer([]byte("first")))
+		require.NoError(t, err)
+		assert.Equal(t, 200, res.StatusCode)
+	}()
+
+	var ts types.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+		assert.Equal(t, "first", string(ts.Payload.Get(0).Get()))
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+
+	// The first request is still awaiting its correlated response and therefore
+	// a second request is rejected once the timeout is reached.
+	res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBuffer([]byte("second")))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	reply := message.New([][]byte{[]byte("reply")})
+	reply.Get(0).Metadata().Set("test_http_in_flight_id", ts.Payload.Get(0).Metadata().Get("test_http_in_flight_id"))
+	require.NoError(t, roundtrip.Writer{}.Write(reply))
+	<-firstDone
+
+	h.CloseAsync()
+	require.NoError(t, h.WaitForClose(time.Second*5))
+}
+
 func createMultipart(payloads []string, contentType string) (hdr string, bodyBytes []byte, err error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -18,6 +18,14 @@ func init() {
 		Summary: `
 Subscribe to topics on MQTT brokers.`,
 		Description: `
+### MQTT 5
+
+When ` + "`protocol_version`" + ` is set to ` + "`5`" + ` the input connects with MQTT 5.0, where messages of QoS 1 and 2 are only acknowledged to the broker once they have been delivered by Benthos. Messages that are not acknowledged are redelivered by the broker when the session is resumed, and therefore in order to avoid losing messages across restarts the session should outlive the connection by setting ` + "`clean_session` to `false` and `session_expiry_interval`" + ` to a period long enough for Benthos to reconnect within.
+
+### Shared Subscriptions
+
+Setting ` + "`shared_subscription_group`" + ` subscribes to each topic as a shared subscription of the group, where messages of the topics are distributed across all clients subscribed with the same group. This allows consumption to be scaled across multiple instances of Benthos. Shared subscriptions are a feature of MQTT 5.0, which is also supported for MQTT 3.1.1 by many brokers.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -28,19 +36,25 @@ This input adds the following metadata fields to each message:
 - mqtt_retained
 - mqtt_topic
 - mqtt_message_id
+- All user properties (MQTT 5 only)
 ` + "```" + `
 
+The field ` + "`mqtt_duplicate`" + ` is only added when connecting with MQTT 3.1.1, and only the first value of each user property is added.
+
 You can access these metadata fields using
 [function interpolation](/docs/configuration/interpolation#metadata).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").Array(),
 			docs.FieldCommon("topics", "A list of topics to consume from.").Array(),
+			mqttconf.ProtocolVersionFieldSpec(),
+			docs.FieldString("shared_subscription_group", "An optional group to subscribe to each topic as a shared subscription of, where messages are distributed across all clients of the group.", "benthos_consumers").Advanced().AtVersion("3.64.0"),
 			docs.FieldCommon("client_id", "An identifier for the client connection."),
 			docs.FieldString("dynamic_client_id_suffix", "Append a dynamically generated suffix to the specified `client_id` on each run of the pipeline. This can be useful when clustering Benthos producers.").Optional().Advanced().HasAnnotatedOptions(
 				"nanoid", "append a nanoid of length 21 characters",
 			),
 			docs.FieldAdvanced("qos", "The level of delivery guarantee to enforce.").HasOptions("0", "1", "2"),
-			docs.FieldAdvanced("clean_session", "Set whether the connection is non-persistent."),
+			docs.FieldAdvanced("clean_session", "Set whether the connection is non-persistent. When connecting with MQTT 5 this sets the clean start flag, which discards any existing session."),
+			docs.FieldInt("session_expiry_interval", "The number of seconds that the session is kept by the broker after the connection is closed, where zero ends the session along with the connection. This field is only used when connecting with MQTT 5.").Advanced().AtVersion("3.64.0"),
 			mqttconf.WillFieldSpec(),
 			docs.FieldString("connect_timeout", "The maximum amount of time to wait in order to establish a connection before the attempt is abandoned.", "1s", "500ms").HasDefault("30s").AtVersion("3.58.0"),
 			docs.FieldAdvanced("user", "A username to assume for the connection."),
@@ -59,7 +73,17 @@ You can access these metadata fields using
 
 // NewMQTT creates a new MQTT input type.
 func NewMQTT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	m, err := reader.NewMQTT(conf.MQTT, log, stats)
+	if err := mqttconf.ValidateProtocolVersion(conf.MQTT.ProtocolVersion); err != nil {
+		return nil, err
+	}
+
+	var m reader.Async
+	var err error
+	if conf.MQTT.ProtocolVersion == mqttconf.ProtocolVersion5 {
+		m, err = reader.NewMQTTV5(conf.MQTT, log, stats)
+	} else {
+		m, err = reader.NewMQTT(conf.MQTT, log, stats)
+	}
 	if err != nil {
 		return nil, err
 	}
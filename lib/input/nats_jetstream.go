@@ -9,15 +9,20 @@ import (
 // NATSJetStreamConfig contains configuration fields for the NATS Jetstream
 // input type.
 type NATSJetStreamConfig struct {
-	URLs          []string    `json:"urls" yaml:"urls"`
-	Subject       string      `json:"subject" yaml:"subject"`
-	Queue         string      `json:"queue" yaml:"queue"`
-	Durable       string      `json:"durable" yaml:"durable"`
-	Deliver       string      `json:"deliver" yaml:"deliver"`
-	AckWait       string      `json:"ack_wait" yaml:"ack_wait"`
-	MaxAckPending int         `json:"max_ack_pending" yaml:"max_ack_pending"`
-	TLS           tls.Config  `json:"tls" yaml:"tls"`
-	Auth          auth.Config `json:"auth" yaml:"auth"`
+	URLs          []string                  `json:"urls" yaml:"urls"`
+	Subject       string                    `json:"subject" yaml:"subject"`
+	Queue         string                    `json:"queue" yaml:"queue"`
+	Durable       string                    `json:"durable" yaml:"durable"`
+	Deliver       string                    `json:"deliver" yaml:"deliver"`
+	AckWait       string                    `json:"ack_wait" yaml:"ack_wait"`
+	MaxAckPending int                       `json:"max_ack_pending" yaml:"max_ack_pending"`
+	MaxDeliver    int                       `json:"max_deliver" yaml:"max_deliver"`
+	Pull          bool                      `json:"pull" yaml:"pull"`
+	PullBatchSize int                       `json:"pull_batch_size" yaml:"pull_batch_size"`
+	TermPatterns  []string                  `json:"term_patterns" yaml:"term_patterns"`
+	Stream        NATSJetStreamStreamConfig `json:"stream" yaml:"stream"`
+	TLS           tls.Config                `json:"tls" yaml:"tls"`
+	Auth          auth.Config               `json:"auth" yaml:"auth"`
 }
 
 // NewNATSJetStreamConfig creates a new NATSJetstreamConfig with default values.
@@ -27,8 +32,33 @@ func NewNATSJetStreamConfig() NATSJetStreamConfig {
 		Subject:       "",
 		AckWait:       "30s",
 		MaxAckPending: 1024,
+		PullBatchSize: 64,
+		TermPatterns:  []string{},
+		Stream:        NewNATSJetStreamStreamConfig(),
 		Deliver:       "all",
 		TLS:           tls.NewConfig(),
 		Auth:          auth.New(),
 	}
 }
+
+// NATSJetStreamStreamConfig describes a NATS JetStream stream to bind to or
+// create.
+type NATSJetStreamStreamConfig struct {
+	Name            string   `json:"name" yaml:"name"`
+	Create          bool     `json:"create" yaml:"create"`
+	Subjects        []string `json:"subjects" yaml:"subjects"`
+	Storage         string   `json:"storage" yaml:"storage"`
+	Replicas        int      `json:"replicas" yaml:"replicas"`
+	MaxAge          string   `json:"max_age" yaml:"max_age"`
+	DuplicateWindow string   `json:"duplicate_window" yaml:"duplicate_window"`
+}
+
+// NewNATSJetStreamStreamConfig creates a new NATSJetStreamStreamConfig with
+// default values.
+func NewNATSJetStreamStreamConfig() NATSJetStreamStreamConfig {
+	return NATSJetStreamStreamConfig{
+		Subjects: []string{},
+		Storage:  "file",
+		Replicas: 1,
+	}
+}
@@ -6,20 +6,38 @@ import (
 
 // PulsarConfig contains configuration for the Pulsar input type.
 type PulsarConfig struct {
-	URL              string      `json:"url" yaml:"url"`
-	Topics           []string    `json:"topics" yaml:"topics"`
-	SubscriptionName string      `json:"subscription_name" yaml:"subscription_name"`
-	SubscriptionType string      `json:"subscription_type" yaml:"subscription_type"`
-	Auth             auth.Config `json:"auth" yaml:"auth"`
+	URL                 string                 `json:"url" yaml:"url"`
+	Topics              []string               `json:"topics" yaml:"topics"`
+	SubscriptionName    string                 `json:"subscription_name" yaml:"subscription_name"`
+	SubscriptionType    string                 `json:"subscription_type" yaml:"subscription_type"`
+	MaxBatchCount       int                    `json:"max_batch_count" yaml:"max_batch_count"`
+	NackRedeliveryDelay string                 `json:"nack_redelivery_delay" yaml:"nack_redelivery_delay"`
+	DeadLetterPolicy    PulsarDeadLetterPolicy `json:"dead_letter_policy" yaml:"dead_letter_policy"`
+	TLS                 auth.TLSConfig         `json:"tls" yaml:"tls"`
+	Auth                auth.Config            `json:"auth" yaml:"auth"`
+}
+
+// PulsarDeadLetterPolicy contains configuration for sending messages that are
+// repeatedly nacked to a dead letter topic.
+type PulsarDeadLetterPolicy struct {
+	MaxRedeliveries int    `json:"max_redeliveries" yaml:"max_redeliveries"`
+	Topic           string `json:"topic" yaml:"topic"`
 }
 
 // NewPulsarConfig creates a new PulsarConfig with default values.
 func NewPulsarConfig() PulsarConfig {
 	return PulsarConfig{
-		URL:              "",
-		Topics:           []string{},
-		SubscriptionName: "",
-		SubscriptionType: "",
-		Auth:             auth.New(),
+		URL:                 "",
+		Topics:              []string{},
+		SubscriptionName:    "",
+		SubscriptionType:    "",
+		MaxBatchCount:       1,
+		NackRedeliveryDelay: "1m",
+		DeadLetterPolicy: PulsarDeadLetterPolicy{
+			MaxRedeliveries: 0,
+			Topic:           "",
+		},
+		TLS:  auth.NewTLS(),
+		Auth: auth.New(),
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
@@ -23,6 +24,13 @@ type AMQP1Config struct {
 	URL            string      `json:"url" yaml:"url"`
 	SourceAddress  string      `json:"source_address" yaml:"source_address"`
 	AzureRenewLock bool        `json:"azure_renew_lock" yaml:"azure_renew_lock"`
+	Credit         int         `json:"credit" yaml:"credit"`
+	LinkName       string      `json:"link_name" yaml:"link_name"`
+	ContainerID    string      `json:"container_id" yaml:"container_id"`
+	Durability     string      `json:"durability" yaml:"durability"`
+	ExpiryPolicy   string      `json:"expiry_policy" yaml:"expiry_policy"`
+	SettleMode     string      `json:"settle_mode" yaml:"settle_mode"`
+	NackAction     string      `json:"nack_action" yaml:"nack_action"`
 	TLS            btls.Config `json:"tls" yaml:"tls"`
 	SASL           sasl.Config `json:"sasl" yaml:"sasl"`
 }
@@ -32,6 +40,13 @@ func NewAMQP1Config() AMQP1Config {
 	return AMQP1Config{
 		URL:           "",
 		SourceAddress: "",
+		Credit:        10,
+		LinkName:      "",
+		ContainerID:   "",
+		Durability:    "none",
+		ExpiryPolicy:  "session-end",
+		SettleMode:    "unsettled",
+		NackAction:    "modify",
 		TLS:           btls.NewConfig(),
 		SASL:          sasl.NewConfig(),
 	}
@@ -39,6 +54,44 @@ func NewAMQP1Config() AMQP1Config {
 
 //------------------------------------------------------------------------------
 
+func amqp1Durability(s string) (amqp.Durability, error) {
+	switch s {
+	case "none":
+		return amqp.DurabilityNone, nil
+	case "configuration":
+		return amqp.DurabilityConfiguration, nil
+	case "unsettled-state":
+		return amqp.DurabilityUnsettledState, nil
+	}
+	return 0, fmt.Errorf("unrecognised durability: %v", s)
+}
+
+func amqp1ExpiryPolicy(s string) (amqp.ExpiryPolicy, error) {
+	switch s {
+	case "link-detach":
+		return amqp.ExpiryLinkDetach, nil
+	case "session-end":
+		return amqp.ExpirySessionEnd, nil
+	case "connection-close":
+		return amqp.ExpiryConnectionClose, nil
+	case "never":
+		return amqp.ExpiryNever, nil
+	}
+	return "", fmt.Errorf("unrecognised expiry_policy: %v", s)
+}
+
+func amqp1SenderSettleMode(s string) (amqp.SenderSettleMode, error) {
+	switch s {
+	case "unsettled":
+		return amqp.ModeUnsettled, nil
+	case "settled":
+		return amqp.ModeSettled, nil
+	}
+	return 0, fmt.Errorf("unrecognised settle_mode: %v", s)
+}
+
+//------------------------------------------------------------------------------
+
 type amqp1Conn struct {
 	client            *amqp.Client
 	session           *amqp.Session
@@ -82,7 +135,9 @@ func (c *amqp1Conn) Close(ctx context.Context) {
 
 // AMQP1 is an input type that reads messages via the AMQP 1.0 protocol.
 type AMQP1 struct {
-	tlsConf *tls.Config
+	tlsConf  *tls.Config
+	linkOpts []amqp.LinkOption
+	settled  bool
 
 	conf  AMQP1Config
 	stats metrics.Type
@@ -105,6 +160,53 @@ func NewAMQP1(conf AMQP1Config, log log.Modular, stats metrics.Type) (*AMQP1, er
 			return nil, err
 		}
 	}
+
+	if conf.Credit < 1 {
+		return nil, fmt.Errorf("credit must be at least 1, got %v", conf.Credit)
+	}
+	a.linkOpts = []amqp.LinkOption{
+		amqp.LinkSourceAddress(conf.SourceAddress),
+		amqp.LinkCredit(uint32(conf.Credit)),
+	}
+
+	durability, err := amqp1Durability(conf.Durability)
+	if err != nil {
+		return nil, err
+	}
+	if durability != amqp.DurabilityNone {
+		if conf.LinkName == "" {
+			return nil, errors.New("a link_name must be specified for durable subscriptions")
+		}
+		a.linkOpts = append(a.linkOpts, amqp.LinkSourceDurability(durability))
+	}
+	expiryPolicy, err := amqp1ExpiryPolicy(conf.ExpiryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if expiryPolicy != amqp.ExpirySessionEnd {
+		a.linkOpts = append(a.linkOpts, amqp.LinkSourceExpiryPolicy(expiryPolicy))
+	}
+	if conf.LinkName != "" {
+		a.linkOpts = append(a.linkOpts, amqp.LinkName(conf.LinkName))
+	}
+
+	settleMode, err := amqp1SenderSettleMode(conf.SettleMode)
+	if err != nil {
+		return nil, err
+	}
+	if settleMode == amqp.ModeSettled {
+		if conf.AzureRenewLock {
+			return nil, errors.New("azure_renew_lock cannot be used with a settle_mode of settled")
+		}
+		a.settled = true
+		a.linkOpts = append(a.linkOpts, amqp.LinkSenderSettle(settleMode))
+	}
+
+	switch conf.NackAction {
+	case "modify", "release", "reject":
+	default:
+		return nil, fmt.Errorf("unrecognised nack_action: %v", conf.NackAction)
+	}
 	return &a, nil
 }
 
@@ -131,6 +233,9 @@ func (a *AMQP1) ConnectWithContext(ctx context.Context) error {
 	if a.conf.TLS.Enabled {
 		opts = append(opts, amqp.ConnTLS(true), amqp.ConnTLSConfig(a.tlsConf))
 	}
+	if a.conf.ContainerID != "" {
+		opts = append(opts, amqp.ConnContainerID(a.conf.ContainerID))
+	}
 
 	// Create client
 	if conn.client, err = amqp.Dial(a.conf.URL, opts...); err != nil {
@@ -144,10 +249,7 @@ func (a *AMQP1) ConnectWithContext(ctx context.Context) error {
 	}
 
 	// Create a receiver
-	if conn.receiver, err = conn.session.NewReceiver(
-		amqp.LinkSourceAddress(a.conf.SourceAddress),
-		amqp.LinkCredit(10),
-	); err != nil {
+	if conn.receiver, err = conn.session.NewReceiver(a.linkOpts...); err != nil {
 		conn.Close(ctx)
 		return err
 	}
@@ -228,14 +330,8 @@ func (a *AMQP1) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn,
 		setMetadata(part, "amqp_content_encoding", amqpMsg.Properties.ContentEncoding)
 		setMetadata(part, "amqp_creation_time", amqpMsg.Properties.CreationTime)
 	}
-	if amqpMsg.Annotations != nil {
-		for k, v := range amqpMsg.Annotations {
-			keyStr, keyIsStr := k.(string)
-			valStr, valIsStr := v.(string)
-			if keyIsStr && valIsStr {
-				setMetadata(part, keyStr, valStr)
-			}
-		}
+	for k, v := range amqpMsg.Annotations {
+		setAnnotationMetadata(part, k, v)
 	}
 
 	msg.Append(part)
@@ -251,15 +347,81 @@ func (a *AMQP1) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn,
 			done = nil
 		}
 
+		// Messages settled by the sender are not acknowledged.
+		if a.settled {
+			return nil
+		}
+
 		// TODO: These methods were moved in v0.16.0, but nacking seems broken
 		// (integration tests fail)
 		if res.Error() != nil {
+			switch a.conf.NackAction {
+			case "release":
+				return conn.receiver.ReleaseMessage(ctx, amqpMsg)
+			case "reject":
+				return conn.receiver.RejectMessage(ctx, amqpMsg, &amqp.Error{
+					Condition:   amqp.ErrorInternalError,
+					Description: res.Error().Error(),
+				})
+			}
 			return conn.receiver.ModifyMessage(ctx, amqpMsg, true, false, amqpMsg.Annotations)
 		}
 		return conn.receiver.AcceptMessage(ctx, amqpMsg)
 	}, nil
 }
 
+// setAnnotationMetadata adds a message annotation to the metadata of a part,
+// where annotations that are not strings are formatted as strings and
+// annotations of composite types are ignored.
+func setAnnotationMetadata(p types.Part, k, v interface{}) {
+	var key string
+	switch t := k.(type) {
+	case string:
+		key = t
+	default:
+		key = fmt.Sprintf("%v", k)
+	}
+
+	var value string
+	switch t := v.(type) {
+	case string:
+		value = t
+	case int:
+		value = strconv.FormatInt(int64(t), 10)
+	case int8:
+		value = strconv.FormatInt(int64(t), 10)
+	case int16:
+		value = strconv.FormatInt(int64(t), 10)
+	case int32:
+		value = strconv.FormatInt(int64(t), 10)
+	case int64:
+		value = strconv.FormatInt(t, 10)
+	case uint8:
+		value = strconv.FormatUint(uint64(t), 10)
+	case uint16:
+		value = strconv.FormatUint(uint64(t), 10)
+	case uint32:
+		value = strconv.FormatUint(uint64(t), 10)
+	case uint64:
+		value = strconv.FormatUint(t, 10)
+	case float32:
+		value = strconv.FormatFloat(float64(t), 'f', -1, 32)
+	case float64:
+		value = strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		value = strconv.FormatBool(t)
+	case time.Time:
+		value = t.Format(time.RFC3339Nano)
+	case []byte:
+		value = string(t)
+	case amqp.UUID:
+		value = t.String()
+	default:
+		return
+	}
+	setMetadata(p, key, value)
+}
+
 // CloseAsync shuts down the AMQP1 input and stops processing requests.
 func (a *AMQP1) CloseAsync() {
 	a.disconnect(context.Background())
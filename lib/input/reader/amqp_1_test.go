@@ -13,6 +13,7 @@ import (
 
 	"github.com/Azure/go-amqp"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -159,3 +160,106 @@ func testAMQP1Disconnected(url, sourceAddress string, t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestAMQP1BadConfig(t *testing.T) {
+	tests := map[string]struct {
+		mutate func(c *AMQP1Config)
+		errStr string
+	}{
+		"no credit": {
+			mutate: func(c *AMQP1Config) { c.Credit = 0 },
+			errStr: "credit must be at least 1, got 0",
+		},
+		"durable without link name": {
+			mutate: func(c *AMQP1Config) { c.Durability = "unsettled-state" },
+			errStr: "a link_name must be specified for durable subscriptions",
+		},
+		"bad durability": {
+			mutate: func(c *AMQP1Config) { c.Durability = "forever" },
+			errStr: "unrecognised durability: forever",
+		},
+		"bad expiry policy": {
+			mutate: func(c *AMQP1Config) { c.ExpiryPolicy = "sometime" },
+			errStr: "unrecognised expiry_policy: sometime",
+		},
+		"bad settle mode": {
+			mutate: func(c *AMQP1Config) { c.SettleMode = "mixed" },
+			errStr: "unrecognised settle_mode: mixed",
+		},
+		"settled with renew lock": {
+			mutate: func(c *AMQP1Config) {
+				c.SettleMode = "settled"
+				c.AzureRenewLock = true
+			},
+			errStr: "azure_renew_lock cannot be used with a settle_mode of settled",
+		},
+		"bad nack action": {
+			mutate: func(c *AMQP1Config) { c.NackAction = "ignore" },
+			errStr: "unrecognised nack_action: ignore",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewAMQP1Config()
+			conf.URL = "amqp://localhost:5672/"
+			conf.SourceAddress = "/foo"
+			test.mutate(&conf)
+
+			_, err := NewAMQP1(conf, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+
+	conf := NewAMQP1Config()
+	conf.URL = "amqp://localhost:5672/"
+	conf.SourceAddress = "topic:/foo"
+	conf.LinkName = "benthos"
+	conf.Durability = "unsettled-state"
+	conf.ExpiryPolicy = "never"
+	conf.SettleMode = "settled"
+	conf.NackAction = "reject"
+
+	m, err := NewAMQP1(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.True(t, m.settled)
+	assert.Len(t, m.linkOpts, 6)
+}
+
+func TestAMQP1AnnotationMetadata(t *testing.T) {
+	enqueuedTime := time.Date(2022, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	part := message.NewPart(nil)
+	for k, v := range (amqp.Annotations{
+		"x-opt-partition-key":   "foo",
+		"x-opt-sequence-number": int64(42),
+		"x-opt-enqueued-time":   enqueuedTime,
+		"x-opt-locked-until":    uint64(10),
+		"x-opt-ratio":           0.5,
+		"x-opt-redelivered":     true,
+		"x-opt-bytes":           []byte("bar"),
+		"x-opt-id":              amqp.UUID{0x01, 0x02},
+		"x-opt-list":            []interface{}{"a", "b"},
+		int64(7):                "numeric key",
+	}) {
+		setAnnotationMetadata(part, k, v)
+	}
+
+	meta := map[string]string{}
+	_ = part.Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]string{
+		"x_opt_partition_key":   "foo",
+		"x_opt_sequence_number": "42",
+		"x_opt_enqueued_time":   "2022-05-01T12:30:00Z",
+		"x_opt_locked_until":    "10",
+		"x_opt_ratio":           "0.5",
+		"x_opt_redelivered":     "true",
+		"x_opt_bytes":           "bar",
+		"x_opt_id":              "01020000-0000-0000-0000-000000000000",
+		"7":                     "numeric key",
+	}, meta)
+}
@@ -13,12 +13,14 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/metadata"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 )
 
 //------------------------------------------------------------------------------
 
 // GCPPubSubConfig contains configuration values for the input type.
 type GCPPubSubConfig struct {
+	gcpcreds.Config        `json:",inline" yaml:",inline"`
 	ProjectID              string `json:"project" yaml:"project"`
 	SubscriptionID         string `json:"subscription" yaml:"subscription"`
 	MaxOutstandingMessages int    `json:"max_outstanding_messages" yaml:"max_outstanding_messages"`
@@ -32,6 +34,7 @@ type GCPPubSubConfig struct {
 // NewGCPPubSubConfig creates a new Config with default values.
 func NewGCPPubSubConfig() GCPPubSubConfig {
 	return GCPPubSubConfig{
+		Config:                 gcpcreds.NewConfig(),
 		ProjectID:              "",
 		SubscriptionID:         "",
 		MaxOutstandingMessages: pubsub.DefaultReceiveSettings.MaxOutstandingMessages,
@@ -67,7 +70,11 @@ func NewGCPPubSub(
 	log log.Modular,
 	stats metrics.Type,
 ) (*GCPPubSub, error) {
-	client, err := pubsub.NewClient(context.Background(), conf.ProjectID)
+	opts, err := conf.ClientOptions(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	client, err := pubsub.NewClient(context.Background(), conf.ProjectID, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -22,36 +22,42 @@ import (
 
 // MQTTConfig contains configuration fields for the MQTT input type.
 type MQTTConfig struct {
-	URLs                   []string      `json:"urls" yaml:"urls"`
-	QoS                    uint8         `json:"qos" yaml:"qos"`
-	Topics                 []string      `json:"topics" yaml:"topics"`
-	ClientID               string        `json:"client_id" yaml:"client_id"`
-	DynamicClientIDSuffix  string        `json:"dynamic_client_id_suffix" yaml:"dynamic_client_id_suffix"`
-	Will                   mqttconf.Will `json:"will" yaml:"will"`
-	CleanSession           bool          `json:"clean_session" yaml:"clean_session"`
-	User                   string        `json:"user" yaml:"user"`
-	Password               string        `json:"password" yaml:"password"`
-	ConnectTimeout         string        `json:"connect_timeout" yaml:"connect_timeout"`
-	StaleConnectionTimeout string        `json:"stale_connection_timeout" yaml:"stale_connection_timeout"`
-	KeepAlive              int64         `json:"keepalive" yaml:"keepalive"`
-	TLS                    tls.Config    `json:"tls" yaml:"tls"`
+	URLs                    []string      `json:"urls" yaml:"urls"`
+	ProtocolVersion         string        `json:"protocol_version" yaml:"protocol_version"`
+	QoS                     uint8         `json:"qos" yaml:"qos"`
+	Topics                  []string      `json:"topics" yaml:"topics"`
+	SharedSubscriptionGroup string        `json:"shared_subscription_group" yaml:"shared_subscription_group"`
+	ClientID                string        `json:"client_id" yaml:"client_id"`
+	DynamicClientIDSuffix   string        `json:"dynamic_client_id_suffix" yaml:"dynamic_client_id_suffix"`
+	Will                    mqttconf.Will `json:"will" yaml:"will"`
+	CleanSession            bool          `json:"clean_session" yaml:"clean_session"`
+	SessionExpiryInterval   int64         `json:"session_expiry_interval" yaml:"session_expiry_interval"`
+	User                    string        `json:"user" yaml:"user"`
+	Password                string        `json:"password" yaml:"password"`
+	ConnectTimeout          string        `json:"connect_timeout" yaml:"connect_timeout"`
+	StaleConnectionTimeout  string        `json:"stale_connection_timeout" yaml:"stale_connection_timeout"`
+	KeepAlive               int64         `json:"keepalive" yaml:"keepalive"`
+	TLS                     tls.Config    `json:"tls" yaml:"tls"`
 }
 
 // NewMQTTConfig creates a new MQTTConfig with default values.
 func NewMQTTConfig() MQTTConfig {
 	return MQTTConfig{
-		URLs:                   []string{"tcp://localhost:1883"},
-		QoS:                    1,
-		Topics:                 []string{"benthos_topic"},
-		ClientID:               "benthos_input",
-		Will:                   mqttconf.EmptyWill(),
-		CleanSession:           true,
-		User:                   "",
-		Password:               "",
-		ConnectTimeout:         "30s",
-		StaleConnectionTimeout: "",
-		KeepAlive:              30,
-		TLS:                    tls.NewConfig(),
+		URLs:                    []string{"tcp://localhost:1883"},
+		ProtocolVersion:         mqttconf.ProtocolVersion311,
+		QoS:                     1,
+		Topics:                  []string{"benthos_topic"},
+		SharedSubscriptionGroup: "",
+		ClientID:                "benthos_input",
+		Will:                    mqttconf.EmptyWill(),
+		CleanSession:            true,
+		SessionExpiryInterval:   0,
+		User:                    "",
+		Password:                "",
+		ConnectTimeout:          "30s",
+		StaleConnectionTimeout:  "",
+		KeepAlive:               30,
+		TLS:                     tls.NewConfig(),
 	}
 }
 
@@ -112,6 +118,7 @@ func NewMQTT(
 	if err := m.conf.Will.Validate(); err != nil {
 		return nil, err
 	}
+	m.conf.Topics = mqttconf.SharedTopics(m.conf.SharedSubscriptionGroup, m.conf.Topics)
 
 	for _, u := range conf.URLs {
 		for _, splitURL := range strings.Split(u, ",") {
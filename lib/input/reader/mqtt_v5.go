@@ -0,0 +1,313 @@
+package reader
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/mqttconf"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/eclipse/paho.golang/paho"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+//------------------------------------------------------------------------------
+
+// MQTTV5 is an input type that reads MQTT Pub/Sub messages using version 5.0
+// of the protocol, where each message is only acknowledged to the broker once
+// it has been delivered.
+type MQTTV5 struct {
+	client  *paho.Client
+	msgChan chan *paho.Publish
+	cMut    sync.Mutex
+
+	connectTimeout time.Duration
+	tlsConf        *tls.Config
+
+	conf MQTTConfig
+
+	interruptChan chan struct{}
+	interruptOnce sync.Once
+
+	urls []string
+
+	stats metrics.Type
+	log   log.Modular
+}
+
+// NewMQTTV5 creates a new MQTT input type that connects with version 5.0 of
+// the protocol.
+func NewMQTTV5(
+	conf MQTTConfig, log log.Modular, stats metrics.Type,
+) (*MQTTV5, error) {
+	m := &MQTTV5{
+		conf:          conf,
+		interruptChan: make(chan struct{}),
+		stats:         stats,
+		log:           log,
+	}
+
+	var err error
+	if m.connectTimeout, err = time.ParseDuration(conf.ConnectTimeout); err != nil {
+		return nil, fmt.Errorf("unable to parse connect timeout duration string: %w", err)
+	}
+	if conf.KeepAlive < 0 || conf.KeepAlive > 65535 {
+		return nil, fmt.Errorf("keepalive must be between 0 and 65535 seconds, got %v", conf.KeepAlive)
+	}
+	if conf.SessionExpiryInterval < 0 || conf.SessionExpiryInterval > 4294967295 {
+		return nil, fmt.Errorf("session_expiry_interval must be between 0 and 4294967295 seconds, got %v", conf.SessionExpiryInterval)
+	}
+
+	switch m.conf.DynamicClientIDSuffix {
+	case "nanoid":
+		nid, err := gonanoid.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate nanoid: %w", err)
+		}
+		m.conf.ClientID += nid
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown dynamic_client_id_suffix: %v", m.conf.DynamicClientIDSuffix)
+	}
+
+	if err := m.conf.Will.Validate(); err != nil {
+		return nil, err
+	}
+	m.conf.Topics = mqttconf.SharedTopics(m.conf.SharedSubscriptionGroup, m.conf.Topics)
+
+	if m.conf.TLS.Enabled {
+		if m.tlsConf, err = m.conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range conf.URLs {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				m.urls = append(m.urls, splitURL)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to an MQTT server.
+func (m *MQTTV5) ConnectWithContext(ctx context.Context) error {
+	m.cMut.Lock()
+	defer m.cMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	select {
+	case <-m.interruptChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	ctx, done := context.WithTimeout(ctx, m.connectTimeout)
+	defer done()
+
+	conn, err := mqttconf.Dial(ctx, m.urls, m.tlsConf)
+	if err != nil {
+		return err
+	}
+
+	var msgMut sync.Mutex
+	msgChan := make(chan *paho.Publish)
+
+	closeMsgChan := func() bool {
+		msgMut.Lock()
+		chanOpen := msgChan != nil
+		if chanOpen {
+			close(msgChan)
+			msgChan = nil
+		}
+		msgMut.Unlock()
+		return chanOpen
+	}
+
+	client := paho.NewClient(paho.ClientConfig{
+		Conn: conn,
+		Router: paho.NewSingleHandlerRouter(func(p *paho.Publish) {
+			msgMut.Lock()
+			if msgChan != nil {
+				select {
+				case msgChan <- p:
+				case <-m.interruptChan:
+				}
+			}
+			msgMut.Unlock()
+		}),
+		EnableManualAcknowledgment: true,
+		OnClientError: func(err error) {
+			if closeMsgChan() {
+				m.log.Errorf("Connection lost due to: %v\n", err)
+			}
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			if closeMsgChan() {
+				m.log.Errorf("Connection closed by server with reason code: %v\n", d.ReasonCode)
+			}
+		},
+	})
+
+	cp := &paho.Connect{
+		ClientID:   m.conf.ClientID,
+		KeepAlive:  uint16(m.conf.KeepAlive),
+		CleanStart: m.conf.CleanSession,
+	}
+	if m.conf.SessionExpiryInterval > 0 {
+		expiry := uint32(m.conf.SessionExpiryInterval)
+		cp.Properties = &paho.ConnectProperties{
+			SessionExpiryInterval: &expiry,
+		}
+	}
+	if m.conf.Will.Enabled {
+		cp.WillMessage = &paho.WillMessage{
+			Retain:  m.conf.Will.Retained,
+			QoS:     m.conf.Will.QoS,
+			Topic:   m.conf.Will.Topic,
+			Payload: []byte(m.conf.Will.Payload),
+		}
+	}
+	if m.conf.User != "" {
+		cp.Username = m.conf.User
+		cp.UsernameFlag = true
+	}
+	if m.conf.Password != "" {
+		cp.Password = []byte(m.conf.Password)
+		cp.PasswordFlag = true
+	}
+
+	connack, err := client.Connect(ctx, cp)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if connack.ReasonCode >= 0x80 {
+		conn.Close()
+		return fmt.Errorf("connection refused with reason code: %v", connack.ReasonCode)
+	}
+
+	subs := make([]paho.SubscribeOptions, 0, len(m.conf.Topics))
+	for _, topic := range m.conf.Topics {
+		subs = append(subs, paho.SubscribeOptions{
+			Topic: topic,
+			QoS:   m.conf.QoS,
+		})
+	}
+	suback, err := client.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: subs,
+	})
+	if err == nil {
+		for i, reason := range suback.Reasons {
+			if reason >= 0x80 && i < len(m.conf.Topics) {
+				err = fmt.Errorf("subscription to topic '%v' refused with reason code: %v", m.conf.Topics[i], reason)
+				break
+			}
+		}
+	}
+	if err != nil {
+		_ = client.Disconnect(&paho.Disconnect{})
+		conn.Close()
+		return fmt.Errorf("failed to subscribe to topics '%v': %w", m.conf.Topics, err)
+	}
+
+	if connack.SessionPresent {
+		m.log.Infof("Resumed MQTT session, receiving messages from topics: %v\n", m.conf.Topics)
+	} else {
+		m.log.Infof("Receiving MQTT messages from topics: %v\n", m.conf.Topics)
+	}
+
+	m.client = client
+	m.msgChan = msgChan
+	return nil
+}
+
+// ReadWithContext attempts to read a new message from an MQTT broker.
+func (m *MQTTV5) ReadWithContext(ctx context.Context) (types.Message, AsyncAckFn, error) {
+	m.cMut.Lock()
+	client, msgChan := m.client, m.msgChan
+	m.cMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	select {
+	case pub, open := <-msgChan:
+		if !open {
+			m.cMut.Lock()
+			if m.client == client {
+				m.msgChan = nil
+				m.client = nil
+			}
+			m.cMut.Unlock()
+			return nil, nil, types.ErrNotConnected
+		}
+
+		msg := message.New([][]byte{pub.Payload})
+
+		meta := msg.Get(0).Metadata()
+		if pub.Properties != nil {
+			for _, prop := range pub.Properties.User {
+				if meta.Get(prop.Key) == "" {
+					meta.Set(prop.Key, prop.Value)
+				}
+			}
+		}
+		meta.Set("mqtt_qos", strconv.Itoa(int(pub.QoS)))
+		meta.Set("mqtt_retained", strconv.FormatBool(pub.Retain))
+		meta.Set("mqtt_topic", pub.Topic)
+		meta.Set("mqtt_message_id", strconv.Itoa(int(pub.PacketID)))
+
+		return msg, func(ctx context.Context, res types.Response) error {
+			// Messages that are not acknowledged are redelivered by the broker
+			// when the session is resumed.
+			if res.Error() != nil || pub.QoS == 0 {
+				return nil
+			}
+			if err := client.Ack(pub); err != nil {
+				return fmt.Errorf("failed to acknowledge message: %w", err)
+			}
+			return nil
+		}, nil
+	case <-ctx.Done():
+	case <-m.interruptChan:
+		return nil, nil, types.ErrTypeClosed
+	}
+	return nil, nil, types.ErrTimeout
+}
+
+// CloseAsync shuts down the MQTT input and stops processing requests.
+func (m *MQTTV5) CloseAsync() {
+	m.interruptOnce.Do(func() {
+		close(m.interruptChan)
+	})
+	m.cMut.Lock()
+	if m.client != nil {
+		_ = m.client.Disconnect(&paho.Disconnect{})
+		m.client = nil
+		m.msgChan = nil
+	}
+	m.cMut.Unlock()
+}
+
+// WaitForClose blocks until the MQTT input has closed down.
+func (m *MQTTV5) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -5,7 +5,7 @@ import (
 	"sync"
 	"time"
 
-	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis"
+	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis/old"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -3,37 +3,73 @@ package reader
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/gorilla/websocket"
 )
 
 //------------------------------------------------------------------------------
 
+// WebsocketPingConfig contains configuration fields for sending pings to a
+// websocket server in order to detect lost connections.
+type WebsocketPingConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Period  string `json:"period" yaml:"period"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// NewWebsocketPingConfig creates a new WebsocketPingConfig with default values.
+func NewWebsocketPingConfig() WebsocketPingConfig {
+	return WebsocketPingConfig{
+		Enabled: false,
+		Period:  "30s",
+		Timeout: "10s",
+	}
+}
+
 // WebsocketConfig contains configuration fields for the Websocket input type.
 type WebsocketConfig struct {
-	URL         string `json:"url" yaml:"url"`
-	OpenMsg     string `json:"open_message" yaml:"open_message"`
-	auth.Config `json:",inline" yaml:",inline"`
-	TLS         btls.Config `json:"tls" yaml:"tls"`
+	URL          string              `json:"url" yaml:"url"`
+	OpenMsg      string              `json:"open_message" yaml:"open_message"`
+	OpenMsgs     []string            `json:"open_messages" yaml:"open_messages"`
+	OpenMsgType  string              `json:"open_message_type" yaml:"open_message_type"`
+	Subprotocols []string            `json:"subprotocols" yaml:"subprotocols"`
+	Ping         WebsocketPingConfig `json:"ping" yaml:"ping"`
+	Reconnect    retries.Config      `json:"reconnect" yaml:"reconnect"`
+	auth.Config  `json:",inline" yaml:",inline"`
+	TLS          btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
 func NewWebsocketConfig() WebsocketConfig {
+	rConf := retries.NewConfig()
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "30s"
 	return WebsocketConfig{
-		URL:     "ws://localhost:4195/get/ws",
-		OpenMsg: "",
-		Config:  auth.NewConfig(),
-		TLS:     btls.NewConfig(),
+		URL:          "ws://localhost:4195/get/ws",
+		OpenMsg:      "",
+		OpenMsgs:     []string{},
+		OpenMsgType:  "binary",
+		Subprotocols: []string{},
+		Ping:         NewWebsocketPingConfig(),
+		Reconnect:    rConf,
+		Config:       auth.NewConfig(),
+		TLS:          btls.NewConfig(),
 	}
 }
 
@@ -49,13 +85,35 @@ type Websocket struct {
 	conf    WebsocketConfig
 	client  *websocket.Conn
 	tlsConf *tls.Config
+
+	openMsgs    []*field.Expression
+	openMsgType int
+
+	pingPeriod  time.Duration
+	pingTimeout time.Duration
+	pingStop    chan struct{}
+
+	reconnectBoff backoff.BackOff
+	reconnecting  bool
 }
 
 // NewWebsocket creates a new Websocket input type.
+//
+// Deprecated: use the V2 API instead.
 func NewWebsocket(
 	conf WebsocketConfig,
 	log log.Modular,
 	stats metrics.Type,
+) (*Websocket, error) {
+	return NewWebsocketV2(conf, types.NoopMgr(), log, stats)
+}
+
+// NewWebsocketV2 creates a new Websocket input type.
+func NewWebsocketV2(
+	conf WebsocketConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
 ) (*Websocket, error) {
 	ws := &Websocket{
 		log:   log,
@@ -69,6 +127,40 @@ func NewWebsocket(
 			return nil, err
 		}
 	}
+
+	switch conf.OpenMsgType {
+	case "binary", "":
+		ws.openMsgType = websocket.BinaryMessage
+	case "text":
+		ws.openMsgType = websocket.TextMessage
+	default:
+		return nil, fmt.Errorf("unrecognised open_message_type: %v", conf.OpenMsgType)
+	}
+	for i, msg := range conf.OpenMsgs {
+		openMsg, err := interop.NewBloblangField(mgr, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse open message %v expression: %v", i, err)
+		}
+		ws.openMsgs = append(ws.openMsgs, openMsg)
+	}
+
+	if conf.Ping.Enabled {
+		var err error
+		if ws.pingPeriod, err = time.ParseDuration(conf.Ping.Period); err != nil {
+			return nil, fmt.Errorf("failed to parse ping period: %v", err)
+		}
+		if ws.pingPeriod <= 0 {
+			return nil, errors.New("ping period must be greater than zero")
+		}
+		if ws.pingTimeout, err = time.ParseDuration(conf.Ping.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse ping timeout: %v", err)
+		}
+	}
+
+	var err error
+	if ws.reconnectBoff, err = conf.Reconnect.Get(); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect config: %v", err)
+	}
 	return ws, nil
 }
 
@@ -81,6 +173,19 @@ func (w *Websocket) getWS() *websocket.Conn {
 	return ws
 }
 
+// dropWS closes the current connection, and must be called whilst the lock is
+// held.
+func (w *Websocket) dropWS() {
+	if w.client != nil {
+		w.client.Close()
+		w.client = nil
+	}
+	if w.pingStop != nil {
+		close(w.pingStop)
+		w.pingStop = nil
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a connection to a Websocket server.
@@ -97,6 +202,20 @@ func (w *Websocket) ConnectWithContext(ctx context.Context) error {
 		return nil
 	}
 
+	if w.reconnecting {
+		wait := w.reconnectBoff.NextBackOff()
+		if wait == backoff.Stop {
+			w.log.Errorf("Giving up on reconnecting to websocket server: %v\n", w.conf.URL)
+			return types.ErrTypeClosed
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	w.reconnecting = true
+
 	headers := http.Header{}
 
 	purl, err := url.Parse(w.conf.URL)
@@ -110,19 +229,42 @@ func (w *Websocket) ConnectWithContext(ctx context.Context) error {
 	}); err != nil {
 		return err
 	}
-	var client *websocket.Conn
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		Subprotocols:     w.conf.Subprotocols,
+	}
 	if w.conf.TLS.Enabled {
-		dialer := websocket.Dialer{
-			TLSClientConfig: w.tlsConf,
-		}
-		if client, _, err = dialer.Dial(w.conf.URL, headers); err != nil {
-			return err
+		dialer.TLSClientConfig = w.tlsConf
+	}
+	client, _, err := dialer.DialContext(ctx, w.conf.URL, headers)
+	if err != nil {
+		return err
+	}
 
-		}
-	} else if client, _, err = websocket.DefaultDialer.Dial(w.conf.URL, headers); err != nil {
+	if err := w.sendOpenMessages(client); err != nil {
+		client.Close()
 		return err
 	}
 
+	if w.pingPeriod > 0 {
+		deadline := w.pingPeriod + w.pingTimeout
+		_ = client.SetReadDeadline(time.Now().Add(deadline))
+		client.SetPongHandler(func(string) error {
+			return client.SetReadDeadline(time.Now().Add(deadline))
+		})
+		w.pingStop = make(chan struct{})
+		go w.pingLoop(client, w.pingStop)
+	}
+
+	w.reconnectBoff.Reset()
+	w.client = client
+	w.log.Infof("Receiving websocket messages from: %v\n", w.conf.URL)
+	return nil
+}
+
+func (w *Websocket) sendOpenMessages(client *websocket.Conn) error {
 	if len(w.conf.OpenMsg) > 0 {
 		if err := client.WriteMessage(
 			websocket.BinaryMessage, []byte(w.conf.OpenMsg),
@@ -130,11 +272,37 @@ func (w *Websocket) ConnectWithContext(ctx context.Context) error {
 			return err
 		}
 	}
-
-	w.client = client
+	refMsg := message.New(nil)
+	for i, openMsg := range w.openMsgs {
+		if err := client.WriteMessage(
+			w.openMsgType, openMsg.Bytes(0, refMsg),
+		); err != nil {
+			return fmt.Errorf("failed to send open message %v: %w", i, err)
+		}
+	}
 	return nil
 }
 
+// pingLoop periodically sends pings to the server until the connection is
+// dropped, where a missing pong causes the next read to fail.
+func (w *Websocket) pingLoop(client *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := client.WriteControl(
+				websocket.PingMessage, nil, time.Now().Add(w.pingTimeout),
+			); err != nil {
+				w.log.Debugf("Failed to send ping: %v\n", err)
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Read attempts to read a new message from the websocket.
@@ -153,11 +321,16 @@ func (w *Websocket) ReadWithContext(ctx context.Context) (types.Message, AsyncAc
 	_, data, err := client.ReadMessage()
 	if err != nil {
 		w.lock.Lock()
-		w.client = nil
+		if w.client == client {
+			w.dropWS()
+		}
 		w.lock.Unlock()
 		err = types.ErrNotConnected
 		return nil, nil, err
 	}
+	if w.pingPeriod > 0 {
+		_ = client.SetReadDeadline(time.Now().Add(w.pingPeriod + w.pingTimeout))
+	}
 
 	return message.New([][]byte{data}), noopAsyncAckFn, nil
 }
@@ -171,10 +344,7 @@ func (w *Websocket) Acknowledge(err error) error {
 // CloseAsync shuts down the Websocket input and stops reading messages.
 func (w *Websocket) CloseAsync() {
 	w.lock.Lock()
-	if w.client != nil {
-		w.client.Close()
-		w.client = nil
-	}
+	w.dropWS()
 	w.lock.Unlock()
 }
 
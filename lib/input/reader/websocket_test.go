@@ -1,10 +1,12 @@
 package reader
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -192,3 +194,165 @@ func TestWebsocketClose(t *testing.T) {
 	wg.Wait()
 	close(closeChan)
 }
+
+func TestWebsocketOpenMsgsReconnect(t *testing.T) {
+	var connCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{
+			Subprotocols: []string{"bar"},
+		}
+
+		var ws *websocket.Conn
+		var err error
+		if ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+			return
+		}
+		defer ws.Close()
+
+		if exp, act := "bar", ws.Subprotocol(); exp != act {
+			t.Errorf("Wrong subprotocol: %v != %v", act, exp)
+		}
+
+		for _, exp := range []string{"hello", `{"subscribe":"foo"}`} {
+			msgType, data, err := ws.ReadMessage()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if msgType != websocket.TextMessage {
+				t.Errorf("Wrong open message type: %v", msgType)
+			}
+			if act := string(data); exp != act {
+				t.Errorf("Wrong open message: %v != %v", act, exp)
+			}
+		}
+
+		// Close the connection after each message in order to force a
+		// reconnect.
+		count := atomic.AddInt32(&connCount, 1)
+		if err = ws.WriteMessage(websocket.BinaryMessage, []byte(fmt.Sprintf("msg%v", count))); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	conf := NewWebsocketConfig()
+	conf.OpenMsgs = []string{"hello", `{"subscribe":"${! "foo" }"}`}
+	conf.OpenMsgType = "text"
+	conf.Subprotocols = []string{"foo", "bar"}
+	conf.Reconnect.Backoff.InitialInterval = "1ms"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocketV2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"msg1", "msg2"} {
+		if err = m.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		var actMsg types.Message
+		if actMsg, err = m.Read(); err != nil {
+			t.Fatal(err)
+		}
+		if act := string(actMsg.Get(0).Get()); act != exp {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+		if _, err = m.Read(); err != types.ErrNotConnected {
+			t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+		}
+	}
+
+	m.CloseAsync()
+	if err = m.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebsocketReconnectGiveUp(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.URL = "ws://localhost:1/nope"
+	conf.Reconnect.MaxRetries = 2
+	conf.Reconnect.Backoff.InitialInterval = "1ms"
+	conf.Reconnect.Backoff.MaxInterval = "1ms"
+
+	m, err := NewWebsocketV2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = m.Connect(); err == nil || err == types.ErrTypeClosed {
+			t.Fatalf("Expected connection error, got: %v", err)
+		}
+	}
+	if err = m.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestWebsocketPingTimeout(t *testing.T) {
+	closeChan := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+
+		var ws *websocket.Conn
+		var err error
+		if ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+			return
+		}
+		defer ws.Close()
+
+		// Pongs are only sent when reading from the connection, so the pings
+		// of the client are left unanswered.
+		<-closeChan
+	}))
+	defer server.Close()
+	defer close(closeChan)
+
+	conf := NewWebsocketConfig()
+	conf.Ping.Enabled = true
+	conf.Ping.Period = "10ms"
+	conf.Ping.Timeout = "10ms"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocketV2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, rErr := m.Read()
+		errChan <- rErr
+	}()
+
+	select {
+	case err = <-errChan:
+		if err != types.ErrNotConnected {
+			t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for lost connection")
+	}
+
+	m.CloseAsync()
+	if err = m.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,7 +2,7 @@ package input
 
 import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
-	"github.com/Jeffail/benthos/v3/internal/impl/redis"
+	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis/old"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -28,7 +28,7 @@ patterns in your channel names. For example:
 
 Use ` + "`\\`" + ` to escape special characters if you want to match them
 verbatim.`,
-		FieldSpecs: redis.ConfigDocs().Add(
+		FieldSpecs: bredis.ConfigDocs().Add(
 			docs.FieldCommon("channels", "A list of channels to consume from.").Array(),
 			docs.FieldCommon("use_patterns", "Whether to use the PSUBSCRIBE command."),
 		),
@@ -2,7 +2,7 @@ package input
 
 import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
-	"github.com/Jeffail/benthos/v3/internal/impl/redis"
+	bredis "github.com/Jeffail/benthos/v3/internal/impl/redis/old"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
@@ -22,7 +22,7 @@ Pulls messages from Redis (v5.0+) streams with the XREADGROUP command. The
 Redis stream entries are key/value pairs, as such it is necessary to specify the
 key that contains the body of the message. All other keys/value pairs are saved
 as metadata fields.`,
-		FieldSpecs: redis.ConfigDocs().Add(
+		FieldSpecs: bredis.ConfigDocs().Add(
 			func() docs.FieldSpec {
 				b := batch.FieldSpec()
 				b.IsDeprecated = true
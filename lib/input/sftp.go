@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"sync"
 	"time"
 
@@ -83,6 +84,7 @@ You can access these metadata fields using [function interpolation](/docs/config
 			).Array(),
 			codec.ReaderDocs,
 			docs.FieldAdvanced("delete_on_finish", "Whether to delete files from the server once they are processed."),
+			docs.FieldAdvanced("move_on_finish", "An optional directory on the server to move files into once they are processed, where each file keeps its name. This field cannot be used together with `delete_on_finish`.", "/processed").AtVersion("3.64.0"),
 			docs.FieldAdvanced("max_buffer", "The largest token size expected when consuming delimited files."),
 			docs.FieldCommon(
 				"watcher",
@@ -111,6 +113,7 @@ type SFTPConfig struct {
 	Paths          []string              `json:"paths" yaml:"paths"`
 	Codec          string                `json:"codec" yaml:"codec"`
 	DeleteOnFinish bool                  `json:"delete_on_finish" yaml:"delete_on_finish"`
+	MoveOnFinish   string                `json:"move_on_finish" yaml:"move_on_finish"`
 	MaxBuffer      int                   `json:"max_buffer" yaml:"max_buffer"`
 	Watcher        watcherConfig         `json:"watcher" yaml:"watcher"`
 }
@@ -123,6 +126,7 @@ func NewSFTPConfig() SFTPConfig {
 		Paths:          []string{},
 		Codec:          "all-bytes",
 		DeleteOnFinish: false,
+		MoveOnFinish:   "",
 		MaxBuffer:      1000000,
 		Watcher: watcherConfig{
 			Enabled:      false,
@@ -163,6 +167,10 @@ func newSFTPReader(conf SFTPConfig, mgr types.Manager, log log.Modular, stats me
 		return nil, err
 	}
 
+	if conf.DeleteOnFinish && conf.MoveOnFinish != "" {
+		return nil, errors.New("cannot specify both delete_on_finish and move_on_finish")
+	}
+
 	var watcherPollInterval, watcherMinAge time.Duration
 	if conf.Watcher.Enabled {
 		if watcherPollInterval, err = time.ParseDuration(conf.Watcher.PollInterval); err != nil {
@@ -241,9 +249,18 @@ func (s *sftpReader) ConnectWithContext(ctx context.Context) error {
 	}
 
 	if s.scanner, err = s.scannerCtor(nextPath, file, func(ctx context.Context, err error) error {
-		if err == nil && s.conf.DeleteOnFinish {
+		if err != nil {
+			return nil
+		}
+		if s.conf.DeleteOnFinish {
 			return s.client.Remove(nextPath)
 		}
+		if s.conf.MoveOnFinish != "" {
+			if err := s.client.MkdirAll(s.conf.MoveOnFinish); err != nil {
+				return fmt.Errorf("failed to create directory %v: %w", s.conf.MoveOnFinish, err)
+			}
+			return s.client.PosixRename(nextPath, path.Join(s.conf.MoveOnFinish, path.Base(nextPath)))
+		}
 		return nil
 	}); err != nil {
 		file.Close()
@@ -0,0 +1,377 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/http"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/client"
+)
+
+func init() {
+	Constructors[TypeSSE] = TypeSpec{
+		constructor: fromSimpleConstructor(NewSSE),
+		Status:      docs.StatusExperimental,
+		Version:     "3.64.0",
+		Summary: `
+Connects to a server that emits [Server-Sent Events](https://html.spec.whatwg.org/multipage/server-sent-events.html) and creates a message for each event received.`,
+		Description: `
+The connection to the server is kept open and each event received is converted into a message, where the data of the event is the contents of the message. When the connection is lost it is re-established after ` + "`reconnect_period`" + `, or after the reconnection time sent by the server with a ` + "`retry`" + ` field, and the ID of the last event received is sent to the server with the ` + "`Last-Event-ID`" + ` header so that it can resume the stream from where it left off. If the server responds with a 204 No Content status then the input is closed.
+
+Authentication and request headers are configured in the same way as the ` + "[`http_client` input](/docs/components/inputs/http_client)" + `, where the ` + "`url` and `headers`" + ` fields support interpolation functions. The ` + "`Accept`" + ` header is set to ` + "`text/event-stream`" + ` unless it is specified within ` + "`headers`" + `.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- sse_event
+- sse_id
+` + "```" + `
+
+The field ` + "`sse_event`" + ` is the type of the event, which is ` + "`message`" + ` when the server does not specify one, and ` + "`sse_id`" + ` is the last event ID set by the server, which is only added when the server has sent one.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+		config: client.FieldSpec(
+			docs.FieldAdvanced("last_event_id", "An optional event ID to send with the `Last-Event-ID` header of the first connection, which allows consumption to resume from a known event."),
+			docs.FieldAdvanced("reconnect_period", "The period to wait before reconnecting once a connection is lost, unless the server has specified a reconnection time with a `retry` field."),
+			docs.FieldAdvanced("max_buffer", "The maximum size of a line of the event stream, which must be larger than the largest line of data expected."),
+		),
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SSEConfig contains configuration for the SSE input type.
+type SSEConfig struct {
+	client.Config   `json:",inline" yaml:",inline"`
+	LastEventID     string `json:"last_event_id" yaml:"last_event_id"`
+	ReconnectPeriod string `json:"reconnect_period" yaml:"reconnect_period"`
+	MaxBuffer       int    `json:"max_buffer" yaml:"max_buffer"`
+}
+
+// NewSSEConfig creates a new SSEConfig with default values.
+func NewSSEConfig() SSEConfig {
+	cConf := client.NewConfig()
+	cConf.Verb = "GET"
+	cConf.URL = "http://localhost:4195/events"
+	return SSEConfig{
+		Config:          cConf,
+		LastEventID:     "",
+		ReconnectPeriod: "3s",
+		MaxBuffer:       1000000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSSE creates a new SSE input type.
+func NewSSE(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	rdr, err := newSSEReader(conf.SSE, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsyncReader(TypeSSE, true, reader.NewAsyncPreserver(rdr), log, stats)
+}
+
+type sseReader struct {
+	conf SSEConfig
+	log  log.Modular
+
+	client          *http.Client
+	reconnectPeriod time.Duration
+
+	// Requests outlive the context of a connection attempt as the body of the
+	// response is consumed until the connection is lost.
+	reqCtx  context.Context
+	reqDone func()
+
+	connMut     sync.Mutex
+	body        io.ReadCloser
+	parser      *sseParser
+	reconnected bool
+}
+
+func newSSEReader(conf SSEConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*sseReader, error) {
+	// Timeout should be left at zero as the response body is streamed.
+	conf.Timeout = ""
+
+	s := &sseReader{
+		conf:   conf,
+		log:    log,
+		parser: newSSEParser(conf.LastEventID),
+	}
+	if conf.ReconnectPeriod != "" {
+		var err error
+		if s.reconnectPeriod, err = time.ParseDuration(conf.ReconnectPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse reconnect period: %v", err)
+		}
+	}
+	if conf.MaxBuffer <= 0 {
+		return nil, errors.New("max_buffer must be larger than zero")
+	}
+
+	cMgr, cLog, cStats := interop.LabelChild("client", mgr, log, stats)
+	var err error
+	if s.client, err = http.NewClient(
+		conf.Config,
+		http.OptSetManager(cMgr),
+		http.OptSetLogger(cLog),
+		http.OptSetStats(cStats),
+		http.OptSetRequestHook(s.prepareRequest),
+	); err != nil {
+		return nil, err
+	}
+
+	s.reqCtx, s.reqDone = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// prepareRequest adds the headers of the event stream protocol to a request,
+// and is called whilst connMut is held.
+func (s *sseReader) prepareRequest(req *nethttp.Request) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	if id := s.parser.lastEventID; id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+}
+
+// ConnectWithContext establishes a connection to the event stream.
+func (s *sseReader) ConnectWithContext(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.body != nil {
+		return nil
+	}
+
+	if s.reconnected {
+		period := s.reconnectPeriod
+		if s.parser.retry > 0 {
+			period = s.parser.retry
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	res, err := s.client.SendToResponse(s.reqCtx, nil, message.New(nil))
+	if err != nil {
+		if strings.Contains(err.Error(), "(Client.Timeout exceeded while awaiting headers)") {
+			err = types.ErrTimeout
+		}
+		return err
+	}
+	s.reconnected = true
+
+	if res.StatusCode == nethttp.StatusNoContent {
+		res.Body.Close()
+		s.log.Infoln("Server responded with no content, closing event stream")
+		return types.ErrTypeClosed
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		res.Body.Close()
+		return fmt.Errorf("unexpected content type of event stream: %v", res.Header.Get("Content-Type"))
+	}
+
+	s.body = res.Body
+	s.parser.reset(res.Body, s.conf.MaxBuffer)
+
+	s.log.Infof("Receiving events from: %v\n", s.conf.URL)
+	return nil
+}
+
+// ReadWithContext reads the next event of the stream as a message.
+func (s *sseReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.body == nil {
+		return nil, nil, types.ErrNotConnected
+	}
+
+	event, err := s.parser.next()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.log.Errorf("Lost connection to event stream: %v\n", err)
+		}
+		s.body.Close()
+		s.body = nil
+		return nil, nil, types.ErrNotConnected
+	}
+
+	part := message.NewPart(event.data)
+	part.Metadata().Set("sse_event", event.event)
+	if event.id != "" {
+		part.Metadata().Set("sse_id", event.id)
+	}
+	msg := message.New(nil)
+	msg.Append(part)
+
+	return msg, func(context.Context, types.Response) error {
+		return nil
+	}, nil
+}
+
+// CloseAsync shuts down the input and stops processing requests.
+func (s *sseReader) CloseAsync() {
+	s.reqDone()
+	go func() {
+		s.connMut.Lock()
+		if s.body != nil {
+			s.body.Close()
+			s.body = nil
+		}
+		s.connMut.Unlock()
+		_ = s.client.Close(context.Background())
+	}()
+}
+
+// WaitForClose blocks until the input has closed down.
+func (s *sseReader) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type sseEvent struct {
+	id    string
+	event string
+	data  []byte
+}
+
+// sseParser parses events from an event stream following
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation,
+// where the last event ID and reconnection time are kept across connections.
+type sseParser struct {
+	scanner     *bufio.Scanner
+	started     bool
+	lastEventID string
+	retry       time.Duration
+}
+
+func newSSEParser(lastEventID string) *sseParser {
+	return &sseParser{lastEventID: lastEventID}
+}
+
+func (p *sseParser) reset(r io.Reader, maxBuffer int) {
+	p.scanner = bufio.NewScanner(r)
+	p.scanner.Buffer(nil, maxBuffer)
+	p.scanner.Split(sseScanLines)
+	p.started = false
+}
+
+var sseBOM = []byte("\xEF\xBB\xBF")
+
+// next returns the next event of the stream, or io.EOF once the stream ends.
+// An event that is incomplete when the stream ends is discarded.
+func (p *sseParser) next() (*sseEvent, error) {
+	var eventType string
+	var data bytes.Buffer
+	var hasData bool
+
+	for p.scanner.Scan() {
+		line := p.scanner.Bytes()
+		if !p.started {
+			line = bytes.TrimPrefix(line, sseBOM)
+			p.started = true
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return &sseEvent{
+				id:    p.lastEventID,
+				event: eventType,
+				data:  bytes.TrimSuffix(data.Bytes(), []byte("\n")),
+			}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		name, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			name, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+
+		switch string(name) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			data.Write(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) == -1 {
+				p.lastEventID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// sseScanLines is a bufio.SplitFunc that splits lines ending with either a
+// CRLF, a LF or a CR.
+func sseScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// A CR at the end of the buffer could be followed by a LF.
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,187 @@
+package input
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEParser(t *testing.T) {
+	type event struct {
+		id, event, data string
+	}
+
+	tests := map[string]struct {
+		input  string
+		events []event
+		retry  time.Duration
+	}{
+		"single data": {
+			input: "data: foo\n\n",
+			events: []event{
+				{event: "message", data: "foo"},
+			},
+		},
+		"multiple data lines": {
+			input: "data: foo\ndata:bar\ndata\n\n",
+			events: []event{
+				{event: "message", data: "foo\nbar\n"},
+			},
+		},
+		"event types and ids": {
+			input: "event: add\nid: 1\ndata: foo\n\ndata: bar\n\nid\nevent: remove\ndata: baz\n\n",
+			events: []event{
+				{id: "1", event: "add", data: "foo"},
+				{id: "1", event: "message", data: "bar"},
+				{event: "remove", data: "baz"},
+			},
+		},
+		"comments and unknown fields": {
+			input: ": keep alive\nfoo: bar\ndata:  spaced\n\n",
+			events: []event{
+				{event: "message", data: " spaced"},
+			},
+		},
+		"no data is not dispatched": {
+			input: "event: nope\n\ndata: foo\n\n",
+			events: []event{
+				{event: "message", data: "foo"},
+			},
+		},
+		"line endings and bom": {
+			input: "\xEF\xBB\xBFdata: foo\r\n\r\ndata: bar\r\rdata: baz\n\n",
+			events: []event{
+				{event: "message", data: "foo"},
+				{event: "message", data: "bar"},
+				{event: "message", data: "baz"},
+			},
+		},
+		"incomplete event discarded": {
+			input: "data: foo\n\ndata: bar\n",
+			events: []event{
+				{event: "message", data: "foo"},
+			},
+		},
+		"retry": {
+			input: "retry: 100\nretry: nope\ndata: foo\n\n",
+			events: []event{
+				{event: "message", data: "foo"},
+			},
+			retry: 100 * time.Millisecond,
+		},
+		"id with null ignored": {
+			input: "id: 1\ndata: foo\n\nid: 2\x003\ndata: bar\n\n",
+			events: []event{
+				{id: "1", event: "message", data: "foo"},
+				{id: "1", event: "message", data: "bar"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := newSSEParser("")
+			p.reset(strings.NewReader(test.input), 1024)
+
+			var events []event
+			for {
+				e, err := p.next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				events = append(events, event{id: e.id, event: e.event, data: string(e.data)})
+			}
+			assert.Equal(t, test.events, events)
+			assert.Equal(t, test.retry, p.retry)
+		})
+	}
+}
+
+func TestSSEReconnect(t *testing.T) {
+	var idsMut sync.Mutex
+	var lastIDs []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		assert.Equal(t, "bar", r.Header.Get("X-Foo"))
+
+		idsMut.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		count := len(lastIDs)
+		idsMut.Unlock()
+
+		if count > 2 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		if count == 1 {
+			w.Write([]byte("retry: 1\nevent: first\nid: a\ndata: foo\n\n"))
+		} else {
+			w.Write([]byte("data: bar\n\n"))
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.SSE.URL = ts.URL
+	conf.SSE.Headers["X-Foo"] = "bar"
+	conf.SSE.ReconnectPeriod = "1h"
+
+	s, err := NewSSE(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	expected := []struct {
+		data, event, id string
+	}{
+		{data: "foo", event: "first", id: "a"},
+		{data: "bar", event: "message", id: "a"},
+	}
+	for _, exp := range expected {
+		var tr types.Transaction
+		select {
+		case tr = <-s.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		require.Equal(t, 1, tr.Payload.Len())
+		part := tr.Payload.Get(0)
+		assert.Equal(t, exp.data, string(part.Get()))
+		assert.Equal(t, exp.event, part.Metadata().Get("sse_event"))
+		assert.Equal(t, exp.id, part.Metadata().Get("sse_id"))
+
+		select {
+		case tr.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+	}
+
+	// The input closes once the server responds with no content
+	select {
+	case _, open := <-s.TransactionChan():
+		assert.False(t, open)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	idsMut.Lock()
+	assert.Equal(t, []string{"", "a", "a"}, lastIDs)
+	idsMut.Unlock()
+
+	s.CloseAsync()
+	require.NoError(t, s.WaitForClose(time.Second*5))
+}
@@ -20,10 +20,45 @@ Connects to a websocket server and continuously receives messages.`,
 		Description: `
 It is possible to configure an ` + "`open_message`" + `, which when set to a
 non-empty string will be sent to the websocket server each time a connection is
-first established.`,
+first established. For servers that expect a sequence of messages, such as
+subscription requests, the field ` + "`open_messages`" + ` can be used instead,
+where each message supports
+[interpolation functions](/docs/configuration/interpolation#bloblang-queries)
+and is sent in order after ` + "`open_message`" + `.
+
+### Reconnecting
+
+When a connection is lost the input reconnects to the server, waiting between
+attempts following the backoff of the ` + "`reconnect`" + ` field, and sends the
+open messages again once reconnected. In order to detect connections that are
+lost silently it is possible to enable ` + "`ping`" + `, in which case pings are
+sent to the server periodically and the connection is considered lost when
+neither a pong nor a message has been received within the timeout.`,
 		FieldSpecs: append(docs.FieldSpecs{
 			docs.FieldCommon("url", "The URL to connect to.", "ws://localhost:4195/get/ws").HasType("string"),
 			docs.FieldAdvanced("open_message", "An optional message to send to the server upon connection."),
+			docs.FieldString(
+				"open_messages", "A list of messages to send to the server in order upon connection, after `open_message` if set.",
+				[]string{
+					`{"type":"subscribe","channels":["ticker"]}`,
+					`{"type":"auth","nonce":"${! uuid_v4() }"}`,
+				},
+			).IsInterpolated().Array().Advanced().AtVersion("3.64.0"),
+			docs.FieldAdvanced("open_message_type", "The type of websocket message to send the messages of `open_messages` as.").HasOptions("binary", "text").AtVersion("3.64.0"),
+			docs.FieldString("subprotocols", "A list of subprotocols to request from the server in order of preference, where the server selects one of them.", []string{"graphql-transport-ws"}).Array().Advanced().AtVersion("3.64.0"),
+			docs.FieldAdvanced("ping", "Send pings to the server periodically in order to detect lost connections.").WithChildren(
+				docs.FieldAdvanced("enabled", "Whether to send pings to the server."),
+				docs.FieldAdvanced("period", "The period between pings."),
+				docs.FieldAdvanced("timeout", "The maximum period after a ping is due to wait for a pong or any other message from the server before the connection is considered lost."),
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("reconnect", "Control time intervals between attempts to reconnect to the server.").WithChildren(
+				docs.FieldAdvanced("max_retries", "The maximum number of consecutive failed reconnection attempts before the input is closed. If set to zero there is no discrete limit."),
+				docs.FieldAdvanced("backoff", "Control time intervals between reconnection attempts.").WithChildren(
+					docs.FieldAdvanced("initial_interval", "The initial period to wait between reconnection attempts."),
+					docs.FieldAdvanced("max_interval", "The maximum period to wait between reconnection attempts."),
+					docs.FieldAdvanced("max_elapsed_time", "The maximum period to spend reconnecting before the input is closed. If zero then no limit is used."),
+				),
+			).AtVersion("3.64.0"),
 			btls.FieldSpec(),
 		}, auth.FieldSpecs()...),
 		Categories: []Category{
@@ -36,7 +71,7 @@ first established.`,
 
 // NewWebsocket creates a new Websocket input type.
 func NewWebsocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	ws, err := reader.NewWebsocket(conf.Websocket, log, stats)
+	ws, err := reader.NewWebsocketV2(conf.Websocket, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
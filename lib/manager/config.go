@@ -23,6 +23,9 @@ type ResourceConfig struct {
 	ResourceOutputs    []output.Config    `json:"output_resources,omitempty" yaml:"output_resources,omitempty"`
 	ResourceCaches     []cache.Config     `json:"cache_resources,omitempty" yaml:"cache_resources,omitempty"`
 	ResourceRateLimits []ratelimit.Config `json:"rate_limit_resources,omitempty" yaml:"rate_limit_resources,omitempty"`
+	ResourcePlugins    []PluginConfig     `json:"plugin_resources,omitempty" yaml:"plugin_resources,omitempty"`
+	ResourceSecrets    []SecretConfig     `json:"secret_resources,omitempty" yaml:"secret_resources,omitempty"`
+	BloblangImports    []string           `json:"bloblang_imports,omitempty" yaml:"bloblang_imports,omitempty"`
 }
 
 // NewResourceConfig creates a ResourceConfig with default values.
@@ -34,6 +37,9 @@ func NewResourceConfig() ResourceConfig {
 		ResourceOutputs:    []output.Config{},
 		ResourceCaches:     []cache.Config{},
 		ResourceRateLimits: []ratelimit.Config{},
+		ResourcePlugins:    []PluginConfig{},
+		ResourceSecrets:    []SecretConfig{},
+		BloblangImports:    []string{},
 	}
 }
 
@@ -88,6 +94,15 @@ func (r *ResourceConfig) collapsed() (ResourceConfig, error) {
 	for k, v := range r.Manager.Plugins {
 		newMaps.Plugins[k] = v
 	}
+	for _, c := range r.ResourcePlugins {
+		if c.Label == "" {
+			return *r, errors.New("plugin resource has an empty label")
+		}
+		if _, exists := newMaps.Plugins[c.Label]; exists {
+			return *r, fmt.Errorf("plugin resource label '%v' collides with a previously defined resource", c.Label)
+		}
+		newMaps.Plugins[c.Label] = c
+	}
 
 	for k, v := range r.Manager.Processors {
 		newMaps.Processors[k] = v
@@ -115,8 +130,21 @@ func (r *ResourceConfig) collapsed() (ResourceConfig, error) {
 		newMaps.RateLimits[c.Label] = c
 	}
 
+	secretLabels := map[string]struct{}{}
+	for _, c := range r.ResourceSecrets {
+		if c.Label == "" {
+			return *r, errors.New("secret resource has an empty label")
+		}
+		if _, exists := secretLabels[c.Label]; exists {
+			return *r, fmt.Errorf("secret resource label '%v' collides with a previously defined resource", c.Label)
+		}
+		secretLabels[c.Label] = struct{}{}
+	}
+
 	return ResourceConfig{
-		Manager: newMaps,
+		Manager:         newMaps,
+		ResourceSecrets: r.ResourceSecrets,
+		BloblangImports: r.BloblangImports,
 	}, nil
 }
 
@@ -132,6 +160,9 @@ func (r *ResourceConfig) AddFrom(extra *ResourceConfig) error {
 	r.ResourceOutputs = append(r.ResourceOutputs, extra.ResourceOutputs...)
 	r.ResourceCaches = append(r.ResourceCaches, extra.ResourceCaches...)
 	r.ResourceRateLimits = append(r.ResourceRateLimits, extra.ResourceRateLimits...)
+	r.ResourcePlugins = append(r.ResourcePlugins, extra.ResourcePlugins...)
+	r.ResourceSecrets = append(r.ResourceSecrets, extra.ResourceSecrets...)
+	r.BloblangImports = append(r.BloblangImports, extra.BloblangImports...)
 	return nil
 }
 
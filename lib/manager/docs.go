@@ -61,5 +61,20 @@ func Spec() docs.FieldSpecs {
 		docs.FieldCommon(
 			"rate_limit_resources", "A list of rate limit resources, each must have a unique label.",
 		).Array().HasType(docs.FieldTypeRateLimit).Linter(lintResource),
+
+		docs.FieldAdvanced(
+			"plugin_resources", "A list of custom resources provided by plugins, each must have a unique label.",
+		).Array().WithChildren(
+			docs.FieldCommon("label", "A unique label of the resource."),
+			docs.FieldCommon("type", "The type of the resource plugin."),
+			docs.FieldCommon("plugin", "The config fields of the resource plugin type.").HasType(docs.FieldTypeUnknown).HasDefault(nil),
+		).Linter(lintResource).AtVersion("3.64.0"),
+
+		secretFieldSpec(),
+
+		docs.FieldString(
+			"bloblang_imports", "A list of Bloblang files containing map and function definitions that are available to all mappings and interpolation functions of the config, as if each mapping had imported them. Relative paths are resolved from the current working directory.",
+			[]string{"./bloblang/common.blobl"},
+		).Array().Advanced().AtVersion("3.64.0"),
 	}
 }
@@ -18,6 +18,7 @@ import (
 
 // PluginConfig is a config struct representing a resource plugin.
 type PluginConfig struct {
+	Label  string      `json:"label,omitempty" yaml:"label,omitempty"`
 	Type   string      `json:"type" yaml:"type"`
 	Plugin interface{} `json:"plugin" yaml:"plugin"`
 }
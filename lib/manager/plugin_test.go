@@ -64,6 +64,56 @@ func TestYAMLPlugin(t *testing.T) {
 	}
 }
 
+func TestYAMLPluginResources(t *testing.T) {
+	RegisterPlugin("foo_resource", newMockPluginConf,
+		func(conf interface{}, mgr types.Manager, logger log.Modular, stats metrics.Type) (interface{}, error) {
+			return conf, nil
+		})
+
+	confStr := `plugin_resources:
+  - label: foobar
+    type: foo_resource
+    plugin:
+      bar: custom
+  - label: barbaz
+    type: foo_resource`
+
+	conf := NewResourceConfig()
+	if err := yaml.Unmarshal([]byte(confStr), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := NewV2(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := mgr.GetPlugin("foobar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "custom", p.(*mockPluginConf).Bar; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+
+	if p, err = mgr.GetPlugin("barbaz"); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "change this", p.(*mockPluginConf).Bar; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+
+	if _, err = mgr.GetPlugin("nope"); err != types.ErrPluginNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrPluginNotFound)
+	}
+
+	conf.ResourcePlugins = append(conf.ResourcePlugins, conf.ResourcePlugins[0])
+	exp := "plugin resource label 'foobar' collides with a previously defined resource"
+	if _, err = NewV2(conf, nil, log.Noop(), metrics.Noop()); err == nil || err.Error() != exp {
+		t.Errorf("Wrong error returned: %v != %v", err, exp)
+	}
+}
+
 func TestPluginDescriptions(t *testing.T) {
 	RegisterPlugin("foo", newMockPluginConf, nil)
 	RegisterPlugin("bar", newMockPluginConf, nil)
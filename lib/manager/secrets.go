@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// SecretConfig contains the configuration of a secret resource, which is a
+// value read from either an environment variable or a file that can be
+// accessed within Bloblang mappings without being written into the config.
+type SecretConfig struct {
+	Label    string `json:"label" yaml:"label"`
+	Env      string `json:"env" yaml:"env"`
+	File     string `json:"file" yaml:"file"`
+	Encoding string `json:"encoding" yaml:"encoding"`
+}
+
+// NewSecretConfig creates a SecretConfig with default values.
+func NewSecretConfig() SecretConfig {
+	return SecretConfig{
+		Label:    "",
+		Env:      "",
+		File:     "",
+		Encoding: "none",
+	}
+}
+
+// UnmarshalYAML ensures that when parsing configs that are in a slice the
+// default values are still applied.
+func (s *SecretConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type confAlias SecretConfig
+	aliased := confAlias(NewSecretConfig())
+	if err := unmarshal(&aliased); err != nil {
+		return err
+	}
+	*s = SecretConfig(aliased)
+	return nil
+}
+
+func secretFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"secret_resources", "A list of secrets, each must have a unique label, which are read once at start up from either an environment variable or a file and can be accessed within Bloblang mappings with the [`secret` function](/docs/guides/bloblang/functions#secret).",
+	).Array().WithChildren(
+		docs.FieldCommon("label", "A unique label of the secret."),
+		docs.FieldCommon("env", "The name of an environment variable to read the secret from.").HasDefault(""),
+		docs.FieldCommon("file", "The path of a file to read the secret from.").HasDefault(""),
+		docs.FieldCommon("encoding", "An encoding of the secret value that is decoded once it is read, in which case leading and trailing whitespace is ignored. Otherwise the value is used exactly as it is read.").HasOptions("none", "hex", "base64").HasDefault("none"),
+	).AtVersion("3.64.0")
+}
+
+// read obtains the value of a secret from its source and decodes it.
+func (s SecretConfig) read() ([]byte, error) {
+	var value []byte
+	switch {
+	case s.Env != "" && s.File != "":
+		return nil, errors.New("only one of the fields env and file may be specified")
+	case s.Env != "":
+		envValue, exists := os.LookupEnv(s.Env)
+		if !exists {
+			return nil, fmt.Errorf("environment variable '%v' is not set", s.Env)
+		}
+		value = []byte(envValue)
+	case s.File != "":
+		fileValue, err := os.ReadFile(s.File)
+		if err != nil {
+			return nil, err
+		}
+		value = fileValue
+	default:
+		return nil, errors.New("either the field env or file must be specified")
+	}
+
+	switch s.Encoding {
+	case "", "none":
+		return value, nil
+	case "hex":
+		return hex.DecodeString(strings.TrimSpace(string(value)))
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(value)))
+	}
+	return nil, fmt.Errorf("unrecognised encoding: %v", s.Encoding)
+}
@@ -141,6 +141,30 @@ func NewV2(conf ResourceConfig, apiReg APIReg, log log.Modular, stats metrics.Ty
 		return nil, err
 	}
 
+	t.bloblEnv = t.bloblEnv.WithCacheAccess(func(name string, fn func(types.Cache)) error {
+		return t.AccessCache(context.Background(), name, fn)
+	})
+
+	secrets := make(map[string][]byte, len(conf.ResourceSecrets))
+	for _, c := range conf.ResourceSecrets {
+		if secrets[c.Label], err = c.read(); err != nil {
+			return nil, fmt.Errorf("failed to read secret '%v': %w", c.Label, err)
+		}
+	}
+	t.bloblEnv = t.bloblEnv.WithSecretAccess(func(name string) ([]byte, error) {
+		value, exists := secrets[name]
+		if !exists {
+			return nil, fmt.Errorf("secret '%v' was not found", name)
+		}
+		return append([]byte(nil), value...), nil
+	})
+
+	if len(conf.BloblangImports) > 0 {
+		if t.bloblEnv, err = t.bloblEnv.WithImports(conf.BloblangImports...); err != nil {
+			return nil, fmt.Errorf("failed to import bloblang definitions: %w", err)
+		}
+	}
+
 	// Sometimes resources of a type might refer to other resources of the same
 	// type. When they are constructed they will check with the manager to
 	// ensure the resource they point to is valid, but not keep the reference.
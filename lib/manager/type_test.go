@@ -2,6 +2,8 @@ package manager_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -10,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/processor"
@@ -89,6 +92,119 @@ func TestManagerCache(t *testing.T) {
 	}
 }
 
+func TestManagerBloblangCacheFunctions(t *testing.T) {
+	conf := manager.NewConfig()
+	conf.Caches["foo"] = cache.NewConfig()
+
+	mgr, err := manager.New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	pConf := processor.NewConfig()
+	pConf.Type = processor.TypeBloblang
+	pConf.Bloblang = `
+root.set = cache_set("foo", this.key, this.value)
+root.get = cache_get("foo", this.key).string()
+root.missing = cache_get("foo", "nope").catch("default")
+`
+
+	proc, err := mgr.NewProcessor(pConf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"key":"a","value":"b"}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"get":"b","missing":"default","set":"b"}`, string(msgs[0].Get(0).Get()))
+
+	c, err := mgr.GetCache("foo")
+	require.NoError(t, err)
+
+	v, err := c.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(v))
+}
+
+func TestManagerBloblangSecrets(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_SECRET", "foo secret")
+	defer os.Unsetenv("BENTHOS_TEST_SECRET")
+
+	secretFile := filepath.Join(t.TempDir(), "secret.hex")
+	require.NoError(t, os.WriteFile(secretFile, []byte("626172207365637265740a\n"), 0o644))
+
+	fooSecret := manager.NewSecretConfig()
+	fooSecret.Label = "foo"
+	fooSecret.Env = "BENTHOS_TEST_SECRET"
+
+	barSecret := manager.NewSecretConfig()
+	barSecret.Label = "bar"
+	barSecret.File = secretFile
+	barSecret.Encoding = "hex"
+
+	conf := manager.NewResourceConfig()
+	conf.ResourceSecrets = append(conf.ResourceSecrets, fooSecret, barSecret)
+
+	mgr, err := manager.NewV2(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	pConf := processor.NewConfig()
+	pConf.Type = processor.TypeBloblang
+	pConf.Bloblang = `
+root.foo = secret("foo").string()
+root.bar = secret("bar").string()
+`
+
+	proc, err := mgr.NewProcessor(pConf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"bar":"bar secret\n","foo":"foo secret"}`, string(msgs[0].Get(0).Get()))
+
+	pConf.Bloblang = `root = secret("baz")`
+	_, err = mgr.NewProcessor(pConf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret 'baz' was not found")
+}
+
+func TestManagerBloblangSecretErrors(t *testing.T) {
+	tests := map[string]struct {
+		secrets []manager.SecretConfig
+		err     string
+	}{
+		"empty label": {
+			secrets: []manager.SecretConfig{{Env: "BENTHOS_TEST_NOPE"}},
+			err:     "secret resource has an empty label",
+		},
+		"duplicate label": {
+			secrets: []manager.SecretConfig{
+				{Label: "foo", Env: "BENTHOS_TEST_NOPE"},
+				{Label: "foo", Env: "BENTHOS_TEST_NOPE"},
+			},
+			err: "secret resource label 'foo' collides with a previously defined resource",
+		},
+		"missing env": {
+			secrets: []manager.SecretConfig{{Label: "foo", Env: "BENTHOS_TEST_NOPE"}},
+			err:     "failed to read secret 'foo': environment variable 'BENTHOS_TEST_NOPE' is not set",
+		},
+		"no source": {
+			secrets: []manager.SecretConfig{{Label: "foo"}},
+			err:     "failed to read secret 'foo': either the field env or file must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := manager.NewResourceConfig()
+			conf.ResourceSecrets = test.secrets
+
+			_, err := manager.NewV2(conf, nil, log.Noop(), metrics.Noop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
 func TestManagerCacheList(t *testing.T) {
 	cacheFoo := cache.NewConfig()
 	cacheFoo.Label = "foo"
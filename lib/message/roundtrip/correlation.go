@@ -0,0 +1,84 @@
+package roundtrip
+
+import (
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type correlationKey struct {
+	metaKey string
+	id      string
+}
+
+type correlationRegistry struct {
+	mut     sync.Mutex
+	metaKey map[string]struct{}
+	waiters map[correlationKey]chan types.Message
+}
+
+var correlations = &correlationRegistry{
+	metaKey: map[string]struct{}{},
+	waiters: map[correlationKey]chan types.Message{},
+}
+
+// AwaitCorrelated registers a waiter for a response message that carries the
+// provided id within the metadata key metaKey, which allows responses to be
+// returned to the origin of a message even when the message has passed through
+// other systems and has therefore lost its result store.
+//
+// The returned channel receives the first response with a matching id, and the
+// returned func must be called once the response is no longer awaited.
+func AwaitCorrelated(metaKey, id string) (<-chan types.Message, func()) {
+	k := correlationKey{metaKey: metaKey, id: id}
+	c := make(chan types.Message, 1)
+
+	correlations.mut.Lock()
+	correlations.metaKey[metaKey] = struct{}{}
+	correlations.waiters[k] = c
+	correlations.mut.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			correlations.mut.Lock()
+			delete(correlations.waiters, k)
+			correlations.mut.Unlock()
+		})
+	}
+}
+
+// SetAsCorrelatedResponse attempts to deliver a message to a waiter registered
+// with AwaitCorrelated, using the metadata of the first message of the batch.
+// Returns false if the message does not carry a metadata key that has ever been
+// awaited, and true if it does, even when a waiter with a matching id no longer
+// exists, in which case the response is dropped.
+func SetAsCorrelatedResponse(msg types.Message) bool {
+	if msg.Len() == 0 {
+		return false
+	}
+	meta := msg.Get(0).Metadata()
+
+	correlations.mut.Lock()
+	defer correlations.mut.Unlock()
+
+	correlated := false
+	for metaKey := range correlations.metaKey {
+		id := meta.Get(metaKey)
+		if id == "" {
+			continue
+		}
+		correlated = true
+		if c, exists := correlations.waiters[correlationKey{metaKey: metaKey, id: id}]; exists {
+			select {
+			case c <- msg.DeepCopy():
+			default:
+			}
+		}
+	}
+	return correlated
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,45 @@
+package roundtrip
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+func TestWriterCorrelated(t *testing.T) {
+	w := Writer{}
+
+	replies, done := AwaitCorrelated("test_correlation_id", "foo")
+	defer done()
+
+	unrelated := message.New([][]byte{[]byte("unrelated")})
+	if err := w.Write(unrelated); err != ErrNoStore {
+		t.Errorf("Expected no store error, got: %v", err)
+	}
+
+	stale := message.New([][]byte{[]byte("stale")})
+	stale.Get(0).Metadata().Set("test_correlation_id", "bar")
+	if err := w.Write(stale); err != nil {
+		t.Error(err)
+	}
+
+	reply := message.New([][]byte{[]byte("hello world")})
+	reply.Get(0).Metadata().Set("test_correlation_id", "foo")
+	if err := w.Write(reply); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res := <-replies:
+		if exp, act := "hello world", string(res.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+	default:
+		t.Fatal("Expected correlated response")
+	}
+
+	done()
+	if err := w.Write(reply); err != nil {
+		t.Errorf("Expected response without a waiter to be dropped: %v", err)
+	}
+}
@@ -20,9 +20,14 @@ func (s Writer) Connect() error {
 }
 
 // Write a message batch to a ResultStore located in the first message of the
-// batch.
+// batch, or to a waiter registered with AwaitCorrelated when the batch carries
+// a correlation ID within its metadata.
 func (s Writer) Write(msg types.Message) error {
-	return SetAsResponse(msg)
+	err := SetAsResponse(msg)
+	if err == ErrNoStore && SetAsCorrelatedResponse(msg) {
+		return nil
+	}
+	return err
 }
 
 // CloseAsync is a noop.
@@ -32,6 +32,10 @@ Message metadata is added to each AMQP message as string annotations. In order t
 			),
 			docs.FieldCommon("target_address", "The target address to write to.", "/foo", "queue:/bar", "topic:/baz"),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			docs.FieldAdvanced("settle_mode", "Whether messages are sent unsettled, where each write waits for the message to be accepted by the server, or pre-settled.").HasAnnotatedOptions(
+				"unsettled", "Writes wait for the server to accept each message.",
+				"settled", "Messages are settled when sent, which improves throughput but means they can be lost.",
+			).AtVersion("3.64.0"),
 			tls.FieldSpec(),
 			sasl.FieldSpec(),
 			docs.FieldCommon("metadata", "Specify criteria for which metadata values are attached to messages as headers.").WithChildren(metadata.ExcludeFilterFields()...),
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/metadata"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -90,6 +91,26 @@ output:
       processors:
         - archive:
             format: json_array
+` + "```" + `
+
+### Multipart Uploads
+
+Uploading each batch as an individual object results in a large number of small objects when throughput is low or batches are small. Instead, setting ` + "`multipart.enabled`" + ` to ` + "`true`" + ` streams the messages of many batches into a single object with a [multipart upload](https://docs.aws.amazon.com/AmazonS3/latest/userguide/mpuoverview.html), where messages are written using the ` + "`multipart.codec`" + `. An object is completed and the next batch begins a new one, with a path calculated from the first message of that batch, once the object reaches ` + "`multipart.max_object_size`" + ` bytes or once it has been open for ` + "`multipart.max_object_age`" + `, whichever happens first. Objects are also completed when Benthos shuts down.
+
+Messages are buffered in memory until there is enough data to upload a part of ` + "`multipart.part_size`" + ` bytes, and an object is only visible within the bucket once it has been completed. Since messages are acknowledged once they have been buffered or uploaded as part of an incomplete object, messages can be lost if Benthos is terminated abruptly, and therefore it's also recommended to configure a [lifecycle rule](https://docs.aws.amazon.com/AmazonS3/latest/userguide/mpu-abort-incomplete-mpu-lifecycle-config.html) that cleans up incomplete multipart uploads from the bucket.
+
+` + "```yaml" + `
+output:
+  aws_s3:
+    bucket: TODO
+    path: logs/${!timestamp_unix_nano()}.jsonl
+    multipart:
+      enabled: true
+      max_object_size: 536870912 # 512MiB
+      max_object_age: 1h
+    batching:
+      count: 1000
+      period: 10s
 ` + "```" + ``,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
@@ -109,6 +130,7 @@ output:
 			).IsInterpolated().Map(),
 			docs.FieldCommon("content_type", "The content type to set for each object.").IsInterpolated(),
 			docs.FieldAdvanced("content_encoding", "An optional content encoding to set for each object.").IsInterpolated(),
+			codec.CompressionDocs.AtVersion("3.64.0"),
 			docs.FieldString("cache_control", "The cache control to set for each object.").Advanced().IsInterpolated(),
 			docs.FieldString("content_disposition", "The content disposition to set for each object.").Advanced().IsInterpolated(),
 			docs.FieldString("content_language", "The content language to set for each object.").Advanced().IsInterpolated(),
@@ -122,6 +144,13 @@ output:
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
+			docs.FieldAdvanced("multipart", "Stream the messages of many batches into large objects with multipart uploads rather than uploading each message as an object.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to stream messages into objects with multipart uploads."),
+				docs.FieldCommon("codec", "The way in which the bytes of messages are written into objects. It's possible to write lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter.", "lines", "delim:\t", "delim:foobar"),
+				docs.FieldAdvanced("part_size", "The number of bytes to buffer before uploading them as a part of an object, which must be at least 5MiB."),
+				docs.FieldCommon("max_object_size", "The number of bytes after which an object is completed and a new object is started. Set to `0` in order to disable size based completion."),
+				docs.FieldCommon("max_object_age", "The period after which an object is completed and a new object is started. Set to an empty string in order to disable time based completion.", "1h", "10m"),
+			).AtVersion("3.64.0"),
 			batch.FieldSpec(),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
@@ -229,6 +258,7 @@ output:
 			).IsInterpolated().Map(),
 			docs.FieldCommon("content_type", "The content type to set for each object.").IsInterpolated(),
 			docs.FieldAdvanced("content_encoding", "An optional content encoding to set for each object.").IsInterpolated(),
+			codec.CompressionDocs,
 			docs.FieldString("cache_control", "The cache control to set for each object.").Advanced().IsInterpolated(),
 			docs.FieldString("content_disposition", "The content disposition to set for each object.").Advanced().IsInterpolated(),
 			docs.FieldString("content_language", "The content language to set for each object.").Advanced().IsInterpolated(),
@@ -242,6 +272,13 @@ output:
 			docs.FieldAdvanced("force_path_style_urls", "Forces the client API to use path style URLs, which helps when connecting to custom endpoints."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("timeout", "The maximum period to wait on an upload before abandoning it and reattempting."),
+			docs.FieldAdvanced("multipart", "Stream the messages of many batches into large objects with multipart uploads rather than uploading each message as an object.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to stream messages into objects with multipart uploads."),
+				docs.FieldCommon("codec", "The way in which the bytes of messages are written into objects. It's possible to write lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter.", "lines", "delim:\t", "delim:foobar"),
+				docs.FieldAdvanced("part_size", "The number of bytes to buffer before uploading them as a part of an object, which must be at least 5MiB."),
+				docs.FieldCommon("max_object_size", "The number of bytes after which an object is completed and a new object is started. Set to `0` in order to disable size based completion."),
+				docs.FieldCommon("max_object_age", "The period after which an object is completed and a new object is started. Set to an empty string in order to disable time based completion.", "1h", "10m"),
+			),
 			batch.FieldSpec(),
 		}.Merge(session.FieldSpecs()),
 		Categories: []Category{
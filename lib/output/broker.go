@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
@@ -88,16 +89,48 @@ potentially disproportionate message allocations to those outputs. Each message
 is sent to a single output, which is determined by allowing outputs to claim
 messages as soon as they are able to process them. This results in certain
 faster outputs potentially processing more messages at the cost of slower
-outputs.`,
+outputs.
+
+### ` + "`priority`" + `
+
+The priority pattern allocates each message to a single output in the same way
+as the greedy pattern, but whilst all outputs are busy up to
+` + "`max_in_flight`" + ` message batches are held in a pending queue. When an
+output becomes available the pending batch with the highest priority is sent
+first, as determined by the [Bloblang mapping](/docs/guides/bloblang/about)
+` + "`priority_mapping`" + `, which must return an integer for each batch where
+higher values are sent first. Batches of equal priority are sent in the order
+that they arrived.
+
+This allows critical events to skip ahead of bulk traffic sharing the same
+pipeline whenever the outputs apply back pressure:
+
+` + "```yaml" + `
+output:
+  broker:
+    pattern: priority
+    max_in_flight: 100
+    priority_mapping: 'root = if meta("type") == "critical" { 10 } else { 0 }'
+    outputs:
+      - resource: foo
+` + "```" + `
+
+The gauge metric ` + "`broker.priority.pending`" + ` reports the number of
+batches currently held in the pending queue.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldAdvanced("copies", "The number of copies of each configured output to spawn."),
 			docs.FieldCommon("pattern", "The brokering pattern to use.").HasOptions(
-				"fan_out", "fan_out_sequential", "round_robin", "greedy",
+				"fan_out", "fan_out_sequential", "round_robin", "greedy", "priority",
 			),
 			docs.FieldAdvanced(
 				"max_in_flight",
-				"The maximum number of parallel message batches to have in flight at any given time. Note that if a child output has a higher `max_in_flight` then the switch output will automatically match it, therefore this value is the minimum `max_in_flight` to set in cases where the child values can't be inferred (such as when using resource outputs as children). Only relevant for `fan_out`, `fan_out_sequential` brokers.",
+				"The maximum number of parallel message batches to have in flight at any given time. Note that if a child output has a higher `max_in_flight` then the switch output will automatically match it, therefore this value is the minimum `max_in_flight` to set in cases where the child values can't be inferred (such as when using resource outputs as children). Only relevant for `fan_out`, `fan_out_sequential` brokers. For the `priority` pattern this is the maximum number of message batches held in the pending queue.",
 			),
+			docs.FieldBloblang(
+				"priority_mapping",
+				"A [Bloblang mapping](/docs/guides/bloblang/about) that returns an integer priority for each message batch, where higher values are sent first. Only relevant for `priority` brokers.",
+				`root = meta("priority").number()`,
+			).Advanced().AtVersion("3.64.0"),
 			docs.FieldCommon("outputs", "A list of child outputs to broker.").Array().HasType(docs.FieldTypeOutput),
 			batch.FieldSpec(),
 		},
@@ -111,21 +144,23 @@ outputs.`,
 
 // BrokerConfig contains configuration fields for the Broker output type.
 type BrokerConfig struct {
-	Copies      int                `json:"copies" yaml:"copies"`
-	Pattern     string             `json:"pattern" yaml:"pattern"`
-	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Outputs     brokerOutputList   `json:"outputs" yaml:"outputs"`
-	Batching    batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Copies          int                `json:"copies" yaml:"copies"`
+	Pattern         string             `json:"pattern" yaml:"pattern"`
+	MaxInFlight     int                `json:"max_in_flight" yaml:"max_in_flight"`
+	PriorityMapping string             `json:"priority_mapping" yaml:"priority_mapping"`
+	Outputs         brokerOutputList   `json:"outputs" yaml:"outputs"`
+	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewBrokerConfig creates a new BrokerConfig with default values.
 func NewBrokerConfig() BrokerConfig {
 	return BrokerConfig{
-		Copies:      1,
-		Pattern:     "fan_out",
-		MaxInFlight: 1,
-		Outputs:     brokerOutputList{},
-		Batching:    batch.NewPolicyConfig(),
+		Copies:          1,
+		Pattern:         "fan_out",
+		MaxInFlight:     1,
+		PriorityMapping: "",
+		Outputs:         brokerOutputList{},
+		Batching:        batch.NewPolicyConfig(),
 	}
 }
 
@@ -149,7 +184,9 @@ func NewBroker(
 	if lOutputs <= 0 {
 		return nil, ErrBrokerNoOutputs
 	}
-	if lOutputs == 1 {
+	// A single output is still brokered with the priority pattern as the
+	// pending queue is what determines the order of messages.
+	if lOutputs == 1 && conf.Broker.Pattern != "priority" {
 		b, err := New(outputConfs[0], mgr, log, stats, pipelines...)
 		if err != nil {
 			return nil, err
@@ -165,6 +202,7 @@ func NewBroker(
 	_, isThreaded := map[string]struct{}{
 		"round_robin": {},
 		"greedy":      {},
+		"priority":    {},
 	}[conf.Broker.Pattern]
 
 	var err error
@@ -205,6 +243,11 @@ func NewBroker(
 		b, err = broker.NewRoundRobin(outputs, stats)
 	case "greedy":
 		b, err = broker.NewGreedy(outputs)
+	case "priority":
+		var prioritise func(types.Message) int
+		if prioritise, err = brokerPrioritiser(conf.Broker.PriorityMapping, mgr, log); err == nil {
+			b, err = broker.NewPriority(outputs, prioritise, conf.Broker.MaxInFlight, stats)
+		}
 	case "try":
 		b, err = broker.NewTry(outputs, stats)
 	default:
@@ -226,3 +269,41 @@ func NewBroker(
 }
 
 //------------------------------------------------------------------------------
+
+// brokerPrioritiser parses a Bloblang mapping into a function that returns the
+// priority of a message batch for the priority broker pattern.
+func brokerPrioritiser(mapping string, mgr types.Manager, log log.Modular) (func(types.Message) int, error) {
+	if mapping == "" {
+		return nil, errors.New("a priority_mapping must be specified for the priority pattern")
+	}
+	exe, err := interop.NewBloblangMapping(mgr, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse priority_mapping: %w", err)
+	}
+	return func(msg types.Message) int {
+		v, err := exe.Exec(query.FunctionContext{
+			Maps:     map[string]query.Function{},
+			Vars:     map[string]interface{}{},
+			Index:    0,
+			MsgBatch: msg,
+		}.WithValueFunc(func() *interface{} {
+			jObj, err := msg.Get(0).JSON()
+			if err != nil {
+				return nil
+			}
+			return &jObj
+		}))
+		if err != nil {
+			log.Errorf("Failed to execute priority_mapping: %v\n", err)
+			return 0
+		}
+		p, err := query.IGetInt(v)
+		if err != nil {
+			log.Errorf("Priority mapping returned a non-integer value: %v\n", err)
+			return 0
+		}
+		return int(p)
+	}, nil
+}
+
+//------------------------------------------------------------------------------
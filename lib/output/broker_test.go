@@ -356,3 +356,34 @@ func TestTryBroker(t *testing.T) {
 		}
 	}
 }
+
+func TestPriorityBrokerMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "priority"
+	conf.Broker.Outputs = append(conf.Broker.Outputs, NewConfig())
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing priority_mapping")
+	}
+
+	prioritise, err := brokerPrioritiser(`root = if this.critical { 10 } else { meta("priority").number().catch(0) }`, nil, log.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	critical := message.New([][]byte{[]byte(`{"critical":true}`)})
+	bulk := message.New([][]byte{[]byte(`{"critical":false}`)})
+	bulk.Get(0).Metadata().Set("priority", "3")
+	broken := message.New([][]byte{[]byte(`not json`)})
+
+	if exp, act := 10, prioritise(critical); exp != act {
+		t.Errorf("Wrong priority: %v != %v", act, exp)
+	}
+	if exp, act := 3, prioritise(bulk); exp != act {
+		t.Errorf("Wrong priority: %v != %v", act, exp)
+	}
+	if exp, act := 0, prioritise(broken); exp != act {
+		t.Errorf("Wrong priority: %v != %v", act, exp)
+	}
+}
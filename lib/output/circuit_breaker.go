@@ -0,0 +1,539 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCircuitBreaker] = TypeSpec{
+		constructor: fromSimpleConstructor(NewCircuitBreaker),
+		Summary: `
+Writes messages to a child output and stops doing so for a period once the
+proportion of failed writes becomes too high, routing messages to an alternate
+output or rejecting them in the meantime.`,
+		Description: `
+The breaker starts closed, where all messages are written to the child output
+and the number of requests and errors is counted within a fixed ` + "`window`" + `.
+Once at least ` + "`min_requests`" + ` requests have been made within a window
+and the proportion of them that failed meets ` + "`error_threshold`" + ` the
+breaker trips open.
+
+Whilst open the child output is not written to at all. Instead, messages are
+written to the ` + "`alternate`" + ` output if one is specified, otherwise they
+are rejected so that the input is able to nack them. After ` + "`open_period`" + `
+has elapsed the breaker becomes half open, where up to
+` + "`half_open_requests`" + ` messages are written to the child output as
+probes. If any probe fails the breaker opens again, and once all of them succeed
+the breaker closes.
+
+Errors that occur whilst the breaker is closed are always returned upstream, the
+request that trips the breaker is not rerouted.
+
+### Monitoring
+
+The current state of the breaker is exposed as the gauge metric
+` + "`circuit_breaker.state`" + `, where 0 is closed, 1 is open and 2 is half open,
+and the number of times it has tripped is counted with
+` + "`circuit_breaker.tripped`" + `. Messages written to the alternate output are
+counted with ` + "`circuit_breaker.alternate`" + ` and messages rejected with
+` + "`circuit_breaker.rejected`" + `.
+
+The state of the breaker can also be obtained with a ` + "`GET`" + ` request to the
+HTTP endpoint ` + "`/circuit_breaker/{label}`" + `, where ` + "`label`" + ` is the
+label of the output.`,
+		config: docs.FieldComponent().WithChildren(
+			docs.FieldCommon("output", "A child output.").HasType(docs.FieldTypeOutput),
+			docs.FieldCommon("alternate", "An optional output to write messages to whilst the breaker is open, otherwise they are rejected.").HasType(docs.FieldTypeOutput),
+			docs.FieldFloat("error_threshold", "The proportion of failed requests within a window, between 0 and 1, at which the breaker trips open."),
+			docs.FieldInt("min_requests", "The minimum number of requests within a window before the breaker is able to trip."),
+			docs.FieldString("window", "The period over which requests and errors are counted whilst the breaker is closed.", "10s", "1m"),
+			docs.FieldString("open_period", "The period the breaker remains open before it becomes half open.", "30s", "5m"),
+			docs.FieldInt("half_open_requests", "The number of probe requests written to the child output whilst the breaker is half open.").Advanced(),
+		),
+		Status:  docs.StatusExperimental,
+		Version: "3.64.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreakerConfig contains configuration values for the CircuitBreaker
+// output type.
+type CircuitBreakerConfig struct {
+	Output           *Config `json:"output" yaml:"output"`
+	Alternate        *Config `json:"alternate,omitempty" yaml:"alternate,omitempty"`
+	ErrorThreshold   float64 `json:"error_threshold" yaml:"error_threshold"`
+	MinRequests      int     `json:"min_requests" yaml:"min_requests"`
+	Window           string  `json:"window" yaml:"window"`
+	OpenPeriod       string  `json:"open_period" yaml:"open_period"`
+	HalfOpenRequests int     `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// NewCircuitBreakerConfig creates a new CircuitBreakerConfig with default
+// values.
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Output:           nil,
+		Alternate:        nil,
+		ErrorThreshold:   0.5,
+		MinRequests:      10,
+		Window:           "10s",
+		OpenPeriod:       "30s",
+		HalfOpenRequests: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyCircuitBreakerConfig struct {
+	Output           interface{} `json:"output" yaml:"output"`
+	Alternate        *Config     `json:"alternate,omitempty" yaml:"alternate,omitempty"`
+	ErrorThreshold   float64     `json:"error_threshold" yaml:"error_threshold"`
+	MinRequests      int         `json:"min_requests" yaml:"min_requests"`
+	Window           string      `json:"window" yaml:"window"`
+	OpenPeriod       string      `json:"open_period" yaml:"open_period"`
+	HalfOpenRequests int         `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+func (c CircuitBreakerConfig) dummy() dummyCircuitBreakerConfig {
+	dummy := dummyCircuitBreakerConfig{
+		Output:           c.Output,
+		Alternate:        c.Alternate,
+		ErrorThreshold:   c.ErrorThreshold,
+		MinRequests:      c.MinRequests,
+		Window:           c.Window,
+		OpenPeriod:       c.OpenPeriod,
+		HalfOpenRequests: c.HalfOpenRequests,
+	}
+	if c.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (c CircuitBreakerConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (c CircuitBreakerConfig) MarshalYAML() (interface{}, error) {
+	return c.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// breaker tracks the state of a circuit breaker and decides whether requests
+// are allowed through it.
+type breaker struct {
+	threshold   float64
+	minRequests int
+	window      time.Duration
+	openPeriod  time.Duration
+	maxProbes   int
+	now         func() time.Time
+
+	mState   metrics.StatGauge
+	mTripped metrics.StatCounter
+
+	mut         sync.Mutex
+	state       breakerState
+	windowStart time.Time
+	requests    int
+	errors      int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// allow returns whether a request may be written to the child output, along
+// with the state of the breaker at the time it was allowed, which must be
+// given back when the result is recorded.
+func (b *breaker) allow() (breakerState, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.state == breakerOpen {
+		if b.now().Sub(b.openedAt) < b.openPeriod {
+			return breakerOpen, false
+		}
+		b.setState(breakerHalfOpen)
+		b.probes, b.successes = 0, 0
+	}
+	if b.state == breakerHalfOpen {
+		if b.probes >= b.maxProbes {
+			return breakerHalfOpen, false
+		}
+		b.probes++
+	}
+	return b.state, true
+}
+
+// record the result of a request that was allowed in the given state. Results
+// of requests allowed in a state that has since changed are ignored.
+func (b *breaker) record(from breakerState, err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if from != b.state {
+		return
+	}
+	switch b.state {
+	case breakerClosed:
+		if now := b.now(); now.Sub(b.windowStart) >= b.window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if err != nil {
+			b.errors++
+		}
+		if b.requests >= b.minRequests && float64(b.errors)/float64(b.requests) >= b.threshold {
+			b.trip()
+		}
+	case breakerHalfOpen:
+		if err != nil {
+			b.trip()
+			return
+		}
+		if b.successes++; b.successes >= b.maxProbes {
+			b.setState(breakerClosed)
+			b.resetWindow(b.now())
+		}
+	}
+}
+
+func (b *breaker) trip() {
+	b.setState(breakerOpen)
+	b.openedAt = b.now()
+	b.mTripped.Incr(1)
+}
+
+func (b *breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests, b.errors = 0, 0
+}
+
+func (b *breaker) setState(s breakerState) {
+	b.state = s
+	b.mState.Set(int64(s))
+}
+
+type breakerStatus struct {
+	State    string     `json:"state"`
+	Requests int        `json:"requests"`
+	Errors   int        `json:"errors"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+func (b *breaker) status() breakerStatus {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	status := breakerStatus{
+		State:    b.state.String(),
+		Requests: b.requests,
+		Errors:   b.errors,
+	}
+	if b.state != breakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreaker is an output type that stops writing to a child output for a
+// period once too many writes have failed.
+type CircuitBreaker struct {
+	running int32
+
+	wrapped   Type
+	alternate Type
+	breaker   *breaker
+
+	log log.Modular
+
+	mRejected  metrics.StatCounter
+	mAlternate metrics.StatCounter
+
+	transactionsIn <-chan types.Transaction
+	wrappedOut     chan types.Transaction
+	alternateOut   chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker output type.
+func NewCircuitBreaker(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	cConf := conf.CircuitBreaker
+	if cConf.Output == nil {
+		return nil, errors.New("cannot create circuit_breaker output without a child")
+	}
+
+	wrapped, err := New(*cConf.Output, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", cConf.Output.Type, err)
+	}
+
+	c, err := newCircuitBreaker(cConf, wrapped, mgr, log, stats)
+	if err != nil {
+		wrapped.CloseAsync()
+		return nil, err
+	}
+
+	if cConf.Alternate != nil {
+		aMgr, aLog, aStats := interop.LabelChild("alternate", mgr, log, stats)
+		if c.alternate, err = New(*cConf.Alternate, aMgr, aLog, aStats); err != nil {
+			wrapped.CloseAsync()
+			return nil, fmt.Errorf("failed to create alternate output '%v': %v", cConf.Alternate.Type, err)
+		}
+	}
+
+	mgr.RegisterEndpoint(
+		"/circuit_breaker/"+interop.GetLabel(mgr),
+		"Get the current state of a circuit_breaker output.",
+		c.handleStatus,
+	)
+	return c, nil
+}
+
+func newCircuitBreaker(conf CircuitBreakerConfig, wrapped Type, mgr types.Manager, log log.Modular, stats metrics.Type) (*CircuitBreaker, error) {
+	if conf.ErrorThreshold <= 0 || conf.ErrorThreshold > 1 {
+		return nil, fmt.Errorf("error_threshold must be greater than 0 and at most 1, got %v", conf.ErrorThreshold)
+	}
+	if conf.MinRequests < 1 {
+		return nil, errors.New("min_requests must be at least 1")
+	}
+	if conf.HalfOpenRequests < 1 {
+		return nil, errors.New("half_open_requests must be at least 1")
+	}
+	window, err := time.ParseDuration(conf.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse window: %v", err)
+	}
+	openPeriod, err := time.ParseDuration(conf.OpenPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse open_period: %v", err)
+	}
+
+	b := &breaker{
+		threshold:   conf.ErrorThreshold,
+		minRequests: conf.MinRequests,
+		window:      window,
+		openPeriod:  openPeriod,
+		maxProbes:   conf.HalfOpenRequests,
+		now:         time.Now,
+
+		mState:   stats.GetGauge("circuit_breaker.state"),
+		mTripped: stats.GetCounter("circuit_breaker.tripped"),
+	}
+	b.resetWindow(b.now())
+	b.setState(breakerClosed)
+
+	return &CircuitBreaker{
+		running: 1,
+		wrapped: wrapped,
+		breaker: b,
+		log:     log,
+
+		mRejected:  stats.GetCounter("circuit_breaker.rejected"),
+		mAlternate: stats.GetCounter("circuit_breaker.alternate"),
+
+		wrappedOut:   make(chan types.Transaction),
+		alternateOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CircuitBreaker) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resBytes, err := json.Marshal(c.breaker.status())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resBytes)
+}
+
+// send writes a message to an output and waits for the response, returning
+// false if the breaker was closed beforehand.
+func (c *CircuitBreaker) send(tChan chan types.Transaction, msg types.Message) (types.Response, bool) {
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-c.closeChan:
+		return nil, false
+	}
+	select {
+	case res := <-resChan:
+		return res, true
+	case <-c.closeChan:
+		return nil, false
+	}
+}
+
+func (c *CircuitBreaker) deliver(msg types.Message) (types.Response, bool) {
+	from, allowed := c.breaker.allow()
+	if allowed {
+		res, open := c.send(c.wrappedOut, msg)
+		if !open {
+			return nil, false
+		}
+		c.breaker.record(from, res.Error())
+		return res, true
+	}
+
+	if c.alternate == nil {
+		c.mRejected.Incr(1)
+		return response.NewError(errCircuitOpen), true
+	}
+	c.mAlternate.Incr(1)
+	return c.send(c.alternateOut, msg)
+}
+
+func (c *CircuitBreaker) loop() {
+	var wg sync.WaitGroup
+
+	defer func() {
+		wg.Wait()
+		close(c.wrappedOut)
+		close(c.alternateOut)
+		c.wrapped.CloseAsync()
+		if c.alternate != nil {
+			c.alternate.CloseAsync()
+		}
+		_ = c.wrapped.WaitForClose(shutdown.MaximumShutdownWait())
+		if c.alternate != nil {
+			_ = c.alternate.WaitForClose(shutdown.MaximumShutdownWait())
+		}
+		close(c.closedChan)
+	}()
+
+	for atomic.LoadInt32(&c.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-c.transactionsIn:
+			if !open {
+				return
+			}
+		case <-c.closeChan:
+			return
+		}
+
+		wg.Add(1)
+		go func(ts types.Transaction) {
+			defer wg.Done()
+
+			res, open := c.deliver(ts.Payload)
+			if !open {
+				return
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-c.closeChan:
+			}
+		}(tran)
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (c *CircuitBreaker) Consume(ts <-chan types.Transaction) error {
+	if c.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := c.wrapped.Consume(c.wrappedOut); err != nil {
+		return err
+	}
+	if c.alternate != nil {
+		if err := c.alternate.Consume(c.alternateOut); err != nil {
+			return err
+		}
+	}
+	c.transactionsIn = ts
+	go c.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (c *CircuitBreaker) Connected() bool {
+	return c.wrapped.Connected()
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (c *CircuitBreaker) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(c.wrapped)
+}
+
+// CloseAsync shuts down the output and stops processing requests.
+func (c *CircuitBreaker) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&c.running, 1, 0) {
+		close(c.closeChan)
+	}
+}
+
+// WaitForClose blocks until the output has closed down.
+func (c *CircuitBreaker) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-c.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		fn     func(c *CircuitBreakerConfig)
+		errStr string
+	}{
+		"bad threshold": {
+			fn:     func(c *CircuitBreakerConfig) { c.ErrorThreshold = 1.5 },
+			errStr: "error_threshold must be greater than 0 and at most 1, got 1.5",
+		},
+		"bad min requests": {
+			fn:     func(c *CircuitBreakerConfig) { c.MinRequests = 0 },
+			errStr: "min_requests must be at least 1",
+		},
+		"bad window": {
+			fn:     func(c *CircuitBreakerConfig) { c.Window = "nope" },
+			errStr: `failed to parse window: time: invalid duration "nope"`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewCircuitBreakerConfig()
+			test.fn(&conf)
+			_, err := newCircuitBreaker(conf, &mockOutput{}, nil, log.Noop(), metrics.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	conf := NewCircuitBreakerConfig()
+	conf.MinRequests = 2
+	conf.HalfOpenRequests = 1
+
+	mOut, mAlt := &mockOutput{}, &mockOutput{}
+	c, err := newCircuitBreaker(conf, mOut, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	c.alternate = mAlt
+
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, c.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	sendMsg := func(content string) {
+		t.Helper()
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	expectTran := func(ts <-chan types.Transaction, exp string, res types.Response) {
+		t.Helper()
+		select {
+		case tran := <-ts:
+			assert.Equal(t, exp, string(tran.Payload.Get(0).Get()))
+			select {
+			case tran.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	expectRes := func(errStr string) {
+		t.Helper()
+		select {
+		case res := <-resChan:
+			if errStr == "" {
+				assert.NoError(t, res.Error())
+			} else {
+				assert.EqualError(t, res.Error(), errStr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	sendMsg("foo")
+	expectTran(mOut.ts, "foo", response.NewError(errors.New("first")))
+	expectRes("first")
+	assert.Equal(t, "closed", c.breaker.status().State)
+
+	sendMsg("bar")
+	expectTran(mOut.ts, "bar", response.NewError(errors.New("second")))
+	expectRes("second")
+	assert.Equal(t, "open", c.breaker.status().State)
+
+	sendMsg("baz")
+	expectTran(mAlt.ts, "baz", response.NewAck())
+	expectRes("")
+
+	now = now.Add(time.Minute)
+
+	sendMsg("qux")
+	expectTran(mOut.ts, "qux", response.NewError(errors.New("probe failed")))
+	expectRes("probe failed")
+	assert.Equal(t, "open", c.breaker.status().State)
+
+	now = now.Add(time.Minute)
+
+	sendMsg("quz")
+	expectTran(mOut.ts, "quz", response.NewAck())
+	expectRes("")
+	assert.Equal(t, "closed", c.breaker.status().State)
+
+	c.alternate = nil
+	c.breaker.mut.Lock()
+	c.breaker.trip()
+	c.breaker.mut.Unlock()
+
+	sendMsg("rejected")
+	expectRes("circuit breaker is open")
+
+	c.CloseAsync()
+	require.NoError(t, c.WaitForClose(time.Second))
+}
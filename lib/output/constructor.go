@@ -56,7 +56,9 @@ type TypeSpec struct {
 
 // AppendProcessorsFromConfig takes a variant arg of pipeline constructor
 // functions and returns a new slice of them where the processors of the
-// provided output configuration will also be initialized.
+// provided output configuration will also be initialized. When the output
+// configuration specifies a rate limit then a pipeline that throttles messages
+// by that rate limit is appended last, so that it is closest to the output.
 func AppendProcessorsFromConfig(
 	conf Config,
 	mgr types.Manager,
@@ -83,6 +85,20 @@ func AppendProcessorsFromConfig(
 			return pipeline.NewProcessor(log, stats, processors...), nil
 		}}...)
 	}
+	if conf.RateLimit != "" {
+		pipelines = append(pipelines, func(i *int) (types.Pipeline, error) {
+			rConf := processor.NewConfig()
+			rConf.Type = processor.TypeRateLimit
+			rConf.RateLimit.Resource = conf.RateLimit
+
+			rMgr, rLog, rMetrics := interop.LabelChild("rate_limit", mgr, log, stats)
+			proc, err := processor.New(rConf, rMgr, rLog, rMetrics)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create output rate limit: %v", err)
+			}
+			return pipeline.NewProcessor(log, stats, proc), nil
+		})
+	}
 	return pipelines
 }
 
@@ -173,6 +189,7 @@ const (
 	TypeBroker             = "broker"
 	TypeCache              = "cache"
 	TypeCassandra          = "cassandra"
+	TypeCircuitBreaker     = "circuit_breaker"
 	TypeDrop               = "drop"
 	TypeDropOn             = "drop_on"
 	TypeDropOnError        = "drop_on_error"
@@ -248,6 +265,7 @@ type Config struct {
 	Broker             BrokerConfig                   `json:"broker" yaml:"broker"`
 	Cache              writer.CacheConfig             `json:"cache" yaml:"cache"`
 	Cassandra          CassandraConfig                `json:"cassandra" yaml:"cassandra"`
+	CircuitBreaker     CircuitBreakerConfig           `json:"circuit_breaker" yaml:"circuit_breaker"`
 	Drop               writer.DropConfig              `json:"drop" yaml:"drop"`
 	DropOn             DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
 	DropOnError        DropOnErrorConfig              `json:"drop_on_error" yaml:"drop_on_error"`
@@ -299,6 +317,8 @@ type Config struct {
 	Websocket          writer.WebsocketConfig         `json:"websocket" yaml:"websocket"`
 	ZMQ4               *writer.ZMQ4Config             `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
 	Processors         []processor.Config             `json:"processors" yaml:"processors"`
+	RateLimit          string                         `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	DeadLetter         *DeadLetterConfig              `json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -324,6 +344,7 @@ func NewConfig() Config {
 		Broker:             NewBrokerConfig(),
 		Cache:              writer.NewCacheConfig(),
 		Cassandra:          NewCassandraConfig(),
+		CircuitBreaker:     NewCircuitBreakerConfig(),
 		Drop:               writer.NewDropConfig(),
 		DropOn:             NewDropOnConfig(),
 		DropOnError:        NewDropOnErrorConfig(),
@@ -375,6 +396,8 @@ func NewConfig() Config {
 		Websocket:          writer.NewWebsocketConfig(),
 		ZMQ4:               writer.NewZMQ4Config(),
 		Processors:         []processor.Config{},
+		RateLimit:          "",
+		DeadLetter:         nil,
 	}
 }
 
@@ -464,13 +487,19 @@ func New(
 	}); ok {
 		return mgrV2.NewOutput(conf, pipelines...)
 	}
+	var out Type
+	var err error
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats, pipelines...)
+		out, err = c.constructor(conf, mgr, log, stats, pipelines...)
+	} else if c, ok := pluginSpecs[conf.Type]; ok {
+		out, err = c.constructor(conf, mgr, log, stats, pipelines...)
+	} else {
+		return nil, types.ErrInvalidOutputType
 	}
-	if c, ok := pluginSpecs[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats, pipelines...)
+	if err != nil {
+		return nil, err
 	}
-	return nil, types.ErrInvalidOutputType
+	return WrapWithDeadLetter(conf, out, mgr, log, stats)
 }
 
 //------------------------------------------------------------------------------
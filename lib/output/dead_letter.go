@@ -0,0 +1,369 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/component/output"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// DeadLetterErrorKey is the metadata key that messages routed to a dead letter
+// output are given, containing the error that caused them to be routed.
+const DeadLetterErrorKey = "dead_letter_error"
+
+// DeadLetterConfig contains configuration fields for routing messages that an
+// output persistently fails to deliver to a dead letter output.
+type DeadLetterConfig struct {
+	Output         *Config `json:"output" yaml:"output"`
+	Check          string  `json:"check" yaml:"check"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewDeadLetterConfig creates a new DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	return DeadLetterConfig{
+		Output: nil,
+		Check:  "",
+		Config: rConf,
+	}
+}
+
+// UnmarshalYAML ensures that when parsing configs the default values are still
+// applied.
+func (d *DeadLetterConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias DeadLetterConfig
+	aliased := confAlias(NewDeadLetterConfig())
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+	*d = DeadLetterConfig(aliased)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// WrapWithDeadLetter wraps an output with the dead letter policy of its config
+// when one is specified, otherwise the output is returned unchanged.
+func WrapWithDeadLetter(conf Config, out Type, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if conf.DeadLetter == nil {
+		return out, nil
+	}
+	d, err := newDeadLetter(*conf.DeadLetter, out, mgr, log, stats)
+	if err != nil {
+		out.CloseAsync()
+		return nil, fmt.Errorf("failed to create dead letter output: %w", err)
+	}
+	return d, nil
+}
+
+// deadLetter is an output wrapper that retries messages that fail to be
+// delivered and, once its retry policy is exhausted or an error is considered
+// permanent, routes them to a dead letter output.
+type deadLetter struct {
+	running int32
+
+	wrapped     Type
+	deadLetter  Type
+	check       *mapping.Executor
+	maxRetries  uint64
+	backoffCtor func() backoff.BackOff
+
+	log log.Modular
+
+	mRetry      metrics.StatCounter
+	mDeadLetter metrics.StatCounter
+
+	transactionsIn <-chan types.Transaction
+	wrappedOut     chan types.Transaction
+	deadLetterOut  chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+func newDeadLetter(conf DeadLetterConfig, wrapped Type, mgr types.Manager, log log.Modular, stats metrics.Type) (*deadLetter, error) {
+	if conf.Output == nil {
+		return nil, errors.New("a dead letter output must be specified")
+	}
+
+	// Retries are counted separately in order to route messages after the
+	// limit is reached, the backoff only determines the period between them.
+	bConf := conf.Config
+	bConf.MaxRetries = 0
+	boffCtor, err := bConf.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &deadLetter{
+		running:     1,
+		wrapped:     wrapped,
+		maxRetries:  conf.MaxRetries,
+		backoffCtor: boffCtor,
+		log:         log,
+
+		mRetry:      stats.GetCounter("dead_letter.retry"),
+		mDeadLetter: stats.GetCounter("dead_letter.routed"),
+
+		wrappedOut:    make(chan types.Transaction),
+		deadLetterOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	if conf.Check != "" {
+		if d.check, err = interop.NewBloblangMapping(mgr, conf.Check); err != nil {
+			return nil, fmt.Errorf("failed to parse check: %w", err)
+		}
+	}
+
+	dMgr, dLog, dStats := interop.LabelChild("dead_letter", mgr, log, stats)
+	if d.deadLetter, err = New(*conf.Output, dMgr, dLog, dStats); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// failedParts returns the messages of a batch that failed to be delivered
+// along with their individual errors.
+func failedParts(msg types.Message, err error) (parts []types.Part, errs []error) {
+	if walkable, ok := err.(batch.WalkableError); ok && walkable.IndexedErrors() > 0 {
+		walkable.WalkParts(func(i int, _ types.Part, pErr error) bool {
+			if pErr != nil && i < msg.Len() {
+				parts = append(parts, msg.Get(i))
+				errs = append(errs, pErr)
+			}
+			return true
+		})
+		if len(parts) > 0 {
+			return
+		}
+	}
+	_ = msg.Iter(func(_ int, p types.Part) error {
+		parts = append(parts, p)
+		errs = append(errs, err)
+		return nil
+	})
+	return
+}
+
+func deadLetterPart(p types.Part, err error) types.Part {
+	p = p.Copy()
+	p.Metadata().Set(DeadLetterErrorKey, err.Error())
+	processor.FlagErr(p, err)
+	return p
+}
+
+// isPermanent returns true if the check of the dead letter policy considers
+// the error of a message to be permanent.
+func (d *deadLetter) isPermanent(p types.Part, err error) bool {
+	if d.check == nil {
+		return false
+	}
+	tmpMsg := message.New(nil)
+	tmpMsg.Append(deadLetterPart(p, err))
+	permanent, cErr := d.check.QueryPart(0, tmpMsg)
+	if cErr != nil {
+		d.log.Errorf("Failed to execute dead letter check: %v\n", cErr)
+		return false
+	}
+	return permanent
+}
+
+// send writes a message to an output and waits for the response, returning
+// false if the wrapper was closed beforehand.
+func (d *deadLetter) send(tChan chan types.Transaction, msg types.Message) (types.Response, bool) {
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(msg, resChan):
+	case <-d.closeChan:
+		return nil, false
+	}
+	select {
+	case res := <-resChan:
+		return res, true
+	case <-d.closeChan:
+		return nil, false
+	}
+}
+
+// deliver attempts to write a message to the wrapped output according to the
+// retry policy, and returns the response to give the original transaction.
+func (d *deadLetter) deliver(msg types.Message) (types.Response, bool) {
+	var boff backoff.BackOff
+	var routed []types.Part
+
+	for attempt := uint64(0); ; attempt++ {
+		res, open := d.send(d.wrappedOut, msg)
+		if !open {
+			return nil, false
+		}
+		if res.Error() == nil {
+			break
+		}
+
+		parts, errs := failedParts(msg, res.Error())
+		retryMsg := message.New(nil)
+		var retryErrs []error
+		for i, p := range parts {
+			if d.isPermanent(p, errs[i]) {
+				routed = append(routed, deadLetterPart(p, errs[i]))
+			} else {
+				retryMsg.Append(p)
+				retryErrs = append(retryErrs, errs[i])
+			}
+		}
+		if retryMsg.Len() == 0 {
+			break
+		}
+
+		if boff == nil {
+			boff = d.backoffCtor()
+		}
+		wait := boff.NextBackOff()
+		if attempt >= d.maxRetries || wait == backoff.Stop {
+			_ = retryMsg.Iter(func(i int, p types.Part) error {
+				routed = append(routed, deadLetterPart(p, retryErrs[i]))
+				return nil
+			})
+			break
+		}
+
+		d.mRetry.Incr(1)
+		d.log.Warnf("Failed to send message: %v\n", res.Error())
+		select {
+		case <-time.After(wait):
+		case <-d.closeChan:
+			return nil, false
+		}
+		msg = retryMsg
+	}
+
+	if len(routed) == 0 {
+		return response.NewAck(), true
+	}
+
+	d.log.Errorf("Routing %v messages to the dead letter output\n", len(routed))
+	d.mDeadLetter.Incr(int64(len(routed)))
+
+	dlMsg := message.New(nil)
+	dlMsg.SetAll(routed)
+	res, open := d.send(d.deadLetterOut, dlMsg)
+	if !open {
+		return nil, false
+	}
+	if res.Error() != nil {
+		d.log.Errorf("Failed to send message to dead letter output: %v\n", res.Error())
+		return response.NewError(res.Error()), true
+	}
+	return response.NewAck(), true
+}
+
+func (d *deadLetter) loop() {
+	var wg sync.WaitGroup
+
+	defer func() {
+		wg.Wait()
+		close(d.wrappedOut)
+		close(d.deadLetterOut)
+		d.wrapped.CloseAsync()
+		d.deadLetter.CloseAsync()
+		_ = d.wrapped.WaitForClose(shutdown.MaximumShutdownWait())
+		_ = d.deadLetter.WaitForClose(shutdown.MaximumShutdownWait())
+		close(d.closedChan)
+	}()
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+		case <-d.closeChan:
+			return
+		}
+
+		wg.Add(1)
+		go func(ts types.Transaction) {
+			defer wg.Done()
+
+			res, open := d.deliver(ts.Payload)
+			if !open {
+				return
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-d.closeChan:
+			}
+		}(tran)
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *deadLetter) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.wrappedOut); err != nil {
+		return err
+	}
+	if err := d.deadLetter.Consume(d.deadLetterOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *deadLetter) Connected() bool {
+	return d.wrapped.Connected()
+}
+
+// MaxInFlight returns the maximum number of in flight messages permitted by the
+// output. This value can be used to determine a sensible value for parent
+// outputs, but should not be relied upon as part of dispatcher logic.
+func (d *deadLetter) MaxInFlight() (int, bool) {
+	return output.GetMaxInFlight(d.wrapped)
+}
+
+// CloseAsync shuts down the output and stops processing requests.
+func (d *deadLetter) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the output has closed down.
+func (d *deadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
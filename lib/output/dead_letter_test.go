@@ -0,0 +1,108 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeadLetterConfigDefaults(t *testing.T) {
+	var dlConf DeadLetterConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+check: 'error().contains("nope")'
+backoff:
+  max_interval: 5s
+`), &dlConf))
+
+	assert.Equal(t, uint64(3), dlConf.MaxRetries)
+	assert.Equal(t, "500ms", dlConf.Backoff.InitialInterval)
+	assert.Equal(t, "5s", dlConf.Backoff.MaxInterval)
+	assert.Equal(t, `error().contains("nope")`, dlConf.Check)
+	assert.Nil(t, dlConf.Output)
+
+	conf := NewConfig()
+	conf.DeadLetter = &dlConf
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "failed to create dead letter output: a dead letter output must be specified")
+}
+
+func TestDeadLetterRouting(t *testing.T) {
+	dlConf := NewConfig()
+	dlConf.Type = TypeDrop
+
+	conf := NewDeadLetterConfig()
+	conf.Output = &dlConf
+	conf.MaxRetries = 1
+	conf.Backoff.InitialInterval = "10us"
+	conf.Backoff.MaxInterval = "10us"
+	conf.Check = `error().contains("permanent")`
+
+	mOut, mDeadLetter := &mockOutput{}, &mockOutput{}
+	d, err := newDeadLetter(conf, mOut, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	d.deadLetter.CloseAsync()
+	d.deadLetter = mDeadLetter
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	expectTran := func(ts <-chan types.Transaction, exp ...string) types.Transaction {
+		t.Helper()
+		var tran types.Transaction
+		select {
+		case tran = <-ts:
+		case <-resChan:
+			t.Fatal("received response early")
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		var act []string
+		for _, b := range message.GetAllBytes(tran.Payload) {
+			act = append(act, string(b))
+		}
+		assert.Equal(t, exp, act)
+		return tran
+	}
+
+	tran := expectTran(mOut.ts, "foo", "bar", "baz")
+	tran.ResponseChan <- response.NewError(batch.NewError(tran.Payload, errors.New("batch failed")).
+		Failed(0, errors.New("temporary")).
+		Failed(2, errors.New("permanent")))
+
+	tran = expectTran(mOut.ts, "foo")
+	tran.ResponseChan <- response.NewError(errors.New("temporary again"))
+
+	tran = expectTran(mDeadLetter.ts, "baz", "foo")
+	assert.Equal(t, "permanent", tran.Payload.Get(0).Metadata().Get(DeadLetterErrorKey))
+	assert.Equal(t, "temporary again", tran.Payload.Get(1).Metadata().Get(DeadLetterErrorKey))
+	assert.Equal(t, "temporary again", tran.Payload.Get(1).Metadata().Get(types.FailFlagKey))
+	tran.ResponseChan <- response.NewAck()
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	d.CloseAsync()
+	require.NoError(t, d.WaitForClose(time.Second))
+}
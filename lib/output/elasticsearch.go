@@ -26,6 +26,22 @@ Both the ` + "`id` and `index`" + ` fields can be dynamically set using function
 interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When
 sending batched messages these interpolations are performed per message part.
 
+### Actions
+
+The ` + "`action`" + ` field determines the bulk action performed for each message:
+
+- ` + "`index`" + ` adds or replaces the document with the ID.
+- ` + "`create`" + ` adds the document and fails when a document with the ID already exists, which is the only action supported by [data streams](https://www.elastic.co/guide/en/elasticsearch/reference/current/data-streams.html). When writing to a data stream the ` + "`type`" + ` field should be empty, and the ` + "`id`" + ` field can be left empty in order for IDs to be generated.
+- ` + "`update`" + ` updates the fields of an existing document with the fields of the message.
+- ` + "`upsert`" + ` updates the fields of an existing document with the fields of the message, or indexes the message as a new document when it does not exist.
+- ` + "`delete`" + ` deletes the document with the ID.
+
+When the field ` + "`script`" + ` is set the ` + "`update`" + ` and ` + "`upsert`" + ` actions instead execute the script on the existing document, where the message is provided to the script as its parameters. With the ` + "`upsert`" + ` action the message is indexed as a new document when it does not exist.
+
+### Errors
+
+Items of a batch that are rejected with a status of 429 or 5xx are retried according to the backoff fields, and only the rejected items are sent again. Items that are rejected with any other status are not retried, and only their messages are considered failed by the output.
+
 ### AWS
 
 It's possible to enable AWS connectivity with this output using the ` + "`aws`" + `
@@ -36,10 +52,11 @@ false for connections to succeed.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"http://localhost:9200"}).Array(),
 			docs.FieldCommon("index", "The index to place messages.").IsInterpolated(),
-			docs.FieldAdvanced("action", "The action to take on the document.").IsInterpolated().HasOptions("index", "update", "delete"),
-			docs.FieldAdvanced("pipeline", "An optional pipeline id to preprocess incoming documents.").IsInterpolated(),
+			docs.FieldAdvanced("action", "The action to take on the document.").IsInterpolated().HasOptions("index", "create", "update", "upsert", "delete"),
+			docs.FieldAdvanced("pipeline", "An optional pipeline id to preprocess incoming documents, which applies to the `index` and `create` actions.").IsInterpolated(),
 			docs.FieldCommon("id", "The ID for indexed messages. Interpolation should be used in order to create a unique ID for each message.").IsInterpolated(),
 			docs.FieldCommon("type", "The document type."),
+			docs.FieldAdvanced("script", "An optional [Painless script](https://www.elastic.co/guide/en/elasticsearch/reference/current/modules-scripting-painless.html) to execute for `update` and `upsert` actions, where the fields of the message are provided as the parameters of the script.", "ctx._source.counter += params.count").AtVersion("3.64.0"),
 			docs.FieldAdvanced("routing", "The routing key to use for the document.").IsInterpolated(),
 			docs.FieldAdvanced("sniff", "Prompts Benthos to sniff for brokers to connect to when establishing a connection."),
 			docs.FieldAdvanced("healthcheck", "Whether to enable healthchecks."),
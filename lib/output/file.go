@@ -3,6 +3,7 @@ package output
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -38,6 +39,7 @@ Messages can be written to different files by using [interpolation functions](/d
 				`/tmp/${! json("document.id") }.json`,
 			).IsInterpolated().AtVersion("3.33.0"),
 			codec.WriterDocs.AtVersion("3.33.0"),
+			codec.CompressionDocs.AtVersion("3.64.0"),
 			docs.FieldDeprecated("delimiter"),
 		},
 		Categories: []Category{
@@ -50,17 +52,19 @@ Messages can be written to different files by using [interpolation functions](/d
 
 // FileConfig contains configuration fields for the file based output type.
 type FileConfig struct {
-	Path  string `json:"path" yaml:"path"`
-	Codec string `json:"codec" yaml:"codec"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Path        string `json:"path" yaml:"path"`
+	Codec       string `json:"codec" yaml:"codec"`
+	Compression string `json:"compression" yaml:"compression"`
+	Delim       string `json:"delimiter" yaml:"delimiter"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Path:  "",
-		Codec: "lines",
-		Delim: "",
+		Path:        "",
+		Codec:       "lines",
+		Compression: "none",
+		Delim:       "",
 	}
 }
 
@@ -71,7 +75,7 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if len(conf.File.Delim) > 0 {
 		conf.File.Codec = "delim:" + conf.File.Delim
 	}
-	f, err := newFileWriter(conf.File.Path, conf.File.Codec, mgr, log, stats)
+	f, err := newFileWriter(conf.File.Path, conf.File.Codec, conf.File.Compression, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -94,15 +98,21 @@ type fileWriter struct {
 	path      *field.Expression
 	codec     codec.WriterConstructor
 	codecConf codec.WriterConfig
+	compress  codec.CompressionWriterConstructor
 
-	handleMut  sync.Mutex
-	handlePath string
-	handle     codec.Writer
+	handleMut   sync.Mutex
+	handlePath  string
+	handle      codec.Writer
+	handleFlush func() error
 
 	shutSig *shutdown.Signaller
 }
 
-func newFileWriter(pathStr, codecStr string, mgr types.Manager, log log.Modular, stats metrics.Type) (*fileWriter, error) {
+func newFileWriter(pathStr, codecStr, compressionStr string, mgr types.Manager, log log.Modular, stats metrics.Type) (*fileWriter, error) {
+	compress, err := codec.GetCompressionWriter(compressionStr)
+	if err != nil {
+		return nil, err
+	}
 	codec, codecConf, err := codec.GetWriter(codecStr)
 	if err != nil {
 		return nil, err
@@ -114,6 +124,7 @@ func newFileWriter(pathStr, codecStr string, mgr types.Manager, log log.Modular,
 	return &fileWriter{
 		codec:     codec,
 		codecConf: codecConf,
+		compress:  compress,
 		path:      path,
 		log:       log,
 		stats:     stats,
@@ -135,10 +146,15 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 		defer w.handleMut.Unlock()
 
 		if w.handle != nil && path == w.handlePath {
-			return w.handle.Write(ctx, p)
+			if err := w.handle.Write(ctx, p); err != nil {
+				return err
+			}
+			return w.flush()
 		}
 		if w.handle != nil {
-			if err := w.handle.Close(ctx); err != nil {
+			err := w.handle.Close(ctx)
+			w.handle, w.handleFlush = nil, nil
+			if err != nil {
 				return err
 			}
 		}
@@ -160,8 +176,19 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 			return err
 		}
 
+		var out io.WriteCloser = file
+		var flush func() error
+		if w.compress != nil {
+			comp, err := w.compress(file)
+			if err != nil {
+				file.Close()
+				return err
+			}
+			out, flush = comp, comp.Flush
+		}
+
 		w.handlePath = path
-		handle, err := w.codec(file)
+		handle, err := w.codec(out)
 		if err != nil {
 			return err
 		}
@@ -172,11 +199,10 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 		}
 
 		if !w.codecConf.CloseAfter {
-			w.handle = handle
-		} else {
-			handle.Close(ctx)
+			w.handle, w.handleFlush = handle, flush
+			return w.flush()
 		}
-		return nil
+		return handle.Close(ctx)
 	})
 	if err != nil {
 		return err
@@ -192,13 +218,22 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 	return nil
 }
 
+// flush writes data buffered by the compression of the open file so that
+// messages are on disk by the time they are acknowledged.
+func (w *fileWriter) flush() error {
+	if w.handleFlush == nil {
+		return nil
+	}
+	return w.handleFlush()
+}
+
 // CloseAsync shuts down the File output and stops processing messages.
 func (w *fileWriter) CloseAsync() {
 	go func() {
 		w.handleMut.Lock()
 		if w.handle != nil {
 			w.handle.Close(context.Background())
-			w.handle = nil
+			w.handle, w.handleFlush = nil, nil
 		}
 		w.handleMut.Unlock()
 		w.shutSig.ShutdownComplete()
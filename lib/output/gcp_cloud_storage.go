@@ -2,6 +2,7 @@ package output
 
 import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 	"google.golang.org/api/googleapi"
 )
 
@@ -22,10 +23,12 @@ const (
 // GCPCloudStorageConfig contains configuration fields for the GCP Cloud Storage
 // output type.
 type GCPCloudStorageConfig struct {
+	gcpcreds.Config `json:",inline" yaml:",inline"`
 	Bucket          string             `json:"bucket" yaml:"bucket"`
 	Path            string             `json:"path" yaml:"path"`
 	ContentType     string             `json:"content_type" yaml:"content_type"`
 	ContentEncoding string             `json:"content_encoding" yaml:"content_encoding"`
+	Compression     string             `json:"compression" yaml:"compression"`
 	ChunkSize       int                `json:"chunk_size" yaml:"chunk_size"`
 	MaxInFlight     int                `json:"max_in_flight" yaml:"max_in_flight"`
 	Batching        batch.PolicyConfig `json:"batching" yaml:"batching"`
@@ -35,10 +38,12 @@ type GCPCloudStorageConfig struct {
 // NewGCPCloudStorageConfig creates a new Config with default values.
 func NewGCPCloudStorageConfig() GCPCloudStorageConfig {
 	return GCPCloudStorageConfig{
+		Config:          gcpcreds.NewConfig(),
 		Bucket:          "",
 		Path:            `${!count("files")}-${!timestamp_unix_nano()}.txt`,
 		ContentType:     "application/octet-stream",
 		ContentEncoding: "",
+		Compression:     "none",
 		ChunkSize:       googleapi.DefaultUploadChunkSize,
 		MaxInFlight:     1,
 		Batching:        batch.NewPolicyConfig(),
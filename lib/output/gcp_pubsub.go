@@ -7,6 +7,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 )
 
 //------------------------------------------------------------------------------
@@ -40,14 +41,14 @@ pipeline:
     - bloblang: meta = deleted()
 ` + "```" + ``,
 		Async: true,
-		FieldSpecs: docs.FieldSpecs{
+		FieldSpecs: append(docs.FieldSpecs{
 			docs.FieldCommon("project", "The project ID of the topic to publish to."),
 			docs.FieldCommon("topic", "The topic to publish to.").IsInterpolated(),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 			docs.FieldAdvanced("publish_timeout", "The maximum length of time to wait before abandoning a publish attempt for a message.", "10s", "5m", "60m"),
 			docs.FieldAdvanced("ordering_key", "The ordering key to use for publishing messages.").IsInterpolated(),
 			docs.FieldCommon("metadata", "Specify criteria for which metadata values are sent as attributes.").WithChildren(metadata.ExcludeFilterFields()...),
-		},
+		}, gcpcreds.FieldSpecs()...),
 		Categories: []Category{
 			CategoryServices,
 			CategoryGCP,
@@ -20,11 +20,19 @@ Pushes messages to an MQTT broker.`,
 		Description: `
 The ` + "`topic`" + ` field can be dynamically set using function interpolations
 described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched
-messages these interpolations are performed per message part.`,
+messages these interpolations are performed per message part.
+
+### MQTT 5
+
+When ` + "`protocol_version`" + ` is set to ` + "`5`" + ` the output connects with MQTT 5.0, where each message can be sent with ` + "`user_properties`" + `, and a message is only acknowledged once the broker has completed the delivery flow of its QoS, which for QoS 2 is once the release of the message has been confirmed. Messages rejected by the broker with a failure reason code are retried.`,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("urls", "A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.", []string{"tcp://localhost:1883"}).Array(),
+			mqttconf.ProtocolVersionFieldSpec(),
 			docs.FieldCommon("topic", "The topic to publish messages to."),
+			docs.FieldString("user_properties", "A map of user properties to add to each message. This field is only used when connecting with MQTT 5.", map[string]string{
+				"source": `${! meta("kafka_topic") }`,
+			}).IsInterpolated().Map().Advanced().AtVersion("3.64.0"),
 			docs.FieldCommon("client_id", "An identifier for the client connection."),
 			docs.FieldString("dynamic_client_id_suffix", "Append a dynamically generated suffix to the specified `client_id` on each run of the pipeline. This can be useful when clustering Benthos producers.").Optional().Advanced().HasAnnotatedOptions(
 				"nanoid", "append a nanoid of length 21 characters",
@@ -51,7 +59,17 @@ messages these interpolations are performed per message part.`,
 
 // NewMQTT creates a new MQTT output type.
 func NewMQTT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	w, err := writer.NewMQTTV2(conf.MQTT, mgr, log, stats)
+	if err := mqttconf.ValidateProtocolVersion(conf.MQTT.ProtocolVersion); err != nil {
+		return nil, err
+	}
+
+	var w AsyncSink
+	var err error
+	if conf.MQTT.ProtocolVersion == mqttconf.ProtocolVersion5 {
+		w, err = writer.NewMQTTV5(conf.MQTT, mgr, log, stats)
+	} else {
+		w, err = writer.NewMQTTV2(conf.MQTT, mgr, log, stats)
+	}
 	if err != nil {
 		return nil, err
 	}
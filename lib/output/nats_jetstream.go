@@ -9,11 +9,13 @@ import (
 // NATSJetStreamConfig contains configuration fields for the NATS Jetstream
 // input type.
 type NATSJetStreamConfig struct {
-	URLs        []string    `json:"urls" yaml:"urls"`
-	Subject     string      `json:"subject" yaml:"subject"`
-	MaxInFlight int         `json:"max_in_flight" yaml:"max_in_flight"`
-	TLS         tls.Config  `json:"tls" yaml:"tls"`
-	Auth        auth.Config `json:"auth" yaml:"auth"`
+	URLs        []string                  `json:"urls" yaml:"urls"`
+	Subject     string                    `json:"subject" yaml:"subject"`
+	MsgID       string                    `json:"msg_id" yaml:"msg_id"`
+	MaxInFlight int                       `json:"max_in_flight" yaml:"max_in_flight"`
+	Stream      NATSJetStreamStreamConfig `json:"stream" yaml:"stream"`
+	TLS         tls.Config                `json:"tls" yaml:"tls"`
+	Auth        auth.Config               `json:"auth" yaml:"auth"`
 }
 
 // NewNATSJetStreamConfig creates a new NATSJetstreamConfig with default values.
@@ -22,7 +24,30 @@ func NewNATSJetStreamConfig() NATSJetStreamConfig {
 		URLs:        []string{nats.DefaultURL},
 		Subject:     "",
 		MaxInFlight: 1024,
+		Stream:      NewNATSJetStreamStreamConfig(),
 		TLS:         tls.NewConfig(),
 		Auth:        auth.New(),
 	}
 }
+
+// NATSJetStreamStreamConfig describes a NATS JetStream stream to bind to or
+// create.
+type NATSJetStreamStreamConfig struct {
+	Name            string   `json:"name" yaml:"name"`
+	Create          bool     `json:"create" yaml:"create"`
+	Subjects        []string `json:"subjects" yaml:"subjects"`
+	Storage         string   `json:"storage" yaml:"storage"`
+	Replicas        int      `json:"replicas" yaml:"replicas"`
+	MaxAge          string   `json:"max_age" yaml:"max_age"`
+	DuplicateWindow string   `json:"duplicate_window" yaml:"duplicate_window"`
+}
+
+// NewNATSJetStreamStreamConfig creates a new NATSJetStreamStreamConfig with
+// default values.
+func NewNATSJetStreamStreamConfig() NATSJetStreamStreamConfig {
+	return NATSJetStreamStreamConfig{
+		Subjects: []string{},
+		Storage:  "file",
+		Replicas: 1,
+	}
+}
@@ -4,12 +4,13 @@ import "github.com/Jeffail/benthos/v3/internal/impl/pulsar/auth"
 
 // PulsarConfig contains configuration for the Pulsar input type.
 type PulsarConfig struct {
-	URL         string      `json:"url" yaml:"url"`
-	Topic       string      `json:"topic" yaml:"topic"`
-	MaxInFlight int         `json:"max_in_flight" yaml:"max_in_flight"`
-	Key         string      `json:"key" yaml:"key"`
-	OrderingKey string      `json:"ordering_key" yaml:"ordering_key"`
-	Auth        auth.Config `json:"auth" yaml:"auth"`
+	URL         string         `json:"url" yaml:"url"`
+	Topic       string         `json:"topic" yaml:"topic"`
+	MaxInFlight int            `json:"max_in_flight" yaml:"max_in_flight"`
+	Key         string         `json:"key" yaml:"key"`
+	OrderingKey string         `json:"ordering_key" yaml:"ordering_key"`
+	TLS         auth.TLSConfig `json:"tls" yaml:"tls"`
+	Auth        auth.Config    `json:"auth" yaml:"auth"`
 }
 
 // NewPulsarConfig creates a new PulsarConfig with default values.
@@ -20,6 +21,7 @@ func NewPulsarConfig() PulsarConfig {
 		MaxInFlight: 1,
 		Key:         "",
 		OrderingKey: "",
+		TLS:         auth.NewTLS(),
 		Auth:        auth.New(),
 	}
 }
@@ -0,0 +1,95 @@
+package output_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/Jeffail/benthos/v3/public/components/all"
+)
+
+func TestOutputRateLimit(t *testing.T) {
+	rlConf := ratelimit.NewConfig()
+	rlConf.Local.Count = 2
+	rlConf.Local.Interval = "200ms"
+
+	mgrConf := manager.NewConfig()
+	mgrConf.RateLimits["foolimit"] = rlConf
+
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeInproc
+	conf.Inproc = "foo"
+	conf.RateLimit = "foolimit"
+
+	out, err := output.New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tinchan := make(chan types.Transaction)
+	require.NoError(t, out.Consume(tinchan))
+
+	toutchan, err := mgr.GetPipe("foo")
+	require.NoError(t, err)
+
+	startedAt := time.Now()
+	for i := 0; i < 3; i++ {
+		resChan := make(chan types.Response)
+		select {
+		case tinchan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+
+		var tran types.Transaction
+		select {
+		case tran = <-toutchan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		assert.Equal(t, [][]byte{[]byte("hello world")}, message.GetAllBytes(tran.Payload))
+
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case <-resChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+
+		if i < 2 {
+			assert.Less(t, int64(time.Since(startedAt)), int64(time.Millisecond*150))
+		}
+	}
+	assert.GreaterOrEqual(t, int64(time.Since(startedAt)), int64(time.Millisecond*150))
+
+	out.CloseAsync()
+	require.NoError(t, out.WaitForClose(time.Second))
+}
+
+func TestOutputRateLimitMissing(t *testing.T) {
+	mgr, err := manager.New(manager.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf := output.NewConfig()
+	conf.Type = output.TypeInproc
+	conf.Inproc = "foo"
+	conf.RateLimit = "doesnotexist"
+
+	_, err = output.New(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -8,10 +8,13 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/component/output"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -37,11 +40,26 @@ This output type is useful whenever we wish to avoid reprocessing a message on
 the event of a failed send. We might, for example, have a dedupe processor that
 we want to avoid reapplying to the same message more than once in the pipeline.
 
+If the child output reports which messages of a batch failed then only those
+messages are retried.
+
+The period between attempts grows exponentially from the initial interval up to
+the max interval, with a random variation determined by the field ` + "`jitter`" + `
+so that many failed messages are not all retried at the same time. Once either
+` + "`max_retries`" + ` or ` + "`backoff.max_elapsed_time`" + ` is reached the messages
+are considered poisonous and are routed to the ` + "`fallback`" + ` output if one is
+specified, with their error stored in the metadata field ` + "`dead_letter_error`" + `,
+otherwise they are rejected. This prevents broken messages from blocking the
+pipeline forever. The gauge metric ` + "`retry.depth`" + ` reports the number of
+messages that are currently being reattempted.
+
 Rather than retrying the same output you may wish to retry the send using a
 different output target (a dead letter queue). In which case you should instead
 use the ` + "[`fallback`](/docs/components/outputs/fallback)" + ` output type.`,
 		FieldSpecs: retries.FieldSpecs().Add(
 			docs.FieldCommon("output", "A child output.").HasType(docs.FieldTypeOutput),
+			docs.FieldFloat("jitter", "A factor between 0 and 1 of random variation applied to each period between retry attempts.").Advanced().AtVersion("3.64.0"),
+			docs.FieldAdvanced("fallback", "An optional output that messages are routed to once the retries or max elapsed time are exhausted, otherwise they are rejected.").HasType(docs.FieldTypeOutput).AtVersion("3.64.0"),
 		),
 		Categories: []Category{
 			CategoryUtility,
@@ -55,6 +73,8 @@ use the ` + "[`fallback`](/docs/components/outputs/fallback)" + ` output type.`,
 type RetryConfig struct {
 	Output         *Config `json:"output" yaml:"output"`
 	retries.Config `json:",inline" yaml:",inline"`
+	Jitter         float64 `json:"jitter" yaml:"jitter"`
+	Fallback       *Config `json:"fallback,omitempty" yaml:"fallback,omitempty"`
 }
 
 // NewRetryConfig creates a new RetryConfig with default values.
@@ -65,8 +85,10 @@ func NewRetryConfig() RetryConfig {
 	rConf.Backoff.MaxInterval = "1s"
 	rConf.Backoff.MaxElapsedTime = "0s"
 	return RetryConfig{
-		Output: nil,
-		Config: retries.NewConfig(),
+		Output:   nil,
+		Config:   retries.NewConfig(),
+		Jitter:   0.5,
+		Fallback: nil,
 	}
 }
 
@@ -75,13 +97,17 @@ func NewRetryConfig() RetryConfig {
 type dummyRetryConfig struct {
 	Output         interface{} `json:"output" yaml:"output"`
 	retries.Config `json:",inline" yaml:",inline"`
+	Jitter         float64 `json:"jitter" yaml:"jitter"`
+	Fallback       *Config `json:"fallback,omitempty" yaml:"fallback,omitempty"`
 }
 
 // MarshalJSON prints an empty object instead of nil.
 func (r RetryConfig) MarshalJSON() ([]byte, error) {
 	dummy := dummyRetryConfig{
-		Output: r.Output,
-		Config: r.Config,
+		Output:   r.Output,
+		Config:   r.Config,
+		Jitter:   r.Jitter,
+		Fallback: r.Fallback,
 	}
 	if r.Output == nil {
 		dummy.Output = struct{}{}
@@ -92,8 +118,10 @@ func (r RetryConfig) MarshalJSON() ([]byte, error) {
 // MarshalYAML prints an empty object instead of nil.
 func (r RetryConfig) MarshalYAML() (interface{}, error) {
 	dummy := dummyRetryConfig{
-		Output: r.Output,
-		Config: r.Config,
+		Output:   r.Output,
+		Config:   r.Config,
+		Jitter:   r.Jitter,
+		Fallback: r.Fallback,
 	}
 	if r.Output == nil {
 		dummy.Output = struct{}{}
@@ -110,6 +138,7 @@ type Retry struct {
 	conf    RetryConfig
 
 	wrapped     Type
+	fallback    Type
 	backoffCtor func() backoff.BackOff
 
 	stats metrics.Type
@@ -117,6 +146,7 @@ type Retry struct {
 
 	transactionsIn  <-chan types.Transaction
 	transactionsOut chan types.Transaction
+	fallbackOut     chan types.Transaction
 
 	closeChan  chan struct{}
 	closedChan chan struct{}
@@ -139,10 +169,18 @@ func NewRetry(
 	}
 
 	var boffCtor func() backoff.BackOff
-	if boffCtor, err = conf.Retry.GetCtor(); err != nil {
+	if boffCtor, err = retryBackoffCtor(conf.Retry); err != nil {
 		return nil, err
 	}
 
+	var fallback Type
+	if conf.Retry.Fallback != nil {
+		fMgr, fLog, fStats := interop.LabelChild("fallback", mgr, log, stats)
+		if fallback, err = New(*conf.Retry.Fallback, fMgr, fLog, fStats); err != nil {
+			return nil, fmt.Errorf("failed to create fallback output '%v': %v", conf.Retry.Fallback.Type, err)
+		}
+	}
+
 	return &Retry{
 		running: 1,
 		conf:    conf.Retry,
@@ -150,8 +188,10 @@ func NewRetry(
 		log:             log,
 		stats:           stats,
 		wrapped:         wrapped,
+		fallback:        fallback,
 		backoffCtor:     boffCtor,
 		transactionsOut: make(chan types.Transaction),
+		fallbackOut:     make(chan types.Transaction),
 
 		closeChan:  make(chan struct{}),
 		closedChan: make(chan struct{}),
@@ -160,6 +200,32 @@ func NewRetry(
 
 //------------------------------------------------------------------------------
 
+// retryBackoffCtor returns a constructor for the backoff of a retry output,
+// where the jitter of the config sets the randomization factor.
+func retryBackoffCtor(conf RetryConfig) (func() backoff.BackOff, error) {
+	if conf.Jitter < 0 || conf.Jitter > 1 {
+		return nil, fmt.Errorf("jitter must be between 0 and 1, got %v", conf.Jitter)
+	}
+
+	bConf := conf.Config
+	bConf.MaxRetries = 0
+	boffCtor, err := bConf.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() backoff.BackOff {
+		var boff backoff.BackOff = boffCtor()
+		if eBoff, ok := boff.(*backoff.ExponentialBackOff); ok {
+			eBoff.RandomizationFactor = conf.Jitter
+		}
+		if conf.MaxRetries > 0 {
+			boff = backoff.WithMaxRetries(boff, conf.MaxRetries)
+		}
+		return boff
+	}, nil
+}
+
 func (r *Retry) loop() {
 	// Metrics paths
 	var (
@@ -169,6 +235,9 @@ func (r *Retry) loop() {
 		mPartsSuccess = r.stats.GetCounter("retry.parts.send.success")
 		mError        = r.stats.GetCounter("retry.send.error")
 		mEndOfRetries = r.stats.GetCounter("retry.end_of_retries")
+		mDepth        = r.stats.GetGauge("retry.depth")
+		mFallback     = r.stats.GetCounter("retry.fallback.send.success")
+		mFallbackErr  = r.stats.GetCounter("retry.fallback.send.error")
 	)
 
 	wg := sync.WaitGroup{}
@@ -176,7 +245,12 @@ func (r *Retry) loop() {
 	defer func() {
 		wg.Wait()
 		close(r.transactionsOut)
+		close(r.fallbackOut)
 		r.wrapped.CloseAsync()
+		if r.fallback != nil {
+			r.fallback.CloseAsync()
+			_ = r.fallback.WaitForClose(shutdown.MaximumShutdownWait())
+		}
 		_ = r.wrapped.WaitForClose(shutdown.MaximumShutdownWait())
 		mRunning.Decr(1)
 		close(r.closedChan)
@@ -224,10 +298,15 @@ func (r *Retry) loop() {
 			var resOut types.Response
 			var inErrLoop bool
 
+			// Only the messages that failed are retried when the error
+			// identifies them.
+			payload := ts.Payload
+
 			defer func() {
 				wg.Done()
 				if inErrLoop {
 					atomic.AddInt64(&errLooped, -1)
+					mDepth.Decr(1)
 
 					// We're exiting our error loop, so (attempt to) interrupt the
 					// consumer.
@@ -250,6 +329,7 @@ func (r *Retry) loop() {
 					if !inErrLoop {
 						inErrLoop = true
 						atomic.AddInt64(&errLooped, 1)
+						mDepth.Incr(1)
 					}
 
 					mError.Incr(1)
@@ -262,7 +342,21 @@ func (r *Retry) loop() {
 					if nextBackoff == backoff.Stop {
 						mEndOfRetries.Incr(1)
 						r.log.Errorf("Failed to send message: %v\n", res.Error())
-						resOut = response.NewNoack()
+						if r.fallback == nil {
+							resOut = response.NewNoack()
+							break
+						}
+						var open bool
+						if resOut, open = r.sendToFallback(payload, res.Error()); !open {
+							return
+						}
+						if resOut.Error() != nil {
+							mFallbackErr.Incr(1)
+							r.log.Errorf("Failed to send message to fallback output: %v\n", resOut.Error())
+							resOut = response.NewNoack()
+						} else {
+							mFallback.Incr(1)
+						}
 						break
 					} else {
 						r.log.Warnf("Failed to send message: %v\n", res.Error())
@@ -273,8 +367,11 @@ func (r *Retry) loop() {
 						return
 					}
 
+					if failed, _, ok := batch.FailedSubset(payload, res.Error()); ok {
+						payload = failed
+					}
 					select {
-					case r.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+					case r.transactionsOut <- types.NewTransaction(payload, resChan):
 					case <-r.closeChan:
 						return
 					}
@@ -295,6 +392,31 @@ func (r *Retry) loop() {
 	}
 }
 
+// sendToFallback writes the failed messages of a batch to the fallback output
+// with their errors attached, returning false if the output was closed before
+// a response was received.
+func (r *Retry) sendToFallback(msg types.Message, err error) (types.Response, bool) {
+	parts, errs := failedParts(msg, err)
+	for i, p := range parts {
+		parts[i] = deadLetterPart(p, errs[i])
+	}
+	fMsg := message.New(nil)
+	fMsg.SetAll(parts)
+
+	resChan := make(chan types.Response)
+	select {
+	case r.fallbackOut <- types.NewTransaction(fMsg, resChan):
+	case <-r.closeChan:
+		return nil, false
+	}
+	select {
+	case res := <-resChan:
+		return res, true
+	case <-r.closeChan:
+		return nil, false
+	}
+}
+
 // Consume assigns a messages channel for the output to read.
 func (r *Retry) Consume(ts <-chan types.Transaction) error {
 	if r.transactionsIn != nil {
@@ -303,6 +425,11 @@ func (r *Retry) Consume(ts <-chan types.Transaction) error {
 	if err := r.wrapped.Consume(r.transactionsOut); err != nil {
 		return err
 	}
+	if r.fallback != nil {
+		if err := r.fallback.Consume(r.fallbackOut); err != nil {
+			return err
+		}
+	}
 	r.transactionsIn = ts
 	go r.loop()
 	return nil
@@ -1,9 +1,11 @@
 package output
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -191,6 +193,215 @@ func TestRetrySadPath(t *testing.T) {
 	}
 }
 
+func TestRetryBatchErrorSubset(t *testing.T) {
+	conf := NewConfig()
+
+	childConf := NewConfig()
+	conf.Retry.Output = &childConf
+	conf.Retry.Backoff.InitialInterval = "10us"
+	conf.Retry.Backoff.MaxInterval = "10us"
+
+	output, err := NewRetry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, ok := output.(*Retry)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut := &mockOutput{
+		ts: make(chan types.Transaction),
+	}
+	ret.wrapped = mOut
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = ret.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	tran := types.NewTransaction(testMsg, resChan)
+
+	go func() {
+		select {
+		case tChan <- tran:
+		case <-time.After(time.Second):
+			t.Error("timed out")
+		}
+	}()
+
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if tran.Payload != testMsg {
+		t.Error("Wrong payload returned")
+	}
+
+	testErr := errors.New("test error")
+	select {
+	case tran.ResponseChan <- response.NewError(batch.NewError(tran.Payload, testErr).Failed(1, testErr)):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case tran = <-mOut.ts:
+	case <-resChan:
+		t.Fatal("Received response not retry")
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := 1, tran.Payload.Len(); exp != act {
+		t.Fatalf("Wrong count of retried messages: %v != %v", act, exp)
+	}
+	if exp, act := "bar", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message retried: %v != %v", act, exp)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err = res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	output.CloseAsync()
+	if err = output.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryFallback(t *testing.T) {
+	conf := NewConfig()
+
+	childConf := NewConfig()
+	fallbackConf := NewConfig()
+	fallbackConf.Type = TypeDrop
+	conf.Retry.Output = &childConf
+	conf.Retry.Fallback = &fallbackConf
+	conf.Retry.MaxRetries = 2
+	conf.Retry.Backoff.InitialInterval = "10us"
+	conf.Retry.Backoff.MaxInterval = "10us"
+
+	output, err := NewRetry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, ok := output.(*Retry)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut, mFallback := &mockOutput{}, &mockOutput{}
+	ret.wrapped = mOut
+	ret.fallback.CloseAsync()
+	ret.fallback = mFallback
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = ret.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("foo")})
+	tran := types.NewTransaction(testMsg, resChan)
+
+	go func() {
+		select {
+		case tChan <- tran:
+		case <-time.After(time.Second):
+			t.Error("timed out")
+		}
+	}()
+
+	testErr := errors.New("test error")
+	for i := 0; i < 3; i++ {
+		select {
+		case tran = <-mOut.ts:
+		case <-resChan:
+			t.Fatal("Received response not retry")
+		case tran = <-mFallback.ts:
+			t.Fatal("Received fallback not retry")
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+
+		select {
+		case tran.ResponseChan <- response.NewError(testErr):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case tran = <-mFallback.ts:
+	case <-mOut.ts:
+		t.Fatal("Received retry not fallback")
+	case <-resChan:
+		t.Fatal("Received response not fallback")
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if exp, act := "foo", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message routed: %v != %v", act, exp)
+	}
+	if exp, act := "test error", tran.Payload.Get(0).Metadata().Get(DeadLetterErrorKey); exp != act {
+		t.Errorf("Wrong error metadata: %v != %v", act, exp)
+	}
+	if exp, act := "", testMsg.Get(0).Metadata().Get(DeadLetterErrorKey); exp != act {
+		t.Errorf("Original message was modified: %v != %v", act, exp)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err = res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	output.CloseAsync()
+	if err = output.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryBadJitter(t *testing.T) {
+	conf := NewConfig()
+
+	childConf := NewConfig()
+	conf.Retry.Output = &childConf
+	conf.Retry.Jitter = 1.5
+
+	if _, err := NewRetry(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad jitter")
+	}
+}
+
 func expectFromRetry(
 	resReturn types.Response,
 	tChan <-chan types.Transaction,
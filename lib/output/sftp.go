@@ -2,6 +2,7 @@ package output
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,9 +15,11 @@ import (
 	sftpSetup "github.com/Jeffail/benthos/v3/internal/impl/sftp"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/sftp"
 )
 
@@ -35,7 +38,11 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return OnlySinglePayloads(a), nil
+			if conf.SFTP.AtomicWrites.Enabled {
+				// Batches are written in full before any file is renamed.
+				return NewBatcherFromConfig(conf.SFTP.Batching, a, mgr, log, stats)
+			}
+			return NewBatcherFromConfig(conf.SFTP.Batching, OnlySinglePayloads(a), mgr, log, stats)
 		}),
 		Status:  docs.StatusExperimental,
 		Version: "3.39.0",
@@ -43,6 +50,12 @@ func init() {
 		Description: `
 In order to have a different path for each object you should use function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
 
+## Atomic Writes
+
+By default files are written to directly, and therefore consumers of the server can observe files that are only partially written. When ` + "`atomic_writes.enabled`" + ` is set each batch of messages is instead written to temporary files, named by adding a unique identifier followed by ` + "`atomic_writes.temp_suffix`" + ` to the path of each file, and once the entire batch has been written the temporary files are renamed to their final paths. A file is therefore only visible in full, and when a batch fails to be written its temporary files are removed.
+
+With atomic writes each file is written from scratch by a batch, which means that a file that already exists at a path is replaced rather than appended to. Renaming files over existing files requires the server to support the ` + "`posix-rename@openssh.com`" + ` extension, which is the case for OpenSSH.
+
 ` + multipartCodecDoc,
 		Async: true,
 		FieldSpecs: docs.FieldSpecs{
@@ -60,6 +73,14 @@ In order to have a different path for each object you should use function interp
 				"The credentials to use to log into the server.",
 			).WithChildren(sftpSetup.CredentialsDocs()...),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			docs.FieldAdvanced(
+				"atomic_writes",
+				"Write each batch to temporary files which are renamed to their final paths once the batch has been written in full.",
+			).WithChildren(
+				docs.FieldCommon("enabled", "Whether atomic writes are enabled."),
+				docs.FieldCommon("temp_suffix", "A suffix added to the path of each file whilst it is being written to."),
+			).AtVersion("3.64.0"),
+			batch.FieldSpec().AtVersion("3.64.0"),
 		},
 		Categories: []Category{
 			CategoryNetwork,
@@ -71,11 +92,18 @@ In order to have a different path for each object you should use function interp
 
 // SFTPConfig contains configuration fields for the SFTP output type.
 type SFTPConfig struct {
-	Address     string                `json:"address" yaml:"address"`
-	Path        string                `json:"path" yaml:"path"`
-	Codec       string                `json:"codec" yaml:"codec"`
-	Credentials sftpSetup.Credentials `json:"credentials" yaml:"credentials"`
-	MaxInFlight int                   `json:"max_in_flight" yaml:"max_in_flight"`
+	Address      string                 `json:"address" yaml:"address"`
+	Path         string                 `json:"path" yaml:"path"`
+	Codec        string                 `json:"codec" yaml:"codec"`
+	Credentials  sftpSetup.Credentials  `json:"credentials" yaml:"credentials"`
+	MaxInFlight  int                    `json:"max_in_flight" yaml:"max_in_flight"`
+	AtomicWrites sftpAtomicWritesConfig `json:"atomic_writes" yaml:"atomic_writes"`
+	Batching     batch.PolicyConfig     `json:"batching" yaml:"batching"`
+}
+
+type sftpAtomicWritesConfig struct {
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+	TempSuffix string `json:"temp_suffix" yaml:"temp_suffix"`
 }
 
 // NewSFTPConfig creates a new Config with default values.
@@ -89,6 +117,11 @@ func NewSFTPConfig() SFTPConfig {
 			Password: "",
 		},
 		MaxInFlight: 1,
+		AtomicWrites: sftpAtomicWritesConfig{
+			Enabled:    false,
+			TempSuffix: ".tmp",
+		},
+		Batching: batch.NewPolicyConfig(),
 	}
 }
 
@@ -128,6 +161,9 @@ func newSFTPWriter(
 	if s.path, err = interop.NewBloblangField(mgr, conf.Path); err != nil {
 		return nil, fmt.Errorf("failed to parse path expression: %w", err)
 	}
+	if conf.AtomicWrites.Enabled && conf.AtomicWrites.TempSuffix == "" {
+		return nil, errors.New("a temp_suffix must be specified when atomic writes are enabled")
+	}
 
 	return s, nil
 }
@@ -155,6 +191,10 @@ func (s *sftpWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 		return types.ErrNotConnected
 	}
 
+	if s.conf.AtomicWrites.Enabled {
+		return s.writeAtomic(ctx, client, msg)
+	}
+
 	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
 		path := s.path.String(i, msg)
 
@@ -208,6 +248,101 @@ func (s *sftpWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 	})
 }
 
+type sftpTempFile struct {
+	path     string
+	tempPath string
+	handle   codec.Writer
+}
+
+// writeAtomic writes the messages of a batch to temporary files and, once all
+// messages have been written, renames each file to its final path.
+func (s *sftpWriter) writeAtomic(ctx context.Context, client *sftp.Client, msg types.Message) error {
+	files := map[string]*sftpTempFile{}
+	var order []*sftpTempFile
+
+	cleanUp := func() {
+		for _, f := range order {
+			if f.handle != nil {
+				f.handle.Close(ctx)
+			}
+			if err := client.Remove(f.tempPath); err != nil {
+				s.log.Warnf("Failed to remove temporary file '%v': %v\n", f.tempPath, err)
+			}
+		}
+	}
+
+	if err := msg.Iter(func(i int, p types.Part) error {
+		path := s.path.String(i, msg)
+
+		f, exists := files[path]
+		if !exists {
+			u4, err := uuid.NewV4()
+			if err != nil {
+				return err
+			}
+			f = &sftpTempFile{
+				path:     path,
+				tempPath: path + "." + u4.String() + s.conf.AtomicWrites.TempSuffix,
+			}
+			if err := client.MkdirAll(filepath.Dir(path)); err != nil {
+				return err
+			}
+			files[path] = f
+			order = append(order, f)
+		}
+
+		if f.handle == nil {
+			// Codecs that close files after each message replace the file
+			// contents, and therefore the file is truncated when reopened.
+			file, err := client.OpenFile(f.tempPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC)
+			if err != nil {
+				return err
+			}
+			if f.handle, err = s.codec(file); err != nil {
+				file.Close()
+				return err
+			}
+		}
+
+		if err := f.handle.Write(ctx, p); err != nil {
+			return err
+		}
+		if s.codecConf.CloseAfter {
+			err := f.handle.Close(ctx)
+			f.handle = nil
+			return err
+		}
+		return nil
+	}); err != nil {
+		cleanUp()
+		return err
+	}
+
+	for _, f := range order {
+		if f.handle == nil {
+			continue
+		}
+		err := f.handle.Close(ctx)
+		f.handle = nil
+		if err != nil {
+			cleanUp()
+			return err
+		}
+	}
+
+	for i, f := range order {
+		if err := client.PosixRename(f.tempPath, f.path); err != nil {
+			for _, c := range order[i:] {
+				if rerr := client.Remove(c.tempPath); rerr != nil {
+					s.log.Warnf("Failed to remove temporary file '%v': %v\n", c.tempPath, rerr)
+				}
+			}
+			return fmt.Errorf("failed to rename temporary file '%v' to '%v': %w", f.tempPath, f.path, err)
+		}
+	}
+	return nil
+}
+
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (s *sftpWriter) CloseAsync() {
 	go func() {
@@ -2,8 +2,11 @@ package output
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	imessage "github.com/Jeffail/benthos/v3/internal/message"
 	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/lib/api"
 	"github.com/Jeffail/benthos/v3/lib/condition"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -23,6 +27,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/util/throttle"
 	"github.com/Jeffail/gabs/v2"
 	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -47,7 +52,34 @@ func init() {
 		Summary: `
 The switch output type allows you to route messages to different outputs based on their contents.`,
 		Description: `
-Messages must successfully route to one or more outputs, otherwise this is considered an error and the message is reprocessed. In order to explicitly drop messages that do not match your cases add one final case with a [drop output](/docs/components/outputs/drop).`,
+Messages must successfully route to one or more outputs, otherwise this is considered an error and the message is reprocessed. In order to explicitly drop messages that do not match your cases add one final case with a [drop output](/docs/components/outputs/drop).
+
+### Metrics
+
+The number of messages routed to each case is counted with the metric
+` + "`switch.case.{id}.routed`" + ` and the number of failed writes to its output
+with ` + "`switch.case.{id}.error`" + `, where the id of a case is its index
+within ` + "`cases`" + ` unless it was added at runtime. Messages that do not
+match any case and are dropped are counted with ` + "`switch.messages.dropped`" + `.
+
+### Dynamic Cases
+
+When the field ` + "`dynamic`" + ` is set to ` + "`true`" + ` the cases of the switch
+can be added, changed and removed at runtime, which allows routing tables to be
+modified without restarting Benthos. Cases configured statically are given ids
+matching their index within ` + "`cases`" + `.
+
+To GET a JSON map of case ids with their current uptimes and configs use the
+` + "`/switch/{label}/cases`" + ` endpoint, where ` + "`label`" + ` is the label of the
+switch output.
+
+To perform CRUD actions on the cases themselves use POST, DELETE, and GET
+methods on the ` + "`/switch/{label}/cases/{case_id}`" + ` endpoint. When using POST
+the body of the request should be a YAML configuration for the case containing
+the fields ` + "`check`" + `, ` + "`output`" + ` and ` + "`continue`" + `. If a case of
+the id already exists it is replaced in place, otherwise the new case is added
+to the end of the list. Removed and replaced case outputs are closed once the
+messages being routed to them are finished.`,
 		config: docs.FieldComponent().WithChildren(
 			docs.FieldCommon(
 				"retry_until_success", `
@@ -67,6 +99,9 @@ behavior is false, which will drop the message.`,
 			docs.FieldAdvanced(
 				"max_in_flight", "The maximum number of parallel message batches to have in flight at any given time. Note that if a child output has a higher `max_in_flight` then the switch output will automatically match it, therefore this value is the minimum `max_in_flight` to set in cases where the child values can't be inferred (such as when using resource outputs as children).",
 			),
+			docs.FieldAdvanced(
+				"dynamic", "Whether cases can be added, changed and removed at runtime via HTTP endpoints. For more information read the [dynamic cases section](#dynamic-cases).",
+			).AtVersion("3.64.0"),
 			docs.FieldCommon(
 				"cases",
 				"A list of switch cases, outlining outputs that can be routed to.",
@@ -205,6 +240,7 @@ type SwitchConfig struct {
 	RetryUntilSuccess bool                 `json:"retry_until_success" yaml:"retry_until_success"`
 	StrictMode        bool                 `json:"strict_mode" yaml:"strict_mode"`
 	MaxInFlight       int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	Dynamic           bool                 `json:"dynamic" yaml:"dynamic"`
 	Cases             []SwitchConfigCase   `json:"cases" yaml:"cases"`
 	Outputs           []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
 }
@@ -216,6 +252,7 @@ func NewSwitchConfig() SwitchConfig {
 		// TODO: V4 consider making this true by default.
 		StrictMode:  false,
 		MaxInFlight: 1,
+		Dynamic:     false,
 		Cases:       []SwitchConfigCase{},
 		Outputs:     []SwitchConfigOutput{},
 	}
@@ -239,6 +276,62 @@ func NewSwitchConfigCase() SwitchConfigCase {
 
 //------------------------------------------------------------------------------
 
+// switchCase is a case of a switch output along with the output that messages
+// passing its check are routed to.
+type switchCase struct {
+	id     string
+	check  *mapping.Executor
+	cont   bool
+	output types.Output
+	tsChan chan types.Transaction
+
+	mRouted metrics.StatCounter
+	mError  metrics.StatCounter
+
+	// Tracks messages currently being routed with this case so that the output
+	// is only closed once they are finished.
+	pending sync.WaitGroup
+}
+
+func newSwitchCase(id string, conf SwitchConfigCase, mgr types.Manager, logger log.Modular, stats metrics.Type) (*switchCase, error) {
+	c := &switchCase{
+		id:      id,
+		cont:    conf.Continue,
+		tsChan:  make(chan types.Transaction),
+		mRouted: stats.GetCounter(fmt.Sprintf("switch.case.%v.routed", id)),
+		mError:  stats.GetCounter(fmt.Sprintf("switch.case.%v.error", id)),
+	}
+
+	oMgr, oLog, oStats := interop.LabelChild(fmt.Sprintf("switch.%v.output", id), mgr, logger, stats)
+	oStats = metrics.Combine(stats, oStats)
+
+	var err error
+	if c.output, err = New(conf.Output, oMgr, oLog, oStats); err != nil {
+		return nil, fmt.Errorf("failed to create case '%v' output type '%v': %v", id, conf.Output.Type, err)
+	}
+	if len(conf.Check) > 0 {
+		if c.check, err = interop.NewBloblangMapping(mgr, conf.Check); err != nil {
+			c.output.CloseAsync()
+			return nil, fmt.Errorf("failed to parse case '%v' check mapping: %v", id, err)
+		}
+	}
+	if err = c.output.Consume(c.tsChan); err != nil {
+		c.output.CloseAsync()
+		return nil, err
+	}
+	return c, nil
+}
+
+// close the output of the case once all pending messages have been routed.
+func (c *switchCase) close() {
+	c.pending.Wait()
+	c.output.CloseAsync()
+	close(c.tsChan)
+	_ = c.output.WaitForClose(shutdown.MaximumShutdownWait())
+}
+
+//------------------------------------------------------------------------------
+
 // Switch is a broker that implements types.Consumer and broadcasts each message
 // out to an array of outputs.
 type Switch struct {
@@ -246,6 +339,7 @@ type Switch struct {
 	stats      metrics.Type
 	mMsgRcvd   metrics.StatCounter
 	mMsgSnt    metrics.StatCounter
+	mMsgDrop   metrics.StatCounter
 	mOutputErr metrics.StatCounter
 
 	maxInFlight  int
@@ -255,11 +349,18 @@ type Switch struct {
 	strictMode        bool
 	outputTSChans     []chan types.Transaction
 	outputs           []types.Output
-	checks            []*mapping.Executor
 	conditions        []types.Condition
-	continues         []bool
 	fallthroughs      []bool
 
+	// Cases are replaced rather than modified when changed at runtime, and
+	// therefore a reference obtained under the read lock remains valid.
+	casesMut   sync.RWMutex
+	cases      []*switchCase
+	removedWG  sync.WaitGroup
+	dynAPI     *api.Dynamic
+	caseMgr    types.Manager
+	caseLogger log.Modular
+
 	ctx        context.Context
 	close      func()
 	closedChan chan struct{}
@@ -281,28 +382,29 @@ func NewSwitch(
 		transactions:      nil,
 		retryUntilSuccess: conf.Switch.RetryUntilSuccess,
 		strictMode:        conf.Switch.StrictMode,
+		caseMgr:           mgr,
+		caseLogger:        logger,
 		closedChan:        make(chan struct{}),
 		ctx:               ctx,
 		close:             done,
 		mMsgRcvd:          stats.GetCounter("switch.messages.received"),
 		mMsgSnt:           stats.GetCounter("switch.messages.sent"),
+		mMsgDrop:          stats.GetCounter("switch.messages.dropped"),
 		mOutputErr:        stats.GetCounter("switch.output.error"),
 	}
 
 	lCases := len(conf.Switch.Cases)
 	lOutputs := len(conf.Switch.Outputs)
-	if lCases < 2 && lOutputs < 2 {
+	if lCases < 2 && lOutputs < 2 && !conf.Switch.Dynamic {
 		return nil, ErrSwitchNoOutputs
 	}
-	if lCases > 0 {
-		if lOutputs > 0 {
+	if lOutputs > 0 {
+		if lCases > 0 {
 			return nil, errors.New("combining switch cases with deprecated outputs is not supported")
 		}
-		o.outputs = make([]types.Output, lCases)
-		o.checks = make([]*mapping.Executor, lCases)
-		o.continues = make([]bool, lCases)
-		o.fallthroughs = make([]bool, lCases)
-	} else {
+		if conf.Switch.Dynamic {
+			return nil, errors.New("dynamic cases are not supported with deprecated outputs")
+		}
 		o.outputs = make([]types.Output, lOutputs)
 		o.conditions = make([]types.Condition, lOutputs)
 		o.fallthroughs = make([]bool, lOutputs)
@@ -323,20 +425,6 @@ func NewSwitch(
 		o.fallthroughs[i] = oConf.Fallthrough
 	}
 
-	for i, cConf := range conf.Switch.Cases {
-		oMgr, oLog, oStats := interop.LabelChild(fmt.Sprintf("switch.%v.output", i), mgr, logger, stats)
-		oStats = metrics.Combine(stats, oStats)
-		if o.outputs[i], err = New(cConf.Output, oMgr, oLog, oStats); err != nil {
-			return nil, fmt.Errorf("failed to create case '%v' output type '%v': %v", i, cConf.Output.Type, err)
-		}
-		if len(cConf.Check) > 0 {
-			if o.checks[i], err = interop.NewBloblangMapping(mgr, cConf.Check); err != nil {
-				return nil, fmt.Errorf("failed to parse case '%v' check mapping: %v", i, err)
-			}
-		}
-		o.continues[i] = cConf.Continue
-	}
-
 	o.outputTSChans = make([]chan types.Transaction, len(o.outputs))
 	for i := range o.outputTSChans {
 		if mif, ok := output.GetMaxInFlight(o.outputs[i]); ok && mif > o.maxInFlight {
@@ -347,11 +435,162 @@ func NewSwitch(
 			return nil, err
 		}
 	}
+
+	for i, cConf := range conf.Switch.Cases {
+		c, err := newSwitchCase(strconv.Itoa(i), cConf, mgr, logger, stats)
+		if err != nil {
+			return nil, err
+		}
+		if mif, ok := output.GetMaxInFlight(c.output); ok && mif > o.maxInFlight {
+			o.maxInFlight = mif
+		}
+		o.cases = append(o.cases, c)
+	}
+
+	if conf.Switch.Dynamic {
+		o.registerDynamicAPI(conf.Switch.Cases, mgr)
+	}
 	return o, nil
 }
 
 //------------------------------------------------------------------------------
 
+// registerDynamicAPI registers HTTP endpoints for adding, changing and removing
+// the cases of the switch at runtime.
+func (o *Switch) registerDynamicAPI(cases []SwitchConfigCase, mgr types.Manager) {
+	o.dynAPI = api.NewDynamic()
+	for i, cConf := range cases {
+		o.caseStarted(strconv.Itoa(i), cConf)
+	}
+
+	o.dynAPI.OnUpdate(func(id string, c []byte) error {
+		cConf := NewSwitchConfigCase()
+		if err := yaml.Unmarshal(c, &cConf); err != nil {
+			return err
+		}
+		return o.SetCase(id, cConf)
+	})
+	o.dynAPI.OnDelete(o.RemoveCase)
+
+	basePath := path.Join("/switch", interop.GetLabel(mgr), "cases")
+	mgr.RegisterEndpoint(
+		path.Join(basePath, "{id}"),
+		"Perform CRUD operations on the cases of a switch output. For more"+
+			" information read the `switch` output type documentation.",
+		o.dynAPI.HandleCRUD,
+	)
+	mgr.RegisterEndpoint(
+		basePath,
+		"Get a map of the cases of a switch output with their current uptimes.",
+		o.dynAPI.HandleList,
+	)
+}
+
+func (o *Switch) caseStarted(id string, conf SwitchConfigCase) {
+	if o.dynAPI == nil {
+		return
+	}
+	sOutConf, err := SanitiseConfig(conf.Output)
+	if err != nil {
+		o.logger.Errorf("Failed to sanitise config: %v\n", err)
+	}
+	confBytes, _ := json.Marshal(map[string]interface{}{
+		"check":    conf.Check,
+		"continue": conf.Continue,
+		"output":   sOutConf,
+	})
+	o.dynAPI.Started(id, confBytes)
+}
+
+// SetCase adds a new case to the end of the switch or, if a case with the same
+// id already exists, replaces it in place. The output of a replaced case is
+// closed once the messages currently being routed to it are finished.
+func (o *Switch) SetCase(id string, conf SwitchConfigCase) error {
+	c, err := newSwitchCase(id, conf, o.caseMgr, o.caseLogger, o.stats)
+	if err != nil {
+		return err
+	}
+
+	o.casesMut.Lock()
+	newCases := make([]*switchCase, 0, len(o.cases)+1)
+	var replaced *switchCase
+	for _, existing := range o.cases {
+		if existing.id == id {
+			replaced = existing
+			newCases = append(newCases, c)
+		} else {
+			newCases = append(newCases, existing)
+		}
+	}
+	if replaced == nil {
+		newCases = append(newCases, c)
+	}
+	o.cases = newCases
+	if replaced != nil {
+		o.closeRemoved(replaced)
+	}
+	o.casesMut.Unlock()
+
+	o.caseStarted(id, conf)
+	return nil
+}
+
+// RemoveCase removes a case from the switch by its id. The output of the case
+// is closed once the messages currently being routed to it are finished.
+func (o *Switch) RemoveCase(id string) error {
+	o.casesMut.Lock()
+	defer o.casesMut.Unlock()
+
+	newCases := make([]*switchCase, 0, len(o.cases))
+	var removed *switchCase
+	for _, existing := range o.cases {
+		if existing.id == id {
+			removed = existing
+		} else {
+			newCases = append(newCases, existing)
+		}
+	}
+	if removed == nil {
+		return fmt.Errorf("case '%v' does not exist", id)
+	}
+	o.cases = newCases
+	o.closeRemoved(removed)
+
+	if o.dynAPI != nil {
+		o.dynAPI.Stopped(id)
+	}
+	return nil
+}
+
+// closeRemoved closes a case that is no longer part of the switch, must be
+// called whilst holding the cases write lock.
+func (o *Switch) closeRemoved(c *switchCase) {
+	o.removedWG.Add(1)
+	go func() {
+		defer o.removedWG.Done()
+		c.close()
+	}()
+}
+
+// acquireCases returns the current cases of the switch, which are prevented
+// from being closed until releaseCases is called.
+func (o *Switch) acquireCases() []*switchCase {
+	o.casesMut.RLock()
+	defer o.casesMut.RUnlock()
+	for _, c := range o.cases {
+		c.pending.Add(1)
+	}
+	return o.cases
+}
+
+func releaseCases(cases []*switchCase) {
+	for _, c := range cases {
+		c.pending.Done()
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // Consume assigns a new transactions channel for the broker to read.
 func (o *Switch) Consume(transactions <-chan types.Transaction) error {
 	if o.transactions != nil {
@@ -383,18 +622,25 @@ func (o *Switch) Connected() bool {
 			return false
 		}
 	}
+	o.casesMut.RLock()
+	defer o.casesMut.RUnlock()
+	for _, c := range o.cases {
+		if !c.output.Connected() {
+			return false
+		}
+	}
 	return true
 }
 
 //------------------------------------------------------------------------------
 
-func (o *Switch) dispatchRetryOnErr(outputTargets [][]types.Part) error {
+func (o *Switch) dispatchRetryOnErr(cases []*switchCase, outputTargets [][]types.Part) error {
 	var owg errgroup.Group
 	for target, parts := range outputTargets {
 		if len(parts) == 0 {
 			continue
 		}
-		msgCopy, i := message.New(nil), target
+		msgCopy, c := message.New(nil), cases[target]
 		msgCopy.SetAll(parts)
 		owg.Go(func() error {
 			throt := throttle.New(throttle.OptCloseChan(o.ctx.Done()))
@@ -403,7 +649,7 @@ func (o *Switch) dispatchRetryOnErr(outputTargets [][]types.Part) error {
 			// Try until success or shutdown.
 			for {
 				select {
-				case o.outputTSChans[i] <- types.NewTransaction(msgCopy, resChan):
+				case c.tsChan <- types.NewTransaction(msgCopy, resChan):
 				case <-o.ctx.Done():
 					return types.ErrTypeClosed
 				}
@@ -412,6 +658,7 @@ func (o *Switch) dispatchRetryOnErr(outputTargets [][]types.Part) error {
 					if res.Error() != nil {
 						o.logger.Errorf("Failed to dispatch switch message: %v\n", res.Error())
 						o.mOutputErr.Incr(1)
+						c.mError.Incr(1)
 						if !throt.Retry() {
 							return types.ErrTypeClosed
 						}
@@ -428,7 +675,7 @@ func (o *Switch) dispatchRetryOnErr(outputTargets [][]types.Part) error {
 	return owg.Wait()
 }
 
-func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage types.Message, outputTargets [][]types.Part) error {
+func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage types.Message, cases []*switchCase, outputTargets [][]types.Part) error {
 	var wg sync.WaitGroup
 
 	var setErr func(error)
@@ -478,7 +725,7 @@ func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage type
 			continue
 		}
 		wg.Add(1)
-		msgCopy, i := message.New(nil), target
+		msgCopy, c := message.New(nil), cases[target]
 		msgCopy.SetAll(parts)
 
 		go func() {
@@ -486,7 +733,7 @@ func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage type
 
 			resChan := make(chan types.Response)
 			select {
-			case o.outputTSChans[i] <- types.NewTransaction(msgCopy, resChan):
+			case c.tsChan <- types.NewTransaction(msgCopy, resChan):
 			case <-o.ctx.Done():
 				setErr(types.ErrTypeClosed)
 				return
@@ -495,6 +742,7 @@ func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage type
 			case res := <-resChan:
 				if res.Error() != nil {
 					o.mOutputErr.Incr(1)
+					c.mError.Incr(1)
 					if bErr, ok := res.Error().(*batch.Error); ok {
 						bErr.WalkParts(func(i int, p types.Part, e error) bool {
 							if e != nil {
@@ -521,19 +769,63 @@ func (o *Switch) dispatchNoRetries(group *imessage.SortGroup, sourceMessage type
 	return getErr()
 }
 
+// route a message to the cases that it passes, returning an error if it could
+// not be delivered.
+func (o *Switch) route(group *imessage.SortGroup, trackedMsg types.Message) error {
+	cases := o.acquireCases()
+	defer releaseCases(cases)
+
+	outputTargets := make([][]types.Part, len(cases))
+	if checksErr := trackedMsg.Iter(func(i int, p types.Part) error {
+		routedAtLeastOnce := false
+		for j, c := range cases {
+			test := true
+			if c.check != nil {
+				var err error
+				if test, err = c.check.QueryPart(i, trackedMsg); err != nil {
+					test = false
+					o.logger.Errorf("Failed to test case %v: %v\n", c.id, err)
+				}
+			}
+			if test {
+				routedAtLeastOnce = true
+				outputTargets[j] = append(outputTargets[j], p.Copy())
+				c.mRouted.Incr(1)
+				if !c.cont {
+					return nil
+				}
+			}
+		}
+		if !routedAtLeastOnce {
+			if o.strictMode {
+				return ErrSwitchNoConditionMet
+			}
+			o.mMsgDrop.Incr(1)
+		}
+		return nil
+	}); checksErr != nil {
+		return checksErr
+	}
+
+	if o.retryUntilSuccess {
+		return o.dispatchRetryOnErr(cases, outputTargets)
+	}
+	return o.dispatchNoRetries(group, trackedMsg, cases, outputTargets)
+}
+
 // loop is an internal loop that brokers incoming messages to many outputs.
 func (o *Switch) loop() {
 	var wg sync.WaitGroup
 
 	defer func() {
 		wg.Wait()
-		for i, output := range o.outputs {
-			output.CloseAsync()
-			close(o.outputTSChans[i])
-		}
-		for _, output := range o.outputs {
-			_ = output.WaitForClose(shutdown.MaximumShutdownWait())
+		o.casesMut.Lock()
+		for _, c := range o.cases {
+			o.closeRemoved(c)
 		}
+		o.cases = nil
+		o.casesMut.Unlock()
+		o.removedWG.Wait()
 		close(o.closedChan)
 	}()
 
@@ -555,48 +847,8 @@ func (o *Switch) loop() {
 
 			group, trackedMsg := imessage.NewSortGroup(ts.Payload)
 
-			outputTargets := make([][]types.Part, len(o.checks))
-			if checksErr := trackedMsg.Iter(func(i int, p types.Part) error {
-				routedAtLeastOnce := false
-				for j, exe := range o.checks {
-					test := true
-					if exe != nil {
-						var err error
-						if test, err = exe.QueryPart(i, trackedMsg); err != nil {
-							test = false
-							o.logger.Errorf("Failed to test case %v: %v\n", j, err)
-						}
-					}
-					if test {
-						routedAtLeastOnce = true
-						outputTargets[j] = append(outputTargets[j], p.Copy())
-						if !o.continues[j] {
-							return nil
-						}
-					}
-				}
-				if !routedAtLeastOnce && o.strictMode {
-					return ErrSwitchNoConditionMet
-				}
-				return nil
-			}); checksErr != nil {
-				select {
-				case ts.ResponseChan <- response.NewError(checksErr):
-				case <-o.ctx.Done():
-					return
-				}
-				continue
-			}
-
-			var resErr error
-			if o.retryUntilSuccess {
-				resErr = o.dispatchRetryOnErr(outputTargets)
-			} else {
-				resErr = o.dispatchNoRetries(group, trackedMsg, outputTargets)
-			}
-
 			var oResponse types.Response = response.NewAck()
-			if resErr != nil {
+			if resErr := o.route(group, trackedMsg); resErr != nil {
 				oResponse = response.NewError(resErr)
 			}
 			select {
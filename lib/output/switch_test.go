@@ -2,8 +2,10 @@ package output
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -31,10 +33,11 @@ func newSwitch(t *testing.T, conf Config, mockOutputs []*MockOutputType) *Switch
 	require.True(t, ok)
 
 	for i := 0; i < len(mockOutputs); i++ {
-		close(rType.outputTSChans[i])
-		rType.outputs[i] = mockOutputs[i]
-		rType.outputTSChans[i] = make(chan types.Transaction)
-		mockOutputs[i].Consume(rType.outputTSChans[i])
+		c := rType.cases[i]
+		close(c.tsChan)
+		c.output = mockOutputs[i]
+		c.tsChan = make(chan types.Transaction)
+		mockOutputs[i].Consume(c.tsChan)
 	}
 	return rType
 }
@@ -1131,3 +1134,74 @@ bpLoop:
 }
 
 //------------------------------------------------------------------------------
+
+func TestSwitchDynamicCases(t *testing.T) {
+	conf := NewConfig()
+	conf.Switch.RetryUntilSuccess = false
+	conf.Switch.Dynamic = true
+
+	s, err := NewSwitch(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	sw := s.(*Switch)
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, s.Consume(readChan))
+
+	sendMsg := func(content string) error {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		select {
+		case res := <-resChan:
+			return res.Error()
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		return nil
+	}
+
+	// Without any cases messages are dropped.
+	require.NoError(t, sendMsg(`{"type":"foo"}`))
+
+	rejectConf := NewConfig()
+	rejectConf.Type = TypeReject
+	rejectConf.Reject = "rejected foo"
+	require.NoError(t, sw.SetCase("foo", SwitchConfigCase{
+		Check:  `this.type == "foo"`,
+		Output: rejectConf,
+	}))
+	require.EqualError(t, sendMsg(`{"type":"foo"}`), "rejected foo")
+	require.NoError(t, sendMsg(`{"type":"bar"}`))
+
+	dropConf := NewConfig()
+	dropConf.Type = TypeDrop
+	require.NoError(t, sw.SetCase("foo", SwitchConfigCase{
+		Check:  `this.type == "foo"`,
+		Output: dropConf,
+	}))
+	rejectConf.Reject = "rejected bar"
+	require.NoError(t, sw.SetCase("bar", SwitchConfigCase{
+		Check:  `this.type == "bar"`,
+		Output: rejectConf,
+	}))
+	require.NoError(t, sendMsg(`{"type":"foo"}`))
+	require.EqualError(t, sendMsg(`{"type":"bar"}`), "rejected bar")
+
+	rec := httptest.NewRecorder()
+	sw.dynAPI.HandleList(rec, httptest.NewRequest("GET", "/switch/cases", nil))
+	var list map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Contains(t, list, "foo")
+	assert.Contains(t, list, "bar")
+
+	require.NoError(t, sw.RemoveCase("bar"))
+	require.EqualError(t, sw.RemoveCase("bar"), "case 'bar' does not exist")
+	require.NoError(t, sendMsg(`{"type":"bar"}`))
+
+	s.CloseAsync()
+	require.NoError(t, s.WaitForClose(time.Second*5))
+}
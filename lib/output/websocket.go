@@ -17,8 +17,29 @@ func init() {
 		constructor: fromSimpleConstructor(NewWebsocket),
 		Summary: `
 Sends messages to an HTTP server via a websocket connection.`,
+		Description: `
+When a connection is lost the output reconnects to the server, waiting between
+attempts following the backoff of the ` + "`reconnect`" + ` field. In order to
+detect connections that are lost silently it is possible to enable ` + "`ping`" + `,
+in which case pings are sent to the server periodically and the connection is
+considered lost when neither a pong nor a message has been received within the
+timeout.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("url", "The URL to connect to."),
+			docs.FieldString("subprotocols", "A list of subprotocols to request from the server in order of preference, where the server selects one of them.", []string{"graphql-transport-ws"}).Array().Advanced().AtVersion("3.64.0"),
+			docs.FieldAdvanced("ping", "Send pings to the server periodically in order to detect lost connections.").WithChildren(
+				docs.FieldAdvanced("enabled", "Whether to send pings to the server."),
+				docs.FieldAdvanced("period", "The period between pings."),
+				docs.FieldAdvanced("timeout", "The maximum period after a ping is due to wait for a pong or any other message from the server before the connection is considered lost."),
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("reconnect", "Control time intervals between attempts to reconnect to the server.").WithChildren(
+				docs.FieldAdvanced("max_retries", "The maximum number of consecutive failed reconnection attempts before the output is closed. If set to zero there is no discrete limit."),
+				docs.FieldAdvanced("backoff", "Control time intervals between reconnection attempts.").WithChildren(
+					docs.FieldAdvanced("initial_interval", "The initial period to wait between reconnection attempts."),
+					docs.FieldAdvanced("max_interval", "The maximum period to wait between reconnection attempts."),
+					docs.FieldAdvanced("max_elapsed_time", "The maximum period to spend reconnecting before the output is closed. If zero then no limit is used."),
+				),
+			).AtVersion("3.64.0"),
 			btls.FieldSpec(),
 		}.Merge(auth.FieldSpecs()),
 		Categories: []Category{
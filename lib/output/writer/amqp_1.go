@@ -23,6 +23,7 @@ type AMQP1Config struct {
 	URL           string                       `json:"url" yaml:"url"`
 	TargetAddress string                       `json:"target_address" yaml:"target_address"`
 	MaxInFlight   int                          `json:"max_in_flight" yaml:"max_in_flight"`
+	SettleMode    string                       `json:"settle_mode" yaml:"settle_mode"`
 	TLS           btls.Config                  `json:"tls" yaml:"tls"`
 	SASL          sasl.Config                  `json:"sasl" yaml:"sasl"`
 	Metadata      metadata.ExcludeFilterConfig `json:"metadata" yaml:"metadata"`
@@ -34,6 +35,7 @@ func NewAMQP1Config() AMQP1Config {
 		URL:           "",
 		TargetAddress: "",
 		MaxInFlight:   1,
+		SettleMode:    "unsettled",
 		TLS:           btls.NewConfig(),
 		SASL:          sasl.NewConfig(),
 		Metadata:      metadata.NewExcludeFilterConfig(),
@@ -53,8 +55,9 @@ type AMQP1 struct {
 	log   log.Modular
 	stats metrics.Type
 
-	conf    AMQP1Config
-	tlsConf *tls.Config
+	conf       AMQP1Config
+	tlsConf    *tls.Config
+	settleMode amqp.SenderSettleMode
 
 	connLock sync.RWMutex
 }
@@ -75,6 +78,14 @@ func NewAMQP1(conf AMQP1Config, log log.Modular, stats metrics.Type) (*AMQP1, er
 	if a.metaFilter, err = conf.Metadata.Filter(); err != nil {
 		return nil, fmt.Errorf("failed to construct metadata filter: %w", err)
 	}
+	switch conf.SettleMode {
+	case "unsettled":
+		a.settleMode = amqp.ModeUnsettled
+	case "settled":
+		a.settleMode = amqp.ModeSettled
+	default:
+		return nil, fmt.Errorf("unrecognised settle_mode: %v", conf.SettleMode)
+	}
 	return &a, nil
 }
 
@@ -123,6 +134,7 @@ func (a *AMQP1) ConnectWithContext(ctx context.Context) error {
 	// Create a sender
 	if sender, err = session.NewSender(
 		amqp.LinkTargetAddress(a.conf.TargetAddress),
+		amqp.LinkSenderSettle(a.settleMode),
 	); err != nil {
 		session.Close(context.Background())
 		client.Close()
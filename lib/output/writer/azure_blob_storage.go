@@ -15,6 +15,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/storage"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -31,6 +32,7 @@ type AzureBlobStorage struct {
 	path        *field.Expression
 	blobType    *field.Expression
 	accessLevel *field.Expression
+	compress    codec.CompressionWriterConstructor
 	client      storage.BlobStorageClient
 	log         log.Modular
 	stats       metrics.Type
@@ -96,6 +98,9 @@ func NewAzureBlobStorageV2(
 	if a.accessLevel, err = interop.NewBloblangField(mgr, conf.PublicAccessLevel); err != nil {
 		return nil, fmt.Errorf("failed to parse public access level expression: %v", err)
 	}
+	if a.compress, err = codec.GetCompressionWriter(conf.Compression); err != nil {
+		return nil, err
+	}
 	return a, nil
 }
 
@@ -144,15 +149,20 @@ func (a *AzureBlobStorage) createContainer(c *storage.Container, accessLevel str
 // WriteWithContext attempts to write message contents to a target storage account as files.
 func (a *AzureBlobStorage) WriteWithContext(_ context.Context, msg types.Message) error {
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
+		body, err := codec.CompressBytes(a.compress, p.Get())
+		if err != nil {
+			return fmt.Errorf("failed to compress blob: %w", err)
+		}
+
 		c := a.client.GetContainerReference(a.container.String(i, msg))
 		b := c.GetBlobReference(a.path.String(i, msg))
-		if err := a.uploadBlob(b, a.blobType.String(i, msg), p.Get()); err != nil {
+		if err := a.uploadBlob(b, a.blobType.String(i, msg), body); err != nil {
 			if containerNotFound(err) {
 				if cerr := a.createContainer(c, a.accessLevel.String(i, msg)); cerr != nil {
 					a.log.Debugf("error creating container: %v.", cerr)
 					return cerr
 				}
-				err = a.uploadBlob(b, a.blobType.String(i, msg), p.Get())
+				err = a.uploadBlob(b, a.blobType.String(i, msg), body)
 				if err != nil {
 					a.log.Debugf("error retrying to upload  blob: %v.", err)
 				}
@@ -12,6 +12,7 @@ type AzureBlobStorageConfig struct {
 	Path                    string `json:"path" yaml:"path"`
 	BlobType                string `json:"blob_type" yaml:"blob_type"`
 	PublicAccessLevel       string `json:"public_access_level" yaml:"public_access_level"`
+	Compression             string `json:"compression" yaml:"compression"`
 	MaxInFlight             int    `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
@@ -25,6 +26,7 @@ func NewAzureBlobStorageConfig() AzureBlobStorageConfig {
 		Path:                    `${!count("files")}-${!timestamp_unix_nano()}.txt`,
 		BlobType:                "BLOCK",
 		PublicAccessLevel:       "PRIVATE",
+		Compression:             "none",
 		MaxInFlight:             1,
 	}
 }
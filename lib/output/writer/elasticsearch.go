@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	ibatch "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -45,6 +46,7 @@ type ElasticsearchConfig struct {
 	Index           string               `json:"index" yaml:"index"`
 	Pipeline        string               `json:"pipeline" yaml:"pipeline"`
 	Routing         string               `json:"routing" yaml:"routing"`
+	Script          string               `json:"script" yaml:"script"`
 	Type            string               `json:"type" yaml:"type"`
 	Timeout         string               `json:"timeout" yaml:"timeout"`
 	TLS             btls.Config          `json:"tls" yaml:"tls"`
@@ -73,6 +75,7 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 		Pipeline:    "",
 		Type:        "doc",
 		Routing:     "",
+		Script:      "",
 		Timeout:     "5s",
 		TLS:         btls.NewConfig(),
 		Auth:        auth.NewBasicAuthConfig(),
@@ -241,6 +244,9 @@ func (e *Elasticsearch) Connect() error {
 }
 
 func shouldRetry(s int) bool {
+	if s == http.StatusTooManyRequests {
+		return true
+	}
 	if s >= 500 && s <= 599 {
 		return true
 	}
@@ -294,14 +300,30 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 		return err
 	}
 
+	var batchErr *ibatch.Error
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = ibatch.NewError(msg, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	// Tracks the index of the message of each pending request.
+	var indexes []int
+
 	b := e.client.Bulk()
-	for _, v := range requests {
+	pending := requests[:0]
+	for i, v := range requests {
 		bulkReq, err := e.buildBulkableRequest(v)
 		if err != nil {
-			return err
+			failed(i, err)
+			continue
 		}
 		b.Add(bulkReq)
+		pending = append(pending, v)
+		indexes = append(indexes, i)
 	}
+	requests = pending
 
 	lastErrReason := "no reason given"
 	for b.NumberOfActions() != 0 {
@@ -310,10 +332,11 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 			return err
 		}
 		if !result.Errors {
-			return nil
+			break
 		}
 
 		var newRequests []*pendingBulkIndex
+		var newIndexes []int
 		for i, resp := range result.Items {
 			for _, item := range resp {
 				if item.Status >= 200 && item.Status <= 299 {
@@ -328,7 +351,10 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 
 				e.log.Errorf("Elasticsearch message '%v' rejected with status [%v]: %v\n", item.Id, item.Status, reason)
 				if !shouldRetry(item.Status) {
-					return fmt.Errorf("failed to send message '%v': %v", item.Id, reason)
+					// Only the message of this item is rejected, the remaining
+					// items can still be delivered.
+					failed(indexes[i], fmt.Errorf("failed to send message '%v': %v", item.Id, reason))
+					continue
 				}
 
 				// IMPORTANT: i exactly matches the index of our source requests
@@ -341,17 +367,28 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 				}
 				b.Add(bulkReq)
 				newRequests = append(newRequests, sourceReq)
+				newIndexes = append(newIndexes, indexes[i])
 			}
 		}
-		requests = newRequests
+		requests, indexes = newRequests, newIndexes
+		if len(requests) == 0 {
+			break
+		}
 
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
-			return fmt.Errorf("retries exhausted for messages, aborting with last error reported as: %v", lastErrReason)
+			err := fmt.Errorf("retries exhausted for messages, aborting with last error reported as: %v", lastErrReason)
+			for _, i := range indexes {
+				failed(i, err)
+			}
+			break
 		}
 		time.Sleep(wait)
 	}
 
+	if batchErr != nil {
+		return batchErr
+	}
 	return nil
 }
 
@@ -368,13 +405,28 @@ func (e *Elasticsearch) WaitForClose(timeout time.Duration) error {
 func (e *Elasticsearch) buildBulkableRequest(p *pendingBulkIndex) (elastic.BulkableRequest, error) {
 	// TODO: V4 the type field should be optional and not used
 	switch p.Action {
-	case "update":
-		return elastic.NewBulkUpdateRequest().
+	case "update", "upsert":
+		r := elastic.NewBulkUpdateRequest().
 			Index(p.Index).
 			Routing(p.Routing).
 			Type(p.Type).
-			Id(p.ID).
-			Doc(p.Doc), nil
+			Id(p.ID)
+		if e.conf.Script != "" {
+			params, ok := p.Doc.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected object document for script parameters, got %T", p.Doc)
+			}
+			r = r.Script(elastic.NewScript(e.conf.Script).Params(params))
+			if p.Action == "upsert" {
+				r = r.Upsert(p.Doc)
+			}
+			return r, nil
+		}
+		r = r.Doc(p.Doc)
+		if p.Action == "upsert" {
+			r = r.DocAsUpsert(true)
+		}
+		return r, nil
 	case "delete":
 		return elastic.NewBulkDeleteRequest().
 			Index(p.Index).
@@ -389,6 +441,15 @@ func (e *Elasticsearch) buildBulkableRequest(p *pendingBulkIndex) (elastic.Bulka
 			Type(p.Type).
 			Id(p.ID).
 			Doc(p.Doc), nil
+	case "create":
+		return elastic.NewBulkIndexRequest().
+			OpType("create").
+			Index(p.Index).
+			Pipeline(p.Pipeline).
+			Routing(p.Routing).
+			Type(p.Type).
+			Id(p.ID).
+			Doc(p.Doc), nil
 	default:
 		return nil, fmt.Errorf("elasticsearch action '%s' is not allowed", p.Action)
 	}
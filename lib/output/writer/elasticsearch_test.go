@@ -0,0 +1,226 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElasticsearchBulkableRequests(t *testing.T) {
+	tests := map[string]struct {
+		action string
+		script string
+		doc    interface{}
+		meta   map[string]interface{}
+		body   map[string]interface{}
+	}{
+		"create": {
+			action: "create",
+			doc:    map[string]interface{}{"foo": "bar"},
+			meta: map[string]interface{}{
+				"create": map[string]interface{}{
+					"_index":   "foo",
+					"_id":      "1",
+					"pipeline": "baz",
+					"routing":  "buz",
+				},
+			},
+			body: map[string]interface{}{"foo": "bar"},
+		},
+		"upsert": {
+			action: "upsert",
+			doc:    map[string]interface{}{"foo": "bar"},
+			meta: map[string]interface{}{
+				"update": map[string]interface{}{
+					"_index":  "foo",
+					"_id":     "1",
+					"routing": "buz",
+				},
+			},
+			body: map[string]interface{}{
+				"doc":           map[string]interface{}{"foo": "bar"},
+				"doc_as_upsert": true,
+			},
+		},
+		"scripted update": {
+			action: "update",
+			script: "ctx._source.count += params.count",
+			doc:    map[string]interface{}{"count": float64(2)},
+			meta: map[string]interface{}{
+				"update": map[string]interface{}{
+					"_index":  "foo",
+					"_id":     "1",
+					"routing": "buz",
+				},
+			},
+			body: map[string]interface{}{
+				"script": map[string]interface{}{
+					"source": "ctx._source.count += params.count",
+					"params": map[string]interface{}{"count": float64(2)},
+				},
+			},
+		},
+		"scripted upsert": {
+			action: "upsert",
+			script: "ctx._source.count += params.count",
+			doc:    map[string]interface{}{"count": float64(2)},
+			meta: map[string]interface{}{
+				"update": map[string]interface{}{
+					"_index":  "foo",
+					"_id":     "1",
+					"routing": "buz",
+				},
+			},
+			body: map[string]interface{}{
+				"script": map[string]interface{}{
+					"source": "ctx._source.count += params.count",
+					"params": map[string]interface{}{"count": float64(2)},
+				},
+				"upsert": map[string]interface{}{"count": float64(2)},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewElasticsearchConfig()
+			conf.Script = test.script
+
+			e, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			req, err := e.buildBulkableRequest(&pendingBulkIndex{
+				Action:   test.action,
+				Index:    "foo",
+				Pipeline: "baz",
+				Routing:  "buz",
+				Doc:      test.doc,
+				ID:       "1",
+			})
+			require.NoError(t, err)
+
+			lines, err := req.Source()
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+
+			var meta, body map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &meta))
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &body))
+			assert.Equal(t, test.meta, meta)
+			assert.Equal(t, test.body, body)
+		})
+	}
+}
+
+func TestElasticsearchScriptNotObject(t *testing.T) {
+	conf := NewElasticsearchConfig()
+	conf.Script = "ctx._source.count += 1"
+
+	e, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	_, err = e.buildBulkableRequest(&pendingBulkIndex{
+		Action: "update",
+		Doc:    "nope",
+		ID:     "1",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected object document")
+}
+
+func TestElasticsearchItemRetries(t *testing.T) {
+	var reqMut sync.Mutex
+	var bulkIDs [][]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			_, _ = w.Write([]byte(`{"version":{"number":"7.17.0"}}`))
+			return
+		}
+
+		var ids []string
+		var items []interface{}
+
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var meta map[string]map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &meta))
+			require.True(t, scanner.Scan())
+
+			id, _ := meta["index"]["_id"].(string)
+			ids = append(ids, id)
+
+			item := map[string]interface{}{"_index": "foo", "_id": id, "status": 201}
+			switch {
+			case id == "b" && len(bulkIDs) == 0:
+				item["status"] = 429
+				item["error"] = map[string]interface{}{"type": "es_rejected_execution_exception", "reason": "too busy"}
+			case id == "c":
+				item["status"] = 400
+				item["error"] = map[string]interface{}{"type": "mapper_parsing_exception", "reason": "bad doc"}
+			}
+			items = append(items, map[string]interface{}{"index": item})
+		}
+
+		reqMut.Lock()
+		bulkIDs = append(bulkIDs, ids)
+		reqMut.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"took":   1,
+			"errors": true,
+			"items":  items,
+		})
+	}))
+	defer server.Close()
+
+	conf := NewElasticsearchConfig()
+	conf.URLs = []string{server.URL}
+	conf.Sniff = false
+	conf.Healthcheck = false
+	conf.ID = `${! json("id") }`
+	conf.Index = "foo"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	e, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, e.Connect())
+
+	err = e.Write(message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b"}`),
+		[]byte(`{"id":"c"}`),
+	}))
+	require.Error(t, err)
+
+	reqMut.Lock()
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"b"}}, bulkIDs)
+	reqMut.Unlock()
+
+	batchErr, ok := err.(*batch.Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, 1, batchErr.IndexedErrors())
+
+	var failed []int
+	batchErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{2}, failed)
+}
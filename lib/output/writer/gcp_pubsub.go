@@ -14,23 +14,26 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
 )
 
 //------------------------------------------------------------------------------
 
 // GCPPubSubConfig contains configuration fields for the output GCPPubSub type.
 type GCPPubSubConfig struct {
-	ProjectID      string                       `json:"project" yaml:"project"`
-	TopicID        string                       `json:"topic" yaml:"topic"`
-	MaxInFlight    int                          `json:"max_in_flight" yaml:"max_in_flight"`
-	PublishTimeout string                       `json:"publish_timeout" yaml:"publish_timeout"`
-	Metadata       metadata.ExcludeFilterConfig `json:"metadata" yaml:"metadata"`
-	OrderingKey    string                       `json:"ordering_key" yaml:"ordering_key"`
+	gcpcreds.Config `json:",inline" yaml:",inline"`
+	ProjectID       string                       `json:"project" yaml:"project"`
+	TopicID         string                       `json:"topic" yaml:"topic"`
+	MaxInFlight     int                          `json:"max_in_flight" yaml:"max_in_flight"`
+	PublishTimeout  string                       `json:"publish_timeout" yaml:"publish_timeout"`
+	Metadata        metadata.ExcludeFilterConfig `json:"metadata" yaml:"metadata"`
+	OrderingKey     string                       `json:"ordering_key" yaml:"ordering_key"`
 }
 
 // NewGCPPubSubConfig creates a new Config with default values.
 func NewGCPPubSubConfig() GCPPubSubConfig {
 	return GCPPubSubConfig{
+		Config:         gcpcreds.NewConfig(),
 		ProjectID:      "",
 		TopicID:        "",
 		MaxInFlight:    1,
@@ -80,7 +83,11 @@ func NewGCPPubSubV2(
 	log log.Modular,
 	stats metrics.Type,
 ) (*GCPPubSub, error) {
-	client, err := pubsub.NewClient(context.Background(), conf.ProjectID)
+	opts, err := conf.ClientOptions(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	client, err := pubsub.NewClient(context.Background(), conf.ProjectID, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,277 @@
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/internal/mqttconf"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/eclipse/paho.golang/paho"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+//------------------------------------------------------------------------------
+
+type mqttUserProperty struct {
+	key   string
+	value *field.Expression
+}
+
+// MQTTV5 is an output type that publishes MQTT messages using version 5.0 of
+// the protocol, where each write blocks until the broker has completed the
+// delivery flow of the QoS of the message.
+type MQTTV5 struct {
+	log   log.Modular
+	stats metrics.Type
+
+	connectTimeout time.Duration
+	writeTimeout   time.Duration
+	tlsConf        *tls.Config
+
+	urls           []string
+	conf           MQTTConfig
+	topic          *field.Expression
+	retained       *field.Expression
+	userProperties []mqttUserProperty
+
+	client  *paho.Client
+	connMut sync.RWMutex
+}
+
+// NewMQTTV5 creates a new MQTT output type that connects with version 5.0 of
+// the protocol.
+func NewMQTTV5(
+	conf MQTTConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*MQTTV5, error) {
+	m := &MQTTV5{
+		log:   log,
+		stats: stats,
+		conf:  conf,
+	}
+
+	var err error
+	if m.connectTimeout, err = time.ParseDuration(conf.ConnectTimeout); err != nil {
+		return nil, fmt.Errorf("unable to parse connect timeout duration string: %w", err)
+	}
+	if m.writeTimeout, err = time.ParseDuration(conf.WriteTimeout); err != nil {
+		return nil, fmt.Errorf("unable to parse write timeout duration string: %w", err)
+	}
+	if conf.KeepAlive < 0 || conf.KeepAlive > 65535 {
+		return nil, fmt.Errorf("keepalive must be between 0 and 65535 seconds, got %v", conf.KeepAlive)
+	}
+
+	if m.topic, err = interop.NewBloblangField(mgr, conf.Topic); err != nil {
+		return nil, fmt.Errorf("failed to parse topic expression: %v", err)
+	}
+
+	if conf.RetainedInterpolated != "" {
+		if m.retained, err = interop.NewBloblangField(mgr, conf.RetainedInterpolated); err != nil {
+			return nil, fmt.Errorf("failed to parse retained expression: %v", err)
+		}
+	}
+
+	keys := make([]string, 0, len(conf.UserProperties))
+	for k := range conf.UserProperties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := interop.NewBloblangField(mgr, conf.UserProperties[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse user property '%v' expression: %v", k, err)
+		}
+		m.userProperties = append(m.userProperties, mqttUserProperty{key: k, value: value})
+	}
+
+	switch m.conf.DynamicClientIDSuffix {
+	case "nanoid":
+		nid, err := gonanoid.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate nanoid: %w", err)
+		}
+		m.conf.ClientID += nid
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown dynamic_client_id_suffix: %v", m.conf.DynamicClientIDSuffix)
+	}
+
+	if err := m.conf.Will.Validate(); err != nil {
+		return nil, err
+	}
+
+	if m.conf.TLS.Enabled {
+		if m.tlsConf, err = m.conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range conf.URLs {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				m.urls = append(m.urls, splitURL)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to an MQTT server.
+func (m *MQTTV5) ConnectWithContext(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	ctx, done := context.WithTimeout(ctx, m.connectTimeout)
+	defer done()
+
+	conn, err := mqttconf.Dial(ctx, m.urls, m.tlsConf)
+	if err != nil {
+		return err
+	}
+
+	var client *paho.Client
+	dropClient := func() {
+		m.connMut.Lock()
+		if m.client == client {
+			m.client = nil
+		}
+		m.connMut.Unlock()
+	}
+
+	client = paho.NewClient(paho.ClientConfig{
+		Conn: conn,
+		OnClientError: func(err error) {
+			m.log.Errorf("Connection lost due to: %v\n", err)
+			go dropClient()
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			m.log.Errorf("Connection closed by server with reason code: %v\n", d.ReasonCode)
+			go dropClient()
+		},
+	})
+
+	cp := &paho.Connect{
+		ClientID:   m.conf.ClientID,
+		KeepAlive:  uint16(m.conf.KeepAlive),
+		CleanStart: true,
+	}
+	if m.conf.Will.Enabled {
+		cp.WillMessage = &paho.WillMessage{
+			Retain:  m.conf.Will.Retained,
+			QoS:     m.conf.Will.QoS,
+			Topic:   m.conf.Will.Topic,
+			Payload: []byte(m.conf.Will.Payload),
+		}
+	}
+	if m.conf.User != "" {
+		cp.Username = m.conf.User
+		cp.UsernameFlag = true
+	}
+	if m.conf.Password != "" {
+		cp.Password = []byte(m.conf.Password)
+		cp.PasswordFlag = true
+	}
+
+	connack, err := client.Connect(ctx, cp)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if connack.ReasonCode >= 0x80 {
+		conn.Close()
+		return fmt.Errorf("connection refused with reason code: %v", connack.ReasonCode)
+	}
+
+	m.client = client
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// WriteWithContext attempts to write a message by pushing it to an MQTT broker.
+func (m *MQTTV5) WriteWithContext(ctx context.Context, msg types.Message) error {
+	m.connMut.RLock()
+	client := m.client
+	m.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	return IterateBatchedSend(msg, func(i int, p types.Part) error {
+		retained := m.conf.Retained
+		if m.retained != nil {
+			var parseErr error
+			retained, parseErr = strconv.ParseBool(m.retained.String(i, msg))
+			if parseErr != nil {
+				m.log.Errorf("Error parsing boolean value from retained flag: %v \n", parseErr)
+			}
+		}
+
+		pub := &paho.Publish{
+			Topic:   m.topic.String(i, msg),
+			QoS:     m.conf.QoS,
+			Retain:  retained,
+			Payload: p.Get(),
+		}
+		if len(m.userProperties) > 0 {
+			pub.Properties = &paho.PublishProperties{}
+			for _, prop := range m.userProperties {
+				pub.Properties.User = append(pub.Properties.User, paho.UserProperty{
+					Key:   prop.key,
+					Value: prop.value.String(i, msg),
+				})
+			}
+		}
+
+		wctx, done := context.WithTimeout(ctx, m.writeTimeout)
+		defer done()
+
+		res, err := client.Publish(wctx, pub)
+		if err != nil {
+			return err
+		}
+		if res != nil && res.ReasonCode >= 0x80 {
+			return fmt.Errorf("message rejected with reason code: %v", res.ReasonCode)
+		}
+		return nil
+	})
+}
+
+// CloseAsync shuts down the MQTT output and stops processing messages.
+func (m *MQTTV5) CloseAsync() {
+	go func() {
+		m.connMut.Lock()
+		if m.client != nil {
+			_ = m.client.Disconnect(&paho.Disconnect{})
+			m.client = nil
+		}
+		m.connMut.Unlock()
+	}()
+}
+
+// WaitForClose blocks until the MQTT output has closed down.
+func (m *MQTTV5) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
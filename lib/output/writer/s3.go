@@ -3,13 +3,16 @@ package writer
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/codec"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/internal/metadata"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -19,6 +22,8 @@ import (
 	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
@@ -33,6 +38,7 @@ type AmazonS3Config struct {
 	Tags                    map[string]string            `json:"tags" yaml:"tags"`
 	ContentType             string                       `json:"content_type" yaml:"content_type"`
 	ContentEncoding         string                       `json:"content_encoding" yaml:"content_encoding"`
+	Compression             string                       `json:"compression" yaml:"compression"`
 	CacheControl            string                       `json:"cache_control" yaml:"cache_control"`
 	ContentDisposition      string                       `json:"content_disposition" yaml:"content_disposition"`
 	ContentLanguage         string                       `json:"content_language" yaml:"content_language"`
@@ -43,6 +49,7 @@ type AmazonS3Config struct {
 	KMSKeyID                string                       `json:"kms_key_id" yaml:"kms_key_id"`
 	ServerSideEncryption    string                       `json:"server_side_encryption" yaml:"server_side_encryption"`
 	MaxInFlight             int                          `json:"max_in_flight" yaml:"max_in_flight"`
+	Multipart               AmazonS3MultipartConfig      `json:"multipart" yaml:"multipart"`
 	Batching                batch.PolicyConfig           `json:"batching" yaml:"batching"`
 }
 
@@ -56,6 +63,7 @@ func NewAmazonS3Config() AmazonS3Config {
 		Tags:                    map[string]string{},
 		ContentType:             "application/octet-stream",
 		ContentEncoding:         "",
+		Compression:             "none",
 		CacheControl:            "",
 		ContentDisposition:      "",
 		ContentLanguage:         "",
@@ -66,6 +74,7 @@ func NewAmazonS3Config() AmazonS3Config {
 		KMSKeyID:                "",
 		ServerSideEncryption:    "",
 		MaxInFlight:             1,
+		Multipart:               NewAmazonS3MultipartConfig(),
 		Batching:                batch.NewPolicyConfig(),
 	}
 }
@@ -92,11 +101,21 @@ type AmazonS3 struct {
 	websiteRedirectLocation *field.Expression
 	storageClass            *field.Expression
 	metaFilter              *metadata.ExcludeFilter
+	compress                codec.CompressionWriterConstructor
 
 	session  *session.Session
 	uploader *s3manager.Uploader
+	s3       s3iface.S3API
 	timeout  time.Duration
 
+	multipartCodec  codec.WriterConstructor
+	multipartMaxAge time.Duration
+	multipartMut    sync.Mutex
+	multipartUpload *s3MultipartUpload
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -127,10 +146,11 @@ func NewAmazonS3V2(
 		}
 	}
 	a := &AmazonS3{
-		conf:    conf,
-		log:     log,
-		stats:   stats,
-		timeout: timeout,
+		conf:       conf,
+		log:        log,
+		stats:      stats,
+		timeout:    timeout,
+		closedChan: make(chan struct{}),
 	}
 	var err error
 	if a.path, err = interop.NewBloblangField(mgr, conf.Path); err != nil {
@@ -177,6 +197,18 @@ func NewAmazonS3V2(
 		return a.tags[i].key < a.tags[j].key
 	})
 
+	if a.compress, err = codec.GetCompressionWriter(conf.Compression); err != nil {
+		return nil, err
+	}
+
+	if conf.Multipart.Enabled {
+		if a.compress != nil {
+			return nil, errors.New("compression cannot be used with multipart uploads")
+		}
+		if err := a.initMultipart(); err != nil {
+			return nil, err
+		}
+	}
 	return a, nil
 }
 
@@ -201,7 +233,12 @@ func (a *AmazonS3) Connect() error {
 
 	a.session = sess
 	a.uploader = s3manager.NewUploader(sess)
+	a.s3 = s3.New(sess)
 
+	if a.conf.Multipart.Enabled {
+		a.log.Infof("Streaming messages into objects with multipart uploads to Amazon S3 bucket: %v\n", a.conf.Bucket)
+		return nil
+	}
 	a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
 	return nil
 }
@@ -223,6 +260,10 @@ func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) err
 	)
 	defer cancel()
 
+	if a.conf.Multipart.Enabled {
+		return a.writeMultipart(ctx, msg)
+	}
+
 	return IterateBatchedSend(msg, func(i int, p types.Part) error {
 		metadata := map[string]*string{}
 		a.metaFilter.Iter(p.Metadata(), func(k, v string) error {
@@ -251,10 +292,15 @@ func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) err
 			websiteRedirectLocation = aws.String(ce)
 		}
 
+		body, err := codec.CompressBytes(a.compress, p.Get())
+		if err != nil {
+			return fmt.Errorf("failed to compress object: %w", err)
+		}
+
 		uploadInput := &s3manager.UploadInput{
 			Bucket:                  &a.conf.Bucket,
 			Key:                     aws.String(a.path.String(i, msg)),
-			Body:                    bytes.NewReader(p.Get()),
+			Body:                    bytes.NewReader(body),
 			ContentType:             aws.String(a.contentType.String(i, msg)),
 			ContentEncoding:         contentEncoding,
 			CacheControl:            cacheControl,
@@ -295,11 +341,24 @@ func (a *AmazonS3) WriteWithContext(wctx context.Context, msg types.Message) err
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonS3) CloseAsync() {
+	a.closeOnce.Do(func() {
+		go func() {
+			if a.conf.Multipart.Enabled {
+				a.closeMultipart()
+			}
+			close(a.closedChan)
+		}()
+	})
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonS3) WaitForClose(time.Duration) error {
+func (a *AmazonS3) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
@@ -0,0 +1,323 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/codec"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// AmazonS3MultipartConfig contains configuration fields for streaming batches
+// into large objects with multipart uploads.
+type AmazonS3MultipartConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	Codec         string `json:"codec" yaml:"codec"`
+	PartSize      int    `json:"part_size" yaml:"part_size"`
+	MaxObjectSize int    `json:"max_object_size" yaml:"max_object_size"`
+	MaxObjectAge  string `json:"max_object_age" yaml:"max_object_age"`
+}
+
+// NewAmazonS3MultipartConfig creates a new AmazonS3MultipartConfig with
+// default values.
+func NewAmazonS3MultipartConfig() AmazonS3MultipartConfig {
+	return AmazonS3MultipartConfig{
+		Enabled:       false,
+		Codec:         "lines",
+		PartSize:      s3MinPartSize,
+		MaxObjectSize: 1024 * 1024 * 1024,
+		MaxObjectAge:  "10m",
+	}
+}
+
+const (
+	// The minimum size of all parts of a multipart upload other than the last.
+	s3MinPartSize = 5 * 1024 * 1024
+
+	// The maximum number of parts of a multipart upload.
+	s3MaxParts = 10000
+
+	// The period to wait before reattempting to complete an upload that failed
+	// to complete in the background.
+	s3MultipartRetryPeriod = time.Second
+)
+
+//------------------------------------------------------------------------------
+
+// s3MultipartUpload is an object currently being written to with a multipart
+// upload, where data is buffered until it is large enough to upload as a part.
+type s3MultipartUpload struct {
+	key      string
+	uploadID string
+	parts    []*s3.CompletedPart
+	size     int
+
+	buf *s3PartBuffer
+	enc codec.Writer
+
+	// Set once the upload should be completed, in which case it is no longer
+	// written to.
+	closing bool
+}
+
+type s3PartBuffer struct {
+	bytes.Buffer
+}
+
+func (s *s3PartBuffer) Close() error {
+	return nil
+}
+
+func (a *AmazonS3) initMultipart() error {
+	conf := a.conf.Multipart
+	if conf.PartSize < s3MinPartSize {
+		return fmt.Errorf("multipart part size must be at least %v bytes", s3MinPartSize)
+	}
+	if conf.MaxObjectSize < 0 {
+		return errors.New("multipart max object size must not be negative")
+	}
+	if conf.MaxObjectAge != "" {
+		var err error
+		if a.multipartMaxAge, err = time.ParseDuration(conf.MaxObjectAge); err != nil {
+			return fmt.Errorf("failed to parse multipart max object age: %v", err)
+		}
+	}
+	if conf.MaxObjectSize == 0 && a.multipartMaxAge <= 0 {
+		return errors.New("either a multipart max object size or max object age must be specified")
+	}
+
+	var codecConf codec.WriterConfig
+	var err error
+	if a.multipartCodec, codecConf, err = codec.GetWriter(conf.Codec); err != nil {
+		return err
+	}
+	if codecConf.Truncate || codecConf.CloseAfter {
+		return fmt.Errorf("codec %v cannot be used with multipart uploads", conf.Codec)
+	}
+	return nil
+}
+
+func (a *AmazonS3) writeMultipart(ctx context.Context, msg types.Message) error {
+	a.multipartMut.Lock()
+	defer a.multipartMut.Unlock()
+
+	// An object that failed to complete is reattempted before writing more
+	// data, as the data of previous batches has already been acknowledged.
+	if up := a.multipartUpload; up != nil && up.closing {
+		if err := a.completeMultipart(ctx, up); err != nil {
+			return err
+		}
+	}
+
+	up := a.multipartUpload
+	if up == nil {
+		var err error
+		if up, err = a.createMultipart(ctx, msg); err != nil {
+			return err
+		}
+		a.multipartUpload = up
+	}
+
+	// If anything fails the buffer is reset so that the batch can be written
+	// again without duplicating data.
+	bufLen, size := up.buf.Len(), up.size
+	reset := func() {
+		up.buf.Truncate(bufLen)
+		up.size = size
+	}
+
+	if err := msg.Iter(func(i int, p types.Part) error {
+		return up.enc.Write(ctx, p)
+	}); err != nil {
+		reset()
+		return err
+	}
+	up.size += up.buf.Len() - bufLen
+
+	if up.buf.Len() >= a.conf.Multipart.PartSize {
+		if err := a.uploadPart(ctx, up); err != nil {
+			reset()
+			return err
+		}
+	}
+
+	if (a.conf.Multipart.MaxObjectSize > 0 && up.size >= a.conf.Multipart.MaxObjectSize) ||
+		len(up.parts) >= s3MaxParts-1 {
+		// The data of this batch has been either uploaded or buffered, and
+		// therefore a failure to complete the object is reattempted
+		// separately.
+		if err := a.completeMultipart(ctx, up); err != nil {
+			a.log.Errorf("Failed to complete multipart upload of object %v: %v\n", up.key, err)
+			a.scheduleMultipartCompletion(up, s3MultipartRetryPeriod)
+		}
+	}
+	return nil
+}
+
+func (a *AmazonS3) createMultipart(ctx context.Context, msg types.Message) (*s3MultipartUpload, error) {
+	metadata := map[string]*string{}
+	a.metaFilter.Iter(msg.Get(0).Metadata(), func(k, v string) error {
+		metadata[k] = aws.String(v)
+		return nil
+	})
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:       &a.conf.Bucket,
+		Key:          aws.String(a.path.String(0, msg)),
+		ContentType:  aws.String(a.contentType.String(0, msg)),
+		StorageClass: aws.String(a.storageClass.String(0, msg)),
+		Metadata:     metadata,
+	}
+	if ce := a.contentEncoding.String(0, msg); len(ce) > 0 {
+		input.ContentEncoding = aws.String(ce)
+	}
+	if ce := a.cacheControl.String(0, msg); len(ce) > 0 {
+		input.CacheControl = aws.String(ce)
+	}
+	if ce := a.contentDisposition.String(0, msg); len(ce) > 0 {
+		input.ContentDisposition = aws.String(ce)
+	}
+	if ce := a.contentLanguage.String(0, msg); len(ce) > 0 {
+		input.ContentLanguage = aws.String(ce)
+	}
+	if ce := a.websiteRedirectLocation.String(0, msg); len(ce) > 0 {
+		input.WebsiteRedirectLocation = aws.String(ce)
+	}
+	if len(a.tags) > 0 {
+		tags := make([]string, len(a.tags))
+		for j, pair := range a.tags {
+			tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(pair.value.String(0, msg))
+		}
+		input.Tagging = aws.String(strings.Join(tags, "&"))
+	}
+	if a.conf.KMSKeyID != "" {
+		input.ServerSideEncryption = aws.String("aws:kms")
+		input.SSEKMSKeyId = &a.conf.KMSKeyID
+	}
+	if a.conf.ServerSideEncryption != "" {
+		input.ServerSideEncryption = &a.conf.ServerSideEncryption
+	}
+
+	output, err := a.s3.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	up := &s3MultipartUpload{
+		key:      *input.Key,
+		uploadID: aws.StringValue(output.UploadId),
+		buf:      &s3PartBuffer{},
+	}
+	if up.enc, err = a.multipartCodec(up.buf); err != nil {
+		return nil, err
+	}
+	if a.multipartMaxAge > 0 {
+		a.scheduleMultipartCompletion(up, a.multipartMaxAge)
+	}
+	return up, nil
+}
+
+// uploadPart uploads the buffered data of an object as its next part.
+func (a *AmazonS3) uploadPart(ctx context.Context, up *s3MultipartUpload) error {
+	partNumber := int64(len(up.parts) + 1)
+	output, err := a.s3.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:        &a.conf.Bucket,
+		Key:           aws.String(up.key),
+		UploadId:      aws.String(up.uploadID),
+		PartNumber:    aws.Int64(partNumber),
+		Body:          bytes.NewReader(up.buf.Bytes()),
+		ContentLength: aws.Int64(int64(up.buf.Len())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %v of object %v: %w", partNumber, up.key, err)
+	}
+	up.parts = append(up.parts, &s3.CompletedPart{
+		ETag:       output.ETag,
+		PartNumber: aws.Int64(partNumber),
+	})
+	up.buf.Reset()
+	return nil
+}
+
+// completeMultipart uploads any remaining data of an object as its last part
+// and completes the upload, after which the next write begins a new object.
+func (a *AmazonS3) completeMultipart(ctx context.Context, up *s3MultipartUpload) error {
+	up.closing = true
+	if up.buf.Len() > 0 {
+		if err := a.uploadPart(ctx, up); err != nil {
+			return err
+		}
+	}
+
+	if len(up.parts) == 0 {
+		if _, err := a.s3.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &a.conf.Bucket,
+			Key:      aws.String(up.key),
+			UploadId: aws.String(up.uploadID),
+		}); err != nil {
+			return fmt.Errorf("failed to abort empty upload of object %v: %w", up.key, err)
+		}
+	} else if _, err := a.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &a.conf.Bucket,
+		Key:      aws.String(up.key),
+		UploadId: aws.String(up.uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: up.parts,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to complete upload of object %v: %w", up.key, err)
+	}
+
+	if a.multipartUpload == up {
+		a.multipartUpload = nil
+	}
+	return nil
+}
+
+// scheduleMultipartCompletion completes an object in the background after a
+// period, unless it has already been completed by then.
+func (a *AmazonS3) scheduleMultipartCompletion(up *s3MultipartUpload, after time.Duration) {
+	time.AfterFunc(after, func() {
+		a.multipartMut.Lock()
+		defer a.multipartMut.Unlock()
+
+		if a.multipartUpload != up {
+			return
+		}
+		ctx, done := a.multipartContext()
+		defer done()
+		if err := a.completeMultipart(ctx, up); err != nil {
+			a.log.Errorf("Failed to complete multipart upload of object %v: %v\n", up.key, err)
+			a.scheduleMultipartCompletion(up, s3MultipartRetryPeriod)
+		}
+	})
+}
+
+func (a *AmazonS3) multipartContext() (context.Context, context.CancelFunc) {
+	if a.timeout > 0 {
+		return context.WithTimeout(context.Background(), a.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// closeMultipart completes the current object, if any, during shut down.
+func (a *AmazonS3) closeMultipart() {
+	a.multipartMut.Lock()
+	defer a.multipartMut.Unlock()
+
+	if up := a.multipartUpload; up != nil {
+		ctx, done := a.multipartContext()
+		defer done()
+		if err := a.completeMultipart(ctx, up); err != nil {
+			a.log.Errorf("Failed to complete multipart upload of object %v during shut down: %v\n", up.key, err)
+		}
+		a.multipartUpload = nil
+	}
+}
@@ -0,0 +1,182 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockS3Multipart struct {
+	s3iface.S3API
+
+	created   []string
+	parts     map[string][][]byte
+	completed []string
+	aborted   []string
+
+	partErr error
+}
+
+func (m *mockS3Multipart) CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput, _ ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	m.created = append(m.created, *input.Key)
+	return &s3.CreateMultipartUploadOutput{
+		UploadId: aws.String("upload-" + *input.Key),
+	}, nil
+}
+
+func (m *mockS3Multipart) UploadPartWithContext(ctx aws.Context, input *s3.UploadPartInput, _ ...request.Option) (*s3.UploadPartOutput, error) {
+	if m.partErr != nil {
+		err := m.partErr
+		m.partErr = nil
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) != *input.ContentLength {
+		return nil, errors.New("content length mismatch")
+	}
+	parts := m.parts[*input.UploadId]
+	if int64(len(parts)+1) != *input.PartNumber {
+		return nil, errors.New("unexpected part number")
+	}
+	m.parts[*input.UploadId] = append(parts, b)
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (m *mockS3Multipart) CompleteMultipartUploadWithContext(ctx aws.Context, input *s3.CompleteMultipartUploadInput, _ ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	if len(input.MultipartUpload.Parts) != len(m.parts[*input.UploadId]) {
+		return nil, errors.New("unexpected number of parts")
+	}
+	m.completed = append(m.completed, *input.Key)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3Multipart) AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, _ ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	m.aborted = append(m.aborted, *input.Key)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestS3MultipartWrites(t *testing.T) {
+	ctx := context.Background()
+
+	conf := NewAmazonS3Config()
+	conf.Bucket = "foo"
+	conf.Path = `${!meta("key")}.txt`
+	conf.Multipart.Enabled = true
+	conf.Multipart.MaxObjectAge = ""
+	conf.Multipart.MaxObjectSize = 9 * 1024 * 1024
+
+	w, err := NewAmazonS3V2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mock := &mockS3Multipart{parts: map[string][][]byte{}}
+	w.s3 = mock
+
+	chunk := bytes.Repeat([]byte("a"), 3*1024*1024)
+	writeChunk := func(key string) error {
+		msg := message.New([][]byte{chunk})
+		msg.Get(0).Metadata().Set("key", key)
+		return w.writeMultipart(ctx, msg)
+	}
+
+	// Buffered until there's enough data for a part
+	require.NoError(t, writeChunk("first"))
+	assert.Equal(t, []string{"first.txt"}, mock.created)
+	assert.Empty(t, mock.parts["upload-first.txt"])
+
+	// A failed part upload must not duplicate the data of the batch once it's
+	// reattempted
+	mock.partErr = errors.New("nope")
+	require.Error(t, writeChunk("second"))
+	require.NoError(t, writeChunk("second"))
+	require.Len(t, mock.parts["upload-first.txt"], 1)
+	assert.Len(t, mock.parts["upload-first.txt"][0], 2*(len(chunk)+1))
+
+	// Reaching the max object size completes the object
+	require.NoError(t, writeChunk("third"))
+	require.Len(t, mock.parts["upload-first.txt"], 2)
+	assert.Len(t, mock.parts["upload-first.txt"][1], len(chunk)+1)
+	assert.Equal(t, []string{"first.txt"}, mock.completed)
+
+	// The next batch begins a new object, which is completed on close
+	require.NoError(t, writeChunk("fourth"))
+	assert.Equal(t, []string{"first.txt", "fourth.txt"}, mock.created)
+
+	w.CloseAsync()
+	require.NoError(t, w.WaitForClose(time.Second))
+	assert.Equal(t, []string{"first.txt", "fourth.txt"}, mock.completed)
+	require.Len(t, mock.parts["upload-fourth.txt"], 1)
+	assert.Equal(t, append(chunk, '\n'), mock.parts["upload-fourth.txt"][0])
+	assert.Empty(t, mock.aborted)
+}
+
+func TestS3MultipartConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		fn          func(c *AmazonS3MultipartConfig)
+		errContains string
+	}{
+		"part size too small": {
+			fn: func(c *AmazonS3MultipartConfig) {
+				c.PartSize = 1024
+			},
+			errContains: "part size must be at least",
+		},
+		"no thresholds": {
+			fn: func(c *AmazonS3MultipartConfig) {
+				c.MaxObjectSize = 0
+				c.MaxObjectAge = ""
+			},
+			errContains: "either a multipart max object size or max object age must be specified",
+		},
+		"bad age": {
+			fn: func(c *AmazonS3MultipartConfig) {
+				c.MaxObjectAge = "nope"
+			},
+			errContains: "failed to parse multipart max object age",
+		},
+		"all-bytes codec": {
+			fn: func(c *AmazonS3MultipartConfig) {
+				c.Codec = "all-bytes"
+			},
+			errContains: "cannot be used with multipart uploads",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewAmazonS3Config()
+			conf.Multipart.Enabled = true
+			test.fn(&conf.Multipart)
+
+			_, err := NewAmazonS3V2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestS3MultipartCompression(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Multipart.Enabled = true
+	conf.Compression = "zstd"
+
+	_, err := NewAmazonS3V2(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "compression cannot be used with multipart uploads")
+}
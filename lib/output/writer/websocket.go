@@ -2,6 +2,8 @@ package writer
 
 import (
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
@@ -11,25 +13,53 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/gorilla/websocket"
 )
 
 //------------------------------------------------------------------------------
 
+// WebsocketPingConfig contains configuration fields for sending pings to a
+// websocket server in order to detect lost connections.
+type WebsocketPingConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Period  string `json:"period" yaml:"period"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// NewWebsocketPingConfig creates a new WebsocketPingConfig with default values.
+func NewWebsocketPingConfig() WebsocketPingConfig {
+	return WebsocketPingConfig{
+		Enabled: false,
+		Period:  "30s",
+		Timeout: "10s",
+	}
+}
+
 // WebsocketConfig contains configuration fields for the Websocket output type.
 type WebsocketConfig struct {
-	URL         string `json:"url" yaml:"url"`
-	auth.Config `json:",inline" yaml:",inline"`
-	TLS         btls.Config `json:"tls" yaml:"tls"`
+	URL          string              `json:"url" yaml:"url"`
+	Subprotocols []string            `json:"subprotocols" yaml:"subprotocols"`
+	Ping         WebsocketPingConfig `json:"ping" yaml:"ping"`
+	Reconnect    retries.Config      `json:"reconnect" yaml:"reconnect"`
+	auth.Config  `json:",inline" yaml:",inline"`
+	TLS          btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
 func NewWebsocketConfig() WebsocketConfig {
+	rConf := retries.NewConfig()
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "30s"
 	return WebsocketConfig{
-		URL:    "ws://localhost:4195/post/ws",
-		Config: auth.NewConfig(),
-		TLS:    btls.NewConfig(),
+		URL:          "ws://localhost:4195/post/ws",
+		Subprotocols: []string{},
+		Ping:         NewWebsocketPingConfig(),
+		Reconnect:    rConf,
+		Config:       auth.NewConfig(),
+		TLS:          btls.NewConfig(),
 	}
 }
 
@@ -45,6 +75,15 @@ type Websocket struct {
 	conf    WebsocketConfig
 	client  *websocket.Conn
 	tlsConf *tls.Config
+
+	pingPeriod  time.Duration
+	pingTimeout time.Duration
+
+	reconnectBoff backoff.BackOff
+	reconnecting  bool
+
+	closeOnce sync.Once
+	closeChan chan struct{}
 }
 
 // NewWebsocket creates a new Websocket output type.
@@ -54,10 +93,11 @@ func NewWebsocket(
 	stats metrics.Type,
 ) (*Websocket, error) {
 	ws := &Websocket{
-		log:   log,
-		stats: stats,
-		lock:  &sync.Mutex{},
-		conf:  conf,
+		log:       log,
+		stats:     stats,
+		lock:      &sync.Mutex{},
+		conf:      conf,
+		closeChan: make(chan struct{}),
 	}
 	if conf.TLS.Enabled {
 		var err error
@@ -65,6 +105,24 @@ func NewWebsocket(
 			return nil, err
 		}
 	}
+
+	if conf.Ping.Enabled {
+		var err error
+		if ws.pingPeriod, err = time.ParseDuration(conf.Ping.Period); err != nil {
+			return nil, fmt.Errorf("failed to parse ping period: %v", err)
+		}
+		if ws.pingPeriod <= 0 {
+			return nil, errors.New("ping period must be greater than zero")
+		}
+		if ws.pingTimeout, err = time.ParseDuration(conf.Ping.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse ping timeout: %v", err)
+		}
+	}
+
+	var err error
+	if ws.reconnectBoff, err = conf.Reconnect.Get(); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect config: %v", err)
+	}
 	return ws, nil
 }
 
@@ -88,6 +146,20 @@ func (w *Websocket) Connect() error {
 		return nil
 	}
 
+	if w.reconnecting {
+		wait := w.reconnectBoff.NextBackOff()
+		if wait == backoff.Stop {
+			w.log.Errorf("Giving up on reconnecting to websocket server: %v\n", w.conf.URL)
+			return types.ErrTypeClosed
+		}
+		select {
+		case <-time.After(wait):
+		case <-w.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+	w.reconnecting = true
+
 	headers := http.Header{}
 
 	purl, err := url.Parse(w.conf.URL)
@@ -102,32 +174,73 @@ func (w *Websocket) Connect() error {
 		return err
 	}
 
-	var client *websocket.Conn
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		Subprotocols:     w.conf.Subprotocols,
+	}
 	if w.conf.TLS.Enabled {
-		dialer := websocket.Dialer{
-			TLSClientConfig: w.tlsConf,
-		}
-		if client, _, err = dialer.Dial(w.conf.URL, headers); err != nil {
-			return err
-
-		}
-	} else if client, _, err = websocket.DefaultDialer.Dial(w.conf.URL, headers); err != nil {
+		dialer.TLSClientConfig = w.tlsConf
+	}
+	client, _, err := dialer.Dial(w.conf.URL, headers)
+	if err != nil {
 		return err
 	}
 
+	var pingStop chan struct{}
+	if w.pingPeriod > 0 {
+		deadline := w.pingPeriod + w.pingTimeout
+		_ = client.SetReadDeadline(time.Now().Add(deadline))
+		client.SetPongHandler(func(string) error {
+			return client.SetReadDeadline(time.Now().Add(deadline))
+		})
+		pingStop = make(chan struct{})
+		go w.pingLoop(client, pingStop)
+	}
+
+	// Reading from the connection processes control messages such as pongs,
+	// and detects when the connection is lost.
 	go func(c *websocket.Conn) {
 		for {
 			if _, _, cerr := c.NextReader(); cerr != nil {
 				c.Close()
 				break
 			}
+			if w.pingPeriod > 0 {
+				_ = c.SetReadDeadline(time.Now().Add(w.pingPeriod + w.pingTimeout))
+			}
+		}
+		if pingStop != nil {
+			close(pingStop)
 		}
 	}(client)
 
+	w.reconnectBoff.Reset()
 	w.client = client
+	w.log.Infof("Sending websocket messages to: %v\n", w.conf.URL)
 	return nil
 }
 
+// pingLoop periodically sends pings to the server until the connection is
+// closed, where a missing pong causes the connection to be closed.
+func (w *Websocket) pingLoop(client *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := client.WriteControl(
+				websocket.PingMessage, nil, time.Now().Add(w.pingTimeout),
+			); err != nil {
+				w.log.Debugf("Failed to send ping: %v\n", err)
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Write attempts to write a message by pushing it to an Websocket broker.
@@ -154,6 +267,9 @@ func (w *Websocket) Write(msg types.Message) error {
 
 // CloseAsync shuts down the Websocket output and stops processing messages.
 func (w *Websocket) CloseAsync() {
+	w.closeOnce.Do(func() {
+		close(w.closeChan)
+	})
 	go func() {
 		w.lock.Lock()
 		if w.client != nil {
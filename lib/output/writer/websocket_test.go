@@ -115,3 +115,76 @@ func TestWebsocketClose(t *testing.T) {
 	wg.Wait()
 	close(closeChan)
 }
+
+func TestWebsocketSubprotocolsAndPings(t *testing.T) {
+	pingChan := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{
+			Subprotocols: []string{"bar"},
+		}
+
+		var ws *websocket.Conn
+		var err error
+		if ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+			return
+		}
+		defer ws.Close()
+
+		if exp, act := "bar", ws.Subprotocol(); exp != act {
+			t.Errorf("Wrong subprotocol: %v != %v", act, exp)
+		}
+
+		ws.SetPingHandler(func(data string) error {
+			select {
+			case pingChan <- struct{}{}:
+			default:
+			}
+			return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		for {
+			if _, _, err = ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conf := NewWebsocketConfig()
+	conf.Subprotocols = []string{"foo", "bar"}
+	conf.Ping.Enabled = true
+	conf.Ping.Period = "10ms"
+	conf.Ping.Timeout = "1s"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-pingChan:
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for ping")
+		}
+	}
+
+	// The connection remains usable whilst pings are answered.
+	if err = m.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Error(err)
+	}
+
+	m.CloseAsync()
+	if err = m.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/tracing"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/linkedin/goavro/v2"
@@ -40,13 +42,21 @@ specifies how the source documents are encoded.
 ### ` + "`from_json`" + `
 
 Attempts to convert JSON documents into Avro documents according to the
-specified encoding.`,
+specified encoding.
+## Logical Types
+
+Values of the logical types ` + "`timestamp-millis`, `timestamp-micros`, `date`, `time-millis`, `time-micros` and `decimal`" + ` are mapped to and from JSON types according to the schema. Timestamps are represented as [RFC 3339][rfc3339] strings, dates as strings of the form ` + "`2006-01-02`" + `, times as integers of the unit of the type, and decimals as numbers with a fractional part of the scale of the type.
+
+When converting from JSON timestamps and dates may also be given as integers of their underlying type, and decimals may also be given as strings in order to avoid losing precision.
+
+[rfc3339]: https://tools.ietf.org/html/rfc3339`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("encoding", "An Avro encoding format to use for conversions to and from a schema.").HasOptions("textual", "binary", "single"),
 			docs.FieldCommon("schema", "A full Avro schema to use."),
 			docs.FieldCommon(
-				"schema_path", "The path of a schema document to apply. Use either this or the `schema` field.",
+				"schema_path", "The path of a schema document to apply, which can be a local file path, or a URL with the scheme `file`, `http` or `https`. Use either this or the `schema` field.",
+				"./schemas/spec.avsc",
 				"file://path/to/spec.avsc",
 				"http://localhost:8081/path/to/spec/versions/1",
 			),
@@ -81,7 +91,7 @@ func NewAvroConfig() AvroConfig {
 
 type avroOperator func(part types.Part) error
 
-func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator, error) {
+func newAvroToJSONOperator(encoding string, codec *goavro.Codec, logical *avroLogicalWalker) (avroOperator, error) {
 	switch encoding {
 	case "textual":
 		return func(part types.Part) error {
@@ -89,6 +99,9 @@ func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator,
 			if err != nil {
 				return fmt.Errorf("failed to convert Avro document to JSON: %v", err)
 			}
+			if jObj, err = logical.Walk(jObj, avroLogicalToJSON); err != nil {
+				return fmt.Errorf("failed to convert Avro logical types to JSON: %v", err)
+			}
 			if err = part.SetJSON(jObj); err != nil {
 				return fmt.Errorf("failed to set JSON: %v", err)
 			}
@@ -100,6 +113,9 @@ func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator,
 			if err != nil {
 				return fmt.Errorf("failed to convert Avro document to JSON: %v", err)
 			}
+			if jObj, err = logical.Walk(jObj, avroLogicalToJSON); err != nil {
+				return fmt.Errorf("failed to convert Avro logical types to JSON: %v", err)
+			}
 			if err = part.SetJSON(jObj); err != nil {
 				return fmt.Errorf("failed to set JSON: %v", err)
 			}
@@ -111,6 +127,9 @@ func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator,
 			if err != nil {
 				return fmt.Errorf("failed to convert Avro document to JSON: %v", err)
 			}
+			if jObj, err = logical.Walk(jObj, avroLogicalToJSON); err != nil {
+				return fmt.Errorf("failed to convert Avro logical types to JSON: %v", err)
+			}
 			if err = part.SetJSON(jObj); err != nil {
 				return fmt.Errorf("failed to set JSON: %v", err)
 			}
@@ -120,13 +139,13 @@ func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator,
 	return nil, fmt.Errorf("encoding '%v' not recognised", encoding)
 }
 
-func newAvroFromJSONOperator(encoding string, codec *goavro.Codec) (avroOperator, error) {
+func newAvroFromJSONOperator(encoding string, codec *goavro.Codec, logical *avroLogicalWalker) (avroOperator, error) {
 	switch encoding {
 	case "textual":
 		return func(part types.Part) error {
-			jObj, err := part.JSON()
+			jObj, err := avroNativeFromJSON(part, logical)
 			if err != nil {
-				return fmt.Errorf("failed to parse message as JSON: %v", err)
+				return err
 			}
 			var textual []byte
 			if textual, err = codec.TextualFromNative(nil, jObj); err != nil {
@@ -137,9 +156,9 @@ func newAvroFromJSONOperator(encoding string, codec *goavro.Codec) (avroOperator
 		}, nil
 	case "binary":
 		return func(part types.Part) error {
-			jObj, err := part.JSON()
+			jObj, err := avroNativeFromJSON(part, logical)
 			if err != nil {
-				return fmt.Errorf("failed to parse message as JSON: %v", err)
+				return err
 			}
 			var binary []byte
 			if binary, err = codec.BinaryFromNative(nil, jObj); err != nil {
@@ -150,9 +169,9 @@ func newAvroFromJSONOperator(encoding string, codec *goavro.Codec) (avroOperator
 		}, nil
 	case "single":
 		return func(part types.Part) error {
-			jObj, err := part.JSON()
+			jObj, err := avroNativeFromJSON(part, logical)
 			if err != nil {
-				return fmt.Errorf("failed to parse message as JSON: %v", err)
+				return err
 			}
 			var single []byte
 			if single, err = codec.SingleFromNative(nil, jObj); err != nil {
@@ -165,17 +184,45 @@ func newAvroFromJSONOperator(encoding string, codec *goavro.Codec) (avroOperator
 	return nil, fmt.Errorf("encoding '%v' not recognised", encoding)
 }
 
-func strToAvroOperator(opStr, encoding string, codec *goavro.Codec) (avroOperator, error) {
+// avroNativeFromJSON parses a message as JSON and converts the values of
+// logical types into the native forms goavro expects. The structure is copied
+// so that a failed conversion leaves the message untouched.
+func avroNativeFromJSON(part types.Part, logical *avroLogicalWalker) (interface{}, error) {
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	if jObj, err = message.CopyJSON(jObj); err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	if jObj, err = logical.Walk(jObj, avroLogicalFromJSON); err != nil {
+		return nil, fmt.Errorf("failed to convert JSON to Avro logical types: %v", err)
+	}
+	return jObj, nil
+}
+
+func strToAvroOperator(opStr, encoding, schema string, codec *goavro.Codec) (avroOperator, error) {
+	logical := newAvroLogicalWalker(schema)
 	switch opStr {
 	case "to_json":
-		return newAvroToJSONOperator(encoding, codec)
+		return newAvroToJSONOperator(encoding, codec, logical)
 	case "from_json":
-		return newAvroFromJSONOperator(encoding, codec)
+		return newAvroFromJSONOperator(encoding, codec, logical)
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
 func loadSchema(schemaPath string) (string, error) {
+	if !(strings.HasPrefix(schemaPath, "file://") ||
+		strings.HasPrefix(schemaPath, "http://") ||
+		strings.HasPrefix(schemaPath, "https://")) {
+		body, err := os.ReadFile(schemaPath)
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+	}
+
 	t := &http.Transport{}
 	t.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
 	c := &http.Client{Transport: t}
@@ -188,6 +235,10 @@ func loadSchema(schemaPath string) (string, error) {
 
 	defer response.Body.Close()
 
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("request returned status: %v", response.StatusCode)
+	}
+
 	body, err := io.ReadAll(response.Body)
 
 	if err != nil {
@@ -233,10 +284,6 @@ func NewAvro(
 	var err error
 
 	if schemaPath := conf.Avro.SchemaPath; schemaPath != "" {
-		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
-			return nil, fmt.Errorf("invalid schema_path provided, must start with file:// or http://")
-		}
-
 		schema, err = loadSchema(schemaPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load Avro schema definition: %v", err)
@@ -250,7 +297,7 @@ func NewAvro(
 		return nil, fmt.Errorf("failed to parse schema: %v", err)
 	}
 
-	if a.operator, err = strToAvroOperator(conf.Avro.Operator, conf.Avro.Encoding, codec); err != nil {
+	if a.operator, err = strToAvroOperator(conf.Avro.Operator, conf.Avro.Encoding, schema, codec); err != nil {
 		return nil, err
 	}
 	return a, nil
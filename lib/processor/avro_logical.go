@@ -0,0 +1,337 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// avroLogicalFn converts the value of a logical type, where the schema is the
+// definition of the annotated type.
+type avroLogicalFn func(logicalType string, schema map[string]interface{}, v interface{}) (interface{}, error)
+
+// avroLogicalWalker walks values alongside the Avro schema that describes them
+// in order to convert the values of logical types, which goavro represents as
+// Go types that have no sensible JSON equivalent.
+type avroLogicalWalker struct {
+	root  interface{}
+	named map[string]namedAvroSchema
+}
+
+type namedAvroSchema struct {
+	schema    map[string]interface{}
+	namespace string
+}
+
+func newAvroLogicalWalker(schema string) *avroLogicalWalker {
+	var root interface{}
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		// Schemas may also be the bare name of a primitive type.
+		root = strings.Trim(strings.TrimSpace(schema), `"`)
+	}
+	w := &avroLogicalWalker{
+		root:  root,
+		named: map[string]namedAvroSchema{},
+	}
+	w.register(root, "")
+	return w
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// avroNamespace returns the namespace of a named type, which is inherited from
+// the enclosing type unless specified.
+func avroNamespace(schema map[string]interface{}, namespace string) string {
+	name, _ := schema["name"].(string)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	if ns, ok := schema["namespace"].(string); ok {
+		return ns
+	}
+	return namespace
+}
+
+// register records the named types of a schema so that they can be resolved
+// when referenced by name.
+func (w *avroLogicalWalker) register(schema interface{}, namespace string) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, branch := range s {
+			w.register(branch, namespace)
+		}
+	case map[string]interface{}:
+		if name, ok := s["name"].(string); ok {
+			switch s["type"] {
+			case "record", "error", "enum", "fixed":
+				namespace = avroNamespace(s, namespace)
+				w.named[avroFullName(name, namespace)] = namedAvroSchema{schema: s, namespace: namespace}
+			}
+		}
+		switch s["type"] {
+		case "record", "error":
+			fields, _ := s["fields"].([]interface{})
+			for _, f := range fields {
+				if fMap, ok := f.(map[string]interface{}); ok {
+					w.register(fMap["type"], namespace)
+				}
+			}
+		case "array":
+			w.register(s["items"], namespace)
+		case "map":
+			w.register(s["values"], namespace)
+		default:
+			if _, isStr := s["type"].(string); !isStr {
+				w.register(s["type"], namespace)
+			}
+		}
+	}
+}
+
+// resolve returns the definition of a type referenced by name, or nil if the
+// name refers to a primitive type.
+func (w *avroLogicalWalker) resolve(name, namespace string) (map[string]interface{}, string) {
+	if n, exists := w.named[avroFullName(name, namespace)]; exists {
+		return n.schema, n.namespace
+	}
+	if n, exists := w.named[name]; exists {
+		return n.schema, n.namespace
+	}
+	return nil, ""
+}
+
+// unionBranchName returns the name goavro uses for a branch of a union within
+// its native representation of the union.
+func (w *avroLogicalWalker) unionBranchName(schema interface{}, namespace string) string {
+	switch s := schema.(type) {
+	case string:
+		if named, ns := w.resolve(s, namespace); named != nil {
+			name, _ := named["name"].(string)
+			return avroFullName(name, ns)
+		}
+		return s
+	case map[string]interface{}:
+		typeStr, _ := s["type"].(string)
+		switch typeStr {
+		case "record", "error", "enum", "fixed":
+			name, _ := s["name"].(string)
+			return avroFullName(name, avroNamespace(s, namespace))
+		}
+		if logical, ok := s["logicalType"].(string); ok {
+			return typeStr + "." + logical
+		}
+		if typeStr != "" {
+			return typeStr
+		}
+		return w.unionBranchName(s["type"], namespace)
+	}
+	return ""
+}
+
+// Walk converts the values of all logical types within a value.
+func (w *avroLogicalWalker) Walk(v interface{}, fn avroLogicalFn) (interface{}, error) {
+	return w.walk(w.root, "", v, fn)
+}
+
+func (w *avroLogicalWalker) walk(schema interface{}, namespace string, v interface{}, fn avroLogicalFn) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch s := schema.(type) {
+	case string:
+		if named, ns := w.resolve(s, namespace); named != nil {
+			return w.walk(named, ns, v, fn)
+		}
+		return v, nil
+	case []interface{}:
+		vMap, ok := v.(map[string]interface{})
+		if !ok || len(vMap) != 1 {
+			return v, nil
+		}
+		for k, inner := range vMap {
+			for _, branch := range s {
+				if w.unionBranchName(branch, namespace) != k {
+					continue
+				}
+				converted, err := w.walk(branch, namespace, inner, fn)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{k: converted}, nil
+			}
+		}
+		return v, nil
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error":
+			vMap, ok := v.(map[string]interface{})
+			if !ok {
+				return v, nil
+			}
+			namespace = avroNamespace(s, namespace)
+			fields, _ := s["fields"].([]interface{})
+			for _, f := range fields {
+				fMap, ok := f.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := fMap["name"].(string)
+				fv, exists := vMap[name]
+				if !exists {
+					continue
+				}
+				converted, err := w.walk(fMap["type"], namespace, fv, fn)
+				if err != nil {
+					return nil, fmt.Errorf("field %v: %w", name, err)
+				}
+				vMap[name] = converted
+			}
+			return vMap, nil
+		case "array":
+			vArr, ok := v.([]interface{})
+			if !ok {
+				return v, nil
+			}
+			for i, ev := range vArr {
+				converted, err := w.walk(s["items"], namespace, ev, fn)
+				if err != nil {
+					return nil, err
+				}
+				vArr[i] = converted
+			}
+			return vArr, nil
+		case "map":
+			vMap, ok := v.(map[string]interface{})
+			if !ok {
+				return v, nil
+			}
+			for k, ev := range vMap {
+				converted, err := w.walk(s["values"], namespace, ev, fn)
+				if err != nil {
+					return nil, err
+				}
+				vMap[k] = converted
+			}
+			return vMap, nil
+		}
+		if logical, ok := s["logicalType"].(string); ok {
+			return fn(logical, s, v)
+		}
+		if _, isStr := s["type"].(string); !isStr {
+			return w.walk(s["type"], namespace, v, fn)
+		}
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
+const avroDateLayout = "2006-01-02"
+
+func avroDecimalScale(schema map[string]interface{}) int {
+	switch s := schema["scale"].(type) {
+	case float64:
+		return int(s)
+	case json.Number:
+		i, _ := s.Int64()
+		return int(i)
+	}
+	return 0
+}
+
+// avroLogicalToJSON converts the native values of logical types into JSON
+// values, where timestamps become RFC 3339 strings, dates become strings of
+// the form 2006-01-02, times become integers of their unit and decimals
+// become numbers.
+func avroLogicalToJSON(logicalType string, schema map[string]interface{}, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case time.Time:
+		if logicalType == "date" {
+			return t.UTC().Format(avroDateLayout), nil
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	case time.Duration:
+		if logicalType == "time-millis" {
+			return json.Number(strconv.FormatInt(int64(t/time.Millisecond), 10)), nil
+		}
+		return json.Number(strconv.FormatInt(int64(t/time.Microsecond), 10)), nil
+	case *big.Rat:
+		return json.Number(t.FloatString(avroDecimalScale(schema))), nil
+	}
+	return v, nil
+}
+
+func avroJSONInt(v interface{}) (int64, bool, error) {
+	switch t := v.(type) {
+	case json.Number:
+		i, err := t.Int64()
+		return i, true, err
+	case float64:
+		return int64(t), true, nil
+	}
+	return 0, false, nil
+}
+
+// avroLogicalFromJSON converts the JSON values of logical types into the
+// native values expected by goavro, accepting the forms produced by
+// avroLogicalToJSON as well as the underlying values of the types.
+func avroLogicalFromJSON(logicalType string, schema map[string]interface{}, v interface{}) (interface{}, error) {
+	switch logicalType {
+	case "timestamp-millis", "timestamp-micros":
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %v: %w", logicalType, err)
+			}
+			return t, nil
+		}
+		if i, ok, err := avroJSONInt(v); ok {
+			return i, err
+		}
+	case "date":
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(avroDateLayout, s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse date: %w", err)
+			}
+			return t, nil
+		}
+		if i, ok, err := avroJSONInt(v); ok {
+			return int32(i), err
+		}
+	case "time-millis":
+		if i, ok, err := avroJSONInt(v); ok {
+			return int32(i), err
+		}
+	case "time-micros":
+		if i, ok, err := avroJSONInt(v); ok {
+			return i, err
+		}
+	case "decimal":
+		var str string
+		switch t := v.(type) {
+		case json.Number:
+			str = t.String()
+		case string:
+			str = t
+		case float64:
+			str = strconv.FormatFloat(t, 'f', -1, 64)
+		default:
+			return v, nil
+		}
+		r, ok := new(big.Rat).SetString(str)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse decimal: %v", str)
+		}
+		return r, nil
+	}
+	return v, nil
+}
@@ -3,6 +3,7 @@ package processor
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"testing"
@@ -11,6 +12,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAvroBasic(t *testing.T) {
@@ -330,3 +333,74 @@ func TestAvroSchemaPathNotExist(t *testing.T) {
 		t.Error("expected error from loading non existant schema file")
 	}
 }
+
+func TestAvroLogicalTypes(t *testing.T) {
+	schema := `{
+	"type": "record",
+	"name": "purchase",
+	"fields": [
+		{ "name": "At", "type": { "type": "long", "logicalType": "timestamp-millis" } },
+		{ "name": "Day", "type": { "type": "int", "logicalType": "date" } },
+		{ "name": "Price", "type": { "type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2 } },
+		{ "name": "Refunded", "type": ["null", { "type": "long", "logicalType": "timestamp-micros" }], "default": null }
+	]
+}`
+
+	schemaPath := filepath.Join(t.TempDir(), "purchase.avsc")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(schema), 0o644))
+
+	input := `{"At":"2021-03-04T05:06:07.123Z","Day":"2021-03-04","Price":12.3,"Refunded":{"long.timestamp-micros":"2021-03-05T05:06:07.123456Z"}}`
+	exp := `{"At":"2021-03-04T05:06:07.123Z","Day":"2021-03-04","Price":12.30,"Refunded":{"long.timestamp-micros":"2021-03-05T05:06:07.123456Z"}}`
+
+	for _, encoding := range []string{"binary", "single"} {
+		encoding := encoding
+		t.Run(encoding, func(t *testing.T) {
+			newProc := func(operator string) Type {
+				conf := NewConfig()
+				conf.Type = TypeAvro
+				conf.Avro.Operator = operator
+				conf.Avro.Encoding = encoding
+				conf.Avro.SchemaPath = schemaPath
+
+				proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+				require.NoError(t, err)
+				return proc
+			}
+
+			msgs, res := newProc("from_json").ProcessMessage(message.New([][]byte{[]byte(input)}))
+			require.Nil(t, res)
+			require.Len(t, msgs, 1)
+			assert.Empty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+
+			msgs, res = newProc("to_json").ProcessMessage(msgs[0])
+			require.Nil(t, res)
+			require.Len(t, msgs, 1)
+			assert.Empty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+			assert.Equal(t, exp, string(msgs[0].Get(0).Get()))
+		})
+	}
+}
+
+func TestAvroLogicalTypesBadInput(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeAvro
+	conf.Avro.Operator = "from_json"
+	conf.Avro.Encoding = "binary"
+	conf.Avro.Schema = `{
+	"type": "record",
+	"name": "purchase",
+	"fields": [
+		{ "name": "At", "type": { "type": "long", "logicalType": "timestamp-millis" } }
+	]
+}`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := `{"At":"not a timestamp"}`
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.NotEmpty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, input, string(msgs[0].Get(0).Get()))
+}
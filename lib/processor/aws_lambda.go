@@ -37,6 +37,11 @@ In order to map or encode the payload to a specific request body, and map the
 response back into the original payload instead of replacing it entirely, you
 can use the ` + "[`branch` processor](/docs/components/processors/branch)" + `.
 
+Setting the ` + "`invocation_type`" + ` to ` + "`Event`" + ` queues each message for
+asynchronous processing by the function, in which case the contents of messages
+are left unchanged. In order to invoke a function with batches of messages use
+the ` + "[`aws_lambda` output](/docs/components/outputs/aws_lambda)" + ` instead.
+
 ### Error Handling
 
 When Benthos is unable to connect to the AWS endpoint or is otherwise unable to invoke the target lambda function it will retry the request according to the configured number of retries. Once these attempts have been exhausted the failed message will continue through the pipeline with it's contents unchanged, but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
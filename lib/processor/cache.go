@@ -2,8 +2,10 @@ package processor
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
@@ -30,13 +32,16 @@ This processor will interpolate functions within the ` + "`key` and `value`" + `
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("resource", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
 			docs.FieldDeprecated("cache"),
-			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete"),
+			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "get_and_delete", "delete", "cas"),
 			docs.FieldCommon("key", "A key to use with the cache.").IsInterpolated(),
+			docs.FieldAdvanced("keys", "A list of keys to use with the cache instead of a single `key`, where the operation is performed for each key. The results of `get` and `get_and_delete` operations are combined into a JSON array. This field cannot be used alongside `key`.", []string{`${! json("a_id") }`, `${! json("b_id") }`}).Array().IsInterpolated().AtVersion("3.64.0"),
 			docs.FieldCommon("value", "A value to use with the cache (when applicable).").IsInterpolated(),
+			docs.FieldAdvanced("old_value", "The expected current value of the key, used by the `cas` operator. When left empty the operation only succeeds if the key does not yet exist.").IsInterpolated().AtVersion("3.64.0"),
 			docs.FieldAdvanced(
 				"ttl", "The TTL of each individual item as a duration string. After this period an item will be eligible for removal during the next compaction. Not all caches support per-key TTLs, and those that do not will fall back to their generally configured TTL setting.",
 				"60s", "5m", "36h",
 			).IsInterpolated().AtVersion("3.33.0"),
+			docs.FieldAdvanced("ttl_metadata_key", "An optional metadata key to store the remaining TTL of items retrieved with the `get` operator as a duration string. The metadata is only set for caches that are able to report remaining TTLs (such as `memory` and `redis`) and for items that have an expiry.").AtVersion("3.64.0"),
 			PartsFieldSpec,
 		},
 		Examples: []docs.AnnotatedExample{
@@ -110,10 +115,64 @@ Retrieve the contents of a cached key and replace the original message payload
 with the result. If the key does not exist the action fails with an error, which
 can be detected with [processor error handling](/docs/configuration/error_handling).
 
+If the field ` + "`ttl_metadata_key`" + ` is set then the remaining TTL of the
+item is stored as a duration string within that metadata key, which can be
+referenced within subsequent mappings with
+` + "`meta(\"<key>\").parse_duration()`" + `.
+
+When processing batches of messages with caches that support it (such as
+` + "`redis` and `memcached`" + `) the keys of all messages within the batch are
+retrieved with a single request, and the results are mapped back to each
+message. If a batched request fails then keys are retrieved individually from
+then on.
+
+### ` + "`get_and_delete`" + `
+
+Retrieve the contents of a cached key, replacing the original message payload
+with the result, and then remove the key from the cache. If the key does not
+exist the action fails with an error. This is useful for implementing
+claim-check patterns where each stored item should only be consumed once. The
+operation is atomic for caches that support it (such as ` + "`memory` and `redis`" + `),
+for all other caches it is performed as a get followed by a delete.
+
 ### ` + "`delete`" + `
 
 Delete a key and its contents from the cache.  If the key does not exist the
-action is a no-op and will not fail with an error.`,
+action is a no-op and will not fail with an error.
+
+### ` + "`cas`" + `
+
+Atomically set a key in the cache to a value only if its current value matches
+the value of the ` + "`old_value`" + ` field, or if ` + "`old_value`" + ` is
+empty only if the key does not yet exist. If the current value does not match
+the action fails with an error, which can be detected with
+[processor error handling](/docs/configuration/error_handling). This operator
+is only supported by caches capable of atomic compare and swap operations, such
+as ` + "`memory`, `redis` and `memcached`" + `.
+
+## Multiple Keys
+
+When the field ` + "`keys`" + ` is set the operation is performed for each key
+in the list, and the processing of a message fails at the first key that results
+in an error. The ` + "`get` and `get_and_delete`" + ` operators replace the
+message payload with a JSON array containing the value of each key in the same
+order as the list. Values that are valid JSON documents are inserted as
+structured values, otherwise they are inserted as strings, and keys that do not
+exist result in a ` + "`null`" + ` element rather than an error:
+
+` + "```yaml" + `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: foocache
+              operator: get
+              keys:
+                - '${! json("user_id") }'
+                - '${! json("account_id") }'
+        result_map: 'root.lookups = this'
+` + "```",
 	}
 }
 
@@ -121,13 +180,16 @@ action is a no-op and will not fail with an error.`,
 
 // CacheConfig contains configuration fields for the Cache processor.
 type CacheConfig struct {
-	Cache    string `json:"cache" yaml:"cache"`
-	Resource string `json:"resource" yaml:"resource"`
-	Parts    []int  `json:"parts" yaml:"parts"`
-	Operator string `json:"operator" yaml:"operator"`
-	Key      string `json:"key" yaml:"key"`
-	Value    string `json:"value" yaml:"value"`
-	TTL      string `json:"ttl" yaml:"ttl"`
+	Cache    string   `json:"cache" yaml:"cache"`
+	Resource string   `json:"resource" yaml:"resource"`
+	Parts    []int    `json:"parts" yaml:"parts"`
+	Operator string   `json:"operator" yaml:"operator"`
+	Key      string   `json:"key" yaml:"key"`
+	Keys     []string `json:"keys" yaml:"keys"`
+	Value    string   `json:"value" yaml:"value"`
+	OldValue string   `json:"old_value" yaml:"old_value"`
+	TTL      string   `json:"ttl" yaml:"ttl"`
+	TTLMeta  string   `json:"ttl_metadata_key" yaml:"ttl_metadata_key"`
 }
 
 // NewCacheConfig returns a CacheConfig with default values.
@@ -138,8 +200,11 @@ func NewCacheConfig() CacheConfig {
 		Parts:    []int{},
 		Operator: "set",
 		Key:      "",
+		Keys:     []string{},
 		Value:    "",
+		OldValue: "",
 		TTL:      "",
+		TTLMeta:  "",
 	}
 }
 
@@ -154,13 +219,22 @@ type Cache struct {
 
 	parts []int
 
-	key   *field.Expression
-	value *field.Expression
-	ttl   *field.Expression
+	key      *field.Expression
+	keys     []*field.Expression
+	value    *field.Expression
+	oldValue *field.Expression
+	ttl      *field.Expression
 
 	mgr       types.Manager
 	cacheName string
 	operator  cacheOperator
+	isGet     bool
+	batchGet  bool
+	ttlMeta   string
+
+	// Set to 1 once a batched get has failed, after which keys are always
+	// obtained individually.
+	batchGetFailed int32
 
 	mCount            metrics.StatCounter
 	mErr              metrics.StatCounter
@@ -186,16 +260,36 @@ func NewCache(
 		return nil, err
 	}
 
+	if conf.Cache.Key != "" && len(conf.Cache.Keys) > 0 {
+		return nil, errors.New("fields key and keys cannot both be set")
+	}
+
 	key, err := interop.NewBloblangField(mgr, conf.Cache.Key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
+	keys := make([]*field.Expression, 0, len(conf.Cache.Keys))
+	for i, k := range conf.Cache.Keys {
+		e, err := interop.NewBloblangField(mgr, k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse keys expression %v: %v", i, err)
+		}
+		keys = append(keys, e)
+	}
+
 	value, err := interop.NewBloblangField(mgr, conf.Cache.Value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
 	}
 
+	var oldValue *field.Expression
+	if conf.Cache.OldValue != "" {
+		if oldValue, err = interop.NewBloblangField(mgr, conf.Cache.OldValue); err != nil {
+			return nil, fmt.Errorf("failed to parse old_value expression: %v", err)
+		}
+	}
+
 	ttl, err := interop.NewBloblangField(mgr, conf.Cache.TTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse ttl expression: %v", err)
@@ -212,13 +306,18 @@ func NewCache(
 
 		parts: conf.Cache.Parts,
 
-		key:   key,
-		value: value,
-		ttl:   ttl,
+		key:      key,
+		keys:     keys,
+		value:    value,
+		oldValue: oldValue,
+		ttl:      ttl,
 
 		mgr:       mgr,
 		cacheName: cacheName,
 		operator:  op,
+		isGet:     conf.Cache.Operator == "get",
+		batchGet:  conf.Cache.Operator == "get" && conf.Cache.TTLMeta == "" && len(keys) == 0,
+		ttlMeta:   conf.Cache.TTLMeta,
 
 		mCount:            stats.GetCounter("count"),
 		mErr:              stats.GetCounter("error"),
@@ -230,43 +329,74 @@ func NewCache(
 
 //------------------------------------------------------------------------------
 
-type cacheOperator func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error)
+// cacheOperator performs an operation against a cache and returns an optional
+// result along with its remaining TTL (when known) and whether the result
+// should replace the message contents.
+type cacheOperator func(cache types.Cache, key string, value, oldValue []byte, ttl *time.Duration) ([]byte, *time.Duration, bool, error)
 
 func newCacheSetOperator() cacheOperator {
-	return func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, value, _ []byte, ttl *time.Duration) ([]byte, *time.Duration, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.SetWithTTL(key, value, ttl)
 		} else {
 			err = cache.Set(key, value)
 		}
-		return nil, false, err
+		return nil, nil, false, err
 	}
 }
 
 func newCacheAddOperator() cacheOperator {
-	return func(cache types.Cache, key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, value, _ []byte, ttl *time.Duration) ([]byte, *time.Duration, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.AddWithTTL(key, value, ttl)
 		} else {
 			err = cache.Add(key, value)
 		}
-		return nil, false, err
+		return nil, nil, false, err
 	}
 }
 
 func newCacheGetOperator() cacheOperator {
-	return func(cache types.Cache, key string, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, _, _ []byte, _ *time.Duration) ([]byte, *time.Duration, bool, error) {
+		if cgt, ok := cache.(types.CacheWithGetTTL); ok {
+			result, remaining, err := cgt.GetWithTTL(key)
+			return result, remaining, true, err
+		}
 		result, err := cache.Get(key)
-		return result, true, err
+		return result, nil, true, err
+	}
+}
+
+func newCacheGetAndDeleteOperator() cacheOperator {
+	return func(cache types.Cache, key string, _, _ []byte, _ *time.Duration) ([]byte, *time.Duration, bool, error) {
+		if cgd, ok := cache.(types.CacheWithGetAndDelete); ok {
+			result, err := cgd.GetAndDelete(key)
+			return result, nil, true, err
+		}
+		result, err := cache.Get(key)
+		if err != nil {
+			return nil, nil, true, err
+		}
+		return result, nil, true, cache.Delete(key)
 	}
 }
 
 func newCacheDeleteOperator() cacheOperator {
-	return func(cache types.Cache, key string, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(cache types.Cache, key string, _, _ []byte, ttl *time.Duration) ([]byte, *time.Duration, bool, error) {
 		err := cache.Delete(key)
-		return nil, false, err
+		return nil, nil, false, err
+	}
+}
+
+func newCacheCASOperator() cacheOperator {
+	return func(cache types.Cache, key string, value, oldValue []byte, ttl *time.Duration) ([]byte, *time.Duration, bool, error) {
+		cas, ok := cache.(types.CacheWithCompareAndSwap)
+		if !ok {
+			return nil, nil, false, types.ErrNotSupported
+		}
+		return nil, nil, false, cas.CompareAndSwap(key, oldValue, value, ttl)
 	}
 }
 
@@ -278,24 +408,143 @@ func cacheOperatorFromString(operator string) (cacheOperator, error) {
 		return newCacheAddOperator(), nil
 	case "get":
 		return newCacheGetOperator(), nil
+	case "get_and_delete":
+		return newCacheGetAndDeleteOperator(), nil
 	case "delete":
 		return newCacheDeleteOperator(), nil
+	case "cas":
+		return newCacheCASOperator(), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
 //------------------------------------------------------------------------------
 
+// getMulti attempts to obtain the values of all keys of a batch within a single
+// request. Returns nil if the cache does not support batched gets or if the
+// request fails, in which case keys should be obtained individually. Batched
+// gets are not attempted again after a failure, as each subsequent batch
+// would otherwise pay for both the failed request and the individual ones.
+func (c *Cache) getMulti(msg types.Message) map[string][]byte {
+	var keys []string
+	if len(c.parts) == 0 {
+		keys = make([]string, 0, msg.Len())
+		for i := 0; i < msg.Len(); i++ {
+			keys = append(keys, c.key.String(i, msg))
+		}
+	} else {
+		keys = make([]string, 0, len(c.parts))
+		for _, i := range c.parts {
+			keys = append(keys, c.key.String(i, msg))
+		}
+	}
+
+	var values map[string][]byte
+	var err error
+	if cerr := interop.AccessCache(context.Background(), c.mgr, c.cacheName, func(cache types.Cache) {
+		if cgm, ok := cache.(types.CacheWithGetMulti); ok {
+			values, err = cgm.GetMulti(keys...)
+		} else {
+			err = types.ErrNotSupported
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&c.batchGetFailed, 0, 1) {
+			if errors.Is(err, types.ErrNotSupported) {
+				c.log.Debugln("Cache does not support batched gets, keys will be obtained individually")
+			} else {
+				c.log.Warnf("Batched get failed, keys will be obtained individually from now on: %v\n", err)
+			}
+		}
+		return nil
+	}
+	return values
+}
+
+// multiKeyResult combines the values of multiple keys into a JSON array, where
+// values that are not valid JSON are inserted as strings and missing keys are
+// null.
+func multiKeyResult(values [][]byte) ([]byte, error) {
+	arr := make([]interface{}, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if json.Valid(v) {
+			arr[i] = json.RawMessage(v)
+		} else {
+			arr[i] = string(v)
+		}
+	}
+	return json.Marshal(arr)
+}
+
+// operateMulti performs the operator against each key of a list, returning the
+// combined results when the operator produces them.
+func (c *Cache) operateMulti(cache types.Cache, keys []string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error) {
+	if cgm, ok := cache.(types.CacheWithGetMulti); ok && c.isGet {
+		values, err := cgm.GetMulti(keys...)
+		if err != nil {
+			return nil, false, err
+		}
+		results := make([][]byte, len(keys))
+		for i, k := range keys {
+			results[i] = values[k]
+		}
+		result, err := multiKeyResult(results)
+		return result, true, err
+	}
+
+	var results [][]byte
+	var useResult bool
+	for _, k := range keys {
+		result, _, use, err := c.operator(cache, k, value, oldValue, ttl)
+		if err != nil && !(use && errors.Is(err, types.ErrKeyNotFound)) {
+			return nil, false, err
+		}
+		useResult = use
+		results = append(results, result)
+	}
+	if !useResult {
+		return nil, false, nil
+	}
+	result, err := multiKeyResult(results)
+	return result, true, err
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	c.mCount.Incr(1)
 	newMsg := msg.Copy()
 
+	var prefetched map[string][]byte
+	if c.batchGet && msg.Len() > 1 && atomic.LoadInt32(&c.batchGetFailed) == 0 {
+		prefetched = c.getMulti(msg)
+	}
+
 	proc := func(index int, span *tracing.Span, part types.Part) error {
 		key := c.key.String(index, msg)
+		if prefetched != nil {
+			result, exists := prefetched[key]
+			if !exists {
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, types.ErrKeyNotFound)
+				return types.ErrKeyNotFound
+			}
+			part.Set(result)
+			return nil
+		}
+
 		value := c.value.Bytes(index, msg)
 
+		var oldValue []byte
+		if c.oldValue != nil {
+			oldValue = c.oldValue.Bytes(index, msg)
+		}
+
 		var ttl *time.Duration
 		if ttls := c.ttl.String(index, msg); ttls != "" {
 			td, err := time.ParseDuration(ttls)
@@ -307,11 +556,25 @@ func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 			ttl = &td
 		}
 
+		var keys []string
+		if len(c.keys) > 0 {
+			keys = make([]string, len(c.keys))
+			for i, k := range c.keys {
+				keys[i] = k.String(index, msg)
+			}
+			key = fmt.Sprintf("%v", keys)
+		}
+
 		var result []byte
+		var remaining *time.Duration
 		var useResult bool
 		var err error
 		if cerr := interop.AccessCache(context.Background(), c.mgr, c.cacheName, func(cache types.Cache) {
-			result, useResult, err = c.operator(cache, key, value, ttl)
+			if len(keys) > 0 {
+				result, useResult, err = c.operateMulti(cache, keys, value, oldValue, ttl)
+			} else {
+				result, remaining, useResult, err = c.operator(cache, key, value, oldValue, ttl)
+			}
 		}); cerr != nil {
 			err = cerr
 		}
@@ -329,6 +592,9 @@ func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 		if useResult {
 			part.Set(result)
 		}
+		if c.ttlMeta != "" && remaining != nil {
+			part.Metadata().Set(c.ttlMeta, remaining.String())
+		}
 		return nil
 	}
 
@@ -1,8 +1,10 @@
 package processor
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -310,6 +312,437 @@ func TestCacheGet(t *testing.T) {
 	}
 }
 
+type getMultiCounterCache struct {
+	types.Cache
+	getMultiCalls int
+	getMultiErr   error
+}
+
+func (c *getMultiCounterCache) GetMulti(keys ...string) (map[string][]byte, error) {
+	c.getMultiCalls++
+	if c.getMultiErr != nil {
+		return nil, c.getMultiErr
+	}
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if v, err := c.Cache.Get(k); err == nil {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func TestCacheGetBatched(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counterCache := &getMultiCounterCache{Cache: memCache}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": counterCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+	memCache.Set("2", []byte("foo 2"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "get"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"1"}`),
+	})
+	expParts := [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`foo 2`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`foo 1`),
+	}
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	if exp, act := expParts, message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+
+	if exp, act := 1, counterCache.getMultiCalls; exp != act {
+		t.Errorf("Wrong count of GetMulti calls: %v != %v", act, exp)
+	}
+
+	for i, exp := range []bool{false, false, true, false} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag at index %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheGetBatchedFailed(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counterCache := &getMultiCounterCache{
+		Cache:       memCache,
+		getMultiErr: errors.New("CROSSSLOT Keys in request don't hash to the same slot"),
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": counterCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+	memCache.Set("2", []byte("foo 2"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "get"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expParts := [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`foo 2`),
+		[]byte(`{"key":"3"}`),
+	}
+
+	// Keys are obtained individually once a batched get has failed, and
+	// subsequent batches no longer attempt batched gets.
+	for i := 0; i < 2; i++ {
+		output, res := proc.ProcessMessage(message.New([][]byte{
+			[]byte(`{"key":"1"}`),
+			[]byte(`{"key":"2"}`),
+			[]byte(`{"key":"3"}`),
+		}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+
+		if len(output) != 1 {
+			t.Fatalf("Wrong count of result messages: %v", len(output))
+		}
+
+		if exp, act := expParts, message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result messages: %s != %s", act, exp)
+		}
+
+		for j, exp := range []bool{false, false, true} {
+			if act := HasFailed(output[0].Get(j)); exp != act {
+				t.Errorf("Wrong fail flag at index %v: %v != %v", j, act, exp)
+			}
+		}
+	}
+
+	if exp, act := 1, counterCache.getMultiCalls; exp != act {
+		t.Errorf("Wrong count of GetMulti calls: %v != %v", act, exp)
+	}
+}
+
+func TestCacheGetTTLMetadata(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "get"
+	conf.Cache.TTLMeta = "remaining_ttl"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if exp, act := "foo 1", string(output[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	remaining, err := time.ParseDuration(output[0].Get(0).Metadata().Get("remaining_ttl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining <= 0 || remaining > time.Minute*5 {
+		t.Errorf("Unexpected remaining TTL: %v", remaining)
+	}
+
+	if !HasFailed(output[0].Get(1)) {
+		t.Error("Expected missing key to fail")
+	}
+	if act := output[0].Get(1).Metadata().Get("remaining_ttl"); act != "" {
+		t.Errorf("Unexpected metadata: %v", act)
+	}
+}
+
+func TestCacheCAS(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+	memCache.Set("2", []byte("foo 2"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.OldValue = "${!json(\"old\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "cas"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","old":"foo 1","value":"bar 1"}`),
+		[]byte(`{"key":"2","old":"nope","value":"bar 2"}`),
+		[]byte(`{"key":"3","old":"foo 3","value":"bar 3"}`),
+	})
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	for i, exp := range []bool{false, true, true} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag at index %v: %v != %v", i, act, exp)
+		}
+	}
+
+	for k, exp := range map[string]string{
+		"1": "bar 1",
+		"2": "foo 2",
+	} {
+		actBytes, err := memCache.Get(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(actBytes); exp != act {
+			t.Errorf("Wrong result for key %v: %v != %v", k, act, exp)
+		}
+	}
+	if _, err = memCache.Get("3"); err != types.ErrKeyNotFound {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCacheCASNotExist(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "cas"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":"bar 1"}`),
+		[]byte(`{"key":"2","value":"bar 2"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	for i, exp := range []bool{true, false} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag at index %v: %v != %v", i, act, exp)
+		}
+	}
+
+	actBytes, err := memCache.Get("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "bar 2", string(actBytes); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+	memCache.Set("2", []byte("foo 2"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "get_and_delete"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"1"}`),
+	})
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	if act, exp := string(output[0].Get(0).Get()), "foo 1"; act != exp {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	for i, exp := range []bool{false, true, true} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag at index %v: %v != %v", i, act, exp)
+		}
+	}
+
+	if _, err = memCache.Get("1"); err != types.ErrKeyNotFound {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err = memCache.Get("2"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCacheMultiKeys(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Keys = []string{"${!json(\"a\")}", "${!json(\"b\")}"}
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "set"
+	setProc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, res := setProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"a":"1","b":"2","value":"{\"foo\":\"bar\"}"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	memCache.Set("3", []byte("not json"))
+
+	for _, k := range []string{"1", "2"} {
+		actBytes, err := memCache.Get(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act, exp := string(actBytes), `{"foo":"bar"}`; act != exp {
+			t.Errorf("Wrong result for key %v: %v != %v", k, act, exp)
+		}
+	}
+
+	conf.Cache.Operator = "get"
+	getProc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := getProc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"a":"1","b":"3"}`),
+		[]byte(`{"a":"4","b":"2"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	for i, exp := range []string{
+		`[{"foo":"bar"},"not json"]`,
+		`[null,{"foo":"bar"}]`,
+	} {
+		if HasFailed(output[0].Get(i)) {
+			t.Errorf("Unexpected failure at index %v", i)
+		}
+		if act := string(output[0].Get(i).Get()); act != exp {
+			t.Errorf("Wrong result at index %v: %v != %v", i, act, exp)
+		}
+	}
+
+	conf.Cache.Key = "${!json(\"a\")}"
+	if _, err = NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from setting both key and keys")
+	}
+}
+
 func TestCacheDelete(t *testing.T) {
 	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -15,6 +15,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -28,11 +29,11 @@ func init() {
 		},
 		Summary: `
 Compresses messages according to the selected algorithm. Supported compression
-algorithms are: gzip, zlib, flate, snappy, lz4.`,
+algorithms are: gzip, zlib, flate, snappy, lz4, zstd.`,
 		Description: `
 The 'level' field might not apply to all algorithms.`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("algorithm", "The compression algorithm to use.").HasOptions("gzip", "zlib", "flate", "snappy", "lz4"),
+			docs.FieldCommon("algorithm", "The compression algorithm to use.").HasOptions("gzip", "zlib", "flate", "snappy", "lz4", "zstd"),
 			docs.FieldCommon("level", "The level of compression to use. May not be applicable to all algorithms."),
 			PartsFieldSpec,
 		},
@@ -133,6 +134,21 @@ func lz4Compress(level int, b []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func zstdCompress(level int, b []byte) ([]byte, error) {
+	var opts []zstd.EOption
+	if level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+
+	w, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	return w.EncodeAll(b, nil), nil
+}
+
 func strToCompressor(str string) (compressFunc, error) {
 	switch str {
 	case "gzip":
@@ -145,6 +161,8 @@ func strToCompressor(str string) (compressFunc, error) {
 		return snappyCompress, nil
 	case "lz4":
 		return lz4Compress, nil
+	case "zstd":
+		return zstdCompress, nil
 	}
 	return nil, fmt.Errorf("compression type not recognised: %v", str)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -258,6 +259,53 @@ func TestCompressLZ4(t *testing.T) {
 	}
 }
 
+func TestCompressZSTD(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "zstd"
+	conf.Compress.Level = 19
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+		[]byte("fourth"),
+		[]byte("5"),
+	}
+
+	proc, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if len(msgs) != 1 {
+		t.Fatal("Compress failed")
+	} else if res != nil {
+		t.Errorf("Expected nil response: %v", res)
+	}
+
+	r, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	act := [][]byte{}
+	for _, b := range message.GetAllBytes(msgs[0]) {
+		if bytes.Equal(b, input[len(act)]) {
+			t.Fatal("Input and output are the same")
+		}
+		decompressed, err := r.DecodeAll(b, nil)
+		if err != nil {
+			t.Fatalf("Failed to decompress output: %s", err)
+		}
+		act = append(act, decompressed)
+	}
+	if !reflect.DeepEqual(input, act) {
+		t.Errorf("Unexpected output: %s != %s", act, input)
+	}
+}
+
 func TestCompressIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
@@ -17,6 +17,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -30,9 +31,9 @@ func init() {
 		},
 		Summary: `
 Decompresses messages according to the selected algorithm. Supported
-decompression types are: gzip, zlib, bzip2, flate, snappy, lz4.`,
+decompression types are: gzip, zlib, bzip2, flate, snappy, lz4, zstd.`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy", "lz4"),
+			docs.FieldCommon("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy", "lz4", "zstd"),
 			PartsFieldSpec,
 		},
 	}
@@ -126,6 +127,16 @@ func lz4Decompress(b []byte) ([]byte, error) {
 	return outBuf.Bytes(), nil
 }
 
+func zstdDecompress(b []byte) ([]byte, error) {
+	r, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.DecodeAll(b, nil)
+}
+
 func strToDecompressor(str string) (decompressFunc, error) {
 	switch str {
 	case "gzip":
@@ -140,6 +151,8 @@ func strToDecompressor(str string) (decompressFunc, error) {
 		return snappyDecompress, nil
 	case "lz4":
 		return lz4Decompress, nil
+	case "zstd":
+		return zstdDecompress, nil
 	}
 	return nil, fmt.Errorf("decompression type not recognised: %v", str)
 }
@@ -71,12 +71,48 @@ sink followed by a restart of the Benthos instance.
 If you intend to preserve at-least-once delivery guarantees you can avoid this
 problem by using a memory based cache. This is a compromise that can achieve
 effective deduplication but parallel deployments of the pipeline as well as
-service restarts increase the chances of duplicates passing undetected.`,
+service restarts increase the chances of duplicates passing undetected.
+
+## Time Windows
+
+By default a message signature remains within the cache until it is evicted
+according to the cache configuration. When a ` + "`window`" + ` is specified
+signatures are written with that duration as their TTL, and therefore messages
+are only considered duplicates when they arrive within the window of the first
+message seen. Not all caches support per-key TTLs, and those that do not will
+fall back to their generally configured TTL setting.
+
+## Payload Comparison
+
+When ` + "`store_payload`" + ` is set to ` + "`true`" + ` the contents of the
+first message seen are stored as the value of its signature, and subsequent
+messages with the same signature are only considered duplicates when their
+contents match exactly. This guards against hash collisions, and when a ` + "`key`" + `
+is specified allows messages with a matching key but modified contents to pass.
+
+## Marking Duplicates
+
+With the ` + "`mode`" + ` set to ` + "`passthrough_marked`" + ` duplicates are
+not dropped, instead each message of a duplicate batch is given the metadata
+field ` + "`dedupe_duplicate`" + ` with the value ` + "`true`" + `, which allows
+downstream processors and outputs to decide how to handle them.
+
+## Metrics
+
+The number of duplicate batches dropped is tracked by the counter
+` + "`duplicate.dropped`" + `, and the number of duplicate batches marked with
+the ` + "`passthrough_marked`" + ` mode is tracked by ` + "`duplicate.marked`" + `.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
 			docs.FieldCommon("hash", "The hash type to used.").HasOptions("none", "xxhash"),
 			docs.FieldCommon("key", "An optional key to use for deduplication (instead of the entire message contents).").IsInterpolated(),
 			docs.FieldCommon("drop_on_err", "Whether messages should be dropped when the cache returns an error."),
+			docs.FieldCommon("window", "An optional duration within which messages are considered duplicates, implemented by writing signatures to the cache with this TTL.", "5m", "1h").AtVersion("3.64.0"),
+			docs.FieldAdvanced("store_payload", "Whether the contents of the first message seen should be stored against its signature, where subsequent messages are only considered duplicates when their contents match.").AtVersion("3.64.0"),
+			docs.FieldCommon("mode", "Determines what happens to duplicate messages.").HasAnnotatedOptions(
+				"drop", "Duplicate messages are dropped.",
+				"passthrough_marked", "Duplicate messages continue through the pipeline with the metadata field `dedupe_duplicate` set to `true`.",
+			).AtVersion("3.64.0"),
 			docs.FieldAdvanced("parts", "An array of message indexes within the batch to deduplicate based on. If left empty all messages are included. This field is only applicable when batching messages [at the input level](/docs/configuration/batching).").Array(),
 		},
 	}
@@ -91,6 +127,9 @@ type DedupeConfig struct {
 	Parts          []int  `json:"parts" yaml:"parts"` // message parts to hash
 	Key            string `json:"key" yaml:"key"`
 	DropOnCacheErr bool   `json:"drop_on_err" yaml:"drop_on_err"`
+	Window         string `json:"window" yaml:"window"`
+	StorePayload   bool   `json:"store_payload" yaml:"store_payload"`
+	Mode           string `json:"mode" yaml:"mode"`
 }
 
 // NewDedupeConfig returns a DedupeConfig with default values.
@@ -101,6 +140,9 @@ func NewDedupeConfig() DedupeConfig {
 		Parts:          []int{0}, // only consider the 1st part
 		Key:            "",
 		DropOnCacheErr: true,
+		Window:         "",
+		StorePayload:   false,
+		Mode:           "drop",
 	}
 }
 
@@ -159,6 +201,8 @@ type Dedupe struct {
 	mgr        types.Manager
 	cacheName  string
 	hasherFunc hasherFunc
+	window     *time.Duration
+	markOnly   bool
 
 	mCount     metrics.StatCounter
 	mErrHash   metrics.StatCounter
@@ -167,6 +211,9 @@ type Dedupe struct {
 	mDropped   metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
+
+	mDupDropped metrics.StatCounter
+	mDupMarked  metrics.StatCounter
 }
 
 // NewDedupe returns a Dedupe processor.
@@ -183,6 +230,24 @@ func NewDedupe(
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
+	var window *time.Duration
+	if conf.Dedupe.Window != "" {
+		w, err := time.ParseDuration(conf.Dedupe.Window)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse window: %v", err)
+		}
+		window = &w
+	}
+
+	var markOnly bool
+	switch conf.Dedupe.Mode {
+	case "drop", "":
+	case "passthrough_marked":
+		markOnly = true
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", conf.Dedupe.Mode)
+	}
+
 	if err := interop.ProbeCache(context.Background(), mgr, conf.Dedupe.Cache); err != nil {
 		return nil, err
 	}
@@ -197,6 +262,8 @@ func NewDedupe(
 		mgr:        mgr,
 		cacheName:  conf.Dedupe.Cache,
 		hasherFunc: hFunc,
+		window:     window,
+		markOnly:   markOnly,
 
 		mCount:     stats.GetCounter("count"),
 		mErrHash:   stats.GetCounter("error.hash"),
@@ -205,11 +272,61 @@ func NewDedupe(
 		mDropped:   stats.GetCounter("dropped"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
+
+		mDupDropped: stats.GetCounter("duplicate.dropped"),
+		mDupMarked:  stats.GetCounter("duplicate.marked"),
 	}, nil
 }
 
 //------------------------------------------------------------------------------
 
+// payload returns the contents of the message parts selected for
+// deduplication, which is stored against a signature when enabled.
+func (d *Dedupe) payload(msg types.Message) []byte {
+	var buf bytes.Buffer
+	for _, index := range d.conf.Dedupe.Parts {
+		buf.Write(msg.Get(index).Get())
+	}
+	return buf.Bytes()
+}
+
+// add attempts to add a signature to the cache, and returns
+// types.ErrKeyAlreadyExists if the message is a duplicate.
+func (d *Dedupe) add(key string, msg types.Message) error {
+	value := []byte{'t'}
+	if d.conf.Dedupe.StorePayload {
+		value = d.payload(msg)
+	}
+
+	var err error
+	if cerr := interop.AccessCache(context.Background(), d.mgr, d.cacheName, func(cache types.Cache) {
+		if cttl, ok := cache.(types.CacheWithTTL); ok && d.window != nil {
+			err = cttl.AddWithTTL(key, value, d.window)
+		} else {
+			err = cache.Add(key, value)
+		}
+		if err != types.ErrKeyAlreadyExists || !d.conf.Dedupe.StorePayload {
+			return
+		}
+
+		// The signature exists, but the message is only a duplicate when the
+		// stored payload matches.
+		stored, gerr := cache.Get(key)
+		if gerr == types.ErrKeyNotFound {
+			err = nil
+		} else if gerr != nil {
+			err = gerr
+		} else if !bytes.Equal(stored, value) {
+			err = nil
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -251,20 +368,33 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 			return nil, response.NewAck()
 		}
 	} else {
-		var err error
-		if cerr := interop.AccessCache(context.Background(), d.mgr, d.cacheName, func(cache types.Cache) {
-			err = cache.Add(string(hasher.Bytes()), []byte{'t'})
-		}); cerr != nil {
-			err = cerr
-		}
-		if err != nil {
+		if err := d.add(string(hasher.Bytes()), msg); err != nil {
 			if err == types.ErrKeyAlreadyExists {
+				if d.markOnly {
+					for _, s := range spans {
+						s.LogKV(
+							"event", "marked",
+							"type", "deduplicated",
+						)
+					}
+					d.mDupMarked.Incr(1)
+					newMsg := msg.Copy()
+					newMsg.Iter(func(i int, p types.Part) error {
+						p.Metadata().Set("dedupe_duplicate", "true")
+						return nil
+					})
+					d.mBatchSent.Incr(1)
+					d.mSent.Incr(int64(newMsg.Len()))
+					msgs := [1]types.Message{newMsg}
+					return msgs[:], nil
+				}
 				for _, s := range spans {
 					s.LogKV(
 						"event", "dropped",
 						"type", "deduplicated",
 					)
 				}
+				d.mDupDropped.Incr(1)
 				d.mDropped.Incr(1)
 				return nil, response.NewAck()
 			}
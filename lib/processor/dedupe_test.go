@@ -462,3 +462,145 @@ func randStringRunes(n int) string {
 	}
 	return string(b)
 }
+
+func TestDedupeWindow(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Key = `${! json("id") }`
+	conf.Dedupe.Window = "30s"
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgOut, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`{"id":"foo"}`)})); msgOut == nil {
+		t.Fatal("First message told not to propagate")
+	}
+	if msgOut, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`{"id":"foo"}`)})); msgOut != nil {
+		t.Error("Duplicate message told to propagate")
+	}
+
+	_, remaining, err := memCache.(types.CacheWithGetTTL).GetWithTTL("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining == nil || *remaining <= 0 || *remaining > time.Second*30 {
+		t.Errorf("Unexpected remaining TTL: %v", remaining)
+	}
+
+	conf.Dedupe.Window = "nope"
+	if _, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad window")
+	}
+}
+
+func TestDedupeStorePayload(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Key = `${! json("id") }`
+	conf.Dedupe.StorePayload = true
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content   string
+		propagate bool
+	}{
+		{content: `{"id":"foo","value":1}`, propagate: true},
+		{content: `{"id":"foo","value":1}`, propagate: false},
+		{content: `{"id":"foo","value":2}`, propagate: true},
+		{content: `{"id":"bar","value":1}`, propagate: true},
+	}
+
+	for i, test := range tests {
+		msgOut, _ := proc.ProcessMessage(message.New([][]byte{[]byte(test.content)}))
+		if exp, act := test.propagate, msgOut != nil; exp != act {
+			t.Errorf("Wrong propagation for message %v: %v != %v", i, act, exp)
+		}
+	}
+
+	stored, err := memCache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"id":"foo","value":1}`, string(stored); exp != act {
+		t.Errorf("Wrong stored payload: %v != %v", act, exp)
+	}
+}
+
+func TestDedupePassthroughMarked(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	stats := metrics.NewLocal()
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Mode = "passthrough_marked"
+	proc, err := NewDedupe(conf, mgr, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgOut, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := "", msgOut[0].Get(0).Metadata().Get("dedupe_duplicate"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	msgIn := message.New([][]byte{[]byte("foo")})
+	msgOut, res = proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := "true", msgOut[0].Get(0).Metadata().Get("dedupe_duplicate"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if act := msgIn.Get(0).Metadata().Get("dedupe_duplicate"); act != "" {
+		t.Errorf("Input message was modified: %v", act)
+	}
+
+	counters := stats.GetCounters()
+	if exp, act := int64(1), counters["duplicate.marked"]; exp != act {
+		t.Errorf("Wrong marked count: %v != %v", act, exp)
+	}
+	if exp, act := int64(0), counters["duplicate.dropped"]; exp != act {
+		t.Errorf("Wrong dropped count: %v != %v", act, exp)
+	}
+
+	conf.Dedupe.Mode = "nope"
+	if _, err = NewDedupe(conf, mgr, log.Noop(), stats); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+}
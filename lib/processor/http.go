@@ -49,6 +49,12 @@ In order to map or encode the payload to a specific request body, and map the
 response back into the original payload instead of replacing it entirely, you
 can use the ` + "[`branch` processor](/docs/components/processors/branch)" + `.
 
+## Pagination
+
+The field ` + "`pagination.next_url`" + ` can be set to a [Bloblang mapping](/docs/guides/bloblang/about) that obtains the URL of the next page from each page of the response, which allows following cursors within response bodies, ` + "`Link`" + ` headers, or incrementing offsets. When set the pages are requested until the mapping yields no URL, and each page, or each item of the pages when ` + "`pagination.items`" + ` is set, becomes a message of the resulting batch.
+
+Each page has the metadata field ` + "`http_page`" + ` set to the index of the page starting from zero, and when the response has a ` + "`Link`" + ` header with a ` + "`next`" + ` relation the metadata field ` + "`http_link_next`" + ` is set to its URL. Pagination cannot be combined with ` + "`parallel`" + `.
+
 ## Response Codes
 
 Benthos considers any response code between 200 and 299 inclusive to indicate a
@@ -77,6 +83,7 @@ can be dropped or placed in a dead letter queue according to your config, you
 can read about these patterns [here](/docs/configuration/error_handling).`,
 		config: client.FieldSpec(
 			docs.FieldCommon("parallel", "When processing batched messages, whether to send messages of the batch in parallel, otherwise they are sent within a single request."),
+			client.PaginationFieldSpec(),
 			docs.FieldDeprecated("max_parallel"),
 			docs.FieldDeprecated("request").OmitWhen(func(v, _ interface{}) (string, bool) {
 				defaultBytes, err := yaml.Marshal(client.NewConfig())
@@ -115,9 +122,10 @@ pipeline:
 
 // HTTPConfig contains configuration fields for the HTTP processor.
 type HTTPConfig struct {
-	Parallel      bool          `json:"parallel" yaml:"parallel"`
-	MaxParallel   int           `json:"max_parallel" yaml:"max_parallel"`
-	Client        client.Config `json:"request" yaml:"request"`
+	Parallel      bool                    `json:"parallel" yaml:"parallel"`
+	MaxParallel   int                     `json:"max_parallel" yaml:"max_parallel"`
+	Pagination    client.PaginationConfig `json:"pagination" yaml:"pagination"`
+	Client        client.Config           `json:"request" yaml:"request"`
 	client.Config `json:",inline" yaml:",inline"`
 }
 
@@ -127,6 +135,7 @@ func NewHTTPConfig() HTTPConfig {
 		Client:      client.NewConfig(),
 		Parallel:    false,
 		MaxParallel: 0,
+		Pagination:  client.NewPaginationConfig(),
 		Config:      client.NewConfig(),
 	}
 }
@@ -136,7 +145,8 @@ func NewHTTPConfig() HTTPConfig {
 // HTTP is a processor that performs an HTTP request using the message as the
 // request body, and returns the response.
 type HTTP struct {
-	client *http.Client
+	client    *http.Client
+	paginator *http.Paginator
 
 	parallel bool
 	max      int
@@ -187,6 +197,12 @@ func NewHTTP(
 	); err != nil {
 		return nil, err
 	}
+	if g.paginator, err = http.NewPaginator(conf.HTTP.Pagination, g.client, mgr); err != nil {
+		return nil, err
+	}
+	if g.paginator != nil && g.parallel {
+		return nil, errors.New("pagination cannot be combined with parallel requests")
+	}
 	return g, nil
 }
 
@@ -199,8 +215,14 @@ func (h *HTTP) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 	var responseMsg types.Message
 
 	if !h.parallel || msg.Len() == 1 {
-		// Easy, just do a single request.
-		resultMsg, err := h.client.Send(context.Background(), msg, msg)
+		// Easy, just do a single request, or follow its pages.
+		var resultMsg types.Message
+		var err error
+		if h.paginator != nil {
+			resultMsg, err = h.paginator.All(context.Background(), msg, msg)
+		} else {
+			resultMsg, err = h.client.Send(context.Background(), msg, msg)
+		}
 		if err != nil {
 			var codeStr string
 			var hErr types.ErrUnexpectedHTTPRes
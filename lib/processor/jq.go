@@ -44,7 +44,9 @@ differences in how these queries are executed versus the jq cli which you can
 
 If the query does not emit any value then the message is filtered, if the query
 returns multiple values then the resulting message will be an array containing
-all values.
+all values. Alternatively, when the field ` + "`split_outputs`" + ` is true each
+value is emitted as a separate message of the batch, where the metadata of the
+original message is copied to each new message.
 
 The full query syntax is described in [jq's documentation][jq-docs].
 
@@ -93,6 +95,7 @@ pipeline:
 			docs.FieldCommon("query", "The jq query to filter and transform messages with."),
 			docs.FieldAdvanced("raw", "Whether to process the input as a raw string instead of as JSON."),
 			docs.FieldAdvanced("output_raw", "Whether to output raw text (unquoted) instead of JSON strings when the emitted values are string types."),
+			docs.FieldAdvanced("split_outputs", "Whether to emit each value returned by the query as a separate message rather than combining multiple values into an array.").AtVersion("3.64.0"),
 		},
 	}
 }
@@ -101,9 +104,10 @@ pipeline:
 
 // JQConfig contains configuration fields for the JQ processor.
 type JQConfig struct {
-	Query     string `json:"query" yaml:"query"`
-	Raw       bool   `json:"raw" yaml:"raw"`
-	OutputRaw bool   `json:"output_raw" yaml:"output_raw"`
+	Query        string `json:"query" yaml:"query"`
+	Raw          bool   `json:"raw" yaml:"raw"`
+	OutputRaw    bool   `json:"output_raw" yaml:"output_raw"`
+	SplitOutputs bool   `json:"split_outputs" yaml:"split_outputs"`
 }
 
 // NewJQConfig returns a JQConfig with default values.
@@ -205,6 +209,11 @@ func (j *JQ) ProcessMessage(msg types.Message) ([]types.Message, types.Response)
 	j.mCount.Incr(1)
 
 	newMsg := msg.Copy()
+
+	// When splitting outputs the parts that emitted multiple values are
+	// expanded once the query has been executed on all parts.
+	var splits map[types.Part][]interface{}
+
 	iteratePartsFilterableWithSpan(TypeJQ, nil, newMsg, func(index int, span *tracing.Span, part types.Part) (bool, error) {
 		in, err := j.getPartValue(part, j.conf.Raw)
 		if err != nil {
@@ -232,6 +241,24 @@ func (j *JQ) ProcessMessage(msg types.Message) ([]types.Message, types.Response)
 			emitted = append(emitted, out)
 		}
 
+		if j.conf.SplitOutputs {
+			if len(emitted) == 0 {
+				j.mDroppedParts.Incr(1)
+				return false, nil
+			}
+			if err = j.setPartValue(part, emitted[0]); err != nil {
+				j.mErr.Incr(1)
+				return false, err
+			}
+			if len(emitted) > 1 {
+				if splits == nil {
+					splits = map[types.Part][]interface{}{}
+				}
+				splits[part] = emitted
+			}
+			return true, nil
+		}
+
 		if j.conf.OutputRaw {
 			raw, err := j.marshalRaw(emitted)
 			if err != nil {
@@ -273,6 +300,27 @@ func (j *JQ) ProcessMessage(msg types.Message) ([]types.Message, types.Response)
 		return true, nil
 	})
 
+	if len(splits) > 0 {
+		newParts := make([]types.Part, 0, newMsg.Len())
+		_ = newMsg.Iter(func(i int, part types.Part) error {
+			newParts = append(newParts, part)
+			values, exists := splits[part]
+			if !exists {
+				return nil
+			}
+			for _, v := range values[1:] {
+				newPart := part.Copy()
+				if err := j.setPartValue(newPart, v); err != nil {
+					j.mErr.Incr(1)
+					FlagErr(newPart, err)
+				}
+				newParts = append(newParts, newPart)
+			}
+			return nil
+		})
+		newMsg.SetAll(newParts)
+	}
+
 	if newMsg.Len() == 0 {
 		j.mDropped.Incr(1)
 		return nil, response.NewAck()
@@ -293,6 +341,26 @@ func (*JQ) WaitForClose(timeout time.Duration) error {
 	return nil
 }
 
+// setPartValue sets the contents of a part to a single value emitted by the
+// query.
+func (j *JQ) setPartValue(part types.Part, value interface{}) error {
+	if j.conf.OutputRaw {
+		raw, err := j.marshalRaw([]interface{}{value})
+		if err != nil {
+			j.log.Debugf("Failed to marshal raw text: %s", err)
+			return err
+		}
+		part.Set(raw)
+		return nil
+	}
+	if err := part.SetJSON(value); err != nil {
+		j.log.Debugf("Failed to set part JSON: %v\n", err)
+		j.mErrJSONSet.Incr(1)
+		return err
+	}
+	return nil
+}
+
 func (j *JQ) marshalRaw(values []interface{}) ([]byte, error) {
 	buf := bytes.NewBufferString("")
 
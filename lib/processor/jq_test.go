@@ -252,3 +252,62 @@ func TestJQ_OutputRaw(t *testing.T) {
 		})
 	}
 }
+
+func TestJQSplitOutputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		outputRaw bool
+		input     []string
+		output    []string
+	}{
+		{
+			name:   "multiple values",
+			query:  ".items[]",
+			input:  []string{`{"items":[{"id":1},{"id":2},{"id":3}]}`, `{"items":[]}`, `{"items":[{"id":4}]}`},
+			output: []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`},
+		},
+		{
+			name:      "raw values",
+			query:     `.items[] | "\(.id):\($metadata.source)"`,
+			outputRaw: true,
+			input:     []string{`{"items":[{"id":"a"},{"id":"b"}]}`},
+			output:    []string{`a:foo`, `b:foo`},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.JQ.Query = test.query
+			conf.JQ.OutputRaw = test.outputRaw
+			conf.JQ.SplitOutputs = true
+
+			jSet, err := NewJQ(conf, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			inMsg := message.New(nil)
+			for _, doc := range test.input {
+				part := message.NewPart([]byte(doc))
+				part.Metadata().Set("source", "foo")
+				inMsg.Append(part)
+			}
+
+			msgs, res := jSet.ProcessMessage(inMsg)
+			require.Nil(t, res)
+			require.Len(t, msgs, 1)
+
+			var actual []string
+			for _, b := range message.GetAllBytes(msgs[0]) {
+				actual = append(actual, string(b))
+			}
+			assert.Equal(t, test.output, actual)
+
+			for i := 0; i < msgs[0].Len(); i++ {
+				assert.Equal(t, "foo", msgs[0].Get(i).Metadata().Get("source"))
+				assert.Empty(t, GetFail(msgs[0].Get(i)))
+			}
+		})
+	}
+}
@@ -25,12 +25,25 @@ func init() {
 			CategoryMapping,
 		},
 		Summary: `
-Checks messages against a provided JSONSchema definition but does not change the
-payload under any circumstances. If a message does not match the schema it can
+Checks messages against a provided JSONSchema definition and optionally
+normalizes them according to it. If a message does not match the schema it can
 be caught using error handling methods outlined [here](/docs/configuration/error_handling).`,
 		Description: `
 Please refer to the [JSON Schema website](https://json-schema.org/) for
-information and tutorials regarding the syntax of the schema.`,
+information and tutorials regarding the syntax of the schema.
+
+### Normalization
+
+By default the payload of messages is not changed under any circumstances. When
+any of the fields ` + "`apply_defaults`, `coerce_types` or `strip_additional_properties`" + `
+are enabled documents are first normalized by walking the schema alongside them,
+following the keywords ` + "`properties`, `patternProperties`, `additionalProperties`, `items`, `allOf`" + `
+and local ` + "`$ref`" + ` references. The normalized document is then validated
+and, when valid, replaces the payload of the message. Messages that fail
+validation are left unchanged.
+
+Subschemas of ` + "`anyOf`, `oneOf` and `if`" + ` are not used for
+normalization as it is ambiguous which of them applies.`,
 		Footnotes: `
 ## Examples
 
@@ -85,6 +98,9 @@ dropped.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("schema", "A schema to apply. Use either this or the `schema_path` field."),
 			docs.FieldCommon("schema_path", "The path of a schema document to apply. Use either this or the `schema` field."),
+			docs.FieldAdvanced("apply_defaults", "Whether to add the `default` value of each property missing from an object, as declared by the schema of the property.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("coerce_types", "Whether to convert values that do not match the `type` of their schema where it can be done without losing information, such as the string `\"10\"` to an integer, numbers and booleans to strings, and the strings `\"true\"` and `\"false\"` to booleans.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("strip_additional_properties", "Whether to remove the properties of objects that are not allowed by a schema with `additionalProperties` set to `false`, rather than failing validation.").AtVersion("3.64.0"),
 			PartsFieldSpec,
 		},
 	}
@@ -95,17 +111,23 @@ dropped.`,
 // JSONSchemaConfig is a configuration struct containing fields for the
 // jsonschema processor.
 type JSONSchemaConfig struct {
-	Parts      []int  `json:"parts" yaml:"parts"`
-	SchemaPath string `json:"schema_path" yaml:"schema_path"`
-	Schema     string `json:"schema" yaml:"schema"`
+	Parts                     []int  `json:"parts" yaml:"parts"`
+	SchemaPath                string `json:"schema_path" yaml:"schema_path"`
+	Schema                    string `json:"schema" yaml:"schema"`
+	ApplyDefaults             bool   `json:"apply_defaults" yaml:"apply_defaults"`
+	CoerceTypes               bool   `json:"coerce_types" yaml:"coerce_types"`
+	StripAdditionalProperties bool   `json:"strip_additional_properties" yaml:"strip_additional_properties"`
 }
 
 // NewJSONSchemaConfig returns a JSONSchemaConfig with default values.
 func NewJSONSchemaConfig() JSONSchemaConfig {
 	return JSONSchemaConfig{
-		Parts:      []int{},
-		SchemaPath: "",
-		Schema:     "",
+		Parts:                     []int{},
+		SchemaPath:                "",
+		Schema:                    "",
+		ApplyDefaults:             false,
+		CoerceTypes:               false,
+		StripAdditionalProperties: false,
 	}
 }
 
@@ -118,6 +140,8 @@ type JSONSchema struct {
 	log    log.Modular
 	schema *jsonschema.Schema
 
+	normalizer *jsonSchemaNormalizer
+
 	mCount     metrics.StatCounter
 	mErrJSONP  metrics.StatCounter
 	mErr       metrics.StatCounter
@@ -129,32 +153,40 @@ type JSONSchema struct {
 func NewJSONSchema(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	var schema *jsonschema.Schema
-	var err error
+	var loader jsonschema.JSONLoader
 
 	// load JSONSchema definition
 	if schemaPath := conf.JSONSchema.SchemaPath; schemaPath != "" {
 		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
 			return nil, fmt.Errorf("invalid schema_path provided, must start with file:// or http://")
 		}
-
-		schema, err = jsonschema.NewSchema(jsonschema.NewReferenceLoader(conf.JSONSchema.SchemaPath))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
-		}
+		loader = jsonschema.NewReferenceLoader(conf.JSONSchema.SchemaPath)
 	} else if conf.JSONSchema.Schema != "" {
-		schema, err = jsonschema.NewSchema(jsonschema.NewStringLoader(conf.JSONSchema.Schema))
+		loader = jsonschema.NewStringLoader(conf.JSONSchema.Schema)
+	} else {
+		return nil, fmt.Errorf("either schema or schema_path must be provided")
+	}
+
+	schema, err := jsonschema.NewSchema(loader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
+	}
+
+	var normalizer *jsonSchemaNormalizer
+	if conf.JSONSchema.ApplyDefaults || conf.JSONSchema.CoerceTypes || conf.JSONSchema.StripAdditionalProperties {
+		root, err := loader.LoadJSON()
 		if err != nil {
 			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
 		}
-	} else {
-		return nil, fmt.Errorf("either schema or schema_path must be provided")
+		normalizer = newJSONSchemaNormalizer(root, conf.JSONSchema)
 	}
 
 	return &JSONSchema{
-		stats:  stats,
-		log:    log,
-		schema: schema,
+		conf:       conf.JSONSchema,
+		stats:      stats,
+		log:        log,
+		schema:     schema,
+		normalizer: normalizer,
 
 		mCount:     stats.GetCounter("count"),
 		mErrJSONP:  stats.GetCounter("error_json_parse"),
@@ -180,6 +212,14 @@ func (s *JSONSchema) ProcessMessage(msg types.Message) ([]types.Message, types.R
 			return err
 		}
 
+		if s.normalizer != nil {
+			if jsonPart, err = s.normalizer.Normalize(jsonPart); err != nil {
+				s.log.Debugf("Failed to normalize json: %v\n", err)
+				s.mErr.Incr(1)
+				return err
+			}
+		}
+
 		partLoader := jsonschema.NewGoLoader(jsonPart)
 		result, err := s.schema.Validate(partLoader)
 		if err != nil {
@@ -206,6 +246,9 @@ func (s *JSONSchema) ProcessMessage(msg types.Message) ([]types.Message, types.R
 		}
 		s.log.Debugf("The document is valid\n")
 
+		if s.normalizer != nil {
+			return part.SetJSON(jsonPart)
+		}
 		return nil
 	}
 
@@ -0,0 +1,267 @@
+package processor
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+// jsonSchemaNormalizer applies the defaults of a JSON schema to documents,
+// coerces values to the types of the schema where no information is lost, and
+// removes properties that the schema does not allow.
+type jsonSchemaNormalizer struct {
+	root interface{}
+
+	applyDefaults   bool
+	coerceTypes     bool
+	stripAdditional bool
+
+	patterns map[string]*regexp.Regexp
+}
+
+func newJSONSchemaNormalizer(root interface{}, conf JSONSchemaConfig) *jsonSchemaNormalizer {
+	return &jsonSchemaNormalizer{
+		root:            root,
+		applyDefaults:   conf.ApplyDefaults,
+		coerceTypes:     conf.CoerceTypes,
+		stripAdditional: conf.StripAdditionalProperties,
+		patterns:        map[string]*regexp.Regexp{},
+	}
+}
+
+// Normalize returns a normalized copy of a document.
+func (n *jsonSchemaNormalizer) Normalize(doc interface{}) (interface{}, error) {
+	docCopy, err := message.CopyJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	return n.normalize(n.root, docCopy, 0), nil
+}
+
+// resolve follows local references of a schema, which are JSON pointers into
+// the root schema document. References to other documents are not followed.
+func (n *jsonSchemaNormalizer) resolve(schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return schema
+		}
+		var target interface{} = n.root
+		for _, seg := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+			if seg == "" {
+				continue
+			}
+			seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+			obj, ok := target.(map[string]interface{})
+			if !ok {
+				return schema
+			}
+			if target, ok = obj[seg]; !ok {
+				return schema
+			}
+		}
+		next, ok := target.(map[string]interface{})
+		if !ok {
+			return schema
+		}
+		schema = next
+	}
+	return schema
+}
+
+func (n *jsonSchemaNormalizer) pattern(p string) *regexp.Regexp {
+	re, exists := n.patterns[p]
+	if !exists {
+		// An invalid pattern is rejected when the schema is compiled, so we
+		// only need to cache the result here.
+		re, _ = regexp.Compile(p)
+		n.patterns[p] = re
+	}
+	return re
+}
+
+// maxJSONSchemaDepth bounds the recursion of schemas that reference
+// themselves.
+const maxJSONSchemaDepth = 100
+
+func (n *jsonSchemaNormalizer) normalize(rawSchema interface{}, v interface{}, depth int) interface{} {
+	schema, ok := rawSchema.(map[string]interface{})
+	if !ok || depth > maxJSONSchemaDepth {
+		return v
+	}
+	schema = n.resolve(schema)
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			v = n.normalize(sub, v, depth+1)
+		}
+	}
+
+	if n.coerceTypes {
+		v = coerceJSONSchemaType(schema["type"], v)
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if n.applyDefaults {
+			for k, propSchema := range props {
+				if _, exists := t[k]; exists {
+					continue
+				}
+				propObj, ok := propSchema.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if def, exists := n.resolve(propObj)["default"]; exists {
+					if defCopy, err := message.CopyJSON(def); err == nil {
+						t[k] = defCopy
+					}
+				}
+			}
+		}
+
+		patternProps, _ := schema["patternProperties"].(map[string]interface{})
+		for k, pv := range t {
+			matched := false
+			if propSchema, exists := props[k]; exists {
+				matched = true
+				pv = n.normalize(propSchema, pv, depth+1)
+			}
+			for p, propSchema := range patternProps {
+				if re := n.pattern(p); re != nil && re.MatchString(k) {
+					matched = true
+					pv = n.normalize(propSchema, pv, depth+1)
+				}
+			}
+			if !matched {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional && n.stripAdditional {
+						delete(t, k)
+						continue
+					}
+				case map[string]interface{}:
+					pv = n.normalize(additional, pv, depth+1)
+				}
+			}
+			t[k] = pv
+		}
+	case []interface{}:
+		switch items := schema["items"].(type) {
+		case map[string]interface{}:
+			for i, e := range t {
+				t[i] = n.normalize(items, e, depth+1)
+			}
+		case []interface{}:
+			for i := 0; i < len(t) && i < len(items); i++ {
+				t[i] = n.normalize(items[i], t[i], depth+1)
+			}
+		}
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
+
+func jsonSchemaTypeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return ""
+}
+
+// coerceJSONSchemaType converts scalar values to one of the types allowed by
+// a schema when the value does not already match, and only when the
+// conversion can be reversed without losing information.
+func coerceJSONSchemaType(schemaType interface{}, v interface{}) interface{} {
+	var types []string
+	switch t := schemaType.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return v
+	}
+
+	current := jsonSchemaTypeOf(v)
+	for _, t := range types {
+		if t == current || (t == "number" && current == "integer") {
+			return v
+		}
+	}
+
+	for _, t := range types {
+		if coerced, ok := coerceJSONValue(t, v); ok {
+			return coerced
+		}
+	}
+	return v
+}
+
+func coerceJSONValue(target string, v interface{}) (interface{}, bool) {
+	switch target {
+	case "string":
+		switch t := v.(type) {
+		case json.Number:
+			return t.String(), true
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(t), true
+		}
+	case "integer":
+		switch t := v.(type) {
+		case string:
+			if i, err := strconv.ParseInt(t, 10, 64); err == nil && strconv.FormatInt(i, 10) == t {
+				return i, true
+			}
+		}
+	case "number":
+		switch t := v.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(t, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return json.Number(t), json.Valid([]byte(t))
+			}
+		}
+	case "boolean":
+		switch v {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return nil, false
+}
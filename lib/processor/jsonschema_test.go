@@ -9,6 +9,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJSONSchemaExternalSchemaCheck(t *testing.T) {
@@ -353,3 +355,109 @@ func TestJSONSchemaInvalidSchema(t *testing.T) {
 		t.Error("expected error from loading bad schema")
 	}
 }
+
+func TestJSONSchemaNormalize(t *testing.T) {
+	schemaDef := `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"definitions": {
+			"tag": {
+				"type": "object",
+				"properties": {
+					"name": { "type": "string" },
+					"weight": { "type": "number", "default": 1 }
+				},
+				"additionalProperties": false
+			}
+		},
+		"properties": {
+			"id": { "type": "integer" },
+			"name": { "type": "string" },
+			"active": { "type": "boolean", "default": true },
+			"tags": {
+				"type": "array",
+				"items": { "$ref": "#/definitions/tag" }
+			}
+		},
+		"additionalProperties": false,
+		"required": ["id"]
+	}`
+
+	tests := []struct {
+		name   string
+		conf   func(c *JSONSchemaConfig)
+		input  string
+		output string
+		err    string
+	}{
+		{
+			name: "apply defaults",
+			conf: func(c *JSONSchemaConfig) {
+				c.ApplyDefaults = true
+			},
+			input:  `{"id":1,"tags":[{"name":"foo"},{"name":"bar","weight":2.5}]}`,
+			output: `{"active":true,"id":1,"tags":[{"name":"foo","weight":1},{"name":"bar","weight":2.5}]}`,
+		},
+		{
+			name: "coerce types",
+			conf: func(c *JSONSchemaConfig) {
+				c.CoerceTypes = true
+			},
+			input:  `{"id":"10","name":5,"active":"false","tags":[{"name":true,"weight":"0.5"}]}`,
+			output: `{"active":false,"id":10,"name":"5","tags":[{"name":"true","weight":0.5}]}`,
+		},
+		{
+			name: "unsafe coercion fails",
+			conf: func(c *JSONSchemaConfig) {
+				c.CoerceTypes = true
+			},
+			input:  `{"id":"10.5"}`,
+			output: `{"id":"10.5"}`,
+			err:    `id invalid type. expected: integer, given: string`,
+		},
+		{
+			name: "strip additional properties",
+			conf: func(c *JSONSchemaConfig) {
+				c.StripAdditionalProperties = true
+			},
+			input:  `{"id":1,"extra":"foo","tags":[{"name":"foo","other":"bar"}]}`,
+			output: `{"id":1,"tags":[{"name":"foo"}]}`,
+		},
+		{
+			name: "invalid documents are not normalized",
+			conf: func(c *JSONSchemaConfig) {
+				c.ApplyDefaults = true
+				c.StripAdditionalProperties = true
+			},
+			input:  `{"extra":"foo"}`,
+			output: `{"extra":"foo"}`,
+			err:    `(root) id is required`,
+		},
+		{
+			name:   "no normalization",
+			conf:   func(c *JSONSchemaConfig) {},
+			input:  `{"id":1}`,
+			output: `{"id":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeJSONSchema
+			conf.JSONSchema.Schema = schemaDef
+			test.conf(&conf.JSONSchema)
+
+			c, err := NewJSONSchema(conf, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			msgs, res := c.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+			require.Nil(t, res)
+			require.Len(t, msgs, 1)
+
+			assert.Equal(t, test.output, string(msgs[0].Get(0).Get()))
+			assert.Equal(t, test.err, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+		})
+	}
+}
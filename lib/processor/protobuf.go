@@ -1,10 +1,16 @@
 package processor
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -20,6 +26,9 @@ import (
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/protobuf/encoding/protojson"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 //------------------------------------------------------------------------------
@@ -55,12 +64,36 @@ to manipulate the contents of the document within Benthos.
 
 ### ` + "`from_json`" + `
 
-Attempts to create a target protobuf message from a generic JSON structure.`,
+Attempts to create a target protobuf message from a generic JSON structure.
+
+## Descriptors
+
+The definition of the target message is obtained from one of the following
+sources, in order of precedence:
+
+- The module of a [Buf Schema Registry](https://buf.build/docs/bsr/introduction) specified with ` + "`registry.module`" + `, which is fetched once when the processor is created.
+- A binary ` + "`FileDescriptorSet`" + ` specified with ` + "`descriptor_set_file`" + `, as produced by ` + "`protoc --include_imports --descriptor_set_out`" + ` or ` + "`buf build`" + `.
+- The .proto files found within ` + "`import_paths`" + `.
+
+Messages that are compiled into Benthos, such as the well-known types of the
+` + "`google.protobuf`" + ` package or messages registered by plugins, are used
+when the message isn't found within these sources. The messages of
+` + "`google.protobuf.Any`" + ` fields are expanded using all of the definitions
+obtained from the same source.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("message", "The fully qualified name of the protobuf message to convert to/from."),
 			docs.FieldString("import_paths", "A list of directories containing .proto files, including all definitions required for parsing the target message. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported.").Array(),
 			docs.FieldDeprecated("import_path"),
+			docs.FieldAdvanced("descriptor_set_file", "An optional path of a binary `FileDescriptorSet` containing the target message along with all of its imports. When set the .proto files of `import_paths` are not parsed.", "./schema.binpb").AtVersion("3.64.0"),
+			docs.FieldAdvanced("registry", "Fetch the definitions of a module from a Buf Schema Registry. When a module is set the .proto files of `import_paths` and the `descriptor_set_file` are ignored.").WithChildren(
+				docs.FieldCommon("url", "The URL of the registry."),
+				docs.FieldCommon("module", "The name of the module to fetch, fully qualified with the remote of the registry.", "buf.build/acme/weather"),
+				docs.FieldCommon("version", "An optional version of the module to fetch, which can be a commit, tag or branch. The latest version of the default branch is fetched when empty.", "main", "v1.0.0"),
+				docs.FieldCommon("token", "An optional token used to authenticate with the registry, which is required for private modules."),
+				docs.FieldAdvanced("timeout", "The maximum period to wait for the definitions of the module to be fetched."),
+			).AtVersion("3.64.0"),
+			docs.FieldAdvanced("discard_unknown", "Whether fields of JSON documents that are not part of the target message should be ignored by the `from_json` operator rather than resulting in an error.").AtVersion("3.64.0"),
 			PartsFieldSpec,
 		},
 		Examples: []docs.AnnotatedExample{
@@ -148,6 +181,24 @@ pipeline:
         operator: to_json
         message: testing.Person
         import_paths: [ testing/schema ]
+`,
+			},
+			{
+				Title: "Buf Schema Registry",
+				Summary: `
+Definitions can also be fetched from a module of the Buf Schema Registry, here
+we convert protobuf messages defined within the module
+` + "`buf.build/acme/weather`" + ` into JSON documents:`,
+				Config: `
+pipeline:
+  processors:
+    - protobuf:
+        operator: to_json
+        message: acme.weather.v1.Forecast
+        registry:
+          module: buf.build/acme/weather
+          version: main
+          token: ${BUF_TOKEN}
 `,
 			},
 		},
@@ -156,23 +207,51 @@ pipeline:
 
 //------------------------------------------------------------------------------
 
+// ProtobufRegistryConfig contains configuration fields for fetching the
+// descriptors of a module from a Buf Schema Registry.
+type ProtobufRegistryConfig struct {
+	URL     string `json:"url" yaml:"url"`
+	Module  string `json:"module" yaml:"module"`
+	Version string `json:"version" yaml:"version"`
+	Token   string `json:"token" yaml:"token"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// NewProtobufRegistryConfig returns a ProtobufRegistryConfig with default
+// values.
+func NewProtobufRegistryConfig() ProtobufRegistryConfig {
+	return ProtobufRegistryConfig{
+		URL:     "https://buf.build",
+		Module:  "",
+		Version: "",
+		Token:   "",
+		Timeout: "10s",
+	}
+}
+
 // ProtobufConfig contains configuration fields for the Protobuf processor.
 type ProtobufConfig struct {
-	Parts       []int    `json:"parts" yaml:"parts"`
-	Operator    string   `json:"operator" yaml:"operator"`
-	Message     string   `json:"message" yaml:"message"`
-	ImportPaths []string `json:"import_paths" yaml:"import_paths"`
-	ImportPath  string   `json:"import_path" yaml:"import_path"`
+	Parts             []int                  `json:"parts" yaml:"parts"`
+	Operator          string                 `json:"operator" yaml:"operator"`
+	Message           string                 `json:"message" yaml:"message"`
+	ImportPaths       []string               `json:"import_paths" yaml:"import_paths"`
+	ImportPath        string                 `json:"import_path" yaml:"import_path"`
+	DescriptorSetFile string                 `json:"descriptor_set_file" yaml:"descriptor_set_file"`
+	Registry          ProtobufRegistryConfig `json:"registry" yaml:"registry"`
+	DiscardUnknown    bool                   `json:"discard_unknown" yaml:"discard_unknown"`
 }
 
 // NewProtobufConfig returns a ProtobufConfig with default values.
 func NewProtobufConfig() ProtobufConfig {
 	return ProtobufConfig{
-		Parts:       []int{},
-		Operator:    "to_json",
-		Message:     "",
-		ImportPaths: []string{},
-		ImportPath:  "",
+		Parts:             []int{},
+		Operator:          "to_json",
+		Message:           "",
+		ImportPaths:       []string{},
+		ImportPath:        "",
+		DescriptorSetFile: "",
+		Registry:          NewProtobufRegistryConfig(),
+		DiscardUnknown:    false,
 	}
 }
 
@@ -180,23 +259,9 @@ func NewProtobufConfig() ProtobufConfig {
 
 type protobufOperator func(part types.Part) error
 
-func newProtobufToJSONOperator(message string, importPaths []string) (protobufOperator, error) {
-	if message == "" {
-		return nil, errors.New("message field must not be empty")
-	}
-
-	descriptors, err := loadDescriptors(importPaths)
-	if err != nil {
-		return nil, err
-	}
-
-	m := getMessageFromDescriptors(message, descriptors)
-	if m == nil {
-		return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", message, importPaths)
-	}
-
+func newProtobufToJSONOperator(m *desc.MessageDescriptor, fds []*desc.FileDescriptor) protobufOperator {
 	marshaller := &jsonpb.Marshaler{
-		AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), descriptors...),
+		AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fds...),
 	}
 
 	return func(part types.Part) error {
@@ -212,26 +277,13 @@ func newProtobufToJSONOperator(message string, importPaths []string) (protobufOp
 
 		part.Set(data)
 		return nil
-	}, nil
-}
-
-func newProtobufFromJSONOperator(message string, importPaths []string) (protobufOperator, error) {
-	if message == "" {
-		return nil, errors.New("message field must not be empty")
-	}
-
-	descriptors, err := loadDescriptors(importPaths)
-	if err != nil {
-		return nil, err
-	}
-
-	m := getMessageFromDescriptors(message, descriptors)
-	if m == nil {
-		return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", message, importPaths)
 	}
+}
 
+func newProtobufFromJSONOperator(m *desc.MessageDescriptor, fds []*desc.FileDescriptor, discardUnknown bool) protobufOperator {
 	unmarshaler := &jsonpb.Unmarshaler{
-		AnyResolver: dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), descriptors...),
+		AllowUnknownFields: discardUnknown,
+		AnyResolver:        dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fds...),
 	}
 
 	return func(part types.Part) error {
@@ -247,17 +299,63 @@ func newProtobufFromJSONOperator(message string, importPaths []string) (protobuf
 
 		part.Set(data)
 		return nil
-	}, nil
+	}
 }
 
-func strToProtobufOperator(opStr, message string, importPaths []string) (protobufOperator, error) {
-	switch opStr {
-	case "to_json":
-		return newProtobufToJSONOperator(message, importPaths)
-	case "from_json":
-		return newProtobufFromJSONOperator(message, importPaths)
+func strToProtobufOperator(conf ProtobufConfig, importPaths []string) (protobufOperator, error) {
+	if conf.Operator != "to_json" && conf.Operator != "from_json" {
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Operator)
+	}
+
+	m, fds, err := loadProtobufMessage(conf, importPaths)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Operator == "to_json" {
+		return newProtobufToJSONOperator(m, fds), nil
+	}
+	return newProtobufFromJSONOperator(m, fds, conf.DiscardUnknown), nil
+}
+
+// loadProtobufMessage finds the descriptor of the target message from the
+// configured source of descriptors, falling back to messages that are compiled
+// into Benthos.
+func loadProtobufMessage(conf ProtobufConfig, importPaths []string) (*desc.MessageDescriptor, []*desc.FileDescriptor, error) {
+	if conf.Message == "" {
+		return nil, nil, errors.New("message field must not be empty")
+	}
+
+	var fds []*desc.FileDescriptor
+	var source string
+	var err error
+	switch {
+	case conf.Registry.Module != "":
+		source = fmt.Sprintf("module '%v'", conf.Registry.Module)
+		fds, err = fetchRegistryDescriptors(conf.Registry)
+	case conf.DescriptorSetFile != "":
+		source = fmt.Sprintf("descriptor set '%v'", conf.DescriptorSetFile)
+		fds, err = loadDescriptorSet(conf.DescriptorSetFile)
+	default:
+		source = fmt.Sprintf("'%v'", importPaths)
+		if fds, err = loadDescriptors(importPaths); err != nil && len(importPaths) == 0 {
+			// Without explicit import paths a lack of .proto files is only a
+			// problem when the message isn't compiled in.
+			if m := getCompiledMessage(conf.Message); m != nil {
+				return m, []*desc.FileDescriptor{m.GetFile()}, nil
+			}
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m := getMessageFromDescriptors(conf.Message, fds); m != nil {
+		return m, fds, nil
 	}
-	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+	if m := getCompiledMessage(conf.Message); m != nil {
+		return m, append(fds, m.GetFile()), nil
+	}
+	return nil, nil, fmt.Errorf("unable to find message '%v' definition within %v", conf.Message, source)
 }
 
 func loadDescriptors(importPaths []string) ([]*desc.FileDescriptor, error) {
@@ -298,6 +396,117 @@ func loadDescriptors(importPaths []string) ([]*desc.FileDescriptor, error) {
 	return fds, err
 }
 
+// loadDescriptorSet reads a binary FileDescriptorSet, which must include the
+// imports of each file within it.
+func loadDescriptorSet(path string) ([]*desc.FileDescriptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+
+	var fdSet descriptorpb.FileDescriptorSet
+	if err := protov2.Unmarshal(b, &fdSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %v", err)
+	}
+	return descriptorsFromSet(&fdSet)
+}
+
+// fetchRegistryDescriptors obtains the descriptors of a module, along with all
+// of its imports, from the reflection API of a Buf Schema Registry.
+func fetchRegistryDescriptors(conf ProtobufRegistryConfig) ([]*desc.FileDescriptor, error) {
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry timeout: %v", err)
+	}
+
+	reqBody := map[string]string{"module": conf.Module}
+	if conf.Version != "" {
+		reqBody["version"] = conf.Version
+	}
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), timeout)
+	defer done()
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost,
+		strings.TrimSuffix(conf.URL, "/")+"/buf.reflect.v1beta1.FileDescriptorSetService/GetFileDescriptorSet",
+		bytes.NewReader(reqBytes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	if conf.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+conf.Token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch descriptors of module '%v': %v", conf.Module, err)
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptors of module '%v': %v", conf.Module, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var resErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if jerr := json.Unmarshal(resBytes, &resErr); jerr == nil && resErr.Message != "" {
+			return nil, fmt.Errorf("failed to fetch descriptors of module '%v': %v: %v", conf.Module, resErr.Code, resErr.Message)
+		}
+		return nil, fmt.Errorf("failed to fetch descriptors of module '%v': status code %v", conf.Module, res.StatusCode)
+	}
+
+	var resObj struct {
+		FileDescriptorSet json.RawMessage `json:"fileDescriptorSet"`
+	}
+	if err := json.Unmarshal(resBytes, &resObj); err != nil {
+		return nil, fmt.Errorf("failed to parse registry response: %v", err)
+	}
+
+	var fdSet descriptorpb.FileDescriptorSet
+	if err := protojson.Unmarshal(resObj.FileDescriptorSet, &fdSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %v", err)
+	}
+	return descriptorsFromSet(&fdSet)
+}
+
+func descriptorsFromSet(fdSet *descriptorpb.FileDescriptorSet) ([]*desc.FileDescriptor, error) {
+	fdMap, err := desc.CreateFileDescriptorsFromSet(fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create descriptors from set: %v", err)
+	}
+
+	fds := make([]*desc.FileDescriptor, 0, len(fdSet.File))
+	for _, fdProto := range fdSet.File {
+		fds = append(fds, fdMap[fdProto.GetName()])
+	}
+	if len(fds) == 0 {
+		return nil, errors.New("descriptor set does not contain any files")
+	}
+	return fds, nil
+}
+
+// getCompiledMessage returns the descriptor of a message that has been
+// compiled into Benthos, such as the well-known types or messages of plugins,
+// or nil if it doesn't exist.
+func getCompiledMessage(message string) *desc.MessageDescriptor {
+	m, err := desc.LoadMessageDescriptor(message)
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
 func getMessageFromDescriptors(message string, fds []*desc.FileDescriptor) *desc.MessageDescriptor {
 	var msg *desc.MessageDescriptor
 	for _, fd := range fds {
@@ -348,7 +557,7 @@ func NewProtobuf(
 	}
 
 	var err error
-	if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator, conf.Protobuf.Message, importPaths); err != nil {
+	if p.operator, err = strToProtobufOperator(conf.Protobuf, importPaths); err != nil {
 		return nil, err
 	}
 	return p, nil
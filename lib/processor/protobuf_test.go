@@ -1,14 +1,23 @@
 package processor
 
 import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/jhump/protoreflect/desc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	protov2 "google.golang.org/protobuf/proto"
 )
 
 func TestProtobuf(t *testing.T) {
@@ -198,3 +207,143 @@ func TestProtobufErrors(t *testing.T) {
 		})
 	}
 }
+
+func testProtobufDescriptorSet(t *testing.T) protov2.Message {
+	t.Helper()
+
+	fds, err := loadDescriptors([]string{"../../config/test/protobuf/schema"})
+	require.NoError(t, err)
+	return desc.ToFileDescriptorSet(fds...)
+}
+
+func testProtobufRoundTrip(t *testing.T, conf Config, msgName, doc string) {
+	t.Helper()
+
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Message = msgName
+
+	conf.Protobuf.Operator = "from_json"
+	fromJSON, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf.Protobuf.Operator = "to_json"
+	toJSON, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := fromJSON.ProcessMessage(message.New([][]byte{[]byte(doc)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Empty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.NotEqual(t, doc, string(msgs[0].Get(0).Get()))
+
+	msgs, res = toJSON.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Empty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, doc, string(msgs[0].Get(0).Get()))
+}
+
+func TestProtobufDescriptorSetFile(t *testing.T) {
+	setBytes, err := protov2.Marshal(testProtobufDescriptorSet(t))
+	require.NoError(t, err)
+
+	setPath := filepath.Join(t.TempDir(), "schema.binpb")
+	require.NoError(t, os.WriteFile(setPath, setBytes, 0o644))
+
+	conf := NewConfig()
+	conf.Protobuf.DescriptorSetFile = setPath
+	testProtobufRoundTrip(t, conf, "testing.Envelope", `{"id":747,"content":{"@type":"type.googleapis.com/testing.House","address":"123"}}`)
+}
+
+func TestProtobufRegistry(t *testing.T) {
+	setJSON, err := protojson.Marshal(testProtobufDescriptorSet(t))
+	require.NoError(t, err)
+
+	var reqBodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/buf.reflect.v1beta1.FileDescriptorSetService/GetFileDescriptorSet", r.URL.Path)
+		assert.Equal(t, "Bearer foo", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqBodies = append(reqBodies, string(body))
+
+		resBytes, err := json.Marshal(map[string]interface{}{
+			"fileDescriptorSet": json.RawMessage(setJSON),
+			"version":           "abc123",
+		})
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(resBytes)
+	}))
+	t.Cleanup(server.Close)
+
+	conf := NewConfig()
+	conf.Protobuf.Registry.URL = server.URL + "/"
+	conf.Protobuf.Registry.Module = "buf.build/acme/testing"
+	conf.Protobuf.Registry.Version = "main"
+	conf.Protobuf.Registry.Token = "foo"
+	testProtobufRoundTrip(t, conf, "testing.Envelope", `{"id":747,"content":{"@type":"type.googleapis.com/testing.Person","firstName":"bob"}}`)
+
+	assert.Equal(t, []string{
+		`{"module":"buf.build/acme/testing","version":"main"}`,
+		`{"module":"buf.build/acme/testing","version":"main"}`,
+	}, reqBodies)
+}
+
+func TestProtobufRegistryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"not_found","message":"repository \"acme/nope\" was not found"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.Registry.URL = server.URL
+	conf.Protobuf.Registry.Module = "buf.build/acme/nope"
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to fetch descriptors of module 'buf.build/acme/nope': not_found: repository "acme/nope" was not found`)
+}
+
+func TestProtobufCompiledMessage(t *testing.T) {
+	// Without import paths the current directory has no .proto files.
+	conf := NewConfig()
+	testProtobufRoundTrip(t, conf, "google.protobuf.FileDescriptorProto", `{"name":"foo.proto","dependency":["bar.proto"]}`)
+
+	conf = NewConfig()
+	conf.Protobuf.ImportPaths = []string{"../../config/test/protobuf/schema"}
+	testProtobufRoundTrip(t, conf, "google.protobuf.FileDescriptorProto", `{"name":"foo.proto"}`)
+
+	conf = NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Message = "testing.Nope"
+	conf.Protobuf.ImportPaths = []string{"../../config/test/protobuf/schema"}
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "unable to find message 'testing.Nope' definition within '[../../config/test/protobuf/schema]'")
+}
+
+func TestProtobufDiscardUnknown(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.ImportPaths = []string{"../../config/test/protobuf/schema"}
+	conf.Protobuf.DiscardUnknown = true
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"firstName":"john","lastName":"oates","ageFoo":10,"age":10}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Empty(t, msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, []byte{0x0a, 0x04, 0x6a, 0x6f, 0x68, 0x6e, 0x12, 0x05, 0x6f, 0x61, 0x74, 0x65, 0x73, 0x20, 0x0a}, msgs[0].Get(0).Get())
+}
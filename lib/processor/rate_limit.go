@@ -2,9 +2,12 @@ package processor
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -25,8 +28,31 @@ Throttles the throughput of a pipeline according to a specified
 ` + "[`rate_limit`](/docs/components/rate_limits/about)" + ` resource. Rate limits are
 shared across components and therefore apply globally to all processing
 pipelines.`,
+		Description: `
+### Credits
+
+By default each message consumes a single unit of access from the rate limit.
+The field ` + "`credits`" + ` can be used in order to consume a number of units
+calculated from the contents of each message instead, such as the size of its
+payload, which allows throttling by throughput of data rather than by message
+count. When ` + "`per_batch`" + ` is set to ` + "`true`" + ` the credits of
+all messages of a batch are summed and consumed by a single access, which
+combined with the default credits of ` + "`1`" + ` consumes units in
+proportion to the number of messages within each batch.
+
+Rate limits that are able to consume multiple units within a single request
+(such as ` + "`local`" + `) are given the total number of units in one go,
+otherwise the rate limit is accessed once for each unit.
+
+If the credits of a message cannot be parsed as an integer then the message is
+flagged as having failed and does not consume any units.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("resource", "The target [`rate_limit` resource](/docs/components/rate_limits/about)."),
+			docs.FieldAdvanced(
+				"credits", "The number of units of access each message consumes from the rate limit, which must resolve to an integer. Messages that resolve to zero or fewer credits do not access the rate limit.",
+				"${! content().length() }", `${! meta("weight") }`,
+			).IsInterpolated().AtVersion("3.64.0"),
+			docs.FieldAdvanced("per_batch", "Whether the credits of all messages of a batch should be summed and consumed by a single access of the rate limit, rather than accessing the rate limit for each message.").AtVersion("3.64.0"),
 		},
 	}
 }
@@ -36,12 +62,16 @@ pipelines.`,
 // RateLimitConfig contains configuration fields for the RateLimit processor.
 type RateLimitConfig struct {
 	Resource string `json:"resource" yaml:"resource"`
+	Credits  string `json:"credits" yaml:"credits"`
+	PerBatch bool   `json:"per_batch" yaml:"per_batch"`
 }
 
 // NewRateLimitConfig returns a RateLimitConfig with default values.
 func NewRateLimitConfig() RateLimitConfig {
 	return RateLimitConfig{
 		Resource: "",
+		Credits:  "1",
+		PerBatch: false,
 	}
 }
 
@@ -50,8 +80,10 @@ func NewRateLimitConfig() RateLimitConfig {
 // RateLimit is a processor that performs an RateLimit request using the message as the
 // request body, and returns the response.
 type RateLimit struct {
-	rlName string
-	mgr    types.Manager
+	rlName   string
+	mgr      types.Manager
+	credits  *field.Expression
+	perBatch bool
 
 	log log.Modular
 
@@ -72,9 +104,15 @@ func NewRateLimit(
 	if err := interop.ProbeRateLimit(context.Background(), mgr, conf.RateLimit.Resource); err != nil {
 		return nil, err
 	}
+	credits, err := interop.NewBloblangField(mgr, conf.RateLimit.Credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credits expression: %v", err)
+	}
 	r := &RateLimit{
 		rlName:       conf.RateLimit.Resource,
 		mgr:          mgr,
+		credits:      credits,
+		perBatch:     conf.RateLimit.PerBatch,
 		log:          log,
 		mCount:       stats.GetCounter("count"),
 		mRateLimited: stats.GetCounter("rate.limited"),
@@ -88,43 +126,78 @@ func NewRateLimit(
 
 //------------------------------------------------------------------------------
 
-// ProcessMessage applies the processor to a message, either creating >0
-// resulting messages or a response to be sent back to the message source.
-func (r *RateLimit) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
-	r.mCount.Incr(1)
+// creditsFor resolves the number of units of access a message consumes.
+func (r *RateLimit) creditsFor(index int, msg types.Message) (int, error) {
+	str := r.credits.String(index, msg)
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse credits '%v' as an integer: %v", str, err)
+	}
+	return n, nil
+}
 
-	msg.Iter(func(i int, p types.Part) error {
+// access blocks until n units of access have been consumed from the rate limit,
+// returning an error only if the processor or rate limit is closed.
+func (r *RateLimit) access(n int) error {
+	for n > 0 {
 		var waitFor time.Duration
 		var err error
+		consumed := 1
 		if rerr := interop.AccessRateLimit(context.Background(), r.mgr, r.rlName, func(rl types.RateLimit) {
-			waitFor, err = rl.Access()
-		}); rerr != nil {
-			err = rerr
-		}
-		for err != nil || waitFor > 0 {
-			if err == types.ErrTypeClosed {
-				return err
-			}
-			if err != nil {
-				r.mErr.Incr(1)
-				r.log.Errorf("Failed to access rate limit: %v\n", err)
-				waitFor = time.Second
+			if rln, ok := rl.(types.RateLimitWithAccessN); ok {
+				consumed = n
+				waitFor, err = rln.AccessN(n)
 			} else {
-				r.mRateLimited.Incr(1)
-			}
-			select {
-			case <-time.After(waitFor):
-			case <-r.closeChan:
-				return types.ErrTypeClosed
-			}
-			if rerr := interop.AccessRateLimit(context.Background(), r.mgr, r.rlName, func(rl types.RateLimit) {
 				waitFor, err = rl.Access()
-			}); rerr != nil {
-				err = rerr
 			}
+		}); rerr != nil {
+			err = rerr
 		}
-		return err
+		if err == types.ErrTypeClosed {
+			return err
+		}
+		if err != nil {
+			r.mErr.Incr(1)
+			r.log.Errorf("Failed to access rate limit: %v\n", err)
+			waitFor = time.Second
+		} else if waitFor > 0 {
+			r.mRateLimited.Incr(1)
+		} else {
+			n -= consumed
+			continue
+		}
+		select {
+		case <-time.After(waitFor):
+		case <-r.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *RateLimit) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	total := 0
+	msg.Iter(func(i int, p types.Part) error {
+		n, err := r.creditsFor(i, msg)
+		if err != nil {
+			r.mErr.Incr(1)
+			r.log.Errorf("Failed to resolve rate limit credits: %v\n", err)
+			FlagErr(p, err)
+			return nil
+		}
+		if r.perBatch {
+			total += n
+			return nil
+		}
+		return r.access(n)
 	})
+	if r.perBatch {
+		_ = r.access(total)
+	}
 
 	r.mBatchSent.Incr(1)
 	r.mSent.Incr(int64(msg.Len()))
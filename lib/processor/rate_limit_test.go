@@ -210,3 +210,123 @@ func TestRateLimitBlocked(t *testing.T) {
 		t.Error("Timed out")
 	}
 }
+
+type fakeRateLimitN struct {
+	fakeRateLimit
+	resNFn func(n int) (time.Duration, error)
+}
+
+func (f fakeRateLimitN) AccessN(n int) (time.Duration, error) {
+	return f.resNFn(n)
+}
+
+func TestRateLimitCredits(t *testing.T) {
+	var hits int32
+	rlFn := func() (time.Duration, error) {
+		atomic.AddInt32(&hits, 1)
+		return 0, nil
+	}
+
+	var accessed []int
+	rlNFn := func(n int) (time.Duration, error) {
+		accessed = append(accessed, n)
+		return 0, nil
+	}
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": fakeRateLimit{resFn: rlFn},
+			"bar": fakeRateLimitN{resNFn: rlNFn},
+		},
+	}
+
+	input := message.New([][]byte{
+		[]byte(`hello`),
+		[]byte(``),
+		[]byte(`hi`),
+	})
+
+	conf := NewConfig()
+	conf.RateLimit.Resource = "foo"
+	conf.RateLimit.Credits = "${! content().length() }"
+	proc, err := NewRateLimit(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	if exp, act := int32(7), atomic.LoadInt32(&hits); exp != act {
+		t.Errorf("Wrong count of rate limit hits: %v != %v", act, exp)
+	}
+
+	conf.RateLimit.Resource = "bar"
+	if proc, err = NewRateLimit(conf, mgr, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if _, res = proc.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []int{5, 2}, accessed; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit accesses: %v != %v", act, exp)
+	}
+
+	accessed = nil
+	conf.RateLimit.Credits = "1"
+	conf.RateLimit.PerBatch = true
+	if proc, err = NewRateLimit(conf, mgr, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if _, res = proc.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []int{3}, accessed; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit accesses: %v != %v", act, exp)
+	}
+}
+
+func TestRateLimitCreditsBadValue(t *testing.T) {
+	var hits int32
+	rlFn := func() (time.Duration, error) {
+		atomic.AddInt32(&hits, 1)
+		return 0, nil
+	}
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": fakeRateLimit{resFn: rlFn},
+		},
+	}
+
+	conf := NewConfig()
+	conf.RateLimit.Resource = "foo"
+	conf.RateLimit.Credits = "${! content() }"
+	proc, err := NewRateLimit(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`2`),
+		[]byte(`nope`),
+	})
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if HasFailed(output[0].Get(0)) {
+		t.Error("Expected first message not to be flagged")
+	}
+	if !HasFailed(output[0].Get(1)) {
+		t.Error("Expected second message to be flagged")
+	}
+	if exp, act := int32(2), atomic.LoadInt32(&hits); exp != act {
+		t.Errorf("Wrong count of rate limit hits: %v != %v", act, exp)
+	}
+}
@@ -85,10 +85,54 @@ With cast set to true, the resulting JSON structure would look like this:
     ]
   }
 }
+` + "```" + `
+
+The parsing of documents can be tuned with the following fields:
+
+- ` + "`attribute_prefix`" + ` changes the prefix of attribute keys, and ` + "`ignore_attributes`" + ` drops attributes entirely.
+- ` + "`array_paths`" + ` lists the paths of elements that should always be arrays, even when only one element appears. This is useful for ensuring the structure of documents is consistent regardless of how many elements they contain.
+- ` + "`preserve_cdata`" + ` parses the contents of CDATA sections under the key ` + "`#cdata`" + ` rather than merging them with the text of their element.
+- ` + "`keep_namespaces`" + ` retains the namespace prefixes of element and attribute names, which are otherwise removed. Namespace declarations are always kept as attributes.
+
+### ` + "`from_json`" + `
+
+Converts a JSON document into XML following the rules of ` + "`to_json`" + ` in reverse, where the document must be an object with a single key, the name of the root element. Keys beginning with the ` + "`attribute_prefix`" + ` become attributes, the key ` + "`#text`" + ` becomes the text of the element, the key ` + "`#cdata`" + ` becomes a CDATA section, arrays become repeated elements and all other keys become child elements in alphabetical order.
+
+For example, given the following JSON:
+
+` + "```json" + `
+{
+  "root":{
+    "-id":"foo",
+    "title":"This is a title",
+    "elements":[
+      {"#text":"foo1","-id":1},
+      {"#cdata":"<foo2>"}
+    ]
+  }
+}
+` + "```" + `
+
+The resulting XML document would look like this (formatted for readability):
+
+` + "```xml" + `
+<root id="foo">
+  <elements id="1">foo1</elements>
+  <elements><![CDATA[<foo2>]]></elements>
+  <title>This is a title</title>
+</root>
 ` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json"),
+			docs.FieldCommon("operator", "An XML [operation](#operators) to apply to messages.").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("cast", "Whether to try to cast values that are numbers and booleans to the right type. Default: all values are strings."),
+			docs.FieldAdvanced("attribute_prefix", "A prefix added to the keys of attributes in order to distinguish them from child elements.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("ignore_attributes", "Whether to drop the attributes of elements when converting to JSON.").AtVersion("3.64.0"),
+			docs.FieldAdvanced(
+				"array_paths", "A list of dot separated paths of elements, starting with the root element, that are always converted to arrays even when they appear only once. A path segment `*` matches any element name.",
+				[]string{"root.elements", "root.*.item"},
+			).Array().AtVersion("3.64.0"),
+			docs.FieldAdvanced("preserve_cdata", "Whether to convert the contents of CDATA sections to the key `#cdata` rather than merging them with the text of their element.").AtVersion("3.64.0"),
+			docs.FieldAdvanced("keep_namespaces", "Whether to keep the namespace prefixes of element and attribute names when converting to JSON.").AtVersion("3.64.0"),
 			PartsFieldSpec,
 		},
 	}
@@ -98,17 +142,59 @@ With cast set to true, the resulting JSON structure would look like this:
 
 // XMLConfig contains configuration fields for the XML processor.
 type XMLConfig struct {
-	Parts    []int  `json:"parts" yaml:"parts"`
-	Operator string `json:"operator" yaml:"operator"`
-	Cast     bool   `json:"cast" yaml:"cast"`
+	Parts            []int    `json:"parts" yaml:"parts"`
+	Operator         string   `json:"operator" yaml:"operator"`
+	Cast             bool     `json:"cast" yaml:"cast"`
+	AttributePrefix  string   `json:"attribute_prefix" yaml:"attribute_prefix"`
+	IgnoreAttributes bool     `json:"ignore_attributes" yaml:"ignore_attributes"`
+	ArrayPaths       []string `json:"array_paths" yaml:"array_paths"`
+	PreserveCDATA    bool     `json:"preserve_cdata" yaml:"preserve_cdata"`
+	KeepNamespaces   bool     `json:"keep_namespaces" yaml:"keep_namespaces"`
 }
 
 // NewXMLConfig returns a XMLConfig with default values.
 func NewXMLConfig() XMLConfig {
 	return XMLConfig{
-		Parts:    []int{},
-		Operator: "to_json",
-		Cast:     false,
+		Parts:            []int{},
+		Operator:         "to_json",
+		Cast:             false,
+		AttributePrefix:  "-",
+		IgnoreAttributes: false,
+		ArrayPaths:       []string{},
+		PreserveCDATA:    false,
+		KeepNamespaces:   false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type xmlOperator func(part types.Part) error
+
+func newXMLToJSONOperator(conv *xml.Converter) xmlOperator {
+	return func(part types.Part) error {
+		root, err := conv.Decode(part.Get())
+		if err != nil {
+			return fmt.Errorf("failed to parse part as XML: %w", err)
+		}
+		if err = part.SetJSON(root); err != nil {
+			return fmt.Errorf("failed to marshal XML as JSON: %w", err)
+		}
+		return nil
+	}
+}
+
+func newXMLFromJSONOperator(conv *xml.Converter) xmlOperator {
+	return func(part types.Part) error {
+		root, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse part as JSON: %w", err)
+		}
+		xmlBytes, err := conv.Encode(root)
+		if err != nil {
+			return fmt.Errorf("failed to convert JSON to XML: %w", err)
+		}
+		part.Set(xmlBytes)
+		return nil
 	}
 }
 
@@ -116,7 +202,8 @@ func NewXMLConfig() XMLConfig {
 
 // XML is a processor that performs an operation on a XML payload.
 type XML struct {
-	parts []int
+	parts    []int
+	operator xmlOperator
 
 	conf  Config
 	log   log.Modular
@@ -132,15 +219,31 @@ type XML struct {
 func NewXML(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	if conf.XML.Operator != "to_json" {
+	opts := xml.NewOptions()
+	opts.Cast = conf.XML.Cast
+	opts.AttributePrefix = conf.XML.AttributePrefix
+	opts.IgnoreAttributes = conf.XML.IgnoreAttributes
+	opts.ArrayPaths = conf.XML.ArrayPaths
+	opts.PreserveCDATA = conf.XML.PreserveCDATA
+	opts.KeepNamespaces = conf.XML.KeepNamespaces
+	conv := xml.NewConverter(opts)
+
+	var operator xmlOperator
+	switch conf.XML.Operator {
+	case "to_json":
+		operator = newXMLToJSONOperator(conv)
+	case "from_json":
+		operator = newXMLFromJSONOperator(conv)
+	default:
 		return nil, fmt.Errorf("operator not recognised: %v", conf.XML.Operator)
 	}
 
 	j := &XML{
-		parts: conf.XML.Parts,
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		parts:    conf.XML.Parts,
+		operator: operator,
+		conf:     conf,
+		log:      log,
+		stats:    stats,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
@@ -159,15 +262,9 @@ func (p *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	newMsg := msg.Copy()
 
 	proc := func(index int, span *tracing.Span, part types.Part) error {
-		root, err := xml.ToMap(part.Get(), p.conf.XML.Cast)
-		if err != nil {
-			p.mErr.Incr(1)
-			p.log.Debugf("Failed to parse part as XML: %v\n", err)
-			return err
-		}
-		if err = part.SetJSON(root); err != nil {
+		if err := p.operator(part); err != nil {
 			p.mErr.Incr(1)
-			p.log.Debugf("Failed to marshal XML as JSON: %v\n", err)
+			p.log.Debugf("Operator failed: %v\n", err)
 			return err
 		}
 		return nil
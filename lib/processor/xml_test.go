@@ -6,6 +6,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestXMLCases(t *testing.T) {
@@ -138,3 +140,160 @@ func TestXMLWithCast(t *testing.T) {
 		t.Error(errStr)
 	}
 }
+
+func TestXMLToJSONOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(conf *XMLConfig)
+		input  string
+		output string
+	}{
+		{
+			name: "attribute prefix",
+			mutate: func(conf *XMLConfig) {
+				conf.AttributePrefix = "@"
+			},
+			input:  `<root id="1"><next>foo</next></root>`,
+			output: `{"root":{"@id":"1","next":"foo"}}`,
+		},
+		{
+			name: "ignore attributes",
+			mutate: func(conf *XMLConfig) {
+				conf.IgnoreAttributes = true
+			},
+			input:  `<root id="1"><next tone="boring">foo</next></root>`,
+			output: `{"root":{"next":"foo"}}`,
+		},
+		{
+			name: "array paths",
+			mutate: func(conf *XMLConfig) {
+				conf.ArrayPaths = []string{"root.elements", "root.*.item"}
+			},
+			input:  `<root><elements>foo</elements><a><item>bar</item></a><b><item>baz</item><item>buz</item></b></root>`,
+			output: `{"root":{"a":{"item":["bar"]},"b":{"item":["baz","buz"]},"elements":["foo"]}}`,
+		},
+		{
+			name:   "cdata merged by default",
+			mutate: func(conf *XMLConfig) {},
+			input:  `<root><next><![CDATA[<foo>]]></next></root>`,
+			output: `{"root":{"next":"<foo>"}}`,
+		},
+		{
+			name: "preserve cdata",
+			mutate: func(conf *XMLConfig) {
+				conf.PreserveCDATA = true
+			},
+			input:  `<root><next>foo<![CDATA[ <bar> ]]></next><other>baz</other></root>`,
+			output: `{"root":{"next":{"#cdata":" <bar> ","#text":"foo"},"other":"baz"}}`,
+		},
+		{
+			name:   "strip namespaces",
+			mutate: func(conf *XMLConfig) {},
+			input:  `<a:root xmlns:a="http://example.com/a"><a:next a:id="1">foo</a:next></a:root>`,
+			output: `{"root":{"-xmlns:a":"http://example.com/a","next":{"#text":"foo","-id":"1"}}}`,
+		},
+		{
+			name: "keep namespaces",
+			mutate: func(conf *XMLConfig) {
+				conf.KeepNamespaces = true
+			},
+			input:  `<a:root xmlns:a="http://example.com/a" xmlns="http://example.com/b"><a:next a:id="1">foo</a:next><other>bar</other></a:root>`,
+			output: `{"a:root":{"-xmlns":"http://example.com/b","-xmlns:a":"http://example.com/a","a:next":{"#text":"foo","-a:id":"1"},"other":"bar"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewConfig()
+			test.mutate(&conf.XML)
+
+			proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+			require.Nil(t, res)
+			require.Len(t, msgsOut, 1)
+			assert.Empty(t, GetFail(msgsOut[0].Get(0)))
+			assert.Equal(t, test.output, string(msgsOut[0].Get(0).Get()))
+		})
+	}
+}
+
+func TestXMLFromJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		err    string
+	}{
+		{
+			name:   "basic",
+			input:  `{"root":{"next":"foo1","inner":{"thing":10}}}`,
+			output: `<root><inner><thing>10</thing></inner><next>foo1</next></root>`,
+		},
+		{
+			name:   "attributes and arrays",
+			input:  `{"root":{"-id":"foo","elements":[{"#text":"foo1","-id":1},{"#text":"foo2","-id":2},"foo3"]}}`,
+			output: `<root id="foo"><elements id="1">foo1</elements><elements id="2">foo2</elements><elements>foo3</elements></root>`,
+		},
+		{
+			name:   "escapes and cdata",
+			input:  `{"root":{"-quote":"\"a&b\"","next":"<foo>","data":{"#cdata":"<bar>]]>"}}}`,
+			output: `<root quote="&quot;a&amp;b&quot;"><data><![CDATA[<bar>]]]]><![CDATA[>]]></data><next>&lt;foo&gt;</next></root>`,
+		},
+		{
+			name:  "multiple roots",
+			input: `{"a":"foo","b":"bar"}`,
+			err:   "failed to convert JSON to XML: expected an object with a single key as the root element",
+		},
+		{
+			name:  "bad element name",
+			input: `{"root":{"not valid":"foo"}}`,
+			err:   `failed to convert JSON to XML: invalid element name: "not valid"`,
+		},
+	}
+
+	conf := NewConfig()
+	conf.XML.Operator = "from_json"
+	proc, err := NewXML(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msgsOut, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+			require.Nil(t, res)
+			require.Len(t, msgsOut, 1)
+			assert.Equal(t, test.err, GetFail(msgsOut[0].Get(0)))
+			if test.err == "" {
+				assert.Equal(t, test.output, string(msgsOut[0].Get(0).Get()))
+			}
+		})
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	input := `<a:root xmlns:a="http://example.com/a" id="1"><a:next>foo</a:next><data><![CDATA[<bar>]]></data><list><item>baz</item></list></a:root>`
+
+	toConf := NewConfig()
+	toConf.XML.PreserveCDATA = true
+	toConf.XML.KeepNamespaces = true
+	toConf.XML.ArrayPaths = []string{"a:root.list.item"}
+	toJSON, err := NewXML(toConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	fromConf := NewConfig()
+	fromConf.XML.Operator = "from_json"
+	fromJSON, err := NewXML(fromConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := toJSON.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	require.Nil(t, res)
+	assert.Equal(t, `{"a:root":{"-id":"1","-xmlns:a":"http://example.com/a","a:next":"foo","data":{"#cdata":"<bar>"},"list":{"item":["baz"]}}}`, string(msgs[0].Get(0).Get()))
+
+	msgs, res = fromJSON.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	assert.Empty(t, GetFail(msgs[0].Get(0)))
+	assert.Equal(t, `<a:root id="1" xmlns:a="http://example.com/a"><a:next>foo</a:next><data><![CDATA[<bar>]]></data><list><item>baz</item></list></a:root>`, string(msgs[0].Get(0).Get()))
+}
@@ -98,12 +98,21 @@ func NewLocal(
 // can be accessed) or a reasonable length of time to wait before requesting
 // again.
 func (r *Local) Access() (time.Duration, error) {
+	return r.AccessN(1)
+}
+
+// AccessN attempts to consume n units of access from the rate limited
+// resource. Either all n units are consumed and a zero duration is returned, or
+// none are consumed and a reasonable length of time to wait before requesting
+// again is returned. A request for more units than the size of the bucket is
+// permitted once the bucket is refreshed, consuming the entire bucket.
+func (r *Local) AccessN(n int) (time.Duration, error) {
 	r.mChecked.Incr(1)
 	r.mut.Lock()
-	r.bucket--
+	r.bucket -= n
 
 	if r.bucket < 0 {
-		r.bucket = 0
+		r.bucket += n
 		remaining := r.period - time.Since(r.lastRefresh)
 
 		if remaining > 0 {
@@ -111,7 +120,9 @@ func (r *Local) Access() (time.Duration, error) {
 			r.mLimited.Incr(1)
 			return remaining, nil
 		}
-		r.bucket = r.size - 1
+		if r.bucket = r.size - n; r.bucket < 0 {
+			r.bucket = 0
+		}
 		r.lastRefresh = time.Now()
 	}
 	r.mut.Unlock()
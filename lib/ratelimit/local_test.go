@@ -7,6 +7,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
 //------------------------------------------------------------------------------
@@ -90,6 +91,44 @@ func TestLocalRateLimitRefresh(t *testing.T) {
 
 //------------------------------------------------------------------------------
 
+func TestLocalRateLimitAccessN(t *testing.T) {
+	conf := NewConfig()
+	conf.Local.Count = 10
+	conf.Local.Interval = "10ms"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rln, ok := rl.(types.RateLimitWithAccessN)
+	if !ok {
+		t.Fatal("Expected local rate limit to support AccessN")
+	}
+
+	if period, _ := rln.AccessN(7); period != 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := rln.AccessN(4); period == 0 {
+		t.Error("Expected limit on request exceeding remaining units")
+	}
+	if period, _ := rln.AccessN(3); period != 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := rl.Access(); period == 0 {
+		t.Error("Expected limit on final request")
+	}
+
+	<-time.After(time.Millisecond * 15)
+
+	if period, _ := rln.AccessN(20); period != 0 {
+		t.Errorf("Expected oversized request to pass after refresh: %v", period)
+	}
+	if period, _ := rl.Access(); period == 0 {
+		t.Error("Expected limit after oversized request")
+	}
+}
+
 func BenchmarkRateLimit(b *testing.B) {
 	/* A rate limit is typically going to be protecting a networked resource
 	 * where the request will likely be measured at least in hundreds of
@@ -63,6 +63,52 @@ Find out more about Bloblang at: https://benthos.dev/docs/guides/bloblang/about`
 		},
 		Action: run,
 		Subcommands: []*cli.Command{
+			{
+				Name:  "repl",
+				Usage: "EXPERIMENTAL: Run an interactive shell for executing Bloblang mappings",
+				Description: `
+Opens an interactive shell where mappings are executed against an input
+document as they are entered, which can be loaded from a file with the flag
+--input-file or the command :load. Use the command :help within the shell in
+order to list all commands.`[1:],
+				Action: runREPL,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "input-file",
+						Value:   "",
+						Aliases: []string{"i"},
+						Usage:   "an optional path to an input file to load as the initial input document.",
+					},
+					&cli.BoolFlag{
+						Name:    "raw",
+						Aliases: []string{"r"},
+						Usage:   "treat the input document as a raw string.",
+					},
+					&cli.BoolFlag{
+						Name:    "pretty",
+						Aliases: []string{"p"},
+						Usage:   "pretty-print output.",
+					},
+					&cli.StringFlag{
+						Name:  "history-file",
+						Usage: "a file to persist the history of mappings to, defaults to ~/.benthos_blobl_history, set to an empty string in order to disable.",
+					},
+					&cli.IntFlag{
+						Name:  "max-token-length",
+						Usage: "Set the buffer size for input lines.",
+						Value: bufio.MaxScanTokenSize,
+					},
+				},
+			},
+			{
+				Name:  "lsp",
+				Usage: "EXPERIMENTAL: Run a Bloblang language server over stdio",
+				Description: `
+Runs a server implementing the Language Server Protocol over stdin and stdout,
+which provides editors with diagnostics for mapping errors, completion of
+functions and methods, and documentation on hover.`[1:],
+				Action: runLSP,
+			},
 			{
 				Name:        "server",
 				Usage:       "EXPERIMENTAL: Run a web server that hosts a Bloblang app",
@@ -0,0 +1,471 @@
+package blobl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/urfave/cli/v2"
+)
+
+// The subset of the language server protocol that is implemented, which
+// provides diagnostics for parse errors, completion of functions, methods and
+// keywords, and hover documentation.
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-16/
+
+const (
+	lspErrParse          = -32700
+	lspErrMethodNotFound = -32601
+	lspErrInvalidParams  = -32602
+
+	lspSyncFull = 1
+
+	lspSeverityError = 1
+
+	lspKindMethod   = 2
+	lspKindFunction = 3
+	lspKindKeyword  = 14
+)
+
+var lspKeywords = []string{
+	"root", "this", "meta", "let", "map", "if", "else", "match", "import",
+}
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspMarkup struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspCompletionItem struct {
+	Label         string     `json:"label"`
+	Kind          int        `json:"kind"`
+	Detail        string     `json:"detail,omitempty"`
+	Documentation *lspMarkup `json:"documentation,omitempty"`
+}
+
+type lspDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+//------------------------------------------------------------------------------
+
+type lspServer struct {
+	env *bloblang.Environment
+
+	functions map[string]query.FunctionSpec
+	methods   map[string]query.MethodSpec
+
+	outMut sync.Mutex
+	out    io.Writer
+
+	docs map[string]string
+}
+
+func newLSPServer(env *bloblang.Environment, out io.Writer) *lspServer {
+	s := &lspServer{
+		env:       env,
+		functions: map[string]query.FunctionSpec{},
+		methods:   map[string]query.MethodSpec{},
+		out:       out,
+		docs:      map[string]string{},
+	}
+	env.WalkFunctions(func(name string, spec query.FunctionSpec) {
+		s.functions[name] = spec
+	})
+	env.WalkMethods(func(name string, spec query.MethodSpec) {
+		s.methods[name] = spec
+	})
+	return s
+}
+
+func (s *lspServer) send(msg lspMessage) error {
+	msg.JSONRPC = "2.0"
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.outMut.Lock()
+	defer s.outMut.Unlock()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %v\r\n\r\n", len(msgBytes)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(msgBytes)
+	return err
+}
+
+func (s *lspServer) notify(method string, params interface{}) error {
+	paramBytes, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.send(lspMessage{Method: method, Params: paramBytes})
+}
+
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 && strings.EqualFold(line[:i], "Content-Length") {
+			if contentLength, err = strconv.Atoi(strings.TrimSpace(line[i+1:])); err != nil {
+				return nil, fmt.Errorf("invalid content length: %w", err)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return nil, errors.New("message is missing a content length header")
+	}
+	body := make([]byte, contentLength)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+// serve handles messages read from a reader until it is exhausted or an exit
+// notification is received.
+func (s *lspServer) serve(in io.Reader) error {
+	r := bufio.NewReader(in)
+	for {
+		body, err := readLSPMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err = s.send(lspMessage{
+				Error: &lspError{Code: lspErrParse, Message: err.Error()},
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, lErr := s.handle(msg.Method, msg.Params)
+		if msg.ID == nil {
+			// Notifications do not receive a response.
+			continue
+		}
+		res := lspMessage{ID: msg.ID, Error: lErr}
+		if lErr == nil {
+			if result == nil {
+				result = json.RawMessage("null")
+			}
+			res.Result = result
+		}
+		if err := s.send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(method string, params json.RawMessage) (interface{}, *lspError) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": lspSyncFull,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]interface{}{
+				"name": "benthos-blobl",
+			},
+		}, nil
+	case "initialized", "shutdown", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		s.updateDocument(p.TextDocument.URI, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		if len(p.ContentChanges) > 0 {
+			s.updateDocument(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspDocumentPosition
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.publishDiagnostics(p.TextDocument.URI, []lspDiagnostic{})
+		return nil, nil
+	case "textDocument/completion":
+		var p lspDocumentPosition
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		return s.completion(s.docs[p.TextDocument.URI], p.Position), nil
+	case "textDocument/hover":
+		var p lspDocumentPosition
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &lspError{Code: lspErrInvalidParams, Message: err.Error()}
+		}
+		if doc := s.hover(s.docs[p.TextDocument.URI], p.Position); doc != nil {
+			return map[string]interface{}{"contents": doc}, nil
+		}
+		return nil, nil
+	}
+	return nil, &lspError{Code: lspErrMethodNotFound, Message: fmt.Sprintf("method not found: %v", method)}
+}
+
+func (s *lspServer) updateDocument(uri, text string) {
+	s.docs[uri] = text
+	s.publishDiagnostics(uri, s.diagnostics(uri, text))
+}
+
+func (s *lspServer) publishDiagnostics(uri string, diags []lspDiagnostic) {
+	_ = s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diags,
+	})
+}
+
+// diagnostics parses a document and returns any parse error as a diagnostic.
+func (s *lspServer) diagnostics(uri, text string) []lspDiagnostic {
+	env := s.env
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		env = env.WithImporterRelativeToFile(u.Path)
+	}
+
+	input := []rune(text)
+	_, err := env.NewMapping(text)
+	if err == nil {
+		return []lspDiagnostic{}
+	}
+
+	offset := len(input)
+	var perr *parser.Error
+	if errors.As(err, &perr) {
+		offset = len(input) - len(perr.Input)
+	}
+	start := lspPositionOf(input, offset)
+	end := start
+	if offset < len(input) && input[offset] != '\n' {
+		end.Character += len(utf16.Encode(input[offset : offset+1]))
+	}
+	return []lspDiagnostic{{
+		Range:    lspRange{Start: start, End: end},
+		Severity: lspSeverityError,
+		Source:   "blobl",
+		Message:  err.Error(),
+	}}
+}
+
+// lspPositionOf converts a rune offset of an input into a zero indexed line and
+// a UTF-16 character offset as required by the protocol.
+func lspPositionOf(input []rune, offset int) lspPosition {
+	var pos lspPosition
+	for _, r := range input[:offset] {
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character += len(utf16.Encode([]rune{r}))
+		}
+	}
+	return pos
+}
+
+// lspOffsetOf converts a protocol position into a rune offset of an input.
+func lspOffsetOf(input []rune, pos lspPosition) int {
+	var line, char int
+	for i, r := range input {
+		if line == pos.Line && char >= pos.Character {
+			return i
+		}
+		if r == '\n' {
+			if line == pos.Line {
+				return i
+			}
+			line++
+			char = 0
+		} else if line == pos.Line {
+			char += len(utf16.Encode([]rune{r}))
+		}
+	}
+	return len(input)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// wordAt returns the identifier that spans an offset of an input, and whether
+// it is preceded by a dot, indicating that it is a method.
+func wordAt(input []rune, offset int) (word string, isMethod bool) {
+	start, end := offset, offset
+	for start > 0 && isIdentRune(input[start-1]) {
+		start--
+	}
+	for end < len(input) && isIdentRune(input[end]) {
+		end++
+	}
+	return string(input[start:end]), start > 0 && input[start-1] == '.'
+}
+
+func functionSignature(name string, params query.Params) string {
+	names := make([]string, 0, len(params.Definitions))
+	for _, p := range params.Definitions {
+		names = append(names, p.Name)
+	}
+	if params.Variadic {
+		names = append(names, "...")
+	}
+	return name + "(" + strings.Join(names, ", ") + ")"
+}
+
+func methodDescription(spec query.MethodSpec) string {
+	if spec.Description != "" || len(spec.Categories) == 0 {
+		return spec.Description
+	}
+	return spec.Categories[0].Description
+}
+
+func (s *lspServer) completion(text string, pos lspPosition) []lspCompletionItem {
+	input := []rune(text)
+	offset := lspOffsetOf(input, pos)
+	_, isMethod := wordAt(input, offset)
+
+	items := []lspCompletionItem{}
+	if isMethod {
+		for name, spec := range s.methods {
+			if spec.Status == query.StatusDeprecated || spec.Status == query.StatusHidden {
+				continue
+			}
+			items = append(items, lspCompletionItem{
+				Label:         name,
+				Kind:          lspKindMethod,
+				Detail:        functionSignature(name, spec.Params),
+				Documentation: &lspMarkup{Kind: "markdown", Value: methodDescription(spec)},
+			})
+		}
+	} else {
+		for name, spec := range s.functions {
+			if spec.Status == query.StatusDeprecated || spec.Status == query.StatusHidden {
+				continue
+			}
+			items = append(items, lspCompletionItem{
+				Label:         name,
+				Kind:          lspKindFunction,
+				Detail:        functionSignature(name, spec.Params),
+				Documentation: &lspMarkup{Kind: "markdown", Value: spec.Description},
+			})
+		}
+		for _, k := range lspKeywords {
+			items = append(items, lspCompletionItem{Label: k, Kind: lspKindKeyword})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+func (s *lspServer) hover(text string, pos lspPosition) *lspMarkup {
+	input := []rune(text)
+	word, isMethod := wordAt(input, lspOffsetOf(input, pos))
+	if word == "" {
+		return nil
+	}
+	if isMethod {
+		if spec, exists := s.methods[word]; exists {
+			return &lspMarkup{
+				Kind:  "markdown",
+				Value: "```coffee\n" + functionSignature(word, spec.Params) + "\n```\n\n" + methodDescription(spec),
+			}
+		}
+		return nil
+	}
+	if spec, exists := s.functions[word]; exists {
+		return &lspMarkup{
+			Kind:  "markdown",
+			Value: "```coffee\n" + functionSignature(word, spec.Params) + "\n```\n\n" + spec.Description,
+		}
+	}
+	return nil
+}
+
+func runLSP(c *cli.Context) error {
+	return newLSPServer(bloblang.NewEnvironment(), os.Stdout).serve(os.Stdin)
+}
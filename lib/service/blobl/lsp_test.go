@@ -0,0 +1,152 @@
+package blobl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lspRequest(id int, method string, params interface{}) string {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}
+	if id > 0 {
+		msg["id"] = id
+	}
+	msgBytes, _ := json.Marshal(msg)
+	return fmt.Sprintf("Content-Length: %v\r\n\r\n%s", len(msgBytes), msgBytes)
+}
+
+func readLSPResponses(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var msgs []map[string]interface{}
+	r := bufio.NewReader(out)
+	for {
+		body, err := readLSPMessage(r)
+		if err != nil {
+			break
+		}
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &msg))
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	uri := "untitled:foo.blobl"
+
+	var in strings.Builder
+	in.WriteString(lspRequest(1, "initialize", map[string]interface{}{}))
+	in.WriteString(lspRequest(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "text": "root = this"},
+	}))
+	in.WriteString(lspRequest(0, "textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "root = this\nroot.foo = nope()\n"}},
+	}))
+	in.WriteString(lspRequest(2, "shutdown", nil))
+	in.WriteString(lspRequest(0, "exit", nil))
+
+	var out bytes.Buffer
+	require.NoError(t, newLSPServer(bloblang.NewEnvironment(), &out).serve(strings.NewReader(in.String())))
+
+	msgs := readLSPResponses(t, &out)
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, float64(1), msgs[0]["id"])
+	assert.Contains(t, msgs[0]["result"], "capabilities")
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[1]["method"])
+	assert.Equal(t, []interface{}{}, msgs[1]["params"].(map[string]interface{})["diagnostics"])
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[2]["method"])
+	diags := msgs[2]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	require.Len(t, diags, 1)
+	diag := diags[0].(map[string]interface{})
+	assert.Equal(t, float64(1), diag["range"].(map[string]interface{})["start"].(map[string]interface{})["line"])
+	assert.Contains(t, diag["message"], "unrecognised function 'nope'")
+
+	assert.Equal(t, float64(2), msgs[3]["id"])
+	assert.Nil(t, msgs[3]["result"])
+	assert.Contains(t, msgs[3], "result")
+}
+
+func TestLSPCompletionAndHover(t *testing.T) {
+	s := newLSPServer(bloblang.NewEnvironment(), &bytes.Buffer{})
+
+	labels := func(items []lspCompletionItem) map[string]int {
+		m := map[string]int{}
+		for _, item := range items {
+			m[item.Label] = item.Kind
+		}
+		return m
+	}
+
+	methods := labels(s.completion("root = this.foo.upp", lspPosition{Line: 0, Character: 19}))
+	assert.Equal(t, lspKindMethod, methods["uppercase"])
+	assert.NotContains(t, methods, "uuid_v4")
+
+	functions := labels(s.completion("root = this\nroot.id = uu", lspPosition{Line: 1, Character: 12}))
+	assert.Equal(t, lspKindFunction, functions["uuid_v4"])
+	assert.Equal(t, lspKindKeyword, functions["match"])
+	assert.NotContains(t, functions, "uppercase")
+
+	doc := s.hover("root = this.foo.uppercase()", lspPosition{Line: 0, Character: 18})
+	require.NotNil(t, doc)
+	assert.Contains(t, doc.Value, "uppercase()")
+
+	doc = s.hover("root = now()", lspPosition{Line: 0, Character: 8})
+	require.NotNil(t, doc)
+	assert.Contains(t, doc.Value, "now()")
+
+	assert.Nil(t, s.hover("root = this.nope_not_a_thing()", lspPosition{Line: 0, Character: 14}))
+}
+
+func TestREPL(t *testing.T) {
+	var out bytes.Buffer
+	r := newREPL(&out, "")
+
+	require.NoError(t, r.run(strings.NewReader(`root.a = this.message.uppercase()
+:input {"message":"foo"}
+root = {
+  "b": this.message
+}
+:history
+:rerun 1
+:quit
+root = "not reached"
+`)))
+
+	outStr := out.String()
+	assert.Contains(t, outStr, `{"a":"HELLO WORLD"}`)
+	assert.Contains(t, outStr, `{"b":"foo"}`)
+	assert.Contains(t, outStr, `{"a":"FOO"}`)
+	assert.Contains(t, outStr, "   2  root = {\n")
+	assert.NotContains(t, outStr, "not reached")
+	assert.Len(t, r.history, 3)
+}
+
+func TestMappingIncomplete(t *testing.T) {
+	for m, exp := range map[string]bool{
+		`root = this`:                   false,
+		`root = this.map_each(ele -> {`: true,
+		`root = [ 1, 2`:                 true,
+		`root = "(" + this`:             false,
+		`root = """foo`:                 true,
+		`root = """foo""" # (`:          false,
+		"root = {\n  \"a\": 1\n}":       false,
+	} {
+		assert.Equal(t, exp, mappingIncomplete(m), m)
+	}
+}
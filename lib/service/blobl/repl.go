@@ -0,0 +1,286 @@
+package blobl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/urfave/cli/v2"
+)
+
+const replMaxHistory = 500
+
+const replHelp = `Enter a mapping in order to execute it against the current input document.
+Mappings that are incomplete, such as those with unclosed brackets, continue
+on the next line, and an empty line executes the mapping so far.
+
+Commands:
+  :load <path>   load the input document from a file
+  :input <doc>   set the input document
+  :show          print the input document
+  :raw           toggle whether the input document is treated as raw bytes
+  :pretty        toggle pretty-printed output
+  :history       list previously executed mappings
+  :rerun <n>     execute a mapping from the history again
+  :help          print this message
+  :quit          exit the REPL`
+
+type repl struct {
+	env        *bloblang.Environment
+	execCache  *execCache
+	input      []byte
+	raw        bool
+	pretty     bool
+	history    []string
+	histFile   string
+	out        io.Writer
+	maxLineLen int
+}
+
+func newREPL(out io.Writer, histFile string) *repl {
+	r := &repl{
+		env:        bloblang.NewEnvironment(),
+		execCache:  newExecCache(),
+		input:      []byte(`{"message":"hello world"}`),
+		histFile:   histFile,
+		out:        out,
+		maxLineLen: bufio.MaxScanTokenSize,
+	}
+	r.loadHistory()
+	return r
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".benthos_blobl_history")
+}
+
+// loadHistory reads previous entries from the history file, where each entry
+// is a JSON encoded string on its own line so that multiple line mappings are
+// preserved.
+func (r *repl) loadHistory() {
+	if r.histFile == "" {
+		return
+	}
+	histBytes, err := os.ReadFile(r.histFile)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(histBytes), "\n") {
+		var entry string
+		if err := json.Unmarshal([]byte(line), &entry); err == nil && entry != "" {
+			r.history = append(r.history, entry)
+		}
+	}
+	if len(r.history) > replMaxHistory {
+		r.history = r.history[len(r.history)-replMaxHistory:]
+	}
+}
+
+func (r *repl) addHistory(entry string) {
+	r.history = append(r.history, entry)
+	if r.histFile == "" {
+		return
+	}
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(r.histFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		fmt.Fprintf(r.out, red("failed to write history file: %v\n"), err)
+		r.histFile = ""
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(entryBytes, '\n'))
+}
+
+func (r *repl) execute(m string) {
+	exec, err := r.env.NewMapping(m)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			fmt.Fprintf(r.out, "%v %v\n", red("failed to parse mapping:"), perr.ErrorAtPositionStructured("", []rune(m)))
+		} else {
+			fmt.Fprintln(r.out, red(err.Error()))
+		}
+		return
+	}
+	resultStr, err := r.execCache.executeMapping(exec, r.raw, r.pretty, r.input)
+	if err != nil {
+		fmt.Fprintln(r.out, red(fmt.Sprintf("failed to execute map: %v", err)))
+		return
+	}
+	fmt.Fprintln(r.out, resultStr)
+}
+
+// command executes a REPL command and returns true if the REPL should exit.
+func (r *repl) command(line string) bool {
+	cmd, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i > 0 {
+		cmd, arg = line[:i], strings.TrimSpace(line[i:])
+	}
+
+	switch cmd {
+	case ":quit", ":exit", ":q":
+		return true
+	case ":help", ":h":
+		fmt.Fprintln(r.out, replHelp)
+	case ":load":
+		if arg == "" {
+			fmt.Fprintln(r.out, red("a file path is required"))
+			break
+		}
+		inputBytes, err := os.ReadFile(arg)
+		if err != nil {
+			fmt.Fprintln(r.out, red(fmt.Sprintf("failed to read input file: %v", err)))
+			break
+		}
+		r.input = []byte(strings.TrimRight(string(inputBytes), "\r\n"))
+		fmt.Fprintf(r.out, "Loaded %v bytes from %v\n", len(r.input), arg)
+	case ":input":
+		r.input = []byte(arg)
+	case ":show":
+		fmt.Fprintln(r.out, string(r.input))
+	case ":raw":
+		r.raw = !r.raw
+		fmt.Fprintf(r.out, "Raw input: %v\n", r.raw)
+	case ":pretty":
+		r.pretty = !r.pretty
+		fmt.Fprintf(r.out, "Pretty output: %v\n", r.pretty)
+	case ":history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%4d  %v\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+	case ":rerun":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(r.history) {
+			fmt.Fprintln(r.out, red(fmt.Sprintf("history entry '%v' does not exist", arg)))
+			break
+		}
+		entry := r.history[n-1]
+		fmt.Fprintln(r.out, entry)
+		r.addHistory(entry)
+		r.execute(entry)
+	default:
+		fmt.Fprintln(r.out, red(fmt.Sprintf("unrecognised command '%v', use :help to list commands", cmd)))
+	}
+	return false
+}
+
+// run reads mappings and commands from a reader until it is exhausted or a
+// quit command is received.
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, r.maxLineLen)
+
+	var lines []string
+	for {
+		if len(lines) == 0 {
+			fmt.Fprint(r.out, "> ")
+		} else {
+			fmt.Fprint(r.out, "... ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := scanner.Text()
+
+		if len(lines) == 0 {
+			if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, ":") {
+				if r.command(trimmed) {
+					return nil
+				}
+				continue
+			}
+		}
+
+		lines = append(lines, line)
+		m := strings.Join(lines, "\n")
+		if strings.TrimSpace(line) != "" && mappingIncomplete(m) {
+			continue
+		}
+		lines = nil
+
+		if strings.TrimSpace(m) == "" {
+			continue
+		}
+		r.addHistory(m)
+		r.execute(m)
+	}
+	fmt.Fprintln(r.out)
+	return scanner.Err()
+}
+
+// mappingIncomplete returns true if a mapping contains unclosed brackets or
+// strings, in which case it is expected to continue on the next line.
+func mappingIncomplete(m string) bool {
+	var depth int
+	var inQuote, inTripleQuote, inComment bool
+
+	runes := []rune(m)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case inComment:
+			if c == '\n' {
+				inComment = false
+			}
+		case inTripleQuote:
+			if c == '"' && strings.HasPrefix(string(runes[i:]), `"""`) {
+				inTripleQuote = false
+				i += 2
+			}
+		case inQuote:
+			switch c {
+			case '\\':
+				i++
+			case '"', '\n':
+				inQuote = false
+			}
+		case c == '"':
+			if strings.HasPrefix(string(runes[i:]), `"""`) {
+				inTripleQuote = true
+				i += 2
+			} else {
+				inQuote = true
+			}
+		case c == '#':
+			inComment = true
+		case c == '(', c == '[', c == '{':
+			depth++
+		case c == ')', c == ']', c == '}':
+			depth--
+		}
+	}
+	return depth > 0 || inTripleQuote
+}
+
+func runREPL(c *cli.Context) error {
+	histFile := c.String("history-file")
+	if !c.IsSet("history-file") {
+		histFile = defaultHistoryFile()
+	}
+
+	r := newREPL(os.Stdout, histFile)
+	r.raw = c.Bool("raw")
+	r.pretty = c.Bool("pretty")
+	r.maxLineLen = c.Int("max-token-length")
+
+	if inputFile := c.String("input-file"); inputFile != "" {
+		r.command(":load " + inputFile)
+	}
+
+	fmt.Fprintln(r.out, "Bloblang REPL, use :help to list commands and :quit to exit.")
+	return r.run(os.Stdin)
+}
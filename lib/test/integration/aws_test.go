@@ -276,6 +276,56 @@ input:
 		)
 	})
 
+	t.Run("s3_watcher", func(t *testing.T) {
+		template := `
+output:
+  aws_s3:
+    bucket: bucket-$ID
+    endpoint: http://localhost:$PORT
+    force_path_style_urls: true
+    region: eu-west-1
+    path: ${!uuid_v4()}.txt
+    credentials:
+      id: xxxxx
+      secret: xxxxx
+      token: xxxxx
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+
+input:
+  aws_s3:
+    bucket: bucket-$ID
+    endpoint: http://localhost:$PORT
+    force_path_style_urls: true
+    region: eu-west-1
+    credentials:
+      id: xxxxx
+      secret: xxxxx
+      token: xxxxx
+    watcher:
+      enabled: true
+      poll_interval: 100ms
+      cache: objects-memory
+
+resources:
+  caches:
+    objects-memory:
+      memory:
+        ttl: 900
+`
+		integration.StreamTests(
+			integration.StreamTestOpenClose(),
+			integration.StreamTestStreamParallel(50),
+			integration.StreamTestStreamSequential(20),
+		).Run(
+			t, template,
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, testID string, vars *integration.StreamTestConfigVars) {
+				require.NoError(t, createBucketQueue(servicePort, "", testID))
+			}),
+			integration.StreamTestOptPort(servicePort),
+		)
+	})
+
 	t.Run("sqs", func(t *testing.T) {
 		template := `
 output:
@@ -93,3 +93,78 @@ input:
 		)
 	})
 })
+
+var _ = registerIntegrationTest("mqtt_v5", func(t *testing.T) {
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 60
+	resource, err := pool.Run("emqx/emqx", "4.4.3", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		inConf := mqtt.NewClientOptions().SetClientID("UNIT_TEST")
+		inConf = inConf.AddBroker(fmt.Sprintf("tcp://localhost:%v", resource.GetPort("1883/tcp")))
+
+		mIn := mqtt.NewClient(inConf)
+		tok := mIn.Connect()
+		tok.Wait()
+		if cErr := tok.Error(); cErr != nil {
+			return cErr
+		}
+		mIn.Disconnect(0)
+		return nil
+	}))
+
+	template := `
+output:
+  mqtt:
+    urls: [ tcp://localhost:$PORT ]
+    protocol_version: "5"
+    qos: 2
+    topic: topic-$ID
+    client_id: client-output-$ID
+    user_properties:
+      id: $ID
+    max_in_flight: $MAX_IN_FLIGHT
+
+input:
+  mqtt:
+    urls: [ tcp://localhost:$PORT ]
+    protocol_version: "5"
+    qos: 2
+    topics: [ topic-$ID ]
+    shared_subscription_group: "$VAR1"
+    client_id: client-input-$ID
+    clean_session: false
+    session_expiry_interval: 60
+`
+	suite := integration.StreamTests(
+		integration.StreamTestOpenClose(),
+		integration.StreamTestSendBatch(10),
+		integration.StreamTestStreamParallel(1000),
+	)
+	suite.Run(
+		t, template,
+		integration.StreamTestOptSleepAfterInput(100*time.Millisecond),
+		integration.StreamTestOptSleepAfterOutput(100*time.Millisecond),
+		integration.StreamTestOptPort(resource.GetPort("1883/tcp")),
+	)
+	t.Run("with shared subscription", func(t *testing.T) {
+		t.Parallel()
+		suite.Run(
+			t, template,
+			integration.StreamTestOptSleepAfterInput(100*time.Millisecond),
+			integration.StreamTestOptSleepAfterOutput(100*time.Millisecond),
+			integration.StreamTestOptPort(resource.GetPort("1883/tcp")),
+			integration.StreamTestOptMaxInFlight(10),
+			integration.StreamTestOptVarOne("benthos"),
+		)
+	})
+})
@@ -103,4 +103,54 @@ resources:
 			integration.StreamTestOptVarTwo("true"),
 		)
 	})
+
+	t.Run("sftp_atomic_writes", func(t *testing.T) {
+		template := `
+output:
+  sftp:
+    address: localhost:$PORT
+    path: /upload/test-$ID/${!uuid_v4()}.txt
+    credentials:
+      username: foo
+      password: pass
+    codec: $VAR1
+    max_in_flight: 1
+    atomic_writes:
+      enabled: true
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+
+input:
+  sftp:
+    address: localhost:$PORT
+    paths:
+      - /upload/test-$ID/*.txt
+    credentials:
+      username: foo
+      password: pass
+    codec: $VAR1
+    move_on_finish: /upload/test-$ID/done
+    watcher:
+      enabled: true
+      minimum_age: 100ms
+      poll_interval: 100ms
+      cache: files-memory
+
+resources:
+  caches:
+    files-memory:
+      memory:
+        ttl: 900
+`
+		suite := integration.StreamTests(
+			integration.StreamTestOpenClose(),
+			integration.StreamTestStreamParallel(50),
+			integration.StreamTestStreamSequential(20),
+		)
+		suite.Run(
+			t, template,
+			integration.StreamTestOptPort(resource.GetPort("22/tcp")),
+			integration.StreamTestOptVarOne("all-bytes"),
+		)
+	})
 })
@@ -94,6 +94,8 @@ var (
 	ErrPluginNotFound    = errors.New("plugin not found")
 	ErrKeyAlreadyExists  = errors.New("key already exists")
 	ErrKeyNotFound       = errors.New("key does not exist")
+	ErrKeyValueMismatch  = errors.New("key value does not match")
+	ErrNotSupported      = errors.New("operation not supported")
 	ErrPipeNotFound      = errors.New("pipe was not found")
 )
 
@@ -59,6 +59,44 @@ type CacheWithTTL interface {
 	Cache
 }
 
+// CacheWithGetMulti is an optional interface implemented by caches that are
+// able to retrieve multiple keys within as few requests as possible.
+type CacheWithGetMulti interface {
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys. Keys that do not exist are omitted from the resulting map, an
+	// error is returned only if the command fails.
+	GetMulti(keys ...string) (map[string][]byte, error)
+}
+
+// CacheWithGetTTL is an optional interface implemented by caches that are able
+// to report the remaining TTL of an item alongside its value.
+type CacheWithGetTTL interface {
+	// GetWithTTL attempts to locate and return a cached value by its key along
+	// with its remaining TTL. The returned TTL is nil when the item does not
+	// expire or when the remaining TTL cannot be determined.
+	GetWithTTL(key string) ([]byte, *time.Duration, error)
+}
+
+// CacheWithCompareAndSwap is an optional interface implemented by caches that
+// are able to atomically swap the value of a key only when its current value
+// matches an expected value.
+type CacheWithCompareAndSwap interface {
+	// CompareAndSwap attempts to set the value of a key only if its current
+	// value matches old. If old is nil then the key must not already exist,
+	// otherwise ErrKeyAlreadyExists is returned. Returns ErrKeyValueMismatch if
+	// the current value does not match, or ErrKeyNotFound if old is non-nil and
+	// the key does not exist.
+	CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error
+}
+
+// CacheWithGetAndDelete is an optional interface implemented by caches that are
+// able to atomically retrieve and remove a key.
+type CacheWithGetAndDelete interface {
+	// GetAndDelete attempts to locate and return a cached value by its key and
+	// then removes it, returns ErrKeyNotFound if the key does not exist.
+	GetAndDelete(key string) ([]byte, error)
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
@@ -73,6 +111,24 @@ type RateLimit interface {
 	Closable
 }
 
+// RateLimitWithAccessN is an optional interface implemented by rate limits that
+// are able to consume multiple units of access within a single request.
+type RateLimitWithAccessN interface {
+	// AccessN attempts to consume n units of access from the rate limited
+	// resource. Either all units are consumed and a zero duration is returned,
+	// or no units are consumed and a reasonable length of time to wait before
+	// requesting again is returned.
+	AccessN(n int) (time.Duration, error)
+}
+
+// RateLimitWithFeedback is an optional interface implemented by rate limits
+// that adapt their allowed rate according to the responses of the resource they
+// protect, with the same semantics as ratelimit.V2Feedback.
+type RateLimitWithFeedback interface {
+	Throttled(retryAfter time.Duration)
+	Succeeded()
+}
+
 //------------------------------------------------------------------------------
 
 // Condition reads a message, calculates a condition and returns a boolean.
@@ -11,6 +11,14 @@ import (
 func FieldSpecs() docs.FieldSpecs {
 	return docs.FieldSpecs{
 		docs.FieldCommon("function", "The function to invoke."),
+		docs.FieldAdvanced("invocation_type", "The type of invocation to perform. `RequestResponse` invocations wait for the function to return a result, `Event` invocations queue the payload for asynchronous processing and return immediately, and `DryRun` invocations only validate the parameters and permissions of the request. Invocation types other than `RequestResponse` do not yield a result.").HasOptions(
+			"RequestResponse", "Event", "DryRun",
+		).AtVersion("3.64.0"),
+		docs.FieldAdvanced(
+			"client_context",
+			"An optional JSON object to pass to the function as its client context, which is base64 encoded before being sent. The client context is only available to functions invoked with the `RequestResponse` invocation type.",
+			`{"custom":{"topic":"${! meta("kafka_topic") }"}}`,
+		).IsInterpolated().AtVersion("3.64.0"),
 		docs.FieldAdvanced("rate_limit", "An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle invocations by."),
 	}.Merge(session.FieldSpecs()).Add(
 		docs.FieldAdvanced("timeout", "The maximum period of time to wait before abandoning an invocation."),
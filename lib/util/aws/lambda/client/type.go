@@ -2,13 +2,16 @@ package client
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/internal/tracing"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
@@ -22,6 +25,8 @@ import (
 type Config struct {
 	session.Config `json:",inline" yaml:",inline"`
 	Function       string `json:"function" yaml:"function"`
+	InvocationType string `json:"invocation_type" yaml:"invocation_type"`
+	ClientContext  string `json:"client_context" yaml:"client_context"`
 	Timeout        string `json:"timeout" yaml:"timeout"`
 	NumRetries     int    `json:"retries" yaml:"retries"`
 	RateLimit      string `json:"rate_limit" yaml:"rate_limit"`
@@ -30,11 +35,13 @@ type Config struct {
 // NewConfig returns a Config with default values.
 func NewConfig() Config {
 	return Config{
-		Config:     session.NewConfig(),
-		Function:   "",
-		Timeout:    "5s",
-		NumRetries: 3,
-		RateLimit:  "",
+		Config:         session.NewConfig(),
+		Function:       "",
+		InvocationType: lambda.InvocationTypeRequestResponse,
+		ClientContext:  "",
+		Timeout:        "5s",
+		NumRetries:     3,
+		RateLimit:      "",
 	}
 }
 
@@ -49,7 +56,8 @@ type Type struct {
 	stats metrics.Type
 	mgr   types.Manager
 
-	timeout time.Duration
+	timeout       time.Duration
+	clientContext *field.Expression
 
 	mCount    metrics.StatCounter
 	mErr      metrics.StatCounter
@@ -80,10 +88,25 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		return nil, errors.New("lambda function must not be empty")
 	}
 
+	switch l.conf.InvocationType {
+	case "":
+		l.conf.InvocationType = lambda.InvocationTypeRequestResponse
+	case lambda.InvocationTypeRequestResponse, lambda.InvocationTypeEvent, lambda.InvocationTypeDryRun:
+	default:
+		return nil, fmt.Errorf("invocation type %v not recognised, try RequestResponse, Event or DryRun", conf.InvocationType)
+	}
+
 	for _, opt := range opts {
 		opt(&l)
 	}
 
+	if conf.ClientContext != "" {
+		var err error
+		if l.clientContext, err = interop.NewBloblangField(l.mgr, conf.ClientContext); err != nil {
+			return nil, fmt.Errorf("failed to parse client_context expression: %v", err)
+		}
+	}
+
 	l.mCount = l.stats.GetCounter("count")
 	l.mSucc = l.stats.GetCounter("success")
 	l.mErr = l.stats.GetCounter("error")
@@ -183,34 +206,75 @@ func (l *Type) Invoke(msg types.Message) (types.Message, error) {
 	return response, nil
 }
 
+// InvocationType returns the type of invocation performed by the client.
+func (l *Type) InvocationType() string {
+	return l.conf.InvocationType
+}
+
+// ClientContext returns the client context of an invocation for the message at
+// an index of a batch, or an empty string when a client context has not been
+// configured.
+func (l *Type) ClientContext(index int, msg types.Message) string {
+	if l.clientContext == nil {
+		return ""
+	}
+	return l.clientContext.String(index, msg)
+}
+
 // InvokeV2 attempts to invoke a lambda function with a message and replaces
-// its contents with the result on success, or returns an error.
+// its contents with the result on success, or returns an error. The contents
+// of the message are left unchanged for invocation types other than
+// RequestResponse, as they do not yield a result.
 func (l *Type) InvokeV2(p types.Part) error {
 	l.mCount.Incr(1)
 
+	msg := message.New(nil)
+	msg.Append(p)
+
+	result, err := l.InvokeRaw(context.Background(), p.Get(), l.ClientContext(0, msg))
+	if err != nil {
+		return err
+	}
+	if result.FunctionError != nil {
+		p.Metadata().Set("lambda_function_error", *result.FunctionError)
+	}
+	if l.conf.InvocationType == lambda.InvocationTypeRequestResponse {
+		p.Set(result.Payload)
+	}
+	return nil
+}
+
+// InvokeRaw attempts to invoke a lambda function with a raw payload and an
+// optional client context, which is base64 encoded by the client. Failed
+// invocations are retried according to the configured number of retries, and
+// the output of the invocation is returned on success. Function errors are not
+// considered failed invocations and are reported by the output instead.
+func (l *Type) InvokeRaw(ctx context.Context, payload []byte, clientContext string) (*lambda.InvokeOutput, error) {
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(l.conf.Function),
+		InvocationType: aws.String(l.conf.InvocationType),
+		Payload:        payload,
+	}
+	if clientContext != "" {
+		input.ClientContext = aws.String(base64.StdEncoding.EncodeToString([]byte(clientContext)))
+	}
+
 	remainingRetries := l.conf.NumRetries
 	for {
 		l.waitForAccess()
 
-		ctx, done := context.WithTimeout(context.Background(), l.timeout)
-		result, err := l.lambda.InvokeWithContext(ctx, &lambda.InvokeInput{
-			FunctionName: aws.String(l.conf.Function),
-			Payload:      p.Get(),
-		})
+		ictx, done := context.WithTimeout(ctx, l.timeout)
+		result, err := l.lambda.InvokeWithContext(ictx, input)
 		done()
 		if err == nil {
-			if result.FunctionError != nil {
-				p.Metadata().Set("lambda_function_error", *result.FunctionError)
-			}
 			l.mSucc.Incr(1)
-			p.Set(result.Payload)
-			return nil
+			return result, nil
 		}
 
 		l.mErr.Incr(1)
 		remainingRetries--
-		if remainingRetries < 0 {
-			return err
+		if remainingRetries < 0 || ctx.Err() != nil {
+			return nil, err
 		}
 	}
 }
@@ -12,8 +12,15 @@ func FieldSpecs() docs.FieldSpecs {
 			docs.FieldAdvanced("id", "The ID of credentials to use."),
 			docs.FieldAdvanced("secret", "The secret for the credentials being used."),
 			docs.FieldAdvanced("token", "The token for the credentials being used, required when using short term credentials."),
+			docs.FieldString("web_identity_token_file", "A path to a web identity token file, such as a Kubernetes service account token, which is exchanged for credentials of the role specified with `role`. When running within EKS with IAM roles for service accounts it is not necessary to set this field as the token file is detected from the environment.").Advanced().AtVersion("3.64.0").HasDefault(""),
 			docs.FieldAdvanced("role", "A role ARN to assume."),
 			docs.FieldAdvanced("role_external_id", "An external ID to provide when assuming a role."),
+			docs.FieldAdvanced("role_chain", "A list of roles to assume in order after `role`, where each role is assumed with the credentials of the previous one.").Array().WithChildren(
+				docs.FieldString("role", "A role ARN to assume.").HasDefault(""),
+				docs.FieldString("role_external_id", "An external ID to provide when assuming the role.").HasDefault(""),
+			).AtVersion("3.64.0").HasDefault([]interface{}{}),
+			docs.FieldString("sts_endpoint", "Allows you to specify a custom endpoint for STS requests made in order to obtain credentials.").Advanced().AtVersion("3.64.0").HasDefault(""),
+			docs.FieldBool("sts_regional_endpoint", "Whether STS requests made in order to obtain credentials should target the endpoint of the configured region rather than the global endpoint.").Advanced().AtVersion("3.64.0").HasDefault(false),
 		),
 	}
 }
@@ -1,24 +1,37 @@
 package session
 
 import (
+	"errors"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 //------------------------------------------------------------------------------
 
-// CredentialsConfig contains configuration params for AWS credentials.
-type CredentialsConfig struct {
-	Profile    string `json:"profile" yaml:"profile"`
-	ID         string `json:"id" yaml:"id"`
-	Secret     string `json:"secret" yaml:"secret"`
-	Token      string `json:"token" yaml:"token"`
+// RoleConfig contains configuration params for a role to assume.
+type RoleConfig struct {
 	Role       string `json:"role" yaml:"role"`
 	ExternalID string `json:"role_external_id" yaml:"role_external_id"`
 }
 
+// CredentialsConfig contains configuration params for AWS credentials.
+type CredentialsConfig struct {
+	Profile              string       `json:"profile" yaml:"profile"`
+	ID                   string       `json:"id" yaml:"id"`
+	Secret               string       `json:"secret" yaml:"secret"`
+	Token                string       `json:"token" yaml:"token"`
+	WebIdentityTokenFile string       `json:"web_identity_token_file" yaml:"web_identity_token_file"`
+	Role                 string       `json:"role" yaml:"role"`
+	ExternalID           string       `json:"role_external_id" yaml:"role_external_id"`
+	RoleChain            []RoleConfig `json:"role_chain" yaml:"role_chain"`
+	STSEndpoint          string       `json:"sts_endpoint" yaml:"sts_endpoint"`
+	STSRegionalEndpoint  bool         `json:"sts_regional_endpoint" yaml:"sts_regional_endpoint"`
+}
+
 // Config contains configuration fields for an AWS session. This config is
 // common across any AWS components.
 type Config struct {
@@ -31,12 +44,16 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		Credentials: CredentialsConfig{
-			Profile:    "",
-			ID:         "",
-			Secret:     "",
-			Token:      "",
-			Role:       "",
-			ExternalID: "",
+			Profile:              "",
+			ID:                   "",
+			Secret:               "",
+			Token:                "",
+			WebIdentityTokenFile: "",
+			Role:                 "",
+			ExternalID:           "",
+			RoleChain:            []RoleConfig{},
+			STSEndpoint:          "",
+			STSRegionalEndpoint:  false,
 		},
 		Endpoint: "",
 		Region:   "eu-west-1", // TODO: V4 empty by default
@@ -56,6 +73,10 @@ func (c Config) GetSession(opts ...func(*aws.Config)) (*session.Session, error)
 		awsConf = awsConf.WithEndpoint(c.Endpoint)
 	}
 
+	if c.Credentials.STSRegionalEndpoint {
+		awsConf.STSRegionalEndpoint = endpoints.RegionalSTSEndpoint
+	}
+
 	if len(c.Credentials.Profile) > 0 {
 		awsConf = awsConf.WithCredentials(credentials.NewSharedCredentials(
 			"", c.Credentials.Profile,
@@ -77,21 +98,69 @@ func (c Config) GetSession(opts ...func(*aws.Config)) (*session.Session, error)
 		return nil, err
 	}
 
-	if len(c.Credentials.Role) > 0 {
-		var opts []func(*stscreds.AssumeRoleProvider)
-		if len(c.Credentials.ExternalID) > 0 {
-			opts = []func(*stscreds.AssumeRoleProvider){
-				func(p *stscreds.AssumeRoleProvider) {
-					p.ExternalID = &c.Credentials.ExternalID
-				},
-			}
+	creds, err := c.Credentials.assumeRoles(sess)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		sess.Config = sess.Config.WithCredentials(creds)
+	}
+	return sess, nil
+}
+
+// assumeRoles returns the credentials obtained by assuming the configured
+// roles in order, starting with the credentials of the session and using the
+// credentials of each role to assume the next. Returns nil if there are no
+// roles to assume.
+func (c CredentialsConfig) assumeRoles(sess *session.Session) (*credentials.Credentials, error) {
+	var creds *credentials.Credentials
+	if len(c.WebIdentityTokenFile) > 0 {
+		if len(c.Role) == 0 {
+			return nil, errors.New("a role must be specified in order to use a web identity token file")
 		}
-		sess.Config = sess.Config.WithCredentials(
-			stscreds.NewCredentials(sess, c.Credentials.Role, opts...),
+		creds = stscreds.NewWebIdentityCredentials(
+			c.stsSession(sess, nil), c.Role, "", c.WebIdentityTokenFile,
 		)
+	} else if len(c.Role) > 0 {
+		creds = assumeRole(c.stsSession(sess, nil), c.Role, c.ExternalID)
 	}
 
-	return sess, nil
+	for _, r := range c.RoleChain {
+		if len(r.Role) == 0 {
+			return nil, errors.New("each role of a role chain must specify a role ARN")
+		}
+		creds = assumeRole(c.stsSession(sess, creds), r.Role, r.ExternalID)
+	}
+	return creds, nil
+}
+
+// stsSession returns the session used for STS requests when obtaining
+// credentials, which targets the STS endpoint when one is configured and uses
+// the credentials of a previously assumed role when provided.
+func (c CredentialsConfig) stsSession(sess *session.Session, creds *credentials.Credentials) *session.Session {
+	if len(c.STSEndpoint) == 0 && creds == nil {
+		return sess
+	}
+	conf := aws.NewConfig()
+	if len(c.STSEndpoint) > 0 {
+		conf = conf.WithEndpoint(c.STSEndpoint)
+	}
+	if creds != nil {
+		conf = conf.WithCredentials(creds)
+	}
+	return sess.Copy(conf)
+}
+
+func assumeRole(sess *session.Session, role, externalID string) *credentials.Credentials {
+	var opts []func(*stscreds.AssumeRoleProvider)
+	if len(externalID) > 0 {
+		opts = []func(*stscreds.AssumeRoleProvider){
+			func(p *stscreds.AssumeRoleProvider) {
+				p.ExternalID = &externalID
+			},
+		}
+	}
+	return stscreds.NewCredentials(sess, role, opts...)
 }
 
 //------------------------------------------------------------------------------
@@ -0,0 +1,14 @@
+package credentials
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// FieldSpecs returns documentation specs for GCP credentials fields.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldString("credentials_json", "The contents of a service account key file, or an external account configuration file for [workload identity federation](https://cloud.google.com/iam/docs/workload-identity-federation), in JSON format to authenticate with. More information can be found [in this document](/docs/guides/cloud/gcp).").Advanced().AtVersion("3.64.0").HasDefault(""),
+		docs.FieldString("credentials_file", "The path of a service account key file, or an external account configuration file for [workload identity federation](https://cloud.google.com/iam/docs/workload-identity-federation), in JSON format to authenticate with.").Advanced().AtVersion("3.64.0").HasDefault(""),
+		docs.FieldString("impersonate_service_account", "The email of a service account to impersonate, where short lived credentials of the service account are obtained with the configured credentials.", "foo@bar.iam.gserviceaccount.com").Advanced().AtVersion("3.64.0").HasDefault(""),
+		docs.FieldString("impersonate_delegates", "An optional chain of service accounts that each have permission to impersonate the next, ending with `impersonate_service_account`.").Array().Advanced().AtVersion("3.64.0").HasDefault([]interface{}{}),
+		docs.FieldString("emulator_endpoint", "The endpoint of an emulator to target instead of GCP, in which case requests are made without authentication and gRPC connections are made without TLS.", "localhost:8085", "http://localhost:4443/storage/v1/").Advanced().AtVersion("3.64.0").HasDefault(""),
+	}
+}
@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration fields for the credentials and endpoint used by
+// the client of a GCP service. This config is common across any GCP
+// components.
+type Config struct {
+	CredentialsJSON           string   `json:"credentials_json" yaml:"credentials_json"`
+	CredentialsFile           string   `json:"credentials_file" yaml:"credentials_file"`
+	ImpersonateServiceAccount string   `json:"impersonate_service_account" yaml:"impersonate_service_account"`
+	ImpersonateDelegates      []string `json:"impersonate_delegates" yaml:"impersonate_delegates"`
+	EmulatorEndpoint          string   `json:"emulator_endpoint" yaml:"emulator_endpoint"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		CredentialsJSON:           "",
+		CredentialsFile:           "",
+		ImpersonateServiceAccount: "",
+		ImpersonateDelegates:      []string{},
+		EmulatorEndpoint:          "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ClientOptions returns the options that apply the configured credentials and
+// endpoint to a client, which can be provided to the constructors of GCP client
+// libraries. When no credentials are configured the application default
+// credentials of the environment are used, which includes external account
+// credentials for workload identity federation.
+//
+// The context is used for requests made in order to impersonate a service
+// account and should therefore only be cancelled once the client is no longer
+// used.
+func (c Config) ClientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	if len(c.EmulatorEndpoint) > 0 {
+		return []option.ClientOption{
+			option.WithEndpoint(c.EmulatorEndpoint),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+		}, nil
+	}
+
+	var opts []option.ClientOption
+	switch {
+	case len(c.CredentialsJSON) > 0 && len(c.CredentialsFile) > 0:
+		return nil, errors.New("only one of credentials_json and credentials_file can be set")
+	case len(c.CredentialsJSON) > 0:
+		opts = append(opts, option.WithCredentialsJSON([]byte(c.CredentialsJSON)))
+	case len(c.CredentialsFile) > 0:
+		opts = append(opts, option.WithCredentialsFile(c.CredentialsFile))
+	}
+
+	if len(c.ImpersonateServiceAccount) > 0 {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: c.ImpersonateServiceAccount,
+			Scopes:          []string{cloudPlatformScope},
+			Delegates:       c.ImpersonateDelegates,
+		}, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to impersonate service account: %w", err)
+		}
+		opts = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+	return opts, nil
+}
+
+//------------------------------------------------------------------------------
@@ -48,18 +48,22 @@ func oAuthFieldSpec() docs.FieldSpec {
 
 func oAuth2FieldSpec() docs.FieldSpec {
 	return docs.FieldAdvanced("oauth2",
-		"Allows you to specify open authentication via OAuth version 2 using the client credentials token flow.",
+		"Allows you to specify open authentication via OAuth version 2 using the client credentials or JWT bearer token flows. Tokens are cached and requested again once they expire, and token requests use the same TLS and proxy settings as the component.",
 	).WithChildren(
 		docs.FieldCommon(
 			"enabled", "Whether to use OAuth version 2 in requests.",
 		).HasType(docs.FieldTypeBool).HasDefault(false),
 
+		docs.FieldAdvanced(
+			"grant_type", "The flow used to obtain tokens, where `client_credentials` authenticates with the client key and secret, and `jwt_bearer` authenticates with a JWT assertion signed by the private key of `jwt_bearer.private_key_file`.",
+		).HasOptions("client_credentials", "jwt_bearer").AtVersion("3.64.0").HasType(docs.FieldTypeString).HasDefault("client_credentials"),
+
 		docs.FieldString(
-			"client_key", "A value used to identify the client to the token provider.",
+			"client_key", "A value used to identify the client to the token provider. When using the `jwt_bearer` grant type this is the issuer of the assertion.",
 		).HasDefault(""),
 
 		docs.FieldString(
-			"client_secret", "A secret used to establish ownership of the client key.",
+			"client_secret", "A secret used to establish ownership of the client key. Not used by the `jwt_bearer` grant type.",
 		).HasDefault(""),
 
 		docs.FieldString(
@@ -69,6 +73,26 @@ func oAuth2FieldSpec() docs.FieldSpec {
 		docs.FieldAdvanced(
 			"scopes", "A list of optional requested permissions.",
 		).Array().AtVersion("3.45.0").HasType(docs.FieldTypeString),
+
+		docs.FieldAdvanced(
+			"jwt_bearer", "Configures the JWT assertion used by the `jwt_bearer` grant type, which is signed with RS256.",
+		).WithChildren(
+			docs.FieldString(
+				"private_key_file", "A file with the PEM encoded via PKCS1 or PKCS8 as private key.",
+			).HasDefault(""),
+
+			docs.FieldString(
+				"subject", "An optional subject of the assertion, such as a user to impersonate.",
+			).HasDefault(""),
+
+			docs.FieldString(
+				"audience", "An optional audience of the assertion. When empty the `token_url` is used.",
+			).HasDefault(""),
+
+			docs.FieldAdvanced(
+				"claims", "Optional private claims to add to the assertion.",
+			).Map().HasType(docs.FieldTypeUnknown).HasDefault(map[string]interface{}{}),
+		).AtVersion("3.64.0"),
 	)
 }
 
@@ -2,36 +2,61 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"os"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/oauth2/jwt"
 )
 
 //------------------------------------------------------------------------------
 
 // OAuth2Config holds the configuration parameters for an OAuth2 exchange.
 type OAuth2Config struct {
-	Enabled      bool     `json:"enabled" yaml:"enabled"`
-	ClientKey    string   `json:"client_key" yaml:"client_key"`
-	ClientSecret string   `json:"client_secret" yaml:"client_secret"`
-	TokenURL     string   `json:"token_url" yaml:"token_url"`
-	Scopes       []string `json:"scopes" yaml:"scopes"`
+	Enabled      bool                  `json:"enabled" yaml:"enabled"`
+	GrantType    string                `json:"grant_type" yaml:"grant_type"`
+	ClientKey    string                `json:"client_key" yaml:"client_key"`
+	ClientSecret string                `json:"client_secret" yaml:"client_secret"`
+	TokenURL     string                `json:"token_url" yaml:"token_url"`
+	Scopes       []string              `json:"scopes" yaml:"scopes"`
+	JWTBearer    OAuth2JWTBearerConfig `json:"jwt_bearer" yaml:"jwt_bearer"`
+}
+
+// OAuth2JWTBearerConfig holds the configuration parameters for obtaining
+// OAuth2 tokens with a signed JWT assertion.
+type OAuth2JWTBearerConfig struct {
+	PrivateKeyFile string                 `json:"private_key_file" yaml:"private_key_file"`
+	Subject        string                 `json:"subject" yaml:"subject"`
+	Audience       string                 `json:"audience" yaml:"audience"`
+	Claims         map[string]interface{} `json:"claims" yaml:"claims"`
 }
 
 // NewOAuth2Config returns a new OAuth2Config with default values.
 func NewOAuth2Config() OAuth2Config {
 	return OAuth2Config{
 		Enabled:      false,
+		GrantType:    "client_credentials",
 		ClientKey:    "",
 		ClientSecret: "",
 		TokenURL:     "",
 		Scopes:       []string{},
+		JWTBearer: OAuth2JWTBearerConfig{
+			PrivateKeyFile: "",
+			Subject:        "",
+			Audience:       "",
+			Claims:         map[string]interface{}{},
+		},
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // Client returns an http.Client with OAuth2 configured.
+//
+// Deprecated: Use WrapClient instead, which supports all grant types and
+// obtains tokens with the TLS and proxy settings of the wrapped client.
 func (oauth OAuth2Config) Client(ctx context.Context) *http.Client {
 	if !oauth.Enabled {
 		var client http.Client
@@ -47,3 +72,52 @@ func (oauth OAuth2Config) Client(ctx context.Context) *http.Client {
 
 	return conf.Client(ctx)
 }
+
+// WrapClient returns an http.Client that adds OAuth2 tokens to requests made
+// with the provided client, or the provided client itself when OAuth2 is
+// disabled. Tokens are cached and only requested again once they expire, and
+// token requests are made with the provided client and therefore share its
+// timeout, TLS and proxy settings.
+//
+// The context is used for all token requests and should therefore be cancelled
+// once the client is no longer used.
+func (oauth OAuth2Config) WrapClient(ctx context.Context, client *http.Client) (*http.Client, error) {
+	if !oauth.Enabled {
+		return client, nil
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+
+	var source oauth2.TokenSource
+	switch oauth.GrantType {
+	case "client_credentials", "":
+		conf := &clientcredentials.Config{
+			ClientID:     oauth.ClientKey,
+			ClientSecret: oauth.ClientSecret,
+			TokenURL:     oauth.TokenURL,
+			Scopes:       oauth.Scopes,
+		}
+		source = conf.TokenSource(ctx)
+	case "jwt_bearer":
+		privateKey, err := os.ReadFile(oauth.JWTBearer.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %v", err)
+		}
+		conf := &jwt.Config{
+			Email:         oauth.ClientKey,
+			PrivateKey:    privateKey,
+			Subject:       oauth.JWTBearer.Subject,
+			Scopes:        oauth.Scopes,
+			TokenURL:      oauth.TokenURL,
+			Audience:      oauth.JWTBearer.Audience,
+			PrivateClaims: oauth.JWTBearer.Claims,
+		}
+		source = conf.TokenSource(ctx)
+	default:
+		return nil, fmt.Errorf("oauth2 grant type %v not recognised, try client_credentials or jwt_bearer", oauth.GrantType)
+	}
+
+	wrapped := oauth2.NewClient(ctx, source)
+	wrapped.Timeout = client.Timeout
+	return wrapped, nil
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+// PaginationConfig contains fields for following the pages of responses from
+// paginated APIs.
+type PaginationConfig struct {
+	NextURL  string `json:"next_url" yaml:"next_url"`
+	Items    string `json:"items" yaml:"items"`
+	MaxPages int    `json:"max_pages" yaml:"max_pages"`
+}
+
+// NewPaginationConfig creates a new PaginationConfig with default values.
+func NewPaginationConfig() PaginationConfig {
+	return PaginationConfig{
+		NextURL:  "",
+		Items:    "",
+		MaxPages: 0,
+	}
+}
+
+// PaginationFieldSpec returns the field spec for a pagination config.
+func PaginationFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("pagination", "Allows you to follow the pages of responses from paginated APIs, where each page is requested from a URL obtained from the previous page until a page without a next URL is reached.").WithChildren(
+		docs.FieldBloblang(
+			"next_url", "An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on each page in order to obtain the URL of the next page. When the mapping results in an empty string, `null` or is deleted the pagination is exhausted. When empty pagination is disabled.",
+			`root = if this.next_cursor != null { "https://api.example.com/things?cursor=" + this.next_cursor.escape_url_query() }`,
+			`root = meta("http_link_next")`,
+			`root = if this.items.length() > 0 { "https://api.example.com/things?offset=%v".format((meta("http_page").number() + 1) * 100) }`,
+		).HasDefault(""),
+		docs.FieldBloblang(
+			"items", "An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on each page, which must result in an array of items that are emitted as individual messages rather than emitting the page as a single message.",
+			`root = this.items`,
+		).HasDefault(""),
+		docs.FieldInt("max_pages", "The maximum number of pages to follow, where zero means unlimited.").HasDefault(0),
+	).AtVersion("3.64.0")
+}
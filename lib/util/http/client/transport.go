@@ -0,0 +1,18 @@
+package client
+
+// TransportConfig contains fields for tuning the connections of an HTTP
+// client.
+type TransportConfig struct {
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host"`
+	HTTP2               bool   `json:"http2" yaml:"http2"`
+	DNSRefreshInterval  string `json:"dns_refresh_interval" yaml:"dns_refresh_interval"`
+}
+
+// NewTransportConfig creates a new TransportConfig with default values.
+func NewTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConnsPerHost: 0,
+		HTTP2:               true,
+		DNSRefreshInterval:  "",
+	}
+}
@@ -49,6 +49,8 @@ type Config struct {
 	SuccessfulOn        []int                        `json:"successful_on" yaml:"successful_on"`
 	TLS                 tls.Config                   `json:"tls" yaml:"tls"`
 	ProxyURL            string                       `json:"proxy_url" yaml:"proxy_url"`
+	Transport           TransportConfig              `json:"transport" yaml:"transport"`
+	HedgeAfter          string                       `json:"hedge_after" yaml:"hedge_after"`
 	auth.Config         `json:",inline" yaml:",inline"`
 	OAuth2              auth.OAuth2Config `json:"oauth2" yaml:"oauth2"`
 }
@@ -72,6 +74,8 @@ func NewConfig() Config {
 		DropOn:              []int{},
 		SuccessfulOn:        []int{},
 		TLS:                 tls.NewConfig(),
+		Transport:           NewTransportConfig(),
+		HedgeAfter:          "",
 		Config:              auth.NewConfig(),
 		OAuth2:              auth.NewOAuth2Config(),
 	}
@@ -137,7 +141,7 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		host:      nil,
 	}
 	h.ctx, h.done = context.WithCancel(context.Background())
-	h.client = conf.OAuth2.Client(h.ctx)
+	h.client = &http.Client{}
 
 	if tout := conf.Timeout; len(tout) > 0 {
 		var err error
@@ -197,6 +201,9 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	}
 
 	var err error
+	if h.client, err = conf.OAuth2.WrapClient(h.ctx, h.client); err != nil {
+		return nil, fmt.Errorf("failed to configure oauth2: %w", err)
+	}
 	if h.url, err = interop.NewBloblangField(h.mgr, conf.URL); err != nil {
 		return nil, fmt.Errorf("failed to parse URL expression: %v", err)
 	}
@@ -1,6 +1,9 @@
 package bloblang
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -106,3 +109,78 @@ func TestEnvironmentDisabledImports(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "imports are disabled in this context")
 }
+
+func TestEnvironmentV2StructuredParams(t *testing.T) {
+	env := NewEnvironment()
+
+	require.NoError(t, env.RegisterFunctionV2("render_each", NewPluginSpec().
+		Param(NewArrayParam("values")).
+		Param(NewQueryParam("template")).
+		Param(NewObjectParam("defaults").Default(map[string]interface{}{})),
+		func(args *ParsedParams) (Function, error) {
+			values, err := args.GetArray("values")
+			if err != nil {
+				return nil, err
+			}
+			template, err := args.GetQuery("template")
+			if err != nil {
+				return nil, err
+			}
+			defaults, err := args.GetObject("defaults")
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) {
+				var res []interface{}
+				for _, v := range values {
+					if d, exists := defaults[v.(string)]; exists {
+						v = d
+					}
+					rendered, err := template.Exec(v)
+					if err != nil {
+						return nil, err
+					}
+					res = append(res, rendered)
+				}
+				return res, nil
+			}, nil
+		}))
+
+	require.NoError(t, env.RegisterFunctionV2("join_all", NewPluginSpec().Variadic(),
+		func(args *ParsedParams) (Function, error) {
+			var strs []string
+			for _, v := range args.GetVariadic() {
+				strs = append(strs, fmt.Sprintf("%v", v))
+			}
+			return func() (interface{}, error) {
+				return strings.Join(strs, ","), nil
+			}, nil
+		}))
+
+	assert.Error(t, env.RegisterFunctionV2("nope", NewPluginSpec().Variadic().Param(NewStringParam("foo")),
+		func(args *ParsedParams) (Function, error) {
+			return nil, errors.New("nope")
+		}))
+
+	exe, err := env.Parse(`root.a = render_each(["foo", "bar"], this.uppercase())
+root.b = render_each(values: ["foo", "bar"], template: v -> v + "!", defaults: {"bar": "baz"})
+root.c = render_each(["foo"], "static")
+root.d = join_all("a", 5, this.c)`)
+	require.NoError(t, err)
+
+	v, err := exe.Query(map[string]interface{}{"c": true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{"FOO", "BAR"},
+		"b": []interface{}{"foo!", "baz!"},
+		"c": []interface{}{"static"},
+		"d": "a,5,true",
+	}, v)
+
+	_, err = env.Parse(`root = render_each({"foo":"bar"}, this)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong argument type, expected array, got object")
+
+	_, err = env.Parse(`root = join_all(a: "foo")`)
+	require.Error(t, err)
+}
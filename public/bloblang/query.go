@@ -0,0 +1,24 @@
+package bloblang
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+)
+
+// Query is a lazily evaluated argument of a function or method plugin, which is
+// obtained from a parameter defined with NewQueryParam.
+type Query struct {
+	fn query.Function
+}
+
+// Exec executes the query against a value, which is referenced within the query
+// by the keyword `this`, and returns the result.
+//
+// The query is executed in isolation from the mapping that it is a part of, and
+// therefore cannot reference variables or the contents and metadata of the
+// message being mapped.
+func (q *Query) Exec(v interface{}) (interface{}, error) {
+	return q.fn.Exec(query.FunctionContext{
+		Maps: map[string]query.Function{},
+		Vars: map[string]interface{}{},
+	}.WithValue(v))
+}
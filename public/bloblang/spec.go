@@ -51,6 +51,34 @@ func NewAnyParam(name string) ParamDefinition {
 	}
 }
 
+// NewArrayParam creates a new array typed parameter. Parameter names must match
+// the regular expression /^[a-z0-9]+(_[a-z0-9]+)*$/ (snake case).
+func NewArrayParam(name string) ParamDefinition {
+	return ParamDefinition{
+		def: query.ParamArray(name, ""),
+	}
+}
+
+// NewObjectParam creates a new object typed parameter. Parameter names must
+// match the regular expression /^[a-z0-9]+(_[a-z0-9]+)*$/ (snake case).
+func NewObjectParam(name string) ParamDefinition {
+	return ParamDefinition{
+		def: query.ParamObject(name, ""),
+	}
+}
+
+// NewQueryParam creates a new query typed parameter, where the argument is not
+// resolved when the plugin is instantiated but is instead provided as a Query
+// that the plugin can execute lazily, any number of times, against values of
+// its choosing. Arguments that are not queries, such as scalar literals, are
+// accepted and always result in the same value. Parameter names must match the
+// regular expression /^[a-z0-9]+(_[a-z0-9]+)*$/ (snake case).
+func NewQueryParam(name string) ParamDefinition {
+	return ParamDefinition{
+		def: query.ParamQuery(name, "", true),
+	}
+}
+
 // Description adds an optional description to the parameter definition, this is
 // used when generating documentation for the parameter to describe what the
 // parameter is for.
@@ -137,6 +165,15 @@ func (p *PluginSpec) Param(def ParamDefinition) *PluginSpec {
 	return p
 }
 
+// Variadic marks the spec as accepting any number of nameless arguments of any
+// type, which can be accessed from the parsed params with the method
+// GetVariadic. A variadic spec cannot also have parameters added to it, and
+// cannot be instantiated with named arguments.
+func (p *PluginSpec) Variadic() *PluginSpec {
+	p.params.Variadic = true
+	return p
+}
+
 // EncodeJSON attempts to parse a JSON object as a byte slice and uses it to
 // populate the configuration spec. The schema of this method is undocumented
 // and is not intended for general use.
@@ -213,3 +250,50 @@ func (p *ParsedParams) GetBool(name string) (bool, error) {
 func (p *ParsedParams) GetOptionalBool(name string) (*bool, error) {
 	return p.par.FieldOptionalBool(name)
 }
+
+// GetArray returns an array argument value with a given name.
+func (p *ParsedParams) GetArray(name string) ([]interface{}, error) {
+	return p.par.FieldArray(name)
+}
+
+// GetOptionalArray returns an array argument value with a given name if it was
+// defined, otherwise nil.
+func (p *ParsedParams) GetOptionalArray(name string) (*[]interface{}, error) {
+	return p.par.FieldOptionalArray(name)
+}
+
+// GetObject returns an object argument value with a given name.
+func (p *ParsedParams) GetObject(name string) (map[string]interface{}, error) {
+	return p.par.FieldObject(name)
+}
+
+// GetOptionalObject returns an object argument value with a given name if it
+// was defined, otherwise nil.
+func (p *ParsedParams) GetOptionalObject(name string) (*map[string]interface{}, error) {
+	return p.par.FieldOptionalObject(name)
+}
+
+// GetQuery returns a query argument value with a given name.
+func (p *ParsedParams) GetQuery(name string) (*Query, error) {
+	fn, err := p.par.FieldQuery(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{fn: fn}, nil
+}
+
+// GetOptionalQuery returns a query argument value with a given name if it was
+// defined, otherwise nil.
+func (p *ParsedParams) GetOptionalQuery(name string) (*Query, error) {
+	fn, err := p.par.FieldOptionalQuery(name)
+	if err != nil || fn == nil {
+		return nil, err
+	}
+	return &Query{fn: fn}, nil
+}
+
+// GetVariadic returns the arguments of a plugin with a variadic spec in the
+// order that they were provided.
+func (p *ParsedParams) GetVariadic() []interface{} {
+	return p.par.Raw()
+}
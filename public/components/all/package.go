@@ -9,17 +9,33 @@ import (
 
 	// Import new service packages.
 	_ "github.com/Jeffail/benthos/v3/internal/impl/aws"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/azure"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/clickhouse"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/confluent"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/csv"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/datadog"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/deltalake"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/gcp"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/generic"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/grpc"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/iceberg"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/jsonpatch"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/kafka"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/loki"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/maxmind"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/mongodb"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/msgpack"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/nats"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/opensearch"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/otlp"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/parquet"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/prometheus"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/pulsar"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/redis"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/splunk"
 	_ "github.com/Jeffail/benthos/v3/internal/impl/sql"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/syslog"
+	_ "github.com/Jeffail/benthos/v3/internal/impl/useragent"
 	"github.com/Jeffail/benthos/v3/internal/template"
 
 	// Import all (supported) sql drivers
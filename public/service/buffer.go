@@ -52,6 +52,12 @@ type BatchBuffer interface {
 	// of the buffer is temporal (a windowing algorithm, etc) it might be
 	// considered correct to simply drop message batches that are not acked.
 	//
+	// The acknowledge function is called with a nil error when the batch was
+	// delivered successfully, or with the error that caused it to be rejected
+	// otherwise. Buffers that preserve delivery guarantees, such as a
+	// replicated log, should only remove a batch once it has been acked with a
+	// nil error, and should make a rejected batch available to be read again.
+	//
 	// When the buffer is closed (EndOfInput has been called and no more
 	// messages are available) this method should return an ErrEndOfBuffer in
 	// order to indicate the end of the buffered stream.
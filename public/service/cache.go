@@ -15,6 +15,7 @@ import (
 var (
 	ErrKeyAlreadyExists = errors.New("key already exists")
 	ErrKeyNotFound      = errors.New("key does not exist")
+	ErrKeyValueMismatch = errors.New("key value does not match")
 )
 
 // Cache is an interface implemented by Benthos caches.
@@ -56,19 +57,56 @@ type batchedCache interface {
 	SetMulti(ctx context.Context, keyValues ...CacheItem) error
 }
 
+// batchedGetCache represents a cache where the underlying implementation is
+// able to benefit from batched get requests. This interface is optional for
+// caches and when implemented will automatically be utilised where possible.
+type batchedGetCache interface {
+	// GetMulti attempts to obtain the values of multiple keys in as few
+	// requests as possible. Keys that do not exist should be omitted from the
+	// resulting map rather than returning ErrKeyNotFound.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// ttlGetCache represents a cache that is able to report the remaining TTL of
+// an item alongside its value. This interface is optional for caches and when
+// implemented will automatically be utilised where possible.
+type ttlGetCache interface {
+	// GetWithTTL attempts to obtain the value of a key along with its remaining
+	// TTL, which should be nil if the item does not expire.
+	GetWithTTL(ctx context.Context, key string) ([]byte, *time.Duration, error)
+}
+
+// compareAndSwapCache represents a cache that is able to atomically swap the
+// value of a key only when its current value matches an expected value. This
+// interface is optional for caches and when implemented will automatically be
+// utilised where possible.
+type compareAndSwapCache interface {
+	// CompareAndSwap attempts to set the value of a key only if its current
+	// value matches old. If old is nil then the key must not already exist, and
+	// ErrKeyAlreadyExists should be returned if it does. If the current value
+	// does not match then ErrKeyValueMismatch should be returned.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error
+}
+
 //------------------------------------------------------------------------------
 
 // Implements types.Cache
 type airGapCache struct {
-	c  Cache
-	cm batchedCache
+	c   Cache
+	cm  batchedCache
+	cgm batchedGetCache
+	cgt ttlGetCache
+	cas compareAndSwapCache
 
 	sig *shutdown.Signaller
 }
 
 func newAirGapCache(c Cache, stats metrics.Type) types.Cache {
-	ag := &airGapCache{c, nil, shutdown.NewSignaller()}
+	ag := &airGapCache{c, nil, nil, nil, nil, shutdown.NewSignaller()}
 	ag.cm, _ = c.(batchedCache)
+	ag.cgm, _ = c.(batchedGetCache)
+	ag.cgt, _ = c.(ttlGetCache)
+	ag.cas, _ = c.(compareAndSwapCache)
 	return cache.NewV2ToV1Cache(ag, stats)
 }
 
@@ -80,6 +118,36 @@ func (a *airGapCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *airGapCache) GetWithTTL(ctx context.Context, key string) ([]byte, *time.Duration, error) {
+	if a.cgt == nil {
+		b, err := a.Get(ctx, key)
+		return b, nil, err
+	}
+	b, ttl, err := a.cgt.GetWithTTL(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		err = types.ErrKeyNotFound
+	}
+	return b, ttl, err
+}
+
+func (a *airGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if a.cgm != nil {
+		return a.cgm.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		b, err := a.c.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, ErrKeyNotFound) || errors.Is(err, types.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = b
+	}
+	return values, nil
+}
+
 func (a *airGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return a.c.Set(ctx, key, value, ttl)
 }
@@ -112,6 +180,22 @@ func (a *airGapCache) Add(ctx context.Context, key string, value []byte, ttl *ti
 	return err
 }
 
+func (a *airGapCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	if a.cas == nil {
+		return types.ErrNotSupported
+	}
+	err := a.cas.CompareAndSwap(ctx, key, old, value, ttl)
+	switch {
+	case errors.Is(err, ErrKeyValueMismatch):
+		err = types.ErrKeyValueMismatch
+	case errors.Is(err, ErrKeyAlreadyExists):
+		err = types.ErrKeyAlreadyExists
+	case errors.Is(err, ErrKeyNotFound):
+		err = types.ErrKeyNotFound
+	}
+	return err
+}
+
 func (a *airGapCache) Delete(ctx context.Context, key string) error {
 	return a.c.Delete(ctx, key)
 }
@@ -139,6 +223,37 @@ func (r *reverseAirGapCache) Get(ctx context.Context, key string) ([]byte, error
 	return b, err
 }
 
+func (r *reverseAirGapCache) GetWithTTL(ctx context.Context, key string) ([]byte, *time.Duration, error) {
+	cgt, ok := r.c.(types.CacheWithGetTTL)
+	if !ok {
+		b, err := r.Get(ctx, key)
+		return b, nil, err
+	}
+	b, ttl, err := cgt.GetWithTTL(key)
+	if errors.Is(err, types.ErrKeyNotFound) {
+		err = ErrKeyNotFound
+	}
+	return b, ttl, err
+}
+
+func (r *reverseAirGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if cgm, ok := r.c.(types.CacheWithGetMulti); ok {
+		return cgm.GetMulti(keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		b, err := r.c.Get(k)
+		if err != nil {
+			if errors.Is(err, types.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = b
+	}
+	return values, nil
+}
+
 func (r *reverseAirGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	if cttl, ok := r.c.(types.CacheWithTTL); ok {
 		return cttl.SetWithTTL(key, value, ttl)
@@ -158,6 +273,23 @@ func (r *reverseAirGapCache) Add(ctx context.Context, key string, value []byte,
 	return
 }
 
+func (r *reverseAirGapCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	cas, ok := r.c.(types.CacheWithCompareAndSwap)
+	if !ok {
+		return types.ErrNotSupported
+	}
+	err := cas.CompareAndSwap(key, old, value, ttl)
+	switch {
+	case errors.Is(err, types.ErrKeyValueMismatch):
+		err = ErrKeyValueMismatch
+	case errors.Is(err, types.ErrKeyAlreadyExists):
+		err = ErrKeyAlreadyExists
+	case errors.Is(err, types.ErrKeyNotFound):
+		err = ErrKeyNotFound
+	}
+	return err
+}
+
 func (r *reverseAirGapCache) Delete(ctx context.Context, key string) error {
 	return r.c.Delete(key)
 }
@@ -89,6 +89,26 @@ func (c *closableCacheMulti) SetMulti(ctx context.Context, keyValues ...CacheIte
 	return nil
 }
 
+type closableCacheGetMulti struct {
+	*closableCache
+
+	getMultiCalls int
+}
+
+func (c *closableCacheGetMulti) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if c.closableCache.err != nil {
+		return nil, c.closableCache.err
+	}
+	c.getMultiCalls++
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.m[k]; ok {
+			values[k] = i.b
+		}
+	}
+	return values, nil
+}
+
 func TestCacheAirGapShutdown(t *testing.T) {
 	rl := &closableCache{}
 	agrl := newAirGapCache(rl, metrics.Noop())
@@ -122,6 +142,101 @@ func TestCacheAirGapGet(t *testing.T) {
 	assert.EqualError(t, err, "key does not exist")
 }
 
+func TestCacheAirGapGetMulti(t *testing.T) {
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {
+				b: []byte("bar"),
+			},
+			"baz": {
+				b: []byte("buz"),
+			},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop()).(types.CacheWithGetMulti)
+
+	values, err := agrl.GetMulti("foo", "baz", "not exist")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+		"baz": []byte("buz"),
+	}, values)
+
+	rl.err = errors.New("nope")
+	_, err = agrl.GetMulti("foo")
+	assert.EqualError(t, err, "nope")
+}
+
+func TestCacheAirGapGetMultiPassthrough(t *testing.T) {
+	rl := &closableCacheGetMulti{
+		closableCache: &closableCache{
+			m: map[string]testCacheItem{
+				"foo": {
+					b: []byte("bar"),
+				},
+			},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop()).(types.CacheWithGetMulti)
+
+	values, err := agrl.GetMulti("foo", "not exist")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+	}, values)
+	assert.Equal(t, 1, rl.getMultiCalls)
+}
+
+type closableCacheCAS struct {
+	*closableCache
+}
+
+func (c *closableCacheCAS) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl *time.Duration) error {
+	i, exists := c.m[key]
+	if old == nil {
+		if exists {
+			return ErrKeyAlreadyExists
+		}
+	} else if !exists {
+		return ErrKeyNotFound
+	} else if string(i.b) != string(old) {
+		return ErrKeyValueMismatch
+	}
+	c.m[key] = testCacheItem{b: value, ttl: ttl}
+	return nil
+}
+
+func TestCacheAirGapCompareAndSwap(t *testing.T) {
+	rl := &closableCacheCAS{
+		closableCache: &closableCache{
+			m: map[string]testCacheItem{
+				"foo": {
+					b: []byte("bar"),
+				},
+			},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop()).(types.CacheWithCompareAndSwap)
+
+	err := agrl.CompareAndSwap("foo", []byte("nope"), []byte("baz"), nil)
+	assert.Equal(t, types.ErrKeyValueMismatch, err)
+
+	err = agrl.CompareAndSwap("foo", nil, []byte("baz"), nil)
+	assert.Equal(t, types.ErrKeyAlreadyExists, err)
+
+	err = agrl.CompareAndSwap("foo", []byte("bar"), []byte("baz"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", string(rl.m["foo"].b))
+
+	err = agrl.CompareAndSwap("new", nil, []byte("buz"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "buz", string(rl.m["new"].b))
+
+	nonCAS := newAirGapCache(&closableCache{}, metrics.Noop()).(types.CacheWithCompareAndSwap)
+	err = nonCAS.CompareAndSwap("foo", nil, []byte("baz"), nil)
+	assert.Equal(t, types.ErrNotSupported, err)
+}
+
 func TestCacheAirGapSet(t *testing.T) {
 	rl := &closableCache{
 		m: map[string]testCacheItem{},
@@ -430,6 +545,23 @@ func TestCacheReverseAirGapGet(t *testing.T) {
 	assert.EqualError(t, err, "key does not exist")
 }
 
+func TestCacheReverseAirGapGetMulti(t *testing.T) {
+	rl := &closableCacheType{
+		m: map[string]testCacheItem{
+			"foo": {
+				b: []byte("bar"),
+			},
+		},
+	}
+	agrl := newReverseAirGapCache(rl)
+
+	values, err := agrl.GetMulti(context.Background(), "foo", "not exist")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+	}, values)
+}
+
 func TestCacheReverseAirGapSet(t *testing.T) {
 	rl := &closableCacheType{
 		m: map[string]testCacheItem{},
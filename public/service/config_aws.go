@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"gopkg.in/yaml.v3"
+)
+
+// NewAWSSessionField defines a new object type config field that describes the
+// region, endpoint and credentials of an AWS session, matching those of the AWS
+// components. It is then possible to extract a *session.Session from the
+// resulting parsed config with the method FieldAWSSession.
+func NewAWSSessionField(name string) *ConfigField {
+	children := sess.FieldSpecs()
+	for i := range children {
+		if children[i].Name == "credentials" {
+			for j := range children[i].Children {
+				if children[i].Children[j].Default == nil {
+					children[i].Children[j] = children[i].Children[j].HasDefault("")
+				}
+			}
+		} else {
+			children[i] = children[i].HasDefault("")
+		}
+	}
+	return &ConfigField{
+		field: docs.FieldCommon(name, "Configure the AWS session used to make requests.").WithChildren(children...),
+	}
+}
+
+// FieldAWSSession accesses a field from a parsed config that was defined with
+// NewAWSSessionField and returns a *session.Session, or an error if the
+// configuration was invalid. The session can be customised further with its
+// method Copy.
+func (p *ParsedConfig) FieldAWSSession(path ...string) (*session.Session, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	conf := sess.NewConfig()
+	conf.Region = ""
+	if err := node.Decode(&conf); err != nil {
+		return nil, err
+	}
+	return conf.GetSession()
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigAWSSession(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewAWSSessionField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  region: us-east-2
+  endpoint: http://localhost:4566
+  credentials:
+    id: foo
+    secret: bar
+`, nil)
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldAWSSession("b")
+	require.Error(t, err)
+
+	sess, err := parsedConfig.FieldAWSSession("a")
+	require.NoError(t, err)
+
+	assert.Equal(t, "us-east-2", *sess.Config.Region)
+	assert.Equal(t, "http://localhost:4566", *sess.Config.Endpoint)
+
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", creds.AccessKeyID)
+	assert.Equal(t, "bar", creds.SecretAccessKey)
+}
+
+func TestConfigAWSSessionRoleChain(t *testing.T) {
+	type stsRequest struct {
+		action, role, externalID, keyID, token string
+	}
+	var reqs []stsRequest
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		req := stsRequest{
+			action:     r.Form.Get("Action"),
+			role:       r.Form.Get("RoleArn"),
+			externalID: r.Form.Get("ExternalId"),
+			token:      r.Form.Get("WebIdentityToken"),
+		}
+		if auth := r.Header.Get("Authorization"); strings.Contains(auth, "Credential=") {
+			req.keyID = strings.Split(strings.SplitN(auth, "Credential=", 2)[1], "/")[0]
+		}
+		reqs = append(reqs, req)
+
+		_, _ = fmt.Fprintf(w, `<%[1]vResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <%[1]vResult>
+    <Credentials>
+      <AccessKeyId>key%[2]v</AccessKeyId>
+      <SecretAccessKey>secret%[2]v</SecretAccessKey>
+      <SessionToken>token%[2]v</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </%[1]vResult>
+  <ResponseMetadata><RequestId>%[2]v</RequestId></ResponseMetadata>
+</%[1]vResponse>`, req.action, len(reqs))
+	}))
+	defer ts.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("webtoken"), 0o600))
+
+	spec := NewConfigSpec().
+		Field(NewAWSSessionField("a")).
+		Field(NewAWSSessionField("b"))
+
+	parsedConfig, err := spec.ParseYAML(fmt.Sprintf(`
+a:
+  region: us-east-2
+  endpoint: http://localhost:4566
+  credentials:
+    id: foo
+    secret: bar
+    role: arn:aws:iam::123456789012:role/first
+    role_external_id: baz
+    role_chain:
+      - role: arn:aws:iam::123456789012:role/second
+    sts_endpoint: %[1]v
+b:
+  region: us-east-2
+  credentials:
+    web_identity_token_file: %[2]v
+    role: arn:aws:iam::123456789012:role/web
+    sts_endpoint: %[1]v
+`, ts.URL, tokenFile), nil)
+	require.NoError(t, err)
+
+	sess, err := parsedConfig.FieldAWSSession("a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4566", *sess.Config.Endpoint)
+
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key2", creds.AccessKeyID)
+	assert.Equal(t, "token2", creds.SessionToken)
+
+	assert.Equal(t, []stsRequest{
+		{action: "AssumeRole", role: "arn:aws:iam::123456789012:role/first", externalID: "baz", keyID: "foo"},
+		{action: "AssumeRole", role: "arn:aws:iam::123456789012:role/second", keyID: "key1"},
+	}, reqs)
+
+	reqs = nil
+	sess, err = parsedConfig.FieldAWSSession("b")
+	require.NoError(t, err)
+
+	creds, err = sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "key1", creds.AccessKeyID)
+
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "AssumeRoleWithWebIdentity", reqs[0].action)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/web", reqs[0].role)
+	assert.Equal(t, "webtoken", reqs[0].token)
+}
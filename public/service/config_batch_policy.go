@@ -14,7 +14,8 @@ import (
 
 // BatchPolicy describes the mechanisms by which batching should be performed of
 // messages destined for a Batch output. This is returned by constructors of
-// batch outputs.
+// batch outputs, and can also be used by inputs in order to create batches with
+// a Batcher, or by wrapping them with AutoBatchInput.
 type BatchPolicy struct {
 	ByteSize int
 	Count    int
@@ -25,6 +26,12 @@ type BatchPolicy struct {
 	procs []processor.Config
 }
 
+// IsNoop returns true if the policy does not describe any conditions under
+// which a batch should be flushed, in which case batching can be skipped.
+func (b BatchPolicy) IsNoop() bool {
+	return b.toInternal().IsNoop()
+}
+
 func (b BatchPolicy) toInternal() batch.PolicyConfig {
 	batchConf := batch.NewPolicyConfig()
 	batchConf.ByteSize = b.ByteSize
@@ -110,8 +117,8 @@ func (b BatchPolicy) NewBatcher(res *Resources) (*Batcher, error) {
 //------------------------------------------------------------------------------
 
 // NewBatchPolicyField defines a new object type config field that describes a
-// batching policy for batched outputs. It is then possible to extract a
-// BatchPolicy from the resulting parsed config with the method
+// batching policy for batched outputs or inputs. It is then possible to extract
+// a BatchPolicy from the resulting parsed config with the method
 // FieldBatchPolicy.
 func NewBatchPolicyField(name string) *ConfigField {
 	bs := batch.FieldSpec()
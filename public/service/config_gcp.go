@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	gcpcreds "github.com/Jeffail/benthos/v3/lib/util/gcp/credentials"
+	"google.golang.org/api/option"
+)
+
+// NewGCPCredentialsField defines a new object type config field that describes
+// the project, credentials and emulator endpoint used by a GCP client. It is
+// then possible to extract the project and a slice of option.ClientOption from
+// the resulting parsed config with the method FieldGCPCredentials, which can be
+// provided to the constructors of GCP client libraries.
+//
+// When neither credentials field is set the application default credentials of
+// the environment are used.
+func NewGCPCredentialsField(name string) *ConfigField {
+	children := docs.FieldSpecs{
+		docs.FieldString("project", "The GCP project to target. If not set, it will be inferred from the credentials or read from the GOOGLE_CLOUD_PROJECT environment variable.").HasDefault(""),
+	}
+	children = append(children, gcpcreds.FieldSpecs()...)
+	return &ConfigField{
+		field: docs.FieldCommon(name, "Configure the project and credentials used to access GCP services.").WithChildren(children...),
+	}
+}
+
+// FieldGCPCredentials accesses a field from a parsed config that was defined
+// with NewGCPCredentialsField and returns the configured project and the client
+// options that apply the configured credentials, or an error if the
+// configuration was invalid.
+func (p *ParsedConfig) FieldGCPCredentials(path ...string) (project string, opts []option.ClientOption, err error) {
+	if project, err = p.FieldString(append(path, "project")...); err != nil {
+		return
+	}
+
+	conf := gcpcreds.NewConfig()
+	if conf.CredentialsJSON, err = p.FieldString(append(path, "credentials_json")...); err != nil {
+		return
+	}
+	if conf.CredentialsFile, err = p.FieldString(append(path, "credentials_file")...); err != nil {
+		return
+	}
+	if conf.ImpersonateServiceAccount, err = p.FieldString(append(path, "impersonate_service_account")...); err != nil {
+		return
+	}
+	if conf.ImpersonateDelegates, err = p.FieldStringList(append(path, "impersonate_delegates")...); err != nil {
+		return
+	}
+	if conf.EmulatorEndpoint, err = p.FieldString(append(path, "emulator_endpoint")...); err != nil {
+		return
+	}
+
+	opts, err = conf.ClientOptions(context.Background())
+	return
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestConfigGCPCredentials(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewGCPCredentialsField("a")).
+		Field(NewGCPCredentialsField("b")).
+		Field(NewGCPCredentialsField("c")).
+		Field(NewGCPCredentialsField("d"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  project: foo
+b:
+  credentials_file: ./creds.json
+c:
+  credentials_json: '{}'
+  credentials_file: ./creds.json
+d:
+  project: bar
+  credentials_file: ./creds.json
+  emulator_endpoint: localhost:8085
+`, nil)
+	require.NoError(t, err)
+
+	project, opts, err := parsedConfig.FieldGCPCredentials("a")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", project)
+	assert.Empty(t, opts)
+
+	project, opts, err = parsedConfig.FieldGCPCredentials("b")
+	require.NoError(t, err)
+	assert.Equal(t, "", project)
+	assert.Len(t, opts, 1)
+
+	_, _, err = parsedConfig.FieldGCPCredentials("c")
+	require.EqualError(t, err, "only one of credentials_json and credentials_file can be set")
+
+	project, opts, err = parsedConfig.FieldGCPCredentials("d")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", project)
+	assert.Equal(t, []option.ClientOption{
+		option.WithEndpoint("localhost:8085"),
+		option.WithoutAuthentication(),
+	}, opts[:2])
+	assert.Len(t, opts, 3)
+}
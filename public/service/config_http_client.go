@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"gopkg.in/yaml.v3"
+)
+
+// NewHTTPClientField defines a new object type config field that describes the
+// authentication, TLS and retry settings of an HTTP client, matching those of
+// the http_client components. It is then possible to extract an *HTTPClient
+// from the resulting parsed config with the method FieldHTTPClient.
+func NewHTTPClientField(name string) *ConfigField {
+	children := auth.FieldSpecsExpanded()
+	children = append(children,
+		btls.FieldSpec(),
+		docs.FieldString("timeout", "A static timeout to apply to requests.").HasDefault("5s"),
+		docs.FieldString("retry_period", "The base period to wait between failed requests.").Advanced().HasDefault("1s"),
+		docs.FieldString("max_retry_backoff", "The maximum period to wait between failed requests.").Advanced().HasDefault("300s"),
+		docs.FieldInt("retries", "The maximum number of retry attempts to make.").Advanced().HasDefault(3),
+		docs.FieldInt("backoff_on", "A list of status codes whereby the request should be considered to have failed and retries should be attempted, but the period between them should be increased gradually.").Array().Advanced().HasDefault([]interface{}{429}),
+		docs.FieldInt("drop_on", "A list of status codes whereby the request should be considered to have failed but retries should not be attempted. This is useful for preventing wasted retries for requests that will never succeed.").Array().Advanced().HasDefault([]interface{}{}),
+		docs.FieldInt("successful_on", "A list of status codes whereby the attempt should be considered successful, this is useful for dropping requests that return non-2XX codes indicating that the message has been dealt with, such as a 303 See Other or a 409 Conflict. All 2XX codes are considered successful unless they are present within `backoff_on` or `drop_on`, regardless of this field.").Array().Advanced().HasDefault([]interface{}{}),
+		docs.FieldString("proxy_url", "An optional HTTP proxy URL.").Advanced().HasDefault(""),
+	)
+	return &ConfigField{
+		field: docs.FieldCommon(name, "Configure the HTTP client used to make requests.").WithChildren(children...),
+	}
+}
+
+type httpClientConfig struct {
+	auth.Config  `yaml:",inline"`
+	OAuth2       auth.OAuth2Config `yaml:"oauth2"`
+	TLS          btls.Config       `yaml:"tls"`
+	Timeout      string            `yaml:"timeout"`
+	Retry        string            `yaml:"retry_period"`
+	MaxBackoff   string            `yaml:"max_retry_backoff"`
+	NumRetries   int               `yaml:"retries"`
+	BackoffOn    []int             `yaml:"backoff_on"`
+	DropOn       []int             `yaml:"drop_on"`
+	SuccessfulOn []int             `yaml:"successful_on"`
+	ProxyURL     string            `yaml:"proxy_url"`
+}
+
+// FieldHTTPClient accesses a field from a parsed config that was defined with
+// NewHTTPClientField and returns an *HTTPClient, or an error if the
+// configuration was invalid.
+func (p *ParsedConfig) FieldHTTPClient(path ...string) (*HTTPClient, error) {
+	v, exists := p.field(path...)
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	conf := httpClientConfig{
+		Config:     auth.NewConfig(),
+		OAuth2:     auth.NewOAuth2Config(),
+		TLS:        btls.NewConfig(),
+		Timeout:    "5s",
+		Retry:      "1s",
+		MaxBackoff: "300s",
+		NumRetries: 3,
+		BackoffOn:  []int{429},
+	}
+	if err := node.Decode(&conf); err != nil {
+		return nil, err
+	}
+	return newHTTPClient(conf)
+}
+
+//------------------------------------------------------------------------------
+
+// HTTPClient is an HTTP client that signs requests with the configured
+// authentication strategies and retries failed requests, and is created from a
+// config field defined with NewHTTPClientField.
+type HTTPClient struct {
+	client *http.Client
+	auth   auth.Config
+
+	numRetries  int
+	retryPeriod time.Duration
+	maxBackoff  time.Duration
+
+	backoffOn map[int]struct{}
+	dropOn    map[int]struct{}
+	successOn map[int]struct{}
+}
+
+func newHTTPClient(conf httpClientConfig) (*HTTPClient, error) {
+	h := &HTTPClient{
+		client:     &http.Client{},
+		auth:       conf.Config,
+		numRetries: conf.NumRetries,
+		backoffOn:  map[int]struct{}{},
+		dropOn:     map[int]struct{}{},
+		successOn:  map[int]struct{}{},
+	}
+
+	var err error
+	if tout := conf.Timeout; len(tout) > 0 {
+		if h.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if tout := conf.Retry; len(tout) > 0 {
+		if h.retryPeriod, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse retry duration string: %v", err)
+		}
+	}
+	if tout := conf.MaxBackoff; len(tout) > 0 {
+		if h.maxBackoff, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse max backoff duration string: %v", err)
+		}
+	}
+
+	var transport *http.Transport
+	if conf.TLS.Enabled || conf.ProxyURL != "" {
+		if c, ok := http.DefaultTransport.(*http.Transport); ok {
+			transport = c.Clone()
+		} else {
+			transport = &http.Transport{}
+		}
+	}
+	if conf.TLS.Enabled {
+		if transport.TLSClientConfig, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.ProxyURL != "" {
+		proxyURL, err := url.Parse(conf.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy_url string: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if transport != nil {
+		h.client.Transport = transport
+	}
+	if h.client, err = conf.OAuth2.WrapClient(context.Background(), h.client); err != nil {
+		return nil, fmt.Errorf("failed to configure oauth2: %w", err)
+	}
+
+	for _, c := range conf.BackoffOn {
+		h.backoffOn[c] = struct{}{}
+	}
+	for _, c := range conf.DropOn {
+		h.dropOn[c] = struct{}{}
+	}
+	for _, c := range conf.SuccessfulOn {
+		h.successOn[c] = struct{}{}
+	}
+	return h, nil
+}
+
+type httpRetryStrategy int
+
+const (
+	httpNoRetry httpRetryStrategy = iota
+	httpRetryLinear
+	httpRetryBackoff
+)
+
+func (h *HTTPClient) checkStatus(code int) (succeeded bool, retStrat httpRetryStrategy) {
+	if _, exists := h.dropOn[code]; exists {
+		return false, httpNoRetry
+	}
+	if _, exists := h.backoffOn[code]; exists {
+		return false, httpRetryBackoff
+	}
+	if _, exists := h.successOn[code]; exists {
+		return true, httpNoRetry
+	}
+	if code < 200 || code > 299 {
+		return false, httpRetryLinear
+	}
+	return true, httpNoRetry
+}
+
+// Do signs and sends an HTTP request and returns the response. Requests that
+// fail, either due to a connection error or an unsuccessful status code, are
+// retried according to the configured retry settings, with the period between
+// attempts increasing for status codes within `backoff_on`. If all attempts
+// fail then the error of the last one is returned, which is an
+// *HTTPStatusError when a response was received.
+//
+// A request with a body can only be retried when its GetBody field is set,
+// which is the case for requests created with http.NewRequest from a
+// *bytes.Buffer, *bytes.Reader or *strings.Reader.
+func (h *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := h.retryPeriod
+
+	for i := 0; ; i++ {
+		if i > 0 {
+			var err error
+			if req, err = replayRequest(req); err != nil {
+				return nil, err
+			}
+		}
+		if err := h.auth.Sign(req); err != nil {
+			return nil, err
+		}
+
+		retryStrat := httpRetryLinear
+		res, err := h.client.Do(req)
+		if err == nil {
+			var succeeded bool
+			if succeeded, retryStrat = h.checkStatus(res.StatusCode); succeeded {
+				return res, nil
+			}
+			err = newHTTPStatusError(res)
+		}
+		if retryStrat == httpNoRetry || i >= h.numRetries || !canReplayRequest(req) {
+			return nil, err
+		}
+
+		wait := h.retryPeriod
+		if retryStrat == httpRetryBackoff {
+			wait = backoff
+			if backoff *= 2; h.maxBackoff > 0 && backoff > h.maxBackoff {
+				backoff = h.maxBackoff
+			}
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func canReplayRequest(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func replayRequest(req *http.Request) (*http.Request, error) {
+	newReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain request body for retry: %w", err)
+		}
+		newReq.Body = body
+	}
+	return newReq, nil
+}
+
+//------------------------------------------------------------------------------
+
+// HTTPStatusError is returned by an HTTPClient when a request received a
+// response with an unsuccessful status code.
+type HTTPStatusError struct {
+	Code   int
+	Status string
+	Body   []byte
+}
+
+func newHTTPStatusError(res *http.Response) error {
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	return &HTTPStatusError{Code: res.StatusCode, Status: res.Status, Body: body}
+}
+
+// Error returns a message describing the response.
+func (e *HTTPStatusError) Error() string {
+	body := strings.ReplaceAll(string(e.Body), "\n", "")
+	return fmt.Sprintf("HTTP request returned unexpected response code (%v): %v, Error: %v", e.Code, e.Status, body)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigHTTPClient(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqBodies []string
+	codes := []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "foo", user)
+		assert.Equal(t, "bar", pass)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqBodies = append(reqBodies, string(body))
+
+		code := codes[0]
+		codes = codes[1:]
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte("response body"))
+	}))
+	defer ts.Close()
+
+	spec := NewConfigSpec().
+		Field(NewHTTPClientField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  basic_auth:
+    enabled: true
+    username: foo
+    password: bar
+  retry_period: 1ms
+  max_retry_backoff: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldHTTPClient("b")
+	require.Error(t, err)
+
+	client, err := parsedConfig.FieldHTTPClient("a")
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", ts.URL, strings.NewReader("hello world"))
+	require.NoError(t, err)
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "response body", string(resBody))
+	assert.Equal(t, []string{"hello world", "hello world", "hello world"}, reqBodies)
+}
+
+func TestConfigHTTPClientErrors(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqCount int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		reqCount++
+		reqMut.Unlock()
+
+		if r.URL.Path == "/drop" {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = w.Write([]byte("nope"))
+	}))
+	defer ts.Close()
+
+	spec := NewConfigSpec().
+		Field(NewHTTPClientField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  retry_period: 1ms
+  retries: 2
+  drop_on: [ 400 ]
+`, nil)
+	require.NoError(t, err)
+
+	client, err := parsedConfig.FieldHTTPClient("a")
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", ts.URL+"/retry", http.NoBody)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	require.Error(t, err)
+
+	var statusErr *HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusInternalServerError, statusErr.Code)
+	assert.Equal(t, "nope", string(statusErr.Body))
+	assert.Equal(t, 3, reqCount)
+
+	req, err = http.NewRequest("GET", ts.URL+"/drop", http.NoBody)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP request returned unexpected response code (400)")
+	assert.Equal(t, 4, reqCount)
+}
+
+func TestConfigHTTPClientBadDuration(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewHTTPClientField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  timeout: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldHTTPClient("a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse timeout string")
+}
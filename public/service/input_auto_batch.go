@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+)
+
+// AutoBatchInput wraps an input implementation with a batching mechanism,
+// resulting in a batched input that reads messages from the child until the
+// batching policy of the provided Batcher is triggered, either by the count,
+// byte size or check conditions of the policy, or by its period elapsing while
+// messages are pending. Any processors of the batching policy are applied to
+// each batch before it is returned.
+//
+// When a batch is acknowledged the ack functions of each message within it are
+// called with the same result. If the child input returns ErrEndOfInput then
+// any pending messages are flushed as a final batch.
+//
+// The Batcher is owned by the resulting input and is closed along with the
+// child input.
+func AutoBatchInput(i Input, batcher *Batcher) BatchInput {
+	return &autoBatchInput{
+		child:   i,
+		batcher: batcher,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type autoBatchInput struct {
+	child   Input
+	batcher *Batcher
+
+	pendingAcks []AckFunc
+}
+
+func (i *autoBatchInput) Connect(ctx context.Context) error {
+	return i.child.Connect(ctx)
+}
+
+func (i *autoBatchInput) flush(ctx context.Context) (MessageBatch, AckFunc, error) {
+	batch, err := i.batcher.Flush(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	acks := i.pendingAcks
+	i.pendingAcks = nil
+
+	ackFn := func(ctx context.Context, err error) error {
+		var ackErr error
+		for _, ack := range acks {
+			if aerr := ack(ctx, err); aerr != nil && ackErr == nil {
+				ackErr = aerr
+			}
+		}
+		return ackErr
+	}
+
+	// When the processors of the policy filter every message out of the batch
+	// then the messages are acknowledged immediately.
+	if len(batch) == 0 {
+		_ = ackFn(ctx, nil)
+		return nil, nil, nil
+	}
+	return batch, ackFn, nil
+}
+
+func (i *autoBatchInput) ReadBatch(ctx context.Context) (MessageBatch, AckFunc, error) {
+	for {
+		readCtx, cancel := ctx, func() {}
+		if len(i.pendingAcks) > 0 {
+			if tout, ok := i.batcher.UntilNext(); ok {
+				if tout <= 0 {
+					if batch, ackFn, err := i.flush(ctx); err != nil || len(batch) > 0 {
+						return batch, ackFn, err
+					}
+					continue
+				}
+				readCtx, cancel = context.WithTimeout(ctx, tout)
+			}
+		}
+
+		msg, ackFn, err := i.child.Read(readCtx)
+		timedOut := readCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+
+		if err != nil {
+			if (timedOut || err == ErrEndOfInput) && len(i.pendingAcks) > 0 {
+				if batch, ackFn, ferr := i.flush(ctx); ferr != nil || len(batch) > 0 {
+					return batch, ackFn, ferr
+				}
+			}
+			if timedOut {
+				continue
+			}
+			return nil, nil, err
+		}
+
+		i.pendingAcks = append(i.pendingAcks, ackFn)
+		if i.batcher.Add(msg) {
+			if batch, ackFn, err := i.flush(ctx); err != nil || len(batch) > 0 {
+				return batch, ackFn, err
+			}
+		}
+	}
+}
+
+func (i *autoBatchInput) Close(ctx context.Context) error {
+	if err := i.batcher.Close(ctx); err != nil {
+		return err
+	}
+	return i.child.Close(ctx)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chanInput struct {
+	msgs chan string
+
+	acksMut sync.Mutex
+	acks    map[string]error
+}
+
+func newChanInput() *chanInput {
+	return &chanInput{
+		msgs: make(chan string),
+		acks: map[string]error{},
+	}
+}
+
+func (c *chanInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c *chanInput) Read(ctx context.Context) (*Message, AckFunc, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, types.ErrTimeout
+	case m, open := <-c.msgs:
+		if !open {
+			return nil, nil, ErrEndOfInput
+		}
+		return NewMessage([]byte(m)), func(ctx context.Context, err error) error {
+			c.acksMut.Lock()
+			c.acks[m] = err
+			c.acksMut.Unlock()
+			return nil
+		}, nil
+	}
+}
+
+func (c *chanInput) Close(ctx context.Context) error {
+	return nil
+}
+
+func batchContents(t *testing.T, batch MessageBatch) []string {
+	t.Helper()
+
+	var contents []string
+	for _, m := range batch {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(b))
+	}
+	return contents
+}
+
+func TestAutoBatchInputCount(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	parsedConfig, err := NewConfigSpec().Field(NewBatchPolicyField("a")).ParseYAML(`
+a:
+  count: 2
+  processors:
+    - bloblang: 'root = content().uppercase()'
+`, nil)
+	require.NoError(t, err)
+
+	bConf, err := parsedConfig.FieldBatchPolicy("a")
+	require.NoError(t, err)
+
+	mgr, err := manager.NewV2(manager.NewResourceConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	pol, err := bConf.NewBatcher(newResourcesFromManager(mgr))
+	require.NoError(t, err)
+
+	child := newChanInput()
+	input := AutoBatchInput(child, pol)
+	require.NoError(t, input.Connect(ctx))
+
+	go func() {
+		for _, m := range []string{"foo", "bar", "baz"} {
+			child.msgs <- m
+		}
+		close(child.msgs)
+	}()
+
+	batch, ackFn, err := input.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FOO", "BAR"}, batchContents(t, batch))
+	require.NoError(t, ackFn(ctx, nil))
+
+	batch, ackFn, err = input.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BAZ"}, batchContents(t, batch))
+	require.NoError(t, ackFn(ctx, types.ErrTimeout))
+
+	_, _, err = input.ReadBatch(ctx)
+	assert.Equal(t, ErrEndOfInput, err)
+
+	assert.Equal(t, map[string]error{
+		"foo": nil,
+		"bar": nil,
+		"baz": types.ErrTimeout,
+	}, child.acks)
+
+	require.NoError(t, input.Close(ctx))
+}
+
+func TestAutoBatchInputPeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	parsedConfig, err := NewConfigSpec().Field(NewBatchPolicyField("a")).ParseYAML(`
+a:
+  count: 10
+  period: 50ms
+`, nil)
+	require.NoError(t, err)
+
+	bConf, err := parsedConfig.FieldBatchPolicy("a")
+	require.NoError(t, err)
+
+	mgr, err := manager.NewV2(manager.NewResourceConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	pol, err := bConf.NewBatcher(newResourcesFromManager(mgr))
+	require.NoError(t, err)
+
+	child := newChanInput()
+	input := AutoBatchInput(child, pol)
+	require.NoError(t, input.Connect(ctx))
+
+	go func() {
+		child.msgs <- "foo"
+		child.msgs <- "bar"
+	}()
+
+	batch, ackFn, err := input.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, batchContents(t, batch))
+	require.NoError(t, ackFn(ctx, nil))
+
+	readCtx, readCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer readCancel()
+
+	_, _, err = input.ReadBatch(readCtx)
+	assert.Equal(t, types.ErrTimeout, err)
+
+	require.NoError(t, input.Close(ctx))
+}
+
+func TestAutoBatchInputFiltered(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	parsedConfig, err := NewConfigSpec().Field(NewBatchPolicyField("a")).ParseYAML(`
+a:
+  count: 2
+  processors:
+    - bloblang: 'root = if content() == "foo" || content() == "bar" { deleted() }'
+`, nil)
+	require.NoError(t, err)
+
+	bConf, err := parsedConfig.FieldBatchPolicy("a")
+	require.NoError(t, err)
+
+	mgr, err := manager.NewV2(manager.NewResourceConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	pol, err := bConf.NewBatcher(newResourcesFromManager(mgr))
+	require.NoError(t, err)
+
+	child := newChanInput()
+	input := AutoBatchInput(child, pol)
+	require.NoError(t, input.Connect(ctx))
+
+	go func() {
+		for _, m := range []string{"foo", "bar", "baz", "buz"} {
+			child.msgs <- m
+		}
+	}()
+
+	batch, ackFn, err := input.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz", "buz"}, batchContents(t, batch))
+	require.NoError(t, ackFn(ctx, nil))
+
+	assert.Equal(t, map[string]error{
+		"foo": nil,
+		"bar": nil,
+		"baz": nil,
+		"buz": nil,
+	}, child.acks)
+
+	require.NoError(t, input.Close(ctx))
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/transaction"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
@@ -91,6 +93,47 @@ func (m *Message) WithContext(ctx context.Context) *Message {
 	}
 }
 
+// OnDelivery registers a function to be called once the delivery of the
+// message, or of the batch that contains it, has been resolved downstream. The
+// function is called with a nil error when the message was delivered
+// successfully, or the error that caused it to be rejected otherwise, and is
+// called before the input that the message originated from acknowledges it
+// with its source. This makes it possible for processors to perform
+// side-effects, such as committing offsets to an external system, that are
+// tied to the delivery of a message rather than its processing.
+//
+// Functions are called synchronously and in the order that they were
+// registered, and the input does not acknowledge the message until they have
+// returned. If a function returns an error then the message is rejected with
+// its source.
+//
+// Copies of a message share the delivery of the original. Returns false if the
+// message is not associated with an input that supports delivery callbacks,
+// such as a message created by a processor with NewMessage, or if its delivery
+// has already been resolved, in which case the function will never be called.
+func (m *Message) OnDelivery(fn func(ctx context.Context, err error) error) bool {
+	return transaction.AddDeliveryHook(m.part, fn)
+}
+
+// OnCommit registers a function to be called once the input that the message
+// originated from has acknowledged it with its source, such as by committing an
+// offset. The function is called with a nil error when the message was
+// delivered and acknowledged successfully, or the error that prevented it
+// otherwise.
+//
+// This makes it possible for outputs that support transactions to defer the
+// final commit of a write until the checkpoint of the input has been stored,
+// which reduces the duplicates caused by a crash between the two. The error
+// returned by the function is logged by the input but cannot reject the
+// message as its source has already been acknowledged.
+//
+// Returns false if the message is not associated with an input that supports
+// commit callbacks, or if its source has already been acknowledged, in which
+// case the function will never be called.
+func (m *Message) OnCommit(fn func(ctx context.Context, err error) error) bool {
+	return transaction.AddCommitHook(m.part, fn)
+}
+
 // AsBytes returns the underlying byte array contents of a message or, if the
 // contents are a structured type, attempts to marshal the contents as a JSON
 // document and returns either the byte array result or an error.
@@ -246,6 +289,41 @@ func (b MessageBatch) BloblangQuery(index int, blobl *bloblang.Executor) (*Messa
 	return nil, nil
 }
 
+// OnCommit registers a function to be called exactly once, after the inputs
+// that the messages of the batch originated from have acknowledged them with
+// their sources. The function is called with a nil error when all messages were
+// delivered and acknowledged successfully, or the first error that prevented it
+// otherwise. Messages of a batch created by an output may originate from any
+// number of input transactions, and the function is only called once all of
+// them have been acknowledged.
+//
+// Returns false if any message of the batch is not associated with an input
+// that supports commit callbacks, in which case the function will never be
+// called and the output should commit immediately.
+func (b MessageBatch) OnCommit(fn func(ctx context.Context, err error) error) bool {
+	parts := make([]types.Part, len(b))
+	for i, m := range b {
+		parts[i] = m.part
+	}
+	return transaction.AddBatchCommitHook(parts, fn)
+}
+
+// AddSyncResponse adds the batch as a synchronous response to the input that
+// the messages originated from, allowing outputs to return the results of
+// their writes to inputs that support them, such as http_server. An error is
+// returned if the messages did not originate from an input that supports
+// synchronous responses.
+func (b MessageBatch) AddSyncResponse() error {
+	if len(b) == 0 {
+		return errors.New("cannot add an empty batch as a response")
+	}
+	msg := message.New(nil)
+	for _, m := range b {
+		msg.Append(m.part)
+	}
+	return roundtrip.SetAsResponse(msg)
+}
+
 // InterpolatedString resolves an interpolated string expression on a message
 // batch, from the perspective of a particular message index.
 //
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// BatchError is an error type that can be returned by batched outputs in order
+// to report which individual messages of a batch failed to be delivered. When
+// a BatchError is returned only the messages that were marked as failed are
+// considered for redelivery, and all other messages are acknowledged. This
+// includes retries by the `retry` output, and the `fallback` output only sends
+// the failed messages to the next output in its list.
+type BatchError struct {
+	err         error
+	batch       MessageBatch
+	indexedErrs map[int]error
+}
+
+// NewBatchError creates a new batch-wide error, where it's possible to add
+// granular errors for individual messages of the batch with Failed.
+func NewBatchError(b MessageBatch, headline error) *BatchError {
+	if berr, ok := headline.(*BatchError); ok {
+		headline = berr.Unwrap()
+	}
+	return &BatchError{
+		err:   headline,
+		batch: b,
+	}
+}
+
+// Failed stores an error state for a particular message of a batch. Returns a
+// pointer to the underlying error, allowing the method to be chained.
+//
+// If Failed is not called then all messages are assumed to have failed. If it
+// is called at least once then all message indexes that aren't explicitly
+// failed are assumed to have been processed successfully.
+func (err *BatchError) Failed(i int, merr error) *BatchError {
+	if err.indexedErrs == nil {
+		err.indexedErrs = make(map[int]error)
+	}
+	err.indexedErrs[i] = merr
+	return err
+}
+
+// IndexedErrors returns the number of indexed errors that have been registered
+// for the batch.
+func (err *BatchError) IndexedErrors() int {
+	return len(err.indexedErrs)
+}
+
+// WalkMessages applies a closure to each message of the batch that caused this
+// error. The closure is provided the message index, the message, and its
+// individual error, which may be nil if the message itself was processed
+// successfully. The closure returns a bool which indicates whether the
+// iteration should be continued.
+func (err *BatchError) WalkMessages(fn func(int, *Message, error) bool) {
+	for i, m := range err.batch {
+		merr := err.err
+		if err.indexedErrs != nil {
+			merr = err.indexedErrs[i]
+		}
+		if !fn(i, m, merr) {
+			return
+		}
+	}
+}
+
+// Error implements the common error interface.
+func (err *BatchError) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap returns the underlying common error.
+func (err *BatchError) Unwrap() error {
+	return err.err
+}
+
+// toInternalBatchError converts a BatchError returned by a plugin into the
+// internal batch error type, referencing the parts of the original message.
+func toInternalBatchError(err error, msg types.Message) error {
+	var berr *BatchError
+	if !errors.As(err, &berr) {
+		return err
+	}
+	ierr := batch.NewError(msg, berr.err)
+	for i, merr := range berr.indexedErrs {
+		ierr.Failed(i, merr)
+	}
+	return ierr
+}
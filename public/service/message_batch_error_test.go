@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchErrorWalkMessages(t *testing.T) {
+	b := MessageBatch{
+		NewMessage([]byte("foo")),
+		NewMessage([]byte("bar")),
+	}
+
+	walk := func(err *BatchError) (errs []string) {
+		err.WalkMessages(func(i int, m *Message, err error) bool {
+			if err == nil {
+				errs = append(errs, "")
+			} else {
+				errs = append(errs, err.Error())
+			}
+			return true
+		})
+		return
+	}
+
+	err := NewBatchError(b, errors.New("nope"))
+	assert.EqualError(t, err, "nope")
+	assert.Equal(t, 0, err.IndexedErrors())
+	assert.Equal(t, []string{"nope", "nope"}, walk(err))
+
+	err = NewBatchError(b, err).Failed(0, errors.New("bad foo"))
+	assert.EqualError(t, err, "nope")
+	assert.Equal(t, 1, err.IndexedErrors())
+	assert.Equal(t, []string{"bad foo", ""}, walk(err))
+}
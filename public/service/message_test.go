@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	ibloblang "github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/transaction"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/public/bloblang"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -313,3 +317,75 @@ func BenchmarkMessageMappingOld(b *testing.B) {
 		}, resI)
 	}
 }
+
+func TestMessageOnDelivery(t *testing.T) {
+	assert.False(t, NewMessage([]byte("foo")).OnDelivery(func(ctx context.Context, err error) error {
+		return nil
+	}))
+
+	batch := message.New([][]byte{[]byte("foo")})
+	hooks := transaction.AttachDeliveryHooks(batch)
+
+	msg := newMessageFromPart(batch.Get(0))
+	msg.SetBytes([]byte("bar"))
+
+	var delivered []error
+	assert.True(t, msg.OnDelivery(func(ctx context.Context, err error) error {
+		delivered = append(delivered, err)
+		return nil
+	}))
+
+	require.NoError(t, hooks.Resolve(context.Background(), response.NewError(errors.New("nope"))))
+	require.Len(t, delivered, 1)
+	assert.EqualError(t, delivered[0], "nope")
+}
+
+func TestMessageBatchOnCommit(t *testing.T) {
+	assert.False(t, MessageBatch{NewMessage([]byte("foo"))}.OnCommit(func(ctx context.Context, err error) error {
+		return nil
+	}))
+
+	inA := message.New([][]byte{[]byte("foo")})
+	inB := message.New([][]byte{[]byte("bar")})
+	hooksA := transaction.AttachDeliveryHooks(inA)
+	hooksB := transaction.AttachDeliveryHooks(inB)
+
+	batch := MessageBatch{
+		newMessageFromPart(inA.Get(0)).Copy(),
+		newMessageFromPart(inB.Get(0)).Copy(),
+	}
+
+	var committed []error
+	assert.True(t, batch.OnCommit(func(ctx context.Context, err error) error {
+		committed = append(committed, err)
+		return nil
+	}))
+
+	require.NoError(t, hooksA.Commit(context.Background(), nil))
+	assert.Empty(t, committed)
+
+	require.NoError(t, hooksB.Commit(context.Background(), nil))
+	assert.Equal(t, []error{nil}, committed)
+}
+
+func TestMessageBatchAddSyncResponse(t *testing.T) {
+	assert.Error(t, MessageBatch{NewMessage([]byte("foo"))}.AddSyncResponse())
+
+	resultStore := roundtrip.NewResultStore()
+	inMsg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	roundtrip.AddResultStore(inMsg, resultStore)
+
+	batch := MessageBatch{
+		newMessageFromPart(inMsg.Get(0)).Copy(),
+		newMessageFromPart(inMsg.Get(1)).Copy(),
+	}
+	batch[0].SetBytes([]byte("foo result"))
+	batch[1].SetBytes([]byte("bar result"))
+	require.NoError(t, batch.AddSyncResponse())
+
+	results := resultStore.Get()
+	require.Len(t, results, 1)
+	require.Equal(t, 2, results[0].Len())
+	assert.Equal(t, "foo result", string(results[0].Get(0).Get()))
+	assert.Equal(t, "bar result", string(results[0].Get(1).Get()))
+}
@@ -64,6 +64,10 @@ type BatchOutput interface {
 	// Write a batch of messages to a sink, or return an error if delivery is
 	// not possible.
 	//
+	// If only some messages of the batch could not be delivered then a
+	// *BatchError can be returned in order to identify them, which allows
+	// the remaining messages to be acknowledged.
+	//
 	// If this method returns ErrNotConnected then write will not be called
 	// again until Connect has returned a nil error.
 	WriteBatch(context.Context, MessageBatch) error
@@ -140,6 +144,9 @@ func (a *airGapBatchWriter) WriteWithContext(ctx context.Context, msg types.Mess
 	if err != nil && errors.Is(err, ErrNotConnected) {
 		err = types.ErrNotConnected
 	}
+	if err != nil {
+		err = toInternalBatchError(err, msg)
+	}
 	return err
 }
 
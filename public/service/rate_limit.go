@@ -20,6 +20,26 @@ type RateLimit interface {
 	Closer
 }
 
+// batchedRateLimit represents a rate limit that is able to consume multiple
+// units of access within a single request. This interface is optional for rate
+// limits and when implemented will automatically be utilised by components
+// that consume access in proportion to the size of data, such as the
+// rate_limit processor.
+type batchedRateLimit interface {
+	// AccessN attempts to consume n units of access. Either all units should be
+	// consumed and a zero duration returned, or no units consumed and a
+	// reasonable length of time to wait before requesting again returned.
+	AccessN(ctx context.Context, n int) (time.Duration, error)
+}
+
+// feedbackRateLimit is an optional interface for rate limits that adapt to the
+// responses of the resource they protect, as described by
+// ratelimit.V2Feedback.
+type feedbackRateLimit interface {
+	Throttled(retryAfter time.Duration)
+	Succeeded()
+}
+
 //------------------------------------------------------------------------------
 
 func newAirGapRateLimit(c RateLimit, stats metrics.Type) types.RateLimit {
@@ -33,7 +53,17 @@ type reverseAirGapRateLimit struct {
 	r types.RateLimit
 }
 
-func newReverseAirGapRateLimit(r types.RateLimit) *reverseAirGapRateLimit {
+func newReverseAirGapRateLimit(r types.RateLimit) RateLimit {
+	rn, isN := r.(types.RateLimitWithAccessN)
+	rf, isF := r.(types.RateLimitWithFeedback)
+	switch {
+	case isN && isF:
+		return &reverseAirGapRateLimitNF{reverseAirGapRateLimitN{reverseAirGapRateLimit{r}, rn}, rf}
+	case isN:
+		return &reverseAirGapRateLimitN{reverseAirGapRateLimit{r}, rn}
+	case isF:
+		return &reverseAirGapRateLimitF{reverseAirGapRateLimit{r}, rf}
+	}
 	return &reverseAirGapRateLimit{r}
 }
 
@@ -55,3 +85,26 @@ func (a *reverseAirGapRateLimit) Close(ctx context.Context) error {
 		}
 	}
 }
+
+// Implements batchedRateLimit around a types.RateLimitWithAccessN
+type reverseAirGapRateLimitN struct {
+	reverseAirGapRateLimit
+	rn types.RateLimitWithAccessN
+}
+
+func (a *reverseAirGapRateLimitN) AccessN(ctx context.Context, n int) (time.Duration, error) {
+	return a.rn.AccessN(n)
+}
+
+// Implements feedbackRateLimit around a types.RateLimitWithFeedback
+type reverseAirGapRateLimitF struct {
+	reverseAirGapRateLimit
+	types.RateLimitWithFeedback
+}
+
+// Implements batchedRateLimit and feedbackRateLimit around a rate limit that
+// implements both types.RateLimitWithAccessN and types.RateLimitWithFeedback
+type reverseAirGapRateLimitNF struct {
+	reverseAirGapRateLimitN
+	types.RateLimitWithFeedback
+}
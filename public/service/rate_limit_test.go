@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type closableRateLimit struct {
@@ -92,3 +94,87 @@ func TestRateLimitReverseAirGapShutdown(t *testing.T) {
 	assert.NoError(t, agrl.Close(context.Background()))
 	assert.True(t, rl.closed)
 }
+
+//------------------------------------------------------------------------------
+
+type accessNRateLimit struct {
+	closableRateLimit
+	lastN int
+}
+
+func (a *accessNRateLimit) AccessN(ctx context.Context, n int) (time.Duration, error) {
+	a.lastN = n
+	return a.next, a.err
+}
+
+func TestRateLimitAirGapAccessN(t *testing.T) {
+	rl := &accessNRateLimit{}
+	agrl := newAirGapRateLimit(rl, metrics.Noop())
+
+	rln, ok := agrl.(types.RateLimitWithAccessN)
+	require.True(t, ok)
+
+	rl.next = time.Second
+	tout, err := rln.AccessN(5)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, tout)
+	assert.Equal(t, 5, rl.lastN)
+}
+
+type accessNRateLimitType struct {
+	closableRateLimitType
+	lastN int
+}
+
+func (a *accessNRateLimitType) AccessN(n int) (time.Duration, error) {
+	a.lastN = n
+	return a.next, a.err
+}
+
+func TestRateLimitReverseAirGapAccessN(t *testing.T) {
+	agrl := newReverseAirGapRateLimit(&closableRateLimitType{})
+	_, ok := agrl.(batchedRateLimit)
+	assert.False(t, ok)
+
+	rl := &accessNRateLimitType{}
+	agrl = newReverseAirGapRateLimit(rl)
+
+	rln, ok := agrl.(batchedRateLimit)
+	require.True(t, ok)
+
+	rl.next = time.Millisecond
+	tout, err := rln.AccessN(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Millisecond, tout)
+	assert.Equal(t, 3, rl.lastN)
+}
+
+type feedbackRateLimitType struct {
+	closableRateLimitType
+	throttled []time.Duration
+	succeeded int
+}
+
+func (f *feedbackRateLimitType) Throttled(retryAfter time.Duration) {
+	f.throttled = append(f.throttled, retryAfter)
+}
+
+func (f *feedbackRateLimitType) Succeeded() {
+	f.succeeded++
+}
+
+func TestRateLimitReverseAirGapFeedback(t *testing.T) {
+	rl := &feedbackRateLimitType{}
+	agrl := newReverseAirGapRateLimit(rl)
+
+	_, ok := agrl.(batchedRateLimit)
+	assert.False(t, ok)
+
+	rlf, ok := agrl.(feedbackRateLimit)
+	require.True(t, ok)
+
+	rlf.Throttled(time.Second)
+	rlf.Succeeded()
+	assert.Equal(t, []time.Duration{time.Second}, rl.throttled)
+	assert.Equal(t, 1, rl.succeeded)
+}
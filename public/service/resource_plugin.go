@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bundle"
+	"github.com/Jeffail/benthos/v3/internal/shutdown"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+// ResourcePluginConstructor is a func that's provided a configuration type and
+// access to a service manager and must return an instantiation of a custom
+// resource based on the config, or an error. The resource can be of any type,
+// and if it implements Closer then it will be closed by the service manager
+// during shut down.
+type ResourcePluginConstructor func(conf *ParsedConfig, mgr *Resources) (interface{}, error)
+
+// RegisterResourcePlugin attempts to register a new custom resource type by
+// providing a description of the configuration for the resource as well as a
+// constructor for the resource itself. Resources of this type can be
+// configured within the `plugin_resources` field of a config, where each
+// resource has a unique `label` and a `type` field matching the name of the
+// plugin, and the constructor will be called once for each resource defined.
+// Resources are created and closed by the service manager alongside caches and
+// rate limits.
+//
+// Other plugin components can then access these resources by name with the
+// AccessPlugin method of Resources. Resource plugins are registered globally
+// and are therefore available to all environments.
+func RegisterResourcePlugin(name string, spec *ConfigSpec, ctor ResourcePluginConstructor) error {
+	if name == "" {
+		return errors.New("resource plugin name must not be empty")
+	}
+	manager.RegisterPlugin(name, func() interface{} {
+		return &yaml.Node{}
+	}, func(conf interface{}, mgr types.Manager, _ log.Modular, _ metrics.Type) (interface{}, error) {
+		nm, ok := mgr.(bundle.NewManagement)
+		if !ok {
+			return nil, errors.New("resource plugins require a service manager")
+		}
+		node, _ := conf.(*yaml.Node)
+		if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			node = node.Content[0]
+		}
+		if node == nil || node.Kind == 0 || node.Tag == "!!null" {
+			node = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		pluginConf, err := spec.configFromNode(nm, node)
+		if err != nil {
+			return nil, err
+		}
+		r, err := ctor(pluginConf, newResourcesFromManager(nm))
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapResourcePlugin(r), nil
+	})
+	manager.DocumentPlugin(name, spec.component.Summary, nil)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Implements types.Closable around a custom resource, which is closed if it
+// implements Closer.
+type airGapResourcePlugin struct {
+	r   interface{}
+	sig *shutdown.Signaller
+}
+
+func newAirGapResourcePlugin(r interface{}) *airGapResourcePlugin {
+	return &airGapResourcePlugin{r: r, sig: shutdown.NewSignaller()}
+}
+
+func (a *airGapResourcePlugin) CloseAsync() {
+	closer, ok := a.r.(Closer)
+	if !ok {
+		a.sig.ShutdownComplete()
+		return
+	}
+	go func() {
+		if err := closer.Close(context.Background()); err == nil {
+			a.sig.ShutdownComplete()
+		}
+	}()
+}
+
+func (a *airGapResourcePlugin) WaitForClose(tout time.Duration) error {
+	select {
+	case <-a.sig.HasClosedChan():
+	case <-time.After(tout):
+		return types.ErrTimeout
+	}
+	return nil
+}
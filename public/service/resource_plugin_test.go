@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type testResourcePlugin struct {
+	prefix string
+	closed bool
+}
+
+func (t *testResourcePlugin) Close(ctx context.Context) error {
+	t.closed = true
+	return nil
+}
+
+func TestResourcePlugin(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewStringField("prefix").Default("default")).
+		Field(NewIntField("count").Default(1))
+
+	var created []*testResourcePlugin
+	require.NoError(t, RegisterResourcePlugin("test_resource_plugin", spec, func(conf *ParsedConfig, mgr *Resources) (interface{}, error) {
+		prefix, err := conf.FieldString("prefix")
+		if err != nil {
+			return nil, err
+		}
+		p := &testResourcePlugin{prefix: prefix}
+		created = append(created, p)
+		return p, nil
+	}))
+
+	resConf := manager.NewResourceConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+plugin_resources:
+  - label: foo
+    type: test_resource_plugin
+    plugin:
+      prefix: hello
+resources:
+  plugins:
+    bar:
+      type: test_resource_plugin
+`), &resConf))
+
+	mgr, err := manager.NewV2(resConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	res := newResourcesFromManager(mgr)
+
+	var foo, bar *testResourcePlugin
+	require.NoError(t, res.AccessPlugin(context.Background(), "foo", func(p interface{}) {
+		foo = p.(*testResourcePlugin)
+	}))
+	require.NoError(t, res.AccessPlugin(context.Background(), "bar", func(p interface{}) {
+		bar = p.(*testResourcePlugin)
+	}))
+	assert.Equal(t, "hello", foo.prefix)
+	assert.Equal(t, "default", bar.prefix)
+
+	assert.Equal(t, types.ErrPluginNotFound, res.AccessPlugin(context.Background(), "baz", func(p interface{}) {
+		t.Error("closure should not be called")
+	}))
+
+	mgr.CloseAsync()
+	require.NoError(t, mgr.WaitForClose(time.Second))
+	assert.True(t, foo.closed)
+	assert.True(t, bar.closed)
+}
@@ -49,3 +49,19 @@ func (r *Resources) AccessRateLimit(ctx context.Context, name string, fn func(r
 		fn(newReverseAirGapRateLimit(r))
 	})
 }
+
+// AccessPlugin attempts to access a custom resource, registered with
+// RegisterResourcePlugin, by name. The resource is provided to the closure as
+// the value returned by the constructor of the plugin, and can be asserted to
+// its concrete type. An error is returned if the resource does not exist.
+func (r *Resources) AccessPlugin(ctx context.Context, name string, fn func(p interface{})) error {
+	p, err := r.mgr.GetPlugin(name)
+	if err != nil {
+		return err
+	}
+	if a, ok := p.(*airGapResourcePlugin); ok {
+		p = a.r
+	}
+	fn(p)
+	return nil
+}